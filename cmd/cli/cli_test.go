@@ -178,3 +178,37 @@ func TestHostmgrHosts(t *testing.T) {
 	cmd, err = app.Parse([]string{"hostmgr", "hosts", "--cpu", "-1.0", "--gpu", "-5.0"})
 	assert.Error(t, err)
 }
+
+func TestParseCompletion(t *testing.T) {
+	cmd, err := app.Parse([]string{"completion", "zsh"})
+	assert.Nil(t, err)
+	assert.Equal(t, completion.FullCommand(), cmd)
+	assert.Equal(t, "zsh", *completionShell)
+
+	_, err = app.Parse([]string{"completion", "fish"})
+	assert.NotNil(t, err)
+}
+
+func TestParseFrameworkInfoMigrate(t *testing.T) {
+	base := "../../config/hostmgr/base.yaml"
+	dev := "../../config/hostmgr/development.yaml"
+	cmd, err := app.Parse([]string{"framework-info-migrate",
+		"--from", base, "--to", base, "--to", dev, "--dry-run"})
+	assert.Nil(t, err)
+	assert.Equal(t, frameworkInfoMigrate.FullCommand(), cmd)
+	assert.Equal(t, []string{base}, *frameworkInfoMigrateFrom)
+	assert.Equal(t, []string{base, dev}, *frameworkInfoMigrateTo)
+	assert.Equal(t, "Peloton", *frameworkInfoMigrateName)
+	assert.True(t, *frameworkInfoMigrateDryRun)
+	assert.False(t, *frameworkInfoMigrateOverwrite)
+
+	_, err = app.Parse([]string{"framework-info-migrate", "--from", base})
+	assert.NotNil(t, err)
+}
+
+func TestParseClusterInfo(t *testing.T) {
+	cmd, err := app.Parse([]string{"cluster", "info", "--json"})
+	assert.Nil(t, err)
+	assert.Equal(t, clusterInfo.FullCommand(), cmd)
+	assert.True(t, *jsonFormat)
+}
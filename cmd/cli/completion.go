@@ -0,0 +1,108 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"os"
+	"time"
+
+	pc "github.com/uber/peloton/pkg/cli"
+	"github.com/uber/peloton/pkg/cli/config"
+	"github.com/uber/peloton/pkg/cli/middleware"
+
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// completionTimeout bounds the time spent fetching completion candidates,
+// so that no candidates are offered if the cluster is unreachable
+const completionTimeout = 2 * time.Second
+
+// printCompletionScript prints the completion script of the shell, which
+// calls back into the CLI with --completion-bash to complete arguments
+func printCompletionScript(shell string) error {
+	context, err := app.ParseContext(nil)
+	if err != nil {
+		return err
+	}
+	template := kingpin.BashCompletionTemplate
+	if shell == "zsh" {
+		template = kingpin.ZshCompletionTemplate
+	}
+	app.UsageWriter(os.Stdout)
+	return app.UsageForContextWithTemplate(context, 0, template)
+}
+
+// completions returns the candidates fetched with a client of the selected
+// cluster, or none if they are not fetched within completionTimeout
+func completions(fetch func(*pc.Client) ([]string, error)) []string {
+	result := make(chan []string, 1)
+	go func() {
+		candidates, err := fetchCompletions(fetch)
+		if err != nil {
+			candidates = nil
+		}
+		result <- candidates
+	}()
+
+	select {
+	case candidates := <-result:
+		return candidates
+	case <-time.After(completionTimeout):
+		return nil
+	}
+}
+
+// fetchCompletions creates a client of the selected cluster and fetches the
+// candidates with it
+func fetchCompletions(fetch func(*pc.Client) ([]string, error)) ([]string, error) {
+	configPath, err := config.DefaultFilePath()
+	if err != nil {
+		return nil, err
+	}
+	configFile, err := config.LoadFile(configPath)
+	if err != nil {
+		// complete with the settings of the flags only
+		configFile = &config.File{}
+	}
+	settings, err := resolveSettings(configFile)
+	if err != nil {
+		return nil, err
+	}
+	client, err := newClient(settings, middleware.RetryPolicy{
+		Timeout: completionTimeout,
+	}, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Cleanup()
+	return fetch(client)
+}
+
+// completeJobIDs completes job identifiers with the most recent jobs
+func completeJobIDs() []string {
+	return completions(func(client *pc.Client) ([]string, error) {
+		return client.JobIDCompletions(pc.CompletionRespoolPath)
+	})
+}
+
+// completeRespoolPaths completes resource pool paths
+func completeRespoolPaths() []string {
+	return completions((*pc.Client).RespoolPathCompletions)
+}
+
+// completeHostnames completes hostnames
+func completeHostnames() []string {
+	return completions((*pc.Client).HostnameCompletions)
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	common_config "github.com/uber/peloton/pkg/common/config"
+	"github.com/uber/peloton/pkg/common/leader"
+	"github.com/uber/peloton/pkg/storage"
+	storage_config "github.com/uber/peloton/pkg/storage/config"
+	"github.com/uber/peloton/pkg/storage/stores"
+
+	"github.com/uber-go/tally"
+)
+
+// frameworkInfoConfig is the part of a host manager config which selects
+// its framework info store
+type frameworkInfoConfig struct {
+	Storage  storage_config.Config `yaml:"storage"`
+	Election leader.ElectionConfig `yaml:"election"`
+}
+
+// newFrameworkInfoStore returns the framework info store selected by host
+// manager config files
+func newFrameworkInfoStore(
+	configFiles []string) (storage.FrameworkInfoStore, error) {
+	var cfg frameworkInfoConfig
+	if err := common_config.Parse(&cfg, configFiles...); err != nil {
+		return nil, err
+	}
+	if cfg.Storage.FrameworkInfoStore == storage_config.FrameworkInfoStoreMemory {
+		return nil, errors.New("framework info kept in memory cannot be migrated")
+	}
+	// the schema is left to the host manager, and the store itself is read
+	// to verify the copy rather than a cache
+	cfg.Storage.AutoMigrate = false
+	cfg.Storage.FrameworkInfoCacheTTL = 0
+
+	var store storage.Store
+	if cfg.Storage.FrameworkInfoStore == "" {
+		store = stores.MustCreateStore(&cfg.Storage, tally.NoopScope)
+	}
+	return stores.MustCreateFrameworkInfoStore(
+		&cfg.Storage, store, cfg.Election, nil, tally.NoopScope), nil
+}
+
+// migrateFrameworkInfo copies the framework info of a framework name from
+// the framework info store of the host manager config files from to the
+// one of the config files to, or only prints what would be copied
+func migrateFrameworkInfo(
+	from []string,
+	to []string,
+	frameworkName string,
+	overwrite bool,
+	dryRun bool,
+	timeout time.Duration) error {
+	src, err := newFrameworkInfoStore(from)
+	if err != nil {
+		return err
+	}
+	dst, err := newFrameworkInfoStore(to)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	migrate := storage.MigrateFrameworkInfo
+	verb := "Copied"
+	if dryRun {
+		migrate = storage.PlanFrameworkInfoMigration
+		verb = "Would copy"
+	}
+	copies, err := migrate(ctx, src, dst, frameworkName, overwrite)
+	if err != nil {
+		return err
+	}
+
+	if len(copies) == 0 {
+		fmt.Printf("Framework info of %s is already in the destination\n",
+			frameworkName)
+		return nil
+	}
+	for _, c := range copies {
+		if c.OldValue == "" {
+			fmt.Printf("%s %s %q\n", verb, c.Field, c.Value)
+		} else {
+			fmt.Printf("%s %s %q replacing %q\n", verb, c.Field, c.Value, c.OldValue)
+		}
+	}
+	return nil
+}
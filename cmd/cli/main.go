@@ -15,22 +15,30 @@
 package main
 
 import (
+	"crypto/tls"
 	"fmt"
+	"io"
 	"math"
+	"net/url"
 	"os"
+	"os/user"
 	"strconv"
 	"strings"
+	"time"
 
 	pt "github.com/uber/peloton/.gen/peloton/api/v0/task"
 
 	pc "github.com/uber/peloton/pkg/cli"
 	"github.com/uber/peloton/pkg/cli/config"
+	"github.com/uber/peloton/pkg/cli/history"
 	"github.com/uber/peloton/pkg/cli/middleware"
 	"github.com/uber/peloton/pkg/common"
 	common_config "github.com/uber/peloton/pkg/common/config"
 	"github.com/uber/peloton/pkg/common/leader"
 	"github.com/uber/peloton/pkg/common/util"
 
+	"github.com/pborman/uuid"
+	log "github.com/sirupsen/logrus"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
@@ -43,36 +51,110 @@ var (
 	// Global CLI flags
 	jsonFormat = app.Flag(
 		"json",
-		"print full json responses").
+		"print responses and errors as canonical json").
 		Short('j').
 		Default("false").
 		Bool()
 
+	outputFormat = app.Flag(
+		"output",
+		"output format of job get/query, task list, respool dump and host query: "+
+			"table, json, yaml, csv or go-template=<template>. "+
+			"csv is supported by job query, task list and host query").
+		Default("").
+		String()
+
+	assumeYes = app.Flag(
+		"yes",
+		"do not ask for confirmation of destructive commands, required if "+
+			"stdin is not a terminal").
+		Short('y').
+		Default("false").
+		Bool()
+
+	skipEmptyHosts = app.Flag(
+		"skip-empty-hosts",
+		"skip empty hosts of host lists, e.g. after a trailing comma, "+
+			"instead of failing").
+		Default("false").
+		Bool()
+
+	maxHosts = app.Flag(
+		"max-hosts",
+		"maximum number of hosts of a host list").
+		Default(strconv.Itoa(pc.DefaultMaxHosts)).
+		Int()
+
+	maxHostRangeExpansion = app.Flag(
+		"max-host-range-expansion",
+		"maximum number of hosts a numeric host range, e.g. "+
+			"compute[0001-0250].dc1, may expand to").
+		Default(strconv.Itoa(pc.DefaultMaxHostRangeExpansion)).
+		Int()
+
+	skipHostValidation = app.Flag(
+		"skip-host-validation",
+		"use the hosts of host lists without checking that they are valid "+
+			"hostnames or IP addresses").
+		Default("false").
+		Bool()
+
+	outputColumns = app.Flag(
+		"columns",
+		"comma separated columns of job query, task list and host query "+
+			"table or csv output in order, e.g. id,name,state,labels").
+		Default("").
+		String()
+
+	noColor = app.Flag(
+		"no-color",
+		"do not color the states of job status, task list and host query "+
+			"tables on a terminal, also disabled by setting $NO_COLOR").
+		Default("false").
+		Bool()
+
+	maxRPS = app.Flag(
+		"max-rps",
+		"maximum number of mutating requests per second of bulk commands, "+
+			"e.g. job stop --all, task kill-by-host and host maintenance, "+
+			"0 to not limit them").
+		Default(strconv.Itoa(pc.DefaultMaxRPS)).
+		Float64()
+
+	progressFormat = app.Flag(
+		"progress-format",
+		"format of the progress of job wait, job stop --all, update status "+
+			"--watch, host maintenance start --wait and host drain: text, or "+
+			"json for a progress event per line on stderr, leaving only the "+
+			"result on stdout").
+		Default(pc.ProgressFormatText).
+		Enum(pc.ProgressFormatText, pc.ProgressFormatJSON)
+
 	// TODO: deprecate jobMgrURL/resMgrURL/hostMgrURL once we fix minicluster container network
 	//       and make sure that local cli can access Uber Prodution hostname/ip
 	jobMgrURL = app.Flag(
 		"jobmgr",
-		"name of the jobmgr address to use (grpc) (set $JOBMGR_URL to override)").
+		"name of the jobmgr address to use (grpc), defaults to localhost:5392 "+
+			"(set $JOBMGR_URL to override)").
 		Short('m').
-		Default("localhost:5392").
 		Envar("JOBMGR_URL").
-		URL()
+		String()
 
 	resMgrURL = app.Flag(
 		"resmgr",
-		"name of the resource manager address to use (grpc) (set $RESMGR_URL to override)").
+		"name of the resource manager address to use (grpc), defaults to "+
+			"localhost:5394 (set $RESMGR_URL to override)").
 		Short('v').
-		Default("localhost:5394").
 		Envar("RESMGR_URL").
-		URL()
+		String()
 
 	hostMgrURL = app.Flag(
 		"hostmgr",
-		"name of the host manager address to use (grpc) (set $HOSTMGR_URL to override)").
+		"name of the host manager address to use (grpc), defaults to "+
+			"localhost:5391 (set $HOSTMGR_URL to override)").
 		Short('u').
-		Default("localhost:5391").
 		Envar("HOSTMGR_URL").
-		URL()
+		String()
 
 	clusterName = app.Flag(
 		"clusterName",
@@ -84,6 +166,13 @@ var (
 		Envar("CLUSTER_NAME").
 		String()
 
+	cluster = app.Flag(
+		"cluster",
+		"name of the cluster profile in ~/.peloton/config.yaml to connect to, "+
+			"flags override the settings of the profile "+
+			"(set $"+config.ClusterEnvVar+" to override)").
+		String()
+
 	zkServers = app.Flag(
 		"zkservers",
 		"zookeeper servers used for peloton service discovery. "+
@@ -96,7 +185,6 @@ var (
 	zkRoot = app.Flag(
 		"zkroot",
 		"zookeeper root path for peloton service discovery(set $ZK_ROOT to override)").
-		Default(common.DefaultLeaderElectionRoot).
 		Envar("ZK_ROOT").
 		String()
 
@@ -106,50 +194,175 @@ var (
 		Envar("BASIC_AUTH_CONFIG").
 		String()
 
+	authUser = app.Flag(
+		"auth-user",
+		"user of basic auth, requires --auth-password-file").
+		String()
+
+	authPasswordFile = app.Flag(
+		"auth-password-file",
+		"file holding the password of basic auth, requires --auth-user").
+		ExistingFile()
+
+	authTokenFile = app.Flag(
+		"auth-token-file",
+		"file holding the token of token auth, cannot be used with basic auth").
+		ExistingFile()
+
 	timeout = app.Flag(
 		"timeout",
-		"default RPC timeout (set $TIMEOUT to override)").
-		Default("20s").
+		"timeout of a single RPC attempt, defaults to 20s (set $TIMEOUT to override)").
 		Short('t').
 		Envar("TIMEOUT").
 		Duration()
 
+	retries = app.Flag(
+		"retries",
+		"number of times RPCs which do not mutate state are retried on transient errors").
+		Default("2").
+		Int()
+
+	retryBackoff = app.Flag(
+		"retry-backoff",
+		"delay before the first retry of an RPC, doubled for every further retry").
+		Default("500ms").
+		Duration()
+
+	waitForLeader = app.Flag(
+		"wait-for-leader",
+		"keep retrying the discovery of the leaders of the peloton services "+
+			"for up to this duration, e.g. while they fail over").
+		Default("0s").
+		Duration()
+
+	retryMutations = app.Flag(
+		"retry-mutations",
+		"also retry RPCs which mutate state, e.g. job create or task stop").
+		Default("false").
+		Bool()
+
+	caCert = app.Flag(
+		"ca-cert",
+		"PEM file of the CAs used to verify peloton services over TLS").
+		ExistingFile()
+
+	clientCert = app.Flag(
+		"client-cert",
+		"PEM file of the client certificate for mutual TLS, requires --client-key").
+		ExistingFile()
+
+	clientKey = app.Flag(
+		"client-key",
+		"PEM file of the client key for mutual TLS, requires --client-cert").
+		ExistingFile()
+
+	insecureSkipVerify = app.Flag(
+		"insecure-skip-verify",
+		"use TLS without verifying the certificates of peloton services, "+
+			"--no-insecure-skip-verify turns the verification back on over a "+
+			"cluster profile").
+		Default("false").
+		Action(flagSet(&insecureSkipVerifySet)).
+		Bool()
+	insecureSkipVerifySet bool
+
+	debug = app.Flag(
+		"debug",
+		"log debug messages, e.g. retried RPCs").
+		Default("false").
+		Bool()
+
+	debugRPC = app.Flag(
+		"debug-rpc",
+		"print every RPC to stderr as a JSON line of its procedure, request, "+
+			"response or error and latency, with secrets redacted").
+		Default("false").
+		Bool()
+
+	requestID = app.Flag(
+		"request-id",
+		"ID carried by every RPC of the invocation in the "+
+			middleware.RequestIDHeader+" header and printed if it fails, "+
+			"e.g. to reproduce a failure. A random UUID if not set").
+		Default("").
+		String()
+
 	// Top level job command
 	job = app.Command("job", "manage jobs")
 
 	jobCreate            = job.Command("create", "create a job")
 	jobCreateID          = jobCreate.Flag("jobID", "optional job identifier, must be UUID format").Short('i').String()
 	jobCreateResPoolPath = jobCreate.Arg("respool", "complete path of the "+
-		"resource pool starting from the root").Required().String()
+		"resource pool starting from the root").HintAction(completeRespoolPaths).Required().String()
 	jobCreateConfig     = jobCreate.Arg("config", "YAML job configuration").Required().ExistingFile()
 	jobCreateSecretPath = jobCreate.Flag("secret-path", "secret mount path").Default("").String()
 	jobCreateSecret     = jobCreate.Flag("secret-data", "secret data string").Default("").String()
 
+	jobValidate            = job.Command("validate", "validate a job configuration without creating the job")
+	jobValidateConfig      = jobValidate.Arg("config", "YAML job configuration").Required().ExistingFile()
+	jobValidateRespoolPath = jobValidate.Flag("respool", "complete path of the resource pool "+
+		"the job would be created in").Default("").Short('r').HintAction(completeRespoolPaths).String()
+
+	jobDiff       = job.Command("diff", "print the changes between the config of a running job and a local config, exits with 1 if they differ")
+	jobDiffName   = jobDiff.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
+	jobDiffConfig = jobDiff.Arg("config", "YAML job configuration").Required().ExistingFile()
+	jobDiffFields = jobDiff.Flag("fields", "comma separated fields to compare, e.g. instanceCount,resource,command,labels,constraint").Default("").String()
+
 	jobDelete     = job.Command("delete", "delete a job")
-	jobDeleteName = jobDelete.Arg("job", "job identifier").Required().String()
+	jobDeleteName = jobDelete.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 
 	jobStop         = job.Command("stop", "stop job(s) by job identifier, owning team or labels")
-	jobStopName     = jobStop.Arg("job", "job identifier").Default("").String()
+	jobStopName     = jobStop.Arg("job", "job identifier").HintAction(completeJobIDs).Default("").String()
 	jobStopProgress = jobStop.Flag("progress",
 		"show progress of the job stopping").Default(
 		"false").Bool()
 	jobStopOwner  = jobStop.Flag("owner", "job owner").Default("").String()
 	jobStopLabels = jobStop.Flag("labels", "job labels").Default("").Short('l').String()
-	jobStopForce  = jobStop.Flag("force", "force stop").Default("false").Short('f').Bool()
+	jobStopForce  = jobStop.Flag("force", "force stop without asking for confirmation, same as --yes").Default("false").Short('f').Bool()
+
+	jobStopAll         = jobStop.Flag("all", "stop all jobs of the resource pool given by --respool").Default("false").Bool()
+	jobStopRespoolPath = jobStop.Flag("respool", "resource pool of the jobs to stop with --all").HintAction(completeRespoolPaths).Default("").Short('r').String()
+	jobStopStates      = jobStop.Flag("state", "states of the jobs to stop with --all").Default(pc.DefaultJobStopStates).String()
+	jobStopConcurrency = jobStop.Flag("concurrency", "number of jobs stopped in parallel with --all").Default(strconv.Itoa(pc.DefaultJobStopConcurrency)).Int()
+	jobStopDryRun      = jobStop.Flag("dry-run", "only list the jobs which would be stopped with --all").Default("false").Bool()
 
 	jobGet     = job.Command("get", "get a job")
-	jobGetName = jobGet.Arg("job", "job identifier").Required().String()
+	jobGetName = jobGet.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
+
+	jobExport       = job.Command("export", "print the config of a job in a format job create accepts, without the fields set by peloton")
+	jobExportName   = jobExport.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
+	jobExportFormat = jobExport.Flag("format", "format of the exported config").Short('o').Default(pc.OutputYAML).Enum(pc.OutputYAML)
+
+	jobClone             = job.Command("clone", "create a copy of a batch job with overrides of its config, and print the ID of the new job")
+	jobCloneName         = jobClone.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
+	jobCloneNewName      = jobClone.Flag("name", "name of the new job, the name of the source job if not set").String()
+	jobCloneInstances    = jobClone.Flag("instances", "instance count of the new job, the count of the source job if not set").Uint32()
+	jobCloneRespoolPath  = jobClone.Flag("respool", "resource pool of the new job, the pool of the source job if not set").Short('r').HintAction(completeRespoolPaths).String()
+	jobCloneOverrides    = jobClone.Flag("set", "override a field of the config as path=value, e.g. defaultconfig.command.value='echo hi', the value is parsed as YAML. Can be repeated").Strings()
+	jobCloneCloneSecrets = jobClone.Flag("clone-secrets", "clone a job with secrets, the secret data is not returned by peloton so the new job is created without the secrets").Default("false").Bool()
 
 	jobRefresh     = job.Command("refresh", "load runtime state of job and re-refresh corresponding action (debug only)")
-	jobRefreshName = jobRefresh.Arg("job", "job identifier").Required().String()
+	jobRefreshName = jobRefresh.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 
-	jobStatus     = job.Command("status", "get job status")
-	jobStatusName = jobStatus.Arg("job", "job identifier").Required().String()
+	jobWait             = job.Command("wait", "wait for a job to terminate, exiting with 0 on SUCCEEDED, 2 on FAILED, 3 on KILLED and 4 on timeout")
+	jobWaitName         = jobWait.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
+	jobWaitTimeout      = jobWait.Flag("wait-timeout", "maximum time to wait for the job (the global --timeout is the RPC timeout)").Default("1h").Duration()
+	jobWaitPollInterval = jobWait.Flag("poll-interval", "interval between two job status polls").Default("5s").Duration()
+
+	jobStatus              = job.Command("status", "get job status")
+	jobStatusName          = jobStatus.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
+	jobStatusWatch         = jobStatus.Flag("watch", "refresh the status until the job is terminal").Short('w').Default("false").Bool()
+	jobStatusWatchInterval = jobStatus.Flag("interval", "refresh interval of --watch").Default("2s").Duration()
+	jobStatusSummary       = jobStatus.Flag("summary", "summarize the task states and the most common failures, --no-summary prints the job runtime").Default("true").Bool()
+
+	jobFailures     = job.Command("failures", "group the failed and lost tasks of a job by failure message, and count the failures of each host")
+	jobFailuresName = jobFailures.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
+	jobFailuresTop  = jobFailures.Flag("top", "number of failure groups and hosts printed, all are printed in json").Default(strconv.Itoa(pc.DefaultJobFailuresTop)).Uint32()
 
 	// peloton -z zookeeper-peloton-devel01 job query --labels="x=y,a=b" --respool=xx --keywords=k1,k2 --states=running,killed --limit=1
 	jobQuery            = job.Command("query", "query jobs by mesos label / respool")
-	jobQueryLabels      = jobQuery.Flag("labels", "labels").Default("").Short('l').String()
-	jobQueryRespoolPath = jobQuery.Flag("respool", "respool path").Default("").Short('r').String()
+	jobQueryLabels      = jobQuery.Flag("labels", "label selector, e.g. \"team in (infra,data),tier!=dev\". k=v labels are matched by peloton, other expressions client-side").Default("").Short('l').String()
+	jobQueryRespoolPath = jobQuery.Flag("respool", "respool path").HintAction(completeRespoolPaths).Default("").Short('r').String()
 	jobQueryKeywords    = jobQuery.Flag("keywords", "keywords").Default("").Short('k').String()
 	jobQueryStates      = jobQuery.Flag("states", "job states").Default("").Short('s').String()
 	jobQueryOwner       = jobQuery.Flag("owner", "job owner").Default("").String()
@@ -158,38 +371,50 @@ var (
 	// We support protobuf timestamps in backend to define time range
 	// To keep CLI simple, lets accept this time range for creation time in last n days
 	jobQueryTimeRange = jobQuery.Flag("timerange", "query jobs created within last d days").Short('d').Default("0").Uint32()
-	jobQueryLimit     = jobQuery.Flag("limit", "maximum number of jobs to return").Default("100").Short('n').Uint32()
+	jobQueryLimit     = jobQuery.Flag("limit", "maximum number of jobs to return").Default("100").Short('n').Action(flagSet(&jobQueryLimitSet)).Uint32()
 	jobQueryMaxLimit  = jobQuery.Flag("total", "total number of jobs to query").Default("100").Short('q').Uint32()
-	jobQueryOffset    = jobQuery.Flag("offset", "offset").Default("0").Short('o').Uint32()
-	jobQuerySortBy    = jobQuery.Flag("sort", "sort by property").Default("creation_time").Short('p').String()
-	jobQuerySortOrder = jobQuery.Flag("sortorder", "sort order (ASC or DESC)").Default("DESC").Short('a').String()
+	jobQueryOffset    = jobQuery.Flag("offset", "offset").Default("0").Short('o').Action(flagSet(&jobQueryOffsetSet)).Uint32()
+	jobQuerySortBy    = jobQuery.Flag("sort", "sort by property, the order in which the server pages through the jobs").Default("creation_time").Short('p').String()
+	jobQuerySortOrder = jobQuery.Flag("sortorder", "server order (ASC or DESC)").Default("DESC").Short('a').String()
+	jobQuerySort      = jobQuery.Flag("sort-results", "sort the fetched jobs by <field>[:desc], one of creation_time, completion_time, state, instance_count or name").Default("").String()
+	jobQueryAll       = jobQuery.Flag("all", "fetch all pages of jobs, conflicts with --limit and --offset").Default("false").Bool()
+	jobQueryLimitSet  bool
+	jobQueryOffsetSet bool
 
 	jobUpdate           = job.Command("update", "update a job")
-	jobUpdateID         = jobUpdate.Arg("job", "job identifier").Required().String()
+	jobUpdateID         = jobUpdate.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	jobUpdateConfig     = jobUpdate.Arg("config", "YAML job configuration").Required().ExistingFile()
 	jobUpdateSecretPath = jobUpdate.Flag("secret-path", "secret mount path").Default("").String()
 	jobUpdateSecret     = jobUpdate.Flag("secret-data", "secret data string").Default("").String()
 
 	jobRestart                = job.Command("rolling-restart", "restart instances in a job using rolling-restart")
-	jobRestartName            = jobRestart.Arg("job", "job identifier").Required().String()
+	jobRestartName            = jobRestart.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	jobRestartBatchSize       = jobRestart.Arg("batch-size", "batch size for the restart").Required().Uint32()
 	jobRestartResourceVersion = jobRestart.Flag("resourceVersion", "resource version of the job for concurrency control").Default("0").Uint64()
 	jobRestartInstanceRanges  = taskRangeListFlag(jobRestart.Flag("range", "restart range of instances (specify multiple times) (from:to syntax, default ALL)").Default(":").Short('r'))
 
+	jobRestartMonitor          = job.Command("restart", "restart instances of a service and monitor the restart until it completes")
+	jobRestartMonitorName      = jobRestartMonitor.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
+	jobRestartMonitorInstances = jobRestartMonitor.Flag("instances", "restart instances in ranges, e.g. 0-9,15,20-25 (default all)").Default("").String()
+	jobRestartMonitorBatchSize = jobRestartMonitor.Flag("batch-size", "maximum number of instances restarted at the same time (0 restarts all at once)").Default("0").Uint32()
+	jobRestartMonitorInPlace   = jobRestartMonitor.Flag("in-place", "restart the instances on their current hosts on a best effort basis").Default("false").Bool()
+	jobRestartMonitorDetach    = jobRestartMonitor.Flag("detach", "start the restart without monitoring it").Default("false").Bool()
+	jobRestartMonitorInterval  = jobRestartMonitor.Flag("interval", "refresh interval of the restart status").Default("2s").Duration()
+
 	jobStart                = job.Command("rolling-start", "start instances in a job using rolling-start")
-	jobStartName            = jobStart.Arg("job", "job identifier").Required().String()
+	jobStartName            = jobStart.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	jobStartBatchSize       = jobStart.Arg("batch-size", "batch size for the start").Required().Uint32()
 	jobStartResourceVersion = jobStart.Flag("resourceVersion", "resource version of the job for concurrency control").Default("0").Uint64()
 	jobStartInstanceRanges  = taskRangeListFlag(jobStart.Flag("range", "start range of instances (specify multiple times) (from:to syntax, default ALL)").Default(":").Short('r'))
 
 	jobStopV1Beta                = job.Command("rolling-stop", "stop instances in a job using rolling-stop")
-	jobStopV1BetaName            = jobStopV1Beta.Arg("job", "job identifier").Required().String()
+	jobStopV1BetaName            = jobStopV1Beta.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	jobStopV1BetaBatchSize       = jobStopV1Beta.Arg("batch-size", "batch size for the stop").Required().Uint32()
 	jobStopV1BetaResourceVersion = jobStopV1Beta.Flag("resourceVersion", "resource version of the job for concurrency control").Default("0").Uint64()
 	jobStopV1BetaInstanceRanges  = taskRangeListFlag(jobStopV1Beta.Flag("range", "stop range of instances (specify multiple times) (from:to syntax, default ALL)").Default(":").Short('r'))
 
 	jobGetCache     = job.Command("cache", "get a job cache")
-	jobGetCacheName = jobGetCache.Arg("job", "job identifier").Required().String()
+	jobGetCacheName = jobGetCache.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 
 	jobGetActiveJobs = job.Command("active-list", "get a list of active jobs")
 
@@ -197,20 +422,20 @@ var (
 	stateless = job.Command("stateless", "manage stateless jobs")
 
 	statelessGetCache     = stateless.Command("cache", "get a job cache")
-	statelessGetCacheName = statelessGetCache.Arg("job", "job identifier").Required().String()
+	statelessGetCacheName = statelessGetCache.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 
 	statelessGet            = stateless.Command("get", "get stateless")
-	statelessGetJobID       = statelessGet.Arg("job", "job identifier").Required().String()
+	statelessGetJobID       = statelessGet.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	statelessGetVersion     = statelessGet.Flag("jobversion", "job specification version").Default("").String()
 	statelessGetSummaryOnly = statelessGet.Flag("summaryonly", "only return the job summary").Default("false").Bool()
 
 	statelessRefresh     = stateless.Command("refresh", "refresh a job")
-	statelessRefreshName = statelessRefresh.Arg("job", "job identifier").Required().String()
+	statelessRefreshName = statelessRefresh.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 
 	watch = app.Command("watch", "watch job / pod runtime changes")
 
 	watchPod         = watch.Command("pod", "watch pod runtime changes")
-	watchPodJobID    = watchPod.Arg("job", "job identifier").String()
+	watchPodJobID    = watchPod.Arg("job", "job identifier").HintAction(completeJobIDs).String()
 	watchPodPodNames = watchPod.Arg("pod", "pod name").Strings()
 	watchLabels      = watchPod.Flag("labels", "filter on labels (key:value pairs)").Strings()
 
@@ -219,21 +444,21 @@ var (
 
 	workflow                   = stateless.Command("workflow", "manage workflow for stateless job")
 	workflowPause              = workflow.Command("pause", "pause a workflow")
-	workflowPauseName          = workflowPause.Arg("job", "job identifier").Required().String()
+	workflowPauseName          = workflowPause.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	workflowPauseEntityVersion = workflowPause.Arg("entityVersion",
 		"entity version for concurrency control").Required().String()
 	workflowPauseOpaqueData = workflowPause.Flag("opaque-data",
 		"opaque data provided by the user").Default("").String()
 
 	workflowResume              = workflow.Command("resume", "resume a workflow")
-	workflowResumeName          = workflowResume.Arg("job", "job identifier").Required().String()
+	workflowResumeName          = workflowResume.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	workflowResumeEntityVersion = workflowResume.Arg("entityVersion",
 		"entity version for concurrency control").Required().String()
 	workflowResumeOpaqueData = workflowResume.Flag("opaque-data",
 		"opaque data provided by the user").Default("").String()
 
 	workflowAbort              = workflow.Command("abort", "abort a workflow")
-	workflowAbortName          = workflowAbort.Arg("job", "job identifier").Required().String()
+	workflowAbortName          = workflowAbort.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	workflowAbortEntityVersion = workflowAbort.Arg("entityVersion",
 		"entity version for concurrency control").Required().String()
 	workflowAbortOpaqueData = workflowAbort.Flag("opaque-data",
@@ -243,13 +468,13 @@ var (
 		"list workflow events in descending create time, "+
 			"for the most recet workflow operation on the job ")
 	workflowEventsJob = workflowEvents.Arg("job",
-		"job identifier").Required().String()
+		"job identifier").HintAction(completeJobIDs).Required().String()
 	workflowEventsInstance = workflowEvents.Arg("instance",
 		"instance ID").Required().Uint32()
 
 	statelessQuery            = stateless.Command("query", "query stateless jobs by mesos label / respool")
 	statelessQueryLabels      = statelessQuery.Flag("labels", "labels").Default("").Short('l').String()
-	statelessQueryRespoolPath = statelessQuery.Flag("respool", "respool path").Default("").Short('r').String()
+	statelessQueryRespoolPath = statelessQuery.Flag("respool", "respool path").HintAction(completeRespoolPaths).Default("").Short('r').String()
 	statelessQueryKeywords    = statelessQuery.Flag("keywords", "keywords").Default("").Short('k').String()
 	statelessQueryStates      = statelessQuery.Flag("states", "job states").Default("").Short('s').String()
 	statelessQueryOwner       = statelessQuery.Flag("owner", "job owner").Default("").String()
@@ -265,11 +490,11 @@ var (
 	statelessQuerySortOrder = statelessQuery.Flag("sortorder", "sort order (ASC or DESC)").Default("DESC").Short('a').String()
 
 	statelessReplace            = stateless.Command("replace", "update by replacing job config")
-	statelessReplaceJobID       = statelessReplace.Arg("job", "job identifier").Required().String()
+	statelessReplaceJobID       = statelessReplace.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	statelessReplaceSpec        = statelessReplace.Arg("spec", "YAML job spec").Required().ExistingFile()
 	statelessReplaceBatchSize   = statelessReplace.Arg("batch-size", "batch size for the update").Required().Uint32()
 	statelessReplaceResPoolPath = statelessReplace.Arg("respool", "complete path of the "+
-		"resource pool starting from the root").Required().String()
+		"resource pool starting from the root").HintAction(completeRespoolPaths).Required().String()
 	statelessReplaceEntityVersion = statelessReplace.Arg("entityVersion",
 		"entity version for concurrency control").Required().String()
 	statelessReplaceOverride = statelessReplace.Flag("override",
@@ -297,12 +522,12 @@ var (
 	statelessListJobs = stateless.Command("list", "list all jobs")
 
 	statelessListPods              = stateless.Command("list-pods", "list all pods in a job")
-	statelessListPodsJobID         = statelessListPods.Arg("job", "job identifier").Required().String()
+	statelessListPodsJobID         = statelessListPods.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	statelessListPodsInstanceRange = taskRangeFlag(statelessListPods.Flag("range", "show range of instances (from:to syntax)").Default(":").Short('r'))
 
 	statelessCreate            = stateless.Command("create", "create stateless job")
 	statelessCreateResPoolPath = statelessCreate.Arg("respool", "complete path of the "+
-		"resource pool starting from the root").Required().String()
+		"resource pool starting from the root").HintAction(completeRespoolPaths).Required().String()
 	statelessCreateSpec               = statelessCreate.Arg("spec", "YAML job specification").Required().ExistingFile()
 	statelessCreateBatchSize          = statelessCreate.Arg("batch-size", "batch size for the create process").Required().Uint32()
 	statelessCreateID                 = statelessCreate.Flag("jobID", "optional job identifier, must be UUID format").Short('i').String()
@@ -324,15 +549,15 @@ var (
 
 	statelessReplaceJobDiff = stateless.Command("replace-diff",
 		"dry-run of replace to the the instances to be added/removed/updated/unchanged")
-	statelessReplaceJobDiffJobID       = statelessReplaceJobDiff.Arg("job", "job identifier").Required().String()
+	statelessReplaceJobDiffJobID       = statelessReplaceJobDiff.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	statelessReplaceJobDiffSpec        = statelessReplaceJobDiff.Arg("spec", "YAML job spec").Required().ExistingFile()
 	statelessReplaceJobDiffResPoolPath = statelessReplaceJobDiff.Arg("respool", "complete path of the "+
-		"resource pool starting from the root").Required().String()
+		"resource pool starting from the root").HintAction(completeRespoolPaths).Required().String()
 	statelessReplaceJobDiffEntityVersion = statelessReplaceJobDiff.Arg("entityVersion",
 		"entity version for concurrency control").Required().String()
 
 	statelessRestartJob            = stateless.Command("restart", "restart instances in the job")
-	statelessRestartName           = statelessRestartJob.Arg("job", "job identifier").Required().String()
+	statelessRestartName           = statelessRestartJob.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	statelessRestartVersion        = statelessRestartJob.Arg("entityVersion", "entity version for concurrency control").Required().String()
 	statelessRestartInstanceRanges = taskRangeListFlag(statelessRestartJob.Flag("range", "restart range of instances (specify multiple times) (from:to syntax, default ALL)").Default(":").Short('r'))
 	statelessRestartOpaqueData     = statelessRestartJob.Flag("opaque-data",
@@ -342,20 +567,20 @@ var (
 		"start the restart with best effort in-place restart").Default("false").Bool()
 
 	statelessStop              = stateless.Command("stop", "stop all pods in a job")
-	statelessStopJobID         = statelessStop.Arg("job", "job identifier").Required().String()
+	statelessStopJobID         = statelessStop.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	statelessStopEntityVersion = statelessStop.Arg("entityVersion",
 		"entity version for concurrency control").Required().String()
 
 	statelessListUpdates     = stateless.Command("list-updates", "list updates")
-	statelessListUpdatesName = statelessListUpdates.Arg("job", "job identifier").Required().String()
+	statelessListUpdatesName = statelessListUpdates.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 
 	statelessStart              = stateless.Command("start", "start job")
-	statelessStartJobID         = statelessStart.Arg("job", "job identifier").Required().String()
+	statelessStartJobID         = statelessStart.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	statelessStartEntityVersion = statelessStart.Arg("entityVersion",
 		"entity version for concurrency control").Required().String()
 
 	statelessDelete              = stateless.Command("delete", "delete a stateless job")
-	statelessDeleteJobID         = statelessDelete.Arg("job", "job identifier").Required().String()
+	statelessDeleteJobID         = statelessDelete.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	statelessDeleteEntityVersion = statelessDelete.Arg("entityVersion",
 		"entity version for concurrency control").Required().String()
 	statelessDeleteForce = statelessDelete.Flag("force", "force delete the job even if it is running. "+
@@ -367,7 +592,7 @@ var (
 	pod = app.Command("pod", "CLI reflects pod(s) actions, such as get pod details, create/restart/update a pod...")
 
 	podGetEvents           = pod.Command("events", "get pod events in reverse chronological order.")
-	podGetEventsJobName    = podGetEvents.Arg("job", "job identifier").Required().String()
+	podGetEventsJobName    = podGetEvents.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	podGetEventsInstanceID = podGetEvents.Arg("instance", "job instance id").Required().Uint32()
 	podGetEventsRunID      = podGetEvents.Flag("run", "get pod events for this runID only").Short('r').String()
 	podGetEventsLimit      = podGetEvents.Flag("limit", "limit to last n runs of the pod, default value 10").Short('l').Uint64()
@@ -405,7 +630,7 @@ var (
 	podDeleteEventsPodID   = podDeleteEvents.Arg("id", "pod identifier").Required().String()
 
 	podQueryPods         = pod.Command("query", "query pods")
-	podQueryPodsJobID    = podQueryPods.Arg("job", "job identifier").Required().String()
+	podQueryPodsJobID    = podQueryPods.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	podQueryPodsStates   = podQueryPods.Flag("states", "pod states").Default("").Short('s').String()
 	podQueryPodsPodNames = podQueryPods.Flag("names", "pod names").Default("").String()
 	podQueryPodsHosts    = podQueryPods.Flag("hosts", "pod hosts").Default("").String()
@@ -428,53 +653,85 @@ var (
 	task = app.Command("task", "manage tasks")
 
 	taskGet           = task.Command("get", "show task status")
-	taskGetJobName    = taskGet.Arg("job", "job identifier").Required().String()
+	taskGetJobName    = taskGet.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	taskGetInstanceID = taskGet.Arg("instance", "job instance id").Required().Uint32()
 
 	taskGetCache           = task.Command("cache", "show task status")
-	taskGetCacheName       = taskGetCache.Arg("job", "job identifier").Required().String()
+	taskGetCacheName       = taskGetCache.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	taskGetCacheInstanceID = taskGetCache.Arg("instance", "job instance id").Required().Uint32()
 
-	taskGetEvents           = task.Command("events", "show task events")
-	taskGetEventsJobName    = taskGetEvents.Arg("job", "job identifier").Required().String()
+	taskGetEvents           = task.Command("events", "show the timeline of the events of a task, newest first")
+	taskGetEventsJobName    = taskGetEvents.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	taskGetEventsInstanceID = taskGetEvents.Arg("instance", "job instance id").Required().Uint32()
+	taskGetEventsRunID      = taskGetEvents.Flag("run", "only show the events of this run, a mesos task id").Short('r').String()
+	taskGetEventsLimit      = taskGetEvents.Flag("limit", "limit to the last n runs of the task").Default("10").Short('l').Uint64()
+	taskGetEventsReverse    = taskGetEvents.Flag("reverse", "show the oldest events first").Default("false").Bool()
+
+	taskWhyPending           = task.Command("why-pending", "diagnose why a task is not running yet, most likely cause first")
+	taskWhyPendingJobName    = taskWhyPending.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
+	taskWhyPendingInstanceID = taskWhyPending.Arg("instance", "job instance id").Required().Uint32()
+	taskWhyPendingPlacement  = taskWhyPending.Flag("placement", "host:port of the http server of a placement engine using the mimir strategy, to explain the failed placements of the task").String()
+	taskWhyPendingTimeout    = taskWhyPending.Flag("source-timeout", "timeout of fetching each source of the diagnosis").Default("5s").Duration()
 
 	taskLogsGet           = task.Command("logs", "show task logs")
-	taskLogsGetFileName   = taskLogsGet.Flag("filename", "log filename to browse").Default("stdout").Short('f').String()
-	taskLogsGetJobName    = taskLogsGet.Arg("job", "job identifier").Required().String()
+	taskLogsGetFileName   = taskLogsGet.Flag("file", "log file to fetch, e.g. stdout or stderr").Default("stdout").Short('f').Action(flagSet(&taskLogsGetFileSet)).String()
+	taskLogsGetFileAlias  = taskLogsGet.Flag("filename", "former name of --file").Hidden().String()
+	taskLogsGetFollow     = taskLogsGet.Flag("follow", "keep fetching new output until the task terminates").Default("false").Bool()
+	taskLogsGetTail       = taskLogsGet.Flag("tail", "only show the last N lines of the file").Default("0").Int()
+	taskLogsGetJobName    = taskLogsGet.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	taskLogsGetInstanceID = taskLogsGet.Arg("instance", "job instance id").Required().Uint32()
 	taskLogsGetTaskID     = taskLogsGet.Arg("taskId", "task identifier").Default("").String()
-
-	taskList              = task.Command("list", "show tasks of a job")
-	taskListJobName       = taskList.Arg("job", "job identifier").Required().String()
-	taskListInstanceRange = taskRangeFlag(taskList.Flag("range", "show range of instances (from:to syntax)").Default(":").Short('r'))
-
-	taskQuery          = task.Command("query", "query tasks by state(s)")
-	taskQueryJobName   = taskQuery.Arg("job", "job identifier").Required().String()
-	taskQueryStates    = taskQuery.Flag("states", "task states").Default("").Short('s').String()
-	taskQueryTaskNames = taskQuery.Flag("names", "task names").Default("").String()
-	taskQueryTaskHosts = taskQuery.Flag("hosts", "task hosts").Default("").String()
-	taskQueryLimit     = taskQuery.Flag("limit", "limit").Default("100").Short('n').Uint32()
-	taskQueryOffset    = taskQuery.Flag("offset", "offset").Default("0").Short('o').Uint32()
-	taskQuerySortBy    = taskQuery.Flag("sort", "sort by property (creation_time, host, instance_id, message, name, reason, state)").Short('p').String()
-	taskQuerySortOrder = taskQuery.Flag("sortorder", "sort order (ASC or DESC)").Short('a').Default("ASC").Enum("ASC", "DESC")
+	taskLogsGetFileSet    bool
+
+	taskList                = task.Command("list", "show tasks of a job")
+	taskListJobName         = taskList.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
+	taskListInstanceRange   = taskRangeFlag(taskList.Flag("range", "show range of instances (from:to syntax)").Default(":").Short('r'))
+	taskListWatch           = taskList.Flag("watch", "refresh the list until all tasks are terminal").Short('w').Default("false").Bool()
+	taskListWatchInterval   = taskList.Flag("interval", "refresh interval of --watch").Default("2s").Duration()
+	taskListStates          = taskList.Flag("states", "only show tasks in comma separated states, e.g. FAILED,LOST").Default("").Short('s').String()
+	taskListHost            = taskList.Flag("host", "only show tasks on comma separated hosts").Default("").String()
+	taskListCompletedAfter  = taskList.Flag("completed-after", "only show tasks completed after a RFC3339 time or a duration relative to now like -2h").Default("").String()
+	taskListCompletedBefore = taskList.Flag("completed-before", "only show tasks completed before a RFC3339 time or a duration relative to now like -2h").Default("").String()
+
+	taskQuery                = task.Command("query", "query tasks by state(s)")
+	taskQueryJobName         = taskQuery.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
+	taskQueryStates          = taskQuery.Flag("states", "task states").Default("").Short('s').String()
+	taskQueryTaskNames       = taskQuery.Flag("names", "task names").Default("").String()
+	taskQueryTaskHosts       = taskQuery.Flag("hosts", "task hosts").Default("").String()
+	taskQueryCompletedAfter  = taskQuery.Flag("completed-after", "only show tasks completed after a RFC3339 time or a duration relative to now like -2h").Default("").String()
+	taskQueryCompletedBefore = taskQuery.Flag("completed-before", "only show tasks completed before a RFC3339 time or a duration relative to now like -2h").Default("").String()
+	taskQueryLimit           = taskQuery.Flag("limit", "limit").Default("100").Short('n').Action(flagSet(&taskQueryLimitSet)).Uint32()
+	taskQueryOffset          = taskQuery.Flag("offset", "offset").Default("0").Short('o').Action(flagSet(&taskQueryOffsetSet)).Uint32()
+	taskQuerySortBy          = taskQuery.Flag("sort", "sort by property (creation_time, host, instance_id, message, name, reason, state)").Short('p').String()
+	taskQuerySortOrder       = taskQuery.Flag("sortorder", "sort order (ASC or DESC)").Short('a').Default("ASC").Enum("ASC", "DESC")
+	taskQueryInstances       = taskQuery.Flag("instances", "only show instances in ranges, e.g. 0-9,15,20-25; fetches all pages of tasks, conflicts with --offset").Default("").String()
+	taskQueryAll             = taskQuery.Flag("all", "fetch all pages of tasks, conflicts with --limit and --offset").Default("false").Bool()
+	taskQueryLimitSet        bool
+	taskQueryOffsetSet       bool
 
 	taskRefresh              = task.Command("refresh", "load runtime state of tasks and re-refresh corresponding action (debug only)")
-	taskRefreshJobName       = taskRefresh.Arg("job", "job identifier").Required().String()
+	taskRefreshJobName       = taskRefresh.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	taskRefreshInstanceRange = taskRangeFlag(taskRefresh.Flag("range", "range of instances (from:to syntax)").Default(":").Short('r'))
 
 	taskStart               = task.Command("start", "start a task")
-	taskStartJobName        = taskStart.Arg("job", "job identifier").Required().String()
+	taskStartJobName        = taskStart.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	taskStartInstanceRanges = taskRangeListFlag(taskStart.Flag("range", "start range of instances (specify multiple times) (from:to syntax, default ALL)").Default(":").Short('r'))
+	taskStartInstances      = taskStart.Flag("instances", "start instances in ranges, e.g. 0-9,15,20-25 (overrides --range)").Default("").String()
 
-	taskStop        = task.Command("stop", "stop tasks in the job. If no instances specified, then stop all tasks")
-	taskStopJobName = taskStop.Arg("job", "job identifier").Required().String()
-	// TODO(mu): Add support for --instances=1,3,5 for better cli experience.
+	taskStop               = task.Command("stop", "stop tasks in the job. If no instances specified, then stop all tasks")
+	taskStopJobName        = taskStop.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	taskStopInstanceRanges = taskRangeListFlag(taskStop.Flag("range", "stop range of instances (specify multiple times) (from:to syntax, default ALL)").Short('r'))
+	taskStopInstances      = taskStop.Flag("instances", "stop instances in ranges, e.g. 0-9,15,20-25 (overrides --range)").Default("").String()
+
+	taskKillByHost         = task.Command("kill-by-host", "kill the running tasks on a host without draining it")
+	taskKillByHostHostname = taskKillByHost.Arg("hostname", "hostname").HintAction(completeHostnames).Required().String()
+	taskKillByHostRespool  = taskKillByHost.Flag("respool", "only kill the tasks of the jobs of this resource pool, the others are skipped").HintAction(completeRespoolPaths).Default("").String()
+	taskKillByHostDryRun   = taskKillByHost.Flag("dry-run", "list the tasks which would be killed without killing them").Default("false").Bool()
 
 	taskRestart               = task.Command("restart", "restart a task")
-	taskRestartJobName        = taskRestart.Arg("job", "job identifier").Required().String()
+	taskRestartJobName        = taskRestart.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	taskRestartInstanceRanges = taskRangeListFlag(taskRestart.Flag("range", "restart range of instances (specify multiple times) (from:to syntax, default ALL)").Default(":").Short('r'))
+	taskRestartInstances      = taskRestart.Flag("instances", "restart instances in ranges, e.g. 0-9,15,20-25 (overrides --range)").Default("").String()
 
 	// Top level resource manager state command
 	resMgr      = app.Command("resmgr", "fetch resource manager state")
@@ -493,19 +750,37 @@ var (
 	resMgrPendingTasksGetLimit = resMgrPendingTasks.Flag("limit",
 		"maximum number of gangs to return").Default("100").Uint32()
 
+	resMgrQueue = resMgr.Command("queue",
+		"list the pending gangs of a resource pool in admission order with "+
+			"their resources, wait time and preemptibility")
+	resMgrQueueRespoolPath = resMgrQueue.Arg("respool", "complete path of the "+
+		"resource pool starting from the root").HintAction(completeRespoolPaths).Required().String()
+	resMgrQueueLimit = resMgrQueue.Flag("limit",
+		"maximum number of gangs to list per queue").Default("100").Uint32()
+
+	resMgrPreemptions = resMgr.Command("preemptions",
+		"list the recent preemption decisions of resource manager, newest first")
+	resMgrPreemptionsSince = resMgrPreemptions.Flag("since",
+		"list the decisions made within this duration, all the retained "+
+			"decisions if 0").Default("1h").Duration()
+
 	// Top level resource pool command
 	resPool = app.Command("respool", "manage resource pools")
 
 	resPoolCreate     = resPool.Command("create", "create a resource pool")
 	resPoolCreatePath = resPoolCreate.Arg("respool", "complete path of the "+
-		"resource pool starting from the root").Required().String()
+		"resource pool starting from the root").HintAction(completeRespoolPaths).Required().String()
 	resPoolCreateConfig = resPoolCreate.Arg("config", "YAML Resource Pool configuration").Required().ExistingFile()
 
 	respoolUpdate     = resPool.Command("update", "update an existing resource pool")
 	respoolUpdatePath = respoolUpdate.Arg("respool", "complete path of the "+
-		"resource pool starting from the root").Required().String()
+		"resource pool starting from the root").HintAction(completeRespoolPaths).Required().String()
 	respoolUpdateConfig = respoolUpdate.Arg("config", "YAML Resource Pool configuration").Required().ExistingFile()
 
+	resPoolApply       = resPool.Command("apply", "create or update the resource pools described by a YAML file, parents first")
+	resPoolApplyConfig = resPoolApply.Arg("config", "YAML file of the resource pools with their paths").Required().ExistingFile()
+	resPoolApplyDryRun = resPoolApply.Flag("dry-run", "only list the resource pools which would be created or updated").Default("false").Bool()
+
 	resPoolDump = resPool.Command(
 		"dump",
 		"Dump all resource pool(s)",
@@ -515,42 +790,99 @@ var (
 		"Dump resource pool(s) in a format - default (yaml)",
 	).Default("yaml").Enum("yaml", "yml", "json")
 
+	resPoolTree      = resPool.Command("tree", "show the resource pool tree")
+	resPoolTreePath  = resPoolTree.Arg("respool", "path of the root of the tree").HintAction(completeRespoolPaths).Default(pc.ResourcePoolPathDelim).String()
+	resPoolTreeStats = resPoolTree.Flag("stats", "show allocation and slack of each resource pool").Default("false").Bool()
+	resPoolTreeASCII = resPoolTree.Flag("ascii", "draw the tree with ascii instead of unicode characters").Default("false").Bool()
+
+	resPoolTop              = resPool.Command("top", "show the utilization of the resource pools, sorted by allocation as a percentage of reservation")
+	resPoolTopPath          = resPoolTop.Arg("respool", "path of the root of the subtree").HintAction(completeRespoolPaths).Default(pc.ResourcePoolPathDelim).String()
+	resPoolTopThreshold     = resPoolTop.Flag("threshold", "only show utilizations of at least this percentage").Default("0").Float64()
+	resPoolTopWatch         = resPoolTop.Flag("watch", "refresh the utilization until interrupted").Short('w').Default("false").Bool()
+	resPoolTopWatchInterval = resPoolTop.Flag("interval", "refresh interval of --watch").Default("2s").Duration()
+
+	resPoolFit          = resPool.Command("fit", "print how many instances of a job fit in a resource pool right now, within its reservation (not its entitlement, which is not reported) and under its limit")
+	resPoolFitPath      = resPoolFit.Arg("respool", "complete path of the resource pool starting from the root").HintAction(completeRespoolPaths).Required().String()
+	resPoolFitCPU       = resPoolFit.Flag("cpu", "cpus of every instance").Default("0").Float64()
+	resPoolFitMemMb     = resPoolFit.Flag("mem-mb", "memory of every instance in MB").Default("0").Float64()
+	resPoolFitGPU       = resPoolFit.Flag("gpu", "gpus of every instance").Default("0").Float64()
+	resPoolFitInstances = resPoolFit.Flag("instances", "number of instances").Default("1").Uint32()
+	resPoolFitJobConfig = resPoolFit.Flag("job-config", "YAML job configuration giving the instances and their resources instead of --cpu, --mem-mb, --gpu and --instances").ExistingFile()
+
+	resPoolLookupID   = resPool.Command("lookup-id", "print the path, parent and reservations of a resource pool by its identifier")
+	resPoolLookupIDID = resPoolLookupID.Arg("respool", "resource pool identifier").Required().String()
+
 	resPoolDelete     = resPool.Command("delete", "delete a resource pool")
 	resPoolDeletePath = resPoolDelete.Arg("respool", "complete path of the "+
-		"resource pool starting from the root").Required().String()
+		"resource pool starting from the root").HintAction(completeRespoolPaths).Required().String()
 
 	// Top level host manager command
 	host            = app.Command("host", "manage hosts")
 	hostMaintenance = host.Command("maintenance", "host maintenance")
 
-	hostMaintenanceStart          = hostMaintenance.Command("start", "start host maintenance on a list of hosts")
-	hostMaintenanceStartHostnames = hostMaintenanceStart.Arg("hostnames", "comma separated hostnames").Required().String()
+	hostMaintenanceStart             = hostMaintenance.Command("start", "start host maintenance on a list of hosts")
+	hostMaintenanceStartHostnames    = hostMaintenanceStart.Arg("hostnames", "comma separated hostnames, or @file (@- for stdin) with one host per line").HintAction(completeHostnames).Required().String()
+	hostMaintenanceStartBatchSize    = hostMaintenanceStart.Flag("batch-size", "maximum number of hosts per request, all hosts in one request if 0").Default("0").Int()
+	hostMaintenanceStartDryRun       = hostMaintenanceStart.Flag("dry-run", "only print the batches of hosts which would be sent").Default("false").Bool()
+	hostMaintenanceStartWait         = hostMaintenanceStart.Flag("wait", "wait for the hosts to be down, reporting the progress on stderr").Default("false").Bool()
+	hostMaintenanceStartWaitTimeout  = hostMaintenanceStart.Flag("wait-timeout", "maximum time to wait for the hosts to be down (the global --timeout is the RPC timeout)").Default("1h").Duration()
+	hostMaintenanceStartPollInterval = hostMaintenanceStart.Flag("poll-interval", "interval between two host state polls").Default("5s").Duration()
 
 	hostMaintenanceComplete          = hostMaintenance.Command("complete", "complete host maintenance on a list of hosts")
-	hostMaintenanceCompleteHostnames = hostMaintenanceComplete.Arg("hostnames", "comma separated hostnames").Required().String()
+	hostMaintenanceCompleteHostnames = hostMaintenanceComplete.Arg("hostnames", "comma separated hostnames, or @file (@- for stdin) with one host per line").HintAction(completeHostnames).Required().String()
+	hostMaintenanceCompleteBatchSize = hostMaintenanceComplete.Flag("batch-size", "maximum number of hosts per request, all hosts in one request if 0").Default("0").Int()
+	hostMaintenanceCompleteDryRun    = hostMaintenanceComplete.Flag("dry-run", "only print the batches of hosts which would be sent").Default("false").Bool()
+
+	hostDrain              = host.Command("drain", "start maintenance on a list of hosts and wait for them to have no Peloton task")
+	hostDrainHostnames     = hostDrain.Arg("hostnames", "comma separated hostnames, or @file (@- for stdin) with one host per line").HintAction(completeHostnames).Required().String()
+	hostDrainTimeout       = hostDrain.Flag("drain-timeout", "maximum time to wait for a host to be drained after its maintenance started (the global --timeout is the RPC timeout)").Default("1h").Duration()
+	hostDrainParallel      = hostDrain.Flag("parallel", "maximum number of hosts drained at the same time").Default("5").Int()
+	hostDrainUndoOnTimeout = hostDrain.Flag("undo-on-timeout", "cancel the maintenance of the hosts which timed out to bring them back up").Default("false").Bool()
+	hostDrainPollInterval  = hostDrain.Flag("poll-interval", "interval between two polls of the tasks on the hosts").Default("10s").Duration()
+
+	hostMaintenanceSchedule     = hostMaintenance.Command("schedule", "maintenance schedule of the Mesos master")
+	hostMaintenanceScheduleView = hostMaintenanceSchedule.Command("view", "view the maintenance windows posted to the Mesos master")
 
-	hostQuery       = host.Command("query", "query hosts by state(s)")
+	hostQuery       = host.Command("query", "query hosts by state(s) and attributes")
 	hostQueryStates = hostQuery.Flag("states", "host state(s) to filter").Default("").Short('s').String()
+	hostQueryLabels = hostQuery.Flag("labels", "host attributes to filter by, e.g. \"rack in (r7,r8),zone!=z1,gpu,!reserved\"").Default("").Short('l').String()
 
 	// Top level volume command
 	volume = app.Command("volume", "manage persistent volume")
 
 	volumeList        = volume.Command("list", "list volumes for a job")
-	volumeListJobName = volumeList.Arg("job", "job identifier").Required().String()
+	volumeListJobName = volumeList.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 
 	volumeDelete         = volume.Command("delete", "delete a volume")
 	volumeDeleteVolumeID = volumeDelete.Arg("volume", "volume identifier").Required().String()
 
+	// Top level secret command
+	secret = app.Command("secret", "manage the secrets of batch jobs")
+
+	secretCreate         = secret.Command("create", "add a secret to a job")
+	secretCreateJobName  = secretCreate.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
+	secretCreatePath     = secretCreate.Flag("path", "path at which the secret is mounted in the containers").Required().String()
+	secretCreateDataFile = secretCreate.Flag("data-file", "file with the secret data").Required().ExistingFile()
+
+	secretUpdate         = secret.Command("update", "replace the data of a secret of a job")
+	secretUpdateJobName  = secretUpdate.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
+	secretUpdatePath     = secretUpdate.Flag("path", "path at which the secret is mounted in the containers").Required().String()
+	secretUpdateDataFile = secretUpdate.Flag("data-file", "file with the new secret data").Required().ExistingFile()
+	secretUpdateVersion  = secretUpdate.Flag("config-version", "version of the job configuration the update is made against, instead of the one read").Default("0").Uint64()
+
+	secretList        = secret.Command("list", "list the paths of the secrets of a job, without their data")
+	secretListJobName = secretList.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
+
 	// Top level job update command
 	update = app.Command("update", "manage job updates")
 
 	// command to create a new job update
 	updateCreate       = update.Command("create", "create a new job update")
-	updateJobID        = updateCreate.Arg("job", "job identifier").Required().String()
+	updateJobID        = updateCreate.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 	updateCreateConfig = updateCreate.Arg("config", "YAML job configuration").Required().ExistingFile()
 	updateBatchSize    = updateCreate.Arg("batch-size", "batch size for the update").Required().Uint32()
 	updateResPoolPath  = updateCreate.Arg("respool", "complete path of the "+
-		"resource pool starting from the root").Required().String()
+		"resource pool starting from the root").HintAction(completeRespoolPaths).Required().String()
 	updateConfigVersion = updateCreate.Flag("configuration-version",
 		"current configuration version").Default("0").Short('c').Uint64()
 	updateOverride = updateCreate.Flag("override",
@@ -567,34 +899,45 @@ var (
 		"opaque data provided by the user").Default("").String()
 	updateCreateInPlace = updateCreate.Flag("in-place",
 		"start the update with best effort in-place update").Default("false").Bool()
+	updateCreateInstances = updateCreate.Flag("instances",
+		"only update the instances in ranges, e.g. 0-9,15,20-25").Default("").String()
+	updateCreateCanaryPercent = updateCreate.Flag("canary-percent",
+		"only update the given percentage of the instances, rounded up, "+
+			"starting from instance 0").Default("0").Uint32()
 
 	// command to fetch the status of a job update
 	updateGet   = update.Command("get", "get status of a job update")
 	updateGetID = updateGet.Arg("update-id", "update identifier").Required().String()
 
+	// command to show the progress of a job update
+	updateStatus         = update.Command("status", "show the progress of a job update, exiting with 0 on SUCCEEDED, 2 on ROLLED_BACK, 3 on FAILED and 4 on ABORTED with --watch")
+	updateStatusID       = updateStatus.Arg("update-or-job", "update identifier, or job identifier for the current update of the job").HintAction(completeJobIDs).Required().String()
+	updateStatusWatch    = updateStatus.Flag("watch", "refresh the progress until the update is terminal").Short('w').Default("false").Bool()
+	updateStatusInterval = updateStatus.Flag("interval", "refresh interval of --watch").Default("2s").Duration()
+
 	// command to fetch the status of job updates for a given job
 	updateList      = update.Command("list", "list status of all updates for a given job")
-	updateListJobID = updateList.Arg("job", "job identifier").Required().String()
+	updateListJobID = updateList.Arg("job", "job identifier").HintAction(completeJobIDs).Required().String()
 
 	// command to fetch the update information in the cache
 	updateCache   = update.Command("cache", "get update information in  the cache")
 	updateCacheID = updateCache.Arg("update-id", "update identifier").Required().String()
 
 	// command to abort an update
-	updateAbort           = update.Command("abort", "abort a job update")
-	updateAbortID         = updateAbort.Arg("update-id", "update identifier").Required().String()
+	updateAbort           = update.Command("abort", "abort a job update, asking for confirmation")
+	updateAbortID         = updateAbort.Arg("update-or-job", "update identifier, or job identifier for the current update of the job").HintAction(completeJobIDs).Required().String()
 	updateAbortOpaqueData = updateAbort.Flag("opaque-data",
 		"opaque data provided by the user").Default("").String()
 
 	// command to pause an update
 	updatePause           = update.Command("pause", "pause a job update")
-	updatePauseID         = updatePause.Arg("update-id", "update identifier").Required().String()
+	updatePauseID         = updatePause.Arg("update-or-job", "update identifier, or job identifier for the current update of the job").HintAction(completeJobIDs).Required().String()
 	updatePauseOpaqueData = updatePause.Flag("opaque-data",
 		"opaque data provided by the user").Default("").String()
 
 	// command to resume an update
 	updateResume           = update.Command("resume", "resume a job update")
-	updateResumeID         = updateResume.Arg("update-id", "update identifier").Required().String()
+	updateResumeID         = updateResume.Arg("update-or-job", "update identifier, or job identifier for the current update of the job").HintAction(completeJobIDs).Required().String()
 	updateResumeOpaqueData = updateResume.Flag("opaque-data",
 		"opaque data provided by the user").Default("").String()
 
@@ -613,8 +956,89 @@ var (
 
 	// command for list status update events present in the event stream
 	eventStream = hostmgr.Command("events", "list all the task status update events present in event stream")
+
+	// Top level command for the CLI configuration
+	configCmd = app.Command("config", "manage the CLI configuration in ~/.peloton/config.yaml")
+
+	// command to print the CLI configuration
+	configView = configCmd.Command("view", "print the CLI configuration")
+
+	// command to set the default cluster profile
+	configUseCluster     = configCmd.Command("use-cluster", "set the cluster profile used by default")
+	configUseClusterName = configUseCluster.Arg("cluster", "name of the cluster profile").Required().String()
+
+	// command to print the local history of the mutating commands
+	historyCmd  = app.Command("history", "print the local history of the mutating commands, recorded if history.enabled is set in ~/.peloton/config.yaml")
+	historyLast = historyCmd.Flag("last", "number of commands printed, 0 for all").Default(strconv.Itoa(pc.DefaultHistoryLast)).Uint32()
+
+	// Top level command for the peloton cluster
+	clusterCmd = app.Command("cluster", "show information about the peloton cluster")
+
+	// command to print the leader and build version of each component
+	clusterInfo = clusterCmd.Command("info", "print the leader, build version "+
+		"and git ref of jobmgr, resmgr and hostmgr, flagging version mismatches. "+
+		"Each component is queried within --timeout")
+
+	// Top level command for debugging the peloton components
+	debugCmd = app.Command("debug", "debug the peloton components")
+
+	// command to print a snapshot of the metrics of a component
+	debugMetrics = debugCmd.Command("metrics", "print a snapshot of the "+
+		"metrics of the leader of a component in the Prometheus text format, "+
+		"with the counters incremented since the last flush of the metrics. "+
+		"The leader is only found with zookeeper discovery")
+	debugMetricsComponent = debugMetrics.Arg("component", "component: jobmgr, resmgr or hostmgr").Required().Enum("jobmgr", "resmgr", "hostmgr")
+	debugMetricsFilter    = debugMetrics.Flag("filter", "only print the metrics whose name starts with this prefix").String()
+
+	// hidden maintenance command to copy the framework info between the
+	// framework info stores of two host manager configs, e.g. when moving
+	// it from Cassandra to ZooKeeper
+	frameworkInfoMigrate = app.Command("framework-info-migrate",
+		"copy the framework id and mesos stream id between the framework "+
+			"info stores of two host manager configs").Hidden()
+	frameworkInfoMigrateFrom = frameworkInfoMigrate.Flag("from",
+		"host manager config file of the source store, may be repeated").
+		Required().ExistingFiles()
+	frameworkInfoMigrateTo = frameworkInfoMigrate.Flag("to",
+		"host manager config file of the destination store, may be repeated").
+		Required().ExistingFiles()
+	frameworkInfoMigrateName = frameworkInfoMigrate.Flag("framework",
+		"name of the framework").Default("Peloton").String()
+	frameworkInfoMigrateOverwrite = frameworkInfoMigrate.Flag("overwrite",
+		"replace framework info which differs in the destination").
+		Default("false").Bool()
+	frameworkInfoMigrateDryRun = frameworkInfoMigrate.Flag("dry-run",
+		"print what would be copied without copying it").
+		Default("false").Bool()
+
+	// hidden maintenance command to export the snapshot of the mimir groups
+	// of a placement engine, e.g. to replay its placements offline
+	placementSnapshot = app.Command("placement-snapshot",
+		"export the labels, relations, metrics and tasks of the hosts "+
+			"seen by a placement engine using the mimir strategy").Hidden()
+	placementSnapshotAddress = placementSnapshot.Arg("address",
+		"host:port of the http server of the placement engine").
+		Required().String()
+	placementSnapshotFile = placementSnapshot.Flag("file",
+		"file to write the snapshot to, - for stdout").
+		Default("-").Short('f').String()
+
+	// command to print the shell completion script
+	completion = app.Command("completion", "print the shell completion script, "+
+		"e.g. source <(peloton completion bash)")
+	completionShell = completion.Arg("shell", "shell to complete: bash or zsh").Required().Enum("bash", "zsh")
 )
 
+// defaultSettings are the connection settings used if they are neither given
+// on the command line nor in the selected cluster profile
+var defaultSettings = config.Settings{
+	ZkRoot:  common.DefaultLeaderElectionRoot,
+	JobMgr:  "localhost:5392",
+	ResMgr:  "localhost:5394",
+	HostMgr: "localhost:5391",
+	Timeout: 20 * time.Second,
+}
+
 // TaskRangeValue allows us to define a new target type for kingpin to allow specifying ranges of tasks with from:to syntax as a TaskRangeFlag
 type TaskRangeValue pt.InstanceRange
 
@@ -710,12 +1134,250 @@ func taskRangeListFlag(s kingpin.Settings) (target *[]*pt.InstanceRange) {
 	return
 }
 
+// tlsFlags returns the TLS configuration given on the command line, or nil
+// if TLS is not configured
+func tlsFlags() *config.TLSConfig {
+	if *caCert == "" && *clientCert == "" && *clientKey == "" &&
+		!insecureSkipVerifySet {
+		return nil
+	}
+	if (*clientCert == "") != (*clientKey == "") {
+		app.Fatalf("--client-cert and --client-key must be used together")
+	}
+	return &config.TLSConfig{
+		CAFile:             *caCert,
+		CertFile:           *clientCert,
+		KeyFile:            *clientKey,
+		InsecureSkipVerify: insecureSkipVerifyFlag(),
+	}
+}
+
+// insecureSkipVerifyFlag returns the value of --insecure-skip-verify if it
+// is given on the command line, and nil otherwise
+func insecureSkipVerifyFlag() *bool {
+	if !insecureSkipVerifySet {
+		return nil
+	}
+	return insecureSkipVerify
+}
+
+// authFlags returns the authentication configuration given on the command
+// line, or nil if authentication is not configured. Passwords and tokens are
+// only accepted in files to keep them out of the process list.
+func authFlags() *config.AuthConfig {
+	if *authUser == "" && *authPasswordFile == "" && *authTokenFile == "" {
+		return nil
+	}
+	return &config.AuthConfig{
+		User:         *authUser,
+		PasswordFile: *authPasswordFile,
+		TokenFile:    *authTokenFile,
+	}
+}
+
+// newStaticServiceDiscovery returns the service discovery of the static
+// peloton endpoints of the settings
+func newStaticServiceDiscovery(settings config.Settings) (leader.Discovery, error) {
+	var urls [3]*url.URL
+	for i, endpoint := range []string{
+		settings.JobMgr, settings.ResMgr, settings.HostMgr} {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		urls[i] = u
+	}
+	return leader.NewStaticServiceDiscovery(urls[0], urls[1], urls[2])
+}
+
+// resolveSettings returns the connection settings given on the command line,
+// completed by the selected cluster profile of the CLI configuration
+func resolveSettings(configFile *config.File) (config.Settings, error) {
+	var profile *config.Profile
+	if name := configFile.SelectCluster(
+		*cluster, os.Getenv(config.ClusterEnvVar)); name != "" {
+		var err error
+		profile, err = configFile.Profile(name)
+		if err != nil {
+			return config.Settings{}, err
+		}
+	}
+
+	return config.Resolve(config.Settings{
+		ZkServers: *zkServers,
+		ZkRoot:    *zkRoot,
+		JobMgr:    *jobMgrURL,
+		ResMgr:    *resMgrURL,
+		HostMgr:   *hostMgrURL,
+		Timeout:   *timeout,
+		TLS:       tlsFlags(),
+		Auth:      authFlags(),
+	}, profile, defaultSettings)
+}
+
+// newDiscovery creates the discovery of the leaders of the peloton services
+// of the settings, through zookeeper if zookeeper servers are set
+func newDiscovery(settings config.Settings) (leader.Discovery, error) {
+	var discovery leader.Discovery
+	var err error
+	if len(settings.ZkServers) > 0 {
+		discovery, err = leader.NewZkServiceDiscovery(settings.ZkServers, settings.ZkRoot)
+	} else {
+		discovery, err = newStaticServiceDiscovery(settings)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("fail to initialize service discovery: %v", err)
+	}
+	return discovery, nil
+}
+
+// loadAuthConfig returns the credentials of the requests to the peloton
+// services, from the legacy basic auth config file if given and otherwise
+// from the auth settings
+func loadAuthConfig(settings config.Settings) (*middleware.AuthConfig, error) {
+	if len(*basicAuthConfigFile) == 0 {
+		authConfig, err := settings.Auth.Credentials()
+		if err != nil {
+			return nil, fmt.Errorf("fail to load auth config: %v", err)
+		}
+		return authConfig, nil
+	}
+
+	if authFlags() != nil {
+		return nil, fmt.Errorf("--basicAuthConfig cannot be used with " +
+			"--auth-user, --auth-password-file or --auth-token-file")
+	}
+	var basicAuthConfig middleware.BasicAuthConfig
+	if err := common_config.Parse(&basicAuthConfig, *basicAuthConfigFile); err != nil {
+		return nil, fmt.Errorf("fail to load auth config file: %v", err)
+	}
+	return &middleware.AuthConfig{Basic: &basicAuthConfig}, nil
+}
+
+// newClient creates the client of the peloton services of the settings,
+// waiting up to waitForLeader for the leaders of the services to be found
+func newClient(
+	settings config.Settings,
+	retryPolicy middleware.RetryPolicy,
+	waitForLeader time.Duration) (*pc.Client, error) {
+	discovery, err := newDiscovery(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfigs := make(map[string]*tls.Config)
+	for role, service := range map[string]string{
+		common.JobManagerRole:      config.ServiceJobMgr,
+		common.ResourceManagerRole: config.ServiceResMgr,
+		common.HostManagerRole:     config.ServiceHostMgr,
+	} {
+		tlsConfigs[role], err = settings.ServiceTLS(service).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("fail to load TLS config of %s: %v", service, err)
+		}
+	}
+
+	authConfig, err := loadAuthConfig(settings)
+	if err != nil {
+		return nil, err
+	}
+
+	var debugRPCOut io.Writer
+	if *debugRPC {
+		debugRPCOut = os.Stderr
+	}
+
+	return pc.New(discovery, retryPolicy, authConfig, tlsConfigs,
+		debugRPCOut, waitForLeader, *jsonFormat, *requestID)
+}
+
+// flagSet returns a kingpin action which records that a flag was given on
+// the command line
+func flagSet(set *bool) kingpin.Action {
+	return func(*kingpin.ParseContext) error {
+		*set = true
+		return nil
+	}
+}
+
 func main() {
 	app.Version(version)
 	app.HelpFlag.Short('h')
 	cmd := kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	if *debug {
+		log.SetLevel(log.DebugLevel)
+	}
+
+	if *requestID == "" {
+		*requestID = uuid.New()
+	}
+
+	if (*jobQueryAll && (jobQueryLimitSet || jobQueryOffsetSet)) ||
+		(*taskQueryAll && (taskQueryLimitSet || taskQueryOffsetSet)) {
+		app.Fatalf("--all cannot be used with --limit or --offset")
+	}
+
+	// --filename is kept as a hidden alias of --file for existing scripts
+	if *taskLogsGetFileAlias != "" {
+		if taskLogsGetFileSet {
+			app.Fatalf("--filename cannot be used with --file")
+		}
+		*taskLogsGetFileName = *taskLogsGetFileAlias
+	}
+
+	tableOrCSV := *outputFormat == pc.OutputTable ||
+		*outputFormat == pc.OutputCSV ||
+		(*outputFormat == "" && !*jsonFormat)
+	if *outputColumns != "" && !tableOrCSV {
+		app.Fatalf("--columns can only be used with table or csv output")
+	}
+
+	if cmd == completion.FullCommand() {
+		app.FatalIfError(printCompletionScript(*completionShell),
+			"Fail to print completion script")
+		return
+	}
 	var err error
 
+	configPath, err := config.DefaultFilePath()
+	if err != nil {
+		app.FatalIfError(err, "Fail to locate CLI config")
+	}
+	configFile, err := config.LoadFile(configPath)
+	if err != nil {
+		// the config commands work on the file itself, the other commands
+		// can run with the settings of the flags only
+		if cmd == configView.FullCommand() ||
+			cmd == configUseCluster.FullCommand() {
+			app.FatalIfError(err, "Fail to load CLI config")
+		}
+		fmt.Fprintf(os.Stderr, "Warning: ignoring CLI config: %v\n", err)
+		configFile = &config.File{}
+	}
+
+	switch cmd {
+	case configView.FullCommand():
+		app.FatalIfError(configFile.Write(os.Stdout), "Fail to print CLI config")
+		return
+	case configUseCluster.FullCommand():
+		err = configFile.UseCluster(*configUseClusterName)
+		if err == nil {
+			err = configFile.Save(configPath)
+		}
+		app.FatalIfError(err, "Fail to set cluster")
+		fmt.Printf("Using cluster %s by default\n", *configUseClusterName)
+		return
+	case historyCmd.FullCommand():
+		file, err := historyFile(configFile.History)
+		if err == nil {
+			err = pc.HistoryAction(file, *historyLast,
+				*jsonFormat || *outputFormat == pc.OutputJSON)
+		}
+		app.FatalIfError(err, "Fail to print history")
+		return
+	}
+
 	if len(*clusterName) > 0 {
 		var zkInfo string
 		zkJSONBytes, err := config.ReadZKConfigFile()
@@ -737,38 +1399,106 @@ func main() {
 		}
 		zkServers = &zkInfoSlice
 	}
-	var discovery leader.Discovery
-	if len(*zkServers) > 0 {
-		discovery, err = leader.NewZkServiceDiscovery(*zkServers, *zkRoot)
-	} else {
-		discovery, err = leader.NewStaticServiceDiscovery(*jobMgrURL, *resMgrURL, *hostMgrURL)
-	}
+
+	settings, err := resolveSettings(configFile)
 	if err != nil {
-		app.FatalIfError(err, "Fail to initialize service discovery")
+		app.FatalIfError(err, "Fail to resolve cluster settings")
 	}
 
-	var basicAuthConfigPtr *middleware.BasicAuthConfig
-	if len(*basicAuthConfigFile) != 0 {
-		var basicAuthConfig middleware.BasicAuthConfig
-		if err := common_config.Parse(&basicAuthConfig, *basicAuthConfigFile); err != nil {
-			app.FatalIfError(err, "Fail to load auth config file")
+	if cmd == frameworkInfoMigrate.FullCommand() {
+		app.FatalIfError(migrateFrameworkInfo(
+			*frameworkInfoMigrateFrom,
+			*frameworkInfoMigrateTo,
+			*frameworkInfoMigrateName,
+			*frameworkInfoMigrateOverwrite,
+			*frameworkInfoMigrateDryRun,
+			settings.Timeout,
+		), "Fail to migrate framework info")
+		return
+	}
+
+	if cmd == placementSnapshot.FullCommand() {
+		app.FatalIfError(pc.PlacementSnapshotAction(
+			*placementSnapshotAddress,
+			*placementSnapshotFile,
+			settings.Timeout,
+		), "Fail to export the placement snapshot")
+		return
+	}
+
+	// the cluster info is reported even if some leaders cannot be found,
+	// which fails the creation of the client
+	if cmd == clusterInfo.FullCommand() {
+		discovery, err := newDiscovery(settings)
+		if err == nil {
+			err = pc.ClusterInfoAction(discovery, settings.Timeout,
+				*jsonFormat || *outputFormat == pc.OutputJSON)
+		}
+		exitIfError(err, "Fail to get cluster info")
+		return
+	}
+
+	if cmd == debugMetrics.FullCommand() {
+		discovery, err := newDiscovery(settings)
+		if err == nil {
+			err = pc.DebugMetricsAction(discovery, *debugMetricsComponent,
+				*debugMetricsFilter, settings.Timeout)
 		}
-		basicAuthConfigPtr = &basicAuthConfig
+		exitIfError(err, "Fail to get the metrics")
+		return
 	}
 
-	client, err := pc.New(discovery, *timeout, basicAuthConfigPtr, *jsonFormat)
+	retryPolicy := middleware.RetryPolicy{
+		Timeout:        settings.Timeout,
+		Retries:        *retries,
+		Backoff:        *retryBackoff,
+		RetryMutations: *retryMutations,
+	}
+	client, err := newClient(settings, retryPolicy, *waitForLeader)
 	if err != nil {
-		app.FatalIfError(err, "Fail to initialize client")
+		exitIfError(err, "Fail to initialize client")
 	}
 	defer client.Cleanup()
+	client.Output = *outputFormat
+	client.Columns = *outputColumns
+	client.NoColor = *noColor
+	client.ProgressFormat = *progressFormat
+	client.SetMaxRPS(*maxRPS)
+	client.AssumeYes = *assumeYes
+	client.SkipEmptyHosts = *skipEmptyHosts
+	client.MaxHosts = *maxHosts
+	client.MaxHostRangeExpansion = *maxHostRangeExpansion
+	client.SkipHostValidation = *skipHostValidation
 
 	switch cmd {
 	case jobCreate.FullCommand():
 		err = client.JobCreateAction(*jobCreateID, *jobCreateResPoolPath,
 			*jobCreateConfig, *jobCreateSecretPath, []byte(*jobCreateSecret))
+	case jobValidate.FullCommand():
+		err = client.JobValidateAction(*jobValidateConfig, *jobValidateRespoolPath)
+	case jobDiff.FullCommand():
+		changed, derr := client.JobDiffAction(*jobDiffName, *jobDiffConfig, *jobDiffFields)
+		exitIfError(derr, "")
+		if changed {
+			client.Cleanup()
+			os.Exit(1)
+		}
 	case jobDelete.FullCommand():
 		err = client.JobDeleteAction(*jobDeleteName)
 	case jobStop.FullCommand():
+		if *jobStopAll {
+			if *jobStopName != "" {
+				app.Fatalf("--all cannot be used with a job identifier")
+			}
+			err = client.JobStopAllAction(
+				*jobStopRespoolPath,
+				*jobStopStates,
+				*jobStopConcurrency,
+				*jobStopDryRun,
+				*jobStopForce,
+			)
+			break
+		}
 		err = client.JobStopAction(
 			*jobStopName,
 			*jobStopProgress,
@@ -778,17 +1508,47 @@ func main() {
 		)
 	case jobGet.FullCommand():
 		err = client.JobGetAction(*jobGetName)
+	case jobExport.FullCommand():
+		err = client.JobExportAction(*jobExportName, *jobExportFormat)
+	case jobClone.FullCommand():
+		err = client.JobCloneAction(
+			*jobCloneName,
+			*jobCloneNewName,
+			*jobCloneInstances,
+			*jobCloneRespoolPath,
+			*jobCloneOverrides,
+			*jobCloneCloneSecrets,
+		)
 	case jobRefresh.FullCommand():
 		err = client.JobRefreshAction(*jobRefreshName)
 	case jobStatus.FullCommand():
-		err = client.JobStatusAction(*jobStatusName)
+		err = client.JobStatusAction(*jobStatusName, *jobStatusWatch, *jobStatusWatchInterval, *jobStatusSummary)
+	case jobFailures.FullCommand():
+		err = client.JobFailuresAction(*jobFailuresName, *jobFailuresTop)
+	case jobWait.FullCommand():
+		state, werr := client.JobWaitAction(*jobWaitName, *jobWaitTimeout, *jobWaitPollInterval)
+		code := pc.JobWaitExitCode(state, werr)
+		if code == pc.JobWaitExitError {
+			exitIfError(werr, "")
+		}
+		client.Cleanup()
+		os.Exit(code)
 	case jobQuery.FullCommand():
-		err = client.JobQueryAction(*jobQueryLabels, *jobQueryRespoolPath, *jobQueryKeywords, *jobQueryStates, *jobQueryOwner, *jobQueryName, *jobQueryTimeRange, *jobQueryLimit, *jobQueryMaxLimit, *jobQueryOffset, *jobQuerySortBy, *jobQuerySortOrder)
+		err = client.JobQueryAction(*jobQueryLabels, *jobQueryRespoolPath, *jobQueryKeywords, *jobQueryStates, *jobQueryOwner, *jobQueryName, *jobQueryTimeRange, *jobQueryLimit, *jobQueryMaxLimit, *jobQueryOffset, *jobQuerySortBy, *jobQuerySortOrder, *jobQuerySort, *jobQueryAll)
 	case jobUpdate.FullCommand():
 		err = client.JobUpdateAction(*jobUpdateID, *jobUpdateConfig,
 			*jobUpdateSecretPath, []byte(*jobUpdateSecret))
 	case jobRestart.FullCommand():
 		err = client.JobRestartAction(*jobRestartName, *jobRestartResourceVersion, *jobRestartInstanceRanges, *jobRestartBatchSize)
+	case jobRestartMonitor.FullCommand():
+		err = client.JobRestartMonitorAction(
+			*jobRestartMonitorName,
+			*jobRestartMonitorInstances,
+			*jobRestartMonitorBatchSize,
+			*jobRestartMonitorInPlace,
+			*jobRestartMonitorDetach,
+			*jobRestartMonitorInterval,
+		)
 	case jobStart.FullCommand():
 		err = client.JobStartAction(*jobStartName, *jobStartResourceVersion, *jobStartInstanceRanges, *jobStartBatchSize)
 	case jobStopV1Beta.FullCommand():
@@ -802,45 +1562,104 @@ func main() {
 	case taskGetCache.FullCommand():
 		err = client.TaskGetCacheAction(*taskGetCacheName, *taskGetCacheInstanceID)
 	case taskGetEvents.FullCommand():
-		err = client.TaskGetEventsAction(*taskGetEventsJobName, *taskGetEventsInstanceID)
+		err = client.TaskGetEventsAction(*taskGetEventsJobName, *taskGetEventsInstanceID, *taskGetEventsRunID, *taskGetEventsLimit, *taskGetEventsReverse)
+	case taskWhyPending.FullCommand():
+		err = client.TaskWhyPendingAction(*taskWhyPendingJobName, *taskWhyPendingInstanceID, *taskWhyPendingPlacement, *taskWhyPendingTimeout)
 	case taskLogsGet.FullCommand():
-		err = client.TaskLogsGetAction(*taskLogsGetFileName, *taskLogsGetJobName, *taskLogsGetInstanceID, *taskLogsGetTaskID)
+		err = client.TaskLogsGetAction(*taskLogsGetFileName, *taskLogsGetJobName, *taskLogsGetInstanceID, *taskLogsGetTaskID, *taskLogsGetFollow, *taskLogsGetTail)
 	case taskList.FullCommand():
-		err = client.TaskListAction(*taskListJobName, taskListInstanceRange)
+		err = client.TaskListAction(
+			*taskListJobName,
+			taskListInstanceRange,
+			*taskListStates,
+			*taskListHost,
+			*taskListCompletedAfter,
+			*taskListCompletedBefore,
+			*taskListWatch,
+			*taskListWatchInterval,
+		)
 	case taskQuery.FullCommand():
-		err = client.TaskQueryAction(*taskQueryJobName, *taskQueryStates, *taskQueryTaskNames, *taskQueryTaskHosts, *taskQueryLimit, *taskQueryOffset, *taskQuerySortBy, *taskQuerySortOrder)
+		err = client.TaskQueryAction(*taskQueryJobName, *taskQueryStates, *taskQueryTaskNames, *taskQueryTaskHosts, *taskQueryCompletedAfter, *taskQueryCompletedBefore, *taskQueryInstances, *taskQueryLimit, *taskQueryOffset, *taskQuerySortBy, *taskQuerySortOrder, *taskQueryAll)
 	case taskRefresh.FullCommand():
 		err = client.TaskRefreshAction(*taskRefreshJobName, taskRefreshInstanceRange)
 	case taskStart.FullCommand():
-		err = client.TaskStartAction(*taskStartJobName, *taskStartInstanceRanges)
+		err = client.TaskStartAction(*taskStartJobName, *taskStartInstanceRanges, *taskStartInstances)
 	case taskStop.FullCommand():
 		err = client.TaskStopAction(*taskStopJobName,
-			*taskStopInstanceRanges)
+			*taskStopInstanceRanges, *taskStopInstances)
+	case taskKillByHost.FullCommand():
+		err = client.TaskKillByHostAction(*taskKillByHostHostname,
+			*taskKillByHostRespool, *taskKillByHostDryRun)
 	case taskRestart.FullCommand():
-		err = client.TaskRestartAction(*taskRestartJobName, *taskRestartInstanceRanges)
+		err = client.TaskRestartAction(*taskRestartJobName, *taskRestartInstanceRanges, *taskRestartInstances)
 	case hostMaintenanceStart.FullCommand():
-		err = client.HostMaintenanceStartAction(*hostMaintenanceStartHostnames)
+		err = client.HostMaintenanceStartAction(
+			*hostMaintenanceStartHostnames,
+			*hostMaintenanceStartBatchSize,
+			*hostMaintenanceStartDryRun,
+			*hostMaintenanceStartWait,
+			*hostMaintenanceStartWaitTimeout,
+			*hostMaintenanceStartPollInterval)
 	case hostMaintenanceComplete.FullCommand():
-		err = client.HostMaintenanceCompleteAction(*hostMaintenanceCompleteHostnames)
+		err = client.HostMaintenanceCompleteAction(
+			*hostMaintenanceCompleteHostnames,
+			*hostMaintenanceCompleteBatchSize,
+			*hostMaintenanceCompleteDryRun)
+	case hostDrain.FullCommand():
+		err = client.HostDrainAction(
+			*hostDrainHostnames,
+			*hostDrainTimeout,
+			*hostDrainParallel,
+			*hostDrainUndoOnTimeout,
+			*hostDrainPollInterval)
+	case hostMaintenanceScheduleView.FullCommand():
+		err = client.HostMaintenanceScheduleViewAction()
 	case hostQuery.FullCommand():
-		err = client.HostQueryAction(*hostQueryStates)
+		err = client.HostQueryAction(*hostQueryStates, *hostQueryLabels)
 	case resMgrActiveTasks.FullCommand():
 		err = client.ResMgrGetActiveTasks(*resMgrActiveTasksGetJobName, *resMgrActiveTasksGetRespoolID, *resMgrActiveTasksGetStates)
 	case resMgrPendingTasks.FullCommand():
 		err = client.ResMgrGetPendingTasks(*resMgrPendingTasksGetRespoolID,
 			uint32(*resMgrPendingTasksGetLimit))
+	case resMgrQueue.FullCommand():
+		err = client.ResMgrQueueAction(*resMgrQueueRespoolPath, *resMgrQueueLimit)
+	case resMgrPreemptions.FullCommand():
+		err = client.ResMgrPreemptionsAction(*resMgrPreemptionsSince)
 	case resPoolCreate.FullCommand():
 		err = client.ResPoolCreateAction(*resPoolCreatePath, *resPoolCreateConfig)
 	case respoolUpdate.FullCommand():
 		err = client.ResPoolUpdateAction(*respoolUpdatePath, *respoolUpdateConfig)
+	case resPoolApply.FullCommand():
+		err = client.ResPoolApplyAction(*resPoolApplyConfig, *resPoolApplyDryRun)
+	case resPoolTree.FullCommand():
+		err = client.ResPoolTreeAction(*resPoolTreePath, *resPoolTreeStats, *resPoolTreeASCII)
 	case resPoolDump.FullCommand():
 		err = client.ResPoolDumpAction(*resPoolDumpFormat)
+	case resPoolTop.FullCommand():
+		err = client.ResPoolTopAction(*resPoolTopPath, *resPoolTopThreshold, *resPoolTopWatch, *resPoolTopWatchInterval)
+	case resPoolFit.FullCommand():
+		err = client.ResPoolFitAction(
+			*resPoolFitPath,
+			*resPoolFitCPU,
+			*resPoolFitMemMb,
+			*resPoolFitGPU,
+			*resPoolFitInstances,
+			*resPoolFitJobConfig,
+		)
+	case resPoolLookupID.FullCommand():
+		err = client.ResPoolLookupIDAction(*resPoolLookupIDID)
 	case resPoolDelete.FullCommand():
 		err = client.ResPoolDeleteAction(*resPoolDeletePath)
 	case volumeList.FullCommand():
 		err = client.VolumeListAction(*volumeListJobName)
 	case volumeDelete.FullCommand():
 		err = client.VolumeDeleteAction(*volumeDeleteVolumeID)
+	case secretCreate.FullCommand():
+		err = client.SecretCreateAction(*secretCreateJobName, *secretCreatePath, *secretCreateDataFile)
+	case secretUpdate.FullCommand():
+		err = client.SecretUpdateAction(*secretUpdateJobName, *secretUpdatePath, *secretUpdateDataFile, *secretUpdateVersion)
+	case secretList.FullCommand():
+		err = client.SecretListAction(*secretListJobName)
 	case updateCreate.FullCommand():
 		err = client.UpdateCreateAction(
 			*updateJobID,
@@ -855,9 +1674,23 @@ func main() {
 			*updateStartInPausedState,
 			*updateCreateOpaqueData,
 			*updateCreateInPlace,
+			*updateCreateInstances,
+			*updateCreateCanaryPercent,
 		)
 	case updateGet.FullCommand():
 		err = client.UpdateGetAction(*updateGetID)
+	case updateStatus.FullCommand():
+		state, serr := client.UpdateStatusAction(*updateStatusID, *updateStatusWatch, *updateStatusInterval)
+		if !*updateStatusWatch {
+			err = serr
+			break
+		}
+		code := pc.UpdateStatusExitCode(state, serr)
+		if code == pc.UpdateStatusExitError {
+			exitIfError(serr, "")
+		}
+		client.Cleanup()
+		os.Exit(code)
 	case updateList.FullCommand():
 		err = client.UpdateListAction(*updateListJobID)
 	case updateCache.FullCommand():
@@ -1003,5 +1836,76 @@ func main() {
 	default:
 		app.Fatalf("Unknown command %s", cmd)
 	}
-	app.FatalIfError(err, "")
+	recordHistory(configFile, client, err)
+	exitIfError(err, "")
+}
+
+// historyFile returns the local history file of the CLI configuration
+func historyFile(historyConfig *config.HistoryConfig) (*history.File, error) {
+	var path string
+	var maxSize int64
+	if historyConfig != nil {
+		path = historyConfig.Path
+		maxSize = int64(historyConfig.MaxSizeMB) << 20
+	}
+	if path == "" {
+		var err error
+		if path, err = history.DefaultFilePath(); err != nil {
+			return nil, err
+		}
+	}
+	return history.NewFile(path, maxSize), nil
+}
+
+// recordHistory records the command and its outcome in the local history
+// if it is enabled and the command sent mutating RPCs. Recording is best
+// effort and never fails the command.
+func recordHistory(configFile *config.File, client *pc.Client, cmdErr error) {
+	if configFile.History == nil || !configFile.History.Enabled {
+		return
+	}
+	procedures := client.MutatingProcedures()
+	if len(procedures) == 0 {
+		return
+	}
+
+	record := history.Record{
+		Time: time.Now(),
+		Cluster: configFile.SelectCluster(
+			*cluster, os.Getenv(config.ClusterEnvVar)),
+		Args:       history.RedactArgs(os.Args[1:]),
+		RequestID:  *requestID,
+		Procedures: procedures,
+		Outcome:    history.OutcomeSucceeded,
+	}
+	if u, err := user.Current(); err == nil {
+		record.User = u.Username
+	}
+	if cmdErr != nil {
+		record.Outcome = history.OutcomeFailed
+		record.Error = cmdErr.Error()
+	}
+
+	file, err := historyFile(configFile.History)
+	if err == nil {
+		err = file.Append(record)
+	}
+	if err != nil {
+		log.WithError(err).Debug("Fail to record the command in the history")
+	}
+}
+
+// exitIfError prints err and the request ID of the invocation and exits with
+// a non-zero code. In JSON mode the error is written to stderr as a JSON
+// object so that wrappers can parse it.
+func exitIfError(err error, prefix string) {
+	if err == nil {
+		return
+	}
+	if *jsonFormat {
+		pc.PrintErrorJSON(os.Stderr, err, *requestID)
+		os.Exit(1)
+	}
+	pc.PrintRequestID(os.Stderr, *requestID)
+	app.FatalIfError(err, prefix)
 }
@@ -41,6 +41,7 @@ import (
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/peer"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/transport/mhttp"
 	"github.com/uber/peloton/pkg/hostmgr/offer"
+	"github.com/uber/peloton/pkg/hostmgr/offer/offerpool"
 	"github.com/uber/peloton/pkg/hostmgr/queue"
 	"github.com/uber/peloton/pkg/hostmgr/reconcile"
 	"github.com/uber/peloton/pkg/hostmgr/task"
@@ -312,6 +313,19 @@ func main() {
 	rootScope.Counter("boot").Inc(1)
 
 	store := stores.MustCreateStore(&cfg.Storage, rootScope)
+	// the ZK session of the leader election is shared with the framework
+	// info store if the framework info is kept in ZK
+	zkClient, err := leader.NewZkClient(cfg.Election)
+	if err != nil {
+		log.Fatalf("Could not create zookeeper client: %v", err)
+	}
+	frameworkInfoStore := stores.MustCreateFrameworkInfoStore(
+		&cfg.Storage,
+		store,
+		cfg.Election,
+		zkClient,
+		rootScope,
+	)
 
 	authHeader, err := mesos.GetAuthHeader(&cfg.Mesos, *mesosSecretFile)
 	if err != nil {
@@ -321,10 +335,14 @@ func main() {
 	// Initialize YARPC dispatcher with necessary inbounds and outbounds
 	driver := mesos.InitSchedulerDriver(
 		&cfg.Mesos,
-		store, // store implements FrameworkInfoStore
+		frameworkInfoStore,
 		authHeader,
 	)
 
+	mux.HandleFunc(
+		mesos.FrameworkInfoHistoryPath,
+		mesos.FrameworkInfoHistoryHandler(driver))
+
 	// Active host manager needs a Mesos inbound
 	var mInbound = mhttp.NewInbound(rootScope, driver)
 	inbounds = append(inbounds, mInbound)
@@ -400,18 +418,43 @@ func main() {
 		dispatcher.ClientConfig(common.MesosMasterOperator),
 		cfg.Mesos.Encoding,
 	)
+	// The ACCEPT calls prepared, and validated, by the driver are sent by
+	// the call sender.
+	callSender := mesos.NewCallSender()
 
 	mesos.InitManager(
 		dispatcher,
 		&cfg.Mesos,
-		store, // store implements FrameworkInfoStore
+		frameworkInfoStore,
+		mesos.NewMessageRouter(rootScope.SubScope("mesos")),
 	)
 
+	// Acknowledge the status updates of the offer operations with an ID,
+	// otherwise Mesos re-sends them forever.
+	operationStatusHandler := mesos.NewOperationStatusHandler(
+		schedulerClient,
+		driver,
+		rootScope.SubScope("mesos"),
+	)
+	operationStatusHandler.Register(dispatcher)
+
 	log.WithFields(log.Fields{
 		"http_port": cfg.HostManager.HTTPPort,
 		"url_path":  common.PelotonEndpointPath,
 	}).Info("HostService initialized")
 
+	// The tasks are reconciled with the Mesos master only while the
+	// framework is subscribed, and explicitly until they get a status.
+	mesosReconciler := mesos.NewReconciler(
+		schedulerClient,
+		driver,
+		cfg.HostManager.Reconciler,
+		rootScope.SubScope("mesos"),
+	)
+	mInbound.AddConnectionListener(mesosReconciler)
+	mesosReconciler.Start()
+	defer mesosReconciler.Stop()
+
 	// Declare background works
 	reconciler := reconcile.NewTaskReconciler(
 		schedulerClient,
@@ -420,6 +463,7 @@ func main() {
 		store, // store implements JobStore
 		store, // store implements TaskStore
 		cfg.HostManager.TaskReconcilerConfig,
+		mesosReconciler,
 	)
 
 	maintenanceHostInfoMap := host.NewMaintenanceHostInfoMap(rootScope)
@@ -480,6 +524,8 @@ func main() {
 		time.Duration(cfg.HostManager.OfferHoldTimeSec)*time.Second,
 		time.Duration(cfg.HostManager.OfferPruningPeriodSec)*time.Second,
 		schedulerClient,
+		mesosMasterDetector,
+		callSender,
 		store, // store implements VolumeStore
 		backgroundManager,
 		cfg.HostManager.HostPruningPeriodSec,
@@ -489,10 +535,33 @@ func main() {
 		bin_packing.CreateRanker(cfg.HostManager.BinPacking),
 		cfg.HostManager.BinPackingRefreshIntervalSec,
 		cfg.HostManager.HostPlacingOfferStatusTimeout,
+		cfg.HostManager.OfferSuppression,
+		[]string{cfg.Mesos.Framework.Role},
+		cfg.HostManager.DeclineBatcher,
 	)
 
+	if cfg.HostManager.OfferChurnLogPeriod > 0 {
+		offer.GetEventHandler().GetOfferPool().AddListener(
+			offerpool.NewChurnListener(cfg.HostManager.OfferChurnLogPeriod))
+	}
+
+	mux.HandleFunc(
+		offer.OfferSuppressionPath,
+		offer.OfferSuppressionHandler(offer.GetEventHandler().GetSuppressor()))
+
 	maintenanceQueue := queue.NewMaintenanceQueue()
 
+	// The acknowledgements of the task status updates are batched, and
+	// their failures logged and counted by the task state manager metrics.
+	ackBatcher := mesos.NewAcknowledgeBatcher(
+		schedulerClient,
+		driver,
+		cfg.HostManager.TaskUpdateAckBatcher,
+		task.NewAcknowledgeCallback(rootScope),
+		rootScope.SubScope("task_update_ack_batcher"),
+	)
+	defer ackBatcher.Stop()
+
 	// Initializing TaskStateManager will start to record task status
 	// update back to storage.  TODO(zhitao): This is
 	// temporary. Eventually we should create proper API protocol for
@@ -500,7 +569,7 @@ func main() {
 	// separately.
 	taskStateManager := task.NewStateManager(
 		dispatcher,
-		schedulerClient,
+		ackBatcher,
 		cfg.HostManager.TaskUpdateBufferSize,
 		cfg.HostManager.TaskUpdateAckConcurrency,
 		resmgrsvc.NewResourceManagerServiceYARPCClient(
@@ -508,13 +577,57 @@ func main() {
 		rootScope,
 	)
 
+	// The kills of the tasks not terminated after a grace period are
+	// escalated. The kill escalator tracks the tasks from their status
+	// updates, including the ones of the reconciliation after a restart.
+	killEscalator := task.NewKillEscalator(
+		cfg.HostManager.KillEscalation,
+		schedulerClient,
+		driver,
+		rootScope,
+	)
+	taskStateManager.AddObserver(killEscalator)
+	taskStateManager.AddObserver(mesosReconciler)
+	backgroundManager.RegisterWorks(
+		background.Work{
+			Name:   "killescalator",
+			Func:   killEscalator.Escalate,
+			Period: killEscalator.CheckPeriod(),
+		},
+	)
+
 	// Create new hostmgr internal service handler.
+	// The allowed users of the tasks are reloaded from the config files, so
+	// that they can be changed without restarting hostmgr.
+	taskUserValidator := hostmgr.NewTaskUserValidator(
+		cfg.Mesos.Framework.User,
+		cfg.Mesos.Framework.AllowedUsers,
+		func() ([]string, error) {
+			var reloaded Config
+			if err := config.Parse(&reloaded, *configFiles...); err != nil {
+				return nil, err
+			}
+			return reloaded.Mesos.Framework.AllowedUsers, nil
+		},
+		rootScope,
+	)
+	if cfg.HostManager.AllowedUsersReloadPeriod > 0 {
+		backgroundManager.RegisterWorks(
+			background.Work{
+				Name:   "taskuservalidator",
+				Func:   taskUserValidator.Reload,
+				Period: cfg.HostManager.AllowedUsersReloadPeriod,
+			},
+		)
+	}
+
 	hostmgr.NewServiceHandler(
 		dispatcher,
 		rootScope,
 		schedulerClient,
 		masterOperatorClient,
 		driver,
+		callSender,
 		store, // store implements VolumeStore
 		cfg.Mesos,
 		mesosMasterDetector,
@@ -523,6 +636,17 @@ func main() {
 		cfg.HostManager.SlackResourceTypes,
 		maintenanceHostInfoMap,
 		taskStateManager,
+		taskUserValidator,
+		killEscalator,
+	)
+
+	// The maintenance schedule of the Mesos master is updated by the host
+	// service and by a background worker keeping it in sync with the hosts
+	// in maintenance, both through the same writer.
+	maintenanceScheduleWriter := host.NewMaintenanceScheduleWriter(
+		masterOperatorClient,
+		maintenanceHostInfoMap,
+		rootScope,
 	)
 
 	hostsvc.InitServiceHandler(
@@ -531,6 +655,15 @@ func main() {
 		masterOperatorClient,
 		maintenanceQueue,
 		maintenanceHostInfoMap,
+		maintenanceScheduleWriter,
+	)
+
+	backgroundManager.RegisterWorks(
+		background.Work{
+			Name:   "maintenanceschedulewriter",
+			Func:   maintenanceScheduleWriter.Write,
+			Period: cfg.HostManager.MaintenanceScheduleWritePeriod,
+		},
 	)
 
 	// Register background worker to start mesos task status update counter.
@@ -576,7 +709,8 @@ func main() {
 		log.Fatalf("Could not start rpc server: %v", err)
 	}
 
-	candidate, err := leader.NewCandidate(
+	candidate, err := leader.NewCandidateWithClient(
+		zkClient,
 		cfg.Election,
 		rootScope,
 		common.HostManagerRole,
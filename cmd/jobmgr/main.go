@@ -52,6 +52,7 @@ import (
 	"github.com/uber/peloton/pkg/jobmgr/volumesvc"
 	"github.com/uber/peloton/pkg/jobmgr/watchsvc"
 	"github.com/uber/peloton/pkg/middleware/inbound"
+	"github.com/uber/peloton/pkg/storage"
 	ormobjects "github.com/uber/peloton/pkg/storage/objects"
 	"github.com/uber/peloton/pkg/storage/stores"
 
@@ -316,6 +317,28 @@ func main() {
 	// store implements JobStore, TaskStore, VolumeStore, UpdateStore
 	// and FrameworkInfoStore
 	store := stores.MustCreateStore(&cfg.Storage, rootScope)
+	// the framework is registered by the host manager, its framework info
+	// can only be read from a store shared with the host manager
+	// the ZK session of the leader election is shared with the framework
+	// info store if the framework info is kept in ZK
+	zkClient, err := leader.NewZkClient(cfg.Election)
+	if err != nil {
+		log.Fatalf("Could not create zookeeper client: %v", err)
+	}
+	var frameworkInfoStore storage.FrameworkInfoStore = store
+	if stores.IsSharedFrameworkInfoStore(&cfg.Storage) {
+		frameworkInfoStore = stores.MustCreateFrameworkInfoStore(
+			&cfg.Storage,
+			store,
+			cfg.Election,
+			zkClient,
+			rootScope,
+		)
+	} else {
+		log.WithField("framework_info_store", cfg.Storage.FrameworkInfoStore).
+			Warn("Framework info store is local to the host manager, " +
+				"reading the framework info from Cassandra")
+	}
 	ormStore, ormErr := ormobjects.NewCassandraStore(
 		&cfg.Storage.Cassandra,
 		rootScope)
@@ -421,6 +444,31 @@ func main() {
 		cfg.JobManager.Watch,
 	)
 
+	// The label index of the cached tasks is rebuilt when the cache is
+	// recovered, and its terminal tasks are purged after their retention.
+	labelIndex := cached.NewIndexListener(cfg.JobManager.LabelIndex, rootScope)
+	if cfg.JobManager.LabelIndex.PurgePeriod > 0 {
+		backgroundManager.RegisterWorks(
+			background.Work{
+				Name:   "LabelIndexPurge",
+				Func:   labelIndex.Purge,
+				Period: cfg.JobManager.LabelIndex.PurgePeriod,
+			},
+		)
+	}
+
+	// The notifications delivered to the cache listeners are audited when
+	// enabled, and returned by a debug endpoint.
+	notificationAudit, err := cached.NewNotificationAudit(
+		cfg.JobManager.NotificationAudit, rootScope)
+	if err != nil {
+		log.WithError(err).Fatal("Cannot create notification audit")
+	}
+	mux.HandleFunc(
+		cached.NotificationAuditPath,
+		cached.NotificationAuditHandler(notificationAudit),
+	)
+
 	jobFactory := cached.InitJobFactory(
 		store, // store implements JobStore
 		store, // store implements TaskStore
@@ -428,7 +476,11 @@ func main() {
 		store, // store implements VolumeStore
 		ormStore,
 		rootScope,
-		[]cached.JobTaskListener{watchsvc.NewWatchListener(watchProcessor)},
+		[]cached.JobTaskListener{
+			watchsvc.NewWatchListener(watchProcessor),
+			labelIndex,
+		},
+		notificationAudit,
 	)
 
 	// TODO: We need to cleanup the client names
@@ -506,6 +558,9 @@ func main() {
 		cfg.JobManager.HTTPPort,
 		cfg.JobManager.GRPCPort,
 		jobFactory,
+		func() (uint64, error) {
+			return leader.Epoch(zkClient, cfg.Election, common.JobManagerRole)
+		},
 		goalStateDriver,
 		taskPreemptor,
 		deadlineTracker,
@@ -514,7 +569,8 @@ func main() {
 		backgroundManager,
 	)
 
-	candidate, err := leader.NewCandidate(
+	candidate, err := leader.NewCandidateWithClient(
+		zkClient,
 		cfg.Election,
 		rootScope,
 		common.JobManagerRole,
@@ -556,7 +612,7 @@ func main() {
 		store, // store implements JobStore
 		store, // store implements TaskStore
 		store, // store implements UpdateStore
-		store, // store implements FrameworkInfoStore
+		frameworkInfoStore,
 		jobFactory,
 		goalStateDriver,
 		candidate,
@@ -570,7 +626,7 @@ func main() {
 		dispatcher,
 		store,
 		store,
-		store,
+		frameworkInfoStore,
 		jobFactory,
 		goalStateDriver,
 		candidate,
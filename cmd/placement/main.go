@@ -43,6 +43,7 @@ import (
 	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
 	_ "go.uber.org/automaxprocs"
 	"go.uber.org/yarpc"
 	"go.uber.org/yarpc/api/transport"
@@ -204,6 +205,11 @@ func main() {
 		WithField("strategy", cfg.Placement.Strategy).
 		Info("Placement engine type")
 
+	labeler, err := mimir_strategy.NewTaskLabeler(cfg.Placement.LabelTemplates)
+	if err != nil {
+		log.WithError(err).Fatal("Invalid label templates")
+	}
+
 	log.WithField("config", cfg).
 		Info("Completed Loading Placement Engine config")
 
@@ -216,6 +222,12 @@ func main() {
 
 	mux.HandleFunc(logging.LevelOverwrite, logging.LevelOverwriteHandler(initialLevel))
 	mux.HandleFunc(buildversion.Get, buildversion.Handler(version))
+	labelDiffs := mimir_strategy.NewLabelDiffTracker(rootScope.SubScope("mimir"))
+	mux.HandleFunc(mimir_strategy.LabelDiffsPath, mimir_strategy.LabelDiffsHandler(labelDiffs))
+	explanations := mimir_strategy.NewExplanationTracker()
+	mux.HandleFunc(mimir_strategy.ExplanationsPath, mimir_strategy.ExplanationsHandler(explanations))
+	groupSnapshots := mimir_strategy.NewGroupSnapshotTracker()
+	mux.HandleFunc(mimir_strategy.SnapshotPath, mimir_strategy.SnapshotHandler(groupSnapshots))
 
 	log.Info("Connecting to HostManager")
 	t := rpc.NewTransport()
@@ -310,7 +322,8 @@ func main() {
 		tallyMetrics,
 	)
 
-	strategy := initPlacementStrategy(cfg)
+	strategy := initPlacementStrategy(
+		cfg, labeler, labelDiffs, groupSnapshots, explanations, rootScope)
 
 	pool := async.NewPool(async.PoolOptions{
 		MaxWorkers: cfg.Placement.Concurrency,
@@ -343,7 +356,13 @@ func main() {
 	select {}
 }
 
-func initPlacementStrategy(cfg config.Config) plugins.Strategy {
+func initPlacementStrategy(
+	cfg config.Config,
+	labeler *mimir_strategy.TaskLabeler,
+	labelDiffs *mimir_strategy.LabelDiffTracker,
+	groupSnapshots *mimir_strategy.GroupSnapshotTracker,
+	explanations *mimir_strategy.ExplanationTracker,
+	scope tally.Scope) plugins.Strategy {
 	var strategy plugins.Strategy
 	switch cfg.Placement.Strategy {
 	case config.Batch:
@@ -352,7 +371,14 @@ func initPlacementStrategy(cfg config.Config) plugins.Strategy {
 		// TODO avyas check mimir concurrency parameters
 		cfg.Placement.Concurrency = 1
 		placer := algorithms.NewPlacer(4, 300)
-		strategy = mimir_strategy.New(placer, &cfg.Placement)
+		strategy = mimir_strategy.New(
+			placer,
+			&cfg.Placement,
+			labeler,
+			labelDiffs,
+			groupSnapshots,
+			explanations,
+			scope.SubScope("mimir"))
 	}
 	return strategy
 }
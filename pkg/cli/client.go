@@ -16,12 +16,18 @@ package cli
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"io"
+	"sync"
 	"time"
 
 	"go.uber.org/yarpc"
+	yarpcmiddleware "go.uber.org/yarpc/api/middleware"
 	"go.uber.org/yarpc/api/transport"
 	"go.uber.org/yarpc/transport/grpc"
+	ggrpc "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 
 	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
 	"github.com/uber/peloton/.gen/peloton/api/v0/job"
@@ -56,54 +62,127 @@ type Client struct {
 	dispatcher      *yarpc.Dispatcher
 	ctx             context.Context
 	cancelFunc      context.CancelFunc
+	// mutations records the mutating RPCs of the client
+	mutations *middleware.MutationOutboundMiddleware
 	// Debug is whether debug output is enabled
 	Debug bool
+	// JSON is whether responses and errors are printed as canonical JSON
+	// instead of tables
+	JSON bool
+	// Output is the output format of responses, one of OutputTable,
+	// OutputJSON, OutputYAML, OutputCSV or a Go template prefixed by
+	// OutputGoTemplatePrefix. It defaults to a table, or JSON if JSON is set.
+	Output string
+	// Columns are the comma separated columns printed in OutputCSV, in
+	// order. All columns are printed if it is empty.
+	Columns string
+	// NoColor is whether the states printed in tables are not colored on a
+	// terminal
+	NoColor bool
+	// ProgressFormat is the format of the progress of long running
+	// commands, ProgressFormatJSON for progress events on stderr, or
+	// ProgressFormatText if it is not set
+	ProgressFormat string
+	// AssumeYes is whether destructive actions run without asking for
+	// confirmation
+	AssumeYes bool
+	// limiter limits the rate of the mutating requests of bulk commands,
+	// they are not limited if it is nil
+	limiter *rateLimiter
+	// confirmIn and confirmOut are the reader and writer of confirmation
+	// prompts, os.Stdin and os.Stderr if not set
+	confirmIn  io.Reader
+	confirmOut io.Writer
+	// MaxHostRangeExpansion caps the number of hosts a host range may
+	// expand to, DefaultMaxHostRangeExpansion is used if it is not set
+	MaxHostRangeExpansion int
+	// SkipEmptyHosts is whether empty hosts of host lists, e.g. after a
+	// trailing separator, are skipped instead of rejected
+	SkipEmptyHosts bool
+	// MaxHosts caps the number of hosts of a host list, DefaultMaxHosts is
+	// used if it is not set
+	MaxHosts int
+	// SkipHostValidation is whether the hosts of host lists are used
+	// without checking that they are valid hostnames or IP addresses
+	SkipHostValidation bool
+	// RetryPolicy is the timeout and retry policy of the unary RPCs
+	RetryPolicy middleware.RetryPolicy
+	// RequestID is the ID of the CLI invocation carried by all its RPCs
+	RequestID string
+
+	// respoolLookups caches resource pool lookups by path for the lifetime
+	// of the client
+	respoolLookupsLock sync.Mutex
+	respoolLookups     map[string]*respoolLookup
 }
 
-// New returns a new RPC client given a framework URL and timeout and error
+// New returns a new RPC client given a framework URL and the timeout and
+// retry policy of its RPCs. Connections to a peloton role, e.g.
+// common.JobManagerRole, use TLS if tlsConfigs has a config for the role.
+// Every attempt of a unary RPC is written to debugRPC as a JSON line if it
+// is set. The discovery of the leaders is retried until waitForLeader
+// expires if it is not zero. All RPCs carry requestID in the
+// middleware.RequestIDHeader header, and the credentials of authConfig if
+// it is set.
 func New(
 	discovery leader.Discovery,
-	timeout time.Duration,
-	authConfig *middleware.BasicAuthConfig,
-	debug bool) (*Client, error) {
+	retryPolicy middleware.RetryPolicy,
+	authConfig *middleware.AuthConfig,
+	tlsConfigs map[string]*tls.Config,
+	debugRPC io.Writer,
+	waitForLeader time.Duration,
+	jsonOutput bool,
+	requestID string) (*Client, error) {
 
-	jobmgrURL, err := discovery.GetAppURL(common.JobManagerRole)
+	urls, err := discoverLeaders(discovery, leaderRoles, waitForLeader)
 	if err != nil {
 		return nil, err
 	}
+	jobmgrURL := urls[common.JobManagerRole]
+	resmgrURL := urls[common.ResourceManagerRole]
+	hostmgrURL := urls[common.HostManagerRole]
 
-	resmgrURL, err := discovery.GetAppURL(common.ResourceManagerRole)
-	if err != nil {
-		return nil, err
-	}
+	jobmgrTransport := newTransport(tlsConfigs[common.JobManagerRole])
+	resmgrTransport := newTransport(tlsConfigs[common.ResourceManagerRole])
+	hostmgrTransport := newTransport(tlsConfigs[common.HostManagerRole])
 
-	hostmgrURL, err := discovery.GetAppURL(common.HostManagerRole)
+	authMiddleware, err := middleware.NewAuthOutboundMiddleware(authConfig)
 	if err != nil {
 		return nil, err
 	}
-
-	t := grpc.NewTransport()
-
-	authMiddleware := middleware.NewBasicAuthOutboundMiddleware(authConfig)
+	requestIDMiddleware := middleware.NewRequestIDOutboundMiddleware(requestID)
+	mutationMiddleware := middleware.NewMutationOutboundMiddleware()
+	unaryMiddleware := []yarpcmiddleware.UnaryOutbound{
+		mutationMiddleware,
+		middleware.NewRetryOutboundMiddleware(retryPolicy),
+		authMiddleware,
+		requestIDMiddleware,
+	}
+	if debugRPC != nil {
+		unaryMiddleware = append(unaryMiddleware,
+			middleware.NewDebugOutboundMiddleware(debugRPC, resolveRPCMessages))
+	}
 
 	dispatcher := yarpc.NewDispatcher(yarpc.Config{
 		Name: common.PelotonCLI,
 		Outbounds: yarpc.Outbounds{
 			common.PelotonJobManager: transport.Outbounds{
-				Unary:  t.NewSingleOutbound(jobmgrURL.Host),
-				Stream: t.NewSingleOutbound(jobmgrURL.Host),
+				Unary:  jobmgrTransport.NewSingleOutbound(jobmgrURL.Host),
+				Stream: jobmgrTransport.NewSingleOutbound(jobmgrURL.Host),
 			},
 			common.PelotonResourceManager: transport.Outbounds{
-				Unary: t.NewSingleOutbound(resmgrURL.Host),
+				Unary: resmgrTransport.NewSingleOutbound(resmgrURL.Host),
 			},
 			common.PelotonHostManager: transport.Outbounds{
-				Unary: t.NewSingleOutbound(hostmgrURL.Host),
+				Unary: hostmgrTransport.NewSingleOutbound(hostmgrURL.Host),
 			},
 		},
 		OutboundMiddleware: yarpc.OutboundMiddleware{
-			Unary:  authMiddleware,
-			Oneway: authMiddleware,
-			Stream: authMiddleware,
+			Unary: middleware.UnaryOutboundChain(unaryMiddleware...),
+			Oneway: middleware.OnewayOutboundChain(
+				authMiddleware, requestIDMiddleware),
+			Stream: middleware.StreamOutboundChain(
+				authMiddleware, requestIDMiddleware),
 		},
 	})
 
@@ -111,9 +190,13 @@ func New(
 		return nil, fmt.Errorf("Unable to start dispatcher: %v", err)
 	}
 
-	ctx, cancelFunc := context.WithTimeout(context.Background(), timeout)
+	// unary RPCs are bounded by the timeout of the retry policy
+	ctx, cancelFunc := context.WithCancel(context.Background())
 	client := Client{
-		Debug: debug,
+		Debug:       jsonOutput,
+		JSON:        jsonOutput,
+		RetryPolicy: retryPolicy,
+		RequestID:   requestID,
 		jobClient: job.NewJobManagerYARPCClient(
 			dispatcher.ClientConfig(common.PelotonJobManager),
 		),
@@ -147,12 +230,32 @@ func New(
 			dispatcher.ClientConfig(common.PelotonJobManager),
 		),
 		dispatcher: dispatcher,
+		mutations:  mutationMiddleware,
 		ctx:        ctx,
 		cancelFunc: cancelFunc,
 	}
 	return &client, nil
 }
 
+// newTransport returns a gRPC transport, which uses TLS if tlsConfig is set
+func newTransport(tlsConfig *tls.Config) *grpc.Transport {
+	if tlsConfig == nil {
+		return grpc.NewTransport()
+	}
+	return grpc.NewTransport(grpc.ClientBuilderOptions(
+		ggrpc.WithTransportCredentials(credentials.NewTLS(tlsConfig)),
+	))
+}
+
+// MutatingProcedures returns the procedures of the mutating RPCs sent by
+// the client, e.g. to record the command in the history
+func (c *Client) MutatingProcedures() []string {
+	if c.mutations == nil {
+		return nil
+	}
+	return c.mutations.Procedures()
+}
+
 // Cleanup ensures the client's YARPC dispatcher is stopped
 func (c *Client) Cleanup() {
 	defer c.cancelFunc()
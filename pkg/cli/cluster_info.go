@@ -0,0 +1,286 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/pkg/common/buildversion"
+	"github.com/uber/peloton/pkg/common/leader"
+)
+
+const (
+	clusterInfoFormatHeader = "Component\tLeader\tAddress\tVersion\tGit Ref\tStatus\t\n"
+	clusterInfoFormatBody   = "%s\t%s\t%s\t%s\t%s\t%s\t\n"
+)
+
+// gitDescribePattern matches the commit of a version built from an untagged
+// commit by git describe --always --tags, e.g. 0.8.5-12-gabcdef12
+var gitDescribePattern = regexp.MustCompile(`-g([0-9a-f]+)(-dirty)?$`)
+
+// ComponentInfo is the build and leader information of a peloton component
+type ComponentInfo struct {
+	// Role is the role of the component, e.g. common.JobManagerRole
+	Role string `json:"role"`
+	// Hostname is the hostname of the leader, only known with zookeeper
+	// discovery
+	Hostname string `json:"hostname,omitempty"`
+	// Address is the RPC address of the leader
+	Address string `json:"address,omitempty"`
+	// Version is the build version of the leader, only known with
+	// zookeeper discovery
+	Version string `json:"version,omitempty"`
+	// GitRef is the tag or commit the leader was built from
+	GitRef string `json:"gitRef,omitempty"`
+	// VersionMismatch is set if the version differs from the one most
+	// components run
+	VersionMismatch bool `json:"versionMismatch,omitempty"`
+	// Error is why the leader is unreachable
+	Error string `json:"error,omitempty"`
+}
+
+// ClusterInfo is the build and leader information of the peloton components
+// the CLI connects to
+type ClusterInfo struct {
+	Components []*ComponentInfo `json:"components"`
+}
+
+// unreachable returns the number of unreachable components
+func (i *ClusterInfo) unreachable() int {
+	count := 0
+	for _, component := range i.Components {
+		if component.Error != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// ClusterInfoAction prints the leader, build version and git ref of the
+// peloton components, flagging the components which run a different
+// version than most. An error is returned if any component is unreachable.
+func ClusterInfoAction(
+	discovery leader.Discovery,
+	timeout time.Duration,
+	jsonOutput bool) error {
+	info := GetClusterInfo(discovery, timeout)
+	if jsonOutput {
+		printResponseJSON(info)
+	} else {
+		printClusterInfo(info)
+	}
+
+	if count := info.unreachable(); count > 0 {
+		return fmt.Errorf("%d of %d components are unreachable",
+			count, len(info.Components))
+	}
+	return nil
+}
+
+// printClusterInfo prints the components as a table
+func printClusterInfo(info *ClusterInfo) {
+	defer tabWriter.Flush()
+	fmt.Fprint(tabWriter, clusterInfoFormatHeader)
+	for _, c := range info.Components {
+		status := "ok"
+		switch {
+		case c.Error != "":
+			status = "unreachable: " + c.Error
+		case c.VersionMismatch:
+			status = "version mismatch"
+		}
+		fmt.Fprintf(tabWriter, clusterInfoFormatBody,
+			c.Role,
+			orDash(c.Hostname),
+			orDash(c.Address),
+			orDash(c.Version),
+			orDash(c.GitRef),
+			status,
+		)
+	}
+}
+
+// orDash returns s, or - if it is empty
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// GetClusterInfo queries the leaders of the peloton components
+// concurrently, each within timeout, so that an unreachable component only
+// fails its own entry of the report.
+func GetClusterInfo(
+	discovery leader.Discovery,
+	timeout time.Duration) *ClusterInfo {
+	info := &ClusterInfo{
+		Components: make([]*ComponentInfo, len(leaderRoles)),
+	}
+	var wg sync.WaitGroup
+	for i, role := range leaderRoles {
+		wg.Add(1)
+		go func(i int, role string) {
+			defer wg.Done()
+			info.Components[i] = getComponentInfo(discovery, role, timeout)
+		}(i, role)
+	}
+	wg.Wait()
+
+	flagVersionMismatches(info.Components)
+	return info
+}
+
+// getComponentInfo queries the leader of a role within timeout. The
+// discovery cannot be cancelled, so a query which times out is abandoned.
+func getComponentInfo(
+	discovery leader.Discovery,
+	role string,
+	timeout time.Duration) *ComponentInfo {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	result := make(chan *ComponentInfo, 1)
+	go func() {
+		result <- fetchComponentInfo(ctx, discovery, role)
+	}()
+	select {
+	case component := <-result:
+		return component
+	case <-ctx.Done():
+		return &ComponentInfo{
+			Role:  role,
+			Error: fmt.Sprintf("timed out after %s", timeout),
+		}
+	}
+}
+
+// fetchComponentInfo queries the leader of a role. The version of the leader
+// is read from its HTTP endpoint if the discovery knows the leader ID, and
+// otherwise only the reachability of its RPC address is checked.
+func fetchComponentInfo(
+	ctx context.Context,
+	discovery leader.Discovery,
+	role string) *ComponentInfo {
+	component := &ComponentInfo{Role: role}
+
+	ids, ok := discovery.(leader.IDDiscovery)
+	if !ok {
+		u, err := discovery.GetAppURL(role)
+		if err != nil {
+			component.Error = err.Error()
+			return component
+		}
+		component.Address = hostPort(u)
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", component.Address)
+		if err != nil {
+			component.Error = err.Error()
+			return component
+		}
+		conn.Close()
+		return component
+	}
+
+	id, err := ids.GetLeaderID(role)
+	if err != nil {
+		component.Error = err.Error()
+		return component
+	}
+	component.Hostname = id.Hostname
+	component.Address = fmt.Sprintf("%s:%d", id.IP, id.GRPCPort)
+	component.Version = id.Version
+	if component.Version == "" {
+		component.Version, err = fetchVersion(
+			ctx, fmt.Sprintf("%s:%d", id.IP, id.HTTPPort))
+		if err != nil {
+			component.Error = err.Error()
+			return component
+		}
+	}
+	component.GitRef = gitRef(component.Version)
+	return component
+}
+
+// hostPort returns the host and port of an app URL. The static discovery
+// returns the whole URL given on the command line as host.
+func hostPort(u *url.URL) string {
+	if parsed, err := url.Parse(u.Host); err == nil && parsed.Host != "" {
+		return parsed.Host
+	}
+	return u.Host
+}
+
+// fetchVersion reads the build version from the HTTP endpoint of a component
+func fetchVersion(ctx context.Context, address string) (string, error) {
+	request, err := http.NewRequest(
+		http.MethodGet, "http://"+address+buildversion.Get, nil)
+	if err != nil {
+		return "", err
+	}
+	response, err := http.DefaultClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return "", err
+	}
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unable to get the version from %s: %s",
+			address, response.Status)
+	}
+	return strings.TrimSpace(string(body)), nil
+}
+
+// gitRef returns the git ref a build version was made from: the commit of a
+// git describe version, or the version itself if it is a tag or a commit
+func gitRef(version string) string {
+	if m := gitDescribePattern.FindStringSubmatch(version); m != nil {
+		return m[1]
+	}
+	return version
+}
+
+// flagVersionMismatches flags the reachable components which run a
+// different version than most reachable components
+func flagVersionMismatches(components []*ComponentInfo) {
+	counts := make(map[string]int)
+	majority := ""
+	for _, c := range components {
+		if c.Version == "" {
+			continue
+		}
+		counts[c.Version]++
+		if counts[c.Version] > counts[majority] ||
+			(counts[c.Version] == counts[majority] && c.Version < majority) {
+			majority = c.Version
+		}
+	}
+	for _, c := range components {
+		c.VersionMismatch = c.Version != "" && c.Version != majority
+	}
+}
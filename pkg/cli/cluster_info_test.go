@@ -0,0 +1,214 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/buildversion"
+	"github.com/uber/peloton/pkg/common/leader"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeIDDiscovery is a discovery of leader IDs, which fails the lookups of
+// the roles in errs and blocks the lookups of the roles in blocked
+type fakeIDDiscovery struct {
+	ids     map[string]*leader.ID
+	errs    map[string]error
+	blocked map[string]chan struct{}
+}
+
+func (d *fakeIDDiscovery) GetAppURL(role string) (*url.URL, error) {
+	id, err := d.GetLeaderID(role)
+	if err != nil {
+		return nil, err
+	}
+	return &url.URL{Host: fmt.Sprintf("%s:%d", id.IP, id.GRPCPort)}, nil
+}
+
+func (d *fakeIDDiscovery) GetLeaderID(role string) (*leader.ID, error) {
+	if blocked, ok := d.blocked[role]; ok {
+		<-blocked
+	}
+	if err := d.errs[role]; err != nil {
+		return nil, err
+	}
+	return d.ids[role], nil
+}
+
+type clusterInfoTestSuite struct {
+	suite.Suite
+	servers   []*httptest.Server
+	discovery *fakeIDDiscovery
+}
+
+func (suite *clusterInfoTestSuite) SetupTest() {
+	suite.servers = nil
+	suite.discovery = &fakeIDDiscovery{
+		ids:     make(map[string]*leader.ID),
+		errs:    make(map[string]error),
+		blocked: make(map[string]chan struct{}),
+	}
+	for _, role := range leaderRoles {
+		suite.addLeader(role, "0.8.5-12-gabcdef12")
+	}
+}
+
+func (suite *clusterInfoTestSuite) TearDownTest() {
+	for _, server := range suite.servers {
+		server.Close()
+	}
+	for _, blocked := range suite.discovery.blocked {
+		close(blocked)
+	}
+}
+
+func TestClusterInfo(t *testing.T) {
+	suite.Run(t, new(clusterInfoTestSuite))
+}
+
+// addLeader serves the version of the leader of a role
+func (suite *clusterInfoTestSuite) addLeader(role string, version string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc(buildversion.Get, buildversion.Handler(version))
+	server := httptest.NewServer(mux)
+	suite.servers = append(suite.servers, server)
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	suite.NoError(err)
+	httpPort, err := strconv.Atoi(port)
+	suite.NoError(err)
+	suite.discovery.ids[role] = &leader.ID{
+		Hostname: role + "-host",
+		IP:       host,
+		HTTPPort: httpPort,
+		GRPCPort: 5392,
+	}
+}
+
+// TestHealthyCluster tests the report of a cluster of reachable components
+// running the same version
+func (suite *clusterInfoTestSuite) TestHealthyCluster() {
+	info := GetClusterInfo(suite.discovery, time.Second)
+	suite.Len(info.Components, len(leaderRoles))
+	for i, c := range info.Components {
+		suite.Equal(leaderRoles[i], c.Role)
+		suite.Equal(leaderRoles[i]+"-host", c.Hostname)
+		suite.Equal("127.0.0.1:5392", c.Address)
+		suite.Equal("0.8.5-12-gabcdef12", c.Version)
+		suite.Equal("abcdef12", c.GitRef)
+		suite.False(c.VersionMismatch)
+		suite.Empty(c.Error)
+	}
+	suite.Equal(0, info.unreachable())
+}
+
+// TestVersionMismatch tests that the components running a different version
+// than most are flagged
+func (suite *clusterInfoTestSuite) TestVersionMismatch() {
+	suite.addLeader(common.HostManagerRole, "0.8.6")
+
+	info := GetClusterInfo(suite.discovery, time.Second)
+	for _, c := range info.Components {
+		if c.Role == common.HostManagerRole {
+			suite.Equal("0.8.6", c.GitRef)
+			suite.True(c.VersionMismatch)
+		} else {
+			suite.False(c.VersionMismatch)
+		}
+	}
+	suite.Equal(0, info.unreachable())
+}
+
+// TestUnreachableComponents tests that components which cannot be found or
+// do not answer in time fail only their own entry
+func (suite *clusterInfoTestSuite) TestUnreachableComponents() {
+	suite.discovery.errs[common.HostManagerRole] = fmt.Errorf("no leader")
+	suite.discovery.blocked[common.ResourceManagerRole] = make(chan struct{})
+
+	start := time.Now()
+	info := GetClusterInfo(suite.discovery, 50*time.Millisecond)
+	suite.True(time.Since(start) < time.Second)
+
+	byRole := make(map[string]*ComponentInfo)
+	for _, c := range info.Components {
+		byRole[c.Role] = c
+	}
+	suite.Empty(byRole[common.JobManagerRole].Error)
+	suite.Equal("0.8.5-12-gabcdef12", byRole[common.JobManagerRole].Version)
+	suite.Equal("no leader", byRole[common.HostManagerRole].Error)
+	suite.Equal("timed out after 50ms", byRole[common.ResourceManagerRole].Error)
+	suite.Equal(2, info.unreachable())
+
+	err := ClusterInfoAction(suite.discovery, 50*time.Millisecond, false)
+	suite.EqualError(err, "2 of 3 components are unreachable")
+}
+
+// TestVersionEndpointFailure tests that a leader whose version cannot be
+// read is unreachable
+func (suite *clusterInfoTestSuite) TestVersionEndpointFailure() {
+	suite.servers[0].Close()
+
+	info := GetClusterInfo(suite.discovery, time.Second)
+	suite.NotEmpty(info.Components[0].Error)
+	suite.Empty(info.Components[0].Version)
+	suite.Equal(1, info.unreachable())
+}
+
+// TestStaticDiscovery tests that only the reachability of the RPC address
+// is checked without leader IDs
+func (suite *clusterInfoTestSuite) TestStaticDiscovery() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.NoError(err)
+	defer listener.Close()
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.NoError(err)
+	closed.Close()
+
+	parse := func(address string) *url.URL {
+		u, err := url.Parse("http://" + address)
+		suite.NoError(err)
+		return u
+	}
+	discovery, err := leader.NewStaticServiceDiscovery(
+		parse(listener.Addr().String()),
+		parse(listener.Addr().String()),
+		parse(closed.Addr().String()))
+	suite.NoError(err)
+
+	info := GetClusterInfo(discovery, time.Second)
+	suite.Equal(listener.Addr().String(), info.Components[0].Address)
+	suite.Empty(info.Components[0].Error)
+	suite.Empty(info.Components[0].Version)
+	suite.Empty(info.Components[1].Error)
+	suite.NotEmpty(info.Components[2].Error)
+	suite.Equal(1, info.unreachable())
+}
+
+func (suite *clusterInfoTestSuite) TestGitRef() {
+	suite.Equal("abcdef12", gitRef("0.8.5-12-gabcdef12"))
+	suite.Equal("abcdef12", gitRef("0.8.5-12-gabcdef12-dirty"))
+	suite.Equal("0.8.5", gitRef("0.8.5"))
+	suite.Equal("abcdef12", gitRef("abcdef12"))
+}
@@ -0,0 +1,114 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"os"
+	"strings"
+)
+
+// ANSI escape codes of the colors of states. All colors have codes of the
+// same length, as the tabwriter counts the escape codes in the width of the
+// cells: a column is aligned as long as all its cells, header included, are
+// colored.
+const (
+	colorReset   = "\033[0m"
+	colorRed     = "\033[31m"
+	colorGreen   = "\033[32m"
+	colorYellow  = "\033[33m"
+	colorDefault = "\033[39m"
+)
+
+// noColorEnv disables colors if it is set to a non-empty value, see
+// https://no-color.org
+const noColorEnv = "NO_COLOR"
+
+var (
+	// used for testing
+	colorIsTerminal = isTerminal
+	colorGetenv     = os.Getenv
+)
+
+// stateColors are the colors of the job, task and host states, the other
+// states have the default color
+var stateColors = map[string]string{
+	"RUNNING":             colorGreen,
+	"SUCCEEDED":           colorGreen,
+	"HOST_STATE_UP":       colorGreen,
+	"FAILED":              colorRed,
+	"LOST":                colorRed,
+	"HOST_STATE_DOWN":     colorRed,
+	"PENDING":             colorYellow,
+	"KILLING":             colorYellow,
+	"HOST_STATE_DRAINING": colorYellow,
+	"HOST_STATE_DRAINED":  colorYellow,
+}
+
+// stateColorizer colors the states printed in tables
+type stateColorizer struct {
+	enabled bool
+}
+
+// colorizer returns the colorizer of the tables printed by the client,
+// enabled if they are printed to a terminal unless colors are disabled with
+// NoColor or the NO_COLOR environment variable
+func (c *Client) colorizer() stateColorizer {
+	return stateColorizer{
+		enabled: !c.NoColor &&
+			colorGetenv(noColorEnv) == "" &&
+			c.outputFormat() == OutputTable &&
+			!c.Debug &&
+			colorIsTerminal(),
+	}
+}
+
+// state returns the state in its color, unchanged if colors are disabled
+func (s stateColorizer) state(state string) string {
+	if !s.enabled {
+		return state
+	}
+	color, ok := stateColors[state]
+	if !ok {
+		color = colorDefault
+	}
+	return color + state + colorReset
+}
+
+// header returns the header of a column of states in the default color so
+// that it is aligned with the colored states, unchanged if colors are
+// disabled
+func (s stateColorizer) header(header string) string {
+	if !s.enabled {
+		return header
+	}
+	return colorDefault + header + colorReset
+}
+
+// yamlStates colors the values of the top level state and goalState fields
+// of a YAML document
+func (s stateColorizer) yamlStates(document string) string {
+	if !s.enabled {
+		return document
+	}
+	lines := strings.Split(document, "\n")
+	for i, line := range lines {
+		for _, field := range []string{"state: ", "goalState: "} {
+			if strings.HasPrefix(line, field) {
+				lines[i] = field + s.state(strings.TrimPrefix(line, field))
+			}
+		}
+	}
+	return strings.Join(lines, "\n")
+}
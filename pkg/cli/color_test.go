@@ -0,0 +1,206 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"testing"
+	"text/tabwriter"
+
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	hostmocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+// colorTaskList is the task list printed today to a pipe
+const colorTaskList = "" +
+	"Instance Name State   Healthy  Start Time Run Time Host   Message     Reason \n" +
+	"0        web  RUNNING DISABLED                     host-1                    \n" +
+	"1        web  FAILED  DISABLED                     host-2 exit code 1        \n"
+
+// ansiEscape matches the ANSI color escape codes
+var ansiEscape = regexp.MustCompile("\033\\[[0-9]+m")
+
+type colorTestSuite struct {
+	suite.Suite
+	mockCtrl      *gomock.Controller
+	mockHost      *hostmocks.MockHostServiceYARPCClient
+	table         *bytes.Buffer
+	output        *fakeOutputter
+	oldTabWriter  *tabwriter.Writer
+	oldOutputter  outputter
+	oldIsTerminal func() bool
+	oldGetenv     func(string) string
+	env           map[string]string
+	client        Client
+}
+
+func (suite *colorTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockHost = hostmocks.NewMockHostServiceYARPCClient(suite.mockCtrl)
+	suite.table = &bytes.Buffer{}
+	suite.output = &fakeOutputter{}
+	suite.oldTabWriter = tabWriter
+	suite.oldOutputter = cliOutPutter
+	suite.oldIsTerminal = colorIsTerminal
+	suite.oldGetenv = colorGetenv
+	tabWriter = tabwriter.NewWriter(suite.table, 0, 0, 1, ' ', 0)
+	cliOutPutter = suite.output
+	suite.setTerminal(true)
+	suite.env = make(map[string]string)
+	colorGetenv = func(key string) string { return suite.env[key] }
+	suite.client = Client{
+		hostClient: suite.mockHost,
+		ctx:        context.Background(),
+	}
+}
+
+func (suite *colorTestSuite) TearDownTest() {
+	tabWriter = suite.oldTabWriter
+	cliOutPutter = suite.oldOutputter
+	colorIsTerminal = suite.oldIsTerminal
+	colorGetenv = suite.oldGetenv
+	suite.mockCtrl.Finish()
+}
+
+func TestColor(t *testing.T) {
+	suite.Run(t, new(colorTestSuite))
+}
+
+// setTerminal sets whether stdout is a terminal
+func (suite *colorTestSuite) setTerminal(terminal bool) {
+	colorIsTerminal = func() bool { return terminal }
+}
+
+func (suite *colorTestSuite) taskListResponse() *task.ListResponse {
+	return &task.ListResponse{
+		Result: &task.ListResponse_Result{
+			Value: map[uint32]*task.TaskInfo{
+				0: {
+					InstanceId: 0,
+					Config:     &task.TaskConfig{Name: "web"},
+					Runtime: &task.RuntimeInfo{
+						State:   task.TaskState_RUNNING,
+						Healthy: task.HealthState_DISABLED,
+						Host:    "host-1",
+					},
+				},
+				1: {
+					InstanceId: 1,
+					Config:     &task.TaskConfig{Name: "web"},
+					Runtime: &task.RuntimeInfo{
+						State:   task.TaskState_FAILED,
+						Healthy: task.HealthState_DISABLED,
+						Host:    "host-2",
+						Message: "exit code 1",
+					},
+				},
+			},
+		},
+	}
+}
+
+// TestColorizerEnabled tests that colors are only enabled for tables
+// printed to a terminal, unless they are disabled
+func (suite *colorTestSuite) TestColorizerEnabled() {
+	tt := []struct {
+		msg      string
+		terminal bool
+		noColor  bool
+		env      string
+		output   string
+		debug    bool
+		enabled  bool
+	}{
+		{msg: "terminal", terminal: true, enabled: true},
+		{msg: "table on a terminal", terminal: true, output: OutputTable, enabled: true},
+		{msg: "pipe", terminal: false},
+		{msg: "--no-color", terminal: true, noColor: true},
+		{msg: "NO_COLOR", terminal: true, env: "1"},
+		{msg: "json", terminal: true, output: OutputJSON},
+		{msg: "csv", terminal: true, output: OutputCSV},
+		{msg: "debug", terminal: true, debug: true},
+	}
+	for _, t := range tt {
+		suite.setTerminal(t.terminal)
+		suite.env[noColorEnv] = t.env
+		c := Client{NoColor: t.noColor, Output: t.output, Debug: t.debug}
+		suite.Equal(t.enabled, c.colorizer().enabled, t.msg)
+	}
+}
+
+// TestStateColors tests the colors of the states
+func (suite *colorTestSuite) TestStateColors() {
+	colors := stateColorizer{enabled: true}
+	suite.Equal("\033[32mRUNNING\033[0m", colors.state("RUNNING"))
+	suite.Equal("\033[31mLOST\033[0m", colors.state("LOST"))
+	suite.Equal("\033[33mKILLING\033[0m", colors.state("KILLING"))
+	suite.Equal("\033[39mKILLED\033[0m", colors.state("KILLED"))
+	suite.Equal("\033[39mState\033[0m", colors.header("State"))
+	suite.Equal("state: \033[33mPENDING\033[0m\ngoalState: "+
+		"\033[32mSUCCEEDED\033[0m\ntaskStats:\n  state: RUNNING\n",
+		colors.yamlStates("state: PENDING\ngoalState: SUCCEEDED\n"+
+			"taskStats:\n  state: RUNNING\n"))
+
+	colors = stateColorizer{}
+	suite.Equal("RUNNING", colors.state("RUNNING"))
+	suite.Equal("State", colors.header("State"))
+	suite.Equal("state: PENDING\n", colors.yamlStates("state: PENDING\n"))
+}
+
+// TestTaskListPiped tests that the task list printed to a pipe is not
+// changed by colors
+func (suite *colorTestSuite) TestTaskListPiped() {
+	suite.setTerminal(false)
+	suite.NoError(suite.client.printTaskList(suite.taskListResponse()))
+	suite.Equal(colorTaskList, suite.table.String())
+}
+
+// TestTaskListColors tests that the states of the task list are colored on
+// a terminal, and that the columns stay aligned
+func (suite *colorTestSuite) TestTaskListColors() {
+	suite.NoError(suite.client.printTaskList(suite.taskListResponse()))
+	suite.Contains(suite.table.String(), "\033[32mRUNNING\033[0m")
+	suite.Contains(suite.table.String(), "\033[31mFAILED\033[0m")
+	suite.Equal(colorTaskList,
+		ansiEscape.ReplaceAllString(suite.table.String(), ""))
+}
+
+// TestHostQueryCSV tests that colors are never printed in CSV output
+func (suite *colorTestSuite) TestHostQueryCSV() {
+	suite.mockHost.EXPECT().
+		QueryHosts(gomock.Any(), gomock.Any()).
+		Return(&host_svc.QueryHostsResponse{
+			HostInfos: []*host.HostInfo{
+				{
+					Hostname: "host-1",
+					Ip:       "10.0.0.1",
+					State:    host.HostState_HOST_STATE_DOWN,
+				},
+			},
+		}, nil)
+
+	suite.client.Output = OutputCSV
+	suite.NoError(suite.client.HostQueryAction("", ""))
+	suite.Equal("hostname,ip,state\nhost-1,10.0.0.1,HOST_STATE_DOWN\n",
+		suite.output.Out)
+	suite.Empty(suite.table.String())
+}
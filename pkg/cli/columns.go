@@ -0,0 +1,324 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/gogo/protobuf/proto"
+)
+
+// columnLabelSeparator separates the key=value pairs of labels in a column
+const columnLabelSeparator = ";"
+
+// columnTable is a response as rows of named columns, which can be selected
+// and ordered with --columns. Each row has a value for each column.
+type columnTable struct {
+	columns []string
+	rows    [][]string
+}
+
+// printFormattedColumns prints a pb message response like printFormatted,
+// calling records to get its rows in the CSV format, or in the table format
+// if columns are selected
+func (c *Client) printFormattedColumns(
+	response proto.Message,
+	records func() (columnTable, error),
+	table func() error) error {
+	format := c.outputFormat()
+	if format != OutputCSV && (format != OutputTable || c.Columns == "") {
+		return c.printFormatted(response, table)
+	}
+	t, err := records()
+	if err != nil {
+		return err
+	}
+	if format == OutputCSV {
+		return printCSV(t, c.Columns)
+	}
+	return printColumnTable(t, c.Columns)
+}
+
+// printColumnTable prints the comma separated columns of the table in their
+// order, all columns if it is empty
+func printColumnTable(t columnTable, columns string) error {
+	indexes, err := selectColumns(t.columns, columns)
+	if err != nil {
+		return err
+	}
+	defer tabWriter.Flush()
+
+	record := make([]string, len(indexes))
+	for i, index := range indexes {
+		record[i] = strings.ToUpper(t.columns[index])
+	}
+	fmt.Fprintln(tabWriter, strings.Join(record, "\t")+"\t")
+	for _, row := range t.rows {
+		for i, index := range indexes {
+			record[i] = row[index]
+		}
+		fmt.Fprintln(tabWriter, strings.Join(record, "\t")+"\t")
+	}
+	return nil
+}
+
+// printCSV prints a header row and the rows of the table as CSV, with the
+// comma separated columns in their order, all columns if it is empty
+func printCSV(t columnTable, columns string) error {
+	indexes, err := selectColumns(t.columns, columns)
+	if err != nil {
+		return err
+	}
+
+	var buffer bytes.Buffer
+	w := csv.NewWriter(&buffer)
+	record := make([]string, len(indexes))
+	for i, index := range indexes {
+		record[i] = t.columns[index]
+	}
+	w.Write(record)
+	for _, row := range t.rows {
+		for i, index := range indexes {
+			record[i] = row[index]
+		}
+		w.Write(record)
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	cliOutPutter.output(buffer.String())
+	return nil
+}
+
+// selectColumns returns the indexes of the comma separated columns,
+// matched case insensitively, or of all columns if none is selected. Unknown
+// columns are an error listing the available ones.
+func selectColumns(available []string, columns string) ([]int, error) {
+	var indexes []int
+	for _, column := range strings.Split(columns, labelSeparator) {
+		column = strings.TrimSpace(column)
+		if column == "" {
+			continue
+		}
+		index := -1
+		for i, name := range available {
+			if strings.EqualFold(name, column) {
+				index = i
+				break
+			}
+		}
+		if index < 0 {
+			return nil, fmt.Errorf("unknown column %s, available columns are %s",
+				column, strings.Join(available, labelSeparator))
+		}
+		indexes = append(indexes, index)
+	}
+	if len(indexes) > 0 {
+		return indexes, nil
+	}
+	for i := range available {
+		indexes = append(indexes, i)
+	}
+	return indexes, nil
+}
+
+// parseSortSpec parses a <field>[:desc] sort spec of one of the fields,
+// matched case insensitively. It returns the field and whether the order is
+// descending, the field is empty if the spec is.
+func parseSortSpec(spec string, fields []string) (string, bool, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" {
+		return "", false, nil
+	}
+	name, order := spec, ""
+	if i := strings.LastIndex(spec, ":"); i >= 0 {
+		name, order = spec[:i], strings.ToLower(spec[i+1:])
+	}
+	if order != "" && order != "asc" && order != "desc" {
+		return "", false, fmt.Errorf("invalid sort order %s of %s, use asc or desc",
+			order, spec)
+	}
+	for _, field := range fields {
+		if strings.EqualFold(field, name) {
+			return field, order == "desc", nil
+		}
+	}
+	return "", false, fmt.Errorf("unknown sort field %s, valid fields are %s",
+		name, strings.Join(fields, labelSeparator))
+}
+
+// jobSortFields are the fields job query results can be sorted by
+var jobSortFields = []string{
+	"creation_time", "completion_time", "state", "instance_count", "name",
+}
+
+// sortJobSummaries sorts job summaries by a <field>[:desc] sort spec of one
+// of jobSortFields, keeping the order of jobs with equal keys
+func sortJobSummaries(jobs []*job.JobSummary, spec string) error {
+	field, desc, err := parseSortSpec(spec, jobSortFields)
+	if err != nil || field == "" {
+		return err
+	}
+
+	var less func(a, b *job.JobSummary) bool
+	switch field {
+	case "creation_time":
+		less = func(a, b *job.JobSummary) bool {
+			return parseTimestamp(a.GetRuntime().GetCreationTime()).Before(
+				parseTimestamp(b.GetRuntime().GetCreationTime()))
+		}
+	case "completion_time":
+		less = func(a, b *job.JobSummary) bool {
+			return parseTimestamp(a.GetRuntime().GetCompletionTime()).Before(
+				parseTimestamp(b.GetRuntime().GetCompletionTime()))
+		}
+	case "state":
+		less = func(a, b *job.JobSummary) bool {
+			return a.GetRuntime().GetState() < b.GetRuntime().GetState()
+		}
+	case "instance_count":
+		less = func(a, b *job.JobSummary) bool {
+			return a.GetInstanceCount() < b.GetInstanceCount()
+		}
+	case "name":
+		less = func(a, b *job.JobSummary) bool {
+			return a.GetName() < b.GetName()
+		}
+	}
+	sort.SliceStable(jobs, func(i, j int) bool {
+		if desc {
+			return less(jobs[j], jobs[i])
+		}
+		return less(jobs[i], jobs[j])
+	})
+	return nil
+}
+
+// parseTimestamp parses a RFC3339Nano timestamp, unset timestamps are zero
+func parseTimestamp(timestamp string) time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, timestamp)
+	return t
+}
+
+// columnTime formats a RFC3339Nano timestamp as RFC3339, regardless of the
+// formatting of tables without selected columns. It is empty if the
+// timestamp is not set.
+func columnTime(timestamp string) string {
+	t := parseTimestamp(timestamp)
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// columnLabels flattens labels to k=v;k=v
+func columnLabels(labels []*peloton.Label) string {
+	pairs := make([]string, 0, len(labels))
+	for _, l := range labels {
+		pairs = append(pairs, l.GetKey()+keyValSeparator+l.GetValue())
+	}
+	return strings.Join(pairs, columnLabelSeparator)
+}
+
+// jobQueryColumns returns the job summaries of a job query response as rows
+func jobQueryColumns(r *job.QueryResponse) (columnTable, error) {
+	if r.GetError() != nil {
+		return columnTable{}, fmt.Errorf("job query failed: %v", r.GetError().String())
+	}
+	t := columnTable{
+		columns: []string{"id", "name", "owner", "state", "creation_time",
+			"completion_time", "total", "running", "succeeded", "failed",
+			"killed", "labels"},
+	}
+	for _, j := range r.GetResults() {
+		stats := j.GetRuntime().GetTaskStats()
+		t.rows = append(t.rows, []string{
+			j.GetId().GetValue(),
+			j.GetName(),
+			j.GetOwningTeam(),
+			j.GetRuntime().GetState().String(),
+			columnTime(j.GetRuntime().GetCreationTime()),
+			columnTime(j.GetRuntime().GetCompletionTime()),
+			strconv.FormatUint(uint64(j.GetInstanceCount()), 10),
+			strconv.FormatUint(uint64(stats["RUNNING"]), 10),
+			strconv.FormatUint(uint64(stats["SUCCEEDED"]), 10),
+			strconv.FormatUint(uint64(stats["FAILED"]), 10),
+			strconv.FormatUint(uint64(stats["KILLED"]), 10),
+			columnLabels(j.GetLabels()),
+		})
+	}
+	return t, nil
+}
+
+// taskListColumns returns the tasks of a task list response as rows, sorted
+// by instance
+func taskListColumns(r *task.ListResponse) (columnTable, error) {
+	if r.GetNotFound() != nil {
+		return columnTable{}, fmt.Errorf("job %s was not found: %s",
+			r.GetNotFound().GetId().GetValue(), r.GetNotFound().GetMessage())
+	}
+	t := columnTable{
+		columns: []string{"instance", "name", "state", "healthy", "start_time",
+			"completion_time", "host", "message", "reason", "labels"},
+	}
+	tasks := make(sortedTaskInfoList, 0, len(r.GetResult().GetValue()))
+	for _, k := range r.GetResult().GetValue() {
+		tasks = append(tasks, k)
+	}
+	sort.Sort(tasks)
+	for _, k := range tasks {
+		runtime := k.GetRuntime()
+		t.rows = append(t.rows, []string{
+			strconv.FormatUint(uint64(k.GetInstanceId()), 10),
+			k.GetConfig().GetName(),
+			runtime.GetState().String(),
+			runtime.GetHealthy().String(),
+			columnTime(runtime.GetStartTime()),
+			columnTime(runtime.GetCompletionTime()),
+			runtime.GetHost(),
+			runtime.GetMessage(),
+			runtime.GetReason(),
+			columnLabels(k.GetConfig().GetLabels()),
+		})
+	}
+	return t, nil
+}
+
+// hostQueryColumns returns the hosts of a host query response as rows
+func hostQueryColumns(r *host_svc.QueryHostsResponse) (columnTable, error) {
+	t := columnTable{
+		columns: []string{"hostname", "ip", "state"},
+	}
+	for _, h := range r.GetHostInfos() {
+		t.rows = append(t.rows, []string{
+			h.GetHostname(),
+			h.GetIp(),
+			h.GetState().String(),
+		})
+	}
+	return t, nil
+}
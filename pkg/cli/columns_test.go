@@ -0,0 +1,274 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"text/tabwriter"
+
+	pberr "github.com/uber/peloton/.gen/peloton/api/v0/errors"
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	hostmocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+type columnsTestSuite struct {
+	suite.Suite
+	mockCtrl     *gomock.Controller
+	mockHost     *hostmocks.MockHostServiceYARPCClient
+	output       *fakeOutputter
+	oldOutputter outputter
+	client       Client
+}
+
+func (suite *columnsTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockHost = hostmocks.NewMockHostServiceYARPCClient(suite.mockCtrl)
+	suite.output = &fakeOutputter{}
+	suite.oldOutputter = cliOutPutter
+	cliOutPutter = suite.output
+	suite.client = Client{
+		hostClient: suite.mockHost,
+		ctx:        context.Background(),
+		Output:     OutputCSV,
+	}
+}
+
+func (suite *columnsTestSuite) TearDownTest() {
+	cliOutPutter = suite.oldOutputter
+	suite.mockCtrl.Finish()
+}
+
+func TestColumns(t *testing.T) {
+	suite.Run(t, new(columnsTestSuite))
+}
+
+func (suite *columnsTestSuite) jobQueryResponse() *job.QueryResponse {
+	return &job.QueryResponse{
+		Results: []*job.JobSummary{
+			{
+				Id:            &peloton.JobID{Value: testJobID},
+				Name:          "web, frontend",
+				OwningTeam:    "team \"6\"",
+				InstanceCount: 3,
+				Labels: []*peloton.Label{
+					{Key: "env", Value: "prod"},
+					{Key: "zone", Value: "a,b"},
+				},
+				Runtime: &job.RuntimeInfo{
+					State:        job.JobState_RUNNING,
+					CreationTime: "2019-01-02T03:04:05.123456789Z",
+					TaskStats:    map[string]uint32{"RUNNING": 2, "FAILED": 1},
+				},
+			},
+		},
+	}
+}
+
+// TestJobQueryCSV tests that fields containing commas and quotes are quoted
+// and timestamps are printed as RFC3339
+func (suite *columnsTestSuite) TestJobQueryCSV() {
+	t, err := jobQueryColumns(suite.jobQueryResponse())
+	suite.NoError(err)
+	suite.NoError(printCSV(t, ""))
+	suite.Equal(
+		"id,name,owner,state,creation_time,completion_time,total,running,"+
+			"succeeded,failed,killed,labels\n"+
+			testJobID+",\"web, frontend\",\"team \"\"6\"\"\",RUNNING,"+
+			"2019-01-02T03:04:05Z,,3,2,0,1,0,\"env=prod;zone=a,b\"\n",
+		suite.output.Out)
+}
+
+// TestJobQueryCSVColumns tests selecting and ordering the columns
+func (suite *columnsTestSuite) TestJobQueryCSVColumns() {
+	t, err := jobQueryColumns(suite.jobQueryResponse())
+	suite.NoError(err)
+	suite.NoError(printCSV(t, "labels, ID,state"))
+	suite.Equal(
+		"labels,id,state\n"+
+			"\"env=prod;zone=a,b\","+testJobID+",RUNNING\n",
+		suite.output.Out)
+
+	suite.EqualError(printCSV(t, "id,host"),
+		"unknown column host, available columns are id,name,owner,state,"+
+			"creation_time,completion_time,total,running,succeeded,failed,"+
+			"killed,labels")
+}
+
+// TestTaskListCSV tests that tasks are printed sorted by instance
+func (suite *columnsTestSuite) TestTaskListCSV() {
+	t, err := taskListColumns(&task.ListResponse{
+		Result: &task.ListResponse_Result{
+			Value: map[uint32]*task.TaskInfo{
+				1: {
+					InstanceId: 1,
+					Config:     &task.TaskConfig{Name: "task-1"},
+					Runtime: &task.RuntimeInfo{
+						State:   task.TaskState_FAILED,
+						Message: "exit code 1, see logs",
+					},
+				},
+				0: {
+					InstanceId: 0,
+					Config:     &task.TaskConfig{Name: "task-0"},
+					Runtime: &task.RuntimeInfo{
+						State:     task.TaskState_RUNNING,
+						StartTime: "2019-01-02T03:04:05.5Z",
+						Host:      "host-1",
+					},
+				},
+			},
+		},
+	})
+	suite.NoError(err)
+	suite.NoError(printCSV(t, "instance,state,start_time,host,message"))
+	suite.Equal(
+		"instance,state,start_time,host,message\n"+
+			"0,RUNNING,2019-01-02T03:04:05Z,host-1,\n"+
+			"1,FAILED,,,\"exit code 1, see logs\"\n",
+		suite.output.Out)
+
+	_, err = taskListColumns(&task.ListResponse{
+		NotFound: &pberr.JobNotFound{
+			Id:      &peloton.JobID{Value: testJobID},
+			Message: "not found",
+		},
+	})
+	suite.Error(err)
+}
+
+// TestHostQueryActionCSV tests printing the hosts of a host query as CSV
+func (suite *columnsTestSuite) TestHostQueryActionCSV() {
+	suite.mockHost.EXPECT().
+		QueryHosts(gomock.Any(), gomock.Any()).
+		Return(&host_svc.QueryHostsResponse{
+			HostInfos: []*host.HostInfo{
+				{
+					Hostname: "host-1",
+					Ip:       "10.0.0.1",
+					State:    host.HostState_HOST_STATE_UP,
+				},
+			},
+		}, nil)
+
+	suite.client.Columns = "hostname,state"
+	suite.NoError(suite.client.HostQueryAction("", ""))
+	suite.Equal("hostname,state\nhost-1,HOST_STATE_UP\n", suite.output.Out)
+}
+
+// TestCSVUnsupported tests that commands without CSV rows reject it
+func (suite *columnsTestSuite) TestCSVUnsupported() {
+	err := suite.client.printFormatted(&job.GetResponse{}, func() error {
+		return nil
+	})
+	suite.Error(err)
+}
+
+// TestPrintColumnTable tests printing the selected columns as a table
+func (suite *columnsTestSuite) TestPrintColumnTable() {
+	var buffer bytes.Buffer
+	oldTabWriter := tabWriter
+	tabWriter = tabwriter.NewWriter(&buffer, 0, 0, 1, ' ', 0)
+	defer func() { tabWriter = oldTabWriter }()
+
+	t, err := jobQueryColumns(suite.jobQueryResponse())
+	suite.NoError(err)
+	suite.NoError(printColumnTable(t, "name,state"))
+	suite.Equal("NAME          STATE   \nweb, frontend RUNNING \n", buffer.String())
+
+	suite.Error(printColumnTable(t, "hostname"))
+}
+
+// sortedJobNames returns the names of jobs sorted by the sort spec
+func (suite *columnsTestSuite) sortedJobNames(spec string) []string {
+	jobs := []*job.JobSummary{
+		{
+			Name:          "b",
+			InstanceCount: 2,
+			Runtime: &job.RuntimeInfo{
+				State:          job.JobState_SUCCEEDED,
+				CreationTime:   "2019-01-02T00:00:00Z",
+				CompletionTime: "2019-01-05T00:00:00Z",
+			},
+		},
+		{
+			Name:          "c",
+			InstanceCount: 1,
+			Runtime: &job.RuntimeInfo{
+				State:        job.JobState_RUNNING,
+				CreationTime: "2019-01-03T00:00:00.5Z",
+			},
+		},
+		{
+			Name:          "a",
+			InstanceCount: 2,
+			Runtime: &job.RuntimeInfo{
+				State:          job.JobState_SUCCEEDED,
+				CreationTime:   "2019-01-01T00:00:00Z",
+				CompletionTime: "2019-01-04T00:00:00Z",
+			},
+		},
+	}
+	suite.NoError(sortJobSummaries(jobs, spec), spec)
+	var names []string
+	for _, j := range jobs {
+		names = append(names, j.GetName())
+	}
+	return names
+}
+
+// TestSortJobSummaries tests sorting jobs by each field in both orders,
+// keeping the order of jobs with equal keys
+func (suite *columnsTestSuite) TestSortJobSummaries() {
+	tt := []struct {
+		spec  string
+		names []string
+	}{
+		{"", []string{"b", "c", "a"}},
+		{"creation_time", []string{"a", "b", "c"}},
+		{"creation_time:desc", []string{"c", "b", "a"}},
+		// jobs without completion time sort first
+		{"completion_time", []string{"c", "a", "b"}},
+		{"completion_time:desc", []string{"b", "a", "c"}},
+		{"state", []string{"c", "b", "a"}},
+		{"state:desc", []string{"b", "a", "c"}},
+		{"instance_count", []string{"c", "b", "a"}},
+		{"instance_count:desc", []string{"b", "a", "c"}},
+		{"name", []string{"a", "b", "c"}},
+		{"Name:DESC", []string{"c", "b", "a"}},
+		{"name:asc", []string{"a", "b", "c"}},
+	}
+	for _, test := range tt {
+		suite.Equal(test.names, suite.sortedJobNames(test.spec), test.spec)
+	}
+}
+
+// TestSortJobSummariesErrors tests that unknown fields and orders are errors
+// listing the valid ones
+func (suite *columnsTestSuite) TestSortJobSummariesErrors() {
+	suite.EqualError(sortJobSummaries(nil, "owner"),
+		"unknown sort field owner, valid fields are creation_time,"+
+			"completion_time,state,instance_count,name")
+	suite.EqualError(sortJobSummaries(nil, "name:up"),
+		"invalid sort order up of name:up, use asc or desc")
+}
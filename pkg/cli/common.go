@@ -15,12 +15,20 @@
 package cli
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"math"
+	"os"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
 
 	"github.com/uber/peloton/pkg/common/stringset"
 )
@@ -40,21 +48,330 @@ func (c *Client) LookupResourcePoolID(resourcePoolPath string) (*peloton.Resourc
 	return response.Id, nil
 }
 
-// ExtractHostnames extracts a list of hosts from a comma-separated list
+// respoolLookupNegativeTTL is how long a failed resource pool lookup is
+// cached for
+const respoolLookupNegativeTTL = 5 * time.Second
+
+// respoolLookup is a cached, possibly in flight, resource pool lookup
+type respoolLookup struct {
+	// done is closed once the lookup completed
+	done chan struct{}
+	id   *peloton.ResourcePoolID
+	err  error
+	// expiry is when a failed lookup has to be retried
+	expiry time.Time
+}
+
+// LookupResourcePoolIDCached returns the resource pool ID for a given path
+// like LookupResourcePoolID, but makes at most one lookup per path for the
+// lifetime of the client. Failed lookups are cached for a short time only.
+// It is safe to call from multiple goroutines.
+func (c *Client) LookupResourcePoolIDCached(
+	resourcePoolPath string) (*peloton.ResourcePoolID, error) {
+	c.respoolLookupsLock.Lock()
+	if c.respoolLookups == nil {
+		c.respoolLookups = make(map[string]*respoolLookup)
+	}
+	lookup, ok := c.respoolLookups[resourcePoolPath]
+	if ok && !lookup.expired() {
+		c.respoolLookupsLock.Unlock()
+		<-lookup.done
+		return lookup.id, lookup.err
+	}
+	lookup = &respoolLookup{done: make(chan struct{})}
+	c.respoolLookups[resourcePoolPath] = lookup
+	c.respoolLookupsLock.Unlock()
+
+	lookup.id, lookup.err = c.LookupResourcePoolID(resourcePoolPath)
+	if lookup.err != nil {
+		lookup.expiry = time.Now().Add(respoolLookupNegativeTTL)
+	}
+	close(lookup.done)
+	return lookup.id, lookup.err
+}
+
+// expired returns whether the lookup completed with an error which is
+// no longer cached
+func (l *respoolLookup) expired() bool {
+	select {
+	case <-l.done:
+		return l.err != nil && time.Now().After(l.expiry)
+	default:
+		return false
+	}
+}
+
+const (
+	// hostListFilePrefix marks a hosts argument as a path to a host list file
+	hostListFilePrefix = "@"
+	// hostListStdin is the host list file name which reads from stdin
+	hostListStdin = "-"
+	// hostListComment starts a comment line in a host list file
+	hostListComment = "#"
+
+	// DefaultMaxHosts is the maximum number of hosts of a host list, unless
+	// overridden on the Client
+	DefaultMaxHosts = 10000
+)
+
+// maxHosts returns the maximum number of hosts of a host list of the client
+func (c *Client) maxHosts() int {
+	if c.MaxHosts > 0 {
+		return c.MaxHosts
+	}
+	return DefaultMaxHosts
+}
+
+// used for testing
+var hostListStdinReader io.Reader = os.Stdin
+
+// ExtractHostnames extracts a list of hosts from a comma-separated list.
+// If hosts starts with "@" the rest is read as the path of a file holding
+// the host list, with "@-" reading it from stdin. Host ranges such as
+// compute[0001-0250].dc1 are expanded. Empty hosts, e.g. after a trailing
+// separator, are skipped if SkipEmptyHosts is set, and host lists of more
+// than MaxHosts hosts are rejected. Unless SkipHostValidation is set, hosts
+// must be RFC 1123 hostnames or IP addresses, with IPv6 addresses bracketed
+// like [2001:db8::1]. All the problems of the list are reported at once as
+// ValidationErrors.
 func (c *Client) ExtractHostnames(hosts string, hostSeparator string) ([]string, error) {
-	hostSet := stringset.New()
-	for _, host := range strings.Split(hosts, hostSeparator) {
+	if strings.HasPrefix(hosts, hostListFilePrefix) {
+		return c.extractHostnamesFromFile(
+			strings.TrimPrefix(hosts, hostListFilePrefix),
+			hostSeparator,
+		)
+	}
+
+	l := newHostList("host list")
+	for i, host := range splitHosts(hosts, hostSeparator) {
+		// the position of the host in the list, for error messages
+		position := fmt.Sprintf("token %d", i+1)
 		// removing leading and trailing white spaces
 		host = strings.TrimSpace(host)
 		if host == "" {
-			return nil, fmt.Errorf("Host cannot be empty")
+			if !c.SkipEmptyHosts {
+				l.errs.Add(position, "host cannot be empty")
+			}
+			continue
+		}
+		if !c.addHosts(l, host, position) {
+			break
+		}
+	}
+	if err := l.errs.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+	if l.count == 0 {
+		return nil, fmt.Errorf("Host list is empty")
+	}
+	return stringset.ToSortedSlice(l.hosts), nil
+}
+
+// hostList accumulates the hosts of a host list and its problems
+type hostList struct {
+	hosts stringset.StringSet
+	count int
+	errs  *ValidationErrors
+}
+
+// newHostList returns an empty host list of the input
+func newHostList(input string) *hostList {
+	return &hostList{
+		hosts: stringset.New(),
+		errs:  newValidationErrors(input),
+	}
+}
+
+// addHosts adds the hosts of a host list entry at position to l, recording
+// the problems of the entry. It returns false once l holds more than
+// MaxHosts hosts, as the rest of the list need not be parsed.
+func (c *Client) addHosts(l *hostList, host string, position string) bool {
+	expanded, err := c.expandHost(host)
+	if err != nil {
+		l.errs.Add(position, "%v", err)
+		return true
+	}
+	if err := c.validateHostnames(expanded); err != nil {
+		l.errs.Add(position, "%v", err)
+		return true
+	}
+	for _, host := range expanded {
+		if l.hosts.Contains(host) {
+			l.errs.Add(position, "duplicate entry for host %s", host)
+			continue
+		}
+		if l.count == c.maxHosts() {
+			l.errs.Add(position, "more than %d hosts", c.maxHosts())
+			return false
+		}
+		l.hosts.Add(host)
+		l.count++
+	}
+	return true
+}
+
+// extractHostnamesFromFile reads a host list from the file at path, or from
+// stdin if path is "-". The file holds one host per line or hosts separated
+// by hostSeparator; blank lines and lines starting with "#" are skipped.
+func (c *Client) extractHostnamesFromFile(path string, hostSeparator string) ([]string, error) {
+	var reader io.Reader
+	if path == hostListStdin {
+		reader = hostListStdinReader
+	} else {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("Unable to open host list: %v", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	l := newHostList("host list " + path)
+	scanner := bufio.NewScanner(reader)
+scan:
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, hostListComment) {
+			continue
+		}
+		position := fmt.Sprintf("line %d", lineNumber)
+		for _, host := range splitHosts(line, hostSeparator) {
+			host = strings.TrimSpace(host)
+			if host == "" {
+				continue
+			}
+			if !c.addHosts(l, host, position) {
+				break scan
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Unable to read host list: %v", err)
+	}
+	if err := l.errs.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+	if l.count == 0 {
+		return nil, fmt.Errorf("Host list %s is empty", path)
+	}
+	return stringset.ToSortedSlice(l.hosts), nil
+}
+
+const (
+	instanceSeparator      = ","
+	instanceRangeSeparator = "-"
+)
+
+// ExtractInstanceRanges parses a comma separated list of instances and
+// inclusive instance ranges, e.g. "0-9,15,20-25", into sorted instance
+// ranges for the API. Reversed and overlapping ranges are rejected. If
+// instanceCount is not zero the ranges are clamped to it and ranges which
+// start past it are rejected. All the problems of the list are reported at
+// once as ValidationErrors.
+func (c *Client) ExtractInstanceRanges(
+	instances string,
+	instanceCount uint32) ([]*task.InstanceRange, error) {
+	errs := newValidationErrors("instance ranges")
+	var ranges []*task.InstanceRange
+	for i, item := range strings.Split(instances, instanceSeparator) {
+		position := fmt.Sprintf("token %d", i+1)
+		item = strings.TrimSpace(item)
+		if item == "" {
+			errs.Add(position, "instance cannot be empty")
+			continue
+		}
+		bounds := strings.Split(item, instanceRangeSeparator)
+		if len(bounds) > 2 {
+			errs.Add(position, "invalid instance range %s", item)
+			continue
+		}
+		from, err := strconv.ParseUint(strings.TrimSpace(bounds[0]), 10, 32)
+		if err != nil {
+			errs.Add(position, "invalid instance range %s", item)
+			continue
+		}
+		to, err := strconv.ParseUint(
+			strings.TrimSpace(bounds[len(bounds)-1]), 10, 32)
+		if err != nil {
+			errs.Add(position, "invalid instance range %s", item)
+			continue
 		}
-		if hostSet.Contains(host) {
-			return nil, fmt.Errorf("Invalid input. Duplicate entry for host %s found", host)
+		if from > to {
+			errs.Add(position, "instance range %s is reversed", item)
+			continue
+		}
+		// the exclusive upper bound of the API range must fit in uint32
+		if to >= math.MaxUint32 {
+			errs.Add(position, "instance range %s: instance %d is too large",
+				item, to)
+			continue
+		}
+		if instanceCount > 0 {
+			if from >= uint64(instanceCount) {
+				errs.Add(position, "instance range %s: job has %d instances",
+					item, instanceCount)
+				continue
+			}
+			if to >= uint64(instanceCount) {
+				to = uint64(instanceCount) - 1
+			}
+		}
+		// instance ranges of the API exclude the upper bound
+		ranges = append(ranges, &task.InstanceRange{
+			From: uint32(from),
+			To:   uint32(to) + 1,
+		})
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].GetFrom() < ranges[j].GetFrom()
+	})
+	// each range is checked against the preceding one reaching furthest
+	furthest := 0
+	for i := 1; i < len(ranges); i++ {
+		if ranges[i].GetFrom() < ranges[furthest].GetTo() {
+			errs.Add("", "instance ranges %d-%d and %d-%d overlap",
+				ranges[furthest].GetFrom(), ranges[furthest].GetTo()-1,
+				ranges[i].GetFrom(), ranges[i].GetTo()-1)
+		}
+		if ranges[i].GetTo() > ranges[furthest].GetTo() {
+			furthest = i
+		}
+	}
+	if err := errs.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+	return ranges, nil
+}
+
+// resolveInstanceRanges returns the instance ranges parsed from instances,
+// clamped to the instance count of the job, or ranges if instances is empty.
+func (c *Client) resolveInstanceRanges(
+	jobID string,
+	ranges []*task.InstanceRange,
+	instances string) ([]*task.InstanceRange, error) {
+	if instances == "" {
+		return ranges, nil
+	}
+
+	response, err := c.jobClient.Get(c.ctx, &job.GetRequest{
+		Id: &peloton.JobID{Value: jobID},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return c.ExtractInstanceRanges(
+		instances,
+		response.GetJobInfo().GetConfig().GetInstanceCount(),
+	)
+}
+
+// instanceInRanges returns whether the instance is in any of the ranges
+func instanceInRanges(instanceID uint32, ranges []*task.InstanceRange) bool {
+	for _, r := range ranges {
+		if instanceID >= r.GetFrom() && instanceID < r.GetTo() {
+			return true
 		}
-		hostSet.Add(host)
 	}
-	hostSlice := hostSet.ToSlice()
-	sort.Strings(hostSlice)
-	return hostSlice, nil
+	return false
 }
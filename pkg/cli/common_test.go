@@ -17,8 +17,14 @@ package cli
 import (
 	"context"
 	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/pborman/uuid"
@@ -27,6 +33,7 @@ import (
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
 	respool_mocks "github.com/uber/peloton/.gen/peloton/api/v0/respool/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
 )
 
 type commonTestSuite struct {
@@ -106,6 +113,64 @@ func (suite *commonTestSuite) TestClient_LookupResourcePoolID() {
 	}
 }
 
+func (suite *commonTestSuite) TestClient_LookupResourcePoolIDCached() {
+	c := Client{
+		resClient: suite.mockRespool,
+		ctx:       suite.ctx,
+	}
+
+	paths := map[string]string{
+		"/a":   uuid.New(),
+		"/a/b": uuid.New(),
+	}
+	for path, id := range paths {
+		suite.mockRespool.EXPECT().
+			LookupResourcePoolID(suite.ctx, &respool.LookupRequest{
+				Path: &respool.ResourcePoolPath{Value: path},
+			}).
+			Return(&respool.LookupResponse{
+				Id: &peloton.ResourcePoolID{Value: id},
+			}, nil).
+			Times(1)
+	}
+
+	// concurrent lookups make one RPC per distinct path
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		for path, id := range paths {
+			wg.Add(1)
+			go func(path, id string) {
+				defer wg.Done()
+				respoolID, err := c.LookupResourcePoolIDCached(path)
+				suite.NoError(err)
+				suite.Equal(id, respoolID.GetValue())
+			}(path, id)
+		}
+	}
+	wg.Wait()
+
+	// failed lookups are cached until they expire
+	missing := &respool.LookupRequest{
+		Path: &respool.ResourcePoolPath{Value: "/missing"},
+	}
+	suite.mockRespool.EXPECT().
+		LookupResourcePoolID(suite.ctx, missing).
+		Return(nil, errors.New("respool not found")).
+		Times(1)
+	for i := 0; i < 3; i++ {
+		_, err := c.LookupResourcePoolIDCached("/missing")
+		suite.EqualError(err, "respool not found")
+	}
+
+	c.respoolLookups["/missing"].expiry = time.Now().Add(-time.Second)
+	suite.mockRespool.EXPECT().
+		LookupResourcePoolID(suite.ctx, missing).
+		Return(nil, errors.New("respool not found")).
+		Times(1)
+	_, err := c.LookupResourcePoolIDCached("/missing")
+	suite.EqualError(err, "respool not found")
+}
+
 func (suite *commonTestSuite) TestClient_ExtractHostnames() {
 	c := Client{
 		Debug:      false,
@@ -125,12 +190,13 @@ func (suite *commonTestSuite) TestClient_ExtractHostnames() {
 	// empty input
 	_, err = c.ExtractHostnames("", ",")
 	suite.Error(err)
-	suite.Equal(errors.New("Host cannot be empty"), err)
+	suite.EqualError(err, "Invalid host list: token 1: host cannot be empty")
 
 	// duplicate input
 	_, err = c.ExtractHostnames("a,a", ",")
 	suite.Error(err)
-	suite.Equal(errors.New("Invalid input. Duplicate entry for host a found"), err)
+	suite.EqualError(err,
+		"Invalid host list: token 2: duplicate entry for host a")
 
 	// input should be sorted
 	hosts, err = c.ExtractHostnames("b, c,a ", ",")
@@ -141,6 +207,303 @@ func (suite *commonTestSuite) TestClient_ExtractHostnames() {
 	suite.Equal("c", hosts[2])
 }
 
+func (suite *commonTestSuite) TestClient_ExtractHostnamesEmptyHosts() {
+	c := Client{}
+
+	// empty hosts are rejected with their position
+	_, err := c.ExtractHostnames("a,b,", ",")
+	suite.EqualError(err, "Invalid host list: token 3: host cannot be empty")
+	_, err = c.ExtractHostnames("a,, b", ",")
+	suite.EqualError(err, "Invalid host list: token 2: host cannot be empty")
+
+	// or skipped
+	c.SkipEmptyHosts = true
+	hosts, err := c.ExtractHostnames("a,b,", ",")
+	suite.NoError(err)
+	suite.Equal([]string{"a", "b"}, hosts)
+	hosts, err = c.ExtractHostnames(",a,, ,b", ",")
+	suite.NoError(err)
+	suite.Equal([]string{"a", "b"}, hosts)
+
+	// unless there is no host at all
+	_, err = c.ExtractHostnames(", ,", ",")
+	suite.EqualError(err, "Host list is empty")
+	_, err = c.ExtractHostnames("", ",")
+	suite.EqualError(err, "Host list is empty")
+}
+
+func (suite *commonTestSuite) TestClient_ExtractHostnamesMaxHosts() {
+	c := Client{MaxHosts: 3}
+
+	hosts, err := c.ExtractHostnames("a,b,c", ",")
+	suite.NoError(err)
+	suite.Equal([]string{"a", "b", "c"}, hosts)
+
+	_, err = c.ExtractHostnames("a,b,c,d", ",")
+	suite.EqualError(err, "Invalid host list: token 4: more than 3 hosts")
+
+	// expanded ranges count
+	_, err = c.ExtractHostnames("a,host[1-3]", ",")
+	suite.EqualError(err, "Invalid host list: token 2: more than 3 hosts")
+
+	// host list files are limited too
+	dir, err := ioutil.TempDir("", "hosts")
+	suite.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hosts")
+	suite.NoError(ioutil.WriteFile(path, []byte("a\nb\nc,d\n"), 0644))
+	_, err = c.ExtractHostnames("@"+path, ",")
+	suite.EqualError(err, "Invalid host list "+path+": line 3: more than 3 hosts")
+}
+
+func (suite *commonTestSuite) TestClient_ExtractHostnamesValidation() {
+	c := Client{}
+
+	hosts, err := c.ExtractHostnames(
+		"compute1.dc1.example.com., 10.0.0.1,[2001:db8::1],compute[1-2]", ",")
+	suite.NoError(err)
+	suite.Equal([]string{
+		"10.0.0.1",
+		"2001:db8::1",
+		"compute1",
+		"compute1.dc1.example.com.",
+		"compute2",
+	}, hosts)
+
+	// all invalid hosts are reported at once
+	_, err = c.ExtractHostnames(
+		"http://compute1,compute2,compute 3,bad_[1-2],10.0.0.256", ",")
+	suite.EqualError(err, "Invalid host list, 4 errors:\n"+
+		"  token 1: \"http://compute1\": invalid character ':'\n"+
+		"  token 3: \"compute 3\": invalid character ' '\n"+
+		"  token 4: \"bad_1\": invalid character '_'\n"+
+		"  token 5: \"10.0.0.256\": invalid IP address")
+
+	// along with the other problems of the list
+	_, err = c.ExtractHostnames("a,,a,b c", ",")
+	suite.EqualError(err, "Invalid host list, 3 errors:\n"+
+		"  token 2: host cannot be empty\n"+
+		"  token 3: duplicate entry for host a\n"+
+		"  token 4: \"b c\": invalid character ' '")
+
+	// and on their lines in host list files
+	dir, err := ioutil.TempDir("", "hosts")
+	suite.NoError(err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "hosts")
+	suite.NoError(ioutil.WriteFile(path, []byte("a\nb c\nd,e/f\n"), 0644))
+	_, err = c.ExtractHostnames("@"+path, ",")
+	suite.EqualError(err, "Invalid host list "+path+", 2 errors:\n"+
+		"  line 2: \"b c\": invalid character ' '\n"+
+		"  line 3: \"e/f\": invalid character '/'")
+
+	// unless validation is skipped
+	c.SkipHostValidation = true
+	hosts, err = c.ExtractHostnames("compute 3,bad_[1-2]", ",")
+	suite.NoError(err)
+	suite.Equal([]string{"bad_1", "bad_2", "compute 3"}, hosts)
+	hosts, err = c.ExtractHostnames("@"+path, ",")
+	suite.NoError(err)
+	suite.Equal([]string{"a", "b c", "d", "e/f"}, hosts)
+}
+
+func (suite *commonTestSuite) TestClient_ExtractHostnamesWithRanges() {
+	c := Client{
+		Debug:      false,
+		resClient:  suite.mockRespool,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	// ranges are expanded, deduplicated and sorted with plain hosts
+	hosts, err := c.ExtractHostnames("host[09-11].dc1, a,host[1,3].dc2", ",")
+	suite.NoError(err)
+	suite.Equal([]string{
+		"a",
+		"host09.dc1",
+		"host1.dc2",
+		"host10.dc1",
+		"host11.dc1",
+		"host3.dc2",
+	}, hosts)
+
+	// expanded hosts are checked for duplicates
+	_, err = c.ExtractHostnames("host[1-3],host2", ",")
+	suite.EqualError(err,
+		"Invalid host list: token 2: duplicate entry for host host2")
+
+	// the expansion cap is configurable
+	c.MaxHostRangeExpansion = 2
+	_, err = c.ExtractHostnames("host[1-3]", ",")
+	suite.EqualError(err,
+		`Invalid host list: token 1: Invalid host range host[1-3]: `+
+			`range "1-3" expands to more than 2 hosts`)
+}
+
+func (suite *commonTestSuite) TestClient_ExtractHostnamesFromFile() {
+	c := Client{
+		Debug:      false,
+		resClient:  suite.mockRespool,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	dir, err := ioutil.TempDir("", "hosts")
+	suite.NoError(err)
+	defer os.RemoveAll(dir)
+
+	writeHostList := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		suite.NoError(ioutil.WriteFile(path, []byte(content), 0644))
+		return path
+	}
+
+	// one host per line, with comments and blank lines skipped
+	path := writeHostList("hosts", "# drained hosts\nc\n\n  a \n# b\nb\n")
+	hosts, err := c.ExtractHostnames("@"+path, ",")
+	suite.NoError(err)
+	suite.Equal([]string{"a", "b", "c"}, hosts)
+
+	// mixed newlines and separators
+	path = writeHostList("mixed", "d, c\na,b,\ne\n")
+	hosts, err = c.ExtractHostnames("@"+path, ",")
+	suite.NoError(err)
+	suite.Equal([]string{"a", "b", "c", "d", "e"}, hosts)
+
+	// duplicates report the line number
+	path = writeHostList("duplicate", "a\n# comment\nb, a\n")
+	_, err = c.ExtractHostnames("@"+path, ",")
+	suite.EqualError(err,
+		"Invalid host list "+path+": line 3: duplicate entry for host a")
+
+	// a file with only comments is empty
+	path = writeHostList("empty", "# nothing\n\n")
+	_, err = c.ExtractHostnames("@"+path, ",")
+	suite.EqualError(err, "Host list "+path+" is empty")
+
+	// missing file
+	_, err = c.ExtractHostnames("@"+filepath.Join(dir, "missing"), ",")
+	suite.Error(err)
+
+	// stdin
+	defer func() { hostListStdinReader = os.Stdin }()
+	hostListStdinReader = strings.NewReader("b\na\n# c\n")
+	hosts, err = c.ExtractHostnames("@-", ",")
+	suite.NoError(err)
+	suite.Equal([]string{"a", "b"}, hosts)
+}
+
+func (suite *commonTestSuite) TestClient_ExtractInstanceRanges() {
+	c := Client{}
+
+	tt := []struct {
+		instances     string
+		instanceCount uint32
+		ranges        []*task.InstanceRange
+		err           string
+	}{
+		{
+			instances: "3",
+			ranges:    []*task.InstanceRange{{From: 3, To: 4}},
+		},
+		{
+			instances: "0-9",
+			ranges:    []*task.InstanceRange{{From: 0, To: 10}},
+		},
+		{
+			instances: "20-25,0-9,15",
+			ranges: []*task.InstanceRange{
+				{From: 0, To: 10},
+				{From: 15, To: 16},
+				{From: 20, To: 26},
+			},
+		},
+		{
+			instances: " 0 - 9 , 15 ",
+			ranges: []*task.InstanceRange{
+				{From: 0, To: 10},
+				{From: 15, To: 16},
+			},
+		},
+		{
+			instances: "9,10",
+			ranges: []*task.InstanceRange{
+				{From: 9, To: 10},
+				{From: 10, To: 11},
+			},
+		},
+		{
+			instances:     "5-20",
+			instanceCount: 10,
+			ranges:        []*task.InstanceRange{{From: 5, To: 10}},
+		},
+		{
+			instances:     "10-20",
+			instanceCount: 10,
+			err:           "Invalid instance ranges: token 1: instance range 10-20: job has 10 instances",
+		},
+		{
+			instances: "9-0",
+			err:       "Invalid instance ranges: token 1: instance range 9-0 is reversed",
+		},
+		{
+			instances: "0-4294967295",
+			err:       "Invalid instance ranges: token 1: instance range 0-4294967295: instance 4294967295 is too large",
+		},
+		{
+			instances: "0-9,5",
+			err:       "Invalid instance ranges: instance ranges 0-9 and 5-5 overlap",
+		},
+		{
+			instances: "0-9,9-12",
+			err:       "Invalid instance ranges: instance ranges 0-9 and 9-12 overlap",
+		},
+		{
+			instances: "",
+			err:       "Invalid instance ranges: token 1: instance cannot be empty",
+		},
+		{
+			instances: "1,,2",
+			err:       "Invalid instance ranges: token 2: instance cannot be empty",
+		},
+		{
+			instances: "a-b",
+			err:       "Invalid instance ranges: token 1: invalid instance range a-b",
+		},
+		{
+			instances: "1-2-3",
+			err:       "Invalid instance ranges: token 1: invalid instance range 1-2-3",
+		},
+		{
+			instances: "-1",
+			err:       "Invalid instance ranges: token 1: invalid instance range -1",
+		},
+		{
+			instances: "1,,x,9-0",
+			err: "Invalid instance ranges, 3 errors:\n" +
+				"  token 2: instance cannot be empty\n" +
+				"  token 3: invalid instance range x\n" +
+				"  token 4: instance range 9-0 is reversed",
+		},
+		{
+			instances: "0-9,5,9-12",
+			err: "Invalid instance ranges, 2 errors:\n" +
+				"  instance ranges 0-9 and 5-5 overlap\n" +
+				"  instance ranges 0-9 and 9-12 overlap",
+		},
+	}
+
+	for _, t := range tt {
+		ranges, err := c.ExtractInstanceRanges(t.instances, t.instanceCount)
+		if t.err != "" {
+			suite.EqualError(err, t.err, t.instances)
+			continue
+		}
+		suite.NoError(err, t.instances)
+		suite.Equal(t.ranges, ranges, t.instances)
+	}
+}
+
 func TestCommon(t *testing.T) {
 	suite.Run(t, new(commonTestSuite))
 }
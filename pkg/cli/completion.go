@@ -0,0 +1,105 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"sort"
+
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/query"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+)
+
+const (
+	// CompletionRespoolPath is the resource pool whose jobs are offered
+	// when completing job identifiers
+	CompletionRespoolPath = "/DefaultResPool"
+
+	// completionJobLimit is the number of most recent jobs offered when
+	// completing job identifiers
+	completionJobLimit = 50
+)
+
+// JobIDCompletions returns the identifiers of the most recently created jobs
+// of the resource pool, to complete job identifier arguments
+func (c *Client) JobIDCompletions(respoolPath string) ([]string, error) {
+	respoolID, err := c.LookupResourcePoolIDCached(respoolPath)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.jobClient.Query(c.ctx, &job.QueryRequest{
+		RespoolID: respoolID,
+		Spec: &job.QuerySpec{
+			Pagination: &query.PaginationSpec{
+				Limit:    completionJobLimit,
+				MaxLimit: completionJobLimit,
+				OrderBy: []*query.OrderBy{{
+					Order:    query.OrderBy_DESC,
+					Property: &query.PropertyPath{Value: "creation_time"},
+				}},
+			},
+		},
+		SummaryOnly: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var jobIDs []string
+	for _, summary := range response.GetResults() {
+		if id := summary.GetId().GetValue(); id != "" {
+			jobIDs = append(jobIDs, id)
+		}
+	}
+	return jobIDs, nil
+}
+
+// RespoolPathCompletions returns the sorted paths of all resource pools, to
+// complete resource pool path arguments
+func (c *Client) RespoolPathCompletions() ([]string, error) {
+	response, err := c.resClient.Query(c.ctx, &respool.QueryRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for _, pool := range response.GetResourcePools() {
+		if path := pool.GetPath().GetValue(); path != "" {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// HostnameCompletions returns the sorted hostnames of all hosts, to complete
+// hostname arguments
+func (c *Client) HostnameCompletions() ([]string, error) {
+	response, err := c.hostClient.QueryHosts(c.ctx, &host_svc.QueryHostsRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	var hostnames []string
+	for _, h := range response.GetHostInfos() {
+		if h.GetHostname() != "" {
+			hostnames = append(hostnames, h.GetHostname())
+		}
+	}
+	sort.Strings(hostnames)
+	return hostnames, nil
+}
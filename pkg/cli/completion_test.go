@@ -0,0 +1,164 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	hostmocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	jobmocks "github.com/uber/peloton/.gen/peloton/api/v0/job/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/query"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	respoolmocks "github.com/uber/peloton/.gen/peloton/api/v0/respool/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+type completionTestSuite struct {
+	suite.Suite
+	mockCtrl    *gomock.Controller
+	mockJob     *jobmocks.MockJobManagerYARPCClient
+	mockRespool *respoolmocks.MockResourceManagerYARPCClient
+	mockHost    *hostmocks.MockHostServiceYARPCClient
+	client      Client
+}
+
+func (suite *completionTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockJob = jobmocks.NewMockJobManagerYARPCClient(suite.mockCtrl)
+	suite.mockRespool = respoolmocks.NewMockResourceManagerYARPCClient(
+		suite.mockCtrl)
+	suite.mockHost = hostmocks.NewMockHostServiceYARPCClient(suite.mockCtrl)
+	suite.client = Client{
+		jobClient:  suite.mockJob,
+		resClient:  suite.mockRespool,
+		hostClient: suite.mockHost,
+		ctx:        context.Background(),
+	}
+}
+
+func (suite *completionTestSuite) TearDownTest() {
+	suite.mockCtrl.Finish()
+}
+
+func TestCompletion(t *testing.T) {
+	suite.Run(t, new(completionTestSuite))
+}
+
+// TestJobIDCompletions tests completing the most recent jobs of the
+// resource pool
+func (suite *completionTestSuite) TestJobIDCompletions() {
+	respoolID := &peloton.ResourcePoolID{Value: "respool-1"}
+	suite.mockRespool.EXPECT().
+		LookupResourcePoolID(gomock.Any(), &respool.LookupRequest{
+			Path: &respool.ResourcePoolPath{Value: CompletionRespoolPath},
+		}).
+		Return(&respool.LookupResponse{Id: respoolID}, nil)
+	suite.mockJob.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *job.QueryRequest) {
+			suite.Equal(respoolID, req.GetRespoolID())
+			pagination := req.GetSpec().GetPagination()
+			suite.Equal(uint32(completionJobLimit), pagination.GetLimit())
+			suite.Equal(uint32(completionJobLimit), pagination.GetMaxLimit())
+			suite.Equal(query.OrderBy_DESC, pagination.GetOrderBy()[0].GetOrder())
+			suite.Equal("creation_time",
+				pagination.GetOrderBy()[0].GetProperty().GetValue())
+		}).
+		Return(&job.QueryResponse{
+			Results: []*job.JobSummary{
+				{Id: &peloton.JobID{Value: "job-2"}},
+				{Id: &peloton.JobID{Value: "job-1"}},
+				{},
+			},
+		}, nil)
+
+	jobIDs, err := suite.client.JobIDCompletions(CompletionRespoolPath)
+	suite.NoError(err)
+	suite.Equal([]string{"job-2", "job-1"}, jobIDs)
+}
+
+// TestJobIDCompletionsError tests that no job is completed if the resource
+// pool is not found
+func (suite *completionTestSuite) TestJobIDCompletionsError() {
+	suite.mockRespool.EXPECT().
+		LookupResourcePoolID(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("respool not found"))
+
+	jobIDs, err := suite.client.JobIDCompletions(CompletionRespoolPath)
+	suite.Error(err)
+	suite.Empty(jobIDs)
+}
+
+// TestRespoolPathCompletions tests completing the paths of all resource pools
+func (suite *completionTestSuite) TestRespoolPathCompletions() {
+	suite.mockRespool.EXPECT().
+		Query(gomock.Any(), &respool.QueryRequest{}).
+		Return(&respool.QueryResponse{
+			ResourcePools: []*respool.ResourcePoolInfo{
+				{Path: &respool.ResourcePoolPath{Value: "/b"}},
+				{Path: &respool.ResourcePoolPath{Value: "/"}},
+				{Path: &respool.ResourcePoolPath{Value: "/a/c"}},
+				{},
+			},
+		}, nil)
+
+	paths, err := suite.client.RespoolPathCompletions()
+	suite.NoError(err)
+	suite.Equal([]string{"/", "/a/c", "/b"}, paths)
+}
+
+func (suite *completionTestSuite) TestRespoolPathCompletionsError() {
+	suite.mockRespool.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("resmgr unavailable"))
+
+	paths, err := suite.client.RespoolPathCompletions()
+	suite.Error(err)
+	suite.Empty(paths)
+}
+
+// TestHostnameCompletions tests completing the hostnames of all hosts
+func (suite *completionTestSuite) TestHostnameCompletions() {
+	suite.mockHost.EXPECT().
+		QueryHosts(gomock.Any(), &hostsvc.QueryHostsRequest{}).
+		Return(&hostsvc.QueryHostsResponse{
+			HostInfos: []*host.HostInfo{
+				{Hostname: "host-2"},
+				{Hostname: "host-1"},
+			},
+		}, nil)
+
+	hostnames, err := suite.client.HostnameCompletions()
+	suite.NoError(err)
+	suite.Equal([]string{"host-1", "host-2"}, hostnames)
+}
+
+func (suite *completionTestSuite) TestHostnameCompletionsError() {
+	suite.mockHost.EXPECT().
+		QueryHosts(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("hostmgr unavailable"))
+
+	hostnames, err := suite.client.HostnameCompletions()
+	suite.Error(err)
+	suite.Empty(hostnames)
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/uber/peloton/pkg/cli/middleware"
+
+	"github.com/pkg/errors"
+)
+
+// AuthConfig is the authentication configuration used to connect to a
+// cluster. Secrets are only read from files so that they never show up on
+// the command line.
+type AuthConfig struct {
+	// User and PasswordFile are the credentials of basic auth
+	User         string `yaml:"user,omitempty"`
+	PasswordFile string `yaml:"password_file,omitempty"`
+	// TokenFile is the file of the token of token auth
+	TokenFile string `yaml:"token_file,omitempty"`
+}
+
+// Credentials reads the credentials of the configuration, or returns nil if
+// authentication is not configured. Basic auth and token auth cannot be
+// configured together.
+func (a *AuthConfig) Credentials() (*middleware.AuthConfig, error) {
+	if a == nil {
+		return nil, nil
+	}
+
+	basic := a.User != "" || a.PasswordFile != ""
+	switch {
+	case basic && a.TokenFile != "":
+		return nil, errors.New(
+			"basic auth and token auth cannot be used together")
+	case basic && (a.User == "" || a.PasswordFile == ""):
+		return nil, errors.New("basic auth requires both a user and a password file")
+	case basic:
+		password, err := readSecret(a.PasswordFile)
+		if err != nil {
+			return nil, err
+		}
+		return &middleware.AuthConfig{
+			Basic: &middleware.BasicAuthConfig{
+				Username: a.User,
+				Password: password,
+			},
+		}, nil
+	case a.TokenFile != "":
+		token, err := readSecret(a.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		return &middleware.AuthConfig{Token: token}, nil
+	}
+	return nil, nil
+}
+
+// readSecret reads a secret from a file, ignoring surrounding whitespace
+func readSecret(path string) (string, error) {
+	buffer, err := ioutil.ReadFile(path)
+	if err != nil {
+		return "", errors.Wrap(err, "unable to read secret file")
+	}
+	secret := strings.TrimSpace(string(buffer))
+	if secret == "" {
+		return "", fmt.Errorf("secret file %s is empty", path)
+	}
+	return secret, nil
+}
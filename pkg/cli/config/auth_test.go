@@ -0,0 +1,85 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"testing"
+
+	"github.com/uber/peloton/pkg/cli/middleware"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuthCredentials(t *testing.T) {
+	secret, cleanup := writeConfig(t, "secret\n")
+	defer cleanup()
+	empty, cleanupEmpty := writeConfig(t, " \n")
+	defer cleanupEmpty()
+
+	credentials, err := (*AuthConfig)(nil).Credentials()
+	assert.NoError(t, err)
+	assert.Nil(t, credentials)
+	credentials, err = (&AuthConfig{}).Credentials()
+	assert.NoError(t, err)
+	assert.Nil(t, credentials)
+
+	credentials, err = (&AuthConfig{
+		User:         "peloton",
+		PasswordFile: secret,
+	}).Credentials()
+	assert.NoError(t, err)
+	assert.Equal(t, &middleware.AuthConfig{
+		Basic: &middleware.BasicAuthConfig{
+			Username: "peloton",
+			Password: "secret",
+		},
+	}, credentials)
+
+	credentials, err = (&AuthConfig{TokenFile: secret}).Credentials()
+	assert.NoError(t, err)
+	assert.Equal(t, &middleware.AuthConfig{Token: "secret"}, credentials)
+
+	_, err = (&AuthConfig{
+		User:         "peloton",
+		PasswordFile: secret,
+		TokenFile:    secret,
+	}).Credentials()
+	assert.EqualError(t, err, "basic auth and token auth cannot be used together")
+
+	_, err = (&AuthConfig{User: "peloton"}).Credentials()
+	assert.Error(t, err)
+	_, err = (&AuthConfig{PasswordFile: secret}).Credentials()
+	assert.Error(t, err)
+	_, err = (&AuthConfig{TokenFile: empty}).Credentials()
+	assert.Error(t, err)
+	_, err = (&AuthConfig{TokenFile: "/does/not/exist"}).Credentials()
+	assert.Error(t, err)
+}
+
+func TestResolveAuth(t *testing.T) {
+	profile := &Profile{
+		Auth: &AuthConfig{User: "peloton", PasswordFile: "/etc/password"},
+	}
+
+	settings, err := Resolve(Settings{}, profile, testDefaultSettings)
+	assert.NoError(t, err)
+	assert.Equal(t, profile.Auth, settings.Auth)
+
+	// the flags replace the whole auth config of the profile
+	flags := &AuthConfig{TokenFile: "/etc/token"}
+	settings, err = Resolve(Settings{Auth: flags}, profile, testDefaultSettings)
+	assert.NoError(t, err)
+	assert.Equal(t, flags, settings.Auth)
+}
@@ -0,0 +1,294 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// profileConfigName is the CLI configuration file holding the named
+	// cluster profiles, in the ~/.peloton dir. Format as
+	// current_cluster: cluster1
+	// clusters:
+	//   cluster1:
+	//     zkservers: [zk1:2181, zk2:2181]
+	//     timeout: 30s
+	//     auth:
+	//       user: peloton
+	//       password_file: /etc/peloton/cluster1.password
+	//   local:
+	//     jobmgr: localhost:5392
+	//     resmgr: localhost:5394
+	//     hostmgr: localhost:5391
+	//     tls:
+	//       ca_file: /etc/peloton/ca.pem
+	//     service_tls:
+	//       hostmgr:
+	//         disabled: true
+	// history:
+	//   enabled: true
+	//   max_size_mb: 10
+	profileConfigName = "config.yaml"
+
+	// ClusterEnvVar is the environment variable selecting the cluster
+	// profile if no cluster is given on the command line
+	ClusterEnvVar = "PELOTON_CLUSTER"
+)
+
+// Profile holds the settings used to connect to a named cluster
+type Profile struct {
+	ZkServers []string   `yaml:"zkservers,omitempty"`
+	ZkRoot    string     `yaml:"zkroot,omitempty"`
+	JobMgr    string     `yaml:"jobmgr,omitempty"`
+	ResMgr    string     `yaml:"resmgr,omitempty"`
+	HostMgr   string     `yaml:"hostmgr,omitempty"`
+	Timeout   string     `yaml:"timeout,omitempty"`
+	TLS       *TLSConfig `yaml:"tls,omitempty"`
+	// ServiceTLS overrides the TLS configuration of single services,
+	// keyed by ServiceJobMgr, ServiceResMgr or ServiceHostMgr
+	ServiceTLS map[string]*TLSConfig `yaml:"service_tls,omitempty"`
+	Auth       *AuthConfig           `yaml:"auth,omitempty"`
+}
+
+// HistoryConfig is the configuration of the local history of the mutating
+// commands of the CLI
+type HistoryConfig struct {
+	// Enabled is whether the mutating commands are recorded
+	Enabled bool `yaml:"enabled"`
+	// Path is the history file, ~/.peloton/history.jsonl if not set
+	Path string `yaml:"path,omitempty"`
+	// MaxSizeMB is the size of the history file in MB above which it is
+	// rotated, 10MB if not set
+	MaxSizeMB int `yaml:"max_size_mb,omitempty"`
+}
+
+// File is the CLI configuration file
+type File struct {
+	// CurrentCluster is the profile used if no cluster is selected
+	CurrentCluster string              `yaml:"current_cluster,omitempty"`
+	Clusters       map[string]*Profile `yaml:"clusters,omitempty"`
+	// History is the configuration of the local history, which is
+	// disabled if it is not set
+	History *HistoryConfig `yaml:"history,omitempty"`
+}
+
+// DefaultFilePath returns the path of the CLI configuration file of the
+// current user
+func DefaultFilePath() (string, error) {
+	user, err := user.Current()
+	if err != nil {
+		return "", errors.Wrap(err, "no user found")
+	}
+	return filepath.Join(user.HomeDir, configPathUserDir, profileConfigName), nil
+}
+
+// LoadFile loads the CLI configuration file at path. A missing file is
+// loaded as an empty configuration.
+func LoadFile(path string) (*File, error) {
+	buffer, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &File{}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("unable to open file %s", path))
+	}
+
+	var file File
+	if err := yaml.Unmarshal(buffer, &file); err != nil {
+		return nil, errors.Wrap(err, fmt.Sprintf("invalid config file %s", path))
+	}
+	for name, profile := range file.Clusters {
+		if profile == nil {
+			return nil, fmt.Errorf("invalid config file %s: "+
+				"cluster %s has no settings", path, name)
+		}
+		if _, err := profile.timeout(); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: "+
+				"cluster %s: %v", path, name, err)
+		}
+		if err := validateServiceTLS(profile.ServiceTLS); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: "+
+				"cluster %s: %v", path, name, err)
+		}
+	}
+	return &file, nil
+}
+
+// Save writes the CLI configuration file to path
+func (f *File) Save(path string) error {
+	buffer, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buffer, 0600)
+}
+
+// Write writes the CLI configuration as YAML to w
+func (f *File) Write(w io.Writer) error {
+	buffer, err := yaml.Marshal(f)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(buffer)
+	return err
+}
+
+// UseCluster sets the profile used if no cluster is selected
+func (f *File) UseCluster(name string) error {
+	if _, err := f.Profile(name); err != nil {
+		return err
+	}
+	f.CurrentCluster = name
+	return nil
+}
+
+// Profile returns the profile of the named cluster
+func (f *File) Profile(name string) (*Profile, error) {
+	profile, ok := f.Clusters[name]
+	if !ok {
+		var names []string
+		for n := range f.Clusters {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("cluster %s not found in config, "+
+			"known clusters are %v", name, names)
+	}
+	return profile, nil
+}
+
+// SelectCluster returns the name of the selected cluster profile, from the
+// command line if given, otherwise from the environment, otherwise the
+// current cluster of the configuration file. It returns an empty name if
+// no cluster is selected.
+func (f *File) SelectCluster(flag string, env string) string {
+	switch {
+	case flag != "":
+		return flag
+	case env != "":
+		return env
+	default:
+		return f.CurrentCluster
+	}
+}
+
+func (p *Profile) timeout() (time.Duration, error) {
+	if p.Timeout == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(p.Timeout)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeout %q", p.Timeout)
+	}
+	return timeout, nil
+}
+
+// Settings are the connection settings of the CLI. Empty fields are unset.
+type Settings struct {
+	ZkServers []string
+	ZkRoot    string
+	JobMgr    string
+	ResMgr    string
+	HostMgr   string
+	Timeout   time.Duration
+	TLS       *TLSConfig
+	// ServiceTLSOverrides override the TLS configuration of single
+	// services, keyed by service name
+	ServiceTLSOverrides map[string]*TLSConfig
+	// Auth is the authentication configuration, which is taken as a whole
+	// from a single source so that basic auth and token auth never mix
+	Auth *AuthConfig
+}
+
+// Resolve returns the explicitly given settings, i.e. from flags or their
+// environment variables, with every unset field taken from the profile if
+// any, and then from the defaults. Explicit service URLs without explicit
+// zookeeper servers disable the zookeeper discovery of the profile, which
+// is otherwise preferred over the URLs.
+func Resolve(explicit Settings, profile *Profile, defaults Settings) (Settings, error) {
+	settings := explicit
+	if profile != nil {
+		timeout, err := profile.timeout()
+		if err != nil {
+			return Settings{}, err
+		}
+		zkServers := profile.ZkServers
+		if len(explicit.ZkServers) == 0 && explicit.hasServiceURLs() {
+			zkServers = nil
+		}
+		settings = merge(settings, Settings{
+			ZkServers: zkServers,
+			ZkRoot:    profile.ZkRoot,
+			JobMgr:    profile.JobMgr,
+			ResMgr:    profile.ResMgr,
+			HostMgr:   profile.HostMgr,
+			Timeout:   timeout,
+			TLS:       profile.TLS,
+
+			ServiceTLSOverrides: overrideServiceTLS(explicit.TLS, profile.ServiceTLS),
+			Auth:                profile.Auth,
+		})
+	}
+	return merge(settings, defaults), nil
+}
+
+// hasServiceURLs returns whether any service URL is set
+func (s Settings) hasServiceURLs() bool {
+	return s.JobMgr != "" || s.ResMgr != "" || s.HostMgr != ""
+}
+
+// merge returns s with its unset fields taken from fallback
+func merge(s Settings, fallback Settings) Settings {
+	if len(s.ZkServers) == 0 {
+		s.ZkServers = fallback.ZkServers
+	}
+	if s.ZkRoot == "" {
+		s.ZkRoot = fallback.ZkRoot
+	}
+	if s.JobMgr == "" {
+		s.JobMgr = fallback.JobMgr
+	}
+	if s.ResMgr == "" {
+		s.ResMgr = fallback.ResMgr
+	}
+	if s.HostMgr == "" {
+		s.HostMgr = fallback.HostMgr
+	}
+	if s.Timeout == 0 {
+		s.Timeout = fallback.Timeout
+	}
+	s.TLS = mergeTLS(s.TLS, fallback.TLS)
+	if s.ServiceTLSOverrides == nil {
+		s.ServiceTLSOverrides = fallback.ServiceTLSOverrides
+	}
+	if s.Auth == nil {
+		s.Auth = fallback.Auth
+	}
+	return s
+}
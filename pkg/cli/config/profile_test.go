@@ -0,0 +1,228 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testProfileConfig = `
+current_cluster: dev
+clusters:
+  dev:
+    zkservers: [zk1:2181, zk2:2181]
+    timeout: 30s
+  local:
+    jobmgr: localhost:5392
+    tls:
+      server_name: peloton
+`
+
+var testDefaultSettings = Settings{
+	ZkRoot:  "/peloton",
+	JobMgr:  "localhost:5392",
+	ResMgr:  "localhost:5394",
+	HostMgr: "localhost:5391",
+	Timeout: 20 * time.Second,
+}
+
+// writeConfig writes the CLI config to a temporary dir and returns its path
+func writeConfig(t *testing.T, content string) (string, func()) {
+	dir, err := ioutil.TempDir("", "peloton-cli-config")
+	require.NoError(t, err)
+	path := filepath.Join(dir, profileConfigName)
+	require.NoError(t, ioutil.WriteFile(path, []byte(content), 0600))
+	return path, func() { os.RemoveAll(dir) }
+}
+
+func TestLoadFile(t *testing.T) {
+	path, cleanup := writeConfig(t, testProfileConfig)
+	defer cleanup()
+
+	file, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "dev", file.CurrentCluster)
+	assert.Len(t, file.Clusters, 2)
+	assert.Equal(t, []string{"zk1:2181", "zk2:2181"}, file.Clusters["dev"].ZkServers)
+	assert.Equal(t, "peloton", file.Clusters["local"].TLS.ServerName)
+
+	profile, err := file.Profile("local")
+	assert.NoError(t, err)
+	assert.Equal(t, "localhost:5392", profile.JobMgr)
+	_, err = file.Profile("prod")
+	assert.EqualError(t, err,
+		"cluster prod not found in config, known clusters are [dev local]")
+}
+
+func TestLoadFileMissing(t *testing.T) {
+	file, err := LoadFile("/does/not/exist/config.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, &File{}, file)
+}
+
+func TestLoadFileHistory(t *testing.T) {
+	path, cleanup := writeConfig(t, testProfileConfig+`
+history:
+  enabled: true
+  max_size_mb: 5
+`)
+	defer cleanup()
+
+	file, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, &HistoryConfig{Enabled: true, MaxSizeMB: 5}, file.History)
+}
+
+func TestLoadFileMalformed(t *testing.T) {
+	tt := []struct {
+		content string
+		err     string
+	}{
+		{
+			content: "clusters: [dev",
+			err:     "invalid config file",
+		},
+		{
+			content: "clusters:\n  dev:\n    zkservers: zk1",
+			err:     "invalid config file",
+		},
+		{
+			content: "clusters:\n  dev:\n",
+			err:     "cluster dev has no settings",
+		},
+		{
+			content: "clusters:\n  dev:\n    timeout: soon\n",
+			err:     `cluster dev: invalid timeout "soon"`,
+		},
+	}
+
+	for _, test := range tt {
+		path, cleanup := writeConfig(t, test.content)
+		_, err := LoadFile(path)
+		if assert.Error(t, err, test.content) {
+			assert.Contains(t, err.Error(), test.err, test.content)
+		}
+		cleanup()
+	}
+}
+
+func TestUseClusterAndSave(t *testing.T) {
+	path, cleanup := writeConfig(t, testProfileConfig)
+	defer cleanup()
+
+	file, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.Error(t, file.UseCluster("prod"))
+	assert.Equal(t, "dev", file.CurrentCluster)
+	require.NoError(t, file.UseCluster("local"))
+	require.NoError(t, file.Save(path))
+
+	saved, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, file, saved)
+
+	var buffer bytes.Buffer
+	require.NoError(t, saved.Write(&buffer))
+	assert.Contains(t, buffer.String(), "current_cluster: local\n")
+}
+
+func TestSelectCluster(t *testing.T) {
+	file := &File{CurrentCluster: "file"}
+	assert.Equal(t, "flag", file.SelectCluster("flag", "env"))
+	assert.Equal(t, "env", file.SelectCluster("", "env"))
+	assert.Equal(t, "file", file.SelectCluster("", ""))
+	assert.Equal(t, "", (&File{}).SelectCluster("", ""))
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	profile := &Profile{
+		ZkServers: []string{"zk1:2181"},
+		JobMgr:    "jobmgr:5392",
+		Timeout:   "30s",
+		TLS:       &TLSConfig{ServerName: "peloton"},
+	}
+
+	// no flags, profile overrides defaults
+	settings, err := Resolve(Settings{}, profile, testDefaultSettings)
+	assert.NoError(t, err)
+	assert.Equal(t, Settings{
+		ZkServers: []string{"zk1:2181"},
+		ZkRoot:    "/peloton",
+		JobMgr:    "jobmgr:5392",
+		ResMgr:    "localhost:5394",
+		HostMgr:   "localhost:5391",
+		Timeout:   30 * time.Second,
+		TLS:       &TLSConfig{ServerName: "peloton"},
+	}, settings)
+
+	// flags override the profile
+	settings, err = Resolve(Settings{
+		ZkServers: []string{"zk9:2181"},
+		Timeout:   time.Minute,
+	}, profile, testDefaultSettings)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"zk9:2181"}, settings.ZkServers)
+	assert.Equal(t, "jobmgr:5392", settings.JobMgr)
+	assert.Equal(t, time.Minute, settings.Timeout)
+
+	// explicit URLs disable the zookeeper discovery of the profile
+	settings, err = Resolve(Settings{
+		HostMgr: "hostmgr:5391",
+	}, profile, testDefaultSettings)
+	assert.NoError(t, err)
+	assert.Empty(t, settings.ZkServers)
+	assert.Equal(t, "hostmgr:5391", settings.HostMgr)
+	assert.Equal(t, "jobmgr:5392", settings.JobMgr)
+
+	// unless zookeeper servers are explicit too
+	settings, err = Resolve(Settings{
+		ZkServers: []string{"zk9:2181"},
+		HostMgr:   "hostmgr:5391",
+	}, profile, testDefaultSettings)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"zk9:2181"}, settings.ZkServers)
+
+	// no profile, defaults only
+	settings, err = Resolve(Settings{}, nil, testDefaultSettings)
+	assert.NoError(t, err)
+	assert.Equal(t, testDefaultSettings, settings)
+
+	_, err = Resolve(Settings{}, &Profile{Timeout: "soon"}, testDefaultSettings)
+	assert.Error(t, err)
+}
+
+func TestTLSClientConfig(t *testing.T) {
+	config, err := (&TLSConfig{ServerName: "peloton"}).ClientConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "peloton", config.ServerName)
+	assert.Nil(t, config.RootCAs)
+
+	path, cleanup := writeConfig(t, "not a certificate")
+	defer cleanup()
+	_, err = (&TLSConfig{CAFile: path}).ClientConfig()
+	assert.Error(t, err)
+	_, err = (&TLSConfig{CAFile: "/does/not/exist.pem"}).ClientConfig()
+	assert.Error(t, err)
+	_, err = (&TLSConfig{CertFile: path, KeyFile: path}).ClientConfig()
+	assert.Error(t, err)
+}
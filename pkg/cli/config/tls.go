@@ -0,0 +1,153 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// Names of the peloton services whose TLS configuration can be overridden
+// in a profile
+const (
+	ServiceJobMgr  = "jobmgr"
+	ServiceResMgr  = "resmgr"
+	ServiceHostMgr = "hostmgr"
+)
+
+// TLSConfig is the TLS configuration used to connect to a cluster
+type TLSConfig struct {
+	// Disabled turns TLS off, e.g. for a service of a cluster which does
+	// not use TLS
+	Disabled bool `yaml:"disabled,omitempty"`
+	// CAFile is the PEM file of the CAs used to verify the servers,
+	// the system CAs are used if it is not set
+	CAFile string `yaml:"ca_file,omitempty"`
+	// CertFile and KeyFile are the PEM files of the client certificate
+	// presented to servers requiring mutual TLS
+	CertFile string `yaml:"cert_file,omitempty"`
+	KeyFile  string `yaml:"key_file,omitempty"`
+	// ServerName overrides the name used to verify the servers
+	ServerName string `yaml:"server_name,omitempty"`
+	// InsecureSkipVerify disables the verification of the servers if set
+	// to true. It is a pointer so that an explicit false can turn the
+	// verification back on over a profile.
+	InsecureSkipVerify *bool `yaml:"insecure_skip_verify,omitempty"`
+}
+
+// ClientConfig returns the crypto/tls configuration of the client, or nil
+// if TLS is not configured or disabled
+func (t *TLSConfig) ClientConfig() (*tls.Config, error) {
+	if t == nil || t.Disabled {
+		return nil, nil
+	}
+
+	config := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify != nil && *t.InsecureSkipVerify,
+	}
+	if t.CAFile != "" {
+		pem, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to read CA file")
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s",
+				t.CAFile)
+		}
+	}
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to load client certificate")
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
+
+// ServiceTLS returns the TLS configuration of a service, which is its
+// override if any and the TLS configuration of the settings otherwise
+func (s Settings) ServiceTLS(service string) *TLSConfig {
+	if override, ok := s.ServiceTLSOverrides[service]; ok {
+		return override
+	}
+	return s.TLS
+}
+
+// overrideServiceTLS returns the TLS overrides of services with the fields
+// set in t taking precedence over theirs, like explicit settings take
+// precedence over the ones of a profile
+func overrideServiceTLS(
+	t *TLSConfig,
+	overrides map[string]*TLSConfig) map[string]*TLSConfig {
+	if t == nil || overrides == nil {
+		return overrides
+	}
+	result := make(map[string]*TLSConfig, len(overrides))
+	for service, override := range overrides {
+		result[service] = mergeTLS(t, override)
+	}
+	return result
+}
+
+// mergeTLS returns t with its unset fields taken from fallback
+func mergeTLS(t *TLSConfig, fallback *TLSConfig) *TLSConfig {
+	if t == nil {
+		return fallback
+	}
+	if fallback == nil {
+		return t
+	}
+	merged := *t
+	if merged.CAFile == "" {
+		merged.CAFile = fallback.CAFile
+	}
+	if merged.CertFile == "" && merged.KeyFile == "" {
+		merged.CertFile = fallback.CertFile
+		merged.KeyFile = fallback.KeyFile
+	}
+	if merged.ServerName == "" {
+		merged.ServerName = fallback.ServerName
+	}
+	if merged.InsecureSkipVerify == nil {
+		merged.InsecureSkipVerify = fallback.InsecureSkipVerify
+	}
+	// TLS cannot be disabled explicitly, only by a profile
+	merged.Disabled = merged.Disabled || fallback.Disabled
+	return &merged
+}
+
+// validateServiceTLS checks that TLS overrides are only given for known
+// services
+func validateServiceTLS(overrides map[string]*TLSConfig) error {
+	for service, override := range overrides {
+		switch service {
+		case ServiceJobMgr, ServiceResMgr, ServiceHostMgr:
+		default:
+			return fmt.Errorf("unknown service %s, expected one of %s, %s "+
+				"or %s", service, ServiceJobMgr, ServiceResMgr, ServiceHostMgr)
+		}
+		if override == nil {
+			return fmt.Errorf("service %s has no TLS settings", service)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package config
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writePEM writes a PEM block to a file of dir and returns its path
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) string {
+	path := filepath.Join(dir, name)
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, ioutil.WriteFile(path, data, 0600))
+	return path
+}
+
+// writeServerCA writes the certificate of a TLS test server as a CA file
+func writeServerCA(t *testing.T, dir, name string, server *httptest.Server) string {
+	return writePEM(t, dir, name, "CERTIFICATE",
+		server.TLS.Certificates[0].Certificate[0])
+}
+
+// writeClientCert generates a self signed client certificate, writes it and
+// its key to dir and returns their paths along with the certificate
+func writeClientCert(t *testing.T, dir string) (string, string, *x509.Certificate) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "peloton-cli"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return writePEM(t, dir, "client.pem", "CERTIFICATE", der),
+		writePEM(t, dir, "client-key.pem", "EC PRIVATE KEY", keyDER),
+		cert
+}
+
+// get fetches the root of the server using the TLS configuration
+func get(t *testing.T, config *TLSConfig, server *httptest.Server) error {
+	tlsConfig, err := config.ClientConfig()
+	require.NoError(t, err)
+	client := &http.Client{
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		Timeout:   5 * time.Second,
+	}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func TestTLSHandshake(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peloton-cli-tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	ca := writeServerCA(t, dir, "ca.pem", server)
+	// a self signed certificate which did not sign the server certificate
+	badCA, _, _ := writeClientCert(t, dir)
+
+	assert.NoError(t, get(t, &TLSConfig{CAFile: ca}, server))
+	assert.Error(t, get(t, &TLSConfig{CAFile: badCA}, server))
+	assert.Error(t, get(t, &TLSConfig{}, server))
+	skip := true
+	assert.NoError(t, get(t, &TLSConfig{
+		CAFile:             badCA,
+		InsecureSkipVerify: &skip,
+	}, server))
+}
+
+func TestMutualTLSHandshake(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peloton-cli-tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile, cert := writeClientCert(t, dir)
+	clientCAs := x509.NewCertPool()
+	clientCAs.AddCert(cert)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  clientCAs,
+	}
+	server.StartTLS()
+	defer server.Close()
+	ca := writeServerCA(t, dir, "ca.pem", server)
+
+	assert.NoError(t, get(t, &TLSConfig{
+		CAFile:   ca,
+		CertFile: certFile,
+		KeyFile:  keyFile,
+	}, server))
+	assert.Error(t, get(t, &TLSConfig{CAFile: ca}, server))
+}
+
+func TestTLSClientConfigDisabled(t *testing.T) {
+	config, err := (&TLSConfig{Disabled: true, CAFile: "/does/not/exist"}).ClientConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, config)
+
+	var unset *TLSConfig
+	config, err = unset.ClientConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, config)
+}
+
+func TestServiceTLS(t *testing.T) {
+	profile := &Profile{
+		TLS: &TLSConfig{CAFile: "/etc/peloton/ca.pem", ServerName: "peloton"},
+		ServiceTLS: map[string]*TLSConfig{
+			ServiceHostMgr: {Disabled: true},
+		},
+	}
+
+	settings, err := Resolve(Settings{}, profile, Settings{})
+	require.NoError(t, err)
+	assert.Equal(t, profile.TLS, settings.ServiceTLS(ServiceJobMgr))
+	assert.Equal(t, profile.TLS, settings.ServiceTLS(ServiceResMgr))
+	assert.True(t, settings.ServiceTLS(ServiceHostMgr).Disabled)
+
+	// flags override single fields of the TLS configuration of the
+	// profile, including the ones of the service overrides
+	skip, verify := true, false
+	settings, err = Resolve(Settings{
+		TLS: &TLSConfig{CAFile: "/tmp/ca.pem", InsecureSkipVerify: &skip},
+	}, profile, Settings{})
+	require.NoError(t, err)
+	assert.Equal(t, &TLSConfig{
+		CAFile:             "/tmp/ca.pem",
+		ServerName:         "peloton",
+		InsecureSkipVerify: &skip,
+	}, settings.ServiceTLS(ServiceJobMgr))
+	assert.Equal(t, &TLSConfig{
+		Disabled:           true,
+		CAFile:             "/tmp/ca.pem",
+		InsecureSkipVerify: &skip,
+	}, settings.ServiceTLS(ServiceHostMgr))
+
+	// an explicit flag turns the verification back on over the profile
+	profile.TLS.InsecureSkipVerify = &skip
+	profile.ServiceTLS[ServiceResMgr] = &TLSConfig{InsecureSkipVerify: &skip}
+	settings, err = Resolve(Settings{
+		TLS: &TLSConfig{InsecureSkipVerify: &verify},
+	}, profile, Settings{})
+	require.NoError(t, err)
+	for _, service := range []string{ServiceJobMgr, ServiceResMgr} {
+		assert.Equal(t, &verify,
+			settings.ServiceTLS(service).InsecureSkipVerify, service)
+	}
+
+	// no TLS at all
+	settings, err = Resolve(Settings{}, nil, Settings{})
+	require.NoError(t, err)
+	assert.Nil(t, settings.ServiceTLS(ServiceJobMgr))
+}
+
+func TestLoadFileServiceTLS(t *testing.T) {
+	path, cleanup := writeConfig(t, `
+clusters:
+  mixed:
+    tls:
+      ca_file: /etc/peloton/ca.pem
+    service_tls:
+      resmgr:
+        disabled: true
+`)
+	defer cleanup()
+	file, err := LoadFile(path)
+	require.NoError(t, err)
+	assert.True(t, file.Clusters["mixed"].ServiceTLS[ServiceResMgr].Disabled)
+
+	path, cleanup = writeConfig(t, `
+clusters:
+  mixed:
+    service_tls:
+      placement:
+        disabled: true
+`)
+	defer cleanup()
+	_, err = LoadFile(path)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unknown service placement")
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	updatesvc "github.com/uber/peloton/.gen/peloton/api/v0/update/svc"
+)
+
+// confirmAborted is printed if the user did not confirm an action
+const confirmAborted = "Aborted\n"
+
+// used for testing
+var confirmIsTerminal = stdinIsTerminal
+
+// stdinIsTerminal returns whether stdin is a terminal
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// confirm asks the user to confirm a destructive action, printing the
+// summary of what will be affected, and returns whether the user typed y.
+// The summary is only fetched if the user is asked, i.e. unless AssumeYes
+// is set. If stdin is not a terminal AssumeYes is required, as nobody can
+// answer the prompt.
+func (c *Client) confirm(summary func() (string, error)) (bool, error) {
+	if c.AssumeYes {
+		return true, nil
+	}
+	message, err := summary()
+	if err != nil {
+		return false, err
+	}
+	if !confirmIsTerminal() {
+		return false, fmt.Errorf(
+			"%s: stdin is not a terminal, use --yes to confirm", message)
+	}
+
+	in, out := c.confirmIn, c.confirmOut
+	if in == nil {
+		in = os.Stdin
+	}
+	if out == nil {
+		out = os.Stderr
+	}
+	fmt.Fprintf(out, "%s. Continue? [y/N]: ", message)
+	answer, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	if answer == "y" || answer == "yes" {
+		return true, nil
+	}
+	fmt.Fprint(out, confirmAborted)
+	return false, nil
+}
+
+// jobStopSummary summarizes the jobs which will be stopped
+func jobStopSummary(jobs []*job.JobSummary) string {
+	var running uint32
+	for _, j := range jobs {
+		running += j.GetRuntime().GetTaskStats()["RUNNING"]
+	}
+	return fmt.Sprintf("Stop %d job(s), %d running task(s)", len(jobs), running)
+}
+
+// jobDeleteSummary fetches the job which will be deleted and summarizes it
+func (c *Client) jobDeleteSummary(jobID string) (string, error) {
+	response, err := c.jobGet(jobID)
+	if err != nil {
+		return "", err
+	}
+	info := response.GetJobInfo()
+	return fmt.Sprintf("Delete job %s (%s, %s), %d running task(s)",
+		jobID,
+		info.GetConfig().GetName(),
+		info.GetRuntime().GetState(),
+		info.GetRuntime().GetTaskStats()["RUNNING"]), nil
+}
+
+// hostMaintenanceSummary fetches the states of the hosts and summarizes
+// them, e.g. "Start maintenance on 3 host(s), 2 HOST_STATE_UP, 1 not found"
+func (c *Client) hostMaintenanceSummary(
+	action string,
+	hostnames []string) (string, error) {
+	response, err := c.hostClient.QueryHosts(c.ctx, &host_svc.QueryHostsRequest{})
+	if err != nil {
+		return "", err
+	}
+	hostStates := make(map[string]string)
+	for _, h := range response.GetHostInfos() {
+		hostStates[h.GetHostname()] = h.GetState().String()
+	}
+
+	counts := make(map[string]int)
+	for _, hostname := range hostnames {
+		state, ok := hostStates[hostname]
+		if !ok {
+			state = "not found"
+		}
+		counts[state]++
+	}
+	var states []string
+	for state := range counts {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+	for i, state := range states {
+		states[i] = fmt.Sprintf("%d %s", counts[state], state)
+	}
+	return fmt.Sprintf("%s on %d host(s), %s",
+		action, len(hostnames), strings.Join(states, ", ")), nil
+}
+
+// updateAbortSummary fetches the update which will be aborted and
+// summarizes it
+func (c *Client) updateAbortSummary(updateID *peloton.UpdateID) (string, error) {
+	response, err := c.updateClient.GetUpdate(c.ctx, &updatesvc.GetUpdateRequest{
+		UpdateId: updateID,
+	})
+	if err != nil {
+		return "", err
+	}
+	info := response.GetUpdateInfo()
+	return fmt.Sprintf("Abort update %s of job %s (%s), %d instance(s) done, %d remaining",
+		updateID.GetValue(),
+		info.GetJobId().GetValue(),
+		info.GetStatus().GetState(),
+		info.GetStatus().GetNumTasksDone(),
+		info.GetStatus().GetNumTasksRemaining()), nil
+}
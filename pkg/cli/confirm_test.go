@@ -0,0 +1,240 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	hostmocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	jobmocks "github.com/uber/peloton/.gen/peloton/api/v0/job/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/update"
+	updatesvc "github.com/uber/peloton/.gen/peloton/api/v0/update/svc"
+	updatesvcmocks "github.com/uber/peloton/.gen/peloton/api/v0/update/svc/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+type confirmTestSuite struct {
+	suite.Suite
+	mockCtrl      *gomock.Controller
+	mockJob       *jobmocks.MockJobManagerYARPCClient
+	mockHost      *hostmocks.MockHostServiceYARPCClient
+	prompt        *bytes.Buffer
+	oldIsTerminal func() bool
+	client        Client
+}
+
+func (suite *confirmTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockJob = jobmocks.NewMockJobManagerYARPCClient(suite.mockCtrl)
+	suite.mockHost = hostmocks.NewMockHostServiceYARPCClient(suite.mockCtrl)
+	suite.prompt = &bytes.Buffer{}
+	suite.oldIsTerminal = confirmIsTerminal
+	confirmIsTerminal = func() bool { return true }
+	suite.client = Client{
+		jobClient:  suite.mockJob,
+		hostClient: suite.mockHost,
+		ctx:        context.Background(),
+		confirmOut: suite.prompt,
+	}
+}
+
+func (suite *confirmTestSuite) TearDownTest() {
+	confirmIsTerminal = suite.oldIsTerminal
+	suite.mockCtrl.Finish()
+}
+
+func TestConfirm(t *testing.T) {
+	suite.Run(t, new(confirmTestSuite))
+}
+
+func confirmSummary(message string) func() (string, error) {
+	return func() (string, error) {
+		return message, nil
+	}
+}
+
+// TestConfirmAccept tests the answers confirming an action
+func (suite *confirmTestSuite) TestConfirmAccept() {
+	for _, answer := range []string{"y\n", "Y\n", " yes \n", "y"} {
+		suite.prompt.Reset()
+		suite.client.confirmIn = strings.NewReader(answer)
+		confirmed, err := suite.client.confirm(confirmSummary("Stop 1 job(s)"))
+		suite.NoError(err)
+		suite.True(confirmed, answer)
+		suite.Equal("Stop 1 job(s). Continue? [y/N]: ", suite.prompt.String())
+	}
+}
+
+// TestConfirmReject tests that anything but y rejects an action
+func (suite *confirmTestSuite) TestConfirmReject() {
+	for _, answer := range []string{"n\n", "\n", "yep\n", ""} {
+		suite.prompt.Reset()
+		suite.client.confirmIn = strings.NewReader(answer)
+		confirmed, err := suite.client.confirm(confirmSummary("Stop 1 job(s)"))
+		suite.NoError(err)
+		suite.False(confirmed, answer)
+		suite.Contains(suite.prompt.String(), confirmAborted)
+	}
+}
+
+// TestConfirmNotTerminal tests that --yes is required if stdin is not a
+// terminal
+func (suite *confirmTestSuite) TestConfirmNotTerminal() {
+	confirmIsTerminal = func() bool { return false }
+	suite.client.confirmIn = strings.NewReader("y\n")
+	confirmed, err := suite.client.confirm(confirmSummary("Stop 1 job(s)"))
+	suite.EqualError(err,
+		"Stop 1 job(s): stdin is not a terminal, use --yes to confirm")
+	suite.False(confirmed)
+	suite.Empty(suite.prompt.String())
+
+	suite.client.AssumeYes = true
+	confirmed, err = suite.client.confirm(confirmSummary("Stop 1 job(s)"))
+	suite.NoError(err)
+	suite.True(confirmed)
+}
+
+// TestConfirmAssumeYes tests that the summary is neither fetched nor
+// printed with --yes
+func (suite *confirmTestSuite) TestConfirmAssumeYes() {
+	suite.client.AssumeYes = true
+	confirmed, err := suite.client.confirm(func() (string, error) {
+		suite.Fail("summary fetched")
+		return "", nil
+	})
+	suite.NoError(err)
+	suite.True(confirmed)
+	suite.Empty(suite.prompt.String())
+}
+
+// TestConfirmSummaryError tests that an action is not confirmed if its
+// summary cannot be fetched
+func (suite *confirmTestSuite) TestConfirmSummaryError() {
+	confirmed, err := suite.client.confirm(func() (string, error) {
+		return "", errors.New("job not found")
+	})
+	suite.Error(err)
+	suite.False(confirmed)
+}
+
+// TestJobDeleteConfirm tests the summary of a job delete, and that the job
+// is not deleted unless confirmed
+func (suite *confirmTestSuite) TestJobDeleteConfirm() {
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{Id: &peloton.JobID{Value: testJobID}}).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{
+				Config: &job.JobConfig{Name: "web"},
+				Runtime: &job.RuntimeInfo{
+					State:     job.JobState_RUNNING,
+					TaskStats: map[string]uint32{"RUNNING": 4200},
+				},
+			},
+		}, nil).
+		Times(2)
+
+	suite.client.confirmIn = strings.NewReader("n\n")
+	suite.NoError(suite.client.JobDeleteAction(testJobID))
+	suite.Contains(suite.prompt.String(),
+		"Delete job "+testJobID+" (web, RUNNING), 4200 running task(s). Continue?")
+
+	suite.mockJob.EXPECT().
+		Delete(gomock.Any(), gomock.Any()).
+		Return(&job.DeleteResponse{}, nil)
+	suite.client.confirmIn = strings.NewReader("y\n")
+	suite.NoError(suite.client.JobDeleteAction(testJobID))
+}
+
+// TestJobStopSummary tests counting the running tasks of the stopped jobs
+func (suite *confirmTestSuite) TestJobStopSummary() {
+	suite.Equal("Stop 3 job(s), 4200 running task(s)", jobStopSummary(
+		[]*job.JobSummary{
+			{Runtime: &job.RuntimeInfo{TaskStats: map[string]uint32{"RUNNING": 4000}}},
+			{Runtime: &job.RuntimeInfo{TaskStats: map[string]uint32{"RUNNING": 200}}},
+			{},
+		}))
+}
+
+// TestHostMaintenanceConfirm tests the summary of a host maintenance, and
+// that it is not started unless confirmed
+func (suite *confirmTestSuite) TestHostMaintenanceConfirm() {
+	suite.mockHost.EXPECT().
+		QueryHosts(gomock.Any(), &host_svc.QueryHostsRequest{}).
+		Return(&host_svc.QueryHostsResponse{
+			HostInfos: []*host.HostInfo{
+				{Hostname: "host-1", State: host.HostState_HOST_STATE_UP},
+				{Hostname: "host-2", State: host.HostState_HOST_STATE_UP},
+				{Hostname: "host-3", State: host.HostState_HOST_STATE_DRAINING},
+			},
+		}, nil)
+
+	suite.client.confirmIn = strings.NewReader("no\n")
+	suite.NoError(suite.client.HostMaintenanceStartAction("host-1,host-2,host-4", 0, false, false, 0, 0))
+	suite.Contains(suite.prompt.String(),
+		"Start maintenance on 3 host(s), 2 HOST_STATE_UP, 1 not found. Continue?")
+}
+
+// TestUpdateAbortConfirm tests the summary of an update abort, and that the
+// update is not aborted unless confirmed
+func (suite *confirmTestSuite) TestUpdateAbortConfirm() {
+	mockUpdate := updatesvcmocks.NewMockUpdateServiceYARPCClient(suite.mockCtrl)
+	suite.client.updateClient = mockUpdate
+
+	updateID := &peloton.UpdateID{Value: "update-1"}
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{Id: &peloton.JobID{Value: testJobID}}).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{
+				Runtime: &job.RuntimeInfo{UpdateID: updateID},
+			},
+		}, nil).
+		Times(2)
+	mockUpdate.EXPECT().
+		GetUpdate(gomock.Any(), &updatesvc.GetUpdateRequest{UpdateId: updateID}).
+		Return(&updatesvc.GetUpdateResponse{
+			UpdateInfo: &update.UpdateInfo{
+				UpdateId: updateID,
+				JobId:    &peloton.JobID{Value: testJobID},
+				Status: &update.UpdateStatus{
+					State:             update.State_ROLLING_FORWARD,
+					NumTasksDone:      3,
+					NumTasksRemaining: 7,
+				},
+			},
+		}, nil).
+		Times(2)
+
+	suite.client.confirmIn = strings.NewReader("n\n")
+	suite.NoError(suite.client.UpdateAbortAction(testJobID, ""))
+	suite.Contains(suite.prompt.String(),
+		"Abort update update-1 of job "+testJobID+
+			" (ROLLING_FORWARD), 3 instance(s) done, 7 remaining. Continue?")
+
+	mockUpdate.EXPECT().
+		AbortUpdate(gomock.Any(), &updatesvc.AbortUpdateRequest{UpdateId: updateID}).
+		Return(&updatesvc.AbortUpdateResponse{}, nil)
+	suite.client.confirmIn = strings.NewReader("y\n")
+	suite.NoError(suite.client.UpdateAbortAction(testJobID, ""))
+}
@@ -0,0 +1,337 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/leader"
+	"github.com/uber/peloton/pkg/common/metrics"
+)
+
+var (
+	// DebugMetricsComponents are the components whose metrics can be
+	// pulled, by the role of their leader
+	DebugMetricsComponents = map[string]string{
+		"jobmgr":  common.JobManagerRole,
+		"resmgr":  common.ResourceManagerRole,
+		"hostmgr": common.HostManagerRole,
+	}
+
+	// promLabelValueEscaper escapes the label values of the Prometheus text
+	// exposition format
+	promLabelValueEscaper = strings.NewReplacer(
+		`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+	// debugMetricsQuantiles are the quantiles of the summaries the timers
+	// are converted to
+	debugMetricsQuantiles = []float64{0.5, 0.9, 0.99}
+
+	// used for testing
+	debugMetricsOutput     io.Writer = os.Stdout
+	debugMetricsHTTPClient           = &http.Client{}
+)
+
+// DebugMetricsAction prints a snapshot of the metrics of the leader of a
+// component in the Prometheus text exposition format, keeping only the
+// metrics whose name starts with filter if it is set. The HTTP address of
+// the leader is only known with zookeeper discovery.
+func DebugMetricsAction(
+	discovery leader.Discovery,
+	component string,
+	filter string,
+	timeout time.Duration) error {
+	role, ok := DebugMetricsComponents[component]
+	if !ok {
+		return fmt.Errorf("unknown component %s", component)
+	}
+	ids, ok := discovery.(leader.IDDiscovery)
+	if !ok {
+		return fmt.Errorf("the HTTP address of the %s leader is only "+
+			"known with zookeeper discovery", component)
+	}
+	id, err := ids.GetLeaderID(role)
+	if err != nil {
+		return err
+	}
+	address := fmt.Sprintf("%s:%d", id.IP, id.HTTPPort)
+
+	client := *debugMetricsHTTPClient
+	client.Timeout = timeout
+	resp, err := client.Get(
+		fmt.Sprintf("http://%s%s", address, metrics.SnapshotPath))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to get the metrics of %s %s: %s %s",
+			component, address, resp.Status, bytes.TrimSpace(body))
+	}
+
+	snapshot := &metrics.Snapshot{}
+	if err := json.Unmarshal(body, snapshot); err != nil {
+		return fmt.Errorf("invalid metrics from %s %s: %v",
+			component, address, err)
+	}
+	return writePrometheusText(debugMetricsOutput, snapshot, filter)
+}
+
+// promSample is a sample of a Prometheus metric family
+type promSample struct {
+	// suffix is appended to the name of the family, e.g. _count
+	suffix string
+	labels string
+	value  float64
+}
+
+// promFamily is a Prometheus metric family
+type promFamily struct {
+	name    string
+	kind    string
+	samples []promSample
+}
+
+// writePrometheusText writes a metrics snapshot in the Prometheus text
+// exposition format, keeping only the metrics whose tally or Prometheus name
+// starts with filter. The counters are untyped, as they are the increments
+// since the last flush rather than monotonic totals, the timers are
+// converted to summaries, and the histograms to histograms without a sum,
+// which a snapshot does not know.
+func writePrometheusText(
+	w io.Writer,
+	snapshot *metrics.Snapshot,
+	filter string) error {
+	families := make(map[string]*promFamily)
+	family := func(name string, kind string) *promFamily {
+		metricName := promMetricName(name)
+		if f, ok := families[metricName]; ok && f.kind != kind {
+			// tally names which only differ by characters invalid in
+			// Prometheus names may be of different kinds
+			metricName += "_" + kind
+		}
+		f, ok := families[metricName]
+		if !ok {
+			f = &promFamily{name: metricName, kind: kind}
+			families[metricName] = f
+		}
+		return f
+	}
+	keep := func(name string) bool {
+		return strings.HasPrefix(name, filter) ||
+			strings.HasPrefix(promMetricName(name), filter)
+	}
+
+	for _, c := range snapshot.Counters {
+		if keep(c.Name) {
+			f := family(c.Name, "untyped")
+			f.samples = append(f.samples,
+				promSample{labels: promLabels(c.Tags, "", ""), value: c.Value})
+		}
+	}
+	for _, g := range snapshot.Gauges {
+		if keep(g.Name) {
+			f := family(g.Name, "gauge")
+			f.samples = append(f.samples,
+				promSample{labels: promLabels(g.Tags, "", ""), value: g.Value})
+		}
+	}
+	for _, t := range snapshot.Timers {
+		if keep(t.Name) {
+			f := family(t.Name, "summary")
+			f.samples = append(f.samples, timerSamples(t)...)
+		}
+	}
+	for _, h := range snapshot.Histograms {
+		if keep(h.Name) {
+			f := family(h.Name, "histogram")
+			f.samples = append(f.samples, histogramSamples(h)...)
+		}
+	}
+
+	names := make([]string, 0, len(families))
+	for name := range families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	out := bufio.NewWriter(w)
+	for _, name := range names {
+		f := families[name]
+		fmt.Fprintf(out, "# TYPE %s %s\n", f.name, f.kind)
+		for _, s := range f.samples {
+			fmt.Fprintf(out, "%s%s%s %s\n",
+				f.name, s.suffix, s.labels, promValue(s.value))
+		}
+	}
+	return out.Flush()
+}
+
+// timerSamples returns the samples of the summary of a timer: the
+// quantiles of its values, their sum and count
+func timerSamples(t *metrics.TimerSnapshot) []promSample {
+	values := append([]float64(nil), t.Values...)
+	sort.Float64s(values)
+
+	var samples []promSample
+	for _, q := range debugMetricsQuantiles {
+		samples = append(samples, promSample{
+			labels: promLabels(t.Tags, "quantile", promValue(q)),
+			value:  quantile(values, q),
+		})
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	labels := promLabels(t.Tags, "", "")
+	return append(samples,
+		promSample{suffix: "_sum", labels: labels, value: sum},
+		promSample{suffix: "_count", labels: labels, value: float64(len(values))},
+	)
+}
+
+// quantile returns the nearest rank quantile q of sorted values, or NaN if
+// there is no value
+func quantile(sorted []float64, q float64) float64 {
+	if len(sorted) == 0 {
+		return math.NaN()
+	}
+	rank := int(math.Ceil(q*float64(len(sorted)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	return sorted[rank]
+}
+
+// histogramSamples returns the cumulative buckets and the count of a
+// histogram, whose last bucket has no upper bound
+func histogramSamples(h *metrics.HistogramSnapshot) []promSample {
+	var samples []promSample
+	count := int64(0)
+	hasInf := false
+	for _, b := range h.Buckets {
+		count += b.Count
+		bound := b.UpperBound
+		if bound >= math.MaxFloat64 {
+			bound = math.Inf(1)
+			hasInf = true
+		}
+		samples = append(samples, promSample{
+			suffix: "_bucket",
+			labels: promLabels(h.Tags, "le", promValue(bound)),
+			value:  float64(count),
+		})
+	}
+	if !hasInf {
+		samples = append(samples, promSample{
+			suffix: "_bucket",
+			labels: promLabels(h.Tags, "le", "+Inf"),
+			value:  float64(count),
+		})
+	}
+	return append(samples, promSample{
+		suffix: "_count",
+		labels: promLabels(h.Tags, "", ""),
+		value:  float64(count),
+	})
+}
+
+// promMetricName replaces the characters which are invalid in a Prometheus
+// metric name, e.g. the dots separating the tally scopes, by underscores
+func promMetricName(name string) string {
+	return promName(name, true)
+}
+
+// promName replaces the characters invalid in a Prometheus metric name,
+// or label name if colons are not allowed, by underscores
+func promName(name string, allowColon bool) string {
+	var b strings.Builder
+	for i, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '_',
+			r == ':' && allowColon:
+		case r >= '0' && r <= '9':
+			if i == 0 {
+				b.WriteRune('_')
+			}
+		default:
+			r = '_'
+		}
+		b.WriteRune(r)
+	}
+	if b.Len() == 0 {
+		return "_"
+	}
+	return b.String()
+}
+
+// promLabels returns the labels of a sample from the tags of a metric,
+// sorted by name, with an extra label if extraName is set
+func promLabels(tags map[string]string, extraName string, extraValue string) string {
+	labels := make(map[string]string, len(tags)+1)
+	for k, v := range tags {
+		labels[promName(k, false)] = v
+	}
+	if extraName != "" {
+		labels[extraName] = extraValue
+	}
+	if len(labels) == 0 {
+		return ""
+	}
+
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	pairs := make([]string, 0, len(names))
+	for _, name := range names {
+		pairs = append(pairs, fmt.Sprintf("%s=\"%s\"",
+			name, promLabelValueEscaper.Replace(labels[name])))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// promValue formats a sample value like Prometheus does
+func promValue(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case math.IsNaN(v):
+		return "NaN"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/leader"
+	"github.com/uber/peloton/pkg/common/metrics"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+	"github.com/stretchr/testify/suite"
+)
+
+// appURLDiscovery is a discovery which only knows the app URLs of the
+// leaders, like the static discovery
+type appURLDiscovery struct{}
+
+func (d *appURLDiscovery) GetAppURL(role string) (*url.URL, error) {
+	return &url.URL{Host: "127.0.0.1:5392"}, nil
+}
+
+type debugMetricsTestSuite struct {
+	suite.Suite
+	server    *httptest.Server
+	status    int
+	body      []byte
+	discovery *fakeIDDiscovery
+	output    *bytes.Buffer
+	oldOutput io.Writer
+}
+
+func (suite *debugMetricsTestSuite) SetupTest() {
+	body, err := ioutil.ReadFile(
+		filepath.Join("testdata", "metrics_snapshot.json"))
+	suite.Require().NoError(err)
+	suite.body = body
+	suite.status = http.StatusOK
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(metrics.SnapshotPath,
+		func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(suite.status)
+			w.Write(suite.body)
+		})
+	suite.server = httptest.NewServer(mux)
+
+	host, port, err := net.SplitHostPort(suite.server.Listener.Addr().String())
+	suite.Require().NoError(err)
+	httpPort, err := strconv.Atoi(port)
+	suite.Require().NoError(err)
+	suite.discovery = &fakeIDDiscovery{
+		ids: map[string]*leader.ID{
+			common.HostManagerRole: {IP: host, HTTPPort: httpPort},
+		},
+		errs: map[string]error{},
+	}
+
+	suite.output = &bytes.Buffer{}
+	suite.oldOutput = debugMetricsOutput
+	debugMetricsOutput = suite.output
+}
+
+func (suite *debugMetricsTestSuite) TearDownTest() {
+	suite.server.Close()
+	debugMetricsOutput = suite.oldOutput
+}
+
+func TestDebugMetrics(t *testing.T) {
+	suite.Run(t, new(debugMetricsTestSuite))
+}
+
+// parse parses the output with the Prometheus text parser
+func (suite *debugMetricsTestSuite) parse() map[string]*dto.MetricFamily {
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(
+		bytes.NewReader(suite.output.Bytes()))
+	suite.Require().NoError(err, suite.output.String())
+	return families
+}
+
+// TestDebugMetricsAction tests converting the fixture snapshot to the
+// Prometheus text exposition format
+func (suite *debugMetricsTestSuite) TestDebugMetricsAction() {
+	suite.NoError(
+		DebugMetricsAction(suite.discovery, "hostmgr", "", time.Second))
+
+	expected, err := ioutil.ReadFile(
+		filepath.Join("testdata", "metrics_snapshot.prom.golden"))
+	suite.NoError(err)
+	suite.Equal(string(expected), suite.output.String())
+
+	families := suite.parse()
+	suite.Len(families, 7)
+
+	acquired := families["hostmanager_offers_acquired"]
+	suite.Equal(dto.MetricType_UNTYPED, acquired.GetType())
+	suite.Len(acquired.GetMetric(), 2)
+	suite.Equal("pool", acquired.GetMetric()[0].GetLabel()[0].GetName())
+	suite.Equal("reserved", acquired.GetMetric()[0].GetLabel()[0].GetValue())
+	suite.Equal(42.0, acquired.GetMetric()[0].GetUntyped().GetValue())
+
+	ready := families["hostmanager_hosts_ready"]
+	suite.Equal(dto.MetricType_GAUGE, ready.GetType())
+	suite.Equal(`dca"1"`, ready.GetMetric()[0].GetLabel()[0].GetValue())
+	suite.Equal(12.5, ready.GetMetric()[0].GetGauge().GetValue())
+
+	acquire := families["hostmanager_api_acquire_host_offers"]
+	suite.Equal(dto.MetricType_SUMMARY, acquire.GetType())
+	summary := acquire.GetMetric()[0].GetSummary()
+	suite.Equal(uint64(5), summary.GetSampleCount())
+	suite.InDelta(0.02, summary.GetSampleSum(), 1e-9)
+	suite.Len(summary.GetQuantile(), 3)
+	suite.Equal(0.5, summary.GetQuantile()[0].GetQuantile())
+	suite.Equal(0.003, summary.GetQuantile()[0].GetValue())
+	suite.Equal(0.99, summary.GetQuantile()[2].GetQuantile())
+	suite.Equal(0.01, summary.GetQuantile()[2].GetValue())
+
+	release := families["hostmanager_api_release_host_offers"]
+	summary = release.GetMetric()[0].GetSummary()
+	suite.Equal(uint64(0), summary.GetSampleCount())
+	suite.True(math.IsNaN(summary.GetQuantile()[0].GetValue()))
+
+	age := families["hostmanager_offers_age"]
+	suite.Equal(dto.MetricType_HISTOGRAM, age.GetType())
+	histogram := age.GetMetric()[0].GetHistogram()
+	suite.Equal(uint64(8), histogram.GetSampleCount())
+	suite.Len(histogram.GetBucket(), 3)
+	suite.Equal(10.0, histogram.GetBucket()[1].GetUpperBound())
+	suite.Equal(uint64(7), histogram.GetBucket()[1].GetCumulativeCount())
+	suite.True(math.IsInf(histogram.GetBucket()[2].GetUpperBound(), 1))
+	suite.Equal(uint64(8), histogram.GetBucket()[2].GetCumulativeCount())
+
+	size := families["hostmanager_offers_size"]
+	histogram = size.GetMetric()[0].GetHistogram()
+	suite.Equal(uint64(7), histogram.GetSampleCount())
+	suite.Len(histogram.GetBucket(), 3)
+	suite.True(math.IsInf(histogram.GetBucket()[2].GetUpperBound(), 1))
+}
+
+// TestDebugMetricsActionFilter tests keeping the metrics by tally or
+// Prometheus name prefix
+func (suite *debugMetricsTestSuite) TestDebugMetricsActionFilter() {
+	for _, filter := range []string{"hostmanager.offers", "hostmanager_offers"} {
+		suite.output.Reset()
+		suite.NoError(DebugMetricsAction(
+			suite.discovery, "hostmgr", filter, time.Second))
+		families := suite.parse()
+		suite.Len(families, 3, filter)
+		suite.Contains(families, "hostmanager_offers_acquired")
+		suite.Contains(families, "hostmanager_offers_age")
+		suite.Contains(families, "hostmanager_offers_size")
+	}
+
+	suite.output.Reset()
+	suite.NoError(DebugMetricsAction(
+		suite.discovery, "hostmgr", "jobmgr", time.Second))
+	suite.Empty(suite.output.String())
+}
+
+// TestDebugMetricsActionErrors tests the failures to get the metrics
+func (suite *debugMetricsTestSuite) TestDebugMetricsActionErrors() {
+	suite.Error(DebugMetricsAction(
+		suite.discovery, "placement", "", time.Second))
+
+	err := DebugMetricsAction(&appURLDiscovery{}, "hostmgr", "", time.Second)
+	suite.Error(err)
+	suite.Contains(err.Error(), "zookeeper discovery")
+
+	suite.discovery.errs[common.HostManagerRole] = fmt.Errorf("no leader")
+	suite.Error(DebugMetricsAction(
+		suite.discovery, "hostmgr", "", time.Second))
+	delete(suite.discovery.errs, common.HostManagerRole)
+
+	suite.status = http.StatusNotImplemented
+	suite.body = []byte("metrics scope does not support snapshots\n")
+	err = DebugMetricsAction(suite.discovery, "hostmgr", "", time.Second)
+	suite.Error(err)
+	suite.Contains(err.Error(), "501 Not Implemented")
+
+	suite.status = http.StatusOK
+	suite.body = []byte("{")
+	suite.Error(DebugMetricsAction(
+		suite.discovery, "hostmgr", "", time.Second))
+	suite.Empty(suite.output.String())
+}
+
+// TestWritePrometheusTextNames tests converting tally names and tags which
+// are invalid in Prometheus, and names which collide once converted
+func (suite *debugMetricsTestSuite) TestWritePrometheusTextNames() {
+	snapshot := &metrics.Snapshot{}
+	suite.NoError(json.Unmarshal([]byte(`{
+		"counters": [{"name": "2xx.responses", "tags": {"http-code": "200"}, "value": 3}],
+		"gauges": [{"name": "2xx-responses", "value": 1}]
+	}`), snapshot))
+
+	suite.NoError(writePrometheusText(suite.output, snapshot, ""))
+	suite.Equal(
+		"# TYPE _2xx_responses untyped\n"+
+			"_2xx_responses{http_code=\"200\"} 3\n"+
+			"# TYPE _2xx_responses_gauge gauge\n"+
+			"_2xx_responses_gauge 1\n",
+		suite.output.String())
+	suite.parse()
+}
@@ -0,0 +1,135 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"sync"
+
+	gogoproto "github.com/gogo/protobuf/proto"
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"go.uber.org/yarpc/pkg/procedure"
+)
+
+// rpcProtoFiles are the proto files of the services called by the client,
+// their generated packages are imported by client.go
+var rpcProtoFiles = []string{
+	"peloton/api/v0/host/svc/host_svc.proto",
+	"peloton/api/v0/job/job.proto",
+	"peloton/api/v0/respool/respool.proto",
+	"peloton/api/v0/task/task.proto",
+	"peloton/api/v0/update/svc/update_svc.proto",
+	"peloton/api/v0/volume/svc/volume_svc.proto",
+	"peloton/api/v1alpha/job/stateless/svc/stateless_svc.proto",
+	"peloton/api/v1alpha/pod/svc/pod_svc.proto",
+	"peloton/api/v1alpha/watch/svc/watch_svc.proto",
+	"peloton/private/hostmgr/hostsvc/hostsvc.proto",
+	"peloton/private/resmgrsvc/resmgrsvc.proto",
+}
+
+// rpcPatchedServices are the v0 services whose names are patched in the
+// generated code, see scripts/patch-v0-api-rpc.sh
+var rpcPatchedServices = map[string]string{
+	"peloton.api.v0.job.JobManager":          "peloton.api.job.JobManager",
+	"peloton.api.v0.task.TaskManager":        "peloton.api.task.TaskManager",
+	"peloton.api.v0.respool.ResourceManager": "peloton.api.respool.ResourceManager",
+}
+
+// rpcMessageTypes are the types of the request and response of a procedure
+type rpcMessageTypes struct {
+	request  reflect.Type
+	response reflect.Type
+}
+
+var (
+	rpcProceduresOnce sync.Once
+	// rpcProcedures are the message types of the procedures of the services
+	// called by the client, by procedure name
+	rpcProcedures map[string]rpcMessageTypes
+)
+
+// resolveRPCMessages returns new request and response messages of a
+// procedure called by the client, it is the message resolver of the RPCs
+// printed by --debug-rpc
+func resolveRPCMessages(name string) (gogoproto.Message, gogoproto.Message) {
+	rpcProceduresOnce.Do(func() {
+		rpcProcedures = loadRPCProcedures(rpcProtoFiles)
+	})
+	types, ok := rpcProcedures[name]
+	if !ok {
+		return nil, nil
+	}
+	return newRPCMessage(types.request), newRPCMessage(types.response)
+}
+
+// loadRPCProcedures returns the message types of the procedures of the
+// services of the registered proto files
+func loadRPCProcedures(files []string) map[string]rpcMessageTypes {
+	procedures := make(map[string]rpcMessageTypes)
+	for _, file := range files {
+		fd, err := decodeFileDescriptor(proto.FileDescriptor(file))
+		if err != nil {
+			continue
+		}
+		for _, service := range fd.GetService() {
+			serviceName := fd.GetPackage() + "." + service.GetName()
+			if patched, ok := rpcPatchedServices[serviceName]; ok {
+				serviceName = patched
+			}
+			for _, method := range service.GetMethod() {
+				request := proto.MessageType(
+					strings.TrimPrefix(method.GetInputType(), "."))
+				response := proto.MessageType(
+					strings.TrimPrefix(method.GetOutputType(), "."))
+				if request == nil || response == nil {
+					continue
+				}
+				procedures[procedure.ToName(serviceName, method.GetName())] =
+					rpcMessageTypes{request: request, response: response}
+			}
+		}
+	}
+	return procedures
+}
+
+// decodeFileDescriptor decodes a gzipped file descriptor registered by
+// generated code
+func decodeFileDescriptor(gz []byte) (*descriptor.FileDescriptorProto, error) {
+	r, err := gzip.NewReader(bytes.NewReader(gz))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	var fd descriptor.FileDescriptorProto
+	if err := proto.Unmarshal(b, &fd); err != nil {
+		return nil, err
+	}
+	return &fd, nil
+}
+
+// newRPCMessage returns a new message of a registered message type
+func newRPCMessage(t reflect.Type) gogoproto.Message {
+	message, _ := reflect.New(t.Elem()).Interface().(gogoproto.Message)
+	return message
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+
+	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveRPCMessages(t *testing.T) {
+	tt := []struct {
+		procedure string
+		request   interface{}
+		response  interface{}
+	}{
+		{
+			// v0 services are called with their patched names
+			procedure: "peloton.api.job.JobManager::Create",
+			request:   &job.CreateRequest{},
+			response:  &job.CreateResponse{},
+		},
+		{
+			procedure: "peloton.api.respool.ResourceManager::CreateResourcePool",
+			request:   &respool.CreateRequest{},
+			response:  &respool.CreateResponse{},
+		},
+		{
+			procedure: "peloton.api.v0.host.svc.HostService::QueryHosts",
+			request:   &hostsvc.QueryHostsRequest{},
+			response:  &hostsvc.QueryHostsResponse{},
+		},
+	}
+	for _, test := range tt {
+		request, response := resolveRPCMessages(test.procedure)
+		assert.Equal(t, test.request, request, test.procedure)
+		assert.Equal(t, test.response, response, test.procedure)
+	}
+
+	request, response := resolveRPCMessages("peloton.api.v0.job.JobManager::Create")
+	assert.Nil(t, request)
+	assert.Nil(t, response)
+}
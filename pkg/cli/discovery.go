@@ -0,0 +1,84 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/leader"
+)
+
+var (
+	// leaderRoles are the peloton roles the client connects to
+	leaderRoles = []string{
+		common.JobManagerRole,
+		common.ResourceManagerRole,
+		common.HostManagerRole,
+	}
+
+	// leaderWaitBackoff is the delay before the first retry of the
+	// discovery of a leader, doubled for every further retry up to
+	// leaderWaitMaxBackoff
+	leaderWaitBackoff    = 500 * time.Millisecond
+	leaderWaitMaxBackoff = 5 * time.Second
+)
+
+// discoverLeaders returns the URLs of the leaders of the roles. The
+// discovery of a leader is retried with backoff until wait expires,
+// reporting every retry on stderr, and only tried once if wait is zero.
+func discoverLeaders(
+	discovery leader.Discovery,
+	roles []string,
+	wait time.Duration) (map[string]*url.URL, error) {
+	deadline := time.Now().Add(wait)
+	urls := make(map[string]*url.URL, len(roles))
+	for _, role := range roles {
+		backoff := leaderWaitBackoff
+		for {
+			u, err := discovery.GetAppURL(role)
+			if err == nil {
+				urls[role] = u
+				break
+			}
+			if wait <= 0 {
+				return nil, fmt.Errorf(
+					"unable to find the leader of %s: %v", role, err)
+			}
+
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return nil, fmt.Errorf(
+					"timed out after %s waiting for the leader of %s: %v",
+					wait, role, err)
+			}
+			if backoff > remaining {
+				backoff = remaining
+			}
+			fmt.Fprintf(progressOutput,
+				"Waiting for the leader of %s: %v, retrying in %s\n",
+				role, err, backoff)
+			time.Sleep(backoff)
+
+			backoff *= 2
+			if backoff > leaderWaitMaxBackoff {
+				backoff = leaderWaitMaxBackoff
+			}
+		}
+	}
+	return urls, nil
+}
@@ -0,0 +1,141 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/pkg/common"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeDiscovery is a discovery which fails the lookups of a role a number
+// of times before returning its URL
+type fakeDiscovery struct {
+	failures map[string]int
+	calls    map[string]int
+}
+
+func (d *fakeDiscovery) GetAppURL(role string) (*url.URL, error) {
+	d.calls[role]++
+	if d.calls[role] <= d.failures[role] {
+		return nil, fmt.Errorf(
+			"no leader of %s found at zookeeper path /peloton/%s/leader", role, role)
+	}
+	return &url.URL{Host: role + ":5392"}, nil
+}
+
+type discoveryTestSuite struct {
+	suite.Suite
+	output            *bytes.Buffer
+	oldProgressOutput io.Writer
+	oldBackoff        time.Duration
+	oldMaxBackoff     time.Duration
+}
+
+func (suite *discoveryTestSuite) SetupTest() {
+	suite.output = &bytes.Buffer{}
+	suite.oldProgressOutput = progressOutput
+	suite.oldBackoff, suite.oldMaxBackoff = leaderWaitBackoff, leaderWaitMaxBackoff
+	progressOutput = suite.output
+	leaderWaitBackoff, leaderWaitMaxBackoff = time.Millisecond, 2*time.Millisecond
+}
+
+func (suite *discoveryTestSuite) TearDownTest() {
+	progressOutput = suite.oldProgressOutput
+	leaderWaitBackoff, leaderWaitMaxBackoff = suite.oldBackoff, suite.oldMaxBackoff
+}
+
+func TestDiscovery(t *testing.T) {
+	suite.Run(t, new(discoveryTestSuite))
+}
+
+// newFakeDiscovery returns a fake discovery failing the lookups of the
+// roles the given number of times
+func newFakeDiscovery(failures map[string]int) *fakeDiscovery {
+	return &fakeDiscovery{failures: failures, calls: make(map[string]int)}
+}
+
+// TestDiscoverLeaders tests that the leaders are found without waiting if
+// they are available
+func (suite *discoveryTestSuite) TestDiscoverLeaders() {
+	leaderWaitBackoff = time.Hour
+	discovery := newFakeDiscovery(nil)
+	urls, err := discoverLeaders(discovery, leaderRoles, time.Hour)
+	suite.NoError(err)
+	suite.Len(urls, 3)
+	suite.Equal(common.JobManagerRole+":5392", urls[common.JobManagerRole].Host)
+	suite.Equal(common.HostManagerRole+":5392", urls[common.HostManagerRole].Host)
+	for _, role := range leaderRoles {
+		suite.Equal(1, discovery.calls[role], role)
+	}
+	suite.Empty(suite.output.String())
+}
+
+// TestDiscoverLeadersEventually tests that the discovery is retried until
+// the leaders are found
+func (suite *discoveryTestSuite) TestDiscoverLeadersEventually() {
+	discovery := newFakeDiscovery(map[string]int{
+		common.JobManagerRole:  3,
+		common.HostManagerRole: 1,
+	})
+	urls, err := discoverLeaders(discovery, leaderRoles, time.Minute)
+	suite.NoError(err)
+	suite.Len(urls, 3)
+	suite.Equal(4, discovery.calls[common.JobManagerRole])
+	suite.Equal(1, discovery.calls[common.ResourceManagerRole])
+	suite.Equal(2, discovery.calls[common.HostManagerRole])
+	suite.Equal(4, strings.Count(suite.output.String(), "Waiting for the leader of"))
+	suite.Contains(suite.output.String(),
+		"Waiting for the leader of "+common.JobManagerRole+
+			": no leader of "+common.JobManagerRole+
+			" found at zookeeper path /peloton/"+common.JobManagerRole+
+			"/leader, retrying in 1ms\n")
+	suite.Contains(suite.output.String(), "retrying in 2ms\n")
+}
+
+// TestDiscoverLeadersTimeout tests that the discovery fails with the role
+// and the last error once the wait expires
+func (suite *discoveryTestSuite) TestDiscoverLeadersTimeout() {
+	discovery := newFakeDiscovery(map[string]int{common.ResourceManagerRole: 1 << 30})
+	_, err := discoverLeaders(discovery, leaderRoles, 20*time.Millisecond)
+	suite.EqualError(err,
+		"timed out after 20ms waiting for the leader of "+
+			common.ResourceManagerRole+": no leader of "+
+			common.ResourceManagerRole+" found at zookeeper path /peloton/"+
+			common.ResourceManagerRole+"/leader")
+	suite.True(discovery.calls[common.ResourceManagerRole] > 1)
+	suite.Zero(discovery.calls[common.HostManagerRole])
+}
+
+// TestDiscoverLeadersNoWait tests that the discovery is not retried
+// without a wait
+func (suite *discoveryTestSuite) TestDiscoverLeadersNoWait() {
+	discovery := newFakeDiscovery(map[string]int{common.JobManagerRole: 1})
+	_, err := discoverLeaders(discovery, leaderRoles, 0)
+	suite.EqualError(err,
+		"unable to find the leader of "+common.JobManagerRole+
+			": no leader of "+common.JobManagerRole+
+			" found at zookeeper path /peloton/"+common.JobManagerRole+"/leader")
+	suite.Equal(1, discovery.calls[common.JobManagerRole])
+	suite.Empty(suite.output.String())
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/uber/peloton/pkg/cli/history"
+)
+
+const (
+	historyFormatHeader = "Time\tUser\tCluster\tRequest ID\tOutcome\tCommand\n"
+	historyFormatBody   = "%s\t%s\t%s\t%s\t%s\t%s\n"
+
+	// DefaultHistoryLast is the default number of commands printed by
+	// history
+	DefaultHistoryLast = 20
+)
+
+// HistoryAction prints the last commands of the local history, oldest
+// first, or all of them if last is 0
+func HistoryAction(file *history.File, last uint32, jsonOutput bool) error {
+	records, err := file.Last(int(last))
+	if err != nil {
+		return err
+	}
+	if jsonOutput {
+		if records == nil {
+			records = []history.Record{}
+		}
+		printResponseJSON(records)
+		return nil
+	}
+
+	defer tabWriter.Flush()
+	if len(records) == 0 {
+		fmt.Fprintf(tabWriter, "No command recorded in %s\n", file.Path())
+		return nil
+	}
+	fmt.Fprint(tabWriter, historyFormatHeader)
+	for _, r := range records {
+		fmt.Fprintf(tabWriter, historyFormatBody,
+			r.Time.Format(time.RFC3339),
+			r.User,
+			r.Cluster,
+			r.RequestID,
+			r.Outcome,
+			historyCommand(r.Args),
+		)
+	}
+	return nil
+}
+
+// historyCommand returns the command line of recorded args, with the args
+// containing spaces quoted
+func historyCommand(args []string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg == "" || strings.ContainsAny(arg, " \t\n\"'") {
+			arg = strconv.Quote(arg)
+		}
+		quoted = append(quoted, arg)
+	}
+	return strings.Join(quoted, " ")
+}
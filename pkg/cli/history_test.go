@@ -0,0 +1,129 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/uber/peloton/pkg/cli/history"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type historyTestSuite struct {
+	suite.Suite
+	dir          string
+	file         *history.File
+	table        *bytes.Buffer
+	output       *fakeOutputter
+	oldTabWriter *tabwriter.Writer
+	oldOutputter outputter
+}
+
+func (suite *historyTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "peloton-cli-history")
+	suite.Require().NoError(err)
+	suite.dir = dir
+	suite.file = history.NewFile(filepath.Join(dir, "history.jsonl"), 0)
+	suite.table = &bytes.Buffer{}
+	suite.output = &fakeOutputter{}
+	suite.oldTabWriter = tabWriter
+	suite.oldOutputter = cliOutPutter
+	tabWriter = tabwriter.NewWriter(suite.table, 0, 0, 1, ' ', 0)
+	cliOutPutter = suite.output
+}
+
+func (suite *historyTestSuite) TearDownTest() {
+	tabWriter = suite.oldTabWriter
+	cliOutPutter = suite.oldOutputter
+	os.RemoveAll(suite.dir)
+}
+
+func TestHistory(t *testing.T) {
+	suite.Run(t, new(historyTestSuite))
+}
+
+// appendRecords appends a successful and a failed command to the history
+func (suite *historyTestSuite) appendRecords() []history.Record {
+	records := []history.Record{
+		{
+			Time:       time.Date(2019, 1, 2, 3, 4, 5, 0, time.UTC),
+			User:       "alice",
+			Cluster:    "dev",
+			Args:       []string{"--yes", "job", "stop", testJobID},
+			RequestID:  "request-1",
+			Procedures: []string{"peloton.api.v0.task.TaskManager::Stop"},
+			Outcome:    history.OutcomeSucceeded,
+		},
+		{
+			Time:       time.Date(2019, 1, 2, 4, 5, 6, 0, time.UTC),
+			User:       "bob",
+			Args:       []string{"host", "maintenance", "start", "host-1", "--note", "bad disk"},
+			RequestID:  "request-2",
+			Procedures: []string{"peloton.api.v0.host.svc.HostService::StartMaintenance"},
+			Outcome:    history.OutcomeFailed,
+			Error:      "unavailable",
+		},
+	}
+	for _, r := range records {
+		suite.Require().NoError(suite.file.Append(r))
+	}
+	return records
+}
+
+// TestHistoryAction tests printing the last commands of the history
+func (suite *historyTestSuite) TestHistoryAction() {
+	suite.appendRecords()
+
+	suite.NoError(HistoryAction(suite.file, DefaultHistoryLast, false))
+	suite.Equal(
+		"Time                 User  Cluster Request ID Outcome   Command\n"+
+			"2019-01-02T03:04:05Z alice dev     request-1  succeeded --yes job stop "+testJobID+"\n"+
+			"2019-01-02T04:05:06Z bob           request-2  failed    host maintenance start host-1 --note \"bad disk\"\n",
+		suite.table.String())
+
+	suite.table.Reset()
+	suite.NoError(HistoryAction(suite.file, 1, false))
+	suite.Contains(suite.table.String(), "request-2")
+	suite.NotContains(suite.table.String(), "request-1")
+}
+
+// TestHistoryActionJSON tests printing the records of the history in JSON
+func (suite *historyTestSuite) TestHistoryActionJSON() {
+	records := suite.appendRecords()
+
+	suite.NoError(HistoryAction(suite.file, 0, true))
+	var printed []history.Record
+	suite.NoError(json.Unmarshal([]byte(suite.output.Out), &printed))
+	suite.Equal(records, printed)
+	suite.Empty(suite.table.String())
+}
+
+// TestHistoryActionEmpty tests printing an empty history
+func (suite *historyTestSuite) TestHistoryActionEmpty() {
+	suite.NoError(HistoryAction(suite.file, DefaultHistoryLast, false))
+	suite.Equal("No command recorded in "+suite.file.Path()+"\n",
+		suite.table.String())
+
+	suite.NoError(HistoryAction(suite.file, DefaultHistoryLast, true))
+	suite.Equal("[]\n", suite.output.Out)
+}
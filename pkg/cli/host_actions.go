@@ -15,24 +15,35 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
+	"time"
 
 	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
 	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
 	pb_task "github.com/uber/peloton/.gen/peloton/api/v0/task"
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 
+	"github.com/uber/peloton/pkg/common/constraints"
 	"github.com/uber/peloton/pkg/hostmgr/scalar"
+
+	"go.uber.org/multierr"
 )
 
 const (
-	hostQueryFormatHeader = "Hostname\tIP\tState\n"
+	hostQueryFormatHeader = "Hostname\tIP\t%s\n"
 	hostQueryFormatBody   = "%s\t%s\t%s\n"
+
+	maintenanceScheduleFormatHeader = "Start\tEnd\tHostnames\n"
+	maintenanceScheduleFormatBody   = "%s\t%s\t%s\n"
 	hostSeparator         = ","
 	getHostsFormatHeader  = "Hostname\tCPU\tGPU\tMEM\tDisk\tState\t\n"
 	getHostsFormatBody    = "%s\t%.2f\t%.2f\t%.2f MB\t%.2f MB\t%s\t\n"
+
+	// hostWaitRPCTimeout is the timeout of a single host state poll
+	hostWaitRPCTimeout = 5 * time.Second
 )
 
 // HostMaintenanceStartAction is the action for starting host maintenance. StartMaintenance puts the host(s)
@@ -41,45 +52,206 @@ const (
 // Primitives for more info). The hosts are first drained of tasks before they are put into maintenance
 // by posting to /machine/down endpoint of Mesos Master.
 // The hosts transition from UP to DRAINING and finally to DOWN.
-func (c *Client) HostMaintenanceStartAction(hosts string) error {
+// The hosts are sent in batches of at most batchSize hosts, all at once if
+// it is 0, the remaining batches are still sent when one fails and the
+// result of each host is printed at the end. With dryRun the batches are
+// only printed.
+// With wait set, it blocks until all hosts are DOWN, polling their states
+// every pollInterval and reporting the number of hosts down on stderr, and
+// returns an error if they are not down after waitTimeout.
+func (c *Client) HostMaintenanceStartAction(
+	hosts string,
+	batchSize int,
+	dryRun bool,
+	wait bool,
+	waitTimeout time.Duration,
+	pollInterval time.Duration) error {
 	hostnames, err := c.ExtractHostnames(hosts, hostSeparator)
 	if err != nil {
 		return err
 	}
-
-	request := &host_svc.StartMaintenanceRequest{
-		Hostnames: hostnames,
-	}
-	_, err = c.hostClient.StartMaintenance(c.ctx, request)
+	batches, err := hostBatches(hostnames, batchSize)
 	if err != nil {
 		return err
 	}
+	if dryRun {
+		printHostBatchPlan("start maintenance", batches)
+		return nil
+	}
+	confirmed, err := c.confirm(func() (string, error) {
+		return c.hostMaintenanceSummary("Start maintenance", hostnames)
+	})
+	if err != nil || !confirmed {
+		return err
+	}
 
-	fmt.Fprintf(tabWriter, "Started draining hosts\n")
-	tabWriter.Flush()
-	return nil
+	ctx, release := withInterrupt(c.ctx)
+	results := sendHostBatches(ctx, c.limiter, batches, func(
+		ctx context.Context,
+		batch []string) error {
+		_, err := c.hostClient.StartMaintenance(
+			ctx,
+			&host_svc.StartMaintenanceRequest{Hostnames: batch})
+		return err
+	})
+	release()
+	draining, errs := printHostBatchResults("start maintenance", results)
+	if len(draining) > 0 {
+		fmt.Fprintf(tabWriter, "Started draining hosts\n")
+		tabWriter.Flush()
+	}
+	if !wait || len(draining) == 0 {
+		return errs
+	}
+	// the hosts which failed are not waited for
+	return multierr.Append(
+		errs,
+		c.waitForHostsDown(draining, waitTimeout, pollInterval))
+}
+
+// waitForHostsDown polls the states of the hosts every pollInterval until
+// all of them are down, reporting the progress on stderr
+func (c *Client) waitForHostsDown(
+	hostnames []string,
+	timeout time.Duration,
+	pollInterval time.Duration) error {
+	expired := time.After(timeout)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	progress := c.startProgress("waiting", "Hosts down", len(hostnames))
+	defer progress.Finish()
+	for {
+		down, err := c.countHostsDown(hostnames)
+		if err != nil {
+			return err
+		}
+		progress.SetCompleted(down)
+		if down == len(hostnames) {
+			progress.Finish()
+			fmt.Fprintf(tabWriter, "All %d host(s) are down\n", down)
+			tabWriter.Flush()
+			return nil
+		}
+
+		select {
+		case <-expired:
+			return fmt.Errorf("timed out waiting for hosts to be down, "+
+				"%d of %d host(s) down", down, len(hostnames))
+		case <-ticker.C:
+		}
+	}
+}
+
+// countHostsDown returns the number of the hosts which are down
+func (c *Client) countHostsDown(hostnames []string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), hostWaitRPCTimeout)
+	defer cancel()
+
+	response, err := c.hostClient.QueryHosts(ctx, &host_svc.QueryHostsRequest{
+		HostStates: []host.HostState{host.HostState_HOST_STATE_DOWN},
+	})
+	if err != nil {
+		return 0, err
+	}
+	down := make(map[string]bool)
+	for _, h := range response.GetHostInfos() {
+		down[h.GetHostname()] = true
+	}
+	count := 0
+	for _, hostname := range hostnames {
+		if down[hostname] {
+			count++
+		}
+	}
+	return count, nil
 }
 
 // HostMaintenanceCompleteAction is the action for completing host maintenance. Complete maintenance brings UP a host
 // which is in maintenance by posting to /machine/up endpoint of Mesos Master i.e. the machine transitions from DOWN to
 // UP state (Please check Mesos Maintenance Primitives for more info)
-func (c *Client) HostMaintenanceCompleteAction(hosts string) error {
+// The hosts are sent in batches of at most batchSize hosts like for
+// HostMaintenanceStartAction.
+func (c *Client) HostMaintenanceCompleteAction(
+	hosts string,
+	batchSize int,
+	dryRun bool) error {
 	hostnames, err := c.ExtractHostnames(hosts, hostSeparator)
 	if err != nil {
 		return err
 	}
+	batches, err := hostBatches(hostnames, batchSize)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		printHostBatchPlan("complete maintenance", batches)
+		return nil
+	}
+	confirmed, err := c.confirm(func() (string, error) {
+		return c.hostMaintenanceSummary("Complete maintenance", hostnames)
+	})
+	if err != nil || !confirmed {
+		return err
+	}
 
-	request := &host_svc.CompleteMaintenanceRequest{
-		Hostnames: hostnames,
+	ctx, release := withInterrupt(c.ctx)
+	results := sendHostBatches(ctx, c.limiter, batches, func(
+		ctx context.Context,
+		batch []string) error {
+		_, err := c.hostClient.CompleteMaintenance(
+			ctx,
+			&host_svc.CompleteMaintenanceRequest{Hostnames: batch})
+		return err
+	})
+	release()
+	completed, errs := printHostBatchResults("complete maintenance", results)
+	if len(completed) > 0 {
+		fmt.Fprintf(tabWriter, "Maintenance completed\n")
+		tabWriter.Flush()
 	}
-	_, err = c.hostClient.CompleteMaintenance(c.ctx, request)
+	return errs
+}
+
+// HostMaintenanceScheduleViewAction is the action for viewing the
+// maintenance windows currently posted to the Mesos master. Windows without
+// end last until the maintenance of their hosts is completed.
+func (c *Client) HostMaintenanceScheduleViewAction() error {
+	response, err := c.hostClient.GetMaintenanceSchedule(
+		c.ctx, &host_svc.GetMaintenanceScheduleRequest{})
 	if err != nil {
 		return err
 	}
+	printMaintenanceScheduleResponse(response, c.Debug)
+	return nil
+}
 
-	fmt.Fprintf(tabWriter, "Maintenance completed\n")
+func printMaintenanceScheduleResponse(
+	r *host_svc.GetMaintenanceScheduleResponse,
+	debug bool) {
+	if debug {
+		printResponseJSON(r)
+	} else {
+		if len(r.GetWindows()) == 0 {
+			fmt.Fprintf(tabWriter, "No maintenance windows found\n")
+			return
+		}
+		fmt.Fprintf(tabWriter, maintenanceScheduleFormatHeader)
+		for _, w := range r.GetWindows() {
+			end := w.GetEndTime()
+			if end == "" {
+				end = "-"
+			}
+			fmt.Fprintf(
+				tabWriter,
+				maintenanceScheduleFormatBody,
+				w.GetStartTime(),
+				end,
+				strings.Join(w.GetHostnames(), hostSeparator),
+			)
+		}
+	}
 	tabWriter.Flush()
-	return nil
 }
 
 // HostQueryAction is the action for querying hosts by states. This can be to used to monitor the state of the host(s)
@@ -90,7 +262,15 @@ func (c *Client) HostMaintenanceCompleteAction(hosts string) error {
 // 										  there will be no further placement of tasks on the host
 //		3.HostState_HOST_STATE_DRAINED - There are no tasks running on this host and it is ready to be 'DOWN'ed
 // 		4.HostState_HOST_STATE_DOWN - The host is in maintenance.
-func (c *Client) HostQueryAction(states string) error {
+// The hosts can be filtered by a label selector of k=v, k!=v, k in (...),
+// k notin (...), k and !k expressions on the Mesos attributes of the hosts,
+// e.g. rack in (r7,r8).
+func (c *Client) HostQueryAction(states string, labels string) error {
+	requirements, err := parseLabelSelector(labels)
+	if err != nil {
+		return err
+	}
+
 	var hostStates []host.HostState
 	for _, state := range strings.Split(states, hostSeparator) {
 		if state != "" {
@@ -105,12 +285,72 @@ func (c *Client) HostQueryAction(states string) error {
 	if err != nil {
 		return err
 	}
+	if len(requirements) > 0 {
+		// the host query API does not support attributes, filter the hosts
+		// by the attributes of their Mesos agents
+		response, err = c.filterHostsByLabels(response, requirements)
+		if err != nil {
+			return err
+		}
+	}
 
-	printHostQueryResponse(response, c.Debug)
-	return nil
+	return c.printFormattedColumns(response, func() (columnTable, error) {
+		return hostQueryColumns(response)
+	}, func() error {
+		printHostQueryResponse(response, c.Debug, c.colorizer())
+		return nil
+	})
+}
+
+// filterHostsByLabels returns the hosts of a host query response whose
+// Mesos agent attributes satisfy the label requirements
+func (c *Client) filterHostsByLabels(
+	response *host_svc.QueryHostsResponse,
+	requirements []labelRequirement) (*host_svc.QueryHostsResponse, error) {
+	agents, err := c.hostMgrClient.GetMesosAgentInfo(
+		c.ctx, &hostsvc.GetMesosAgentInfoRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if agents.GetError() != nil {
+		return nil, fmt.Errorf("unable to get host attributes: %s",
+			agents.GetError().GetHostNotFound().GetMessage())
+	}
+
+	hostLabels := make(map[string]map[string][]string)
+	for _, agent := range agents.GetAgents() {
+		info := agent.GetAgentInfo()
+		labels := make(map[string][]string)
+		labelValues := constraints.GetHostLabelValues(
+			info.GetHostname(), info.GetAttributes())
+		for key, values := range labelValues {
+			for value := range values {
+				labels[key] = append(labels[key], value)
+			}
+		}
+		hostLabels[info.GetHostname()] = labels
+	}
+
+	filtered := &host_svc.QueryHostsResponse{}
+	for _, h := range response.GetHostInfos() {
+		labels, ok := hostLabels[h.GetHostname()]
+		if !ok {
+			// hosts without an agent only have the hostname label
+			labels = map[string][]string{
+				constraints.HostNameKey: {h.GetHostname()},
+			}
+		}
+		if matchLabelSelector(requirements, labels) {
+			filtered.HostInfos = append(filtered.HostInfos, h)
+		}
+	}
+	return filtered, nil
 }
 
-func printHostQueryResponse(r *host_svc.QueryHostsResponse, debug bool) {
+func printHostQueryResponse(
+	r *host_svc.QueryHostsResponse,
+	debug bool,
+	colors stateColorizer) {
 	if debug {
 		printResponseJSON(r)
 	} else {
@@ -118,14 +358,14 @@ func printHostQueryResponse(r *host_svc.QueryHostsResponse, debug bool) {
 			fmt.Fprintf(tabWriter, "No hosts found\n")
 			return
 		}
-		fmt.Fprintf(tabWriter, hostQueryFormatHeader)
+		fmt.Fprintf(tabWriter, hostQueryFormatHeader, colors.header("State"))
 		for _, h := range r.GetHostInfos() {
 			fmt.Fprintf(
 				tabWriter,
 				hostQueryFormatBody,
 				h.GetHostname(),
 				h.GetIp(),
-				h.GetState(),
+				colors.state(h.GetState().String()),
 			)
 		}
 	}
@@ -15,11 +15,14 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesosmaster "github.com/uber/peloton/.gen/mesos/v1/master"
 	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
 	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
 	hostmocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
@@ -62,6 +65,7 @@ func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceStartAction() {
 		hostClient: suite.mockHostmgr,
 		dispatcher: nil,
 		ctx:        suite.ctx,
+		AssumeYes:  true,
 	}
 
 	resp := &hostsvc.StartMaintenanceResponse{}
@@ -69,35 +73,106 @@ func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceStartAction() {
 	suite.mockHostmgr.EXPECT().
 		StartMaintenance(gomock.Any(), gomock.Any()).
 		Return(resp, nil)
-	err := c.HostMaintenanceStartAction("hostname")
+	err := c.HostMaintenanceStartAction("hostname", 0, false, false, 0, 0)
 	suite.NoError(err)
 
 	// Test StartMaintenance error
 	suite.mockHostmgr.EXPECT().
 		StartMaintenance(gomock.Any(), gomock.Any()).
 		Return(nil, fmt.Errorf("fake StartMaintenance error"))
-	err = c.HostMaintenanceStartAction("hostname")
+	err = c.HostMaintenanceStartAction("hostname", 0, false, false, 0, 0)
 	suite.Error(err)
 
 	// Test empty hostname error
-	err = c.HostMaintenanceStartAction("")
+	err = c.HostMaintenanceStartAction("", 0, false, false, 0, 0)
 	suite.Error(err)
 
 	//Test duplicate hostname error
-	err = c.HostMaintenanceStartAction("hostname, hostname")
+	err = c.HostMaintenanceStartAction("hostname, hostname", 0, false, false, 0, 0)
 	suite.Error(err)
 
 	// Test invalid input error
-	err = c.HostMaintenanceStartAction("hostname,,")
+	err = c.HostMaintenanceStartAction("hostname,,", 0, false, false, 0, 0)
 	suite.Error(err)
 }
 
+// TestClientHostMaintenanceStartActionWait tests waiting for the hosts to
+// be down after starting maintenance
+func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceStartActionWait() {
+	c := Client{
+		hostClient: suite.mockHostmgr,
+		ctx:        suite.ctx,
+		AssumeYes:  true,
+	}
+	progress := &bytes.Buffer{}
+	oldProgressOutput, oldProgressIsTerminal := progressOutput, progressIsTerminal
+	progressOutput = progress
+	progressIsTerminal = func() bool { return false }
+	defer func() {
+		progressOutput, progressIsTerminal = oldProgressOutput, oldProgressIsTerminal
+	}()
+
+	downRequest := &hostsvc.QueryHostsRequest{
+		HostStates: []host.HostState{host.HostState_HOST_STATE_DOWN},
+	}
+	suite.mockHostmgr.EXPECT().
+		StartMaintenance(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.StartMaintenanceResponse{}, nil)
+	gomock.InOrder(
+		suite.mockHostmgr.EXPECT().
+			QueryHosts(gomock.Any(), downRequest).
+			Return(&hostsvc.QueryHostsResponse{
+				HostInfos: []*host.HostInfo{
+					{Hostname: "host-1"},
+					{Hostname: "host-3"},
+				},
+			}, nil),
+		suite.mockHostmgr.EXPECT().
+			QueryHosts(gomock.Any(), downRequest).
+			Return(&hostsvc.QueryHostsResponse{
+				HostInfos: []*host.HostInfo{
+					{Hostname: "host-1"},
+					{Hostname: "host-2"},
+				},
+			}, nil),
+	)
+	suite.NoError(c.HostMaintenanceStartAction(
+		"host-1,host-2", 0, false, true, time.Minute, time.Millisecond))
+	suite.Equal("Hosts down 2/2\n", progress.String())
+
+	// Test timeout
+	progress.Reset()
+	suite.mockHostmgr.EXPECT().
+		StartMaintenance(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.StartMaintenanceResponse{}, nil)
+	suite.mockHostmgr.EXPECT().
+		QueryHosts(gomock.Any(), downRequest).
+		Return(&hostsvc.QueryHostsResponse{
+			HostInfos: []*host.HostInfo{{Hostname: "host-1"}},
+		}, nil)
+	suite.EqualError(
+		c.HostMaintenanceStartAction("host-1,host-2", 0, false, true, 0, time.Hour),
+		"timed out waiting for hosts to be down, 1 of 2 host(s) down")
+	suite.Equal("Hosts down 1/2\n", progress.String())
+
+	// Test QueryHosts error
+	suite.mockHostmgr.EXPECT().
+		StartMaintenance(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.StartMaintenanceResponse{}, nil)
+	suite.mockHostmgr.EXPECT().
+		QueryHosts(gomock.Any(), downRequest).
+		Return(nil, fmt.Errorf("fake QueryHosts error"))
+	suite.Error(c.HostMaintenanceStartAction(
+		"host-1", 0, false, true, time.Minute, time.Millisecond))
+}
+
 func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceCompleteAction() {
 	c := Client{
 		Debug:      false,
 		hostClient: suite.mockHostmgr,
 		dispatcher: nil,
 		ctx:        suite.ctx,
+		AssumeYes:  true,
 	}
 
 	resp := &hostsvc.CompleteMaintenanceResponse{}
@@ -105,29 +180,86 @@ func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceCompleteAction()
 	suite.mockHostmgr.EXPECT().
 		CompleteMaintenance(gomock.Any(), gomock.Any()).
 		Return(resp, nil)
-	err := c.HostMaintenanceCompleteAction("hostname")
+	err := c.HostMaintenanceCompleteAction("hostname", 0, false)
 	suite.NoError(err)
 
 	//Test CompleteMaintenance error
 	suite.mockHostmgr.EXPECT().
 		CompleteMaintenance(gomock.Any(), gomock.Any()).
 		Return(nil, fmt.Errorf("fake CompleteMaintenance error"))
-	err = c.HostMaintenanceCompleteAction("hostname")
+	err = c.HostMaintenanceCompleteAction("hostname", 0, false)
 	suite.Error(err)
 
 	// Test empty hostname error
-	err = c.HostMaintenanceCompleteAction("")
+	err = c.HostMaintenanceCompleteAction("", 0, false)
 	suite.Error(err)
 
 	//Test duplicate hostname error
-	err = c.HostMaintenanceCompleteAction("hostname, hostname")
+	err = c.HostMaintenanceCompleteAction("hostname, hostname", 0, false)
 	suite.Error(err)
 
 	// Test invalid input error
-	err = c.HostMaintenanceStartAction("hostname,,")
+	err = c.HostMaintenanceStartAction("hostname,,", 0, false, false, 0, 0)
 	suite.Error(err)
 }
 
+func (suite *hostmgrActionsTestSuite) TestClientHostMaintenanceScheduleViewAction() {
+	c := Client{
+		Debug:      false,
+		hostClient: suite.mockHostmgr,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	tt := []struct {
+		debug bool
+		resp  *hostsvc.GetMaintenanceScheduleResponse
+		err   error
+	}{
+		{
+			resp: &hostsvc.GetMaintenanceScheduleResponse{
+				Windows: []*host.MaintenanceWindow{
+					{
+						Hostnames: []string{"host1", "host2"},
+						StartTime: "2017-07-14T02:40:00Z",
+					},
+					{
+						Hostnames: []string{"host3"},
+						StartTime: "2017-07-14T02:40:00Z",
+						EndTime:   "2017-07-14T03:40:00Z",
+					},
+				},
+			},
+		},
+		{
+			debug: true,
+			resp: &hostsvc.GetMaintenanceScheduleResponse{
+				Windows: []*host.MaintenanceWindow{
+					{Hostnames: []string{"host1"}},
+				},
+			},
+		},
+		{
+			resp: &hostsvc.GetMaintenanceScheduleResponse{},
+		},
+		{
+			err: fmt.Errorf("fake GetMaintenanceSchedule error"),
+		},
+	}
+
+	for _, t := range tt {
+		c.Debug = t.debug
+		suite.mockHostmgr.EXPECT().
+			GetMaintenanceSchedule(gomock.Any(), gomock.Any()).
+			Return(t.resp, t.err)
+		if t.err != nil {
+			suite.Error(c.HostMaintenanceScheduleViewAction())
+		} else {
+			suite.NoError(c.HostMaintenanceScheduleViewAction())
+		}
+	}
+}
+
 func (suite *hostmgrActionsTestSuite) TestClientHostQueryAction() {
 	c := Client{
 		Debug:      false,
@@ -172,11 +304,95 @@ func (suite *hostmgrActionsTestSuite) TestClientHostQueryAction() {
 			QueryHosts(gomock.Any(), gomock.Any()).
 			Return(t.resp, t.err)
 		if t.err != nil {
-			suite.Error(c.HostQueryAction(""))
+			suite.Error(c.HostQueryAction("", ""))
 		} else {
-			suite.NoError(c.HostQueryAction("HOST_STATE_DRAINING"))
+			suite.NoError(c.HostQueryAction("HOST_STATE_DRAINING", ""))
+		}
+	}
+}
+
+// TestClientHostQueryActionLabels tests filtering the queried hosts by the
+// attributes of their Mesos agents
+func (suite *hostmgrActionsTestSuite) TestClientHostQueryActionLabels() {
+	mockHostMgr := hostmgrMocks.NewMockInternalHostServiceYARPCClient(
+		suite.mockCtrl)
+	output := &fakeOutputter{}
+	oldOutputter := cliOutPutter
+	cliOutPutter = output
+	defer func() { cliOutPutter = oldOutputter }()
+
+	c := Client{
+		hostClient:    suite.mockHostmgr,
+		hostMgrClient: mockHostMgr,
+		ctx:           suite.ctx,
+		Output:        OutputCSV,
+		Columns:       "hostname",
+	}
+
+	text := func(name, value string) *mesos.Attribute {
+		valueType := mesos.Value_TEXT
+		return &mesos.Attribute{
+			Name: &name,
+			Type: &valueType,
+			Text: &mesos.Value_Text{Value: &value},
 		}
 	}
+	agent := func(hostname string, attributes ...*mesos.Attribute) *mesosmaster.Response_GetAgents_Agent {
+		return &mesosmaster.Response_GetAgents_Agent{
+			AgentInfo: &mesos.AgentInfo{
+				Hostname:   &hostname,
+				Attributes: attributes,
+			},
+		}
+	}
+
+	tt := []struct {
+		labels    string
+		hostnames string
+	}{
+		{"rack=r7", "hostname\nhost-1\nhost-2\n"},
+		{"rack=r7,zone!=z1", "hostname\nhost-2\n"},
+		{"zone", "hostname\nhost-1\nhost-3\n"},
+		{"!zone", "hostname\nhost-2\nhost-4\n"},
+		{"hostname=host-4", "hostname\nhost-4\n"},
+	}
+	for _, t := range tt {
+		suite.mockHostmgr.EXPECT().
+			QueryHosts(gomock.Any(), &hostsvc.QueryHostsRequest{
+				HostStates: []host.HostState{host.HostState_HOST_STATE_DRAINING},
+			}).
+			Return(&hostsvc.QueryHostsResponse{
+				HostInfos: []*host.HostInfo{
+					{Hostname: "host-1"},
+					{Hostname: "host-2"},
+					{Hostname: "host-3"},
+					{Hostname: "host-4"},
+				},
+			}, nil)
+		mockHostMgr.EXPECT().
+			GetMesosAgentInfo(gomock.Any(), &hostmgrsvc.GetMesosAgentInfoRequest{}).
+			Return(&hostmgrsvc.GetMesosAgentInfoResponse{
+				Agents: []*mesosmaster.Response_GetAgents_Agent{
+					agent("host-1", text("rack", "r7"), text("zone", "z1")),
+					agent("host-2", text("rack", "r7")),
+					agent("host-3", text("rack", "r8"), text("zone", "z2")),
+				},
+			}, nil)
+
+		suite.NoError(c.HostQueryAction("HOST_STATE_DRAINING", t.labels), t.labels)
+		suite.Equal(t.hostnames, output.Out, t.labels)
+	}
+
+	// invalid selectors fail before querying the hosts
+	suite.Error(c.HostQueryAction("", "rack=r7,=r8"))
+
+	suite.mockHostmgr.EXPECT().
+		QueryHosts(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.QueryHostsResponse{}, nil)
+	mockHostMgr.EXPECT().
+		GetMesosAgentInfo(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake GetMesosAgentInfo error"))
+	suite.Error(c.HostQueryAction("", "rack=r7"))
 }
 
 type hostmgrActionsInternalTestSuite struct {
@@ -0,0 +1,319 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
+
+	"go.uber.org/multierr"
+)
+
+const (
+	// hostDrainMaxPollErrors is the number of consecutive failed polls of
+	// the tasks on the drained hosts after which the drain is aborted
+	hostDrainMaxPollErrors = 5
+
+	hostDrainFormatHeader = "Hostname\tResult\tTasks\tDuration\t\n"
+	hostDrainFormatBody   = "%s\t%s\t%d\t%s\t\n"
+)
+
+// hostDrain is the progress of the drain of a single host
+type hostDrain struct {
+	hostname string
+	started  time.Time
+	finished time.Time
+	// tasks is the number of tasks on the host at the last poll, -1 before
+	// the first one
+	tasks    int
+	drained  bool
+	timedOut bool
+	undone   bool
+	err      error
+}
+
+// result returns the result of the drain for the summary
+func (d *hostDrain) result() string {
+	switch {
+	case d.drained:
+		return "drained"
+	case d.undone:
+		return "timed out, back up"
+	case d.timedOut:
+		return "timed out"
+	default:
+		return fmt.Sprintf("failed: %v", d.err)
+	}
+}
+
+// duration returns how long the drain of the host took
+func (d *hostDrain) duration() string {
+	if d.started.IsZero() {
+		return "-"
+	}
+	return d.finished.Sub(d.started).Round(time.Second).String()
+}
+
+// HostDrainAction drains hosts of their Peloton tasks. Maintenance is
+// started on at most parallel hosts at a time, and the tasks on these hosts
+// are polled every pollInterval until none is left. A host still running
+// tasks timeout after its maintenance started fails to drain, and with
+// undoOnTimeout its maintenance is cancelled to bring it back up. A failed
+// poll is retried at the next poll interval, and the drain is aborted after
+// hostDrainMaxPollErrors consecutive failed polls. The
+// progress of every host is printed as its number of tasks changes, or
+// written as progress events with the json progress format, followed by a
+// summary, and an error is returned if any host failed to drain.
+func (c *Client) HostDrainAction(
+	hosts string,
+	timeout time.Duration,
+	parallel int,
+	undoOnTimeout bool,
+	pollInterval time.Duration) error {
+	if parallel <= 0 {
+		return fmt.Errorf("invalid parallelism %d, it must be positive", parallel)
+	}
+	hostnames, err := c.ExtractHostnames(hosts, hostSeparator)
+	if err != nil {
+		return err
+	}
+	confirmed, err := c.confirm(func() (string, error) {
+		return c.hostMaintenanceSummary("Drain", hostnames)
+	})
+	if err != nil || !confirmed {
+		return err
+	}
+
+	drains := make([]*hostDrain, 0, len(hostnames))
+	for _, hostname := range hostnames {
+		drains = append(drains, &hostDrain{hostname: hostname, tasks: -1})
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	progress := c.startProgressSteps(len(drains))
+	var active []*hostDrain
+	next, pollErrors := 0, 0
+	for next < len(drains) || len(active) > 0 {
+		if free := parallel - len(active); free > 0 && next < len(drains) {
+			end := next + free
+			if end > len(drains) {
+				end = len(drains)
+			}
+			active = append(active,
+				c.startHostDrains(drains[next:end], progress)...)
+			next = end
+			if len(active) == 0 {
+				continue
+			}
+		}
+
+		counts, err := c.countTasksOnHosts(active)
+		if err != nil {
+			pollErrors++
+			if pollErrors >= hostDrainMaxPollErrors {
+				progress.Finish("Failed to poll the tasks on the hosts: %v", err)
+				return err
+			}
+			progress.Report("draining",
+				"Failed to poll the tasks on the hosts, retrying: %v", err)
+			tabWriter.Flush()
+			<-ticker.C
+			continue
+		}
+		pollErrors = 0
+		active = updateHostDrains(active, counts, timeout, progress)
+
+		// the next hosts are started right away when some drain finished
+		if len(active) > 0 && (len(active) == parallel || next == len(drains)) {
+			<-ticker.C
+		}
+	}
+
+	var errs error
+	if undoOnTimeout {
+		errs = c.undoHostDrains(drains, progress)
+	}
+
+	drained := 0
+	fmt.Fprint(tabWriter, hostDrainFormatHeader)
+	for _, d := range drains {
+		if d.drained {
+			drained++
+		}
+		tasks := d.tasks
+		if tasks < 0 {
+			tasks = 0
+		}
+		fmt.Fprintf(tabWriter, hostDrainFormatBody,
+			d.hostname, d.result(), tasks, d.duration())
+	}
+	fmt.Fprintf(tabWriter, "Drained %d of %d host(s)\n", drained, len(drains))
+	tabWriter.Flush()
+	progress.Finish("Drained %d of %d host(s)", drained, len(drains))
+
+	if failed := len(drains) - drained; failed > 0 {
+		errs = multierr.Append(errs, fmt.Errorf(
+			"%d of %d host(s) failed to drain", failed, len(drains)))
+	}
+	return errs
+}
+
+// startHostDrains starts maintenance on the hosts with a single request,
+// once the rate limiter of the client allows it, and returns the drains
+// which started
+func (c *Client) startHostDrains(
+	drains []*hostDrain,
+	progress *progressSteps) []*hostDrain {
+	hostnames := make([]string, 0, len(drains))
+	for _, d := range drains {
+		hostnames = append(hostnames, d.hostname)
+	}
+
+	err := c.limiter.Wait(c.ctx)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), hostWaitRPCTimeout)
+		defer cancel()
+		_, err = c.hostClient.StartMaintenance(ctx, &host_svc.StartMaintenanceRequest{
+			Hostnames: hostnames,
+		})
+	}
+	now := time.Now()
+	for _, d := range drains {
+		if err != nil {
+			d.err = err
+			progress.Complete()
+			progress.Report("starting", "Failed to start maintenance on host %s: %v",
+				d.hostname, err)
+			continue
+		}
+		d.started = now
+		progress.Report("starting", "Started draining host %s", d.hostname)
+	}
+	tabWriter.Flush()
+
+	if err != nil {
+		return nil
+	}
+	return drains
+}
+
+// countTasksOnHosts returns the number of tasks on each host being drained,
+// i.e. the tasks placed on the hosts tracked by the resource manager
+func (c *Client) countTasksOnHosts(drains []*hostDrain) (map[string]int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), hostWaitRPCTimeout)
+	defer cancel()
+
+	hostnames := make([]string, 0, len(drains))
+	for _, d := range drains {
+		hostnames = append(hostnames, d.hostname)
+	}
+	response, err := c.resMgrClient.GetTasksByHosts(ctx, &resmgrsvc.GetTasksByHostsRequest{
+		Hostnames: hostnames,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response.GetError() != nil {
+		return nil, errors.New(response.GetError().GetMessage())
+	}
+
+	counts := make(map[string]int)
+	for hostname, tasks := range response.GetHostTasksMap() {
+		counts[hostname] = len(tasks.GetTasks())
+	}
+	return counts, nil
+}
+
+// updateHostDrains updates the drains with the number of tasks on their
+// hosts, reporting their progress, and returns the drains still active
+func updateHostDrains(
+	drains []*hostDrain,
+	counts map[string]int,
+	timeout time.Duration,
+	progress *progressSteps) []*hostDrain {
+	now := time.Now()
+	var active []*hostDrain
+	for _, d := range drains {
+		count := counts[d.hostname]
+		if count != d.tasks && count > 0 {
+			progress.Report("draining", "Host %s has %d task(s) left",
+				d.hostname, count)
+		}
+		d.tasks = count
+
+		switch {
+		case count == 0:
+			d.drained = true
+			d.finished = now
+			progress.Complete()
+			progress.Report("draining", "Host %s drained in %s",
+				d.hostname, d.duration())
+		case now.Sub(d.started) >= timeout:
+			d.timedOut = true
+			d.finished = now
+			progress.Complete()
+			progress.Report("draining", "Host %s timed out with %d task(s) left",
+				d.hostname, count)
+		default:
+			active = append(active, d)
+		}
+	}
+	tabWriter.Flush()
+	return active
+}
+
+// undoHostDrains cancels the maintenance of the hosts which timed out,
+// bringing them back up
+func (c *Client) undoHostDrains(
+	drains []*hostDrain,
+	progress *progressSteps) error {
+	var timedOut []*hostDrain
+	var hostnames []string
+	for _, d := range drains {
+		if d.timedOut {
+			timedOut = append(timedOut, d)
+			hostnames = append(hostnames, d.hostname)
+		}
+	}
+	if len(timedOut) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), hostWaitRPCTimeout)
+	defer cancel()
+	_, err := c.hostClient.CancelMaintenance(ctx, &host_svc.CancelMaintenanceRequest{
+		Hostnames: hostnames,
+	})
+	if err != nil {
+		progress.Report("undoing", "Failed to bring %d host(s) back up: %v",
+			len(hostnames), err)
+		tabWriter.Flush()
+		return fmt.Errorf("failed to bring the hosts which timed out back up: %v", err)
+	}
+	for _, d := range timedOut {
+		d.undone = true
+	}
+	progress.Report("undoing", "Brought %d host(s) which timed out back up",
+		len(hostnames))
+	tabWriter.Flush()
+	return nil
+}
@@ -0,0 +1,299 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	hostmocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/private/resmgr"
+	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
+	resmocks "github.com/uber/peloton/.gen/peloton/private/resmgrsvc/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+type hostDrainTestSuite struct {
+	suite.Suite
+	mockCtrl     *gomock.Controller
+	mockHost     *hostmocks.MockHostServiceYARPCClient
+	mockRes      *resmocks.MockResourceManagerServiceYARPCClient
+	output       *bytes.Buffer
+	oldTabWriter *tabwriter.Writer
+	client       Client
+}
+
+func (suite *hostDrainTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockHost = hostmocks.NewMockHostServiceYARPCClient(suite.mockCtrl)
+	suite.mockRes = resmocks.NewMockResourceManagerServiceYARPCClient(suite.mockCtrl)
+	suite.output = &bytes.Buffer{}
+	suite.oldTabWriter = tabWriter
+	tabWriter = tabwriter.NewWriter(suite.output, 0, 0, 1, ' ', 0)
+	suite.client = Client{
+		hostClient:   suite.mockHost,
+		resMgrClient: suite.mockRes,
+		ctx:          context.Background(),
+		AssumeYes:    true,
+	}
+}
+
+func (suite *hostDrainTestSuite) TearDownTest() {
+	tabWriter = suite.oldTabWriter
+	suite.mockCtrl.Finish()
+}
+
+func TestHostDrain(t *testing.T) {
+	suite.Run(t, new(hostDrainTestSuite))
+}
+
+// expectStart expects maintenance to be started on the hosts
+func (suite *hostDrainTestSuite) expectStart(
+	err error,
+	hostnames ...string) *gomock.Call {
+	call := suite.mockHost.EXPECT().
+		StartMaintenance(gomock.Any(), &hostsvc.StartMaintenanceRequest{
+			Hostnames: hostnames,
+		})
+	if err != nil {
+		return call.Return(nil, err)
+	}
+	return call.Return(&hostsvc.StartMaintenanceResponse{}, nil)
+}
+
+// expectPoll expects a poll of the tasks on the hosts, returning the given
+// number of tasks on each host
+func (suite *hostDrainTestSuite) expectPoll(
+	hostnames []string,
+	tasks map[string]int) []*gomock.Call {
+	hostTasksMap := make(map[string]*resmgrsvc.TaskList)
+	for hostname, count := range tasks {
+		taskList := &resmgrsvc.TaskList{}
+		for i := 0; i < count; i++ {
+			taskList.Tasks = append(taskList.Tasks, &resmgr.Task{
+				Id:       &peloton.TaskID{Value: fmt.Sprintf("job-1-%d", i)},
+				Hostname: hostname,
+			})
+		}
+		hostTasksMap[hostname] = taskList
+	}
+	return []*gomock.Call{
+		suite.mockRes.EXPECT().
+			GetTasksByHosts(gomock.Any(), &resmgrsvc.GetTasksByHostsRequest{
+				Hostnames: hostnames,
+			}).
+			Return(&resmgrsvc.GetTasksByHostsResponse{
+				HostTasksMap: hostTasksMap,
+			}, nil),
+	}
+}
+
+// expectPollError expects a failed poll of the tasks on the hosts
+func (suite *hostDrainTestSuite) expectPollError(times int) []*gomock.Call {
+	return []*gomock.Call{
+		suite.mockRes.EXPECT().
+			GetTasksByHosts(gomock.Any(), gomock.Any()).
+			Return(nil, errors.New("fake GetTasksByHosts error")).
+			Times(times),
+	}
+}
+
+// inOrder orders the calls of the expectations
+func inOrder(calls ...[]*gomock.Call) {
+	var all []*gomock.Call
+	for _, c := range calls {
+		all = append(all, c...)
+	}
+	gomock.InOrder(all...)
+}
+
+// TestHostDrain tests that the hosts are drained once no task is left on
+// them
+func (suite *hostDrainTestSuite) TestHostDrain() {
+	hostnames := []string{"host-1", "host-2"}
+	inOrder(
+		[]*gomock.Call{suite.expectStart(nil, hostnames...)},
+		suite.expectPoll(hostnames, map[string]int{"host-1": 2, "host-2": 1}),
+		suite.expectPoll(hostnames, map[string]int{"host-1": 1}),
+		suite.expectPoll([]string{"host-1"}, nil),
+	)
+
+	suite.NoError(suite.client.HostDrainAction(
+		"host-1,host-2", time.Minute, 5, true, time.Millisecond))
+	output := suite.output.String()
+	suite.Contains(output, "Host host-1 has 2 task(s) left")
+	suite.Contains(output, "Host host-1 has 1 task(s) left")
+	suite.Contains(output, "Host host-2 drained in")
+	suite.Contains(output, "Host host-1 drained in")
+	suite.Contains(output, "Drained 2 of 2 host(s)")
+}
+
+// TestHostDrainTimeoutUndo tests that the hosts which timed out are brought
+// back up with undo on timeout
+func (suite *hostDrainTestSuite) TestHostDrainTimeoutUndo() {
+	hostnames := []string{"host-1", "host-2"}
+	inOrder(
+		[]*gomock.Call{suite.expectStart(nil, hostnames...)},
+		suite.expectPoll(hostnames, map[string]int{"host-1": 3}),
+		[]*gomock.Call{
+			suite.mockHost.EXPECT().
+				CancelMaintenance(gomock.Any(), &hostsvc.CancelMaintenanceRequest{
+					Hostnames: []string{"host-1"},
+				}).
+				Return(&hostsvc.CancelMaintenanceResponse{}, nil),
+		},
+	)
+
+	suite.EqualError(suite.client.HostDrainAction(
+		"host-1,host-2", 0, 5, true, time.Millisecond),
+		"1 of 2 host(s) failed to drain")
+	output := suite.output.String()
+	suite.Contains(output, "Host host-1 timed out with 3 task(s) left")
+	suite.Contains(output, "Brought 1 host(s) which timed out back up")
+	suite.Regexp("host-1 +timed out, back up +3 ", output)
+	suite.Contains(output, "Drained 1 of 2 host(s)")
+
+	// Test CancelMaintenance error
+	suite.output.Reset()
+	inOrder(
+		[]*gomock.Call{suite.expectStart(nil, "host-1")},
+		suite.expectPoll([]string{"host-1"}, map[string]int{"host-1": 3}),
+		[]*gomock.Call{
+			suite.mockHost.EXPECT().
+				CancelMaintenance(gomock.Any(), gomock.Any()).
+				Return(nil, errors.New("fake CancelMaintenance error")),
+		},
+	)
+	err := suite.client.HostDrainAction("host-1", 0, 5, true, time.Millisecond)
+	suite.Error(err)
+	suite.Contains(err.Error(), "fake CancelMaintenance error")
+	suite.Contains(err.Error(), "1 of 1 host(s) failed to drain")
+	suite.Regexp("host-1 +timed out +3 ", suite.output.String())
+}
+
+// TestHostDrainPartialFailure tests that the hosts are drained at most
+// parallel at a time, and that the failures of some hosts do not stop the
+// drain of the others
+func (suite *hostDrainTestSuite) TestHostDrainPartialFailure() {
+	inOrder(
+		[]*gomock.Call{suite.expectStart(nil, "host-1")},
+		suite.expectPoll([]string{"host-1"}, nil),
+		[]*gomock.Call{
+			suite.expectStart(errors.New("fake StartMaintenance error"), "host-2"),
+			suite.expectStart(nil, "host-3"),
+		},
+		suite.expectPoll([]string{"host-3"}, map[string]int{"host-3": 1}),
+	)
+
+	suite.EqualError(suite.client.HostDrainAction(
+		"host-1,host-2,host-3", 0, 1, false, time.Millisecond),
+		"2 of 3 host(s) failed to drain")
+	output := suite.output.String()
+	suite.Contains(output, "Host host-1 drained in")
+	suite.Contains(output,
+		"Failed to start maintenance on host host-2: fake StartMaintenance error")
+	suite.Regexp("host-2 +failed: fake StartMaintenance error +0 +- ", output)
+	suite.Regexp("host-3 +timed out +1 ", output)
+	suite.Contains(output, "Drained 1 of 3 host(s)")
+}
+
+// TestHostDrainErrors tests the errors of the host list, the parallelism
+// and the polls of the tasks
+func (suite *hostDrainTestSuite) TestHostDrainErrors() {
+	suite.Error(suite.client.HostDrainAction(
+		"host-1", time.Minute, 0, false, time.Millisecond))
+	suite.Error(suite.client.HostDrainAction(
+		"", time.Minute, 1, false, time.Millisecond))
+
+	inOrder(
+		[]*gomock.Call{suite.expectStart(nil, "host-1")},
+		suite.expectPollError(hostDrainMaxPollErrors),
+	)
+	suite.EqualError(suite.client.HostDrainAction(
+		"host-1", time.Minute, 1, false, time.Millisecond),
+		"fake GetTasksByHosts error")
+}
+
+// TestHostDrainPollRetry tests that a failed poll of the tasks is retried
+func (suite *hostDrainTestSuite) TestHostDrainPollRetry() {
+	inOrder(
+		[]*gomock.Call{suite.expectStart(nil, "host-1")},
+		suite.expectPoll([]string{"host-1"}, map[string]int{"host-1": 1}),
+		suite.expectPollError(hostDrainMaxPollErrors-1),
+		suite.expectPoll([]string{"host-1"}, nil),
+	)
+	suite.NoError(suite.client.HostDrainAction(
+		"host-1", time.Minute, 1, false, time.Millisecond))
+	output := suite.output.String()
+	suite.Contains(output,
+		"Failed to poll the tasks on the hosts, retrying: fake GetTasksByHosts error")
+	suite.Contains(output, "Host host-1 drained in")
+}
+
+// TestHostDrainJSONProgress tests that with the json progress format the
+// progress of every phase of the drain is written as progress events, and
+// only the summary is printed
+func (suite *hostDrainTestSuite) TestHostDrainJSONProgress() {
+	var events bytes.Buffer
+	oldProgressOutput := progressOutput
+	progressOutput = &events
+	defer func() { progressOutput = oldProgressOutput }()
+	suite.client.ProgressFormat = ProgressFormatJSON
+
+	hostnames := []string{"host-1", "host-2"}
+	inOrder(
+		[]*gomock.Call{suite.expectStart(nil, hostnames...)},
+		suite.expectPoll(hostnames, map[string]int{"host-1": 3}),
+		[]*gomock.Call{
+			suite.mockHost.EXPECT().
+				CancelMaintenance(gomock.Any(), &hostsvc.CancelMaintenanceRequest{
+					Hostnames: []string{"host-1"},
+				}).
+				Return(&hostsvc.CancelMaintenanceResponse{}, nil),
+		},
+	)
+
+	suite.Error(suite.client.HostDrainAction(
+		"host-1,host-2", 0, 5, true, time.Millisecond))
+
+	parsed, err := parseProgressEvents(events.String())
+	suite.Require().NoError(err)
+	suite.Equal([]string{
+		"starting 0/2",
+		"starting 0/2",
+		"draining 0/2",
+		"draining 1/2",
+		"draining 2/2",
+		"undoing 2/2",
+		"done 2/2",
+	}, progressPhases(parsed))
+	suite.Equal("Host host-1 timed out with 3 task(s) left", parsed[3].Message)
+	suite.Equal("Drained 1 of 2 host(s)", parsed[6].Message)
+
+	output := suite.output.String()
+	suite.NotContains(output, "Started draining host")
+	suite.NotContains(output, "task(s) left")
+	suite.Regexp("host-1 +timed out, back up +3 ", output)
+	suite.Contains(output, "Drained 1 of 2 host(s)")
+}
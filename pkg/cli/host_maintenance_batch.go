@@ -0,0 +1,131 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.uber.org/multierr"
+)
+
+const (
+	hostBatchPlanFormatHeader = "Batch\tHosts\tHostnames\n"
+	hostBatchPlanFormatBody   = "%d\t%d\t%s\n"
+
+	hostBatchResultFormatHeader = "Hostname\tBatch\tResult\tError\n"
+	hostBatchResultFormatBody   = "%s\t%d\t%s\t%s\n"
+)
+
+// hostBatchResult is the result of a maintenance request for a host
+type hostBatchResult struct {
+	hostname string
+	batch    int
+	err      error
+}
+
+// hostBatches splits the hostnames in batches of at most batchSize hosts,
+// in their order, a single batch if batchSize is 0
+func hostBatches(hostnames []string, batchSize int) ([][]string, error) {
+	if batchSize < 0 {
+		return nil, fmt.Errorf("invalid batch size %d", batchSize)
+	}
+	if batchSize == 0 || batchSize > len(hostnames) {
+		batchSize = len(hostnames)
+	}
+	var batches [][]string
+	for start := 0; start < len(hostnames); start += batchSize {
+		end := start + batchSize
+		if end > len(hostnames) {
+			end = len(hostnames)
+		}
+		batches = append(batches, hostnames[start:end])
+	}
+	return batches, nil
+}
+
+// printHostBatchPlan prints the hosts of each batch of a dry run
+func printHostBatchPlan(action string, batches [][]string) {
+	defer tabWriter.Flush()
+	fmt.Fprint(tabWriter, hostBatchPlanFormatHeader)
+	hosts := 0
+	for i, batch := range batches {
+		hosts += len(batch)
+		fmt.Fprintf(tabWriter, hostBatchPlanFormatBody,
+			i+1, len(batch), strings.Join(batch, hostSeparator))
+	}
+	fmt.Fprintf(tabWriter, "Dry run, %s not sent for %d host(s) in "+
+		"%d batch(es)\n", action, hosts, len(batches))
+}
+
+// sendHostBatches sends a request for each batch of hosts in turn, at the
+// rate allowed by limiter, carrying on with the next batches when one
+// fails, and returns the result of each host in their order. The hosts of a
+// batch all succeed or fail together as the host manager updates the Mesos
+// master once per request. The batches not sent once ctx is done fail with
+// its error.
+func sendHostBatches(
+	ctx context.Context,
+	limiter *rateLimiter,
+	batches [][]string,
+	send func(ctx context.Context, hostnames []string) error) []hostBatchResult {
+	var results []hostBatchResult
+	for i, batch := range batches {
+		err := limiter.Wait(ctx)
+		if err == nil {
+			err = send(ctx, batch)
+		}
+		for _, hostname := range batch {
+			results = append(results, hostBatchResult{
+				hostname: hostname,
+				batch:    i + 1,
+				err:      err,
+			})
+		}
+	}
+	return results
+}
+
+// printHostBatchResults prints the result of each host, and returns the
+// hosts which succeeded along with an error for each batch which failed
+func printHostBatchResults(
+	action string,
+	results []hostBatchResult) ([]string, error) {
+	defer tabWriter.Flush()
+
+	var succeeded []string
+	var errs error
+	failedBatches := make(map[int]bool)
+	fmt.Fprint(tabWriter, hostBatchResultFormatHeader)
+	for _, r := range results {
+		result, message := "succeeded", ""
+		if r.err != nil {
+			result, message = "failed", r.err.Error()
+			if !failedBatches[r.batch] {
+				failedBatches[r.batch] = true
+				errs = multierr.Append(errs, fmt.Errorf(
+					"failed to %s for batch %d: %v", action, r.batch, r.err))
+			}
+		} else {
+			succeeded = append(succeeded, r.hostname)
+		}
+		fmt.Fprintf(tabWriter, hostBatchResultFormatBody,
+			r.hostname, r.batch, result, message)
+	}
+	fmt.Fprintf(tabWriter, "%d of %d host(s) succeeded, %d failed\n",
+		len(succeeded), len(results), len(results)-len(succeeded))
+	return succeeded, errs
+}
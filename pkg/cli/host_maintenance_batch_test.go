@@ -0,0 +1,195 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"text/tabwriter"
+
+	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	hostmocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/multierr"
+)
+
+type hostMaintenanceBatchTestSuite struct {
+	suite.Suite
+	mockCtrl     *gomock.Controller
+	mockHost     *hostmocks.MockHostServiceYARPCClient
+	output       *bytes.Buffer
+	oldTabWriter *tabwriter.Writer
+	client       Client
+}
+
+func (suite *hostMaintenanceBatchTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockHost = hostmocks.NewMockHostServiceYARPCClient(suite.mockCtrl)
+	suite.output = &bytes.Buffer{}
+	suite.oldTabWriter = tabWriter
+	tabWriter = tabwriter.NewWriter(suite.output, 0, 0, 1, ' ', 0)
+	suite.client = Client{
+		hostClient: suite.mockHost,
+		ctx:        context.Background(),
+		AssumeYes:  true,
+	}
+}
+
+func (suite *hostMaintenanceBatchTestSuite) TearDownTest() {
+	tabWriter = suite.oldTabWriter
+	suite.mockCtrl.Finish()
+}
+
+func TestHostMaintenanceBatch(t *testing.T) {
+	suite.Run(t, new(hostMaintenanceBatchTestSuite))
+}
+
+// TestHostBatches tests splitting the hosts at the boundaries of the batches
+func (suite *hostMaintenanceBatchTestSuite) TestHostBatches() {
+	hostnames := []string{"host-1", "host-2", "host-3", "host-4"}
+	tt := []struct {
+		batchSize int
+		batches   [][]string
+	}{
+		{
+			batchSize: 0,
+			batches:   [][]string{hostnames},
+		},
+		{
+			batchSize: 1,
+			batches: [][]string{
+				{"host-1"}, {"host-2"}, {"host-3"}, {"host-4"},
+			},
+		},
+		{
+			batchSize: 2,
+			batches: [][]string{
+				{"host-1", "host-2"}, {"host-3", "host-4"},
+			},
+		},
+		{
+			batchSize: 3,
+			batches: [][]string{
+				{"host-1", "host-2", "host-3"}, {"host-4"},
+			},
+		},
+		{
+			batchSize: 4,
+			batches:   [][]string{hostnames},
+		},
+		{
+			batchSize: 5,
+			batches:   [][]string{hostnames},
+		},
+	}
+	for _, t := range tt {
+		batches, err := hostBatches(hostnames, t.batchSize)
+		suite.NoError(err)
+		suite.Equal(t.batches, batches, "batch size %d", t.batchSize)
+	}
+
+	_, err := hostBatches(hostnames, -1)
+	suite.Error(err)
+}
+
+// TestHostMaintenanceStartDryRun tests that a dry run prints the batches
+// without sending them
+func (suite *hostMaintenanceBatchTestSuite) TestHostMaintenanceStartDryRun() {
+	suite.NoError(suite.client.HostMaintenanceStartAction(
+		"host-1,host-2,host-3", 2, true, true, 0, 0))
+	suite.Equal(
+		"Batch Hosts Hostnames\n"+
+			"1     2     host-1,host-2\n"+
+			"2     1     host-3\n"+
+			"Dry run, start maintenance not sent for 3 host(s) in 2 batch(es)\n",
+		suite.output.String())
+}
+
+// TestHostMaintenanceStartBatchFailure tests that the batches after one
+// whose request failed are still sent, and that the hosts of the failed
+// batch are reported as failed
+func (suite *hostMaintenanceBatchTestSuite) TestHostMaintenanceStartBatchFailure() {
+	gomock.InOrder(
+		suite.mockHost.EXPECT().
+			StartMaintenance(gomock.Any(), &hostsvc.StartMaintenanceRequest{
+				Hostnames: []string{"host-1", "host-2"},
+			}).
+			Return(&hostsvc.StartMaintenanceResponse{}, nil),
+		suite.mockHost.EXPECT().
+			StartMaintenance(gomock.Any(), &hostsvc.StartMaintenanceRequest{
+				Hostnames: []string{"host-3", "host-4"},
+			}).
+			Return(nil, errors.New("unknown host host-4")),
+		suite.mockHost.EXPECT().
+			StartMaintenance(gomock.Any(), &hostsvc.StartMaintenanceRequest{
+				Hostnames: []string{"host-5"},
+			}).
+			Return(&hostsvc.StartMaintenanceResponse{}, nil),
+	)
+
+	suite.EqualError(
+		suite.client.HostMaintenanceStartAction(
+			"host-1,host-2,host-3,host-4,host-5", 2, false, false, 0, 0),
+		"failed to start maintenance for batch 2: unknown host host-4")
+	suite.Equal(
+		"Hostname Batch Result    Error\n"+
+			"host-1   1     succeeded \n"+
+			"host-2   1     succeeded \n"+
+			"host-3   2     failed    unknown host host-4\n"+
+			"host-4   2     failed    unknown host host-4\n"+
+			"host-5   3     succeeded \n"+
+			"3 of 5 host(s) succeeded, 2 failed\n"+
+			"Started draining hosts\n",
+		suite.output.String())
+}
+
+// TestHostMaintenanceCompleteErrors tests that an error is returned for
+// each batch which failed, and none if all failed hosts are reported
+func (suite *hostMaintenanceBatchTestSuite) TestHostMaintenanceCompleteErrors() {
+	gomock.InOrder(
+		suite.mockHost.EXPECT().
+			CompleteMaintenance(gomock.Any(), gomock.Any()).
+			Return(nil, errors.New("timeout")),
+		suite.mockHost.EXPECT().
+			CompleteMaintenance(gomock.Any(), gomock.Any()).
+			Return(&hostsvc.CompleteMaintenanceResponse{}, nil),
+		suite.mockHost.EXPECT().
+			CompleteMaintenance(gomock.Any(), gomock.Any()).
+			Return(nil, errors.New("host host-3 not in maintenance")),
+	)
+
+	err := suite.client.HostMaintenanceCompleteAction(
+		"host-1,host-2,host-3", 1, false)
+	suite.Equal([]error{
+		errors.New("failed to complete maintenance for batch 1: timeout"),
+		errors.New("failed to complete maintenance for batch 3: " +
+			"host host-3 not in maintenance"),
+	}, multierr.Errors(err))
+	suite.Contains(suite.output.String(),
+		"1 of 3 host(s) succeeded, 2 failed\nMaintenance completed\n")
+
+	// nothing is reported as completed if all batches failed
+	suite.output.Reset()
+	suite.mockHost.EXPECT().
+		CompleteMaintenance(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("timeout"))
+	suite.Error(suite.client.HostMaintenanceCompleteAction(
+		"host-1,host-2,host-3", 0, false))
+	suite.NotContains(suite.output.String(), "Maintenance completed")
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DefaultMaxHostRangeExpansion is the maximum number of hosts a single
+	// host range like compute[0001-0250].dc1 may expand to, unless
+	// overridden on the Client.
+	DefaultMaxHostRangeExpansion = 4096
+
+	hostRangeOpen      = "["
+	hostRangeClose     = "]"
+	hostRangeSeparator = ","
+	hostRangeDash      = "-"
+)
+
+// maxHostRangeExpansion returns the host range expansion cap of the client
+func (c *Client) maxHostRangeExpansion() int {
+	if c.MaxHostRangeExpansion > 0 {
+		return c.MaxHostRangeExpansion
+	}
+	return DefaultMaxHostRangeExpansion
+}
+
+// splitHosts splits hosts on hostSeparator, ignoring separators inside
+// host range brackets.
+func splitHosts(hosts string, hostSeparator string) []string {
+	var result []string
+	depth, start := 0, 0
+	for i := 0; i < len(hosts); i++ {
+		switch {
+		case strings.HasPrefix(hosts[i:], hostRangeOpen):
+			depth++
+		case strings.HasPrefix(hosts[i:], hostRangeClose):
+			depth--
+		case depth == 0 && strings.HasPrefix(hosts[i:], hostSeparator):
+			result = append(result, hosts[start:i])
+			start = i + len(hostSeparator)
+			i = start - 1
+		}
+	}
+	return append(result, hosts[start:])
+}
+
+// expandHostRange expands the bracket ranges in host, e.g.
+// compute[01-03,07].dc1 expands to compute01.dc1, compute02.dc1,
+// compute03.dc1 and compute07.dc1. Zero padding of the range bounds is
+// preserved and an error is returned if host expands to more than max hosts.
+func expandHostRange(host string, max int) ([]string, error) {
+	begin := strings.Index(host, hostRangeOpen)
+	if begin < 0 {
+		if strings.Contains(host, hostRangeClose) {
+			return nil, fmt.Errorf("Invalid host range %s: unbalanced brackets", host)
+		}
+		return []string{host}, nil
+	}
+	end := strings.Index(host[begin:], hostRangeClose)
+	if end < 0 {
+		return nil, fmt.Errorf("Invalid host range %s: unbalanced brackets", host)
+	}
+	end += begin
+	prefix, spec, rest := host[:begin], host[begin+1:end], host[end+1:]
+	if strings.Contains(spec, hostRangeOpen) || strings.Contains(prefix, hostRangeClose) {
+		return nil, fmt.Errorf("Invalid host range %s: unbalanced brackets", host)
+	}
+
+	values, err := expandHostRangeSpec(spec, max)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid host range %s: %v", host, err)
+	}
+	suffixes, err := expandHostRange(rest, max)
+	if err != nil {
+		return nil, err
+	}
+	if len(values)*len(suffixes) > max {
+		return nil, fmt.Errorf(
+			"Host range %s expands to more than %d hosts", host, max)
+	}
+
+	var result []string
+	for _, value := range values {
+		for _, suffix := range suffixes {
+			result = append(result, prefix+value+suffix)
+		}
+	}
+	return result, nil
+}
+
+// expandHostRangeSpec expands the contents of a single bracket group, a
+// comma separated list of numbers and inclusive number ranges.
+func expandHostRangeSpec(spec string, max int) ([]string, error) {
+	var result []string
+	for _, item := range strings.Split(spec, hostRangeSeparator) {
+		item = strings.TrimSpace(item)
+		bounds := strings.Split(item, hostRangeDash)
+		if len(bounds) > 2 || bounds[0] == "" {
+			return nil, fmt.Errorf("invalid range %q", item)
+		}
+		first, last := bounds[0], bounds[len(bounds)-1]
+		from, err := strconv.ParseUint(first, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q", item)
+		}
+		to, err := strconv.ParseUint(last, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %q", item)
+		}
+		if from > to {
+			return nil, fmt.Errorf("reversed range %q", item)
+		}
+		if to-from >= uint64(max) || len(result)+int(to-from) >= max {
+			return nil, fmt.Errorf("range %q expands to more than %d hosts", item, max)
+		}
+
+		// a leading zero on the lower bound pads all values to its width
+		width := 0
+		if len(first) > 1 && strings.HasPrefix(first, "0") {
+			width = len(first)
+		}
+		for v := from; v <= to; v++ {
+			result = append(result, fmt.Sprintf("%0*d", width, v))
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitHosts(t *testing.T) {
+	assert.Equal(t,
+		[]string{"a", " compute[01-03,07].dc1", "b[1,2]"},
+		splitHosts("a, compute[01-03,07].dc1,b[1,2]", ","))
+	assert.Equal(t, []string{"a", "b"}, splitHosts("a::b", "::"))
+	assert.Equal(t, []string{""}, splitHosts("", ","))
+}
+
+func TestExpandHostRange(t *testing.T) {
+	tt := []struct {
+		host     string
+		max      int
+		expected []string
+		err      string
+	}{
+		{
+			host:     "compute1.dc1",
+			max:      10,
+			expected: []string{"compute1.dc1"},
+		},
+		{
+			host: "compute[0008-0011].dc1",
+			max:  10,
+			expected: []string{
+				"compute0008.dc1",
+				"compute0009.dc1",
+				"compute0010.dc1",
+				"compute0011.dc1",
+			},
+		},
+		{
+			host:     "compute[8-10]",
+			max:      10,
+			expected: []string{"compute8", "compute9", "compute10"},
+		},
+		{
+			host: "compute[01-03,07].dc1",
+			max:  10,
+			expected: []string{
+				"compute01.dc1",
+				"compute02.dc1",
+				"compute03.dc1",
+				"compute07.dc1",
+			},
+		},
+		{
+			host: "rack[1-2]-host[01,03]",
+			max:  10,
+			expected: []string{
+				"rack1-host01",
+				"rack1-host03",
+				"rack2-host01",
+				"rack2-host03",
+			},
+		},
+		{
+			host: "compute[3-1]",
+			max:  10,
+			err:  `Invalid host range compute[3-1]: reversed range "3-1"`,
+		},
+		{
+			host: "compute[1-x]",
+			max:  10,
+			err:  `Invalid host range compute[1-x]: invalid range "1-x"`,
+		},
+		{
+			host: "compute[1-2-3]",
+			max:  10,
+			err:  `Invalid host range compute[1-2-3]: invalid range "1-2-3"`,
+		},
+		{
+			host: "compute[1-3",
+			max:  10,
+			err:  "Invalid host range compute[1-3: unbalanced brackets",
+		},
+		{
+			host: "compute1-3]",
+			max:  10,
+			err:  "Invalid host range compute1-3]: unbalanced brackets",
+		},
+		{
+			host: "compute[0001-2500]",
+			max:  1000,
+			err: `Invalid host range compute[0001-2500]: ` +
+				`range "0001-2500" expands to more than 1000 hosts`,
+		},
+		{
+			host: "compute[1-5,6-11]",
+			max:  10,
+			err: `Invalid host range compute[1-5,6-11]: ` +
+				`range "6-11" expands to more than 10 hosts`,
+		},
+		{
+			host: "rack[1-4]-host[1-3]",
+			max:  10,
+			err:  "Host range rack[1-4]-host[1-3] expands to more than 10 hosts",
+		},
+	}
+
+	for _, test := range tt {
+		hosts, err := expandHostRange(test.host, test.max)
+		if test.err != "" {
+			assert.EqualError(t, err, test.err, test.host)
+			continue
+		}
+		assert.NoError(t, err, test.host)
+		assert.Equal(t, test.expected, hosts, test.host)
+	}
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	// maximum lengths of a hostname and of its labels, per RFC 1123
+	maxHostnameLength      = 253
+	maxHostnameLabelLength = 63
+
+	hostnameLabelSeparator = "."
+)
+
+// expandHost returns the hosts of a host list entry: the address of a
+// bracketed IPv6 literal like [2001:db8::1], whose brackets are not a host
+// range, or the expansion of the host ranges of host.
+func (c *Client) expandHost(host string) ([]string, error) {
+	if strings.HasPrefix(host, hostRangeOpen) &&
+		strings.HasSuffix(host, hostRangeClose) {
+		address := host[len(hostRangeOpen) : len(host)-len(hostRangeClose)]
+		if strings.Contains(address, ":") && net.ParseIP(address) != nil {
+			return []string{address}, nil
+		}
+	}
+	return expandHostRange(host, c.maxHostRangeExpansion())
+}
+
+// validateHostnames checks the hosts expanded from a host list entry,
+// returning the error of the first invalid one. Nothing is checked if
+// SkipHostValidation is set.
+func (c *Client) validateHostnames(hosts []string) error {
+	if c.SkipHostValidation {
+		return nil
+	}
+	for _, host := range hosts {
+		if err := validateHostname(host); err != nil {
+			return fmt.Errorf("%q: %v", host, err)
+		}
+	}
+	return nil
+}
+
+// validateHostname checks that host is an IP address or an RFC 1123
+// hostname, optionally fully qualified with a trailing dot.
+func validateHostname(host string) error {
+	if net.ParseIP(host) != nil {
+		return nil
+	}
+
+	name := strings.TrimSuffix(host, hostnameLabelSeparator)
+	if name == "" {
+		return fmt.Errorf("empty hostname")
+	}
+	if len(name) > maxHostnameLength {
+		return fmt.Errorf(
+			"hostname longer than %d characters", maxHostnameLength)
+	}
+	numeric := true
+	for _, label := range strings.Split(name, hostnameLabelSeparator) {
+		if label == "" {
+			return fmt.Errorf("empty label")
+		}
+		if len(label) > maxHostnameLabelLength {
+			return fmt.Errorf(
+				"label longer than %d characters", maxHostnameLabelLength)
+		}
+		if strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+			return fmt.Errorf("label %s starts or ends with a hyphen", label)
+		}
+		for _, r := range label {
+			switch {
+			case r >= '0' && r <= '9':
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r == '-':
+				numeric = false
+			default:
+				return fmt.Errorf("invalid character %q", r)
+			}
+		}
+	}
+	// names of only numeric labels, e.g. 10.0.0.256, are meant as addresses
+	if numeric && strings.Contains(name, hostnameLabelSeparator) {
+		return fmt.Errorf("invalid IP address")
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateHostname(t *testing.T) {
+	tt := []struct {
+		host string
+		err  string
+	}{
+		{host: "compute1"},
+		{host: "compute-1.dc1.example.com"},
+		{host: "compute-1.dc1.example.com."},
+		{host: "COMPUTE1.DC1"},
+		{host: "1compute"},
+		{host: "10.0.0.1"},
+		{host: "2001:db8::1"},
+		{host: strings.Repeat("a", 63) + ".dc1"},
+		{
+			host: "compute 1",
+			err:  "invalid character ' '",
+		},
+		{
+			host: "http://compute1",
+			err:  "invalid character ':'",
+		},
+		{
+			host: "compute_1",
+			err:  "invalid character '_'",
+		},
+		{
+			host: "compute1..dc1",
+			err:  "empty label",
+		},
+		{
+			host: ".",
+			err:  "empty hostname",
+		},
+		{
+			host: "-compute1",
+			err:  "label -compute1 starts or ends with a hyphen",
+		},
+		{
+			host: "compute1.dc1-",
+			err:  "label dc1- starts or ends with a hyphen",
+		},
+		{
+			host: strings.Repeat("a", 64) + ".dc1",
+			err:  "label longer than 63 characters",
+		},
+		{
+			host: strings.Repeat("a.", 128),
+			err:  "hostname longer than 253 characters",
+		},
+		{
+			host: "10.0.0.256",
+			err:  "invalid IP address",
+		},
+	}
+
+	for _, test := range tt {
+		err := validateHostname(test.host)
+		if test.err == "" {
+			assert.NoError(t, err, test.host)
+		} else {
+			assert.EqualError(t, err, test.err, test.host)
+		}
+	}
+}
+
+func TestExpandHost(t *testing.T) {
+	c := Client{}
+
+	hosts, err := c.expandHost("[2001:db8::1]")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2001:db8::1"}, hosts)
+
+	hosts, err = c.expandHost("compute[1-2]")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"compute1", "compute2"}, hosts)
+
+	// brackets holding neither an IPv6 address nor a range
+	_, err = c.expandHost("[10.0.0.1]")
+	assert.Error(t, err)
+	_, err = c.expandHost("[compute1]")
+	assert.Error(t, err)
+}
@@ -52,15 +52,27 @@ const (
 	jobSummaryFormatHeader = "ID\tName\tOwner\tState\tCreation Time\tCompletion Time\tTotal\t" +
 		"Running\tSucceeded\tFailed\tKilled\t\n"
 	jobSummaryFormatBody = "%s\t%s\t%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t\n"
-
-	jobStopConfirmationMessage = "The above jobs will be stopped. " +
-		"Are you sure you want to continue?"
 )
 
-// JobCreateAction is the action for creating a job
+// JobCreateAction is the action for creating a job. The config may be
+// exported by job export, stateless job specs are created with the v1alpha
+// stateless API.
 func (c *Client) JobCreateAction(
 	jobID, respoolPath, cfg, secretPath string, secret []byte,
 ) error {
+	buffer, err := ioutil.ReadFile(cfg)
+	if err != nil {
+		return fmt.Errorf("unable to open file %s: %v", cfg, err)
+	}
+	metadata, err := parseJobExportMetadata(buffer)
+	if err != nil {
+		return fmt.Errorf("unable to parse file %s: %v", cfg, err)
+	}
+	if metadata.APIVersion == jobExportStatelessAPIVersion {
+		return c.StatelessCreateAction(jobID, respoolPath, 0, cfg,
+			secretPath, secret, "", false, 0, 0)
+	}
+
 	respoolID, err := c.LookupResourcePoolID(respoolPath)
 	if err != nil {
 		return err
@@ -71,13 +83,10 @@ func (c *Client) JobCreateAction(
 	}
 
 	var jobConfig job.JobConfig
-	buffer, err := ioutil.ReadFile(cfg)
-	if err != nil {
-		return fmt.Errorf("unable to open file %s: %v", cfg, err)
-	}
 	if err := yaml.Unmarshal(buffer, &jobConfig); err != nil {
 		return fmt.Errorf("unable to parse file %s: %v", cfg, err)
 	}
+	printUnprovisionedSecrets(metadata, secretPath, secret)
 
 	// TODO remove this once respool is moved out of jobconfig
 	// set the resource pool ID
@@ -105,6 +114,13 @@ func (c *Client) JobCreateAction(
 
 // JobDeleteAction is the action for deleting a job
 func (c *Client) JobDeleteAction(jobID string) error {
+	confirmed, err := c.confirm(func() (string, error) {
+		return c.jobDeleteSummary(jobID)
+	})
+	if err != nil || !confirmed {
+		return err
+	}
+
 	var request = &job.DeleteRequest{
 		Id: &peloton.JobID{
 			Value: jobID,
@@ -124,8 +140,10 @@ func (c *Client) JobGetAction(jobID string) error {
 	if err != nil {
 		return err
 	}
-	printJobGetResponse(response, c.Debug)
-	return nil
+	return c.printFormatted(response, func() error {
+		printJobGetResponse(response, c.Debug)
+		return nil
+	})
 }
 
 func (c *Client) jobGet(jobID string) (*job.GetResponse, error) {
@@ -174,23 +192,66 @@ func (c *Client) JobRefreshAction(jobID string) error {
 	return err
 }
 
-// JobStatusAction is the action for getting status of a job
-func (c *Client) JobStatusAction(jobID string) error {
+// JobStatusAction is the action for getting status of a job. With watch set
+// the status is refreshed every interval until the job is terminal. With
+// summary set the task state counts and the most common failures are
+// printed instead of the job runtime.
+func (c *Client) JobStatusAction(
+	jobID string,
+	watch bool,
+	interval time.Duration,
+	summary bool) error {
+	if !watch {
+		response, err := c.jobStatus(c.ctx, jobID)
+		if err != nil {
+			return err
+		}
+		return c.printJobStatus(c.ctx, jobID, response, summary)
+	}
+
+	return c.Watch(interval, func(ctx context.Context) (bool, error) {
+		response, err := c.jobStatus(ctx, jobID)
+		if err != nil {
+			return false, err
+		}
+		if err := c.printJobStatus(ctx, jobID, response, summary); err != nil {
+			return false, err
+		}
+		return util.IsPelotonJobStateTerminal(
+			response.GetJobInfo().GetRuntime().GetState()), nil
+	})
+}
+
+// printJobStatus prints the summary of the status of a job, or its runtime
+// if summary is not set or the output is JSON
+func (c *Client) printJobStatus(
+	ctx context.Context,
+	jobID string,
+	response *job.GetResponse,
+	summary bool) error {
+	if !summary || c.Debug {
+		printJobStatusResponse(response, c.Debug, c.colorizer())
+		return nil
+	}
+	return c.printJobStatusSummary(ctx, jobID, response)
+}
+
+func (c *Client) jobStatus(
+	ctx context.Context,
+	jobID string) (*job.GetResponse, error) {
 	var request = &job.GetRequest{
 		Id: &peloton.JobID{
 			Value: jobID,
 		},
 	}
-	response, err := c.jobClient.Get(c.ctx, request)
-	if err != nil {
-		return err
-	}
-	printJobStatusResponse(response, c.Debug)
-	return nil
+	return c.jobClient.Get(ctx, request)
 }
 
 // JobQueryAction is the action for getting job ids by labels,
-// respool path, keywords, state(s), owner and jobname
+// respool path, keywords, state(s), owner and jobname. If all is set
+// every page of results is fetched, using limit as the page size. The
+// results are ordered by the server by sortBy, and then sorted by the
+// client by the <field>[:desc] sortResults spec if it is set.
 func (c *Client) JobQueryAction(
 	labels string,
 	respoolPath string,
@@ -203,19 +264,25 @@ func (c *Client) JobQueryAction(
 	maxLimit uint32,
 	offset uint32,
 	sortBy string,
-	sortOrder string) error {
-	var apiLabels []*peloton.Label
-	var err error
-	if len(labels) > 0 {
-		apiLabels, err = parsePelotonLabels(labels)
-		if err != nil {
-			return err
-		}
+	sortOrder string,
+	sortResults string,
+	all bool) error {
+	if all && offset != 0 {
+		return errors.New("offset cannot be used to query all jobs")
+	}
+	if _, _, err := parseSortSpec(sortResults, jobSortFields); err != nil {
+		return err
+	}
+
+	requirements, err := parseLabelSelector(labels)
+	if err != nil {
+		return err
 	}
+	apiLabels, clientRequirements := splitLabelSelector(requirements)
 
 	var respoolID *peloton.ResourcePoolID
 	if len(respoolPath) > 0 {
-		respoolID, err = c.LookupResourcePoolID(respoolPath)
+		respoolID, err = c.LookupResourcePoolIDCached(respoolPath)
 		if err != nil {
 			return err
 		}
@@ -287,12 +354,54 @@ func (c *Client) JobQueryAction(
 		Spec:        spec,
 		SummaryOnly: true,
 	}
-	response, err := c.jobClient.Query(c.ctx, request)
-	if err != nil {
+	if !all {
+		response, err := c.jobClient.Query(c.ctx, request)
+		if err != nil {
+			return err
+		}
+		response.Results = filterJobSummariesByLabels(
+			response.GetResults(), clientRequirements)
+		if err := sortJobSummaries(response.GetResults(), sortResults); err != nil {
+			return err
+		}
+		return c.printFormattedColumns(response, func() (columnTable, error) {
+			return jobQueryColumns(response)
+		}, func() error {
+			printJobQueryResponse(response, c.Debug)
+			return nil
+		})
+	}
+
+	// fetch all pages, the total number of jobs is capped by the
+	// max limit on the server
+	spec.Pagination.MaxLimit = queryAllMaxResults
+	var response *job.QueryResponse
+	var results []*job.JobSummary
+	err = fetchAllPages("jobs", limit, func(pageOffset, pageSize uint32) (int, bool, error) {
+		spec.Pagination.Offset = pageOffset
+		spec.Pagination.Limit = pageSize
+		page, err := c.jobClient.Query(c.ctx, request)
+		if err != nil {
+			return 0, false, err
+		}
+		response = page
+		results = append(results, page.GetResults()...)
+		return len(page.GetResults()), page.GetError() != nil, nil
+	})
+	// the jobs fetched before the truncation are still printed
+	if err := warnIfTruncated(err); err != nil {
 		return err
 	}
-	printJobQueryResponse(response, c.Debug)
-	return nil
+	response.Results = filterJobSummariesByLabels(results, clientRequirements)
+	if err := sortJobSummaries(response.GetResults(), sortResults); err != nil {
+		return err
+	}
+	return c.printFormattedColumns(response, func() (columnTable, error) {
+		return jobQueryColumns(response)
+	}, func() error {
+		printJobQueryResponse(response, c.Debug)
+		return nil
+	})
 }
 
 // JobUpdateAction is the action of updating a job
@@ -427,8 +536,13 @@ func (c *Client) JobStopAction(
 			jobsToStop = append(jobsToStop, jobSummary.GetId())
 		}
 
-		if !isForceStop && !askForConfirmation(jobStopConfirmationMessage) {
-			return nil
+		if !isForceStop && len(jobsToStop) > 0 {
+			confirmed, err := c.confirm(func() (string, error) {
+				return jobStopSummary(response.GetResults()), nil
+			})
+			if err != nil || !confirmed {
+				return err
+			}
 		}
 	}
 
@@ -786,7 +900,10 @@ func printJobGetResponse(r *job.GetResponse, jsonFormat bool) {
 	tabWriter.Flush()
 }
 
-func printJobStatusResponse(r *job.GetResponse, jsonFormat bool) {
+func printJobStatusResponse(
+	r *job.GetResponse,
+	jsonFormat bool,
+	colors stateColorizer) {
 	if r.GetJobInfo() == nil || r.GetJobInfo().GetRuntime() == nil {
 		fmt.Fprint(tabWriter, "Unable to get job status\n")
 	} else {
@@ -802,7 +919,7 @@ func printJobStatusResponse(r *job.GetResponse, jsonFormat bool) {
 			return
 		}
 
-		fmt.Printf("%v\n", string(out))
+		fmt.Printf("%v\n", colors.yamlStates(string(out)))
 	}
 	tabWriter.Flush()
 }
@@ -860,40 +977,82 @@ func printJobQueryResponse(r *job.QueryResponse, jsonFormat bool) {
 	}
 }
 
+// parsePelotonLabels parses comma separated k=v labels with the label
+// selector parser, other expressions are not supported by the job APIs
 func parsePelotonLabels(labels string) ([]*peloton.Label, error) {
+	requirements, err := parseLabelSelector(labels)
+	if err != nil {
+		return nil, err
+	}
 	var pelotonLabels []*peloton.Label
-	for _, l := range strings.Split(labels, labelSeparator) {
-		labelVals := strings.Split(l, keyValSeparator)
-		if len(labelVals) != 2 {
-			fmt.Printf("Invalid label %v", l)
-			return nil, errors.New("Invalid label" + l)
+	for _, r := range requirements {
+		if r.Operator != labelEquals {
+			return nil, fmt.Errorf(
+				"invalid label %s, only k=v labels are supported", r.Key)
 		}
 		pelotonLabels = append(pelotonLabels, &peloton.Label{
-			Key:   labelVals[0],
-			Value: labelVals[1],
+			Key:   r.Key,
+			Value: r.Value,
 		})
 	}
 	return pelotonLabels, nil
 }
 
-// askForConfirmation uses Scanln to parse user input. A user must type in "yes" or "no" and
-// then press enter. It has fuzzy matching, so "y", "Y", "yes", "YES", and "Yes" all count as
-// confirmations. If the input is not recognized, it will ask again. The function does not return
-// until it gets a valid response from the user.
-func askForConfirmation(s string) bool {
-	for {
-		fmt.Printf("%s [y/n]: ", s)
-		var response string
-		_, err := fmt.Scanln(&response)
-		if err != nil {
-			return false
+// splitLabelSelector splits label requirements into the labels matched by
+// the job query API, k=v and k in (v) requirements, and the requirements
+// which need to be matched client-side
+func splitLabelSelector(
+	requirements []labelRequirement) ([]*peloton.Label, []labelRequirement) {
+	var pelotonLabels []*peloton.Label
+	var clientRequirements []labelRequirement
+	for _, r := range requirements {
+		switch {
+		case r.Operator == labelEquals:
+			pelotonLabels = append(pelotonLabels, &peloton.Label{
+				Key:   r.Key,
+				Value: r.Value,
+			})
+		case r.Operator == labelIn && len(r.Values) == 1:
+			pelotonLabels = append(pelotonLabels, &peloton.Label{
+				Key:   r.Key,
+				Value: r.Values[0],
+			})
+		default:
+			clientRequirements = append(clientRequirements, r)
 		}
-		response = strings.ToLower(strings.TrimSpace(response))
-		if response == "y" || response == "yes" {
-			return true
+	}
+	return pelotonLabels, clientRequirements
+}
+
+// filterJobSummariesByLabels returns the jobs whose labels satisfy the
+// label requirements the job query API does not support. It prints a
+// warning if any jobs are filtered client-side, as pages of the query
+// results may then have fewer jobs than the limit.
+func filterJobSummariesByLabels(
+	jobs []*job.JobSummary,
+	requirements []labelRequirement) []*job.JobSummary {
+	if len(requirements) == 0 {
+		return jobs
+	}
+
+	var expressions []string
+	for _, r := range requirements {
+		expressions = append(expressions, r.String())
+	}
+	fmt.Fprintf(warningOutput,
+		"Warning: labels %s are filtered client-side, pages of the results "+
+			"may have fewer jobs than the limit\n",
+		strings.Join(expressions, labelSeparator))
+
+	var filtered []*job.JobSummary
+	for _, j := range jobs {
+		labels := make(map[string][]string)
+		for _, l := range j.GetLabels() {
+			labels[l.GetKey()] = append(labels[l.GetKey()], l.GetValue())
 		}
-		if response == "n" || response == "no" {
-			return false
+		if matchLabelSelector(requirements, labels) {
+			filtered = append(filtered, j)
 		}
 	}
+	return filtered
 }
@@ -15,11 +15,15 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
+	"text/tabwriter"
 	"time"
 
 	pberrors "github.com/uber/peloton/.gen/peloton/api/v0/errors"
@@ -689,15 +693,19 @@ func (suite *jobActionsTestSuite) TestClientJobQueryAction() {
 
 	suite.NoError(suite.client.JobQueryAction(
 		"key=value", "", "keyword,", "RUNNING", "test_owner",
-		"test_name", 0, 10, 100, 0, "creation_time", "DESC",
+		"test_name", 0, 10, 100, 0, "creation_time", "DESC", "", false,
 	))
 	suite.Error(suite.client.JobQueryAction(
-		"key=value1,value2", "", "keyword,", "RUNNING",
-		"test_owner", "test_name", 0, 10, 100, 0, "creation_time", "DESC",
+		"key in (value1,value2", "", "keyword,", "RUNNING",
+		"test_owner", "test_name", 0, 10, 100, 0, "creation_time", "DESC", "", false,
 	))
 	suite.Error(suite.client.JobQueryAction(
 		"key=value", "", "keyword,", "RUNNING", "test_owner",
-		"test_name", 0, 10, 100, 0, "creation_time", "RANDOM",
+		"test_name", 0, 10, 100, 0, "creation_time", "RANDOM", "", false,
+	))
+	suite.Error(suite.client.JobQueryAction(
+		"key=value", "", "keyword,", "RUNNING", "test_owner",
+		"test_name", 0, 10, 100, 0, "creation_time", "DESC", "owner", false,
 	))
 
 	suite.client.Debug = true
@@ -706,7 +714,190 @@ func (suite *jobActionsTestSuite) TestClientJobQueryAction() {
 		Return(resp, nil)
 	suite.NoError(suite.client.JobQueryAction(
 		"key=value", "", "keyword,", "RUNNING", "test_owner",
-		"test_name", 0, 10, 100, 0, "creation_time", "DESC",
+		"test_name", 0, 10, 100, 0, "creation_time", "DESC", "", false,
+	))
+}
+
+// TestClientJobQueryActionLabelSelector tests that k=v labels are queried
+// by the server and the other label expressions are applied client-side
+// with a warning
+func (suite *jobActionsTestSuite) TestClientJobQueryActionLabelSelector() {
+	fo := &fakeOutputter{}
+	cliOutPutter = fo
+	defer func() { cliOutPutter = newStdOutOutputter() }()
+	var warnings bytes.Buffer
+	warningOutput = &warnings
+	defer func() { warningOutput = os.Stderr }()
+
+	labeled := func(name string, labels ...string) *job.JobSummary {
+		j := &job.JobSummary{Name: name}
+		for i := 0; i < len(labels); i += 2 {
+			j.Labels = append(j.Labels, &peloton.Label{
+				Key:   labels[i],
+				Value: labels[i+1],
+			})
+		}
+		return j
+	}
+	suite.mockJob.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *job.QueryRequest) {
+			suite.Equal([]*peloton.Label{
+				{Key: "env", Value: "prod"},
+				{Key: "zone", Value: "z1"},
+			}, req.GetSpec().GetLabels())
+		}).
+		Return(&job.QueryResponse{Results: []*job.JobSummary{
+			labeled("infra-prod", "team", "infra", "tier", "prod"),
+			labeled("data-dev", "team", "data", "tier", "dev"),
+			labeled("money", "team", "money"),
+			labeled("data-untiered", "team", "data"),
+		}}, nil)
+
+	suite.client.Output = OutputJSON
+	defer func() { suite.client.Output = "" }()
+	suite.NoError(suite.client.JobQueryAction(
+		"team in (infra, data), tier!=dev, env=prod, zone in (z1)",
+		"", "", "", "", "", 0, 10, 100, 0, "", "DESC", "", false,
+	))
+	suite.Contains(fo.Out, "infra-prod")
+	suite.Contains(fo.Out, "data-untiered")
+	suite.NotContains(fo.Out, "data-dev")
+	suite.NotContains(fo.Out, "money")
+	suite.Equal("Warning: labels team in (infra,data),tier!=dev are filtered "+
+		"client-side, pages of the results may have fewer jobs than the limit\n",
+		warnings.String())
+
+	// k=v labels alone are only queried by the server
+	warnings.Reset()
+	suite.mockJob.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		Return(&job.QueryResponse{}, nil)
+	suite.NoError(suite.client.JobQueryAction(
+		"env=prod", "", "", "", "", "", 0, 10, 100, 0, "", "DESC", "", false,
+	))
+	suite.Empty(warnings.String())
+}
+
+// TestClientJobQueryActionGolden tests the job query output in both
+// table and JSON mode against golden files
+func (suite *jobActionsTestSuite) TestClientJobQueryActionGolden() {
+	resp := &job.QueryResponse{
+		Results: []*job.JobSummary{
+			{
+				Id:            &peloton.JobID{Value: testJobID},
+				Name:          "test",
+				OwningTeam:    "test",
+				InstanceCount: 10,
+				Runtime: &job.RuntimeInfo{
+					State:        job.JobState_RUNNING,
+					CreationTime: "2019-01-01T00:00:00Z",
+					TaskStats: map[string]uint32{
+						"RUNNING": 10,
+					},
+				},
+			},
+		},
+	}
+
+	var table bytes.Buffer
+	oldTabWriter := tabWriter
+	tabWriter = tabwriter.NewWriter(
+		&table, 0, 0, 2, ' ', tabwriter.AlignRight|tabwriter.Debug,
+	)
+	defer func() { tabWriter = oldTabWriter }()
+
+	oldOutputter := cliOutPutter
+	fo := &fakeOutputter{}
+	cliOutPutter = fo
+	defer func() { cliOutPutter = oldOutputter }()
+
+	tt := []struct {
+		json   bool
+		golden string
+		output func() string
+	}{
+		{
+			json:   false,
+			golden: "job_query.table.golden",
+			output: table.String,
+		},
+		{
+			json:   true,
+			golden: "job_query.json.golden",
+			output: func() string { return fo.Out },
+		},
+	}
+
+	for _, t := range tt {
+		suite.client.JSON = t.json
+		suite.mockJob.EXPECT().
+			Query(gomock.Any(), gomock.Any()).
+			Return(resp, nil)
+		suite.NoError(suite.client.JobQueryAction(
+			"", "", "", "", "", "", 0, 10, 100, 0, "", "DESC", "", false,
+		))
+
+		expected, err := ioutil.ReadFile(filepath.Join("testdata", t.golden))
+		suite.NoError(err)
+		suite.Equal(string(expected), t.output())
+	}
+}
+
+// TestClientJobQueryActionAll tests fetching all pages of a job query
+func (suite *jobActionsTestSuite) TestClientJobQueryActionAll() {
+	fo := &fakeOutputter{}
+	cliOutPutter = fo
+	defer func() { cliOutPutter = newStdOutOutputter() }()
+	var progress bytes.Buffer
+	progressOutput = &progress
+	defer func() { progressOutput = os.Stderr }()
+
+	page := func(names ...string) *job.QueryResponse {
+		resp := &job.QueryResponse{}
+		for _, name := range names {
+			resp.Results = append(resp.Results, &job.JobSummary{Name: name})
+		}
+		return resp
+	}
+
+	var offsets, limits []uint32
+	record := func(_ context.Context, req *job.QueryRequest) {
+		offsets = append(offsets, req.GetSpec().GetPagination().GetOffset())
+		limits = append(limits, req.GetSpec().GetPagination().GetLimit())
+		suite.Equal(uint32(queryAllMaxResults),
+			req.GetSpec().GetPagination().GetMaxLimit())
+	}
+	gomock.InOrder(
+		suite.mockJob.EXPECT().Query(gomock.Any(), gomock.Any()).
+			Do(record).Return(page("a", "b"), nil),
+		suite.mockJob.EXPECT().Query(gomock.Any(), gomock.Any()).
+			Do(record).Return(page("c", "d"), nil),
+		suite.mockJob.EXPECT().Query(gomock.Any(), gomock.Any()).
+			Do(record).Return(page("e"), nil),
+	)
+
+	suite.client.Output = "go-template={{range .Results}}{{.Name}} {{end}}"
+	suite.NoError(suite.client.JobQueryAction(
+		"", "", "", "", "", "", 0, 2, 100, 0, "", "DESC", "", true,
+	))
+	suite.Equal([]uint32{0, 2, 4}, offsets)
+	suite.Equal([]uint32{2, 2, 2}, limits)
+	suite.Equal("a b c d e ", fo.Out)
+	suite.Contains(progress.String(), "Fetched 4 jobs")
+
+	// paging stops on errors
+	suite.mockJob.EXPECT().Query(gomock.Any(), gomock.Any()).
+		Return(page("a", "b"), nil)
+	suite.mockJob.EXPECT().Query(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("unable to query jobs"))
+	suite.Error(suite.client.JobQueryAction(
+		"", "", "", "", "", "", 0, 2, 100, 0, "", "DESC", "", true,
+	))
+
+	// offset conflicts with all
+	suite.Error(suite.client.JobQueryAction(
+		"", "", "", "", "", "", 0, 2, 100, 4, "", "DESC", "", true,
 	))
 }
 
@@ -726,7 +917,7 @@ func (suite *jobActionsTestSuite) TestClientJobQueryActionWithRespoolError() {
 
 	suite.Error(suite.client.JobQueryAction(
 		"key=value", path, "keyword,", "RUNNING", "test_owner",
-		"test_name", 0, 10, 100, 0, "creation_time", "DESC",
+		"test_name", 0, 10, 100, 0, "creation_time", "DESC", "", false,
 	))
 }
 
@@ -762,7 +953,7 @@ func (suite *jobActionsTestSuite) TestClientJobQueryActionWithError() {
 
 	suite.Error(suite.client.JobQueryAction(
 		"key=value", "", "keyword,", "RUNNING", "test_owner",
-		"test_name", 0, 10, 100, 0, "creation_time", "ASC",
+		"test_name", 0, 10, 100, 0, "creation_time", "ASC", "", false,
 	))
 }
 
@@ -805,7 +996,7 @@ func (suite *jobActionsTestSuite) TestClientJobQueryActionResponseError() {
 
 	suite.NoError(suite.client.JobQueryAction(
 		"key=value", "", "keyword,", "RUNNING", "test_owner",
-		"test_name", 0, 10, 100, 0, "creation_time", "DESC",
+		"test_name", 0, 10, 100, 0, "creation_time", "DESC", "", false,
 	))
 }
 
@@ -816,7 +1007,7 @@ func (suite *jobActionsTestSuite) TestClientJobQueryActionWithDays() {
 		Return(nil, nil)
 	suite.NoError(suite.client.JobQueryAction(
 		"key=value", "", "keyword,", "RUNNING", "test_owner",
-		"test_name", 5, 10, 100, 0, "creation_time", "DESC",
+		"test_name", 5, 10, 100, 0, "creation_time", "DESC", "", false,
 	))
 }
 
@@ -979,13 +1170,50 @@ func (suite *jobActionsTestSuite) TestClientJobStatusAction() {
 			Get(gomock.Any(), t.req).
 			Return(t.resp, t.getError)
 		if t.getError != nil {
-			suite.Error(suite.client.JobStatusAction(testJobID))
+			suite.Error(suite.client.JobStatusAction(testJobID, false, 0, false))
 		} else {
-			suite.NoError(suite.client.JobStatusAction(testJobID))
+			suite.NoError(suite.client.JobStatusAction(testJobID, false, 0, false))
 		}
 	}
 }
 
+// TestClientJobStatusActionWatch tests watching the status of a job
+// until it reaches a terminal state
+func (suite *jobActionsTestSuite) TestClientJobStatusActionWatch() {
+	_, restore := withWatchOutput(false)
+	defer restore()
+
+	statusResponse := func(state job.JobState) *job.GetResponse {
+		return &job.GetResponse{
+			JobInfo: &job.JobInfo{
+				Runtime: &job.RuntimeInfo{State: state},
+			},
+		}
+	}
+
+	req := &job.GetRequest{Id: &peloton.JobID{Value: testJobID}}
+	gomock.InOrder(
+		suite.mockJob.EXPECT().
+			Get(gomock.Any(), req).
+			Return(statusResponse(job.JobState_PENDING), nil),
+		suite.mockJob.EXPECT().
+			Get(gomock.Any(), req).
+			Return(statusResponse(job.JobState_RUNNING), nil),
+		suite.mockJob.EXPECT().
+			Get(gomock.Any(), req).
+			Return(statusResponse(job.JobState_SUCCEEDED), nil),
+	)
+	suite.NoError(
+		suite.client.JobStatusAction(testJobID, true, time.Millisecond, false))
+
+	// errors stop the watch
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), req).
+		Return(nil, errors.New("unable to get job status"))
+	suite.Error(
+		suite.client.JobStatusAction(testJobID, true, time.Millisecond, false))
+}
+
 // TestClientJobGetCacheAction tests fetching job in cache
 func (suite *jobActionsTestSuite) TestClientJobGetCacheAction() {
 	tt := []struct {
@@ -1078,6 +1306,7 @@ func (suite *jobActionsTestSuite) TestClientJobDeleteAction() {
 		},
 	}
 
+	suite.client.AssumeYes = true
 	for _, t := range tt {
 		resp := &job.DeleteResponse{}
 		suite.mockJob.EXPECT().
@@ -2046,3 +2275,119 @@ func (suite *jobActionsTestSuite) TestClientJobStopActionJobIDAndLabels() {
 	}).Return(getResponse, nil)
 	suite.NoError(suite.client.JobStopAction(testJobID, false, "", "key=value", true))
 }
+
+// jobStatusSummaryFailedTasks returns the failed tasks of the job status
+// summary fixture, with repeated failure messages
+func jobStatusSummaryFailedTasks() []*task.TaskInfo {
+	var tasks []*task.TaskInfo
+	add := func(count int, message string, reason string) {
+		for i := 0; i < count; i++ {
+			tasks = append(tasks, &task.TaskInfo{
+				InstanceId: uint32(len(tasks)),
+				Runtime: &task.RuntimeInfo{
+					State:   task.TaskState_FAILED,
+					Message: message,
+					Reason:  reason,
+				},
+			})
+		}
+	}
+	add(5, "Command exited with status 137", "REASON_COMMAND_EXITED")
+	add(2, "", "REASON_CONTAINER_LAUNCH_FAILED")
+	add(2, "Failed to pull image", "REASON_CONTAINER_LAUNCH_FAILED")
+	add(1, "Health check failed", "REASON_TASK_HEALTH_CHECK_FAILED")
+	add(1, "  Killed by OOM  ", "REASON_CONTAINER_LIMITATION_MEMORY")
+	add(1, "", "")
+	return tasks
+}
+
+// TestClientJobStatusActionSummary tests the summary of the task states
+// and failures of a job against a golden file
+func (suite *jobActionsTestSuite) TestClientJobStatusActionSummary() {
+	var table bytes.Buffer
+	oldTabWriter := tabWriter
+	tabWriter = tabwriter.NewWriter(&table, 0, 0, 1, ' ', 0)
+	defer func() { tabWriter = oldTabWriter }()
+
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{Id: &peloton.JobID{Value: testJobID}}).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{
+				Id: &peloton.JobID{Value: testJobID},
+				Runtime: &job.RuntimeInfo{
+					State: job.JobState_RUNNING,
+					TaskStats: map[string]uint32{
+						"RUNNING":   4200,
+						"FAILED":    12,
+						"PENDING":   3,
+						"SUCCEEDED": 0,
+					},
+				},
+			},
+		}, nil)
+	suite.mockTask.EXPECT().
+		Query(gomock.Any(), &task.QueryRequest{
+			JobId: &peloton.JobID{Value: testJobID},
+			Spec: &task.QuerySpec{
+				TaskStates: []task.TaskState{task.TaskState_FAILED},
+				Pagination: &query.PaginationSpec{
+					Limit: jobStatusFailureQueryLimit,
+				},
+			},
+		}).
+		Return(&task.QueryResponse{
+			Records: jobStatusSummaryFailedTasks(),
+		}, nil)
+
+	suite.NoError(suite.client.JobStatusAction(testJobID, false, 0, true))
+	expected, err := ioutil.ReadFile(
+		filepath.Join("testdata", "job_status_summary.golden"))
+	suite.NoError(err)
+	suite.Equal(string(expected), table.String())
+}
+
+// TestClientJobStatusActionSummaryNoFailures tests that failed tasks are
+// only queried if the job has failed tasks
+func (suite *jobActionsTestSuite) TestClientJobStatusActionSummaryNoFailures() {
+	var table bytes.Buffer
+	oldTabWriter := tabWriter
+	tabWriter = tabwriter.NewWriter(&table, 0, 0, 1, ' ', 0)
+	defer func() { tabWriter = oldTabWriter }()
+
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), gomock.Any()).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{
+				Runtime: &job.RuntimeInfo{
+					State:     job.JobState_SUCCEEDED,
+					TaskStats: map[string]uint32{"SUCCEEDED": 10},
+				},
+			},
+		}, nil)
+	suite.NoError(suite.client.JobStatusAction(testJobID, false, 0, true))
+	suite.Equal("Job "+testJobID+" is SUCCEEDED\nTasks: 10 SUCCEEDED\n",
+		table.String())
+
+	// errors of the failed task query are returned
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), gomock.Any()).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{
+				Runtime: &job.RuntimeInfo{
+					State:     job.JobState_FAILED,
+					TaskStats: map[string]uint32{"FAILED": 1},
+				},
+			},
+		}, nil)
+	suite.mockTask.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("query failed"))
+	suite.Error(suite.client.JobStatusAction(testJobID, false, 0, true))
+}
+
+// TestFormatTaskStats tests ordering the task states by their counts
+func (suite *jobActionsTestSuite) TestFormatTaskStats() {
+	suite.Equal("none", formatTaskStats(nil))
+	suite.Equal("4 FAILED, 4 RUNNING, 1 PENDING", formatTaskStats(
+		map[string]uint32{"PENDING": 1, "RUNNING": 4, "FAILED": 4, "KILLED": 0}))
+}
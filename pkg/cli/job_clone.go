@@ -0,0 +1,218 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+
+	"gopkg.in/yaml.v2"
+)
+
+// jobCloneOverrideSeparator separates the path of a field from its value in
+// the overrides of job clone
+const jobCloneOverrideSeparator = "="
+
+// JobCloneAction creates a copy of a batch job with the overrides applied
+// to its configuration, and prints the ID of the new job. The fields set by
+// the job manager, like the change log, are left out, and the job is created
+// in the resource pool of the source job unless respoolPath is set. The
+// overrides are path=value pairs, the path is the lowercased field names of
+// the configuration separated by dots, and the value is parsed as YAML like
+// in the configuration of job create.
+//
+// The job manager does not return the data of secrets, so the secrets of
+// the source job cannot be copied. Jobs with secrets are only cloned with
+// cloneSecrets set, without the secrets, which have to be provisioned again
+// with secret create.
+func (c *Client) JobCloneAction(
+	jobID string,
+	name string,
+	instances uint32,
+	respoolPath string,
+	overrides []string,
+	cloneSecrets bool) error {
+	response, err := c.jobGet(jobID)
+	if err != nil {
+		return err
+	}
+	if err := jobGetResponseError(jobID, response); err != nil {
+		return err
+	}
+	config := response.GetJobInfo().GetConfig()
+	if config == nil {
+		return fmt.Errorf("job %s has no configuration", jobID)
+	}
+	if config.GetType() == job.JobType_SERVICE {
+		return fmt.Errorf("job %s is a service job, only batch jobs can be "+
+			"cloned, use job export instead", jobID)
+	}
+
+	secrets := response.GetSecrets()
+	if len(secrets) > 0 && !cloneSecrets {
+		var paths []string
+		for _, s := range secrets {
+			paths = append(paths, s.GetPath())
+		}
+		return fmt.Errorf("job %s has secrets at %s whose data cannot be "+
+			"cloned, use --clone-secrets to clone it without them",
+			jobID, strings.Join(paths, ", "))
+	}
+
+	config.ChangeLog = nil
+	if respoolPath != "" {
+		respoolID, err := c.LookupResourcePoolID(respoolPath)
+		if err != nil {
+			return err
+		}
+		if respoolID == nil {
+			return fmt.Errorf("unable to find resource pool ID for "+
+				":%s", respoolPath)
+		}
+		config.RespoolID = respoolID
+	}
+	if name != "" {
+		config.Name = name
+	}
+	if instances != 0 {
+		config.InstanceCount = instances
+		// the configurations of the instances dropped are not carried
+		for id := range config.InstanceConfig {
+			if id >= instances {
+				delete(config.InstanceConfig, id)
+			}
+		}
+	}
+	for _, override := range overrides {
+		parts := strings.SplitN(override, jobCloneOverrideSeparator, 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return fmt.Errorf("invalid override %s, expected path=value",
+				override)
+		}
+		if err := setConfigField(config, parts[0], parts[1]); err != nil {
+			return fmt.Errorf("invalid override %s: %v", override, err)
+		}
+	}
+
+	createResponse, err := c.jobClient.Create(c.ctx, &job.CreateRequest{
+		Config: config,
+	})
+	if err != nil {
+		return err
+	}
+	printJobCreateResponse(createResponse, c.Debug)
+	if createResponse.GetError() != nil {
+		return fmt.Errorf("failed to clone job %s", jobID)
+	}
+
+	for _, s := range secrets {
+		fmt.Fprintf(tabWriter, "Secret %s of job %s is not cloned, provision "+
+			"it with peloton secret create %s --path %s --data-file <file>\n",
+			s.GetPath(), jobID, createResponse.GetJobId().GetValue(),
+			s.GetPath())
+	}
+	tabWriter.Flush()
+	return nil
+}
+
+// setConfigField sets the field at path of a job configuration to value
+// parsed as YAML. The path is made of the lowercased names of the fields,
+// the indexes of lists and the keys of maps separated by dots, e.g.
+// defaultconfig.command.value. Unset messages on the path are created.
+func setConfigField(config interface{}, path string, value string) error {
+	segments := strings.Split(path, ".")
+	v := reflect.ValueOf(config)
+	for i, segment := range segments {
+		for v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		parent := strings.Join(segments[:i], ".")
+
+		switch v.Kind() {
+		case reflect.Struct:
+			field, ok := configField(v, segment)
+			if !ok {
+				return fmt.Errorf("unknown field %s", strings.Join(segments[:i+1], "."))
+			}
+			v = field
+		case reflect.Slice:
+			index, err := strconv.Atoi(segment)
+			if err != nil || index < 0 || index >= v.Len() {
+				return fmt.Errorf("invalid index %s of %s, it has %d elements",
+					segment, parent, v.Len())
+			}
+			v = v.Index(index)
+		case reflect.Map:
+			key := reflect.New(v.Type().Key())
+			if err := yaml.Unmarshal([]byte(segment), key.Interface()); err != nil {
+				return fmt.Errorf("invalid key %s of %s: %v", segment, parent, err)
+			}
+			if v.IsNil() {
+				v.Set(reflect.MakeMap(v.Type()))
+			}
+			elem := v.MapIndex(key.Elem())
+			if i == len(segments)-1 {
+				parsed := reflect.New(v.Type().Elem())
+				if err := yaml.Unmarshal([]byte(value), parsed.Interface()); err != nil {
+					return err
+				}
+				v.SetMapIndex(key.Elem(), parsed.Elem())
+				return nil
+			}
+			// map values are not addressable, so only the fields of
+			// messages referenced by pointers can be set
+			if v.Type().Elem().Kind() != reflect.Ptr {
+				return fmt.Errorf("cannot set fields of %s.%s", parent, segment)
+			}
+			if !elem.IsValid() || elem.IsNil() {
+				elem = reflect.New(v.Type().Elem().Elem())
+				v.SetMapIndex(key.Elem(), elem)
+			}
+			v = elem
+		default:
+			return fmt.Errorf("%s has no field %s", parent, segment)
+		}
+	}
+
+	parsed := reflect.New(v.Type())
+	if err := yaml.Unmarshal([]byte(value), parsed.Interface()); err != nil {
+		return err
+	}
+	v.Set(parsed.Elem())
+	return nil
+}
+
+// configField returns the field of a message whose lowercased name is
+// name, as job create reads the fields of configurations
+func configField(v reflect.Value, name string) (reflect.Value, bool) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" || strings.HasPrefix(f.Name, "XXX_") {
+			continue
+		}
+		if strings.ToLower(f.Name) == strings.ToLower(name) {
+			return v.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
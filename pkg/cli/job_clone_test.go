@@ -0,0 +1,223 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"text/tabwriter"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	jobmocks "github.com/uber/peloton/.gen/peloton/api/v0/job/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	respoolmocks "github.com/uber/peloton/.gen/peloton/api/v0/respool/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	_cloneSourceJobID = "source-job"
+	_cloneNewJobID    = "new-job"
+)
+
+type jobCloneTestSuite struct {
+	suite.Suite
+	mockCtrl     *gomock.Controller
+	mockJob      *jobmocks.MockJobManagerYARPCClient
+	mockRespool  *respoolmocks.MockResourceManagerYARPCClient
+	output       *bytes.Buffer
+	oldTabWriter *tabwriter.Writer
+	client       Client
+}
+
+func (suite *jobCloneTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockJob = jobmocks.NewMockJobManagerYARPCClient(suite.mockCtrl)
+	suite.mockRespool = respoolmocks.NewMockResourceManagerYARPCClient(suite.mockCtrl)
+	suite.output = &bytes.Buffer{}
+	suite.oldTabWriter = tabWriter
+	tabWriter = tabwriter.NewWriter(suite.output, 0, 0, 1, ' ', 0)
+	suite.client = Client{
+		jobClient: suite.mockJob,
+		resClient: suite.mockRespool,
+		ctx:       context.Background(),
+	}
+}
+
+func (suite *jobCloneTestSuite) TearDownTest() {
+	tabWriter = suite.oldTabWriter
+	suite.mockCtrl.Finish()
+}
+
+func TestJobClone(t *testing.T) {
+	suite.Run(t, new(jobCloneTestSuite))
+}
+
+// sourceConfig returns the configuration of the source job, a batch job of
+// three instances with a configuration for instances 0 and 2
+func (suite *jobCloneTestSuite) sourceConfig() *job.JobConfig {
+	command := "echo source"
+	return &job.JobConfig{
+		Name:          "source",
+		Type:          job.JobType_BATCH,
+		InstanceCount: 3,
+		ChangeLog:     &peloton.ChangeLog{Version: 4},
+		RespoolID:     &peloton.ResourcePoolID{Value: "source-respool"},
+		DefaultConfig: &task.TaskConfig{
+			Command:  &mesos.CommandInfo{Value: &command},
+			Resource: &task.ResourceConfig{CpuLimit: 1, MemLimitMb: 128},
+		},
+		InstanceConfig: map[uint32]*task.TaskConfig{
+			0: {Name: "zero"},
+			2: {Name: "two"},
+		},
+	}
+}
+
+// expectGet expects the source job to be fetched with the given secrets
+func (suite *jobCloneTestSuite) expectGet(
+	config *job.JobConfig,
+	secrets ...*peloton.Secret) {
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{
+			Id: &peloton.JobID{Value: _cloneSourceJobID},
+		}).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{Config: config},
+			Secrets: secrets,
+		}, nil)
+}
+
+// TestJobCloneOverrides tests that the overrides are applied to nested
+// fields, lists and maps of the configuration of the new job
+func (suite *jobCloneTestSuite) TestJobCloneOverrides() {
+	suite.expectGet(suite.sourceConfig())
+	suite.mockRespool.EXPECT().
+		LookupResourcePoolID(gomock.Any(), &respool.LookupRequest{
+			Path: &respool.ResourcePoolPath{Value: "/infra/batch"},
+		}).
+		Return(&respool.LookupResponse{
+			Id: &peloton.ResourcePoolID{Value: "clone-respool"},
+		}, nil)
+	suite.mockJob.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *job.CreateRequest) {
+			suite.Empty(req.GetId().GetValue())
+			suite.Empty(req.GetSecrets())
+			config := req.GetConfig()
+			suite.Nil(config.GetChangeLog())
+			suite.Equal("clone-respool", config.GetRespoolID().GetValue())
+			suite.Equal("clone", config.GetName())
+			suite.Equal(uint32(2), config.GetInstanceCount())
+			suite.Equal("echo clone", config.GetDefaultConfig().GetCommand().GetValue())
+			suite.Equal(2.0, config.GetDefaultConfig().GetResource().GetCpuLimit())
+			suite.Equal(128.0, config.GetDefaultConfig().GetResource().GetMemLimitMb())
+			suite.Equal(uint32(3), config.GetSla().GetPriority())
+			suite.Len(config.GetLabels(), 1)
+			suite.Equal("team", config.GetLabels()[0].GetKey())
+			suite.Equal("infra", config.GetLabels()[0].GetValue())
+			suite.Len(config.GetInstanceConfig(), 2)
+			suite.Equal("first", config.GetInstanceConfig()[0].GetName())
+			suite.Equal("second", config.GetInstanceConfig()[1].GetName())
+		}).
+		Return(&job.CreateResponse{
+			JobId: &peloton.JobID{Value: _cloneNewJobID},
+		}, nil)
+
+	suite.NoError(suite.client.JobCloneAction(
+		_cloneSourceJobID, "clone", 2, "/infra/batch",
+		[]string{
+			"defaultconfig.command.value=echo clone",
+			"defaultConfig.resource.cpuLimit=2",
+			"sla.priority=3",
+			"labels=[{key: team, value: infra}]",
+			"instanceconfig.0.name=first",
+			"instanceconfig.1.name=second",
+		},
+		false))
+	suite.Contains(suite.output.String(), "Job new-job created")
+}
+
+// TestJobCloneSecrets tests that jobs with secrets are only cloned with the
+// acknowledgement, and without the secrets
+func (suite *jobCloneTestSuite) TestJobCloneSecrets() {
+	secret := &peloton.Secret{
+		Id:   &peloton.SecretID{Value: "secret-id"},
+		Path: "/etc/ssl/key",
+	}
+	suite.expectGet(suite.sourceConfig(), secret)
+	suite.EqualError(suite.client.JobCloneAction(
+		_cloneSourceJobID, "", 0, "", nil, false),
+		"job source-job has secrets at /etc/ssl/key whose data cannot be "+
+			"cloned, use --clone-secrets to clone it without them")
+
+	suite.expectGet(suite.sourceConfig(), secret)
+	suite.mockJob.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *job.CreateRequest) {
+			suite.Empty(req.GetSecrets())
+			suite.Equal("source-respool",
+				req.GetConfig().GetRespoolID().GetValue())
+			suite.Equal(uint32(3), req.GetConfig().GetInstanceCount())
+		}).
+		Return(&job.CreateResponse{
+			JobId: &peloton.JobID{Value: _cloneNewJobID},
+		}, nil)
+	suite.NoError(suite.client.JobCloneAction(
+		_cloneSourceJobID, "", 0, "", nil, true))
+	suite.Contains(suite.output.String(), "Secret /etc/ssl/key of job "+
+		"source-job is not cloned, provision it with peloton secret create "+
+		"new-job --path /etc/ssl/key --data-file <file>")
+}
+
+// TestJobCloneFailures tests that invalid overrides, service jobs and
+// rejected creations fail the clone
+func (suite *jobCloneTestSuite) TestJobCloneFailures() {
+	for _, override := range []string{
+		"defaultconfig.command",
+		"=value",
+		"defaultconfig.unknown=1",
+		"sla.priority=high",
+		"labels.3.key=team",
+	} {
+		suite.expectGet(suite.sourceConfig())
+		suite.Error(suite.client.JobCloneAction(
+			_cloneSourceJobID, "", 0, "", []string{override}, false), override)
+	}
+
+	service := suite.sourceConfig()
+	service.Type = job.JobType_SERVICE
+	suite.expectGet(service)
+	suite.Error(suite.client.JobCloneAction(
+		_cloneSourceJobID, "", 0, "", nil, false))
+
+	suite.expectGet(suite.sourceConfig())
+	suite.mockJob.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Return(&job.CreateResponse{
+			Error: &job.CreateResponse_Error{
+				InvalidConfig: &job.InvalidJobConfig{Message: "invalid"},
+			},
+		}, nil)
+	suite.EqualError(suite.client.JobCloneAction(
+		_cloneSourceJobID, "", 0, "", nil, false),
+		"failed to clone job source-job")
+	suite.Contains(suite.output.String(), "Invalid job config: invalid")
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/proto"
+	"gopkg.in/yaml.v2"
+)
+
+// jobDiffNoChanges is printed if the job configs do not differ
+const jobDiffNoChanges = "no changes\n"
+
+// jobConfigChange is a field which differs between two job configs. Old or
+// New is nil if the field is not set in the respective config.
+type jobConfigChange struct {
+	Path string      `json:"path"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// jobDiffResponse is the JSON output of the job diff command
+type jobDiffResponse struct {
+	Changes []jobConfigChange `json:"changes"`
+}
+
+// JobDiffAction is the action for printing the differences between the
+// config of a running job and a local job config, e.g. before updating the
+// job. Only the fields in the comma separated list of fields are compared,
+// all if it is empty. It returns whether the configs differ.
+func (c *Client) JobDiffAction(jobID, cfg, fields string) (bool, error) {
+	var localConfig job.JobConfig
+	buffer, err := ioutil.ReadFile(cfg)
+	if err != nil {
+		return false, fmt.Errorf("unable to open file %s: %v", cfg, err)
+	}
+	if err := yaml.Unmarshal(buffer, &localConfig); err != nil {
+		return false, fmt.Errorf("unable to parse file %s: %v", cfg, err)
+	}
+
+	response, err := c.jobGet(jobID)
+	if err != nil {
+		return false, err
+	}
+	if response.GetJobInfo().GetConfig() == nil {
+		return false, fmt.Errorf("unable to get config of job %s", jobID)
+	}
+
+	changes, err := diffJobConfigs(
+		response.GetJobInfo().GetConfig(), &localConfig)
+	if err != nil {
+		return false, err
+	}
+	changes = filterJobConfigChanges(changes, fields)
+
+	if c.JSON {
+		if changes == nil {
+			changes = []jobConfigChange{}
+		}
+		printResponseJSON(jobDiffResponse{Changes: changes})
+	} else {
+		cliOutPutter.output(formatJobConfigChanges(jobID, cfg, changes))
+	}
+	return len(changes) > 0, nil
+}
+
+// formatJobConfigChanges formats the changes as a unified diff
+func formatJobConfigChanges(jobID, cfg string, changes []jobConfigChange) string {
+	if len(changes) == 0 {
+		return jobDiffNoChanges
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "--- job %s\n", jobID)
+	fmt.Fprintf(&out, "+++ %s\n", cfg)
+	for _, change := range changes {
+		if change.Old != nil {
+			fmt.Fprintf(&out, "- %s: %s\n", change.Path, formatJobConfigValue(change.Old))
+		}
+		if change.New != nil {
+			fmt.Fprintf(&out, "+ %s: %s\n", change.Path, formatJobConfigValue(change.New))
+		}
+	}
+	return out.String()
+}
+
+// formatJobConfigValue formats a value of a job config field as JSON
+func formatJobConfigValue(value interface{}) string {
+	buffer, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(buffer)
+}
+
+// diffJobConfigs returns the changes between two normalized job configs,
+// sorted by the paths of the changed fields
+func diffJobConfigs(oldConfig, newConfig *job.JobConfig) ([]jobConfigChange, error) {
+	oldFields, err := flattenJobConfig(normalizeJobConfig(oldConfig))
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := flattenJobConfig(normalizeJobConfig(newConfig))
+	if err != nil {
+		return nil, err
+	}
+
+	var paths []string
+	for path := range oldFields {
+		paths = append(paths, path)
+	}
+	for path := range newFields {
+		if _, ok := oldFields[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var changes []jobConfigChange
+	for _, path := range paths {
+		if !reflect.DeepEqual(oldFields[path], newFields[path]) {
+			changes = append(changes, jobConfigChange{
+				Path: path,
+				Old:  oldFields[path],
+				New:  newFields[path],
+			})
+		}
+	}
+	return changes, nil
+}
+
+// filterJobConfigChanges returns the changes of the comma separated fields,
+// matched case insensitively against the elements of the changed paths
+func filterJobConfigChanges(changes []jobConfigChange, fields string) []jobConfigChange {
+	filter := make(map[string]bool)
+	for _, field := range strings.Split(fields, labelSeparator) {
+		if field = strings.TrimSpace(field); field != "" {
+			filter[strings.ToLower(field)] = true
+		}
+	}
+	if len(filter) == 0 {
+		return changes
+	}
+
+	var filtered []jobConfigChange
+	for _, change := range changes {
+		for _, element := range strings.Split(change.Path, ".") {
+			if i := strings.Index(element, "["); i >= 0 {
+				element = element[:i]
+			}
+			if filter[strings.ToLower(element)] {
+				filtered = append(filtered, change)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// normalizeJobConfig returns a copy of the job config without the fields
+// set by the job manager, with the server side defaults applied and
+// labels sorted
+func normalizeJobConfig(cfg *job.JobConfig) *job.JobConfig {
+	cfg = proto.Clone(cfg).(*job.JobConfig)
+	cfg.ChangeLog = nil
+	cfg.RespoolID = nil
+	sortLabels(cfg.Labels)
+	sort.Strings(cfg.LdapGroups)
+
+	normalizeTaskConfig(cfg.DefaultConfig)
+	for _, instanceConfig := range cfg.InstanceConfig {
+		normalizeTaskConfig(instanceConfig)
+	}
+	return cfg
+}
+
+// normalizeTaskConfig applies the server side defaults to a task config
+// and sorts its labels
+func normalizeTaskConfig(taskConfig *task.TaskConfig) {
+	if taskConfig == nil {
+		return
+	}
+	sortLabels(taskConfig.Labels)
+	if restartPolicy := taskConfig.GetRestartPolicy(); restartPolicy.GetMaxFailures() > jobValidateMaxTaskRetries {
+		restartPolicy.MaxFailures = jobValidateMaxTaskRetries
+	}
+}
+
+// sortLabels sorts labels by key and value
+func sortLabels(labels []*peloton.Label) {
+	sort.SliceStable(labels, func(i, j int) bool {
+		if labels[i].GetKey() != labels[j].GetKey() {
+			return labels[i].GetKey() < labels[j].GetKey()
+		}
+		return labels[i].GetValue() < labels[j].GetValue()
+	})
+}
+
+// flattenJobConfig returns the fields set in the job config, keyed by their
+// paths in the canonical JSON of the config
+func flattenJobConfig(cfg *job.JobConfig) (map[string]interface{}, error) {
+	body, err := (&jsonpb.Marshaler{}).MarshalToString(cfg)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(body), &value); err != nil {
+		return nil, err
+	}
+
+	fields := make(map[string]interface{})
+	flattenJSON(value, "", fields)
+	return fields, nil
+}
+
+// flattenJSON adds the leaf values of a JSON value to fields, keyed by their
+// paths. List items with a key, like labels, are keyed by it instead of
+// their index.
+func flattenJSON(value interface{}, path string, fields map[string]interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			itemPath := key
+			if path != "" {
+				itemPath = path + "." + key
+			}
+			flattenJSON(item, itemPath, fields)
+		}
+	case []interface{}:
+		for i, item := range v {
+			itemKey := strconv.Itoa(i)
+			m, ok := item.(map[string]interface{})
+			if key, keyed := m["key"].(string); ok && keyed {
+				itemKey = key
+				// a label, its empty value is omitted in the JSON
+				label, hasValue := m["value"]
+				if len(m) == 1 || (hasValue && len(m) == 2) {
+					if label == nil {
+						label = ""
+					}
+					fields[path+"["+key+"]"] = label
+					continue
+				}
+			}
+			flattenJSON(item, path+"["+itemKey+"]", fields)
+		}
+	default:
+		fields[path] = value
+	}
+}
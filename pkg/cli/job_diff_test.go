@@ -0,0 +1,154 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	jobmocks "github.com/uber/peloton/.gen/peloton/api/v0/job/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v2"
+)
+
+const jobDiffTestdata = "testdata/job_diff"
+
+type jobDiffTestSuite struct {
+	suite.Suite
+	mockCtrl     *gomock.Controller
+	mockJob      *jobmocks.MockJobManagerYARPCClient
+	output       *fakeOutputter
+	oldOutputter outputter
+	client       Client
+}
+
+func (suite *jobDiffTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockJob = jobmocks.NewMockJobManagerYARPCClient(suite.mockCtrl)
+	suite.output = &fakeOutputter{}
+	suite.oldOutputter = cliOutPutter
+	cliOutPutter = suite.output
+	suite.client = Client{
+		jobClient: suite.mockJob,
+		ctx:       context.Background(),
+	}
+}
+
+func (suite *jobDiffTestSuite) TearDownTest() {
+	cliOutPutter = suite.oldOutputter
+	suite.mockCtrl.Finish()
+}
+
+func TestJobDiff(t *testing.T) {
+	suite.Run(t, new(jobDiffTestSuite))
+}
+
+// expectJobGet returns the config of base.yaml as the running job, with the
+// fields set by the job manager
+func (suite *jobDiffTestSuite) expectJobGet() {
+	buffer, err := ioutil.ReadFile(filepath.Join(jobDiffTestdata, "base.yaml"))
+	suite.Require().NoError(err)
+	var cfg job.JobConfig
+	suite.Require().NoError(yaml.Unmarshal(buffer, &cfg))
+	cfg.ChangeLog = &peloton.ChangeLog{Version: 3}
+	cfg.RespoolID = &peloton.ResourcePoolID{Value: "respool-1"}
+	cfg.LdapGroups = []string{"money", "team6"}
+
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{Id: &peloton.JobID{Value: testJobID}}).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{Config: &cfg},
+		}, nil)
+}
+
+// TestJobDiffGolden tests the diff output against golden files
+func (suite *jobDiffTestSuite) TestJobDiffGolden() {
+	tt := []struct {
+		config string
+		json   bool
+		golden string
+	}{
+		{
+			config: "resources.yaml",
+			golden: "job_diff_resources.golden",
+		},
+		{
+			config: "labels.yaml",
+			golden: "job_diff_labels.golden",
+		},
+		{
+			config: "labels.yaml",
+			json:   true,
+			golden: "job_diff_labels.json.golden",
+		},
+	}
+
+	for _, t := range tt {
+		suite.expectJobGet()
+		suite.client.JSON = t.json
+		changed, err := suite.client.JobDiffAction(
+			testJobID, filepath.Join(jobDiffTestdata, t.config), "")
+		suite.NoError(err)
+		suite.True(changed)
+
+		expected, err := ioutil.ReadFile(filepath.Join("testdata", t.golden))
+		suite.NoError(err)
+		suite.Equal(string(expected), suite.output.Out, t.golden)
+	}
+}
+
+// TestJobDiffNoChanges tests that the normalized configs do not differ
+func (suite *jobDiffTestSuite) TestJobDiffNoChanges() {
+	suite.expectJobGet()
+	changed, err := suite.client.JobDiffAction(
+		testJobID, filepath.Join(jobDiffTestdata, "base.yaml"), "")
+	suite.NoError(err)
+	suite.False(changed)
+	suite.Equal(jobDiffNoChanges, suite.output.Out)
+}
+
+// TestJobDiffFields tests filtering the compared fields
+func (suite *jobDiffTestSuite) TestJobDiffFields() {
+	path := filepath.Join(jobDiffTestdata, "resources.yaml")
+
+	suite.expectJobGet()
+	changed, err := suite.client.JobDiffAction(testJobID, path, "command,labels")
+	suite.NoError(err)
+	suite.False(changed)
+	suite.Equal(jobDiffNoChanges, suite.output.Out)
+
+	suite.expectJobGet()
+	changed, err = suite.client.JobDiffAction(testJobID, path, "CPULimit")
+	suite.NoError(err)
+	suite.True(changed)
+	suite.Contains(suite.output.Out, "+ defaultConfig.resource.cpuLimit: 2\n")
+	suite.NotContains(suite.output.Out, "memLimitMb")
+}
+
+// TestJobDiffNotFound tests diffing against a job without config
+func (suite *jobDiffTestSuite) TestJobDiffNotFound() {
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), gomock.Any()).
+		Return(&job.GetResponse{}, nil)
+	_, err := suite.client.JobDiffAction(
+		testJobID, filepath.Join(jobDiffTestdata, "base.yaml"), "")
+	suite.Error(err)
+}
@@ -0,0 +1,269 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	statelesssvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless/svc"
+	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	// jobExportAPIVersionKey is the key of the API version of an exported
+	// job configuration, only set for stateless job specs
+	jobExportAPIVersionKey = "apiversion"
+	// jobExportSecretsKey is the key of the secrets of an exported job
+	// configuration
+	jobExportSecretsKey = "secrets"
+	// jobExportStatelessAPIVersion is the API version of exported stateless
+	// job specs
+	jobExportStatelessAPIVersion = "v1alpha"
+)
+
+// exportedSecret is the reference to a secret of an exported job. The job
+// manager does not return the data of secrets, so it has to be provisioned
+// again when the job is created.
+type exportedSecret struct {
+	ID          string `yaml:"id"`
+	Path        string `yaml:"path"`
+	Reprovision bool   `yaml:"reprovision"`
+}
+
+// jobExportMetadata are the keys of an exported job configuration which are
+// not fields of the configuration
+type jobExportMetadata struct {
+	APIVersion string           `yaml:"apiversion"`
+	Secrets    []exportedSecret `yaml:"secrets"`
+}
+
+// JobExportAction is the action for exporting the configuration of a job
+// in a format job create accepts. The fields set by the job manager, like
+// the resource pool and the change log, are left out. Service jobs are
+// exported as the spec of the v1alpha stateless API.
+func (c *Client) JobExportAction(jobID string, format string) error {
+	if format != OutputYAML {
+		return fmt.Errorf("unsupported export format %s", format)
+	}
+
+	response, err := c.jobGet(jobID)
+	if err != nil {
+		return err
+	}
+	if response.GetError() != nil {
+		return fmt.Errorf("failed to get job %s: %s",
+			jobID, response.GetError().String())
+	}
+	config := response.GetJobInfo().GetConfig()
+	if config == nil {
+		return fmt.Errorf("job %s not found", jobID)
+	}
+
+	var fields map[string]interface{}
+	var secrets []exportedSecret
+	if config.GetType() == job.JobType_SERVICE {
+		fields, secrets, err = c.exportStatelessJobSpec(jobID)
+		if err != nil {
+			return err
+		}
+	} else {
+		fields, secrets = exportJobConfig(config, response)
+	}
+
+	header := fmt.Sprintf(
+		"# Exported from job %s, create it with peloton job create\n", jobID)
+	if len(secrets) > 0 {
+		fields[jobExportSecretsKey] = secrets
+		header += "# The data of secrets is not exported, provision it " +
+			"again with --secret-path and --secret-data\n"
+	}
+	out, err := yaml.Marshal(fields)
+	if err != nil {
+		return err
+	}
+	cliOutPutter.output(header + string(out))
+	return nil
+}
+
+// exportJobConfig returns the fields of a job configuration without the
+// ones set by the job manager, and the secrets of the job
+func exportJobConfig(
+	config *job.JobConfig,
+	response *job.GetResponse) (map[string]interface{}, []exportedSecret) {
+	config.ChangeLog = nil
+	config.RespoolID = nil
+
+	var secrets []exportedSecret
+	for _, secret := range response.GetSecrets() {
+		secrets = append(secrets, exportedSecret{
+			ID:          secret.GetId().GetValue(),
+			Path:        secret.GetPath(),
+			Reprovision: true,
+		})
+	}
+	return exportFields(config), secrets
+}
+
+// exportStatelessJobSpec returns the fields of the spec of a stateless job
+// without the ones set by the job manager, and the secrets of the job
+func (c *Client) exportStatelessJobSpec(
+	jobID string) (map[string]interface{}, []exportedSecret, error) {
+	response, err := c.statelessClient.GetJob(c.ctx, &statelesssvc.GetJobRequest{
+		JobId: &v1alphapeloton.JobID{Value: jobID},
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	spec := response.GetJobInfo().GetSpec()
+	if spec == nil {
+		return nil, nil, fmt.Errorf("job %s has no stateless spec", jobID)
+	}
+	spec.Revision = nil
+	spec.RespoolId = nil
+
+	var secrets []exportedSecret
+	for _, secret := range response.GetSecrets() {
+		secrets = append(secrets, exportedSecret{
+			ID:          secret.GetSecretId().GetValue(),
+			Path:        secret.GetPath(),
+			Reprovision: true,
+		})
+	}
+	fields := exportFields(spec)
+	fields[jobExportAPIVersionKey] = jobExportStatelessAPIVersion
+	return fields, secrets, nil
+}
+
+// exportFields returns the fields of a job configuration or stateless spec
+// keyed as yaml.v2 reads them back
+func exportFields(config interface{}) map[string]interface{} {
+	fields, ok := exportValue(reflect.ValueOf(config))
+	if !ok {
+		return make(map[string]interface{})
+	}
+	return fields.(map[string]interface{})
+}
+
+// exportValue returns a value of a generated type in the form yaml.v2
+// decodes back into it, and false if the value is unset. Struct fields are
+// keyed by their lowercased names and left out if unset, enums are written
+// as integers as they are not decoded from their names. Pointers to
+// scalars of proto2 messages are kept even if zero, as their defaults may
+// differ.
+func exportValue(v reflect.Value) (interface{}, bool) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return nil, false
+		}
+		value, ok := exportValue(v.Elem())
+		if !ok && v.Kind() == reflect.Ptr {
+			return v.Elem().Interface(), true
+		}
+		return value, ok
+	case reflect.Struct:
+		fields := make(map[string]interface{})
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" || strings.HasPrefix(f.Name, "XXX_") {
+				continue
+			}
+			if value, ok := exportValue(v.Field(i)); ok {
+				fields[strings.ToLower(f.Name)] = value
+			}
+		}
+		// set messages are kept even without fields
+		return fields, true
+	case reflect.Map:
+		if v.Len() == 0 {
+			return nil, false
+		}
+		m := make(map[interface{}]interface{}, v.Len())
+		for _, key := range v.MapKeys() {
+			value, _ := exportValue(v.MapIndex(key))
+			m[key.Interface()] = value
+		}
+		return m, true
+	case reflect.Slice:
+		if v.Len() == 0 {
+			return nil, false
+		}
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Bytes(), true
+		}
+		list := make([]interface{}, v.Len())
+		for i := range list {
+			list[i], _ = exportValue(v.Index(i))
+		}
+		return list, true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), v.Int() != 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), v.Uint() != 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), v.Float() != 0
+	case reflect.Bool:
+		return v.Bool(), v.Bool()
+	case reflect.String:
+		return v.String(), v.String() != ""
+	default:
+		return nil, false
+	}
+}
+
+// parseJobExportMetadata returns the keys of an exported job configuration
+// which are not fields of the configuration
+func parseJobExportMetadata(buffer []byte) (jobExportMetadata, error) {
+	var metadata jobExportMetadata
+	err := yaml.Unmarshal(buffer, &metadata)
+	return metadata, err
+}
+
+// printUnprovisionedSecrets prints the secrets of an exported job
+// configuration which are not provisioned by the secret flags of job create
+func printUnprovisionedSecrets(
+	metadata jobExportMetadata,
+	secretPath string,
+	secret []byte) {
+	for _, s := range metadata.Secrets {
+		if !s.Reprovision || (s.Path == secretPath && len(secret) > 0) {
+			continue
+		}
+		fmt.Fprintf(tabWriter,
+			"Secret %s at path %s of the exported job is not provisioned, "+
+				"set it with --secret-path and --secret-data\n", s.ID, s.Path)
+	}
+	tabWriter.Flush()
+}
+
+// stripJobExportMetadata removes the keys of the metadata of exported job
+// configurations from the paths of unknown fields
+func stripJobExportMetadata(paths [][]string) [][]string {
+	var stripped [][]string
+	for _, path := range paths {
+		if len(path) > 0 && (path[0] == jobExportAPIVersionKey ||
+			path[0] == jobExportSecretsKey) {
+			continue
+		}
+		stripped = append(stripped, path)
+	}
+	return stripped
+}
@@ -0,0 +1,266 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"text/tabwriter"
+
+	pberr "github.com/uber/peloton/.gen/peloton/api/v0/errors"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	jobmocks "github.com/uber/peloton/.gen/peloton/api/v0/job/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	respoolmocks "github.com/uber/peloton/.gen/peloton/api/v0/respool/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless"
+	statelesssvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless/svc"
+	statelessmocks "github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless/svc/mocks"
+	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"gopkg.in/yaml.v2"
+)
+
+const testExportRespoolID = "export-respool"
+
+type jobExportTestSuite struct {
+	suite.Suite
+	mockCtrl      *gomock.Controller
+	mockJob       *jobmocks.MockJobManagerYARPCClient
+	mockRespool   *respoolmocks.MockResourceManagerYARPCClient
+	mockStateless *statelessmocks.MockJobServiceYARPCClient
+	table         *bytes.Buffer
+	oldTabWriter  *tabwriter.Writer
+	output        *fakeOutputter
+	oldOutputter  outputter
+	client        Client
+}
+
+func (suite *jobExportTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockJob = jobmocks.NewMockJobManagerYARPCClient(suite.mockCtrl)
+	suite.mockRespool = respoolmocks.NewMockResourceManagerYARPCClient(
+		suite.mockCtrl)
+	suite.mockStateless = statelessmocks.NewMockJobServiceYARPCClient(
+		suite.mockCtrl)
+	suite.table = &bytes.Buffer{}
+	suite.oldTabWriter = tabWriter
+	tabWriter = tabwriter.NewWriter(suite.table, 0, 0, 1, ' ', 0)
+	suite.output = &fakeOutputter{}
+	suite.oldOutputter = cliOutPutter
+	cliOutPutter = suite.output
+	suite.client = Client{
+		jobClient:       suite.mockJob,
+		resClient:       suite.mockRespool,
+		statelessClient: suite.mockStateless,
+		ctx:             context.Background(),
+	}
+}
+
+func (suite *jobExportTestSuite) TearDownTest() {
+	tabWriter = suite.oldTabWriter
+	cliOutPutter = suite.oldOutputter
+	suite.mockCtrl.Finish()
+}
+
+func TestJobExport(t *testing.T) {
+	suite.Run(t, new(jobExportTestSuite))
+}
+
+// writeExport writes the exported config to a temporary file and returns
+// its name
+func (suite *jobExportTestSuite) writeExport() string {
+	f, err := ioutil.TempFile("", "job-export")
+	suite.NoError(err)
+	defer f.Close()
+	_, err = f.WriteString(suite.output.Out)
+	suite.NoError(err)
+	return f.Name()
+}
+
+// expectRespoolLookup expects the lookup of the resource pool the exported
+// job is created in
+func (suite *jobExportTestSuite) expectRespoolLookup() {
+	suite.mockRespool.EXPECT().
+		LookupResourcePoolID(gomock.Any(), &respool.LookupRequest{
+			Path: &respool.ResourcePoolPath{Value: testRespoolPath},
+		}).
+		Return(&respool.LookupResponse{
+			Id: &peloton.ResourcePoolID{Value: testExportRespoolID},
+		}, nil)
+}
+
+// TestJobExportRoundTrip tests that creating a job from its exported config
+// creates it with the same config, without the fields set by the job
+// manager
+func (suite *jobExportTestSuite) TestJobExportRoundTrip() {
+	var config job.JobConfig
+	buffer, err := ioutil.ReadFile(testJobConfig)
+	suite.NoError(err)
+	suite.NoError(yaml.Unmarshal(buffer, &config))
+
+	exported := proto.Clone(&config).(*job.JobConfig)
+	exported.ChangeLog = &peloton.ChangeLog{Version: 3, CreatedAt: 1000}
+	exported.RespoolID = &peloton.ResourcePoolID{Value: "old-respool"}
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{Id: &peloton.JobID{Value: testJobID}}).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{
+				Id:      &peloton.JobID{Value: testJobID},
+				Config:  exported,
+				Runtime: &job.RuntimeInfo{State: job.JobState_RUNNING},
+			},
+			Secrets: []*peloton.Secret{{
+				Id:   &peloton.SecretID{Value: "secret-1"},
+				Path: testSecretPath,
+			}},
+		}, nil)
+
+	suite.NoError(suite.client.JobExportAction(testJobID, OutputYAML))
+	suite.NotContains(suite.output.Out, "changelog")
+	suite.NotContains(suite.output.Out, "old-respool")
+	suite.NotContains(suite.output.Out, "runtime")
+	suite.Contains(suite.output.Out, "reprovision: true")
+	cfg := suite.writeExport()
+	defer os.Remove(cfg)
+
+	// the keys of the export are not reported as unknown fields
+	_, problems := validateJobConfig([]byte(suite.output.Out))
+	for _, p := range problems {
+		suite.NotContains(p.Message, "unknown field")
+	}
+
+	config.RespoolID = &peloton.ResourcePoolID{Value: testExportRespoolID}
+	suite.expectRespoolLookup()
+	suite.mockJob.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *job.CreateRequest) {
+			suite.True(proto.Equal(&config, req.GetConfig()),
+				"%v != %v", &config, req.GetConfig())
+			suite.Empty(req.GetSecrets())
+		}).
+		Return(&job.CreateResponse{JobId: &peloton.JobID{Value: testJobID}}, nil)
+
+	suite.NoError(suite.client.JobCreateAction(testJobID, testRespoolPath, cfg, "", nil))
+	suite.Contains(suite.table.String(),
+		"Secret secret-1 at path /tmp/secret of the exported job is not provisioned")
+}
+
+// TestJobExportCreateWithSecret tests that a secret of an exported config
+// provisioned with the secret flags is created with the job
+func (suite *jobExportTestSuite) TestJobExportCreateWithSecret() {
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), gomock.Any()).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{
+				Config: &job.JobConfig{Name: "secret-job", InstanceCount: 1},
+			},
+			Secrets: []*peloton.Secret{{
+				Id:   &peloton.SecretID{Value: "secret-1"},
+				Path: testSecretPath,
+			}},
+		}, nil)
+	suite.NoError(suite.client.JobExportAction(testJobID, OutputYAML))
+	cfg := suite.writeExport()
+	defer os.Remove(cfg)
+
+	suite.expectRespoolLookup()
+	suite.mockJob.EXPECT().
+		Create(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *job.CreateRequest) {
+			suite.Equal("secret-job", req.GetConfig().GetName())
+			suite.Len(req.GetSecrets(), 1)
+			suite.Equal(testSecretPath, req.GetSecrets()[0].GetPath())
+		}).
+		Return(&job.CreateResponse{JobId: &peloton.JobID{Value: testJobID}}, nil)
+
+	suite.NoError(suite.client.JobCreateAction(
+		testJobID, testRespoolPath, cfg, testSecretPath, []byte(testSecretStr)))
+	suite.NotContains(suite.table.String(), "not provisioned")
+}
+
+// TestJobExportStateless tests that service jobs are exported as stateless
+// job specs, which job create creates with the stateless API
+func (suite *jobExportTestSuite) TestJobExportStateless() {
+	var spec stateless.JobSpec
+	buffer, err := ioutil.ReadFile(testStatelessSpecConfig)
+	suite.NoError(err)
+	suite.NoError(yaml.Unmarshal(buffer, &spec))
+
+	exported := proto.Clone(&spec).(*stateless.JobSpec)
+	exported.Revision = &v1alphapeloton.Revision{Version: 2}
+	exported.RespoolId = &v1alphapeloton.ResourcePoolID{Value: "old-respool"}
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), gomock.Any()).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{
+				Config: &job.JobConfig{Type: job.JobType_SERVICE},
+			},
+		}, nil)
+	suite.mockStateless.EXPECT().
+		GetJob(gomock.Any(), &statelesssvc.GetJobRequest{
+			JobId: &v1alphapeloton.JobID{Value: testJobID},
+		}).
+		Return(&statelesssvc.GetJobResponse{
+			JobInfo: &stateless.JobInfo{Spec: exported},
+		}, nil)
+
+	suite.NoError(suite.client.JobExportAction(testJobID, OutputYAML))
+	suite.Contains(suite.output.Out, "apiversion: v1alpha\n")
+	suite.Contains(suite.output.Out, "defaultspec:\n")
+	suite.NotContains(suite.output.Out, "old-respool")
+	cfg := suite.writeExport()
+	defer os.Remove(cfg)
+
+	spec.RespoolId = &v1alphapeloton.ResourcePoolID{Value: testExportRespoolID}
+	suite.expectRespoolLookup()
+	suite.mockStateless.EXPECT().
+		CreateJob(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *statelesssvc.CreateJobRequest) {
+			suite.True(proto.Equal(&spec, req.GetSpec()),
+				"%v != %v", &spec, req.GetSpec())
+		}).
+		Return(&statelesssvc.CreateJobResponse{
+			JobId: &v1alphapeloton.JobID{Value: testJobID},
+		}, nil)
+
+	suite.NoError(suite.client.JobCreateAction(testJobID, testRespoolPath, cfg, "", nil))
+}
+
+// TestJobExportNotFound tests that the error of the get response is
+// returned
+func (suite *jobExportTestSuite) TestJobExportNotFound() {
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), gomock.Any()).
+		Return(&job.GetResponse{
+			Error: &job.GetResponse_Error{
+				NotFound: &pberr.JobNotFound{Message: "job not found"},
+			},
+		}, nil)
+	suite.Error(suite.client.JobExportAction(testJobID, OutputYAML))
+	suite.Empty(suite.output.Out)
+}
+
+// TestJobExportFormat tests that unsupported formats are rejected
+func (suite *jobExportTestSuite) TestJobExportFormat() {
+	suite.EqualError(suite.client.JobExportAction(testJobID, OutputJSON),
+		"unsupported export format json")
+}
@@ -0,0 +1,271 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/query"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+)
+
+const (
+	jobFailuresGroupFormatHeader = "Count\tExample Instance\tExample Host\tFailure Message\n"
+	jobFailuresGroupFormatBody   = "%d\t%d\t%s\t%s\n"
+	jobFailuresHostFormatHeader  = "Host\tFailures\n"
+	jobFailuresHostFormatBody    = "%s\t%d\n"
+
+	// DefaultJobFailuresTop is the default number of failure groups and
+	// hosts printed by job failures
+	DefaultJobFailuresTop = 10
+)
+
+// failureMessageNormalizer replaces a variable part of failure messages,
+// e.g. a task ID, with a placeholder
+type failureMessageNormalizer struct {
+	pattern     *regexp.Regexp
+	placeholder string
+}
+
+// failureMessageNormalizers normalize the failure messages so that the
+// messages of tasks failing the same way are equal. They are applied in
+// order, task IDs before timestamps as both contain dashes.
+var failureMessageNormalizers = []failureMessageNormalizer{
+	{
+		// job and task IDs, e.g. <uuid>-<instance>-<run> or
+		// <uuid>-<instance>-<uuid>
+		pattern: regexp.MustCompile(
+			`(?i)[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12}(-[0-9]+\b)*` +
+				`(-[0-9a-f]{8}(-[0-9a-f]{4}){3}-[0-9a-f]{12})?`),
+		placeholder: "<id>",
+	},
+	{
+		// timestamps, e.g. 2019-01-02T03:04:05.123Z or 2019-01-02 03:04:05
+		pattern: regexp.MustCompile(
+			`[0-9]{4}-[0-9]{2}-[0-9]{2}[T ][0-9]{2}:[0-9]{2}:[0-9]{2}` +
+				`(\.[0-9]+)?(Z|[+-][0-9]{2}:?[0-9]{2})?`),
+		placeholder: "<time>",
+	},
+	{
+		// hex addresses, e.g. 0xc420010000
+		pattern:     regexp.MustCompile(`0[xX][0-9a-fA-F]+`),
+		placeholder: "<addr>",
+	},
+	{
+		// whitespace, including the new lines of stack traces
+		pattern:     regexp.MustCompile(`\s+`),
+		placeholder: " ",
+	},
+}
+
+// jobFailureGroup is a normalized failure message and the failed or lost
+// tasks with it
+type jobFailureGroup struct {
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+	// ExampleInstance is the lowest instance of the group, ExampleHost and
+	// ExampleMessage its host and failure message before normalization
+	ExampleInstance uint32   `json:"exampleInstance"`
+	ExampleHost     string   `json:"exampleHost"`
+	ExampleMessage  string   `json:"exampleMessage"`
+	Instances       []uint32 `json:"instances"`
+}
+
+// jobFailureHost is a host and the number of tasks which failed or were
+// lost on it
+type jobFailureHost struct {
+	Host     string `json:"host"`
+	Failures int    `json:"failures"`
+}
+
+// jobFailuresResponse is the JSON output of the job failures command
+type jobFailuresResponse struct {
+	JobID string `json:"jobId"`
+	// Tasks is the number of failed or lost tasks queried
+	Tasks  int               `json:"tasks"`
+	Groups []jobFailureGroup `json:"groups"`
+	Hosts  []jobFailureHost  `json:"hosts"`
+}
+
+// JobFailuresAction is the action for analyzing why the tasks of a job
+// failed. It queries the failed and lost tasks of the job, groups them by
+// their normalized failure messages, and prints the top groups with an
+// example instance and host, and the top hosts by number of failures. All
+// groups and hosts are printed in JSON. The tasks fetched until the query
+// is truncated are analyzed with a warning.
+func (c *Client) JobFailuresAction(jobID string, top uint32) error {
+	request := &task.QueryRequest{
+		JobId: &peloton.JobID{Value: jobID},
+		Spec: &task.QuerySpec{
+			TaskStates: []task.TaskState{
+				task.TaskState_FAILED,
+				task.TaskState_LOST,
+			},
+			Pagination: &query.PaginationSpec{},
+		},
+	}
+	var tasks []*task.TaskInfo
+	err := fetchAllPages("tasks", queryAllPageSize,
+		func(offset, limit uint32) (int, bool, error) {
+			request.Spec.Pagination.Offset = offset
+			request.Spec.Pagination.Limit = limit
+			response, err := c.taskClient.Query(c.ctx, request)
+			if err != nil {
+				return 0, false, err
+			}
+			if response.GetError() != nil {
+				return 0, false, fmt.Errorf(
+					"failed to query failed tasks of job %s: %s",
+					jobID, response.GetError().String())
+			}
+			tasks = append(tasks, response.GetRecords()...)
+			return len(response.GetRecords()), false, nil
+		})
+	if err := warnIfTruncated(err); err != nil {
+		return err
+	}
+
+	response := jobFailuresResponse{
+		JobID:  jobID,
+		Tasks:  len(tasks),
+		Groups: groupFailuresByMessage(tasks),
+		Hosts:  countFailuresByHost(tasks),
+	}
+	if c.outputFormat() == OutputJSON {
+		printResponseJSON(response)
+		return nil
+	}
+	printJobFailures(response, int(top))
+	return nil
+}
+
+// normalizeFailureMessage returns a failure message with its variable parts
+// replaced by placeholders
+func normalizeFailureMessage(message string) string {
+	for _, n := range failureMessageNormalizers {
+		message = n.pattern.ReplaceAllString(message, n.placeholder)
+	}
+	return strings.TrimSpace(message)
+}
+
+// failureMessage returns the failure message of a task, falling back to its
+// failure reason
+func failureMessage(t *task.TaskInfo) string {
+	message := strings.TrimSpace(t.GetRuntime().GetMessage())
+	if message == "" {
+		message = t.GetRuntime().GetReason()
+	}
+	if message == "" {
+		message = "unknown"
+	}
+	return message
+}
+
+// groupFailuresByMessage groups tasks by their normalized failure message,
+// the largest groups first
+func groupFailuresByMessage(tasks []*task.TaskInfo) []jobFailureGroup {
+	groups := make(map[string]*jobFailureGroup)
+	for _, t := range tasks {
+		message := failureMessage(t)
+		normalized := normalizeFailureMessage(message)
+		g, ok := groups[normalized]
+		if !ok {
+			g = &jobFailureGroup{Message: normalized}
+			groups[normalized] = g
+		}
+		if g.Count == 0 || t.GetInstanceId() < g.ExampleInstance {
+			g.ExampleInstance = t.GetInstanceId()
+			g.ExampleHost = t.GetRuntime().GetHost()
+			g.ExampleMessage = message
+		}
+		g.Count++
+		g.Instances = append(g.Instances, t.GetInstanceId())
+	}
+
+	result := make([]jobFailureGroup, 0, len(groups))
+	for _, g := range groups {
+		sort.Slice(g.Instances, func(i, j int) bool {
+			return g.Instances[i] < g.Instances[j]
+		})
+		result = append(result, *g)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		return result[i].Message < result[j].Message
+	})
+	return result
+}
+
+// countFailuresByHost counts the tasks which failed or were lost on each
+// host, the hosts with the most failures first. Tasks which never ran on a
+// host are not counted.
+func countFailuresByHost(tasks []*task.TaskInfo) []jobFailureHost {
+	counts := make(map[string]int)
+	for _, t := range tasks {
+		if host := t.GetRuntime().GetHost(); host != "" {
+			counts[host]++
+		}
+	}
+
+	hosts := make([]jobFailureHost, 0, len(counts))
+	for host, count := range counts {
+		hosts = append(hosts, jobFailureHost{Host: host, Failures: count})
+	}
+	sort.Slice(hosts, func(i, j int) bool {
+		if hosts[i].Failures != hosts[j].Failures {
+			return hosts[i].Failures > hosts[j].Failures
+		}
+		return hosts[i].Host < hosts[j].Host
+	})
+	return hosts
+}
+
+// printJobFailures prints the top groups of failures and the top hosts
+func printJobFailures(r jobFailuresResponse, top int) {
+	defer tabWriter.Flush()
+
+	if r.Tasks == 0 {
+		fmt.Fprintf(tabWriter, "Job %s has no failed or lost tasks\n", r.JobID)
+		return
+	}
+	fmt.Fprintf(tabWriter, "%d failed or lost task(s) of job %s in %d group(s)\n",
+		r.Tasks, r.JobID, len(r.Groups))
+	fmt.Fprint(tabWriter, jobFailuresGroupFormatHeader)
+	for i, g := range r.Groups {
+		if top > 0 && i == top {
+			break
+		}
+		fmt.Fprintf(tabWriter, jobFailuresGroupFormatBody,
+			g.Count, g.ExampleInstance, g.ExampleHost, g.Message)
+	}
+
+	if len(r.Hosts) == 0 {
+		return
+	}
+	fmt.Fprintf(tabWriter, "\nFailures of %d host(s):\n", len(r.Hosts))
+	fmt.Fprint(tabWriter, jobFailuresHostFormatHeader)
+	for i, h := range r.Hosts {
+		if top > 0 && i == top {
+			break
+		}
+		fmt.Fprintf(tabWriter, jobFailuresHostFormatBody, h.Host, h.Failures)
+	}
+}
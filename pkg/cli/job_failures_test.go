@@ -0,0 +1,332 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"testing"
+	"text/tabwriter"
+
+	pberr "github.com/uber/peloton/.gen/peloton/api/v0/errors"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/query"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	taskmocks "github.com/uber/peloton/.gen/peloton/api/v0/task/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+type jobFailuresTestSuite struct {
+	suite.Suite
+	mockCtrl     *gomock.Controller
+	mockTask     *taskmocks.MockTaskManagerYARPCClient
+	table        *bytes.Buffer
+	output       *fakeOutputter
+	oldTabWriter *tabwriter.Writer
+	oldOutputter outputter
+	client       Client
+}
+
+func (suite *jobFailuresTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockTask = taskmocks.NewMockTaskManagerYARPCClient(suite.mockCtrl)
+	suite.table = &bytes.Buffer{}
+	suite.output = &fakeOutputter{}
+	suite.oldTabWriter = tabWriter
+	suite.oldOutputter = cliOutPutter
+	tabWriter = tabwriter.NewWriter(suite.table, 0, 0, 1, ' ', 0)
+	cliOutPutter = suite.output
+	suite.client = Client{
+		taskClient: suite.mockTask,
+		ctx:        context.Background(),
+	}
+}
+
+func (suite *jobFailuresTestSuite) TearDownTest() {
+	tabWriter = suite.oldTabWriter
+	cliOutPutter = suite.oldOutputter
+	suite.mockCtrl.Finish()
+}
+
+func TestJobFailures(t *testing.T) {
+	suite.Run(t, new(jobFailuresTestSuite))
+}
+
+// jobFailuresTasks returns the failed and lost tasks of the job failures
+// fixture, whose messages only differ by task IDs, timestamps, addresses
+// and whitespace within each group
+func jobFailuresTasks() []*task.TaskInfo {
+	add := func(
+		instance uint32,
+		state task.TaskState,
+		host string,
+		message string,
+		reason string) *task.TaskInfo {
+		return &task.TaskInfo{
+			InstanceId: instance,
+			Runtime: &task.RuntimeInfo{
+				State:   state,
+				Host:    host,
+				Message: message,
+				Reason:  reason,
+			},
+		}
+	}
+	return []*task.TaskInfo{
+		add(6, task.TaskState_FAILED, "host-2",
+			"Command exited with status 1: task "+
+				"9a8b7c6d-5e4f-4a3b-2c1d-0e9f8a7b6c5d-6-1 failed at "+
+				"2019-01-02 05:06:07.123+0000",
+			"REASON_COMMAND_EXITED"),
+		add(0, task.TaskState_FAILED, "host-1",
+			"Command exited with status 1: task "+
+				"0fa2b3c4-1d2e-4f50-8a6b-7c8d9e0f1a2b-0-3 failed at "+
+				"2019-01-02T03:04:05Z",
+			"REASON_COMMAND_EXITED"),
+		add(1, task.TaskState_FAILED, "host-2",
+			"Command exited with status 1: task "+
+				"0FA2B3C4-1D2E-4F50-8A6B-7C8D9E0F1A2B-1-"+
+				"5c4d3e2f-1a0b-4c9d-8e7f-6a5b4c3d2e1f failed at "+
+				"2019-01-03T03:04:05.999999-07:00",
+			"REASON_COMMAND_EXITED"),
+		add(2, task.TaskState_LOST, "host-1",
+			"Task lost: agent removed", "REASON_AGENT_REMOVED"),
+		add(3, task.TaskState_FAILED, "host-1",
+			"panic: runtime error: invalid memory address\n\tat 0xc420010000\n"+
+				"goroutine 1",
+			"REASON_COMMAND_EXITED"),
+		add(4, task.TaskState_FAILED, "host-3",
+			"panic: runtime error: invalid memory address\n\tat 0xC420ABCDEF  "+
+				"goroutine 1 ",
+			"REASON_COMMAND_EXITED"),
+		add(5, task.TaskState_FAILED, "",
+			"", "REASON_CONTAINER_LAUNCH_FAILED"),
+	}
+}
+
+// TestNormalizeFailureMessage tests replacing the variable parts of failure
+// messages
+func (suite *jobFailuresTestSuite) TestNormalizeFailureMessage() {
+	tt := []struct {
+		message    string
+		normalized string
+	}{
+		{
+			message:    "Command exited with status 137",
+			normalized: "Command exited with status 137",
+		},
+		{
+			message:    "task 0fa2b3c4-1d2e-4f50-8a6b-7c8d9e0f1a2b-0-3 killed",
+			normalized: "task <id> killed",
+		},
+		{
+			message: "task 0fa2b3c4-1d2e-4f50-8a6b-7c8d9e0f1a2b-12-" +
+				"5c4d3e2f-1a0b-4c9d-8e7f-6a5b4c3d2e1f killed",
+			normalized: "task <id> killed",
+		},
+		{
+			message:    "job 0FA2B3C4-1D2E-4F50-8A6B-7C8D9E0F1A2B not found",
+			normalized: "job <id> not found",
+		},
+		{
+			message:    "deadline 2019-01-02T03:04:05.123456789Z exceeded",
+			normalized: "deadline <time> exceeded",
+		},
+		{
+			message:    "at 2019-01-02 03:04:05 +0000 UTC",
+			normalized: "at <time> +0000 UTC",
+		},
+		{
+			message:    "at 2019-01-02T03:04:05-07:00, retrying",
+			normalized: "at <time>, retrying",
+		},
+		{
+			message:    "SIGSEGV: segmentation violation addr=0x0 pc=0x45f8a1",
+			normalized: "SIGSEGV: segmentation violation addr=<addr> pc=<addr>",
+		},
+		{
+			message:    "  out of memory\n\tkilled \n",
+			normalized: "out of memory killed",
+		},
+	}
+	for _, t := range tt {
+		suite.Equal(t.normalized, normalizeFailureMessage(t.message), t.message)
+	}
+}
+
+// TestGroupFailuresByMessage tests grouping tasks by their normalized
+// failure message, the largest groups first with their lowest instance as
+// example
+func (suite *jobFailuresTestSuite) TestGroupFailuresByMessage() {
+	suite.Equal([]jobFailureGroup{
+		{
+			Message:         "Command exited with status 1: task <id> failed at <time>",
+			Count:           3,
+			ExampleInstance: 0,
+			ExampleHost:     "host-1",
+			ExampleMessage: "Command exited with status 1: task " +
+				"0fa2b3c4-1d2e-4f50-8a6b-7c8d9e0f1a2b-0-3 failed at " +
+				"2019-01-02T03:04:05Z",
+			Instances: []uint32{0, 1, 6},
+		},
+		{
+			Message:         "panic: runtime error: invalid memory address at <addr> goroutine 1",
+			Count:           2,
+			ExampleInstance: 3,
+			ExampleHost:     "host-1",
+			ExampleMessage: "panic: runtime error: invalid memory address\n" +
+				"\tat 0xc420010000\ngoroutine 1",
+			Instances: []uint32{3, 4},
+		},
+		{
+			Message:         "REASON_CONTAINER_LAUNCH_FAILED",
+			Count:           1,
+			ExampleInstance: 5,
+			ExampleMessage:  "REASON_CONTAINER_LAUNCH_FAILED",
+			Instances:       []uint32{5},
+		},
+		{
+			Message:         "Task lost: agent removed",
+			Count:           1,
+			ExampleInstance: 2,
+			ExampleHost:     "host-1",
+			ExampleMessage:  "Task lost: agent removed",
+			Instances:       []uint32{2},
+		},
+	}, groupFailuresByMessage(jobFailuresTasks()))
+}
+
+// TestCountFailuresByHost tests counting the failures of each host, the
+// tasks which never ran on a host are not counted
+func (suite *jobFailuresTestSuite) TestCountFailuresByHost() {
+	suite.Equal([]jobFailureHost{
+		{Host: "host-1", Failures: 3},
+		{Host: "host-2", Failures: 2},
+		{Host: "host-3", Failures: 1},
+	}, countFailuresByHost(jobFailuresTasks()))
+}
+
+// expectQuery expects a query of the failed and lost tasks of the job
+func (suite *jobFailuresTestSuite) expectQuery() *gomock.Call {
+	return suite.mockTask.EXPECT().
+		Query(gomock.Any(), &task.QueryRequest{
+			JobId: &peloton.JobID{Value: testJobID},
+			Spec: &task.QuerySpec{
+				TaskStates: []task.TaskState{
+					task.TaskState_FAILED,
+					task.TaskState_LOST,
+				},
+				Pagination: &query.PaginationSpec{
+					Limit: queryAllPageSize,
+				},
+			},
+		})
+}
+
+// TestJobFailuresAction tests printing the top groups and hosts
+func (suite *jobFailuresTestSuite) TestJobFailuresAction() {
+	suite.expectQuery().Return(&task.QueryResponse{
+		Records: jobFailuresTasks(),
+	}, nil)
+
+	suite.NoError(suite.client.JobFailuresAction(testJobID, 2))
+	suite.Equal(
+		"7 failed or lost task(s) of job "+testJobID+" in 4 group(s)\n"+
+			"Count Example Instance Example Host Failure Message\n"+
+			"3     0                host-1       Command exited with status 1: task <id> failed at <time>\n"+
+			"2     3                host-1       panic: runtime error: invalid memory address at <addr> goroutine 1\n"+
+			"\n"+
+			"Failures of 3 host(s):\n"+
+			"Host   Failures\n"+
+			"host-1 3\n"+
+			"host-2 2\n",
+		suite.table.String())
+}
+
+// TestJobFailuresActionJSON tests that all the groups and hosts are printed
+// in JSON
+func (suite *jobFailuresTestSuite) TestJobFailuresActionJSON() {
+	suite.expectQuery().Return(&task.QueryResponse{
+		Records: jobFailuresTasks(),
+	}, nil)
+
+	suite.client.JSON = true
+	suite.NoError(suite.client.JobFailuresAction(testJobID, 1))
+	var response jobFailuresResponse
+	suite.NoError(json.Unmarshal([]byte(suite.output.Out), &response))
+	suite.Equal(jobFailuresResponse{
+		JobID:  testJobID,
+		Tasks:  7,
+		Groups: groupFailuresByMessage(jobFailuresTasks()),
+		Hosts:  countFailuresByHost(jobFailuresTasks()),
+	}, response)
+	suite.Empty(suite.table.String())
+}
+
+// TestJobFailuresActionNoFailures tests a job without failed tasks
+func (suite *jobFailuresTestSuite) TestJobFailuresActionNoFailures() {
+	suite.expectQuery().Return(&task.QueryResponse{}, nil)
+
+	suite.NoError(suite.client.JobFailuresAction(testJobID, DefaultJobFailuresTop))
+	suite.Equal("Job "+testJobID+" has no failed or lost tasks\n",
+		suite.table.String())
+}
+
+// TestJobFailuresActionTruncated tests that the tasks fetched until the
+// query is truncated are analyzed with a warning
+func (suite *jobFailuresTestSuite) TestJobFailuresActionTruncated() {
+	var warnings bytes.Buffer
+	warningOutput = &warnings
+	defer func() { warningOutput = os.Stderr }()
+
+	records := make([]*task.TaskInfo, queryAllPageSize)
+	for i := range records {
+		records[i] = jobFailuresTasks()[0]
+	}
+	suite.mockTask.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		Return(&task.QueryResponse{Records: records}, nil).
+		Times(queryAllMaxResults / queryAllPageSize)
+
+	suite.client.JSON = true
+	suite.NoError(suite.client.JobFailuresAction(testJobID, DefaultJobFailuresTop))
+	var response jobFailuresResponse
+	suite.NoError(json.Unmarshal([]byte(suite.output.Out), &response))
+	suite.Equal(queryAllMaxResults, response.Tasks)
+	suite.Contains(warnings.String(), "more tasks may remain")
+}
+
+// TestJobFailuresActionErrors tests the errors of the task query
+func (suite *jobFailuresTestSuite) TestJobFailuresActionErrors() {
+	suite.expectQuery().Return(nil, errors.New("unavailable"))
+	suite.EqualError(
+		suite.client.JobFailuresAction(testJobID, DefaultJobFailuresTop),
+		"unavailable")
+
+	suite.expectQuery().Return(&task.QueryResponse{
+		Error: &task.QueryResponse_Error{
+			NotFound: &pberr.JobNotFound{
+				Id:      &peloton.JobID{Value: testJobID},
+				Message: "not found",
+			},
+		},
+	}, nil)
+	suite.Error(suite.client.JobFailuresAction(testJobID, DefaultJobFailuresTop))
+}
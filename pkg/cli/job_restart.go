@@ -0,0 +1,163 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless"
+	statelesssvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless/svc"
+	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+	v1alphapod "github.com/uber/peloton/.gen/peloton/api/v1alpha/pod"
+)
+
+const (
+	jobRestartFormatHeader = "Job\tWorkflow State\tCompleted\tRemaining\tFailed\tCurrent Instances\t\n"
+	jobRestartFormatBody   = "%s\t%s\t%d\t%d\t%d\t%s\t\n"
+)
+
+// JobRestartMonitorAction is the action for restarting the instances of a
+// running service. The instances, e.g. "0-9,15", default to all instances
+// of the job, and batchSize bounds the number of instances restarted at
+// the same time. Unless detach is set, the restart workflow is then
+// monitored every interval until it completes, and an error is returned if
+// it fails, is aborted or is replaced by another workflow.
+func (c *Client) JobRestartMonitorAction(
+	jobID string,
+	instances string,
+	batchSize uint32,
+	inPlace bool,
+	detach bool,
+	interval time.Duration) error {
+	response, err := c.statelessClient.GetJob(c.ctx, &statelesssvc.GetJobRequest{
+		JobId: &v1alphapeloton.JobID{Value: jobID},
+	})
+	if err != nil {
+		return err
+	}
+
+	var ranges []*v1alphapod.InstanceIDRange
+	if instances != "" {
+		instanceRanges, err := c.ExtractInstanceRanges(
+			instances,
+			response.GetJobInfo().GetSpec().GetInstanceCount())
+		if err != nil {
+			return err
+		}
+		for _, r := range instanceRanges {
+			ranges = append(ranges, &v1alphapod.InstanceIDRange{
+				From: r.GetFrom(),
+				To:   r.GetTo(),
+			})
+		}
+	}
+
+	restartResponse, err := c.statelessClient.RestartJob(
+		c.ctx,
+		&statelesssvc.RestartJobRequest{
+			JobId:   &v1alphapeloton.JobID{Value: jobID},
+			Version: response.GetJobInfo().GetStatus().GetVersion(),
+			RestartSpec: &stateless.RestartSpec{
+				BatchSize: batchSize,
+				Ranges:    ranges,
+				InPlace:   inPlace,
+			},
+		})
+	if err != nil {
+		return err
+	}
+	version := restartResponse.GetVersion()
+	fmt.Fprintf(tabWriter, "Restart of job %s started, new entity version %s\n",
+		jobID, version.GetValue())
+	tabWriter.Flush()
+	if detach {
+		return nil
+	}
+
+	var state stateless.WorkflowState
+	var done bool
+	if err := c.Watch(interval, func(ctx context.Context) (bool, error) {
+		var err error
+		state, done, err = c.jobRestartStatus(ctx, jobID, version)
+		return done, err
+	}); err != nil || !done {
+		// the restart continues if the monitoring is interrupted
+		return err
+	}
+	if state != stateless.WorkflowState_WORKFLOW_STATE_SUCCEEDED {
+		return fmt.Errorf("restart of job %s ended in state %s",
+			jobID, state.String())
+	}
+	fmt.Fprintf(tabWriter, "Restart of job %s succeeded\n", jobID)
+	tabWriter.Flush()
+	return nil
+}
+
+// jobRestartStatus prints the status of the restart workflow of a job, and
+// returns its state and whether the workflow is terminal. The state of a
+// workflow which replaced the restart is returned as aborted.
+func (c *Client) jobRestartStatus(
+	ctx context.Context,
+	jobID string,
+	version *v1alphapeloton.EntityVersion,
+) (stateless.WorkflowState, bool, error) {
+	response, err := c.statelessClient.GetJob(ctx, &statelesssvc.GetJobRequest{
+		JobId: &v1alphapeloton.JobID{Value: jobID},
+	})
+	if err != nil {
+		return stateless.WorkflowState_WORKFLOW_STATE_INVALID, false, err
+	}
+
+	status := response.GetJobInfo().GetStatus().GetWorkflowStatus()
+	if status.GetVersion().GetValue() != version.GetValue() {
+		fmt.Fprintf(tabWriter,
+			"Restart of job %s was replaced by a workflow at entity version %s\n",
+			jobID, status.GetVersion().GetValue())
+		tabWriter.Flush()
+		return stateless.WorkflowState_WORKFLOW_STATE_ABORTED, true, nil
+	}
+
+	var current []string
+	for _, instance := range status.GetInstancesCurrent() {
+		current = append(current, fmt.Sprint(instance))
+	}
+	fmt.Fprint(tabWriter, jobRestartFormatHeader)
+	fmt.Fprintf(tabWriter, jobRestartFormatBody,
+		jobID,
+		status.GetState().String(),
+		status.GetNumInstancesCompleted(),
+		status.GetNumInstancesRemaining(),
+		status.GetNumInstancesFailed(),
+		strings.Join(current, instanceSeparator),
+	)
+	tabWriter.Flush()
+	return status.GetState(), isWorkflowStateTerminal(status.GetState()), nil
+}
+
+// isWorkflowStateTerminal returns whether a workflow in the state has
+// completed
+func isWorkflowStateTerminal(state stateless.WorkflowState) bool {
+	switch state {
+	case stateless.WorkflowState_WORKFLOW_STATE_SUCCEEDED,
+		stateless.WorkflowState_WORKFLOW_STATE_ABORTED,
+		stateless.WorkflowState_WORKFLOW_STATE_FAILED,
+		stateless.WorkflowState_WORKFLOW_STATE_ROLLED_BACK:
+		return true
+	}
+	return false
+}
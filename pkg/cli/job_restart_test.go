@@ -0,0 +1,248 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless"
+	statelesssvc "github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless/svc"
+	statelessmocks "github.com/uber/peloton/.gen/peloton/api/v1alpha/job/stateless/svc/mocks"
+	v1alphapeloton "github.com/uber/peloton/.gen/peloton/api/v1alpha/peloton"
+	v1alphapod "github.com/uber/peloton/.gen/peloton/api/v1alpha/pod"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	testRestartVersion    = "1-1-1"
+	testRestartNewVersion = "1-1-2"
+)
+
+type jobRestartTestSuite struct {
+	suite.Suite
+	mockCtrl         *gomock.Controller
+	mockStateless    *statelessmocks.MockJobServiceYARPCClient
+	table            *bytes.Buffer
+	oldTabWriter     *tabwriter.Writer
+	oldWatchOutput   io.Writer
+	oldWatchTerminal func() bool
+	client           Client
+}
+
+func (suite *jobRestartTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockStateless = statelessmocks.NewMockJobServiceYARPCClient(
+		suite.mockCtrl)
+	suite.table = &bytes.Buffer{}
+	suite.oldTabWriter = tabWriter
+	tabWriter = tabwriter.NewWriter(suite.table, 0, 0, 1, ' ', 0)
+	suite.oldWatchOutput, suite.oldWatchTerminal = watchOutput, watchIsTerminal
+	watchOutput = &bytes.Buffer{}
+	watchIsTerminal = func() bool { return false }
+	suite.client = Client{
+		statelessClient: suite.mockStateless,
+		ctx:             context.Background(),
+	}
+}
+
+func (suite *jobRestartTestSuite) TearDownTest() {
+	tabWriter = suite.oldTabWriter
+	watchOutput, watchIsTerminal = suite.oldWatchOutput, suite.oldWatchTerminal
+	suite.mockCtrl.Finish()
+}
+
+func TestJobRestart(t *testing.T) {
+	suite.Run(t, new(jobRestartTestSuite))
+}
+
+// getJobResponse returns the response of GetJob for a job of 10 instances
+// with the workflow status
+func getJobResponse(
+	version string,
+	workflow *stateless.WorkflowStatus) *statelesssvc.GetJobResponse {
+	return &statelesssvc.GetJobResponse{
+		JobInfo: &stateless.JobInfo{
+			JobId: &v1alphapeloton.JobID{Value: testJobID},
+			Spec:  &stateless.JobSpec{InstanceCount: 10},
+			Status: &stateless.JobStatus{
+				Version:        &v1alphapeloton.EntityVersion{Value: version},
+				WorkflowStatus: workflow,
+			},
+		},
+	}
+}
+
+// restartWorkflow returns the status of the restart workflow in the state
+func restartWorkflow(
+	state stateless.WorkflowState,
+	completed uint32) *stateless.WorkflowStatus {
+	return &stateless.WorkflowStatus{
+		Type:                  stateless.WorkflowType_WORKFLOW_TYPE_RESTART,
+		State:                 state,
+		NumInstancesCompleted: completed,
+		NumInstancesRemaining: 4 - completed,
+		InstancesCurrent:      []uint32{completed, completed + 1},
+		Version:               &v1alphapeloton.EntityVersion{Value: testRestartNewVersion},
+	}
+}
+
+// expectRestart expects the restart of the job with the spec
+func (suite *jobRestartTestSuite) expectRestart(spec *stateless.RestartSpec) {
+	suite.mockStateless.EXPECT().
+		GetJob(gomock.Any(), &statelesssvc.GetJobRequest{
+			JobId: &v1alphapeloton.JobID{Value: testJobID},
+		}).
+		Return(getJobResponse(testRestartVersion, nil), nil)
+	suite.mockStateless.EXPECT().
+		RestartJob(gomock.Any(), &statelesssvc.RestartJobRequest{
+			JobId:       &v1alphapeloton.JobID{Value: testJobID},
+			Version:     &v1alphapeloton.EntityVersion{Value: testRestartVersion},
+			RestartSpec: spec,
+		}).
+		Return(&statelesssvc.RestartJobResponse{
+			Version: &v1alphapeloton.EntityVersion{Value: testRestartNewVersion},
+		}, nil)
+}
+
+// TestJobRestartRequest tests the restart request built from the instance
+// ranges, batch size and in-place flag
+func (suite *jobRestartTestSuite) TestJobRestartRequest() {
+	suite.expectRestart(&stateless.RestartSpec{
+		BatchSize: 5,
+		Ranges: []*v1alphapod.InstanceIDRange{
+			{From: 0, To: 4},
+			{From: 7, To: 10},
+		},
+		InPlace: true,
+	})
+	suite.NoError(suite.client.JobRestartMonitorAction(
+		testJobID, "7-15,0-3", 5, true, true, time.Millisecond))
+	suite.Contains(suite.table.String(),
+		"Restart of job "+testJobID+" started, new entity version "+
+			testRestartNewVersion)
+
+	// all instances are restarted by default
+	suite.expectRestart(&stateless.RestartSpec{})
+	suite.NoError(suite.client.JobRestartMonitorAction(
+		testJobID, "", 0, false, true, time.Millisecond))
+}
+
+// TestJobRestartInvalidInstances tests that invalid instance ranges are
+// rejected before the restart
+func (suite *jobRestartTestSuite) TestJobRestartInvalidInstances() {
+	for _, instances := range []string{"3-1", "10-12", "a-b", "0-5,4"} {
+		suite.mockStateless.EXPECT().
+			GetJob(gomock.Any(), gomock.Any()).
+			Return(getJobResponse(testRestartVersion, nil), nil)
+		suite.Error(suite.client.JobRestartMonitorAction(
+			testJobID, instances, 1, false, true, time.Millisecond), instances)
+	}
+}
+
+// TestJobRestartMonitor tests monitoring the restart until its workflow is
+// terminal
+func (suite *jobRestartTestSuite) TestJobRestartMonitor() {
+	tt := []struct {
+		name     string
+		final    *statelesssvc.GetJobResponse
+		expected string
+	}{
+		{
+			name: "succeeded",
+			final: getJobResponse(testRestartNewVersion, restartWorkflow(
+				stateless.WorkflowState_WORKFLOW_STATE_SUCCEEDED, 4)),
+		},
+		{
+			name: "failed",
+			final: getJobResponse(testRestartNewVersion, restartWorkflow(
+				stateless.WorkflowState_WORKFLOW_STATE_FAILED, 2)),
+			expected: "restart of job " + testJobID +
+				" ended in state WORKFLOW_STATE_FAILED",
+		},
+		{
+			name: "aborted",
+			final: getJobResponse(testRestartNewVersion, restartWorkflow(
+				stateless.WorkflowState_WORKFLOW_STATE_ABORTED, 2)),
+			expected: "restart of job " + testJobID +
+				" ended in state WORKFLOW_STATE_ABORTED",
+		},
+		{
+			name: "replaced",
+			final: getJobResponse("1-1-3", &stateless.WorkflowStatus{
+				Type:    stateless.WorkflowType_WORKFLOW_TYPE_UPDATE,
+				State:   stateless.WorkflowState_WORKFLOW_STATE_ROLLING_FORWARD,
+				Version: &v1alphapeloton.EntityVersion{Value: "1-1-3"},
+			}),
+			expected: "restart of job " + testJobID +
+				" ended in state WORKFLOW_STATE_ABORTED",
+		},
+	}
+
+	for _, t := range tt {
+		suite.table.Reset()
+		suite.expectRestart(&stateless.RestartSpec{BatchSize: 2})
+		gomock.InOrder(
+			suite.mockStateless.EXPECT().
+				GetJob(gomock.Any(), gomock.Any()).
+				Return(getJobResponse(testRestartNewVersion, restartWorkflow(
+					stateless.WorkflowState_WORKFLOW_STATE_ROLLING_FORWARD, 0)), nil),
+			suite.mockStateless.EXPECT().
+				GetJob(gomock.Any(), gomock.Any()).
+				Return(t.final, nil),
+		)
+
+		err := suite.client.JobRestartMonitorAction(
+			testJobID, "", 2, false, false, time.Millisecond)
+		if t.expected == "" {
+			suite.NoError(err, t.name)
+			suite.Contains(suite.table.String(),
+				"Restart of job "+testJobID+" succeeded", t.name)
+		} else {
+			suite.EqualError(err, t.expected, t.name)
+		}
+		suite.Contains(suite.table.String(), "WORKFLOW_STATE_ROLLING_FORWARD 0", t.name)
+	}
+}
+
+// TestJobRestartMonitorError tests that the monitoring stops on errors of
+// the job status
+func (suite *jobRestartTestSuite) TestJobRestartMonitorError() {
+	suite.expectRestart(&stateless.RestartSpec{})
+	suite.mockStateless.EXPECT().
+		GetJob(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("get job failed"))
+	suite.EqualError(suite.client.JobRestartMonitorAction(
+		testJobID, "", 0, false, false, time.Millisecond), "get job failed")
+}
+
+// TestIsWorkflowStateTerminal tests the terminal workflow states
+func (suite *jobRestartTestSuite) TestIsWorkflowStateTerminal() {
+	for state := range stateless.WorkflowState_name {
+		s := stateless.WorkflowState(state)
+		expected := s == stateless.WorkflowState_WORKFLOW_STATE_SUCCEEDED ||
+			s == stateless.WorkflowState_WORKFLOW_STATE_ABORTED ||
+			s == stateless.WorkflowState_WORKFLOW_STATE_FAILED ||
+			s == stateless.WorkflowState_WORKFLOW_STATE_ROLLED_BACK
+		suite.Equal(expected, isWorkflowStateTerminal(s), s.String())
+	}
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/query"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+)
+
+const (
+	jobStatusFailuresFormatHeader = "Count\tFailure Message\n"
+	jobStatusFailuresFormatBody   = "%d\t%s\n"
+
+	// jobStatusFailureQueryLimit is the maximum number of failed tasks
+	// queried for the failure messages
+	jobStatusFailureQueryLimit = 500
+	// jobStatusMaxFailureMessages is the number of most common failure
+	// messages printed
+	jobStatusMaxFailureMessages = 5
+)
+
+// jobStatusFailure is a failure message and the number of failed tasks
+// with it
+type jobStatusFailure struct {
+	message string
+	count   int
+}
+
+// printJobStatusSummary prints the state of a job, the number of its tasks
+// in each state and the most common failure messages of its failed tasks
+func (c *Client) printJobStatusSummary(
+	ctx context.Context,
+	jobID string,
+	r *job.GetResponse) error {
+	defer tabWriter.Flush()
+
+	runtime := r.GetJobInfo().GetRuntime()
+	if runtime == nil {
+		fmt.Fprint(tabWriter, "Unable to get job status\n")
+		return nil
+	}
+	fmt.Fprintf(tabWriter, "Job %s is %s\n", jobID,
+		c.colorizer().state(runtime.GetState().String()))
+	fmt.Fprintf(tabWriter, "Tasks: %s\n", formatTaskStats(runtime.GetTaskStats()))
+
+	if runtime.GetTaskStats()[task.TaskState_FAILED.String()] == 0 {
+		return nil
+	}
+	failures, queried, err := c.jobFailures(ctx, jobID)
+	if err != nil {
+		return err
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	fmt.Fprintf(tabWriter, "\nMost common failures of %d failed task(s):\n", queried)
+	fmt.Fprint(tabWriter, jobStatusFailuresFormatHeader)
+	for _, f := range failures {
+		fmt.Fprintf(tabWriter, jobStatusFailuresFormatBody, f.count, f.message)
+	}
+	return nil
+}
+
+// formatTaskStats formats the number of tasks in each state, the most
+// common states first, e.g. "4200 RUNNING, 12 FAILED, 3 PENDING"
+func formatTaskStats(stats map[string]uint32) string {
+	var states []string
+	for state, count := range stats {
+		if count > 0 {
+			states = append(states, state)
+		}
+	}
+	if len(states) == 0 {
+		return "none"
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if stats[states[i]] != stats[states[j]] {
+			return stats[states[i]] > stats[states[j]]
+		}
+		return states[i] < states[j]
+	})
+	for i, state := range states {
+		states[i] = fmt.Sprintf("%d %s", stats[state], state)
+	}
+	return strings.Join(states, ", ")
+}
+
+// jobFailures queries a bounded number of the failed tasks of a job, and
+// returns their most common failure messages and the number of tasks
+// queried
+func (c *Client) jobFailures(
+	ctx context.Context,
+	jobID string) ([]jobStatusFailure, int, error) {
+	response, err := c.taskClient.Query(ctx, &task.QueryRequest{
+		JobId: &peloton.JobID{Value: jobID},
+		Spec: &task.QuerySpec{
+			TaskStates: []task.TaskState{task.TaskState_FAILED},
+			Pagination: &query.PaginationSpec{
+				Limit: jobStatusFailureQueryLimit,
+			},
+		},
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	if response.GetError() != nil {
+		return nil, 0, fmt.Errorf("failed to query failed tasks of job %s: %s",
+			jobID, response.GetError().String())
+	}
+	return groupFailures(response.GetRecords()), len(response.GetRecords()), nil
+}
+
+// groupFailures groups tasks by their failure message, falling back to the
+// failure reason, and returns the most common ones
+func groupFailures(tasks []*task.TaskInfo) []jobStatusFailure {
+	counts := make(map[string]int)
+	for _, t := range tasks {
+		counts[failureMessage(t)]++
+	}
+
+	failures := make([]jobStatusFailure, 0, len(counts))
+	for message, count := range counts {
+		failures = append(failures, jobStatusFailure{message: message, count: count})
+	}
+	sort.Slice(failures, func(i, j int) bool {
+		if failures[i].count != failures[j].count {
+			return failures[i].count > failures[j].count
+		}
+		return failures[i].message < failures[j].message
+	})
+	if len(failures) > jobStatusMaxFailureMessages {
+		failures = failures[:jobStatusMaxFailureMessages]
+	}
+	return failures
+}
@@ -0,0 +1,272 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/query"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"go.uber.org/multierr"
+)
+
+const (
+	// DefaultJobStopConcurrency is the default number of jobs stopped in
+	// parallel by a bulk job stop
+	DefaultJobStopConcurrency = 10
+
+	// DefaultJobStopStates are the states of the jobs stopped by default
+	// by a bulk job stop
+	DefaultJobStopStates = "INITIALIZED,PENDING,RUNNING"
+
+	jobStopAllFormatHeader = "ID\tName\tState\tOwner\t\n"
+	jobStopAllFormatBody   = "%s\t%s\t%s\t%s\t\n"
+)
+
+// jobStopResult is the result of stopping a single job of a bulk job stop
+type jobStopResult struct {
+	jobID *peloton.JobID
+	err   error
+}
+
+// JobStopAllAction stops all jobs of a resource pool in one of the given
+// states. The matching jobs are listed and, unless dryRun is set, stopped
+// with at most concurrency stop requests in flight once the user confirmed.
+// It returns an error if any job failed to stop.
+func (c *Client) JobStopAllAction(
+	respoolPath string,
+	states string,
+	concurrency int,
+	dryRun bool,
+	skipConfirmation bool) error {
+	if respoolPath == "" {
+		return errors.New("resource pool is required to stop all jobs")
+	}
+	if concurrency <= 0 {
+		return fmt.Errorf("invalid concurrency %d", concurrency)
+	}
+
+	jobStates, err := parseJobStates(states)
+	if err != nil {
+		return err
+	}
+
+	respoolID, err := c.LookupResourcePoolIDCached(respoolPath)
+	if err != nil {
+		return err
+	}
+	if respoolID == nil {
+		return fmt.Errorf("resource pool %s not found", respoolPath)
+	}
+
+	jobs, err := c.queryAllJobs(&job.QueryRequest{
+		RespoolID: respoolID,
+		Spec: &job.QuerySpec{
+			JobStates: jobStates,
+			Pagination: &query.PaginationSpec{
+				MaxLimit: queryAllMaxResults,
+			},
+		},
+		SummaryOnly: true,
+	})
+	if IsTruncatedResults(err) {
+		return fmt.Errorf("not stopping a partial set of jobs: %v, "+
+			"narrow the job states or the resource pool", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(jobs) == 0 {
+		fmt.Fprintf(tabWriter, "No matching job(s) found\n")
+		tabWriter.Flush()
+		return nil
+	}
+
+	fmt.Fprint(tabWriter, jobStopAllFormatHeader)
+	for _, j := range jobs {
+		fmt.Fprintf(tabWriter, jobStopAllFormatBody,
+			j.GetId().GetValue(),
+			j.GetName(),
+			j.GetRuntime().GetState(),
+			j.GetOwner(),
+		)
+	}
+	tabWriter.Flush()
+
+	if dryRun {
+		fmt.Fprintf(tabWriter, "Dry run, not stopping %d job(s)\n", len(jobs))
+		tabWriter.Flush()
+		return nil
+	}
+
+	if !skipConfirmation {
+		confirmed, err := c.confirm(func() (string, error) {
+			return jobStopSummary(jobs), nil
+		})
+		if err != nil || !confirmed {
+			return err
+		}
+	}
+
+	ctx, release := withInterrupt(c.ctx)
+	defer release()
+
+	var errs error
+	stopped, failed := 0, 0
+	progress := c.startProgress("stopping", "Stopping jobs", len(jobs))
+	if c.limiter != nil {
+		progress.ShowRate(c.limiter.EffectiveRate)
+	}
+	for result := range c.stopJobs(ctx, jobs, concurrency, progress) {
+		if result.err != nil {
+			failed++
+			errs = multierr.Append(errs, fmt.Errorf(
+				"failed to stop job %s: %v", result.jobID.GetValue(), result.err))
+		} else {
+			stopped++
+		}
+		result := result
+		progress.Print(func() {
+			if result.err != nil {
+				fmt.Fprintf(tabWriter, "Failed to stop job %s: %v\n",
+					result.jobID.GetValue(), result.err)
+			} else {
+				fmt.Fprintf(tabWriter, "Stopped job %s\n", result.jobID.GetValue())
+			}
+			tabWriter.Flush()
+		})
+	}
+	progress.Finish()
+	if ctx.Err() != nil {
+		errs = multierr.Append(errs, fmt.Errorf(
+			"interrupted, %d job(s) not processed", len(jobs)-stopped-failed))
+	}
+
+	fmt.Fprintf(tabWriter, "Stopped %d of %d job(s), %d failed\n",
+		stopped, len(jobs), failed)
+	tabWriter.Flush()
+	return errs
+}
+
+// stopJobs stops the jobs with a pool of concurrency workers, and returns
+// a channel of the results which is closed once all jobs are processed.
+// The progress is incremented as soon as a job is processed. No more jobs
+// are processed once ctx is done.
+func (c *Client) stopJobs(
+	ctx context.Context,
+	jobs []*job.JobSummary,
+	concurrency int,
+	progress *progressBar) <-chan jobStopResult {
+	jobIDs := make(chan *peloton.JobID)
+	results := make(chan jobStopResult)
+
+	go func() {
+		defer close(jobIDs)
+		for _, j := range jobs {
+			select {
+			case jobIDs <- j.GetId():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency && i < len(jobs); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for jobID := range jobIDs {
+				err := c.stopJob(ctx, jobID)
+				progress.Increment()
+				results <- jobStopResult{jobID: jobID, err: err}
+			}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+	return results
+}
+
+// stopJob stops all tasks of a job, once the rate limiter of the client
+// allows it
+func (c *Client) stopJob(ctx context.Context, jobID *peloton.JobID) error {
+	if err := c.limiter.Wait(ctx); err != nil {
+		return err
+	}
+	response, err := c.taskClient.Stop(ctx, &task.StopRequest{JobId: jobID})
+	if err != nil {
+		return err
+	}
+	if response.GetError() != nil {
+		return errors.New(response.GetError().String())
+	}
+	if len(response.GetInvalidInstanceIds()) > 0 {
+		return fmt.Errorf("failed to stop instances %v",
+			response.GetInvalidInstanceIds())
+	}
+	return nil
+}
+
+// queryAllJobs fetches all pages of the results of a job query
+func (c *Client) queryAllJobs(request *job.QueryRequest) ([]*job.JobSummary, error) {
+	var results []*job.JobSummary
+	pagination := request.GetSpec().GetPagination()
+	err := fetchAllPages("jobs", queryAllPageSize, func(offset, limit uint32) (int, bool, error) {
+		pagination.Offset = offset
+		pagination.Limit = limit
+		response, err := c.jobClient.Query(c.ctx, request)
+		if err != nil {
+			return 0, false, err
+		}
+		if response.GetError() != nil {
+			return 0, true, errors.New(response.GetError().String())
+		}
+		results = append(results, response.GetResults()...)
+		return len(response.GetResults()), false, nil
+	})
+	return results, err
+}
+
+// parseJobStates parses a comma separated list of job states
+func parseJobStates(states string) ([]job.JobState, error) {
+	var jobStates []job.JobState
+	for _, s := range strings.Split(states, labelSeparator) {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		state, ok := job.JobState_value[strings.ToUpper(s)]
+		if !ok {
+			return nil, fmt.Errorf("invalid job state %s", s)
+		}
+		jobStates = append(jobStates, job.JobState(state))
+	}
+	if len(jobStates) == 0 {
+		return nil, errors.New("no job states given")
+	}
+	return jobStates, nil
+}
@@ -0,0 +1,237 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	jobmocks "github.com/uber/peloton/.gen/peloton/api/v0/job/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	respoolmocks "github.com/uber/peloton/.gen/peloton/api/v0/respool/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	taskmocks "github.com/uber/peloton/.gen/peloton/api/v0/task/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+const testStopRespoolPath = "/a/b"
+
+type jobStopAllTestSuite struct {
+	suite.Suite
+	mockCtrl     *gomock.Controller
+	mockJob      *jobmocks.MockJobManagerYARPCClient
+	mockTask     *taskmocks.MockTaskManagerYARPCClient
+	mockRespool  *respoolmocks.MockResourceManagerYARPCClient
+	output       *bytes.Buffer
+	progress     *bytes.Buffer
+	oldTabWriter *tabwriter.Writer
+	client       Client
+}
+
+func (suite *jobStopAllTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockJob = jobmocks.NewMockJobManagerYARPCClient(suite.mockCtrl)
+	suite.mockTask = taskmocks.NewMockTaskManagerYARPCClient(suite.mockCtrl)
+	suite.mockRespool = respoolmocks.NewMockResourceManagerYARPCClient(
+		suite.mockCtrl)
+	suite.output = &bytes.Buffer{}
+	suite.oldTabWriter = tabWriter
+	tabWriter = tabwriter.NewWriter(suite.output, 0, 0, 1, ' ', 0)
+	suite.progress = &bytes.Buffer{}
+	progressOutput = suite.progress
+	progressIsTerminal = func() bool { return false }
+	suite.client = Client{
+		resClient:  suite.mockRespool,
+		taskClient: suite.mockTask,
+		jobClient:  suite.mockJob,
+		ctx:        context.Background(),
+	}
+}
+
+func (suite *jobStopAllTestSuite) TearDownTest() {
+	tabWriter = suite.oldTabWriter
+	progressOutput = os.Stderr
+	progressIsTerminal = stderrIsTerminal
+	suite.mockCtrl.Finish()
+}
+
+func TestJobStopAll(t *testing.T) {
+	suite.Run(t, new(jobStopAllTestSuite))
+}
+
+// expectQuery sets up the resource pool lookup and a single page of n
+// running jobs, and returns their ids
+func (suite *jobStopAllTestSuite) expectQuery(n int) []*peloton.JobID {
+	respoolID := &peloton.ResourcePoolID{Value: "respool-1"}
+	suite.mockRespool.EXPECT().
+		LookupResourcePoolID(gomock.Any(), &respool.LookupRequest{
+			Path: &respool.ResourcePoolPath{Value: testStopRespoolPath},
+		}).
+		Return(&respool.LookupResponse{Id: respoolID}, nil)
+
+	var ids []*peloton.JobID
+	var results []*job.JobSummary
+	for i := 0; i < n; i++ {
+		id := &peloton.JobID{Value: fmt.Sprintf("job-%d", i)}
+		ids = append(ids, id)
+		results = append(results, &job.JobSummary{
+			Id:      id,
+			Name:    fmt.Sprintf("name-%d", i),
+			Owner:   "team",
+			Runtime: &job.RuntimeInfo{State: job.JobState_RUNNING},
+		})
+	}
+	suite.mockJob.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *job.QueryRequest) {
+			suite.Equal(respoolID, req.GetRespoolID())
+			suite.Equal([]job.JobState{
+				job.JobState_RUNNING,
+				job.JobState_PENDING,
+			}, req.GetSpec().GetJobStates())
+			suite.Equal(uint32(0), req.GetSpec().GetPagination().GetOffset())
+		}).
+		Return(&job.QueryResponse{Results: results}, nil)
+	return ids
+}
+
+// TestJobStopAllPartialFailure tests that failures to stop some jobs are
+// reported without stopping the others
+func (suite *jobStopAllTestSuite) TestJobStopAllPartialFailure() {
+	ids := suite.expectQuery(4)
+	for i, id := range ids {
+		var err error
+		if i%2 == 1 {
+			err = errors.New("stop failed")
+		}
+		suite.mockTask.EXPECT().
+			Stop(gomock.Any(), &task.StopRequest{JobId: id}).
+			Return(&task.StopResponse{}, err)
+	}
+
+	err := suite.client.JobStopAllAction(
+		testStopRespoolPath, "RUNNING,pending", 2, false, true)
+	suite.Error(err)
+	suite.Contains(err.Error(), "failed to stop job job-1: stop failed")
+	suite.Contains(err.Error(), "failed to stop job job-3: stop failed")
+
+	output := suite.output.String()
+	suite.Contains(output, "job-0 name-0 RUNNING team")
+	suite.Contains(output, "Stopped job job-0\n")
+	suite.Contains(output, "Stopped job job-2\n")
+	suite.Contains(output, "Failed to stop job job-1: stop failed\n")
+	suite.Contains(output, "Failed to stop job job-3: stop failed\n")
+	suite.Contains(output, "Stopped 2 of 4 job(s), 2 failed\n")
+}
+
+// TestJobStopAllConcurrency tests that no more than concurrency stop
+// requests are in flight
+func (suite *jobStopAllTestSuite) TestJobStopAllConcurrency() {
+	concurrency := 3
+	ids := suite.expectQuery(12)
+
+	var lock sync.Mutex
+	inFlight, maxInFlight := 0, 0
+	suite.mockTask.EXPECT().
+		Stop(gomock.Any(), gomock.Any()).
+		Do(func(context.Context, *task.StopRequest) {
+			lock.Lock()
+			inFlight++
+			if inFlight > maxInFlight {
+				maxInFlight = inFlight
+			}
+			lock.Unlock()
+
+			time.Sleep(5 * time.Millisecond)
+
+			lock.Lock()
+			inFlight--
+			lock.Unlock()
+		}).
+		Return(&task.StopResponse{}, nil).
+		Times(len(ids))
+
+	suite.NoError(suite.client.JobStopAllAction(
+		testStopRespoolPath, "RUNNING,PENDING", concurrency, false, true))
+	suite.True(maxInFlight <= concurrency)
+	suite.Contains(suite.output.String(), "Stopped 12 of 12 job(s), 0 failed\n")
+	// the progress is reported on stderr only
+	suite.True(strings.HasSuffix(suite.progress.String(), "Stopping jobs 12/12\n"))
+	suite.NotContains(suite.output.String(), "Stopping jobs")
+}
+
+// TestJobStopAllDryRun tests that a dry run does not stop any job
+func (suite *jobStopAllTestSuite) TestJobStopAllDryRun() {
+	suite.expectQuery(2)
+
+	suite.NoError(suite.client.JobStopAllAction(
+		testStopRespoolPath, "RUNNING,PENDING", 2, true, false))
+	suite.Contains(suite.output.String(), "job-1 name-1 RUNNING team")
+	suite.Contains(suite.output.String(), "Dry run, not stopping 2 job(s)\n")
+}
+
+// TestJobStopAllNoJobs tests stopping a resource pool without matching jobs
+func (suite *jobStopAllTestSuite) TestJobStopAllNoJobs() {
+	suite.expectQuery(0)
+
+	suite.NoError(suite.client.JobStopAllAction(
+		testStopRespoolPath, "RUNNING,PENDING", 2, false, true))
+	suite.Equal("No matching job(s) found\n", suite.output.String())
+}
+
+// TestJobStopAllTruncated tests that no job is stopped if the query of the
+// jobs to stop is truncated
+func (suite *jobStopAllTestSuite) TestJobStopAllTruncated() {
+	suite.mockRespool.EXPECT().
+		LookupResourcePoolID(gomock.Any(), gomock.Any()).
+		Return(&respool.LookupResponse{
+			Id: &peloton.ResourcePoolID{Value: "respool-1"},
+		}, nil)
+	page := make([]*job.JobSummary, queryAllPageSize)
+	suite.mockJob.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		Return(&job.QueryResponse{Results: page}, nil).
+		Times(queryAllMaxResults / queryAllPageSize)
+
+	err := suite.client.JobStopAllAction(
+		testStopRespoolPath, "RUNNING", 2, false, true)
+	suite.Error(err)
+	suite.Contains(err.Error(), "not stopping a partial set of jobs")
+	suite.Empty(suite.output.String())
+}
+
+// TestJobStopAllInvalidInput tests input rejected before any RPC is made
+func (suite *jobStopAllTestSuite) TestJobStopAllInvalidInput() {
+	suite.Error(suite.client.JobStopAllAction(
+		"", "RUNNING", 2, false, true))
+	suite.Error(suite.client.JobStopAllAction(
+		testStopRespoolPath, "RUNNING", 0, false, true))
+	suite.Error(suite.client.JobStopAllAction(
+		testStopRespoolPath, "RUNNING,BOGUS", 2, false, true))
+	suite.Error(suite.client.JobStopAllAction(
+		testStopRespoolPath, "", 2, false, true))
+}
@@ -0,0 +1,529 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/uber/peloton/pkg/common/taskconfig"
+	jobconfig "github.com/uber/peloton/pkg/jobmgr/job/config"
+
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/yarpc/yarpcerrors"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	jobConfigError   = "error"
+	jobConfigWarning = "warning"
+
+	// jobValidateMaxTasksPerJob is the default maximum number of tasks of a
+	// job accepted by the job manager
+	jobValidateMaxTasksPerJob = 100000
+	// jobValidateMaxTaskRetries is the maximum number of failures of a task
+	// the job manager retries
+	jobValidateMaxTaskRetries = 100
+
+	jobValidateFormatHeader = "Severity\tLine\tMessage\t\n"
+	jobValidateFormatBody   = "%s\t%s\t%s\t\n"
+)
+
+// yamlErrorLine matches the line reference of YAML parse errors
+var yamlErrorLine = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+
+// jobConfigProblem is an error or warning found in a job configuration
+type jobConfigProblem struct {
+	Severity string
+	// Line is the line of the YAML file, 0 if unknown
+	Line    int
+	Message string
+}
+
+// JobValidateAction is the action for validating a job configuration
+// without creating the job. The configuration is checked locally as written,
+// as the v0 job API has no validate only request and job create does no
+// client-side defaulting besides setting the resource pool, which is only
+// looked up here if given.
+func (c *Client) JobValidateAction(cfg string, respoolPath string) error {
+	buffer, err := ioutil.ReadFile(cfg)
+	if err != nil {
+		return fmt.Errorf("unable to open file %s: %v", cfg, err)
+	}
+
+	var problems []jobConfigProblem
+	if respoolPath != "" {
+		respoolID, err := c.LookupResourcePoolID(respoolPath)
+		if err != nil || respoolID == nil {
+			problems = append(problems, jobConfigProblem{
+				Severity: jobConfigError,
+				Message: fmt.Sprintf(
+					"unable to find resource pool %s: %v", respoolPath, err),
+			})
+		}
+	}
+	_, configProblems := validateJobConfig(buffer)
+	problems = append(problems, configProblems...)
+
+	errorCount := printJobValidateResponse(cfg, problems)
+	if errorCount > 0 {
+		return fmt.Errorf("job config %s has %d error(s)", cfg, errorCount)
+	}
+	return nil
+}
+
+// printJobValidateResponse prints the problems found in the job
+// configuration and returns the number of errors
+func printJobValidateResponse(cfg string, problems []jobConfigProblem) int {
+	if len(problems) == 0 {
+		fmt.Fprintf(tabWriter, "Job config %s is valid\n", cfg)
+		tabWriter.Flush()
+		return 0
+	}
+
+	errorCount := 0
+	fmt.Fprint(tabWriter, jobValidateFormatHeader)
+	for _, p := range problems {
+		if p.Severity == jobConfigError {
+			errorCount++
+		}
+		line := "-"
+		if p.Line > 0 {
+			line = strconv.Itoa(p.Line)
+		}
+		fmt.Fprintf(tabWriter, jobValidateFormatBody, p.Severity, line, p.Message)
+	}
+	tabWriter.Flush()
+	return errorCount
+}
+
+// jobConfigValidator collects the problems of a job configuration
+type jobConfigValidator struct {
+	buffer   []byte
+	config   *job.JobConfig
+	problems []jobConfigProblem
+	// seen dedups the problems of the default config shared by instances
+	seen map[jobConfigProblem]bool
+}
+
+// validateJobConfig parses and validates a YAML job configuration. It
+// returns the parsed configuration, nil if it cannot be parsed, and the
+// problems found.
+func validateJobConfig(buffer []byte) (*job.JobConfig, []jobConfigProblem) {
+	v := &jobConfigValidator{
+		buffer: buffer,
+		seen:   make(map[jobConfigProblem]bool),
+	}
+
+	var jobConfig job.JobConfig
+	if err := yaml.Unmarshal(buffer, &jobConfig); err != nil {
+		v.addParseError(err)
+		return nil, v.problems
+	}
+	v.config = &jobConfig
+
+	var raw interface{}
+	if err := yaml.Unmarshal(buffer, &raw); err == nil {
+		unknown := unknownYAMLFields(raw, reflect.TypeOf(jobConfig), nil)
+		for _, path := range stripJobExportMetadata(unknown) {
+			v.add(jobConfigWarning, path,
+				"unknown field %s is ignored", strings.Join(path, "."))
+		}
+	}
+
+	v.validateJob()
+	if len(v.errors()) > 0 {
+		return v.config, v.problems
+	}
+
+	// apply the remaining checks of the job manager
+	if err := jobconfig.ValidateConfig(
+		proto.Clone(v.config).(*job.JobConfig),
+		jobValidateMaxTasksPerJob,
+	); err != nil {
+		message := err.Error()
+		if yarpcerrors.IsStatus(err) {
+			message = yarpcerrors.FromError(err).Message()
+		}
+		v.add(jobConfigError, nil, "%s", message)
+	}
+	return v.config, v.problems
+}
+
+// add adds a problem at the line of the YAML path
+func (v *jobConfigValidator) add(
+	severity string, path []string, format string, args ...interface{}) {
+	problem := jobConfigProblem{
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	}
+	if len(path) > 0 {
+		problem.Line = yamlLine(v.buffer, path...)
+	}
+	if v.seen[problem] {
+		return
+	}
+	v.seen[problem] = true
+	v.problems = append(v.problems, problem)
+}
+
+// addParseError adds the errors of parsing the YAML job configuration
+func (v *jobConfigValidator) addParseError(err error) {
+	messages := []string{err.Error()}
+	if typeErr, ok := err.(*yaml.TypeError); ok {
+		messages = typeErr.Errors
+	}
+	for _, message := range messages {
+		problem := jobConfigProblem{
+			Severity: jobConfigError,
+			Message:  message,
+		}
+		if match := yamlErrorLine.FindStringSubmatch(message); match != nil {
+			problem.Line, _ = strconv.Atoi(match[1])
+			problem.Message = match[2]
+		}
+		v.problems = append(v.problems, problem)
+	}
+}
+
+// errors returns the problems which are errors
+func (v *jobConfigValidator) errors() []jobConfigProblem {
+	var errs []jobConfigProblem
+	for _, p := range v.problems {
+		if p.Severity == jobConfigError {
+			errs = append(errs, p)
+		}
+	}
+	return errs
+}
+
+// validateJob checks the job and the task configs of all its instances
+func (v *jobConfigValidator) validateJob() {
+	cfg := v.config
+	if cfg.GetInstanceCount() == 0 {
+		v.add(jobConfigError, []string{"instancecount"},
+			"instance count must be greater than 0")
+	}
+	if cfg.GetType() != job.JobType_BATCH && cfg.GetType() != job.JobType_SERVICE {
+		v.add(jobConfigError, []string{"type"},
+			"unsupported job type %v", cfg.GetType())
+	}
+
+	var instances []int
+	for i := range cfg.GetInstanceConfig() {
+		instances = append(instances, int(i))
+	}
+	sort.Ints(instances)
+	for _, i := range instances {
+		if uint32(i) >= cfg.GetInstanceCount() {
+			v.add(jobConfigWarning, []string{"instanceconfig", strconv.Itoa(i)},
+				"instance config %d is ignored, the job has %d instances",
+				i, cfg.GetInstanceCount())
+		}
+	}
+
+	for i := uint32(0); i < cfg.GetInstanceCount(); i++ {
+		taskConfig := taskconfig.Merge(
+			cfg.GetDefaultConfig(), cfg.GetInstanceConfig()[i])
+		if taskConfig == nil {
+			v.add(jobConfigError, []string{"defaultconfig"},
+				"instance %d has no task config", i)
+			continue
+		}
+		v.validateTask(i, taskConfig)
+	}
+}
+
+// source returns the description and YAML path of a field of the task
+// config of an instance, which is either set in the instance config or
+// inherited from the default config
+func (v *jobConfigValidator) source(
+	instance uint32, field string, set func(*task.TaskConfig) bool) (string, []string) {
+	if instanceConfig, ok := v.config.GetInstanceConfig()[instance]; ok &&
+		set(instanceConfig) {
+		return fmt.Sprintf("instance %d", instance),
+			[]string{"instanceconfig", strconv.Itoa(int(instance)), field}
+	}
+	return "default config", []string{"defaultconfig", field}
+}
+
+// validateTask checks the task config of an instance
+func (v *jobConfigValidator) validateTask(instance uint32, taskConfig *task.TaskConfig) {
+	if taskConfig.GetCommand() == nil {
+		where, path := v.source(instance, "command",
+			func(t *task.TaskConfig) bool { return t.GetCommand() != nil })
+		v.add(jobConfigError, path, "%s: missing command", where)
+	}
+
+	where, path := v.source(instance, "resource",
+		func(t *task.TaskConfig) bool { return t.GetResource() != nil })
+	v.validateResources(where, path, taskConfig.GetResource())
+
+	where, path = v.source(instance, "ports",
+		func(t *task.TaskConfig) bool { return t.GetPorts() != nil })
+	v.validatePorts(where, path, taskConfig)
+
+	if taskConfig.GetConstraint() != nil {
+		where, path = v.source(instance, "constraint",
+			func(t *task.TaskConfig) bool { return t.GetConstraint() != nil })
+		v.validateConstraint(where, path, taskConfig.GetConstraint())
+	}
+
+	if maxFailures := taskConfig.GetRestartPolicy().GetMaxFailures(); maxFailures > jobValidateMaxTaskRetries {
+		where, path = v.source(instance, "restartpolicy",
+			func(t *task.TaskConfig) bool { return t.GetRestartPolicy() != nil })
+		v.add(jobConfigWarning, append(path, "maxfailures"),
+			"%s: max failures %d is reduced to %d",
+			where, maxFailures, jobValidateMaxTaskRetries)
+	}
+}
+
+// validateResources checks that the resources of a task are positive
+func (v *jobConfigValidator) validateResources(
+	where string, path []string, resource *task.ResourceConfig) {
+	if resource.GetCpuLimit() <= 0 {
+		v.add(jobConfigError, append(path, "cpulimit"),
+			"%s: cpu limit must be positive", where)
+	}
+	if resource.GetMemLimitMb() <= 0 {
+		v.add(jobConfigError, append(path, "memlimitmb"),
+			"%s: memory limit must be positive", where)
+	}
+	if resource.GetDiskLimitMb() < 0 {
+		v.add(jobConfigError, append(path, "disklimitmb"),
+			"%s: disk limit must not be negative", where)
+	}
+	if resource.GetGpuLimit() < 0 {
+		v.add(jobConfigError, append(path, "gpulimit"),
+			"%s: gpu limit must not be negative", where)
+	}
+}
+
+// validatePorts checks that the ports of a task are complete and do not
+// conflict with each other
+func (v *jobConfigValidator) validatePorts(
+	where string, path []string, taskConfig *task.TaskConfig) {
+	customExecutor := taskConfig.GetExecutor().GetType() == mesos.ExecutorInfo_CUSTOM
+	names := make(map[string]bool)
+	values := make(map[uint32]bool)
+	envNames := make(map[string]bool)
+	for _, port := range taskConfig.GetPorts() {
+		switch {
+		case port.GetName() == "":
+			v.add(jobConfigError, path, "%s: port name is missing", where)
+		case names[port.GetName()]:
+			v.add(jobConfigError, path,
+				"%s: port name %s is used more than once", where, port.GetName())
+		}
+		names[port.GetName()] = true
+
+		if port.GetValue() != 0 {
+			if values[port.GetValue()] {
+				v.add(jobConfigError, path,
+					"%s: port %d is used more than once", where, port.GetValue())
+			}
+			values[port.GetValue()] = true
+		} else if port.GetEnvName() == "" && !customExecutor {
+			v.add(jobConfigError, path,
+				"%s: dynamic port %s has no env name", where, port.GetName())
+		}
+
+		if port.GetEnvName() != "" {
+			if envNames[port.GetEnvName()] {
+				v.add(jobConfigError, path,
+					"%s: port env name %s is used more than once",
+					where, port.GetEnvName())
+			}
+			envNames[port.GetEnvName()] = true
+		}
+	}
+}
+
+// validateConstraint checks the syntax of a placement constraint and of its
+// nested constraints
+func (v *jobConfigValidator) validateConstraint(
+	where string, path []string, constraint *task.Constraint) {
+	switch constraint.GetType() {
+	case task.Constraint_LABEL_CONSTRAINT:
+		labelConstraint := constraint.GetLabelConstraint()
+		labelPath := append(path, "labelconstraint")
+		if labelConstraint == nil {
+			v.add(jobConfigError, path,
+				"%s: label constraint is missing", where)
+			return
+		}
+		if labelConstraint.GetLabel().GetKey() == "" {
+			v.add(jobConfigError, labelPath,
+				"%s: label constraint has no label key", where)
+		}
+		if labelConstraint.GetKind() != task.LabelConstraint_TASK &&
+			labelConstraint.GetKind() != task.LabelConstraint_HOST {
+			v.add(jobConfigError, append(labelPath, "kind"),
+				"%s: label constraint kind must be TASK (1) or HOST (2)", where)
+		}
+		switch labelConstraint.GetCondition() {
+		case task.LabelConstraint_CONDITION_LESS_THAN,
+			task.LabelConstraint_CONDITION_EQUAL,
+			task.LabelConstraint_CONDITION_GREATER_THAN:
+		default:
+			v.add(jobConfigError, append(labelPath, "condition"),
+				"%s: label constraint condition must be LESS_THAN (1), "+
+					"EQUAL (2) or GREATER_THAN (3)", where)
+		}
+	case task.Constraint_AND_CONSTRAINT:
+		constraints := constraint.GetAndConstraint().GetConstraints()
+		if len(constraints) == 0 {
+			v.add(jobConfigError, path,
+				"%s: and constraint has no constraints", where)
+		}
+		for _, c := range constraints {
+			v.validateConstraint(where,
+				append(path, "andconstraint", "constraints"), c)
+		}
+	case task.Constraint_OR_CONSTRAINT:
+		constraints := constraint.GetOrConstraint().GetConstraints()
+		if len(constraints) == 0 {
+			v.add(jobConfigError, path,
+				"%s: or constraint has no constraints", where)
+		}
+		for _, c := range constraints {
+			v.validateConstraint(where,
+				append(path, "orconstraint", "constraints"), c)
+		}
+	default:
+		v.add(jobConfigError, append(path, "type"),
+			"%s: constraint type must be LABEL_CONSTRAINT (1), "+
+				"AND_CONSTRAINT (2) or OR_CONSTRAINT (3)", where)
+	}
+}
+
+// unknownYAMLFields returns the paths of the keys of a parsed YAML document
+// which are not fields of the type it is decoded into
+func unknownYAMLFields(value interface{}, t reflect.Type, path []string) [][]string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	var unknown [][]string
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := value.(map[interface{}]interface{})
+		if !ok {
+			return nil
+		}
+		fields := make(map[string]reflect.Type)
+		for i := 0; i < t.NumField(); i++ {
+			if f := t.Field(i); f.PkgPath == "" {
+				fields[strings.ToLower(f.Name)] = f.Type
+			}
+		}
+		values, keys := stringYAMLKeys(m)
+		for _, key := range keys {
+			keyPath := append(append([]string{}, path...), key)
+			fieldType, ok := fields[key]
+			if !ok {
+				unknown = append(unknown, keyPath)
+				continue
+			}
+			unknown = append(unknown,
+				unknownYAMLFields(values[key], fieldType, keyPath)...)
+		}
+	case reflect.Map:
+		m, ok := value.(map[interface{}]interface{})
+		if !ok {
+			return nil
+		}
+		values, keys := stringYAMLKeys(m)
+		for _, key := range keys {
+			keyPath := append(append([]string{}, path...), key)
+			unknown = append(unknown,
+				unknownYAMLFields(values[key], t.Elem(), keyPath)...)
+		}
+	case reflect.Slice:
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil
+		}
+		for i, item := range list {
+			itemPath := append(append([]string{}, path...), strconv.Itoa(i))
+			unknown = append(unknown, unknownYAMLFields(item, t.Elem(), itemPath)...)
+		}
+	}
+	return unknown
+}
+
+// stringYAMLKeys returns a YAML mapping keyed by its keys as strings, and
+// the sorted keys
+func stringYAMLKeys(m map[interface{}]interface{}) (map[string]interface{}, []string) {
+	values := make(map[string]interface{}, len(m))
+	var keys []string
+	for key, value := range m {
+		name := fmt.Sprint(key)
+		values[name] = value
+		keys = append(keys, name)
+	}
+	sort.Strings(keys)
+	return values, keys
+}
+
+// yamlLine returns the line of a YAML document where the key at the path is
+// set. If the key is not found, it returns the line of its deepest ancestor
+// found, or 0. Keys of list items are not searched.
+func yamlLine(buffer []byte, path ...string) int {
+	line := 0
+	depth := 0
+	parentIndent := -1
+	childIndent := -1
+	for i, text := range strings.Split(string(buffer), "\n") {
+		if depth == len(path) {
+			break
+		}
+		content := strings.TrimLeft(text, " ")
+		if content == "" || strings.HasPrefix(content, "#") ||
+			strings.HasPrefix(content, "---") {
+			continue
+		}
+		indent := len(text) - len(content)
+		if indent <= parentIndent {
+			// left the block of the parent key
+			break
+		}
+		if childIndent < 0 {
+			childIndent = indent
+		}
+		colon := strings.Index(content, ":")
+		if indent != childIndent || colon < 0 {
+			continue
+		}
+		key := strings.Trim(strings.TrimSpace(content[:colon]), `"'`)
+		if key != path[depth] {
+			continue
+		}
+		line = i + 1
+		depth++
+		parentIndent = indent
+		childIndent = -1
+	}
+	return line
+}
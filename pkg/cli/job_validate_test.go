@@ -0,0 +1,210 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	respoolmocks "github.com/uber/peloton/.gen/peloton/api/v0/respool/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+const jobValidateTestdata = "testdata/job_validate"
+
+type jobValidateTestSuite struct {
+	suite.Suite
+	mockCtrl     *gomock.Controller
+	mockRespool  *respoolmocks.MockResourceManagerYARPCClient
+	output       *bytes.Buffer
+	oldTabWriter *tabwriter.Writer
+	client       Client
+}
+
+func (suite *jobValidateTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockRespool = respoolmocks.NewMockResourceManagerYARPCClient(
+		suite.mockCtrl)
+	suite.output = &bytes.Buffer{}
+	suite.oldTabWriter = tabWriter
+	tabWriter = tabwriter.NewWriter(suite.output, 0, 0, 1, ' ', 0)
+	suite.client = Client{
+		resClient: suite.mockRespool,
+		ctx:       context.Background(),
+	}
+}
+
+func (suite *jobValidateTestSuite) TearDownTest() {
+	tabWriter = suite.oldTabWriter
+	suite.mockCtrl.Finish()
+}
+
+func TestJobValidate(t *testing.T) {
+	suite.Run(t, new(jobValidateTestSuite))
+}
+
+// validateFixture validates a job config of the test data
+func (suite *jobValidateTestSuite) validateFixture(name string) []jobConfigProblem {
+	buffer, err := ioutil.ReadFile(filepath.Join(jobValidateTestdata, name))
+	suite.Require().NoError(err)
+	_, problems := validateJobConfig(buffer)
+	return problems
+}
+
+// TestValidateJobConfig tests the problems found in the job configs of the
+// test data
+func (suite *jobValidateTestSuite) TestValidateJobConfig() {
+	tt := []struct {
+		fixture  string
+		problems []jobConfigProblem
+	}{
+		{
+			fixture: "valid.yaml",
+		},
+		{
+			fixture: "bad_type.yaml",
+			problems: []jobConfigProblem{
+				{jobConfigError, 2, "cannot unmarshal !!str `many` into uint32"},
+			},
+		},
+		{
+			fixture: "no_instances.yaml",
+			problems: []jobConfigProblem{
+				{jobConfigError, 2, "instance count must be greater than 0"},
+			},
+		},
+		{
+			fixture: "missing_command.yaml",
+			problems: []jobConfigProblem{
+				{jobConfigError, 3, "default config: missing command"},
+			},
+		},
+		{
+			fixture: "bad_resources.yaml",
+			problems: []jobConfigProblem{
+				{jobConfigError, 5, "default config: cpu limit must be positive"},
+				{jobConfigError, 13, "instance 1: memory limit must be positive"},
+			},
+		},
+		{
+			fixture: "port_conflict.yaml",
+			problems: []jobConfigProblem{
+				{jobConfigError, 7, "default config: port 8080 is used more than once"},
+				{jobConfigError, 7, "default config: port name http is used more than once"},
+				{jobConfigError, 7, "default config: dynamic port debug has no env name"},
+			},
+		},
+		{
+			fixture: "bad_constraint.yaml",
+			problems: []jobConfigProblem{
+				{jobConfigError, 12, "default config: label constraint has no label key"},
+				{jobConfigError, 12, "default config: label constraint kind must be TASK (1) or HOST (2)"},
+				{jobConfigError, 12, "default config: or constraint has no constraints"},
+			},
+		},
+		{
+			fixture: "unknown_field.yaml",
+			problems: []jobConfigProblem{
+				{jobConfigWarning, 7, "unknown field defaultconfig.resource.cpus is ignored"},
+				{jobConfigWarning, 11, "instance config 5 is ignored, the job has 2 instances"},
+			},
+		},
+		{
+			fixture: "server_rule.yaml",
+			problems: []jobConfigProblem{
+				{jobConfigError, 0, "Job specified MaximumRunningInstances > InstanceCount"},
+			},
+		},
+	}
+
+	for _, test := range tt {
+		suite.Equal(test.problems, suite.validateFixture(test.fixture), test.fixture)
+	}
+}
+
+// TestValidateJobConfigSyntaxError tests that YAML syntax errors are
+// reported with their line
+func (suite *jobValidateTestSuite) TestValidateJobConfigSyntaxError() {
+	problems := suite.validateFixture("syntax_error.yaml")
+	suite.Len(problems, 1)
+	suite.Equal(jobConfigError, problems[0].Severity)
+	suite.True(problems[0].Line > 0)
+}
+
+// TestJobValidateAction tests validating a valid job config in a resource pool
+func (suite *jobValidateTestSuite) TestJobValidateAction() {
+	suite.mockRespool.EXPECT().
+		LookupResourcePoolID(gomock.Any(), &respool.LookupRequest{
+			Path: &respool.ResourcePoolPath{Value: "/a/b"},
+		}).
+		Return(&respool.LookupResponse{
+			Id: &peloton.ResourcePoolID{Value: "respool-1"},
+		}, nil)
+
+	path := filepath.Join(jobValidateTestdata, "valid.yaml")
+	suite.NoError(suite.client.JobValidateAction(path, "/a/b"))
+	suite.Contains(suite.output.String(), "is valid")
+}
+
+// TestJobValidateActionErrors tests that an invalid job config or resource
+// pool fails the validation
+func (suite *jobValidateTestSuite) TestJobValidateActionErrors() {
+	suite.mockRespool.EXPECT().
+		LookupResourcePoolID(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("respool not found"))
+
+	path := filepath.Join(jobValidateTestdata, "missing_command.yaml")
+	err := suite.client.JobValidateAction(path, "/a/b")
+	suite.EqualError(err, "job config "+path+" has 2 error(s)")
+	suite.Contains(suite.output.String(), "unable to find resource pool /a/b")
+	suite.Contains(suite.output.String(), "default config: missing command")
+
+	// warnings do not fail the validation
+	suite.output.Reset()
+	path = filepath.Join(jobValidateTestdata, "unknown_field.yaml")
+	suite.NoError(suite.client.JobValidateAction(path, ""))
+	suite.Contains(suite.output.String(), "warning")
+}
+
+func (suite *jobValidateTestSuite) TestYAMLLine() {
+	buffer := []byte(`name: test
+# comment
+defaultconfig:
+  resource:
+    cpulimit: 1
+  ports:
+  - name: http
+instanceconfig:
+  "1":
+    resource:
+      cpulimit: 2
+`)
+	suite.Equal(1, yamlLine(buffer, "name"))
+	suite.Equal(5, yamlLine(buffer, "defaultconfig", "resource", "cpulimit"))
+	suite.Equal(11, yamlLine(buffer, "instanceconfig", "1", "resource", "cpulimit"))
+	// the deepest key found
+	suite.Equal(6, yamlLine(buffer, "defaultconfig", "ports", "0", "name"))
+	suite.Equal(3, yamlLine(buffer, "defaultconfig", "command"))
+	suite.Equal(0, yamlLine(buffer, "sla"))
+}
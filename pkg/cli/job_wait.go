@@ -0,0 +1,184 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/uber/peloton/pkg/common/util"
+
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+// Exit codes of the job wait command
+const (
+	JobWaitExitSucceeded = 0
+	JobWaitExitError     = 1
+	JobWaitExitFailed    = 2
+	JobWaitExitKilled    = 3
+	JobWaitExitTimeout   = 4
+)
+
+const (
+	// jobWaitRPCTimeout is the timeout of a single job status poll
+	jobWaitRPCTimeout = 5 * time.Second
+
+	// jobWaitMaxRetries is the number of consecutive failed polls tolerated
+	// before giving up
+	jobWaitMaxRetries = 5
+)
+
+// ErrJobWaitTimeout is returned when a job does not reach a terminal state
+// before the wait timeout expires
+var ErrJobWaitTimeout = errors.New("timed out waiting for job to terminate")
+
+// JobWaitAction blocks until the job reaches a terminal state, polling its
+// status every pollInterval, and prints a summary of its task states.
+// The number of terminated tasks is reported on stderr while waiting.
+// It returns the terminal state of the job, or ErrJobWaitTimeout if the job
+// is still active after timeout.
+func (c *Client) JobWaitAction(
+	jobID string,
+	timeout time.Duration,
+	pollInterval time.Duration) (job.JobState, error) {
+	expired := time.After(timeout)
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var progress *progressBar
+	finishProgress := func() {
+		if progress != nil {
+			progress.Finish()
+		}
+	}
+	defer finishProgress()
+
+	var last *job.RuntimeInfo
+	failures := 0
+	for {
+		runtime, err := c.pollJobRuntime(jobID)
+		switch {
+		case err == nil:
+			failures = 0
+			last = runtime
+			if progress == nil {
+				progress = c.startProgress("waiting",
+					fmt.Sprintf("Terminated tasks of job %s", jobID), 0)
+			}
+			terminated, total := jobTaskProgress(runtime)
+			progress.SetCounts(terminated, total)
+			if util.IsPelotonJobStateTerminal(runtime.GetState()) {
+				finishProgress()
+				printJobWaitSummary(jobID, runtime)
+				return runtime.GetState(), nil
+			}
+		case yarpcerrors.IsNotFound(err) || yarpcerrors.IsInvalidArgument(err):
+			return job.JobState_UNKNOWN, err
+		default:
+			failures++
+			if failures > jobWaitMaxRetries {
+				return job.JobState_UNKNOWN, err
+			}
+		}
+
+		select {
+		case <-expired:
+			finishProgress()
+			if last != nil {
+				printJobWaitSummary(jobID, last)
+			}
+			return last.GetState(), ErrJobWaitTimeout
+		case <-ticker.C:
+		}
+	}
+}
+
+func (c *Client) pollJobRuntime(jobID string) (*job.RuntimeInfo, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), jobWaitRPCTimeout)
+	defer cancel()
+
+	response, err := c.jobStatus(ctx, jobID)
+	if err != nil {
+		return nil, err
+	}
+	runtime := response.GetJobInfo().GetRuntime()
+	if runtime == nil {
+		return nil, fmt.Errorf("unable to get status of job %s", jobID)
+	}
+	return runtime, nil
+}
+
+// JobWaitExitCode maps the result of JobWaitAction to the exit code of
+// the job wait command.
+func JobWaitExitCode(state job.JobState, err error) int {
+	if err == ErrJobWaitTimeout {
+		return JobWaitExitTimeout
+	}
+	if err != nil {
+		return JobWaitExitError
+	}
+
+	switch state {
+	case job.JobState_SUCCEEDED:
+		return JobWaitExitSucceeded
+	case job.JobState_FAILED:
+		return JobWaitExitFailed
+	case job.JobState_KILLED:
+		return JobWaitExitKilled
+	default:
+		return JobWaitExitError
+	}
+}
+
+// jobTaskProgress returns the number of tasks of a job in a terminal state
+// and the total number of tasks
+func jobTaskProgress(runtime *job.RuntimeInfo) (int, int) {
+	terminated, total := 0, 0
+	for state, count := range runtime.GetTaskStats() {
+		total += int(count)
+		if util.IsPelotonStateTerminal(task.TaskState(task.TaskState_value[state])) {
+			terminated += int(count)
+		}
+	}
+	return terminated, total
+}
+
+// printJobWaitSummary prints a one line summary of the job state and the
+// number of tasks in each state
+func printJobWaitSummary(jobID string, runtime *job.RuntimeInfo) {
+	var states []string
+	for state := range runtime.GetTaskStats() {
+		states = append(states, state)
+	}
+	sort.Strings(states)
+
+	summary := fmt.Sprintf("Job %s %s", jobID, runtime.GetState())
+	for i, state := range states {
+		separator := " "
+		if i == 0 {
+			separator = ": "
+		}
+		summary += fmt.Sprintf("%s%s=%d",
+			separator, state, runtime.GetTaskStats()[state])
+	}
+	cliOutPutter.output(summary + "\n")
+}
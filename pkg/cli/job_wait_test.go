@@ -0,0 +1,174 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	jobmocks "github.com/uber/peloton/.gen/peloton/api/v0/job/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+type jobWaitTestSuite struct {
+	suite.Suite
+	mockCtrl  *gomock.Controller
+	mockJob   *jobmocks.MockJobManagerYARPCClient
+	outputter *fakeOutputter
+	progress  *bytes.Buffer
+	client    Client
+}
+
+func (suite *jobWaitTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockJob = jobmocks.NewMockJobManagerYARPCClient(suite.mockCtrl)
+	suite.outputter = &fakeOutputter{}
+	cliOutPutter = suite.outputter
+	suite.progress = &bytes.Buffer{}
+	progressOutput = suite.progress
+	progressIsTerminal = func() bool { return false }
+	suite.client = Client{
+		jobClient: suite.mockJob,
+		ctx:       context.Background(),
+	}
+}
+
+func (suite *jobWaitTestSuite) TearDownTest() {
+	cliOutPutter = newStdOutOutputter()
+	progressOutput = os.Stderr
+	progressIsTerminal = stderrIsTerminal
+	suite.mockCtrl.Finish()
+}
+
+func TestJobWait(t *testing.T) {
+	suite.Run(t, new(jobWaitTestSuite))
+}
+
+func (suite *jobWaitTestSuite) expectGet(
+	state job.JobState,
+	err error) *gomock.Call {
+	var resp *job.GetResponse
+	if err == nil {
+		resp = &job.GetResponse{
+			JobInfo: &job.JobInfo{
+				Runtime: &job.RuntimeInfo{
+					State: state,
+					TaskStats: map[string]uint32{
+						"SUCCEEDED": 8,
+						"FAILED":    2,
+					},
+				},
+			},
+		}
+	}
+	return suite.mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{
+			Id: &peloton.JobID{Value: testJobID},
+		}).
+		Return(resp, err)
+}
+
+// TestJobWaitTerminalStates tests waiting for each terminal job state
+func (suite *jobWaitTestSuite) TestJobWaitTerminalStates() {
+	tt := []struct {
+		state    job.JobState
+		exitCode int
+	}{
+		{state: job.JobState_SUCCEEDED, exitCode: JobWaitExitSucceeded},
+		{state: job.JobState_FAILED, exitCode: JobWaitExitFailed},
+		{state: job.JobState_KILLED, exitCode: JobWaitExitKilled},
+	}
+
+	for _, t := range tt {
+		gomock.InOrder(
+			suite.expectGet(job.JobState_RUNNING, nil),
+			suite.expectGet(t.state, nil),
+		)
+		state, err := suite.client.JobWaitAction(
+			testJobID, time.Minute, time.Millisecond)
+		suite.NoError(err)
+		suite.Equal(t.state, state)
+		suite.Equal(t.exitCode, JobWaitExitCode(state, err))
+		suite.Equal(
+			"Job "+testJobID+" "+t.state.String()+": FAILED=2 SUCCEEDED=8\n",
+			suite.outputter.Out)
+		suite.Equal("Terminated tasks of job "+testJobID+" 10/10\n",
+			suite.progress.String())
+		suite.progress.Reset()
+	}
+}
+
+// TestJobTaskProgress tests counting the terminated tasks of a job
+func (suite *jobWaitTestSuite) TestJobTaskProgress() {
+	terminated, total := jobTaskProgress(&job.RuntimeInfo{
+		TaskStats: map[string]uint32{
+			"RUNNING":   3,
+			"PENDING":   1,
+			"SUCCEEDED": 4,
+			"KILLED":    2,
+			"LOST":      1,
+		},
+	})
+	suite.Equal(7, terminated)
+	suite.Equal(11, total)
+}
+
+// TestJobWaitTimeout tests a job which does not terminate in time
+func (suite *jobWaitTestSuite) TestJobWaitTimeout() {
+	suite.expectGet(job.JobState_RUNNING, nil).AnyTimes()
+
+	state, err := suite.client.JobWaitAction(
+		testJobID, 10*time.Millisecond, time.Millisecond)
+	suite.Equal(ErrJobWaitTimeout, err)
+	suite.Equal(job.JobState_RUNNING, state)
+	suite.Equal(JobWaitExitTimeout, JobWaitExitCode(state, err))
+}
+
+// TestJobWaitRetries tests that transient errors are retried
+func (suite *jobWaitTestSuite) TestJobWaitRetries() {
+	gomock.InOrder(
+		suite.expectGet(job.JobState_UNKNOWN, yarpcerrors.UnavailableErrorf("")),
+		suite.expectGet(job.JobState_UNKNOWN, errors.New("transient")),
+		suite.expectGet(job.JobState_SUCCEEDED, nil),
+	)
+	state, err := suite.client.JobWaitAction(
+		testJobID, time.Minute, time.Millisecond)
+	suite.NoError(err)
+	suite.Equal(JobWaitExitSucceeded, JobWaitExitCode(state, err))
+
+	// too many consecutive failures
+	suite.expectGet(job.JobState_UNKNOWN, errors.New("transient")).
+		Times(jobWaitMaxRetries + 1)
+	state, err = suite.client.JobWaitAction(
+		testJobID, time.Minute, time.Millisecond)
+	suite.EqualError(err, "transient")
+	suite.Equal(JobWaitExitError, JobWaitExitCode(state, err))
+
+	// not found is not retried
+	suite.expectGet(job.JobState_UNKNOWN, yarpcerrors.NotFoundErrorf("job"))
+	state, err = suite.client.JobWaitAction(
+		testJobID, time.Minute, time.Millisecond)
+	suite.Error(err)
+	suite.Equal(JobWaitExitError, JobWaitExitCode(state, err))
+}
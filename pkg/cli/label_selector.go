@@ -0,0 +1,397 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// labelOperator is the operator of a label requirement
+type labelOperator int
+
+const (
+	// labelEquals requires a label to have a value, k=v
+	labelEquals labelOperator = iota
+	// labelNotEquals requires a label not to have a value, k!=v. It is
+	// satisfied if the label is not set.
+	labelNotEquals
+	// labelExists requires a label to be set, k
+	labelExists
+	// labelNotExists requires a label not to be set, !k
+	labelNotExists
+	// labelIn requires a label to have one of a set of values,
+	// k in (v1, v2)
+	labelIn
+	// labelNotIn requires a label to have none of a set of values,
+	// k notin (v1, v2). It is satisfied if the label is not set.
+	labelNotIn
+)
+
+// labelRequirement is a requirement of a label selector on a label key
+type labelRequirement struct {
+	Key      string
+	Operator labelOperator
+	Value    string
+	// Values are the values of the in and notin operators
+	Values []string
+}
+
+// String returns the requirement as a label selector expression
+func (r labelRequirement) String() string {
+	switch r.Operator {
+	case labelEquals:
+		return r.Key + "=" + r.Value
+	case labelNotEquals:
+		return r.Key + "!=" + r.Value
+	case labelNotExists:
+		return "!" + r.Key
+	case labelIn:
+		return r.Key + " in (" + strings.Join(r.Values, labelSeparator) + ")"
+	case labelNotIn:
+		return r.Key + " notin (" + strings.Join(r.Values, labelSeparator) + ")"
+	default:
+		return r.Key
+	}
+}
+
+// labelTokenKind is the kind of a token of a label selector
+type labelTokenKind int
+
+const (
+	labelTokenEnd labelTokenKind = iota
+	// labelTokenText is a key or value, unquoted or quoted
+	labelTokenText
+	labelTokenComma
+	labelTokenOpen
+	labelTokenClose
+	labelTokenEquals
+	labelTokenNotEquals
+	labelTokenNot
+)
+
+// labelToken is a token of a label selector
+type labelToken struct {
+	kind labelTokenKind
+	text string
+	// quoted is set for quoted text, which is never an operator
+	quoted bool
+}
+
+// labelSelectorSpecial are the characters which end unquoted text
+const labelSelectorSpecial = ",()=!'\" \t"
+
+// tokenizeLabelSelector splits a label selector into tokens. Text may be
+// quoted with single or double quotes to contain spaces, commas,
+// parentheses and operators.
+func tokenizeLabelSelector(selector string) ([]labelToken, error) {
+	var tokens []labelToken
+	for i := 0; i < len(selector); {
+		c := selector[i]
+		switch {
+		case c == ' ' || c == '\t':
+			i++
+		case c == ',':
+			tokens = append(tokens, labelToken{kind: labelTokenComma, text: ","})
+			i++
+		case c == '(':
+			tokens = append(tokens, labelToken{kind: labelTokenOpen, text: "("})
+			i++
+		case c == ')':
+			tokens = append(tokens, labelToken{kind: labelTokenClose, text: ")"})
+			i++
+		case c == '=':
+			tokens = append(tokens, labelToken{kind: labelTokenEquals, text: "="})
+			i++
+		case strings.HasPrefix(selector[i:], "!="):
+			tokens = append(tokens, labelToken{kind: labelTokenNotEquals, text: "!="})
+			i += len("!=")
+		case c == '!':
+			tokens = append(tokens, labelToken{kind: labelTokenNot, text: "!"})
+			i++
+		case c == '"' || c == '\'':
+			end := strings.IndexByte(selector[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated quote at offset %d", i)
+			}
+			tokens = append(tokens, labelToken{
+				kind:   labelTokenText,
+				text:   selector[i+1 : i+1+end],
+				quoted: true,
+			})
+			i += end + 2
+		default:
+			end := strings.IndexAny(selector[i:], labelSelectorSpecial)
+			if end < 0 {
+				end = len(selector) - i
+			}
+			tokens = append(tokens, labelToken{
+				kind: labelTokenText,
+				text: selector[i : i+end],
+			})
+			i += end
+		}
+	}
+	return append(tokens, labelToken{kind: labelTokenEnd}), nil
+}
+
+// labelSelectorParser parses the tokens of a label selector
+type labelSelectorParser struct {
+	tokens []labelToken
+	pos    int
+	// depth is the parenthesis depth of the current expression
+	depth int
+}
+
+// next returns the next token and advances to the following one
+func (p *labelSelectorParser) next() labelToken {
+	t := p.tokens[p.pos]
+	switch t.kind {
+	case labelTokenEnd:
+		return t
+	case labelTokenOpen:
+		p.depth++
+	case labelTokenClose:
+		p.depth--
+	}
+	p.pos++
+	return t
+}
+
+// peek returns the next token without advancing
+func (p *labelSelectorParser) peek() labelToken {
+	return p.tokens[p.pos]
+}
+
+// parseLabelSelector parses a comma separated label selector of k=v, k!=v,
+// k in (v1, v2), k notin (v1, v2), k (exists) and !k (does not exist)
+// expressions. Keys and values may be quoted. The problems of all the
+// expressions are reported at once as ValidationErrors.
+func parseLabelSelector(selector string) ([]labelRequirement, error) {
+	errs := newValidationErrors(fmt.Sprintf("label selector %q", selector))
+	tokens, err := tokenizeLabelSelector(selector)
+	if err != nil {
+		errs.Add("", "%v", err)
+		return nil, errs
+	}
+
+	p := &labelSelectorParser{tokens: tokens}
+	var requirements []labelRequirement
+	expression := 1
+	for p.peek().kind != labelTokenEnd {
+		if p.peek().kind == labelTokenComma {
+			// empty expressions are ignored
+			p.next()
+			expression++
+			continue
+		}
+		position := fmt.Sprintf("expression %d", expression)
+		p.depth = 0
+		r, err := p.parseRequirement()
+		if err != nil {
+			errs.Add(position, "%v", err)
+			p.skipExpression()
+			continue
+		}
+
+		switch t := p.peek(); t.kind {
+		case labelTokenEnd, labelTokenComma:
+			requirements = append(requirements, r)
+		default:
+			errs.Add(position, "unexpected %q after %s", t.text, r)
+			p.skipExpression()
+		}
+	}
+	if err := errs.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+	return requirements, nil
+}
+
+// skipExpression advances past the rest of an invalid expression, to the
+// next comma outside parentheses or the end of the selector
+func (p *labelSelectorParser) skipExpression() {
+	for {
+		switch p.peek().kind {
+		case labelTokenEnd:
+			return
+		case labelTokenComma:
+			if p.depth <= 0 {
+				return
+			}
+		}
+		p.next()
+	}
+}
+
+// parseRequirement parses a single expression of a label selector
+func (p *labelSelectorParser) parseRequirement() (labelRequirement, error) {
+	if p.peek().kind == labelTokenNot {
+		p.next()
+		key, err := p.parseKey()
+		if err != nil {
+			return labelRequirement{}, err
+		}
+		return labelRequirement{Key: key, Operator: labelNotExists}, nil
+	}
+
+	key, err := p.parseKey()
+	if err != nil {
+		return labelRequirement{}, err
+	}
+	r := labelRequirement{Key: key, Operator: labelExists}
+	switch t := p.peek(); {
+	case t.kind == labelTokenEquals:
+		p.next()
+		r.Operator = labelEquals
+		r.Value, err = p.parseValue()
+	case t.kind == labelTokenNotEquals:
+		p.next()
+		r.Operator = labelNotEquals
+		r.Value, err = p.parseValue()
+	case t.kind == labelTokenText && !t.quoted && t.text == "in":
+		p.next()
+		r.Operator = labelIn
+		r.Values, err = p.parseValueSet()
+	case t.kind == labelTokenText && !t.quoted && t.text == "notin":
+		p.next()
+		r.Operator = labelNotIn
+		r.Values, err = p.parseValueSet()
+	}
+	return r, err
+}
+
+// parseKey parses the key of an expression
+func (p *labelSelectorParser) parseKey() (string, error) {
+	t := p.next()
+	if t.kind != labelTokenText || t.text == "" {
+		return "", fmt.Errorf("missing key before %s", describeLabelToken(t))
+	}
+	return t.text, nil
+}
+
+// parseValue parses the value of an = or != expression, which may be empty
+func (p *labelSelectorParser) parseValue() (string, error) {
+	switch t := p.peek(); t.kind {
+	case labelTokenText:
+		p.next()
+		return t.text, nil
+	case labelTokenEnd, labelTokenComma:
+		return "", nil
+	default:
+		return "", fmt.Errorf("unexpected %q in value", t.text)
+	}
+}
+
+// parseValueSet parses the parenthesized, comma separated values of an in
+// or notin expression
+func (p *labelSelectorParser) parseValueSet() ([]string, error) {
+	if t := p.next(); t.kind != labelTokenOpen {
+		return nil, fmt.Errorf("expected ( before %s", describeLabelToken(t))
+	}
+	var values []string
+	for {
+		t := p.next()
+		if t.kind != labelTokenText {
+			return nil, fmt.Errorf("expected a value before %s",
+				describeLabelToken(t))
+		}
+		values = append(values, t.text)
+
+		switch t := p.next(); t.kind {
+		case labelTokenClose:
+			return values, nil
+		case labelTokenComma:
+		default:
+			return nil, fmt.Errorf("expected , or ) before %s",
+				describeLabelToken(t))
+		}
+	}
+}
+
+// describeLabelToken describes a token in an error message
+func describeLabelToken(t labelToken) string {
+	if t.kind == labelTokenEnd {
+		return "end of selector"
+	}
+	return strconv.Quote(t.text)
+}
+
+// matchLabelSelector returns whether labels, the values of each label key,
+// satisfy all requirements
+func matchLabelSelector(
+	requirements []labelRequirement,
+	labels map[string][]string) bool {
+	for _, r := range requirements {
+		values, ok := labels[r.Key]
+		switch r.Operator {
+		case labelEquals:
+			if !containsLabelValue(values, r.Value) {
+				return false
+			}
+		case labelNotEquals:
+			if containsLabelValue(values, r.Value) {
+				return false
+			}
+		case labelExists:
+			if !ok {
+				return false
+			}
+		case labelNotExists:
+			if ok {
+				return false
+			}
+		case labelIn:
+			if !containsAnyLabelValue(values, r.Values) {
+				return false
+			}
+		case labelNotIn:
+			if containsAnyLabelValue(values, r.Values) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// containsAnyLabelValue returns whether values contain any of set
+func containsAnyLabelValue(values []string, set []string) bool {
+	for _, value := range set {
+		if containsLabelValue(values, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsLabelValue returns whether values contain value. Numbers are
+// compared by value, as scalar attributes are formatted with a fixed
+// precision.
+func containsLabelValue(values []string, value string) bool {
+	number, err := strconv.ParseFloat(value, 64)
+	isNumber := err == nil
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+		if isNumber {
+			if n, err := strconv.ParseFloat(v, 64); err == nil && n == number {
+				return true
+			}
+		}
+	}
+	return false
+}
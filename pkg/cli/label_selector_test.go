@@ -0,0 +1,202 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseLabelSelector(t *testing.T) {
+	requirements, err := parseLabelSelector(
+		"rack=r7, zone!=z1,gpu,!reserved,empty=")
+	assert.NoError(t, err)
+	assert.Equal(t, []labelRequirement{
+		{Key: "rack", Operator: labelEquals, Value: "r7"},
+		{Key: "zone", Operator: labelNotEquals, Value: "z1"},
+		{Key: "gpu", Operator: labelExists},
+		{Key: "reserved", Operator: labelNotExists},
+		{Key: "empty", Operator: labelEquals},
+	}, requirements)
+
+	requirements, err = parseLabelSelector("")
+	assert.NoError(t, err)
+	assert.Empty(t, requirements)
+
+	for _, selector := range []string{
+		"=r7", "!=z1", "!", "rack=r7=r8", "rack!=!r7", "my rack=r7",
+	} {
+		_, err := parseLabelSelector(selector)
+		assert.Error(t, err, selector)
+	}
+}
+
+func TestParseLabelSelectorSetOperators(t *testing.T) {
+	requirements, err := parseLabelSelector(
+		"team in (infra, data),tier notin(dev),  zone  =  z1 ,in=x")
+	assert.NoError(t, err)
+	assert.Equal(t, []labelRequirement{
+		{Key: "team", Operator: labelIn, Values: []string{"infra", "data"}},
+		{Key: "tier", Operator: labelNotIn, Values: []string{"dev"}},
+		{Key: "zone", Operator: labelEquals, Value: "z1"},
+		// in is only an operator after a key
+		{Key: "in", Operator: labelEquals, Value: "x"},
+	}, requirements)
+}
+
+func TestParseLabelSelectorWhitespace(t *testing.T) {
+	for _, selector := range []string{
+		"team in (infra,data)",
+		"  team   in   (  infra ,  data  )  ",
+		"\tteam in\t(infra,\tdata)",
+		"team in(infra,data)",
+	} {
+		requirements, err := parseLabelSelector(selector)
+		assert.NoError(t, err, selector)
+		assert.Equal(t, []labelRequirement{
+			{Key: "team", Operator: labelIn, Values: []string{"infra", "data"}},
+		}, requirements, selector)
+	}
+}
+
+func TestParseLabelSelectorQuoting(t *testing.T) {
+	requirements, err := parseLabelSelector(
+		`desc="a, b (c)",'my key'!='x=y',team in ("in", 'a b', ""),"in"`)
+	assert.NoError(t, err)
+	assert.Equal(t, []labelRequirement{
+		{Key: "desc", Operator: labelEquals, Value: "a, b (c)"},
+		{Key: "my key", Operator: labelNotEquals, Value: "x=y"},
+		{Key: "team", Operator: labelIn, Values: []string{"in", "a b", ""}},
+		{Key: "in", Operator: labelExists},
+	}, requirements)
+
+	// a quoted in is a value, not an operator
+	_, err = parseLabelSelector(`team "in" (infra)`)
+	assert.Error(t, err)
+}
+
+func TestParseLabelSelectorMalformed(t *testing.T) {
+	for _, selector := range []string{
+		"team in",
+		"team in infra",
+		"team in ()",
+		"team in (infra,)",
+		"team in (infra",
+		"team in (infra data)",
+		"team notin (infra))",
+		"team in (infra) extra",
+		`team="infra`,
+		`team in ('infra)`,
+		`""=infra`,
+		"!team=infra",
+		"(team)",
+		"team=(infra)",
+	} {
+		_, err := parseLabelSelector(selector)
+		assert.Error(t, err, selector)
+	}
+}
+
+func TestParseLabelSelectorAllErrors(t *testing.T) {
+	// the problems of every expression are reported at once
+	_, err := parseLabelSelector("=a, team in (x y), env=prod, !")
+	assert.EqualError(t, err,
+		`Invalid label selector "=a, team in (x y), env=prod, !", 3 errors:`+"\n"+
+			`  expression 1: missing key before "="`+"\n"+
+			`  expression 2: expected , or ) before "y"`+"\n"+
+			`  expression 4: missing key before end of selector`)
+
+	// commas inside parentheses do not end invalid expressions
+	_, err = parseLabelSelector("team in (a,,b), env=prod")
+	assert.EqualError(t, err,
+		`Invalid label selector "team in (a,,b), env=prod": `+
+			`expression 1: expected a value before ","`)
+}
+
+func TestLabelRequirementString(t *testing.T) {
+	requirements, err := parseLabelSelector(
+		"a=1,b!=2,c,!d,e in (3,4),f notin (5)")
+	assert.NoError(t, err)
+	var expressions []string
+	for _, r := range requirements {
+		expressions = append(expressions, r.String())
+	}
+	assert.Equal(t, []string{
+		"a=1", "b!=2", "c", "!d", "e in (3,4)", "f notin (5)",
+	}, expressions)
+}
+
+func TestMatchLabelSelector(t *testing.T) {
+	labels := map[string][]string{
+		"rack":  {"r7"},
+		"zone":  {"z1", "z2"},
+		"cores": {"16.000000"},
+	}
+
+	tt := []struct {
+		selector string
+		match    bool
+	}{
+		{"", true},
+		{"rack=r7", true},
+		{"rack=r8", false},
+		{"rack=r7,zone=z2", true},
+		{"rack!=r8", true},
+		{"rack!=r7", false},
+		{"zone!=z2", false},
+		// a label which is not set is not equal to any value
+		{"gpu!=true", true},
+		{"zone", true},
+		{"gpu", false},
+		{"!gpu", true},
+		{"!rack", false},
+		// scalar values are compared as numbers
+		{"cores=16", true},
+		{"cores!=16.0", false},
+		{"cores=8", false},
+		{"rack in (r6,r7)", true},
+		{"rack in (r6,r8)", false},
+		{"zone in (z2)", true},
+		{"gpu in (true)", false},
+		{"rack notin (r6,r8)", true},
+		{"zone notin (z1)", false},
+		// a label which is not set is not in any set of values
+		{"gpu notin (true)", true},
+		{"cores in (8,16)", true},
+	}
+	for _, test := range tt {
+		requirements, err := parseLabelSelector(test.selector)
+		assert.NoError(t, err, test.selector)
+		assert.Equal(t, test.match,
+			matchLabelSelector(requirements, labels), test.selector)
+	}
+}
+
+func TestParsePelotonLabels(t *testing.T) {
+	labels, err := parsePelotonLabels("team=money,env=prod")
+	assert.NoError(t, err)
+	assert.Equal(t, []*peloton.Label{
+		{Key: "team", Value: "money"},
+		{Key: "env", Value: "prod"},
+	}, labels)
+
+	for _, selector := range []string{"team=money,env", "team!=money", "=money"} {
+		_, err := parsePelotonLabels(selector)
+		assert.Error(t, err, selector)
+	}
+}
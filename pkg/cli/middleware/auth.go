@@ -2,14 +2,22 @@ package middleware
 
 import (
 	"context"
+	"errors"
 
 	"go.uber.org/yarpc/api/middleware"
 	"go.uber.org/yarpc/api/transport"
 )
 
+// The headers of the credentials, which the auth inbound middleware of the
+// services passes to their security manager: basic auth reads the username
+// and password headers, token auth the bearer token of the authorization
+// header.
 const (
 	_usernameHeader = "username"
 	_passwordHeader = "password"
+	_tokenHeader    = "authorization"
+
+	_tokenPrefix = "Bearer "
 )
 
 type outboundMiddleware interface {
@@ -18,7 +26,10 @@ type outboundMiddleware interface {
 	middleware.StreamOutbound
 }
 
-var _ outboundMiddleware = &BasicAuthOutboundMiddleware{}
+var (
+	_ outboundMiddleware = &BasicAuthOutboundMiddleware{}
+	_ outboundMiddleware = &AuthOutboundMiddleware{}
+)
 
 // BasicAuthConfig is the config for basic auth
 type BasicAuthConfig struct {
@@ -71,3 +82,69 @@ func (m *BasicAuthOutboundMiddleware) addAuthToHeader(headers transport.Headers)
 
 	return headers
 }
+
+// AuthConfig is the config of the credentials added to all outbound
+// requests, either basic auth or a token
+type AuthConfig struct {
+	Basic *BasicAuthConfig
+	Token string
+}
+
+// validate checks that at most one kind of credentials is configured
+func (c *AuthConfig) validate() error {
+	if c.Basic != nil && c.Token != "" {
+		return errors.New("basic auth and token auth cannot be used together")
+	}
+	return nil
+}
+
+// AuthOutboundMiddleware adds either basic auth or token auth
+// to all outbound requests
+type AuthOutboundMiddleware struct {
+	basic *BasicAuthOutboundMiddleware
+	token string
+}
+
+// NewAuthOutboundMiddleware creates AuthOutboundMiddleware, which adds no
+// credentials if config is nil. It fails if config has both basic auth
+// and a token.
+func NewAuthOutboundMiddleware(config *AuthConfig) (*AuthOutboundMiddleware, error) {
+	if config == nil {
+		return &AuthOutboundMiddleware{
+			basic: NewBasicAuthOutboundMiddleware(nil),
+		}, nil
+	}
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+	return &AuthOutboundMiddleware{
+		basic: NewBasicAuthOutboundMiddleware(config.Basic),
+		token: config.Token,
+	}, nil
+}
+
+// Call adds auth info to yarpc request header and relay the request
+func (m *AuthOutboundMiddleware) Call(ctx context.Context, request *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	request.Headers = m.addAuthToHeader(request.Headers)
+	return out.Call(ctx, request)
+}
+
+// CallOneway adds auth info to yarpc request header and relay the request
+func (m *AuthOutboundMiddleware) CallOneway(ctx context.Context, request *transport.Request, out transport.OnewayOutbound) (transport.Ack, error) {
+	request.Headers = m.addAuthToHeader(request.Headers)
+	return out.CallOneway(ctx, request)
+}
+
+// CallStream adds auth info to yarpc request header and relay the request
+func (m *AuthOutboundMiddleware) CallStream(ctx context.Context, request *transport.StreamRequest, out transport.StreamOutbound) (*transport.ClientStream, error) {
+	request.Meta.Headers = m.addAuthToHeader(request.Meta.Headers)
+	return out.CallStream(ctx, request)
+}
+
+func (m *AuthOutboundMiddleware) addAuthToHeader(headers transport.Headers) transport.Headers {
+	headers = m.basic.addAuthToHeader(headers)
+	if m.token != "" {
+		headers = headers.With(_tokenHeader, _tokenPrefix+m.token)
+	}
+	return headers
+}
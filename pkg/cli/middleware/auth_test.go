@@ -0,0 +1,125 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+)
+
+type authMiddlewareTestSuite struct {
+	suite.Suite
+	ctrl *gomock.Controller
+}
+
+func (suite *authMiddlewareTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+}
+
+func (suite *authMiddlewareTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	suite.Run(t, new(authMiddlewareTestSuite))
+}
+
+// call sends a unary request through the middleware of config and returns
+// the headers received by the outbound
+func (suite *authMiddlewareTestSuite) call(config *AuthConfig) transport.Headers {
+	m, err := NewAuthOutboundMiddleware(config)
+	suite.NoError(err)
+
+	var headers transport.Headers
+	outbound := transporttest.NewMockUnaryOutbound(suite.ctrl)
+	outbound.EXPECT().Call(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, request *transport.Request) {
+			headers = request.Headers
+		}).
+		Return(&transport.Response{}, nil)
+
+	_, err = m.Call(context.Background(), &transport.Request{}, outbound)
+	suite.NoError(err)
+	return headers
+}
+
+// TestBasicAuth tests that basic auth adds the username and password headers
+func (suite *authMiddlewareTestSuite) TestBasicAuth() {
+	headers := suite.call(&AuthConfig{
+		Basic: &BasicAuthConfig{Username: "user", Password: "password"},
+	})
+
+	username, _ := headers.Get(_usernameHeader)
+	suite.Equal("user", username)
+	password, _ := headers.Get(_passwordHeader)
+	suite.Equal("password", password)
+	_, ok := headers.Get(_tokenHeader)
+	suite.False(ok)
+}
+
+// TestTokenAuth tests that token auth adds the bearer token header
+func (suite *authMiddlewareTestSuite) TestTokenAuth() {
+	headers := suite.call(&AuthConfig{Token: "secret"})
+
+	token, _ := headers.Get(_tokenHeader)
+	suite.Equal("Bearer secret", token)
+	_, ok := headers.Get(_usernameHeader)
+	suite.False(ok)
+	_, ok = headers.Get(_passwordHeader)
+	suite.False(ok)
+}
+
+// TestNoAuth tests that no header is added without credentials
+func (suite *authMiddlewareTestSuite) TestNoAuth() {
+	for _, config := range []*AuthConfig{nil, {}} {
+		headers := suite.call(config)
+		suite.Equal(0, headers.Len())
+	}
+}
+
+// TestBasicAndTokenAuth tests that basic auth and token auth cannot be
+// configured together
+func (suite *authMiddlewareTestSuite) TestBasicAndTokenAuth() {
+	_, err := NewAuthOutboundMiddleware(&AuthConfig{
+		Basic: &BasicAuthConfig{Username: "user", Password: "password"},
+		Token: "secret",
+	})
+	suite.EqualError(err, "basic auth and token auth cannot be used together")
+}
+
+// TestCallStream tests that the token is added to stream requests
+func (suite *authMiddlewareTestSuite) TestCallStream() {
+	m, err := NewAuthOutboundMiddleware(&AuthConfig{Token: "secret"})
+	suite.NoError(err)
+
+	outbound := transporttest.NewMockStreamOutbound(suite.ctrl)
+	outbound.EXPECT().CallStream(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, request *transport.StreamRequest) {
+			token, _ := request.Meta.Headers.Get(_tokenHeader)
+			suite.Equal("Bearer secret", token)
+		}).
+		Return(nil, nil)
+
+	_, err = m.CallStream(
+		context.Background(),
+		&transport.StreamRequest{Meta: &transport.RequestMeta{}},
+		outbound)
+	suite.NoError(err)
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gogo/protobuf/jsonpb"
+	"github.com/gogo/protobuf/proto"
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+)
+
+var _ middleware.UnaryOutbound = &DebugOutboundMiddleware{}
+
+// redactedValue replaces the values of secret fields and headers
+const redactedValue = "<redacted>"
+
+// secretFields are the fields of requests and responses whose values are
+// redacted, e.g. the secrets of a job
+var secretFields = map[string]bool{
+	"password": true,
+	"secret":   true,
+	"secrets":  true,
+}
+
+// secretHeaderNames are the substrings of the names of the headers whose
+// values are redacted, e.g. the basic auth password
+var secretHeaderNames = []string{
+	"auth",
+	"password",
+	"secret",
+	"token",
+}
+
+// MessageResolver returns new messages of the request and response of a
+// procedure, or nils if they are not known
+type MessageResolver func(procedure string) (request proto.Message, response proto.Message)
+
+// debugRecord is the JSON line written for every outbound request
+type debugRecord struct {
+	Procedure string            `json:"procedure"`
+	Service   string            `json:"service"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Request   json.RawMessage   `json:"request,omitempty"`
+	Response  json.RawMessage   `json:"response,omitempty"`
+	Error     string            `json:"error,omitempty"`
+	LatencyMs float64           `json:"latency_ms"`
+}
+
+// DebugOutboundMiddleware writes every unary outbound request, its response
+// or error and its latency as a JSON line, with secret fields and headers
+// redacted
+type DebugOutboundMiddleware struct {
+	out     io.Writer
+	resolve MessageResolver
+	// lock serializes the writes of concurrent requests
+	lock sync.Mutex
+}
+
+// NewDebugOutboundMiddleware creates DebugOutboundMiddleware writing to out.
+// Bodies are printed as JSON if resolve knows the messages of their
+// procedure, and as base64 otherwise.
+func NewDebugOutboundMiddleware(out io.Writer, resolve MessageResolver) *DebugOutboundMiddleware {
+	return &DebugOutboundMiddleware{
+		out:     out,
+		resolve: resolve,
+	}
+}
+
+// Call sends the request and writes it with its response
+func (m *DebugOutboundMiddleware) Call(ctx context.Context, request *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	var requestMessage, responseMessage proto.Message
+	if m.resolve != nil {
+		requestMessage, responseMessage = m.resolve(request.Procedure)
+	}
+
+	record := debugRecord{
+		Procedure: request.Procedure,
+		Service:   request.Service,
+		Headers:   redactHeaders(request.Headers),
+	}
+
+	if request.Body != nil {
+		body, err := ioutil.ReadAll(request.Body)
+		if err != nil {
+			return nil, err
+		}
+		request.Body = bytes.NewReader(body)
+		record.Request = marshalBody(body, requestMessage)
+	}
+
+	start := time.Now()
+	response, err := out.Call(ctx, request)
+	record.LatencyMs = float64(time.Since(start)) / float64(time.Millisecond)
+
+	if err != nil {
+		record.Error = err.Error()
+	} else if response != nil && response.Body != nil {
+		body, readErr := ioutil.ReadAll(response.Body)
+		response.Body.Close()
+		if readErr != nil {
+			return nil, readErr
+		}
+		response.Body = ioutil.NopCloser(bytes.NewReader(body))
+		record.Response = marshalBody(body, responseMessage)
+	}
+
+	m.write(record)
+	return response, err
+}
+
+// write writes a record as a single line
+func (m *DebugOutboundMiddleware) write(record debugRecord) {
+	line, err := marshalJSON(record)
+	if err != nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.out.Write(append(line, '\n'))
+}
+
+// marshalJSON returns the JSON of a value without escaping HTML characters,
+// e.g. the brackets of redactedValue
+func marshalJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// marshalBody returns the JSON of a protobuf encoded body with its secret
+// fields redacted, or a base64 string if it cannot be decoded as message
+func marshalBody(body []byte, message proto.Message) json.RawMessage {
+	if message != nil {
+		if err := proto.Unmarshal(body, message); err == nil {
+			if raw, err := marshalMessage(message); err == nil {
+				return raw
+			}
+		}
+	}
+	raw, _ := json.Marshal(body)
+	return raw
+}
+
+// marshalMessage returns the JSON of a message with its secret fields
+// redacted
+func marshalMessage(message proto.Message) (json.RawMessage, error) {
+	marshaler := &jsonpb.Marshaler{OrigName: true}
+	s, err := marshaler.MarshalToString(message)
+	if err != nil {
+		return nil, err
+	}
+	var value interface{}
+	if err := json.Unmarshal([]byte(s), &value); err != nil {
+		return nil, err
+	}
+	return marshalJSON(redactFields(value))
+}
+
+// redactFields replaces the values of the secret fields of a JSON value
+func redactFields(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if secretFields[strings.ToLower(key)] {
+				v[key] = redactedValue
+			} else {
+				v[key] = redactFields(field)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = redactFields(item)
+		}
+	}
+	return value
+}
+
+// redactHeaders returns the headers with the values of secret headers
+// redacted
+func redactHeaders(headers transport.Headers) map[string]string {
+	if headers.Len() == 0 {
+		return nil
+	}
+	redacted := make(map[string]string, headers.Len())
+	for key, value := range headers.Items() {
+		for _, name := range secretHeaderNames {
+			if strings.Contains(strings.ToLower(key), name) {
+				value = redactedValue
+				break
+			}
+		}
+		redacted[key] = value
+	}
+	return redacted
+}
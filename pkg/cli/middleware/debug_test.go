@@ -0,0 +1,206 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+)
+
+type debugMiddlewareTestSuite struct {
+	suite.Suite
+	ctrl     *gomock.Controller
+	outbound *transporttest.MockUnaryOutbound
+	out      *bytes.Buffer
+	debug    *DebugOutboundMiddleware
+}
+
+func (suite *debugMiddlewareTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.outbound = transporttest.NewMockUnaryOutbound(suite.ctrl)
+	suite.out = &bytes.Buffer{}
+	suite.debug = NewDebugOutboundMiddleware(suite.out,
+		func(procedure string) (proto.Message, proto.Message) {
+			if procedure != testCreateProcedure {
+				return nil, nil
+			}
+			return &job.CreateRequest{}, &job.CreateResponse{}
+		})
+}
+
+func (suite *debugMiddlewareTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestDebugMiddleware(t *testing.T) {
+	suite.Run(t, new(debugMiddlewareTestSuite))
+}
+
+// newCreateRequest returns a job create request with a secret
+func (suite *debugMiddlewareTestSuite) newCreateRequest() *transport.Request {
+	body, err := proto.Marshal(&job.CreateRequest{
+		Id: &peloton.JobID{Value: "my-job"},
+		Secrets: []*peloton.Secret{{
+			Path:  "/tmp/secret",
+			Value: &peloton.Secret_Value{Data: []byte("my-test-secret")},
+		}},
+	})
+	suite.NoError(err)
+	return &transport.Request{
+		Service:   "peloton-jobmgr",
+		Procedure: testCreateProcedure,
+		Headers: transport.NewHeaders().
+			With(_usernameHeader, "user").
+			With(_passwordHeader, "my-password"),
+		Body: bytes.NewReader(body),
+	}
+}
+
+// records returns the JSON lines written by the middleware
+func (suite *debugMiddlewareTestSuite) records() []map[string]interface{} {
+	var records []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(suite.out.String()), "\n") {
+		var record map[string]interface{}
+		suite.NoError(json.Unmarshal([]byte(line), &record), line)
+		records = append(records, record)
+	}
+	return records
+}
+
+// TestDebugRedaction tests that a request and its response are written as a
+// JSON line with its secrets and auth headers redacted
+func (suite *debugMiddlewareTestSuite) TestDebugRedaction() {
+	responseBody, err := proto.Marshal(&job.CreateResponse{
+		JobId: &peloton.JobID{Value: "my-job"},
+	})
+	suite.NoError(err)
+	suite.outbound.EXPECT().
+		Call(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, request *transport.Request) {
+			// the request body is still sent
+			body, err := ioutil.ReadAll(request.Body)
+			suite.NoError(err)
+			suite.Contains(string(body), "my-test-secret")
+		}).
+		Return(&transport.Response{
+			Body: ioutil.NopCloser(bytes.NewReader(responseBody)),
+		}, nil)
+
+	response, err := suite.debug.Call(
+		context.Background(), suite.newCreateRequest(), suite.outbound)
+	suite.NoError(err)
+
+	// the response body is still returned
+	body, err := ioutil.ReadAll(response.Body)
+	suite.NoError(err)
+	suite.Equal(responseBody, body)
+
+	suite.NotContains(suite.out.String(), "my-test-secret")
+	suite.NotContains(suite.out.String(), "bXktdGVzdC1zZWNyZXQ=")
+	suite.NotContains(suite.out.String(), "my-password")
+
+	records := suite.records()
+	suite.Len(records, 1)
+	record := records[0]
+	suite.Equal(testCreateProcedure, record["procedure"])
+	suite.Equal("peloton-jobmgr", record["service"])
+	suite.Equal(map[string]interface{}{
+		_usernameHeader: "user",
+		_passwordHeader: redactedValue,
+	}, record["headers"])
+	suite.Equal(map[string]interface{}{
+		"id":      map[string]interface{}{"value": "my-job"},
+		"secrets": redactedValue,
+	}, record["request"])
+	suite.Equal(map[string]interface{}{
+		"jobId": map[string]interface{}{"value": "my-job"},
+	}, record["response"])
+	suite.Contains(record, "latency_ms")
+	suite.NotContains(record, "error")
+}
+
+// TestDebugError tests that the error of a request is written
+func (suite *debugMiddlewareTestSuite) TestDebugError() {
+	suite.outbound.EXPECT().
+		Call(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("job already exists"))
+
+	_, err := suite.debug.Call(
+		context.Background(), suite.newCreateRequest(), suite.outbound)
+	suite.Error(err)
+
+	records := suite.records()
+	suite.Len(records, 1)
+	suite.Equal("job already exists", records[0]["error"])
+	suite.NotContains(records[0], "response")
+}
+
+// TestDebugUnknownProcedure tests that the bodies of procedures whose
+// messages are unknown are written as base64
+func (suite *debugMiddlewareTestSuite) TestDebugUnknownProcedure() {
+	suite.outbound.EXPECT().
+		Call(gomock.Any(), gomock.Any()).
+		Return(&transport.Response{
+			Body: ioutil.NopCloser(strings.NewReader("response-body")),
+		}, nil)
+
+	_, err := suite.debug.Call(context.Background(), &transport.Request{
+		Procedure: testGetProcedure,
+		Body:      strings.NewReader(testRequestBody),
+	}, suite.outbound)
+	suite.NoError(err)
+
+	records := suite.records()
+	suite.Len(records, 1)
+	suite.Equal("cmVxdWVzdC1ib2R5", records[0]["request"])
+	suite.Equal("cmVzcG9uc2UtYm9keQ==", records[0]["response"])
+}
+
+// TestDebugStdoutUnaffected tests that nothing is written to stdout
+func (suite *debugMiddlewareTestSuite) TestDebugStdoutUnaffected() {
+	r, w, err := os.Pipe()
+	suite.NoError(err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	suite.outbound.EXPECT().
+		Call(gomock.Any(), gomock.Any()).
+		Return(&transport.Response{}, nil)
+	_, err = suite.debug.Call(
+		context.Background(), suite.newCreateRequest(), suite.outbound)
+	suite.NoError(err)
+
+	w.Close()
+	written, err := ioutil.ReadAll(r)
+	suite.NoError(err)
+	suite.Empty(written)
+	suite.Len(suite.records(), 1)
+}
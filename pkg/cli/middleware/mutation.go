@@ -0,0 +1,67 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+)
+
+var _ middleware.UnaryOutbound = &MutationOutboundMiddleware{}
+
+// MutationOutboundMiddleware records the procedures of the unary outbound
+// requests which may mutate state, i.e. which are not idempotent, e.g. to
+// keep a history of the mutating commands of the CLI
+type MutationOutboundMiddleware struct {
+	lock       sync.Mutex
+	procedures []string
+}
+
+// NewMutationOutboundMiddleware creates MutationOutboundMiddleware
+func NewMutationOutboundMiddleware() *MutationOutboundMiddleware {
+	return &MutationOutboundMiddleware{}
+}
+
+// Call records the procedure of the request if it is not idempotent, and
+// relays the request
+func (m *MutationOutboundMiddleware) Call(ctx context.Context, request *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	if !isIdempotent(request.Procedure) {
+		m.record(request.Procedure)
+	}
+	return out.Call(ctx, request)
+}
+
+// record records a procedure unless it was already recorded
+func (m *MutationOutboundMiddleware) record(procedure string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	for _, p := range m.procedures {
+		if p == procedure {
+			return
+		}
+	}
+	m.procedures = append(m.procedures, procedure)
+}
+
+// Procedures returns the mutating procedures called so far, in the order
+// of their first call
+func (m *MutationOutboundMiddleware) Procedures() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]string(nil), m.procedures...)
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+)
+
+type mutationMiddlewareTestSuite struct {
+	suite.Suite
+	ctrl *gomock.Controller
+}
+
+func (suite *mutationMiddlewareTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+}
+
+func (suite *mutationMiddlewareTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestMutationMiddleware(t *testing.T) {
+	suite.Run(t, new(mutationMiddlewareTestSuite))
+}
+
+// TestCall tests that only the procedures of the requests which are not
+// idempotent are recorded, once each, including the failed ones
+func (suite *mutationMiddlewareTestSuite) TestCall() {
+	outbound := transporttest.NewMockUnaryOutbound(suite.ctrl)
+	outbound.EXPECT().Call(gomock.Any(), gomock.Any()).
+		Return(&transport.Response{}, nil).Times(4)
+	outbound.EXPECT().Call(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("unavailable"))
+
+	m := NewMutationOutboundMiddleware()
+	suite.Empty(m.Procedures())
+	for _, procedure := range []string{
+		"peloton.api.v0.task.TaskManager::List",
+		"peloton.api.v0.task.TaskManager::Stop",
+		"peloton.api.v0.job.JobManager::Query",
+		"peloton.api.v0.task.TaskManager::Stop",
+	} {
+		_, err := m.Call(context.Background(),
+			&transport.Request{Procedure: procedure}, outbound)
+		suite.NoError(err)
+	}
+	_, err := m.Call(context.Background(),
+		&transport.Request{Procedure: "peloton.api.v0.host.svc.HostService::StartMaintenance"},
+		outbound)
+	suite.Error(err)
+
+	suite.Equal([]string{
+		"peloton.api.v0.task.TaskManager::Stop",
+		"peloton.api.v0.host.svc.HostService::StartMaintenance",
+	}, m.Procedures())
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+
+	"go.uber.org/yarpc/api/transport"
+)
+
+// RequestIDHeader is the header carrying the ID of the CLI invocation which
+// sent a request, so that its requests can be found in the server logs
+const RequestIDHeader = "x-peloton-request-id"
+
+var _ outboundMiddleware = &RequestIDOutboundMiddleware{}
+
+// RequestIDOutboundMiddleware adds the request ID of the CLI invocation to
+// the headers of all outbound requests
+type RequestIDOutboundMiddleware struct {
+	requestID string
+}
+
+// NewRequestIDOutboundMiddleware creates RequestIDOutboundMiddleware adding
+// requestID, which is not added if it is empty
+func NewRequestIDOutboundMiddleware(requestID string) *RequestIDOutboundMiddleware {
+	return &RequestIDOutboundMiddleware{
+		requestID: requestID,
+	}
+}
+
+// Call adds the request ID to yarpc request header and relay the request
+func (m *RequestIDOutboundMiddleware) Call(ctx context.Context, request *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	request.Headers = m.addRequestIDToHeader(request.Headers)
+	return out.Call(ctx, request)
+}
+
+// CallOneway adds the request ID to yarpc request header and relay the
+// request
+func (m *RequestIDOutboundMiddleware) CallOneway(ctx context.Context, request *transport.Request, out transport.OnewayOutbound) (transport.Ack, error) {
+	request.Headers = m.addRequestIDToHeader(request.Headers)
+	return out.CallOneway(ctx, request)
+}
+
+// CallStream adds the request ID to yarpc request header and relay the
+// request
+func (m *RequestIDOutboundMiddleware) CallStream(ctx context.Context, request *transport.StreamRequest, out transport.StreamOutbound) (*transport.ClientStream, error) {
+	request.Meta.Headers = m.addRequestIDToHeader(request.Meta.Headers)
+	return out.CallStream(ctx, request)
+}
+
+func (m *RequestIDOutboundMiddleware) addRequestIDToHeader(headers transport.Headers) transport.Headers {
+	if m.requestID == "" {
+		return headers
+	}
+	return headers.With(RequestIDHeader, m.requestID)
+}
@@ -0,0 +1,132 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+)
+
+type requestIDMiddlewareTestSuite struct {
+	suite.Suite
+	ctrl *gomock.Controller
+}
+
+func (suite *requestIDMiddlewareTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+}
+
+func (suite *requestIDMiddlewareTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	suite.Run(t, new(requestIDMiddlewareTestSuite))
+}
+
+// requireHeader checks the value of a header of a captured request
+func (suite *requestIDMiddlewareTestSuite) requireHeader(
+	headers transport.Headers,
+	key string,
+	expected string) {
+	value, ok := headers.Get(key)
+	suite.True(ok, key)
+	suite.Equal(expected, value, key)
+}
+
+// TestCall tests that the request ID is added to unary requests
+func (suite *requestIDMiddlewareTestSuite) TestCall() {
+	outbound := transporttest.NewMockUnaryOutbound(suite.ctrl)
+	outbound.EXPECT().Call(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, request *transport.Request) {
+			suite.requireHeader(request.Headers, RequestIDHeader, "request-id")
+		}).
+		Return(&transport.Response{}, nil)
+
+	_, err := NewRequestIDOutboundMiddleware("request-id").Call(
+		context.Background(), &transport.Request{}, outbound)
+	suite.NoError(err)
+}
+
+// TestCallWithoutRequestID tests that no header is added without request ID
+func (suite *requestIDMiddlewareTestSuite) TestCallWithoutRequestID() {
+	outbound := transporttest.NewMockUnaryOutbound(suite.ctrl)
+	outbound.EXPECT().Call(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, request *transport.Request) {
+			_, ok := request.Headers.Get(RequestIDHeader)
+			suite.False(ok)
+		}).
+		Return(&transport.Response{}, nil)
+
+	_, err := NewRequestIDOutboundMiddleware("").Call(
+		context.Background(), &transport.Request{}, outbound)
+	suite.NoError(err)
+}
+
+// TestCallOneway tests that the request ID is added to oneway requests
+// along with the headers of the other middleware of the chain
+func (suite *requestIDMiddlewareTestSuite) TestCallOneway() {
+	chain := OnewayOutboundChain(
+		NewBasicAuthOutboundMiddleware(&BasicAuthConfig{
+			Username: "user",
+			Password: "password",
+		}),
+		NewRequestIDOutboundMiddleware("request-id"),
+	)
+
+	outbound := transporttest.NewMockOnewayOutbound(suite.ctrl)
+	outbound.EXPECT().CallOneway(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, request *transport.Request) {
+			suite.requireHeader(request.Headers, RequestIDHeader, "request-id")
+			suite.requireHeader(request.Headers, _usernameHeader, "user")
+		}).
+		Return(nil, nil)
+
+	_, err := chain.CallOneway(
+		context.Background(), &transport.Request{}, outbound)
+	suite.NoError(err)
+}
+
+// TestCallStream tests that the request ID is added to stream requests
+// along with the headers of the other middleware of the chain
+func (suite *requestIDMiddlewareTestSuite) TestCallStream() {
+	chain := StreamOutboundChain(
+		NewBasicAuthOutboundMiddleware(&BasicAuthConfig{
+			Username: "user",
+			Password: "password",
+		}),
+		NewRequestIDOutboundMiddleware("request-id"),
+	)
+
+	outbound := transporttest.NewMockStreamOutbound(suite.ctrl)
+	outbound.EXPECT().CallStream(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, request *transport.StreamRequest) {
+			suite.requireHeader(
+				request.Meta.Headers, RequestIDHeader, "request-id")
+			suite.requireHeader(request.Meta.Headers, _usernameHeader, "user")
+		}).
+		Return(nil, nil)
+
+	_, err := chain.CallStream(
+		context.Background(),
+		&transport.StreamRequest{Meta: &transport.RequestMeta{}},
+		outbound)
+	suite.NoError(err)
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/yarpc/api/middleware"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/pkg/procedure"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+var _ middleware.UnaryOutbound = &RetryOutboundMiddleware{}
+
+// idempotentMethodPrefixes are the prefixes of the names of the RPC methods
+// which do not mutate any state and are safe to retry
+var idempotentMethodPrefixes = []string{
+	"Browse",
+	"Dump",
+	"Get",
+	"List",
+	"Lookup",
+	"Query",
+}
+
+// RetryPolicy is the timeout and retry policy of unary outbound requests
+type RetryPolicy struct {
+	// Timeout is the timeout of a single attempt of a request,
+	// the deadline of the request context is used if it is not set
+	Timeout time.Duration
+	// Retries is the number of times a request failing with a transient
+	// error is retried
+	Retries int
+	// Backoff is the delay before the first retry, it doubles with every
+	// further retry
+	Backoff time.Duration
+	// RetryMutations allows retrying requests which are not idempotent
+	RetryMutations bool
+}
+
+// RetryOutboundMiddleware applies a RetryPolicy to all unary outbound
+// requests
+type RetryOutboundMiddleware struct {
+	policy RetryPolicy
+}
+
+// NewRetryOutboundMiddleware creates RetryOutboundMiddleware
+func NewRetryOutboundMiddleware(policy RetryPolicy) *RetryOutboundMiddleware {
+	return &RetryOutboundMiddleware{
+		policy: policy,
+	}
+}
+
+// Call sends the request, retrying it on transient errors if it is
+// idempotent or mutations may be retried
+func (m *RetryOutboundMiddleware) Call(ctx context.Context, request *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	// the body is consumed by every attempt
+	var body []byte
+	if request.Body != nil {
+		var err error
+		if body, err = ioutil.ReadAll(request.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	retries := 0
+	if m.policy.RetryMutations || isIdempotent(request.Procedure) {
+		retries = m.policy.Retries
+	}
+
+	for attempt := 1; ; attempt++ {
+		attemptRequest := *request
+		attemptRequest.Body = bytes.NewReader(body)
+		response, err := m.call(ctx, &attemptRequest, out)
+		if err == nil || attempt > retries || !isTransient(ctx, err) {
+			return response, err
+		}
+
+		backoff := m.policy.Backoff << uint(attempt-1)
+		log.WithFields(log.Fields{
+			"procedure": request.Procedure,
+			"attempt":   attempt,
+			"backoff":   backoff,
+			"error":     err,
+		}).Debug("Retrying failed request")
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(backoff):
+		}
+	}
+}
+
+// call sends a single attempt of the request
+func (m *RetryOutboundMiddleware) call(ctx context.Context, request *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	if m.policy.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.policy.Timeout)
+		defer cancel()
+	}
+	return out.Call(ctx, request)
+}
+
+// isIdempotent returns whether the procedure does not mutate any state
+func isIdempotent(name string) bool {
+	_, method := procedure.FromName(name)
+	for _, prefix := range idempotentMethodPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTransient returns whether the request failed with an error which may
+// not happen again, and the request context is still valid
+func isTransient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	return yarpcerrors.IsUnavailable(err) ||
+		yarpcerrors.IsDeadlineExceeded(err) ||
+		yarpcerrors.IsResourceExhausted(err) ||
+		yarpcerrors.IsAborted(err)
+}
+
+// unaryOutboundChain applies a list of unary outbound middleware, the first
+// one being the outermost
+type unaryOutboundChain []middleware.UnaryOutbound
+
+// UnaryOutboundChain combines a list of unary outbound middleware into one
+func UnaryOutboundChain(mw ...middleware.UnaryOutbound) middleware.UnaryOutbound {
+	return unaryOutboundChain(mw)
+}
+
+// Call applies the middleware of the chain to the request
+func (c unaryOutboundChain) Call(ctx context.Context, request *transport.Request, out transport.UnaryOutbound) (*transport.Response, error) {
+	for i := len(c) - 1; i >= 0; i-- {
+		out = middleware.ApplyUnaryOutbound(out, c[i])
+	}
+	return out.Call(ctx, request)
+}
+
+// onewayOutboundChain applies a list of oneway outbound middleware, the
+// first one being the outermost
+type onewayOutboundChain []middleware.OnewayOutbound
+
+// OnewayOutboundChain combines a list of oneway outbound middleware into one
+func OnewayOutboundChain(mw ...middleware.OnewayOutbound) middleware.OnewayOutbound {
+	return onewayOutboundChain(mw)
+}
+
+// CallOneway applies the middleware of the chain to the request
+func (c onewayOutboundChain) CallOneway(ctx context.Context, request *transport.Request, out transport.OnewayOutbound) (transport.Ack, error) {
+	for i := len(c) - 1; i >= 0; i-- {
+		out = middleware.ApplyOnewayOutbound(out, c[i])
+	}
+	return out.CallOneway(ctx, request)
+}
+
+// streamOutboundChain applies a list of stream outbound middleware, the
+// first one being the outermost
+type streamOutboundChain []middleware.StreamOutbound
+
+// StreamOutboundChain combines a list of stream outbound middleware into one
+func StreamOutboundChain(mw ...middleware.StreamOutbound) middleware.StreamOutbound {
+	return streamOutboundChain(mw)
+}
+
+// CallStream applies the middleware of the chain to the request
+func (c streamOutboundChain) CallStream(ctx context.Context, request *transport.StreamRequest, out transport.StreamOutbound) (*transport.ClientStream, error) {
+	for i := len(c) - 1; i >= 0; i-- {
+		out = middleware.ApplyStreamOutbound(out, c[i])
+	}
+	return out.CallStream(ctx, request)
+}
@@ -0,0 +1,237 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package middleware
+
+import (
+	"context"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/api/transport"
+	"go.uber.org/yarpc/api/transport/transporttest"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	testGetProcedure    = "peloton.api.v0.job.JobManager::Get"
+	testCreateProcedure = "peloton.api.v0.job.JobManager::Create"
+	testRequestBody     = "request-body"
+)
+
+type retryMiddlewareTestSuite struct {
+	suite.Suite
+	ctrl     *gomock.Controller
+	outbound *transporttest.MockUnaryOutbound
+}
+
+func (suite *retryMiddlewareTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.outbound = transporttest.NewMockUnaryOutbound(suite.ctrl)
+}
+
+func (suite *retryMiddlewareTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestRetryMiddleware(t *testing.T) {
+	suite.Run(t, new(retryMiddlewareTestSuite))
+}
+
+func (suite *retryMiddlewareTestSuite) newRequest(procedure string) *transport.Request {
+	return &transport.Request{
+		Procedure: procedure,
+		Body:      strings.NewReader(testRequestBody),
+	}
+}
+
+// expectCall expects a call which returns err, checking that every attempt
+// carries the whole request body
+func (suite *retryMiddlewareTestSuite) expectCall(err error) *gomock.Call {
+	var response *transport.Response
+	if err == nil {
+		response = &transport.Response{}
+	}
+	return suite.outbound.EXPECT().
+		Call(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, request *transport.Request) {
+			body, readErr := ioutil.ReadAll(request.Body)
+			suite.NoError(readErr)
+			suite.Equal(testRequestBody, string(body))
+		}).
+		Return(response, err)
+}
+
+// TestRetryIdempotent tests that idempotent requests are retried on
+// transient errors
+func (suite *retryMiddlewareTestSuite) TestRetryIdempotent() {
+	m := NewRetryOutboundMiddleware(RetryPolicy{
+		Retries: 2,
+		Backoff: time.Millisecond,
+	})
+
+	gomock.InOrder(
+		suite.expectCall(yarpcerrors.UnavailableErrorf("resmgr unavailable")),
+		suite.expectCall(yarpcerrors.DeadlineExceededErrorf("timeout")),
+		suite.expectCall(nil),
+	)
+	response, err := m.Call(
+		context.Background(), suite.newRequest(testGetProcedure), suite.outbound)
+	suite.NoError(err)
+	suite.NotNil(response)
+}
+
+// TestRetryExhausted tests that the last error is returned once all
+// retries failed
+func (suite *retryMiddlewareTestSuite) TestRetryExhausted() {
+	m := NewRetryOutboundMiddleware(RetryPolicy{
+		Retries: 2,
+		Backoff: time.Millisecond,
+	})
+
+	suite.expectCall(yarpcerrors.UnavailableErrorf("resmgr unavailable")).Times(3)
+	_, err := m.Call(
+		context.Background(), suite.newRequest(testGetProcedure), suite.outbound)
+	suite.True(yarpcerrors.IsUnavailable(err))
+}
+
+// TestNoRetryMutation tests that mutations are not retried by default
+func (suite *retryMiddlewareTestSuite) TestNoRetryMutation() {
+	m := NewRetryOutboundMiddleware(RetryPolicy{
+		Retries: 2,
+		Backoff: time.Millisecond,
+	})
+
+	suite.expectCall(yarpcerrors.UnavailableErrorf("resmgr unavailable"))
+	_, err := m.Call(
+		context.Background(), suite.newRequest(testCreateProcedure), suite.outbound)
+	suite.True(yarpcerrors.IsUnavailable(err))
+}
+
+// TestRetryMutation tests that mutations are retried if allowed
+func (suite *retryMiddlewareTestSuite) TestRetryMutation() {
+	m := NewRetryOutboundMiddleware(RetryPolicy{
+		Retries:        2,
+		Backoff:        time.Millisecond,
+		RetryMutations: true,
+	})
+
+	gomock.InOrder(
+		suite.expectCall(yarpcerrors.UnavailableErrorf("resmgr unavailable")),
+		suite.expectCall(nil),
+	)
+	_, err := m.Call(
+		context.Background(), suite.newRequest(testCreateProcedure), suite.outbound)
+	suite.NoError(err)
+}
+
+// TestNoRetryPermanentError tests that errors which are not transient are
+// not retried
+func (suite *retryMiddlewareTestSuite) TestNoRetryPermanentError() {
+	m := NewRetryOutboundMiddleware(RetryPolicy{
+		Retries: 2,
+		Backoff: time.Millisecond,
+	})
+
+	suite.expectCall(yarpcerrors.NotFoundErrorf("job not found"))
+	_, err := m.Call(
+		context.Background(), suite.newRequest(testGetProcedure), suite.outbound)
+	suite.True(yarpcerrors.IsNotFound(err))
+}
+
+// TestNoRetryCancelled tests that requests are not retried once their
+// context is done
+func (suite *retryMiddlewareTestSuite) TestNoRetryCancelled() {
+	m := NewRetryOutboundMiddleware(RetryPolicy{
+		Retries: 2,
+		Backoff: time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	suite.expectCall(yarpcerrors.UnavailableErrorf("resmgr unavailable")).
+		Do(func(context.Context, *transport.Request) { cancel() })
+	_, err := m.Call(ctx, suite.newRequest(testGetProcedure), suite.outbound)
+	suite.Error(err)
+}
+
+// TestTimeout tests that every attempt gets its own timeout
+func (suite *retryMiddlewareTestSuite) TestTimeout() {
+	m := NewRetryOutboundMiddleware(RetryPolicy{
+		Timeout: time.Minute,
+		Retries: 1,
+		Backoff: time.Millisecond,
+	})
+
+	checkDeadline := func(ctx context.Context, _ *transport.Request) {
+		deadline, ok := ctx.Deadline()
+		suite.True(ok)
+		suite.True(time.Until(deadline) > 50*time.Second)
+	}
+	gomock.InOrder(
+		suite.outbound.EXPECT().Call(gomock.Any(), gomock.Any()).
+			Do(checkDeadline).
+			Return(nil, yarpcerrors.DeadlineExceededErrorf("timeout")),
+		suite.outbound.EXPECT().Call(gomock.Any(), gomock.Any()).
+			Do(checkDeadline).
+			Return(&transport.Response{}, nil),
+	)
+	_, err := m.Call(
+		context.Background(), suite.newRequest(testGetProcedure), suite.outbound)
+	suite.NoError(err)
+}
+
+func (suite *retryMiddlewareTestSuite) TestIsIdempotent() {
+	suite.True(isIdempotent("peloton.api.v0.job.JobManager::Query"))
+	suite.True(isIdempotent("peloton.api.v0.respool.ResourceManager::LookupResourcePoolID"))
+	suite.True(isIdempotent("peloton.api.v0.task.TaskManager::BrowseSandbox"))
+	suite.False(isIdempotent("peloton.api.v0.task.TaskManager::Stop"))
+	suite.False(isIdempotent("peloton.api.v0.job.JobManager::Create"))
+}
+
+// TestUnaryOutboundChain tests that the retry middleware retries the whole
+// chain of inner middleware
+func (suite *retryMiddlewareTestSuite) TestUnaryOutboundChain() {
+	auth := NewBasicAuthOutboundMiddleware(&BasicAuthConfig{
+		Username: "user",
+		Password: "password",
+	})
+	chain := UnaryOutboundChain(
+		NewRetryOutboundMiddleware(RetryPolicy{
+			Retries: 1,
+			Backoff: time.Millisecond,
+		}),
+		auth,
+	)
+
+	checkAuth := func(_ context.Context, request *transport.Request) {
+		username, ok := request.Headers.Get(_usernameHeader)
+		suite.True(ok)
+		suite.Equal("user", username)
+	}
+	gomock.InOrder(
+		suite.outbound.EXPECT().Call(gomock.Any(), gomock.Any()).
+			Do(checkAuth).
+			Return(nil, yarpcerrors.UnavailableErrorf("resmgr unavailable")),
+		suite.outbound.EXPECT().Call(gomock.Any(), gomock.Any()).
+			Do(checkAuth).
+			Return(&transport.Response{}, nil),
+	)
+	_, err := chain.Call(
+		context.Background(), suite.newRequest(testGetProcedure), suite.outbound)
+	suite.NoError(err)
+}
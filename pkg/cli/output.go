@@ -15,21 +15,44 @@
 package cli
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"strings"
 	"text/tabwriter"
+	"text/template"
 
 	"github.com/gogo/protobuf/jsonpb"
 	"github.com/gogo/protobuf/proto"
+	"go.uber.org/yarpc/yarpcerrors"
 	"gopkg.in/yaml.v2"
 )
 
+// Output formats of the response printers
+const (
+	// OutputTable prints responses as human readable tables
+	OutputTable = "table"
+	// OutputJSON prints responses as canonical JSON
+	OutputJSON = "json"
+	// OutputYAML prints responses as YAML
+	OutputYAML = "yaml"
+	// OutputCSV prints the rows of query results as CSV with a header row
+	OutputCSV = "csv"
+	// OutputGoTemplatePrefix prefixes a Go template executed on responses,
+	// e.g. go-template={{.JobInfo.Runtime.State}}
+	OutputGoTemplatePrefix = "go-template="
+)
+
 var (
 	tabWriter = tabwriter.NewWriter(
 		os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight|tabwriter.Debug,
 	)
+
+	// warningOutput is where warnings are printed, so that they do not mix
+	// with the formatted output of commands
+	warningOutput io.Writer = os.Stderr
 )
 
 // used for testing
@@ -73,14 +96,125 @@ type outputter interface {
 
 type stdOutPutputter struct{}
 
-func (o stdOutPutputter) output(l string) { fmt.Printf(l) }
+func (o stdOutPutputter) output(l string) { fmt.Print(l) }
 func newStdOutOutputter() outputter       { return stdOutPutputter{} }
 
 var (
 	cliEncoder   = newJSONEncoderDecoder()
 	cliOutPutter = newStdOutOutputter()
+
+	// protoJSONMarshaler emits the canonical proto3 JSON mapping of a
+	// response, used when the client is in JSON mode.
+	protoJSONMarshaler = jsonpb.Marshaler{Indent: "  "}
 )
 
+// printResponseProtoJSON prints a pb message response as canonical JSON,
+// bypassing any table formatting.
+func printResponseProtoJSON(message proto.Message) error {
+	body, err := protoJSONMarshaler.MarshalToString(message)
+	if err != nil {
+		return fmt.Errorf("Failed to marshal response : %v", err)
+	}
+	cliOutPutter.output(fmt.Sprintf("%v\n", body))
+	return nil
+}
+
+// outputFormat returns the output format of the client, defaulting to a
+// table, or JSON in JSON mode.
+func (c *Client) outputFormat() string {
+	switch {
+	case c.Output != "":
+		return c.Output
+	case c.JSON:
+		return OutputJSON
+	default:
+		return OutputTable
+	}
+}
+
+// printFormatted prints a pb message response in the output format of the
+// client, calling table to print it in the table format.
+func (c *Client) printFormatted(
+	response proto.Message,
+	table func() error) error {
+	format := c.outputFormat()
+	switch {
+	case format == OutputTable:
+		return table()
+	case format == OutputJSON:
+		return printResponseProtoJSON(response)
+	case format == OutputYAML:
+		out, err := marshallResponse(defaultResponseFormat, response)
+		if err != nil {
+			return err
+		}
+		cliOutPutter.output(string(out))
+		return nil
+	case strings.HasPrefix(format, OutputGoTemplatePrefix):
+		return printResponseTemplate(
+			strings.TrimPrefix(format, OutputGoTemplatePrefix), response)
+	case format == OutputCSV:
+		return fmt.Errorf(
+			"Output format %s is only supported by job query, task list and host query",
+			format)
+	default:
+		return fmt.Errorf("Invalid output format %s", format)
+	}
+}
+
+// printResponseTemplate prints a response by executing the Go template
+// text on it
+func printResponseTemplate(text string, response interface{}) error {
+	tmpl, err := template.New("output").Parse(text)
+	if err != nil {
+		return fmt.Errorf("Failed to parse template %q: %v", text, err)
+	}
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, response); err != nil {
+		// the execution error names the field path which failed
+		return fmt.Errorf("Failed to execute template %q: %v", text, err)
+	}
+	cliOutPutter.output(buffer.String())
+	return nil
+}
+
+// jsonError is the object written for a failed command in JSON mode.
+type jsonError struct {
+	Code      string            `json:"code,omitempty"`
+	Error     string            `json:"error"`
+	Errors    []ValidationError `json:"errors,omitempty"`
+	RequestID string            `json:"requestId,omitempty"`
+}
+
+// PrintErrorJSON writes err to w as a single line JSON object, including the
+// RPC status code when err carries one, every problem of ValidationErrors
+// and the request ID of the invocation, so that wrappers can parse it.
+func PrintErrorJSON(w io.Writer, err error, requestID string) {
+	e := jsonError{Error: err.Error(), RequestID: requestID}
+	if yarpcerrors.IsStatus(err) {
+		status := yarpcerrors.FromError(err)
+		e.Code = status.Code().String()
+		e.Error = status.Message()
+	}
+	if v, ok := err.(*ValidationErrors); ok {
+		e.Errors = v.Errors
+	}
+	buffer, mErr := json.Marshal(e)
+	if mErr != nil {
+		fmt.Fprintf(w, "%v\n", err)
+		return
+	}
+	fmt.Fprintf(w, "%s\n", buffer)
+}
+
+// PrintRequestID writes the request ID of a failed invocation to w, to be
+// quoted in support requests
+func PrintRequestID(w io.Writer, requestID string) {
+	if requestID != "" {
+		fmt.Fprintf(w, "Request ID: %s\n", requestID)
+	}
+}
+
 func printResponseJSON(response interface{}) {
 	buffer, err := cliEncoder.MarshalIndent(response, "", "  ")
 	if err == nil {
@@ -15,6 +15,7 @@
 package cli
 
 import (
+	"bytes"
 	"github.com/pkg/errors"
 	"testing"
 
@@ -23,6 +24,7 @@ import (
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/yarpc/yarpcerrors"
 )
 
 var respose = &job.GetResponse{
@@ -125,3 +127,132 @@ func TestPrintResponseJSON(t *testing.T) {
 		"\"owningTeam\": \"test team\",\n      \"description\": \"test job\",\n"+
 		"      \"instanceCount\": 1\n    }\n  }\n}\n")
 }
+
+func TestPrintErrorJSON(t *testing.T) {
+	var buffer bytes.Buffer
+	PrintErrorJSON(&buffer, errors.New("fake error"), "")
+	assert.Equal(t, "{\"error\":\"fake error\"}\n", buffer.String())
+
+	buffer.Reset()
+	PrintErrorJSON(&buffer, yarpcerrors.NotFoundErrorf("job not found"), "")
+	assert.Equal(t,
+		"{\"code\":\"not-found\",\"error\":\"job not found\"}\n",
+		buffer.String())
+
+	// the request id of the invocation is included
+	buffer.Reset()
+	PrintErrorJSON(&buffer, errors.New("fake error"), "request-id")
+	assert.Equal(t,
+		"{\"error\":\"fake error\",\"requestId\":\"request-id\"}\n",
+		buffer.String())
+
+	// every problem of validation errors is listed
+	errs := newValidationErrors("host list")
+	errs.Add("token 1", "host cannot be empty")
+	errs.Add("", "more than 3 hosts")
+	buffer.Reset()
+	PrintErrorJSON(&buffer, errs, "")
+	assert.Equal(t,
+		"{\"error\":\"Invalid host list, 2 errors:\\n  token 1: host cannot be empty"+
+			"\\n  more than 3 hosts\","+
+			"\"errors\":[{\"position\":\"token 1\",\"message\":\"host cannot be empty\"},"+
+			"{\"message\":\"more than 3 hosts\"}]}\n",
+		buffer.String())
+}
+
+func TestPrintRequestID(t *testing.T) {
+	var buffer bytes.Buffer
+	PrintRequestID(&buffer, "request-id")
+	assert.Equal(t, "Request ID: request-id\n", buffer.String())
+
+	buffer.Reset()
+	PrintRequestID(&buffer, "")
+	assert.Empty(t, buffer.String())
+}
+
+func TestPrintFormatted(t *testing.T) {
+	cliEncoder = newJSONEncoderDecoder()
+	fo := &fakeOutputter{}
+	cliOutPutter = fo
+	defer func() { cliOutPutter = newStdOutOutputter() }()
+
+	tt := []struct {
+		json     bool
+		output   string
+		expected string
+		table    bool
+		err      string
+		field    string
+	}{
+		{
+			output: OutputTable,
+			table:  true,
+		},
+		{
+			// table is the default
+			table: true,
+		},
+		{
+			output: OutputJSON,
+			expected: "{\n  \"jobInfo\": {\n    \"id\": {\n" +
+				"      \"value\": \"481d565e-28da-457d-8434-f6bb7faa0e95\"\n" +
+				"    },\n    \"config\": {\n      \"name\": \"test job\",\n" +
+				"      \"owningTeam\": \"test team\",\n" +
+				"      \"description\": \"test job\",\n" +
+				"      \"instanceCount\": 1\n    }\n  }\n}\n",
+		},
+		{
+			// JSON mode defaults to JSON
+			json: true,
+			expected: "{\n  \"jobInfo\": {\n    \"id\": {\n" +
+				"      \"value\": \"481d565e-28da-457d-8434-f6bb7faa0e95\"\n" +
+				"    },\n    \"config\": {\n      \"name\": \"test job\",\n" +
+				"      \"owningTeam\": \"test team\",\n" +
+				"      \"description\": \"test job\",\n" +
+				"      \"instanceCount\": 1\n    }\n  }\n}\n",
+		},
+		{
+			output: OutputYAML,
+			expected: "jobInfo:\n  config:\n    description: test job\n    " +
+				"instanceCount: 1\n    name: test job\n    owner: \"\"\n    " +
+				"owningTeam: test team\n    type: BATCH\n  id:\n    " +
+				"value: 481d565e-28da-457d-8434-f6bb7faa0e95\n",
+		},
+		{
+			output:   "go-template={{.JobInfo.Config.Name}}/{{.JobInfo.Id.Value}}",
+			expected: "test job/481d565e-28da-457d-8434-f6bb7faa0e95",
+		},
+		{
+			output: "go-template={{.JobInfo.Config.Name",
+			err:    `Failed to parse template "{{.JobInfo.Config.Name"`,
+		},
+		{
+			output: "go-template={{.JobInfo.Config.Foo}}",
+			err:    `Failed to execute template "{{.JobInfo.Config.Foo}}": `,
+			field:  "<.JobInfo.Config.Foo>",
+		},
+		{
+			output: "xml",
+			err:    "Invalid output format xml",
+		},
+	}
+
+	for _, test := range tt {
+		fo.Out = ""
+		c := Client{JSON: test.json, Output: test.output}
+		table := false
+		err := c.printFormatted(respose, func() error {
+			table = true
+			return nil
+		})
+		if test.err != "" {
+			assert.Error(t, err, test.output)
+			assert.Contains(t, err.Error(), test.err, test.output)
+			assert.Contains(t, err.Error(), test.field, test.output)
+			continue
+		}
+		assert.NoError(t, err, test.output)
+		assert.Equal(t, test.table, table, test.output)
+		assert.Equal(t, test.expected, fo.Out, test.output)
+	}
+}
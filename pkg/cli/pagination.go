@@ -0,0 +1,103 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+const (
+	// queryAllPageSize is the page size used to fetch all query results
+	// if no limit is given
+	queryAllPageSize = 100
+
+	// queryAllMaxResults caps the number of results fetched for a query
+	// of all results
+	queryAllMaxResults = 10000
+)
+
+// used for testing
+var progressOutput io.Writer = os.Stderr
+
+// TruncatedResultsError is returned by fetchAllPages when it stops after
+// queryAllMaxResults results while more results may remain
+type TruncatedResultsError struct {
+	Entity  string
+	Fetched int
+}
+
+// Error implements error.Error
+func (e *TruncatedResultsError) Error() string {
+	return fmt.Sprintf("stopped after fetching %d %s, more %s may remain",
+		e.Fetched, e.Entity, e.Entity)
+}
+
+// IsTruncatedResults returns whether the error is a TruncatedResultsError
+func IsTruncatedResults(err error) bool {
+	_, ok := err.(*TruncatedResultsError)
+	return ok
+}
+
+// warnIfTruncated prints a warning and returns nil if err is a
+// TruncatedResultsError, for callers which can use the fetched results
+// even if they are incomplete. Other errors are returned as is.
+func warnIfTruncated(err error) error {
+	if !IsTruncatedResults(err) {
+		return err
+	}
+	fmt.Fprintf(warningOutput, "Warning: %v\n", err)
+	return nil
+}
+
+// fetchAllPages calls fetch with increasing offsets until it returns fewer
+// than pageSize results or queryAllMaxResults results have been fetched.
+// fetch returns the number of results in the page at offset, and whether
+// paging should stop early, e.g. because the response carries an error.
+// The number of fetched results is reported on stderr for large result sets.
+// A TruncatedResultsError is returned if paging stops at
+// queryAllMaxResults, the results fetched until then are still valid and
+// it is up to the caller to decide whether they are enough.
+func fetchAllPages(
+	entity string,
+	pageSize uint32,
+	fetch func(offset, limit uint32) (int, bool, error)) error {
+	if pageSize == 0 {
+		pageSize = queryAllPageSize
+	}
+
+	fetched := 0
+	defer func() {
+		if fetched > int(pageSize) {
+			fmt.Fprintln(progressOutput)
+		}
+	}()
+
+	for {
+		count, stop, err := fetch(uint32(fetched), pageSize)
+		if err != nil {
+			return err
+		}
+		fetched += count
+		if stop || count < int(pageSize) {
+			return nil
+		}
+		if fetched >= queryAllMaxResults {
+			return &TruncatedResultsError{Entity: entity, Fetched: fetched}
+		}
+		fmt.Fprintf(progressOutput, "\rFetched %d %s", fetched, entity)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFetchAllPagesTruncated(t *testing.T) {
+	progressOutput = ioutil.Discard
+	defer func() { progressOutput = os.Stderr }()
+
+	calls := 0
+	err := fetchAllPages("jobs", 1000, func(offset, limit uint32) (int, bool, error) {
+		calls++
+		return int(limit), false, nil
+	})
+	assert.True(t, IsTruncatedResults(err))
+	assert.EqualError(t, err,
+		"stopped after fetching 10000 jobs, more jobs may remain")
+	assert.Equal(t, queryAllMaxResults/1000, calls)
+
+	// a short page is the last one
+	err = fetchAllPages("jobs", 1000, func(offset, limit uint32) (int, bool, error) {
+		return 10, false, nil
+	})
+	assert.NoError(t, err)
+	assert.False(t, IsTruncatedResults(errors.New("failed to query")))
+}
+
+func TestWarnIfTruncated(t *testing.T) {
+	var warnings bytes.Buffer
+	warningOutput = &warnings
+	defer func() { warningOutput = os.Stderr }()
+
+	assert.NoError(t, warnIfTruncated(nil))
+	assert.Empty(t, warnings.String())
+
+	assert.NoError(t, warnIfTruncated(
+		&TruncatedResultsError{Entity: "tasks", Fetched: 10000}))
+	assert.Contains(t, warnings.String(), "stopped after fetching 10000 tasks")
+
+	assert.EqualError(t, warnIfTruncated(errors.New("failed to query")),
+		"failed to query")
+}
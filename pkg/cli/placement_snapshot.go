@@ -0,0 +1,78 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"time"
+
+	mimir_strategy "github.com/uber/peloton/pkg/placement/plugins/mimir"
+)
+
+var (
+	// used for testing
+	placementSnapshotOutput     io.Writer = os.Stdout
+	placementSnapshotHTTPClient           = &http.Client{}
+)
+
+// PlacementSnapshotAction exports the snapshot of the mimir groups of the
+// placement engine at address, the host:port of its http server, to the
+// output file, or to stdout if the output file is "-".
+func PlacementSnapshotAction(
+	address string,
+	outputFile string,
+	timeout time.Duration) error {
+	client := *placementSnapshotHTTPClient
+	client.Timeout = timeout
+	resp, err := client.Get(
+		fmt.Sprintf("http://%s%s", address, mimir_strategy.SnapshotPath))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unable to export the snapshot of placement engine %s: %s %s",
+			address, resp.Status, bytes.TrimSpace(body))
+	}
+
+	// check the snapshot can be loaded before writing it
+	snapshot := &mimir_strategy.Snapshot{}
+	if err := json.Unmarshal(body, snapshot); err != nil {
+		return fmt.Errorf("invalid snapshot from placement engine %s: %v",
+			address, err)
+	}
+
+	if outputFile == "-" {
+		_, err = placementSnapshotOutput.Write(body)
+		return err
+	}
+	if err := ioutil.WriteFile(outputFile, body, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(placementSnapshotOutput, "Wrote the snapshot of %d hosts to %s\n",
+		len(snapshot.Groups), outputFile)
+	return nil
+}
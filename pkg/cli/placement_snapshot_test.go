@@ -0,0 +1,105 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	mimir_strategy "github.com/uber/peloton/pkg/placement/plugins/mimir"
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/labels"
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/placement"
+)
+
+func newFakePlacementEngine(t *testing.T) *httptest.Server {
+	group := placement.NewGroup("host1")
+	group.Labels.Add(labels.NewLabel("rack", "a1"))
+	group.Metrics.Set(mimir_strategy.CPUAvailable, 400)
+	body, err := json.Marshal(
+		mimir_strategy.ExportGroups([]*placement.Group{group}))
+	require.NoError(t, err)
+
+	return httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != mimir_strategy.SnapshotPath {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(body)
+		}))
+}
+
+func TestPlacementSnapshotActionWritesFile(t *testing.T) {
+	server := newFakePlacementEngine(t)
+	defer server.Close()
+	dir, err := ioutil.TempDir("", "placement-snapshot")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	output := &bytes.Buffer{}
+	placementSnapshotOutput = output
+	defer func() { placementSnapshotOutput = os.Stdout }()
+
+	path := filepath.Join(dir, "snapshot.json")
+	require.NoError(t, PlacementSnapshotAction(
+		strings.TrimPrefix(server.URL, "http://"), path, time.Second))
+	assert.Contains(t, output.String(), "snapshot of 1 hosts")
+
+	content, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	snapshot := &mimir_strategy.Snapshot{}
+	require.NoError(t, json.Unmarshal(content, snapshot))
+	groups := mimir_strategy.LoadGroups(snapshot)
+	require.Equal(t, 1, len(groups))
+	assert.Equal(t, "host1", groups[0].Name)
+	assert.Equal(t, 1, groups[0].Labels.Count(labels.NewLabel("rack", "a1")))
+	assert.Equal(t, 400.0, groups[0].Metrics.Get(mimir_strategy.CPUAvailable))
+}
+
+func TestPlacementSnapshotActionStdout(t *testing.T) {
+	server := newFakePlacementEngine(t)
+	defer server.Close()
+
+	output := &bytes.Buffer{}
+	placementSnapshotOutput = output
+	defer func() { placementSnapshotOutput = os.Stdout }()
+
+	require.NoError(t, PlacementSnapshotAction(
+		strings.TrimPrefix(server.URL, "http://"), "-", time.Second))
+	snapshot := &mimir_strategy.Snapshot{}
+	require.NoError(t, json.Unmarshal(output.Bytes(), snapshot))
+	assert.Equal(t, 1, len(snapshot.Groups))
+}
+
+func TestPlacementSnapshotActionError(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	err := PlacementSnapshotAction(
+		strings.TrimPrefix(server.URL, "http://"), "-", time.Second)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "404")
+}
@@ -0,0 +1,371 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Formats of the progress of the long running commands
+const (
+	// ProgressFormatText reports the progress as a progress bar on stderr
+	ProgressFormatText = "text"
+	// ProgressFormatJSON reports the progress as a JSON progress event per
+	// line on stderr, for the tools wrapping the CLI
+	ProgressFormatJSON = "json"
+)
+
+const (
+	// progressEventVersion is the version of the schema of the progress
+	// events, bumped on incompatible changes
+	progressEventVersion = 1
+
+	// progressPhaseDone is the phase of the last progress event of an
+	// operation
+	progressPhaseDone = "done"
+
+	// progressSpinner are the frames of the spinner of a progress bar on a
+	// terminal
+	progressSpinner = `|/-\`
+
+	// clearLine moves the cursor to the start of the line and clears it
+	clearLine = "\r\033[K"
+)
+
+var (
+	// used for testing
+	progressIsTerminal = stderrIsTerminal
+
+	// progressTerminalInterval is the interval between two renderings of a
+	// progress bar on a terminal, which animate the spinner
+	progressTerminalInterval = 100 * time.Millisecond
+	// progressPlainInterval is the interval between two progress lines if
+	// stderr is not a terminal
+	progressPlainInterval = 10 * time.Second
+
+	// progressNow returns the time of the progress events
+	progressNow = time.Now
+
+	// progressEventLock serializes the writes of the progress events, so
+	// that they are not interleaved and in order of time
+	progressEventLock sync.Mutex
+)
+
+// progressEvent is a progress event of a long running operation, written
+// as a JSON line on stderr with the json progress format
+type progressEvent struct {
+	// V is the version of the schema of the event
+	V int `json:"v"`
+	// Phase is the phase of the operation, e.g. waiting, the last event of
+	// an operation has the phase done
+	Phase     string    `json:"phase"`
+	Completed int64     `json:"completed"`
+	Total     int64     `json:"total"`
+	Message   string    `json:"message"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// writeProgressEvent writes a progress event as a JSON line on stderr
+func writeProgressEvent(phase string, completed, total int64, message string) {
+	progressEventLock.Lock()
+	defer progressEventLock.Unlock()
+	body, err := json.Marshal(progressEvent{
+		V:         progressEventVersion,
+		Phase:     phase,
+		Completed: completed,
+		Total:     total,
+		Message:   message,
+		Timestamp: progressNow().UTC(),
+	})
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(progressOutput, "%s\n", body)
+}
+
+// progressBar reports the progress of a long running operation as
+// completed/total on stderr, so that it does not mix with the output of the
+// command. On a terminal the line is redrawn with a spinner, otherwise a
+// plain line is printed periodically. With the json progress format a
+// progress event is written instead every time the counts change. The
+// counts may be updated concurrently.
+type progressBar struct {
+	description string
+	completed   int64
+	total       int64
+	terminal    bool
+	// phase is the phase of the progress events if json is set
+	phase string
+	json  bool
+	// emitted is whether a progress event was written, with the counts of
+	// the last one in lastCompleted and lastTotal
+	emitted       bool
+	lastCompleted int64
+	lastTotal     int64
+	// rate returns the rate of the requests of the operation, printed
+	// with the counts if it is set
+	rate func() float64
+
+	// lock serializes the writes to the progress output
+	lock  sync.Mutex
+	frame int
+
+	stop     chan struct{}
+	stopped  chan struct{}
+	finished sync.Once
+}
+
+// newProgressBar returns a progress bar of an operation on total items and
+// starts rendering it until Finish is called
+func newProgressBar(description string, total int) *progressBar {
+	p := &progressBar{
+		description: description,
+		total:       int64(total),
+		terminal:    progressIsTerminal(),
+		stop:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+
+	interval := progressPlainInterval
+	if p.terminal {
+		interval = progressTerminalInterval
+	}
+	go p.run(interval)
+	return p
+}
+
+// newJSONProgressBar returns a progress bar of an operation on total items
+// which writes a progress event of phase every time the counts change
+// until Finish is called
+func newJSONProgressBar(phase string, description string, total int) *progressBar {
+	p := &progressBar{
+		description: description,
+		total:       int64(total),
+		phase:       phase,
+		json:        true,
+		stop:        make(chan struct{}),
+		stopped:     make(chan struct{}),
+	}
+	close(p.stopped)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.emit()
+	return p
+}
+
+// startProgress returns a progress bar of an operation on total items in
+// the progress format of the client, with phase as the phase of the
+// progress events
+func (c *Client) startProgress(phase string, description string, total int) *progressBar {
+	if c.ProgressFormat == ProgressFormatJSON {
+		return newJSONProgressBar(phase, description, total)
+	}
+	return newProgressBar(description, total)
+}
+
+// run renders the progress bar every interval until it is stopped
+func (p *progressBar) run(interval time.Duration) {
+	defer close(p.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-p.stop:
+			return
+		case <-ticker.C:
+			p.lock.Lock()
+			p.render()
+			p.lock.Unlock()
+		}
+	}
+}
+
+// Increment marks one more item as completed
+func (p *progressBar) Increment() {
+	atomic.AddInt64(&p.completed, 1)
+	p.changed()
+}
+
+// SetCompleted sets the number of completed items
+func (p *progressBar) SetCompleted(completed int) {
+	atomic.StoreInt64(&p.completed, int64(completed))
+	p.changed()
+}
+
+// SetTotal sets the total number of items
+func (p *progressBar) SetTotal(total int) {
+	atomic.StoreInt64(&p.total, int64(total))
+	p.changed()
+}
+
+// SetCounts sets the number of completed items and the total number of
+// items at once
+func (p *progressBar) SetCounts(completed int, total int) {
+	atomic.StoreInt64(&p.completed, int64(completed))
+	atomic.StoreInt64(&p.total, int64(total))
+	p.changed()
+}
+
+// changed writes a progress event if the counts changed since the last one
+// with the json progress format
+func (p *progressBar) changed() {
+	if !p.json {
+		return
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if completed, total := p.counts(); !p.emitted ||
+		completed != p.lastCompleted || total != p.lastTotal {
+		p.emit()
+	}
+}
+
+// emit writes a progress event with the current counts, the caller holds
+// the lock
+func (p *progressBar) emit() {
+	p.emitted = true
+	p.lastCompleted, p.lastTotal = p.counts()
+	writeProgressEvent(p.phase, p.lastCompleted, p.lastTotal, p.line())
+}
+
+// ShowRate prints the rate of the requests of the operation returned by
+// rate with the counts. It must be called before the progress changes.
+func (p *progressBar) ShowRate(rate func() float64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.rate = rate
+}
+
+// counts returns the number of completed items and the total
+func (p *progressBar) counts() (int64, int64) {
+	return atomic.LoadInt64(&p.completed), atomic.LoadInt64(&p.total)
+}
+
+// render writes the progress, the caller holds the lock
+func (p *progressBar) render() {
+	if !p.terminal {
+		fmt.Fprintf(progressOutput, "%s\n", p.line())
+		return
+	}
+	fmt.Fprintf(progressOutput, "%s%c %s", clearLine,
+		progressSpinner[p.frame%len(progressSpinner)], p.line())
+	p.frame++
+}
+
+// line returns the description and counts of the progress, and the rate of
+// the requests if it is shown, the caller holds the lock
+func (p *progressBar) line() string {
+	completed, total := p.counts()
+	line := fmt.Sprintf("%s %d/%d", p.description, completed, total)
+	if p.rate != nil {
+		line += fmt.Sprintf(" (%.1f req/s)", p.rate())
+	}
+	return line
+}
+
+// Print calls print, which writes the output of the command, with the
+// progress line cleared on a terminal so that they do not mix
+func (p *progressBar) Print(print func()) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	if p.terminal && !p.json {
+		fmt.Fprint(progressOutput, clearLine)
+	}
+	print()
+	if p.terminal && !p.json {
+		p.render()
+	}
+}
+
+// Finish stops rendering the progress bar and prints the final counts, or
+// writes the progress event of the done phase with the json progress format
+func (p *progressBar) Finish() {
+	p.finished.Do(func() {
+		close(p.stop)
+		<-p.stopped
+
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		if p.json {
+			completed, total := p.counts()
+			writeProgressEvent(progressPhaseDone, completed, total, p.line())
+			return
+		}
+		if p.terminal {
+			fmt.Fprint(progressOutput, clearLine)
+		}
+		fmt.Fprintf(progressOutput, "%s\n", p.line())
+	})
+}
+
+// progressSteps reports the steps of a long running operation on items
+// which prints a line per step, e.g. a host drain: as lines of the output
+// of the command with the text progress format, or as progress events on
+// stderr with the json progress format. It is not safe for concurrent use.
+type progressSteps struct {
+	json      bool
+	completed int64
+	total     int64
+}
+
+// startProgressSteps returns the progress steps of an operation on total
+// items in the progress format of the client
+func (c *Client) startProgressSteps(total int) *progressSteps {
+	return &progressSteps{
+		json:  c.ProgressFormat == ProgressFormatJSON,
+		total: int64(total),
+	}
+}
+
+// Complete marks one more item as completed
+func (s *progressSteps) Complete() {
+	s.completed++
+}
+
+// Report reports a step of a phase of the operation, with a message
+// formatted according to a format specifier
+func (s *progressSteps) Report(phase string, format string, args ...interface{}) {
+	message := fmt.Sprintf(format, args...)
+	if s.json {
+		writeProgressEvent(phase, s.completed, s.total, message)
+		return
+	}
+	fmt.Fprintln(tabWriter, message)
+}
+
+// Finish writes the progress event of the done phase with the json
+// progress format, the text format prints the result of the operation
+// instead
+func (s *progressSteps) Finish(format string, args ...interface{}) {
+	if s.json {
+		writeProgressEvent(progressPhaseDone, s.completed, s.total,
+			fmt.Sprintf(format, args...))
+	}
+}
+
+// stderrIsTerminal returns whether stderr is a terminal
+func stderrIsTerminal() bool {
+	info, err := os.Stderr.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
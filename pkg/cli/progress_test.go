@@ -0,0 +1,316 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type progressTestSuite struct {
+	suite.Suite
+	output                      *bytes.Buffer
+	oldProgressOutput           io.Writer
+	oldProgressIsTerminal       func() bool
+	oldProgressTerminalInterval time.Duration
+	oldProgressPlainInterval    time.Duration
+	oldProgressNow              func() time.Time
+}
+
+func (suite *progressTestSuite) SetupTest() {
+	suite.output = &bytes.Buffer{}
+	suite.oldProgressOutput = progressOutput
+	suite.oldProgressIsTerminal = progressIsTerminal
+	suite.oldProgressTerminalInterval = progressTerminalInterval
+	suite.oldProgressPlainInterval = progressPlainInterval
+	suite.oldProgressNow = progressNow
+	progressOutput = suite.output
+}
+
+func (suite *progressTestSuite) TearDownTest() {
+	progressOutput = suite.oldProgressOutput
+	progressIsTerminal = suite.oldProgressIsTerminal
+	progressTerminalInterval = suite.oldProgressTerminalInterval
+	progressPlainInterval = suite.oldProgressPlainInterval
+	progressNow = suite.oldProgressNow
+}
+
+func TestProgress(t *testing.T) {
+	suite.Run(t, new(progressTestSuite))
+}
+
+// setTerminal sets whether the progress output is a terminal
+func (suite *progressTestSuite) setTerminal(terminal bool) {
+	progressIsTerminal = func() bool { return terminal }
+}
+
+// TestProgressConcurrentIncrement tests that increments from many
+// goroutines are all counted
+func (suite *progressTestSuite) TestProgressConcurrentIncrement() {
+	suite.setTerminal(false)
+	progressPlainInterval = time.Hour
+
+	p := newProgressBar("Stopping jobs", 100)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				p.Increment()
+			}
+		}()
+	}
+	wg.Wait()
+	p.Finish()
+	suite.Equal("Stopping jobs 100/100\n", suite.output.String())
+
+	// finishing again prints nothing
+	p.Finish()
+	suite.Equal("Stopping jobs 100/100\n", suite.output.String())
+}
+
+// TestProgressPlain tests that without a terminal the progress is printed
+// as plain lines
+func (suite *progressTestSuite) TestProgressPlain() {
+	suite.setTerminal(false)
+	progressPlainInterval = time.Millisecond
+
+	p := newProgressBar("Hosts down", 4)
+	p.SetCompleted(1)
+	rendered := func() bool {
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		return strings.Contains(suite.output.String(), "Hosts down 1/4\n")
+	}
+	for deadline := time.Now().Add(time.Second); !rendered(); {
+		suite.Require().True(time.Now().Before(deadline), "progress not rendered")
+		time.Sleep(time.Millisecond)
+	}
+	p.Print(func() { fmt.Fprint(progressOutput, "output\n") })
+	p.SetTotal(5)
+	p.SetCompleted(5)
+	p.Finish()
+
+	suite.NotContains(suite.output.String(), clearLine)
+	suite.Contains(suite.output.String(), "output\n")
+	suite.True(strings.HasSuffix(suite.output.String(), "Hosts down 5/5\n"))
+}
+
+// TestProgressTerminal tests that on a terminal the progress line is
+// redrawn with a spinner and cleared around the output of the command
+func (suite *progressTestSuite) TestProgressTerminal() {
+	suite.setTerminal(true)
+	progressTerminalInterval = time.Hour
+
+	p := newProgressBar("Stopping jobs", 2)
+	p.lock.Lock()
+	p.render()
+	p.render()
+	p.lock.Unlock()
+	suite.Equal(
+		clearLine+"| Stopping jobs 0/2"+clearLine+"/ Stopping jobs 0/2",
+		suite.output.String())
+
+	suite.output.Reset()
+	p.Increment()
+	p.Print(func() { fmt.Fprint(progressOutput, "Job stopped\n") })
+	suite.Equal(
+		clearLine+"Job stopped\n"+clearLine+"- Stopping jobs 1/2",
+		suite.output.String())
+
+	suite.output.Reset()
+	p.Increment()
+	p.Finish()
+	suite.Equal(clearLine+"Stopping jobs 2/2\n", suite.output.String())
+}
+
+// TestProgressRate tests that the rate of the requests is printed with the
+// counts once it is shown
+func (suite *progressTestSuite) TestProgressRate() {
+	suite.setTerminal(false)
+	progressPlainInterval = time.Hour
+
+	p := newProgressBar("Stopping jobs", 2)
+	p.ShowRate(func() float64 { return 12.34 })
+	p.Increment()
+	p.Increment()
+	p.Finish()
+	suite.Equal("Stopping jobs 2/2 (12.3 req/s)\n", suite.output.String())
+}
+
+// progressEventFields are the fields of the version 1 progress events
+var progressEventFields = []string{
+	"completed", "message", "phase", "timestamp", "total", "v"}
+
+// parseProgressEvents parses the progress events written on stderr,
+// checking that every line is an event of the current schema, that the
+// events are in order of time, and that the operation ended with the done
+// phase
+func parseProgressEvents(output string) ([]progressEvent, error) {
+	var events []progressEvent
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(line, &fields); err != nil {
+			return nil, fmt.Errorf("invalid event %s: %v", line, err)
+		}
+		var names []string
+		for name := range fields {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		if strings.Join(names, ",") != strings.Join(progressEventFields, ",") {
+			return nil, fmt.Errorf("invalid fields of event %s", line)
+		}
+
+		var e progressEvent
+		if err := json.Unmarshal(line, &e); err != nil {
+			return nil, fmt.Errorf("invalid event %s: %v", line, err)
+		}
+		switch {
+		case e.V != progressEventVersion:
+			return nil, fmt.Errorf("invalid version of event %s", line)
+		case e.Phase == "" || e.Message == "":
+			return nil, fmt.Errorf("missing phase or message in event %s", line)
+		case e.Completed < 0 || e.Completed > e.Total:
+			return nil, fmt.Errorf("invalid counts of event %s", line)
+		case e.Timestamp.IsZero():
+			return nil, fmt.Errorf("missing timestamp in event %s", line)
+		case len(events) > 0 &&
+			e.Timestamp.Before(events[len(events)-1].Timestamp):
+			return nil, fmt.Errorf("event %s out of order", line)
+		case len(events) > 0 &&
+			events[len(events)-1].Phase == progressPhaseDone:
+			return nil, fmt.Errorf("event %s after the done phase", line)
+		}
+		events = append(events, e)
+	}
+	if len(events) == 0 || events[len(events)-1].Phase != progressPhaseDone {
+		return nil, fmt.Errorf("no event of the done phase in %q", output)
+	}
+	return events, nil
+}
+
+// progressPhases returns the phases and counts of progress events
+func progressPhases(events []progressEvent) []string {
+	var phases []string
+	for _, e := range events {
+		phases = append(phases,
+			fmt.Sprintf("%s %d/%d", e.Phase, e.Completed, e.Total))
+	}
+	return phases
+}
+
+// setProgressClock makes the progress events one second apart
+func (suite *progressTestSuite) setProgressClock() {
+	now := time.Date(2019, 3, 1, 10, 0, 0, 0, time.UTC)
+	progressNow = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+}
+
+// TestProgressJSON tests the progress events of a simulated operation in
+// multiple phases, reported by a progress bar and by progress steps
+func (suite *progressTestSuite) TestProgressJSON() {
+	suite.setTerminal(true)
+	suite.setProgressClock()
+	c := &Client{ProgressFormat: ProgressFormatJSON}
+
+	steps := c.startProgressSteps(2)
+	steps.Report("starting", "Started draining host %s", "host-1")
+	steps.Report("starting", "Started draining host %s", "host-2")
+
+	p := c.startProgress("draining", "Hosts drained", 2)
+	p.SetTotal(2)
+	p.Increment()
+	p.Print(func() { fmt.Fprint(progressOutput, "") })
+	p.SetCounts(2, 2)
+	p.Finish()
+	p.Finish()
+
+	events, err := parseProgressEvents(suite.output.String())
+	suite.Require().NoError(err, suite.output.String())
+	suite.Equal([]string{
+		"starting 0/2",
+		"starting 0/2",
+		"draining 0/2",
+		"draining 1/2",
+		"draining 2/2",
+		"done 2/2",
+	}, progressPhases(events))
+	suite.Equal("Started draining host host-1", events[0].Message)
+	suite.Equal("Hosts drained 1/2", events[3].Message)
+	suite.Equal(time.Date(2019, 3, 1, 10, 0, 1, 0, time.UTC), events[0].Timestamp)
+	suite.NotContains(suite.output.String(), clearLine)
+}
+
+// TestProgressStepsText tests that the progress steps are printed as lines
+// of the output with the text progress format
+func (suite *progressTestSuite) TestProgressStepsText() {
+	var table bytes.Buffer
+	oldTabWriter := tabWriter
+	tabWriter = tabwriter.NewWriter(&table, 0, 0, 1, ' ', 0)
+	defer func() { tabWriter = oldTabWriter }()
+
+	steps := (&Client{}).startProgressSteps(1)
+	steps.Report("starting", "Started draining host %s", "host-1")
+	steps.Complete()
+	steps.Finish("Drained %d of %d host(s)", 1, 1)
+	tabWriter.Flush()
+
+	suite.Equal("Started draining host host-1\n", table.String())
+	suite.Empty(suite.output.String())
+}
+
+// TestProgressJSONConcurrentIncrement tests that the progress events of
+// concurrent increments are in order
+func (suite *progressTestSuite) TestProgressJSONConcurrentIncrement() {
+	p := newJSONProgressBar("stopping", "Stopping jobs", 100)
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 10; j++ {
+				p.Increment()
+			}
+		}()
+	}
+	wg.Wait()
+	p.Finish()
+
+	events, err := parseProgressEvents(suite.output.String())
+	suite.Require().NoError(err)
+	for i := 1; i < len(events); i++ {
+		suite.True(events[i-1].Completed <= events[i].Completed)
+	}
+	last := events[len(events)-1]
+	suite.Equal(int64(100), last.Completed)
+	suite.Equal("Stopping jobs 100/100", last.Message)
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"sync"
+	"time"
+)
+
+// DefaultMaxRPS is the default maximum rate of the mutating requests of the
+// bulk commands, in requests per second
+const DefaultMaxRPS = 20
+
+// rateLimiter is a token bucket limiting the rate of the mutating requests
+// of the bulk commands, shared by all their workers. A token is added every
+// 1/rate second, up to a single token so that requests are evenly spaced.
+// A nil rateLimiter does not limit the rate.
+type rateLimiter struct {
+	sync.Mutex
+
+	rate float64
+	// tokens is the number of tokens of the bucket, negative if waiting
+	// requests reserved the tokens to come
+	tokens float64
+	// last is the last time tokens were added
+	last time.Time
+
+	// issued is the number of requests issued, the first one at first and
+	// the last one at latest
+	issued int64
+	first  time.Time
+	latest time.Time
+
+	// now and after are overridden in tests
+	now   func() time.Time
+	after func(d time.Duration) <-chan time.Time
+}
+
+// newRateLimiter returns a rate limiter of maxRPS requests per second, nil
+// if maxRPS is not positive
+func newRateLimiter(maxRPS float64) *rateLimiter {
+	if maxRPS <= 0 {
+		return nil
+	}
+	l := &rateLimiter{
+		rate:   maxRPS,
+		tokens: 1,
+		now:    time.Now,
+		after:  time.After,
+	}
+	l.last = l.now()
+	return l
+}
+
+// Wait waits until a request may be issued, or until ctx is done in which
+// case it returns the error of ctx
+func (l *rateLimiter) Wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if l == nil {
+		return nil
+	}
+
+	l.Lock()
+	now := l.now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > 1 {
+		l.tokens = 1
+	}
+	l.last = now
+	// reserve a token, waiting for it if the bucket is empty
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.rate * float64(time.Second))
+	l.Unlock()
+
+	if delay > 0 {
+		select {
+		case <-ctx.Done():
+			// give back the reserved token
+			l.Lock()
+			l.tokens++
+			l.Unlock()
+			return ctx.Err()
+		case <-l.after(delay):
+		}
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	l.latest = l.now()
+	if l.issued == 0 {
+		l.first = l.latest
+	}
+	l.issued++
+	return nil
+}
+
+// EffectiveRate returns the rate of the requests issued between the first
+// and the latest one, in requests per second, 0 until two were issued
+func (l *rateLimiter) EffectiveRate() float64 {
+	if l == nil {
+		return 0
+	}
+	l.Lock()
+	defer l.Unlock()
+	elapsed := l.latest.Sub(l.first).Seconds()
+	if l.issued < 2 || elapsed <= 0 {
+		return 0
+	}
+	return float64(l.issued-1) / elapsed
+}
+
+// SetMaxRPS limits the mutating requests of the bulk commands of the
+// client to maxRPS requests per second, they are not limited if it is not
+// positive
+func (c *Client) SetMaxRPS(maxRPS float64) {
+	c.limiter = newRateLimiter(maxRPS)
+}
+
+// withInterrupt returns a context canceled when the user interrupts the
+// command with Ctrl-C, so that a bulk command stops issuing requests and
+// reports what it did. A second Ctrl-C terminates the command. The returned
+// function releases the context.
+func withInterrupt(parent context.Context) (context.Context, func()) {
+	ctx, cancel := context.WithCancel(parent)
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	go func() {
+		select {
+		case <-interrupt:
+			signal.Stop(interrupt)
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		signal.Stop(interrupt)
+		cancel()
+	}
+}
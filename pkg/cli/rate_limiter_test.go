@@ -0,0 +1,201 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeClock is the clock of a rate limiter under test, waiting advances it
+// right away unless it is blocked
+type fakeClock struct {
+	sync.Mutex
+	now     time.Time
+	blocked bool
+	// waits are the durations waited, waiting is notified of every wait
+	waits   []time.Duration
+	waiting chan time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.Lock()
+	defer c.Unlock()
+	c.waits = append(c.waits, d)
+	if c.waiting != nil {
+		c.waiting <- d
+	}
+	ch := make(chan time.Time, 1)
+	if !c.blocked {
+		c.now = c.now.Add(d)
+		ch <- c.now
+	}
+	return ch
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = c.now.Add(d)
+}
+
+type rateLimiterTestSuite struct {
+	suite.Suite
+	clock *fakeClock
+}
+
+func (suite *rateLimiterTestSuite) SetupTest() {
+	suite.clock = &fakeClock{now: time.Unix(1546300800, 0)}
+}
+
+func TestRateLimiter(t *testing.T) {
+	suite.Run(t, new(rateLimiterTestSuite))
+}
+
+// newLimiter returns a rate limiter of maxRPS requests per second using
+// the fake clock
+func (suite *rateLimiterTestSuite) newLimiter(maxRPS float64) *rateLimiter {
+	l := newRateLimiter(maxRPS)
+	l.now = suite.clock.Now
+	l.after = suite.clock.After
+	l.last = suite.clock.Now()
+	return l
+}
+
+// TestRateLimiterRate tests that the requests are issued at the maximum
+// rate, evenly spaced
+func (suite *rateLimiterTestSuite) TestRateLimiterRate() {
+	l := suite.newLimiter(10)
+	start := suite.clock.Now()
+	for i := 0; i < 21; i++ {
+		suite.NoError(l.Wait(context.Background()))
+	}
+
+	suite.Equal(2*time.Second, suite.clock.Now().Sub(start))
+	suite.Len(suite.clock.waits, 20)
+	for _, d := range suite.clock.waits {
+		suite.Equal(100*time.Millisecond, d)
+	}
+	suite.InDelta(10, l.EffectiveRate(), 0.001)
+}
+
+// TestRateLimiterIdle tests that tokens do not accumulate while the client
+// is idle, so that the requests following a pause are not bursted
+func (suite *rateLimiterTestSuite) TestRateLimiterIdle() {
+	l := suite.newLimiter(5)
+	suite.NoError(l.Wait(context.Background()))
+	suite.clock.Advance(time.Minute)
+
+	suite.NoError(l.Wait(context.Background()))
+	suite.Empty(suite.clock.waits)
+	suite.NoError(l.Wait(context.Background()))
+	suite.Equal([]time.Duration{200 * time.Millisecond}, suite.clock.waits)
+	suite.InDelta(2/(time.Minute+200*time.Millisecond).Seconds(),
+		l.EffectiveRate(), 0.001)
+}
+
+// TestRateLimiterCanceled tests that a waiting request returns as soon as
+// its context is canceled, and gives back its token
+func (suite *rateLimiterTestSuite) TestRateLimiterCanceled() {
+	l := suite.newLimiter(10)
+	suite.NoError(l.Wait(context.Background()))
+
+	suite.clock.blocked = true
+	suite.clock.waiting = make(chan time.Duration, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- l.Wait(ctx)
+	}()
+	suite.Equal(100*time.Millisecond, <-suite.clock.waiting)
+	cancel()
+	select {
+	case err := <-done:
+		suite.Equal(context.Canceled, err)
+	case <-time.After(time.Second):
+		suite.Fail("wait not canceled")
+	}
+	suite.Equal(0.0, l.EffectiveRate())
+
+	// the canceled request did not use the next token
+	suite.clock.Advance(100 * time.Millisecond)
+	suite.NoError(l.Wait(context.Background()))
+	suite.Len(suite.clock.waits, 1)
+
+	// a canceled context is not waited for
+	suite.Equal(context.Canceled, l.Wait(ctx))
+	suite.Len(suite.clock.waits, 1)
+}
+
+// TestRateLimiterUnlimited tests that a nil rate limiter never waits
+func (suite *rateLimiterTestSuite) TestRateLimiterUnlimited() {
+	suite.Nil(newRateLimiter(0))
+	suite.Nil(newRateLimiter(-1))
+
+	var l *rateLimiter
+	suite.NoError(l.Wait(context.Background()))
+	suite.Equal(0.0, l.EffectiveRate())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	suite.Equal(context.Canceled, l.Wait(ctx))
+
+	c := Client{}
+	c.SetMaxRPS(DefaultMaxRPS)
+	suite.NotNil(c.limiter)
+	c.SetMaxRPS(0)
+	suite.Nil(c.limiter)
+}
+
+// TestSendHostBatchesCanceled tests that the host batches are sent at the
+// rate of the limiter, and that the batches left once the context is
+// canceled fail without being sent
+func (suite *rateLimiterTestSuite) TestSendHostBatchesCanceled() {
+	l := suite.newLimiter(2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var sent [][]string
+	results := sendHostBatches(ctx, l,
+		[][]string{{"host-1", "host-2"}, {"host-3"}, {"host-4"}},
+		func(ctx context.Context, hostnames []string) error {
+			sent = append(sent, hostnames)
+			if len(sent) == 2 {
+				cancel()
+				return errors.New("interrupted")
+			}
+			return nil
+		})
+
+	suite.Equal([][]string{{"host-1", "host-2"}, {"host-3"}}, sent)
+	suite.Equal([]time.Duration{500 * time.Millisecond}, suite.clock.waits)
+	suite.Equal([]hostBatchResult{
+		{hostname: "host-1", batch: 1},
+		{hostname: "host-2", batch: 1},
+		{hostname: "host-3", batch: 2, err: errors.New("interrupted")},
+		{hostname: "host-4", batch: 3, err: context.Canceled},
+	}, results)
+}
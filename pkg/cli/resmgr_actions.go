@@ -17,6 +17,7 @@ package cli
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
@@ -25,6 +26,16 @@ import (
 const (
 	activeTaskListFormatHeader = "TaskID\tState\tReason\tLast Update Time\n"
 	activeTaskListFormatBody   = "%s\t%s\t%s\t%s\n"
+
+	pendingGangListFormatHeader = "Queue\tPosition\tTasks\tCPU Limit\t" +
+		"Memory Limit\tDisk Limit\tGPU Limit\tWait\tPreemptible\n"
+	pendingGangListFormatBody = "%s\t%d\t%s\t%.1f\t%.0f MB\t%.0f MB\t" +
+		"%.0f\t%s\t%t\n"
+
+	preemptionListFormatHeader = "Time\tTask\tResource Pool\tState\t" +
+		"Reason\tCPU Limit\tMemory Limit\tDisk Limit\tGPU Limit\n"
+	preemptionListFormatBody = "%s\t%s\t%s\t%s\t%s\t%.1f\t%.0f MB\t" +
+		"%.0f MB\t%.0f\n"
 )
 
 // ResMgrGetActiveTasks fetches the active tasks from resource manager.
@@ -64,6 +75,112 @@ func (c *Client) ResMgrGetPendingTasks(respoolID string, limit uint32) error {
 	return nil
 }
 
+// ResMgrQueueAction lists the pending gangs of a resource pool in the order
+// in which they are admitted from each queue, with the resources they ask
+// for, how long they have been waiting and whether they are preemptible.
+func (c *Client) ResMgrQueueAction(respoolPath string, limit uint32) error {
+	respoolID, err := c.LookupResourcePoolID(respoolPath)
+	if err != nil {
+		return err
+	}
+	if respoolID == nil {
+		return fmt.Errorf("unable to find resource pool ID for "+
+			":%s", respoolPath)
+	}
+
+	resp, err := c.resMgrClient.GetPendingGangs(
+		c.ctx,
+		&resmgrsvc.GetPendingGangsRequest{
+			RespoolID: respoolID,
+			Limit:     limit,
+		})
+	if err != nil {
+		return err
+	}
+	return c.printFormatted(resp, func() error {
+		printPendingGangsResponse(resp, time.Now())
+		return nil
+	})
+}
+
+// ResMgrPreemptionsAction lists the preemption decisions of the resource
+// manager made within since, newest first, or all the ones it retains if
+// since is 0.
+func (c *Client) ResMgrPreemptionsAction(since time.Duration) error {
+	resp, err := c.resMgrClient.GetPreemptions(
+		c.ctx,
+		&resmgrsvc.GetPreemptionsRequest{
+			SinceSeconds: uint32(since / time.Second),
+		})
+	if err != nil {
+		return err
+	}
+	return c.printFormatted(resp, func() error {
+		printPreemptionsResponse(resp)
+		return nil
+	})
+}
+
+func printPendingGangsResponse(
+	r *resmgrsvc.GetPendingGangsResponse,
+	now time.Time) {
+	defer tabWriter.Flush()
+	if len(r.GetPendingGangs()) == 0 {
+		fmt.Fprintln(tabWriter, "No pending gangs")
+		return
+	}
+	fmt.Fprint(tabWriter, pendingGangListFormatHeader)
+	for _, gang := range r.GetPendingGangs() {
+		// the wait is unknown for gangs whose tasks are not tracked
+		wait := "-"
+		if pendingSince, err := time.Parse(
+			time.RFC3339Nano, gang.GetPendingSince()); err == nil {
+			wait = now.Sub(pendingSince).Round(time.Second).String()
+		}
+		resource := gang.GetResource()
+		fmt.Fprintf(
+			tabWriter,
+			pendingGangListFormatBody,
+			gang.GetQueue(),
+			gang.GetPosition(),
+			strings.Join(gang.GetTaskIDs(), ","),
+			resource.GetCpuLimit(),
+			resource.GetMemLimitMb(),
+			resource.GetDiskLimitMb(),
+			resource.GetGpuLimit(),
+			wait,
+			gang.GetPreemptible())
+	}
+}
+
+func printPreemptionsResponse(r *resmgrsvc.GetPreemptionsResponse) {
+	defer tabWriter.Flush()
+	if len(r.GetPreemptions()) == 0 {
+		fmt.Fprintln(tabWriter, "No preemptions")
+		return
+	}
+	fmt.Fprint(tabWriter, preemptionListFormatHeader)
+	for _, p := range r.GetPreemptions() {
+		respool := p.GetRespoolPath()
+		if respool == "" {
+			respool = p.GetRespoolID().GetValue()
+		}
+		resource := p.GetResource()
+		fmt.Fprintf(
+			tabWriter,
+			preemptionListFormatBody,
+			p.GetTime(),
+			p.GetTaskID().GetValue(),
+			respool,
+			p.GetState(),
+			p.GetReason(),
+			resource.GetCpuLimit(),
+			resource.GetMemLimitMb(),
+			resource.GetDiskLimitMb(),
+			resource.GetGpuLimit())
+	}
+}
+
 func printActiveTasksResponse(r *resmgrsvc.GetActiveTasksResponse, debug bool) {
 	if debug {
 		printResponseJSON(r)
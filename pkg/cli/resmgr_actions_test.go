@@ -15,11 +15,18 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"testing"
+	"text/tabwriter"
+	"time"
 
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	respool_mocks "github.com/uber/peloton/.gen/peloton/api/v0/respool/mocks"
 	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	"github.com/uber/peloton/.gen/peloton/private/resmgr"
 	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
 	res_mocks "github.com/uber/peloton/.gen/peloton/private/resmgrsvc/mocks"
 
@@ -169,3 +176,138 @@ func (suite *resmgrActionsTestSuite) TestClientGetPendingTasks() {
 	err = c.ResMgrGetPendingTasks("respool-1", 10)
 	suite.NoError(err)
 }
+
+// captureTable redirects the table output to the returned buffer until the
+// returned function is called
+func captureTable() (*bytes.Buffer, func()) {
+	output := &bytes.Buffer{}
+	old := tabWriter
+	tabWriter = tabwriter.NewWriter(output, 0, 0, 1, ' ', 0)
+	return output, func() { tabWriter = old }
+}
+
+func (suite *resmgrActionsTestSuite) TestClientQueue() {
+	mockRespool := respool_mocks.NewMockResourceManagerYARPCClient(suite.mockCtrl)
+	c := Client{
+		resMgrClient: suite.mockRes,
+		resClient:    mockRespool,
+		ctx:          suite.ctx,
+	}
+	output, restore := captureTable()
+	defer restore()
+
+	respoolID := &peloton.ResourcePoolID{Value: "respool-1"}
+	mockRespool.EXPECT().
+		LookupResourcePoolID(gomock.Any(), &respool.LookupRequest{
+			Path: &respool.ResourcePoolPath{Value: "/infra/batch"},
+		}).
+		Return(&respool.LookupResponse{Id: respoolID}, nil).
+		Times(2)
+
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	resp := &resmgrsvc.GetPendingGangsResponse{
+		PendingGangs: []*resmgrsvc.GetPendingGangsResponse_PendingGang{
+			{
+				Queue:    "pending",
+				Position: 1,
+				TaskIDs:  []string{"job-1-0", "job-1-1"},
+				Resource: &task.ResourceConfig{
+					CpuLimit:    1.5,
+					MemLimitMb:  256,
+					DiskLimitMb: 1024,
+				},
+				Preemptible:  true,
+				PendingSince: now.Add(-90 * time.Second).Format(time.RFC3339Nano),
+			},
+			{
+				Queue:    "non-preemptible",
+				Position: 1,
+				TaskIDs:  []string{"job-2-0"},
+				Resource: &task.ResourceConfig{CpuLimit: 4, GpuLimit: 1},
+			},
+		},
+	}
+	suite.mockRes.EXPECT().
+		GetPendingGangs(gomock.Any(), &resmgrsvc.GetPendingGangsRequest{
+			RespoolID: respoolID,
+			Limit:     10,
+		}).
+		Return(resp, nil)
+	suite.NoError(c.ResMgrQueueAction("/infra/batch", 10))
+
+	// the wait is relative to the time of the listing
+	output.Reset()
+	printPendingGangsResponse(resp, now)
+	suite.Equal(
+		"Queue           Position Tasks           CPU Limit Memory Limit "+
+			"Disk Limit GPU Limit Wait  Preemptible\n"+
+			"pending         1        job-1-0,job-1-1 1.5       256 MB       "+
+			"1024 MB    0         1m30s true\n"+
+			"non-preemptible 1        job-2-0         4.0       0 MB         "+
+			"0 MB       1         -     false\n",
+		output.String())
+
+	suite.mockRes.EXPECT().
+		GetPendingGangs(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake res error"))
+	suite.Error(c.ResMgrQueueAction("/infra/batch", 10))
+
+	mockRespool.EXPECT().
+		LookupResourcePoolID(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake respool error"))
+	suite.Error(c.ResMgrQueueAction("/infra/unknown", 10))
+}
+
+func (suite *resmgrActionsTestSuite) TestClientPreemptions() {
+	c := Client{
+		resMgrClient: suite.mockRes,
+		ctx:          suite.ctx,
+	}
+	output, restore := captureTable()
+	defer restore()
+
+	resp := &resmgrsvc.GetPreemptionsResponse{
+		Preemptions: []*resmgrsvc.Preemption{
+			{
+				TaskID:      &peloton.TaskID{Value: "job-1-0"},
+				RespoolID:   &peloton.ResourcePoolID{Value: "respool-1"},
+				RespoolPath: "/infra/batch",
+				State:       task.TaskState_RUNNING,
+				Reason:      resmgr.PreemptionReason_PREEMPTION_REASON_REVOKE_RESOURCES,
+				Resource:    &task.ResourceConfig{CpuLimit: 2, MemLimitMb: 512},
+				Time:        "2019-03-01T12:00:00Z",
+			},
+		},
+	}
+	suite.mockRes.EXPECT().
+		GetPreemptions(gomock.Any(), &resmgrsvc.GetPreemptionsRequest{
+			SinceSeconds: 3600,
+		}).
+		Return(resp, nil)
+	suite.NoError(c.ResMgrPreemptionsAction(time.Hour))
+	suite.Equal(
+		"Time                 Task    Resource Pool State   "+
+			"Reason                             CPU Limit Memory Limit "+
+			"Disk Limit GPU Limit\n"+
+			"2019-03-01T12:00:00Z job-1-0 /infra/batch  RUNNING "+
+			"PREEMPTION_REASON_REVOKE_RESOURCES 2.0       512 MB       "+
+			"0 MB       0\n",
+		output.String())
+
+	// the decisions are printed as json with the json output
+	jsonOutput := &fakeOutputter{}
+	oldOutputter := cliOutPutter
+	cliOutPutter = jsonOutput
+	defer func() { cliOutPutter = oldOutputter }()
+	c.JSON = true
+	suite.mockRes.EXPECT().
+		GetPreemptions(gomock.Any(), &resmgrsvc.GetPreemptionsRequest{}).
+		Return(resp, nil)
+	suite.NoError(c.ResMgrPreemptionsAction(0))
+	suite.Contains(jsonOutput.Out, `"respoolPath": "/infra/batch"`)
+
+	suite.mockRes.EXPECT().
+		GetPreemptions(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("fake res error"))
+	suite.Error(c.ResMgrPreemptionsAction(time.Hour))
+}
@@ -15,20 +15,49 @@
 package cli
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
 	"gopkg.in/yaml.v2"
 
 	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+
+	"github.com/uber/peloton/pkg/common"
 )
 
 // ResourcePoolPathDelim is the resource pool path delimiter
 const ResourcePoolPathDelim = "/"
 
+// respoolTreeBranches are the prefixes used to draw a resource pool tree:
+// a child, the last child, a continued parent level and an ended one
+type respoolTreeBranches struct {
+	child, lastChild, parent, lastParent string
+}
+
+var (
+	respoolTreeUnicode = respoolTreeBranches{
+		child:      "├── ",
+		lastChild:  "└── ",
+		parent:     "│   ",
+		lastParent: "    ",
+	}
+	respoolTreeASCII = respoolTreeBranches{
+		child:      "|-- ",
+		lastChild:  "`-- ",
+		parent:     "|   ",
+		lastParent: "    ",
+	}
+
+	// respoolTreeKinds are the resource kinds shown in a resource pool tree
+	respoolTreeKinds = []string{common.CPU, common.MEMORY, common.GPU}
+)
+
 // ResPoolCreateAction is the action for creating a resource pool
 func (c *Client) ResPoolCreateAction(respoolPath string, cfgFile string) error {
 	if respoolPath == ResourcePoolPathDelim {
@@ -159,6 +188,225 @@ func readResourcePoolConfig(cfgFile string) (respool.ResourcePoolConfig, error)
 	return respoolConfig, nil
 }
 
+// ResPoolTreeAction prints the resource pool tree rooted at respoolPath with
+// the reservation and limit of each pool, and its allocation and slack if
+// stats is set. Unicode box-drawing characters are used unless ascii is set.
+func (c *Client) ResPoolTreeAction(respoolPath string, stats, ascii bool) error {
+	rootID, err := c.LookupResourcePoolID(respoolPath)
+	if err != nil {
+		return err
+	}
+	if rootID == nil {
+		return errors.Errorf("unable to find resource pool %s", respoolPath)
+	}
+
+	pools, err := c.queryResourcePools(c.ctx)
+	if err != nil {
+		return err
+	}
+	root, ok := pools[rootID.GetValue()]
+	if !ok {
+		return errors.Errorf("unable to find resource pool %s", respoolPath)
+	}
+
+	branches := respoolTreeUnicode
+	if ascii {
+		branches = respoolTreeASCII
+	}
+	var buffer bytes.Buffer
+	buffer.WriteString(respoolPath + formatRespoolTreeNode(root, stats) + "\n")
+	printRespoolTree(&buffer, pools, root, "", branches, stats)
+	cliOutPutter.output(buffer.String())
+	return nil
+}
+
+// ResPoolLookupIDAction prints the path, parent and reservations of the
+// resource pool with the given ID
+func (c *Client) ResPoolLookupIDAction(id string) error {
+	pools, err := c.queryResourcePools(c.ctx)
+	if err != nil {
+		return err
+	}
+	path, err := resourcePoolPath(pools, id)
+	if err != nil {
+		return err
+	}
+
+	parentPath := "-"
+	if id != common.RootResPoolID {
+		parentPath, err = resourcePoolPath(pools, respoolParentID(pools, pools[id]))
+		if err != nil {
+			return err
+		}
+	}
+
+	var buffer bytes.Buffer
+	fmt.Fprintf(&buffer, "ID: %s\n", id)
+	fmt.Fprintf(&buffer, "Path: %s\n", path)
+	fmt.Fprintf(&buffer, "Parent: %s\n", parentPath)
+	fmt.Fprintf(&buffer, "Reservation/Limit: %s\n",
+		formatRespoolReservations(pools[id]))
+	cliOutPutter.output(buffer.String())
+	return nil
+}
+
+// LookupResourcePoolPath returns the path of the resource pool with the
+// given ID. The resource pool tree is queried once per call.
+func (c *Client) LookupResourcePoolPath(id string) (string, error) {
+	pools, err := c.queryResourcePools(c.ctx)
+	if err != nil {
+		return "", err
+	}
+	return resourcePoolPath(pools, id)
+}
+
+// queryResourcePools returns all resource pools keyed by their IDs
+func (c *Client) queryResourcePools(
+	ctx context.Context) (map[string]*respool.ResourcePoolInfo, error) {
+	response, err := c.resClient.Query(ctx, &respool.QueryRequest{})
+	if err != nil {
+		return nil, err
+	}
+	if response.GetError() != nil {
+		return nil, errors.New("error querying resource pools")
+	}
+
+	pools := make(map[string]*respool.ResourcePoolInfo)
+	for _, pool := range response.GetResourcePools() {
+		pools[pool.GetId().GetValue()] = pool
+	}
+	return pools, nil
+}
+
+// resourcePoolPath returns the path of a resource pool by walking up the
+// tree to the root. Pools whose ancestors are missing, e.g. because they
+// were deleted, are an error.
+func resourcePoolPath(
+	pools map[string]*respool.ResourcePoolInfo,
+	id string) (string, error) {
+	if _, ok := pools[id]; !ok {
+		return "", errors.Errorf("resource pool %s not found", id)
+	}
+
+	var names []string
+	visited := make(map[string]bool)
+	for current := id; current != common.RootResPoolID; {
+		pool, ok := pools[current]
+		if !ok {
+			return "", errors.Errorf(
+				"resource pool %s is orphaned, its ancestor %s was not found",
+				id, current)
+		}
+		if visited[current] {
+			return "", errors.Errorf(
+				"resource pool %s has a cycle in its ancestors at %s", id, current)
+		}
+		visited[current] = true
+
+		names = append(names, pool.GetConfig().GetName())
+		current = respoolParentID(pools, pool)
+		if current == "" {
+			return "", errors.Errorf(
+				"resource pool %s is orphaned, %s has no parent",
+				id, pool.GetId().GetValue())
+		}
+	}
+
+	for i, j := 0, len(names)-1; i < j; i, j = i+1, j-1 {
+		names[i], names[j] = names[j], names[i]
+	}
+	return ResourcePoolPathDelim + strings.Join(names, ResourcePoolPathDelim), nil
+}
+
+// respoolParentID returns the ID of the parent of a resource pool, from
+// the pool itself or else from the children of the other pools
+func respoolParentID(
+	pools map[string]*respool.ResourcePoolInfo,
+	pool *respool.ResourcePoolInfo) string {
+	if parent := pool.GetParent().GetValue(); parent != "" {
+		return parent
+	}
+	if parent := pool.GetConfig().GetParent().GetValue(); parent != "" {
+		return parent
+	}
+	for _, p := range pools {
+		for _, child := range p.GetChildren() {
+			if child.GetValue() == pool.GetId().GetValue() {
+				return p.GetId().GetValue()
+			}
+		}
+	}
+	return ""
+}
+
+// printRespoolTree writes the children of pool, sorted by name, to buffer
+func printRespoolTree(
+	buffer *bytes.Buffer,
+	pools map[string]*respool.ResourcePoolInfo,
+	pool *respool.ResourcePoolInfo,
+	prefix string,
+	branches respoolTreeBranches,
+	stats bool) {
+	var children []*respool.ResourcePoolInfo
+	for _, id := range pool.GetChildren() {
+		if child, ok := pools[id.GetValue()]; ok {
+			children = append(children, child)
+		}
+	}
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].GetConfig().GetName() <
+			children[j].GetConfig().GetName()
+	})
+
+	for i, child := range children {
+		branch, next := branches.child, branches.parent
+		if i == len(children)-1 {
+			branch, next = branches.lastChild, branches.lastParent
+		}
+		buffer.WriteString(prefix + branch + child.GetConfig().GetName() +
+			formatRespoolTreeNode(child, stats) + "\n")
+		printRespoolTree(buffer, pools, child, prefix+next, branches, stats)
+	}
+}
+
+// formatRespoolTreeNode formats the resources of a resource pool
+func formatRespoolTreeNode(
+	pool *respool.ResourcePoolInfo,
+	stats bool) string {
+	usages := make(map[string]*respool.ResourceUsage)
+	for _, u := range pool.GetUsage() {
+		usages[u.GetKind()] = u
+	}
+
+	var allocations []string
+	for _, kind := range respoolTreeKinds {
+		allocations = append(allocations, fmt.Sprintf("%s %g/%g",
+			kind, usages[kind].GetAllocation(), usages[kind].GetSlack()))
+	}
+
+	node := " [reservation/limit: " + formatRespoolReservations(pool) + "]"
+	if stats {
+		node += " [allocation/slack: " + strings.Join(allocations, ", ") + "]"
+	}
+	return node
+}
+
+// formatRespoolReservations formats the reservation and limit of each
+// resource kind of a resource pool
+func formatRespoolReservations(pool *respool.ResourcePoolInfo) string {
+	resources := make(map[string]*respool.ResourceConfig)
+	for _, r := range pool.GetConfig().GetResources() {
+		resources[r.GetKind()] = r
+	}
+
+	var limits []string
+	for _, kind := range respoolTreeKinds {
+		limits = append(limits, fmt.Sprintf("%s %g/%g",
+			kind, resources[kind].GetReservation(), resources[kind].GetLimit()))
+	}
+	return strings.Join(limits, ", ")
+}
+
 // ResPoolDumpAction dumps the resource pool tree
 func (c *Client) ResPoolDumpAction(resPoolDumpFormat string) error {
 	response, err := c.resClient.Query(c.ctx, &respool.QueryRequest{})
@@ -166,7 +414,9 @@ func (c *Client) ResPoolDumpAction(resPoolDumpFormat string) error {
 		return err
 	}
 
-	return printResPoolDumpResponse(resPoolDumpFormat, response, c.Debug)
+	return c.printFormatted(response, func() error {
+		return printResPoolDumpResponse(resPoolDumpFormat, response, c.Debug)
+	})
 }
 
 func printResPoolDumpResponse(resPoolDumpFormat string,
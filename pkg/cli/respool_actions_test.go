@@ -17,6 +17,7 @@ package cli
 import (
 	"context"
 	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	respoolmocks "github.com/uber/peloton/.gen/peloton/api/v0/respool/mocks"
@@ -192,6 +193,236 @@ func (suite *resPoolActions) TestClientResPoolDumpAction() {
 	}
 }
 
+// getRespoolTree returns a three level resource pool hierarchy
+func (suite *resPoolActions) getRespoolTree() []*respool.ResourcePoolInfo {
+	resources := func(cpu, mem, gpu float64) []*respool.ResourceConfig {
+		return []*respool.ResourceConfig{
+			{Kind: "cpu", Reservation: cpu, Limit: cpu * 2},
+			{Kind: "memory", Reservation: mem, Limit: mem * 2},
+			{Kind: "gpu", Reservation: gpu, Limit: gpu * 2},
+		}
+	}
+	usage := func(cpu, mem, gpu float64) []*respool.ResourceUsage {
+		return []*respool.ResourceUsage{
+			{Kind: "cpu", Allocation: cpu, Slack: 1},
+			{Kind: "memory", Allocation: mem},
+			{Kind: "gpu", Allocation: gpu},
+		}
+	}
+	return []*respool.ResourcePoolInfo{
+		{
+			Id: &peloton.ResourcePoolID{Value: "root"},
+			Children: []*peloton.ResourcePoolID{
+				{Value: "users"},
+				{Value: "infra"},
+			},
+		},
+		{
+			Id: &peloton.ResourcePoolID{Value: "infra"},
+			Config: &respool.ResourcePoolConfig{
+				Name:      "infra",
+				Resources: resources(100, 1024, 4),
+			},
+			Children: []*peloton.ResourcePoolID{
+				{Value: "compute"},
+				{Value: "batch"},
+			},
+			Usage: usage(50, 512, 2),
+		},
+		{
+			Id: &peloton.ResourcePoolID{Value: "compute"},
+			Config: &respool.ResourcePoolConfig{
+				Name:      "compute",
+				Resources: resources(60, 512, 4),
+			},
+			Usage: usage(40, 256, 2),
+		},
+		{
+			Id: &peloton.ResourcePoolID{Value: "batch"},
+			Config: &respool.ResourcePoolConfig{
+				Name:      "batch",
+				Resources: resources(40, 512, 0),
+			},
+			Usage: usage(10, 256, 0),
+		},
+		{
+			Id: &peloton.ResourcePoolID{Value: "users"},
+			Config: &respool.ResourcePoolConfig{
+				Name:      "users",
+				Resources: resources(10.5, 256, 0),
+			},
+		},
+	}
+}
+
+// TestClientResPoolTreeAction tests rendering the resource pool tree
+// against golden files
+func (suite *resPoolActions) TestClientResPoolTreeAction() {
+	c := Client{
+		resClient: suite.mockRespool,
+		ctx:       suite.ctx,
+	}
+	fo := &fakeOutputter{}
+	cliOutPutter = fo
+	defer func() { cliOutPutter = newStdOutOutputter() }()
+
+	tt := []struct {
+		path   string
+		id     string
+		stats  bool
+		ascii  bool
+		golden string
+	}{
+		{
+			path:   "/",
+			id:     "root",
+			golden: "respool_tree.golden",
+		},
+		{
+			path:   "/infra",
+			id:     "infra",
+			stats:  true,
+			ascii:  true,
+			golden: "respool_tree_stats_ascii.golden",
+		},
+	}
+
+	for _, t := range tt {
+		suite.withMockResourcePoolLookup(
+			&respool.LookupRequest{
+				Path: &respool.ResourcePoolPath{Value: t.path},
+			},
+			&respool.LookupResponse{
+				Id: &peloton.ResourcePoolID{Value: t.id},
+			},
+			nil,
+		)
+		suite.mockRespool.EXPECT().
+			Query(gomock.Any(), &respool.QueryRequest{}).
+			Return(&respool.QueryResponse{
+				ResourcePools: suite.getRespoolTree(),
+			}, nil)
+
+		suite.NoError(c.ResPoolTreeAction(t.path, t.stats, t.ascii))
+		expected, err := ioutil.ReadFile(filepath.Join("testdata", t.golden))
+		suite.NoError(err)
+		suite.Equal(string(expected), fo.Out)
+	}
+
+	// lookup failure
+	suite.withMockResourcePoolLookup(
+		&respool.LookupRequest{
+			Path: &respool.ResourcePoolPath{Value: "/missing"},
+		},
+		nil,
+		errors.New("respool not found"),
+	)
+	suite.Error(c.ResPoolTreeAction("/missing", false, false))
+}
+
+// TestClientLookupResourcePoolPath tests resolving the paths of resource
+// pools by their IDs
+func (suite *resPoolActions) TestClientLookupResourcePoolPath() {
+	c := Client{
+		resClient: suite.mockRespool,
+		ctx:       suite.ctx,
+	}
+	orphan := &respool.ResourcePoolInfo{
+		Id:     &peloton.ResourcePoolID{Value: "orphan"},
+		Config: &respool.ResourcePoolConfig{Name: "orphan"},
+		Parent: &peloton.ResourcePoolID{Value: "deleted"},
+	}
+
+	tt := []struct {
+		pools []*respool.ResourcePoolInfo
+		id    string
+		path  string
+		err   string
+	}{
+		{
+			// parents given by the children of the pools
+			pools: suite.getRespoolTree(),
+			id:    "compute",
+			path:  "/infra/compute",
+		},
+		{
+			pools: suite.getRespoolTree(),
+			id:    "users",
+			path:  "/users",
+		},
+		{
+			pools: suite.getRespoolTree(),
+			id:    "root",
+			path:  "/",
+		},
+		{
+			// parents given by the pools
+			pools: suite.getRespoolInfos()[1:],
+			id:    "respool1",
+			path:  "/respool1",
+		},
+		{
+			pools: suite.getRespoolTree(),
+			id:    "unknown",
+			err:   "resource pool unknown not found",
+		},
+		{
+			pools: append(suite.getRespoolTree(), orphan),
+			id:    "orphan",
+			err:   "resource pool orphan is orphaned, its ancestor deleted was not found",
+		},
+	}
+
+	for _, t := range tt {
+		suite.mockRespool.EXPECT().
+			Query(gomock.Any(), &respool.QueryRequest{}).
+			Return(&respool.QueryResponse{ResourcePools: t.pools}, nil)
+
+		path, err := c.LookupResourcePoolPath(t.id)
+		if t.err != "" {
+			suite.EqualError(err, t.err, t.id)
+		} else {
+			suite.NoError(err, t.id)
+			suite.Equal(t.path, path, t.id)
+		}
+	}
+
+	suite.mockRespool.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("resmgr unavailable"))
+	_, err := c.LookupResourcePoolPath("compute")
+	suite.Error(err)
+}
+
+// TestClientResPoolLookupIDAction tests printing a nested resource pool
+func (suite *resPoolActions) TestClientResPoolLookupIDAction() {
+	c := Client{
+		resClient: suite.mockRespool,
+		ctx:       suite.ctx,
+	}
+	fo := &fakeOutputter{}
+	cliOutPutter = fo
+	defer func() { cliOutPutter = newStdOutOutputter() }()
+
+	suite.mockRespool.EXPECT().
+		Query(gomock.Any(), &respool.QueryRequest{}).
+		Return(&respool.QueryResponse{
+			ResourcePools: suite.getRespoolTree(),
+		}, nil)
+	suite.NoError(c.ResPoolLookupIDAction("compute"))
+	suite.Equal("ID: compute\n"+
+		"Path: /infra/compute\n"+
+		"Parent: /infra\n"+
+		"Reservation/Limit: cpu 60/120, memory 512/1024, gpu 4/8\n", fo.Out)
+
+	suite.mockRespool.EXPECT().
+		Query(gomock.Any(), &respool.QueryRequest{}).
+		Return(&respool.QueryResponse{
+			ResourcePools: suite.getRespoolTree(),
+		}, nil)
+	suite.Error(c.ResPoolLookupIDAction("unknown"))
+}
+
 func (suite *resPoolActions) getConfig() *respool.ResourcePoolConfig {
 	var config respool.ResourcePoolConfig
 	buffer, err := ioutil.ReadFile(_defaultResPoolConfig)
@@ -0,0 +1,363 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+
+	"github.com/gogo/protobuf/proto"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	respoolApplyCreate    = "create"
+	respoolApplyUpdate    = "update"
+	respoolApplyUnchanged = "unchanged"
+
+	respoolApplyFormatHeader = "Path\tOperation\t\n"
+	respoolApplyFormatBody   = "%s\t%s\t\n"
+)
+
+// respoolApplyFile is the YAML file of respool apply
+type respoolApplyFile struct {
+	Pools []*respoolApplySpec `yaml:"pools"`
+}
+
+// respoolApplySpec is a resource pool of a respool apply file. Its config
+// is the one of respool create, with the parent given by the path.
+type respoolApplySpec struct {
+	Path   string                     `yaml:"path"`
+	Config respool.ResourcePoolConfig `yaml:",inline"`
+}
+
+// respoolApplyOp is the operation applying a resource pool of the file
+type respoolApplyOp struct {
+	spec       *respoolApplySpec
+	parentPath string
+	operation  string
+	// id is the ID of the existing resource pool, nil if it is created
+	id *peloton.ResourcePoolID
+}
+
+// ResPoolApplyAction creates or updates the resource pools described by a
+// YAML file. The file is validated against the existing resource pools
+// before any change: the paths must be well-formed with parents either
+// existing or in the file, and the reservations and limits of every pool
+// must not exceed the limits of its parent. The pools are then created or
+// updated parents first, and the pools identical to the existing ones are
+// left unchanged so that a file can be applied again. With dryRun the
+// planned operations are only printed. It returns an error if any pool
+// failed to apply.
+func (c *Client) ResPoolApplyAction(cfgFile string, dryRun bool) error {
+	specs, err := readRespoolApplyFile(cfgFile)
+	if err != nil {
+		return err
+	}
+
+	pools, err := c.queryResourcePools(c.ctx)
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]*respool.ResourcePoolInfo)
+	for id, pool := range pools {
+		// the orphaned pools cannot be the parents of the pools of the file
+		if path, err := resourcePoolPath(pools, id); err == nil {
+			existing[path] = pool
+		}
+	}
+
+	ops, err := planRespoolApply(cfgFile, specs, existing)
+	if err != nil {
+		return err
+	}
+
+	if dryRun {
+		fmt.Fprint(tabWriter, respoolApplyFormatHeader)
+		for _, op := range ops {
+			fmt.Fprintf(tabWriter, respoolApplyFormatBody, op.spec.Path, op.operation)
+		}
+		fmt.Fprintf(tabWriter, "Dry run, not applying %d resource pool(s)\n", len(ops))
+		tabWriter.Flush()
+		return nil
+	}
+
+	ids := make(map[string]*peloton.ResourcePoolID)
+	for path, pool := range existing {
+		ids[path] = pool.GetId()
+	}
+	failed := make(map[string]bool)
+	counts := make(map[string]int)
+	for _, op := range ops {
+		path := op.spec.Path
+		if failed[op.parentPath] {
+			failed[path] = true
+			fmt.Fprintf(tabWriter, "Skipped resource pool %s, its parent %s failed\n",
+				path, op.parentPath)
+			continue
+		}
+
+		switch op.operation {
+		case respoolApplyCreate:
+			id, err := c.createApplyResourcePool(op, ids[op.parentPath])
+			if err != nil {
+				failed[path] = true
+				fmt.Fprintf(tabWriter, "Failed to create resource pool %s: %v\n", path, err)
+				continue
+			}
+			ids[path] = id
+			fmt.Fprintf(tabWriter, "Created resource pool %s with ID %s\n",
+				path, id.GetValue())
+		case respoolApplyUpdate:
+			if err := c.updateApplyResourcePool(op, ids[op.parentPath]); err != nil {
+				failed[path] = true
+				fmt.Fprintf(tabWriter, "Failed to update resource pool %s: %v\n", path, err)
+				continue
+			}
+			fmt.Fprintf(tabWriter, "Updated resource pool %s\n", path)
+		default:
+			fmt.Fprintf(tabWriter, "Resource pool %s is unchanged\n", path)
+		}
+		counts[op.operation]++
+	}
+	fmt.Fprintf(tabWriter,
+		"Applied %d resource pool(s): %d created, %d updated, %d unchanged, %d failed\n",
+		len(ops), counts[respoolApplyCreate], counts[respoolApplyUpdate],
+		counts[respoolApplyUnchanged], len(failed))
+	tabWriter.Flush()
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d of %d resource pool(s) failed to apply",
+			len(failed), len(ops))
+	}
+	return nil
+}
+
+// createApplyResourcePool creates the resource pool of an operation under
+// its parent and returns its ID
+func (c *Client) createApplyResourcePool(
+	op *respoolApplyOp,
+	parentID *peloton.ResourcePoolID) (*peloton.ResourcePoolID, error) {
+	config := op.spec.Config
+	config.Parent = parentID
+	response, err := c.resClient.CreateResourcePool(c.ctx, &respool.CreateRequest{
+		Config: &config,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response.GetError() != nil {
+		return nil, errors.New(response.GetError().String())
+	}
+	return response.GetResult(), nil
+}
+
+// updateApplyResourcePool updates the resource pool of an operation
+func (c *Client) updateApplyResourcePool(
+	op *respoolApplyOp,
+	parentID *peloton.ResourcePoolID) error {
+	config := op.spec.Config
+	config.Parent = parentID
+	response, err := c.resClient.UpdateResourcePool(c.ctx, &respool.UpdateRequest{
+		Id:     op.id,
+		Config: &config,
+	})
+	if err != nil {
+		return err
+	}
+	if response.GetError() != nil {
+		return errors.New(response.GetError().String())
+	}
+	return nil
+}
+
+// readRespoolApplyFile reads the resource pools of a respool apply file
+func readRespoolApplyFile(cfgFile string) ([]*respoolApplySpec, error) {
+	buffer, err := ioutil.ReadFile(cfgFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file %s: %v", cfgFile, err)
+	}
+	var file respoolApplyFile
+	if err := yaml.Unmarshal(buffer, &file); err != nil {
+		return nil, fmt.Errorf("unable to parse file %s: %v", cfgFile, err)
+	}
+	if len(file.Pools) == 0 {
+		return nil, fmt.Errorf("no resource pool in file %s", cfgFile)
+	}
+	return file.Pools, nil
+}
+
+// validateRespoolPath returns an error if a path is not the absolute path
+// of a resource pool other than the root
+func validateRespoolPath(path string) error {
+	if !strings.HasPrefix(path, ResourcePoolPathDelim) {
+		return fmt.Errorf("path %q should begin with %s", path, ResourcePoolPathDelim)
+	}
+	if path == ResourcePoolPathDelim {
+		return errors.New("cannot apply root resource pool")
+	}
+	for _, name := range strings.Split(path[1:], ResourcePoolPathDelim) {
+		if name == "" {
+			return fmt.Errorf("path %q has an empty resource pool name", path)
+		}
+	}
+	return nil
+}
+
+// planRespoolApply validates the resource pools of a file against the
+// existing ones, and returns the operations applying them ordered parents
+// first. All the problems of the file are reported at once.
+func planRespoolApply(
+	cfgFile string,
+	specs []*respoolApplySpec,
+	existing map[string]*respool.ResourcePoolInfo) ([]*respoolApplyOp, error) {
+	errs := newValidationErrors("resource pool file " + cfgFile)
+
+	inFile := make(map[string]*respoolApplySpec)
+	var ops []*respoolApplyOp
+	for i, spec := range specs {
+		position := fmt.Sprintf("pool %d", i+1)
+		if err := validateRespoolPath(spec.Path); err != nil {
+			errs.Add(position, "%v", err)
+			continue
+		}
+		if _, ok := inFile[spec.Path]; ok {
+			errs.Add(position, "duplicate resource pool %s", spec.Path)
+			continue
+		}
+		inFile[spec.Path] = spec
+
+		if spec.Config.GetParent() != nil {
+			errs.Add(position, "parent should not be supplied, it is given by the path")
+		}
+		name := parseRespoolName(spec.Path)
+		if spec.Config.Name == "" {
+			spec.Config.Name = name
+		} else if spec.Config.Name != name {
+			errs.Add(position, "resource pool name in path:%s and config:%s don't match",
+				name, spec.Config.Name)
+		}
+		for _, r := range spec.Config.GetResources() {
+			if r.GetReservation() > r.GetLimit() {
+				errs.Add(position, "resource %s, reservation %v exceeds limit %v",
+					r.GetKind(), r.GetReservation(), r.GetLimit())
+			}
+		}
+
+		ops = append(ops, &respoolApplyOp{
+			spec:       spec,
+			parentPath: respoolParentPath(spec.Path),
+		})
+	}
+
+	// the parents are applied before their children
+	sort.SliceStable(ops, func(i, j int) bool {
+		return strings.Count(ops[i].spec.Path, ResourcePoolPathDelim) <
+			strings.Count(ops[j].spec.Path, ResourcePoolPathDelim)
+	})
+
+	for _, op := range ops {
+		position := "pool " + op.spec.Path
+		var parent *respool.ResourcePoolConfig
+		if spec, ok := inFile[op.parentPath]; ok {
+			parent = &spec.Config
+		} else if pool, ok := existing[op.parentPath]; ok {
+			parent = pool.GetConfig()
+		} else {
+			errs.Add(position, "parent %s not found", op.parentPath)
+			continue
+		}
+		validateRespoolParentLimits(errs, position, op.parentPath,
+			&op.spec.Config, parent)
+
+		op.operation = respoolApplyCreate
+		if pool, ok := existing[op.spec.Path]; ok {
+			op.id = pool.GetId()
+			op.operation = respoolApplyUpdate
+			if respoolConfigUnchanged(&op.spec.Config, pool.GetConfig()) {
+				op.operation = respoolApplyUnchanged
+			}
+		}
+	}
+
+	if err := errs.ErrorOrNil(); err != nil {
+		return nil, err
+	}
+	return ops, nil
+}
+
+// respoolParentPath returns the path of the parent of a resource pool
+func respoolParentPath(path string) string {
+	parent := path[:strings.LastIndex(path, ResourcePoolPathDelim)]
+	if parent == "" {
+		return ResourcePoolPathDelim
+	}
+	return parent
+}
+
+// validateRespoolParentLimits records the resources of a resource pool
+// whose reservation or limit exceeds the limit of its parent
+func validateRespoolParentLimits(
+	errs *ValidationErrors,
+	position string,
+	parentPath string,
+	config *respool.ResourcePoolConfig,
+	parent *respool.ResourcePoolConfig) {
+	limits := make(map[string]float64)
+	for _, r := range parent.GetResources() {
+		limits[r.GetKind()] = r.GetLimit()
+	}
+	for _, r := range config.GetResources() {
+		limit, ok := limits[r.GetKind()]
+		if !ok {
+			errs.Add(position, "parent %s doesn't have resource kind %s",
+				parentPath, r.GetKind())
+			continue
+		}
+		if r.GetReservation() > limit {
+			errs.Add(position, "resource %s, reservation %v exceeds parent %s limit %v",
+				r.GetKind(), r.GetReservation(), parentPath, limit)
+		}
+		if r.GetLimit() > limit {
+			errs.Add(position, "resource %s, limit %v exceeds parent %s limit %v",
+				r.GetKind(), r.GetLimit(), parentPath, limit)
+		}
+	}
+}
+
+// respoolConfigUnchanged returns whether applying a config leaves the
+// existing config of a resource pool unchanged. The parent and change log
+// are ignored, and an unset policy keeps the existing one.
+func respoolConfigUnchanged(
+	config *respool.ResourcePoolConfig,
+	existing *respool.ResourcePoolConfig) bool {
+	if existing == nil {
+		return false
+	}
+	current := *existing
+	current.Parent = nil
+	current.ChangeLog = nil
+	applied := *config
+	if applied.Policy == respool.SchedulingPolicy_UNKNOWN {
+		applied.Policy = current.Policy
+	}
+	return proto.Equal(&applied, &current)
+}
@@ -0,0 +1,283 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"testing"
+	"text/tabwriter"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/changelog"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	respoolmocks "github.com/uber/peloton/.gen/peloton/api/v0/respool/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+var (
+	_respoolApplyFile        = filepath.Join("testdata", "respool_apply.yaml")
+	_respoolApplyInvalidFile = filepath.Join("testdata", "respool_apply_invalid.yaml")
+)
+
+type respoolApplyTestSuite struct {
+	suite.Suite
+	mockCtrl     *gomock.Controller
+	mockRespool  *respoolmocks.MockResourceManagerYARPCClient
+	output       *bytes.Buffer
+	oldTabWriter *tabwriter.Writer
+	client       Client
+}
+
+func (suite *respoolApplyTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockRespool = respoolmocks.NewMockResourceManagerYARPCClient(
+		suite.mockCtrl)
+	suite.output = &bytes.Buffer{}
+	suite.oldTabWriter = tabWriter
+	tabWriter = tabwriter.NewWriter(suite.output, 0, 0, 1, ' ', 0)
+	suite.client = Client{
+		resClient: suite.mockRespool,
+		ctx:       context.Background(),
+	}
+}
+
+func (suite *respoolApplyTestSuite) TearDownTest() {
+	tabWriter = suite.oldTabWriter
+	suite.mockCtrl.Finish()
+}
+
+func TestRespoolApply(t *testing.T) {
+	suite.Run(t, new(respoolApplyTestSuite))
+}
+
+// respoolApplyTree returns the existing resource pools, /infra with its
+// children /infra/compute and /infra/batch, and /users
+func respoolApplyTree() []*respool.ResourcePoolInfo {
+	pool := func(
+		id, parent string,
+		cpu, mem float64,
+		children ...string) *respool.ResourcePoolInfo {
+		info := &respool.ResourcePoolInfo{
+			Id:     &peloton.ResourcePoolID{Value: id},
+			Parent: &peloton.ResourcePoolID{Value: parent},
+			Config: &respool.ResourcePoolConfig{
+				Name:   id,
+				Policy: respool.SchedulingPolicy_PriorityFIFO,
+				Resources: []*respool.ResourceConfig{
+					{Kind: "cpu", Reservation: cpu, Limit: cpu * 2, Share: 1},
+					{Kind: "memory", Reservation: mem, Limit: mem * 2, Share: 1},
+				},
+			},
+		}
+		for _, child := range children {
+			info.Children = append(info.Children,
+				&peloton.ResourcePoolID{Value: child})
+		}
+		return info
+	}
+	root := pool("root", "", 200, 8192, "infra", "users")
+	root.Parent = nil
+	return []*respool.ResourcePoolInfo{
+		root,
+		pool("infra", "root", 100, 1024, "compute", "batch"),
+		pool("compute", "infra", 60, 512),
+		pool("batch", "infra", 40, 512),
+		pool("users", "root", 10.5, 256),
+	}
+}
+
+func (suite *respoolApplyTestSuite) expectQuery(
+	pools []*respool.ResourcePoolInfo) {
+	suite.mockRespool.EXPECT().
+		Query(gomock.Any(), &respool.QueryRequest{}).
+		Return(&respool.QueryResponse{ResourcePools: pools}, nil)
+}
+
+// TestRespoolApplyNested tests that a nested hierarchy is created parents
+// first, and that the changed pools are updated
+func (suite *respoolApplyTestSuite) TestRespoolApplyNested() {
+	suite.expectQuery(respoolApplyTree())
+	gomock.InOrder(
+		suite.mockRespool.EXPECT().
+			CreateResourcePool(gomock.Any(), gomock.Any()).
+			Do(func(_ context.Context, req *respool.CreateRequest) {
+				suite.Equal("services", req.GetConfig().GetName())
+				suite.Equal("root", req.GetConfig().GetParent().GetValue())
+				suite.Equal("infra", req.GetConfig().GetOwningTeam())
+				suite.Len(req.GetConfig().GetResources(), 2)
+			}).
+			Return(&respool.CreateResponse{
+				Result: &peloton.ResourcePoolID{Value: "services"},
+			}, nil),
+		suite.mockRespool.EXPECT().
+			CreateResourcePool(gomock.Any(), gomock.Any()).
+			Do(func(_ context.Context, req *respool.CreateRequest) {
+				suite.Equal("web", req.GetConfig().GetName())
+				suite.Equal("services", req.GetConfig().GetParent().GetValue())
+			}).
+			Return(&respool.CreateResponse{
+				Result: &peloton.ResourcePoolID{Value: "web"},
+			}, nil),
+		suite.mockRespool.EXPECT().
+			UpdateResourcePool(gomock.Any(), gomock.Any()).
+			Do(func(_ context.Context, req *respool.UpdateRequest) {
+				suite.Equal("compute", req.GetId().GetValue())
+				suite.Equal("compute", req.GetConfig().GetName())
+				suite.Equal("infra", req.GetConfig().GetParent().GetValue())
+				suite.Equal(80.0, req.GetConfig().GetResources()[0].GetReservation())
+			}).
+			Return(&respool.UpdateResponse{}, nil),
+	)
+
+	suite.NoError(suite.client.ResPoolApplyAction(_respoolApplyFile, false))
+	output := suite.output.String()
+	suite.Contains(output, "Created resource pool /services with ID services\n")
+	suite.Contains(output, "Created resource pool /services/web with ID web\n")
+	suite.Contains(output, "Updated resource pool /infra/compute\n")
+	suite.Contains(output,
+		"Applied 3 resource pool(s): 2 created, 1 updated, 0 unchanged, 0 failed\n")
+}
+
+// TestRespoolApplyDryRun tests that a dry run only prints the planned
+// operations
+func (suite *respoolApplyTestSuite) TestRespoolApplyDryRun() {
+	suite.expectQuery(respoolApplyTree())
+
+	suite.NoError(suite.client.ResPoolApplyAction(_respoolApplyFile, true))
+	suite.Equal("Path           Operation \n"+
+		"/services      create    \n"+
+		"/services/web  create    \n"+
+		"/infra/compute update    \n"+
+		"Dry run, not applying 3 resource pool(s)\n",
+		suite.output.String())
+}
+
+// TestRespoolApplyIdempotent tests that applying a file again leaves the
+// pools unchanged
+func (suite *respoolApplyTestSuite) TestRespoolApplyIdempotent() {
+	specs, err := readRespoolApplyFile(_respoolApplyFile)
+	suite.NoError(err)
+
+	// the pools as created by the first apply, with the policy defaulted
+	// and a change log set by the resource manager
+	pools := respoolApplyTree()
+	ids := map[string]string{
+		"/": "root", "/infra": "infra", "/services": "services",
+	}
+	for _, spec := range specs {
+		config := spec.Config
+		config.Name = parseRespoolName(spec.Path)
+		config.Parent = &peloton.ResourcePoolID{
+			Value: ids[respoolParentPath(spec.Path)],
+		}
+		if config.Policy == respool.SchedulingPolicy_UNKNOWN {
+			config.Policy = respool.SchedulingPolicy_PriorityFIFO
+		}
+		config.ChangeLog = &changelog.ChangeLog{Version: 2}
+		id := config.Name
+		if spec.Path == "/infra/compute" {
+			pools[2].Config = &config
+			continue
+		}
+		pools = append(pools, &respool.ResourcePoolInfo{
+			Id:     &peloton.ResourcePoolID{Value: id},
+			Parent: config.Parent,
+			Config: &config,
+		})
+	}
+	suite.expectQuery(pools)
+
+	suite.NoError(suite.client.ResPoolApplyAction(_respoolApplyFile, false))
+	suite.Contains(suite.output.String(),
+		"Applied 3 resource pool(s): 0 created, 0 updated, 3 unchanged, 0 failed\n")
+}
+
+// TestRespoolApplyInvalid tests that all the problems of a file are
+// reported before any change
+func (suite *respoolApplyTestSuite) TestRespoolApplyInvalid() {
+	suite.expectQuery(respoolApplyTree())
+
+	err := suite.client.ResPoolApplyAction(_respoolApplyInvalidFile, false)
+	suite.Error(err)
+	errs, ok := err.(*ValidationErrors)
+	suite.True(ok)
+	suite.Equal([]ValidationError{
+		{
+			Position: "pool 2",
+			Message:  `path "/infra//broken" has an empty resource pool name`,
+		},
+		{
+			Position: "pool 3",
+			Message:  "resource cpu, reservation 5 exceeds limit 2",
+		},
+		{
+			Position: "pool /infra/batch",
+			Message:  "resource cpu, reservation 250 exceeds parent /infra limit 200",
+		},
+		{
+			Position: "pool /infra/batch",
+			Message:  "resource cpu, limit 250 exceeds parent /infra limit 200",
+		},
+		{
+			Position: "pool /missing/team",
+			Message:  "parent /missing not found",
+		},
+	}, errs.Errors)
+	suite.Empty(suite.output.String())
+}
+
+// TestRespoolApplyFailure tests that the children of a pool which failed
+// to apply are skipped
+func (suite *respoolApplyTestSuite) TestRespoolApplyFailure() {
+	suite.expectQuery(respoolApplyTree())
+	gomock.InOrder(
+		suite.mockRespool.EXPECT().
+			CreateResourcePool(gomock.Any(), gomock.Any()).
+			Return(&respool.CreateResponse{
+				Error: &respool.CreateResponse_Error{
+					InvalidResourcePoolConfig: &respool.InvalidResourcePoolConfig{
+						Message: "aggregated child reservation exceeds parent",
+					},
+				},
+			}, nil),
+		suite.mockRespool.EXPECT().
+			UpdateResourcePool(gomock.Any(), gomock.Any()).
+			Return(&respool.UpdateResponse{}, nil),
+	)
+
+	suite.EqualError(
+		suite.client.ResPoolApplyAction(_respoolApplyFile, false),
+		"2 of 3 resource pool(s) failed to apply")
+	output := suite.output.String()
+	suite.Contains(output, "Failed to create resource pool /services: ")
+	suite.Contains(output, "aggregated child reservation exceeds parent")
+	suite.Contains(output,
+		"Skipped resource pool /services/web, its parent /services failed\n")
+	suite.Contains(output, "Updated resource pool /infra/compute\n")
+}
+
+// TestRespoolParentPath tests the parent paths of resource pools
+func (suite *respoolApplyTestSuite) TestRespoolParentPath() {
+	suite.Equal("/", respoolParentPath("/infra"))
+	suite.Equal("/infra", respoolParentPath("/infra/compute"))
+	suite.Error(validateRespoolPath("infra"))
+	suite.Error(validateRespoolPath("/"))
+	suite.Error(validateRespoolPath("/infra/"))
+	suite.NoError(validateRespoolPath("/infra/compute"))
+}
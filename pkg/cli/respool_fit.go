@@ -0,0 +1,335 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math"
+	"reflect"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	"github.com/uber/peloton/pkg/common"
+	"github.com/uber/peloton/pkg/common/taskconfig"
+)
+
+const (
+	respoolFitFormatHeader = "Kind\tDemand\tReservation\tLimit\tAllocation\tSlack\t\n"
+	respoolFitFormatBody   = "%s\t%g\t%g\t%g\t%g\t%g\t\n"
+
+	// respoolFitEpsilon absorbs the rounding errors of summing fractional
+	// resources, e.g. ten instances of 0.1 cpu fitting in 1 cpu
+	respoolFitEpsilon = 1e-9
+)
+
+// respoolFitRequest is a number of consecutive instances of a job with the
+// same resources
+type respoolFitRequest struct {
+	resources map[string]float64
+	count     int
+}
+
+// respoolFitKind is the demand of a job for a resource kind and the usage
+// of the kind in the resource pool
+type respoolFitKind struct {
+	Kind string `json:"kind"`
+	// Demand is the sum of the resources of all instances of the job
+	Demand      float64 `json:"demand"`
+	Reservation float64 `json:"reservation"`
+	Limit       float64 `json:"limit"`
+	Allocation  float64 `json:"allocation"`
+	Slack       float64 `json:"slack"`
+}
+
+// respoolFit is the number of instances which fit under a bound of a
+// resource pool, and the resource kind binding them
+type respoolFit struct {
+	Instances int    `json:"instances"`
+	Binding   string `json:"binding"`
+}
+
+// respoolFitResponse is the JSON output of the respool fit command
+type respoolFitResponse struct {
+	Respool   string           `json:"respool"`
+	Instances int              `json:"instances"`
+	Kinds     []respoolFitKind `json:"kinds"`
+	// Reservation is the fit within the reservation of the pool, which is
+	// guaranteed to it but is not its current entitlement, and Limit the
+	// fit under its limit, which it only reaches while other pools leave
+	// capacity unused
+	Reservation respoolFit `json:"reservation"`
+	Limit       respoolFit `json:"limit"`
+}
+
+// ResPoolFitAction prints how many instances of a job fit in a resource
+// pool right now, within its reservation and under its limit, along with
+// the resource kind binding each of them. The resources of every instance
+// are given by cpu, memMb and gpu for the given number of instances, or by
+// the job config cfg if it is set. The entitlement of the pool is not
+// reported by the resource pool query API, so the fit within the
+// reservation is only what the pool is guaranteed, not its entitlement.
+func (c *Client) ResPoolFitAction(
+	respoolPath string,
+	cpu float64,
+	memMb float64,
+	gpu float64,
+	instances uint32,
+	cfg string) error {
+	var requests []respoolFitRequest
+	if cfg != "" {
+		if cpu != 0 || memMb != 0 || gpu != 0 {
+			return errors.New(
+				"--job-config cannot be used with --cpu, --mem-mb or --gpu")
+		}
+		var err error
+		if requests, err = readJobResources(cfg); err != nil {
+			return err
+		}
+	} else {
+		if cpu < 0 || memMb < 0 || gpu < 0 {
+			return errors.New("resources cannot be negative")
+		}
+		if instances > 0 {
+			requests = append(requests, respoolFitRequest{
+				resources: map[string]float64{
+					common.CPU:    cpu,
+					common.MEMORY: memMb,
+					common.GPU:    gpu,
+				},
+				count: int(instances),
+			})
+		}
+	}
+	if len(requests) == 0 {
+		return errors.New("no instances requested")
+	}
+
+	pools, err := c.queryResourcePools(c.ctx)
+	if err != nil {
+		return err
+	}
+	pool, err := findResourcePool(pools, respoolPath)
+	if err != nil {
+		return err
+	}
+
+	response, err := fitResourcePool(respoolPath, pool, requests)
+	if err != nil {
+		return err
+	}
+	if c.outputFormat() == OutputJSON {
+		printResponseJSON(response)
+		return nil
+	}
+	printRespoolFit(response)
+	return nil
+}
+
+// readJobResources returns the resources of the instances of the job config
+// cfg, taking the instance configs into account, with the consecutive
+// instances of the same resources in a single request
+func readJobResources(cfg string) ([]respoolFitRequest, error) {
+	buffer, err := ioutil.ReadFile(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file %s: %v", cfg, err)
+	}
+	var jobConfig job.JobConfig
+	if err := yaml.Unmarshal(buffer, &jobConfig); err != nil {
+		return nil, fmt.Errorf("unable to parse file %s: %v", cfg, err)
+	}
+
+	var requests []respoolFitRequest
+	for i := uint32(0); i < jobConfig.GetInstanceCount(); i++ {
+		resource := taskconfig.Merge(
+			jobConfig.GetDefaultConfig(),
+			jobConfig.GetInstanceConfig()[i]).GetResource()
+		resources := map[string]float64{
+			common.CPU:    resource.GetCpuLimit(),
+			common.MEMORY: resource.GetMemLimitMb(),
+			common.GPU:    resource.GetGpuLimit(),
+		}
+		if n := len(requests); n > 0 &&
+			reflect.DeepEqual(requests[n-1].resources, resources) {
+			requests[n-1].count++
+			continue
+		}
+		requests = append(requests, respoolFitRequest{
+			resources: resources,
+			count:     1,
+		})
+	}
+	return requests, nil
+}
+
+// findResourcePool returns the resource pool at a path
+func findResourcePool(
+	pools map[string]*respool.ResourcePoolInfo,
+	respoolPath string) (*respool.ResourcePoolInfo, error) {
+	if respoolPath != ResourcePoolPathDelim {
+		respoolPath = strings.TrimSuffix(respoolPath, ResourcePoolPathDelim)
+	}
+	for id, pool := range pools {
+		if path, err := resourcePoolPath(pools, id); err == nil &&
+			path == respoolPath {
+			return pool, nil
+		}
+	}
+	return nil, errors.Errorf("unable to find resource pool %s", respoolPath)
+}
+
+// fitResourcePool returns how many of the instances with the requested
+// resources fit in the pool, within its reservation and under its limit
+func fitResourcePool(
+	respoolPath string,
+	pool *respool.ResourcePoolInfo,
+	requests []respoolFitRequest) (respoolFitResponse, error) {
+	resources := make(map[string]*respool.ResourceConfig)
+	for _, r := range pool.GetConfig().GetResources() {
+		resources[r.GetKind()] = r
+	}
+	allocations := make(map[string]*respool.ResourceUsage)
+	for _, u := range pool.GetUsage() {
+		allocations[u.GetKind()] = u
+	}
+
+	response := respoolFitResponse{Respool: respoolPath}
+	for _, r := range requests {
+		response.Instances += r.count
+	}
+	demand := make(map[string]float64)
+	freeReservation := make(map[string]float64)
+	freeLimit := make(map[string]float64)
+	for _, kind := range respoolTreeKinds {
+		k := respoolFitKind{
+			Kind:        kind,
+			Reservation: resources[kind].GetReservation(),
+			Limit:       resources[kind].GetLimit(),
+			Allocation:  allocations[kind].GetAllocation(),
+			Slack:       allocations[kind].GetSlack(),
+		}
+		for _, r := range requests {
+			k.Demand += r.resources[kind] * float64(r.count)
+		}
+		response.Kinds = append(response.Kinds, k)
+		demand[kind] = k.Demand
+		freeReservation[kind] = k.Reservation - k.Allocation
+		freeLimit[kind] = k.Limit - k.Allocation
+	}
+
+	if _, binding := tightestKind(freeLimit, demand); binding == "" {
+		return respoolFitResponse{}, errors.New(
+			"no resources requested, set --cpu, --mem-mb or --gpu")
+	}
+	response.Reservation = fitInstances(requests, freeReservation, demand)
+	response.Limit = fitInstances(requests, freeLimit, demand)
+	return response, nil
+}
+
+// fitInstances returns how many of the instances fit in order in the free
+// resources. The binding kind is the one which fits the fewest instances of
+// the first request which does not fit entirely, or the tightest kind if
+// all instances fit.
+func fitInstances(
+	requests []respoolFitRequest,
+	free map[string]float64,
+	demand map[string]float64) respoolFit {
+	remaining := make(map[string]float64)
+	for kind, f := range free {
+		remaining[kind] = f
+	}
+
+	fit := respoolFit{}
+	for _, r := range requests {
+		count, binding := r.count, ""
+		for _, kind := range respoolTreeKinds {
+			if r.resources[kind] <= 0 {
+				continue
+			}
+			fits := math.Floor(
+				(remaining[kind] + respoolFitEpsilon) / r.resources[kind])
+			if fits < float64(count) {
+				count, binding = int(math.Max(fits, 0)), kind
+			}
+		}
+		fit.Instances += count
+		if binding != "" {
+			fit.Binding = binding
+			return fit
+		}
+		for kind, v := range r.resources {
+			remaining[kind] -= v * float64(count)
+		}
+	}
+	_, fit.Binding = tightestKind(free, demand)
+	return fit
+}
+
+// tightestKind returns the smallest ratio of the free resources to the
+// demand, and the kind with this ratio, among the kinds with a demand. The
+// kind is empty if there is no demand.
+func tightestKind(
+	free map[string]float64,
+	demand map[string]float64) (float64, string) {
+	var tightest string
+	var ratio float64
+	for _, kind := range respoolTreeKinds {
+		if demand[kind] <= 0 {
+			continue
+		}
+		r := free[kind] / demand[kind]
+		if tightest == "" || r < ratio {
+			tightest, ratio = kind, r
+		}
+	}
+	return ratio, tightest
+}
+
+// printRespoolFit prints the demand and usage of every resource kind, and
+// how many instances fit within the reservation and under the limit
+func printRespoolFit(r respoolFitResponse) {
+	defer tabWriter.Flush()
+	fmt.Fprint(tabWriter, respoolFitFormatHeader)
+	for _, k := range r.Kinds {
+		fmt.Fprintf(tabWriter, respoolFitFormatBody,
+			k.Kind, k.Demand, k.Reservation, k.Limit, k.Allocation, k.Slack)
+	}
+	tabWriter.Flush()
+
+	fmt.Fprintf(tabWriter,
+		"Within reservation: %d of %d instance(s) fit, bound by %s\n",
+		r.Reservation.Instances, r.Instances, r.Reservation.Binding)
+	fmt.Fprintf(tabWriter,
+		"Under limit: %d of %d instance(s) fit, bound by %s\n",
+		r.Limit.Instances, r.Instances, r.Limit.Binding)
+	switch {
+	case r.Limit.Instances < r.Instances:
+		fmt.Fprintf(tabWriter,
+			"The job does not fit in resource pool %s right now\n", r.Respool)
+	case r.Reservation.Instances < r.Instances:
+		fmt.Fprintf(tabWriter,
+			"The job fits under the limit of resource pool %s, but %d "+
+				"instance(s) only run while other pools leave capacity unused\n",
+			r.Respool, r.Instances-r.Reservation.Instances)
+	default:
+		fmt.Fprintf(tabWriter,
+			"The job fits within the reservation of resource pool %s\n",
+			r.Respool)
+	}
+}
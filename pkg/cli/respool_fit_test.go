@@ -0,0 +1,284 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+
+	"github.com/golang/mock/gomock"
+)
+
+// respoolFitJobConfig is a job of 4 instances whose last instance needs
+// more cpu and a gpu
+const respoolFitJobConfig = `
+name: fit
+instancecount: 4
+defaultconfig:
+  resource:
+    cpulimit: 2
+    memlimitmb: 128
+instanceconfig:
+  3:
+    resource:
+      cpulimit: 8
+      memlimitmb: 128
+      gpulimit: 1
+`
+
+// getRespoolFitPool returns a pool which has 6 cpu, 4096MB of memory and 2
+// gpus left within its reservation, and 16 cpu, 8192MB of memory and 4 gpus
+// under its limit
+func getRespoolFitPool() *respool.ResourcePoolInfo {
+	return &respool.ResourcePoolInfo{
+		Config: &respool.ResourcePoolConfig{
+			Name: "fit",
+			Resources: []*respool.ResourceConfig{
+				{Kind: "cpu", Reservation: 10, Limit: 20},
+				{Kind: "memory", Reservation: 5120, Limit: 9216},
+				{Kind: "gpu", Reservation: 2, Limit: 4},
+			},
+		},
+		Usage: []*respool.ResourceUsage{
+			{Kind: "cpu", Allocation: 4, Slack: 1},
+			{Kind: "memory", Allocation: 1024},
+		},
+	}
+}
+
+// respoolFitRequests returns a request of count instances of the given
+// resources
+func respoolFitRequests(cpu, mem, gpu float64, count int) []respoolFitRequest {
+	return []respoolFitRequest{
+		{
+			resources: map[string]float64{
+				"cpu":    cpu,
+				"memory": mem,
+				"gpu":    gpu,
+			},
+			count: count,
+		},
+	}
+}
+
+// TestFitResourcePool tests the number of instances fitting within the
+// reservation and under the limit, and the kind binding them
+func (suite *resPoolActions) TestFitResourcePool() {
+	tt := []struct {
+		msg         string
+		requests    []respoolFitRequest
+		instances   int
+		reservation respoolFit
+		limit       respoolFit
+	}{
+		{
+			msg:         "bound by cpu",
+			instances:   10,
+			requests:    respoolFitRequests(2, 512, 0, 10),
+			reservation: respoolFit{Instances: 3, Binding: "cpu"},
+			limit:       respoolFit{Instances: 8, Binding: "cpu"},
+		},
+		{
+			msg:         "bound by memory",
+			instances:   10,
+			requests:    respoolFitRequests(0.5, 2048, 0, 10),
+			reservation: respoolFit{Instances: 2, Binding: "memory"},
+			limit:       respoolFit{Instances: 4, Binding: "memory"},
+		},
+		{
+			msg:         "bound by gpu",
+			instances:   10,
+			requests:    respoolFitRequests(1, 256, 1, 10),
+			reservation: respoolFit{Instances: 2, Binding: "gpu"},
+			limit:       respoolFit{Instances: 4, Binding: "gpu"},
+		},
+		{
+			msg:         "fits within the reservation, memory is the tightest",
+			instances:   10,
+			requests:    respoolFitRequests(0.1, 400, 0, 10),
+			reservation: respoolFit{Instances: 10, Binding: "memory"},
+			limit:       respoolFit{Instances: 10, Binding: "memory"},
+		},
+		{
+			msg: "fits under the limit only",
+			requests: append(respoolFitRequests(1, 256, 0, 5),
+				respoolFitRequests(2, 256, 0, 5)...),
+			instances:   10,
+			reservation: respoolFit{Instances: 5, Binding: "cpu"},
+			limit:       respoolFit{Instances: 10, Binding: "cpu"},
+		},
+		{
+			msg:         "fractional resources fit exactly",
+			instances:   10,
+			requests:    respoolFitRequests(0.6, 0, 0, 10),
+			reservation: respoolFit{Instances: 10, Binding: "cpu"},
+			limit:       respoolFit{Instances: 10, Binding: "cpu"},
+		},
+		{
+			msg:         "large number of instances",
+			requests:    respoolFitRequests(0.001, 0, 0, 100000),
+			instances:   100000,
+			reservation: respoolFit{Instances: 6000, Binding: "cpu"},
+			limit:       respoolFit{Instances: 16000, Binding: "cpu"},
+		},
+	}
+	for _, t := range tt {
+		response, err := fitResourcePool("/fit", getRespoolFitPool(), t.requests)
+		suite.NoError(err, t.msg)
+		suite.Equal(t.instances, response.Instances, t.msg)
+		suite.Equal(t.reservation, response.Reservation, t.msg)
+		suite.Equal(t.limit, response.Limit, t.msg)
+	}
+}
+
+// TestFitResourcePoolZeroLimit tests that nothing fits in a pool whose
+// limit of a requested kind is zero, or which is allocated beyond its limit
+func (suite *resPoolActions) TestFitResourcePoolZeroLimit() {
+	pool := getRespoolFitPool()
+	pool.Config.Resources[2].Reservation = 0
+	pool.Config.Resources[2].Limit = 0
+	response, err := fitResourcePool(
+		"/fit", pool, respoolFitRequests(1, 256, 1, 3))
+	suite.NoError(err)
+	suite.Equal(respoolFit{Instances: 0, Binding: "gpu"}, response.Reservation)
+	suite.Equal(respoolFit{Instances: 0, Binding: "gpu"}, response.Limit)
+
+	// kinds which are not requested do not bind
+	response, err = fitResourcePool(
+		"/fit", pool, respoolFitRequests(1, 256, 0, 3))
+	suite.NoError(err)
+	suite.Equal(respoolFit{Instances: 3, Binding: "cpu"}, response.Limit)
+
+	// a pool without resources
+	response, err = fitResourcePool("/fit", &respool.ResourcePoolInfo{},
+		respoolFitRequests(1, 0, 0, 3))
+	suite.NoError(err)
+	suite.Equal(respoolFit{Instances: 0, Binding: "cpu"}, response.Reservation)
+	suite.Equal(respoolFit{Instances: 0, Binding: "cpu"}, response.Limit)
+
+	// a pool allocated beyond its limit
+	pool = getRespoolFitPool()
+	pool.Usage[0].Allocation = 25
+	response, err = fitResourcePool(
+		"/fit", pool, respoolFitRequests(1, 0, 0, 3))
+	suite.NoError(err)
+	suite.Equal(respoolFit{Instances: 0, Binding: "cpu"}, response.Limit)
+
+	_, err = fitResourcePool("/fit", pool, respoolFitRequests(0, 0, 0, 3))
+	suite.Error(err)
+}
+
+// expectRespoolFitQuery expects a query of the resource pools, with the
+// fit pool under root
+func (suite *resPoolActions) expectRespoolFitQuery() {
+	pool := getRespoolFitPool()
+	pool.Id = &peloton.ResourcePoolID{Value: "fit"}
+	pool.Parent = &peloton.ResourcePoolID{Value: "root"}
+	suite.mockRespool.EXPECT().
+		Query(gomock.Any(), &respool.QueryRequest{}).
+		Return(&respool.QueryResponse{
+			ResourcePools: []*respool.ResourcePoolInfo{
+				{
+					Id:       &peloton.ResourcePoolID{Value: "root"},
+					Children: []*peloton.ResourcePoolID{{Value: "fit"}},
+				},
+				pool,
+			},
+		}, nil)
+}
+
+// TestClientResPoolFitAction tests printing the fit of explicit resources
+func (suite *resPoolActions) TestClientResPoolFitAction() {
+	c := Client{
+		resClient: suite.mockRespool,
+		ctx:       suite.ctx,
+	}
+	var table bytes.Buffer
+	oldTabWriter := tabWriter
+	tabWriter = tabwriter.NewWriter(&table, 0, 0, 1, ' ', 0)
+	defer func() { tabWriter = oldTabWriter }()
+
+	suite.expectRespoolFitQuery()
+	suite.NoError(c.ResPoolFitAction("/fit/", 1, 1024, 0, 5, ""))
+	suite.Equal(
+		"Kind   Demand Reservation Limit Allocation Slack \n"+
+			"cpu    5      10          20    4          1     \n"+
+			"memory 5120   5120        9216  1024       0     \n"+
+			"gpu    0      2           4     0          0     \n"+
+			"Within reservation: 4 of 5 instance(s) fit, bound by memory\n"+
+			"Under limit: 5 of 5 instance(s) fit, bound by memory\n"+
+			"The job fits under the limit of resource pool /fit/, but 1 "+
+			"instance(s) only run while other pools leave capacity unused\n",
+		table.String())
+
+	suite.expectRespoolFitQuery()
+	suite.Error(c.ResPoolFitAction("/missing", 1, 1024, 0, 5, ""))
+
+	suite.Error(c.ResPoolFitAction("/fit", 1, 1024, 0, 0, ""))
+	suite.Error(c.ResPoolFitAction("/fit", -1, 1024, 0, 1, ""))
+}
+
+// TestClientResPoolFitActionJobConfig tests the fit of the instances of a
+// job config, including their instance configs
+func (suite *resPoolActions) TestClientResPoolFitActionJobConfig() {
+	dir, err := ioutil.TempDir("", "respool-fit")
+	suite.NoError(err)
+	defer os.RemoveAll(dir)
+	cfg := filepath.Join(dir, "job.yaml")
+	suite.NoError(ioutil.WriteFile(cfg, []byte(respoolFitJobConfig), 0644))
+
+	requests, err := readJobResources(cfg)
+	suite.NoError(err)
+	suite.Equal(append(respoolFitRequests(2, 128, 0, 3),
+		respoolFitRequests(8, 128, 1, 1)...), requests)
+
+	c := Client{
+		resClient: suite.mockRespool,
+		ctx:       suite.ctx,
+		Output:    OutputJSON,
+	}
+	output := &fakeOutputter{}
+	oldOutputter := cliOutPutter
+	cliOutPutter = output
+	defer func() { cliOutPutter = oldOutputter }()
+
+	suite.expectRespoolFitQuery()
+	suite.NoError(c.ResPoolFitAction("/fit", 0, 0, 0, 1, cfg))
+	var response respoolFitResponse
+	suite.NoError(json.Unmarshal([]byte(output.Out), &response))
+	suite.Equal("/fit", response.Respool)
+	suite.Equal(4, response.Instances)
+	suite.Equal(respoolFitKind{
+		Kind:        "cpu",
+		Demand:      14,
+		Reservation: 10,
+		Limit:       20,
+		Allocation:  4,
+		Slack:       1,
+	}, response.Kinds[0])
+	suite.Equal(respoolFit{Instances: 3, Binding: "cpu"}, response.Reservation)
+	suite.Equal(respoolFit{Instances: 4, Binding: "cpu"}, response.Limit)
+
+	suite.Error(c.ResPoolFitAction("/fit", 1, 0, 0, 1, cfg))
+	suite.Error(c.ResPoolFitAction("/fit", 0, 0, 0, 1,
+		filepath.Join(dir, "missing.yaml")))
+}
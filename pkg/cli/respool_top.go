@@ -0,0 +1,201 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+)
+
+const (
+	respoolTopFormatHeader = "Respool\tKind\tReservation\tLimit\tAllocation\t" +
+		"Slack\tReserved %\tLimit %\tSlack %\t\n"
+	respoolTopFormatBody = "%s\t%s\t%g\t%g\t%g\t%g\t%s\t%s\t%s\t\n"
+)
+
+// respoolUsage is the usage of a resource kind of a resource pool
+type respoolUsage struct {
+	path        string
+	kind        string
+	reservation float64
+	limit       float64
+	allocation  float64
+	slack       float64
+}
+
+// percent returns part as a percentage of whole, and false if whole is zero
+// and the percentage is undefined
+func percent(part, whole float64) (float64, bool) {
+	if whole == 0 {
+		return 0, false
+	}
+	return part / whole * 100, true
+}
+
+// utilization returns the allocation as a percentage of the reservation
+func (u respoolUsage) utilization() (float64, bool) {
+	return percent(u.allocation, u.reservation)
+}
+
+// limitUtilization returns the allocation as a percentage of the limit
+func (u respoolUsage) limitUtilization() (float64, bool) {
+	return percent(u.allocation, u.limit)
+}
+
+// slackShare returns the slack as a percentage of the allocation
+func (u respoolUsage) slackShare() (float64, bool) {
+	return percent(u.slack, u.allocation)
+}
+
+// formatPercent formats a percentage, or - if it is undefined
+func formatPercent(value float64, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f", value)
+}
+
+// ResPoolTopAction prints the usage of each resource kind of the resource
+// pools of the subtree rooted at respoolPath, sorted by utilization, the
+// allocation as a percentage of the reservation. Only usages with at least
+// threshold percent utilization are printed if threshold is set. With
+// watch set the usages are refreshed every interval. The demand of the
+// pools is not reported by the resource pool query API.
+func (c *Client) ResPoolTopAction(
+	respoolPath string,
+	threshold float64,
+	watch bool,
+	interval time.Duration) error {
+	if !watch {
+		return c.printRespoolTop(c.ctx, respoolPath, threshold)
+	}
+	return c.Watch(interval, func(ctx context.Context) (bool, error) {
+		return false, c.printRespoolTop(ctx, respoolPath, threshold)
+	})
+}
+
+// printRespoolTop queries and prints the usages of the resource pools
+func (c *Client) printRespoolTop(
+	ctx context.Context,
+	respoolPath string,
+	threshold float64) error {
+	pools, err := c.queryResourcePools(ctx)
+	if err != nil {
+		return err
+	}
+	usages, err := respoolUsages(pools, respoolPath)
+	if err != nil {
+		return err
+	}
+
+	defer tabWriter.Flush()
+	fmt.Fprint(tabWriter, respoolTopFormatHeader)
+	for _, u := range usages {
+		utilization, ok := u.utilization()
+		if threshold > 0 && (!ok || utilization < threshold) {
+			continue
+		}
+		fmt.Fprintf(tabWriter, respoolTopFormatBody,
+			u.path,
+			u.kind,
+			u.reservation,
+			u.limit,
+			u.allocation,
+			u.slack,
+			formatPercent(utilization, ok),
+			formatPercent(u.limitUtilization()),
+			formatPercent(u.slackShare()),
+		)
+	}
+	return nil
+}
+
+// respoolUsages returns the usages of the resource kinds of the resource
+// pools of the subtree rooted at respoolPath, sorted by utilization with
+// undefined utilizations last. Kinds a pool neither reserves, limits nor
+// allocates are left out.
+func respoolUsages(
+	pools map[string]*respool.ResourcePoolInfo,
+	respoolPath string) ([]respoolUsage, error) {
+	root := strings.TrimSuffix(respoolPath, ResourcePoolPathDelim)
+	found := false
+
+	var usages []respoolUsage
+	for id, pool := range pools {
+		path, err := resourcePoolPath(pools, id)
+		if err != nil {
+			// orphaned pools are not part of any subtree
+			continue
+		}
+		if path != respoolPath && path != root &&
+			!strings.HasPrefix(path, root+ResourcePoolPathDelim) {
+			continue
+		}
+		found = true
+
+		resources := make(map[string]*respool.ResourceConfig)
+		for _, r := range pool.GetConfig().GetResources() {
+			resources[r.GetKind()] = r
+		}
+		allocations := make(map[string]*respool.ResourceUsage)
+		for _, u := range pool.GetUsage() {
+			allocations[u.GetKind()] = u
+		}
+		for _, kind := range respoolTreeKinds {
+			u := respoolUsage{
+				path:        path,
+				kind:        kind,
+				reservation: resources[kind].GetReservation(),
+				limit:       resources[kind].GetLimit(),
+				allocation:  allocations[kind].GetAllocation(),
+				slack:       allocations[kind].GetSlack(),
+			}
+			if u.reservation == 0 && u.limit == 0 && u.allocation == 0 {
+				continue
+			}
+			usages = append(usages, u)
+		}
+	}
+	if !found {
+		return nil, errors.Errorf("unable to find resource pool %s", respoolPath)
+	}
+
+	kindOrder := make(map[string]int)
+	for i, kind := range respoolTreeKinds {
+		kindOrder[kind] = i
+	}
+	sort.Slice(usages, func(i, j int) bool {
+		ui, oki := usages[i].utilization()
+		uj, okj := usages[j].utilization()
+		if oki != okj {
+			return oki
+		}
+		if ui != uj {
+			return ui > uj
+		}
+		if usages[i].path != usages[j].path {
+			return usages[i].path < usages[j].path
+		}
+		return kindOrder[usages[i].kind] < kindOrder[usages[j].kind]
+	})
+	return usages, nil
+}
@@ -0,0 +1,129 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"text/tabwriter"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+
+	"github.com/golang/mock/gomock"
+)
+
+// getRespoolTopPools returns the resource pool tree with an elastic pool,
+// which has a limit but no reservation, under users
+func (suite *resPoolActions) getRespoolTopPools() map[string]*respool.ResourcePoolInfo {
+	pools := make(map[string]*respool.ResourcePoolInfo)
+	for _, pool := range suite.getRespoolTree() {
+		pools[pool.GetId().GetValue()] = pool
+	}
+	pools["users"].Children = []*peloton.ResourcePoolID{{Value: "elastic"}}
+	pools["elastic"] = &respool.ResourcePoolInfo{
+		Id:     &peloton.ResourcePoolID{Value: "elastic"},
+		Parent: &peloton.ResourcePoolID{Value: "users"},
+		Config: &respool.ResourcePoolConfig{
+			Name: "elastic",
+			Resources: []*respool.ResourceConfig{
+				{Kind: "cpu", Limit: 10},
+			},
+		},
+		Usage: []*respool.ResourceUsage{
+			{Kind: "cpu", Allocation: 5},
+		},
+	}
+	return pools
+}
+
+// TestRespoolUsages tests computing and sorting the utilization of the
+// resource pools of a subtree
+func (suite *resPoolActions) TestRespoolUsages() {
+	usages, err := respoolUsages(suite.getRespoolTopPools(), "/infra")
+	suite.NoError(err)
+
+	type row struct {
+		path, kind, reserved, limit, slack string
+	}
+	var rows []row
+	for _, u := range usages {
+		rows = append(rows, row{
+			path:     u.path,
+			kind:     u.kind,
+			reserved: formatPercent(u.utilization()),
+			limit:    formatPercent(u.limitUtilization()),
+			slack:    formatPercent(u.slackShare()),
+		})
+	}
+	suite.Equal([]row{
+		{"/infra/compute", "cpu", "66.7", "33.3", "2.5"},
+		{"/infra", "cpu", "50.0", "25.0", "2.0"},
+		{"/infra", "memory", "50.0", "25.0", "0.0"},
+		{"/infra", "gpu", "50.0", "25.0", "0.0"},
+		{"/infra/batch", "memory", "50.0", "25.0", "0.0"},
+		{"/infra/compute", "memory", "50.0", "25.0", "0.0"},
+		{"/infra/compute", "gpu", "50.0", "25.0", "0.0"},
+		{"/infra/batch", "cpu", "25.0", "12.5", "10.0"},
+	}, rows)
+
+	_, err = respoolUsages(suite.getRespoolTopPools(), "/missing")
+	suite.Error(err)
+}
+
+// TestRespoolUsagesZeroReservation tests that the utilization of a pool
+// without reservation is undefined instead of dividing by zero
+func (suite *resPoolActions) TestRespoolUsagesZeroReservation() {
+	usages, err := respoolUsages(suite.getRespoolTopPools(), "/users")
+	suite.NoError(err)
+	suite.Len(usages, 3)
+
+	// pools with an undefined utilization are sorted last
+	elastic := usages[len(usages)-1]
+	suite.Equal("/users/elastic", elastic.path)
+	suite.Equal("-", formatPercent(elastic.utilization()))
+	suite.Equal("50.0", formatPercent(elastic.limitUtilization()))
+	suite.Equal("0.0", formatPercent(elastic.slackShare()))
+
+	// the users pool neither allocates cpu nor memory
+	suite.Equal("0.0", formatPercent(usages[0].utilization()))
+	suite.Equal("-", formatPercent(usages[0].slackShare()))
+}
+
+// TestClientResPoolTopAction tests filtering the utilizations by a
+// threshold
+func (suite *resPoolActions) TestClientResPoolTopAction() {
+	c := Client{
+		resClient: suite.mockRespool,
+		ctx:       suite.ctx,
+	}
+	var table bytes.Buffer
+	oldTabWriter := tabWriter
+	tabWriter = tabwriter.NewWriter(&table, 0, 0, 1, ' ', 0)
+	defer func() { tabWriter = oldTabWriter }()
+
+	var pools []*respool.ResourcePoolInfo
+	for _, pool := range suite.getRespoolTopPools() {
+		pools = append(pools, pool)
+	}
+	suite.mockRespool.EXPECT().
+		Query(gomock.Any(), &respool.QueryRequest{}).
+		Return(&respool.QueryResponse{ResourcePools: pools}, nil)
+
+	suite.NoError(c.ResPoolTopAction("/", 60, false, 0))
+	suite.Equal(
+		"Respool        Kind Reservation Limit Allocation Slack Reserved % Limit % Slack % \n"+
+			"/infra/compute cpu  60          120   40         1     66.7       33.3    2.5     \n",
+		table.String())
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+
+	jobmgrtask "github.com/uber/peloton/pkg/jobmgr/task"
+)
+
+const (
+	// secretMaxDataSize is the maximum size in bytes of the data file of
+	// a secret, before it is base64 encoded
+	secretMaxDataSize = 1 << 20
+
+	secretListFormatHeader = "Path\tID\t\n"
+	secretListFormatBody   = "%s\t%s\t\n"
+)
+
+// SecretCreateAction adds a secret mounted at path in the containers of a
+// batch job, with the content of dataFile
+func (c *Client) SecretCreateAction(jobID, path, dataFile string) error {
+	return c.applyJobSecret(jobID, path, dataFile, true, 0)
+}
+
+// SecretUpdateAction replaces the data of the secret mounted at path in the
+// containers of a batch job with the content of dataFile. If version is not
+// zero the update is made against that version of the job configuration
+// instead of the one read.
+func (c *Client) SecretUpdateAction(
+	jobID, path, dataFile string,
+	version uint64) error {
+	return c.applyJobSecret(jobID, path, dataFile, false, version)
+}
+
+// applyJobSecret creates or updates a secret of a job with a job update.
+// The existing secrets of the job are carried by ID without their data, so
+// that they are left unchanged. The update is made against the version of
+// the job configuration read, or version if it is not zero, and the job
+// manager fails it if the configuration is at another version.
+func (c *Client) applyJobSecret(
+	jobID, path, dataFile string,
+	create bool,
+	version uint64) error {
+	if path == "" {
+		return errors.New("secret path cannot be empty")
+	}
+	data, err := readSecretDataFile(dataFile)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.jobGet(jobID)
+	if err != nil {
+		return err
+	}
+	if err := jobGetResponseError(jobID, response); err != nil {
+		return err
+	}
+	config := response.GetJobInfo().GetConfig()
+	if version != 0 {
+		if config.GetChangeLog() == nil {
+			config.ChangeLog = &peloton.ChangeLog{}
+		}
+		config.ChangeLog.Version = version
+	}
+
+	found := false
+	var secrets []*peloton.Secret
+	for _, s := range response.GetSecrets() {
+		if s.GetPath() != path {
+			secrets = append(secrets, jobmgrtask.CreateSecretProto(
+				s.GetId().GetValue(), s.GetPath(), nil))
+			continue
+		}
+		if create {
+			return fmt.Errorf("job %s already has a secret at path %s, "+
+				"use secret update", jobID, path)
+		}
+		found = true
+		secrets = append(secrets, jobmgrtask.CreateSecretProto(
+			s.GetId().GetValue(), path, data))
+	}
+	if !create && !found {
+		return fmt.Errorf("job %s has no secret at path %s, use secret create",
+			jobID, path)
+	}
+	if create {
+		secrets = append(secrets, jobmgrtask.CreateSecretProto("", path, data))
+	}
+
+	updateResponse, err := c.jobClient.Update(c.ctx, &job.UpdateRequest{
+		Id:      &peloton.JobID{Value: jobID},
+		Config:  config,
+		Secrets: secrets,
+	})
+	if err != nil {
+		return err
+	}
+	if updateResponse.GetError() != nil {
+		printJobUpdateResponse(updateResponse, c.Debug)
+		tabWriter.Flush()
+		return fmt.Errorf("failed to update the secrets of job %s", jobID)
+	}
+
+	operation := "Updated"
+	if create {
+		operation = "Created"
+	}
+	fmt.Fprintf(tabWriter, "%s secret %s of job %s from configuration version %d\n",
+		operation, path, jobID, config.GetChangeLog().GetVersion())
+	tabWriter.Flush()
+	return nil
+}
+
+// SecretListAction lists the paths and IDs of the secrets of a job, along
+// with the version of the job configuration. The secret data is never
+// printed.
+func (c *Client) SecretListAction(jobID string) error {
+	response, err := c.jobGet(jobID)
+	if err != nil {
+		return err
+	}
+	if err := jobGetResponseError(jobID, response); err != nil {
+		return err
+	}
+
+	secrets := response.GetSecrets()
+	sort.Slice(secrets, func(i, j int) bool {
+		return secrets[i].GetPath() < secrets[j].GetPath()
+	})
+	fmt.Fprintf(tabWriter, "Job %s configuration version %d, %d secret(s)\n",
+		jobID, response.GetJobInfo().GetConfig().GetChangeLog().GetVersion(),
+		len(secrets))
+	if len(secrets) > 0 {
+		fmt.Fprint(tabWriter, secretListFormatHeader)
+		for _, s := range secrets {
+			fmt.Fprintf(tabWriter, secretListFormatBody,
+				s.GetPath(), s.GetId().GetValue())
+		}
+	}
+	tabWriter.Flush()
+	return nil
+}
+
+// readSecretDataFile reads the data of a secret, rejecting files larger
+// than secretMaxDataSize
+func readSecretDataFile(dataFile string) ([]byte, error) {
+	info, err := os.Stat(dataFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file %s: %v", dataFile, err)
+	}
+	if info.Size() > secretMaxDataSize {
+		return nil, fmt.Errorf(
+			"secret data file %s is %d bytes, larger than the %d bytes limit",
+			dataFile, info.Size(), secretMaxDataSize)
+	}
+	data, err := ioutil.ReadFile(dataFile)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open file %s: %v", dataFile, err)
+	}
+	if len(data) == 0 {
+		return nil, fmt.Errorf("secret data file %s is empty", dataFile)
+	}
+	return data, nil
+}
+
+// jobGetResponseError returns the error of a job get response
+func jobGetResponseError(jobID string, response *job.GetResponse) error {
+	if response.GetError().GetNotFound() != nil {
+		return fmt.Errorf("job %s not found: %s",
+			jobID, response.GetError().GetNotFound().GetMessage())
+	}
+	if response.GetError() != nil {
+		return fmt.Errorf("failed to get job %s: %s",
+			jobID, response.GetError().String())
+	}
+	return nil
+}
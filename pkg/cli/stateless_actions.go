@@ -447,6 +447,9 @@ func (c *Client) StatelessCreateAction(
 	if err := yaml.Unmarshal(buffer, &jobSpec); err != nil {
 		return fmt.Errorf("unable to parse file %s: %v", cfg, err)
 	}
+	if metadata, err := parseJobExportMetadata(buffer); err == nil {
+		printUnprovisionedSecrets(metadata, secretPath, secret)
+	}
 
 	jobSpec.RespoolId = &v1alphapeloton.ResourcePoolID{Value: respoolID.GetValue()}
 
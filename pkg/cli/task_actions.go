@@ -15,10 +15,10 @@
 package cli
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
-	"net/http"
+	"net"
 	"sort"
 	"strings"
 	"time"
@@ -26,10 +26,12 @@ import (
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	"github.com/uber/peloton/.gen/peloton/api/v0/query"
 	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/uber/peloton/pkg/common/util"
 )
 
 const (
-	taskListFormatHeader = "Instance\tName\tState\tHealthy\tStart Time\tRun Time\t" +
+	taskListFormatHeader = "Instance\tName\t%s\tHealthy\tStart Time\tRun Time\t" +
 		"Host\tMessage\tReason\t\n"
 	taskListFormatBody    = "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t\n"
 	podEventsFormatHeader = "Mesos Task Id\tDesired Mesos Task Id\tActual State\tGoal State\tConfig Version\tDesired Config Version\tHealthy\tHost\tMessage\tReason\tUpdate Time\t\n"
@@ -55,7 +57,7 @@ func (c *Client) TaskGetAction(jobID string, instanceID uint32) error {
 	if err != nil {
 		return err
 	}
-	printTaskGetResponse(response, c.Debug)
+	printTaskGetResponse(response, c.Debug, c.colorizer())
 	return nil
 }
 
@@ -78,7 +80,16 @@ func (c *Client) TaskGetCacheAction(jobID string, instanceID uint32) error {
 }
 
 // TaskLogsGetAction is the action to get logs files for given job instance.
-func (c *Client) TaskLogsGetAction(fileName string, jobID string, instanceID uint32, taskID string) error {
+// It locates the file in the sandbox of the task on its Mesos agent and
+// prints it, or only its last tail lines if tail is positive. If follow is
+// set, the file is polled for new data until the task terminates.
+func (c *Client) TaskLogsGetAction(
+	fileName string,
+	jobID string,
+	instanceID uint32,
+	taskID string,
+	follow bool,
+	tail int) error {
 	var request = &task.BrowseSandboxRequest{
 		JobId: &peloton.JobID{
 			Value: jobID,
@@ -95,6 +106,13 @@ func (c *Client) TaskLogsGetAction(fileName string, jobID string, instanceID uin
 		return errors.New(response.Error.String())
 	}
 
+	if len(response.GetPaths()) == 0 {
+		return fmt.Errorf(
+			"no sandbox files found for instance %d of job %s, "+
+				"the task may not have been launched yet",
+			instanceID, jobID)
+	}
+
 	var filePath string
 
 	for _, path := range response.GetPaths() {
@@ -110,43 +128,77 @@ func (c *Client) TaskLogsGetAction(fileName string, jobID string, instanceID uin
 			response.GetPaths())
 	}
 
-	logFileDownloadURL := fmt.Sprintf(
-		"http://%s:%s/files/download?path=%s",
-		response.GetHostname(),
-		response.GetPort(),
-		filePath)
-
-	resp, err := http.Get(logFileDownloadURL)
-	if err != nil {
-		return err
+	file := &agentFile{
+		agent: net.JoinHostPort(response.GetHostname(), response.GetPort()),
+		path:  filePath,
 	}
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
-		return err
+
+	var offset int64
+	if tail > 0 {
+		data, end, err := file.tail(tail)
+		if err != nil {
+			return err
+		}
+		if _, err := taskLogsOutput.Write(data); err != nil {
+			return err
+		}
+		offset = end
 	}
 
-	fmt.Printf("\n\n%s", body)
+	for {
+		// check the task state before reading, so that everything written
+		// before the task terminated is read by the last poll
+		terminal := true
+		if follow {
+			terminal, err = c.taskRunTerminal(jobID, instanceID, taskID)
+			if err != nil {
+				return err
+			}
+		}
 
-	return nil
+		data, _, end, err := file.read(offset)
+		if err != nil {
+			return err
+		}
+		if _, err := taskLogsOutput.Write(data); err != nil {
+			return err
+		}
+		offset = end
+
+		if terminal {
+			return nil
+		}
+		time.Sleep(taskLogsPollInterval)
+	}
 }
 
-// TaskGetEventsAction is the action to get a task instance
-func (c *Client) TaskGetEventsAction(jobID string, instanceID uint32) error {
-	var request = &task.GetPodEventsRequest{
+// taskRunTerminal returns whether the run of the task instance is terminal.
+// An empty taskID refers to the current run of the instance.
+func (c *Client) taskRunTerminal(
+	jobID string,
+	instanceID uint32,
+	taskID string) (bool, error) {
+	response, err := c.taskClient.Get(c.ctx, &task.GetRequest{
 		JobId: &peloton.JobID{
 			Value: jobID,
 		},
 		InstanceId: instanceID,
-	}
-	response, err := c.taskClient.GetPodEvents(c.ctx, request)
+	})
 	if err != nil {
-		return err
+		return false, err
 	}
-	fmt.Fprintf(tabWriter,
-		"** Task Events CLI is deprecated, please use Pod Events CLI  **\n")
-	printPodGetEventsResponse(response, c.Debug)
-	return nil
+	if response.GetNotFound() != nil {
+		return false, errors.New(response.GetNotFound().GetMessage())
+	}
+
+	current := response.GetResult()
+	if taskID == "" ||
+		current.GetRuntime().GetMesosTaskId().GetValue() == taskID {
+		return util.IsPelotonStateTerminal(current.GetRuntime().GetState()), nil
+	}
+
+	// previous runs of an instance are always terminal
+	return true, nil
 }
 
 // PodGetEventsAction returns pod events in reverse chronological order.
@@ -171,47 +223,160 @@ func (c *Client) PodGetEventsAction(
 	return nil
 }
 
-// TaskListAction is the action to list tasks
-func (c *Client) TaskListAction(jobID string, instanceRange *task.InstanceRange) error {
+// TaskListAction is the action to list tasks. With watch set the list is
+// refreshed every interval until all listed tasks are terminal. The tasks
+// can be filtered by comma separated states and hosts, and by a completion
+// window bounded by RFC3339 times or durations relative to now like -2h.
+// Tasks filtered by states or hosts are fetched with the task query API.
+func (c *Client) TaskListAction(
+	jobID string,
+	instanceRange *task.InstanceRange,
+	states string,
+	hosts string,
+	completedAfter string,
+	completedBefore string,
+	watch bool,
+	interval time.Duration) error {
+	filter, err := newTaskFilter(states, hosts, completedAfter, completedBefore)
+	if err != nil {
+		return err
+	}
+	filter.warnClientSide(false)
+
+	if !watch {
+		response, err := c.taskList(c.ctx, jobID, instanceRange, filter)
+		if err != nil {
+			return err
+		}
+		return c.printTaskList(response)
+	}
+
+	return c.Watch(interval, func(ctx context.Context) (bool, error) {
+		response, err := c.taskList(ctx, jobID, instanceRange, filter)
+		if err != nil {
+			return false, err
+		}
+		if err := c.printTaskList(response); err != nil {
+			return false, err
+		}
+		return tasksTerminal(response.GetResult().GetValue()), nil
+	})
+}
+
+func (c *Client) taskList(
+	ctx context.Context,
+	jobID string,
+	instanceRange *task.InstanceRange,
+	filter *taskFilter) (*task.ListResponse, error) {
+	if filter.pushDown() {
+		return c.taskListByQuery(ctx, jobID, instanceRange, filter)
+	}
+
 	var request = &task.ListRequest{
 		JobId: &peloton.JobID{
 			Value: jobID,
 		},
 		Range: instanceRange,
 	}
-	response, err := c.taskClient.List(c.ctx, request)
+	response, err := c.taskClient.List(ctx, request)
+	if err != nil || !filter.clientSide() {
+		return response, err
+	}
+	for instanceID, t := range response.GetResult().GetValue() {
+		if !filter.matchCompletion(t) {
+			delete(response.Result.Value, instanceID)
+		}
+	}
+	return response, nil
+}
 
-	if err != nil {
-		return err
+// taskListByQuery lists the tasks in the instance range with the task query
+// API, which filters them by the states and hosts of the filter. The tasks
+// fetched until the query is truncated are listed with a warning.
+func (c *Client) taskListByQuery(
+	ctx context.Context,
+	jobID string,
+	instanceRange *task.InstanceRange,
+	filter *taskFilter) (*task.ListResponse, error) {
+	tasks, err := c.queryTasksOnHosts(
+		ctx, &peloton.JobID{Value: jobID}, filter.hosts, filter.states)
+	if err := warnIfTruncated(err); err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint32]*task.TaskInfo)
+	for _, t := range filter.filterTasks(tasks) {
+		if instanceRange != nil &&
+			!instanceInRanges(t.GetInstanceId(), []*task.InstanceRange{instanceRange}) {
+			continue
+		}
+		result[t.GetInstanceId()] = t
 	}
-	printTaskListResponse(response, c.Debug)
-	return nil
+	return &task.ListResponse{
+		Result: &task.ListResponse_Result{Value: result},
+	}, nil
 }
 
-// TaskQueryAction is the action to query task
+func (c *Client) printTaskList(response *task.ListResponse) error {
+	return c.printFormattedColumns(response, func() (columnTable, error) {
+		return taskListColumns(response)
+	}, func() error {
+		printTaskListResponse(response, c.Debug, c.colorizer())
+		return nil
+	})
+}
+
+// tasksTerminal returns whether there are tasks and all of them are in a
+// terminal state
+func tasksTerminal(tasks map[uint32]*task.TaskInfo) bool {
+	if len(tasks) == 0 {
+		return false
+	}
+	for _, t := range tasks {
+		if !util.IsPelotonStateTerminal(t.GetRuntime().GetState()) {
+			return false
+		}
+	}
+	return true
+}
+
+// TaskQueryAction is the action to query task. Instances, if not empty,
+// filters the returned tasks by instance ranges like "0-9,15", and the
+// completion window, if set, by the completion time of the tasks. If all is
+// set, or instances is not empty, every page of tasks is fetched, using
+// limit as the page size, so that the instance filter applies to all the
+// tasks of the job and not only to the first page.
 func (c *Client) TaskQueryAction(
 	jobID string,
 	states string,
 	names string,
 	hosts string,
+	completedAfter string,
+	completedBefore string,
+	instances string,
 	limit uint32,
 	offset uint32,
 	sortBy string,
-	sortOrder string) error {
-	var taskStates []task.TaskState
-	var taskNames, taskHosts []string
-	for _, k := range strings.Split(states, labelSeparator) {
-		if k != "" {
-			taskStates = append(taskStates, task.TaskState(task.TaskState_value[k]))
-		}
+	sortOrder string,
+	all bool) error {
+	if all && offset != 0 {
+		return errors.New("offset cannot be used to query all tasks")
 	}
 
-	for _, host := range strings.Split(hosts, labelSeparator) {
-		if host != "" {
-			taskHosts = append(taskHosts, host)
-		}
+	filter, err := newTaskFilter(states, hosts, completedAfter, completedBefore)
+	if err != nil {
+		return err
 	}
 
+	ranges, err := c.resolveInstanceRanges(jobID, nil, instances)
+	if err != nil {
+		return err
+	}
+	if len(ranges) > 0 && offset != 0 {
+		return errors.New("offset cannot be used to query instances")
+	}
+
+	var taskNames []string
 	for _, name := range strings.Split(names, labelSeparator) {
 		if name != "" {
 			taskNames = append(taskNames, name)
@@ -243,9 +408,9 @@ func (c *Client) TaskQueryAction(
 			Value: jobID,
 		},
 		Spec: &task.QuerySpec{
-			TaskStates: taskStates,
+			TaskStates: filter.states,
 			Names:      taskNames,
-			Hosts:      taskHosts,
+			Hosts:      filter.hosts,
 			Pagination: &query.PaginationSpec{
 				Limit:   limit,
 				Offset:  offset,
@@ -253,12 +418,39 @@ func (c *Client) TaskQueryAction(
 			},
 		},
 	}
-	response, err := c.taskClient.Query(c.ctx, request)
+	var response *task.QueryResponse
+	if all || len(ranges) > 0 {
+		var records []*task.TaskInfo
+		err = fetchAllPages("tasks", limit, func(pageOffset, pageSize uint32) (int, bool, error) {
+			request.Spec.Pagination.Offset = pageOffset
+			request.Spec.Pagination.Limit = pageSize
+			page, err := c.taskClient.Query(c.ctx, request)
+			if err != nil {
+				return 0, false, err
+			}
+			response = page
+			for _, t := range page.GetRecords() {
+				if len(ranges) == 0 || instanceInRanges(t.GetInstanceId(), ranges) {
+					records = append(records, t)
+				}
+			}
+			return len(page.GetRecords()), page.GetError() != nil, nil
+		})
+		// the tasks fetched before the truncation are still printed
+		err = warnIfTruncated(err)
+		if err == nil {
+			response.Records = records
+		}
+	} else {
+		response, err = c.taskClient.Query(c.ctx, request)
+	}
 
 	if err != nil {
 		return err
 	}
-	printTaskQueryResponse(response, c.Debug)
+	filter.warnClientSide(true)
+	response.Records = filter.filterTasks(response.GetRecords())
+	printTaskQueryResponse(response, c.Debug, c.colorizer())
 	return nil
 }
 
@@ -274,8 +466,17 @@ func (c *Client) TaskRefreshAction(jobID string, instanceRange *task.InstanceRan
 	return err
 }
 
-// TaskStartAction is the action to start a task
-func (c *Client) TaskStartAction(jobID string, instanceRanges []*task.InstanceRange) error {
+// TaskStartAction is the action to start a task. Instances, if not empty,
+// overrides instanceRanges with ranges like "0-9,15".
+func (c *Client) TaskStartAction(
+	jobID string,
+	instanceRanges []*task.InstanceRange,
+	instances string) error {
+	instanceRanges, err := c.resolveInstanceRanges(jobID, instanceRanges, instances)
+	if err != nil {
+		return err
+	}
+
 	var request = &task.StartRequest{
 		JobId: &peloton.JobID{
 			Value: jobID,
@@ -290,9 +491,15 @@ func (c *Client) TaskStartAction(jobID string, instanceRanges []*task.InstanceRa
 	return nil
 }
 
-// TaskStopAction is the action to stop a task
+// TaskStopAction is the action to stop a task. Instances, if not empty,
+// overrides instanceRanges with ranges like "0-9,15".
 func (c *Client) TaskStopAction(jobID string,
-	instanceRanges []*task.InstanceRange) error {
+	instanceRanges []*task.InstanceRange,
+	instances string) error {
+	instanceRanges, err := c.resolveInstanceRanges(jobID, instanceRanges, instances)
+	if err != nil {
+		return err
+	}
 
 	id := &peloton.JobID{
 		Value: jobID,
@@ -323,8 +530,17 @@ func (c *Client) TaskStopAction(jobID string,
 	return nil
 }
 
-// TaskRestartAction is the action to restart a task
-func (c *Client) TaskRestartAction(jobID string, instanceRanges []*task.InstanceRange) error {
+// TaskRestartAction is the action to restart a task. Instances, if not
+// empty, overrides instanceRanges with ranges like "0-9,15".
+func (c *Client) TaskRestartAction(
+	jobID string,
+	instanceRanges []*task.InstanceRange,
+	instances string) error {
+	instanceRanges, err := c.resolveInstanceRanges(jobID, instanceRanges, instances)
+	if err != nil {
+		return err
+	}
+
 	var request = &task.RestartRequest{
 		JobId: &peloton.JobID{
 			Value: jobID,
@@ -340,7 +556,7 @@ func (c *Client) TaskRestartAction(jobID string, instanceRanges []*task.Instance
 }
 
 // printTask print the single row output of the task
-func printTask(t *task.TaskInfo) {
+func printTask(t *task.TaskInfo, colors stateColorizer) {
 	cfg := t.GetConfig()
 	runtime := t.GetRuntime()
 
@@ -371,7 +587,7 @@ func printTask(t *task.TaskInfo) {
 		taskListFormatBody,
 		t.GetInstanceId(),
 		cfg.GetName(),
-		runtime.GetState().String(),
+		colors.state(runtime.GetState().String()),
 		runtime.GetHealthy().String(),
 		startTimeStr,
 		durationStr,
@@ -381,7 +597,10 @@ func printTask(t *task.TaskInfo) {
 	)
 }
 
-func printTaskGetResponse(r *task.GetResponse, debug bool) {
+func printTaskGetResponse(
+	r *task.GetResponse,
+	debug bool,
+	colors stateColorizer) {
 	defer tabWriter.Flush()
 
 	if debug {
@@ -404,8 +623,8 @@ func printTaskGetResponse(r *task.GetResponse, debug bool) {
 	}
 
 	if r.GetResult() != nil {
-		fmt.Fprint(tabWriter, taskListFormatHeader)
-		printTask(r.GetResult())
+		fmt.Fprintf(tabWriter, taskListFormatHeader, colors.header("State"))
+		printTask(r.GetResult(), colors)
 		return
 	}
 	fmt.Fprint(tabWriter, "Unexpected error, no results in response.\n")
@@ -446,7 +665,10 @@ func printPodGetEventsResponse(r *task.GetPodEventsResponse, debug bool) {
 	}
 }
 
-func printTaskListResponse(r *task.ListResponse, debug bool) {
+func printTaskListResponse(
+	r *task.ListResponse,
+	debug bool,
+	colors stateColorizer) {
 	defer tabWriter.Flush()
 
 	if debug {
@@ -460,7 +682,7 @@ func printTaskListResponse(r *task.ListResponse, debug bool) {
 		return
 	}
 
-	fmt.Fprint(tabWriter, taskListFormatHeader)
+	fmt.Fprintf(tabWriter, taskListFormatHeader, colors.header("State"))
 	// we want to show tasks in sorted order
 	tasks := make(sortedTaskInfoList, len(r.GetResult().GetValue()))
 	i := 0
@@ -471,11 +693,14 @@ func printTaskListResponse(r *task.ListResponse, debug bool) {
 	sort.Sort(tasks)
 
 	for _, t := range tasks {
-		printTask(t)
+		printTask(t, colors)
 	}
 }
 
-func printTaskQueryResponse(r *task.QueryResponse, debug bool) {
+func printTaskQueryResponse(
+	r *task.QueryResponse,
+	debug bool,
+	colors stateColorizer) {
 	defer tabWriter.Flush()
 
 	if debug {
@@ -494,9 +719,9 @@ func printTaskQueryResponse(r *task.QueryResponse, debug bool) {
 		return
 	}
 
-	fmt.Fprint(tabWriter, taskListFormatHeader)
+	fmt.Fprintf(tabWriter, taskListFormatHeader, colors.header("State"))
 	for _, t := range r.GetRecords() {
-		printTask(t)
+		printTask(t, colors)
 	}
 }
 
@@ -15,12 +15,15 @@
 package cli
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"os"
 	"testing"
 	"time"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	jobmocks "github.com/uber/peloton/.gen/peloton/api/v0/job/mocks"
 	taskmocks "github.com/uber/peloton/.gen/peloton/api/v0/task/mocks"
 
 	"github.com/golang/mock/gomock"
@@ -28,6 +31,7 @@ import (
 	"github.com/stretchr/testify/suite"
 
 	pberr "github.com/uber/peloton/.gen/peloton/api/v0/errors"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	"github.com/uber/peloton/.gen/peloton/api/v0/query"
 	"github.com/uber/peloton/.gen/peloton/api/v0/task"
@@ -184,7 +188,7 @@ func (suite *taskActionsTestSuite) TestClientTaskListAction() {
 			t.taskListResponse,
 			t.listError,
 		)
-		err := c.TaskListAction(jobID.Value, nil)
+		err := c.TaskListAction(jobID.Value, nil, "", "", "", "", false, 0)
 		if t.listError != nil {
 			suite.EqualError(err, t.listError.Error())
 		} else {
@@ -202,6 +206,125 @@ func (suite *taskActionsTestSuite) withMockTaskListResponse(
 		Return(resp, err)
 }
 
+// TestClientTaskListFilterPushDown tests that the states and hosts of
+// task list are pushed down to the task query API, with the instance range
+// applied to the fetched tasks
+func (suite *taskActionsTestSuite) TestClientTaskListFilterPushDown() {
+	c := Client{
+		taskClient: suite.mockTask,
+		ctx:        suite.ctx,
+	}
+	jobID := &peloton.JobID{
+		Value: uuid.New(),
+	}
+	var records []*task.TaskInfo
+	for _, t := range suite.getListResult(jobID).GetValue() {
+		records = append(records, t)
+	}
+
+	suite.withMockTaskQueryResponse(
+		&task.QueryRequest{
+			JobId: jobID,
+			Spec: &task.QuerySpec{
+				TaskStates: []task.TaskState{
+					task.TaskState_FAILED,
+					task.TaskState_LOST,
+				},
+				Hosts: []string{"mesos-slave-01", "mesos-slave-02"},
+				Pagination: &query.PaginationSpec{
+					Limit: queryAllPageSize,
+				},
+			},
+		},
+		&task.QueryResponse{Records: records},
+		nil,
+	)
+
+	filter, err := newTaskFilter(
+		"FAILED,lost", "mesos-slave-01,mesos-slave-02", "", "")
+	suite.NoError(err)
+	response, err := c.taskList(suite.ctx, jobID.GetValue(),
+		&task.InstanceRange{From: 1, To: 3}, filter)
+	suite.NoError(err)
+	suite.Len(response.GetResult().GetValue(), 2)
+	suite.Contains(response.GetResult().GetValue(), uint32(1))
+	suite.Contains(response.GetResult().GetValue(), uint32(2))
+}
+
+// TestClientTaskListFilterPushDownTruncated tests that the tasks fetched
+// until the task query is truncated are listed with a warning
+func (suite *taskActionsTestSuite) TestClientTaskListFilterPushDownTruncated() {
+	c := Client{
+		taskClient: suite.mockTask,
+		ctx:        suite.ctx,
+	}
+	jobID := &peloton.JobID{
+		Value: uuid.New(),
+	}
+	var warnings bytes.Buffer
+	warningOutput = &warnings
+	defer func() { warningOutput = os.Stderr }()
+
+	suite.mockTask.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, req *task.QueryRequest) (*task.QueryResponse, error) {
+			var records []*task.TaskInfo
+			offset := req.GetSpec().GetPagination().GetOffset()
+			for i := uint32(0); i < queryAllPageSize; i++ {
+				records = append(records, &task.TaskInfo{InstanceId: offset + i})
+			}
+			return &task.QueryResponse{Records: records}, nil
+		}).
+		Times(queryAllMaxResults / queryAllPageSize)
+
+	filter, err := newTaskFilter("RUNNING", "", "", "")
+	suite.NoError(err)
+	response, err := c.taskList(suite.ctx, jobID.GetValue(), nil, filter)
+	suite.NoError(err)
+	suite.Len(response.GetResult().GetValue(), queryAllMaxResults)
+	suite.Contains(warnings.String(), "more tasks may remain")
+}
+
+// TestClientTaskListFilterClientSide tests that the completion window of
+// task list is applied to the listed tasks, with a warning
+func (suite *taskActionsTestSuite) TestClientTaskListFilterClientSide() {
+	c := Client{
+		taskClient: suite.mockTask,
+		ctx:        suite.ctx,
+	}
+	jobID := &peloton.JobID{
+		Value: uuid.New(),
+	}
+	var warnings bytes.Buffer
+	warningOutput = &warnings
+	defer func() { warningOutput = os.Stderr }()
+
+	suite.withMockTaskListResponse(
+		&task.ListRequest{JobId: jobID},
+		&task.ListResponse{Result: suite.getListResult(jobID)},
+		nil,
+	)
+	suite.NoError(c.TaskListAction(jobID.GetValue(), nil, "", "",
+		"2017-01-03T00:00:00Z", "", false, 0))
+	suite.Contains(warnings.String(), "completion times are filtered client-side")
+
+	filter, err := newTaskFilter("", "", "2017-01-03T00:00:00Z", "")
+	suite.NoError(err)
+	suite.withMockTaskListResponse(
+		&task.ListRequest{JobId: jobID},
+		&task.ListResponse{Result: suite.getListResult(jobID)},
+		nil,
+	)
+	response, err := c.taskList(suite.ctx, jobID.GetValue(), nil, filter)
+	suite.NoError(err)
+	suite.Len(response.GetResult().GetValue(), 1)
+	suite.Equal(task.TaskState_SUCCEEDED,
+		response.GetResult().GetValue()[2].GetRuntime().GetState())
+
+	suite.Error(c.TaskListAction(jobID.GetValue(), nil, "BROKEN", "",
+		"", "", false, 0))
+}
+
 func (suite *taskActionsTestSuite) getQueryResult(
 	jobID *peloton.JobID, states []task.TaskState) []*task.TaskInfo {
 
@@ -554,8 +677,8 @@ func (suite *taskActionsTestSuite) TestClientTaskQueryAction() {
 			t.queryError,
 		)
 		err := c.TaskQueryAction(
-			jobID.Value, "RUNNING", t.names, "taskHost",
-			10, 0, "state", t.orderString,
+			jobID.Value, "RUNNING", t.names, "taskHost", "", "", "",
+			10, 0, "state", t.orderString, false,
 		)
 		if t.queryError != nil {
 			suite.EqualError(err, t.queryError.Error())
@@ -580,7 +703,59 @@ func (suite *taskActionsTestSuite) TestClientTaskQueryActionInvalidOrder() {
 	}
 
 	suite.Error(c.TaskQueryAction(
-		jobID.Value, "RUNNING", "", "taskHost", 10, 0, "state", "ABC"))
+		jobID.Value, "RUNNING", "", "taskHost", "", "", "", 10, 0, "state", "ABC",
+		false))
+}
+
+// TestClientTaskQueryActionWithInstances tests that the instance filter of
+// task query applies to every page of tasks and not only to the first one
+func (suite *taskActionsTestSuite) TestClientTaskQueryActionWithInstances() {
+	mockJob := jobmocks.NewMockJobManagerYARPCClient(suite.mockCtrl)
+	c := Client{
+		taskClient: suite.mockTask,
+		jobClient:  mockJob,
+		ctx:        suite.ctx,
+	}
+
+	jobID := &peloton.JobID{
+		Value: uuid.New(),
+	}
+	mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{Id: jobID}).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{
+				Config: &job.JobConfig{InstanceCount: 3},
+			},
+		}, nil).
+		Times(2)
+
+	records := suite.getQueryResult(
+		jobID, []task.TaskState{task.TaskState_RUNNING})
+	for _, offset := range []uint32{0, 2} {
+		end := int(offset) + 2
+		if end > len(records) {
+			end = len(records)
+		}
+		suite.withMockTaskQueryResponse(
+			&task.QueryRequest{
+				JobId: jobID,
+				Spec: &task.QuerySpec{
+					Pagination: &query.PaginationSpec{
+						Limit:  2,
+						Offset: offset,
+					},
+				},
+			},
+			&task.QueryResponse{Records: records[offset:end]},
+			nil,
+		)
+	}
+	suite.NoError(c.TaskQueryAction(
+		jobID.Value, "", "", "", "", "", "2", 2, 0, "", "ASC", false))
+
+	// an offset cannot be combined with the instance filter
+	suite.Error(c.TaskQueryAction(
+		jobID.Value, "", "", "", "", "", "2", 2, 1, "", "ASC", false))
 }
 
 // TestClientTaskBrowseSandboxAction tests browsing sandbox
@@ -668,7 +843,7 @@ func (suite *taskActionsTestSuite) TestClientTaskBrowseSandboxAction() {
 			BrowseSandbox(gomock.Any(), t.req).
 			Return(t.resp, t.err)
 
-		suite.Error(c.TaskLogsGetAction("get", jobID.Value, instanceID, taskID))
+		suite.Error(c.TaskLogsGetAction("get", jobID.Value, instanceID, taskID, false, 0))
 	}
 }
 
@@ -932,13 +1107,54 @@ func (suite *taskActionsTestSuite) TestClientTaskStartAction() {
 			Return(t.resp, t.err)
 
 		if t.err != nil {
-			suite.Error(c.TaskStartAction(jobID.Value, instanceRange))
+			suite.Error(c.TaskStartAction(jobID.Value, instanceRange, ""))
 		} else {
-			suite.NoError(c.TaskStartAction(jobID.Value, instanceRange))
+			suite.NoError(c.TaskStartAction(jobID.Value, instanceRange, ""))
 		}
 	}
 }
 
+// TestClientTaskRestartActionWithInstances tests restarting instances
+// given as a list of ranges clamped to the job instance count
+func (suite *taskActionsTestSuite) TestClientTaskRestartActionWithInstances() {
+	mockJob := jobmocks.NewMockJobManagerYARPCClient(suite.mockCtrl)
+	c := Client{
+		Debug:      false,
+		taskClient: suite.mockTask,
+		jobClient:  mockJob,
+		dispatcher: nil,
+		ctx:        suite.ctx,
+	}
+
+	jobID := &peloton.JobID{
+		Value: uuid.New(),
+	}
+	getJob := mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{Id: jobID}).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{
+				Config: &job.JobConfig{InstanceCount: 20},
+			},
+		}, nil)
+	suite.mockTask.EXPECT().
+		Restart(gomock.Any(), &task.RestartRequest{
+			JobId: jobID,
+			Ranges: []*task.InstanceRange{
+				{From: 0, To: 10},
+				{From: 15, To: 20},
+			},
+		}).
+		Return(&task.RestartResponse{}, nil).
+		After(getJob)
+	suite.NoError(c.TaskRestartAction(jobID.Value, nil, "15-25, 0-9"))
+
+	// invalid instances fail before restarting
+	mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{Id: jobID}).
+		Return(&job.GetResponse{}, nil)
+	suite.Error(c.TaskRestartAction(jobID.Value, nil, "9-0"))
+}
+
 func (suite *taskActionsTestSuite) TestClientTaskRestartAction() {
 	c := Client{
 		Debug:      false,
@@ -1023,9 +1239,9 @@ func (suite *taskActionsTestSuite) TestClientTaskRestartAction() {
 			Return(t.resp, t.err)
 
 		if t.err != nil {
-			suite.Error(c.TaskRestartAction(jobID.Value, instanceRange))
+			suite.Error(c.TaskRestartAction(jobID.Value, instanceRange, ""))
 		} else {
-			suite.NoError(c.TaskRestartAction(jobID.Value, instanceRange))
+			suite.NoError(c.TaskRestartAction(jobID.Value, instanceRange, ""))
 		}
 	}
 }
@@ -1201,9 +1417,9 @@ func (suite *taskActionsTestSuite) TestClientTaskStopAction() {
 		}
 
 		if t.err == nil && t.secondErr == nil {
-			suite.NoError(c.TaskStopAction(jobID.Value, instanceRange))
+			suite.NoError(c.TaskStopAction(jobID.Value, instanceRange, ""))
 		} else {
-			suite.Error(c.TaskStopAction(jobID.Value, instanceRange))
+			suite.Error(c.TaskStopAction(jobID.Value, instanceRange, ""))
 		}
 	}
 }
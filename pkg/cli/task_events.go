@@ -0,0 +1,97 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+)
+
+const (
+	taskEventsFormatHeader = "Time\tRun\tActual State\tGoal State\tHost\t" +
+		"Config Version\tMessage\t\n"
+	taskEventsFormatBody = "%s\t%s\t%s\t%s\t%s\t%d\t%s\t\n"
+)
+
+// TaskGetEventsAction prints the timeline of the events of an instance,
+// newest first unless reverse is set. The events of the last limit runs
+// are printed, or only the events of runID if it is set.
+func (c *Client) TaskGetEventsAction(
+	jobID string,
+	instanceID uint32,
+	runID string,
+	limit uint64,
+	reverse bool) error {
+	response, err := c.taskClient.GetPodEvents(c.ctx, &task.GetPodEventsRequest{
+		JobId:      &peloton.JobID{Value: jobID},
+		InstanceId: instanceID,
+		RunId:      runID,
+		Limit:      limit,
+	})
+	if err != nil {
+		return err
+	}
+	if response.GetError() != nil {
+		return fmt.Errorf("failed to get events of instance %d of job %s: %s",
+			instanceID, jobID, response.GetError().GetMessage())
+	}
+
+	events := sortTaskEvents(response.GetResult(), reverse)
+	return c.printFormatted(
+		&task.GetPodEventsResponse{Result: events},
+		func() error {
+			printTaskEvents(events)
+			return nil
+		})
+}
+
+// sortTaskEvents sorts events by their timestamp, newest first unless
+// reverse is set. Events with the same timestamp keep their order.
+func sortTaskEvents(events []*task.PodEvent, reverse bool) []*task.PodEvent {
+	sorted := make([]*task.PodEvent, len(events))
+	copy(sorted, events)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		ti := parseTimestamp(sorted[i].GetTimestamp())
+		tj := parseTimestamp(sorted[j].GetTimestamp())
+		if reverse {
+			return ti.Before(tj)
+		}
+		return ti.After(tj)
+	})
+	return sorted
+}
+
+// printTaskEvents prints the timeline of the events of an instance
+func printTaskEvents(events []*task.PodEvent) {
+	defer tabWriter.Flush()
+
+	fmt.Fprint(tabWriter, taskEventsFormatHeader)
+	for _, event := range events {
+		fmt.Fprintf(
+			tabWriter,
+			taskEventsFormatBody,
+			event.GetTimestamp(),
+			event.GetTaskId().GetValue(),
+			event.GetActualState(),
+			event.GetGoalState(),
+			event.GetHostname(),
+			event.GetConfigVersion(),
+			event.GetMessage(),
+		)
+	}
+}
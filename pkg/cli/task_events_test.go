@@ -0,0 +1,193 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"text/tabwriter"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	taskmocks "github.com/uber/peloton/.gen/peloton/api/v0/task/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+type taskEventsTestSuite struct {
+	suite.Suite
+	mockCtrl     *gomock.Controller
+	mockTask     *taskmocks.MockTaskManagerYARPCClient
+	table        *bytes.Buffer
+	oldTabWriter *tabwriter.Writer
+	output       *fakeOutputter
+	oldOutputter outputter
+	client       Client
+}
+
+func (suite *taskEventsTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockTask = taskmocks.NewMockTaskManagerYARPCClient(suite.mockCtrl)
+	suite.table = &bytes.Buffer{}
+	suite.oldTabWriter = tabWriter
+	tabWriter = tabwriter.NewWriter(
+		suite.table, 0, 0, 2, ' ', tabwriter.AlignRight|tabwriter.Debug,
+	)
+	suite.output = &fakeOutputter{}
+	suite.oldOutputter = cliOutPutter
+	cliOutPutter = suite.output
+	suite.client = Client{
+		taskClient: suite.mockTask,
+		ctx:        context.Background(),
+	}
+}
+
+func (suite *taskEventsTestSuite) TearDownTest() {
+	tabWriter = suite.oldTabWriter
+	cliOutPutter = suite.oldOutputter
+	suite.mockCtrl.Finish()
+}
+
+func TestTaskEvents(t *testing.T) {
+	suite.Run(t, new(taskEventsTestSuite))
+}
+
+// newTaskEvent returns an event of a run of instance 0 of the test job
+func newTaskEvent(
+	timestamp string,
+	run int,
+	actualState string,
+	hostname string,
+	configVersion uint64,
+	message string) *task.PodEvent {
+	runID := fmt.Sprintf("%s-0-%d", testJobID, run)
+	return &task.PodEvent{
+		TaskId:        &mesos.TaskID{Value: &runID},
+		ActualState:   actualState,
+		GoalState:     "RUNNING",
+		Timestamp:     timestamp,
+		ConfigVersion: configVersion,
+		Hostname:      hostname,
+		Message:       message,
+	}
+}
+
+// taskEventsFixture returns the events of two runs of an instance, the
+// second one restarted after the first one failed. The events are not
+// in order.
+func taskEventsFixture() []*task.PodEvent {
+	return []*task.PodEvent{
+		newTaskEvent("2019-01-01T00:05:40Z", 2, "RUNNING", "host-2", 2, ""),
+		newTaskEvent("2019-01-01T00:05:30Z", 2, "PENDING", "", 2, "Task restarted"),
+		newTaskEvent("2019-01-01T00:01:00Z", 1, "RUNNING", "host-1", 1, ""),
+		newTaskEvent("2019-01-01T00:05:00Z", 1, "FAILED", "host-1", 1,
+			"Command exited with status 1"),
+		newTaskEvent("2019-01-01T00:00:00Z", 1, "INITIALIZED", "", 1, ""),
+		newTaskEvent("2019-01-01T00:00:10Z", 1, "LAUNCHED", "host-1", 1, "Task launched"),
+	}
+}
+
+// TestTaskGetEventsGolden tests the timeline of the events of an instance
+// in table and JSON output against golden files
+func (suite *taskEventsTestSuite) TestTaskGetEventsGolden() {
+	tt := []struct {
+		json    bool
+		reverse bool
+		golden  string
+		output  func() string
+	}{
+		{
+			golden: "task_events.golden",
+			output: suite.table.String,
+		},
+		{
+			reverse: true,
+			golden:  "task_events_reverse.golden",
+			output:  suite.table.String,
+		},
+		{
+			json:   true,
+			golden: "task_events.json.golden",
+			output: func() string { return suite.output.Out },
+		},
+	}
+
+	for _, t := range tt {
+		suite.table.Reset()
+		suite.client.JSON = t.json
+		suite.mockTask.EXPECT().
+			GetPodEvents(gomock.Any(), &task.GetPodEventsRequest{
+				JobId:      &peloton.JobID{Value: testJobID},
+				InstanceId: 0,
+				Limit:      2,
+			}).
+			Return(&task.GetPodEventsResponse{Result: taskEventsFixture()}, nil)
+		suite.NoError(suite.client.TaskGetEventsAction(testJobID, 0, "", 2, t.reverse))
+
+		expected, err := ioutil.ReadFile(filepath.Join("testdata", t.golden))
+		suite.NoError(err)
+		suite.Equal(string(expected), t.output(), t.golden)
+	}
+}
+
+// TestTaskGetEventsRun tests that the events of a single run are requested
+func (suite *taskEventsTestSuite) TestTaskGetEventsRun() {
+	runID := testJobID + "-0-2"
+	suite.mockTask.EXPECT().
+		GetPodEvents(gomock.Any(), &task.GetPodEventsRequest{
+			JobId:      &peloton.JobID{Value: testJobID},
+			InstanceId: 0,
+			RunId:      runID,
+		}).
+		Return(&task.GetPodEventsResponse{Result: taskEventsFixture()[:2]}, nil)
+	suite.NoError(suite.client.TaskGetEventsAction(testJobID, 0, runID, 0, false))
+	suite.Contains(suite.table.String(), "Task restarted")
+	suite.NotContains(suite.table.String(), "Task launched")
+}
+
+// TestTaskGetEventsError tests that the error of the response is returned
+func (suite *taskEventsTestSuite) TestTaskGetEventsError() {
+	suite.mockTask.EXPECT().
+		GetPodEvents(gomock.Any(), gomock.Any()).
+		Return(&task.GetPodEventsResponse{
+			Error: &task.GetPodEventsResponse_Error{Message: "read failed"},
+		}, nil)
+	suite.EqualError(
+		suite.client.TaskGetEventsAction(testJobID, 0, "", 0, false),
+		"failed to get events of instance 0 of job "+testJobID+": read failed")
+	suite.Empty(suite.table.String())
+}
+
+// TestSortTaskEvents tests that events with the same timestamp keep their
+// order
+func (suite *taskEventsTestSuite) TestSortTaskEvents() {
+	events := []*task.PodEvent{
+		newTaskEvent("2019-01-01T00:00:00Z", 1, "PENDING", "", 1, "first"),
+		newTaskEvent("2019-01-01T00:00:00Z", 1, "READY", "", 1, "second"),
+		newTaskEvent("2019-01-01T00:00:01Z", 1, "RUNNING", "", 1, "third"),
+	}
+	sorted := sortTaskEvents(events, false)
+	suite.Equal([]string{"third", "first", "second"}, []string{
+		sorted[0].GetMessage(), sorted[1].GetMessage(), sorted[2].GetMessage(),
+	})
+	// the events are not sorted in place
+	suite.Equal("first", events[0].GetMessage())
+}
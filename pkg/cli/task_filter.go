@@ -0,0 +1,157 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+)
+
+// taskFilter filters the tasks of task list and task query. The states and
+// hosts are pushed down to the task query API, which does not support
+// filtering by completion time, so the completion window is applied to the
+// fetched tasks.
+type taskFilter struct {
+	states []task.TaskState
+	hosts  []string
+	// completedAfter and completedBefore bound the completion time of the
+	// tasks, they are zero if not set
+	completedAfter  time.Time
+	completedBefore time.Time
+}
+
+// newTaskFilter parses the comma separated states and hosts, and the
+// bounds of the completion time of a task filter
+func newTaskFilter(
+	states string,
+	hosts string,
+	completedAfter string,
+	completedBefore string) (*taskFilter, error) {
+	f := &taskFilter{}
+	for _, s := range strings.Split(states, labelSeparator) {
+		s = strings.ToUpper(strings.TrimSpace(s))
+		if s == "" {
+			continue
+		}
+		state, ok := task.TaskState_value[s]
+		if !ok {
+			return nil, fmt.Errorf("unknown task state %s", s)
+		}
+		f.states = append(f.states, task.TaskState(state))
+	}
+	for _, host := range strings.Split(hosts, labelSeparator) {
+		if host = strings.TrimSpace(host); host != "" {
+			f.hosts = append(f.hosts, host)
+		}
+	}
+
+	now := time.Now()
+	var err error
+	if f.completedAfter, err = parseFilterTime(completedAfter, now); err != nil {
+		return nil, fmt.Errorf("invalid completed after time: %v", err)
+	}
+	if f.completedBefore, err = parseFilterTime(completedBefore, now); err != nil {
+		return nil, fmt.Errorf("invalid completed before time: %v", err)
+	}
+	if !f.completedAfter.IsZero() && !f.completedBefore.IsZero() &&
+		f.completedAfter.After(f.completedBefore) {
+		return nil, fmt.Errorf("completed after time %s is after completed before time %s",
+			f.completedAfter.Format(time.RFC3339),
+			f.completedBefore.Format(time.RFC3339))
+	}
+	return f, nil
+}
+
+// parseFilterTime parses a RFC3339 time, or a duration relative to now like
+// -2h. The time is zero if value is empty.
+func parseFilterTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf(
+			"%s is neither a RFC3339 time nor a duration relative to now like -2h",
+			value)
+	}
+	return now.Add(d), nil
+}
+
+// pushDown returns whether the filter has states or hosts for the task
+// query API
+func (f *taskFilter) pushDown() bool {
+	return len(f.states) > 0 || len(f.hosts) > 0
+}
+
+// clientSide returns whether the filter has a completion window, which is
+// applied to the fetched tasks
+func (f *taskFilter) clientSide() bool {
+	return !f.completedAfter.IsZero() || !f.completedBefore.IsZero()
+}
+
+// warnClientSide prints a warning that the completion window is applied to
+// the fetched tasks. With paged results, pages may then have fewer tasks
+// than the limit.
+func (f *taskFilter) warnClientSide(paged bool) {
+	if !f.clientSide() {
+		return
+	}
+	warning := "Warning: completion times are filtered client-side"
+	if paged {
+		warning += ", pages of the results may have fewer tasks than the limit"
+	}
+	fmt.Fprintln(warningOutput, warning)
+}
+
+// matchCompletion returns whether a task completed within the completion
+// window, tasks which did not complete match only without a window
+func (f *taskFilter) matchCompletion(t *task.TaskInfo) bool {
+	if !f.clientSide() {
+		return true
+	}
+	completed := parseTimestamp(t.GetRuntime().GetCompletionTime())
+	if completed.IsZero() {
+		return false
+	}
+	if !f.completedAfter.IsZero() && completed.Before(f.completedAfter) {
+		return false
+	}
+	if !f.completedBefore.IsZero() && completed.After(f.completedBefore) {
+		return false
+	}
+	return true
+}
+
+// filterTasks returns the tasks which completed within the completion
+// window of the filter
+func (f *taskFilter) filterTasks(tasks []*task.TaskInfo) []*task.TaskInfo {
+	if !f.clientSide() {
+		return tasks
+	}
+	var filtered []*task.TaskInfo
+	for _, t := range tasks {
+		if f.matchCompletion(t) {
+			filtered = append(filtered, t)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFilterTime(t *testing.T) {
+	now := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	parsed, err := parseFilterTime("", now)
+	require.NoError(t, err)
+	assert.True(t, parsed.IsZero())
+
+	parsed, err = parseFilterTime("2019-02-28T10:00:00Z", now)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2019, 2, 28, 10, 0, 0, 0, time.UTC), parsed)
+
+	parsed, err = parseFilterTime("-2h", now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(-2*time.Hour), parsed)
+
+	_, err = parseFilterTime("yesterday", now)
+	assert.Error(t, err)
+}
+
+func TestNewTaskFilter(t *testing.T) {
+	f, err := newTaskFilter("failed, LOST", "host-1,,host-2", "", "")
+	require.NoError(t, err)
+	assert.Equal(t,
+		[]task.TaskState{task.TaskState_FAILED, task.TaskState_LOST}, f.states)
+	assert.Equal(t, []string{"host-1", "host-2"}, f.hosts)
+	assert.True(t, f.pushDown())
+	assert.False(t, f.clientSide())
+
+	_, err = newTaskFilter("BROKEN", "", "", "")
+	assert.EqualError(t, err, "unknown task state BROKEN")
+	_, err = newTaskFilter("", "", "-1h", "-2h")
+	assert.Error(t, err)
+	_, err = newTaskFilter("", "", "", "tomorrow")
+	assert.Error(t, err)
+}
+
+func TestTaskFilterCompletion(t *testing.T) {
+	tasks := []*task.TaskInfo{
+		{InstanceId: 0, Runtime: &task.RuntimeInfo{}},
+		{InstanceId: 1, Runtime: &task.RuntimeInfo{
+			CompletionTime: "2019-03-01T09:00:00.123Z",
+		}},
+		{InstanceId: 2, Runtime: &task.RuntimeInfo{
+			CompletionTime: "2019-03-01T11:00:00Z",
+		}},
+	}
+
+	f, err := newTaskFilter("", "", "", "")
+	require.NoError(t, err)
+	assert.Len(t, f.filterTasks(tasks), 3)
+
+	f, err = newTaskFilter("", "", "2019-03-01T10:00:00Z", "")
+	require.NoError(t, err)
+	assert.False(t, f.pushDown())
+	filtered := f.filterTasks(tasks)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, uint32(2), filtered[0].GetInstanceId())
+
+	f, err = newTaskFilter("", "", "", "2019-03-01T10:00:00Z")
+	require.NoError(t, err)
+	filtered = f.filterTasks(tasks)
+	require.Len(t, filtered, 1)
+	assert.Equal(t, uint32(1), filtered[0].GetInstanceId())
+}
@@ -0,0 +1,427 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/query"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"go.uber.org/multierr"
+)
+
+const (
+	// taskKillBatchSize is the maximum number of instances of a job
+	// stopped by a single stop request of a task kill by host
+	taskKillBatchSize = 100
+
+	// taskKillQueryConcurrency is the maximum number of task queries in
+	// flight while looking for the tasks on a host, as the task query API
+	// only queries the tasks of a single job
+	taskKillQueryConcurrency = 8
+
+	taskKillByHostFormatHeader = "Job\tInstance\tName\tState\tMesos Task Id\t\n"
+	taskKillByHostFormatBody   = "%s\t%d\t%s\t%s\t%s\t\n"
+)
+
+var (
+	// taskKillByHostJobStates are the states of the jobs which may have
+	// tasks running on a host
+	taskKillByHostJobStates = []job.JobState{
+		job.JobState_INITIALIZED,
+		job.JobState_PENDING,
+		job.JobState_RUNNING,
+		job.JobState_KILLING,
+	}
+
+	// taskKillByHostStates are the states of the tasks killed on a host
+	taskKillByHostStates = []task.TaskState{
+		task.TaskState_LAUNCHED,
+		task.TaskState_STARTING,
+		task.TaskState_RUNNING,
+	}
+)
+
+// taskKillResult is the result of killing a single instance of a job
+type taskKillResult struct {
+	instance uint32
+	err      error
+}
+
+// hostTasks are the tasks of a job running on a host
+type hostTasks struct {
+	job   *job.JobSummary
+	tasks []*task.TaskInfo
+}
+
+// TaskKillByHostAction kills the tasks running on a host. The tasks are
+// listed and, unless dryRun is set, stopped once the user confirmed, with
+// the instances of a job batched into stop requests of at most
+// taskKillBatchSize instances. If respoolPath is set only the jobs of the
+// resource pool are queried. Right before the instances of a job are
+// stopped the tasks on the host are queried again, and the instances whose
+// task moved to another host or was relaunched since they were listed are
+// skipped. Nothing is killed if any query is truncated, and an error is
+// returned if any task failed to stop.
+func (c *Client) TaskKillByHostAction(
+	hostname string,
+	respoolPath string,
+	dryRun bool) error {
+	if err := c.checkHostExists(hostname); err != nil {
+		return err
+	}
+
+	var respoolID *peloton.ResourcePoolID
+	if respoolPath != "" {
+		var err error
+		respoolID, err = c.LookupResourcePoolIDCached(respoolPath)
+		if err != nil {
+			return err
+		}
+		if respoolID == nil {
+			return fmt.Errorf("resource pool %s not found", respoolPath)
+		}
+	}
+
+	jobs, err := c.queryAllJobs(&job.QueryRequest{
+		RespoolID: respoolID,
+		Spec: &job.QuerySpec{
+			JobStates: taskKillByHostJobStates,
+			Pagination: &query.PaginationSpec{
+				MaxLimit: queryAllMaxResults,
+			},
+		},
+		SummaryOnly: true,
+	})
+	if IsTruncatedResults(err) {
+		return fmt.Errorf("not killing a partial set of tasks: %v", err)
+	}
+	if err != nil {
+		return err
+	}
+
+	killed, err := c.queryJobTasksOnHost(c.ctx, jobs, hostname)
+	if IsTruncatedResults(err) {
+		return fmt.Errorf("not killing a partial set of tasks: %v", err)
+	}
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, h := range killed {
+		count += len(h.tasks)
+	}
+
+	if count == 0 {
+		fmt.Fprintf(tabWriter, "No running task(s) found on host %s\n", hostname)
+		tabWriter.Flush()
+		return nil
+	}
+
+	fmt.Fprint(tabWriter, taskKillByHostFormatHeader)
+	for _, h := range killed {
+		for _, t := range h.tasks {
+			fmt.Fprintf(tabWriter, taskKillByHostFormatBody,
+				h.job.GetId().GetValue(),
+				t.GetInstanceId(),
+				t.GetConfig().GetName(),
+				t.GetRuntime().GetState(),
+				t.GetRuntime().GetMesosTaskId().GetValue(),
+			)
+		}
+	}
+	tabWriter.Flush()
+
+	if dryRun {
+		fmt.Fprintf(tabWriter, "Dry run, not killing %d task(s)\n", count)
+		tabWriter.Flush()
+		return nil
+	}
+
+	confirmed, err := c.confirm(func() (string, error) {
+		return fmt.Sprintf("Kill %d task(s) of %d job(s) on host %s",
+			count, len(killed), hostname), nil
+	})
+	if err != nil || !confirmed {
+		return err
+	}
+
+	ctx, release := withInterrupt(c.ctx)
+	defer release()
+
+	var errs error
+	stopped, failed, skipped := 0, 0, 0
+	for _, h := range killed {
+		if ctx.Err() != nil {
+			break
+		}
+		jobID := h.job.GetId()
+		tasks, err := c.recheckTasksOnHost(ctx, jobID, hostname, h.tasks)
+		if err != nil {
+			failed += len(h.tasks)
+			errs = multierr.Append(errs, fmt.Errorf(
+				"failed to check the tasks of job %s: %v",
+				jobID.GetValue(), err))
+			fmt.Fprintf(tabWriter, "Failed to check the tasks of job %s: %v\n",
+				jobID.GetValue(), err)
+			tabWriter.Flush()
+			continue
+		}
+		skipped += len(h.tasks) - len(tasks)
+		if len(tasks) == 0 {
+			continue
+		}
+		for _, batch := range instanceBatches(tasks, taskKillBatchSize) {
+			if ctx.Err() != nil {
+				break
+			}
+			for _, result := range c.stopInstances(ctx, jobID, batch) {
+				if result.err != nil {
+					failed++
+					errs = multierr.Append(errs, fmt.Errorf(
+						"failed to kill instance %d of job %s: %v",
+						result.instance, jobID.GetValue(), result.err))
+					fmt.Fprintf(tabWriter,
+						"Failed to kill instance %d of job %s: %v\n",
+						result.instance, jobID.GetValue(), result.err)
+				} else {
+					stopped++
+					fmt.Fprintf(tabWriter, "Killed instance %d of job %s\n",
+						result.instance, jobID.GetValue())
+				}
+			}
+			tabWriter.Flush()
+		}
+	}
+
+	if ctx.Err() != nil {
+		errs = multierr.Append(errs, fmt.Errorf(
+			"interrupted, %d task(s) not processed",
+			count-stopped-failed-skipped))
+	}
+
+	fmt.Fprintf(tabWriter, "Killed %d of %d task(s) on host %s, %d failed",
+		stopped, count, hostname, failed)
+	if skipped > 0 {
+		fmt.Fprintf(tabWriter, ", %d skipped", skipped)
+	}
+	fmt.Fprintln(tabWriter)
+	tabWriter.Flush()
+	return errs
+}
+
+// queryJobTasksOnHost returns the tasks of the jobs running on a host,
+// for the jobs which have any, in the order of the jobs. The tasks of the
+// jobs are queried by a pool of taskKillQueryConcurrency workers, once the
+// rate limiter of the client allows it.
+func (c *Client) queryJobTasksOnHost(
+	ctx context.Context,
+	jobs []*job.JobSummary,
+	hostname string) ([]hostTasks, error) {
+	tasks := make([][]*task.TaskInfo, len(jobs))
+	errs := make([]error, len(jobs))
+	indexes := make(chan int)
+	go func() {
+		defer close(indexes)
+		for i := range jobs {
+			indexes <- i
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < taskKillQueryConcurrency && w < len(jobs); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				if errs[i] = c.limiter.Wait(ctx); errs[i] != nil {
+					continue
+				}
+				tasks[i], errs[i] = c.queryTasksOnHosts(ctx, jobs[i].GetId(),
+					[]string{hostname}, taskKillByHostStates)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var result []hostTasks
+	for i, j := range jobs {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if len(tasks[i]) > 0 {
+			result = append(result, hostTasks{job: j, tasks: tasks[i]})
+		}
+	}
+	return result, nil
+}
+
+// recheckTasksOnHost queries the tasks of a job running on a host again,
+// and returns the tasks which are still running the same run on the host.
+// The tasks which moved to another host or were relaunched are reported as
+// skipped, so that their new run is not stopped.
+func (c *Client) recheckTasksOnHost(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	hostname string,
+	tasks []*task.TaskInfo) ([]*task.TaskInfo, error) {
+	current, err := c.queryTasksOnHosts(
+		ctx, jobID, []string{hostname}, taskKillByHostStates)
+	if err != nil {
+		return nil, err
+	}
+	runs := make(map[uint32]string)
+	for _, t := range current {
+		runs[t.GetInstanceId()] = t.GetRuntime().GetMesosTaskId().GetValue()
+	}
+
+	var result []*task.TaskInfo
+	for _, t := range tasks {
+		run, ok := runs[t.GetInstanceId()]
+		if !ok || run != t.GetRuntime().GetMesosTaskId().GetValue() {
+			fmt.Fprintf(tabWriter,
+				"Skipping instance %d of job %s, its task is no longer "+
+					"running on host %s\n",
+				t.GetInstanceId(), jobID.GetValue(), hostname)
+			continue
+		}
+		result = append(result, t)
+	}
+	return result, nil
+}
+
+// checkHostExists returns an error if the host is not known to the cluster
+func (c *Client) checkHostExists(hostname string) error {
+	response, err := c.hostClient.QueryHosts(c.ctx, &hostsvc.QueryHostsRequest{})
+	if err != nil {
+		return err
+	}
+	for _, h := range response.GetHostInfos() {
+		if h.GetHostname() == hostname {
+			return nil
+		}
+	}
+	return fmt.Errorf("host %s not found", hostname)
+}
+
+// queryTasksOnHosts returns the tasks of a job in one of the states on any
+// of the hosts
+func (c *Client) queryTasksOnHosts(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	hostnames []string,
+	states []task.TaskState) ([]*task.TaskInfo, error) {
+	request := &task.QueryRequest{
+		JobId: jobID,
+		Spec: &task.QuerySpec{
+			TaskStates: states,
+			Hosts:      hostnames,
+			Pagination: &query.PaginationSpec{},
+		},
+	}
+	var tasks []*task.TaskInfo
+	err := fetchAllPages("tasks", queryAllPageSize, func(offset, limit uint32) (int, bool, error) {
+		request.Spec.Pagination.Offset = offset
+		request.Spec.Pagination.Limit = limit
+		response, err := c.taskClient.Query(ctx, request)
+		if err != nil {
+			return 0, false, err
+		}
+		if response.GetError() != nil {
+			return 0, true, errors.New(response.GetError().String())
+		}
+		tasks = append(tasks, response.GetRecords()...)
+		return len(response.GetRecords()), false, nil
+	})
+	return tasks, err
+}
+
+// instanceBatches returns the sorted instances of tasks in batches of at
+// most size instances
+func instanceBatches(tasks []*task.TaskInfo, size int) [][]uint32 {
+	instances := make([]uint32, 0, len(tasks))
+	for _, t := range tasks {
+		instances = append(instances, t.GetInstanceId())
+	}
+	sort.Slice(instances, func(i, j int) bool {
+		return instances[i] < instances[j]
+	})
+
+	var batches [][]uint32
+	for len(instances) > size {
+		batches = append(batches, instances[:size])
+		instances = instances[size:]
+	}
+	return append(batches, instances)
+}
+
+// instanceRanges returns the ranges of sorted instances, merging
+// consecutive instances into one range
+func instanceRanges(instances []uint32) []*task.InstanceRange {
+	var ranges []*task.InstanceRange
+	for _, instance := range instances {
+		if n := len(ranges); n > 0 && ranges[n-1].GetTo() == instance {
+			ranges[n-1].To++
+			continue
+		}
+		ranges = append(ranges, &task.InstanceRange{
+			From: instance,
+			To:   instance + 1,
+		})
+	}
+	return ranges
+}
+
+// stopInstances stops instances of a job with a single stop request, once
+// the rate limiter of the client allows it, and returns the result of every
+// instance
+func (c *Client) stopInstances(
+	ctx context.Context,
+	jobID *peloton.JobID,
+	instances []uint32) []taskKillResult {
+	results := make([]taskKillResult, 0, len(instances))
+	var response *task.StopResponse
+	err := c.limiter.Wait(ctx)
+	if err == nil {
+		response, err = c.taskClient.Stop(ctx, &task.StopRequest{
+			JobId:  jobID,
+			Ranges: instanceRanges(instances),
+		})
+	}
+	if err == nil && response.GetError() != nil {
+		err = errors.New(response.GetError().String())
+	}
+
+	invalid := make(map[uint32]bool)
+	for _, instance := range response.GetInvalidInstanceIds() {
+		invalid[instance] = true
+	}
+	for _, instance := range instances {
+		result := taskKillResult{instance: instance, err: err}
+		if err == nil && invalid[instance] {
+			result.err = errors.New("instance failed to stop")
+		}
+		results = append(results, result)
+	}
+	return results
+}
@@ -0,0 +1,310 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"text/tabwriter"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+	hostsvc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
+	hostmocks "github.com/uber/peloton/.gen/peloton/api/v0/host/svc/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	jobmocks "github.com/uber/peloton/.gen/peloton/api/v0/job/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	respoolmocks "github.com/uber/peloton/.gen/peloton/api/v0/respool/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	taskmocks "github.com/uber/peloton/.gen/peloton/api/v0/task/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+const testKillHostname = "host-1"
+
+type taskKillByHostTestSuite struct {
+	suite.Suite
+	mockCtrl     *gomock.Controller
+	mockHost     *hostmocks.MockHostServiceYARPCClient
+	mockJob      *jobmocks.MockJobManagerYARPCClient
+	mockTask     *taskmocks.MockTaskManagerYARPCClient
+	mockRespool  *respoolmocks.MockResourceManagerYARPCClient
+	output       *bytes.Buffer
+	oldTabWriter *tabwriter.Writer
+	client       Client
+}
+
+func (suite *taskKillByHostTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockHost = hostmocks.NewMockHostServiceYARPCClient(suite.mockCtrl)
+	suite.mockJob = jobmocks.NewMockJobManagerYARPCClient(suite.mockCtrl)
+	suite.mockTask = taskmocks.NewMockTaskManagerYARPCClient(suite.mockCtrl)
+	suite.mockRespool = respoolmocks.NewMockResourceManagerYARPCClient(
+		suite.mockCtrl)
+	suite.output = &bytes.Buffer{}
+	suite.oldTabWriter = tabWriter
+	tabWriter = tabwriter.NewWriter(suite.output, 0, 0, 1, ' ', 0)
+	progressOutput = &bytes.Buffer{}
+	suite.client = Client{
+		hostClient: suite.mockHost,
+		jobClient:  suite.mockJob,
+		taskClient: suite.mockTask,
+		resClient:  suite.mockRespool,
+		ctx:        context.Background(),
+		AssumeYes:  true,
+	}
+}
+
+func (suite *taskKillByHostTestSuite) TearDownTest() {
+	tabWriter = suite.oldTabWriter
+	progressOutput = os.Stderr
+	suite.mockCtrl.Finish()
+}
+
+func TestTaskKillByHost(t *testing.T) {
+	suite.Run(t, new(taskKillByHostTestSuite))
+}
+
+// testKillRecords returns the records of the instances of a job running on
+// the test host, with run as the run of their mesos task id
+func testKillRecords(jobID string, instances []uint32, run int) []*task.TaskInfo {
+	var records []*task.TaskInfo
+	for _, instance := range instances {
+		mesosTaskID := fmt.Sprintf("%s-%d-%d", jobID, instance, run)
+		records = append(records, &task.TaskInfo{
+			InstanceId: instance,
+			Runtime: &task.RuntimeInfo{
+				State:       task.TaskState_RUNNING,
+				MesosTaskId: &mesos.TaskID{Value: &mesosTaskID},
+			},
+		})
+	}
+	return records
+}
+
+// expectEnumeration sets up the host, the jobs of the resource pool and the
+// instances of each job running on the host, which are queried queries
+// times per job
+func (suite *taskKillByHostTestSuite) expectEnumeration(
+	respoolID *peloton.ResourcePoolID,
+	instances map[string][]uint32,
+	queries int) {
+	suite.mockHost.EXPECT().
+		QueryHosts(gomock.Any(), &hostsvc.QueryHostsRequest{}).
+		Return(&hostsvc.QueryHostsResponse{
+			HostInfos: []*host.HostInfo{{Hostname: testKillHostname}},
+		}, nil)
+
+	var jobs []*job.JobSummary
+	for _, id := range []string{"job-1", "job-2"} {
+		if _, ok := instances[id]; ok {
+			jobs = append(jobs, &job.JobSummary{Id: &peloton.JobID{Value: id}})
+		}
+	}
+	suite.mockJob.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, req *job.QueryRequest) {
+			suite.Equal(respoolID, req.GetRespoolID())
+			suite.Equal(taskKillByHostJobStates, req.GetSpec().GetJobStates())
+		}).
+		Return(&job.QueryResponse{Results: jobs}, nil)
+
+	suite.mockTask.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			req *task.QueryRequest) (*task.QueryResponse, error) {
+			suite.Equal([]string{testKillHostname}, req.GetSpec().GetHosts())
+			suite.Equal(taskKillByHostStates, req.GetSpec().GetTaskStates())
+			jobID := req.GetJobId().GetValue()
+			return &task.QueryResponse{
+				Records: testKillRecords(jobID, instances[jobID], 1),
+			}, nil
+		}).
+		Times(len(jobs) * queries)
+}
+
+// TestTaskKillByHost tests that the tasks on the host are stopped with the
+// consecutive instances of a job merged into ranges
+func (suite *taskKillByHostTestSuite) TestTaskKillByHost() {
+	suite.expectEnumeration(nil,
+		map[string][]uint32{"job-1": {5, 0, 1, 2}, "job-2": {7}}, 2)
+
+	suite.mockTask.EXPECT().
+		Stop(gomock.Any(), &task.StopRequest{
+			JobId: &peloton.JobID{Value: "job-1"},
+			Ranges: []*task.InstanceRange{
+				{From: 0, To: 3},
+				{From: 5, To: 6},
+			},
+		}).
+		Return(&task.StopResponse{
+			StoppedInstanceIds: []uint32{0, 1, 5},
+			InvalidInstanceIds: []uint32{2},
+		}, nil)
+	suite.mockTask.EXPECT().
+		Stop(gomock.Any(), &task.StopRequest{
+			JobId:  &peloton.JobID{Value: "job-2"},
+			Ranges: []*task.InstanceRange{{From: 7, To: 8}},
+		}).
+		Return(nil, errors.New("connection refused"))
+
+	err := suite.client.TaskKillByHostAction(testKillHostname, "", false)
+	suite.Error(err)
+	suite.Contains(err.Error(), "failed to kill instance 2 of job job-1")
+	suite.Contains(err.Error(), "failed to kill instance 7 of job job-2")
+	suite.Contains(suite.output.String(), "Killed instance 0 of job job-1\n")
+	suite.Contains(suite.output.String(), "Killed instance 5 of job job-1\n")
+	suite.Contains(suite.output.String(),
+		"Killed 3 of 5 task(s) on host host-1, 2 failed\n")
+}
+
+// TestTaskKillByHostRespool tests that only the jobs of the resource pool
+// are queried
+func (suite *taskKillByHostTestSuite) TestTaskKillByHostRespool() {
+	respoolID := &peloton.ResourcePoolID{Value: "respool-1"}
+	suite.mockRespool.EXPECT().
+		LookupResourcePoolID(gomock.Any(), &respool.LookupRequest{
+			Path: &respool.ResourcePoolPath{Value: "/a"},
+		}).
+		Return(&respool.LookupResponse{Id: respoolID}, nil)
+	suite.expectEnumeration(respoolID,
+		map[string][]uint32{"job-1": {0}}, 2)
+
+	suite.mockTask.EXPECT().
+		Stop(gomock.Any(), &task.StopRequest{
+			JobId:  &peloton.JobID{Value: "job-1"},
+			Ranges: []*task.InstanceRange{{From: 0, To: 1}},
+		}).
+		Return(&task.StopResponse{StoppedInstanceIds: []uint32{0}}, nil)
+
+	suite.NoError(suite.client.TaskKillByHostAction(testKillHostname, "/a", false))
+	suite.Contains(suite.output.String(),
+		"Killed 1 of 1 task(s) on host host-1, 0 failed\n")
+}
+
+// TestTaskKillByHostMovedTasks tests that the instances whose task moved to
+// another host or was relaunched since they were listed are not stopped
+func (suite *taskKillByHostTestSuite) TestTaskKillByHostMovedTasks() {
+	suite.mockHost.EXPECT().
+		QueryHosts(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.QueryHostsResponse{
+			HostInfos: []*host.HostInfo{{Hostname: testKillHostname}},
+		}, nil)
+	suite.mockJob.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		Return(&job.QueryResponse{Results: []*job.JobSummary{
+			{Id: &peloton.JobID{Value: "job-1"}},
+		}}, nil)
+	// instance 1 is relaunched on the host and instance 2 moved away
+	// between the listing and the stop
+	rechecked := append(testKillRecords("job-1", []uint32{0}, 1),
+		testKillRecords("job-1", []uint32{1}, 2)...)
+	gomock.InOrder(
+		suite.mockTask.EXPECT().
+			Query(gomock.Any(), gomock.Any()).
+			Return(&task.QueryResponse{
+				Records: testKillRecords("job-1", []uint32{0, 1, 2}, 1),
+			}, nil),
+		suite.mockTask.EXPECT().
+			Query(gomock.Any(), gomock.Any()).
+			Return(&task.QueryResponse{Records: rechecked}, nil),
+	)
+	suite.mockTask.EXPECT().
+		Stop(gomock.Any(), &task.StopRequest{
+			JobId:  &peloton.JobID{Value: "job-1"},
+			Ranges: []*task.InstanceRange{{From: 0, To: 1}},
+		}).
+		Return(&task.StopResponse{StoppedInstanceIds: []uint32{0}}, nil)
+
+	suite.NoError(suite.client.TaskKillByHostAction(testKillHostname, "", false))
+	output := suite.output.String()
+	suite.Contains(output, "Skipping instance 1 of job job-1, its task is "+
+		"no longer running on host host-1\n")
+	suite.Contains(output, "Skipping instance 2 of job job-1")
+	suite.Contains(output,
+		"Killed 1 of 3 task(s) on host host-1, 0 failed, 2 skipped\n")
+}
+
+// TestTaskKillByHostTruncated tests that no task is killed if the tasks on
+// the host cannot all be listed
+func (suite *taskKillByHostTestSuite) TestTaskKillByHostTruncated() {
+	suite.mockHost.EXPECT().
+		QueryHosts(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.QueryHostsResponse{
+			HostInfos: []*host.HostInfo{{Hostname: testKillHostname}},
+		}, nil)
+	suite.mockJob.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		Return(&job.QueryResponse{Results: []*job.JobSummary{
+			{Id: &peloton.JobID{Value: "job-1"}},
+		}}, nil)
+	suite.mockTask.EXPECT().
+		Query(gomock.Any(), gomock.Any()).
+		Return(&task.QueryResponse{
+			Records: make([]*task.TaskInfo, queryAllPageSize),
+		}, nil).
+		Times(queryAllMaxResults / queryAllPageSize)
+
+	err := suite.client.TaskKillByHostAction(testKillHostname, "", false)
+	suite.Error(err)
+	suite.Contains(err.Error(), "not killing a partial set of tasks")
+	suite.Empty(suite.output.String())
+}
+
+// TestTaskKillByHostDryRun tests that a dry run lists the tasks without
+// stopping them
+func (suite *taskKillByHostTestSuite) TestTaskKillByHostDryRun() {
+	suite.expectEnumeration(nil,
+		map[string][]uint32{"job-1": {0, 1}, "job-2": {3}}, 1)
+	suite.mockTask.EXPECT().Stop(gomock.Any(), gomock.Any()).Times(0)
+
+	suite.NoError(suite.client.TaskKillByHostAction(testKillHostname, "", true))
+	suite.Contains(suite.output.String(), "job-2 3")
+	suite.Contains(suite.output.String(), "Dry run, not killing 3 task(s)\n")
+}
+
+// TestTaskKillByHostNotFound tests that nothing is queried for an unknown
+// host
+func (suite *taskKillByHostTestSuite) TestTaskKillByHostNotFound() {
+	suite.mockHost.EXPECT().
+		QueryHosts(gomock.Any(), gomock.Any()).
+		Return(&hostsvc.QueryHostsResponse{}, nil)
+	suite.EqualError(
+		suite.client.TaskKillByHostAction(testKillHostname, "", false),
+		"host host-1 not found")
+}
+
+// TestInstanceBatches tests batching instances and merging them into ranges
+func (suite *taskKillByHostTestSuite) TestInstanceBatches() {
+	var tasks []*task.TaskInfo
+	for _, instance := range []uint32{4, 3, 0, 1, 2, 9} {
+		tasks = append(tasks, &task.TaskInfo{InstanceId: instance})
+	}
+	batches := instanceBatches(tasks, 4)
+	suite.Equal([][]uint32{{0, 1, 2, 3}, {4, 9}}, batches)
+	suite.Equal([]*task.InstanceRange{{From: 0, To: 4}}, instanceRanges(batches[0]))
+	suite.Equal([]*task.InstanceRange{
+		{From: 4, To: 5},
+		{From: 9, To: 10},
+	}, instanceRanges(batches[1]))
+}
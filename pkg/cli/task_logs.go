@@ -0,0 +1,177 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+const (
+	// taskLogsMaxRedirects is the number of redirects followed when
+	// fetching a file from a Mesos agent
+	taskLogsMaxRedirects = 5
+
+	// taskLogsTailChunkSize is the size of the first chunk read from the
+	// end of a file to find its last lines
+	taskLogsTailChunkSize = 64 * 1024
+)
+
+var (
+	// used for testing
+	taskLogsOutput       io.Writer = os.Stdout
+	taskLogsPollInterval           = time.Second
+	taskLogsHTTPClient             = &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= taskLogsMaxRedirects {
+				return fmt.Errorf("stopped after %d redirects from agent %s",
+					len(via), via[0].URL.Host)
+			}
+			return nil
+		},
+	}
+)
+
+// agentFile is a file in the sandbox of a task on a Mesos agent
+type agentFile struct {
+	// agent is the host:port of the Mesos agent
+	agent string
+	// path is the absolute path of the file on the agent
+	path string
+}
+
+// url returns the URL to download the file from the agent files API
+func (f *agentFile) url() string {
+	return fmt.Sprintf("http://%s/files/download?path=%s",
+		f.agent, url.QueryEscape(f.path))
+}
+
+// read reads the file from offset to its end using a range request, or its
+// last -offset bytes if offset is negative. It returns the data read along
+// with its start and end offsets in the file.
+func (f *agentFile) read(offset int64) ([]byte, int64, int64, error) {
+	req, err := http.NewRequest(http.MethodGet, f.url(), nil)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	if offset >= 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+	} else {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d", offset))
+	}
+
+	resp, err := taskLogsHTTPClient.Do(req)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		var start, last, size int64
+		if _, err := fmt.Sscanf(resp.Header.Get("Content-Range"),
+			"bytes %d-%d/%d", &start, &last, &size); err != nil {
+			return nil, 0, 0, fmt.Errorf(
+				"invalid content range %q from agent %s",
+				resp.Header.Get("Content-Range"), f.agent)
+		}
+		return data, start, start + int64(len(data)), nil
+
+	case http.StatusOK:
+		// the agent ignored the range, skip what was asked to be skipped
+		data, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		size := int64(len(data))
+		start := offset
+		if offset < 0 {
+			start = size + offset
+			if start < 0 {
+				start = 0
+			}
+		}
+		if start > size {
+			start = size
+		}
+		return data[start:], start, size, nil
+
+	case http.StatusRequestedRangeNotSatisfiable:
+		// nothing was written since the last read
+		if offset < 0 {
+			offset = 0
+		}
+		return nil, offset, offset, nil
+
+	case http.StatusNotFound:
+		return nil, 0, 0, fmt.Errorf(
+			"file %s not found on agent %s, "+
+				"the task may not have been launched yet",
+			f.path, f.agent)
+
+	default:
+		body, _ := ioutil.ReadAll(resp.Body)
+		return nil, 0, 0, fmt.Errorf(
+			"unable to read file %s from agent %s: %s %s",
+			f.path, f.agent, resp.Status, bytes.TrimSpace(body))
+	}
+}
+
+// tail returns the last lines of the file, and the offset of its end
+func (f *agentFile) tail(lines int) ([]byte, int64, error) {
+	if lines <= 0 {
+		return nil, 0, errors.New("number of lines to tail must be positive")
+	}
+
+	for chunk := int64(taskLogsTailChunkSize); ; chunk *= 2 {
+		data, start, end, err := f.read(-chunk)
+		if err != nil {
+			return nil, 0, err
+		}
+		if last, ok := lastLines(data, lines); ok || start == 0 {
+			return last, end, nil
+		}
+	}
+}
+
+// lastLines returns the last n lines of data, and whether data contained
+// more than n lines.
+func lastLines(data []byte, n int) ([]byte, bool) {
+	end := len(data)
+	if end > 0 && data[end-1] == '\n' {
+		end--
+	}
+	for i := end - 1; i >= 0; i-- {
+		if data[i] != '\n' {
+			continue
+		}
+		n--
+		if n == 0 {
+			return data[i+1:], true
+		}
+	}
+	return data, false
+}
@@ -0,0 +1,328 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	taskmocks "github.com/uber/peloton/.gen/peloton/api/v0/task/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const testSandboxStdout = "/var/lib/mesos/slaves/s1/frameworks/f1/executors/e1/runs/latest/stdout"
+
+// fakeAgent mimics the files API of a Mesos agent serving a single file
+type fakeAgent struct {
+	sync.Mutex
+	server  *httptest.Server
+	path    string
+	content []byte
+}
+
+func newFakeAgent(path string, content string) *fakeAgent {
+	agent := &fakeAgent{path: path, content: []byte(content)}
+	agent.server = httptest.NewServer(http.HandlerFunc(agent.serveFile))
+	return agent
+}
+
+func (a *fakeAgent) serveFile(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/files/download" ||
+		r.URL.Query().Get("path") != a.path {
+		http.NotFound(w, r)
+		return
+	}
+	a.Lock()
+	content := a.content
+	a.Unlock()
+	http.ServeContent(w, r, "", time.Time{}, bytes.NewReader(content))
+}
+
+func (a *fakeAgent) append(content string) {
+	a.Lock()
+	defer a.Unlock()
+	a.content = append(a.content, content...)
+}
+
+func (a *fakeAgent) hostPort() (string, string) {
+	u, _ := url.Parse(a.server.URL)
+	host, port, _ := net.SplitHostPort(u.Host)
+	return host, port
+}
+
+func (a *fakeAgent) file() *agentFile {
+	host, port := a.hostPort()
+	return &agentFile{agent: net.JoinHostPort(host, port), path: a.path}
+}
+
+func TestAgentFileRead(t *testing.T) {
+	agent := newFakeAgent(testSandboxStdout, "hello\nworld\n")
+	defer agent.server.Close()
+	file := agent.file()
+
+	data, start, end, err := file.read(0)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello\nworld\n", string(data))
+	assert.Equal(t, int64(0), start)
+	assert.Equal(t, int64(12), end)
+
+	data, start, end, err = file.read(6)
+	assert.NoError(t, err)
+	assert.Equal(t, "world\n", string(data))
+	assert.Equal(t, int64(6), start)
+	assert.Equal(t, int64(12), end)
+
+	data, start, end, err = file.read(-6)
+	assert.NoError(t, err)
+	assert.Equal(t, "world\n", string(data))
+	assert.Equal(t, int64(6), start)
+	assert.Equal(t, int64(12), end)
+
+	// nothing new
+	data, _, end, err = file.read(12)
+	assert.NoError(t, err)
+	assert.Empty(t, data)
+	assert.Equal(t, int64(12), end)
+
+	agent.append("again\n")
+	data, _, end, err = file.read(12)
+	assert.NoError(t, err)
+	assert.Equal(t, "again\n", string(data))
+	assert.Equal(t, int64(18), end)
+}
+
+func TestAgentFileReadNotFound(t *testing.T) {
+	agent := newFakeAgent(testSandboxStdout, "")
+	defer agent.server.Close()
+	file := agent.file()
+	file.path = "/does/not/exist"
+
+	_, _, _, err := file.read(0)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found on agent")
+	assert.Contains(t, err.Error(), "may not have been launched yet")
+}
+
+func TestAgentFileReadRedirect(t *testing.T) {
+	agent := newFakeAgent(testSandboxStdout, "hello\nworld\n")
+	defer agent.server.Close()
+
+	redirector := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r,
+				agent.server.URL+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+		}))
+	defer redirector.Close()
+
+	file := agent.file()
+	file.agent = strings.TrimPrefix(redirector.URL, "http://")
+	data, _, _, err := file.read(6)
+	assert.NoError(t, err)
+	assert.Equal(t, "world\n", string(data))
+
+	// redirect loops are reported
+	loop := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, r.URL.RequestURI(), http.StatusFound)
+		}))
+	defer loop.Close()
+	file.agent = strings.TrimPrefix(loop.URL, "http://")
+	_, _, _, err = file.read(0)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "redirects from agent")
+}
+
+func TestAgentFileTail(t *testing.T) {
+	var content bytes.Buffer
+	for i := 0; i < 20000; i++ {
+		content.WriteString("line ")
+		content.WriteString(strings.Repeat("x", i%10))
+		content.WriteString("\n")
+	}
+	agent := newFakeAgent(testSandboxStdout, content.String())
+	defer agent.server.Close()
+	file := agent.file()
+
+	data, end, err := file.tail(2)
+	assert.NoError(t, err)
+	assert.Equal(t, "line xxxxxxxx\nline xxxxxxxxx\n", string(data))
+	assert.Equal(t, int64(content.Len()), end)
+
+	// more lines than fit in the first chunk
+	data, _, err = file.tail(15000)
+	assert.NoError(t, err)
+	assert.Equal(t, 15000, strings.Count(string(data), "\n"))
+	assert.True(t, strings.HasSuffix(content.String(), string(data)))
+
+	// more lines than the file has
+	data, _, err = file.tail(30000)
+	assert.NoError(t, err)
+	assert.Equal(t, content.String(), string(data))
+
+	_, _, err = file.tail(0)
+	assert.Error(t, err)
+}
+
+func TestLastLines(t *testing.T) {
+	last, ok := lastLines([]byte("a\nb\nc\n"), 2)
+	assert.True(t, ok)
+	assert.Equal(t, "b\nc\n", string(last))
+
+	last, ok = lastLines([]byte("a\nb\nc"), 1)
+	assert.True(t, ok)
+	assert.Equal(t, "c", string(last))
+
+	last, ok = lastLines([]byte("a\nb\n"), 2)
+	assert.False(t, ok)
+	assert.Equal(t, "a\nb\n", string(last))
+}
+
+type taskLogsTestSuite struct {
+	suite.Suite
+	mockCtrl *gomock.Controller
+	mockTask *taskmocks.MockTaskManagerYARPCClient
+	agent    *fakeAgent
+	output   *bytes.Buffer
+	client   Client
+}
+
+func (suite *taskLogsTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockTask = taskmocks.NewMockTaskManagerYARPCClient(suite.mockCtrl)
+	suite.agent = newFakeAgent(testSandboxStdout, "hello\n")
+	suite.output = &bytes.Buffer{}
+	taskLogsOutput = suite.output
+	taskLogsPollInterval = time.Millisecond
+	suite.client = Client{
+		taskClient: suite.mockTask,
+		ctx:        context.Background(),
+	}
+}
+
+func (suite *taskLogsTestSuite) TearDownTest() {
+	suite.agent.server.Close()
+	taskLogsOutput = os.Stdout
+	taskLogsPollInterval = time.Second
+	suite.mockCtrl.Finish()
+}
+
+func TestTaskLogs(t *testing.T) {
+	suite.Run(t, new(taskLogsTestSuite))
+}
+
+func (suite *taskLogsTestSuite) expectBrowseSandbox(
+	taskID string,
+	paths ...string) {
+	host, port := suite.agent.hostPort()
+	suite.mockTask.EXPECT().
+		BrowseSandbox(gomock.Any(), &task.BrowseSandboxRequest{
+			JobId:      &peloton.JobID{Value: testJobID},
+			InstanceId: 0,
+			TaskId:     taskID,
+		}).
+		Return(&task.BrowseSandboxResponse{
+			Hostname: host,
+			Port:     port,
+			Paths:    paths,
+		}, nil)
+}
+
+func (suite *taskLogsTestSuite) expectGet(state task.TaskState) *gomock.Call {
+	taskID := "mesos-task-1"
+	return suite.mockTask.EXPECT().
+		Get(gomock.Any(), &task.GetRequest{
+			JobId:      &peloton.JobID{Value: testJobID},
+			InstanceId: 0,
+		}).
+		Return(&task.GetResponse{
+			Result: &task.TaskInfo{
+				Runtime: &task.RuntimeInfo{
+					State:       state,
+					MesosTaskId: &mesos.TaskID{Value: &taskID},
+				},
+			},
+		}, nil)
+}
+
+// TestTaskLogsGet tests fetching a whole log file
+func (suite *taskLogsTestSuite) TestTaskLogsGet() {
+	suite.expectBrowseSandbox("", "/some/other/file", testSandboxStdout)
+	suite.NoError(suite.client.TaskLogsGetAction(
+		"stdout", testJobID, 0, "", false, 0))
+	suite.Equal("hello\n", suite.output.String())
+}
+
+// TestTaskLogsTail tests fetching the last lines of a log file
+func (suite *taskLogsTestSuite) TestTaskLogsTail() {
+	suite.agent.append("world\nagain\n")
+	suite.expectBrowseSandbox("", testSandboxStdout)
+	suite.NoError(suite.client.TaskLogsGetAction(
+		"stdout", testJobID, 0, "", false, 2))
+	suite.Equal("world\nagain\n", suite.output.String())
+}
+
+// TestTaskLogsFollow tests polling a log file until the task terminates
+func (suite *taskLogsTestSuite) TestTaskLogsFollow() {
+	suite.expectBrowseSandbox("", testSandboxStdout)
+	gomock.InOrder(
+		suite.expectGet(task.TaskState_RUNNING),
+		suite.expectGet(task.TaskState_RUNNING).
+			Do(func(context.Context, *task.GetRequest) {
+				suite.agent.append("world\n")
+			}),
+		suite.expectGet(task.TaskState_SUCCEEDED).
+			Do(func(context.Context, *task.GetRequest) {
+				suite.agent.append("done\n")
+			}),
+	)
+	suite.NoError(suite.client.TaskLogsGetAction(
+		"stdout", testJobID, 0, "", true, 0))
+	suite.Equal("hello\nworld\ndone\n", suite.output.String())
+}
+
+// TestTaskLogsFollowPreviousRun tests that following the logs of a
+// previous run of a task stops immediately
+func (suite *taskLogsTestSuite) TestTaskLogsFollowPreviousRun() {
+	suite.expectBrowseSandbox("mesos-task-0", testSandboxStdout)
+	suite.expectGet(task.TaskState_RUNNING)
+	suite.NoError(suite.client.TaskLogsGetAction(
+		"stdout", testJobID, 0, "mesos-task-0", true, 0))
+	suite.Equal("hello\n", suite.output.String())
+}
+
+// TestTaskLogsNotLaunched tests fetching the logs of a task which has no
+// sandbox yet
+func (suite *taskLogsTestSuite) TestTaskLogsNotLaunched() {
+	suite.expectBrowseSandbox("")
+	err := suite.client.TaskLogsGetAction(
+		"stdout", testJobID, 0, "", false, 0)
+	suite.Error(err)
+	suite.Contains(err.Error(), "may not have been launched yet")
+}
@@ -0,0 +1,507 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
+
+	"github.com/uber/peloton/pkg/common"
+	mimir_strategy "github.com/uber/peloton/pkg/placement/plugins/mimir"
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/placement"
+)
+
+// whyPendingQueueLimit is the number of gangs of the pending queues of the
+// resource pool searched for the task
+const whyPendingQueueLimit = 1000
+
+var (
+	// used for testing
+	whyPendingHTTPClient = &http.Client{}
+
+	// whyPendingStates are the states of a task which has not started
+	// running yet
+	whyPendingStates = map[task.TaskState]bool{
+		task.TaskState_INITIALIZED: true,
+		task.TaskState_PENDING:     true,
+		task.TaskState_READY:       true,
+		task.TaskState_PLACING:     true,
+		task.TaskState_PLACED:      true,
+		task.TaskState_LAUNCHING:   true,
+	}
+)
+
+// whyPending is what is known about a task waiting to run, gathered from
+// the job manager, the resource manager and the placement engine. Each
+// source which could not be fetched is left empty and its error recorded.
+type whyPending struct {
+	taskID string
+	info   *task.TaskInfo
+	// respoolID is the resource pool of the job of the task
+	respoolID string
+	// entry is the task in the resource manager, nil if the resource
+	// manager does not track the task
+	entry *resmgrsvc.GetActiveTasksResponse_TaskEntry
+	// queue is the pending queue of the resource pool with the gang of the
+	// task, position the 1-based position of the gang in the queue and
+	// gangSize the number of tasks of the gang. The queue is empty if the
+	// task was not found in the pending queues.
+	queue    string
+	position int
+	gangSize int
+	pool     *respool.ResourcePoolInfo
+	// explanation is why the task did not fit the hosts offered in the last
+	// placement round which failed it, nil if it was not explained
+	explanation *mimir_strategy.TaskExplanation
+	// errors are the errors of the sources which could not be fetched, in
+	// the order they were fetched
+	errors []string
+}
+
+// resourceShortage is a resource kind of which the task needs more than
+// the headroom of its resource pool under the limit
+type resourceShortage struct {
+	kind     string
+	demand   float64
+	headroom float64
+}
+
+// failedRequirement is a placement requirement of the task and the number
+// of hosts which failed it
+type failedRequirement struct {
+	requirement string
+	hosts       int
+}
+
+// TaskWhyPendingAction diagnoses why a task is not running yet. It gathers
+// the state of the task, its position in the pending queues of the resource
+// manager, the headroom of its resource pool and, if placementAddress is
+// set, why the placement engine at placementAddress could not place it.
+// Each source is fetched with its own timeout, and the causes found are
+// printed most likely first even if some sources are unavailable.
+func (c *Client) TaskWhyPendingAction(
+	jobID string,
+	instanceID uint32,
+	placementAddress string,
+	sourceTimeout time.Duration) error {
+	w := &whyPending{
+		taskID: fmt.Sprintf("%s-%d", jobID, instanceID),
+	}
+	fetched := 0
+	fetch := func(source string, f func(ctx context.Context) error) {
+		ctx, cancel := context.WithTimeout(c.ctx, sourceTimeout)
+		defer cancel()
+		if err := f(ctx); err != nil {
+			w.errors = append(w.errors, fmt.Sprintf("%s: %v", source, err))
+			return
+		}
+		fetched++
+	}
+
+	fetch("task", func(ctx context.Context) error {
+		return c.whyPendingTask(ctx, w, jobID, instanceID)
+	})
+	fetch("job", func(ctx context.Context) error {
+		return c.whyPendingJob(ctx, w, jobID)
+	})
+	fetch("resource manager task", func(ctx context.Context) error {
+		return c.whyPendingEntry(ctx, w, jobID)
+	})
+	if w.respoolID != "" {
+		fetch("resource manager pending queues", func(ctx context.Context) error {
+			return c.whyPendingQueue(ctx, w)
+		})
+		fetch("resource pool", func(ctx context.Context) error {
+			return c.whyPendingPool(ctx, w)
+		})
+	}
+	if placementAddress != "" {
+		fetch("placement explanation", func(ctx context.Context) error {
+			return whyPendingExplanation(ctx, w, placementAddress)
+		})
+	}
+
+	if fetched == 0 {
+		return fmt.Errorf("unable to diagnose task %s: %s",
+			w.taskID, strings.Join(w.errors, ", "))
+	}
+	w.print(placementAddress != "")
+	return nil
+}
+
+// whyPendingTask fetches the runtime and resources of the task
+func (c *Client) whyPendingTask(
+	ctx context.Context,
+	w *whyPending,
+	jobID string,
+	instanceID uint32) error {
+	response, err := c.taskClient.Get(ctx, &task.GetRequest{
+		JobId:      &peloton.JobID{Value: jobID},
+		InstanceId: instanceID,
+	})
+	if err != nil {
+		return err
+	}
+	if response.GetNotFound() != nil {
+		return fmt.Errorf("job not found: %s", response.GetNotFound().GetMessage())
+	}
+	if response.GetOutOfRange() != nil {
+		return fmt.Errorf("instance %d out of range of %d instances",
+			instanceID, response.GetOutOfRange().GetInstanceCount())
+	}
+	w.info = response.GetResult()
+	return nil
+}
+
+// whyPendingJob fetches the resource pool of the job
+func (c *Client) whyPendingJob(
+	ctx context.Context,
+	w *whyPending,
+	jobID string) error {
+	response, err := c.jobClient.Get(ctx, &job.GetRequest{
+		Id: &peloton.JobID{Value: jobID},
+	})
+	if err != nil {
+		return err
+	}
+	if err := jobGetResponseError(jobID, response); err != nil {
+		return err
+	}
+	w.respoolID = response.GetJobInfo().GetConfig().GetRespoolID().GetValue()
+	return nil
+}
+
+// whyPendingEntry fetches the state of the task in the resource manager
+func (c *Client) whyPendingEntry(
+	ctx context.Context,
+	w *whyPending,
+	jobID string) error {
+	response, err := c.resMgrClient.GetActiveTasks(ctx, &resmgrsvc.GetActiveTasksRequest{
+		JobID: jobID,
+	})
+	if err != nil {
+		return err
+	}
+	if response.GetError() != nil {
+		return fmt.Errorf("%s", response.GetError().GetMessage())
+	}
+	for _, entries := range response.GetTasksByState() {
+		for _, entry := range entries.GetTaskEntry() {
+			if entry.GetTaskID() == w.taskID {
+				w.entry = entry
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// whyPendingQueue finds the gang of the task in the pending queues of its
+// resource pool
+func (c *Client) whyPendingQueue(ctx context.Context, w *whyPending) error {
+	response, err := c.resMgrClient.GetPendingTasks(ctx, &resmgrsvc.GetPendingTasksRequest{
+		RespoolID: &peloton.ResourcePoolID{Value: w.respoolID},
+		Limit:     whyPendingQueueLimit,
+	})
+	if err != nil {
+		return err
+	}
+	for queue, gangs := range response.GetPendingGangsByQueue() {
+		for i, gang := range gangs.GetPendingGangs() {
+			for _, id := range gang.GetTaskIDs() {
+				if id == w.taskID {
+					w.queue = queue
+					w.position = i + 1
+					w.gangSize = len(gang.GetTaskIDs())
+					return nil
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// whyPendingPool fetches the resource pool of the job
+func (c *Client) whyPendingPool(ctx context.Context, w *whyPending) error {
+	response, err := c.resClient.GetResourcePool(ctx, &respool.GetRequest{
+		Id: &peloton.ResourcePoolID{Value: w.respoolID},
+	})
+	if err != nil {
+		return err
+	}
+	if response.GetError().GetNotFound() != nil {
+		return fmt.Errorf("resource pool %s not found", w.respoolID)
+	}
+	w.pool = response.GetPoolinfo()
+	return nil
+}
+
+// whyPendingExplanation fetches why the placement engine at address could
+// not place the task from its explanations debug endpoint
+func whyPendingExplanation(
+	ctx context.Context,
+	w *whyPending,
+	address string) error {
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s%s?task_id=%s",
+		address, mimir_strategy.ExplanationsPath, url.QueryEscape(w.taskID)), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := whyPendingHTTPClient.Do(request.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s %s", resp.Status, bytes.TrimSpace(body))
+	}
+	explanations := map[string]*mimir_strategy.TaskExplanation{}
+	if err := json.Unmarshal(body, &explanations); err != nil {
+		return fmt.Errorf("invalid explanations: %v", err)
+	}
+	w.explanation = explanations[w.taskID]
+	return nil
+}
+
+// shortages returns the resource kinds of which the task needs more than
+// the headroom of its resource pool, the limit minus the allocation
+func (w *whyPending) shortages() []resourceShortage {
+	if w.info == nil || w.pool == nil {
+		return nil
+	}
+	resource := w.info.GetConfig().GetResource()
+	demands := map[string]float64{
+		common.CPU:    resource.GetCpuLimit(),
+		common.MEMORY: resource.GetMemLimitMb(),
+		common.DISK:   resource.GetDiskLimitMb(),
+		common.GPU:    resource.GetGpuLimit(),
+	}
+	limits := make(map[string]float64)
+	for _, r := range w.pool.GetConfig().GetResources() {
+		limits[r.GetKind()] = r.GetLimit()
+	}
+	allocations := make(map[string]float64)
+	for _, u := range w.pool.GetUsage() {
+		allocations[u.GetKind()] = u.GetAllocation()
+	}
+
+	var result []resourceShortage
+	for _, kind := range []string{common.CPU, common.MEMORY, common.DISK, common.GPU} {
+		headroom := limits[kind] - allocations[kind]
+		if demands[kind] > 0 && demands[kind] > headroom {
+			result = append(result, resourceShortage{
+				kind:     kind,
+				demand:   demands[kind],
+				headroom: headroom,
+			})
+		}
+	}
+	return result
+}
+
+// failedRequirements returns the placement requirements of the task which
+// failed on the hosts offered, failing on the most hosts first
+func (w *whyPending) failedRequirements() []failedRequirement {
+	if w.explanation == nil {
+		return nil
+	}
+	counts := make(map[string]int)
+	for _, explanation := range w.explanation.Hosts {
+		// a requirement counts once per host, even if it failed in several
+		// branches of the requirement of the task
+		failed := make(map[string]bool)
+		for _, leaf := range explanation.Failed() {
+			failed[describeFailedRequirement(leaf)] = true
+		}
+		for requirement := range failed {
+			counts[requirement]++
+		}
+	}
+
+	var result []failedRequirement
+	for requirement, hosts := range counts {
+		result = append(result, failedRequirement{
+			requirement: requirement,
+			hosts:       hosts,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].hosts != result[j].hosts {
+			return result[i].hosts > result[j].hosts
+		}
+		return result[i].requirement < result[j].requirement
+	})
+	return result
+}
+
+// describeFailedRequirement describes a failed requirement with the values
+// compared, if any
+func describeFailedRequirement(explanation *placement.Explanation) string {
+	if explanation.Observed == nil || explanation.Expected == nil {
+		return explanation.Requirement
+	}
+	return fmt.Sprintf("%s (observed %g, expected %s %g)",
+		explanation.Requirement, *explanation.Observed,
+		explanation.Comparison, *explanation.Expected)
+}
+
+// causes returns the likely causes of the task not running, most likely
+// first. The state of the task in the resource manager decides which cause
+// comes first: pending tasks wait for the entitlement of their resource
+// pool, while ready and placing tasks wait for hosts to be placed on.
+func (w *whyPending) causes(explained bool) []string {
+	state := w.info.GetRuntime().GetState()
+	if w.info != nil && !whyPendingStates[state] {
+		return []string{fmt.Sprintf("the task is %s, it is not pending", state)}
+	}
+
+	var quota, queue, constraints, offers []string
+	for _, s := range w.shortages() {
+		quota = append(quota, fmt.Sprintf(
+			"resource pool %s has %g %s of headroom under its limit, the task needs %g",
+			w.poolName(), s.headroom, s.kind, s.demand))
+	}
+	if w.queue != "" && w.position > 1 {
+		queue = append(queue, fmt.Sprintf(
+			"the task is queued behind %d gang(s) in the %s queue of resource pool %s",
+			w.position-1, w.queue, w.poolName()))
+	}
+	if w.explanation != nil {
+		total := len(w.explanation.Hosts)
+		for _, f := range w.failedRequirements() {
+			constraints = append(constraints, fmt.Sprintf(
+				"placement requirement %s failed on %d of %d host(s) offered",
+				f.requirement, f.hosts, total))
+		}
+		if total == 0 {
+			offers = append(offers, "no hosts were offered to the placement "+
+				"engine in the last placement round of the task")
+		}
+	} else if explained && w.placing() {
+		offers = append(offers, "the placement engine did not explain a failed "+
+			"placement of the task, no hosts may be offered to it")
+	}
+
+	var result []string
+	if w.placing() {
+		result = append(result, constraints...)
+		result = append(result, offers...)
+		result = append(result, quota...)
+		result = append(result, queue...)
+	} else {
+		result = append(result, quota...)
+		result = append(result, queue...)
+		result = append(result, constraints...)
+		result = append(result, offers...)
+	}
+	return result
+}
+
+// placing returns whether the resource manager admitted the task and is
+// waiting for it to be placed
+func (w *whyPending) placing() bool {
+	switch w.entry.GetTaskState() {
+	case task.TaskState_READY.String(), task.TaskState_PLACING.String():
+		return true
+	}
+	return false
+}
+
+// poolName returns the path of the resource pool, or its ID if the pool
+// could not be fetched
+func (w *whyPending) poolName() string {
+	if path := w.pool.GetPath().GetValue(); path != "" {
+		return path
+	}
+	return w.respoolID
+}
+
+// print prints what is known about the task followed by the likely causes
+// of it not running, and the sources which could not be fetched
+func (w *whyPending) print(explained bool) {
+	defer tabWriter.Flush()
+
+	fmt.Fprintf(tabWriter, "Task %s\n", w.taskID)
+	if w.info != nil {
+		runtime := w.info.GetRuntime()
+		fmt.Fprintf(tabWriter, "  State:\t%s, goal state %s\n",
+			runtime.GetState(), runtime.GetGoalState())
+		if runtime.GetReason() != "" || runtime.GetMessage() != "" {
+			fmt.Fprintf(tabWriter, "  Reason:\t%s %s\n",
+				runtime.GetReason(), runtime.GetMessage())
+		}
+	}
+	if w.entry != nil {
+		fmt.Fprintf(tabWriter, "  Resource manager:\t%s since %s %s\n",
+			w.entry.GetTaskState(), w.entry.GetLastUpdateTime(),
+			w.entry.GetReason())
+	}
+	if w.queue != "" {
+		fmt.Fprintf(tabWriter, "  Queue:\tgang %d of the %s queue, %d task(s) in the gang\n",
+			w.position, w.queue, w.gangSize)
+	}
+	if w.pool != nil {
+		var headrooms []string
+		allocations := make(map[string]float64)
+		for _, u := range w.pool.GetUsage() {
+			allocations[u.GetKind()] = u.GetAllocation()
+		}
+		for _, r := range w.pool.GetConfig().GetResources() {
+			headrooms = append(headrooms, fmt.Sprintf("%s %g/%g",
+				r.GetKind(), allocations[r.GetKind()], r.GetLimit()))
+		}
+		fmt.Fprintf(tabWriter, "  Resource pool:\t%s, allocation/limit %s\n",
+			w.poolName(), strings.Join(headrooms, ", "))
+	}
+	if w.explanation != nil {
+		fmt.Fprintf(tabWriter, "  Placement:\tfailed on %d host(s) at %s\n",
+			len(w.explanation.Hosts), w.explanation.Time.Format(time.RFC3339))
+	}
+
+	causes := w.causes(explained)
+	if len(causes) == 0 {
+		fmt.Fprintln(tabWriter, "No cause found")
+	} else {
+		fmt.Fprintln(tabWriter, "Likely causes, most likely first:")
+		for i, cause := range causes {
+			fmt.Fprintf(tabWriter, "  %d. %s\n", i+1, cause)
+		}
+	}
+	if len(w.errors) > 0 {
+		fmt.Fprintln(tabWriter, "Partial diagnosis, unable to fetch:")
+		for _, err := range w.errors {
+			fmt.Fprintf(tabWriter, "  %s\n", err)
+		}
+	}
+}
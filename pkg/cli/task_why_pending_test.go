@@ -0,0 +1,365 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"text/tabwriter"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/job"
+	jobmocks "github.com/uber/peloton/.gen/peloton/api/v0/job/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
+	respoolmocks "github.com/uber/peloton/.gen/peloton/api/v0/respool/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
+	taskmocks "github.com/uber/peloton/.gen/peloton/api/v0/task/mocks"
+	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
+	res_mocks "github.com/uber/peloton/.gen/peloton/private/resmgrsvc/mocks"
+
+	"github.com/uber/peloton/pkg/common"
+	mimir_strategy "github.com/uber/peloton/pkg/placement/plugins/mimir"
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/placement"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	_whyPendingJobID   = "job-1"
+	_whyPendingTaskID  = "job-1-3"
+	_whyPendingPoolID  = "respool-1"
+	_whyPendingTimeout = time.Second
+)
+
+type whyPendingTestSuite struct {
+	suite.Suite
+	mockCtrl     *gomock.Controller
+	mockTask     *taskmocks.MockTaskManagerYARPCClient
+	mockJob      *jobmocks.MockJobManagerYARPCClient
+	mockRes      *res_mocks.MockResourceManagerServiceYARPCClient
+	mockRespool  *respoolmocks.MockResourceManagerYARPCClient
+	output       *bytes.Buffer
+	oldTabWriter *tabwriter.Writer
+	// explanations are served by the placement engine server
+	explanations map[string]*mimir_strategy.TaskExplanation
+	server       *httptest.Server
+	client       Client
+}
+
+func (suite *whyPendingTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockTask = taskmocks.NewMockTaskManagerYARPCClient(suite.mockCtrl)
+	suite.mockJob = jobmocks.NewMockJobManagerYARPCClient(suite.mockCtrl)
+	suite.mockRes = res_mocks.NewMockResourceManagerServiceYARPCClient(suite.mockCtrl)
+	suite.mockRespool = respoolmocks.NewMockResourceManagerYARPCClient(suite.mockCtrl)
+	suite.output = &bytes.Buffer{}
+	suite.oldTabWriter = tabWriter
+	tabWriter = tabwriter.NewWriter(suite.output, 0, 0, 1, ' ', 0)
+	suite.client = Client{
+		taskClient:   suite.mockTask,
+		jobClient:    suite.mockJob,
+		resMgrClient: suite.mockRes,
+		resClient:    suite.mockRespool,
+		ctx:          context.Background(),
+	}
+
+	suite.explanations = map[string]*mimir_strategy.TaskExplanation{}
+	suite.server = httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			suite.Equal(mimir_strategy.ExplanationsPath, r.URL.Path)
+			result := map[string]*mimir_strategy.TaskExplanation{}
+			taskID := r.URL.Query().Get("task_id")
+			if explanation, ok := suite.explanations[taskID]; ok {
+				result[taskID] = explanation
+			}
+			json.NewEncoder(w).Encode(result)
+		}))
+}
+
+func (suite *whyPendingTestSuite) TearDownTest() {
+	suite.server.Close()
+	tabWriter = suite.oldTabWriter
+	suite.mockCtrl.Finish()
+}
+
+func TestWhyPending(t *testing.T) {
+	suite.Run(t, new(whyPendingTestSuite))
+}
+
+// address returns the host:port of the placement engine server
+func (suite *whyPendingTestSuite) address() string {
+	return strings.TrimPrefix(suite.server.URL, "http://")
+}
+
+// expectTask expects the task and its job to be fetched, the task needs
+// one cpu and 1024MB of memory
+func (suite *whyPendingTestSuite) expectTask(state task.TaskState) {
+	suite.mockTask.EXPECT().
+		Get(gomock.Any(), &task.GetRequest{
+			JobId:      &peloton.JobID{Value: _whyPendingJobID},
+			InstanceId: 3,
+		}).
+		Return(&task.GetResponse{
+			Result: &task.TaskInfo{
+				InstanceId: 3,
+				Config: &task.TaskConfig{
+					Resource: &task.ResourceConfig{
+						CpuLimit:   1,
+						MemLimitMb: 1024,
+					},
+				},
+				Runtime: &task.RuntimeInfo{
+					State:     state,
+					GoalState: task.TaskState_RUNNING,
+				},
+			},
+		}, nil)
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{
+			Id: &peloton.JobID{Value: _whyPendingJobID},
+		}).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{
+				Config: &job.JobConfig{
+					RespoolID: &peloton.ResourcePoolID{Value: _whyPendingPoolID},
+				},
+			},
+		}, nil)
+}
+
+// expectResMgr expects the state of the task in the resource manager to
+// be fetched, with the gang of the task at position in the pending queue
+// if position is not zero
+func (suite *whyPendingTestSuite) expectResMgr(state task.TaskState, position int) {
+	suite.mockRes.EXPECT().
+		GetActiveTasks(gomock.Any(), &resmgrsvc.GetActiveTasksRequest{
+			JobID: _whyPendingJobID,
+		}).
+		Return(&resmgrsvc.GetActiveTasksResponse{
+			TasksByState: map[string]*resmgrsvc.GetActiveTasksResponse_TaskEntries{
+				state.String(): {
+					TaskEntry: []*resmgrsvc.GetActiveTasksResponse_TaskEntry{
+						{TaskID: "job-1-2", TaskState: state.String()},
+						{TaskID: _whyPendingTaskID, TaskState: state.String()},
+					},
+				},
+			},
+		}, nil)
+
+	var gangs []*resmgrsvc.GetPendingTasksResponse_PendingGang
+	for i := 1; i < position; i++ {
+		gangs = append(gangs, &resmgrsvc.GetPendingTasksResponse_PendingGang{
+			TaskIDs: []string{"job-2-0"},
+		})
+	}
+	if position > 0 {
+		gangs = append(gangs, &resmgrsvc.GetPendingTasksResponse_PendingGang{
+			TaskIDs: []string{_whyPendingTaskID},
+		})
+	}
+	suite.mockRes.EXPECT().
+		GetPendingTasks(gomock.Any(), &resmgrsvc.GetPendingTasksRequest{
+			RespoolID: &peloton.ResourcePoolID{Value: _whyPendingPoolID},
+			Limit:     whyPendingQueueLimit,
+		}).
+		Return(&resmgrsvc.GetPendingTasksResponse{
+			PendingGangsByQueue: map[string]*resmgrsvc.GetPendingTasksResponse_PendingGangs{
+				"PENDING": {PendingGangs: gangs},
+			},
+		}, nil)
+}
+
+// expectPool expects the resource pool of the job to be fetched, with a
+// limit of 10 cpus and 10240MB of memory
+func (suite *whyPendingTestSuite) expectPool(cpuAllocation float64) {
+	suite.mockRespool.EXPECT().
+		GetResourcePool(gomock.Any(), &respool.GetRequest{
+			Id: &peloton.ResourcePoolID{Value: _whyPendingPoolID},
+		}).
+		Return(&respool.GetResponse{
+			Poolinfo: &respool.ResourcePoolInfo{
+				Id:   &peloton.ResourcePoolID{Value: _whyPendingPoolID},
+				Path: &respool.ResourcePoolPath{Value: "/infra/batch"},
+				Config: &respool.ResourcePoolConfig{
+					Resources: []*respool.ResourceConfig{
+						{Kind: common.CPU, Reservation: 5, Limit: 10},
+						{Kind: common.MEMORY, Reservation: 5120, Limit: 10240},
+					},
+				},
+				Usage: []*respool.ResourceUsage{
+					{Kind: common.CPU, Allocation: cpuAllocation},
+					{Kind: common.MEMORY, Allocation: 2048},
+				},
+			},
+		}, nil)
+}
+
+// failedExplanation explains a failed requirement of an observed and an
+// expected value
+func failedExplanation(requirement string, observed, expected float64) *placement.Explanation {
+	return &placement.Explanation{
+		Type:        "label",
+		Requirement: requirement,
+		Observed:    &observed,
+		Comparison:  "equal",
+		Expected:    &expected,
+	}
+}
+
+// TestWhyPendingConstraintBlocked tests that the placement requirements
+// failing on the most hosts come first for a task waiting for placement
+func (suite *whyPendingTestSuite) TestWhyPendingConstraintBlocked() {
+	suite.expectTask(task.TaskState_PENDING)
+	suite.expectResMgr(task.TaskState_READY, 0)
+	suite.expectPool(2)
+	zone := "host.zone=dca1"
+	sku := "host.sku=gpu"
+	suite.explanations[_whyPendingTaskID] = &mimir_strategy.TaskExplanation{
+		Time: time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC),
+		Hosts: map[string]*placement.Explanation{
+			"host-1": {
+				Type: "and",
+				Explanations: []*placement.Explanation{
+					failedExplanation(zone, 0, 1),
+					failedExplanation(sku, 0, 1),
+				},
+			},
+			"host-2": {
+				Type: "and",
+				Explanations: []*placement.Explanation{
+					failedExplanation(zone, 0, 1),
+					{Type: "label", Requirement: sku, Passed: true},
+				},
+			},
+		},
+	}
+
+	suite.NoError(suite.client.TaskWhyPendingAction(
+		_whyPendingJobID, 3, suite.address(), _whyPendingTimeout))
+	output := suite.output.String()
+	suite.Contains(output, "Task job-1-3")
+	suite.Contains(output, "Resource manager: READY")
+	suite.Contains(output, "Placement:        failed on 2 host(s) at 2019-03-01T12:00:00Z")
+	suite.Contains(output, "1. placement requirement host.zone=dca1 "+
+		"(observed 0, expected equal 1) failed on 2 of 2 host(s) offered")
+	suite.Contains(output, "2. placement requirement host.sku=gpu "+
+		"(observed 0, expected equal 1) failed on 1 of 2 host(s) offered")
+	suite.NotContains(output, "headroom")
+	suite.NotContains(output, "Partial diagnosis")
+}
+
+// TestWhyPendingQuotaBlocked tests that a shortage of headroom of the
+// resource pool comes first for a task waiting for entitlement, followed
+// by its position in the pending queue
+func (suite *whyPendingTestSuite) TestWhyPendingQuotaBlocked() {
+	suite.expectTask(task.TaskState_PENDING)
+	suite.expectResMgr(task.TaskState_PENDING, 3)
+	suite.expectPool(9.5)
+
+	suite.NoError(suite.client.TaskWhyPendingAction(
+		_whyPendingJobID, 3, suite.address(), _whyPendingTimeout))
+	output := suite.output.String()
+	suite.Contains(output, "Queue:            gang 3 of the PENDING queue, 1 task(s) in the gang")
+	suite.Contains(output, "Resource pool:    /infra/batch, allocation/limit cpu 9.5/10, memory 2048/10240")
+	suite.Contains(output, "1. resource pool /infra/batch has 0.5 cpu of "+
+		"headroom under its limit, the task needs 1")
+	suite.Contains(output, "2. the task is queued behind 2 gang(s) in the "+
+		"PENDING queue of resource pool /infra/batch")
+	suite.NotContains(output, "  3. ")
+}
+
+// TestWhyPendingNoOffers tests that a task failing placement without hosts
+// offered is diagnosed as having no offers
+func (suite *whyPendingTestSuite) TestWhyPendingNoOffers() {
+	suite.expectTask(task.TaskState_PENDING)
+	suite.expectResMgr(task.TaskState_PLACING, 0)
+	suite.expectPool(2)
+	suite.explanations[_whyPendingTaskID] = &mimir_strategy.TaskExplanation{
+		Time:  time.Now(),
+		Hosts: map[string]*placement.Explanation{},
+	}
+
+	suite.NoError(suite.client.TaskWhyPendingAction(
+		_whyPendingJobID, 3, suite.address(), _whyPendingTimeout))
+	suite.Contains(suite.output.String(), "1. no hosts were offered to the "+
+		"placement engine in the last placement round of the task")
+
+	// without an explanation from the placement engine
+	suite.output.Reset()
+	delete(suite.explanations, _whyPendingTaskID)
+	suite.expectTask(task.TaskState_PENDING)
+	suite.expectResMgr(task.TaskState_PLACING, 0)
+	suite.expectPool(2)
+	suite.NoError(suite.client.TaskWhyPendingAction(
+		_whyPendingJobID, 3, suite.address(), _whyPendingTimeout))
+	suite.Contains(suite.output.String(), "1. the placement engine did not "+
+		"explain a failed placement of the task")
+}
+
+// TestWhyPendingPartial tests that the diagnosis is printed from the
+// sources which could be fetched, and fails only if none could be
+func (suite *whyPendingTestSuite) TestWhyPendingPartial() {
+	suite.expectTask(task.TaskState_PENDING)
+	suite.mockRes.EXPECT().
+		GetActiveTasks(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("resmgr unavailable"))
+	suite.mockRes.EXPECT().
+		GetPendingTasks(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("resmgr unavailable"))
+	suite.expectPool(9.5)
+	suite.server.Close()
+
+	suite.NoError(suite.client.TaskWhyPendingAction(
+		_whyPendingJobID, 3, suite.address(), _whyPendingTimeout))
+	output := suite.output.String()
+	suite.Contains(output, "1. resource pool /infra/batch has 0.5 cpu")
+	suite.Contains(output, "Partial diagnosis, unable to fetch:")
+	suite.Contains(output, "resource manager task: resmgr unavailable")
+	suite.Contains(output, "resource manager pending queues: resmgr unavailable")
+	suite.Contains(output, "placement explanation:")
+
+	suite.mockTask.EXPECT().
+		Get(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("jobmgr unavailable"))
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("jobmgr unavailable"))
+	suite.mockRes.EXPECT().
+		GetActiveTasks(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("resmgr unavailable"))
+	suite.Error(suite.client.TaskWhyPendingAction(
+		_whyPendingJobID, 3, "", _whyPendingTimeout))
+}
+
+// TestWhyPendingNotPending tests that a running task is not diagnosed
+func (suite *whyPendingTestSuite) TestWhyPendingNotPending() {
+	suite.expectTask(task.TaskState_RUNNING)
+	suite.expectResMgr(task.TaskState_RUNNING, 0)
+	suite.expectPool(9.5)
+
+	suite.NoError(suite.client.TaskWhyPendingAction(
+		_whyPendingJobID, 3, "", _whyPendingTimeout))
+	output := suite.output.String()
+	suite.Contains(output, "1. the task is RUNNING, it is not pending")
+	suite.NotContains(output, "  2. ")
+}
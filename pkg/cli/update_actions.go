@@ -15,11 +15,16 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/uber/peloton/.gen/peloton/api/v0/job"
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
 	"github.com/uber/peloton/.gen/peloton/api/v0/update"
 	updatesvc "github.com/uber/peloton/.gen/peloton/api/v0/update/svc"
 
@@ -32,6 +37,19 @@ const (
 		"NumberTasksFailed\tNumberTasksRemaining\n"
 	updateListFormatBody = "%s\t%s\t%d\t%d\t%d\n"
 	invalidVersionError  = "invalid job configuration version"
+
+	updateStatusFormatHeader = "Update-ID\tJob\tState\tDone\tFailed\tRemaining\t" +
+		"Current Instances\tUpdated Instances\t\n"
+	updateStatusFormatBody = "%s\t%s\t%s\t%d\t%d\t%d\t%s\t%s\t\n"
+)
+
+// Exit codes of the update status command with --watch
+const (
+	UpdateStatusExitSucceeded  = 0
+	UpdateStatusExitError      = 1
+	UpdateStatusExitRolledBack = 2
+	UpdateStatusExitFailed     = 3
+	UpdateStatusExitAborted    = 4
 )
 
 // isUpdateTerminated returns true if update is complete or abortee
@@ -45,12 +63,76 @@ func (c *Client) isUpdateTerminated(updateID *peloton.UpdateID) (bool, error) {
 		return false, err
 	}
 
-	switch response.GetUpdateInfo().GetStatus().GetState() {
+	return isUpdateStateTerminal(response.GetUpdateInfo().GetStatus().GetState()), nil
+}
+
+// isUpdateStateTerminal returns whether an update in the state has completed
+func isUpdateStateTerminal(state update.State) bool {
+	switch state {
 	case update.State_SUCCEEDED, update.State_ABORTED,
 		update.State_FAILED, update.State_ROLLED_BACK:
-		return true, nil
+		return true
+	}
+	return false
+}
+
+// resolveUpdateID returns the current update of the job if id identifies a
+// job, and id as the update identifier otherwise
+func (c *Client) resolveUpdateID(id string) (*peloton.UpdateID, error) {
+	response, err := c.jobClient.Get(c.ctx, &job.GetRequest{
+		Id: &peloton.JobID{Value: id},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if response.GetError().GetNotFound() != nil || response.GetJobInfo() == nil {
+		return &peloton.UpdateID{Value: id}, nil
+	}
+	if response.GetError() != nil {
+		return nil, fmt.Errorf("failed to get job %s: %s",
+			id, response.GetError().String())
 	}
-	return false, nil
+
+	updateID := response.GetJobInfo().GetRuntime().GetUpdateID()
+	if len(updateID.GetValue()) == 0 {
+		return nil, fmt.Errorf("job %s has no update", id)
+	}
+	return updateID, nil
+}
+
+// updateInstanceRanges returns the instance ranges an update is restricted
+// to, parsed from a list of instance ranges or made of the lowest canary
+// percentage of the instances of the job, rounded up. It returns no ranges
+// if neither are given.
+func (c *Client) updateInstanceRanges(
+	instances string,
+	canaryPercent uint32,
+	instanceCount uint32,
+	newInstanceCount uint32) ([]*task.InstanceRange, error) {
+	if instances == "" && canaryPercent == 0 {
+		return nil, nil
+	}
+	if newInstanceCount != instanceCount {
+		return nil, fmt.Errorf(
+			"instance count cannot be changed from %d to %d by an update "+
+				"restricted to instances", instanceCount, newInstanceCount)
+	}
+	if instanceCount == 0 {
+		return nil, fmt.Errorf("job has no instances to update")
+	}
+	if instances != "" {
+		return c.ExtractInstanceRanges(instances, instanceCount)
+	}
+	return canaryInstanceRanges(instanceCount, canaryPercent), nil
+}
+
+// canaryInstanceRanges returns the range of the lowest instances making
+// the percentage of the instances, rounded up.
+func canaryInstanceRanges(
+	instanceCount uint32,
+	percent uint32) []*task.InstanceRange {
+	canaryCount := (uint64(instanceCount)*uint64(percent) + 99) / 100
+	return []*task.InstanceRange{{From: 0, To: uint32(canaryCount)}}
 }
 
 // UpdateCreateAction will create a new job update.
@@ -66,10 +148,20 @@ func (c *Client) UpdateCreateAction(
 	updateRollbackOnFailure bool,
 	updateStartInPausedState bool,
 	opaqueData string,
-	inPlace bool) error {
+	inPlace bool,
+	instances string,
+	canaryPercent uint32) error {
 	var jobConfig job.JobConfig
 	var response *updatesvc.CreateUpdateResponse
 
+	if instances != "" && canaryPercent > 0 {
+		return fmt.Errorf("--instances and --canary-percent cannot be used together")
+	}
+	if canaryPercent > 100 {
+		return fmt.Errorf(
+			"canary percentage %d must be between 1 and 100", canaryPercent)
+	}
+
 	// read the job configuration
 	buffer, err := ioutil.ReadFile(cfg)
 	if err != nil {
@@ -137,6 +229,16 @@ func (c *Client) UpdateCreateAction(
 			}
 		}
 
+		instanceRanges, err := c.updateInstanceRanges(
+			instances,
+			canaryPercent,
+			jobGetResponse.GetJobInfo().GetConfig().GetInstanceCount(),
+			jobConfig.GetInstanceCount(),
+		)
+		if err != nil {
+			return err
+		}
+
 		// set the configuration version
 		jobConfig.ChangeLog = &peloton.ChangeLog{
 			Version: jobRuntime.GetConfigurationVersion(),
@@ -159,6 +261,7 @@ func (c *Client) UpdateCreateAction(
 				RollbackOnFailure:   updateRollbackOnFailure,
 				StartPaused:         updateStartInPausedState,
 				InPlace:             inPlace,
+				InstanceRanges:      instanceRanges,
 			},
 			OpaqueData: opaque,
 		}
@@ -195,6 +298,192 @@ func (c *Client) UpdateGetAction(updateID string) error {
 	return nil
 }
 
+// UpdateStatusAction prints the progress of an update, given by its
+// identifier or the identifier of its job. With watch set the progress is
+// refreshed every interval until the update is terminal, or with the json
+// progress format written as progress events on every change and printed
+// once the update is terminal. It returns the last state of the update.
+func (c *Client) UpdateStatusAction(
+	id string,
+	watch bool,
+	interval time.Duration) (update.State, error) {
+	updateID, err := c.resolveUpdateID(id)
+	if err != nil {
+		return update.State_INVALID, err
+	}
+
+	if !watch {
+		return c.printUpdateStatus(c.ctx, updateID)
+	}
+	if c.ProgressFormat == ProgressFormatJSON {
+		return c.watchUpdateProgress(updateID, interval)
+	}
+
+	var state update.State
+	err = c.Watch(interval, func(ctx context.Context) (bool, error) {
+		var err error
+		state, err = c.printUpdateStatus(ctx, updateID)
+		return isUpdateStateTerminal(state), err
+	})
+	return state, err
+}
+
+// watchUpdateProgress polls the progress of an update every interval until
+// it is terminal, writing a progress event every time its state or counts
+// change, and prints its status once it is terminal. It returns the last
+// state of the update.
+func (c *Client) watchUpdateProgress(
+	updateID *peloton.UpdateID,
+	interval time.Duration) (update.State, error) {
+	var last *update.UpdateStatus
+	err := c.poll(interval, func(ctx context.Context) (bool, error) {
+		info, err := c.getUpdateInfo(ctx, updateID)
+		if err != nil {
+			return false, err
+		}
+		status := info.GetStatus()
+		if last == nil || last.GetState() != status.GetState() ||
+			last.GetNumTasksDone() != status.GetNumTasksDone() ||
+			last.GetNumTasksFailed() != status.GetNumTasksFailed() ||
+			last.GetNumTasksRemaining() != status.GetNumTasksRemaining() {
+			writeUpdateProgressEvent(
+				strings.ToLower(status.GetState().String()), updateID, status)
+		}
+		last = status
+		if !isUpdateStateTerminal(status.GetState()) {
+			return false, nil
+		}
+		printUpdateStatusTable(updateID, info, "-", "-")
+		return true, nil
+	})
+	writeUpdateProgressEvent(progressPhaseDone, updateID, last)
+	return last.GetState(), err
+}
+
+// writeUpdateProgressEvent writes a progress event of an update, with the
+// tasks which are done or failed as completed
+func writeUpdateProgressEvent(
+	phase string,
+	updateID *peloton.UpdateID,
+	status *update.UpdateStatus) {
+	completed := status.GetNumTasksDone() + status.GetNumTasksFailed()
+	writeProgressEvent(
+		phase,
+		int64(completed),
+		int64(completed+status.GetNumTasksRemaining()),
+		fmt.Sprintf("Update %s %s: %d done, %d failed, %d remaining",
+			updateID.GetValue(),
+			status.GetState(),
+			status.GetNumTasksDone(),
+			status.GetNumTasksFailed(),
+			status.GetNumTasksRemaining()))
+}
+
+// getUpdateInfo returns the information of an update
+func (c *Client) getUpdateInfo(
+	ctx context.Context,
+	updateID *peloton.UpdateID) (*update.UpdateInfo, error) {
+	response, err := c.updateClient.GetUpdate(ctx, &updatesvc.GetUpdateRequest{
+		UpdateId: updateID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response.GetUpdateInfo(), nil
+}
+
+// printUpdateStatus prints the progress of an update and returns its state.
+// The instances being updated are only known to the cache of the job
+// manager while the update is active, so they are left out if the cache
+// does not have the update.
+func (c *Client) printUpdateStatus(
+	ctx context.Context,
+	updateID *peloton.UpdateID) (update.State, error) {
+	info, err := c.getUpdateInfo(ctx, updateID)
+	if err != nil {
+		return update.State_INVALID, err
+	}
+	status := info.GetStatus()
+
+	current, updated := "-", "-"
+	if !isUpdateStateTerminal(status.GetState()) {
+		cache, err := c.updateClient.GetUpdateCache(ctx,
+			&updatesvc.GetUpdateCacheRequest{UpdateId: updateID})
+		if err == nil {
+			current = formatInstanceIDs(cache.GetInstancesCurrent())
+			updated = formatInstanceIDs(cache.GetInstancesDone())
+		}
+	}
+
+	printUpdateStatusTable(updateID, info, current, updated)
+	return status.GetState(), nil
+}
+
+// printUpdateStatusTable prints the progress of an update, with the
+// instances currently being updated and the ones already updated
+func printUpdateStatusTable(
+	updateID *peloton.UpdateID,
+	info *update.UpdateInfo,
+	current string,
+	updated string) {
+	status := info.GetStatus()
+	defer tabWriter.Flush()
+	fmt.Fprint(tabWriter, updateStatusFormatHeader)
+	fmt.Fprintf(tabWriter, updateStatusFormatBody,
+		updateID.GetValue(),
+		info.GetJobId().GetValue(),
+		status.GetState().String(),
+		status.GetNumTasksDone(),
+		status.GetNumTasksFailed(),
+		status.GetNumTasksRemaining(),
+		current,
+		updated,
+	)
+}
+
+// formatInstanceIDs formats instance identifiers as a comma separated list
+// of instances and ranges, e.g. 0-3,7
+func formatInstanceIDs(instances []uint32) string {
+	if len(instances) == 0 {
+		return "-"
+	}
+	sorted := append([]uint32(nil), instances...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	var items []string
+	for _, r := range instanceRanges(sorted) {
+		if r.GetTo()-r.GetFrom() == 1 {
+			items = append(items, fmt.Sprint(r.GetFrom()))
+		} else {
+			items = append(items, fmt.Sprintf("%d%s%d",
+				r.GetFrom(), instanceRangeSeparator, r.GetTo()-1))
+		}
+	}
+	return strings.Join(items, instanceSeparator)
+}
+
+// UpdateStatusExitCode maps the result of UpdateStatusAction with watch set
+// to the exit code of the update status command.
+func UpdateStatusExitCode(state update.State, err error) int {
+	if err != nil {
+		return UpdateStatusExitError
+	}
+
+	switch state {
+	case update.State_SUCCEEDED:
+		return UpdateStatusExitSucceeded
+	case update.State_ROLLED_BACK:
+		return UpdateStatusExitRolledBack
+	case update.State_FAILED:
+		return UpdateStatusExitFailed
+	case update.State_ABORTED:
+		return UpdateStatusExitAborted
+	default:
+		// the watch was interrupted before the update completed
+		return UpdateStatusExitError
+	}
+}
+
 // UpdateListAction lists all actions of a job update
 func (c *Client) UpdateListAction(jobID string) error {
 	var request = &updatesvc.ListUpdatesRequest{
@@ -230,66 +519,90 @@ func (c *Client) UpdateGetCacheAction(updateID string) error {
 	return nil
 }
 
-// UpdateAbortAction aborts a given update
-func (c *Client) UpdateAbortAction(updateID string, opaqueData string) error {
+// UpdateAbortAction aborts a given update, given by its identifier or the
+// identifier of its job
+func (c *Client) UpdateAbortAction(id string, opaqueData string) error {
+	updateID, err := c.resolveUpdateID(id)
+	if err != nil {
+		return err
+	}
+	confirmed, err := c.confirm(func() (string, error) {
+		return c.updateAbortSummary(updateID)
+	})
+	if err != nil || !confirmed {
+		return err
+	}
+
 	var opaque *peloton.OpaqueData
 	if len(opaqueData) > 0 {
 		opaque = &peloton.OpaqueData{Data: opaqueData}
 	}
 
 	var request = &updatesvc.AbortUpdateRequest{
-		UpdateId: &peloton.UpdateID{
-			Value: updateID,
-		},
+		UpdateId:   updateID,
 		OpaqueData: opaque,
 	}
 
-	_, err := c.updateClient.AbortUpdate(c.ctx, request)
+	_, err = c.updateClient.AbortUpdate(c.ctx, request)
 	if err != nil {
 		return err
 	}
+	fmt.Fprintf(tabWriter, "Update %s aborted\n", updateID.GetValue())
+	tabWriter.Flush()
 	return nil
 }
 
-// UpdateResumeAction resumes a given update
-func (c *Client) UpdateResumeAction(updateID string, opaqueData string) error {
+// UpdateResumeAction resumes a given update, given by its identifier or the
+// identifier of its job
+func (c *Client) UpdateResumeAction(id string, opaqueData string) error {
+	updateID, err := c.resolveUpdateID(id)
+	if err != nil {
+		return err
+	}
+
 	var opaque *peloton.OpaqueData
 	if len(opaqueData) > 0 {
 		opaque = &peloton.OpaqueData{Data: opaqueData}
 	}
 
 	var request = &updatesvc.ResumeUpdateRequest{
-		UpdateId: &peloton.UpdateID{
-			Value: updateID,
-		},
+		UpdateId:   updateID,
 		OpaqueData: opaque,
 	}
 
-	_, err := c.updateClient.ResumeUpdate(c.ctx, request)
+	_, err = c.updateClient.ResumeUpdate(c.ctx, request)
 	if err != nil {
 		return err
 	}
+	fmt.Fprintf(tabWriter, "Update %s resumed\n", updateID.GetValue())
+	tabWriter.Flush()
 	return nil
 }
 
-// UpdatePauseAction pauses a given update
-func (c *Client) UpdatePauseAction(updateID string, opaqueData string) error {
+// UpdatePauseAction pauses a given update, given by its identifier or the
+// identifier of its job
+func (c *Client) UpdatePauseAction(id string, opaqueData string) error {
+	updateID, err := c.resolveUpdateID(id)
+	if err != nil {
+		return err
+	}
+
 	var opaque *peloton.OpaqueData
 	if len(opaqueData) > 0 {
 		opaque = &peloton.OpaqueData{Data: opaqueData}
 	}
 
 	var request = &updatesvc.PauseUpdateRequest{
-		UpdateId: &peloton.UpdateID{
-			Value: updateID,
-		},
+		UpdateId:   updateID,
 		OpaqueData: opaque,
 	}
 
-	_, err := c.updateClient.PauseUpdate(c.ctx, request)
+	_, err = c.updateClient.PauseUpdate(c.ctx, request)
 	if err != nil {
 		return err
 	}
+	fmt.Fprintf(tabWriter, "Update %s paused\n", updateID.GetValue())
+	tabWriter.Flush()
 	return nil
 }
 
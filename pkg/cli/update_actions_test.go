@@ -15,15 +15,22 @@
 package cli
 
 import (
+	"bytes"
 	"context"
+	"fmt"
 	"io/ioutil"
+	"strings"
 	"testing"
+	"text/tabwriter"
+	"time"
 
+	pberrors "github.com/uber/peloton/.gen/peloton/api/v0/errors"
 	"github.com/uber/peloton/.gen/peloton/api/v0/job"
 	jobmocks "github.com/uber/peloton/.gen/peloton/api/v0/job/mocks"
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	"github.com/uber/peloton/.gen/peloton/api/v0/respool"
 	respoolmocks "github.com/uber/peloton/.gen/peloton/api/v0/respool/mocks"
+	"github.com/uber/peloton/.gen/peloton/api/v0/task"
 	"github.com/uber/peloton/.gen/peloton/api/v0/update"
 	"github.com/uber/peloton/.gen/peloton/api/v0/update/svc"
 	updatesvcmocks "github.com/uber/peloton/.gen/peloton/api/v0/update/svc/mocks"
@@ -211,6 +218,8 @@ func (suite *updateActionsTestSuite) TestClientUpdateCreate() {
 			false,
 			"",
 			false,
+			"",
+			uint32(0),
 		)
 
 		if t.err != nil {
@@ -276,6 +285,8 @@ func (suite *updateActionsTestSuite) TestClientUpdateCreateResPoolErrors() {
 			false,
 			"",
 			false,
+			"",
+			uint32(0),
 		)
 		suite.Error(err)
 	}
@@ -386,6 +397,8 @@ func (suite *updateActionsTestSuite) TestClientUpdateCreateJobGetErrors() {
 			false,
 			"",
 			false,
+			"",
+			uint32(0),
 		)
 		suite.Error(err)
 	}
@@ -493,10 +506,190 @@ func (suite *updateActionsTestSuite) TestClientUpdateCreateRetry() {
 		false,
 		"",
 		false,
+		"",
+		uint32(0),
 	)
 	suite.NoError(err)
 }
 
+// TestCanaryInstanceRanges tests the rounding up of the canary
+// percentage at small instance counts
+func (suite *updateActionsTestSuite) TestCanaryInstanceRanges() {
+	tt := []struct {
+		instanceCount uint32
+		percent       uint32
+		to            uint32
+	}{
+		{instanceCount: 1, percent: 5, to: 1},
+		{instanceCount: 3, percent: 5, to: 1},
+		{instanceCount: 20, percent: 5, to: 1},
+		{instanceCount: 21, percent: 5, to: 2},
+		{instanceCount: 3, percent: 50, to: 2},
+		{instanceCount: 4, percent: 50, to: 2},
+		{instanceCount: 7, percent: 100, to: 7},
+		{instanceCount: 200, percent: 5, to: 10},
+	}
+
+	for _, t := range tt {
+		suite.Equal(
+			[]*task.InstanceRange{{From: 0, To: t.to}},
+			canaryInstanceRanges(t.instanceCount, t.percent),
+			"%d%% of %d instances", t.percent, t.instanceCount)
+	}
+}
+
+// TestClientUpdateInstanceRanges tests the instance ranges an update is
+// restricted to, parsed like the instances of the task commands
+func (suite *updateActionsTestSuite) TestClientUpdateInstanceRanges() {
+	c := Client{}
+
+	ranges, err := c.updateInstanceRanges("", 0, 10, 12)
+	suite.NoError(err)
+	suite.Nil(ranges)
+
+	ranges, err = c.updateInstanceRanges("7,0-2", 0, 10, 10)
+	suite.NoError(err)
+	suite.Equal([]*task.InstanceRange{
+		{From: 0, To: 3},
+		{From: 7, To: 8},
+	}, ranges)
+
+	// the ranges are clamped to the instances of the job
+	ranges, err = c.updateInstanceRanges("0-9", 0, 5, 5)
+	suite.NoError(err)
+	suite.Equal([]*task.InstanceRange{{From: 0, To: 5}}, ranges)
+
+	ranges, err = c.updateInstanceRanges("", 25, 10, 10)
+	suite.NoError(err)
+	suite.Equal([]*task.InstanceRange{{From: 0, To: 3}}, ranges)
+
+	_, err = c.updateInstanceRanges("3-1", 0, 10, 10)
+	suite.Error(err)
+	suite.Contains(err.Error(), "reversed")
+
+	_, err = c.updateInstanceRanges("0-1", 0, 10, 12)
+	suite.Error(err)
+	suite.Contains(err.Error(), "instance count cannot be changed")
+
+	_, err = c.updateInstanceRanges("", 5, 0, 0)
+	suite.Error(err)
+}
+
+// TestClientUpdateCreateInstances tests creating a new update restricted
+// to instances or to a canary percentage of the instances
+func (suite *updateActionsTestSuite) TestClientUpdateCreateInstances() {
+	c := Client{
+		Debug:        false,
+		updateClient: suite.mockUpdate,
+		resClient:    suite.mockRespool,
+		jobClient:    suite.mockJob,
+		dispatcher:   nil,
+		ctx:          suite.ctx,
+	}
+
+	jobConfig := suite.getConfig()
+	respoolID := &peloton.ResourcePoolID{Value: uuid.NewRandom().String()}
+	jobGetResponse := &job.GetResponse{
+		JobInfo: &job.JobInfo{
+			Config: &job.JobConfig{
+				InstanceCount: jobConfig.GetInstanceCount(),
+			},
+			Runtime: &job.RuntimeInfo{
+				ConfigurationVersion: 3,
+			},
+		},
+	}
+
+	tt := []struct {
+		instances     string
+		canaryPercent uint32
+		ranges        []*task.InstanceRange
+	}{
+		{
+			instances: "0-1,8",
+			ranges: []*task.InstanceRange{
+				{From: 0, To: 2},
+				{From: 8, To: 9},
+			},
+		},
+		{
+			canaryPercent: 15,
+			ranges:        []*task.InstanceRange{{From: 0, To: 2}},
+		},
+	}
+
+	for _, t := range tt {
+		suite.mockRespool.EXPECT().
+			LookupResourcePoolID(context.Background(), gomock.Any()).
+			Return(&respool.LookupResponse{Id: respoolID}, nil)
+
+		suite.mockJob.EXPECT().
+			Get(gomock.Any(), gomock.Any()).
+			Return(jobGetResponse, nil)
+
+		suite.mockUpdate.EXPECT().
+			CreateUpdate(context.Background(), gomock.Any()).
+			Do(func(_ context.Context, req *svc.CreateUpdateRequest) {
+				suite.Equal(suite.jobID.GetValue(), req.GetJobId().GetValue())
+				suite.Equal(uint32(2), req.GetUpdateConfig().GetBatchSize())
+				suite.Equal(t.ranges, req.GetUpdateConfig().GetInstanceRanges())
+			}).
+			Return(&svc.CreateUpdateResponse{UpdateID: suite.updateID}, nil)
+
+		err := c.UpdateCreateAction(
+			suite.jobID.GetValue(),
+			testJobUpdateConfig,
+			uint32(2),
+			"/DefaultResPool",
+			uint64(0),
+			false,
+			uint32(0),
+			uint32(0),
+			false,
+			false,
+			"",
+			false,
+			t.instances,
+			t.canaryPercent,
+		)
+		suite.NoError(err)
+	}
+}
+
+// TestClientUpdateCreateConflictingInstances tests that the instances and
+// the canary percentage cannot be both given
+func (suite *updateActionsTestSuite) TestClientUpdateCreateConflictingInstances() {
+	c := Client{
+		ctx: suite.ctx,
+	}
+
+	for _, t := range []struct {
+		instances     string
+		canaryPercent uint32
+	}{
+		{instances: "0-1", canaryPercent: 5},
+		{canaryPercent: 101},
+	} {
+		err := c.UpdateCreateAction(
+			suite.jobID.GetValue(),
+			testJobUpdateConfig,
+			uint32(2),
+			"/DefaultResPool",
+			uint64(0),
+			false,
+			uint32(0),
+			uint32(0),
+			false,
+			false,
+			"",
+			false,
+			t.instances,
+			t.canaryPercent,
+		)
+		suite.Error(err)
+	}
+}
+
 // TestClientUpdateGet tests fetching status of a given update
 func (suite *updateActionsTestSuite) TestClientUpdateGet() {
 	c := Client{
@@ -676,8 +869,10 @@ func (suite *updateActionsTestSuite) TestClientUpdateAbort() {
 	c := Client{
 		Debug:        false,
 		updateClient: suite.mockUpdate,
+		jobClient:    suite.mockJob,
 		dispatcher:   nil,
 		ctx:          suite.ctx,
+		AssumeYes:    true,
 	}
 
 	resp := &svc.AbortUpdateResponse{}
@@ -693,6 +888,7 @@ func (suite *updateActionsTestSuite) TestClientUpdateAbort() {
 	}
 
 	for _, t := range tt {
+		suite.expectUpdateIDLookup()
 		suite.mockUpdate.EXPECT().
 			AbortUpdate(context.Background(), gomock.Any()).
 			Do(func(_ context.Context, req *svc.AbortUpdateRequest) {
@@ -713,6 +909,7 @@ func (suite *updateActionsTestSuite) TestClientUpdatePause() {
 	c := Client{
 		Debug:        false,
 		updateClient: suite.mockUpdate,
+		jobClient:    suite.mockJob,
 		dispatcher:   nil,
 		ctx:          suite.ctx,
 	}
@@ -730,6 +927,7 @@ func (suite *updateActionsTestSuite) TestClientUpdatePause() {
 	}
 
 	for _, t := range tt {
+		suite.expectUpdateIDLookup()
 		suite.mockUpdate.EXPECT().
 			PauseUpdate(context.Background(), gomock.Any()).
 			Do(func(_ context.Context, req *svc.PauseUpdateRequest) {
@@ -750,6 +948,7 @@ func (suite *updateActionsTestSuite) TestClientUpdateResume() {
 	c := Client{
 		Debug:        false,
 		updateClient: suite.mockUpdate,
+		jobClient:    suite.mockJob,
 		dispatcher:   nil,
 		ctx:          suite.ctx,
 	}
@@ -767,6 +966,7 @@ func (suite *updateActionsTestSuite) TestClientUpdateResume() {
 	}
 
 	for _, t := range tt {
+		suite.expectUpdateIDLookup()
 		suite.mockUpdate.EXPECT().
 			ResumeUpdate(context.Background(), gomock.Any()).
 			Do(func(_ context.Context, req *svc.ResumeUpdateRequest) {
@@ -781,3 +981,265 @@ func (suite *updateActionsTestSuite) TestClientUpdateResume() {
 		}
 	}
 }
+
+// expectUpdateIDLookup expects the lookup of the update identifier as a job
+// identifier, which is not found
+func (suite *updateActionsTestSuite) expectUpdateIDLookup() {
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{
+			Id: &peloton.JobID{Value: suite.updateID.GetValue()},
+		}).
+		Return(&job.GetResponse{
+			Error: &job.GetResponse_Error{
+				NotFound: &pberrors.JobNotFound{Message: "job not found"},
+			},
+		}, nil)
+}
+
+// TestClientResolveUpdateID tests resolving a job identifier to the
+// identifier of its current update
+func (suite *updateActionsTestSuite) TestClientResolveUpdateID() {
+	c := Client{
+		jobClient: suite.mockJob,
+		ctx:       suite.ctx,
+	}
+
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{Id: suite.jobID}).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{
+				Runtime: &job.RuntimeInfo{UpdateID: suite.updateID},
+			},
+		}, nil)
+	updateID, err := c.resolveUpdateID(suite.jobID.GetValue())
+	suite.NoError(err)
+	suite.Equal(suite.updateID.GetValue(), updateID.GetValue())
+
+	suite.expectUpdateIDLookup()
+	updateID, err = c.resolveUpdateID(suite.updateID.GetValue())
+	suite.NoError(err)
+	suite.Equal(suite.updateID.GetValue(), updateID.GetValue())
+
+	// job without update
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{Id: suite.jobID}).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{Runtime: &job.RuntimeInfo{}},
+		}, nil)
+	_, err = c.resolveUpdateID(suite.jobID.GetValue())
+	suite.EqualError(err, "job "+suite.jobID.GetValue()+" has no update")
+
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("get job failed"))
+	_, err = c.resolveUpdateID(suite.jobID.GetValue())
+	suite.Error(err)
+}
+
+// TestClientUpdatePauseByJob tests pausing the update of a job given by the
+// job identifier
+func (suite *updateActionsTestSuite) TestClientUpdatePauseByJob() {
+	c := Client{
+		updateClient: suite.mockUpdate,
+		jobClient:    suite.mockJob,
+		ctx:          suite.ctx,
+	}
+
+	suite.mockJob.EXPECT().
+		Get(gomock.Any(), &job.GetRequest{Id: suite.jobID}).
+		Return(&job.GetResponse{
+			JobInfo: &job.JobInfo{
+				Runtime: &job.RuntimeInfo{UpdateID: suite.updateID},
+			},
+		}, nil)
+	suite.mockUpdate.EXPECT().
+		PauseUpdate(gomock.Any(), &svc.PauseUpdateRequest{
+			UpdateId:   suite.updateID,
+			OpaqueData: &peloton.OpaqueData{Data: "data"},
+		}).
+		Return(&svc.PauseUpdateResponse{}, nil)
+	suite.NoError(c.UpdatePauseAction(suite.jobID.GetValue(), "data"))
+}
+
+// updateStatusResponse returns the get response of the update in the state
+func (suite *updateActionsTestSuite) updateStatusResponse(
+	state update.State) *svc.GetUpdateResponse {
+	return &svc.GetUpdateResponse{
+		UpdateInfo: &update.UpdateInfo{
+			UpdateId: suite.updateID,
+			JobId:    suite.jobID,
+			Status: &update.UpdateStatus{
+				State:             state,
+				NumTasksDone:      4,
+				NumTasksFailed:    1,
+				NumTasksRemaining: 5,
+			},
+		},
+	}
+}
+
+// TestClientUpdateStatus tests printing the progress of an update
+func (suite *updateActionsTestSuite) TestClientUpdateStatus() {
+	c := Client{
+		updateClient: suite.mockUpdate,
+		jobClient:    suite.mockJob,
+		ctx:          suite.ctx,
+	}
+	var table bytes.Buffer
+	oldTabWriter := tabWriter
+	tabWriter = tabwriter.NewWriter(&table, 0, 0, 1, ' ', 0)
+	defer func() { tabWriter = oldTabWriter }()
+
+	suite.expectUpdateIDLookup()
+	suite.mockUpdate.EXPECT().
+		GetUpdate(gomock.Any(), &svc.GetUpdateRequest{UpdateId: suite.updateID}).
+		Return(suite.updateStatusResponse(update.State_ROLLING_FORWARD), nil)
+	suite.mockUpdate.EXPECT().
+		GetUpdateCache(gomock.Any(), &svc.GetUpdateCacheRequest{UpdateId: suite.updateID}).
+		Return(&svc.GetUpdateCacheResponse{
+			InstancesCurrent: []uint32{6, 5},
+			InstancesDone:    []uint32{0, 1, 2, 4},
+		}, nil)
+
+	state, err := c.UpdateStatusAction(suite.updateID.GetValue(), false, 0)
+	suite.NoError(err)
+	suite.Equal(update.State_ROLLING_FORWARD, state)
+	suite.Contains(table.String(), "ROLLING_FORWARD 4    1      5         5-6               0-2,4")
+
+	// the instances of terminal updates are not in the cache
+	table.Reset()
+	suite.expectUpdateIDLookup()
+	suite.mockUpdate.EXPECT().
+		GetUpdate(gomock.Any(), gomock.Any()).
+		Return(suite.updateStatusResponse(update.State_SUCCEEDED), nil)
+	state, err = c.UpdateStatusAction(suite.updateID.GetValue(), false, 0)
+	suite.NoError(err)
+	suite.Equal(update.State_SUCCEEDED, state)
+	suite.Contains(table.String(), "SUCCEEDED 4    1      5         -                 -")
+
+	suite.expectUpdateIDLookup()
+	suite.mockUpdate.EXPECT().
+		GetUpdate(gomock.Any(), gomock.Any()).
+		Return(nil, errors.New("did not find the update"))
+	_, err = c.UpdateStatusAction(suite.updateID.GetValue(), false, 0)
+	suite.Error(err)
+}
+
+// TestClientUpdateStatusWatch tests that watching an update stops once it
+// is terminal, with the exit code of its terminal state
+func (suite *updateActionsTestSuite) TestClientUpdateStatusWatch() {
+	c := Client{
+		updateClient: suite.mockUpdate,
+		jobClient:    suite.mockJob,
+		ctx:          suite.ctx,
+	}
+	oldOutput, oldIsTerminal := watchOutput, watchIsTerminal
+	watchOutput = &bytes.Buffer{}
+	watchIsTerminal = func() bool { return false }
+	defer func() {
+		watchOutput, watchIsTerminal = oldOutput, oldIsTerminal
+	}()
+
+	tt := []struct {
+		state update.State
+		code  int
+	}{
+		{update.State_SUCCEEDED, UpdateStatusExitSucceeded},
+		{update.State_ROLLED_BACK, UpdateStatusExitRolledBack},
+		{update.State_FAILED, UpdateStatusExitFailed},
+		{update.State_ABORTED, UpdateStatusExitAborted},
+	}
+	for _, t := range tt {
+		suite.expectUpdateIDLookup()
+		gomock.InOrder(
+			suite.mockUpdate.EXPECT().
+				GetUpdate(gomock.Any(), gomock.Any()).
+				Return(suite.updateStatusResponse(update.State_ROLLING_FORWARD), nil),
+			suite.mockUpdate.EXPECT().
+				GetUpdateCache(gomock.Any(), gomock.Any()).
+				Return(nil, errors.New("update not in cache")),
+			suite.mockUpdate.EXPECT().
+				GetUpdate(gomock.Any(), gomock.Any()).
+				Return(suite.updateStatusResponse(update.State_PAUSED), nil),
+			suite.mockUpdate.EXPECT().
+				GetUpdateCache(gomock.Any(), gomock.Any()).
+				Return(&svc.GetUpdateCacheResponse{}, nil),
+			suite.mockUpdate.EXPECT().
+				GetUpdate(gomock.Any(), gomock.Any()).
+				Return(suite.updateStatusResponse(t.state), nil),
+		)
+
+		state, err := c.UpdateStatusAction(
+			suite.updateID.GetValue(), true, time.Millisecond)
+		suite.NoError(err)
+		suite.Equal(t.state, state)
+		suite.Equal(t.code, UpdateStatusExitCode(state, err), t.state.String())
+	}
+
+	suite.Equal(UpdateStatusExitError,
+		UpdateStatusExitCode(update.State_INVALID, errors.New("watch failed")))
+	suite.Equal(UpdateStatusExitError,
+		UpdateStatusExitCode(update.State_ROLLING_FORWARD, nil))
+}
+
+// TestClientUpdateStatusWatchJSON tests that with the json progress format
+// a progress event is written every time the update changes, and that its
+// status is only printed once it is terminal
+func (suite *updateActionsTestSuite) TestClientUpdateStatusWatchJSON() {
+	c := Client{
+		updateClient:   suite.mockUpdate,
+		jobClient:      suite.mockJob,
+		ctx:            suite.ctx,
+		ProgressFormat: ProgressFormatJSON,
+	}
+	var table, events, watched bytes.Buffer
+	oldTabWriter, oldProgressOutput, oldWatchOutput :=
+		tabWriter, progressOutput, watchOutput
+	tabWriter = tabwriter.NewWriter(&table, 0, 0, 1, ' ', 0)
+	progressOutput = &events
+	watchOutput = &watched
+	defer func() {
+		tabWriter, progressOutput, watchOutput =
+			oldTabWriter, oldProgressOutput, oldWatchOutput
+	}()
+
+	suite.expectUpdateIDLookup()
+	gomock.InOrder(
+		suite.mockUpdate.EXPECT().
+			GetUpdate(gomock.Any(), gomock.Any()).
+			Return(suite.updateStatusResponse(update.State_ROLLING_FORWARD), nil),
+		suite.mockUpdate.EXPECT().
+			GetUpdate(gomock.Any(), gomock.Any()).
+			Return(suite.updateStatusResponse(update.State_ROLLING_FORWARD), nil),
+		suite.mockUpdate.EXPECT().
+			GetUpdate(gomock.Any(), gomock.Any()).
+			Return(suite.updateStatusResponse(update.State_SUCCEEDED), nil),
+	)
+
+	state, err := c.UpdateStatusAction(
+		suite.updateID.GetValue(), true, time.Millisecond)
+	suite.NoError(err)
+	suite.Equal(update.State_SUCCEEDED, state)
+
+	parsed, err := parseProgressEvents(events.String())
+	suite.Require().NoError(err)
+	suite.Equal([]string{
+		"rolling_forward 5/10",
+		"succeeded 5/10",
+		"done 5/10",
+	}, progressPhases(parsed))
+	suite.Equal(fmt.Sprintf(
+		"Update %s ROLLING_FORWARD: 4 done, 1 failed, 5 remaining",
+		suite.updateID.GetValue()), parsed[0].Message)
+
+	suite.Empty(watched.String())
+	suite.Equal(2, strings.Count(table.String(), "\n"))
+	suite.Contains(table.String(), "SUCCEEDED")
+}
+
+// TestFormatInstanceIDs tests formatting instances as ranges
+func (suite *updateActionsTestSuite) TestFormatInstanceIDs() {
+	suite.Equal("-", formatInstanceIDs(nil))
+	suite.Equal("3", formatInstanceIDs([]uint32{3}))
+	suite.Equal("0-2,5,7-8", formatInstanceIDs([]uint32{8, 0, 1, 2, 5, 7}))
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"fmt"
+)
+
+// maxPrintedValidationErrors caps the problems listed in the message of
+// ValidationErrors, the JSON error output lists all of them
+const maxPrintedValidationErrors = 20
+
+// ValidationError is a problem with an item of CLI input, e.g. a host of
+// a host list
+type ValidationError struct {
+	// Position locates the item in the input, e.g. "token 3" or "line 2",
+	// and is empty for problems of the input as a whole
+	Position string `json:"position,omitempty"`
+	Message  string `json:"message"`
+}
+
+// Error returns the message of the problem, prefixed with its position
+func (e ValidationError) Error() string {
+	if e.Position == "" {
+		return e.Message
+	}
+	return e.Position + ": " + e.Message
+}
+
+// ValidationErrors accumulates the problems of a CLI input, so that the
+// parsers of host lists, instance ranges and label selectors report all of
+// them at once instead of only the first.
+type ValidationErrors struct {
+	// Input describes the input, e.g. "host list"
+	Input  string
+	Errors []ValidationError
+}
+
+// newValidationErrors returns an empty set of problems of the input
+func newValidationErrors(input string) *ValidationErrors {
+	return &ValidationErrors{Input: input}
+}
+
+// Add records a problem at a position of the input
+func (e *ValidationErrors) Add(
+	position string,
+	format string,
+	args ...interface{}) {
+	e.Errors = append(e.Errors, ValidationError{
+		Position: position,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// Len returns the number of problems
+func (e *ValidationErrors) Len() int {
+	return len(e.Errors)
+}
+
+// ErrorOrNil returns e if any problem was recorded, or nil. Parsers return
+// it rather than e so that callers can compare the error to nil.
+func (e *ValidationErrors) ErrorOrNil() error {
+	if e.Len() == 0 {
+		return nil
+	}
+	return e
+}
+
+// Error returns a single line message for a single problem, or a line per
+// problem after a summary line.
+func (e *ValidationErrors) Error() string {
+	if e.Len() == 1 {
+		return fmt.Sprintf("Invalid %s: %v", e.Input, e.Errors[0])
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "Invalid %s, %d errors:", e.Input, e.Len())
+	for i, err := range e.Errors {
+		if i == maxPrintedValidationErrors {
+			fmt.Fprintf(&b, "\n  ... and %d more", e.Len()-i)
+			break
+		}
+		fmt.Fprintf(&b, "\n  %v", err)
+	}
+	return b.String()
+}
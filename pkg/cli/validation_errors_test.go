@@ -0,0 +1,53 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidationErrors(t *testing.T) {
+	errs := newValidationErrors("host list")
+	assert.NoError(t, errs.ErrorOrNil())
+
+	errs.Add("token 2", "host cannot be empty")
+	assert.Equal(t, 1, errs.Len())
+	assert.EqualError(t, errs.ErrorOrNil(),
+		"Invalid host list: token 2: host cannot be empty")
+
+	errs.Add("", "more than %d hosts", 3)
+	assert.EqualError(t, errs.ErrorOrNil(),
+		"Invalid host list, 2 errors:\n"+
+			"  token 2: host cannot be empty\n"+
+			"  more than 3 hosts")
+}
+
+func TestValidationErrorsTruncated(t *testing.T) {
+	errs := newValidationErrors("host list")
+	for i := 1; i <= maxPrintedValidationErrors+5; i++ {
+		errs.Add(fmt.Sprintf("token %d", i), "host cannot be empty")
+	}
+
+	lines := strings.Split(errs.Error(), "\n")
+	assert.Len(t, lines, maxPrintedValidationErrors+2)
+	assert.Equal(t, "Invalid host list, 25 errors:", lines[0])
+	assert.Equal(t, "  ... and 5 more", lines[len(lines)-1])
+	// all of them are kept for the JSON output
+	assert.Len(t, errs.Errors, maxPrintedValidationErrors+5)
+}
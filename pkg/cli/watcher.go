@@ -0,0 +1,102 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+)
+
+const (
+	// DefaultWatchInterval is the default interval between two refreshes
+	// of a watched command
+	DefaultWatchInterval = 2 * time.Second
+
+	// watchRPCTimeout is the timeout of the RPCs made by a single refresh
+	watchRPCTimeout = 5 * time.Second
+
+	// clearScreen moves the cursor home and clears the terminal
+	clearScreen = "\033[H\033[2J"
+)
+
+var (
+	// used for testing
+	watchOutput     io.Writer = os.Stdout
+	watchIsTerminal           = isTerminal
+)
+
+// WatchFunc fetches and renders the output of a watched command once.
+// It returns true once the watched entity reached a terminal state and
+// the watch should stop.
+type WatchFunc func(ctx context.Context) (bool, error)
+
+// Watch calls render every interval until it returns done or an error, or
+// the user interrupts the command. On a terminal the screen is cleared
+// before every refresh, otherwise each refresh is preceded by a timestamp.
+func (c *Client) Watch(interval time.Duration, render WatchFunc) error {
+	terminal := watchIsTerminal()
+	return c.poll(interval, func(ctx context.Context) (bool, error) {
+		if terminal {
+			fmt.Fprint(watchOutput, clearScreen)
+		} else {
+			fmt.Fprintf(watchOutput, "--- %s ---\n",
+				time.Now().UTC().Format(time.RFC3339))
+		}
+		return render(ctx)
+	})
+}
+
+// poll calls fetch every interval until it returns done or an error, or
+// the user interrupts the command
+func (c *Client) poll(interval time.Duration, fetch WatchFunc) error {
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	defer signal.Stop(interrupt)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		ctx, cancel := context.WithTimeout(context.Background(), watchRPCTimeout)
+		done, err := fetch(ctx)
+		cancel()
+		if err != nil || done {
+			return err
+		}
+
+		select {
+		case <-interrupt:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// isTerminal returns whether stdout is a terminal
+func isTerminal() bool {
+	info, err := os.Stdout.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
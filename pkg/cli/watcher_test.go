@@ -0,0 +1,84 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cli
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withWatchOutput redirects the watch output into a buffer for the duration
+// of a test
+func withWatchOutput(terminal bool) (*bytes.Buffer, func()) {
+	var buffer bytes.Buffer
+	oldOutput, oldIsTerminal := watchOutput, watchIsTerminal
+	watchOutput = &buffer
+	watchIsTerminal = func() bool { return terminal }
+	return &buffer, func() {
+		watchOutput, watchIsTerminal = oldOutput, oldIsTerminal
+	}
+}
+
+func TestWatchStopsWhenDone(t *testing.T) {
+	buffer, restore := withWatchOutput(false)
+	defer restore()
+
+	c := Client{}
+	calls := 0
+	err := c.Watch(time.Millisecond, func(ctx context.Context) (bool, error) {
+		_, ok := ctx.Deadline()
+		assert.True(t, ok)
+		calls++
+		return calls == 3, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+	assert.Equal(t, 3, strings.Count(buffer.String(), "---\n"))
+	assert.NotContains(t, buffer.String(), clearScreen)
+}
+
+func TestWatchStopsOnError(t *testing.T) {
+	_, restore := withWatchOutput(false)
+	defer restore()
+
+	c := Client{}
+	calls := 0
+	err := c.Watch(time.Millisecond, func(ctx context.Context) (bool, error) {
+		calls++
+		return false, errors.New("fake error")
+	})
+	assert.EqualError(t, err, "fake error")
+	assert.Equal(t, 1, calls)
+}
+
+func TestWatchClearsTerminal(t *testing.T) {
+	buffer, restore := withWatchOutput(true)
+	defer restore()
+
+	c := Client{}
+	calls := 0
+	err := c.Watch(time.Millisecond, func(ctx context.Context) (bool, error) {
+		calls++
+		return calls == 2, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, clearScreen+clearScreen, buffer.String())
+}
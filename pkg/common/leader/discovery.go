@@ -34,6 +34,14 @@ type Discovery interface {
 	GetAppURL(role string) (*url.URL, error)
 }
 
+// IDDiscovery is implemented by the discoveries which know the ID of the
+// leader of a role, e.g. its hostname and HTTP port, and not only its app
+// URL
+type IDDiscovery interface {
+	// Returns the ID of the leader of a given Peloton role
+	GetLeaderID(role string) (*ID, error)
+}
+
 // NewStaticServiceDiscovery creates a staticDiscovery object
 func NewStaticServiceDiscovery(
 	jobmgrURL *url.URL,
@@ -100,18 +108,29 @@ type zkDiscovery struct {
 
 // GetAppURL reads app URL from Zookeeper for a given Peloton role
 func (s *zkDiscovery) GetAppURL(role string) (*url.URL, error) {
+	id, err := s.GetLeaderID(role)
+	if err != nil {
+		return nil, err
+	}
+	return &url.URL{
+		Host: fmt.Sprintf("%s:%d", id.IP, id.GRPCPort),
+	}, nil
+}
+
+// GetLeaderID reads the leader ID from Zookeeper for a given Peloton role
+func (s *zkDiscovery) GetLeaderID(role string) (*ID, error) {
 	zkPath := leaderZkPath(s.zkRoot, role)
 	leader, err := s.zkClient.Get(zkPath)
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf(
+			"no leader of %s found at zookeeper path %s: %v", role, zkPath, err)
 	}
 
-	id := ID{}
-	if err := json.Unmarshal([]byte(leader.Value), &id); err != nil {
+	id := &ID{}
+	if err := json.Unmarshal([]byte(leader.Value), id); err != nil {
 		log.WithField("leader", leader.Value).Error("Failed to parse leader json")
-		return nil, err
+		return nil, fmt.Errorf(
+			"invalid leader of %s at zookeeper path %s: %v", role, zkPath, err)
 	}
-	return &url.URL{
-		Host: fmt.Sprintf("%s:%d", id.IP, id.GRPCPort),
-	}, nil
+	return id, nil
 }
@@ -74,25 +74,28 @@ func NewCandidate(
 	parent tally.Scope,
 	role string,
 	nomination Nomination) (Candidate, error) {
-	var leaderPath string
-	if role == "" {
-		return nil, errors.New("You need to specify a role to campaign " +
-			"for that isnt the empty string")
-	}
-
-	client, err := zookeeper.New(
-		cfg.ZKServers,
-		&store.Config{ConnectionTimeout: znodeEphemeralTimeout},
-	)
+	client, err := NewZkClient(cfg)
 	if err != nil {
 		return nil, err
 	}
+	return NewCandidateWithClient(client, cfg, parent, role, nomination)
+}
 
-	if role == common.PelotonAuroraBridgeRole {
-		leaderPath = leaderBridgeZKPath(cfg.Root, role)
-	} else {
-		leaderPath = leaderZkPath(cfg.Root, role)
+// NewCandidateWithClient is NewCandidate with the ZK client used for the
+// election, e.g. a client whose session is shared with other users of ZK
+// in the same process.
+func NewCandidateWithClient(
+	client store.Store,
+	cfg ElectionConfig,
+	parent tally.Scope,
+	role string,
+	nomination Nomination) (Candidate, error) {
+	if role == "" {
+		return nil, errors.New("You need to specify a role to campaign " +
+			"for that isnt the empty string")
 	}
+
+	leaderPath := electionZkPath(cfg.Root, role)
 	log.WithFields(log.Fields{
 		"id":          nomination.GetID(),
 		"role":        role,
@@ -122,6 +125,15 @@ func NewCandidate(
 	return &el, nil
 }
 
+// NewZkClient returns a client of the ZK servers of the election config,
+// with the session timeout used for leader election
+func NewZkClient(cfg ElectionConfig) (store.Store, error) {
+	return zookeeper.New(
+		cfg.ZKServers,
+		&store.Config{ConnectionTimeout: znodeEphemeralTimeout},
+	)
+}
+
 // Start begins running election for leadership
 // and calls your callbacks when you gain/lose leadership.
 // NOTE: this handles connection errors and retries, and runs until you
@@ -285,6 +297,25 @@ func (el *election) Resign() {
 	el.candidate.Resign()
 }
 
+// Epoch returns the epoch of the current leadership of a role, the version
+// of its leader znode. The node is written by each candidate once it holds
+// the leadership lock, so the epoch increases with each leadership gained.
+func Epoch(client store.Store, cfg ElectionConfig, role string) (uint64, error) {
+	pair, err := client.Get(electionZkPath(cfg.Root, role))
+	if err != nil {
+		return 0, err
+	}
+	return pair.LastIndex, nil
+}
+
+// electionZkPath returns the ZK path of the leader node of a role
+func electionZkPath(rootPath string, role string) string {
+	if role == common.PelotonAuroraBridgeRole {
+		return leaderBridgeZKPath(rootPath, role)
+	}
+	return leaderZkPath(rootPath, role)
+}
+
 // leaderZkPath returns the full ZK path to the leader node given a
 // election config (the path root) and a component
 func leaderZkPath(rootPath string, role string) string {
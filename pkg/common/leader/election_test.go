@@ -15,6 +15,7 @@
 package leader
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 	"sync"
@@ -22,6 +23,7 @@ import (
 	"time"
 
 	"github.com/docker/leadership"
+	"github.com/docker/libkv/store"
 	libkvmock "github.com/docker/libkv/store/mock"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
@@ -93,6 +95,26 @@ func TestNewCandidate(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+// TestEpoch tests that the epoch of a leadership is the version of the
+// leader node of the role
+func TestEpoch(t *testing.T) {
+	config := ElectionConfig{Root: "/peloton"}
+	kv, err := libkvmock.New([]string{}, nil)
+	assert.NoError(t, err)
+	mockStore := kv.(*libkvmock.Mock)
+
+	mockStore.On("Get", "peloton/testrole/leader").
+		Return(&store.KVPair{LastIndex: 3}, nil).Once()
+	epoch, err := Epoch(mockStore, config, "testrole")
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), epoch)
+
+	mockStore.On("Get", "peloton/testrole/leader").
+		Return((*store.KVPair)(nil), errors.New("zk error")).Once()
+	_, err = Epoch(mockStore, config, "testrole")
+	assert.Error(t, err)
+}
+
 func TestLeaderElection(t *testing.T) {
 	// the zkservers will be replaced with the mock libkv client, dont worry :)
 	role := "testrole"
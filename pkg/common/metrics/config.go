@@ -85,6 +85,9 @@ func InitMetricScope(
 
 	var metricScope tally.Scope
 	var scopeCloser io.Closer
+	// the timers are reported to the reporters directly, record them for
+	// the snapshots of the metrics
+	timers := NewTimerRecorder()
 	if cfg.MultiReporter {
 		var m3Reporter tallym3.Reporter
 		var promReporter tallyprom.Reporter
@@ -124,7 +127,7 @@ func InitMetricScope(
 			tally.ScopeOptions{
 				Prefix:         rootMetricScope,
 				Tags:           map[string]string{},
-				CachedReporter: reporter,
+				CachedReporter: timers.WrapCachedReporter(reporter),
 				Separator:      metricSeparator,
 			},
 			metricFlushInterval)
@@ -147,12 +150,15 @@ func InitMetricScope(
 			tally.ScopeOptions{
 				Prefix:    rootMetricScope,
 				Tags:      map[string]string{},
-				Reporter:  reporter,
+				Reporter:  timers.WrapReporter(reporter),
 				Separator: metricSeparator,
 			},
 			metricFlushInterval)
 	}
 
+	// serve the snapshot of the metrics for debugging, e.g. by the CLI
+	mux.HandleFunc(SnapshotPath, SnapshotHandler(metricScope, timers))
+
 	return metricScope, scopeCloser, mux
 }
 
@@ -0,0 +1,191 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"encoding/json"
+	"math"
+	nethttp "net/http"
+	"sort"
+	"time"
+
+	"github.com/uber-go/tally"
+)
+
+// SnapshotPath is the path of the debug endpoint which returns a snapshot of
+// the metrics of the root scope
+const SnapshotPath = "/debug/metrics/snapshot"
+
+// ValueSnapshot is the value of a counter or a gauge
+type ValueSnapshot struct {
+	Name  string            `json:"name"`
+	Tags  map[string]string `json:"tags,omitempty"`
+	Value float64           `json:"value"`
+}
+
+// TimerSnapshot is the values of a timer, in seconds
+type TimerSnapshot struct {
+	Name   string            `json:"name"`
+	Tags   map[string]string `json:"tags,omitempty"`
+	Values []float64         `json:"values"`
+}
+
+// BucketSnapshot is the number of samples of a histogram bucket. The upper
+// bound of the last bucket, which has no upper bound, is math.MaxFloat64.
+type BucketSnapshot struct {
+	UpperBound float64 `json:"upperBound"`
+	Count      int64   `json:"count"`
+}
+
+// HistogramSnapshot is the buckets of a histogram, ordered by upper bound,
+// with the bounds of the duration histograms in seconds
+type HistogramSnapshot struct {
+	Name    string            `json:"name"`
+	Tags    map[string]string `json:"tags,omitempty"`
+	Buckets []BucketSnapshot  `json:"buckets"`
+}
+
+// Snapshot is the metrics of a scope since the last time the scope was
+// reported, i.e. the counters are the increments since the last flush, and
+// the timers the values not reported yet, or the most recent values of a
+// TimerRecorder
+type Snapshot struct {
+	Counters   []*ValueSnapshot     `json:"counters"`
+	Gauges     []*ValueSnapshot     `json:"gauges"`
+	Timers     []*TimerSnapshot     `json:"timers"`
+	Histograms []*HistogramSnapshot `json:"histograms"`
+}
+
+// NewSnapshot converts a tally snapshot, ordering the metrics by ID
+func NewSnapshot(s tally.Snapshot) *Snapshot {
+	snapshot := &Snapshot{
+		Counters:   []*ValueSnapshot{},
+		Gauges:     []*ValueSnapshot{},
+		Timers:     []*TimerSnapshot{},
+		Histograms: []*HistogramSnapshot{},
+	}
+
+	for _, id := range sortedIDs(s.Counters()) {
+		c := s.Counters()[id]
+		snapshot.Counters = append(snapshot.Counters, &ValueSnapshot{
+			Name:  c.Name(),
+			Tags:  c.Tags(),
+			Value: float64(c.Value()),
+		})
+	}
+	for _, id := range sortedIDs(s.Gauges()) {
+		g := s.Gauges()[id]
+		snapshot.Gauges = append(snapshot.Gauges, &ValueSnapshot{
+			Name:  g.Name(),
+			Tags:  g.Tags(),
+			Value: g.Value(),
+		})
+	}
+	for _, id := range sortedIDs(s.Timers()) {
+		t := s.Timers()[id]
+		timer := &TimerSnapshot{
+			Name:   t.Name(),
+			Tags:   t.Tags(),
+			Values: []float64{},
+		}
+		for _, v := range t.Values() {
+			timer.Values = append(timer.Values, v.Seconds())
+		}
+		snapshot.Timers = append(snapshot.Timers, timer)
+	}
+	for _, id := range sortedIDs(s.Histograms()) {
+		h := s.Histograms()[id]
+		histogram := &HistogramSnapshot{
+			Name:    h.Name(),
+			Tags:    h.Tags(),
+			Buckets: []BucketSnapshot{},
+		}
+		for bound, count := range h.Values() {
+			histogram.Buckets = append(histogram.Buckets, BucketSnapshot{
+				UpperBound: bound,
+				Count:      count,
+			})
+		}
+		for bound, count := range h.Durations() {
+			upperBound := math.MaxFloat64
+			if bound != time.Duration(math.MaxInt64) {
+				upperBound = bound.Seconds()
+			}
+			histogram.Buckets = append(histogram.Buckets, BucketSnapshot{
+				UpperBound: upperBound,
+				Count:      count,
+			})
+		}
+		sort.Slice(histogram.Buckets, func(i, j int) bool {
+			return histogram.Buckets[i].UpperBound < histogram.Buckets[j].UpperBound
+		})
+		snapshot.Histograms = append(snapshot.Histograms, histogram)
+	}
+	return snapshot
+}
+
+// sortedIDs returns the sorted keys of a map of metrics by ID
+func sortedIDs(metrics interface{}) []string {
+	var ids []string
+	switch m := metrics.(type) {
+	case map[string]tally.CounterSnapshot:
+		for id := range m {
+			ids = append(ids, id)
+		}
+	case map[string]tally.GaugeSnapshot:
+		for id := range m {
+			ids = append(ids, id)
+		}
+	case map[string]tally.TimerSnapshot:
+		for id := range m {
+			ids = append(ids, id)
+		}
+	case map[string]tally.HistogramSnapshot:
+		for id := range m {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// SnapshotHandler returns a handler which serves the snapshot of the metrics
+// of scope as JSON, if the scope supports snapshots as tally root scopes do.
+// The timers of a root scope with a reporter are not in its snapshots, so
+// they are taken from timers if it is set.
+func SnapshotHandler(
+	scope tally.Scope,
+	timers *TimerRecorder) func(nethttp.ResponseWriter, *nethttp.Request) {
+	return func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		testScope, ok := scope.(tally.TestScope)
+		if !ok {
+			nethttp.Error(w, "metrics scope does not support snapshots",
+				nethttp.StatusNotImplemented)
+			return
+		}
+		snapshot := NewSnapshot(testScope.Snapshot())
+		if timers != nil {
+			snapshot.Timers = timers.Snapshot()
+		}
+		body, err := json.Marshal(snapshot)
+		if err != nil {
+			nethttp.Error(w, err.Error(), nethttp.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(nethttp.StatusOK)
+		w.Write(body)
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"encoding/json"
+	"math"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally"
+)
+
+// newTestSnapshotScope returns a scope with a metric of every type
+func newTestSnapshotScope() tally.TestScope {
+	scope := tally.NewTestScope("peloton", nil)
+	scope.Tagged(map[string]string{"result": "fail"}).Counter("calls").Inc(2)
+	scope.Counter("calls").Inc(3)
+	scope.Gauge("hosts").Update(12)
+	timer := scope.Timer("latency")
+	timer.Record(2 * time.Second)
+	timer.Record(500 * time.Millisecond)
+	scope.Histogram("size", tally.ValueBuckets{1, 10}).RecordValue(5)
+	scope.Histogram("age", tally.DurationBuckets{time.Second}).
+		RecordDuration(time.Minute)
+	return scope
+}
+
+// TestNewSnapshot tests converting a tally snapshot
+func TestNewSnapshot(t *testing.T) {
+	snapshot := NewSnapshot(newTestSnapshotScope().Snapshot())
+
+	assert.Equal(t, []*ValueSnapshot{
+		{Name: "peloton.calls", Tags: map[string]string{}, Value: 3},
+		{Name: "peloton.calls", Tags: map[string]string{"result": "fail"}, Value: 2},
+	}, snapshot.Counters)
+	assert.Equal(t, []*ValueSnapshot{
+		{Name: "peloton.hosts", Tags: map[string]string{}, Value: 12},
+	}, snapshot.Gauges)
+	assert.Len(t, snapshot.Timers, 1)
+	assert.Equal(t, []float64{2, 0.5}, snapshot.Timers[0].Values)
+
+	assert.Len(t, snapshot.Histograms, 2)
+	age := snapshot.Histograms[0]
+	assert.Equal(t, "peloton.age", age.Name)
+	assert.Equal(t, []BucketSnapshot{
+		{UpperBound: 1, Count: 0},
+		{UpperBound: math.MaxFloat64, Count: 1},
+	}, age.Buckets[len(age.Buckets)-2:])
+	size := snapshot.Histograms[1]
+	assert.Equal(t, "peloton.size", size.Name)
+	for i := 1; i < len(size.Buckets); i++ {
+		assert.True(t, size.Buckets[i-1].UpperBound < size.Buckets[i].UpperBound)
+	}
+}
+
+// TestSnapshotHandler tests serving the snapshot of a scope
+func TestSnapshotHandler(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	SnapshotHandler(newTestSnapshotScope(), nil)(recorder,
+		httptest.NewRequest(nethttp.MethodGet, SnapshotPath, nil))
+	assert.Equal(t, nethttp.StatusOK, recorder.Code)
+
+	snapshot := &Snapshot{}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), snapshot))
+	assert.Len(t, snapshot.Counters, 2)
+	assert.Len(t, snapshot.Histograms, 2)
+
+	// a scope which does not support snapshots
+	recorder = httptest.NewRecorder()
+	SnapshotHandler(struct{ tally.Scope }{tally.NoopScope}, nil)(recorder,
+		httptest.NewRequest(nethttp.MethodGet, SnapshotPath, nil))
+	assert.Equal(t, nethttp.StatusNotImplemented, recorder.Code)
+}
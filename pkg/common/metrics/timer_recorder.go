@@ -0,0 +1,162 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/uber-go/tally"
+)
+
+// timerRecorderMaxValues is the number of most recent values kept for each
+// timer by a TimerRecorder
+const timerRecorderMaxValues = 1000
+
+// TimerRecorder keeps the most recent values of the timers reported to the
+// reporters it wraps. A root scope with a reporter reports the timers to it
+// directly, so its snapshots have no timer values.
+type TimerRecorder struct {
+	sync.Mutex
+	timers map[string]*recordedTimer
+}
+
+// recordedTimer is the most recent values of a timer, in a ring buffer
+type recordedTimer struct {
+	name   string
+	tags   map[string]string
+	values []time.Duration
+	// next is the index of the oldest value once the buffer is full
+	next int
+}
+
+// NewTimerRecorder creates a TimerRecorder
+func NewTimerRecorder() *TimerRecorder {
+	return &TimerRecorder{timers: make(map[string]*recordedTimer)}
+}
+
+// WrapReporter returns a reporter which records the timers reported to
+// reporter
+func (r *TimerRecorder) WrapReporter(reporter tally.StatsReporter) tally.StatsReporter {
+	return &recordingReporter{StatsReporter: reporter, recorder: r}
+}
+
+// WrapCachedReporter returns a cached reporter which records the timers
+// reported to reporter
+func (r *TimerRecorder) WrapCachedReporter(
+	reporter tally.CachedStatsReporter) tally.CachedStatsReporter {
+	return &recordingCachedReporter{CachedStatsReporter: reporter, recorder: r}
+}
+
+// record adds a value of a timer, dropping its oldest value if it already
+// has timerRecorderMaxValues values
+func (r *TimerRecorder) record(
+	name string,
+	tags map[string]string,
+	interval time.Duration) {
+	id := tally.KeyForPrefixedStringMap(name, tags)
+
+	r.Lock()
+	defer r.Unlock()
+	t, ok := r.timers[id]
+	if !ok {
+		t = &recordedTimer{name: name, tags: tags}
+		r.timers[id] = t
+	}
+	if len(t.values) < timerRecorderMaxValues {
+		t.values = append(t.values, interval)
+		return
+	}
+	t.values[t.next] = interval
+	t.next = (t.next + 1) % timerRecorderMaxValues
+}
+
+// Snapshot returns the recorded values of the timers, oldest first, in
+// seconds, ordered by ID
+func (r *TimerRecorder) Snapshot() []*TimerSnapshot {
+	r.Lock()
+	defer r.Unlock()
+
+	ids := make([]string, 0, len(r.timers))
+	for id := range r.timers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	timers := []*TimerSnapshot{}
+	for _, id := range ids {
+		t := r.timers[id]
+		timer := &TimerSnapshot{
+			Name:   t.name,
+			Tags:   t.tags,
+			Values: make([]float64, 0, len(t.values)),
+		}
+		for i := range t.values {
+			v := t.values[(t.next+i)%len(t.values)]
+			timer.Values = append(timer.Values, v.Seconds())
+		}
+		timers = append(timers, timer)
+	}
+	return timers
+}
+
+// recordingReporter is a tally.StatsReporter which records its timers
+type recordingReporter struct {
+	tally.StatsReporter
+	recorder *TimerRecorder
+}
+
+// ReportTimer implements tally.StatsReporter.ReportTimer
+func (r *recordingReporter) ReportTimer(
+	name string,
+	tags map[string]string,
+	interval time.Duration) {
+	r.recorder.record(name, tags, interval)
+	r.StatsReporter.ReportTimer(name, tags, interval)
+}
+
+// recordingCachedReporter is a tally.CachedStatsReporter which records its
+// timers
+type recordingCachedReporter struct {
+	tally.CachedStatsReporter
+	recorder *TimerRecorder
+}
+
+// AllocateTimer implements tally.CachedStatsReporter.AllocateTimer
+func (r *recordingCachedReporter) AllocateTimer(
+	name string,
+	tags map[string]string) tally.CachedTimer {
+	return &recordingCachedTimer{
+		CachedTimer: r.CachedStatsReporter.AllocateTimer(name, tags),
+		name:        name,
+		tags:        tags,
+		recorder:    r.recorder,
+	}
+}
+
+// recordingCachedTimer is a tally.CachedTimer which records its values
+type recordingCachedTimer struct {
+	tally.CachedTimer
+	name     string
+	tags     map[string]string
+	recorder *TimerRecorder
+}
+
+// ReportTimer implements tally.CachedTimer.ReportTimer
+func (t *recordingCachedTimer) ReportTimer(interval time.Duration) {
+	t.recorder.record(t.name, t.tags, interval)
+	t.CachedTimer.ReportTimer(interval)
+}
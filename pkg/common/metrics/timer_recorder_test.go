@@ -0,0 +1,86 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"encoding/json"
+	nethttp "net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally"
+	tallymulti "github.com/uber-go/tally/multi"
+)
+
+// TestTimerRecorder tests that the timers of a root scope with a reporter
+// are recorded, keeping their most recent values
+func TestTimerRecorder(t *testing.T) {
+	timers := NewTimerRecorder()
+	scope, closer := tally.NewRootScope(tally.ScopeOptions{
+		Prefix:   "peloton",
+		Reporter: timers.WrapReporter(tally.NullStatsReporter),
+	}, time.Hour)
+	defer closer.Close()
+
+	latency := scope.Tagged(map[string]string{"result": "fail"}).Timer("latency")
+	for i := 0; i < timerRecorderMaxValues+2; i++ {
+		latency.Record(time.Duration(i) * time.Millisecond)
+	}
+	scope.Timer("duration").Record(2 * time.Second)
+
+	// the scope snapshot has no timer values
+	for _, timer := range scope.(tally.TestScope).Snapshot().Timers() {
+		assert.Empty(t, timer.Values())
+	}
+
+	snapshot := timers.Snapshot()
+	assert.Len(t, snapshot, 2)
+	assert.Equal(t, "peloton.duration", snapshot[0].Name)
+	assert.Equal(t, []float64{2}, snapshot[0].Values)
+	assert.Equal(t, "peloton.latency", snapshot[1].Name)
+	assert.Equal(t, map[string]string{"result": "fail"}, snapshot[1].Tags)
+	assert.Len(t, snapshot[1].Values, timerRecorderMaxValues)
+	assert.Equal(t, 0.002, snapshot[1].Values[0])
+	assert.Equal(t, float64(timerRecorderMaxValues+1)/1000,
+		snapshot[1].Values[timerRecorderMaxValues-1])
+
+	recorder := httptest.NewRecorder()
+	SnapshotHandler(scope, timers)(recorder,
+		httptest.NewRequest(nethttp.MethodGet, SnapshotPath, nil))
+	assert.Equal(t, nethttp.StatusOK, recorder.Code)
+	served := &Snapshot{}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), served))
+	assert.Len(t, served.Timers, 2)
+	assert.Equal(t, snapshot[1].Values, served.Timers[1].Values)
+}
+
+// TestTimerRecorderCachedReporter tests that the timers of a root scope
+// with a cached reporter are recorded
+func TestTimerRecorderCachedReporter(t *testing.T) {
+	timers := NewTimerRecorder()
+	scope, closer := tally.NewRootScope(tally.ScopeOptions{
+		Prefix: "peloton",
+		CachedReporter: timers.WrapCachedReporter(
+			tallymulti.NewMultiCachedReporter()),
+	}, time.Hour)
+	defer closer.Close()
+
+	scope.Timer("latency").Record(time.Second)
+	snapshot := timers.Snapshot()
+	assert.Len(t, snapshot, 1)
+	assert.Equal(t, []float64{1}, snapshot[0].Values)
+}
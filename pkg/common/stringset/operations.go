@@ -0,0 +1,104 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stringset
+
+import (
+	"sort"
+)
+
+// ToSortedSlice returns a slice containing all elements in the set in
+// sorted order
+func ToSortedSlice(s StringSet) []string {
+	keys := s.ToSlice()
+	sort.Strings(keys)
+	return keys
+}
+
+// Range calls f for the elements in the set, in no particular order, until
+// f returns false. The set may be modified by f, as the elements visited
+// are a snapshot of the set.
+func Range(s StringSet, f func(key string) bool) {
+	visit(s.ToSlice(), f)
+}
+
+// SortedRange calls f for the elements in the set in sorted order, until f
+// returns false. The set may be modified by f, as the elements visited are
+// a snapshot of the set.
+func SortedRange(s StringSet, f func(key string) bool) {
+	visit(ToSortedSlice(s), f)
+}
+
+// Union returns a new set containing the elements in either set
+func Union(a, b StringSet) StringSet {
+	result := fromSlice(a.ToSlice())
+	result.AddAll(b.ToSlice())
+	return result
+}
+
+// Intersect returns a new set containing the elements in both sets
+func Intersect(a, b StringSet) StringSet {
+	other := fromSlice(b.ToSlice())
+	result := New()
+	for _, key := range a.ToSlice() {
+		if other.m[key] {
+			result.Add(key)
+		}
+	}
+	return result
+}
+
+// Difference returns a new set containing the elements in a which are not
+// in b
+func Difference(a, b StringSet) StringSet {
+	result := fromSlice(a.ToSlice())
+	result.RemoveAll(b.ToSlice())
+	return result
+}
+
+// Equal checks if both sets contain the same elements
+func Equal(a, b StringSet) bool {
+	aKeys := a.ToSlice()
+	bKeys := b.ToSlice()
+	if len(aKeys) != len(bKeys) {
+		return false
+	}
+	other := fromSlice(bKeys)
+	for _, key := range aKeys {
+		if !other.m[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// fromSlice returns a new set containing the keys
+func fromSlice(keys []string) *stringSet {
+	s := &stringSet{
+		m: make(map[string]bool, len(keys)),
+	}
+	for _, key := range keys {
+		s.m[key] = true
+	}
+	return s
+}
+
+// visit calls f for the keys until f returns false
+func visit(keys []string, f func(key string) bool) {
+	for _, key := range keys {
+		if !f(key) {
+			return
+		}
+	}
+}
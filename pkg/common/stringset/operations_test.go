@@ -0,0 +1,106 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package stringset
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newSet returns a new set containing the keys
+func newSet(keys ...string) StringSet {
+	s := New()
+	s.AddAll(keys)
+	return s
+}
+
+func TestToSortedSlice(t *testing.T) {
+	assert.Equal(t, []string{}, ToSortedSlice(New()))
+	assert.Equal(t, []string{"a", "b", "c"}, ToSortedSlice(newSet("c", "a", "b")))
+}
+
+func TestRange(t *testing.T) {
+	var keys []string
+	Range(newSet("c", "a", "b"), func(key string) bool {
+		keys = append(keys, key)
+		return true
+	})
+	assert.ElementsMatch(t, []string{"a", "b", "c"}, keys)
+
+	// the set can be modified while ranging over it
+	s := newSet("c", "a", "b")
+	Range(s, func(key string) bool {
+		s.Remove(key)
+		return true
+	})
+	assert.Empty(t, s.ToSlice())
+
+	Range(New(), func(key string) bool {
+		assert.Fail(t, "unexpected key", key)
+		return true
+	})
+}
+
+func TestSortedRange(t *testing.T) {
+	var keys []string
+	SortedRange(newSet("c", "a", "b"), func(key string) bool {
+		keys = append(keys, key)
+		return key != "b"
+	})
+	assert.Equal(t, []string{"a", "b"}, keys)
+}
+
+func TestUnion(t *testing.T) {
+	a := newSet("a", "b")
+	b := newSet("b", "c")
+	assert.Equal(t, []string{"a", "b", "c"}, ToSortedSlice(Union(a, b)))
+	assert.Equal(t, []string{"a", "b"}, ToSortedSlice(Union(a, New())))
+	assert.Equal(t, []string{"a", "b"}, ToSortedSlice(Union(a, a)))
+	assert.Empty(t, Union(New(), New()).ToSlice())
+
+	// the operands are left unchanged
+	assert.Equal(t, []string{"a", "b"}, ToSortedSlice(a))
+	assert.Equal(t, []string{"b", "c"}, ToSortedSlice(b))
+}
+
+func TestIntersect(t *testing.T) {
+	a := newSet("a", "b")
+	b := newSet("b", "c")
+	assert.Equal(t, []string{"b"}, ToSortedSlice(Intersect(a, b)))
+	assert.Empty(t, Intersect(a, New()).ToSlice())
+	assert.Empty(t, Intersect(a, newSet("c")).ToSlice())
+	assert.Equal(t, []string{"a", "b"}, ToSortedSlice(Intersect(a, a)))
+}
+
+func TestDifference(t *testing.T) {
+	a := newSet("a", "b")
+	b := newSet("b", "c")
+	assert.Equal(t, []string{"a"}, ToSortedSlice(Difference(a, b)))
+	assert.Equal(t, []string{"c"}, ToSortedSlice(Difference(b, a)))
+	assert.Equal(t, []string{"a", "b"}, ToSortedSlice(Difference(a, New())))
+	assert.Empty(t, Difference(New(), a).ToSlice())
+	assert.Empty(t, Difference(a, a).ToSlice())
+}
+
+func TestEqual(t *testing.T) {
+	a := newSet("a", "b")
+	assert.True(t, Equal(a, a))
+	assert.True(t, Equal(a, newSet("b", "a")))
+	assert.True(t, Equal(New(), New()))
+	assert.False(t, Equal(a, New()))
+	assert.False(t, Equal(a, newSet("a", "c")))
+	assert.False(t, Equal(a, newSet("a", "b", "c")))
+}
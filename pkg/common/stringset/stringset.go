@@ -22,13 +22,18 @@ import (
 type StringSet interface {
 	// Add adds 'key' to the set
 	Add(key string)
+	// AddAll adds all 'keys' to the set at once
+	AddAll(keys []string)
 	// Remove removes 'key' from the set
 	Remove(key string)
+	// RemoveAll removes all 'keys' from the set at once
+	RemoveAll(keys []string)
 	// Contains checks if the set contains 'key'
 	Contains(key string) bool
 	// Clear clears the contents of set
 	Clear()
-	// ToSlice returns a slice containing all elements in the set
+	// ToSlice returns a slice containing all elements in the set, a
+	// consistent snapshot of the set when it is modified concurrently
 	ToSlice() []string
 }
 
@@ -54,6 +59,17 @@ func (s *stringSet) Add(key string) {
 	s.m[key] = true
 }
 
+// AddAll adds all 'keys' to the set at once, so that they are seen either
+// all or none by concurrent readers
+func (s *stringSet) AddAll(keys []string) {
+	defer s.Unlock()
+	s.Lock()
+
+	for _, key := range keys {
+		s.m[key] = true
+	}
+}
+
 // Contains checks if the set contains 'key'
 func (s *stringSet) Contains(key string) bool {
 	defer s.RUnlock()
@@ -70,6 +86,17 @@ func (s *stringSet) Remove(key string) {
 	delete(s.m, key)
 }
 
+// RemoveAll removes all 'keys' from the set at once, so that they are seen
+// either all or none by concurrent readers
+func (s *stringSet) RemoveAll(keys []string) {
+	defer s.Unlock()
+	s.Lock()
+
+	for _, key := range keys {
+		delete(s.m, key)
+	}
+}
+
 // Clear clears the contents of the set
 func (s *stringSet) Clear() {
 	defer s.Unlock()
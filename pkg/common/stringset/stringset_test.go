@@ -15,6 +15,9 @@
 package stringset
 
 import (
+	"fmt"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -97,3 +100,111 @@ func TestStringSet_ToSlice(t *testing.T) {
 		assert.True(t, testSet.Contains(item))
 	}
 }
+
+func TestStringSet_AddAll(t *testing.T) {
+	testSet := New()
+	testSet.Add("testitem1")
+	testSet.AddAll([]string{"testitem1", "testitem2", "testitem3"})
+	assert.ElementsMatch(t,
+		[]string{"testitem1", "testitem2", "testitem3"}, testSet.ToSlice())
+
+	testSet.AddAll(nil)
+	assert.Len(t, testSet.ToSlice(), 3)
+}
+
+func TestStringSet_RemoveAll(t *testing.T) {
+	testSet := New()
+	testSet.AddAll([]string{"testitem1", "testitem2", "testitem3"})
+	testSet.RemoveAll([]string{"testitem1", "testitem3", "testitem4"})
+	assert.Equal(t, []string{"testitem2"}, testSet.ToSlice())
+}
+
+// TestStringSet_Concurrent tests that concurrent readers see the keys added
+// or removed at once by concurrent writers either all or none
+func TestStringSet_Concurrent(t *testing.T) {
+	const writers, batches = 4, 100
+	testSet := New()
+	batch := func(writer, i int) []string {
+		return []string{
+			fmt.Sprintf("%d-%d-a", writer, i),
+			fmt.Sprintf("%d-%d-b", writer, i),
+		}
+	}
+
+	var wg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(writer int) {
+			defer wg.Done()
+			for i := 0; i < batches; i++ {
+				testSet.AddAll(batch(writer, i))
+				if i%2 == 0 {
+					testSet.RemoveAll(batch(writer, i))
+				}
+			}
+		}(w)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < batches; i++ {
+			counts := make(map[string]int)
+			for _, key := range testSet.ToSlice() {
+				counts[strings.TrimSuffix(
+					strings.TrimSuffix(key, "-a"), "-b")]++
+			}
+			for prefix, count := range counts {
+				assert.Equal(t, 2, count, "partial batch %s", prefix)
+			}
+		}
+	}()
+	wg.Wait()
+	<-done
+
+	assert.Len(t, testSet.ToSlice(), writers*batches)
+	for w := 0; w < writers; w++ {
+		for i := 0; i < batches; i++ {
+			for _, key := range batch(w, i) {
+				assert.Equal(t, i%2 != 0, testSet.Contains(key))
+			}
+		}
+	}
+}
+
+// testKeys returns n keys for the benchmarks
+func testKeys(n int) []string {
+	keys := make([]string, n)
+	for i := range keys {
+		keys[i] = fmt.Sprintf("host-%d", i)
+	}
+	return keys
+}
+
+func BenchmarkStringSet_Add(b *testing.B) {
+	keys := testKeys(100)
+	for i := 0; i < b.N; i++ {
+		testSet := New()
+		for _, key := range keys {
+			testSet.Add(key)
+		}
+	}
+}
+
+func BenchmarkStringSet_AddAll(b *testing.B) {
+	keys := testKeys(100)
+	for i := 0; i < b.N; i++ {
+		New().AddAll(keys)
+	}
+}
+
+func BenchmarkStringSet_ContainsParallel(b *testing.B) {
+	keys := testKeys(100)
+	testSet := New()
+	testSet.AddAll(keys)
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			testSet.Contains(keys[i%len(keys)])
+		}
+	})
+}
@@ -17,6 +17,10 @@ package util
 import (
 	"fmt"
 	"strings"
+
+	"github.com/gogo/protobuf/proto"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
 )
 
 const (
@@ -53,3 +57,21 @@ func ExtractIPAndPortFromMesosAgentPID(pid string) (string, string, error) {
 	}
 	return ip, port, nil
 }
+
+// CopyFrameworkInfo returns a deep copy of a framework info, which shares
+// no nested message with it so that either can be modified
+func CopyFrameworkInfo(info *mesos.FrameworkInfo) *mesos.FrameworkInfo {
+	if info == nil {
+		return nil
+	}
+	return proto.Clone(info).(*mesos.FrameworkInfo)
+}
+
+// CopyOffer returns a deep copy of an offer, which shares no nested message
+// with it so that either can be modified
+func CopyOffer(offer *mesos.Offer) *mesos.Offer {
+	if offer == nil {
+		return nil
+	}
+	return proto.Clone(offer).(*mesos.Offer)
+}
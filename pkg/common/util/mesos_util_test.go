@@ -19,6 +19,8 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
 )
 
 // Test extraction of IP and port from Agent PID
@@ -50,3 +52,57 @@ func TestExtractIPAndPortFromMesosPid(t *testing.T) {
 		}
 	}
 }
+
+// Test that a copied framework info shares no nested message
+func TestCopyFrameworkInfo(t *testing.T) {
+	info := &mesos.FrameworkInfo{
+		User: PtrStr("peloton"),
+		Name: PtrStr("Peloton"),
+		Id:   &mesos.FrameworkID{Value: PtrStr("framework-id")},
+		Capabilities: []*mesos.FrameworkInfo_Capability{
+			{Type: mesos.FrameworkInfo_Capability_GPU_RESOURCES.Enum()},
+		},
+		Labels: &mesos.Labels{
+			Labels: []*mesos.Label{{Key: PtrStr("key"), Value: PtrStr("value")}},
+		},
+	}
+	copied := CopyFrameworkInfo(info)
+	assert.Equal(t, info, copied)
+
+	*copied.Name = "other"
+	copied.Id.Value = PtrStr("other-framework-id")
+	copied.Capabilities[0].Type = mesos.FrameworkInfo_Capability_PARTITION_AWARE.Enum()
+	copied.Labels.Labels[0].Value = PtrStr("other-value")
+	assert.Equal(t, "Peloton", info.GetName())
+	assert.Equal(t, "framework-id", info.GetId().GetValue())
+	assert.Equal(t, mesos.FrameworkInfo_Capability_GPU_RESOURCES,
+		info.GetCapabilities()[0].GetType())
+	assert.Equal(t, "value", info.GetLabels().GetLabels()[0].GetValue())
+
+	assert.Nil(t, CopyFrameworkInfo(nil))
+}
+
+// Test that a copied offer shares no nested message
+func TestCopyOffer(t *testing.T) {
+	offer := &mesos.Offer{
+		Id:       &mesos.OfferID{Value: PtrStr("offer-id")},
+		AgentId:  &mesos.AgentID{Value: PtrStr("agent-id")},
+		Hostname: PtrStr("host"),
+		Resources: []*mesos.Resource{
+			{
+				Name:   PtrStr("cpus"),
+				Type:   mesos.Value_SCALAR.Enum(),
+				Scalar: &mesos.Value_Scalar{Value: PtrFloat64(1)},
+			},
+		},
+	}
+	copied := CopyOffer(offer)
+	assert.Equal(t, offer, copied)
+
+	copied.AgentId.Value = PtrStr("other-agent-id")
+	*copied.Resources[0].Scalar.Value = 2
+	assert.Equal(t, "agent-id", offer.GetAgentId().GetValue())
+	assert.Equal(t, 1.0, offer.GetResources()[0].GetScalar().GetValue())
+
+	assert.Nil(t, CopyOffer(nil))
+}
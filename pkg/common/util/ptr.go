@@ -0,0 +1,45 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+// The helpers below return a pointer to a copy of a value, for the optional
+// fields of protobuf messages. Unlike taking the address of a variable, the
+// pointer never aliases the variable, e.g. a loop variable or a config
+// field.
+
+// PtrStr returns a pointer to a copy of a string
+func PtrStr(s string) *string {
+	return &s
+}
+
+// PtrBool returns a pointer to a copy of a bool
+func PtrBool(b bool) *bool {
+	return &b
+}
+
+// PtrFloat64 returns a pointer to a copy of a float64
+func PtrFloat64(f float64) *float64 {
+	return &f
+}
+
+// PtrInt32 returns a pointer to a copy of an int32
+func PtrInt32(i int32) *int32 {
+	return &i
+}
+
+// PtrUint64 returns a pointer to a copy of a uint64
+func PtrUint64(u uint64) *uint64 {
+	return &u
+}
@@ -424,6 +424,25 @@ func TestPtrPrintf(t *testing.T) {
 		*PtrPrintf("Thi%s %v is %dame", "$", "test", 1))
 }
 
+// Test that the pointer helpers do not alias their argument
+func TestPtrHelpers(t *testing.T) {
+	s, b, f, i, u := "value", true, 1.5, int32(-2), uint64(3)
+	sp, bp, fp, ip, up := PtrStr(s), PtrBool(b), PtrFloat64(f), PtrInt32(i), PtrUint64(u)
+	s, b, f, i, u = "other", false, 2.5, 4, 5
+	assert.Equal(t, "value", *sp)
+	assert.True(t, *bp)
+	assert.Equal(t, 1.5, *fp)
+	assert.Equal(t, int32(-2), *ip)
+	assert.Equal(t, uint64(3), *up)
+
+	var ptrs []*string
+	for _, v := range []string{"a", "b"} {
+		ptrs = append(ptrs, PtrStr(v))
+	}
+	assert.Equal(t, "a", *ptrs[0])
+	assert.Equal(t, "b", *ptrs[1])
+}
+
 // Test Max/Min for uint32s
 func TestMaxMinUint32(t *testing.T) {
 	min := uint32(15)
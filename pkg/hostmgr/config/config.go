@@ -17,7 +17,10 @@ package config
 import (
 	"time"
 
+	hostmgr_mesos "github.com/uber/peloton/pkg/hostmgr/mesos"
+	"github.com/uber/peloton/pkg/hostmgr/offer/offerpool"
 	"github.com/uber/peloton/pkg/hostmgr/reconcile"
+	"github.com/uber/peloton/pkg/hostmgr/task"
 )
 
 // Config is Host Manager specific configuration
@@ -46,8 +49,15 @@ type Config struct {
 	// Size of the channel buffer of the status updates
 	TaskUpdateBufferSize int `yaml:"taskupdate_buffer_size"`
 
+	// Batching of the acknowledgements of the status updates
+	TaskUpdateAckBatcher hostmgr_mesos.AcknowledgeBatcherConfig `yaml:"taskupdate_ack_batcher"`
+
 	TaskReconcilerConfig *reconcile.TaskReconcilerConfig `yaml:"task_reconciler"`
 
+	// Reconciliation of the tasks with the Mesos master, to which the
+	// task reconciler delegates its explicit rounds
+	Reconciler hostmgr_mesos.ReconcilerConfig `yaml:"reconciler"`
+
 	HostmapRefreshInterval time.Duration `yaml:"hostmap_refresh_interval"`
 
 	// Period in sec for running host pruning
@@ -68,6 +78,23 @@ type Config struct {
 	// Host Drainer Period
 	HostDrainerPeriod time.Duration `yaml:"host_drainer_period"`
 
+	// Period of the writes of the maintenance schedule of the Mesos master
+	MaintenanceScheduleWritePeriod time.Duration `yaml:"maintenance_schedule_write_period"`
+
+	// Period of the reloads of the allowed users of the tasks from the
+	// config files, 0 to not reload them
+	AllowedUsersReloadPeriod time.Duration `yaml:"allowed_users_reload_period"`
+
+	// Suppression of the offers of the roles without placement demand
+	OfferSuppression *offerpool.SuppressorConfig `yaml:"offer_suppression"`
+
+	// Batching of the declined offers, and their refuse durations by reason
+	DeclineBatcher *offerpool.DeclineBatcherConfig `yaml:"decline_batcher"`
+
+	// Escalation of the kills of the tasks not terminated after a grace
+	// period
+	KillEscalation *task.KillEscalatorConfig `yaml:"kill_escalation"`
+
 	// Represents scarce resource types such as GPU.
 	ScarceResourceTypes []string `yaml:"scarce_resource_types"`
 
@@ -78,4 +105,7 @@ type Config struct {
 	BinPacking string `yaml:"bin_packing"`
 	// Bin Packing Refresh Interval
 	BinPackingRefreshIntervalSec time.Duration `yaml:"bin_packing_refresh_interval"`
+
+	// Period of the logs of the churn of the offer pool, 0 to not log it
+	OfferChurnLogPeriod time.Duration `yaml:"offer_churn_log_period"`
 }
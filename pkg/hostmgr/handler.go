@@ -18,6 +18,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/http"
 	"strings"
 	"sync"
 	"time"
@@ -87,7 +88,10 @@ type ServiceHandler struct {
 	operatorMasterClient   mpb.MasterOperatorClient
 	metrics                *metrics.Metrics
 	offerPool              offerpool.Pool
+	suppressor             offerpool.Suppressor
 	frameworkInfoProvider  hostmgr_mesos.FrameworkInfoProvider
+	schedulerDriver        hostmgr_mesos.SchedulerDriver
+	callSender             hostmgr_mesos.CallSender
 	volumeStore            storage.PersistentVolumeStore
 	roleName               string
 	mesosDetector          hostmgr_mesos.MasterDetector
@@ -97,6 +101,8 @@ type ServiceHandler struct {
 	slackResourceTypes     []string
 	maintenanceHostInfoMap host.MaintenanceHostInfoMap
 	taskStateManager       taskStateManager.StateManager
+	taskUserValidator      *TaskUserValidator
+	killEscalator          *taskStateManager.KillEscalator
 }
 
 // NewServiceHandler creates a new ServiceHandler.
@@ -105,7 +111,8 @@ func NewServiceHandler(
 	parent tally.Scope,
 	schedulerClient mpb.SchedulerClient,
 	masterOperatorClient mpb.MasterOperatorClient,
-	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider,
+	schedulerDriver hostmgr_mesos.SchedulerDriver,
+	callSender hostmgr_mesos.CallSender,
 	volumeStore storage.PersistentVolumeStore,
 	mesosConfig hostmgr_mesos.Config,
 	mesosDetector hostmgr_mesos.MasterDetector,
@@ -113,14 +120,19 @@ func NewServiceHandler(
 	maintenanceQueue mqueue.MaintenanceQueue,
 	slackResourceTypes []string,
 	maintenanceHostInfoMap host.MaintenanceHostInfoMap,
-	taskStateManager taskStateManager.StateManager) *ServiceHandler {
+	taskStateManager taskStateManager.StateManager,
+	taskUserValidator *TaskUserValidator,
+	killEscalator *taskStateManager.KillEscalator) *ServiceHandler {
 
 	handler := &ServiceHandler{
 		schedulerClient:        schedulerClient,
 		operatorMasterClient:   masterOperatorClient,
 		metrics:                metrics.NewMetrics(parent),
 		offerPool:              offer.GetEventHandler().GetOfferPool(),
-		frameworkInfoProvider:  frameworkInfoProvider,
+		suppressor:             offer.GetEventHandler().GetSuppressor(),
+		frameworkInfoProvider:  schedulerDriver,
+		schedulerDriver:        schedulerDriver,
+		callSender:             callSender,
 		volumeStore:            volumeStore,
 		roleName:               mesosConfig.Framework.Role,
 		mesosDetector:          mesosDetector,
@@ -128,6 +140,8 @@ func NewServiceHandler(
 		slackResourceTypes:     slackResourceTypes,
 		maintenanceHostInfoMap: maintenanceHostInfoMap,
 		taskStateManager:       taskStateManager,
+		taskUserValidator:      taskUserValidator,
+		killEscalator:          killEscalator,
 	}
 	// Creating Reserver object for handler
 	handler.reserver = reserver.NewReserver(
@@ -257,6 +271,12 @@ func (h *ServiceHandler) AcquireHostOffers(
 		}, nil
 	}
 
+	// The placement engines acquire offers for the tasks dequeued from the
+	// resource manager, so this is the demand of offers for the role.
+	if h.suppressor != nil {
+		h.suppressor.DemandReceived(h.roleName)
+	}
+
 	result, resultCount, err := h.offerPool.ClaimForPlace(body.GetFilter())
 	if err != nil {
 		log.WithError(err).Warn("ClaimForPlace failed")
@@ -297,6 +317,7 @@ func (h *ServiceHandler) AcquireHostOffers(
 			Attributes: attributes,
 			Resources:  resources,
 			Id:         &peloton.HostOfferID{Value: hostOffer.ID},
+			Domain:     offers[0].GetDomain(),
 		}
 
 		response.HostOffers = append(response.HostOffers, &pHostOffer)
@@ -511,6 +532,23 @@ func (h *ServiceHandler) OfferOperations(
 		agentID,
 	)
 	offerOperations, err := factory.GetOfferOperations()
+
+	// The ACCEPT call is prepared, and validated, before the volume info is
+	// written. Failing to prepare a valid call is a failure of the call.
+	var acceptReq *http.Request
+	var prepareErr error
+	if err == nil {
+		acceptReq, prepareErr = h.schedulerDriver.PrepareAcceptRequest(
+			ctx,
+			h.mesosDetector.HostPort(),
+			offerIds,
+			offerOperations,
+			nil,
+		)
+		if hostmgr_mesos.IsAcceptValidationError(prepareErr) {
+			err = prepareErr
+		}
+	}
 	if err == nil {
 		// write the volume info into db if no error.
 		err = h.persistVolumeInfo(ctx, offerOperations, req.GetHostname())
@@ -541,23 +579,16 @@ func (h *ServiceHandler) OfferOperations(
 		}, nil
 	}
 
-	callType := sched.Call_ACCEPT
-	msg := &sched.Call{
-		FrameworkId: h.frameworkInfoProvider.GetFrameworkID(ctx),
-		Type:        &callType,
-		Accept: &sched.Call_Accept{
-			OfferIds:   offerIds,
-			Operations: offerOperations,
-		},
-	}
-
 	log.WithFields(log.Fields{
-		"call": msg,
+		"offers":     offerIds,
+		"operations": offerOperations,
 	}).Debug("Accepting offer with operations.")
 
 	// TODO: add retry / put back offer and tasks in failure scenarios
-	msid := h.frameworkInfoProvider.GetMesosStreamID(ctx)
-	err = h.schedulerClient.Call(msid, msg)
+	err = prepareErr
+	if err == nil {
+		err = h.callSender.Send(ctx, acceptReq)
+	}
 	if err != nil {
 		h.metrics.OfferOperationsFail.Inc(1)
 		log.WithError(err).WithFields(log.Fields{
@@ -664,6 +695,21 @@ func (h *ServiceHandler) LaunchTasks(
 		}, nil
 	}
 
+	if err := h.validateTaskUsers(req); err != nil {
+		log.WithFields(log.Fields{
+			"hostname":      req.GetHostname(),
+			"host_offer_id": req.GetId(),
+		}).WithError(err).Warn("launch tasks as a user not allowed")
+		h.metrics.LaunchTasksInvalid.Inc(1)
+		return &hostsvc.LaunchTasksResponse{
+			Error: &hostsvc.LaunchTasksResponse_Error{
+				InvalidArgument: &hostsvc.InvalidArgument{
+					Message: err.Error(),
+				},
+			},
+		}, nil
+	}
+
 	hostToTaskIDs := make(map[string][]*peloton.TaskID)
 	for _, launchableTask := range req.GetTasks() {
 		hostHeld := h.offerPool.GetHostHeldForTask(launchableTask.GetId())
@@ -777,31 +823,46 @@ func (h *ServiceHandler) LaunchTasks(
 		mesosTaskIds = append(mesosTaskIds, mesosTask.GetTaskId().GetValue())
 	}
 
-	callType := sched.Call_ACCEPT
-	opType := mesos.Offer_Operation_LAUNCH
-	msg := &sched.Call{
-		FrameworkId: h.frameworkInfoProvider.GetFrameworkID(ctx),
-		Type:        &callType,
-		Accept: &sched.Call_Accept{
-			OfferIds: offerIds,
-			Operations: []*mesos.Offer_Operation{
-				{
-					Type: &opType,
-					Launch: &mesos.Offer_Operation_Launch{
-						TaskInfos: mesosTasks,
-					},
-				},
+	operations := []*mesos.Offer_Operation{
+		{
+			Type: mesos.Offer_Operation_LAUNCH.Enum(),
+			Launch: &mesos.Offer_Operation_Launch{
+				TaskInfos: mesosTasks,
 			},
 		},
 	}
+	acceptReq, err := h.schedulerDriver.PrepareAcceptRequest(
+		ctx,
+		h.mesosDetector.HostPort(),
+		offerIds,
+		operations,
+		nil,
+	)
+	if hostmgr_mesos.IsAcceptValidationError(err) {
+		log.WithFields(log.Fields{
+			"offers":        offerIds,
+			"hostname":      req.GetHostname(),
+			"host_offer_id": req.GetId().GetValue(),
+		}).WithError(err).Warn("invalid launch of tasks")
+		h.metrics.LaunchTasksInvalidOffers.Inc(1)
+		return &hostsvc.LaunchTasksResponse{
+			Error: &hostsvc.LaunchTasksResponse_Error{
+				InvalidOffers: &hostsvc.InvalidOffers{
+					Message: err.Error(),
+				},
+			},
+		}, nil
+	}
 
 	log.WithFields(log.Fields{
-		"call": msg,
+		"offers":     offerIds,
+		"operations": operations,
 	}).Debug("Launching tasks to Mesos.")
 
 	// TODO: add retry / put back offer and tasks in failure scenarios
-	msid := h.frameworkInfoProvider.GetMesosStreamID(ctx)
-	err = h.schedulerClient.Call(msid, msg)
+	if err == nil {
+		err = h.callSender.Send(ctx, acceptReq)
+	}
 	if err != nil {
 		h.metrics.LaunchTasksFail.Inc(int64(len(mesosTasks)))
 		log.WithFields(log.Fields{
@@ -850,6 +911,22 @@ func validateLaunchTasks(request *hostsvc.LaunchTasksRequest) error {
 	return nil
 }
 
+// validateTaskUsers validates the users the tasks of a launch run as.
+func (h *ServiceHandler) validateTaskUsers(
+	request *hostsvc.LaunchTasksRequest) error {
+	if h.taskUserValidator == nil {
+		return nil
+	}
+	for _, launchableTask := range request.GetTasks() {
+		user := launchableTask.GetConfig().GetCommand().GetUser()
+		if err := h.taskUserValidator.Validate(user); err != nil {
+			return fmt.Errorf("cannot launch task %s: %v",
+				launchableTask.GetTaskId().GetValue(), err)
+		}
+	}
+	return nil
+}
+
 // ShutdownExecutors implements InternalHostService.ShutdownExecutors.
 func (h *ServiceHandler) ShutdownExecutors(
 	ctx context.Context,
@@ -880,10 +957,9 @@ func (h *ServiceHandler) ShutdownExecutors(
 			executorID := shutdownExecutor.GetExecutorId()
 			agentID := shutdownExecutor.GetAgentId()
 
-			callType := sched.Call_SHUTDOWN
 			msg := &sched.Call{
 				FrameworkId: h.frameworkInfoProvider.GetFrameworkID(ctx),
-				Type:        &callType,
+				Type:        sched.Call_SHUTDOWN.Enum(),
 				Shutdown: &sched.Call_Shutdown{
 					ExecutorId: executorID,
 					AgentId:    agentID,
@@ -1039,10 +1115,9 @@ func (h *ServiceHandler) killTasks(
 		wg.Add(1)
 		go func(taskID *mesos.TaskID) {
 			defer wg.Done()
-			callType := sched.Call_KILL
 			msg := &sched.Call{
 				FrameworkId: h.frameworkInfoProvider.GetFrameworkID(ctx),
-				Type:        &callType,
+				Type:        sched.Call_KILL.Enum(),
 				Kill: &sched.Call_Kill{
 					TaskId: taskID,
 				},
@@ -1066,6 +1141,9 @@ func (h *ServiceHandler) killTasks(
 
 			h.metrics.KillTasks.Inc(1)
 			log.WithField("task", taskID).Info("Task kill request sent")
+			if h.killEscalator != nil {
+				h.killEscalator.KillIssued(taskID)
+			}
 		}(taskID)
 	}
 
@@ -1347,9 +1425,7 @@ func (h *ServiceHandler) MarkHostsDrained(
 	request *hostsvc.MarkHostsDrainedRequest,
 ) (*hostsvc.MarkHostsDrainedResponse, error) {
 	hostSet := stringset.New()
-	for _, host := range request.GetHostnames() {
-		hostSet.Add(host)
-	}
+	hostSet.AddAll(request.GetHostnames())
 	var machineIDs []*mesos.MachineID
 	for _, hostInfo := range h.maintenanceHostInfoMap.GetDrainingHostInfos([]string{}) {
 		if hostSet.Contains(hostInfo.GetHostname()) {
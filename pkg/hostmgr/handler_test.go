@@ -17,6 +17,7 @@ package hostmgr
 import (
 	"errors"
 	"fmt"
+	"net/http"
 	"sort"
 	"strings"
 	"sync"
@@ -41,6 +42,7 @@ import (
 	"github.com/uber/peloton/pkg/hostmgr/config"
 	"github.com/uber/peloton/pkg/hostmgr/host"
 	hm "github.com/uber/peloton/pkg/hostmgr/host/mocks"
+	hostmgr_mesos "github.com/uber/peloton/pkg/hostmgr/mesos"
 	hostmgr_mesos_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/mocks"
 	mpb_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
 	"github.com/uber/peloton/pkg/hostmgr/metrics"
@@ -66,6 +68,8 @@ const (
 	_streamID      = "streamID"
 	_frameworkID   = "frameworkID"
 
+	_masterHostPort = "master:5050"
+
 	_perHostCPU  = 10.0
 	_perHostMem  = 20.0
 	_perHostDisk = 30.0
@@ -88,6 +92,10 @@ var (
 	rootCtx      = context.Background()
 	_testKey     = "testKey"
 	_pelotonRole = "peloton"
+
+	// _acceptRequest is the request of the accept calls prepared by the
+	// mocked scheduler driver
+	_acceptRequest = &http.Request{}
 )
 
 func generateOffers(numOffers int) []*mesos.Offer {
@@ -192,6 +200,8 @@ type HostMgrHandlerTestSuite struct {
 	schedulerClient        *mpb_mocks.MockSchedulerClient
 	masterOperatorClient   *mpb_mocks.MockMasterOperatorClient
 	provider               *hostmgr_mesos_mocks.MockFrameworkInfoProvider
+	driver                 *hostmgr_mesos_mocks.MockSchedulerDriver
+	callSender             *hostmgr_mesos_mocks.MockCallSender
 	volumeStore            *storage_mocks.MockPersistentVolumeStore
 	pool                   offerpool.Pool
 	handler                *ServiceHandler
@@ -231,6 +241,8 @@ func (suite *HostMgrHandlerTestSuite) SetupTest() {
 	suite.schedulerClient = mpb_mocks.NewMockSchedulerClient(suite.ctrl)
 	suite.masterOperatorClient = mpb_mocks.NewMockMasterOperatorClient(suite.ctrl)
 	suite.provider = hostmgr_mesos_mocks.NewMockFrameworkInfoProvider(suite.ctrl)
+	suite.driver = hostmgr_mesos_mocks.NewMockSchedulerDriver(suite.ctrl)
+	suite.callSender = hostmgr_mesos_mocks.NewMockCallSender(suite.ctrl)
 	suite.volumeStore = storage_mocks.NewMockPersistentVolumeStore(suite.ctrl)
 	suite.mesosDetector = hostmgr_mesos_mocks.NewMockMasterDetector(suite.ctrl)
 	suite.taskStateManager = task_state_mocks.NewMockStateManager(suite.ctrl)
@@ -252,6 +264,7 @@ func (suite *HostMgrHandlerTestSuite) SetupTest() {
 		[]string{},        /*slack_resource_types*/
 		bin_packing.CreateRanker("FIRST_FIT"),
 		time.Duration(30*time.Second),
+		nil, /* declineBatcher */
 	)
 
 	suite.maintenanceQueue = qm.NewMockMaintenanceQueue(suite.ctrl)
@@ -263,6 +276,8 @@ func (suite *HostMgrHandlerTestSuite) SetupTest() {
 		metrics:                metrics.NewMetrics(suite.testScope),
 		offerPool:              suite.pool,
 		frameworkInfoProvider:  suite.provider,
+		schedulerDriver:        suite.driver,
+		callSender:             suite.callSender,
 		volumeStore:            suite.volumeStore,
 		mesosDetector:          suite.mesosDetector,
 		maintenanceQueue:       suite.maintenanceQueue,
@@ -742,29 +757,27 @@ func (suite *HostMgrHandlerTestSuite) TestAcquireAndLaunch() {
 	launchReq.Tasks = generateLaunchableTasks(1)
 
 	gomock.InOrder(
-		// Set expectations on provider
-		suite.provider.EXPECT().GetFrameworkID(context.Background()).Return(
-			suite.frameworkID),
-		// Set expectations on provider
-		suite.provider.EXPECT().GetMesosStreamID(context.Background()).Return(_streamID),
-		// Set expectations on scheduler schedulerClient
-		suite.schedulerClient.EXPECT().
-			Call(
-				gomock.Eq(_streamID),
+		// Set expectations on the driver preparing the accept call
+		suite.mesosDetector.EXPECT().HostPort().Return(_masterHostPort),
+		suite.driver.EXPECT().
+			PrepareAcceptRequest(
+				gomock.Any(),
+				_masterHostPort,
 				gomock.Any(),
+				gomock.Any(),
+				nil,
 			).
-			Do(func(_ string, msg proto.Message) {
-				// Verify clientCall message.
-				call := msg.(*sched.Call)
-				suite.Equal(sched.Call_ACCEPT, call.GetType())
-				suite.Equal(_frameworkID, call.GetFrameworkId().GetValue())
-
-				accept := call.GetAccept()
-				suite.NotNil(accept)
-				suite.Equal(1, len(accept.GetOfferIds()))
-				suite.Equal("offer-0", accept.GetOfferIds()[0].GetValue())
-				suite.Equal(1, len(accept.GetOperations()))
-				operation := accept.GetOperations()[0]
+			Do(func(
+				_ context.Context,
+				_ string,
+				offerIDs []*mesos.OfferID,
+				operations []*mesos.Offer_Operation,
+				_ *mesos.Filters) {
+				// Verify the accept call.
+				suite.Equal(1, len(offerIDs))
+				suite.Equal("offer-0", offerIDs[0].GetValue())
+				suite.Equal(1, len(operations))
+				operation := operations[0]
 				suite.Equal(
 					mesos.Offer_Operation_LAUNCH,
 					operation.GetType())
@@ -775,6 +788,10 @@ func (suite *HostMgrHandlerTestSuite) TestAcquireAndLaunch() {
 					fmt.Sprintf(_taskIDFmt, 0),
 					launch.GetTaskInfos()[0].GetTaskId().GetValue())
 			}).
+			Return(_acceptRequest, nil),
+		// Set expectations on the call sender
+		suite.callSender.EXPECT().
+			Send(gomock.Any(), _acceptRequest).
 			Return(nil),
 	)
 
@@ -830,29 +847,27 @@ func (suite *HostMgrHandlerTestSuite) TestAcquireAndLaunchOnNonHeldTask() {
 	suite.Equal(hs1.GetHostStatus(), summary.HeldHost)
 
 	gomock.InOrder(
-		// Set expectations on provider
-		suite.provider.EXPECT().GetFrameworkID(context.Background()).Return(
-			suite.frameworkID),
-		// Set expectations on provider
-		suite.provider.EXPECT().GetMesosStreamID(context.Background()).Return(_streamID),
-		// Set expectations on scheduler schedulerClient
-		suite.schedulerClient.EXPECT().
-			Call(
-				gomock.Eq(_streamID),
+		// Set expectations on the driver preparing the accept call
+		suite.mesosDetector.EXPECT().HostPort().Return(_masterHostPort),
+		suite.driver.EXPECT().
+			PrepareAcceptRequest(
+				gomock.Any(),
+				_masterHostPort,
+				gomock.Any(),
 				gomock.Any(),
+				nil,
 			).
-			Do(func(_ string, msg proto.Message) {
-				// Verify clientCall message.
-				call := msg.(*sched.Call)
-				suite.Equal(sched.Call_ACCEPT, call.GetType())
-				suite.Equal(_frameworkID, call.GetFrameworkId().GetValue())
-
-				accept := call.GetAccept()
-				suite.NotNil(accept)
-				suite.Equal(1, len(accept.GetOfferIds()))
-				suite.Equal("offer-0", accept.GetOfferIds()[0].GetValue())
-				suite.Equal(1, len(accept.GetOperations()))
-				operation := accept.GetOperations()[0]
+			Do(func(
+				_ context.Context,
+				_ string,
+				offerIDs []*mesos.OfferID,
+				operations []*mesos.Offer_Operation,
+				_ *mesos.Filters) {
+				// Verify the accept call.
+				suite.Equal(1, len(offerIDs))
+				suite.Equal("offer-0", offerIDs[0].GetValue())
+				suite.Equal(1, len(operations))
+				operation := operations[0]
 				suite.Equal(
 					mesos.Offer_Operation_LAUNCH,
 					operation.GetType())
@@ -863,6 +878,10 @@ func (suite *HostMgrHandlerTestSuite) TestAcquireAndLaunchOnNonHeldTask() {
 					fmt.Sprintf(_taskIDFmt, 0),
 					launch.GetTaskInfos()[0].GetTaskId().GetValue())
 			}).
+			Return(_acceptRequest, nil),
+		// Set expectations on the call sender
+		suite.callSender.EXPECT().
+			Send(gomock.Any(), _acceptRequest).
 			Return(nil),
 	)
 
@@ -929,29 +948,27 @@ func (suite *HostMgrHandlerTestSuite) TestAcquireAndLaunchOperation() {
 	operationReq.Operations[0].Launch.Tasks = generateLaunchableTasks(1)
 
 	gomock.InOrder(
-		// Set expectations on provider
-		suite.provider.EXPECT().GetFrameworkID(context.Background()).Return(
-			suite.frameworkID),
-		// Set expectations on provider
-		suite.provider.EXPECT().GetMesosStreamID(context.Background()).Return(_streamID),
-		// Set expectations on scheduler schedulerClient
-		suite.schedulerClient.EXPECT().
-			Call(
-				gomock.Eq(_streamID),
+		// Set expectations on the driver preparing the accept call
+		suite.mesosDetector.EXPECT().HostPort().Return(_masterHostPort),
+		suite.driver.EXPECT().
+			PrepareAcceptRequest(
 				gomock.Any(),
+				_masterHostPort,
+				gomock.Any(),
+				gomock.Any(),
+				nil,
 			).
-			Do(func(_ string, msg proto.Message) {
-				// Verify clientCall message.
-				call := msg.(*sched.Call)
-				suite.Equal(sched.Call_ACCEPT, call.GetType())
-				suite.Equal(_frameworkID, call.GetFrameworkId().GetValue())
-
-				accept := call.GetAccept()
-				suite.NotNil(accept)
-				suite.Equal(1, len(accept.GetOfferIds()))
-				suite.Equal("offer-0", accept.GetOfferIds()[0].GetValue())
-				suite.Equal(1, len(accept.GetOperations()))
-				operation := accept.GetOperations()[0]
+			Do(func(
+				_ context.Context,
+				_ string,
+				offerIDs []*mesos.OfferID,
+				operations []*mesos.Offer_Operation,
+				_ *mesos.Filters) {
+				// Verify the accept call.
+				suite.Equal(1, len(offerIDs))
+				suite.Equal("offer-0", offerIDs[0].GetValue())
+				suite.Equal(1, len(operations))
+				operation := operations[0]
 				suite.Equal(
 					mesos.Offer_Operation_LAUNCH,
 					operation.GetType())
@@ -962,6 +979,10 @@ func (suite *HostMgrHandlerTestSuite) TestAcquireAndLaunchOperation() {
 					fmt.Sprintf(_taskIDFmt, 0),
 					launch.GetTaskInfos()[0].GetTaskId().GetValue())
 			}).
+			Return(_acceptRequest, nil),
+		// Set expectations on the call sender
+		suite.callSender.EXPECT().
+			Send(gomock.Any(), _acceptRequest).
 			Return(nil),
 	)
 
@@ -1737,29 +1758,27 @@ func (suite *HostMgrHandlerTestSuite) TestLaunchOperationWithReservedOffers() {
 		Return(nil)
 
 	gomock.InOrder(
-		// Set expectations on provider
-		suite.provider.EXPECT().GetFrameworkID(context.Background()).Return(
-			suite.frameworkID),
-		// Set expectations on provider
-		suite.provider.EXPECT().GetMesosStreamID(context.Background()).Return(_streamID),
-		// Set expectations on scheduler schedulerClient
-		suite.schedulerClient.EXPECT().
-			Call(
-				gomock.Eq(_streamID),
+		// Set expectations on the driver preparing the accept call
+		suite.mesosDetector.EXPECT().HostPort().Return(_masterHostPort),
+		suite.driver.EXPECT().
+			PrepareAcceptRequest(
+				gomock.Any(),
+				_masterHostPort,
 				gomock.Any(),
+				gomock.Any(),
+				nil,
 			).
-			Do(func(_ string, msg proto.Message) {
-				// Verify clientCall message.
-				call := msg.(*sched.Call)
-				suite.Equal(sched.Call_ACCEPT, call.GetType())
-				suite.Equal(_frameworkID, call.GetFrameworkId().GetValue())
-
-				accept := call.GetAccept()
-				suite.NotNil(accept)
-				suite.Equal(1, len(accept.GetOfferIds()))
-				suite.Equal("offer-0", accept.GetOfferIds()[0].GetValue())
-				suite.Equal(1, len(accept.GetOperations()))
-				launchOp := accept.GetOperations()[0]
+			Do(func(
+				_ context.Context,
+				_ string,
+				offerIDs []*mesos.OfferID,
+				operations []*mesos.Offer_Operation,
+				_ *mesos.Filters) {
+				// Verify the accept call.
+				suite.Equal(1, len(offerIDs))
+				suite.Equal("offer-0", offerIDs[0].GetValue())
+				suite.Equal(1, len(operations))
+				launchOp := operations[0]
 				suite.Equal(
 					mesos.Offer_Operation_LAUNCH,
 					launchOp.GetType())
@@ -1770,6 +1789,10 @@ func (suite *HostMgrHandlerTestSuite) TestLaunchOperationWithReservedOffers() {
 					fmt.Sprintf(_taskIDFmt, 0),
 					launch.GetTaskInfos()[0].GetTaskId().GetValue())
 			}).
+			Return(_acceptRequest, nil),
+		// Set expectations on the call sender
+		suite.callSender.EXPECT().
+			Send(gomock.Any(), _acceptRequest).
 			Return(nil),
 	)
 
@@ -1887,39 +1910,29 @@ func (suite *HostMgrHandlerTestSuite) TestReserveCreateLaunchOperation() {
 		suite.testScope.Snapshot().Counters()["offer_operations_invalid+"].Value())
 
 	gomock.InOrder(
-		suite.volumeStore.EXPECT().
-			GetPersistentVolume(gomock.Any(), gomock.Any()).
-			Return(nil, nil),
-
-		suite.volumeStore.EXPECT().
-			CreatePersistentVolume(gomock.Any(), gomock.Any()).
-			Return(nil),
-
-		// Set expectations on provider
-		suite.provider.EXPECT().GetFrameworkID(context.Background()).Return(
-			suite.frameworkID),
-		// Set expectations on provider
-		suite.provider.EXPECT().GetMesosStreamID(context.Background()).Return(_streamID),
-		// Set expectations on scheduler schedulerClient
-		suite.schedulerClient.EXPECT().
-			Call(
-				gomock.Eq(_streamID),
+		// Set expectations on the driver preparing the accept call
+		suite.mesosDetector.EXPECT().HostPort().Return(_masterHostPort),
+		suite.driver.EXPECT().
+			PrepareAcceptRequest(
 				gomock.Any(),
+				_masterHostPort,
+				gomock.Any(),
+				gomock.Any(),
+				nil,
 			).
-			Do(func(_ string, msg proto.Message) {
-				// Verify clientCall message.
-				call := msg.(*sched.Call)
-				suite.Equal(sched.Call_ACCEPT, call.GetType())
-				suite.Equal(_frameworkID, call.GetFrameworkId().GetValue())
-
-				accept := call.GetAccept()
-				suite.NotNil(accept)
-				suite.Equal(1, len(accept.GetOfferIds()))
-				suite.Equal("offer-0", accept.GetOfferIds()[0].GetValue())
-				suite.Equal(3, len(accept.GetOperations()))
-				reserveOp := accept.GetOperations()[0]
-				createOp := accept.GetOperations()[1]
-				launchOp := accept.GetOperations()[2]
+			Do(func(
+				_ context.Context,
+				_ string,
+				offerIDs []*mesos.OfferID,
+				operations []*mesos.Offer_Operation,
+				_ *mesos.Filters) {
+				// Verify the accept call.
+				suite.Equal(1, len(offerIDs))
+				suite.Equal("offer-0", offerIDs[0].GetValue())
+				suite.Equal(3, len(operations))
+				reserveOp := operations[0]
+				createOp := operations[1]
+				launchOp := operations[2]
 				suite.Equal(
 					mesos.Offer_Operation_RESERVE,
 					reserveOp.GetType())
@@ -1936,6 +1949,19 @@ func (suite *HostMgrHandlerTestSuite) TestReserveCreateLaunchOperation() {
 					fmt.Sprintf(_taskIDFmt, 0),
 					launch.GetTaskInfos()[0].GetTaskId().GetValue())
 			}).
+			Return(_acceptRequest, nil),
+
+		suite.volumeStore.EXPECT().
+			GetPersistentVolume(gomock.Any(), gomock.Any()).
+			Return(nil, nil),
+
+		suite.volumeStore.EXPECT().
+			CreatePersistentVolume(gomock.Any(), gomock.Any()).
+			Return(nil),
+
+		// Set expectations on the call sender
+		suite.callSender.EXPECT().
+			Send(gomock.Any(), _acceptRequest).
 			Return(nil),
 	)
 
@@ -1990,35 +2016,29 @@ func (suite *HostMgrHandlerTestSuite) TestReserveCreateLaunchOperationWithCreate
 	volumeInfo := &volume.PersistentVolumeInfo{}
 
 	gomock.InOrder(
-		suite.volumeStore.EXPECT().
-			GetPersistentVolume(gomock.Any(), gomock.Any()).
-			Return(volumeInfo, nil),
-
-		// Set expectations on provider
-		suite.provider.EXPECT().GetFrameworkID(context.Background()).Return(
-			suite.frameworkID),
-		// Set expectations on provider
-		suite.provider.EXPECT().GetMesosStreamID(context.Background()).Return(_streamID),
-		// Set expectations on scheduler schedulerClient
-		suite.schedulerClient.EXPECT().
-			Call(
-				gomock.Eq(_streamID),
+		// Set expectations on the driver preparing the accept call
+		suite.mesosDetector.EXPECT().HostPort().Return(_masterHostPort),
+		suite.driver.EXPECT().
+			PrepareAcceptRequest(
+				gomock.Any(),
+				_masterHostPort,
 				gomock.Any(),
+				gomock.Any(),
+				nil,
 			).
-			Do(func(_ string, msg proto.Message) {
-				// Verify clientCall message.
-				call := msg.(*sched.Call)
-				suite.Equal(sched.Call_ACCEPT, call.GetType())
-				suite.Equal(_frameworkID, call.GetFrameworkId().GetValue())
-
-				accept := call.GetAccept()
-				suite.NotNil(accept)
-				suite.Equal(1, len(accept.GetOfferIds()))
-				suite.Equal("offer-0", accept.GetOfferIds()[0].GetValue())
-				suite.Equal(3, len(accept.GetOperations()))
-				reserveOp := accept.GetOperations()[0]
-				createOp := accept.GetOperations()[1]
-				launchOp := accept.GetOperations()[2]
+			Do(func(
+				_ context.Context,
+				_ string,
+				offerIDs []*mesos.OfferID,
+				operations []*mesos.Offer_Operation,
+				_ *mesos.Filters) {
+				// Verify the accept call.
+				suite.Equal(1, len(offerIDs))
+				suite.Equal("offer-0", offerIDs[0].GetValue())
+				suite.Equal(3, len(operations))
+				reserveOp := operations[0]
+				createOp := operations[1]
+				launchOp := operations[2]
 				suite.Equal(
 					mesos.Offer_Operation_RESERVE,
 					reserveOp.GetType())
@@ -2035,6 +2055,15 @@ func (suite *HostMgrHandlerTestSuite) TestReserveCreateLaunchOperationWithCreate
 					fmt.Sprintf(_taskIDFmt, 0),
 					launch.GetTaskInfos()[0].GetTaskId().GetValue())
 			}).
+			Return(_acceptRequest, nil),
+
+		suite.volumeStore.EXPECT().
+			GetPersistentVolume(gomock.Any(), gomock.Any()).
+			Return(volumeInfo, nil),
+
+		// Set expectations on the call sender
+		suite.callSender.EXPECT().
+			Send(gomock.Any(), _acceptRequest).
 			Return(nil),
 	)
 
@@ -2592,15 +2621,19 @@ func (suite *HostMgrHandlerTestSuite) TestOfferOperationsError() {
 	operationReq.Hostname = acquiredHostOffers[0].GetHostname()
 	errString := "Fake scheduler call error"
 	gomock.InOrder(
-		suite.provider.EXPECT().GetFrameworkID(context.Background()).Return(
-			suite.frameworkID),
-		suite.provider.EXPECT().GetMesosStreamID(context.Background()).
-			Return(_streamID),
-		suite.schedulerClient.EXPECT().
-			Call(
-				gomock.Eq(_streamID),
+		suite.mesosDetector.EXPECT().HostPort().Return(_masterHostPort),
+		suite.driver.EXPECT().
+			PrepareAcceptRequest(
 				gomock.Any(),
-			).Return(fmt.Errorf(errString)),
+				_masterHostPort,
+				gomock.Any(),
+				gomock.Any(),
+				nil,
+			).
+			Return(_acceptRequest, nil),
+		suite.callSender.EXPECT().
+			Send(gomock.Any(), _acceptRequest).
+			Return(fmt.Errorf(errString)),
 	)
 
 	operationReq = &hostsvc.OfferOperationsRequest{
@@ -2752,23 +2785,55 @@ func (suite *HostMgrHandlerTestSuite) TestLaunchTasksInvalidArgError() {
 	}
 }
 
+// TestLaunchTasksUserNotAllowed tests that tasks running as a user not
+// allowed are rejected before their offers are claimed
+func (suite *HostMgrHandlerTestSuite) TestLaunchTasksUserNotAllowed() {
+	acquiredHostOffers := suite.withHostOffers(1)
+	suite.handler.taskUserValidator = NewTaskUserValidator(
+		"root", []string{"peloton"}, nil, tally.NoopScope)
+
+	tasks := generateLaunchableTasks(2)
+	tasks[1].Config.Command.User = util.PtrStr("nobody")
+	launchResp, err := suite.handler.LaunchTasks(
+		rootCtx,
+		&hostsvc.LaunchTasksRequest{
+			Hostname: acquiredHostOffers[0].GetHostname(),
+			AgentId:  acquiredHostOffers[0].GetAgentId(),
+			Tasks:    tasks,
+			Id:       acquiredHostOffers[0].GetId(),
+		},
+	)
+	suite.NoError(err)
+	suite.Equal(
+		"cannot launch task "+tasks[1].GetTaskId().GetValue()+
+			": user nobody is not allowed, the allowed users are root and [peloton]",
+		launchResp.GetError().GetInvalidArgument().GetMessage())
+	suite.Equal(
+		int64(1),
+		suite.testScope.Snapshot().Counters()["launch_tasks_invalid+"].Value())
+}
+
 func (suite *HostMgrHandlerTestSuite) TestLaunchTasksSchedulerError() {
 	acquiredHostOffers := suite.withHostOffers(1)
 
 	// Test framework client error
 	errString := "fake scheduler call error"
 	gomock.InOrder(
-		// Set expectations on provider
-		suite.provider.EXPECT().GetFrameworkID(context.Background()).Return(
-			suite.frameworkID),
-		// Set expectations on provider
-		suite.provider.EXPECT().GetMesosStreamID(context.Background()).Return(_streamID),
-		// Set expectations on scheduler schedulerClient
-		suite.schedulerClient.EXPECT().
-			Call(
-				gomock.Eq(_streamID),
+		// Set expectations on the driver preparing the accept call
+		suite.mesosDetector.EXPECT().HostPort().Return(_masterHostPort),
+		suite.driver.EXPECT().
+			PrepareAcceptRequest(
+				gomock.Any(),
+				_masterHostPort,
+				gomock.Any(),
 				gomock.Any(),
-			).Return(fmt.Errorf(errString)),
+				nil,
+			).
+			Return(_acceptRequest, nil),
+		// Set expectations on the call sender
+		suite.callSender.EXPECT().
+			Send(gomock.Any(), _acceptRequest).
+			Return(fmt.Errorf(errString)),
 	)
 
 	launchResp, err := suite.handler.LaunchTasks(
@@ -2788,6 +2853,48 @@ func (suite *HostMgrHandlerTestSuite) TestLaunchTasksSchedulerError() {
 		errString)
 }
 
+// TestLaunchTasksInvalidAcceptCall tests that the launch of tasks whose
+// accept call is rejected by the driver fails with invalid offers, without
+// calling Mesos
+func (suite *HostMgrHandlerTestSuite) TestLaunchTasksInvalidAcceptCall() {
+	acquiredHostOffers := suite.withHostOffers(1)
+
+	verr := &hostmgr_mesos.AcceptValidationError{
+		Violation: hostmgr_mesos.AcceptNoOffers,
+		Index:     -1,
+	}
+	gomock.InOrder(
+		suite.mesosDetector.EXPECT().HostPort().Return(_masterHostPort),
+		suite.driver.EXPECT().
+			PrepareAcceptRequest(
+				gomock.Any(),
+				_masterHostPort,
+				gomock.Any(),
+				gomock.Any(),
+				nil,
+			).
+			Return(nil, verr),
+	)
+
+	launchResp, err := suite.handler.LaunchTasks(
+		rootCtx,
+		&hostsvc.LaunchTasksRequest{
+			Hostname: acquiredHostOffers[0].GetHostname(),
+			AgentId:  acquiredHostOffers[0].GetAgentId(),
+			Tasks:    generateLaunchableTasks(1),
+			Id:       acquiredHostOffers[0].GetId(),
+		},
+	)
+
+	suite.NoError(err)
+	suite.Equal(
+		verr.Error(),
+		launchResp.GetError().GetInvalidOffers().GetMessage())
+	suite.Equal(
+		int64(1),
+		suite.testScope.Snapshot().Counters()["launch_tasks_invalid_offers+"].Value())
+}
+
 func (suite *HostMgrHandlerTestSuite) TestReleaseHostsHeldForTasks() {
 	defer suite.ctrl.Finish()
 
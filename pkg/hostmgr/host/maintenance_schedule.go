@@ -0,0 +1,253 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"sync"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_maintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
+
+	log "github.com/sirupsen/logrus"
+	uatomic "github.com/uber-go/atomic"
+	"github.com/uber-go/tally"
+)
+
+// maintenanceScheduleMetrics are the metrics of a MaintenanceScheduleWriter
+type maintenanceScheduleMetrics struct {
+	update            tally.Counter
+	updateFail        tally.Counter
+	scheduledMachines tally.Gauge
+}
+
+// MaintenanceScheduleWriter keeps the maintenance schedule of the Mesos
+// master in sync with the hosts in maintenance in Peloton, so that Mesos
+// tags the offers of these hosts with their unavailability. It is run as a
+// background work, i.e. only on the leader. The schedule can only be
+// replaced as a whole, so every update of the schedule by the host manager
+// goes through the writer, under its lock.
+type MaintenanceScheduleWriter struct {
+	sync.Mutex
+
+	operatorClient mpb.MasterOperatorClient
+	hostInfoMap    MaintenanceHostInfoMap
+	metrics        *maintenanceScheduleMetrics
+	now            func() time.Time
+
+	// previous are the hosts in maintenance at the previous write. Only
+	// the machines which left the maintenance of Peloton are removed from
+	// the schedule, not the ones scheduled by others.
+	previous map[string]bool
+}
+
+// NewMaintenanceScheduleWriter creates a MaintenanceScheduleWriter for the
+// hosts in maintenance of hostInfoMap
+func NewMaintenanceScheduleWriter(
+	operatorClient mpb.MasterOperatorClient,
+	hostInfoMap MaintenanceHostInfoMap,
+	scope tally.Scope) *MaintenanceScheduleWriter {
+	scheduleScope := scope.SubScope("maintenance_schedule")
+	return &MaintenanceScheduleWriter{
+		operatorClient: operatorClient,
+		hostInfoMap:    hostInfoMap,
+		metrics: &maintenanceScheduleMetrics{
+			update:            scheduleScope.Counter("update"),
+			updateFail:        scheduleScope.Counter("update_fail"),
+			scheduledMachines: scheduleScope.Gauge("scheduled_machines"),
+		},
+		now:      time.Now,
+		previous: make(map[string]bool),
+	}
+}
+
+// Write updates the maintenance schedule of the Mesos master if it differs
+// from the hosts in maintenance. The hosts missing from the schedule are
+// added as a window starting now, and the hosts which left maintenance
+// since the previous write are removed from their windows.
+func (w *MaintenanceScheduleWriter) Write(_ *uatomic.Bool) {
+	if err := w.write(); err != nil {
+		w.metrics.updateFail.Inc(1)
+		log.WithError(err).Warn("Cannot write maintenance schedule")
+	}
+}
+
+// AddWindow adds a maintenance window of machineIDs starting now to the
+// maintenance schedule of the Mesos master. The window has no duration, as
+// the hosts are in maintenance until it is completed.
+func (w *MaintenanceScheduleWriter) AddWindow(machineIDs []*mesos.MachineID) error {
+	w.Lock()
+	defer w.Unlock()
+
+	response, err := w.operatorClient.GetMaintenanceSchedule()
+	if err != nil {
+		return err
+	}
+	schedule := response.GetSchedule()
+	if schedule == nil {
+		schedule = &mesos_maintenance.Schedule{}
+	}
+	nanos := w.now().UnixNano()
+	schedule.Windows = append(schedule.Windows, &mesos_maintenance.Window{
+		MachineIds: machineIDs,
+		Unavailability: &mesos.Unavailability{
+			Start: &mesos.TimeInfo{Nanoseconds: &nanos},
+		},
+	})
+	if err := w.operatorClient.UpdateMaintenanceSchedule(schedule); err != nil {
+		w.metrics.updateFail.Inc(1)
+		return err
+	}
+	w.metrics.update.Inc(1)
+	log.WithField("maintenance_schedule", schedule).
+		Info("Maintenance Schedule posted to Mesos Master")
+	return nil
+}
+
+// RemoveMachines removes the hosts from the maintenance windows of the
+// Mesos master, dropping the windows left without machines, and then from
+// the hosts in maintenance, so that a concurrent write does not schedule
+// them again.
+func (w *MaintenanceScheduleWriter) RemoveMachines(hostnames []string) error {
+	w.Lock()
+	defer w.Unlock()
+
+	removed := make(map[string]bool)
+	for _, hostname := range hostnames {
+		removed[hostname] = true
+	}
+
+	response, err := w.operatorClient.GetMaintenanceSchedule()
+	if err != nil {
+		return err
+	}
+	schedule := response.GetSchedule()
+	if schedule == nil {
+		schedule = &mesos_maintenance.Schedule{}
+	}
+	var windows []*mesos_maintenance.Window
+	for _, window := range schedule.GetWindows() {
+		var machineIDs []*mesos.MachineID
+		for _, machineID := range window.GetMachineIds() {
+			if !removed[machineID.GetHostname()] {
+				machineIDs = append(machineIDs, machineID)
+			}
+		}
+		if len(machineIDs) == 0 {
+			continue
+		}
+		window.MachineIds = machineIDs
+		windows = append(windows, window)
+	}
+	schedule.Windows = windows
+	if err := w.operatorClient.UpdateMaintenanceSchedule(schedule); err != nil {
+		w.metrics.updateFail.Inc(1)
+		return err
+	}
+	w.metrics.update.Inc(1)
+	log.WithField("maintenance_schedule", schedule).
+		Info("Maintenance Schedule posted to Mesos Master")
+
+	w.hostInfoMap.RemoveHostInfos(hostnames)
+	for _, hostname := range hostnames {
+		delete(w.previous, hostname)
+	}
+	return nil
+}
+
+func (w *MaintenanceScheduleWriter) write() error {
+	w.Lock()
+	defer w.Unlock()
+
+	var hostInfos []*host.HostInfo
+	hostInfos = append(hostInfos, w.hostInfoMap.GetDrainingHostInfos(nil)...)
+	hostInfos = append(hostInfos, w.hostInfoMap.GetDownHostInfos(nil)...)
+	current := make(map[string]bool)
+	for _, hostInfo := range hostInfos {
+		current[hostInfo.GetHostname()] = true
+	}
+
+	response, err := w.operatorClient.GetMaintenanceSchedule()
+	if err != nil {
+		return err
+	}
+	schedule := response.GetSchedule()
+	if schedule == nil {
+		schedule = &mesos_maintenance.Schedule{}
+	}
+
+	changed := false
+	scheduled := make(map[string]bool)
+	var windows []*mesos_maintenance.Window
+	for _, window := range schedule.GetWindows() {
+		var machineIDs []*mesos.MachineID
+		for _, machineID := range window.GetMachineIds() {
+			hostname := machineID.GetHostname()
+			if w.previous[hostname] && !current[hostname] {
+				changed = true
+				continue
+			}
+			scheduled[hostname] = true
+			machineIDs = append(machineIDs, machineID)
+		}
+		if len(machineIDs) == 0 {
+			continue
+		}
+		window.MachineIds = machineIDs
+		windows = append(windows, window)
+	}
+
+	// The hosts are in maintenance until it is completed, so the windows
+	// have no duration.
+	var missing []*mesos.MachineID
+	for _, hostInfo := range hostInfos {
+		if scheduled[hostInfo.GetHostname()] {
+			continue
+		}
+		hostname := hostInfo.GetHostname()
+		ip := hostInfo.GetIp()
+		missing = append(missing, &mesos.MachineID{
+			Hostname: &hostname,
+			Ip:       &ip,
+		})
+		scheduled[hostname] = true
+	}
+	if len(missing) > 0 {
+		nanos := w.now().UnixNano()
+		windows = append(windows, &mesos_maintenance.Window{
+			MachineIds: missing,
+			Unavailability: &mesos.Unavailability{
+				Start: &mesos.TimeInfo{Nanoseconds: &nanos},
+			},
+		})
+		changed = true
+	}
+
+	w.metrics.scheduledMachines.Update(float64(len(scheduled)))
+	if changed {
+		schedule.Windows = windows
+		if err := w.operatorClient.UpdateMaintenanceSchedule(schedule); err != nil {
+			return err
+		}
+		w.metrics.update.Inc(1)
+		log.WithField("maintenance_schedule", schedule).
+			Info("Maintenance Schedule posted to Mesos Master")
+	}
+	w.previous = current
+	return nil
+}
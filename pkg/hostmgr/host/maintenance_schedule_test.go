@@ -0,0 +1,242 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package host
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	mesos_maintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
+	mesos_master "github.com/uber/peloton/.gen/mesos/v1/master"
+	host "github.com/uber/peloton/.gen/peloton/api/v0/host"
+
+	mpb_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type maintenanceScheduleWriterTestSuite struct {
+	suite.Suite
+	mockCtrl                 *gomock.Controller
+	mockMasterOperatorClient *mpb_mocks.MockMasterOperatorClient
+	hostInfoMap              MaintenanceHostInfoMap
+	writer                   *MaintenanceScheduleWriter
+	now                      time.Time
+}
+
+func (suite *maintenanceScheduleWriterTestSuite) SetupTest() {
+	suite.mockCtrl = gomock.NewController(suite.T())
+	suite.mockMasterOperatorClient = mpb_mocks.NewMockMasterOperatorClient(suite.mockCtrl)
+	suite.hostInfoMap = NewMaintenanceHostInfoMap(tally.NoopScope)
+	suite.writer = NewMaintenanceScheduleWriter(
+		suite.mockMasterOperatorClient,
+		suite.hostInfoMap,
+		tally.NoopScope)
+	suite.now = time.Unix(1500000000, 0)
+	suite.writer.now = func() time.Time { return suite.now }
+}
+
+func (suite *maintenanceScheduleWriterTestSuite) TearDownTest() {
+	suite.mockCtrl.Finish()
+}
+
+func TestMaintenanceScheduleWriter(t *testing.T) {
+	suite.Run(t, new(maintenanceScheduleWriterTestSuite))
+}
+
+func newTestHostInfo(i int, state host.HostState) *host.HostInfo {
+	return &host.HostInfo{
+		Hostname: fmt.Sprintf("host%d", i),
+		Ip:       fmt.Sprintf("172.17.0.%d", i),
+		State:    state,
+	}
+}
+
+func newTestWindow(start time.Time, hosts ...int) *mesos_maintenance.Window {
+	var machineIDs []*mesos.MachineID
+	for _, i := range hosts {
+		hostname := fmt.Sprintf("host%d", i)
+		ip := fmt.Sprintf("172.17.0.%d", i)
+		machineIDs = append(machineIDs, &mesos.MachineID{
+			Hostname: &hostname,
+			Ip:       &ip,
+		})
+	}
+	nanos := start.UnixNano()
+	return &mesos_maintenance.Window{
+		MachineIds: machineIDs,
+		Unavailability: &mesos.Unavailability{
+			Start: &mesos.TimeInfo{Nanoseconds: &nanos},
+		},
+	}
+}
+
+// expectSchedule expects the current maintenance schedule to be read
+func (suite *maintenanceScheduleWriterTestSuite) expectSchedule(
+	windows ...*mesos_maintenance.Window) {
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(&mesos_master.Response_GetMaintenanceSchedule{
+			Schedule: &mesos_maintenance.Schedule{Windows: windows},
+		}, nil)
+}
+
+// expectUpdate expects the maintenance schedule to be updated to windows
+func (suite *maintenanceScheduleWriterTestSuite) expectUpdate(
+	windows ...*mesos_maintenance.Window) {
+	suite.mockMasterOperatorClient.EXPECT().
+		UpdateMaintenanceSchedule(&mesos_maintenance.Schedule{Windows: windows}).
+		Return(nil)
+}
+
+// TestWriteAddsMissingHosts tests that the hosts in maintenance missing
+// from the schedule are added as a new window
+func (suite *maintenanceScheduleWriterTestSuite) TestWriteAddsMissingHosts() {
+	earlier := suite.now.Add(-time.Hour)
+	suite.hostInfoMap.AddHostInfos([]*host.HostInfo{
+		newTestHostInfo(1, host.HostState_HOST_STATE_DRAINING),
+		newTestHostInfo(2, host.HostState_HOST_STATE_DOWN),
+	})
+
+	suite.expectSchedule(newTestWindow(earlier, 2))
+	suite.expectUpdate(newTestWindow(earlier, 2), newTestWindow(suite.now, 1))
+	suite.NoError(suite.writer.write())
+}
+
+// TestWriteIdempotent tests that the schedule is not updated when it
+// already holds the hosts in maintenance
+func (suite *maintenanceScheduleWriterTestSuite) TestWriteIdempotent() {
+	suite.hostInfoMap.AddHostInfos([]*host.HostInfo{
+		newTestHostInfo(1, host.HostState_HOST_STATE_DRAINING),
+	})
+
+	suite.expectSchedule()
+	suite.expectUpdate(newTestWindow(suite.now, 1))
+	suite.NoError(suite.writer.write())
+
+	suite.expectSchedule(newTestWindow(suite.now, 1))
+	suite.NoError(suite.writer.write())
+}
+
+// TestWriteRemovesHosts tests that the hosts which left maintenance are
+// removed from the schedule, but not the hosts scheduled by others
+func (suite *maintenanceScheduleWriterTestSuite) TestWriteRemovesHosts() {
+	suite.hostInfoMap.AddHostInfos([]*host.HostInfo{
+		newTestHostInfo(1, host.HostState_HOST_STATE_DRAINING),
+		newTestHostInfo(2, host.HostState_HOST_STATE_DRAINING),
+	})
+	suite.expectSchedule(newTestWindow(suite.now, 1, 2))
+	suite.NoError(suite.writer.write())
+
+	suite.hostInfoMap.RemoveHostInfos([]string{"host1"})
+	suite.expectSchedule(
+		newTestWindow(suite.now, 1, 2),
+		newTestWindow(suite.now, 3))
+	suite.expectUpdate(
+		newTestWindow(suite.now, 2),
+		newTestWindow(suite.now, 3))
+	suite.NoError(suite.writer.write())
+
+	// empty windows are dropped
+	suite.hostInfoMap.RemoveHostInfos([]string{"host2"})
+	suite.expectSchedule(
+		newTestWindow(suite.now, 2),
+		newTestWindow(suite.now, 3))
+	suite.expectUpdate(newTestWindow(suite.now, 3))
+	suite.NoError(suite.writer.write())
+}
+
+// TestWriteRetriesRemoval tests that a failed update is retried
+func (suite *maintenanceScheduleWriterTestSuite) TestWriteRetriesRemoval() {
+	suite.hostInfoMap.AddHostInfos([]*host.HostInfo{
+		newTestHostInfo(1, host.HostState_HOST_STATE_DRAINING),
+	})
+	suite.expectSchedule(newTestWindow(suite.now, 1))
+	suite.NoError(suite.writer.write())
+
+	suite.hostInfoMap.RemoveHostInfos([]string{"host1"})
+	suite.expectSchedule(newTestWindow(suite.now, 1))
+	suite.mockMasterOperatorClient.EXPECT().
+		UpdateMaintenanceSchedule(gomock.Any()).
+		Return(fmt.Errorf("fake UpdateMaintenanceSchedule error"))
+	suite.Error(suite.writer.write())
+
+	suite.expectSchedule(newTestWindow(suite.now, 1))
+	suite.expectUpdate()
+	suite.NoError(suite.writer.write())
+}
+
+// TestWriteGetScheduleError tests that the schedule is not updated if it
+// cannot be read
+func (suite *maintenanceScheduleWriterTestSuite) TestWriteGetScheduleError() {
+	suite.hostInfoMap.AddHostInfos([]*host.HostInfo{
+		newTestHostInfo(1, host.HostState_HOST_STATE_DRAINING),
+	})
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(nil, fmt.Errorf("fake GetMaintenanceSchedule error"))
+	suite.Error(suite.writer.write())
+}
+
+// TestAddWindow tests that a window of the hosts starting now is appended
+// to the schedule
+func (suite *maintenanceScheduleWriterTestSuite) TestAddWindow() {
+	earlier := suite.now.Add(-time.Hour)
+	suite.expectSchedule(newTestWindow(earlier, 1))
+	suite.expectUpdate(newTestWindow(earlier, 1), newTestWindow(suite.now, 2, 3))
+	suite.NoError(suite.writer.AddWindow(
+		newTestWindow(suite.now, 2, 3).GetMachineIds()))
+
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(nil, fmt.Errorf("fake GetMaintenanceSchedule error"))
+	suite.Error(suite.writer.AddWindow(
+		newTestWindow(suite.now, 2).GetMachineIds()))
+}
+
+// TestRemoveMachines tests that the hosts are removed from the schedule
+// and from the hosts in maintenance, so that the next write does not
+// schedule them again
+func (suite *maintenanceScheduleWriterTestSuite) TestRemoveMachines() {
+	suite.hostInfoMap.AddHostInfos([]*host.HostInfo{
+		newTestHostInfo(1, host.HostState_HOST_STATE_DRAINING),
+		newTestHostInfo(2, host.HostState_HOST_STATE_DRAINING),
+	})
+	suite.expectSchedule(newTestWindow(suite.now, 1), newTestWindow(suite.now, 2, 3))
+	suite.expectUpdate(newTestWindow(suite.now, 3))
+	suite.NoError(suite.writer.RemoveMachines([]string{"host1", "host2"}))
+	suite.Empty(suite.hostInfoMap.GetDrainingHostInfos(nil))
+
+	suite.expectSchedule(newTestWindow(suite.now, 3))
+	suite.NoError(suite.writer.write())
+}
+
+// TestRemoveMachinesUpdateError tests that the hosts stay in maintenance
+// when the schedule cannot be updated
+func (suite *maintenanceScheduleWriterTestSuite) TestRemoveMachinesUpdateError() {
+	suite.hostInfoMap.AddHostInfos([]*host.HostInfo{
+		newTestHostInfo(1, host.HostState_HOST_STATE_DRAINING),
+	})
+	suite.expectSchedule(newTestWindow(suite.now, 1))
+	suite.mockMasterOperatorClient.EXPECT().
+		UpdateMaintenanceSchedule(gomock.Any()).
+		Return(fmt.Errorf("fake UpdateMaintenanceSchedule error"))
+	suite.Error(suite.writer.RemoveMachines([]string{"host1"}))
+	suite.Len(suite.hostInfoMap.GetDrainingHostInfos(nil), 1)
+}
@@ -20,7 +20,6 @@ import (
 	"time"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
-	mesos_maintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
 	hpb "github.com/uber/peloton/.gen/peloton/api/v0/host"
 	host_svc "github.com/uber/peloton/.gen/peloton/api/v0/host/svc"
 
@@ -41,20 +40,24 @@ type serviceHandler struct {
 	metrics                *Metrics
 	operatorMasterClient   mpb.MasterOperatorClient
 	maintenanceHostInfoMap host.MaintenanceHostInfoMap
+	scheduleWriter         *host.MaintenanceScheduleWriter
 }
 
-// InitServiceHandler initializes the HostService
+// InitServiceHandler initializes the HostService. The maintenance schedule
+// of the Mesos master is only updated through scheduleWriter.
 func InitServiceHandler(
 	d *yarpc.Dispatcher,
 	parent tally.Scope,
 	operatorMasterClient mpb.MasterOperatorClient,
 	maintenanceQueue queue.MaintenanceQueue,
-	hostInfoMap host.MaintenanceHostInfoMap) {
+	hostInfoMap host.MaintenanceHostInfoMap,
+	scheduleWriter *host.MaintenanceScheduleWriter) {
 	handler := &serviceHandler{
 		maintenanceQueue:       maintenanceQueue,
 		metrics:                NewMetrics(parent.SubScope("hostsvc")),
 		operatorMasterClient:   operatorMasterClient,
 		maintenanceHostInfoMap: hostInfoMap,
+		scheduleWriter:         scheduleWriter,
 	}
 	d.Register(host_svc.BuildHostServiceYARPCProcedures(handler))
 	log.Info("Hostsvc handler initialized")
@@ -143,39 +146,14 @@ func (m *serviceHandler) StartMaintenance(
 		return nil, err
 	}
 
-	// Get current maintenance schedule
-	response, err := m.operatorMasterClient.GetMaintenanceSchedule()
-	if err != nil {
-		m.metrics.StartMaintenanceFail.Inc(1)
-		return nil, err
-	}
-	schedule := response.GetSchedule()
-	// Set current time as the `start` of maintenance window
-	nanos := time.Now().UnixNano()
-
 	// The maintenance duration has no real significance. A machine can be put into
 	// maintenance even after its maintenance window has passed. According to Mesos,
 	// omitting the duration means that the unavailability will last forever. Since
 	// we do not know the duration, we are omitting it.
-
-	// Construct maintenance window
-	maintenanceWindow := &mesos_maintenance.Window{
-		MachineIds: machineIds,
-		Unavailability: &mesos.Unavailability{
-			Start: &mesos.TimeInfo{
-				Nanoseconds: &nanos,
-			},
-		},
-	}
-	schedule.Windows = append(schedule.Windows, maintenanceWindow)
-
-	err = m.operatorMasterClient.UpdateMaintenanceSchedule(schedule)
-	if err != nil {
+	if err := m.scheduleWriter.AddWindow(machineIds); err != nil {
 		m.metrics.StartMaintenanceFail.Inc(1)
 		return nil, err
 	}
-	log.WithField("maintenance_schedule", schedule).
-		Info("Maintenance Schedule posted to Mesos Master")
 
 	var hostInfos []*hpb.HostInfo
 	for _, machine := range machineIds {
@@ -240,6 +218,78 @@ func (m *serviceHandler) CompleteMaintenance(
 	return &host_svc.CompleteMaintenanceResponse{}, nil
 }
 
+// CancelMaintenance cancels the maintenance of the specified hosts which are
+// still DRAINING, e.g. when they did not drain in time. The hosts are removed
+// from the maintenance schedule of the Mesos Master, which brings them back
+// UP, as they were never transitioned to DOWN.
+func (m *serviceHandler) CancelMaintenance(
+	ctx context.Context,
+	request *host_svc.CancelMaintenanceRequest,
+) (*host_svc.CancelMaintenanceResponse, error) {
+	m.metrics.CancelMaintenanceAPI.Inc(1)
+
+	drainingHosts := stringset.New()
+	for _, hostInfo := range m.maintenanceHostInfoMap.GetDrainingHostInfos([]string{}) {
+		drainingHosts.Add(hostInfo.GetHostname())
+	}
+
+	hostnames := request.GetHostnames()
+	for _, hostname := range hostnames {
+		if !drainingHosts.Contains(hostname) {
+			m.metrics.CancelMaintenanceFail.Inc(1)
+			return nil, fmt.Errorf("invalid request. Host %s is not DRAINING", hostname)
+		}
+	}
+
+	if err := m.scheduleWriter.RemoveMachines(hostnames); err != nil {
+		m.metrics.CancelMaintenanceFail.Inc(1)
+		return nil, err
+	}
+
+	m.metrics.CancelMaintenanceSuccess.Inc(1)
+	return &host_svc.CancelMaintenanceResponse{}, nil
+}
+
+// GetMaintenanceSchedule returns the maintenance windows currently posted
+// to the Mesos Master
+func (m *serviceHandler) GetMaintenanceSchedule(
+	ctx context.Context,
+	request *host_svc.GetMaintenanceScheduleRequest,
+) (*host_svc.GetMaintenanceScheduleResponse, error) {
+	m.metrics.GetMaintenanceScheduleAPI.Inc(1)
+
+	response, err := m.operatorMasterClient.GetMaintenanceSchedule()
+	if err != nil {
+		m.metrics.GetMaintenanceScheduleFail.Inc(1)
+		return nil, err
+	}
+
+	var windows []*hpb.MaintenanceWindow
+	for _, window := range response.GetSchedule().GetWindows() {
+		var hostnames []string
+		for _, machineID := range window.GetMachineIds() {
+			hostnames = append(hostnames, machineID.GetHostname())
+		}
+		unavailability := window.GetUnavailability()
+		start := time.Unix(0, unavailability.GetStart().GetNanoseconds())
+		maintenanceWindow := &hpb.MaintenanceWindow{
+			Hostnames: hostnames,
+			StartTime: start.UTC().Format(time.RFC3339),
+		}
+		if unavailability.GetDuration() != nil {
+			end := start.Add(
+				time.Duration(unavailability.GetDuration().GetNanoseconds()))
+			maintenanceWindow.EndTime = end.UTC().Format(time.RFC3339)
+		}
+		windows = append(windows, maintenanceWindow)
+	}
+
+	m.metrics.GetMaintenanceScheduleSuccess.Inc(1)
+	return &host_svc.GetMaintenanceScheduleResponse{
+		Windows: windows,
+	}, nil
+}
+
 // Build host info for registered agents
 func buildHostInfoForRegisteredAgents() (map[string]*hpb.HostInfo, error) {
 	agentMap := host.GetAgentMap()
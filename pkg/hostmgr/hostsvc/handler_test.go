@@ -18,6 +18,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
 	mesosmaintenance "github.com/uber/peloton/.gen/mesos/v1/maintenance"
@@ -119,6 +120,10 @@ func (suite *HostSvcHandlerTestSuite) SetupTest() {
 	suite.handler.operatorMasterClient = suite.mockMasterOperatorClient
 	suite.handler.maintenanceQueue = suite.mockMaintenanceQueue
 	suite.handler.maintenanceHostInfoMap = suite.mockMaintenanceMap
+	suite.handler.scheduleWriter = host.NewMaintenanceScheduleWriter(
+		suite.mockMasterOperatorClient,
+		suite.mockMaintenanceMap,
+		tally.NoopScope)
 
 	response := suite.makeAgentsResponse()
 	loader := &host.Loader{
@@ -377,6 +382,110 @@ func (suite *HostSvcHandlerTestSuite) TestCompleteMaintenanceError() {
 	suite.Nil(resp)
 }
 
+// TestCancelMaintenance tests that the draining hosts are removed from
+// the schedule and from the hosts in maintenance
+func (suite *HostSvcHandlerTestSuite) TestCancelMaintenance() {
+	var (
+		drainingHostInfos []*hpb.HostInfo
+		hosts             []string
+	)
+	for _, machine := range suite.drainingMachines {
+		hosts = append(hosts, machine.GetHostname())
+		drainingHostInfos = append(drainingHostInfos, &hpb.HostInfo{
+			Hostname: machine.GetHostname(),
+			Ip:       machine.GetIp(),
+			State:    hpb.HostState_HOST_STATE_DRAINING,
+		})
+	}
+
+	// the draining host shares its window with another host, which stays
+	// in maintenance
+	otherHost, otherIP := "host4", "172.17.0.8"
+	otherMachine := &mesos.MachineID{Hostname: &otherHost, Ip: &otherIP}
+	nanos := time.Now().UnixNano()
+	schedule := &mesosmaintenance.Schedule{
+		Windows: []*mesosmaintenance.Window{
+			{
+				MachineIds: suite.drainingMachines,
+				Unavailability: &mesos.Unavailability{
+					Start: &mesos.TimeInfo{Nanoseconds: &nanos},
+				},
+			},
+			{
+				MachineIds: append(
+					[]*mesos.MachineID{otherMachine}, suite.drainingMachines...),
+				Unavailability: &mesos.Unavailability{
+					Start: &mesos.TimeInfo{Nanoseconds: &nanos},
+				},
+			},
+		},
+	}
+
+	gomock.InOrder(
+		suite.mockMaintenanceMap.EXPECT().
+			GetDrainingHostInfos([]string{}).
+			Return(drainingHostInfos),
+		suite.mockMasterOperatorClient.EXPECT().
+			GetMaintenanceSchedule().
+			Return(&mesosmaster.Response_GetMaintenanceSchedule{
+				Schedule: schedule,
+			}, nil),
+		suite.mockMasterOperatorClient.EXPECT().
+			UpdateMaintenanceSchedule(gomock.Any()).
+			Do(func(schedule *mesosmaintenance.Schedule) {
+				suite.Len(schedule.GetWindows(), 1)
+				suite.Equal(
+					[]*mesos.MachineID{otherMachine},
+					schedule.GetWindows()[0].GetMachineIds())
+			}).Return(nil),
+		suite.mockMaintenanceMap.EXPECT().
+			RemoveHostInfos(hosts),
+	)
+
+	resp, err := suite.handler.CancelMaintenance(suite.ctx,
+		&svcpb.CancelMaintenanceRequest{
+			Hostnames: hosts,
+		})
+	suite.NoError(err)
+	suite.NotNil(resp)
+}
+
+// TestCancelMaintenanceError tests the failures to cancel maintenance
+func (suite *HostSvcHandlerTestSuite) TestCancelMaintenanceError() {
+	drainingHostInfos := []*hpb.HostInfo{
+		{
+			Hostname: suite.drainingMachines[0].GetHostname(),
+			Ip:       suite.drainingMachines[0].GetIp(),
+			State:    hpb.HostState_HOST_STATE_DRAINING,
+		},
+	}
+
+	// Test a host which is not draining
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(drainingHostInfos)
+	resp, err := suite.handler.CancelMaintenance(suite.ctx,
+		&svcpb.CancelMaintenanceRequest{
+			Hostnames: suite.hostsToDown,
+		})
+	suite.Error(err)
+	suite.Nil(resp)
+
+	// Test error while updating the schedule, the host stays in maintenance
+	suite.mockMaintenanceMap.EXPECT().
+		GetDrainingHostInfos([]string{}).
+		Return(drainingHostInfos)
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(nil, fmt.Errorf("fake GetMaintenanceSchedule error"))
+	resp, err = suite.handler.CancelMaintenance(suite.ctx,
+		&svcpb.CancelMaintenanceRequest{
+			Hostnames: []string{suite.drainingMachines[0].GetHostname()},
+		})
+	suite.Error(err)
+	suite.Nil(resp)
+}
+
 func (suite *HostSvcHandlerTestSuite) TestQueryHosts() {
 	var (
 		hostInfos         []*hpb.HostInfo
@@ -540,3 +649,56 @@ func (suite *HostSvcHandlerTestSuite) TestQueryHostsError() {
 	suite.NoError(err)
 	suite.NotNil(resp)
 }
+
+func (suite *HostSvcHandlerTestSuite) TestGetMaintenanceSchedule() {
+	start := int64(1500000000) * int64(time.Second)
+	duration := int64(time.Hour)
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(&mesosmaster.Response_GetMaintenanceSchedule{
+			Schedule: &mesosmaintenance.Schedule{
+				Windows: []*mesosmaintenance.Window{
+					{
+						MachineIds: append(
+							suite.downMachines, suite.drainingMachines...),
+						Unavailability: &mesos.Unavailability{
+							Start: &mesos.TimeInfo{Nanoseconds: &start},
+						},
+					},
+					{
+						MachineIds: suite.upMachines,
+						Unavailability: &mesos.Unavailability{
+							Start:    &mesos.TimeInfo{Nanoseconds: &start},
+							Duration: &mesos.DurationInfo{Nanoseconds: &duration},
+						},
+					},
+				},
+			},
+		}, nil)
+
+	resp, err := suite.handler.GetMaintenanceSchedule(
+		suite.ctx, &svcpb.GetMaintenanceScheduleRequest{})
+	suite.NoError(err)
+	suite.Equal([]*hpb.MaintenanceWindow{
+		{
+			Hostnames: []string{"host2", "host3"},
+			StartTime: "2017-07-14T02:40:00Z",
+		},
+		{
+			Hostnames: []string{"host1"},
+			StartTime: "2017-07-14T02:40:00Z",
+			EndTime:   "2017-07-14T03:40:00Z",
+		},
+	}, resp.GetWindows())
+}
+
+func (suite *HostSvcHandlerTestSuite) TestGetMaintenanceScheduleError() {
+	suite.mockMasterOperatorClient.EXPECT().
+		GetMaintenanceSchedule().
+		Return(nil, fmt.Errorf("fake GetMaintenanceSchedule error"))
+
+	resp, err := suite.handler.GetMaintenanceSchedule(
+		suite.ctx, &svcpb.GetMaintenanceScheduleRequest{})
+	suite.Error(err)
+	suite.Nil(resp)
+}
@@ -26,9 +26,17 @@ type Metrics struct {
 	CompleteMaintenanceSuccess tally.Counter
 	CompleteMaintenanceFail    tally.Counter
 
+	CancelMaintenanceAPI     tally.Counter
+	CancelMaintenanceSuccess tally.Counter
+	CancelMaintenanceFail    tally.Counter
+
 	QueryHostsAPI     tally.Counter
 	QueryHostsSuccess tally.Counter
 	QueryHostsFail    tally.Counter
+
+	GetMaintenanceScheduleAPI     tally.Counter
+	GetMaintenanceScheduleSuccess tally.Counter
+	GetMaintenanceScheduleFail    tally.Counter
 }
 
 // NewMetrics returns a new instance of host.svc.Metrics
@@ -45,8 +53,16 @@ func NewMetrics(scope tally.Scope) *Metrics {
 		CompleteMaintenanceSuccess: successScope.Counter("complete_maintenance"),
 		CompleteMaintenanceFail:    failScope.Counter("complete_maintenance"),
 
+		CancelMaintenanceAPI:     apiScope.Counter("cancel_maintenance"),
+		CancelMaintenanceSuccess: successScope.Counter("cancel_maintenance"),
+		CancelMaintenanceFail:    failScope.Counter("cancel_maintenance"),
+
 		QueryHostsAPI:     apiScope.Counter("query_hosts"),
 		QueryHostsSuccess: successScope.Counter("query_hosts"),
 		QueryHostsFail:    failScope.Counter("query_hosts"),
+
+		GetMaintenanceScheduleAPI:     apiScope.Counter("get_maintenance_schedule"),
+		GetMaintenanceScheduleSuccess: successScope.Counter("get_maintenance_schedule"),
+		GetMaintenanceScheduleFail:    failScope.Counter("get_maintenance_schedule"),
 	}
 }
@@ -0,0 +1,192 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesos
+
+import (
+	"fmt"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+)
+
+// AcceptViolation is a constraint of the ACCEPT calls violated by the
+// offers or the operations of a call
+type AcceptViolation string
+
+const (
+	// AcceptNoOffers is the violation of a call without offers, or with an
+	// empty offer ID
+	AcceptNoOffers AcceptViolation = "no offer to accept"
+
+	// AcceptInvalidOperation is the violation of an operation whose type
+	// is not supported, or which lacks the field of its type
+	AcceptInvalidOperation AcceptViolation = "invalid operation"
+
+	// AcceptOperationAfterLaunch is the violation of a RESERVE or CREATE
+	// operation after a LAUNCH or LAUNCH_GROUP operation, whose tasks could
+	// not use the reservation or the volume
+	AcceptOperationAfterLaunch AcceptViolation = "reserve or create operation after a launch"
+
+	// AcceptReservationNotReserved is the violation of a CREATE or LAUNCH
+	// operation using a reservation before the RESERVE operation of the
+	// same call making it
+	AcceptReservationNotReserved AcceptViolation = "reserved resource used before its reserve operation"
+)
+
+// AcceptValidationError is returned for an ACCEPT call violating a
+// constraint, before it is sent to Mesos
+type AcceptValidationError struct {
+	Violation AcceptViolation
+	// Index is the index of the operation violating the constraint, or -1
+	// if the violation is not of an operation
+	Index int
+	// Type is the type of the operation violating the constraint
+	Type mesos.Offer_Operation_Type
+}
+
+// Error implements error.Error
+func (e *AcceptValidationError) Error() string {
+	if e.Index < 0 {
+		return fmt.Sprintf("invalid ACCEPT call: %s", e.Violation)
+	}
+	return fmt.Sprintf("invalid ACCEPT call: operation %d (%s): %s",
+		e.Index, e.Type, e.Violation)
+}
+
+// IsAcceptValidationError returns whether the error is an
+// AcceptValidationError
+func IsAcceptValidationError(err error) bool {
+	_, ok := err.(*AcceptValidationError)
+	return ok
+}
+
+// ValidateAcceptCall checks the offer IDs and the operations of an ACCEPT
+// call, whose operations Mesos applies in order. The offer IDs must not be
+// empty, the operations must be of a supported type and have the field of
+// their type, and the RESERVE and CREATE operations must precede the LAUNCH
+// and LAUNCH_GROUP ones. The reservations used by the CREATE and launch
+// operations must be made by a prior RESERVE operation if the call makes
+// them, the ones it does not make are expected to be in the offers.
+func ValidateAcceptCall(
+	offerIDs []*mesos.OfferID,
+	operations []*mesos.Offer_Operation) error {
+	if len(offerIDs) == 0 {
+		return &AcceptValidationError{Violation: AcceptNoOffers, Index: -1}
+	}
+	for _, id := range offerIDs {
+		if id.GetValue() == "" {
+			return &AcceptValidationError{Violation: AcceptNoOffers, Index: -1}
+		}
+	}
+
+	// reservations are the reservations made by the call, and reserved the
+	// ones made by the operations validated so far
+	reservations := make(map[string]bool)
+	for _, op := range operations {
+		for _, res := range op.GetReserve().GetResources() {
+			reservations[reservationKey(res)] = true
+		}
+	}
+	reserved := make(map[string]bool)
+
+	launched := false
+	for i, op := range operations {
+		violation := func(v AcceptViolation) error {
+			return &AcceptValidationError{
+				Violation: v,
+				Index:     i,
+				Type:      op.GetType(),
+			}
+		}
+		if !isValidOperation(op) {
+			return violation(AcceptInvalidOperation)
+		}
+
+		var used []*mesos.Resource
+		switch op.GetType() {
+		case mesos.Offer_Operation_RESERVE:
+			if launched {
+				return violation(AcceptOperationAfterLaunch)
+			}
+			for _, res := range op.GetReserve().GetResources() {
+				reserved[reservationKey(res)] = true
+			}
+		case mesos.Offer_Operation_CREATE:
+			if launched {
+				return violation(AcceptOperationAfterLaunch)
+			}
+			used = op.GetCreate().GetVolumes()
+		case mesos.Offer_Operation_LAUNCH:
+			launched = true
+			used = taskResources(op.GetLaunch().GetTaskInfos())
+		case mesos.Offer_Operation_LAUNCH_GROUP:
+			launched = true
+			used = append(
+				taskResources(op.GetLaunchGroup().GetTaskGroup().GetTasks()),
+				op.GetLaunchGroup().GetExecutor().GetResources()...)
+		}
+
+		for _, res := range used {
+			if !isReserved(res) {
+				continue
+			}
+			key := reservationKey(res)
+			if reservations[key] && !reserved[key] {
+				return violation(AcceptReservationNotReserved)
+			}
+		}
+	}
+	return nil
+}
+
+// isValidOperation returns whether an operation is of a supported type
+// and has the field of its type
+func isValidOperation(op *mesos.Offer_Operation) bool {
+	switch op.GetType() {
+	case mesos.Offer_Operation_RESERVE:
+		return len(op.GetReserve().GetResources()) > 0
+	case mesos.Offer_Operation_UNRESERVE:
+		return len(op.GetUnreserve().GetResources()) > 0
+	case mesos.Offer_Operation_CREATE:
+		return len(op.GetCreate().GetVolumes()) > 0
+	case mesos.Offer_Operation_DESTROY:
+		return len(op.GetDestroy().GetVolumes()) > 0
+	case mesos.Offer_Operation_LAUNCH:
+		return len(op.GetLaunch().GetTaskInfos()) > 0
+	case mesos.Offer_Operation_LAUNCH_GROUP:
+		return len(op.GetLaunchGroup().GetTaskGroup().GetTasks()) > 0
+	}
+	return false
+}
+
+// taskResources returns the resources of tasks and of their executors
+func taskResources(tasks []*mesos.TaskInfo) []*mesos.Resource {
+	var resources []*mesos.Resource
+	for _, t := range tasks {
+		resources = append(resources, t.GetResources()...)
+		resources = append(resources, t.GetExecutor().GetResources()...)
+	}
+	return resources
+}
+
+// isReserved returns whether a resource is reserved to a role
+func isReserved(res *mesos.Resource) bool {
+	return res.GetRole() != "" && res.GetRole() != "*"
+}
+
+// reservationKey returns the key of the reservation of a resource, its role
+// and reservation labels
+func reservationKey(res *mesos.Resource) string {
+	return res.GetRole() + "/" + res.GetReservation().GetLabels().String()
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesos
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+
+	"github.com/uber/peloton/pkg/common/util"
+)
+
+// testResource returns a resource, reserved with the label value if it is
+// set
+func testResource(name string, label string) *mesos.Resource {
+	res := &mesos.Resource{
+		Name:   util.PtrStr(name),
+		Type:   mesos.Value_SCALAR.Enum(),
+		Scalar: &mesos.Value_Scalar{Value: util.PtrFloat64(1)},
+	}
+	if label != "" {
+		res.Role = util.PtrStr("peloton")
+		res.Reservation = &mesos.Resource_ReservationInfo{
+			Labels: &mesos.Labels{
+				Labels: []*mesos.Label{
+					{Key: util.PtrStr("key"), Value: util.PtrStr(label)},
+				},
+			},
+		}
+	}
+	return res
+}
+
+func testReserve(label string) *mesos.Offer_Operation {
+	return &mesos.Offer_Operation{
+		Type: mesos.Offer_Operation_RESERVE.Enum(),
+		Reserve: &mesos.Offer_Operation_Reserve{
+			Resources: []*mesos.Resource{
+				testResource("cpus", label),
+				testResource("disk", label),
+			},
+		},
+	}
+}
+
+func testCreate(label string) *mesos.Offer_Operation {
+	return &mesos.Offer_Operation{
+		Type: mesos.Offer_Operation_CREATE.Enum(),
+		Create: &mesos.Offer_Operation_Create{
+			Volumes: []*mesos.Resource{testResource("disk", label)},
+		},
+	}
+}
+
+func testLaunch(label string) *mesos.Offer_Operation {
+	return &mesos.Offer_Operation{
+		Type: mesos.Offer_Operation_LAUNCH.Enum(),
+		Launch: &mesos.Offer_Operation_Launch{
+			TaskInfos: []*mesos.TaskInfo{
+				{
+					Name:      util.PtrStr("task"),
+					Resources: []*mesos.Resource{testResource("cpus", label)},
+				},
+			},
+		},
+	}
+}
+
+func testLaunchGroup(label string) *mesos.Offer_Operation {
+	return &mesos.Offer_Operation{
+		Type: mesos.Offer_Operation_LAUNCH_GROUP.Enum(),
+		LaunchGroup: &mesos.Offer_Operation_LaunchGroup{
+			TaskGroup: &mesos.TaskGroupInfo{
+				Tasks: testLaunch(label).GetLaunch().GetTaskInfos(),
+			},
+		},
+	}
+}
+
+func TestValidateAcceptCall(t *testing.T) {
+	offerIDs := []*mesos.OfferID{{Value: util.PtrStr("offer")}}
+
+	tests := []struct {
+		name       string
+		offerIDs   []*mesos.OfferID
+		operations []*mesos.Offer_Operation
+		violation  AcceptViolation
+		index      int
+	}{
+		{
+			name:       "launch",
+			offerIDs:   offerIDs,
+			operations: []*mesos.Offer_Operation{testLaunch("")},
+		},
+		{
+			name:     "reserve create launch",
+			offerIDs: offerIDs,
+			operations: []*mesos.Offer_Operation{
+				testReserve("l1"), testCreate("l1"), testLaunch("l1"),
+			},
+		},
+		{
+			name:     "reserve create launch group",
+			offerIDs: offerIDs,
+			operations: []*mesos.Offer_Operation{
+				testReserve("l1"), testCreate("l1"), testLaunchGroup("l1"),
+			},
+		},
+		{
+			// the reservation of an offer is not made by the call
+			name:       "create launch on reserved offer",
+			offerIDs:   offerIDs,
+			operations: []*mesos.Offer_Operation{testCreate("l1"), testLaunch("l1")},
+		},
+		{
+			name:     "reservation of another reserve",
+			offerIDs: offerIDs,
+			operations: []*mesos.Offer_Operation{
+				testReserve("l2"), testCreate("l1"), testLaunch("l1"),
+			},
+		},
+		{
+			name:     "several launches",
+			offerIDs: offerIDs,
+			operations: []*mesos.Offer_Operation{
+				testLaunch(""), testLaunchGroup(""),
+			},
+		},
+		{
+			name:       "no offer",
+			operations: []*mesos.Offer_Operation{testLaunch("")},
+			violation:  AcceptNoOffers,
+			index:      -1,
+		},
+		{
+			name:       "empty offer id",
+			offerIDs:   []*mesos.OfferID{{}},
+			operations: []*mesos.Offer_Operation{testLaunch("")},
+			violation:  AcceptNoOffers,
+			index:      -1,
+		},
+		{
+			name:       "nil operation",
+			offerIDs:   offerIDs,
+			operations: []*mesos.Offer_Operation{testLaunch(""), nil},
+			violation:  AcceptInvalidOperation,
+			index:      1,
+		},
+		{
+			name:     "launch without tasks",
+			offerIDs: offerIDs,
+			operations: []*mesos.Offer_Operation{
+				{Type: mesos.Offer_Operation_LAUNCH.Enum()},
+			},
+			violation: AcceptInvalidOperation,
+			index:     0,
+		},
+		{
+			name:     "unsupported operation",
+			offerIDs: offerIDs,
+			operations: []*mesos.Offer_Operation{
+				{Type: mesos.Offer_Operation_UNKNOWN.Enum()},
+			},
+			violation: AcceptInvalidOperation,
+			index:     0,
+		},
+		{
+			name:       "create after launch",
+			offerIDs:   offerIDs,
+			operations: []*mesos.Offer_Operation{testLaunch("l1"), testCreate("l1")},
+			violation:  AcceptOperationAfterLaunch,
+			index:      1,
+		},
+		{
+			name:     "reserve after launch group",
+			offerIDs: offerIDs,
+			operations: []*mesos.Offer_Operation{
+				testLaunchGroup(""), testReserve("l1"),
+			},
+			violation: AcceptOperationAfterLaunch,
+			index:     1,
+		},
+		{
+			name:     "create before reserve",
+			offerIDs: offerIDs,
+			operations: []*mesos.Offer_Operation{
+				testCreate("l1"), testReserve("l1"), testLaunch("l1"),
+			},
+			violation: AcceptReservationNotReserved,
+			index:     0,
+		},
+		{
+			name:     "launch before reserve",
+			offerIDs: offerIDs,
+			operations: []*mesos.Offer_Operation{
+				testLaunch("l1"), testReserve("l1"),
+			},
+			violation: AcceptReservationNotReserved,
+			index:     0,
+		},
+	}
+
+	for _, tt := range tests {
+		err := ValidateAcceptCall(tt.offerIDs, tt.operations)
+		if tt.violation == "" {
+			assert.NoError(t, err, tt.name)
+			continue
+		}
+		assert.True(t, IsAcceptValidationError(err), tt.name)
+		verr, ok := err.(*AcceptValidationError)
+		if assert.True(t, ok, tt.name) {
+			assert.Equal(t, tt.violation, verr.Violation, tt.name)
+			assert.Equal(t, tt.index, verr.Index, tt.name)
+		}
+	}
+}
+
+func TestAcceptValidationError(t *testing.T) {
+	err := &AcceptValidationError{
+		Violation: AcceptOperationAfterLaunch,
+		Index:     1,
+		Type:      mesos.Offer_Operation_CREATE,
+	}
+	assert.EqualError(t, err, "invalid ACCEPT call: operation 1 (CREATE): "+
+		"reserve or create operation after a launch")
+
+	err = &AcceptValidationError{Violation: AcceptNoOffers, Index: -1}
+	assert.EqualError(t, err, "invalid ACCEPT call: no offer to accept")
+
+	assert.False(t, IsAcceptValidationError(errors.New("accept failed")))
+}
@@ -0,0 +1,258 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesos
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+
+	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
+)
+
+const (
+	_defaultAckWindow       = 10 * time.Millisecond
+	_defaultAckMaxBatchSize = 100
+	_defaultAckMaxInFlight  = 10
+)
+
+// errAcknowledgeBatcherStopped is returned for the acknowledgements given
+// to a stopped batcher
+var errAcknowledgeBatcherStopped = errors.New("acknowledge batcher is stopped")
+
+// Acknowledgement identifies the task status update to acknowledge
+type Acknowledgement struct {
+	AgentID *mesos.AgentID
+	TaskID  *mesos.TaskID
+	UUID    []byte
+}
+
+// AcknowledgeCallback is called once for every acknowledgement accepted by
+// an AcknowledgeBatcher, with the error of its ACKNOWLEDGE call if it failed.
+type AcknowledgeCallback func(ack *Acknowledgement, err error)
+
+// AcknowledgeBatcherConfig is the configuration of an AcknowledgeBatcher
+type AcknowledgeBatcherConfig struct {
+	// Window is how long acknowledgements are coalesced before their batch
+	// is sent
+	Window time.Duration `yaml:"window"`
+	// MaxBatchSize sends a batch before its window expires once it holds
+	// that many acknowledgements
+	MaxBatchSize int `yaml:"max_batch_size"`
+	// MaxInFlight bounds the number of concurrent ACKNOWLEDGE calls
+	MaxInFlight int `yaml:"max_in_flight"`
+}
+
+// acknowledgeBatcherMetrics are the metrics of an AcknowledgeBatcher
+type acknowledgeBatcherMetrics struct {
+	batchSize  tally.Histogram
+	ackLatency tally.Timer
+	ack        tally.Counter
+	ackFail    tally.Counter
+}
+
+func newAcknowledgeBatcherMetrics(scope tally.Scope) *acknowledgeBatcherMetrics {
+	return &acknowledgeBatcherMetrics{
+		batchSize: scope.Histogram("ack_batch_size",
+			tally.MustMakeExponentialValueBuckets(1, 2, 10)),
+		ackLatency: scope.Timer("ack_latency"),
+		ack:        scope.Counter("ack"),
+		ackFail:    scope.Counter("ack_fail"),
+	}
+}
+
+// pendingAcknowledgement is an accepted acknowledgement waiting for its
+// batch to be sent
+type pendingAcknowledgement struct {
+	ack      *Acknowledgement
+	accepted time.Time
+}
+
+// AcknowledgeBatcher coalesces the acknowledgements of task status updates
+// given during a short window and sends them as ACKNOWLEDGE calls with a
+// bounded concurrency. The Mesos v1 scheduler API acknowledges a single
+// update per call, so a batch saves the scheduling of a call per update
+// during bursts, and duplicate acknowledgements of a batch are sent once.
+type AcknowledgeBatcher struct {
+	sync.Mutex
+
+	client   mpb.SchedulerClient
+	provider FrameworkInfoProvider
+	config   AcknowledgeBatcherConfig
+	callback AcknowledgeCallback
+	metrics  *acknowledgeBatcherMetrics
+
+	pending []*pendingAcknowledgement
+	// generation identifies the current batch, so that the window of a
+	// batch flushed by its size does not flush the next one
+	generation uint64
+	stopped    bool
+
+	// inFlight holds a token for every ACKNOWLEDGE call being sent
+	inFlight chan struct{}
+	// batches tracks the batches being sent
+	batches sync.WaitGroup
+}
+
+// NewAcknowledgeBatcher creates an AcknowledgeBatcher sending the
+// ACKNOWLEDGE calls with client, for the framework of provider. The unset
+// fields of config take their default values.
+func NewAcknowledgeBatcher(
+	client mpb.SchedulerClient,
+	provider FrameworkInfoProvider,
+	config AcknowledgeBatcherConfig,
+	callback AcknowledgeCallback,
+	scope tally.Scope) *AcknowledgeBatcher {
+	if config.Window <= 0 {
+		config.Window = _defaultAckWindow
+	}
+	if config.MaxBatchSize <= 0 {
+		config.MaxBatchSize = _defaultAckMaxBatchSize
+	}
+	if config.MaxInFlight <= 0 {
+		config.MaxInFlight = _defaultAckMaxInFlight
+	}
+	return &AcknowledgeBatcher{
+		client:   client,
+		provider: provider,
+		config:   config,
+		callback: callback,
+		metrics:  newAcknowledgeBatcherMetrics(scope),
+		inFlight: make(chan struct{}, config.MaxInFlight),
+	}
+}
+
+// Acknowledge adds an acknowledgement to the current batch. Once it is
+// accepted, the callback is eventually called with its result. It is not
+// accepted if the batcher is stopped.
+func (b *AcknowledgeBatcher) Acknowledge(ack *Acknowledgement) error {
+	b.Lock()
+	defer b.Unlock()
+
+	if b.stopped {
+		return errAcknowledgeBatcherStopped
+	}
+
+	b.pending = append(b.pending, &pendingAcknowledgement{
+		ack:      ack,
+		accepted: time.Now(),
+	})
+	switch len(b.pending) {
+	case b.config.MaxBatchSize:
+		b.flush()
+	case 1:
+		generation := b.generation
+		time.AfterFunc(b.config.Window, func() {
+			b.Lock()
+			defer b.Unlock()
+			if b.generation == generation {
+				b.flush()
+			}
+		})
+	}
+	return nil
+}
+
+// Stop sends the pending acknowledgements and waits until all the
+// acknowledgements were sent. No acknowledgement is accepted afterwards.
+func (b *AcknowledgeBatcher) Stop() {
+	b.Lock()
+	b.stopped = true
+	b.flush()
+	b.Unlock()
+
+	b.batches.Wait()
+}
+
+// flush sends the current batch asynchronously and starts a new one. It is
+// called with the lock held.
+func (b *AcknowledgeBatcher) flush() {
+	if len(b.pending) == 0 {
+		return
+	}
+	batch := b.pending
+	b.pending = nil
+	b.generation++
+
+	b.batches.Add(1)
+	go func() {
+		defer b.batches.Done()
+		b.send(batch)
+	}()
+}
+
+// send sends the ACKNOWLEDGE calls of a batch, at most MaxInFlight at a
+// time, and reports their results
+func (b *AcknowledgeBatcher) send(batch []*pendingAcknowledgement) {
+	b.metrics.batchSize.RecordValue(float64(len(batch)))
+
+	ctx := context.Background()
+	frameworkID := b.provider.GetFrameworkID(ctx)
+	streamID := b.provider.GetMesosStreamID(ctx)
+
+	// duplicates of an acknowledgement share the result of a single call
+	groups := make(map[string][]*pendingAcknowledgement)
+	var keys []string
+	for _, p := range batch {
+		key := string(p.ack.UUID)
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], p)
+	}
+
+	var calls sync.WaitGroup
+	for _, key := range keys {
+		b.inFlight <- struct{}{}
+		calls.Add(1)
+		go func(group []*pendingAcknowledgement) {
+			defer calls.Done()
+			ack := group[0].ack
+			err := b.client.Call(
+				streamID, mpb.NewAcknowledgeCall(
+					frameworkID, ack.AgentID, ack.TaskID, ack.UUID))
+			<-b.inFlight
+
+			if err != nil {
+				log.WithError(err).
+					WithField("task_id", ack.TaskID.GetValue()).
+					Warn("Failed to acknowledge task status update")
+			}
+			for _, p := range group {
+				b.report(p, err)
+			}
+		}(groups[key])
+	}
+	calls.Wait()
+}
+
+// report reports the result of an acknowledgement
+func (b *AcknowledgeBatcher) report(p *pendingAcknowledgement, err error) {
+	b.metrics.ackLatency.Record(time.Since(p.accepted))
+	if err != nil {
+		b.metrics.ackFail.Inc(1)
+	} else {
+		b.metrics.ack.Inc(1)
+	}
+	if b.callback != nil {
+		b.callback(p.ack, err)
+	}
+}
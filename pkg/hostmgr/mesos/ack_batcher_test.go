@@ -0,0 +1,300 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
+
+	"github.com/uber/peloton/pkg/common/util"
+)
+
+// fakeSchedulerClient records the calls it receives, failing the calls
+// whose callKey is in errs and blocking every call until release
+// is closed if it is set
+type fakeSchedulerClient struct {
+	sync.Mutex
+	calls       []*sched.Call
+	errs        map[string]error
+	release     chan struct{}
+	inFlight    int
+	maxInFlight int
+}
+
+func (c *fakeSchedulerClient) Call(
+	mesosStreamID string,
+	msg *sched.Call) error {
+	c.Lock()
+	c.calls = append(c.calls, msg)
+	c.inFlight++
+	if c.inFlight > c.maxInFlight {
+		c.maxInFlight = c.inFlight
+	}
+	release := c.release
+	c.Unlock()
+
+	if release != nil {
+		<-release
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	c.inFlight--
+	return c.errs[callKey(msg)]
+}
+
+// callKey returns the key of a call in the errs of a fakeSchedulerClient:
+// the acknowledged task or operation ID, if any
+func callKey(msg *sched.Call) string {
+	if msg.GetType() == sched.Call_ACKNOWLEDGE_OPERATION_STATUS {
+		return msg.GetAcknowledgeOperationStatus().GetOperationId().GetValue()
+	}
+	return msg.GetAcknowledge().GetTaskId().GetValue()
+}
+
+func (c *fakeSchedulerClient) Suppress(
+	string, *mesos.FrameworkID, []string) error {
+	return nil
+}
+
+func (c *fakeSchedulerClient) Revive(
+	string, *mesos.FrameworkID, []string) error {
+	return nil
+}
+
+func (c *fakeSchedulerClient) callCount() int {
+	c.Lock()
+	defer c.Unlock()
+	return len(c.calls)
+}
+
+// fakeFrameworkInfoProvider provides static framework info
+type fakeFrameworkInfoProvider struct{}
+
+func (fakeFrameworkInfoProvider) GetMesosStreamID(context.Context) string {
+	return _streamID
+}
+
+func (fakeFrameworkInfoProvider) GetFrameworkID(
+	context.Context) *mesos.FrameworkID {
+	return &mesos.FrameworkID{Value: util.PtrStr(_frameworkID)}
+}
+
+// ackResult is the result of an acknowledgement reported to the callback
+type ackResult struct {
+	ack *Acknowledgement
+	err error
+}
+
+type acknowledgeBatcherTestSuite struct {
+	suite.Suite
+
+	client  *fakeSchedulerClient
+	scope   tally.TestScope
+	results chan ackResult
+}
+
+func (suite *acknowledgeBatcherTestSuite) SetupTest() {
+	suite.client = &fakeSchedulerClient{errs: make(map[string]error)}
+	suite.scope = tally.NewTestScope("", nil)
+	suite.results = make(chan ackResult, 100)
+}
+
+func TestAcknowledgeBatcher(t *testing.T) {
+	suite.Run(t, new(acknowledgeBatcherTestSuite))
+}
+
+func (suite *acknowledgeBatcherTestSuite) newBatcher(
+	config AcknowledgeBatcherConfig) *AcknowledgeBatcher {
+	return NewAcknowledgeBatcher(
+		suite.client,
+		fakeFrameworkInfoProvider{},
+		config,
+		func(ack *Acknowledgement, err error) {
+			suite.results <- ackResult{ack: ack, err: err}
+		},
+		suite.scope)
+}
+
+func newTestAcknowledgement(i int) *Acknowledgement {
+	return &Acknowledgement{
+		AgentID: &mesos.AgentID{Value: util.PtrStr("agent")},
+		TaskID:  &mesos.TaskID{Value: util.PtrStr(fmt.Sprintf("task-%d", i))},
+		UUID:    []byte(fmt.Sprintf("uuid-%d", i)),
+	}
+}
+
+// waitResults waits for the results of count acknowledgements
+func (suite *acknowledgeBatcherTestSuite) waitResults(count int) []ackResult {
+	var results []ackResult
+	for len(results) < count {
+		select {
+		case result := <-suite.results:
+			results = append(results, result)
+		case <-time.After(5 * time.Second):
+			suite.FailNow("timed out waiting for acknowledgements",
+				"got %d of %d", len(results), count)
+		}
+	}
+	return results
+}
+
+func (suite *acknowledgeBatcherTestSuite) counter(name string) int64 {
+	counter, ok := suite.scope.Snapshot().Counters()[name+"+"]
+	if !ok {
+		return 0
+	}
+	return counter.Value()
+}
+
+// TestFlushOnWindow tests that a batch is sent once its window expires
+func (suite *acknowledgeBatcherTestSuite) TestFlushOnWindow() {
+	b := suite.newBatcher(AcknowledgeBatcherConfig{
+		Window:       50 * time.Millisecond,
+		MaxBatchSize: 100,
+	})
+	defer b.Stop()
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		suite.NoError(b.Acknowledge(newTestAcknowledgement(i)))
+	}
+	suite.Equal(0, suite.client.callCount())
+
+	for _, result := range suite.waitResults(3) {
+		suite.NoError(result.err)
+	}
+	suite.True(time.Since(start) >= 50*time.Millisecond)
+	suite.Equal(3, suite.client.callCount())
+	suite.Equal(int64(3), suite.counter("ack"))
+
+	call := suite.client.calls[0]
+	suite.Equal(sched.Call_ACKNOWLEDGE, call.GetType())
+	suite.Equal(_frameworkID, call.GetFrameworkId().GetValue())
+	suite.Equal("agent", call.GetAcknowledge().GetAgentId().GetValue())
+}
+
+// TestFlushOnSize tests that a full batch is sent before its window expires
+func (suite *acknowledgeBatcherTestSuite) TestFlushOnSize() {
+	b := suite.newBatcher(AcknowledgeBatcherConfig{
+		Window:       time.Hour,
+		MaxBatchSize: 2,
+	})
+	defer b.Stop()
+
+	for i := 0; i < 3; i++ {
+		suite.NoError(b.Acknowledge(newTestAcknowledgement(i)))
+	}
+	var tasks []string
+	for _, result := range suite.waitResults(2) {
+		tasks = append(tasks, result.ack.TaskID.GetValue())
+	}
+	suite.ElementsMatch([]string{"task-0", "task-1"}, tasks)
+	// the third acknowledgement waits for the window of the next batch
+	select {
+	case result := <-suite.results:
+		suite.Fail("unexpected acknowledgement", result.ack.TaskID.GetValue())
+	case <-time.After(50 * time.Millisecond):
+	}
+	suite.Equal(2, suite.client.callCount())
+}
+
+// TestStopFlushes tests that the pending acknowledgements are sent on stop
+// and that no acknowledgement is accepted afterwards
+func (suite *acknowledgeBatcherTestSuite) TestStopFlushes() {
+	b := suite.newBatcher(AcknowledgeBatcherConfig{
+		Window:       time.Hour,
+		MaxBatchSize: 100,
+	})
+	for i := 0; i < 3; i++ {
+		suite.NoError(b.Acknowledge(newTestAcknowledgement(i)))
+	}
+
+	b.Stop()
+	suite.Len(suite.results, 3)
+	suite.Equal(3, suite.client.callCount())
+
+	suite.Equal(errAcknowledgeBatcherStopped,
+		b.Acknowledge(newTestAcknowledgement(3)))
+	suite.Len(suite.results, 3)
+}
+
+// TestFailedAcknowledgement tests that failed calls are reported to the
+// callback
+func (suite *acknowledgeBatcherTestSuite) TestFailedAcknowledgement() {
+	suite.client.errs["task-1"] = errors.New("mesos unavailable")
+	b := suite.newBatcher(AcknowledgeBatcherConfig{Window: time.Hour})
+	suite.NoError(b.Acknowledge(newTestAcknowledgement(0)))
+	suite.NoError(b.Acknowledge(newTestAcknowledgement(1)))
+	b.Stop()
+
+	for _, result := range suite.waitResults(2) {
+		if result.ack.TaskID.GetValue() == "task-1" {
+			suite.EqualError(result.err, "mesos unavailable")
+		} else {
+			suite.NoError(result.err)
+		}
+	}
+	suite.Equal(int64(1), suite.counter("ack"))
+	suite.Equal(int64(1), suite.counter("ack_fail"))
+}
+
+// TestDuplicateAcknowledgements tests that the duplicates of a batch are
+// sent once and all reported
+func (suite *acknowledgeBatcherTestSuite) TestDuplicateAcknowledgements() {
+	b := suite.newBatcher(AcknowledgeBatcherConfig{Window: time.Hour})
+	suite.NoError(b.Acknowledge(newTestAcknowledgement(0)))
+	suite.NoError(b.Acknowledge(newTestAcknowledgement(0)))
+	b.Stop()
+
+	suite.Len(suite.results, 2)
+	suite.Equal(1, suite.client.callCount())
+}
+
+// TestMaxInFlight tests that the concurrent calls are bounded
+func (suite *acknowledgeBatcherTestSuite) TestMaxInFlight() {
+	suite.client.release = make(chan struct{})
+	b := suite.newBatcher(AcknowledgeBatcherConfig{
+		Window:      time.Hour,
+		MaxInFlight: 2,
+	})
+	for i := 0; i < 5; i++ {
+		suite.NoError(b.Acknowledge(newTestAcknowledgement(i)))
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		b.Stop()
+		close(stopped)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	suite.Equal(2, suite.client.callCount())
+	close(suite.client.release)
+	<-stopped
+
+	suite.Equal(5, suite.client.callCount())
+	suite.Equal(2, suite.client.maxInFlight)
+	suite.Len(suite.results, 5)
+}
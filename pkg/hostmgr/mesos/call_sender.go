@@ -0,0 +1,81 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesos
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// _callKeepAlive is the keep-alive period of the connections of a
+// CallSender to the Mesos master
+const _callKeepAlive = 30 * time.Second
+
+// CallSender sends the calls prepared by a SchedulerDriver to the Mesos
+// master.
+type CallSender interface {
+	// Send posts the request of a call, and returns an error unless Mesos
+	// accepted the call.
+	Send(ctx context.Context, req *http.Request) error
+}
+
+// callSender implements CallSender with a HTTP client of its own.
+type callSender struct {
+	client *http.Client
+}
+
+// NewCallSender returns a CallSender posting the calls with a HTTP client
+// of its own, like the Mesos outbounds.
+func NewCallSender() CallSender {
+	return &callSender{
+		client: &http.Client{
+			Transport: &http.Transport{
+				Proxy: http.ProxyFromEnvironment,
+				Dial: (&net.Dialer{
+					Timeout:   30 * time.Second,
+					KeepAlive: _callKeepAlive,
+				}).Dial,
+				TLSHandshakeTimeout:   10 * time.Second,
+				ExpectContinueTimeout: 1 * time.Second,
+			},
+		},
+	}
+}
+
+// Send implements CallSender.Send.
+func (s *callSender) Send(ctx context.Context, req *http.Request) error {
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	contents, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+	return fmt.Errorf(
+		"{\"status_code\": %d, \"contents\": \"%s\"}",
+		resp.StatusCode,
+		strings.TrimSuffix(string(contents), "\n"))
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesos
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCallSenderSend(t *testing.T) {
+	var body string
+	var streamID string
+	status := http.StatusAccepted
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			body = string(b)
+			streamID = r.Header.Get("Mesos-Stream-Id")
+			w.WriteHeader(status)
+			w.Write([]byte("invalid call\n"))
+		}))
+	defer server.Close()
+
+	newRequest := func() *http.Request {
+		req, err := http.NewRequest(
+			"POST", server.URL, strings.NewReader("call"))
+		assert.NoError(t, err)
+		req.Header.Set("Mesos-Stream-Id", "stream")
+		return req
+	}
+
+	sender := NewCallSender()
+	assert.NoError(t, sender.Send(context.Background(), newRequest()))
+	assert.Equal(t, "call", body)
+	assert.Equal(t, "stream", streamID)
+
+	status = http.StatusBadRequest
+	assert.EqualError(t, sender.Send(context.Background(), newRequest()),
+		"{\"status_code\": 400, \"contents\": \"invalid call\"}")
+
+	// The call is not sent once the context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, sender.Send(ctx, newRequest()))
+}
@@ -14,6 +14,10 @@
 
 package mesos
 
+import (
+	"time"
+)
+
 // Config for Mesos specific configuration
 type Config struct {
 	Framework *FrameworkConfig `yaml:"framework"`
@@ -32,4 +36,18 @@ type FrameworkConfig struct {
 	TaskKillingStateSupported   bool    `yaml:"task_killing_state"`
 	PartitionAwareSupported     bool    `yaml:"partition_aware"`
 	RevocableResourcesSupported bool    `yaml:"revocable_resources"`
+
+	// AllowedUsers are the users other than User the tasks may run as,
+	// "*" allows any user. No user is rejected if empty.
+	AllowedUsers []string `yaml:"allowed_users"`
+
+	// MesosStreamIDMaxAge is the age after which a persisted Mesos stream
+	// ID of a previous subscription is treated as absent, 0 to never treat
+	// it as stale. The stream ID of the current subscription of the host
+	// manager is used however old it is.
+	MesosStreamIDMaxAge time.Duration `yaml:"mesos_stream_id_max_age"`
+	// LegacyMesosStreamIDStale treats a Mesos stream ID persisted without
+	// the time it was set as absent, to be enabled once every stream ID
+	// was persisted again after the upgrade
+	LegacyMesosStreamIDStale bool `yaml:"legacy_mesos_stream_id_stale"`
 }
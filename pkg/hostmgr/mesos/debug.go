@@ -0,0 +1,68 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesos
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/uber/peloton/pkg/storage"
+)
+
+const (
+	// FrameworkInfoHistoryPath is the path of the debug endpoint which
+	// returns the recent changes of the framework id and mesos stream id.
+	FrameworkInfoHistoryPath = "/debug/mesos/framework_info_history"
+
+	// _defaultFrameworkInfoHistoryLimit is the number of changes returned
+	// without a limit query parameter.
+	_defaultFrameworkInfoHistoryLimit = 20
+)
+
+// FrameworkInfoHistoryHandler returns the handler of the debug endpoint
+// which returns the recent changes of the framework info of the driver as
+// JSON, the most recent first. The number of changes is set by the limit
+// query parameter, 0 returns all retained changes.
+func FrameworkInfoHistoryHandler(
+	d SchedulerDriver) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit := _defaultFrameworkInfoHistoryLimit
+		if value := r.URL.Query().Get("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+				http.Error(w, "invalid limit: "+value, http.StatusBadRequest)
+				return
+			}
+		}
+
+		changes, err := d.GetFrameworkInfoHistory(r.Context(), limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if changes == nil {
+			changes = []*storage.FrameworkInfoChange{}
+		}
+		body, err := json.Marshal(changes)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
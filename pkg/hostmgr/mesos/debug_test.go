@@ -0,0 +1,76 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesos
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/uber/peloton/pkg/storage"
+)
+
+// TestFrameworkInfoHistoryHandler tests that the debug endpoint returns the
+// recent changes of the framework info, the most recent first
+func (suite *schedulerDriverTestSuite) TestFrameworkInfoHistoryHandler() {
+	ctx := context.Background()
+	suite.NoError(suite.store.SetMesosFrameworkID(ctx, _frameworkName, _frameworkID))
+	suite.NoError(suite.driver.PostSubscribe(ctx, _streamID))
+
+	w := httptest.NewRecorder()
+	FrameworkInfoHistoryHandler(suite.driver)(
+		w, httptest.NewRequest("GET", FrameworkInfoHistoryPath, nil))
+	suite.Equal(http.StatusOK, w.Code)
+	var changes []*storage.FrameworkInfoChange
+	suite.NoError(json.Unmarshal(w.Body.Bytes(), &changes))
+	suite.Len(changes, 2)
+	suite.Equal(storage.FrameworkInfoFieldMesosStreamID, changes[0].Field)
+	suite.Equal(_streamID, changes[0].NewValue)
+	suite.Equal(storage.FrameworkInfoFieldFrameworkID, changes[1].Field)
+	suite.Equal(_frameworkID, changes[1].NewValue)
+
+	w = httptest.NewRecorder()
+	FrameworkInfoHistoryHandler(suite.driver)(
+		w, httptest.NewRequest("GET", FrameworkInfoHistoryPath+"?limit=1", nil))
+	suite.Equal(http.StatusOK, w.Code)
+	suite.NoError(json.Unmarshal(w.Body.Bytes(), &changes))
+	suite.Len(changes, 1)
+	suite.Equal(_streamID, changes[0].NewValue)
+}
+
+// TestFrameworkInfoHistoryHandlerErrors tests the errors of the debug
+// endpoint of the framework info history
+func (suite *schedulerDriverTestSuite) TestFrameworkInfoHistoryHandlerErrors() {
+	w := httptest.NewRecorder()
+	FrameworkInfoHistoryHandler(suite.driver)(
+		w, httptest.NewRequest("GET", FrameworkInfoHistoryPath+"?limit=x", nil))
+	suite.Equal(http.StatusBadRequest, w.Code)
+
+	// a framework without history has no changes
+	w = httptest.NewRecorder()
+	FrameworkInfoHistoryHandler(suite.driver)(
+		w, httptest.NewRequest("GET", FrameworkInfoHistoryPath, nil))
+	suite.Equal(http.StatusOK, w.Code)
+	suite.Equal("[]", w.Body.String())
+
+	suite.store.SetError(errors.New("store unavailable"))
+	w = httptest.NewRecorder()
+	FrameworkInfoHistoryHandler(suite.driver)(
+		w, httptest.NewRequest("GET", FrameworkInfoHistoryPath, nil))
+	suite.Equal(http.StatusInternalServerError, w.Code)
+	suite.Contains(w.Body.String(), "store unavailable")
+}
@@ -24,6 +24,8 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/pkg/errors"
 	log "github.com/sirupsen/logrus"
@@ -53,6 +55,30 @@ const (
 type SchedulerDriver interface {
 	mhttp.MesosDriver
 	FrameworkInfoProvider
+
+	// GetFrameworkInfoHistory returns up to limit of the most recent
+	// changes of the framework id and mesos stream id, the most recent
+	// first.
+	GetFrameworkInfoHistory(ctx context.Context, limit int) ([]*storage.FrameworkInfoChange, error)
+
+	// PrepareSuppressRequest returns a HTTP post request of the call
+	// suppressing the offers of roles, or of all the subscribed roles if
+	// roles is empty.
+	PrepareSuppressRequest(ctx context.Context, mesosMasterHostPort string, roles []string) (*http.Request, error)
+
+	// PrepareReviveRequest returns a HTTP post request of the call
+	// reviving the offers of roles, or of all the subscribed roles if
+	// roles is empty.
+	PrepareReviveRequest(ctx context.Context, mesosMasterHostPort string, roles []string) (*http.Request, error)
+
+	// PrepareMessageRequest returns a HTTP post request of the call
+	// sending data to an executor of an agent.
+	PrepareMessageRequest(ctx context.Context, mesosMasterHostPort string, agentID string, executorID string, data []byte) (*http.Request, error)
+
+	// PrepareAcceptRequest returns a HTTP post request of the call
+	// applying operations to the resources of offerIDs, or an
+	// AcceptValidationError if the call is invalid.
+	PrepareAcceptRequest(ctx context.Context, mesosMasterHostPort string, offerIDs []*mesos.OfferID, operations []*mesos.Offer_Operation, filters *mesos.Filters) (*http.Request, error)
 }
 
 // FrameworkInfoProvider can be used to retrieve mesosStreamID and frameworkID.
@@ -66,8 +92,14 @@ type schedulerDriver struct {
 	store         storage.FrameworkInfoStore
 	frameworkID   *mesos.FrameworkID
 	mesosStreamID string
-	cfg           *FrameworkConfig
-	encoding      string
+	// subscribeStreamID is the mesos stream id in the store when the last
+	// subscription was prepared, nil if it could not be read
+	subscribeStreamID *string
+	// subscribedStreamID is the mesos stream id of the last subscription of
+	// the driver, a string
+	subscribedStreamID atomic.Value
+	cfg                *FrameworkConfig
+	encoding           string
 
 	defaultHeaders http.Header
 }
@@ -121,21 +153,32 @@ func (d *schedulerDriver) GetFrameworkID(ctx context.Context) *mesos.FrameworkID
 		"framework_name": d.cfg.Name,
 	}).Debug("Loaded frameworkID")
 	d.frameworkID = &mesos.FrameworkID{
-		Value: &frameworkIDVal,
+		Value: util.PtrStr(frameworkIDVal),
 	}
 	return d.frameworkID
 }
 
-// GetMesosStreamID reads DB for the Mesos stream ID.
+// GetMesosStreamID reads DB for the Mesos stream ID. A stale stream ID of
+// a previous subscription, e.g. one read at startup or after a failover, is
+// skipped, as calls using it would be rejected by Mesos.
 // Implements FrameworkInfoProvider.GetMesosStreamID().
 func (d *schedulerDriver) GetMesosStreamID(ctx context.Context) string {
-	id, err := d.store.GetMesosStreamID(ctx, d.cfg.Name)
+	id, setTime, err := d.store.GetMesosStreamIDWithTime(ctx, d.cfg.Name)
 	if err != nil {
 		log.WithError(err).
 			WithField("framework_name", d.cfg.Name).
 			Error("Failed to GetmesosStreamID from db")
 		return ""
 	}
+	if d.isStaleMesosStreamID(id, setTime) {
+		log.WithFields(log.Fields{
+			"stream_id":      id,
+			"stream_id_time": setTime,
+			"max_age":        d.cfg.MesosStreamIDMaxAge,
+			"framework":      d.cfg.Name,
+		}).Warn("Skipping stale Mesos stream id")
+		return ""
+	}
 	log.WithFields(log.Fields{
 		"stream_id": id,
 		"framework": d.cfg.Name,
@@ -146,6 +189,35 @@ func (d *schedulerDriver) GetMesosStreamID(ctx context.Context) string {
 	return id
 }
 
+// GetFrameworkInfoHistory returns the recent changes of the framework info
+// of the framework from the store.
+// Implements SchedulerDriver.GetFrameworkInfoHistory().
+func (d *schedulerDriver) GetFrameworkInfoHistory(
+	ctx context.Context,
+	limit int) ([]*storage.FrameworkInfoChange, error) {
+	return d.store.GetFrameworkInfoHistory(ctx, d.cfg.Name, limit)
+}
+
+// isStaleMesosStreamID returns whether a Mesos stream ID set at the given
+// time is too old to be used. The stream ID of the subscription of the
+// driver is never stale however long the subscription lasts, the age only
+// applies to the stream IDs of previous subscriptions. The time is zero for
+// a stream ID persisted without it, which is only stale once the migration
+// period is over.
+func (d *schedulerDriver) isStaleMesosStreamID(
+	id string,
+	setTime time.Time) bool {
+	if subscribed, _ := d.subscribedStreamID.Load().(string); subscribed != "" &&
+		id == subscribed {
+		return false
+	}
+	if setTime.IsZero() {
+		return d.cfg.LegacyMesosStreamIDStale
+	}
+	return d.cfg.MesosStreamIDMaxAge > 0 &&
+		time.Since(setTime) > d.cfg.MesosStreamIDMaxAge
+}
+
 // Returns the name of Scheduler driver.
 // Implements mhttp.MesosDriver.Name().
 func (d *schedulerDriver) Name() string {
@@ -172,36 +244,32 @@ func (d *schedulerDriver) prepareSubscribe(ctx context.Context) (*sched.Call, er
 	var capabilities []*mesos.FrameworkInfo_Capability
 	if d.cfg.GPUSupported {
 		log.Info("GPU capability is supported")
-		gpuSupported := mesos.FrameworkInfo_Capability_GPU_RESOURCES
 		gpuCapability := &mesos.FrameworkInfo_Capability{
-			Type: &gpuSupported,
+			Type: mesos.FrameworkInfo_Capability_GPU_RESOURCES.Enum(),
 		}
 		capabilities = append(capabilities, gpuCapability)
 	}
 
 	if d.cfg.TaskKillingStateSupported {
 		log.Info("Task_Killing_State capability is supported")
-		taskKillingStateSupported := mesos.FrameworkInfo_Capability_TASK_KILLING_STATE
 		taskKillingStateCapability := &mesos.FrameworkInfo_Capability{
-			Type: &taskKillingStateSupported,
+			Type: mesos.FrameworkInfo_Capability_TASK_KILLING_STATE.Enum(),
 		}
 		capabilities = append(capabilities, taskKillingStateCapability)
 	}
 
 	if d.cfg.PartitionAwareSupported {
 		log.Info("Partition Aware capability is supported")
-		partitionAwareSupported := mesos.FrameworkInfo_Capability_PARTITION_AWARE
 		partitionAwareCapability := &mesos.FrameworkInfo_Capability{
-			Type: &partitionAwareSupported,
+			Type: mesos.FrameworkInfo_Capability_PARTITION_AWARE.Enum(),
 		}
 		capabilities = append(capabilities, partitionAwareCapability)
 	}
 
 	if d.cfg.RevocableResourcesSupported {
 		log.Info("Revocable resources capability is supported")
-		revocableResourcesSupported := mesos.FrameworkInfo_Capability_REVOCABLE_RESOURCES
 		revocableResourcesCapability := &mesos.FrameworkInfo_Capability{
-			Type: &revocableResourcesSupported,
+			Type: mesos.FrameworkInfo_Capability_REVOCABLE_RESOURCES.Enum(),
 		}
 		capabilities = append(capabilities, revocableResourcesCapability)
 	}
@@ -213,17 +281,16 @@ func (d *schedulerDriver) prepareSubscribe(ctx context.Context) (*sched.Call, er
 		return nil, errors.Wrap(err, msg)
 	}
 
-	// Peloton has no reason to run as non-checkpoint framework.
-	checkpoint := true
-
+	// The config fields are copied rather than aliased by the framework
+	// info. Peloton has no reason to run as non-checkpoint framework.
 	info := &mesos.FrameworkInfo{
-		User:            &d.cfg.User,
-		Name:            &d.cfg.Name,
-		FailoverTimeout: &d.cfg.FailoverTimeout,
-		Checkpoint:      &checkpoint,
+		User:            util.PtrStr(d.cfg.User),
+		Name:            util.PtrStr(d.cfg.Name),
+		FailoverTimeout: util.PtrFloat64(d.cfg.FailoverTimeout),
+		Checkpoint:      util.PtrBool(true),
 		Capabilities:    capabilities,
-		Hostname:        &host,
-		Principal:       &d.cfg.Principal,
+		Hostname:        util.PtrStr(host),
+		Principal:       util.PtrStr(d.cfg.Principal),
 	}
 
 	// To make peloton consistent, if we are not able to load a valid frameworkId
@@ -232,17 +299,16 @@ func (d *schedulerDriver) prepareSubscribe(ctx context.Context) (*sched.Call, er
 	frameworkID := d.GetFrameworkID(ctx)
 	if v := frameworkID.GetValue(); len(v) == 0 {
 		frameworkID = &mesos.FrameworkID{
-			Value: util.PtrPrintf(pelotonFrameworkID),
+			Value: util.PtrStr(pelotonFrameworkID),
 		}
 	} else if v != pelotonFrameworkID {
 		// TODO: Require consistent framework once all clusters are rebuilt.
 		log.WithField("framework_id", v).Warn("Framework id is not consistent")
 	}
 
-	callType := sched.Call_SUBSCRIBE
 	msg := &sched.Call{
 		FrameworkId: frameworkID,
-		Type:        &callType,
+		Type:        sched.Call_SUBSCRIBE.Enum(),
 		Subscribe:   &sched.Call_Subscribe{FrameworkInfo: info},
 	}
 
@@ -254,7 +320,7 @@ func (d *schedulerDriver) prepareSubscribe(ctx context.Context) (*sched.Call, er
 	}).Info("Reregister to Mesos master with previous framework ID")
 
 	if d.cfg.Role != "" {
-		info.Role = &d.cfg.Role
+		info.Role = util.PtrStr(d.cfg.Role)
 	}
 
 	return msg, nil
@@ -275,15 +341,127 @@ func (d *schedulerDriver) PrepareSubscribeRequest(ctx context.Context, mesosMast
 		return nil, errors.Wrap(err, "Failed prepareSubscribe")
 	}
 
+	// The stream id is only replaced after subscribing if it did not
+	// change in the meantime, i.e. no other host manager subscribed.
+	d.subscribeStreamID = nil
+	if id, err := d.store.GetMesosStreamID(ctx, d.cfg.Name); err == nil {
+		d.subscribeStreamID = util.PtrStr(id)
+	} else {
+		log.WithError(err).
+			WithField("framework_name", d.cfg.Name).
+			Info("Mesos stream ID not loaded before subscribing")
+	}
+
 	body, err := mpb.MarshalPbMessage(subscribe, d.encoding)
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed to marshal subscribe call")
 	}
+	return d.newCallRequest(mesosMasterHostPort, body)
+}
+
+// PrepareSuppressRequest returns a HTTP post request suppressing the offers
+// of roles, or of all the subscribed roles if roles is empty.
+// Implements SchedulerDriver.PrepareSuppressRequest().
+func (d *schedulerDriver) PrepareSuppressRequest(
+	ctx context.Context,
+	mesosMasterHostPort string,
+	roles []string) (*http.Request, error) {
+	return d.prepareCallRequest(ctx, mesosMasterHostPort,
+		func(frameworkID *mesos.FrameworkID) *sched.Call {
+			return mpb.NewSuppressCall(frameworkID, roles)
+		})
+}
+
+// PrepareReviveRequest returns a HTTP post request reviving the offers of
+// roles, or of all the subscribed roles if roles is empty.
+// Implements SchedulerDriver.PrepareReviveRequest().
+func (d *schedulerDriver) PrepareReviveRequest(
+	ctx context.Context,
+	mesosMasterHostPort string,
+	roles []string) (*http.Request, error) {
+	return d.prepareCallRequest(ctx, mesosMasterHostPort,
+		func(frameworkID *mesos.FrameworkID) *sched.Call {
+			return mpb.NewReviveCall(frameworkID, roles)
+		})
+}
+
+// PrepareMessageRequest returns a HTTP post request sending data to an
+// executor of an agent.
+// Implements SchedulerDriver.PrepareMessageRequest().
+func (d *schedulerDriver) PrepareMessageRequest(
+	ctx context.Context,
+	mesosMasterHostPort string,
+	agentID string,
+	executorID string,
+	data []byte) (*http.Request, error) {
+	return d.prepareCallRequest(ctx, mesosMasterHostPort,
+		func(frameworkID *mesos.FrameworkID) *sched.Call {
+			return mpb.NewMessageCall(frameworkID, agentID, executorID, data)
+		})
+}
+
+// PrepareAcceptRequest returns a HTTP post request applying operations to
+// the resources of offerIDs, with the default filters if filters is nil.
+// The call is validated by ValidateAcceptCall before it is prepared.
+// Implements SchedulerDriver.PrepareAcceptRequest().
+func (d *schedulerDriver) PrepareAcceptRequest(
+	ctx context.Context,
+	mesosMasterHostPort string,
+	offerIDs []*mesos.OfferID,
+	operations []*mesos.Offer_Operation,
+	filters *mesos.Filters) (*http.Request, error) {
+	if err := ValidateAcceptCall(offerIDs, operations); err != nil {
+		return nil, err
+	}
+	return d.prepareCallRequest(ctx, mesosMasterHostPort,
+		func(frameworkID *mesos.FrameworkID) *sched.Call {
+			return mpb.NewAcceptCall(frameworkID, offerIDs, operations, filters)
+		})
+}
 
+// prepareCallRequest returns a HTTP post request of the call of newCall
+// for the persisted framework ID. Unlike the subscribe call, it requires the
+// framework ID assigned by Mesos and carries the stream ID of the
+// subscription.
+func (d *schedulerDriver) prepareCallRequest(
+	ctx context.Context,
+	mesosMasterHostPort string,
+	newCall func(*mesos.FrameworkID) *sched.Call) (*http.Request, error) {
+
+	if len(mesosMasterHostPort) == 0 {
+		return nil, errors.New("No active leader detected")
+	}
+
+	frameworkID := d.GetFrameworkID(ctx)
+	if frameworkID.GetValue() == "" {
+		return nil, errors.New("No framework ID found")
+	}
+
+	call := newCall(frameworkID)
+	body, err := mpb.MarshalPbMessage(call, d.encoding)
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to marshal %s call",
+			call.GetType())
+	}
+
+	req, err := d.newCallRequest(mesosMasterHostPort, body)
+	if err != nil {
+		return nil, err
+	}
+	if streamID := d.GetMesosStreamID(ctx); streamID != "" {
+		req.Header.Set("Mesos-Stream-Id", streamID)
+	}
+	return req, nil
+}
+
+// newCallRequest returns a HTTP post request of a marshalled call to the
+// Mesos master, with the default headers and the encoding of the driver.
+func (d *schedulerDriver) newCallRequest(
+	mesosMasterHostPort string,
+	body string) (*http.Request, error) {
 	url := d.Endpoint()
 	url.Host = mesosMasterHostPort
-	var req *http.Request
-	req, err = http.NewRequest("POST", url.String(), strings.NewReader(body))
+	req, err := http.NewRequest("POST", url.String(), strings.NewReader(body))
 	if err != nil {
 		return nil, errors.Wrap(err, "Failed HTTP request")
 	}
@@ -299,10 +477,27 @@ func (d *schedulerDriver) PrepareSubscribeRequest(ctx context.Context, mesosMast
 	return req, nil
 }
 
-// Invoked after the subscription to Mesos is done
+// Invoked after the subscription to Mesos is done. The subscription is
+// aborted if the stream id was replaced by another subscriber since it was
+// prepared.
 // Implements mhttp.MesosDriver.PostSubscribe().
-func (d *schedulerDriver) PostSubscribe(ctx context.Context, mesosStreamID string) {
-	err := d.store.SetMesosStreamID(ctx, d.cfg.Name, mesosStreamID)
+func (d *schedulerDriver) PostSubscribe(ctx context.Context, mesosStreamID string) error {
+	var err error
+	if d.subscribeStreamID != nil {
+		err = d.store.SetMesosStreamIDIfCurrent(
+			ctx, d.cfg.Name, mesosStreamID, *d.subscribeStreamID)
+	} else {
+		err = d.store.SetMesosStreamID(ctx, d.cfg.Name, mesosStreamID)
+	}
+	if storage.IsMesosStreamIDConflict(err) {
+		log.WithError(err).
+			WithFields(log.Fields{
+				"framework_name": d.cfg.Name,
+				"stream_id":      mesosStreamID,
+			}).Warn("Mesos stream ID replaced by another subscriber")
+		return err
+	}
+	d.subscribedStreamID.Store(mesosStreamID)
 	if err != nil {
 		log.WithError(err).
 			WithFields(log.Fields{
@@ -310,6 +505,7 @@ func (d *schedulerDriver) PostSubscribe(ctx context.Context, mesosStreamID strin
 				"stream_id":      mesosStreamID,
 			}).Error("Failed to save Mesos stream ID")
 	}
+	return nil
 }
 
 // GetContentEncoding returns the http content encoding of the Mesos
@@ -15,7 +15,9 @@
 package mesos
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"errors"
 	"io/ioutil"
 	"net/http"
@@ -23,16 +25,17 @@ import (
 	"os"
 	"reflect"
 	"testing"
+	"time"
 
-	"github.com/golang/mock/gomock"
 	log "github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/suite"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
 	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
 
+	"github.com/uber/peloton/pkg/common/util"
 	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
-	storage_mocks "github.com/uber/peloton/pkg/storage/mocks"
+	"github.com/uber/peloton/pkg/storage"
 )
 
 const (
@@ -47,14 +50,12 @@ const (
 type schedulerDriverTestSuite struct {
 	suite.Suite
 
-	ctrl   *gomock.Controller
-	store  *storage_mocks.MockFrameworkInfoStore
+	store  *storage.InMemoryFrameworkInfoStore
 	driver *schedulerDriver
 }
 
 func (suite *schedulerDriverTestSuite) SetupTest() {
-	suite.ctrl = gomock.NewController(suite.T())
-	suite.store = storage_mocks.NewMockFrameworkInfoStore(suite.ctrl)
+	suite.store = storage.NewInMemoryFrameworkInfoStore()
 	suite.driver = InitSchedulerDriver(
 		&Config{
 			Framework: &FrameworkConfig{
@@ -74,7 +75,6 @@ func (suite *schedulerDriverTestSuite) SetupTest() {
 
 func (suite *schedulerDriverTestSuite) TearDownTest() {
 	log.Debug("tearing down")
-	suite.ctrl.Finish()
 }
 
 func (suite *schedulerDriverTestSuite) TestGetAuthHeader() {
@@ -124,39 +124,40 @@ func (suite *schedulerDriverTestSuite) TestGetFrameworkID() {
 	// Before first call, cache is nil.
 	suite.Nil(suite.driver.frameworkID)
 
-	suite.store.EXPECT().
-		GetFrameworkID(context.Background(), gomock.Eq(_frameworkName)).
-		Return(value, nil)
+	suite.NoError(suite.store.SetMesosFrameworkID(
+		context.Background(), _frameworkName, value))
 
 	suite.Equal(frameworkID, suite.driver.GetFrameworkID(context.Background()))
 	suite.Equal(frameworkID, suite.driver.frameworkID)
+
+	// The cached framework ID is returned after the first call.
+	suite.NoError(suite.store.SetMesosFrameworkID(
+		context.Background(), _frameworkName, "other-framework-id"))
 	suite.Equal(frameworkID, suite.driver.GetFrameworkID(context.Background()))
 }
 
 func (suite *schedulerDriverTestSuite) TestGetFrameworkIDError() {
-	err := errors.New("test")
-
-	suite.store.EXPECT().
-		GetFrameworkID(context.Background(), gomock.Eq(_frameworkName)).
-		Return("", err)
-
+	// No framework info stored.
 	suite.Nil(suite.driver.GetFrameworkID(context.Background()))
 
-	suite.store.EXPECT().
-		GetFrameworkID(context.Background(), gomock.Eq(_frameworkName)).
-		Return("", nil)
+	// Only the stream ID is stored, the framework ID is empty.
+	suite.NoError(suite.store.SetMesosStreamID(
+		context.Background(), _frameworkName, _streamID))
+	suite.Nil(suite.driver.GetFrameworkID(context.Background()))
 
+	suite.NoError(suite.store.SetMesosFrameworkID(
+		context.Background(), _frameworkName, _frameworkID))
+	suite.store.SetError(errors.New("test"))
 	suite.Nil(suite.driver.GetFrameworkID(context.Background()))
+	suite.Nil(suite.driver.frameworkID)
 }
 
 func (suite *schedulerDriverTestSuite) TestGetStreamID() {
 	// Before first call, cache is empty.
 	suite.Empty(suite.driver.mesosStreamID)
 
-	suite.store.EXPECT().
-		GetMesosStreamID(context.Background(), _frameworkName).
-		Return(_streamID, nil).
-		Times(2)
+	suite.NoError(suite.store.SetMesosStreamID(
+		context.Background(), _frameworkName, _streamID))
 
 	suite.Equal(_streamID, suite.driver.GetMesosStreamID(context.Background()))
 	suite.Equal(_streamID, suite.driver.mesosStreamID)
@@ -168,14 +169,80 @@ func (suite *schedulerDriverTestSuite) TestGetStreamIDError() {
 	// Before first call, cache is empty.
 	suite.Empty(suite.driver.mesosStreamID)
 
-	suite.store.EXPECT().
-		GetMesosStreamID(context.Background(), _frameworkName).
-		Return("", err)
+	suite.store.SetError(err)
 
 	suite.Empty(suite.driver.GetMesosStreamID(context.Background()))
 	suite.Empty(suite.driver.mesosStreamID)
 }
 
+// timedStreamIDStore is a FrameworkInfoStore whose mesos stream id was set
+// at a fixed time, zero for a stream id persisted without its time
+type timedStreamIDStore struct {
+	*storage.InMemoryFrameworkInfoStore
+	streamIDTime time.Time
+}
+
+func (s *timedStreamIDStore) GetMesosStreamIDWithTime(
+	ctx context.Context,
+	frameworkName string) (string, time.Time, error) {
+	id, err := s.GetMesosStreamID(ctx, frameworkName)
+	return id, s.streamIDTime, err
+}
+
+// TestGetStreamIDStale tests that fresh stream ids are returned, and stale
+// ones are skipped
+func (suite *schedulerDriverTestSuite) TestGetStreamIDStale() {
+	ctx := context.Background()
+	store := &timedStreamIDStore{InMemoryFrameworkInfoStore: suite.store}
+	suite.driver.store = store
+	suite.driver.cfg.MesosStreamIDMaxAge = time.Hour
+	suite.NoError(suite.store.SetMesosStreamID(ctx, _frameworkName, _streamID))
+
+	store.streamIDTime = time.Now().Add(-time.Minute)
+	suite.Equal(_streamID, suite.driver.GetMesosStreamID(ctx))
+
+	store.streamIDTime = time.Now().Add(-2 * time.Hour)
+	suite.Empty(suite.driver.GetMesosStreamID(ctx))
+
+	// stream ids never expire without a max age
+	suite.driver.cfg.MesosStreamIDMaxAge = 0
+	suite.Equal(_streamID, suite.driver.GetMesosStreamID(ctx))
+}
+
+// TestGetStreamIDSubscribed tests that the stream id of the subscription
+// of the driver is never stale, unlike the one of a previous subscription
+func (suite *schedulerDriverTestSuite) TestGetStreamIDSubscribed() {
+	ctx := context.Background()
+	store := &timedStreamIDStore{
+		InMemoryFrameworkInfoStore: suite.store,
+		streamIDTime:               time.Now().Add(-2 * time.Hour),
+	}
+	suite.driver.store = store
+	suite.driver.cfg.MesosStreamIDMaxAge = time.Hour
+	suite.NoError(suite.store.SetMesosStreamID(ctx, _frameworkName, _streamID))
+	suite.Empty(suite.driver.GetMesosStreamID(ctx))
+
+	suite.NoError(suite.driver.PostSubscribe(ctx, _streamID))
+	suite.Equal(_streamID, suite.driver.GetMesosStreamID(ctx))
+
+	suite.NoError(suite.store.SetMesosStreamID(ctx, _frameworkName, "other-stream-id"))
+	suite.Empty(suite.driver.GetMesosStreamID(ctx))
+}
+
+// TestGetStreamIDLegacy tests that stream ids persisted without their time
+// are only skipped once the migration period is over
+func (suite *schedulerDriverTestSuite) TestGetStreamIDLegacy() {
+	ctx := context.Background()
+	suite.driver.store = &timedStreamIDStore{InMemoryFrameworkInfoStore: suite.store}
+	suite.driver.cfg.MesosStreamIDMaxAge = time.Hour
+	suite.NoError(suite.store.SetMesosStreamID(ctx, _frameworkName, _streamID))
+
+	suite.Equal(_streamID, suite.driver.GetMesosStreamID(ctx))
+
+	suite.driver.cfg.LegacyMesosStreamIDStale = true
+	suite.Empty(suite.driver.GetMesosStreamID(ctx))
+}
+
 func (suite *schedulerDriverTestSuite) TestStaticMethods() {
 	suite.Equal(ServiceName, suite.driver.Name())
 
@@ -191,27 +258,50 @@ func (suite *schedulerDriverTestSuite) TestStaticMethods() {
 }
 
 func (suite *schedulerDriverTestSuite) TestPostSubscribe() {
+	suite.NoError(suite.driver.PostSubscribe(context.Background(), _streamID))
+
+	streamID, err := suite.store.GetMesosStreamID(
+		context.Background(), _frameworkName)
+	suite.NoError(err)
+	suite.Equal(_streamID, streamID)
+
+	suite.store.SetError(errors.New("error saving stream id"))
 
-	suite.store.EXPECT().
-		SetMesosStreamID(context.Background(), _frameworkName, _streamID).
-		Return(nil)
+	// failing to save the stream id does not abort the subscription
+	suite.NoError(suite.driver.PostSubscribe(
+		context.Background(), "other-stream-id"))
 
-	suite.driver.PostSubscribe(context.Background(), _streamID)
+	suite.store.SetError(nil)
+	streamID, err = suite.store.GetMesosStreamID(
+		context.Background(), _frameworkName)
+	suite.NoError(err)
+	suite.Equal(_streamID, streamID)
+}
+
+// TestPostSubscribeConflict tests that a subscription is aborted if
+// another subscriber replaced the stream id since it was prepared
+func (suite *schedulerDriverTestSuite) TestPostSubscribeConflict() {
+	ctx := context.Background()
+	suite.NoError(suite.store.SetMesosStreamID(ctx, _frameworkName, _streamID))
+
+	_, err := suite.driver.PrepareSubscribeRequest(ctx, _hostPort)
+	suite.NoError(err)
+	suite.NoError(suite.driver.PostSubscribe(ctx, "new-stream-id"))
 
-	err := errors.New("error saving stream id")
-	suite.store.EXPECT().
-		SetMesosStreamID(context.Background(), _frameworkName, _streamID).
-		Return(err)
+	_, err = suite.driver.PrepareSubscribeRequest(ctx, _hostPort)
+	suite.NoError(err)
+	suite.NoError(suite.store.SetMesosStreamID(ctx, _frameworkName, "other-stream-id"))
+	err = suite.driver.PostSubscribe(ctx, "stale-stream-id")
+	suite.True(storage.IsMesosStreamIDConflict(err))
 
-	// TODO: Do something here.
-	suite.driver.PostSubscribe(context.Background(), _streamID)
+	streamID, err := suite.store.GetMesosStreamID(ctx, _frameworkName)
+	suite.NoError(err)
+	suite.Equal("other-stream-id", streamID)
 }
 
 func (suite *schedulerDriverTestSuite) TestFrameworkInfoCapability() {
-	value := _frameworkID
-	suite.store.EXPECT().
-		GetFrameworkID(context.Background(), gomock.Eq(_frameworkName)).
-		Return(value, nil)
+	suite.NoError(suite.store.SetMesosFrameworkID(
+		context.Background(), _frameworkName, _frameworkID))
 
 	subscribe, err := suite.driver.prepareSubscribe(context.Background())
 	suite.Nil(err)
@@ -233,11 +323,8 @@ func (suite *schedulerDriverTestSuite) TestPrepareLoadedFrameworkID() {
 	suite.Error(err)
 	suite.Nil(req)
 
-	value := _frameworkID
-
-	suite.store.EXPECT().
-		GetFrameworkID(context.Background(), gomock.Eq(_frameworkName)).
-		Return(value, nil)
+	suite.NoError(suite.store.SetMesosFrameworkID(
+		context.Background(), _frameworkName, _frameworkID))
 
 	req, err = suite.driver.PrepareSubscribeRequest(context.Background(), _hostPort)
 	suite.NoError(err)
@@ -257,10 +344,7 @@ func (suite *schedulerDriverTestSuite) TestPrepareFirstTimeRegister() {
 	suite.Error(err)
 	suite.Nil(req)
 
-	suite.store.EXPECT().
-		GetFrameworkID(context.Background(), gomock.Eq(_frameworkName)).
-		Return("", nil)
-
+	// No framework ID is stored on the first registration.
 	req, err = suite.driver.PrepareSubscribeRequest(context.Background(), _hostPort)
 	suite.NoError(err)
 	suite.Equal("POST", req.Method)
@@ -276,6 +360,206 @@ func (suite *schedulerDriverTestSuite) TestPrepareFirstTimeRegister() {
 	suite.Equal(pelotonFrameworkID, pc.GetFrameworkId().GetValue())
 }
 
+// readCall reads the scheduler call of a HTTP request
+func (suite *schedulerDriverTestSuite) readCall(req *http.Request) *sched.Call {
+	body, err := ioutil.ReadAll(req.Body)
+	suite.NoError(err)
+	call := reflect.New(reflect.TypeOf(sched.Call{}))
+	suite.NoError(mpb.UnmarshalPbMessage(body, call, _encoding))
+	return call.Interface().(*sched.Call)
+}
+
+func (suite *schedulerDriverTestSuite) TestPrepareSuppressRequest() {
+	suite.NoError(suite.store.SetMesosFrameworkID(
+		context.Background(), _frameworkName, _frameworkID))
+	suite.NoError(suite.store.SetMesosStreamID(
+		context.Background(), _frameworkName, _streamID))
+
+	req, err := suite.driver.PrepareSuppressRequest(
+		context.Background(), _hostPort, []string{"peloton"})
+	suite.NoError(err)
+	suite.Equal("POST", req.Method)
+	suite.Equal("http://test-host:1234/api/v1/scheduler", req.URL.String())
+	suite.Contains(req.Header["Content-Type"], "application/json")
+	suite.Equal(_streamID, req.Header.Get("Mesos-Stream-Id"))
+
+	call := suite.readCall(req)
+	suite.Equal(sched.Call_SUPPRESS, call.GetType())
+	suite.Equal(_frameworkID, call.GetFrameworkId().GetValue())
+	suite.Equal([]string{"peloton"}, call.GetSuppress().GetRoles())
+	suite.Nil(call.GetRevive())
+}
+
+func (suite *schedulerDriverTestSuite) TestPrepareReviveRequest() {
+	suite.NoError(suite.store.SetMesosFrameworkID(
+		context.Background(), _frameworkName, _frameworkID))
+
+	// No roles revive the offers of all the subscribed roles.
+	req, err := suite.driver.PrepareReviveRequest(
+		context.Background(), _hostPort, nil)
+	suite.NoError(err)
+	suite.Empty(req.Header.Get("Mesos-Stream-Id"))
+
+	call := suite.readCall(req)
+	suite.Equal(sched.Call_REVIVE, call.GetType())
+	suite.Equal(_frameworkID, call.GetFrameworkId().GetValue())
+	suite.NotNil(call.GetRevive())
+	suite.Empty(call.GetRevive().GetRoles())
+	suite.Nil(call.GetSuppress())
+}
+
+func (suite *schedulerDriverTestSuite) TestPrepareOffersRequestError() {
+	// No framework ID is stored, unlike subscribing the persisted one is
+	// required.
+	req, err := suite.driver.PrepareSuppressRequest(
+		context.Background(), _hostPort, nil)
+	suite.EqualError(err, "No framework ID found")
+	suite.Nil(req)
+	req, err = suite.driver.PrepareReviveRequest(
+		context.Background(), _hostPort, nil)
+	suite.EqualError(err, "No framework ID found")
+	suite.Nil(req)
+
+	suite.NoError(suite.store.SetMesosFrameworkID(
+		context.Background(), _frameworkName, _frameworkID))
+	req, err = suite.driver.PrepareSuppressRequest(
+		context.Background(), "", nil)
+	suite.EqualError(err, "No active leader detected")
+	suite.Nil(req)
+}
+
+func (suite *schedulerDriverTestSuite) TestPrepareMessageRequest() {
+	req, err := suite.driver.PrepareMessageRequest(
+		context.Background(), _hostPort, "agent", "executor", []byte("data"))
+	suite.EqualError(err, "No framework ID found")
+	suite.Nil(req)
+
+	suite.NoError(suite.store.SetMesosFrameworkID(
+		context.Background(), _frameworkName, _frameworkID))
+	suite.NoError(suite.store.SetMesosStreamID(
+		context.Background(), _frameworkName, _streamID))
+
+	req, err = suite.driver.PrepareMessageRequest(
+		context.Background(), _hostPort, "agent", "executor", []byte("data"))
+	suite.NoError(err)
+	suite.Equal("POST", req.Method)
+	suite.Equal(_streamID, req.Header.Get("Mesos-Stream-Id"))
+
+	// The JSON encoding carries the data as base64.
+	body, err := ioutil.ReadAll(req.Body)
+	suite.NoError(err)
+	suite.Contains(string(body), base64.StdEncoding.EncodeToString([]byte("data")))
+	req.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	call := suite.readCall(req)
+	suite.Equal(sched.Call_MESSAGE, call.GetType())
+	suite.Equal(_frameworkID, call.GetFrameworkId().GetValue())
+	suite.Equal("agent", call.GetMessage().GetAgentId().GetValue())
+	suite.Equal("executor", call.GetMessage().GetExecutorId().GetValue())
+	suite.Equal([]byte("data"), call.GetMessage().GetData())
+}
+
+func (suite *schedulerDriverTestSuite) TestPrepareAcceptRequest() {
+	offerIDs := []*mesos.OfferID{{Value: util.PtrStr("offer")}}
+	refuseSeconds := 5.0
+	filters := &mesos.Filters{RefuseSeconds: &refuseSeconds}
+
+	suite.NoError(suite.store.SetMesosFrameworkID(
+		context.Background(), _frameworkName, _frameworkID))
+	suite.NoError(suite.store.SetMesosStreamID(
+		context.Background(), _frameworkName, _streamID))
+
+	tests := []struct {
+		name       string
+		hostPort   string
+		offerIDs   []*mesos.OfferID
+		operations []*mesos.Offer_Operation
+		violation  AcceptViolation
+		err        string
+	}{
+		{
+			name:       "launch",
+			hostPort:   _hostPort,
+			offerIDs:   offerIDs,
+			operations: []*mesos.Offer_Operation{testLaunch("")},
+		},
+		{
+			name:     "reserve create launch",
+			hostPort: _hostPort,
+			offerIDs: offerIDs,
+			operations: []*mesos.Offer_Operation{
+				testReserve("l1"), testCreate("l1"), testLaunch("l1"),
+			},
+		},
+		{
+			name:       "no offers",
+			hostPort:   _hostPort,
+			operations: []*mesos.Offer_Operation{testLaunch("")},
+			violation:  AcceptNoOffers,
+		},
+		{
+			name:     "reserve after launch",
+			hostPort: _hostPort,
+			offerIDs: offerIDs,
+			operations: []*mesos.Offer_Operation{
+				testLaunch(""), testReserve("l1"),
+			},
+			violation: AcceptOperationAfterLaunch,
+		},
+		{
+			name:     "launch before its reserve",
+			hostPort: _hostPort,
+			offerIDs: offerIDs,
+			operations: []*mesos.Offer_Operation{
+				testLaunch("l1"), testReserve("l1"),
+			},
+			violation: AcceptReservationNotReserved,
+		},
+		{
+			name:       "no leader",
+			offerIDs:   offerIDs,
+			operations: []*mesos.Offer_Operation{testLaunch("")},
+			err:        "No active leader detected",
+		},
+	}
+
+	for _, tt := range tests {
+		req, err := suite.driver.PrepareAcceptRequest(
+			context.Background(), tt.hostPort, tt.offerIDs, tt.operations,
+			filters)
+		if tt.violation != "" {
+			// An invalid call is rejected before it is prepared.
+			verr, ok := err.(*AcceptValidationError)
+			if suite.True(ok, tt.name) {
+				suite.Equal(tt.violation, verr.Violation, tt.name)
+			}
+			suite.Nil(req, tt.name)
+			continue
+		}
+		if tt.err != "" {
+			suite.EqualError(err, tt.err, tt.name)
+			suite.Nil(req, tt.name)
+			continue
+		}
+
+		suite.NoError(err, tt.name)
+		suite.Equal("POST", req.Method, tt.name)
+		suite.Equal(_streamID, req.Header.Get("Mesos-Stream-Id"), tt.name)
+
+		call := suite.readCall(req)
+		suite.Equal(sched.Call_ACCEPT, call.GetType(), tt.name)
+		suite.Equal(_frameworkID, call.GetFrameworkId().GetValue(), tt.name)
+		suite.Equal(
+			"offer", call.GetAccept().GetOfferIds()[0].GetValue(), tt.name)
+		suite.Len(
+			call.GetAccept().GetOperations(), len(tt.operations), tt.name)
+		suite.Equal(
+			refuseSeconds,
+			call.GetAccept().GetFilters().GetRefuseSeconds(),
+			tt.name)
+	}
+}
+
 func TestSchedulerDriverTestSuite(t *testing.T) {
 	suite.Run(t, new(schedulerDriverTestSuite))
 }
@@ -26,15 +26,18 @@ import (
 	"github.com/uber/peloton/pkg/storage"
 )
 
-// InitManager initializes the mesosManager
+// InitManager initializes the mesosManager. The framework messages of the
+// executors are routed by messageRouter, and only logged if it is nil.
 func InitManager(
 	d *yarpc.Dispatcher,
 	mesosConfig *Config,
-	store storage.FrameworkInfoStore) {
+	store storage.FrameworkInfoStore,
+	messageRouter *MessageRouter) {
 
 	m := mesosManager{
 		store:         store,
 		frameworkName: mesosConfig.Framework.Name,
+		messageRouter: messageRouter,
 	}
 
 	for name, hdl := range getCallbacks(&m) {
@@ -46,6 +49,7 @@ func InitManager(
 type mesosManager struct {
 	store         storage.FrameworkInfoStore
 	frameworkName string
+	messageRouter *MessageRouter
 }
 
 type schedulerEventCallback func(context.Context, *sched.Event) error
@@ -87,6 +91,9 @@ func (m *mesosManager) Subscribed(ctx context.Context, body *sched.Event) error
 func (m *mesosManager) Message(ctx context.Context, body *sched.Event) error {
 	msg := body.GetMessage()
 	log.WithField("msg", msg).Debug("mesosManager: message called")
+	if m.messageRouter != nil {
+		m.messageRouter.Route(ctx, msg)
+	}
 	return nil
 }
 
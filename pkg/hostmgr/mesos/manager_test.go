@@ -20,6 +20,7 @@ import (
 
 	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
 	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
@@ -39,8 +40,9 @@ func (suite *managerTestSuite) SetupTest() {
 	suite.ctrl = gomock.NewController(suite.T())
 	suite.store = storage_mocks.NewMockFrameworkInfoStore(suite.ctrl)
 	suite.manager = &mesosManager{
-		suite.store,
-		_frameworkName,
+		store:         suite.store,
+		frameworkName: _frameworkName,
+		messageRouter: NewMessageRouter(tally.NoopScope),
 	}
 }
 
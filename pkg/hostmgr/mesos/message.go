@@ -0,0 +1,119 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesos
+
+import (
+	"context"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+
+	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
+)
+
+// MessageHandler handles the data of a framework message sent by an
+// executor of an agent. The data is already decoded from the base64 of the
+// JSON encoding.
+type MessageHandler func(
+	ctx context.Context,
+	agentID string,
+	executorID string,
+	data []byte) error
+
+// messageRouterMetrics are the metrics of a MessageRouter
+type messageRouterMetrics struct {
+	routed     tally.Counter
+	unroutable tally.Counter
+	handleFail tally.Counter
+}
+
+// MessageRouter routes the framework messages sent by executors to the
+// handler registered for the longest prefix of their executor ID.
+type MessageRouter struct {
+	sync.RWMutex
+
+	// handlers are the message handlers by executor ID prefix
+	handlers map[string]MessageHandler
+	metrics  *messageRouterMetrics
+}
+
+// NewMessageRouter creates a MessageRouter without handlers
+func NewMessageRouter(scope tally.Scope) *MessageRouter {
+	return &MessageRouter{
+		handlers: make(map[string]MessageHandler),
+		metrics: &messageRouterMetrics{
+			routed:     scope.Counter("framework_message_routed"),
+			unroutable: scope.Counter("framework_message_unroutable"),
+			handleFail: scope.Counter("framework_message_handle_fail"),
+		},
+	}
+}
+
+// Register registers the handler of the messages of the executors whose ID
+// starts with executorIDPrefix, replacing the handler of the same prefix
+// if any.
+func (r *MessageRouter) Register(
+	executorIDPrefix string,
+	handler MessageHandler) {
+	r.Lock()
+	defer r.Unlock()
+	r.handlers[executorIDPrefix] = handler
+}
+
+// Route passes a framework message to its handler. Errors are only logged,
+// as failing the event would end the subscription to the Mesos master.
+func (r *MessageRouter) Route(ctx context.Context, msg *sched.Event_Message) {
+	agentID := msg.GetAgentId().GetValue()
+	executorID := msg.GetExecutorId().GetValue()
+
+	handler := r.handler(executorID)
+	if handler == nil {
+		r.metrics.unroutable.Inc(1)
+		log.WithFields(log.Fields{
+			"agent_id":    agentID,
+			"executor_id": executorID,
+		}).Debug("No handler of framework message")
+		return
+	}
+
+	r.metrics.routed.Inc(1)
+	if err := handler(ctx, agentID, executorID, msg.GetData()); err != nil {
+		r.metrics.handleFail.Inc(1)
+		log.WithError(err).
+			WithFields(log.Fields{
+				"agent_id":    agentID,
+				"executor_id": executorID,
+			}).Warn("Failed to handle framework message")
+	}
+}
+
+// handler returns the handler of the longest prefix of an executor ID, or
+// nil if there is none
+func (r *MessageRouter) handler(executorID string) MessageHandler {
+	r.RLock()
+	defer r.RUnlock()
+
+	var handler MessageHandler
+	longest := -1
+	for prefix, h := range r.handlers {
+		if len(prefix) > longest && strings.HasPrefix(executorID, prefix) {
+			handler = h
+			longest = len(prefix)
+		}
+	}
+	return handler
+}
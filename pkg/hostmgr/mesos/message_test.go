@@ -0,0 +1,142 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesos
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
+
+	"github.com/uber/peloton/pkg/common/util"
+)
+
+// routedMessage is a message received by a test handler
+type routedMessage struct {
+	handler    string
+	agentID    string
+	executorID string
+	data       string
+}
+
+type messageRouterTestSuite struct {
+	suite.Suite
+
+	scope    tally.TestScope
+	router   *MessageRouter
+	messages []routedMessage
+}
+
+func (suite *messageRouterTestSuite) SetupTest() {
+	suite.scope = tally.NewTestScope("", nil)
+	suite.router = NewMessageRouter(suite.scope)
+	suite.messages = nil
+}
+
+func TestMessageRouter(t *testing.T) {
+	suite.Run(t, new(messageRouterTestSuite))
+}
+
+// register registers a handler recording its messages under name
+func (suite *messageRouterTestSuite) register(prefix, name string, err error) {
+	suite.router.Register(prefix, func(
+		ctx context.Context,
+		agentID string,
+		executorID string,
+		data []byte) error {
+		suite.messages = append(suite.messages, routedMessage{
+			handler:    name,
+			agentID:    agentID,
+			executorID: executorID,
+			data:       string(data),
+		})
+		return err
+	})
+}
+
+func newTestMessage(executorID string, data string) *sched.Event_Message {
+	return &sched.Event_Message{
+		AgentId:    &mesos.AgentID{Value: util.PtrStr("agent")},
+		ExecutorId: &mesos.ExecutorID{Value: util.PtrStr(executorID)},
+		Data:       []byte(data),
+	}
+}
+
+func (suite *messageRouterTestSuite) counter(name string) int64 {
+	counter, ok := suite.scope.Snapshot().Counters()[name+"+"]
+	if !ok {
+		return 0
+	}
+	return counter.Value()
+}
+
+// TestRouteLongestPrefix tests that a message goes to the handler of the
+// longest prefix of its executor ID
+func (suite *messageRouterTestSuite) TestRouteLongestPrefix() {
+	suite.register("thermos-", "thermos", nil)
+	suite.register("thermos-peloton-", "peloton", nil)
+	suite.register("", "default", nil)
+
+	suite.router.Route(context.Background(), newTestMessage("thermos-peloton-1", "a"))
+	suite.router.Route(context.Background(), newTestMessage("thermos-other", "b"))
+	suite.router.Route(context.Background(), newTestMessage("custom", "c"))
+
+	suite.Equal([]routedMessage{
+		{"peloton", "agent", "thermos-peloton-1", "a"},
+		{"thermos", "agent", "thermos-other", "b"},
+		{"default", "agent", "custom", "c"},
+	}, suite.messages)
+	suite.Equal(int64(3), suite.counter("framework_message_routed"))
+}
+
+// TestRouteUnroutable tests that the messages without handler are counted
+func (suite *messageRouterTestSuite) TestRouteUnroutable() {
+	suite.register("thermos-", "thermos", nil)
+
+	suite.router.Route(context.Background(), newTestMessage("custom", "a"))
+	suite.Empty(suite.messages)
+	suite.Equal(int64(1), suite.counter("framework_message_unroutable"))
+	suite.Equal(int64(0), suite.counter("framework_message_routed"))
+}
+
+// TestRouteHandlerError tests that handler errors are only counted
+func (suite *messageRouterTestSuite) TestRouteHandlerError() {
+	suite.register("thermos-", "thermos", errors.New("bad message"))
+
+	suite.router.Route(context.Background(), newTestMessage("thermos-1", "a"))
+	suite.Len(suite.messages, 1)
+	suite.Equal(int64(1), suite.counter("framework_message_handle_fail"))
+}
+
+// TestManagerRoutesMessage tests that the MESSAGE events are routed by the
+// manager and never fail the event stream
+func (suite *messageRouterTestSuite) TestManagerRoutesMessage() {
+	suite.register("thermos-", "thermos", errors.New("bad message"))
+	m := &mesosManager{messageRouter: suite.router}
+
+	suite.NoError(m.Message(context.Background(), &sched.Event{
+		Message: newTestMessage("thermos-1", "a"),
+	}))
+	suite.NoError(m.Message(context.Background(), &sched.Event{
+		Message: newTestMessage("custom", "b"),
+	}))
+	suite.Len(suite.messages, 1)
+	suite.Equal(int64(1), suite.counter("framework_message_unroutable"))
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesos
+
+import (
+	"context"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+	"go.uber.org/yarpc"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
+
+	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
+)
+
+// OperationStatusObserver observes the outcome of the offer operations
+// with an ID, such as the reservations and volumes of hostmgr.
+type OperationStatusObserver interface {
+	// OperationStatusUpdated is called for every operation status update
+	// received from Mesos. Updates re-sent while the acknowledgement of the
+	// same update is pending are not observed again, but an update may be
+	// observed again if its acknowledgement failed.
+	OperationStatusUpdated(ctx context.Context, status *mesos.OperationStatus)
+}
+
+// operationStatusMetrics are the metrics of an OperationStatusHandler
+type operationStatusMetrics struct {
+	update      tally.Counter
+	updateDedup tally.Counter
+	ack         tally.Counter
+	ackFail     tally.Counter
+	unacked     tally.Gauge
+}
+
+// OperationStatusHandler handles the UPDATE_OPERATION_STATUS events of
+// Mesos: the updates are passed to the observers, and the reliable ones are
+// acknowledged as Mesos re-sends them until they are.
+type OperationStatusHandler struct {
+	sync.Mutex
+
+	client   mpb.SchedulerClient
+	provider FrameworkInfoProvider
+	metrics  *operationStatusMetrics
+
+	observers []OperationStatusObserver
+	// unacked are the UUIDs of the updates being acknowledged
+	unacked map[string]struct{}
+	// acks tracks the acknowledgements being sent
+	acks sync.WaitGroup
+}
+
+// NewOperationStatusHandler creates an OperationStatusHandler acknowledging
+// the updates with client, for the framework of provider
+func NewOperationStatusHandler(
+	client mpb.SchedulerClient,
+	provider FrameworkInfoProvider,
+	scope tally.Scope) *OperationStatusHandler {
+	return &OperationStatusHandler{
+		client:   client,
+		provider: provider,
+		metrics: &operationStatusMetrics{
+			update:      scope.Counter("operation_status_update"),
+			updateDedup: scope.Counter("operation_status_update_dedup"),
+			ack:         scope.Counter("operation_status_ack"),
+			ackFail:     scope.Counter("operation_status_ack_fail"),
+			unacked:     scope.Gauge("unacked_operation_status"),
+		},
+		unacked: make(map[string]struct{}),
+	}
+}
+
+// Register registers the handler of the UPDATE_OPERATION_STATUS events
+func (h *OperationStatusHandler) Register(d *yarpc.Dispatcher) {
+	mpb.Register(
+		d,
+		ServiceName,
+		mpb.Procedure(sched.Event_UPDATE_OPERATION_STATUS.String(), h.Update))
+}
+
+// AddObserver adds an observer of the operation status updates
+func (h *OperationStatusHandler) AddObserver(observer OperationStatusObserver) {
+	h.Lock()
+	defer h.Unlock()
+	h.observers = append(h.observers, observer)
+}
+
+// Update is the Mesos callback of the operation status updates
+func (h *OperationStatusHandler) Update(
+	ctx context.Context,
+	body *sched.Event) error {
+	status := body.GetUpdateOperationStatus().GetStatus()
+	h.metrics.update.Inc(1)
+
+	// Updates without UUID are not delivered reliably, so they are not
+	// acknowledged.
+	uuid := status.GetUuid().GetValue()
+	if len(uuid) > 0 {
+		h.Lock()
+		if _, ok := h.unacked[string(uuid)]; ok {
+			h.Unlock()
+			h.metrics.updateDedup.Inc(1)
+			return nil
+		}
+		h.unacked[string(uuid)] = struct{}{}
+		h.metrics.unacked.Update(float64(len(h.unacked)))
+		h.Unlock()
+	}
+
+	log.WithFields(log.Fields{
+		"operation_id": status.GetOperationId().GetValue(),
+		"state":        status.GetState().String(),
+	}).Debug("Operation status update received")
+
+	h.Lock()
+	observers := h.observers
+	h.Unlock()
+	for _, observer := range observers {
+		observer.OperationStatusUpdated(ctx, status)
+	}
+
+	if len(uuid) > 0 {
+		h.acks.Add(1)
+		go func() {
+			defer h.acks.Done()
+			h.acknowledge(status)
+		}()
+	}
+
+	// Return nil otherwise the framework would disconnect with the mesos
+	// master
+	return nil
+}
+
+// acknowledge sends the ACKNOWLEDGE_OPERATION_STATUS call of an update. If
+// it fails, Mesos re-sends the update which is acknowledged again.
+func (h *OperationStatusHandler) acknowledge(status *mesos.OperationStatus) {
+	ctx := context.Background()
+	uuid := status.GetUuid().GetValue()
+	err := h.client.Call(
+		h.provider.GetMesosStreamID(ctx),
+		mpb.NewAcknowledgeOperationStatusCall(
+			h.provider.GetFrameworkID(ctx),
+			status.GetOperationId(),
+			uuid))
+
+	h.Lock()
+	delete(h.unacked, string(uuid))
+	h.metrics.unacked.Update(float64(len(h.unacked)))
+	h.Unlock()
+
+	if err != nil {
+		h.metrics.ackFail.Inc(1)
+		log.WithError(err).
+			WithField("operation_id", status.GetOperationId().GetValue()).
+			Warn("Failed to acknowledge operation status update")
+		return
+	}
+	h.metrics.ack.Inc(1)
+}
@@ -0,0 +1,170 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesos
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
+
+	"github.com/uber/peloton/pkg/common/util"
+)
+
+// fakeOperationStatusObserver records the operation IDs of the updates it
+// observes
+type fakeOperationStatusObserver struct {
+	sync.Mutex
+	operations []string
+}
+
+func (o *fakeOperationStatusObserver) OperationStatusUpdated(
+	ctx context.Context,
+	status *mesos.OperationStatus) {
+	o.Lock()
+	defer o.Unlock()
+	o.operations = append(o.operations, status.GetOperationId().GetValue())
+}
+
+type operationStatusHandlerTestSuite struct {
+	suite.Suite
+
+	client   *fakeSchedulerClient
+	scope    tally.TestScope
+	observer *fakeOperationStatusObserver
+	handler  *OperationStatusHandler
+}
+
+func (suite *operationStatusHandlerTestSuite) SetupTest() {
+	suite.client = &fakeSchedulerClient{errs: make(map[string]error)}
+	suite.scope = tally.NewTestScope("", nil)
+	suite.observer = &fakeOperationStatusObserver{}
+	suite.handler = NewOperationStatusHandler(
+		suite.client,
+		fakeFrameworkInfoProvider{},
+		suite.scope)
+	suite.handler.AddObserver(suite.observer)
+}
+
+func TestOperationStatusHandler(t *testing.T) {
+	suite.Run(t, new(operationStatusHandlerTestSuite))
+}
+
+func newTestOperationStatusEvent(
+	operationID string,
+	uuid string) *sched.Event {
+	status := &mesos.OperationStatus{
+		OperationId: &mesos.OperationID{Value: util.PtrStr(operationID)},
+		State:       mesos.OperationState_OPERATION_FINISHED.Enum(),
+	}
+	if uuid != "" {
+		status.Uuid = &mesos.UUID{Value: []byte(uuid)}
+	}
+	return &sched.Event{
+		Type: sched.Event_UPDATE_OPERATION_STATUS.Enum(),
+		UpdateOperationStatus: &sched.Event_UpdateOperationStatus{
+			Status: status,
+		},
+	}
+}
+
+func (suite *operationStatusHandlerTestSuite) counter(name string) int64 {
+	counter, ok := suite.scope.Snapshot().Counters()[name+"+"]
+	if !ok {
+		return 0
+	}
+	return counter.Value()
+}
+
+func (suite *operationStatusHandlerTestSuite) gauge(name string) float64 {
+	return suite.scope.Snapshot().Gauges()[name+"+"].Value()
+}
+
+// TestAcknowledgeUpdate tests that an update is observed and acknowledged
+func (suite *operationStatusHandlerTestSuite) TestAcknowledgeUpdate() {
+	suite.NoError(suite.handler.Update(
+		context.Background(), newTestOperationStatusEvent("op-0", "uuid-0")))
+	suite.handler.acks.Wait()
+
+	suite.Equal([]string{"op-0"}, suite.observer.operations)
+	suite.Equal(1, suite.client.callCount())
+	call := suite.client.calls[0]
+	suite.Equal(sched.Call_ACKNOWLEDGE_OPERATION_STATUS, call.GetType())
+	suite.Equal(_frameworkID, call.GetFrameworkId().GetValue())
+	suite.Equal("op-0",
+		call.GetAcknowledgeOperationStatus().GetOperationId().GetValue())
+	suite.Equal([]byte("uuid-0"), call.GetAcknowledgeOperationStatus().GetUuid())
+	suite.Equal(int64(1), suite.counter("operation_status_ack"))
+	suite.Equal(float64(0), suite.gauge("unacked_operation_status"))
+}
+
+// TestDuplicateUpdates tests that the duplicates of an update received
+// while its acknowledgement is pending are dropped
+func (suite *operationStatusHandlerTestSuite) TestDuplicateUpdates() {
+	suite.client.release = make(chan struct{})
+	for i := 0; i < 3; i++ {
+		suite.NoError(suite.handler.Update(
+			context.Background(), newTestOperationStatusEvent("op-0", "uuid-0")))
+	}
+	suite.Equal(float64(1), suite.gauge("unacked_operation_status"))
+	suite.Equal(int64(2), suite.counter("operation_status_update_dedup"))
+
+	close(suite.client.release)
+	suite.handler.acks.Wait()
+	suite.Equal([]string{"op-0"}, suite.observer.operations)
+	suite.Equal(1, suite.client.callCount())
+	suite.Equal(float64(0), suite.gauge("unacked_operation_status"))
+
+	// once acknowledged, an update re-sent by Mesos is acknowledged again
+	suite.NoError(suite.handler.Update(
+		context.Background(), newTestOperationStatusEvent("op-0", "uuid-0")))
+	suite.handler.acks.Wait()
+	suite.Equal(2, suite.client.callCount())
+}
+
+// TestAcknowledgeFailure tests that a failed acknowledgement lets the
+// update re-sent by Mesos be acknowledged again
+func (suite *operationStatusHandlerTestSuite) TestAcknowledgeFailure() {
+	suite.client.errs["op-0"] = errors.New("mesos unavailable")
+	suite.NoError(suite.handler.Update(
+		context.Background(), newTestOperationStatusEvent("op-0", "uuid-0")))
+	suite.handler.acks.Wait()
+	suite.Equal(int64(1), suite.counter("operation_status_ack_fail"))
+	suite.Equal(float64(0), suite.gauge("unacked_operation_status"))
+
+	delete(suite.client.errs, "op-0")
+	suite.NoError(suite.handler.Update(
+		context.Background(), newTestOperationStatusEvent("op-0", "uuid-0")))
+	suite.handler.acks.Wait()
+	suite.Equal(2, suite.client.callCount())
+	suite.Equal(int64(1), suite.counter("operation_status_ack"))
+	suite.Equal([]string{"op-0", "op-0"}, suite.observer.operations)
+}
+
+// TestUnreliableUpdate tests that the updates without UUID are observed
+// but not acknowledged
+func (suite *operationStatusHandlerTestSuite) TestUnreliableUpdate() {
+	suite.NoError(suite.handler.Update(
+		context.Background(), newTestOperationStatusEvent("op-0", "")))
+	suite.handler.acks.Wait()
+	suite.Equal([]string{"op-0"}, suite.observer.operations)
+	suite.Equal(0, suite.client.callCount())
+}
@@ -0,0 +1,380 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesos
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
+
+	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
+	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/transport/mhttp"
+)
+
+const (
+	_defaultImplicitReconcileInterval = 15 * time.Minute
+	_defaultExplicitReconcileBatch    = 1000
+	_defaultExplicitReconcileDelay    = 30 * time.Second
+	_defaultExplicitReconcileMaxDelay = 10 * time.Minute
+	_defaultExplicitReconcileRounds   = 8
+)
+
+// reconcilerClock is the source of time of the Reconciler, faked by tests
+type reconcilerClock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// systemClock is the reconcilerClock of the system time
+type systemClock struct{}
+
+func (systemClock) Now() time.Time                         { return time.Now() }
+func (systemClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// ReconcilerConfig is the configuration of a Reconciler
+type ReconcilerConfig struct {
+	// ImplicitInterval is the period of implicit reconciliation, which
+	// starts whenever the framework (re)subscribes
+	ImplicitInterval time.Duration `yaml:"implicit_interval"`
+	// ExplicitBatchSize is the maximum number of tasks of an explicit
+	// reconciliation call, as Mesos recommends to limit it
+	ExplicitBatchSize int `yaml:"explicit_batch_size"`
+	// ExplicitDelay is the delay before a task which got no status after
+	// its explicit reconciliation is reconciled again. It doubles for every
+	// further round up to ExplicitMaxDelay.
+	ExplicitDelay    time.Duration `yaml:"explicit_delay"`
+	ExplicitMaxDelay time.Duration `yaml:"explicit_max_delay"`
+	// ExplicitMaxRounds is the number of explicit reconciliations of a task
+	// after which it is given up
+	ExplicitMaxRounds int `yaml:"explicit_max_rounds"`
+}
+
+// reconcilerMetrics are the metrics of a Reconciler
+type reconcilerMetrics struct {
+	implicit          tally.Counter
+	implicitFail      tally.Counter
+	explicitCalls     tally.Counter
+	explicitFail      tally.Counter
+	explicitAbandoned tally.Counter
+	unreconciledTasks tally.Gauge
+}
+
+func newReconcilerMetrics(scope tally.Scope) *reconcilerMetrics {
+	return &reconcilerMetrics{
+		implicit:          scope.Counter("reconcile_implicit"),
+		implicitFail:      scope.Counter("reconcile_implicit_fail"),
+		explicitCalls:     scope.Counter("reconcile_explicit_calls"),
+		explicitFail:      scope.Counter("reconcile_explicit_fail"),
+		explicitAbandoned: scope.Counter("reconcile_explicit_abandoned"),
+		unreconciledTasks: scope.Gauge("unreconciled_tasks"),
+	}
+}
+
+// unreconciledTask is a task explicitly reconciled until it gets a status
+type unreconciledTask struct {
+	task *sched.Call_Reconcile_Task
+	// rounds is the number of explicit reconciliations of the task
+	rounds int
+	// next is when the task is reconciled next
+	next time.Time
+}
+
+var _ mhttp.ConnectionListener = &Reconciler{}
+
+// Reconciler reconciles the tasks of the framework with the Mesos master.
+// It reconciles all the tasks implicitly whenever the framework subscribes
+// and then periodically, and reconciles the given tasks explicitly until
+// they get a status update. It only runs while the framework is connected
+// to the Mesos master.
+type Reconciler struct {
+	sync.Mutex
+
+	client   mpb.SchedulerClient
+	provider FrameworkInfoProvider
+	config   ReconcilerConfig
+	clock    reconcilerClock
+	metrics  *reconcilerMetrics
+
+	connected    bool
+	nextImplicit time.Time
+	// unreconciled are the tasks reconciled explicitly by task ID
+	unreconciled map[string]*unreconciledTask
+
+	// wake makes the run loop check the reconciliations due
+	wake    chan struct{}
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// NewReconciler creates a Reconciler sending the reconciliation calls with
+// client, for the framework of provider, e.g. the scheduler driver. The
+// unset fields of config take their default values. The Reconciler is
+// notified of the connection to the Mesos master as a
+// mhttp.ConnectionListener of the inbound.
+func NewReconciler(
+	client mpb.SchedulerClient,
+	provider FrameworkInfoProvider,
+	config ReconcilerConfig,
+	scope tally.Scope) *Reconciler {
+	return newReconciler(client, provider, config, systemClock{}, scope)
+}
+
+func newReconciler(
+	client mpb.SchedulerClient,
+	provider FrameworkInfoProvider,
+	config ReconcilerConfig,
+	clock reconcilerClock,
+	scope tally.Scope) *Reconciler {
+	if config.ImplicitInterval <= 0 {
+		config.ImplicitInterval = _defaultImplicitReconcileInterval
+	}
+	if config.ExplicitBatchSize <= 0 {
+		config.ExplicitBatchSize = _defaultExplicitReconcileBatch
+	}
+	if config.ExplicitDelay <= 0 {
+		config.ExplicitDelay = _defaultExplicitReconcileDelay
+	}
+	if config.ExplicitMaxDelay <= 0 {
+		config.ExplicitMaxDelay = _defaultExplicitReconcileMaxDelay
+	}
+	if config.ExplicitMaxRounds <= 0 {
+		config.ExplicitMaxRounds = _defaultExplicitReconcileRounds
+	}
+	return &Reconciler{
+		client:       client,
+		provider:     provider,
+		config:       config,
+		clock:        clock,
+		metrics:      newReconcilerMetrics(scope),
+		unreconciled: make(map[string]*unreconciledTask),
+		wake:         make(chan struct{}, 1),
+		stop:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+}
+
+// Start starts the reconciliation loop
+func (r *Reconciler) Start() {
+	go r.run()
+}
+
+// Stop stops the reconciliation loop and waits until it returns
+func (r *Reconciler) Stop() {
+	close(r.stop)
+	<-r.stopped
+}
+
+// Connected starts an implicit reconciliation and resumes the explicit
+// ones after the framework subscribed.
+// Implements mhttp.ConnectionListener.Connected().
+func (r *Reconciler) Connected() {
+	r.Lock()
+	r.connected = true
+	r.nextImplicit = r.clock.Now()
+	r.Unlock()
+	r.wakeUp()
+}
+
+// Disconnected pauses the reconciliation.
+// Implements mhttp.ConnectionListener.Disconnected().
+func (r *Reconciler) Disconnected() {
+	r.Lock()
+	r.connected = false
+	r.Unlock()
+	r.wakeUp()
+}
+
+// ReconcileTasks reconciles tasks explicitly until they get a status
+// update, which is reported by TaskStatusReceived.
+func (r *Reconciler) ReconcileTasks(tasks []*sched.Call_Reconcile_Task) {
+	r.Lock()
+	now := r.clock.Now()
+	for _, task := range tasks {
+		r.unreconciled[task.GetTaskId().GetValue()] = &unreconciledTask{
+			task: task,
+			next: now,
+		}
+	}
+	r.metrics.unreconciledTasks.Update(float64(len(r.unreconciled)))
+	r.Unlock()
+	r.wakeUp()
+}
+
+// TaskStatusReceived stops the explicit reconciliation of a task once it
+// got a status update.
+func (r *Reconciler) TaskStatusReceived(taskID string) {
+	r.Lock()
+	defer r.Unlock()
+	delete(r.unreconciled, taskID)
+	r.metrics.unreconciledTasks.Update(float64(len(r.unreconciled)))
+}
+
+// TaskStatusUpdated reports the task of a status update received from Mesos
+// to TaskStatusReceived, as an observer of the task state manager.
+func (r *Reconciler) TaskStatusUpdated(
+	_ context.Context,
+	status *mesos.TaskStatus) {
+	r.TaskStatusReceived(status.GetTaskId().GetValue())
+}
+
+// wakeUp makes the run loop check the reconciliations due
+func (r *Reconciler) wakeUp() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run sends the reconciliations when they are due until the Reconciler is
+// stopped
+func (r *Reconciler) run() {
+	defer close(r.stopped)
+	for {
+		var timer <-chan time.Time
+		if delay, ok := r.reconcile(); ok {
+			timer = r.clock.After(delay)
+		}
+		select {
+		case <-r.stop:
+			return
+		case <-r.wake:
+		case <-timer:
+		}
+	}
+}
+
+// reconcile sends the reconciliations due and returns the delay until the
+// next one is due. Nothing is sent and nothing is due while disconnected.
+func (r *Reconciler) reconcile() (time.Duration, bool) {
+	r.Lock()
+	if !r.connected {
+		r.Unlock()
+		return 0, false
+	}
+	now := r.clock.Now()
+	implicit := !now.Before(r.nextImplicit)
+	var due []*sched.Call_Reconcile_Task
+	for id, t := range r.unreconciled {
+		if now.Before(t.next) {
+			continue
+		}
+		if t.rounds >= r.config.ExplicitMaxRounds {
+			delete(r.unreconciled, id)
+			r.metrics.explicitAbandoned.Inc(1)
+			log.WithFields(log.Fields{
+				"task_id":  id,
+				"agent_id": t.task.GetAgentId().GetValue(),
+				"rounds":   t.rounds,
+			}).Warn("Task got no status after explicit reconciliation")
+			continue
+		}
+		due = append(due, t.task)
+	}
+	r.Unlock()
+
+	// tasks are reconciled in a stable order across rounds
+	sort.Slice(due, func(i, j int) bool {
+		return due[i].GetTaskId().GetValue() < due[j].GetTaskId().GetValue()
+	})
+
+	ctx := context.Background()
+	frameworkID := r.provider.GetFrameworkID(ctx)
+	streamID := r.provider.GetMesosStreamID(ctx)
+
+	if implicit {
+		if err := r.client.Call(
+			streamID, mpb.NewReconcileCall(frameworkID, nil)); err != nil {
+			r.metrics.implicitFail.Inc(1)
+			log.WithError(err).Warn("Failed to reconcile tasks implicitly")
+		} else {
+			r.metrics.implicit.Inc(1)
+		}
+	}
+
+	var failed []*sched.Call_Reconcile_Task
+	for start := 0; start < len(due); start += r.config.ExplicitBatchSize {
+		end := start + r.config.ExplicitBatchSize
+		if end > len(due) {
+			end = len(due)
+		}
+		batch := due[start:end]
+		r.metrics.explicitCalls.Inc(1)
+		if err := r.client.Call(
+			streamID, mpb.NewReconcileCall(frameworkID, batch)); err != nil {
+			r.metrics.explicitFail.Inc(1)
+			log.WithError(err).
+				WithField("tasks", len(batch)).
+				Warn("Failed to reconcile tasks explicitly")
+			failed = append(failed, batch...)
+		}
+	}
+
+	r.Lock()
+	defer r.Unlock()
+	if implicit {
+		r.nextImplicit = now.Add(r.config.ImplicitInterval)
+	}
+	r.scheduleExplicit(now, due, failed)
+	r.metrics.unreconciledTasks.Update(float64(len(r.unreconciled)))
+
+	if !r.connected {
+		return 0, false
+	}
+	next := r.nextImplicit
+	for _, t := range r.unreconciled {
+		if t.next.Before(next) {
+			next = t.next
+		}
+	}
+	return next.Sub(now), true
+}
+
+// scheduleExplicit schedules the next explicit reconciliation of the due
+// tasks which are still unreconciled: the tasks of failed calls are retried
+// after the initial delay, and the other ones after a delay doubling with
+// every round. It is called with the lock held.
+func (r *Reconciler) scheduleExplicit(
+	now time.Time,
+	due []*sched.Call_Reconcile_Task,
+	failed []*sched.Call_Reconcile_Task) {
+	isFailed := make(map[*sched.Call_Reconcile_Task]bool, len(failed))
+	for _, task := range failed {
+		isFailed[task] = true
+	}
+	for _, task := range due {
+		t, ok := r.unreconciled[task.GetTaskId().GetValue()]
+		if !ok || t.task != task {
+			// got a status, or was given again, in the meantime
+			continue
+		}
+		if isFailed[task] {
+			t.next = now.Add(r.config.ExplicitDelay)
+			continue
+		}
+		t.rounds++
+		delay := r.config.ExplicitDelay << uint(t.rounds-1)
+		if delay > r.config.ExplicitMaxDelay || delay <= 0 {
+			delay = r.config.ExplicitMaxDelay
+		}
+		t.next = now.Add(delay)
+	}
+}
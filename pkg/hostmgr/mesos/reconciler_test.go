@@ -0,0 +1,269 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mesos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
+
+	"github.com/uber/peloton/pkg/common/util"
+)
+
+// fakeClock is a reconcilerClock whose time only moves when advanced
+type fakeClock struct {
+	sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	// the reconciliation loop is driven by the tests
+	return nil
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+	c.now = c.now.Add(d)
+}
+
+type reconcilerTestSuite struct {
+	suite.Suite
+
+	client     *fakeSchedulerClient
+	clock      *fakeClock
+	scope      tally.TestScope
+	reconciler *Reconciler
+}
+
+func (suite *reconcilerTestSuite) SetupTest() {
+	suite.client = &fakeSchedulerClient{errs: make(map[string]error)}
+	suite.clock = &fakeClock{now: time.Unix(1500000000, 0)}
+	suite.scope = tally.NewTestScope("", nil)
+	suite.reconciler = newReconciler(
+		suite.client,
+		fakeFrameworkInfoProvider{},
+		ReconcilerConfig{
+			ImplicitInterval:  time.Hour,
+			ExplicitBatchSize: 2,
+			ExplicitDelay:     10 * time.Second,
+			ExplicitMaxDelay:  25 * time.Second,
+			ExplicitMaxRounds: 3,
+		},
+		suite.clock,
+		suite.scope)
+}
+
+func TestReconciler(t *testing.T) {
+	suite.Run(t, new(reconcilerTestSuite))
+}
+
+func newTestReconcileTasks(count int) []*sched.Call_Reconcile_Task {
+	var tasks []*sched.Call_Reconcile_Task
+	for i := 0; i < count; i++ {
+		tasks = append(tasks, &sched.Call_Reconcile_Task{
+			TaskId:  &mesos.TaskID{Value: util.PtrStr(fmt.Sprintf("task-%d", i))},
+			AgentId: &mesos.AgentID{Value: util.PtrStr("agent")},
+		})
+	}
+	return tasks
+}
+
+// takeCalls returns the task IDs of the calls sent since the last time,
+// with nil for an implicit reconciliation
+func (suite *reconcilerTestSuite) takeCalls() [][]string {
+	suite.client.Lock()
+	defer suite.client.Unlock()
+
+	var calls [][]string
+	for _, call := range suite.client.calls {
+		suite.Equal(sched.Call_RECONCILE, call.GetType())
+		suite.Equal(_frameworkID, call.GetFrameworkId().GetValue())
+		var ids []string
+		for _, task := range call.GetReconcile().GetTasks() {
+			ids = append(ids, task.GetTaskId().GetValue())
+		}
+		calls = append(calls, ids)
+	}
+	suite.client.calls = nil
+	return calls
+}
+
+func (suite *reconcilerTestSuite) gauge(name string) float64 {
+	return suite.scope.Snapshot().Gauges()[name+"+"].Value()
+}
+
+func (suite *reconcilerTestSuite) counter(name string) int64 {
+	counter, ok := suite.scope.Snapshot().Counters()[name+"+"]
+	if !ok {
+		return 0
+	}
+	return counter.Value()
+}
+
+// TestPeriodicImplicitReconcile tests that all the tasks are reconciled
+// implicitly after subscribing and then on every period
+func (suite *reconcilerTestSuite) TestPeriodicImplicitReconcile() {
+	suite.reconciler.Connected()
+	delay, ok := suite.reconciler.reconcile()
+	suite.True(ok)
+	suite.Equal(time.Hour, delay)
+	suite.Equal([][]string{nil}, suite.takeCalls())
+
+	suite.clock.Advance(30 * time.Minute)
+	delay, ok = suite.reconciler.reconcile()
+	suite.True(ok)
+	suite.Equal(30*time.Minute, delay)
+	suite.Empty(suite.takeCalls())
+
+	suite.clock.Advance(30 * time.Minute)
+	delay, _ = suite.reconciler.reconcile()
+	suite.Equal(time.Hour, delay)
+	suite.Equal([][]string{nil}, suite.takeCalls())
+	suite.Equal(int64(2), suite.counter("reconcile_implicit"))
+
+	// subscribing again reconciles right away
+	suite.clock.Advance(time.Minute)
+	suite.reconciler.Connected()
+	suite.reconciler.reconcile()
+	suite.Equal([][]string{nil}, suite.takeCalls())
+}
+
+// TestPauseResume tests that nothing is reconciled while disconnected, and
+// that the reconciliation resumes once connected again
+func (suite *reconcilerTestSuite) TestPauseResume() {
+	suite.reconciler.Connected()
+	suite.reconciler.reconcile()
+	suite.takeCalls()
+
+	suite.reconciler.Disconnected()
+	suite.reconciler.ReconcileTasks(newTestReconcileTasks(1))
+	suite.clock.Advance(2 * time.Hour)
+	_, ok := suite.reconciler.reconcile()
+	suite.False(ok)
+	suite.Empty(suite.takeCalls())
+
+	suite.reconciler.Connected()
+	_, ok = suite.reconciler.reconcile()
+	suite.True(ok)
+	suite.Equal([][]string{nil, {"task-0"}}, suite.takeCalls())
+}
+
+// TestExplicitReconcileChunking tests that explicit reconciliation calls
+// hold at most ExplicitBatchSize tasks
+func (suite *reconcilerTestSuite) TestExplicitReconcileChunking() {
+	suite.reconciler.Connected()
+	suite.reconciler.ReconcileTasks(newTestReconcileTasks(5))
+	suite.Equal(float64(5), suite.gauge("unreconciled_tasks"))
+
+	suite.reconciler.reconcile()
+	suite.Equal([][]string{
+		nil,
+		{"task-0", "task-1"},
+		{"task-2", "task-3"},
+		{"task-4"},
+	}, suite.takeCalls())
+	suite.Equal(int64(3), suite.counter("reconcile_explicit_calls"))
+}
+
+// TestExplicitReconcileFollowUps tests that the tasks without status are
+// reconciled again with exponential spacing until they run out of rounds
+func (suite *reconcilerTestSuite) TestExplicitReconcileFollowUps() {
+	suite.reconciler.Connected()
+	suite.reconciler.ReconcileTasks(newTestReconcileTasks(2))
+	delay, _ := suite.reconciler.reconcile()
+	suite.Equal(10*time.Second, delay)
+	suite.Equal([][]string{nil, {"task-0", "task-1"}}, suite.takeCalls())
+
+	// task-0 got a status, task-1 is reconciled again
+	suite.reconciler.TaskStatusReceived("task-0")
+	suite.Equal(float64(1), suite.gauge("unreconciled_tasks"))
+	suite.clock.Advance(10 * time.Second)
+	delay, _ = suite.reconciler.reconcile()
+	suite.Equal(20*time.Second, delay)
+	suite.Equal([][]string{{"task-1"}}, suite.takeCalls())
+
+	// the delay is capped
+	suite.clock.Advance(20 * time.Second)
+	delay, _ = suite.reconciler.reconcile()
+	suite.Equal(25*time.Second, delay)
+	suite.Equal([][]string{{"task-1"}}, suite.takeCalls())
+
+	// task-1 is given up after its third round
+	suite.clock.Advance(25 * time.Second)
+	suite.reconciler.reconcile()
+	suite.Empty(suite.takeCalls())
+	suite.Equal(float64(0), suite.gauge("unreconciled_tasks"))
+	suite.Equal(int64(1), suite.counter("reconcile_explicit_abandoned"))
+}
+
+// TestExplicitReconcileFailure tests that the tasks of failed calls are
+// retried without using up their rounds
+func (suite *reconcilerTestSuite) TestExplicitReconcileFailure() {
+	suite.client.errs[""] = errors.New("mesos unavailable")
+	suite.reconciler.Connected()
+	suite.reconciler.ReconcileTasks(newTestReconcileTasks(1))
+
+	for i := 0; i < 5; i++ {
+		delay, _ := suite.reconciler.reconcile()
+		suite.Equal(10*time.Second, delay)
+		suite.clock.Advance(10 * time.Second)
+	}
+	suite.Equal(int64(5), suite.counter("reconcile_explicit_fail"))
+	suite.Equal(float64(1), suite.gauge("unreconciled_tasks"))
+	suite.Equal(int64(0), suite.counter("reconcile_explicit_abandoned"))
+}
+
+// TestRunLoop tests that the reconciliation loop reconciles once connected
+func (suite *reconcilerTestSuite) TestRunLoop() {
+	suite.reconciler.Start()
+	defer suite.reconciler.Stop()
+
+	suite.reconciler.Connected()
+	suite.reconciler.ReconcileTasks(newTestReconcileTasks(1))
+	deadline := time.Now().Add(5 * time.Second)
+	var calls [][]string
+	for len(calls) < 2 && time.Now().Before(deadline) {
+		calls = append(calls, suite.takeCalls()...)
+		time.Sleep(time.Millisecond)
+	}
+	suite.Equal([][]string{nil, {"task-0"}}, calls)
+}
+
+// TestTaskStatusUpdated tests that a status update received by the task
+// state manager stops the explicit reconciliation of its task
+func (suite *reconcilerTestSuite) TestTaskStatusUpdated() {
+	tasks := newTestReconcileTasks(2)
+	suite.reconciler.ReconcileTasks(tasks)
+	suite.reconciler.TaskStatusUpdated(
+		context.Background(),
+		&mesos.TaskStatus{TaskId: tasks[1].GetTaskId()})
+	suite.Equal(float64(1), suite.gauge("unreconciled_tasks"))
+}
@@ -22,6 +22,7 @@ import (
 	"go.uber.org/yarpc/api/transport"
 	"golang.org/x/net/context"
 
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
 	"github.com/uber/peloton/.gen/mesos/v1/scheduler"
 )
 
@@ -30,6 +31,193 @@ type SchedulerClient interface {
 	// Call performs an outbound Mesos JSON request.
 	// Returns an error if the request failed.
 	Call(mesosStreamID string, msg *mesos_v1_scheduler.Call) error
+
+	// Suppress asks Mesos to stop sending offers for roles, or for all the
+	// roles the framework is subscribed to if roles is empty.
+	Suppress(
+		mesosStreamID string,
+		frameworkID *mesos.FrameworkID,
+		roles []string) error
+
+	// Revive asks Mesos to send offers for roles again, or for all the
+	// roles the framework is subscribed to if roles is empty.
+	Revive(
+		mesosStreamID string,
+		frameworkID *mesos.FrameworkID,
+		roles []string) error
+}
+
+// NewAcknowledgeCall returns the ACKNOWLEDGE call of a framework for the
+// status update of a task with uuid
+func NewAcknowledgeCall(
+	frameworkID *mesos.FrameworkID,
+	agentID *mesos.AgentID,
+	taskID *mesos.TaskID,
+	uuid []byte) *mesos_v1_scheduler.Call {
+	return &mesos_v1_scheduler.Call{
+		FrameworkId: frameworkID,
+		Type:        mesos_v1_scheduler.Call_ACKNOWLEDGE.Enum(),
+		Acknowledge: &mesos_v1_scheduler.Call_Acknowledge{
+			AgentId: agentID,
+			TaskId:  taskID,
+			Uuid:    uuid,
+		},
+	}
+}
+
+// NewAcknowledgeOperationStatusCall returns the ACKNOWLEDGE_OPERATION_STATUS
+// call of a framework for the status update of an operation identified by
+// uuid
+func NewAcknowledgeOperationStatusCall(
+	frameworkID *mesos.FrameworkID,
+	operationID *mesos.OperationID,
+	uuid []byte) *mesos_v1_scheduler.Call {
+	return &mesos_v1_scheduler.Call{
+		FrameworkId: frameworkID,
+		Type:        mesos_v1_scheduler.Call_ACKNOWLEDGE_OPERATION_STATUS.Enum(),
+		AcknowledgeOperationStatus: &mesos_v1_scheduler.Call_AcknowledgeOperationStatus{
+			OperationId: operationID,
+			Uuid:        uuid,
+		},
+	}
+}
+
+// NewAcceptCall returns the ACCEPT call of a framework applying operations
+// to the resources of offerIDs, with the default filters if filters is nil
+func NewAcceptCall(
+	frameworkID *mesos.FrameworkID,
+	offerIDs []*mesos.OfferID,
+	operations []*mesos.Offer_Operation,
+	filters *mesos.Filters) *mesos_v1_scheduler.Call {
+	return &mesos_v1_scheduler.Call{
+		FrameworkId: frameworkID,
+		Type:        mesos_v1_scheduler.Call_ACCEPT.Enum(),
+		Accept: &mesos_v1_scheduler.Call_Accept{
+			OfferIds:   offerIDs,
+			Operations: operations,
+			Filters:    filters,
+		},
+	}
+}
+
+// NewDeclineCall returns the DECLINE call of a framework for offerIDs, whose
+// resources are not offered again for refuseSeconds
+func NewDeclineCall(
+	frameworkID *mesos.FrameworkID,
+	offerIDs []*mesos.OfferID,
+	refuseSeconds float64) *mesos_v1_scheduler.Call {
+	return &mesos_v1_scheduler.Call{
+		FrameworkId: frameworkID,
+		Type:        mesos_v1_scheduler.Call_DECLINE.Enum(),
+		Decline: &mesos_v1_scheduler.Call_Decline{
+			OfferIds: offerIDs,
+			Filters:  &mesos.Filters{RefuseSeconds: &refuseSeconds},
+		},
+	}
+}
+
+// NewDeclineInverseOffersCall returns the DECLINE_INVERSE_OFFERS call of a
+// framework for inverseOfferIDs, which are not offered again for
+// refuseSeconds
+func NewDeclineInverseOffersCall(
+	frameworkID *mesos.FrameworkID,
+	inverseOfferIDs []*mesos.OfferID,
+	refuseSeconds float64) *mesos_v1_scheduler.Call {
+	return &mesos_v1_scheduler.Call{
+		FrameworkId: frameworkID,
+		Type:        mesos_v1_scheduler.Call_DECLINE_INVERSE_OFFERS.Enum(),
+		DeclineInverseOffers: &mesos_v1_scheduler.Call_DeclineInverseOffers{
+			InverseOfferIds: inverseOfferIDs,
+			Filters:         &mesos.Filters{RefuseSeconds: &refuseSeconds},
+		},
+	}
+}
+
+// NewKillCall returns the KILL call of a framework for a task, running on
+// an agent unless agentID is nil
+func NewKillCall(
+	frameworkID *mesos.FrameworkID,
+	taskID *mesos.TaskID,
+	agentID *mesos.AgentID) *mesos_v1_scheduler.Call {
+	return &mesos_v1_scheduler.Call{
+		FrameworkId: frameworkID,
+		Type:        mesos_v1_scheduler.Call_KILL.Enum(),
+		Kill: &mesos_v1_scheduler.Call_Kill{
+			TaskId:  taskID,
+			AgentId: agentID,
+		},
+	}
+}
+
+// NewShutdownCall returns the SHUTDOWN call of a framework for an executor
+// of an agent
+func NewShutdownCall(
+	frameworkID *mesos.FrameworkID,
+	executorID *mesos.ExecutorID,
+	agentID *mesos.AgentID) *mesos_v1_scheduler.Call {
+	return &mesos_v1_scheduler.Call{
+		FrameworkId: frameworkID,
+		Type:        mesos_v1_scheduler.Call_SHUTDOWN.Enum(),
+		Shutdown: &mesos_v1_scheduler.Call_Shutdown{
+			ExecutorId: executorID,
+			AgentId:    agentID,
+		},
+	}
+}
+
+// NewReconcileCall returns the RECONCILE call of a framework for tasks, or
+// for all its tasks if tasks is empty
+func NewReconcileCall(
+	frameworkID *mesos.FrameworkID,
+	tasks []*mesos_v1_scheduler.Call_Reconcile_Task) *mesos_v1_scheduler.Call {
+	return &mesos_v1_scheduler.Call{
+		FrameworkId: frameworkID,
+		Type:        mesos_v1_scheduler.Call_RECONCILE.Enum(),
+		Reconcile:   &mesos_v1_scheduler.Call_Reconcile{Tasks: tasks},
+	}
+}
+
+// NewMessageCall returns the MESSAGE call of a framework sending data to an
+// executor of an agent. The data is sent as is, it is only base64 encoded
+// by the JSON encoding.
+func NewMessageCall(
+	frameworkID *mesos.FrameworkID,
+	agentID string,
+	executorID string,
+	data []byte) *mesos_v1_scheduler.Call {
+	return &mesos_v1_scheduler.Call{
+		FrameworkId: frameworkID,
+		Type:        mesos_v1_scheduler.Call_MESSAGE.Enum(),
+		Message: &mesos_v1_scheduler.Call_Message{
+			AgentId:    &mesos.AgentID{Value: &agentID},
+			ExecutorId: &mesos.ExecutorID{Value: &executorID},
+			Data:       data,
+		},
+	}
+}
+
+// NewSuppressCall returns the SUPPRESS call of a framework for roles, or
+// for all its subscribed roles if roles is empty.
+func NewSuppressCall(
+	frameworkID *mesos.FrameworkID,
+	roles []string) *mesos_v1_scheduler.Call {
+	return &mesos_v1_scheduler.Call{
+		FrameworkId: frameworkID,
+		Type:        mesos_v1_scheduler.Call_SUPPRESS.Enum(),
+		Suppress:    &mesos_v1_scheduler.Call_Suppress{Roles: roles},
+	}
+}
+
+// NewReviveCall returns the REVIVE call of a framework for roles, or for
+// all its subscribed roles if roles is empty.
+func NewReviveCall(
+	frameworkID *mesos.FrameworkID,
+	roles []string) *mesos_v1_scheduler.Call {
+	return &mesos_v1_scheduler.Call{
+		FrameworkId: frameworkID,
+		Type:        mesos_v1_scheduler.Call_REVIVE.Enum(),
+		Revive:      &mesos_v1_scheduler.Call_Revive{Roles: roles},
+	}
 }
 
 // NewSchedulerClient builds a new Mesos Scheduler JSON client.
@@ -75,3 +263,23 @@ func (c *schedulerClient) Call(mesosStreamID string, msg *mesos_v1_scheduler.Cal
 	// All Mesos calls are one-way so no need to decode response body
 	return err
 }
+
+func (c *schedulerClient) Suppress(
+	mesosStreamID string,
+	frameworkID *mesos.FrameworkID,
+	roles []string) error {
+	if frameworkID.GetValue() == "" {
+		return fmt.Errorf("no framework ID to suppress offers")
+	}
+	return c.Call(mesosStreamID, NewSuppressCall(frameworkID, roles))
+}
+
+func (c *schedulerClient) Revive(
+	mesosStreamID string,
+	frameworkID *mesos.FrameworkID,
+	roles []string) error {
+	if frameworkID.GetValue() == "" {
+		return fmt.Errorf("no framework ID to revive offers")
+	}
+	return c.Call(mesosStreamID, NewReviveCall(frameworkID, roles))
+}
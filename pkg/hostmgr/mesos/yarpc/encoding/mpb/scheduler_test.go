@@ -16,6 +16,7 @@ package mpb
 
 import (
 	"bytes"
+	"context"
 	"io/ioutil"
 	"testing"
 
@@ -25,6 +26,7 @@ import (
 	"go.uber.org/yarpc/api/transport"
 	transport_mocks "go.uber.org/yarpc/api/transport/transporttest"
 
+	"github.com/gogo/protobuf/proto"
 	"github.com/golang/mock/gomock"
 	"github.com/pborman/uuid"
 	"github.com/pkg/errors"
@@ -164,6 +166,56 @@ func (suite *schedulerClientTestSuite) TestSchedulerClient_Call() {
 	}
 }
 
+// expectCall expects a call to be sent and returns the call it receives
+func (suite *schedulerClientTestSuite) expectCall() *sched.Call {
+	mockUnaryOutbound := transport_mocks.NewMockUnaryOutbound(suite.ctrl)
+	call := &sched.Call{}
+	gomock.InOrder(
+		suite.mockClientCfg.EXPECT().Caller().Return("testCall"),
+		suite.mockClientCfg.EXPECT().Service().Return("testSvc"),
+		suite.mockClientCfg.EXPECT().GetUnaryOutbound().Return(
+			mockUnaryOutbound,
+		),
+		mockUnaryOutbound.EXPECT().Call(gomock.Any(), gomock.Any()).
+			Do(func(_ context.Context, req *transport.Request) {
+				body, err := ioutil.ReadAll(req.Body)
+				suite.NoError(err)
+				suite.NoError(proto.Unmarshal(body, call))
+			}).
+			Return(&transport.Response{}, nil),
+	)
+	return call
+}
+
+func (suite *schedulerClientTestSuite) TestSchedulerClient_Suppress() {
+	frameworkID := &mesos.FrameworkID{Value: proto.String("framework-id")}
+
+	call := suite.expectCall()
+	schedClient := NewSchedulerClient(suite.mockClientCfg, suite.defaultEncoding)
+	suite.NoError(schedClient.Suppress("123", frameworkID, []string{"peloton"}))
+	suite.Equal(sched.Call_SUPPRESS, call.GetType())
+	suite.Equal("framework-id", call.GetFrameworkId().GetValue())
+	suite.Equal([]string{"peloton"}, call.GetSuppress().GetRoles())
+
+	suite.EqualError(schedClient.Suppress("123", nil, nil),
+		"no framework ID to suppress offers")
+}
+
+func (suite *schedulerClientTestSuite) TestSchedulerClient_Revive() {
+	frameworkID := &mesos.FrameworkID{Value: proto.String("framework-id")}
+
+	call := suite.expectCall()
+	schedClient := NewSchedulerClient(suite.mockClientCfg, suite.defaultEncoding)
+	suite.NoError(schedClient.Revive("123", frameworkID, nil))
+	suite.Equal(sched.Call_REVIVE, call.GetType())
+	suite.Equal("framework-id", call.GetFrameworkId().GetValue())
+	suite.NotNil(call.GetRevive())
+	suite.Empty(call.GetRevive().GetRoles())
+
+	suite.EqualError(schedClient.Revive("123", nil, nil),
+		"no framework ID to revive offers")
+}
+
 func TestSchedulerClientTestSuite(t *testing.T) {
 	suite.Run(t, new(schedulerClientTestSuite))
 }
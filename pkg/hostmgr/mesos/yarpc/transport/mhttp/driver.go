@@ -38,8 +38,9 @@ type MesosDriver interface {
 	// setting up an event stream connection
 	PrepareSubscribeRequest(ctx context.Context, mesosMasterHostPort string) (*http.Request, error)
 
-	// Invoked after the subscription to Mesos is done
-	PostSubscribe(ctx context.Context, mesosStreamID string)
+	// Invoked after the subscription to Mesos is done, the subscription is
+	// aborted if it returns an error
+	PostSubscribe(ctx context.Context, mesosStreamID string) error
 
 	// GetContentEncoding returns the http content encoding of the Mesos
 	// HTTP traffic
@@ -51,11 +51,28 @@ type Inbound interface {
 	transport.Inbound
 
 	StartMesosLoop(ctx context.Context, newHostPort string) (chan error, error)
+
+	// AddConnectionListener notifies listener of the changes of the
+	// subscription of the inbound. It must be called before the Mesos loop
+	// is started.
+	AddConnectionListener(listener ConnectionListener)
 }
 
 // InboundOption is an option for an Mesos HTTP inbound.
 type InboundOption func(*inbound)
 
+// ConnectionListener is notified of the changes of the subscription of an
+// inbound to the Mesos master.
+type ConnectionListener interface {
+	// Connected is called once the inbound subscribed to the Mesos master
+	// and processes its events
+	Connected()
+
+	// Disconnected is called once the inbound stopped processing the events
+	// of the Mesos master
+	Disconnected()
+}
+
 // NewInbound builds a new Mesos HTTP inbound after registering with
 // Mesos master via Subscribe message
 func NewInbound(parent tally.Scope, d MesosDriver, opts ...InboundOption) Inbound {
@@ -81,6 +98,7 @@ type inbound struct {
 	client       *http.Client
 	runningState atomic.Bool
 	ticker       *time.Ticker
+	listeners    []ConnectionListener
 }
 
 // Start would initialize some variables, actual mesos communication would be
@@ -96,6 +114,13 @@ func (i *inbound) Start() error {
 	return nil
 }
 
+// AddConnectionListener implements Inbound.AddConnectionListener.
+func (i *inbound) AddConnectionListener(listener ConnectionListener) {
+	i.Lock()
+	defer i.Unlock()
+	i.listeners = append(i.listeners, listener)
+}
+
 // StartMesosLoop subscribes to mesos master as a framework, and starts a
 // go-routine to dispatch the mesos callbacks.
 // The call can be called multiple times to start/stop talking to Mesos master,
@@ -158,7 +183,11 @@ func (i *inbound) StartMesosLoop(ctx context.Context, hostPort string) (chan err
 			"Failed to obtain stream id from values: %v",
 			values)
 	}
-	i.driver.PostSubscribe(ctx, values[0])
+	if err := i.driver.PostSubscribe(ctx, values[0]); err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf(
+			"Failed to PostSubscribe: %v", err)
+	}
 
 	started := make(chan interface{}, 1)
 	end := make(chan error, 1)
@@ -175,6 +204,7 @@ func (i *inbound) processUntilEnd(
 	started chan interface{},
 	resp *http.Response) error {
 
+	defer i.notifyDisconnected()
 	defer i.runningState.Store(false)
 	defer i.metrics.Running.Update(0)
 	defer resp.Body.Close()
@@ -189,6 +219,9 @@ func (i *inbound) processUntilEnd(
 
 	i.runningState.Store(true)
 	i.metrics.Running.Update(1)
+	for _, listener := range i.listeners {
+		listener.Connected()
+	}
 	started <- nil
 	reader := bufio.NewReader(resp.Body)
 	for {
@@ -246,6 +279,14 @@ func (i *inbound) processUntilEnd(
 	}
 }
 
+// notifyDisconnected notifies the listeners that the inbound stopped
+// processing the events of the Mesos master
+func (i *inbound) notifyDisconnected() {
+	for _, listener := range i.listeners {
+		listener.Disconnected()
+	}
+}
+
 // stopInternal must be called with mutex locked
 func (i *inbound) stopInternal() error {
 	i.stopFlag.Store(true)
@@ -0,0 +1,42 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offer
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/uber/peloton/pkg/hostmgr/offer/offerpool"
+)
+
+// OfferSuppressionPath is the path of the debug endpoint which returns the
+// suppression state of the offers per role.
+const OfferSuppressionPath = "/debug/offers/suppression"
+
+// OfferSuppressionHandler returns the handler of the debug endpoint which
+// returns the suppression state of the offers of each role as JSON.
+func OfferSuppressionHandler(
+	s offerpool.Suppressor) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := json.Marshal(s.GetState())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
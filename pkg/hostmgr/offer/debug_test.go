@@ -0,0 +1,46 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offer
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/uber/peloton/pkg/hostmgr/offer/offerpool"
+	offerpool_mocks "github.com/uber/peloton/pkg/hostmgr/offer/offerpool/mocks"
+)
+
+// TestOfferSuppressionHandler tests that the debug endpoint returns the
+// suppression state of the roles
+func (suite *eventHandlerTestSuite) TestOfferSuppressionHandler() {
+	since := time.Unix(1500000000, 0).UTC()
+	suppressor := offerpool_mocks.NewMockSuppressor(suite.ctrl)
+	suppressor.EXPECT().GetState().Return([]*offerpool.RoleSuppressionState{
+		{Role: "peloton", State: "SUPPRESSED", Since: since},
+	})
+
+	w := httptest.NewRecorder()
+	OfferSuppressionHandler(suppressor)(
+		w, httptest.NewRequest("GET", OfferSuppressionPath, nil))
+	suite.Equal(http.StatusOK, w.Code)
+	var states []*offerpool.RoleSuppressionState
+	suite.NoError(json.Unmarshal(w.Body.Bytes(), &states))
+	suite.Len(states, 1)
+	suite.Equal("peloton", states[0].Role)
+	suite.Equal("SUPPRESSED", states[0].State)
+	suite.True(since.Equal(states[0].Since))
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/uber-go/tally"
 	"go.uber.org/yarpc"
 
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
 	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
 	"github.com/uber/peloton/pkg/common/background"
 	"github.com/uber/peloton/pkg/hostmgr/binpacking"
@@ -44,6 +45,14 @@ const (
 
 	_poolMetricsRefresh       = "poolMetricsRefresh"
 	_poolMetricsRefreshPeriod = 10 * time.Second
+
+	// _inverseOfferRefuseDuration is how long the inverse offers of an
+	// unavailability without end are declined for, and the maximum for the
+	// others
+	_inverseOfferRefuseDuration = time.Hour
+	// _inverseOfferMinRefuseDuration is the minimum duration inverse offers
+	// are declined for, e.g. once their unavailability is over
+	_inverseOfferMinRefuseDuration = 5 * time.Second
 )
 
 // EventHandler defines the interface for offer event handler that is
@@ -60,13 +69,21 @@ type EventHandler interface {
 
 	// GetOfferPool returns the underlying Pool holding the offers.
 	GetOfferPool() offerpool.Pool
+
+	// GetSuppressor returns the Suppressor of the offers per role.
+	GetSuppressor() offerpool.Suppressor
 }
 
 // eventHandler is the handler for Mesos Offer events
 type eventHandler struct {
-	offerPool   offerpool.Pool
-	offerPruner Pruner
-	metrics     *offerpool.Metrics
+	offerPool      offerpool.Pool
+	offerPruner    Pruner
+	suppressor     offerpool.Suppressor
+	declineBatcher offerpool.DeclineBatcher
+	metrics        *offerpool.Metrics
+
+	schedulerClient       mpb.SchedulerClient
+	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider
 }
 
 // Singleton event handler for offers
@@ -79,6 +96,8 @@ func InitEventHandler(
 	offerHoldTime time.Duration,
 	offerPruningPeriod time.Duration,
 	schedulerClient mpb.SchedulerClient,
+	mesosDetector hostmgr_mesos.MasterDetector,
+	callSender hostmgr_mesos.CallSender,
 	volumeStore storage.PersistentVolumeStore,
 	backgroundMgr background.Manager,
 	placingHostPruningPeriodSec time.Duration,
@@ -87,13 +106,23 @@ func InitEventHandler(
 	slackResourceTypes []string,
 	ranker binpacking.Ranker,
 	binPackingRefreshIntervalSec time.Duration,
-	hostPlacingOfferStatusTimeout time.Duration) {
+	hostPlacingOfferStatusTimeout time.Duration,
+	suppressorConfig *offerpool.SuppressorConfig,
+	roles []string,
+	declineBatcherConfig *offerpool.DeclineBatcherConfig) {
 
 	if handler != nil {
 		log.Warning("Offer event handler has already been initialized")
 		return
 	}
 	metrics := offerpool.NewMetrics(parent)
+	declineBatcher := offerpool.NewDeclineBatcher(
+		declineBatcherConfig,
+		schedulerClient,
+		hostmgr_mesos.GetSchedulerDriver(),
+		metrics,
+		parent,
+	)
 	pool := offerpool.NewOfferPool(
 		offerHoldTime,
 		schedulerClient,
@@ -104,6 +133,7 @@ func InitEventHandler(
 		slackResourceTypes,
 		ranker,
 		hostPlacingOfferStatusTimeout,
+		declineBatcher,
 	)
 
 	placingHostPruner := prune.NewPlacingHostPruner(
@@ -145,8 +175,9 @@ func InitEventHandler(
 		pool,
 		parent.SubScope(_resourceCleanerName),
 		volumeStore,
-		schedulerClient,
 		hostmgr_mesos.GetSchedulerDriver(),
+		mesosDetector,
+		callSender,
 	)
 	backgroundMgr.RegisterWorks(
 		background.Work{
@@ -166,9 +197,21 @@ func InitEventHandler(
 	)
 	//TODO: refactor OfferPruner as a background worker
 	handler = &eventHandler{
-		offerPool:   pool,
-		offerPruner: NewOfferPruner(pool, offerPruningPeriod, metrics),
-		metrics:     metrics,
+		offerPool: pool,
+		offerPruner: NewOfferPruner(
+			pool, offerPruningPeriod, metrics, declineBatcher),
+		suppressor: offerpool.NewSuppressor(
+			suppressorConfig,
+			roles,
+			schedulerClient,
+			hostmgr_mesos.GetSchedulerDriver(),
+			metrics,
+		),
+		declineBatcher: declineBatcher,
+		metrics:        metrics,
+
+		schedulerClient:       schedulerClient,
+		frameworkInfoProvider: hostmgr_mesos.GetSchedulerDriver(),
 	}
 	procedures := map[sched.Event_Type]interface{}{
 		sched.Event_OFFERS:                handler.Offers,
@@ -211,10 +254,70 @@ func (h *eventHandler) InverseOffers(ctx context.Context, body *sched.Event) err
 	log.WithField("event", event).
 		Debug("OfferManager: processing InverseOffers event")
 
-	// TODO: Handle inverse offers from Mesos
+	// Hosts are drained by the host maintenance of Peloton, so the inverse
+	// offers are declined until the end of their unavailability for Mesos
+	// not to send them again meanwhile. The inverse offers declined for the
+	// same duration are declined together.
+	now := time.Now()
+	var refuseDurations []time.Duration
+	inverseOfferIDs := make(map[time.Duration][]*mesos.OfferID)
+	for _, inverseOffer := range event.GetInverseOffers() {
+		refuseDuration := inverseOfferRefuseDuration(
+			inverseOffer.GetUnavailability(), now)
+		if _, ok := inverseOfferIDs[refuseDuration]; !ok {
+			refuseDurations = append(refuseDurations, refuseDuration)
+		}
+		inverseOfferIDs[refuseDuration] = append(
+			inverseOfferIDs[refuseDuration], inverseOffer.GetId())
+	}
+	for _, refuseDuration := range refuseDurations {
+		h.declineInverseOffers(
+			ctx, inverseOfferIDs[refuseDuration], refuseDuration)
+	}
 	return nil
 }
 
+// inverseOfferRefuseDuration returns how long the inverse offers of an
+// unavailability are declined for at now
+func inverseOfferRefuseDuration(
+	unavailability *mesos.Unavailability,
+	now time.Time) time.Duration {
+	if unavailability.GetDuration() == nil {
+		return _inverseOfferRefuseDuration
+	}
+	end := time.Unix(0, unavailability.GetStart().GetNanoseconds()+
+		unavailability.GetDuration().GetNanoseconds())
+	refuseDuration := end.Sub(now).Truncate(time.Second)
+	if refuseDuration < _inverseOfferMinRefuseDuration {
+		return _inverseOfferMinRefuseDuration
+	}
+	if refuseDuration > _inverseOfferRefuseDuration {
+		return _inverseOfferRefuseDuration
+	}
+	return refuseDuration
+}
+
+// declineInverseOffers declines inverse offers for refuseDuration. Failures
+// are only logged as Mesos sends the inverse offers again.
+func (h *eventHandler) declineInverseOffers(
+	ctx context.Context,
+	inverseOfferIDs []*mesos.OfferID,
+	refuseDuration time.Duration) {
+	msg := mpb.NewDeclineInverseOffersCall(
+		h.frameworkInfoProvider.GetFrameworkID(ctx),
+		inverseOfferIDs,
+		refuseDuration.Seconds())
+	msid := h.frameworkInfoProvider.GetMesosStreamID(ctx)
+	if err := h.schedulerClient.Call(msid, msg); err != nil {
+		log.WithError(err).
+			WithField("call", msg).
+			Warn("Failed to decline inverse offers")
+		h.metrics.InverseOfferDeclineFail.Inc(1)
+		return
+	}
+	h.metrics.InverseOfferDecline.Inc(int64(len(inverseOfferIDs)))
+}
+
 // Rescind offers
 func (h *eventHandler) Rescind(ctx context.Context, body *sched.Event) error {
 
@@ -241,10 +344,19 @@ func (h *eventHandler) GetOfferPool() offerpool.Pool {
 	return h.offerPool
 }
 
+// GetSuppressor returns the Suppressor of the offers per role.
+func (h *eventHandler) GetSuppressor() offerpool.Suppressor {
+	return h.suppressor
+}
+
 // Start runs startup related procedures
 func (h *eventHandler) Start() error {
+	// Start batching declined offers
+	h.declineBatcher.Start()
 	// Start offer pruner
 	h.offerPruner.Start()
+	// Start suppressing the offers of the roles without demand
+	h.suppressor.Start()
 
 	// TODO: add error handling
 	return nil
@@ -256,6 +368,10 @@ func (h *eventHandler) Stop() error {
 	h.offerPool.Clear()
 	// Stop offer pruner
 	h.offerPruner.Stop()
+	// Stop offer suppressor
+	h.suppressor.Stop()
+	// Stop batching declined offers, once the pending ones are declined
+	h.declineBatcher.Stop()
 
 	// TODO: add error handling
 	return nil
@@ -0,0 +1,181 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
+
+	"github.com/uber/peloton/pkg/common/util"
+	hostmgr_mesos_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/mocks"
+	mpb_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
+	"github.com/uber/peloton/pkg/hostmgr/offer/offerpool"
+)
+
+const (
+	_frameworkID = "framework-id"
+	_streamID    = "stream-id"
+)
+
+type eventHandlerTestSuite struct {
+	suite.Suite
+
+	ctrl            *gomock.Controller
+	schedulerClient *mpb_mocks.MockSchedulerClient
+	provider        *hostmgr_mesos_mocks.MockFrameworkInfoProvider
+	scope           tally.TestScope
+	handler         *eventHandler
+}
+
+func (suite *eventHandlerTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.schedulerClient = mpb_mocks.NewMockSchedulerClient(suite.ctrl)
+	suite.provider = hostmgr_mesos_mocks.NewMockFrameworkInfoProvider(suite.ctrl)
+	suite.scope = tally.NewTestScope("", nil)
+	suite.handler = &eventHandler{
+		metrics:               offerpool.NewMetrics(suite.scope),
+		schedulerClient:       suite.schedulerClient,
+		frameworkInfoProvider: suite.provider,
+	}
+
+	suite.provider.EXPECT().
+		GetFrameworkID(gomock.Any()).
+		Return(&mesos.FrameworkID{Value: util.PtrStr(_frameworkID)}).
+		AnyTimes()
+	suite.provider.EXPECT().
+		GetMesosStreamID(gomock.Any()).
+		Return(_streamID).
+		AnyTimes()
+}
+
+func (suite *eventHandlerTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestEventHandler(t *testing.T) {
+	suite.Run(t, new(eventHandlerTestSuite))
+}
+
+// newInverseOffer returns an inverse offer for an unavailability starting
+// now, which lasts for duration unless it is zero
+func newInverseOffer(id string, duration time.Duration) *mesos.InverseOffer {
+	start := time.Now().UnixNano()
+	unavailability := &mesos.Unavailability{
+		Start: &mesos.TimeInfo{Nanoseconds: &start},
+	}
+	if duration > 0 {
+		nanos := duration.Nanoseconds()
+		unavailability.Duration = &mesos.DurationInfo{Nanoseconds: &nanos}
+	}
+	return &mesos.InverseOffer{
+		Id:             &mesos.OfferID{Value: util.PtrStr(id)},
+		Unavailability: unavailability,
+	}
+}
+
+func (suite *eventHandlerTestSuite) counter(name string) int64 {
+	counter, ok := suite.scope.Snapshot().Counters()[name+"+"]
+	if !ok {
+		return 0
+	}
+	return counter.Value()
+}
+
+// TestInverseOffersDeclined tests that the inverse offers are declined
+// until the end of their unavailability
+func (suite *eventHandlerTestSuite) TestInverseOffersDeclined() {
+	refused := make(map[string]float64)
+	suite.schedulerClient.EXPECT().
+		Call(_streamID, gomock.Any()).
+		Do(func(_ string, call *sched.Call) {
+			suite.Equal(sched.Call_DECLINE_INVERSE_OFFERS, call.GetType())
+			suite.Equal(_frameworkID, call.GetFrameworkId().GetValue())
+			decline := call.GetDeclineInverseOffers()
+			for _, id := range decline.GetInverseOfferIds() {
+				refused[id.GetValue()] = decline.GetFilters().GetRefuseSeconds()
+			}
+		}).
+		Return(nil).
+		Times(2)
+
+	suite.NoError(suite.handler.InverseOffers(context.Background(), &sched.Event{
+		InverseOffers: &sched.Event_InverseOffers{
+			InverseOffers: []*mesos.InverseOffer{
+				newInverseOffer("inverse-offer-0", 0),
+				newInverseOffer("inverse-offer-1", 0),
+				newInverseOffer("inverse-offer-2", 10*time.Minute),
+			},
+		},
+	}))
+
+	suite.Len(refused, 3)
+	suite.Equal(_inverseOfferRefuseDuration.Seconds(), refused["inverse-offer-0"])
+	suite.Equal(_inverseOfferRefuseDuration.Seconds(), refused["inverse-offer-1"])
+	// the unavailability started a bit earlier
+	suite.InDelta((10 * time.Minute).Seconds(), refused["inverse-offer-2"], 1)
+	suite.Equal(int64(3), suite.counter("pool.inverse_offers.decline"))
+}
+
+// TestInverseOffersDeclineFailure tests that failing to decline inverse
+// offers does not fail the event
+func (suite *eventHandlerTestSuite) TestInverseOffersDeclineFailure() {
+	suite.schedulerClient.EXPECT().
+		Call(_streamID, gomock.Any()).
+		Return(errors.New("mesos unavailable"))
+
+	suite.NoError(suite.handler.InverseOffers(context.Background(), &sched.Event{
+		InverseOffers: &sched.Event_InverseOffers{
+			InverseOffers: []*mesos.InverseOffer{
+				newInverseOffer("inverse-offer-0", 0),
+			},
+		},
+	}))
+	suite.Equal(int64(1), suite.counter("pool.inverse_offers.decline_fail"))
+}
+
+// TestInverseOfferRefuseDuration tests the bounds of the durations inverse
+// offers are declined for
+func (suite *eventHandlerTestSuite) TestInverseOfferRefuseDuration() {
+	now := time.Now()
+	start := now.Add(-time.Hour).UnixNano()
+	unavailability := func(duration time.Duration) *mesos.Unavailability {
+		nanos := duration.Nanoseconds()
+		return &mesos.Unavailability{
+			Start:    &mesos.TimeInfo{Nanoseconds: &start},
+			Duration: &mesos.DurationInfo{Nanoseconds: &nanos},
+		}
+	}
+
+	suite.Equal(_inverseOfferRefuseDuration,
+		inverseOfferRefuseDuration(&mesos.Unavailability{
+			Start: &mesos.TimeInfo{Nanoseconds: &start},
+		}, now))
+	// over
+	suite.Equal(_inverseOfferMinRefuseDuration,
+		inverseOfferRefuseDuration(unavailability(time.Minute), now))
+	suite.Equal(30*time.Minute,
+		inverseOfferRefuseDuration(unavailability(90*time.Minute), now))
+	suite.Equal(_inverseOfferRefuseDuration,
+		inverseOfferRefuseDuration(unavailability(24*time.Hour), now))
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offerpool
+
+import (
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/uber/peloton/pkg/hostmgr/scalar"
+)
+
+// OfferChurn is the churn of the offer pool over a period
+type OfferChurn struct {
+	Start time.Time
+	End   time.Time
+
+	// OffersAdded and OffersRemoved are the number of offers added to and
+	// removed from the pool
+	OffersAdded   int
+	OffersRemoved int
+	// ResourcesAdded are the non-revocable resources of the offers added
+	ResourcesAdded scalar.Resources
+	// RemovedReasons are the number of offers removed by reason
+	RemovedReasons map[string]int
+	// HostsMoved is the number of changes of status of the hosts
+	HostsMoved int
+	// Hosts is the number of hosts with an offer added or removed, or a
+	// change of status
+	Hosts int
+}
+
+// ChurnListener is a reference OfferPoolListener which logs the churn of
+// the offer pool every period. The churn of a period is logged with the
+// first change after its end, so nothing is logged while the pool does not
+// change.
+type ChurnListener struct {
+	sync.Mutex
+
+	period time.Duration
+	now    func() time.Time
+	// report reports the churn of a period, overridden in tests
+	report func(churn OfferChurn)
+
+	churn OfferChurn
+	hosts map[string]bool
+}
+
+// NewChurnListener creates a ChurnListener logging the churn every period
+func NewChurnListener(period time.Duration) *ChurnListener {
+	l := &ChurnListener{
+		period: period,
+		now:    time.Now,
+		report: logOfferChurn,
+	}
+	l.reset(l.now())
+	return l
+}
+
+// logOfferChurn logs the churn of a period
+func logOfferChurn(churn OfferChurn) {
+	log.WithFields(log.Fields{
+		"start":           churn.Start,
+		"end":             churn.End,
+		"offers_added":    churn.OffersAdded,
+		"offers_removed":  churn.OffersRemoved,
+		"resources_added": churn.ResourcesAdded,
+		"removed_reasons": churn.RemovedReasons,
+		"hosts_moved":     churn.HostsMoved,
+		"hosts":           churn.Hosts,
+	}).Info("Offer pool churn")
+}
+
+// Name returns the name of the listener
+func (l *ChurnListener) Name() string {
+	return "offer_churn_listener"
+}
+
+// OfferAdded counts an offer added
+func (l *ChurnListener) OfferAdded(hostname string, offer OfferSummary) {
+	l.record(hostname, func(churn *OfferChurn) {
+		churn.OffersAdded++
+		churn.ResourcesAdded = churn.ResourcesAdded.Add(offer.Resources)
+	})
+}
+
+// OfferRemoved counts an offer removed
+func (l *ChurnListener) OfferRemoved(
+	hostname string,
+	offerID string,
+	reason string) {
+	l.record(hostname, func(churn *OfferChurn) {
+		churn.OffersRemoved++
+		churn.RemovedReasons[reason]++
+	})
+}
+
+// HostMoved counts a change of status of a host
+func (l *ChurnListener) HostMoved(transition HostTransition) {
+	l.record(transition.Hostname, func(churn *OfferChurn) {
+		churn.HostsMoved++
+	})
+}
+
+// record reports the churn of the period if it is over, and records a
+// change of a host in the churn of the current period
+func (l *ChurnListener) record(hostname string, change func(*OfferChurn)) {
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	if end := l.churn.Start.Add(l.period); !now.Before(end) {
+		l.churn.End = end
+		l.churn.Hosts = len(l.hosts)
+		l.report(l.churn)
+		l.reset(now)
+	}
+	change(&l.churn)
+	l.hosts[hostname] = true
+}
+
+// reset starts a new period at start
+func (l *ChurnListener) reset(start time.Time) {
+	l.churn = OfferChurn{
+		Start:          start,
+		RemovedReasons: make(map[string]int),
+	}
+	l.hosts = make(map[string]bool)
+}
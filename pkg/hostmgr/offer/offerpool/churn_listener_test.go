@@ -0,0 +1,82 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offerpool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/uber/peloton/pkg/hostmgr/scalar"
+	"github.com/uber/peloton/pkg/hostmgr/summary"
+)
+
+// TestChurnListener tests that the churn of a period is reported with the
+// first change after its end
+func TestChurnListener(t *testing.T) {
+	start := time.Unix(1000, 0)
+	now := start
+	var reported []OfferChurn
+
+	l := NewChurnListener(time.Minute)
+	l.now = func() time.Time { return now }
+	l.report = func(churn OfferChurn) { reported = append(reported, churn) }
+	l.reset(start)
+
+	l.OfferAdded("host-1", OfferSummary{
+		OfferID:   "offer-1",
+		Resources: scalar.Resources{CPU: 1, Mem: 10},
+	})
+	l.OfferAdded("host-2", OfferSummary{
+		OfferID:   "offer-2",
+		Resources: scalar.Resources{CPU: 2, Mem: 20},
+	})
+	l.HostMoved(HostTransition{
+		Hostname: "host-1",
+		From:     summary.ReadyHost,
+		To:       summary.PlacingHost,
+	})
+	now = start.Add(30 * time.Second)
+	l.OfferRemoved("host-1", "offer-1", "offer is claimed for launch.")
+	l.OfferRemoved("host-2", "offer-2", "offer is rescinded.")
+	assert.Empty(t, reported)
+
+	now = start.Add(90 * time.Second)
+	l.OfferRemoved("host-3", "offer-3", "offer is rescinded.")
+	assert.Equal(t, []OfferChurn{
+		{
+			Start:          start,
+			End:            start.Add(time.Minute),
+			OffersAdded:    2,
+			OffersRemoved:  2,
+			ResourcesAdded: scalar.Resources{CPU: 3, Mem: 30},
+			RemovedReasons: map[string]int{
+				"offer is claimed for launch.": 1,
+				"offer is rescinded.":          1,
+			},
+			HostsMoved: 1,
+			Hosts:      2,
+		},
+	}, reported)
+
+	// the next period starts with the change which reported the last one
+	now = start.Add(150 * time.Second)
+	l.OfferAdded("host-1", OfferSummary{OfferID: "offer-4"})
+	assert.Len(t, reported, 2)
+	assert.Equal(t, start.Add(90*time.Second), reported[1].Start)
+	assert.Equal(t, 1, reported[1].OffersRemoved)
+	assert.Equal(t, 1, reported[1].Hosts)
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offerpool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+
+	"github.com/uber/peloton/pkg/common/lifecycle"
+	hostmgr_mesos "github.com/uber/peloton/pkg/hostmgr/mesos"
+	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+)
+
+// DeclineReason is the reason offers are declined for
+type DeclineReason string
+
+const (
+	// DeclineReasonMaintenance is the reason of the offers of hosts in or
+	// close to maintenance
+	DeclineReasonMaintenance DeclineReason = "maintenance"
+	// DeclineReasonExpired is the reason of the offers held for longer
+	// than the offer hold time without being used
+	DeclineReasonExpired DeclineReason = "expired"
+)
+
+const (
+	_defaultDeclineWindow = time.Second
+	// _defaultDeclineRefuseDuration is the refuse duration of the reasons
+	// without one, the default of Mesos
+	_defaultDeclineRefuseDuration = 5 * time.Second
+)
+
+// _defaultDeclineRefuseDurations are the refuse durations of the reasons
+// not configured
+var _defaultDeclineRefuseDurations = map[DeclineReason]time.Duration{
+	DeclineReasonMaintenance: time.Minute,
+	DeclineReasonExpired:     10 * time.Second,
+}
+
+// DeclineBatcherConfig is the configuration of the DeclineBatcher
+type DeclineBatcherConfig struct {
+	// Window is how long the declined offers are batched for before being
+	// sent to Mesos
+	Window time.Duration `yaml:"window"`
+
+	// RefuseDurations are how long Mesos does not offer again the resources
+	// of the offers declined, by decline reason
+	RefuseDurations map[DeclineReason]time.Duration `yaml:"refuse_durations"`
+}
+
+// DeclineBatcher batches the offers declined by reason, so that a single
+// DECLINE call is made per reason for all the offers declined within a
+// window, with a refuse duration specific to the reason
+type DeclineBatcher interface {
+	// Start starts batching the declined offers.
+	Start()
+
+	// Stop stops batching the declined offers, after sending the pending
+	// ones.
+	Stop()
+
+	// Decline declines offerIDs for reason. The offers are declined right
+	// away when the batcher is not started.
+	Decline(reason DeclineReason, offerIDs []*mesos.OfferID)
+}
+
+// declineBatcher implements DeclineBatcher
+type declineBatcher struct {
+	sync.Mutex
+
+	window                time.Duration
+	refuseDurations       map[DeclineReason]time.Duration
+	schedulerClient       mpb.SchedulerClient
+	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider
+	metrics               *Metrics
+	scope                 tally.Scope
+	lifeCycle             lifecycle.LifeCycle
+
+	running bool
+	pending map[DeclineReason][]*mesos.OfferID
+}
+
+// NewDeclineBatcher creates a DeclineBatcher, which counts the declined
+// offers in the decline metrics of the offer pool as well as by reason
+func NewDeclineBatcher(
+	config *DeclineBatcherConfig,
+	schedulerClient mpb.SchedulerClient,
+	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider,
+	metrics *Metrics,
+	parent tally.Scope) DeclineBatcher {
+	b := &declineBatcher{
+		window:                _defaultDeclineWindow,
+		refuseDurations:       make(map[DeclineReason]time.Duration),
+		schedulerClient:       schedulerClient,
+		frameworkInfoProvider: frameworkInfoProvider,
+		metrics:               metrics,
+		scope:                 parent.SubScope("pool").SubScope("decline_batcher"),
+		lifeCycle:             lifecycle.NewLifeCycle(),
+		pending:               make(map[DeclineReason][]*mesos.OfferID),
+	}
+	for reason, refuseDuration := range _defaultDeclineRefuseDurations {
+		b.refuseDurations[reason] = refuseDuration
+	}
+	if config != nil {
+		if config.Window > 0 {
+			b.window = config.Window
+		}
+		for reason, refuseDuration := range config.RefuseDurations {
+			b.refuseDurations[reason] = refuseDuration
+		}
+	}
+	return b
+}
+
+// Start starts batching the declined offers
+func (b *declineBatcher) Start() {
+	if !b.lifeCycle.Start() {
+		log.Warn("Decline batcher is already running, no action will be performed")
+		return
+	}
+	b.Lock()
+	b.running = true
+	b.Unlock()
+
+	started := make(chan int, 1)
+	go func() {
+		defer b.lifeCycle.StopComplete()
+
+		log.Info("Starting decline batching loop")
+		close(started)
+
+		ticker := time.NewTicker(b.window)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-b.lifeCycle.StopCh():
+				log.Info("Exiting the decline batching loop")
+				return
+			case <-ticker.C:
+				b.flush()
+			}
+		}
+	}()
+	// Wait until go routine is started
+	<-started
+}
+
+// Stop stops batching the declined offers, and sends the pending ones
+func (b *declineBatcher) Stop() {
+	if !b.lifeCycle.Stop() {
+		log.Warn("Decline batcher is already stopped, no action will be performed")
+		return
+	}
+
+	log.Info("Stopping decline batcher")
+	b.lifeCycle.Wait()
+
+	b.Lock()
+	b.running = false
+	b.Unlock()
+	b.flush()
+	log.Info("Decline batcher stopped")
+}
+
+// Decline declines offerIDs for reason
+func (b *declineBatcher) Decline(
+	reason DeclineReason,
+	offerIDs []*mesos.OfferID) {
+	if len(offerIDs) == 0 {
+		return
+	}
+
+	b.Lock()
+	if b.running {
+		b.pending[reason] = append(b.pending[reason], offerIDs...)
+		b.Unlock()
+		return
+	}
+	b.Unlock()
+	b.decline(reason, offerIDs)
+}
+
+// flush declines the pending offers, with one call per reason
+func (b *declineBatcher) flush() {
+	b.Lock()
+	pending := b.pending
+	b.pending = make(map[DeclineReason][]*mesos.OfferID)
+	b.Unlock()
+
+	for reason, offerIDs := range pending {
+		b.decline(reason, offerIDs)
+	}
+}
+
+// decline declines offerIDs for the refuse duration of reason. Failures are
+// only logged, as Mesos invalidates the offers after its offer timeout.
+func (b *declineBatcher) decline(
+	reason DeclineReason,
+	offerIDs []*mesos.OfferID) {
+	refuseDuration, ok := b.refuseDurations[reason]
+	if !ok {
+		refuseDuration = _defaultDeclineRefuseDuration
+	}
+
+	ctx := context.Background()
+	msg := mpb.NewDeclineCall(
+		b.frameworkInfoProvider.GetFrameworkID(ctx),
+		offerIDs,
+		refuseDuration.Seconds())
+	msid := b.frameworkInfoProvider.GetMesosStreamID(ctx)
+	reasonScope := b.scope.Tagged(map[string]string{"reason": string(reason)})
+	if err := b.schedulerClient.Call(msid, msg); err != nil {
+		log.WithError(err).
+			WithField("reason", reason).
+			WithField("call", msg).
+			Warn("Failed to decline offers")
+		b.metrics.DeclineFail.Inc(1)
+		reasonScope.Counter("decline_fail").Inc(int64(len(offerIDs)))
+		return
+	}
+	b.metrics.Decline.Inc(int64(len(offerIDs)))
+	reasonScope.Counter("decline").Inc(int64(len(offerIDs)))
+	reasonScope.Counter("decline_call").Inc(1)
+}
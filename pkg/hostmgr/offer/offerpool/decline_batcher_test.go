@@ -0,0 +1,201 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offerpool
+
+import (
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
+
+	"github.com/uber/peloton/pkg/common/util"
+	hostmgr_mesos_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/mocks"
+	mpb_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+const _declineStreamID = "stream-id"
+
+type declineBatcherTestSuite struct {
+	suite.Suite
+
+	ctrl            *gomock.Controller
+	schedulerClient *mpb_mocks.MockSchedulerClient
+	provider        *hostmgr_mesos_mocks.MockFrameworkInfoProvider
+	scope           tally.TestScope
+	batcher         *declineBatcher
+
+	// declined are the offers declined by refuse seconds
+	declined map[float64][]string
+}
+
+func (suite *declineBatcherTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.schedulerClient = mpb_mocks.NewMockSchedulerClient(suite.ctrl)
+	suite.provider = hostmgr_mesos_mocks.NewMockFrameworkInfoProvider(suite.ctrl)
+	suite.scope = tally.NewTestScope("", nil)
+	suite.declined = make(map[float64][]string)
+
+	suite.batcher = NewDeclineBatcher(
+		&DeclineBatcherConfig{
+			Window: time.Hour,
+			RefuseDurations: map[DeclineReason]time.Duration{
+				DeclineReasonMaintenance: time.Hour,
+			},
+		},
+		suite.schedulerClient,
+		suite.provider,
+		NewMetrics(suite.scope),
+		suite.scope,
+	).(*declineBatcher)
+
+	suite.provider.EXPECT().
+		GetFrameworkID(gomock.Any()).
+		Return(&mesos.FrameworkID{Value: util.PtrStr("framework-id")}).
+		AnyTimes()
+	suite.provider.EXPECT().
+		GetMesosStreamID(gomock.Any()).
+		Return(_declineStreamID).
+		AnyTimes()
+}
+
+func (suite *declineBatcherTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestDeclineBatcher(t *testing.T) {
+	suite.Run(t, new(declineBatcherTestSuite))
+}
+
+func newOfferIDs(ids ...string) []*mesos.OfferID {
+	var offerIDs []*mesos.OfferID
+	for _, id := range ids {
+		offerIDs = append(offerIDs, &mesos.OfferID{Value: util.PtrStr(id)})
+	}
+	return offerIDs
+}
+
+// expectDeclines expects n DECLINE calls, and records the offers declined
+func (suite *declineBatcherTestSuite) expectDeclines(n int, err error) {
+	suite.schedulerClient.EXPECT().
+		Call(_declineStreamID, gomock.Any()).
+		Do(func(_ string, call *sched.Call) {
+			suite.Equal(sched.Call_DECLINE, call.GetType())
+			refuseSeconds := call.GetDecline().GetFilters().GetRefuseSeconds()
+			for _, offerID := range call.GetDecline().GetOfferIds() {
+				suite.declined[refuseSeconds] = append(
+					suite.declined[refuseSeconds], offerID.GetValue())
+			}
+			sort.Strings(suite.declined[refuseSeconds])
+		}).
+		Return(err).
+		Times(n)
+}
+
+func (suite *declineBatcherTestSuite) counter(
+	name string,
+	reason DeclineReason) int64 {
+	key := name + "+reason=" + string(reason)
+	counter, ok := suite.scope.Snapshot().Counters()[key]
+	if !ok {
+		return 0
+	}
+	return counter.Value()
+}
+
+// poolCounter returns the value of a counter of the offer pool metrics
+func (suite *declineBatcherTestSuite) poolCounter(name string) int64 {
+	counter, ok := suite.scope.Snapshot().Counters()[name+"+"]
+	if !ok {
+		return 0
+	}
+	return counter.Value()
+}
+
+// TestDeclineGroupedByReason tests that the offers declined within a window
+// are declined with one call per reason, for the refuse duration of the
+// reason
+func (suite *declineBatcherTestSuite) TestDeclineGroupedByReason() {
+	suite.batcher.running = true
+	suite.batcher.Decline(DeclineReasonMaintenance, newOfferIDs("offer-1"))
+	suite.batcher.Decline(DeclineReasonExpired, newOfferIDs("offer-2"))
+	suite.batcher.Decline(
+		DeclineReasonMaintenance, newOfferIDs("offer-3", "offer-4"))
+	suite.batcher.Decline("unknown", newOfferIDs("offer-5"))
+	suite.batcher.Decline(DeclineReasonExpired, nil)
+
+	suite.expectDeclines(3, nil)
+	suite.batcher.flush()
+
+	suite.Equal(map[float64][]string{
+		// configured
+		time.Hour.Seconds(): {"offer-1", "offer-3", "offer-4"},
+		// default of the reason
+		_defaultDeclineRefuseDurations[DeclineReasonExpired].Seconds(): {
+			"offer-2"},
+		// default of the reasons without one
+		_defaultDeclineRefuseDuration.Seconds(): {"offer-5"},
+	}, suite.declined)
+	suite.Equal(int64(3), suite.counter(
+		"pool.decline_batcher.decline", DeclineReasonMaintenance))
+	suite.Equal(int64(1), suite.counter(
+		"pool.decline_batcher.decline_call", DeclineReasonMaintenance))
+	suite.Equal(int64(1), suite.counter(
+		"pool.decline_batcher.decline", DeclineReasonExpired))
+	suite.Equal(int64(5), suite.poolCounter("pool.offers.decline"))
+
+	// nothing pending anymore
+	suite.batcher.flush()
+}
+
+// TestDeclineFailure tests that the offers which cannot be declined are
+// counted by reason
+func (suite *declineBatcherTestSuite) TestDeclineFailure() {
+	suite.batcher.running = true
+	suite.batcher.Decline(
+		DeclineReasonExpired, newOfferIDs("offer-1", "offer-2"))
+
+	suite.expectDeclines(1, errors.New("mesos unavailable"))
+	suite.batcher.flush()
+	suite.Equal(int64(2), suite.counter(
+		"pool.decline_batcher.decline_fail", DeclineReasonExpired))
+	suite.Equal(int64(0), suite.counter(
+		"pool.decline_batcher.decline", DeclineReasonExpired))
+	suite.Equal(int64(1), suite.poolCounter("pool.offers.decline_fail"))
+}
+
+// TestFlushOnStop tests that the pending offers are declined on stop, and
+// that the offers declined while stopped are declined right away
+func (suite *declineBatcherTestSuite) TestFlushOnStop() {
+	suite.batcher.Start()
+	suite.batcher.Decline(DeclineReasonMaintenance, newOfferIDs("offer-1"))
+	suite.batcher.Decline(DeclineReasonExpired, newOfferIDs("offer-2"))
+
+	suite.expectDeclines(2, nil)
+	suite.batcher.Stop()
+	suite.Len(suite.declined, 2)
+
+	suite.expectDeclines(1, nil)
+	suite.batcher.Decline(DeclineReasonExpired, newOfferIDs("offer-3"))
+	expired := _defaultDeclineRefuseDurations[DeclineReasonExpired]
+	suite.Equal(
+		[]string{"offer-2", "offer-3"}, suite.declined[expired.Seconds()])
+}
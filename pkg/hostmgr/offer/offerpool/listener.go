@@ -0,0 +1,244 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offerpool
+
+import (
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+
+	"github.com/uber/peloton/pkg/common/constraints"
+	"github.com/uber/peloton/pkg/hostmgr/scalar"
+	"github.com/uber/peloton/pkg/hostmgr/summary"
+)
+
+// _listenerQueueSize is the number of changes queued for a listener, the
+// changes are dropped for the listener while its queue is full
+const _listenerQueueSize = 10000
+
+// OfferSummary is a compact summary of an offer delivered to the
+// OfferPoolListeners
+type OfferSummary struct {
+	// OfferID is the ID of the Mesos offer
+	OfferID string
+	// Resources are the non-revocable resources of the offer
+	Resources scalar.Resources
+	// RevocableResources are the revocable resources of the offer
+	RevocableResources scalar.Resources
+	// Labels are the attributes of the agent of the offer, as evaluated
+	// by the constraints, including the hostname
+	Labels constraints.LabelValues
+}
+
+// HostTransition is a change of the status of a host in the offer pool
+type HostTransition struct {
+	Hostname string
+	From     summary.HostStatus
+	To       summary.HostStatus
+}
+
+// OfferPoolListener defines an interface that must be implemented by a
+// listener interested in the changes of the offer pool, e.g. to run
+// experimental placement logic against the live offers. The changes are
+// delivered asynchronously in the order they happen, from a queue per
+// listener, so that slow listeners do not slow down the offer pool; the
+// changes are dropped while the queue of a listener is full. A panic in a
+// listener is recovered and logged, and does not affect the offer pool or
+// the other listeners.
+// Implementations must not modify the provided objects.
+type OfferPoolListener interface {
+	// Name returns a user-friendly name for the listener
+	Name() string
+
+	// OfferAdded is invoked when an offer is added to the pool
+	OfferAdded(hostname string, offer OfferSummary)
+
+	// OfferRemoved is invoked when an offer is removed from the pool,
+	// because it is used, declined, rescinded or expired
+	OfferRemoved(hostname string, offerID string, reason string)
+
+	// HostMoved is invoked when a host changes status in the pool, e.g.
+	// when it is claimed for placement
+	HostMoved(transition HostTransition)
+}
+
+// newOfferSummary returns the summary of an offer
+func newOfferSummary(offer *mesos.Offer) OfferSummary {
+	revocable, nonRevocable := scalar.FilterRevocableMesosResources(
+		offer.GetResources())
+	return OfferSummary{
+		OfferID:            offer.GetId().GetValue(),
+		Resources:          scalar.FromMesosResources(nonRevocable),
+		RevocableResources: scalar.FromMesosResources(revocable),
+		Labels: constraints.GetHostLabelValues(
+			offer.GetHostname(),
+			offer.GetAttributes()),
+	}
+}
+
+// listenerQueue delivers the changes of the offer pool to a listener from a
+// goroutine
+type listenerQueue struct {
+	listener OfferPoolListener
+	changes  chan func(OfferPoolListener)
+
+	dropped tally.Counter
+	panics  tally.Counter
+}
+
+// newListenerQueue returns the queue of a listener, and starts delivering
+// the changes queued until it is closed
+func newListenerQueue(
+	listener OfferPoolListener,
+	metrics *Metrics) *listenerQueue {
+	q := &listenerQueue{
+		listener: listener,
+		changes:  make(chan func(OfferPoolListener), _listenerQueueSize),
+		dropped:  metrics.ListenerChangesDropped,
+		panics:   metrics.ListenerPanics,
+	}
+	go q.run()
+	return q
+}
+
+// enqueue queues a change for the listener, unless its queue is full
+func (q *listenerQueue) enqueue(change func(OfferPoolListener)) {
+	select {
+	case q.changes <- change:
+	default:
+		q.dropped.Inc(1)
+	}
+}
+
+// close stops the delivery once the changes queued are delivered
+func (q *listenerQueue) close() {
+	close(q.changes)
+}
+
+func (q *listenerQueue) run() {
+	for change := range q.changes {
+		q.deliver(change)
+	}
+}
+
+// deliver delivers a change to the listener, recovering from its panics
+func (q *listenerQueue) deliver(change func(OfferPoolListener)) {
+	defer func() {
+		if r := recover(); r != nil {
+			q.panics.Inc(1)
+			log.WithFields(log.Fields{
+				"listener": q.listener.Name(),
+				"panic":    r,
+			}).Error("offer pool listener panicked")
+		}
+	}()
+	change(q.listener)
+}
+
+// listeners are the listeners registered on the offer pool
+type listeners struct {
+	sync.RWMutex
+
+	queues []*listenerQueue
+}
+
+// add registers a listener
+func (l *listeners) add(listener OfferPoolListener, metrics *Metrics) {
+	l.Lock()
+	defer l.Unlock()
+
+	l.queues = append(l.queues, newListenerQueue(listener, metrics))
+}
+
+// remove unregisters a listener, the changes already queued for it are
+// still delivered
+func (l *listeners) remove(listener OfferPoolListener) {
+	l.Lock()
+	defer l.Unlock()
+
+	for i, q := range l.queues {
+		if q.listener == listener {
+			q.close()
+			l.queues = append(l.queues[:i], l.queues[i+1:]...)
+			return
+		}
+	}
+}
+
+// empty returns whether no listener is registered, so that the changes are
+// not built for nothing
+func (l *listeners) empty() bool {
+	l.RLock()
+	defer l.RUnlock()
+
+	return len(l.queues) == 0
+}
+
+// publish queues a change for all the listeners
+func (l *listeners) publish(change func(OfferPoolListener)) {
+	l.RLock()
+	defer l.RUnlock()
+
+	for _, q := range l.queues {
+		q.enqueue(change)
+	}
+}
+
+// offerAdded publishes the offers added to the pool
+func (l *listeners) offerAdded(offers []*mesos.Offer) {
+	if l.empty() {
+		return
+	}
+	for _, offer := range offers {
+		hostname := offer.GetHostname()
+		offerSummary := newOfferSummary(offer)
+		l.publish(func(listener OfferPoolListener) {
+			listener.OfferAdded(hostname, offerSummary)
+		})
+	}
+}
+
+// offerRemoved publishes an offer removed from the pool
+func (l *listeners) offerRemoved(hostname string, offerID string, reason string) {
+	l.publish(func(listener OfferPoolListener) {
+		listener.OfferRemoved(hostname, offerID, reason)
+	})
+}
+
+// trackHostStatus calls change on a host, and publishes the change of the
+// status of the host it made, if any
+func (l *listeners) trackHostStatus(hs summary.HostSummary, change func()) {
+	if l.empty() {
+		change()
+		return
+	}
+	from := hs.GetHostStatus()
+	change()
+	to := hs.GetHostStatus()
+	if from == to {
+		return
+	}
+	transition := HostTransition{
+		Hostname: hs.GetHostname(),
+		From:     from,
+		To:       to,
+	}
+	l.publish(func(listener OfferPoolListener) {
+		listener.HostMoved(transition)
+	})
+}
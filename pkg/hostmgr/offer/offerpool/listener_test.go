@@ -0,0 +1,182 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offerpool
+
+import (
+	"context"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+
+	"github.com/uber/peloton/pkg/common/constraints"
+	"github.com/uber/peloton/pkg/hostmgr/scalar"
+	"github.com/uber/peloton/pkg/hostmgr/summary"
+)
+
+type offerAddedEvent struct {
+	hostname string
+	offer    OfferSummary
+}
+
+type offerRemovedEvent struct {
+	hostname string
+	offerID  string
+	reason   string
+}
+
+// recordingListener sends the changes it is notified of on a channel
+type recordingListener struct {
+	events chan interface{}
+}
+
+func newRecordingListener() *recordingListener {
+	return &recordingListener{events: make(chan interface{}, 100)}
+}
+
+func (l *recordingListener) Name() string {
+	return "recording_listener"
+}
+
+func (l *recordingListener) OfferAdded(hostname string, offer OfferSummary) {
+	l.events <- offerAddedEvent{hostname: hostname, offer: offer}
+}
+
+func (l *recordingListener) OfferRemoved(
+	hostname string,
+	offerID string,
+	reason string) {
+	l.events <- offerRemovedEvent{
+		hostname: hostname,
+		offerID:  offerID,
+		reason:   reason,
+	}
+}
+
+func (l *recordingListener) HostMoved(transition HostTransition) {
+	l.events <- transition
+}
+
+// panickingListener panics on every change
+type panickingListener struct{}
+
+func (l panickingListener) Name() string {
+	return "panicking_listener"
+}
+
+func (l panickingListener) OfferAdded(hostname string, offer OfferSummary) {
+	panic("offer added")
+}
+
+func (l panickingListener) OfferRemoved(
+	hostname string,
+	offerID string,
+	reason string) {
+	panic("offer removed")
+}
+
+func (l panickingListener) HostMoved(transition HostTransition) {
+	panic("host moved")
+}
+
+// nextEvent returns the next change the listener is notified of
+func (suite *OfferPoolTestSuite) nextEvent(l *recordingListener) interface{} {
+	select {
+	case event := <-l.events:
+		return event
+	case <-time.After(5 * time.Second):
+		suite.FailNow("no change notified to the listener")
+		return nil
+	}
+}
+
+// TestListenerChanges tests that a listener is notified of the offers added
+// and removed and of the hosts which change status, regardless of a
+// panicking listener
+func (suite *OfferPoolTestSuite) TestListenerChanges() {
+	recording := newRecordingListener()
+	suite.pool.AddListener(panickingListener{})
+	suite.pool.AddListener(recording)
+	defer suite.pool.RemoveListener(panickingListener{})
+	defer suite.pool.RemoveListener(recording)
+
+	hostname := "hostname1"
+	offer := suite.createOffer(hostname,
+		scalar.Resources{CPU: 1, Mem: 2, Disk: 3, GPU: 4})
+	zone := "zone"
+	zoneValue := "zone-1"
+	textType := mesos.Value_TEXT
+	offer.Attributes = []*mesos.Attribute{
+		{
+			Name: &zone,
+			Type: &textType,
+			Text: &mesos.Value_Text{Value: &zoneValue},
+		},
+	}
+	suite.pool.AddOffers(context.Background(), []*mesos.Offer{offer})
+	suite.Equal(offerAddedEvent{
+		hostname: hostname,
+		offer: OfferSummary{
+			OfferID:   offer.GetId().GetValue(),
+			Resources: scalar.Resources{CPU: 1, Mem: 2, Disk: 3, GPU: 4},
+			Labels: constraints.LabelValues{
+				constraints.HostNameKey: {hostname: 1},
+				zone:                    {zoneValue: 1},
+			},
+		},
+	}, suite.nextEvent(recording))
+
+	_, _, err := suite.pool.ClaimForPlace(&hostsvc.HostFilter{
+		Quantity: &hostsvc.QuantityControl{MaxHosts: 1},
+	})
+	suite.NoError(err)
+	suite.Equal(HostTransition{
+		Hostname: hostname,
+		From:     summary.ReadyHost,
+		To:       summary.PlacingHost,
+	}, suite.nextEvent(recording))
+
+	suite.NoError(suite.pool.ReturnUnusedOffers(hostname))
+	suite.Equal(HostTransition{
+		Hostname: hostname,
+		From:     summary.PlacingHost,
+		To:       summary.ReadyHost,
+	}, suite.nextEvent(recording))
+
+	suite.True(suite.pool.RescindOffer(offer.GetId()))
+	suite.Equal(offerRemovedEvent{
+		hostname: hostname,
+		offerID:  offer.GetId().GetValue(),
+		reason:   "offer is rescinded.",
+	}, suite.nextEvent(recording))
+}
+
+// TestListenerRemoved tests that a listener is not notified of the changes
+// after it is removed
+func (suite *OfferPoolTestSuite) TestListenerRemoved() {
+	recording := newRecordingListener()
+	suite.pool.AddListener(recording)
+	suite.pool.RemoveListener(recording)
+
+	suite.pool.AddOffers(context.Background(), []*mesos.Offer{
+		suite.createOffer("hostname1", scalar.Resources{CPU: 1}),
+	})
+	suite.True(suite.pool.listeners.empty())
+	select {
+	case event := <-recording.events:
+		suite.Failf("change notified to a removed listener", "%v", event)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -43,6 +43,22 @@ type Metrics struct {
 	RescindEvents     tally.Counter
 	Decline           tally.Counter
 	DeclineFail       tally.Counter
+
+	// metrics for inverse offers
+	InverseOfferDecline     tally.Counter
+	InverseOfferDeclineFail tally.Counter
+
+	// metrics for the suppression of offers per role
+	Suppress        tally.Counter
+	SuppressFail    tally.Counter
+	Revive          tally.Counter
+	ReviveFail      tally.Counter
+	WatchdogRevive  tally.Counter
+	SuppressedRoles tally.Gauge
+
+	// metrics for the delivery of the changes to the listeners
+	ListenerChangesDropped tally.Counter
+	ListenerPanics         tally.Counter
 }
 
 // NewMetrics returns a new Metrics struct, with all metrics initialized
@@ -58,6 +74,9 @@ func NewMetrics(scope tally.Scope) *Metrics {
 
 	hostsScope := poolScope.SubScope("hosts")
 	offersScope := poolScope.SubScope("offers")
+	inverseOffersScope := poolScope.SubScope("inverse_offers")
+	suppressionScope := poolScope.SubScope("suppression")
+	listenersScope := poolScope.SubScope("listeners")
 
 	return &Metrics{
 		Ready:            scalar.NewGaugeMaps(readyScope),
@@ -72,11 +91,24 @@ func NewMetrics(scope tally.Scope) *Metrics {
 		Decline:           offersScope.Counter("decline"),
 		DeclineFail:       offersScope.Counter("decline_fail"),
 
+		InverseOfferDecline:     inverseOffersScope.Counter("decline"),
+		InverseOfferDeclineFail: inverseOffersScope.Counter("decline_fail"),
+
+		Suppress:        suppressionScope.Counter("suppress"),
+		SuppressFail:    suppressionScope.Counter("suppress_fail"),
+		Revive:          suppressionScope.Counter("revive"),
+		ReviveFail:      suppressionScope.Counter("revive_fail"),
+		WatchdogRevive:  suppressionScope.Counter("watchdog_revive"),
+		SuppressedRoles: suppressionScope.Gauge("suppressed_roles"),
+
 		ReadyHosts:               hostsScope.Gauge("ready"),
 		PlacingHosts:             hostsScope.Gauge("placing"),
 		AvailableHosts:           hostsScope.Gauge("available"),
 		ReturnUnusedHosts:        hostsScope.Counter("return_unused"),
 		ResetExpiredPlacingHosts: hostsScope.Counter("reset_expired_placing"),
 		ResetExpiredHeldHosts:    hostsScope.Counter("reset_expired_held"),
+
+		ListenerChangesDropped: listenersScope.Counter("changes_dropped"),
+		ListenerPanics:         listenersScope.Counter("panics"),
 	}
 }
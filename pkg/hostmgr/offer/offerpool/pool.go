@@ -133,6 +133,13 @@ type Pool interface {
 
 	// ReleaseHoldForTasks release the hold of host for the tasks specified
 	ReleaseHoldForTasks(hostname string, taskIDs []*peloton.TaskID) error
+
+	// AddListener registers a listener to be notified of the changes of
+	// the offers and hosts of the pool
+	AddListener(listener OfferPoolListener)
+
+	// RemoveListener unregisters a listener
+	RemoveListener(listener OfferPoolListener)
 }
 
 const (
@@ -163,7 +170,8 @@ func NewOfferPool(
 	scarceResourceTypes []string,
 	slackResourceTypes []string,
 	binPackingRanker binpacking.Ranker,
-	hostPlacingOfferStatusTimeout time.Duration) Pool {
+	hostPlacingOfferStatusTimeout time.Duration,
+	declineBatcher DeclineBatcher) Pool {
 
 	// GPU is only supported scarce resource type.
 	if !reflect.DeepEqual(supportedScarceResourceTypes, scarceResourceTypes) {
@@ -197,6 +205,7 @@ func NewOfferPool(
 
 		volumeStore:      volumeStore,
 		binPackingRanker: binPackingRanker,
+		declineBatcher:   declineBatcher,
 	}
 
 	return p
@@ -240,9 +249,16 @@ type offerPool struct {
 	// indicate if bin packing is enabled/disabled
 	binPackingRanker binpacking.Ranker
 
+	// declineBatcher declines the unavailable offers, they are declined
+	// right away without it
+	declineBatcher DeclineBatcher
+
 	// taskHeldIndex --- key: task id,
 	// value: host held for the task
 	taskHeldIndex sync.Map
+
+	// listeners are notified of the changes of the offers and hosts
+	listeners listeners
 }
 
 // ClaimForPlace obtains offers from pool conforming to given constraints.
@@ -260,10 +276,16 @@ func (p *offerPool) ClaimForPlace(hostFilter *hostsvc.HostFilter) (
 		hostFilter,
 		constraints.NewEvaluator(task.LabelConstraint_HOST))
 
+	tryMatch := func(hs summary.HostSummary) {
+		p.listeners.trackHostStatus(hs, func() {
+			matcher.tryMatch(hs.GetHostname(), hs)
+		})
+	}
+
 	// if host hint is provided, try to return the hosts in hints first
 	for _, filterHints := range hostFilter.GetHint().GetHostHint() {
 		if hs, ok := p.hostOfferIndex[filterHints.GetHostname()]; ok {
-			matcher.tryMatch(hs.GetHostname(), hs)
+			tryMatch(hs)
 			if matcher.HasEnoughHosts() {
 				break
 			}
@@ -279,7 +301,7 @@ func (p *offerPool) ClaimForPlace(hostFilter *hostsvc.HostFilter) (
 		sortedSummaryList = p.getRankedHostSummaryList(p.hostOfferIndex)
 	}
 	for _, s := range sortedSummaryList {
-		matcher.tryMatch(s.(summary.HostSummary).GetHostname(), s.(summary.HostSummary))
+		tryMatch(s.(summary.HostSummary))
 		if matcher.HasEnoughHosts() {
 			break
 		}
@@ -325,11 +347,13 @@ func (p *offerPool) ClaimForLaunch(
 		return nil, errors.New("cannot find input hostname " + hostname)
 	}
 
-	if useReservedOffers {
-		offerMap, err = hs.ClaimReservedOffersForLaunch()
-	} else {
-		offerMap, err = hs.ClaimForLaunch(hostOfferID, taskIDs...)
-	}
+	p.listeners.trackHostStatus(hs, func() {
+		if useReservedOffers {
+			offerMap, err = hs.ClaimReservedOffersForLaunch()
+		} else {
+			offerMap, err = hs.ClaimForLaunch(hostOfferID, taskIDs...)
+		}
+	})
 
 	if err != nil {
 		return nil, err
@@ -340,6 +364,7 @@ func (p *offerPool) ClaimForLaunch(
 	}
 
 	for id := range offerMap {
+		p.listeners.offerRemoved(hostname, id, "offer is claimed for launch.")
 		if _, ok := p.timedOffers.Load(id); ok {
 			// Remove offer from the offerid -> hostname map.
 			p.timedOffers.Delete(id)
@@ -405,7 +430,11 @@ func (p *offerPool) AddOffers(
 		log.
 			WithField("unavailable_offers", unavailableOffers).
 			Debug("Offer unavailable due to maintenance on these hosts.")
-		p.DeclineOffers(ctx, unavailableOffers)
+		if p.declineBatcher != nil {
+			p.declineBatcher.Decline(DeclineReasonMaintenance, unavailableOffers)
+		} else {
+			p.DeclineOffers(ctx, unavailableOffers)
+		}
 	}
 	log.
 		WithField("acceptable_offers", acceptableOffers).
@@ -437,6 +466,7 @@ func (p *offerPool) AddOffers(
 		}(hostname, offers)
 	}
 	wg.Wait()
+	p.listeners.offerAdded(acceptableOffers)
 
 	return acceptableOffers
 }
@@ -462,8 +492,11 @@ func (p *offerPool) removeOffer(offerID, reason string) {
 			"offer_id": offerID,
 		}).Warn("host not found in hostOfferIndex")
 	} else {
-		hostOffers.RemoveMesosOffer(offerID, reason)
+		p.listeners.trackHostStatus(hostOffers, func() {
+			hostOffers.RemoveMesosOffer(offerID, reason)
+		})
 	}
+	p.listeners.offerRemoved(hostName, offerID, reason)
 }
 
 // RescindOffer is a callback event when Mesos Master rescinds a offer.
@@ -521,6 +554,8 @@ func (p *offerPool) Clear() {
 	log.Info("Clean up offerpool.")
 	p.timedOffers.Range(func(key interface{}, value interface{}) bool {
 		p.timedOffers.Delete(key)
+		p.listeners.offerRemoved(
+			value.(*TimedOffer).Hostname, key.(string), "offer pool is cleared.")
 		return true
 	})
 	p.hostOfferIndex = map[string]summary.HostSummary{}
@@ -533,10 +568,9 @@ func (p *offerPool) DeclineOffers(
 	p.RLock()
 	defer p.RUnlock()
 
-	callType := sched.Call_DECLINE
 	msg := &sched.Call{
 		FrameworkId: p.mesosFrameworkInfoProvider.GetFrameworkID(ctx),
-		Type:        &callType,
+		Type:        sched.Call_DECLINE.Enum(),
 		Decline: &sched.Call_Decline{
 			OfferIds: offerIDs,
 		},
@@ -575,7 +609,10 @@ func (p *offerPool) ReturnUnusedOffers(hostname string) error {
 		return nil
 	}
 
-	err := hostOffers.ReturnPlacingHost()
+	var err error
+	p.listeners.trackHostStatus(hostOffers, func() {
+		err = hostOffers.ReturnPlacingHost()
+	})
 	if err != nil {
 		return err
 	}
@@ -592,7 +629,13 @@ func (p *offerPool) ResetExpiredPlacingHostSummaries(now time.Time) []string {
 	defer p.RUnlock()
 	var resetHostnames []string
 	for hostname, summ := range p.hostOfferIndex {
-		if reset, res, taskExpired := summ.ResetExpiredPlacingOfferStatus(now); reset {
+		var reset bool
+		var res scalar.Resources
+		var taskExpired []*peloton.TaskID
+		p.listeners.trackHostStatus(summ, func() {
+			reset, res, taskExpired = summ.ResetExpiredPlacingOfferStatus(now)
+		})
+		if reset {
 			resetHostnames = append(resetHostnames, hostname)
 			for _, task := range taskExpired {
 				p.removeTaskHold(hostname, task)
@@ -616,7 +659,13 @@ func (p *offerPool) ResetExpiredHeldHostSummaries(now time.Time) []string {
 	defer p.RUnlock()
 	var resetHostnames []string
 	for hostname, summ := range p.hostOfferIndex {
-		if reset, res, taskExpired := summ.ResetExpiredHostHeldStatus(now); reset {
+		var reset bool
+		var res scalar.Resources
+		var taskExpired []*peloton.TaskID
+		p.listeners.trackHostStatus(summ, func() {
+			reset, res, taskExpired = summ.ResetExpiredHostHeldStatus(now)
+		})
+		if reset {
 			resetHostnames = append(resetHostnames, hostname)
 			for _, task := range taskExpired {
 				p.removeTaskHold(hostname, task)
@@ -791,13 +840,15 @@ func (p *offerPool) HoldForTasks(hostname string, taskIDs []*peloton.TaskID) err
 	}
 
 	var errs []error
-	for _, taskID := range taskIDs {
-		if err := hs.HoldForTask(taskID); err != nil {
-			errs = append(errs, err)
-		} else {
-			p.addTaskHold(hostname, taskID)
+	p.listeners.trackHostStatus(hs, func() {
+		for _, taskID := range taskIDs {
+			if err := hs.HoldForTask(taskID); err != nil {
+				errs = append(errs, err)
+			} else {
+				p.addTaskHold(hostname, taskID)
+			}
 		}
-	}
+	})
 
 	if len(errs) != 0 {
 		return multierr.Combine(errs...)
@@ -814,13 +865,15 @@ func (p *offerPool) ReleaseHoldForTasks(hostname string, taskIDs []*peloton.Task
 	}
 
 	var errs []error
-	for _, taskID := range taskIDs {
-		if err := hs.ReleaseHoldForTask(taskID); err != nil {
-			errs = append(errs, err)
-		} else {
-			p.removeTaskHold(hostname, taskID)
+	p.listeners.trackHostStatus(hs, func() {
+		for _, taskID := range taskIDs {
+			if err := hs.ReleaseHoldForTask(taskID); err != nil {
+				errs = append(errs, err)
+			} else {
+				p.removeTaskHold(hostname, taskID)
+			}
 		}
-	}
+	})
 
 	if len(errs) != 0 {
 		return multierr.Combine(errs...)
@@ -829,6 +882,22 @@ func (p *offerPool) ReleaseHoldForTasks(hostname string, taskIDs []*peloton.Task
 	return nil
 }
 
+// AddListener registers a listener to be notified of the changes of the
+// offers and hosts of the pool
+func (p *offerPool) AddListener(listener OfferPoolListener) {
+	p.listeners.add(listener, p.metrics)
+	log.WithField("listener", listener.Name()).
+		Info("Offer pool listener added")
+}
+
+// RemoveListener unregisters a listener, the changes already queued for it
+// are still delivered
+func (p *offerPool) RemoveListener(listener OfferPoolListener) {
+	p.listeners.remove(listener)
+	log.WithField("listener", listener.Name()).
+		Info("Offer pool listener removed")
+}
+
 // addTaskHold update the index when a host is held for a task
 func (p *offerPool) addTaskHold(hostname string, id *peloton.TaskID) {
 	oldHost, loaded := p.taskHeldIndex.LoadOrStore(id.GetValue(), hostname)
@@ -226,6 +226,7 @@ func (suite *OfferPoolTestSuite) TestSlackResourceTypes() {
 		[]string{common.MesosCPU, "DUMMY"},
 		binpacking.CreateRanker("DEFRAG"),
 		time.Duration(30*time.Second),
+		nil,
 	)
 	suite.True(hmutil.IsSlackResourceType(
 		common.MesosCPU,
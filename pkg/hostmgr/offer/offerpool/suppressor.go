@@ -0,0 +1,362 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offerpool
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/pkg/common/lifecycle"
+	hostmgr_mesos "github.com/uber/peloton/pkg/hostmgr/mesos"
+	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	_defaultSuppressQuietPeriod = 5 * time.Minute
+	_defaultMaxSuppressDuration = 30 * time.Minute
+	_defaultSuppressCheckPeriod = 10 * time.Second
+	_suppressionStateRevived    = "REVIVED"
+	_suppressionStateSuppressed = "SUPPRESSED"
+)
+
+// SuppressorConfig is the configuration of the suppression of offers per
+// role
+type SuppressorConfig struct {
+	// Enabled enables suppressing the offers of the roles without demand
+	Enabled bool `yaml:"enabled"`
+
+	// QuietPeriod is how long a role has to be without demand, and revived,
+	// before its offers are suppressed
+	QuietPeriod time.Duration `yaml:"quiet_period"`
+
+	// MaxSuppressDuration is how long the offers of a role stay suppressed
+	// at most, they are revived afterwards even without demand
+	MaxSuppressDuration time.Duration `yaml:"max_suppress_duration"`
+
+	// CheckPeriod is the period the roles are checked for suppression
+	CheckPeriod time.Duration `yaml:"check_period"`
+}
+
+// RoleSuppressionState is the suppression state of the offers of a role
+type RoleSuppressionState struct {
+	Role string `json:"role"`
+	// State is either SUPPRESSED or REVIVED
+	State string `json:"state"`
+	// Since is when the role entered State
+	Since time.Time `json:"since"`
+	// LastDemand is when the last demand of offers was received for the
+	// role, zero if none was received since the start
+	LastDemand time.Time `json:"last_demand"`
+}
+
+// Suppressor suppresses the offers of the roles without placement demand,
+// and revives them as soon as there is demand again
+type Suppressor interface {
+	// Start starts checking the roles for suppression. All the roles are
+	// considered revived at start.
+	Start()
+
+	// Stop stops checking the roles for suppression.
+	Stop()
+
+	// DemandReceived records a demand of offers for role, which revives
+	// its offers if they are suppressed.
+	DemandReceived(role string)
+
+	// GetState returns the suppression state of all the roles.
+	GetState() []*RoleSuppressionState
+}
+
+// roleState is the suppression state of a role
+type roleState struct {
+	// suppressed is true once the offers of the role are suppressed, and
+	// until they are revived successfully
+	suppressed bool
+	since      time.Time
+	lastDemand time.Time
+}
+
+// suppressor implements Suppressor
+type suppressor struct {
+	sync.Mutex
+
+	config                SuppressorConfig
+	schedulerClient       mpb.SchedulerClient
+	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider
+	metrics               *Metrics
+	now                   func() time.Time
+	lifeCycle             lifecycle.LifeCycle
+
+	roles map[string]*roleState
+
+	// demand is signaled when a suppressed role receives a demand, for it
+	// to be revived without waiting for the next check
+	demand chan struct{}
+}
+
+// NewSuppressor creates a Suppressor of the offers of roles. The roles
+// receiving demand are added to the roles.
+func NewSuppressor(
+	config *SuppressorConfig,
+	roles []string,
+	schedulerClient mpb.SchedulerClient,
+	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider,
+	metrics *Metrics) Suppressor {
+	s := &suppressor{
+		schedulerClient:       schedulerClient,
+		frameworkInfoProvider: frameworkInfoProvider,
+		metrics:               metrics,
+		now:                   time.Now,
+		lifeCycle:             lifecycle.NewLifeCycle(),
+		roles:                 make(map[string]*roleState),
+		demand:                make(chan struct{}, 1),
+	}
+	if config != nil {
+		s.config = *config
+	}
+	if s.config.QuietPeriod <= 0 {
+		s.config.QuietPeriod = _defaultSuppressQuietPeriod
+	}
+	if s.config.MaxSuppressDuration <= 0 {
+		s.config.MaxSuppressDuration = _defaultMaxSuppressDuration
+	}
+	if s.config.CheckPeriod <= 0 {
+		s.config.CheckPeriod = _defaultSuppressCheckPeriod
+	}
+	for _, role := range roles {
+		s.roles[role] = &roleState{}
+	}
+	return s
+}
+
+// Start starts checking the roles for suppression
+func (s *suppressor) Start() {
+	if !s.config.Enabled {
+		return
+	}
+	if !s.lifeCycle.Start() {
+		log.Warn("Offer suppressor is already running, no action will be performed")
+		return
+	}
+
+	// The offer handlers are started once the framework is subscribed, and
+	// Mesos revives the offers of all the roles of a framework subscribing.
+	s.Lock()
+	now := s.now()
+	for _, state := range s.roles {
+		state.suppressed = false
+		state.since = now
+	}
+	s.metrics.SuppressedRoles.Update(0)
+	s.Unlock()
+
+	started := make(chan int, 1)
+	go func() {
+		defer s.lifeCycle.StopComplete()
+
+		log.Info("Starting offer suppression loop")
+		close(started)
+
+		for {
+			timer := time.NewTimer(s.config.CheckPeriod)
+			select {
+			case <-s.lifeCycle.StopCh():
+				timer.Stop()
+				log.Info("Exiting the offer suppression loop")
+				return
+			case <-s.demand:
+			case <-timer.C:
+			}
+			timer.Stop()
+			s.check()
+		}
+	}()
+	// Wait until go routine is started
+	<-started
+}
+
+// Stop stops checking the roles for suppression
+func (s *suppressor) Stop() {
+	if !s.config.Enabled {
+		return
+	}
+	if !s.lifeCycle.Stop() {
+		log.Warn("Offer suppressor is already stopped, no action will be performed")
+		return
+	}
+
+	log.Info("Stopping offer suppressor")
+	s.lifeCycle.Wait()
+	log.Info("Offer suppressor stopped")
+}
+
+// DemandReceived records a demand of offers for role
+func (s *suppressor) DemandReceived(role string) {
+	if !s.config.Enabled {
+		return
+	}
+
+	s.Lock()
+	state, ok := s.roles[role]
+	if !ok {
+		state = &roleState{since: s.now()}
+		s.roles[role] = state
+	}
+	state.lastDemand = s.now()
+	suppressed := state.suppressed
+	s.Unlock()
+
+	if suppressed {
+		select {
+		case s.demand <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// GetState returns the suppression state of all the roles, sorted by role
+func (s *suppressor) GetState() []*RoleSuppressionState {
+	s.Lock()
+	defer s.Unlock()
+
+	result := make([]*RoleSuppressionState, 0, len(s.roles))
+	for role, state := range s.roles {
+		roleState := &RoleSuppressionState{
+			Role:       role,
+			State:      _suppressionStateRevived,
+			Since:      state.since,
+			LastDemand: state.lastDemand,
+		}
+		if state.suppressed {
+			roleState.State = _suppressionStateSuppressed
+		}
+		result = append(result, roleState)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Role < result[j].Role
+	})
+	return result
+}
+
+// check suppresses the offers of the roles which have been quiet for the
+// quiet period, then revives the offers of the suppressed roles which
+// received demand or have been suppressed for the max suppress duration.
+// The quiet period starts at the last demand or revive, whichever is later,
+// so that a revived role is not suppressed again right away. The Mesos
+// calls are made without holding the lock, so that demand is not blocked
+// on them.
+func (s *suppressor) check() {
+	var toSuppress []string
+	s.Lock()
+	now := s.now()
+	for role, state := range s.roles {
+		if state.suppressed {
+			continue
+		}
+		quietSince := state.since
+		if state.lastDemand.After(quietSince) {
+			quietSince = state.lastDemand
+		}
+		if now.Sub(quietSince) >= s.config.QuietPeriod {
+			toSuppress = append(toSuppress, role)
+		}
+	}
+	s.Unlock()
+
+	for _, role := range toSuppress {
+		if err := s.call(role, true); err != nil {
+			log.WithError(err).
+				WithField("role", role).
+				Warn("Failed to suppress offers")
+			s.metrics.SuppressFail.Inc(1)
+			continue
+		}
+		log.WithField("role", role).Info("Offers suppressed")
+		s.metrics.Suppress.Inc(1)
+		s.Lock()
+		s.roles[role].suppressed = true
+		s.roles[role].since = now
+		s.Unlock()
+	}
+
+	// The demand received while suppressing is seen here, as its time is
+	// not before the suppression.
+	var toRevive []string
+	var watchdog []bool
+	s.Lock()
+	now = s.now()
+	for role, state := range s.roles {
+		if !state.suppressed {
+			continue
+		}
+		if !state.lastDemand.Before(state.since) {
+			toRevive = append(toRevive, role)
+			watchdog = append(watchdog, false)
+		} else if now.Sub(state.since) >= s.config.MaxSuppressDuration {
+			toRevive = append(toRevive, role)
+			watchdog = append(watchdog, true)
+		}
+	}
+	s.Unlock()
+
+	// A role whose revive fails stays suppressed, and is revived again at
+	// the next check.
+	for i, role := range toRevive {
+		if err := s.call(role, false); err != nil {
+			log.WithError(err).
+				WithField("role", role).
+				Warn("Failed to revive offers")
+			s.metrics.ReviveFail.Inc(1)
+			continue
+		}
+		if watchdog[i] {
+			log.WithField("role", role).
+				Warn("Offers revived after max suppress duration")
+			s.metrics.WatchdogRevive.Inc(1)
+		} else {
+			log.WithField("role", role).Info("Offers revived on demand")
+		}
+		s.metrics.Revive.Inc(1)
+		s.Lock()
+		s.roles[role].suppressed = false
+		s.roles[role].since = now
+		s.Unlock()
+	}
+
+	s.Lock()
+	suppressed := 0
+	for _, state := range s.roles {
+		if state.suppressed {
+			suppressed++
+		}
+	}
+	s.Unlock()
+	s.metrics.SuppressedRoles.Update(float64(suppressed))
+}
+
+// call suppresses the offers of role, or revives them if suppress is false
+func (s *suppressor) call(role string, suppress bool) error {
+	ctx := context.Background()
+	frameworkID := s.frameworkInfoProvider.GetFrameworkID(ctx)
+	streamID := s.frameworkInfoProvider.GetMesosStreamID(ctx)
+	if suppress {
+		return s.schedulerClient.Suppress(streamID, frameworkID, []string{role})
+	}
+	return s.schedulerClient.Revive(streamID, frameworkID, []string{role})
+}
@@ -0,0 +1,251 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package offerpool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+
+	"github.com/uber/peloton/pkg/common/util"
+	hostmgr_mesos_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/mocks"
+	mpb_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+const (
+	_suppressorRole     = "peloton"
+	_suppressorStreamID = "stream-id"
+	_quietPeriod        = 5 * time.Minute
+	_maxSuppress        = 30 * time.Minute
+)
+
+type suppressorTestSuite struct {
+	suite.Suite
+
+	ctrl            *gomock.Controller
+	schedulerClient *mpb_mocks.MockSchedulerClient
+	provider        *hostmgr_mesos_mocks.MockFrameworkInfoProvider
+	scope           tally.TestScope
+	frameworkID     *mesos.FrameworkID
+	now             time.Time
+	suppressor      *suppressor
+}
+
+func (suite *suppressorTestSuite) SetupTest() {
+	suite.ctrl = gomock.NewController(suite.T())
+	suite.schedulerClient = mpb_mocks.NewMockSchedulerClient(suite.ctrl)
+	suite.provider = hostmgr_mesos_mocks.NewMockFrameworkInfoProvider(suite.ctrl)
+	suite.scope = tally.NewTestScope("", nil)
+	suite.frameworkID = &mesos.FrameworkID{Value: util.PtrStr("framework-id")}
+	suite.now = time.Unix(1500000000, 0)
+
+	suite.suppressor = NewSuppressor(
+		&SuppressorConfig{
+			Enabled:             true,
+			QuietPeriod:         _quietPeriod,
+			MaxSuppressDuration: _maxSuppress,
+		},
+		[]string{_suppressorRole},
+		suite.schedulerClient,
+		suite.provider,
+		NewMetrics(suite.scope),
+	).(*suppressor)
+	suite.suppressor.now = func() time.Time { return suite.now }
+	suite.suppressor.roles[_suppressorRole].since = suite.now
+
+	suite.provider.EXPECT().
+		GetFrameworkID(gomock.Any()).
+		Return(suite.frameworkID).
+		AnyTimes()
+	suite.provider.EXPECT().
+		GetMesosStreamID(gomock.Any()).
+		Return(_suppressorStreamID).
+		AnyTimes()
+}
+
+func (suite *suppressorTestSuite) TearDownTest() {
+	suite.ctrl.Finish()
+}
+
+func TestSuppressor(t *testing.T) {
+	suite.Run(t, new(suppressorTestSuite))
+}
+
+func (suite *suppressorTestSuite) counter(name string) int64 {
+	counter, ok := suite.scope.Snapshot().Counters()[name+"+"]
+	if !ok {
+		return 0
+	}
+	return counter.Value()
+}
+
+func (suite *suppressorTestSuite) state() string {
+	states := suite.suppressor.GetState()
+	suite.Len(states, 1)
+	return states[0].State
+}
+
+func (suite *suppressorTestSuite) expectSuppress(err error) {
+	suite.schedulerClient.EXPECT().
+		Suppress(_suppressorStreamID, suite.frameworkID, []string{_suppressorRole}).
+		Return(err)
+}
+
+func (suite *suppressorTestSuite) expectRevive(err error) {
+	suite.schedulerClient.EXPECT().
+		Revive(_suppressorStreamID, suite.frameworkID, []string{_suppressorRole}).
+		Return(err)
+}
+
+// suppress suppresses the role after the quiet period
+func (suite *suppressorTestSuite) suppress() {
+	suite.now = suite.now.Add(_quietPeriod)
+	suite.expectSuppress(nil)
+	suite.suppressor.check()
+	suite.Equal(_suppressionStateSuppressed, suite.state())
+}
+
+// TestSuppressAfterQuietPeriod tests that a role is suppressed only once
+// it had no demand for the quiet period
+func (suite *suppressorTestSuite) TestSuppressAfterQuietPeriod() {
+	suite.now = suite.now.Add(_quietPeriod / 2)
+	suite.suppressor.DemandReceived(_suppressorRole)
+
+	// quiet since the demand for less than the quiet period
+	suite.now = suite.now.Add(_quietPeriod - time.Second)
+	suite.suppressor.check()
+	suite.Equal(_suppressionStateRevived, suite.state())
+
+	suite.now = suite.now.Add(time.Second)
+	suite.expectSuppress(nil)
+	suite.suppressor.check()
+	suite.Equal(_suppressionStateSuppressed, suite.state())
+	suite.Equal(int64(1), suite.counter("pool.suppression.suppress"))
+
+	// no more call once suppressed
+	suite.now = suite.now.Add(_quietPeriod)
+	suite.suppressor.check()
+}
+
+// TestSuppressFailure tests that a role stays revived when it cannot be
+// suppressed, and that suppressing it is retried
+func (suite *suppressorTestSuite) TestSuppressFailure() {
+	suite.now = suite.now.Add(_quietPeriod)
+	suite.expectSuppress(errors.New("mesos unavailable"))
+	suite.suppressor.check()
+	suite.Equal(_suppressionStateRevived, suite.state())
+	suite.Equal(int64(1), suite.counter("pool.suppression.suppress_fail"))
+
+	suite.expectSuppress(nil)
+	suite.suppressor.check()
+	suite.Equal(_suppressionStateSuppressed, suite.state())
+}
+
+// TestReviveOnDemand tests that a suppressed role is revived on demand, and
+// not suppressed again before the quiet period since the revive
+func (suite *suppressorTestSuite) TestReviveOnDemand() {
+	suite.suppress()
+
+	suite.now = suite.now.Add(time.Minute)
+	suite.suppressor.DemandReceived(_suppressorRole)
+	select {
+	case <-suite.suppressor.demand:
+	default:
+		suite.Fail("demand of suppressed role not signaled")
+	}
+
+	suite.expectRevive(nil)
+	suite.suppressor.check()
+	suite.Equal(_suppressionStateRevived, suite.state())
+	suite.Equal(int64(1), suite.counter("pool.suppression.revive"))
+	suite.Equal(int64(0), suite.counter("pool.suppression.watchdog_revive"))
+
+	// hysteresis: the quiet period restarts at the revive
+	suite.now = suite.now.Add(_quietPeriod - time.Second)
+	suite.suppressor.check()
+	suite.Equal(_suppressionStateRevived, suite.state())
+}
+
+// TestReviveFailureRetried tests that a role stays suppressed when it cannot
+// be revived, and that reviving it is retried at the next check
+func (suite *suppressorTestSuite) TestReviveFailureRetried() {
+	suite.suppress()
+
+	suite.suppressor.DemandReceived(_suppressorRole)
+	suite.expectRevive(errors.New("mesos unavailable"))
+	suite.suppressor.check()
+	suite.Equal(_suppressionStateSuppressed, suite.state())
+	suite.Equal(int64(1), suite.counter("pool.suppression.revive_fail"))
+
+	suite.now = suite.now.Add(time.Second)
+	suite.expectRevive(nil)
+	suite.suppressor.check()
+	suite.Equal(_suppressionStateRevived, suite.state())
+}
+
+// TestWatchdogRevive tests that a role suppressed for the max suppress
+// duration is revived without demand
+func (suite *suppressorTestSuite) TestWatchdogRevive() {
+	suite.suppress()
+
+	suite.now = suite.now.Add(_maxSuppress - time.Second)
+	suite.suppressor.check()
+	suite.Equal(_suppressionStateSuppressed, suite.state())
+
+	suite.now = suite.now.Add(time.Second)
+	suite.expectRevive(nil)
+	suite.suppressor.check()
+	suite.Equal(_suppressionStateRevived, suite.state())
+	suite.Equal(int64(1), suite.counter("pool.suppression.watchdog_revive"))
+}
+
+// TestDemandForNewRole tests that the roles receiving demand are tracked
+func (suite *suppressorTestSuite) TestDemandForNewRole() {
+	suite.suppressor.DemandReceived("other")
+
+	states := suite.suppressor.GetState()
+	suite.Len(states, 2)
+	suite.Equal("other", states[0].Role)
+	suite.Equal(_suppressionStateRevived, states[0].State)
+	suite.Equal(suite.now, states[0].LastDemand)
+	suite.Equal(_suppressorRole, states[1].Role)
+}
+
+// TestDisabled tests that nothing is tracked nor suppressed when the
+// suppression is disabled
+func (suite *suppressorTestSuite) TestDisabled() {
+	suite.suppressor.config.Enabled = false
+	suite.suppressor.Start()
+	suite.suppressor.DemandReceived("other")
+	suite.Len(suite.suppressor.GetState(), 1)
+	suite.suppressor.Stop()
+}
+
+// TestStartStop tests that the roles are revived at start, since Mesos
+// revives the offers of a subscribing framework
+func (suite *suppressorTestSuite) TestStartStop() {
+	suite.suppressor.config.CheckPeriod = time.Hour
+	suite.suppressor.roles[_suppressorRole].suppressed = true
+
+	suite.suppressor.Start()
+	suite.Equal(_suppressionStateRevived, suite.state())
+	suite.suppressor.Stop()
+}
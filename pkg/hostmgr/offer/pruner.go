@@ -15,12 +15,12 @@
 package offer
 
 import (
-	"context"
 	"time"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
 
 	"github.com/uber/peloton/pkg/common/lifecycle"
+	"github.com/uber/peloton/pkg/common/util"
 	"github.com/uber/peloton/pkg/hostmgr/offer/offerpool"
 
 	log "github.com/sirupsen/logrus"
@@ -42,11 +42,13 @@ func NewOfferPruner(
 	pool offerpool.Pool,
 	offerPruningPeriod time.Duration,
 	metrics *offerpool.Metrics,
+	declineBatcher offerpool.DeclineBatcher,
 ) Pruner {
 	pruner := &offerPruner{
 		pool:               pool,
 		offerPruningPeriod: offerPruningPeriod,
 		metrics:            metrics,
+		declineBatcher:     declineBatcher,
 		lifeCycle:          lifecycle.NewLifeCycle(),
 	}
 	return pruner
@@ -57,6 +59,7 @@ type offerPruner struct {
 	pool               offerpool.Pool
 	offerPruningPeriod time.Duration
 	metrics            *offerpool.Metrics
+	declineBatcher     offerpool.DeclineBatcher
 	lifeCycle          lifecycle.LifeCycle // lifecycle manager
 }
 
@@ -86,15 +89,12 @@ func (p *offerPruner) Start() {
 				if len(expiredOffers) != 0 {
 					var offerIDs []*mesos.OfferID
 					for id := range expiredOffers {
-						tmp := id
 						offerIDs = append(offerIDs, &mesos.OfferID{
-							Value: &tmp,
+							Value: util.PtrStr(id),
 						})
 					}
 					log.WithField("offers", offerIDs).Debug("Offers to decline")
-					if err := p.pool.DeclineOffers(context.Background(), offerIDs); err != nil {
-						log.WithError(err).Error("Failed to decline offers")
-					}
+					p.declineBatcher.Decline(offerpool.DeclineReasonExpired, offerIDs)
 				}
 			}
 			timer.Stop()
@@ -37,6 +37,12 @@ type TaskReconciler interface {
 	SetExplicitReconcileTurn(flag bool)
 }
 
+// ExplicitReconciler reconciles tasks explicitly until they get a status
+// update, such as hostmgr_mesos.Reconciler.
+type ExplicitReconciler interface {
+	ReconcileTasks(tasks []*sched.Call_Reconcile_Task)
+}
+
 // taskReconciler implements TaskReconciler.
 type taskReconciler struct {
 	metrics *Metrics
@@ -45,6 +51,9 @@ type taskReconciler struct {
 	jobStore              storage.JobStore
 	taskStore             storage.TaskStore
 	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider
+	// explicitReconciler, if set, is given the non-terminal tasks of the
+	// explicit rounds, and runs the implicit reconciliations itself
+	explicitReconciler ExplicitReconciler
 
 	explicitReconcileBatchInterval time.Duration
 	explicitReconcileBatchSize     int
@@ -54,14 +63,16 @@ type taskReconciler struct {
 	isExplicitReconcileTurn atomic.Bool
 }
 
-// NewTaskReconciler initialize the task reconciler.
+// NewTaskReconciler initialize the task reconciler. The explicit rounds
+// are delegated to explicitReconciler unless it is nil.
 func NewTaskReconciler(
 	client mpb.SchedulerClient,
 	parent tally.Scope,
 	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider,
 	jobStore storage.JobStore,
 	taskStore storage.TaskStore,
-	cfg *TaskReconcilerConfig) TaskReconciler {
+	cfg *TaskReconcilerConfig,
+	explicitReconciler ExplicitReconciler) TaskReconciler {
 
 	reconciler := &taskReconciler{
 		schedulerClient:       client,
@@ -69,6 +80,7 @@ func NewTaskReconciler(
 		taskStore:             taskStore,
 		metrics:               NewMetrics(parent.SubScope("reconcile")),
 		frameworkInfoProvider: frameworkInfoProvider,
+		explicitReconciler:    explicitReconciler,
 		explicitReconcileBatchInterval: time.Duration(
 			cfg.ExplicitReconcileBatchIntervalSec) * time.Second,
 		explicitReconcileBatchSize: cfg.ExplicitReconcileBatchSize,
@@ -82,6 +94,11 @@ func (r *taskReconciler) Reconcile(running *atomic.Bool) {
 	// Explicit and implicit reconcile might overlap if we have more than 360K
 	// tasks to reconcile.
 	ctx := context.Background()
+	if r.explicitReconciler != nil {
+		// the explicit reconciler reconciles implicitly on its own
+		go r.reconcileExplicitly(ctx, running)
+		return
+	}
 	if r.isExplicitReconcileTurn.Toggle() {
 		go r.reconcileExplicitly(ctx, running)
 	} else {
@@ -140,6 +157,14 @@ func (r *taskReconciler) reconcileExplicitly(
 	log.WithField("reconcile_tasks_total", reconcileTasksLen).
 		Info("Total number of tasks to reconcile explicitly.")
 
+	if r.explicitReconciler != nil {
+		r.explicitReconciler.ReconcileTasks(reconcileTasks)
+		r.metrics.ExplicitTasksPerRun.Update(float64(reconcileTasksLen))
+		r.metrics.ReconcileExplicitly.Inc(1)
+		log.Info("Reconcile tasks explicitly delegated.")
+		return
+	}
+
 	frameworkID := r.frameworkInfoProvider.GetFrameworkID(ctx)
 	streamID := r.frameworkInfoProvider.GetMesosStreamID(ctx)
 	callType := sched.Call_RECONCILE
@@ -171,10 +171,54 @@ func (suite *TaskReconcilerTestSuite) TestNewTaskReconciler() {
 			ExplicitReconcileBatchIntervalSec: int(explicitReconcileBatchInterval / time.Millisecond),
 			ExplicitReconcileBatchSize:        testBatchSize,
 		},
+		nil,
 	)
 	suite.NotNil(reconciler)
 }
 
+// fakeExplicitReconciler records the tasks given to it
+type fakeExplicitReconciler struct {
+	tasks chan []*sched.Call_Reconcile_Task
+}
+
+func (r *fakeExplicitReconciler) ReconcileTasks(
+	tasks []*sched.Call_Reconcile_Task) {
+	r.tasks <- tasks
+}
+
+// TestTaskReconcilationDelegated tests that the explicit rounds hand the
+// non-terminal tasks to the explicit reconciler, without any implicit round
+func (suite *TaskReconcilerTestSuite) TestTaskReconcilationDelegated() {
+	explicitReconciler := &fakeExplicitReconciler{
+		tasks: make(chan []*sched.Call_Reconcile_Task, 1),
+	}
+	suite.reconciler.explicitReconciler = explicitReconciler
+	gomock.InOrder(
+		suite.mockJobStore.EXPECT().
+			GetJobsByStates(context.Background(), _nonTerminalJobStates).
+			Return([]peloton.JobID{*suite.testJobID}, nil),
+		suite.mockTaskStore.EXPECT().
+			GetTasksForJobAndStates(
+				context.Background(),
+				suite.testJobID,
+				[]task.TaskState{
+					task.TaskState_LAUNCHED,
+					task.TaskState_STARTING,
+					task.TaskState_RUNNING,
+					task.TaskState_KILLING,
+				}).
+			Return(suite.taskInfos, nil),
+	)
+
+	suite.running.Store(true)
+	suite.reconciler.Reconcile(&suite.running)
+	tasks := <-explicitReconciler.tasks
+	suite.Equal(testInstanceCount, len(tasks))
+	suite.Equal(testAgentID, tasks[0].GetAgentId().GetValue())
+	// the turn is not toggled to an implicit round
+	suite.True(suite.reconciler.isExplicitReconcileTurn.Load())
+}
+
 func (suite *TaskReconcilerTestSuite) TestTaskReconcilationPeriodicalCalls() {
 	// need to sync the goroutine created by taskReconciler.Reconcile
 	// and the test goroutine to avoid data race in test
@@ -23,14 +23,12 @@ import (
 	"github.com/uber-go/tally"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
-	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
 
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	"github.com/uber/peloton/.gen/peloton/api/v0/volume"
 	"github.com/uber/peloton/pkg/hostmgr/factory/operation"
 	hostmgrmesos "github.com/uber/peloton/pkg/hostmgr/mesos"
-	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
 	"github.com/uber/peloton/pkg/hostmgr/offer/offerpool"
 	"github.com/uber/peloton/pkg/hostmgr/reservation"
 	"github.com/uber/peloton/pkg/hostmgr/summary"
@@ -48,11 +46,12 @@ type Cleaner interface {
 
 // cleaner implements interface Cleaner to recycle reserved resources.
 type cleaner struct {
-	offerPool                  offerpool.Pool
-	scope                      tally.Scope
-	volumeStore                storage.PersistentVolumeStore
-	mSchedulerClient           mpb.SchedulerClient
-	mesosFrameworkInfoProvider hostmgrmesos.FrameworkInfoProvider
+	offerPool       offerpool.Pool
+	scope           tally.Scope
+	volumeStore     storage.PersistentVolumeStore
+	schedulerDriver hostmgrmesos.SchedulerDriver
+	mesosDetector   hostmgrmesos.MasterDetector
+	callSender      hostmgrmesos.CallSender
 }
 
 // NewCleaner initializes the reservation resource cleaner.
@@ -60,15 +59,17 @@ func NewCleaner(
 	pool offerpool.Pool,
 	scope tally.Scope,
 	volumeStore storage.PersistentVolumeStore,
-	schedulerClient mpb.SchedulerClient,
-	frameworkInfoProvider hostmgrmesos.FrameworkInfoProvider) Cleaner {
+	schedulerDriver hostmgrmesos.SchedulerDriver,
+	mesosDetector hostmgrmesos.MasterDetector,
+	callSender hostmgrmesos.CallSender) Cleaner {
 
 	return &cleaner{
-		offerPool:                  pool,
-		scope:                      scope,
-		volumeStore:                volumeStore,
-		mSchedulerClient:           schedulerClient,
-		mesosFrameworkInfoProvider: frameworkInfoProvider,
+		offerPool:       pool,
+		scope:           scope,
+		volumeStore:     volumeStore,
+		schedulerDriver: schedulerDriver,
+		mesosDetector:   mesosDetector,
+		callSender:      callSender,
 	}
 }
 
@@ -205,21 +206,21 @@ func (c *cleaner) callMesosForOfferOperations(
 		return err
 	}
 
-	callType := sched.Call_ACCEPT
-	msg := &sched.Call{
-		FrameworkId: c.mesosFrameworkInfoProvider.GetFrameworkID(ctx),
-		Type:        &callType,
-		Accept: &sched.Call_Accept{
-			OfferIds:   []*mesos.OfferID{offer.GetId()},
-			Operations: offerOperations,
-		},
+	req, err := c.schedulerDriver.PrepareAcceptRequest(
+		ctx,
+		c.mesosDetector.HostPort(),
+		[]*mesos.OfferID{offer.GetId()},
+		offerOperations,
+		nil,
+	)
+	if err != nil {
+		return err
 	}
 
 	log.WithFields(log.Fields{
-		"offer": offer,
-		"call":  msg,
+		"offer":      offer,
+		"operations": offerOperations,
 	}).Info("cleaning offer with operations")
 
-	msid := c.mesosFrameworkInfoProvider.GetMesosStreamID(ctx)
-	return c.mSchedulerClient.Call(msid, msg)
+	return c.callSender.Send(ctx, req)
 }
@@ -16,20 +16,19 @@ package cleaner
 
 import (
 	"context"
+	"net/http"
 	"testing"
 
 	"github.com/golang/mock/gomock"
-	"github.com/golang/protobuf/proto"
 	"github.com/stretchr/testify/assert"
 	"github.com/uber-go/tally"
 
 	mesos "github.com/uber/peloton/.gen/mesos/v1"
-	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
 	"github.com/uber/peloton/.gen/peloton/api/v0/volume"
 
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	"github.com/uber/peloton/pkg/common/util"
-	mpb_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
+	hostmgr_mesos_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/mocks"
 	offerpool_mocks "github.com/uber/peloton/pkg/hostmgr/offer/offerpool/mocks"
 	"github.com/uber/peloton/pkg/hostmgr/summary"
 	store_mocks "github.com/uber/peloton/pkg/storage/mocks"
@@ -40,6 +39,8 @@ const (
 	_perHostMem  = 20.0
 	_perHostDisk = 30.0
 	pelotonRole  = "peloton"
+
+	_testHostPort = "master:5050"
 )
 
 var (
@@ -50,20 +51,11 @@ var (
 	_testValue    = "testValue"
 )
 
-type mockMesosStreamIDProvider struct {
-}
-
-func (msp *mockMesosStreamIDProvider) GetMesosStreamID(ctx context.Context) string {
-	return "stream"
-}
-
-func (msp *mockMesosStreamIDProvider) GetFrameworkID(ctx context.Context) *mesos.FrameworkID {
-	return nil
-}
-
 func TestCleanUnusedResources(t *testing.T) {
 	ctrl := gomock.NewController(t)
-	mockSchedulerClient := mpb_mocks.NewMockSchedulerClient(ctrl)
+	mockDriver := hostmgr_mesos_mocks.NewMockSchedulerDriver(ctrl)
+	mockDetector := hostmgr_mesos_mocks.NewMockMasterDetector(ctrl)
+	mockCallSender := hostmgr_mesos_mocks.NewMockCallSender(ctrl)
 	mockVolumeStore := store_mocks.NewMockPersistentVolumeStore(ctrl)
 	mockOfferPool := offerpool_mocks.NewMockPool(ctrl)
 	defer ctrl.Finish()
@@ -73,8 +65,9 @@ func TestCleanUnusedResources(t *testing.T) {
 		mockOfferPool,
 		testScope,
 		mockVolumeStore,
-		mockSchedulerClient,
-		&mockMesosStreamIDProvider{})
+		mockDriver,
+		mockDetector,
+		mockCallSender)
 
 	reservation := &mesos.Resource_ReservationInfo{
 		Labels: &mesos.Labels{
@@ -105,31 +98,38 @@ func TestCleanUnusedResources(t *testing.T) {
 	reservedOffers[offer.GetId().GetValue()] = offer
 	hostOffers := make(map[string]map[string]*mesos.Offer)
 	hostOffers[offer.GetHostname()] = reservedOffers
+	acceptReq := &http.Request{}
 
 	gomock.InOrder(
 		mockOfferPool.EXPECT().GetOffers(summary.Reserved).Return(hostOffers, 4),
 		mockOfferPool.EXPECT().RemoveReservedOffer(offer.GetHostname(), offer.GetId().GetValue()),
-		mockSchedulerClient.EXPECT().
-			Call(
-				gomock.Eq("stream"),
-				gomock.Any()).
-			Do(func(_ string, msg proto.Message) {
-				// Verify implicit reconcile call.
-				call := msg.(*sched.Call)
-				assert.Equal(t, sched.Call_ACCEPT, call.GetType())
-				assert.Equal(t, "", call.GetFrameworkId().GetValue())
-				assert.Equal(t, []*mesos.OfferID{offer.GetId()}, call.GetAccept().GetOfferIds())
-				assert.Equal(t, 1, len(call.GetAccept().GetOperations()))
+		mockDetector.EXPECT().HostPort().Return(_testHostPort),
+		mockDriver.EXPECT().
+			PrepareAcceptRequest(
+				gomock.Any(),
+				_testHostPort,
+				[]*mesos.OfferID{offer.GetId()},
+				gomock.Any(),
+				nil).
+			Do(func(
+				_ context.Context,
+				_ string,
+				_ []*mesos.OfferID,
+				operations []*mesos.Offer_Operation,
+				_ *mesos.Filters) {
+				// Verify the operations of the accept call.
+				assert.Equal(t, 1, len(operations))
 				assert.Equal(
 					t,
 					mesos.Offer_Operation_UNRESERVE,
-					call.GetAccept().GetOperations()[0].GetType())
-				for _, res := range call.GetAccept().GetOperations()[0].GetUnreserve().GetResources() {
+					operations[0].GetType())
+				for _, res := range operations[0].GetUnreserve().GetResources() {
 					assert.Equal(t, reservation, res.GetReservation())
 					assert.NotEqual(t, res.GetName(), "disk")
 				}
 			}).
-			Return(nil),
+			Return(acceptReq, nil),
+		mockCallSender.EXPECT().Send(gomock.Any(), acceptReq).Return(nil),
 	)
 
 	cleaner.Run(nil)
@@ -137,7 +137,9 @@ func TestCleanUnusedResources(t *testing.T) {
 
 func TestCleanVolume(t *testing.T) {
 	ctrl := gomock.NewController(t)
-	mockSchedulerClient := mpb_mocks.NewMockSchedulerClient(ctrl)
+	mockDriver := hostmgr_mesos_mocks.NewMockSchedulerDriver(ctrl)
+	mockDetector := hostmgr_mesos_mocks.NewMockMasterDetector(ctrl)
+	mockCallSender := hostmgr_mesos_mocks.NewMockCallSender(ctrl)
 	mockVolumeStore := store_mocks.NewMockPersistentVolumeStore(ctrl)
 	mockOfferPool := offerpool_mocks.NewMockPool(ctrl)
 	defer ctrl.Finish()
@@ -147,8 +149,9 @@ func TestCleanVolume(t *testing.T) {
 		mockOfferPool,
 		testScope,
 		mockVolumeStore,
-		mockSchedulerClient,
-		&mockMesosStreamIDProvider{})
+		mockDriver,
+		mockDetector,
+		mockCallSender)
 
 	reservation := &mesos.Resource_ReservationInfo{
 		Labels: &mesos.Labels{
@@ -198,39 +201,46 @@ func TestCleanVolume(t *testing.T) {
 		State:     volume.VolumeState_CREATED,
 		GoalState: volume.VolumeState_DELETED,
 	}
+	acceptReq := &http.Request{}
 
 	gomock.InOrder(
 		mockOfferPool.EXPECT().GetOffers(summary.Reserved).Return(hostOffers, 4),
 		mockVolumeStore.EXPECT().GetPersistentVolume(gomock.Any(), volumeID).Return(volumeInfo, nil),
 		mockVolumeStore.EXPECT().UpdatePersistentVolume(gomock.Any(), volumeInfo).Return(nil),
 		mockOfferPool.EXPECT().RemoveReservedOffer(offer.GetHostname(), offer.GetId().GetValue()),
-		mockSchedulerClient.EXPECT().
-			Call(
-				gomock.Eq("stream"),
-				gomock.Any()).
-			Do(func(_ string, msg proto.Message) {
-				// Verify implicit reconcile call.
-				call := msg.(*sched.Call)
-				assert.Equal(t, sched.Call_ACCEPT, call.GetType())
-				assert.Equal(t, "", call.GetFrameworkId().GetValue())
-				assert.Equal(t, []*mesos.OfferID{offer.GetId()}, call.GetAccept().GetOfferIds())
-				assert.Equal(t, 2, len(call.GetAccept().GetOperations()))
+		mockDetector.EXPECT().HostPort().Return(_testHostPort),
+		mockDriver.EXPECT().
+			PrepareAcceptRequest(
+				gomock.Any(),
+				_testHostPort,
+				[]*mesos.OfferID{offer.GetId()},
+				gomock.Any(),
+				nil).
+			Do(func(
+				_ context.Context,
+				_ string,
+				_ []*mesos.OfferID,
+				operations []*mesos.Offer_Operation,
+				_ *mesos.Filters) {
+				// Verify the operations of the accept call.
+				assert.Equal(t, 2, len(operations))
 				assert.Equal(
 					t,
 					mesos.Offer_Operation_DESTROY,
-					call.GetAccept().GetOperations()[0].GetType())
-				destroyResource := call.GetAccept().GetOperations()[0].GetDestroy().GetVolumes()[0]
+					operations[0].GetType())
+				destroyResource := operations[0].GetDestroy().GetVolumes()[0]
 				assert.Equal(t, "disk", destroyResource.GetName())
 				assert.Equal(t, reservation, destroyResource.GetReservation())
 				assert.Equal(
 					t,
 					mesos.Offer_Operation_UNRESERVE,
-					call.GetAccept().GetOperations()[1].GetType())
-				for _, res := range call.GetAccept().GetOperations()[1].GetUnreserve().GetResources() {
+					operations[1].GetType())
+				for _, res := range operations[1].GetUnreserve().GetResources() {
 					assert.Equal(t, reservation, res.GetReservation())
 				}
 			}).
-			Return(nil),
+			Return(acceptReq, nil),
+		mockCallSender.EXPECT().Send(gomock.Any(), acceptReq).Return(nil),
 	)
 
 	cleaner.Run(nil)
@@ -238,7 +248,9 @@ func TestCleanVolume(t *testing.T) {
 
 func TestNotCleanVolumeIfVolumeGoalstateNotDeleted(t *testing.T) {
 	ctrl := gomock.NewController(t)
-	mockSchedulerClient := mpb_mocks.NewMockSchedulerClient(ctrl)
+	mockDriver := hostmgr_mesos_mocks.NewMockSchedulerDriver(ctrl)
+	mockDetector := hostmgr_mesos_mocks.NewMockMasterDetector(ctrl)
+	mockCallSender := hostmgr_mesos_mocks.NewMockCallSender(ctrl)
 	mockVolumeStore := store_mocks.NewMockPersistentVolumeStore(ctrl)
 	mockOfferPool := offerpool_mocks.NewMockPool(ctrl)
 	defer ctrl.Finish()
@@ -248,8 +260,9 @@ func TestNotCleanVolumeIfVolumeGoalstateNotDeleted(t *testing.T) {
 		mockOfferPool,
 		testScope,
 		mockVolumeStore,
-		mockSchedulerClient,
-		&mockMesosStreamIDProvider{})
+		mockDriver,
+		mockDetector,
+		mockCallSender)
 
 	reservation := &mesos.Resource_ReservationInfo{
 		Labels: &mesos.Labels{
@@ -0,0 +1,355 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+
+	hostmgr_mesos "github.com/uber/peloton/pkg/hostmgr/mesos"
+	"github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb"
+
+	log "github.com/sirupsen/logrus"
+	uatomic "github.com/uber-go/atomic"
+	"github.com/uber-go/tally"
+)
+
+const (
+	_defaultKillGracePeriod    = 5 * time.Minute
+	_defaultMaxKillAttempts    = 3
+	_defaultKillEscalatorCheck = 30 * time.Second
+)
+
+// KillEscalatorConfig is the configuration of the KillEscalator
+type KillEscalatorConfig struct {
+	// Enabled enables the escalation of the kills of the tasks
+	Enabled bool `yaml:"enabled"`
+
+	// GracePeriod is how long a task has to terminate after a kill before
+	// the kill is escalated
+	GracePeriod time.Duration `yaml:"grace_period"`
+
+	// MaxKillAttempts is the number of kills sent for a task, including
+	// the first one, before the kill is escalated to a shutdown of its
+	// executor
+	MaxKillAttempts int `yaml:"max_kill_attempts"`
+
+	// ShutdownExecutor enables shutting down the executor of the tasks
+	// which are still not terminated after the last kill attempt
+	ShutdownExecutor bool `yaml:"shutdown_executor"`
+
+	// CheckPeriod is the period the kills are checked for escalation
+	CheckPeriod time.Duration `yaml:"check_period"`
+}
+
+// killEscalatorMetrics are the metrics of a KillEscalator
+type killEscalatorMetrics struct {
+	killEscalation     tally.Counter
+	killEscalationFail tally.Counter
+	shutdown           tally.Counter
+	shutdownFail       tally.Counter
+	abandoned          tally.Counter
+	tracked            tally.Gauge
+	stuckKilling       tally.Gauge
+}
+
+// killState is the kill state of a task
+type killState struct {
+	taskID     *mesos.TaskID
+	agentID    *mesos.AgentID
+	executorID *mesos.ExecutorID
+
+	// lastKill is when the last kill, or shutdown, of the task was sent
+	lastKill time.Time
+	// attempts is the number of kills sent
+	attempts int
+	shutdown bool
+	killing  bool
+}
+
+// KillEscalator escalates the kills of the tasks which do not terminate
+// within a grace period: the kill is sent again, and the executor of the
+// task is shut down after the last kill attempt if enabled. The tasks are
+// tracked from their kill, or from their first TASK_KILLING update, so that
+// the tasks being killed when hostmgr restarts are tracked again from the
+// updates of the reconciliation, until their terminal update.
+type KillEscalator struct {
+	sync.Mutex
+
+	config                KillEscalatorConfig
+	schedulerClient       mpb.SchedulerClient
+	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider
+	metrics               *killEscalatorMetrics
+	now                   func() time.Time
+
+	// tasks are the kill states by task id
+	tasks map[string]*killState
+}
+
+// NewKillEscalator creates a KillEscalator
+func NewKillEscalator(
+	config *KillEscalatorConfig,
+	schedulerClient mpb.SchedulerClient,
+	frameworkInfoProvider hostmgr_mesos.FrameworkInfoProvider,
+	parent tally.Scope) *KillEscalator {
+	scope := parent.SubScope("kill_escalator")
+	e := &KillEscalator{
+		schedulerClient:       schedulerClient,
+		frameworkInfoProvider: frameworkInfoProvider,
+		metrics: &killEscalatorMetrics{
+			killEscalation:     scope.Counter("kill_escalation"),
+			killEscalationFail: scope.Counter("kill_escalation_fail"),
+			shutdown:           scope.Counter("shutdown_escalation"),
+			shutdownFail:       scope.Counter("shutdown_escalation_fail"),
+			abandoned:          scope.Counter("abandoned"),
+			tracked:            scope.Gauge("tracked"),
+			stuckKilling:       scope.Gauge("stuck_killing"),
+		},
+		now:   time.Now,
+		tasks: make(map[string]*killState),
+	}
+	if config != nil {
+		e.config = *config
+	}
+	if e.config.GracePeriod <= 0 {
+		e.config.GracePeriod = _defaultKillGracePeriod
+	}
+	if e.config.MaxKillAttempts <= 0 {
+		e.config.MaxKillAttempts = _defaultMaxKillAttempts
+	}
+	if e.config.CheckPeriod <= 0 {
+		e.config.CheckPeriod = _defaultKillEscalatorCheck
+	}
+	return e
+}
+
+// CheckPeriod returns the period the kills are checked for escalation
+func (e *KillEscalator) CheckPeriod() time.Duration {
+	return e.config.CheckPeriod
+}
+
+// KillIssued records that a kill was sent for a task
+func (e *KillEscalator) KillIssued(taskID *mesos.TaskID) {
+	if !e.config.Enabled {
+		return
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	state, ok := e.tasks[taskID.GetValue()]
+	if !ok {
+		state = &killState{taskID: taskID}
+		e.tasks[taskID.GetValue()] = state
+	}
+	state.lastKill = e.now()
+	state.attempts++
+}
+
+// TaskStatusUpdated tracks the kill state of a task from its status update
+func (e *KillEscalator) TaskStatusUpdated(
+	ctx context.Context,
+	status *mesos.TaskStatus) {
+	if !e.config.Enabled {
+		return
+	}
+
+	e.Lock()
+	defer e.Unlock()
+	taskID := status.GetTaskId().GetValue()
+	if isTerminalMesosState(status.GetState()) {
+		delete(e.tasks, taskID)
+		return
+	}
+
+	state, ok := e.tasks[taskID]
+	if !ok {
+		if status.GetState() != mesos.TaskState_TASK_KILLING {
+			return
+		}
+		// The kill was sent before hostmgr restarted, or by another party
+		state = &killState{
+			taskID:   status.GetTaskId(),
+			lastKill: e.now(),
+			attempts: 1,
+		}
+		e.tasks[taskID] = state
+	}
+	if status.GetState() == mesos.TaskState_TASK_KILLING {
+		state.killing = true
+	}
+	if status.GetAgentId() != nil {
+		state.agentID = status.GetAgentId()
+	}
+	if status.GetExecutorId() != nil {
+		state.executorID = status.GetExecutorId()
+	}
+}
+
+// Escalate escalates the kills of the tasks which did not terminate within
+// the grace period. It is run as a background work, i.e. only on the
+// leader.
+func (e *KillEscalator) Escalate(_ *uatomic.Bool) {
+	if !e.config.Enabled {
+		return
+	}
+	e.escalate()
+}
+
+// escalationStep is the escalation of the kill of a task
+type escalationStep int
+
+const (
+	_escalationKill escalationStep = iota
+	_escalationShutdown
+	_escalationAbandon
+)
+
+func (e *KillEscalator) escalate() {
+	e.Lock()
+	now := e.now()
+	steps := make(map[*killState]escalationStep)
+	for taskID, state := range e.tasks {
+		if now.Sub(state.lastKill) < e.config.GracePeriod {
+			continue
+		}
+		switch {
+		case state.attempts < e.config.MaxKillAttempts:
+			steps[state] = _escalationKill
+		case e.config.ShutdownExecutor && !state.shutdown &&
+			state.agentID != nil && state.executorID != nil:
+			steps[state] = _escalationShutdown
+		default:
+			// Every escalation failed, the task is left to reconciliation
+			delete(e.tasks, taskID)
+			steps[state] = _escalationAbandon
+		}
+	}
+	e.Unlock()
+
+	ctx := context.Background()
+	for state, step := range steps {
+		switch step {
+		case _escalationKill:
+			e.killAgain(ctx, state, now)
+		case _escalationShutdown:
+			e.shutdownExecutor(ctx, state, now)
+		case _escalationAbandon:
+			log.WithField("task_id", state.taskID.GetValue()).
+				WithField("killing", state.killing).
+				Warn("Task not terminated after kill escalation")
+			e.metrics.abandoned.Inc(1)
+		}
+	}
+
+	e.Lock()
+	stuck := 0
+	for _, state := range e.tasks {
+		if state.attempts > 1 || state.shutdown {
+			stuck++
+		}
+	}
+	e.metrics.tracked.Update(float64(len(e.tasks)))
+	e.metrics.stuckKilling.Update(float64(stuck))
+	e.Unlock()
+}
+
+// killAgain sends the kill of a task again. A failed kill is retried at
+// the next check, as the attempt is only counted once sent.
+func (e *KillEscalator) killAgain(
+	ctx context.Context,
+	state *killState,
+	now time.Time) {
+	e.Lock()
+	agentID := state.agentID
+	e.Unlock()
+
+	msg := mpb.NewKillCall(
+		e.frameworkInfoProvider.GetFrameworkID(ctx),
+		state.taskID,
+		agentID)
+	msid := e.frameworkInfoProvider.GetMesosStreamID(ctx)
+	if err := e.schedulerClient.Call(msid, msg); err != nil {
+		log.WithError(err).
+			WithField("task_id", state.taskID.GetValue()).
+			Warn("Failed to escalate task kill")
+		e.metrics.killEscalationFail.Inc(1)
+		return
+	}
+	log.WithField("task_id", state.taskID.GetValue()).
+		Info("Task kill sent again after grace period")
+	e.metrics.killEscalation.Inc(1)
+
+	e.Lock()
+	state.attempts++
+	state.lastKill = now
+	e.Unlock()
+}
+
+// shutdownExecutor shuts down the executor of a task
+func (e *KillEscalator) shutdownExecutor(
+	ctx context.Context,
+	state *killState,
+	now time.Time) {
+	e.Lock()
+	agentID := state.agentID
+	executorID := state.executorID
+	e.Unlock()
+
+	msg := mpb.NewShutdownCall(
+		e.frameworkInfoProvider.GetFrameworkID(ctx),
+		executorID,
+		agentID)
+	msid := e.frameworkInfoProvider.GetMesosStreamID(ctx)
+	if err := e.schedulerClient.Call(msid, msg); err != nil {
+		log.WithError(err).
+			WithField("task_id", state.taskID.GetValue()).
+			WithField("executor_id", executorID.GetValue()).
+			Warn("Failed to shut down executor of task not killed")
+		e.metrics.shutdownFail.Inc(1)
+		return
+	}
+	log.WithField("task_id", state.taskID.GetValue()).
+		WithField("executor_id", executorID.GetValue()).
+		WithField("agent_id", agentID.GetValue()).
+		Warn("Executor shut down for task not killed")
+	e.metrics.shutdown.Inc(1)
+
+	e.Lock()
+	state.shutdown = true
+	state.lastKill = now
+	e.Unlock()
+}
+
+// isTerminalMesosState returns true if a task in state is not running on
+// its agent anymore
+func isTerminalMesosState(state mesos.TaskState) bool {
+	switch state {
+	case mesos.TaskState_TASK_FINISHED,
+		mesos.TaskState_TASK_FAILED,
+		mesos.TaskState_TASK_KILLED,
+		mesos.TaskState_TASK_ERROR,
+		mesos.TaskState_TASK_LOST,
+		mesos.TaskState_TASK_DROPPED,
+		mesos.TaskState_TASK_GONE,
+		mesos.TaskState_TASK_GONE_BY_OPERATOR,
+		mesos.TaskState_TASK_UNKNOWN:
+		return true
+	default:
+		return false
+	}
+}
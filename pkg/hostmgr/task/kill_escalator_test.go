@@ -0,0 +1,225 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package task
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	mesos "github.com/uber/peloton/.gen/mesos/v1"
+	sched "github.com/uber/peloton/.gen/mesos/v1/scheduler"
+
+	"github.com/uber/peloton/pkg/common/util"
+	hostmgr_mesos_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/mocks"
+	mpb_mocks "github.com/uber/peloton/pkg/hostmgr/mesos/yarpc/encoding/mpb/mocks"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+const (
+	_killTaskID     = "task-id"
+	_killAgentID    = "agent-id"
+	_killExecutorID = "executor-id"
+)
+
+type killEscalatorTestSuite struct {
+	suite.Suite
+
+	ctrl            *gomock.Controller
+	schedulerClient *mpb_mocks.MockSchedulerClient
+	provider        *hostmgr_mesos_mocks.MockFrameworkInfoProvider
+	scope           tally.TestScope
+	escalator       *KillEscalator
+	now             time.Time
+}
+
+func (s *killEscalatorTestSuite) SetupTest() {
+	s.ctrl = gomock.NewController(s.T())
+	s.schedulerClient = mpb_mocks.NewMockSchedulerClient(s.ctrl)
+	s.provider = hostmgr_mesos_mocks.NewMockFrameworkInfoProvider(s.ctrl)
+	s.scope = tally.NewTestScope("", nil)
+	s.now = time.Now()
+
+	s.escalator = NewKillEscalator(
+		&KillEscalatorConfig{
+			Enabled:          true,
+			GracePeriod:      time.Minute,
+			MaxKillAttempts:  2,
+			ShutdownExecutor: true,
+		},
+		s.schedulerClient,
+		s.provider,
+		s.scope,
+	)
+	s.escalator.now = func() time.Time { return s.now }
+
+	s.provider.EXPECT().
+		GetFrameworkID(gomock.Any()).
+		Return(&mesos.FrameworkID{Value: util.PtrStr(_frameworkID)}).
+		AnyTimes()
+	s.provider.EXPECT().
+		GetMesosStreamID(gomock.Any()).
+		Return(_streamID).
+		AnyTimes()
+}
+
+func (s *killEscalatorTestSuite) TearDownTest() {
+	s.ctrl.Finish()
+}
+
+func TestKillEscalator(t *testing.T) {
+	suite.Run(t, new(killEscalatorTestSuite))
+}
+
+// taskStatus returns the status of the task in state
+func (s *killEscalatorTestSuite) taskStatus(
+	state mesos.TaskState) *mesos.TaskStatus {
+	return &mesos.TaskStatus{
+		TaskId:     &mesos.TaskID{Value: util.PtrStr(_killTaskID)},
+		State:      state.Enum(),
+		AgentId:    &mesos.AgentID{Value: util.PtrStr(_killAgentID)},
+		ExecutorId: &mesos.ExecutorID{Value: util.PtrStr(_killExecutorID)},
+	}
+}
+
+// expectCall expects a call of callType to Mesos
+func (s *killEscalatorTestSuite) expectCall(
+	callType sched.Call_Type,
+	err error) {
+	s.schedulerClient.EXPECT().
+		Call(_streamID, gomock.Any()).
+		Do(func(_ string, call *sched.Call) {
+			s.Equal(callType, call.GetType())
+			switch callType {
+			case sched.Call_KILL:
+				s.Equal(_killTaskID, call.GetKill().GetTaskId().GetValue())
+				s.Equal(_killAgentID, call.GetKill().GetAgentId().GetValue())
+			case sched.Call_SHUTDOWN:
+				s.Equal(_killExecutorID,
+					call.GetShutdown().GetExecutorId().GetValue())
+				s.Equal(_killAgentID,
+					call.GetShutdown().GetAgentId().GetValue())
+			}
+		}).
+		Return(err)
+}
+
+func (s *killEscalatorTestSuite) counter(name string) int64 {
+	counter, ok := s.scope.Snapshot().Counters()["kill_escalator."+name+"+"]
+	if !ok {
+		return 0
+	}
+	return counter.Value()
+}
+
+func (s *killEscalatorTestSuite) gauge(name string) float64 {
+	return s.scope.Snapshot().Gauges()["kill_escalator."+name+"+"].Value()
+}
+
+// TestKilledWithinGracePeriod tests that the kill of a task terminated
+// within the grace period is not escalated
+func (s *killEscalatorTestSuite) TestKilledWithinGracePeriod() {
+	ctx := context.Background()
+	s.escalator.KillIssued(&mesos.TaskID{Value: util.PtrStr(_killTaskID)})
+	s.escalator.TaskStatusUpdated(
+		ctx, s.taskStatus(mesos.TaskState_TASK_KILLING))
+
+	s.now = s.now.Add(30 * time.Second)
+	s.escalator.Escalate(nil)
+	s.Equal(float64(1), s.gauge("tracked"))
+	s.Equal(float64(0), s.gauge("stuck_killing"))
+
+	s.escalator.TaskStatusUpdated(
+		ctx, s.taskStatus(mesos.TaskState_TASK_KILLED))
+	s.now = s.now.Add(time.Hour)
+	s.escalator.Escalate(nil)
+	s.Equal(float64(0), s.gauge("tracked"))
+	s.Empty(s.escalator.tasks)
+}
+
+// TestEscalation tests that the kill of a task not terminated is sent
+// again after the grace period, then its executor is shut down, and the
+// task is abandoned if still not terminated
+func (s *killEscalatorTestSuite) TestEscalation() {
+	s.escalator.KillIssued(&mesos.TaskID{Value: util.PtrStr(_killTaskID)})
+	s.escalator.TaskStatusUpdated(
+		context.Background(), s.taskStatus(mesos.TaskState_TASK_KILLING))
+
+	// kill again, failing first
+	s.now = s.now.Add(time.Minute)
+	s.expectCall(sched.Call_KILL, errors.New("mesos unavailable"))
+	s.escalator.Escalate(nil)
+	s.Equal(int64(1), s.counter("kill_escalation_fail"))
+
+	s.expectCall(sched.Call_KILL, nil)
+	s.escalator.Escalate(nil)
+	s.Equal(int64(1), s.counter("kill_escalation"))
+	s.Equal(float64(1), s.gauge("stuck_killing"))
+
+	// within the grace period of the last kill
+	s.now = s.now.Add(30 * time.Second)
+	s.escalator.Escalate(nil)
+
+	// shut down the executor
+	s.now = s.now.Add(30 * time.Second)
+	s.expectCall(sched.Call_SHUTDOWN, nil)
+	s.escalator.Escalate(nil)
+	s.Equal(int64(1), s.counter("shutdown_escalation"))
+
+	// abandon the task
+	s.now = s.now.Add(time.Minute)
+	s.escalator.Escalate(nil)
+	s.Equal(int64(1), s.counter("abandoned"))
+	s.Empty(s.escalator.tasks)
+}
+
+// TestTrackedFromKilling tests that the task killing without a kill sent,
+// e.g. before a restart, are tracked from their status update
+func (s *killEscalatorTestSuite) TestTrackedFromKilling() {
+	ctx := context.Background()
+	s.escalator.TaskStatusUpdated(
+		ctx, s.taskStatus(mesos.TaskState_TASK_RUNNING))
+	s.Empty(s.escalator.tasks)
+
+	s.escalator.TaskStatusUpdated(
+		ctx, s.taskStatus(mesos.TaskState_TASK_KILLING))
+	s.Len(s.escalator.tasks, 1)
+
+	s.now = s.now.Add(time.Minute)
+	s.expectCall(sched.Call_KILL, nil)
+	s.escalator.Escalate(nil)
+	s.Equal(2, s.escalator.tasks[_killTaskID].attempts)
+
+	s.escalator.TaskStatusUpdated(
+		ctx, s.taskStatus(mesos.TaskState_TASK_LOST))
+	s.Empty(s.escalator.tasks)
+}
+
+// TestDisabled tests that no kill is tracked when the escalation is
+// disabled
+func (s *killEscalatorTestSuite) TestDisabled() {
+	s.escalator.config.Enabled = false
+	s.escalator.KillIssued(&mesos.TaskID{Value: util.PtrStr(_killTaskID)})
+	s.escalator.TaskStatusUpdated(
+		context.Background(), s.taskStatus(mesos.TaskState_TASK_KILLING))
+	s.Empty(s.escalator.tasks)
+
+	s.now = s.now.Add(time.Hour)
+	s.escalator.Escalate(nil)
+}
@@ -22,6 +22,7 @@ import (
 type Metrics struct {
 	taskUpdateCounter   tally.Counter
 	taskUpdateAck       tally.Counter
+	taskUpdateAckFail   tally.Counter
 	taskAckChannelSize  tally.Gauge
 	taskAckMapSize      tally.Gauge
 	taskUpdateAckDeDupe tally.Counter
@@ -35,6 +36,7 @@ func NewMetrics(scope tally.Scope) *Metrics {
 	return &Metrics{
 		taskUpdateCounter:   scope.Counter("task_updates"),
 		taskUpdateAck:       scope.Counter("task_update_ack"),
+		taskUpdateAckFail:   scope.Counter("task_update_ack_fail"),
 		taskAckChannelSize:  scope.Gauge("task_ack_channel_size"),
 		taskAckMapSize:      scope.Gauge("task_ack_map_size"),
 		taskUpdateAckDeDupe: scope.Counter("task_update_ack_dedupe"),
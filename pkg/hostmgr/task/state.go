@@ -58,10 +58,20 @@ type StateManager interface {
 	// GetStatusUpdateEvents returns all the outstanding status update events
 	// from the event stream
 	GetStatusUpdateEvents() ([]*pb_eventstream.Event, error)
+
+	// AddObserver adds an observer of the task status updates.
+	AddObserver(observer TaskStatusObserver)
+}
+
+// TaskStatusObserver observes the task status updates received from Mesos
+type TaskStatusObserver interface {
+	// TaskStatusUpdated is called with each task status update received,
+	// including the ones of reconciliation.
+	TaskStatusUpdated(ctx context.Context, status *mesos.TaskStatus)
 }
 
 type stateManager struct {
-	schedulerclient mpb.SchedulerClient
+	ackBatcher *hostmgr_mesos.AcknowledgeBatcher
 
 	updateAckConcurrency int
 	ackChannel           chan *mesos.TaskStatus // Buffers the mesos task status updates to be acknowledged
@@ -69,6 +79,9 @@ type stateManager struct {
 
 	eventStreamHandler *eventstream.Handler
 	metrics            *Metrics
+
+	observersLock sync.RWMutex
+	observers     []TaskStatusObserver
 }
 
 // eventForwarder is the struct to forward status update events to
@@ -129,7 +142,7 @@ func initEventStreamHandler(
 // for Job Manager & Resource Manager for consumption of these task status updates.
 func NewStateManager(
 	d *yarpc.Dispatcher,
-	schedulerClient mpb.SchedulerClient,
+	ackBatcher *hostmgr_mesos.AcknowledgeBatcher,
 	updateBufferSize int,
 	updateAckConcurrency int,
 	resmgrClient resmgrsvc.ResourceManagerServiceYARPCClient,
@@ -137,7 +150,7 @@ func NewStateManager(
 
 	stateManagerScope := parentScope.SubScope("taskStateManager")
 	handler := &stateManager{
-		schedulerclient:      schedulerClient,
+		ackBatcher:           ackBatcher,
 		updateAckConcurrency: updateAckConcurrency,
 		ackChannel:           make(chan *mesos.TaskStatus, updateBufferSize),
 		metrics:              NewMetrics(stateManagerScope),
@@ -217,6 +230,13 @@ func (m *stateManager) Update(ctx context.Context, body *sched.Event) error {
 		"task_state_" + taskUpdate.GetStatus().GetState().String())
 	taskStateCounter.Inc(1)
 
+	m.observersLock.RLock()
+	observers := m.observers
+	m.observersLock.RUnlock()
+	for _, observer := range observers {
+		observer.TaskStatusUpdated(ctx, taskUpdate.GetStatus())
+	}
+
 	event := &pb_eventstream.Event{
 		MesosTaskStatus: taskUpdate.GetStatus(),
 		Type:            pb_eventstream.Event_MESOS_TASK_STATUS,
@@ -234,6 +254,13 @@ func (m *stateManager) Update(ctx context.Context, body *sched.Event) error {
 	return nil
 }
 
+// AddObserver adds an observer of the task status updates
+func (m *stateManager) AddObserver(observer TaskStatusObserver) {
+	m.observersLock.Lock()
+	defer m.observersLock.Unlock()
+	m.observers = append(m.observers, observer)
+}
+
 // UpdateCounters tracks the count for task status update & ack count.
 func (m *stateManager) UpdateCounters(_ *uatomic.Bool) {
 	m.metrics.taskAckChannelSize.Update(float64(len(m.ackChannel)))
@@ -267,9 +294,7 @@ func (m *stateManager) startAsyncProcessTaskUpdates() {
 					// if ack failed at mesos master then agent will re-send
 					m.ackStatusMap.Delete(uuid)
 
-					if err := m.acknowledgeTaskUpdate(
-						context.Background(),
-						taskStatus); err != nil {
+					if err := m.acknowledgeTaskUpdate(taskStatus); err != nil {
 						log.WithField("task_status", *taskStatus).
 							WithError(err).
 							Error("Failed to acknowledgeTaskUpdate")
@@ -281,29 +306,35 @@ func (m *stateManager) startAsyncProcessTaskUpdates() {
 }
 
 // acknowledgeTaskUpdate, ACK task status update events
-// thru POST scheduler client call to Mesos Master.
+// thru the acknowledge batcher, which reports the failures of its calls
+// to Mesos Master to its callback.
 func (m *stateManager) acknowledgeTaskUpdate(
-	ctx context.Context,
 	taskStatus *mesos.TaskStatus) error {
 	m.metrics.taskUpdateAck.Inc(1)
-	callType := sched.Call_ACKNOWLEDGE
-	msg := &sched.Call{
-		FrameworkId: hostmgr_mesos.GetSchedulerDriver().GetFrameworkID(ctx),
-		Type:        &callType,
-		Acknowledge: &sched.Call_Acknowledge{
-			AgentId: taskStatus.AgentId,
-			TaskId:  taskStatus.TaskId,
-			Uuid:    taskStatus.Uuid,
-		},
-	}
-	msid := hostmgr_mesos.GetSchedulerDriver().GetMesosStreamID(ctx)
-	err := m.schedulerclient.Call(msid, msg)
-	if err != nil {
-		return err
+	return m.ackBatcher.Acknowledge(&hostmgr_mesos.Acknowledgement{
+		AgentID: taskStatus.GetAgentId(),
+		TaskID:  taskStatus.GetTaskId(),
+		UUID:    taskStatus.GetUuid(),
+	})
+}
+
+// NewAcknowledgeCallback returns the callback of the acknowledge batcher of
+// the task status updates, which logs and counts the failed acknowledgements.
+func NewAcknowledgeCallback(
+	parentScope tally.Scope) hostmgr_mesos.AcknowledgeCallback {
+	metrics := NewMetrics(parentScope.SubScope("taskStateManager"))
+	return func(ack *hostmgr_mesos.Acknowledgement, err error) {
+		if err != nil {
+			metrics.taskUpdateAckFail.Inc(1)
+			log.WithField("task_id", ack.TaskID.GetValue()).
+				WithField("agent_id", ack.AgentID.GetValue()).
+				WithError(err).
+				Error("Failed to acknowledgeTaskUpdate")
+			return
+		}
+		log.WithField("task_id", ack.TaskID.GetValue()).
+			Debug("Acked task update")
 	}
-	log.WithField("task_status", *taskStatus).
-		Debug("Acked task update")
-	return nil
 }
 
 // EventPurged is for implementing PurgedEventsProcessor interface.
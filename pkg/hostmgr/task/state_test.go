@@ -16,6 +16,7 @@ package task
 
 import (
 	"context"
+	"errors"
 	"net/http"
 	"sync"
 	"testing"
@@ -145,7 +146,12 @@ func (s *stateManagerTestSuite) TearDownTest() {
 func (s *stateManagerTestSuite) createNewStateManager(ackConcurrency int) StateManager {
 	return NewStateManager(
 		s.dispatcher,
-		s.schedulerClient,
+		hostmgr_mesos.NewAcknowledgeBatcher(
+			s.schedulerClient,
+			s.driver,
+			hostmgr_mesos.AcknowledgeBatcherConfig{},
+			NewAcknowledgeCallback(s.testScope),
+			s.testScope),
 		10,
 		ackConcurrency,
 		s.resMgrClient,
@@ -275,6 +281,33 @@ func (s *stateManagerTestSuite) TestAckTaskStatusUpdate() {
 	s.Equal(s.testScope.Snapshot().Gauges()["taskStateManager.task_ack_map_size+"].Value(), float64(0))
 }
 
+func (s *stateManagerTestSuite) TestAckTaskStatusUpdateFailure() {
+	s.stateManager = s.createNewStateManager(10)
+	items := []*cirbuf.CircularBufferItem{
+		{
+			SequenceID: uint64(1),
+			Value:      s.event,
+		},
+	}
+
+	gomock.InOrder(
+		s.store.EXPECT().
+			GetFrameworkID(gomock.Any(), gomock.Eq(_frameworkName)).
+			Return(_frameworkID, nil),
+		s.store.EXPECT().
+			GetMesosStreamID(gomock.Any(), gomock.Eq(_frameworkName)).
+			Return(_streamID, nil),
+		s.schedulerClient.EXPECT().
+			Call(_streamID, gomock.Any()).
+			Return(errors.New("ack failed")),
+	)
+
+	s.stateManager.EventPurged(items)
+	time.Sleep(500 * time.Millisecond)
+	s.Equal(int64(1), s.testScope.Snapshot().
+		Counters()["taskStateManager.task_update_ack_fail+"].Value())
+}
+
 func TestStateManager(t *testing.T) {
 	suite.Run(t, new(stateManagerTestSuite))
 }
@@ -0,0 +1,127 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostmgr
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	uatomic "github.com/uber-go/atomic"
+	"github.com/uber-go/tally"
+)
+
+// AnyTaskUser is the allowed user which allows tasks to run as any user
+const AnyTaskUser = "*"
+
+// taskUserMetrics are the metrics of a TaskUserValidator
+type taskUserMetrics struct {
+	denied     tally.Counter
+	reload     tally.Counter
+	reloadFail tally.Counter
+}
+
+// TaskUserValidator validates the users the tasks are launched as against
+// the users allowed by the framework config, so that a launch as another
+// user is rejected by hostmgr instead of failing in Mesos. The tasks
+// without user run as the framework user, which is always allowed. No user
+// is rejected when no allowed user is configured.
+type TaskUserValidator struct {
+	sync.RWMutex
+
+	frameworkUser string
+	anyUser       bool
+	allowed       map[string]bool
+
+	// load returns the allowed users of the current config
+	load    func() ([]string, error)
+	metrics *taskUserMetrics
+}
+
+// NewTaskUserValidator creates a TaskUserValidator of allowedUsers, which
+// are reloaded with load
+func NewTaskUserValidator(
+	frameworkUser string,
+	allowedUsers []string,
+	load func() ([]string, error),
+	parent tally.Scope) *TaskUserValidator {
+	scope := parent.SubScope("task_user")
+	v := &TaskUserValidator{
+		frameworkUser: frameworkUser,
+		load:          load,
+		metrics: &taskUserMetrics{
+			denied:     scope.Counter("denied"),
+			reload:     scope.Counter("reload"),
+			reloadFail: scope.Counter("reload_fail"),
+		},
+	}
+	v.Update(allowedUsers)
+	return v
+}
+
+// Update replaces the allowed users
+func (v *TaskUserValidator) Update(allowedUsers []string) {
+	allowed := make(map[string]bool)
+	anyUser := len(allowedUsers) == 0
+	for _, user := range allowedUsers {
+		if user == AnyTaskUser {
+			anyUser = true
+		}
+		allowed[user] = true
+	}
+
+	v.Lock()
+	defer v.Unlock()
+	v.anyUser = anyUser
+	v.allowed = allowed
+}
+
+// Validate returns an error if a task cannot run as user
+func (v *TaskUserValidator) Validate(user string) error {
+	if user == "" || user == v.frameworkUser {
+		return nil
+	}
+
+	v.RLock()
+	defer v.RUnlock()
+	if v.anyUser || v.allowed[user] {
+		return nil
+	}
+	v.metrics.denied.Inc(1)
+
+	var allowed []string
+	for allowedUser := range v.allowed {
+		allowed = append(allowed, allowedUser)
+	}
+	sort.Strings(allowed)
+	return fmt.Errorf(
+		"user %s is not allowed, the allowed users are %s and %v",
+		user, v.frameworkUser, allowed)
+}
+
+// Reload reloads the allowed users from the config. It is run as a
+// background work, and the allowed users are kept if the config cannot be
+// loaded.
+func (v *TaskUserValidator) Reload(_ *uatomic.Bool) {
+	allowedUsers, err := v.load()
+	if err != nil {
+		v.metrics.reloadFail.Inc(1)
+		log.WithError(err).Warn("Cannot reload allowed task users")
+		return
+	}
+	v.Update(allowedUsers)
+	v.metrics.reload.Inc(1)
+}
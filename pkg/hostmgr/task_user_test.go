@@ -0,0 +1,82 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package hostmgr
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally"
+)
+
+// TestTaskUserValidatorAllowed tests that the framework user, the allowed
+// users and the tasks without user are allowed, and no other user
+func TestTaskUserValidatorAllowed(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	v := NewTaskUserValidator(
+		"root", []string{"peloton", "uber"}, nil, scope)
+
+	assert.NoError(t, v.Validate(""))
+	assert.NoError(t, v.Validate("root"))
+	assert.NoError(t, v.Validate("peloton"))
+	assert.NoError(t, v.Validate("uber"))
+
+	err := v.Validate("nobody")
+	assert.EqualError(t, err,
+		"user nobody is not allowed, the allowed users are root and [peloton uber]")
+	assert.Equal(t, int64(1),
+		scope.Snapshot().Counters()["task_user.denied+"].Value())
+}
+
+// TestTaskUserValidatorWildcard tests that any user is allowed with the
+// wildcard or without allowed users
+func TestTaskUserValidatorWildcard(t *testing.T) {
+	v := NewTaskUserValidator(
+		"root", []string{"peloton", AnyTaskUser}, nil, tally.NoopScope)
+	assert.NoError(t, v.Validate("nobody"))
+
+	v = NewTaskUserValidator("root", nil, nil, tally.NoopScope)
+	assert.NoError(t, v.Validate("nobody"))
+}
+
+// TestTaskUserValidatorReload tests that the allowed users are reloaded,
+// and kept when they cannot be loaded
+func TestTaskUserValidatorReload(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	allowedUsers := []string{AnyTaskUser}
+	var loadErr error
+	v := NewTaskUserValidator(
+		"root",
+		allowedUsers,
+		func() ([]string, error) { return allowedUsers, loadErr },
+		scope)
+	assert.NoError(t, v.Validate("nobody"))
+
+	allowedUsers = []string{"peloton"}
+	v.Reload(nil)
+	assert.Error(t, v.Validate("nobody"))
+	assert.NoError(t, v.Validate("peloton"))
+
+	allowedUsers = []string{"nobody"}
+	loadErr = errors.New("invalid config")
+	v.Reload(nil)
+	assert.Error(t, v.Validate("nobody"))
+	assert.NoError(t, v.Validate("peloton"))
+
+	counters := scope.Snapshot().Counters()
+	assert.Equal(t, int64(1), counters["task_user.reload+"].Value())
+	assert.Equal(t, int64(1), counters["task_user.reload_fail+"].Value())
+}
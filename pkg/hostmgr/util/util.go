@@ -54,6 +54,7 @@ func MesosOffersToHostOffers(hostoffers map[string][]*mesos.Offer) []*hostsvc.Ho
 			AgentId:    offers[0].GetAgentId(),
 			Attributes: attributes,
 			Resources:  resources,
+			Domain:     offers[0].GetDomain(),
 		}
 
 		hostOffers = append(hostOffers, &hostOffer)
@@ -0,0 +1,319 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cached
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+)
+
+const (
+	_defaultAuditBufferSize  = 1000
+	_defaultAuditShards      = 16
+	_defaultAuditMaxFileSize = 100 * 1024 * 1024
+	// _auditFileQueueSize is the number of records queued to be written to
+	// the audit file, the records are dropped when the queue is full
+	_auditFileQueueSize = 4096
+)
+
+// NotificationAuditConfig is the configuration of the audit of the
+// notifications delivered to the listeners
+type NotificationAuditConfig struct {
+	// Enabled enables the audit of the notifications
+	Enabled bool `yaml:"enabled"`
+
+	// BufferSize is the number of notifications kept per shard
+	BufferSize int `yaml:"buffer_size"`
+
+	// Shards is the number of shards of the notifications kept, by job
+	Shards int `yaml:"shards"`
+
+	// File is the path of the file the notifications are also written to,
+	// none if empty
+	File string `yaml:"file"`
+
+	// MaxFileSize is the size in bytes after which the file is rotated
+	MaxFileSize int64 `yaml:"max_file_size"`
+}
+
+// NotificationRecord is a notification delivered to a listener
+type NotificationRecord struct {
+	// Time is when the notification was published
+	Time time.Time `json:"time"`
+	// Listener is the name of the listener
+	Listener string `json:"listener"`
+	JobID    string `json:"job_id"`
+	// InstanceID is the instance of the task notified, nil for the
+	// notifications of the job runtime
+	InstanceID *uint32 `json:"instance_id,omitempty"`
+	// FromState is the state previously notified, empty if unknown
+	FromState string `json:"from_state,omitempty"`
+	ToState   string `json:"to_state"`
+	// Latency is the time between the publication of the notification and
+	// its delivery to the listener
+	Latency time.Duration `json:"latency"`
+}
+
+// auditShard keeps the last notifications delivered for some of the jobs
+type auditShard struct {
+	sync.Mutex
+
+	records []NotificationRecord
+	// next is the index of the next record
+	next int
+	// lastStates are the last states notified by job, and within a job by
+	// task, the state of the job runtime being kept under the empty key.
+	// The states of a job are dropped when it is cleared from the cache.
+	lastStates map[string]map[string]string
+}
+
+// NotificationAudit keeps the last notifications delivered to the
+// listeners, to find out whether a change was notified. The notifications
+// are kept in ring buffers sharded by job, so that their recording does not
+// contend on a single lock.
+type NotificationAudit struct {
+	shards []*auditShard
+	file   chan NotificationRecord
+
+	fileDropped tally.Counter
+	fileFail    tally.Counter
+}
+
+// NewNotificationAudit creates a NotificationAudit, or returns nil if the
+// audit is not enabled.
+func NewNotificationAudit(
+	config NotificationAuditConfig,
+	parentScope tally.Scope) (*NotificationAudit, error) {
+	if !config.Enabled {
+		return nil, nil
+	}
+
+	bufferSize := config.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = _defaultAuditBufferSize
+	}
+	shards := config.Shards
+	if shards <= 0 {
+		shards = _defaultAuditShards
+	}
+
+	scope := parentScope.SubScope("notification_audit")
+	a := &NotificationAudit{
+		fileDropped: scope.Counter("file_dropped"),
+		fileFail:    scope.Counter("file_fail"),
+	}
+	for i := 0; i < shards; i++ {
+		a.shards = append(a.shards, &auditShard{
+			records:    make([]NotificationRecord, bufferSize),
+			lastStates: make(map[string]map[string]string),
+		})
+	}
+
+	if config.File != "" {
+		maxFileSize := config.MaxFileSize
+		if maxFileSize <= 0 {
+			maxFileSize = _defaultAuditMaxFileSize
+		}
+		w, err := newAuditFileWriter(config.File, maxFileSize)
+		if err != nil {
+			return nil, err
+		}
+		a.file = make(chan NotificationRecord, _auditFileQueueSize)
+		go a.writeFile(w)
+	}
+	return a, nil
+}
+
+// shard returns the shard of a job
+func (a *NotificationAudit) shard(jobID string) *auditShard {
+	h := fnv.New32a()
+	h.Write([]byte(jobID))
+	return a.shards[h.Sum32()%uint32(len(a.shards))]
+}
+
+// published returns the record of a notification published for a job, or
+// for a task if instanceID is not nil, to be completed for each listener
+// it is delivered to. It returns nil if a is nil, i.e. the audit is not
+// enabled.
+func (a *NotificationAudit) published(
+	jobID string,
+	instanceID *uint32,
+	state string,
+	deleted bool) *NotificationRecord {
+	if a == nil {
+		return nil
+	}
+
+	var key string
+	if instanceID != nil {
+		key = fmt.Sprint(*instanceID)
+	}
+	s := a.shard(jobID)
+	s.Lock()
+	states := s.lastStates[jobID]
+	from := states[key]
+	if deleted {
+		delete(states, key)
+		if len(states) == 0 {
+			delete(s.lastStates, jobID)
+		}
+	} else {
+		if states == nil {
+			states = make(map[string]string)
+			s.lastStates[jobID] = states
+		}
+		states[key] = state
+	}
+	s.Unlock()
+
+	return &NotificationRecord{
+		Time:       time.Now(),
+		JobID:      jobID,
+		InstanceID: instanceID,
+		FromState:  from,
+		ToState:    state,
+	}
+}
+
+// forget drops the last states notified for a job and its tasks, once the
+// job is cleared from the cache. The notifications kept are not dropped,
+// they are evicted from the ring buffer.
+func (a *NotificationAudit) forget(jobID string) {
+	if a == nil {
+		return
+	}
+
+	s := a.shard(jobID)
+	s.Lock()
+	delete(s.lastStates, jobID)
+	s.Unlock()
+}
+
+// delivered records the delivery of a notification to a listener
+func (a *NotificationAudit) delivered(
+	published *NotificationRecord,
+	listener string) {
+	if a == nil || published == nil {
+		return
+	}
+
+	r := *published
+	r.Listener = listener
+	r.Latency = time.Since(r.Time)
+
+	s := a.shard(r.JobID)
+	s.Lock()
+	s.records[s.next] = r
+	s.next = (s.next + 1) % len(s.records)
+	s.Unlock()
+
+	if a.file != nil {
+		select {
+		case a.file <- r:
+		default:
+			a.fileDropped.Inc(1)
+		}
+	}
+}
+
+// Query returns the notifications kept for a job, oldest first
+func (a *NotificationAudit) Query(jobID string) []NotificationRecord {
+	s := a.shard(jobID)
+	s.Lock()
+	defer s.Unlock()
+
+	var records []NotificationRecord
+	for i := 0; i < len(s.records); i++ {
+		r := s.records[(s.next+i)%len(s.records)]
+		if r.JobID == jobID {
+			records = append(records, r)
+		}
+	}
+	return records
+}
+
+// writeFile writes the notifications queued to the audit file
+func (a *NotificationAudit) writeFile(w *auditFileWriter) {
+	for r := range a.file {
+		if err := w.write(r); err != nil {
+			a.fileFail.Inc(1)
+			log.WithError(err).Warn("failed to write notification audit")
+		}
+	}
+}
+
+// auditFileWriter writes the notifications to a file as JSON lines, which
+// is rotated to a single backup when larger than maxSize
+type auditFileWriter struct {
+	path    string
+	maxSize int64
+	file    *os.File
+	size    int64
+}
+
+func newAuditFileWriter(path string, maxSize int64) (*auditFileWriter, error) {
+	w := &auditFileWriter{path: path, maxSize: maxSize}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// open opens the audit file for appending
+func (w *auditFileWriter) open() error {
+	file, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	w.file = file
+	w.size = info.Size()
+	return nil
+}
+
+// write appends a notification to the file, after rotating it if too large
+func (w *auditFileWriter) write(r NotificationRecord) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	if w.size > 0 && w.size+int64(len(line)) > w.maxSize {
+		w.file.Close()
+		renameErr := os.Rename(w.path, w.path+".1")
+		if err := w.open(); err != nil {
+			return err
+		}
+		if renameErr != nil {
+			return renameErr
+		}
+	}
+
+	n, err := w.file.Write(line)
+	w.size += int64(n)
+	return err
+}
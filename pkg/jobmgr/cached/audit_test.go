@@ -0,0 +1,187 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cached
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	pbjob "github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	pbtask "github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+)
+
+// TestNotificationAuditDisabled tests that no audit is created when it is
+// not enabled
+func TestNotificationAuditDisabled(t *testing.T) {
+	a, err := NewNotificationAudit(NotificationAuditConfig{}, tally.NoopScope)
+	assert.NoError(t, err)
+	assert.Nil(t, a)
+
+	// recording is a no-op
+	a.delivered(a.published("job", nil, "RUNNING", false), "listener")
+}
+
+// TestNotificationAuditQueryAndEviction tests that the notifications
+// delivered are returned by job, and the oldest ones evicted
+func TestNotificationAuditQueryAndEviction(t *testing.T) {
+	a, err := NewNotificationAudit(
+		NotificationAuditConfig{Enabled: true, BufferSize: 4, Shards: 1},
+		tally.NoopScope)
+	require.NoError(t, err)
+
+	instanceID := uint32(1)
+	for _, state := range []string{"PENDING", "LAUNCHED", "RUNNING"} {
+		a.delivered(a.published("job-1", &instanceID, state, false), "watch")
+	}
+	a.delivered(a.published("job-2", nil, "RUNNING", false), "watch")
+
+	records := a.Query("job-1")
+	require.Len(t, records, 3)
+	assert.Equal(t, "watch", records[0].Listener)
+	assert.Equal(t, uint32(1), *records[0].InstanceID)
+	assert.Equal(t, "", records[0].FromState)
+	assert.Equal(t, "PENDING", records[0].ToState)
+	assert.Equal(t, "LAUNCHED", records[2].FromState)
+	assert.Equal(t, "RUNNING", records[2].ToState)
+	assert.True(t, records[2].Latency >= 0)
+
+	jobRecords := a.Query("job-2")
+	require.Len(t, jobRecords, 1)
+	assert.Nil(t, jobRecords[0].InstanceID)
+
+	// the oldest notifications are evicted
+	a.delivered(a.published("job-1", &instanceID, "SUCCEEDED", false), "watch")
+	a.delivered(a.published("job-1", &instanceID, "DELETED", true), "watch")
+	records = a.Query("job-1")
+	require.Len(t, records, 3)
+	assert.Equal(t, "RUNNING", records[0].ToState)
+	assert.Equal(t, "DELETED", records[2].ToState)
+	assert.Len(t, a.Query("job-2"), 1)
+
+	// the deleted task has no previous state anymore
+	assert.Empty(t, a.published("job-1", &instanceID, "RUNNING", false).FromState)
+}
+
+// TestNotificationAuditForget tests that the last states notified for a job
+// are dropped when it is forgotten
+func TestNotificationAuditForget(t *testing.T) {
+	a, err := NewNotificationAudit(
+		NotificationAuditConfig{Enabled: true, Shards: 1}, tally.NoopScope)
+	require.NoError(t, err)
+
+	instanceID := uint32(0)
+	a.published("job-1", &instanceID, "RUNNING", false)
+	a.published("job-1", nil, "RUNNING", false)
+	a.published("job-2", nil, "RUNNING", false)
+	assert.Len(t, a.shards[0].lastStates, 2)
+
+	a.forget("job-1")
+	assert.Len(t, a.shards[0].lastStates, 1)
+	assert.Empty(t, a.published("job-1", &instanceID, "SUCCEEDED", false).FromState)
+	assert.Empty(t, a.published("job-1", nil, "SUCCEEDED", false).FromState)
+	assert.Equal(t, "RUNNING",
+		a.published("job-2", nil, "SUCCEEDED", false).FromState)
+
+	// the last state of a job is dropped with its last task deleted
+	a.forget("job-1")
+	a.published("job-1", &instanceID, "DELETED", true)
+	assert.Len(t, a.shards[0].lastStates, 1)
+
+	// forgetting is a no-op if the audit is not enabled
+	var disabled *NotificationAudit
+	disabled.forget("job-1")
+}
+
+// TestNotificationAuditJobFactory tests that the notifications delivered
+// by the job factory are audited for each listener
+func TestNotificationAuditJobFactory(t *testing.T) {
+	a, err := NewNotificationAudit(
+		NotificationAuditConfig{Enabled: true}, tally.NoopScope)
+	require.NoError(t, err)
+	f := &jobFactory{
+		jobs: map[string]*job{},
+		mtx:  NewMetrics(tally.NoopScope),
+		listeners: []JobTaskListener{
+			&FakeJobListener{},
+			&FakeTaskListener{},
+		},
+		audit: a,
+	}
+	jobID := &peloton.JobID{Value: "3c8a3c3e-71e3-49c5-9aed-2929823f595c"}
+
+	f.notifyTaskRuntimeChanged(jobID, 0, pbjob.JobType_BATCH,
+		&pbtask.RuntimeInfo{State: pbtask.TaskState_RUNNING}, nil,
+		f.nextSequence())
+	f.notifyJobRuntimeChanged(jobID, pbjob.JobType_BATCH,
+		&pbjob.RuntimeInfo{State: pbjob.JobState_RUNNING}, f.nextSequence())
+
+	records := a.Query(jobID.GetValue())
+	require.Len(t, records, 4)
+	assert.Equal(t, "fake_job_listener", records[0].Listener)
+	assert.Equal(t, "fake_task_listener", records[1].Listener)
+	assert.Equal(t, "RUNNING", records[1].ToState)
+	assert.NotNil(t, records[1].InstanceID)
+	assert.Nil(t, records[3].InstanceID)
+
+	// debug endpoint
+	recorder := httptest.NewRecorder()
+	NotificationAuditHandler(a)(recorder, httptest.NewRequest(
+		http.MethodGet, NotificationAuditPath+"?job_id="+jobID.GetValue(), nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	var body []NotificationRecord
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Len(t, body, 4)
+
+	recorder = httptest.NewRecorder()
+	NotificationAuditHandler(a)(recorder, httptest.NewRequest(
+		http.MethodGet, NotificationAuditPath, nil))
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+// TestAuditFileRotation tests that the audit file is rotated when too large
+func TestAuditFileRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "notifications.log")
+
+	w, err := newAuditFileWriter(path, 200)
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, w.write(NotificationRecord{
+			JobID:    "job",
+			Listener: "watch",
+			ToState:  "RUNNING",
+		}))
+	}
+
+	rotated, err := ioutil.ReadFile(path + ".1")
+	require.NoError(t, err)
+	assert.NotEmpty(t, rotated)
+	current, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotEmpty(t, current)
+	assert.True(t, len(current) <= 200)
+}
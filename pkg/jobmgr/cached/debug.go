@@ -0,0 +1,51 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cached
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// NotificationAuditPath is the path of the debug endpoint which returns the
+// notifications delivered to the listeners for the job given by the job_id
+// query parameter.
+const NotificationAuditPath = "/debug/notifications"
+
+// NotificationAuditHandler returns the handler of the debug endpoint which
+// returns the notifications of a job kept by the audit as JSON.
+func NotificationAuditHandler(
+	a *NotificationAudit) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if a == nil {
+			http.Error(w, "notification audit is not enabled", http.StatusNotFound)
+			return
+		}
+		jobID := r.URL.Query().Get("job_id")
+		if jobID == "" {
+			http.Error(w, "missing job_id", http.StatusBadRequest)
+			return
+		}
+
+		body, err := json.Marshal(a.Query(jobID))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
@@ -0,0 +1,312 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cached
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	pbjob "github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	pbtask "github.com/uber/peloton/.gen/peloton/api/v0/task"
+	"github.com/uber/peloton/pkg/common/util"
+
+	log "github.com/sirupsen/logrus"
+	uatomic "github.com/uber-go/atomic"
+	"github.com/uber-go/tally"
+)
+
+const (
+	_defaultLabelIndexTerminalRetention = 10 * time.Minute
+	_defaultLabelIndexMaxResults        = 1000
+)
+
+// LabelIndexConfig is the configuration of the IndexListener
+type LabelIndexConfig struct {
+	// TerminalRetention is how long the terminal tasks are kept in the
+	// index
+	TerminalRetention time.Duration `yaml:"terminal_retention"`
+
+	// MaxResults is the maximum number of tasks returned by a lookup
+	MaxResults int `yaml:"max_results"`
+
+	// PurgePeriod is the period the terminal tasks are purged from the
+	// index, 0 to not purge them
+	PurgePeriod time.Duration `yaml:"purge_period"`
+}
+
+// LabeledTask is a task found in the label index
+type LabeledTask struct {
+	JobID      *peloton.JobID
+	InstanceID uint32
+	// State is the state of the task when last indexed
+	State pbtask.TaskState
+}
+
+// labeledTaskKey identifies a task in the label index
+type labeledTaskKey struct {
+	jobID      string
+	instanceID uint32
+}
+
+// indexedTask is a task in the label index
+type indexedTask struct {
+	labels []*peloton.Label
+	state  pbtask.TaskState
+	// terminalSince is when the task was first indexed as terminal
+	terminalSince time.Time
+}
+
+// IndexListener is a JobTaskListener maintaining an in-memory index of the
+// tasks in cache by label, so that the tasks with a label can be found
+// without querying the store. The index is built from the task runtime
+// changes, and rebuilt from the cache when it is recovered. The terminal
+// tasks are kept in the index for a retention period.
+type IndexListener struct {
+	sync.RWMutex
+
+	terminalRetention time.Duration
+	maxResults        int
+	now               func() time.Time
+
+	// tasks are the tasks indexed
+	tasks map[labeledTaskKey]*indexedTask
+	// index is the tasks by label key and value
+	index map[string]map[string]map[labeledTaskKey]bool
+
+	indexedTasks tally.Gauge
+	purgedTasks  tally.Counter
+}
+
+// NewIndexListener creates an IndexListener
+func NewIndexListener(
+	config LabelIndexConfig,
+	parentScope tally.Scope) *IndexListener {
+	scope := parentScope.SubScope("label_index")
+	l := &IndexListener{
+		terminalRetention: config.TerminalRetention,
+		maxResults:        config.MaxResults,
+		now:               time.Now,
+		tasks:             make(map[labeledTaskKey]*indexedTask),
+		index:             make(map[string]map[string]map[labeledTaskKey]bool),
+		indexedTasks:      scope.Gauge("indexed_tasks"),
+		purgedTasks:       scope.Counter("purged_tasks"),
+	}
+	if l.terminalRetention <= 0 {
+		l.terminalRetention = _defaultLabelIndexTerminalRetention
+	}
+	if l.maxResults <= 0 {
+		l.maxResults = _defaultLabelIndexMaxResults
+	}
+	return l
+}
+
+// Name returns the name of the listener
+func (l *IndexListener) Name() string {
+	return "label_index_listener"
+}
+
+// JobRuntimeChanged is a no-op, as the index only contains tasks
+func (l *IndexListener) JobRuntimeChanged(
+	jobID *peloton.JobID,
+	jobType pbjob.JobType,
+	runtime *pbjob.RuntimeInfo,
+	sequence NotificationSequence) {
+}
+
+// TaskRuntimeChanged indexes the task by its labels, and removes the
+// deleted tasks from the index
+func (l *IndexListener) TaskRuntimeChanged(
+	jobID *peloton.JobID,
+	instanceID uint32,
+	jobType pbjob.JobType,
+	runtime *pbtask.RuntimeInfo,
+	labels []*peloton.Label,
+	sequence NotificationSequence) {
+	key := labeledTaskKey{jobID: jobID.GetValue(), instanceID: instanceID}
+
+	l.Lock()
+	defer l.Unlock()
+	if runtime.GetState() == pbtask.TaskState_DELETED {
+		l.remove(key)
+		return
+	}
+	l.add(key, runtime.GetState(), labels)
+}
+
+// CacheRecovered rebuilds the index from the tasks recovered in cache
+func (l *IndexListener) CacheRecovered(factory JobFactory) {
+	tasks := make(map[labeledTaskKey]*indexedTask)
+	for jobID := range factory.GetAllJobs() {
+		snapshot := factory.GetJobSnapshot(&peloton.JobID{Value: jobID})
+		if snapshot == nil {
+			continue
+		}
+		for instanceID, runtime := range snapshot.TaskRuntimes {
+			key := labeledTaskKey{jobID: jobID, instanceID: instanceID}
+			tasks[key] = &indexedTask{
+				labels: snapshot.TaskLabels[instanceID],
+				state:  runtime.GetState(),
+			}
+		}
+	}
+
+	l.Lock()
+	defer l.Unlock()
+	l.tasks = make(map[labeledTaskKey]*indexedTask)
+	l.index = make(map[string]map[string]map[labeledTaskKey]bool)
+	for key, task := range tasks {
+		l.add(key, task.state, task.labels)
+	}
+	log.WithField("tasks", len(l.tasks)).Info("label index rebuilt")
+}
+
+// FindTasksByLabel returns the tasks with a label, sorted by job and
+// instance id, from offset and up to limit tasks, or the maximum number of
+// results if lower. The tasks with any value of the label key are returned
+// when value is empty. It also returns the total number of tasks found.
+func (l *IndexListener) FindTasksByLabel(
+	key string,
+	value string,
+	offset int,
+	limit int) ([]*LabeledTask, int) {
+	l.RLock()
+	defer l.RUnlock()
+
+	var keys []labeledTaskKey
+	for labelValue, tasks := range l.index[key] {
+		if value != "" && labelValue != value {
+			continue
+		}
+		for taskKey := range tasks {
+			keys = append(keys, taskKey)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].jobID != keys[j].jobID {
+			return keys[i].jobID < keys[j].jobID
+		}
+		return keys[i].instanceID < keys[j].instanceID
+	})
+	// a task has a label key once, but an empty value matches all of them
+	keys = uniqueLabeledTaskKeys(keys)
+
+	total := len(keys)
+	if offset < 0 || offset >= total {
+		return nil, total
+	}
+	if limit <= 0 || limit > l.maxResults {
+		limit = l.maxResults
+	}
+	end := offset + limit
+	if end > total {
+		end = total
+	}
+
+	var result []*LabeledTask
+	for _, taskKey := range keys[offset:end] {
+		result = append(result, &LabeledTask{
+			JobID:      &peloton.JobID{Value: taskKey.jobID},
+			InstanceID: taskKey.instanceID,
+			State:      l.tasks[taskKey].state,
+		})
+	}
+	return result, total
+}
+
+// Purge removes from the index the tasks terminal for longer than the
+// retention. It is run as a background work.
+func (l *IndexListener) Purge(_ *uatomic.Bool) {
+	l.Lock()
+	defer l.Unlock()
+
+	now := l.now()
+	for key, task := range l.tasks {
+		if !task.terminalSince.IsZero() &&
+			now.Sub(task.terminalSince) >= l.terminalRetention {
+			l.remove(key)
+			l.purgedTasks.Inc(1)
+		}
+	}
+	l.indexedTasks.Update(float64(len(l.tasks)))
+}
+
+// add indexes a task by its labels, replacing its previous labels. The
+// lock must be held.
+func (l *IndexListener) add(
+	key labeledTaskKey,
+	state pbtask.TaskState,
+	labels []*peloton.Label) {
+	var terminalSince time.Time
+	if previous, ok := l.tasks[key]; ok {
+		terminalSince = previous.terminalSince
+		l.remove(key)
+	}
+	if !util.IsPelotonStateTerminal(state) {
+		terminalSince = time.Time{}
+	} else if terminalSince.IsZero() {
+		terminalSince = l.now()
+	}
+
+	l.tasks[key] = &indexedTask{
+		labels:        labels,
+		state:         state,
+		terminalSince: terminalSince,
+	}
+	for _, label := range labels {
+		values, ok := l.index[label.GetKey()]
+		if !ok {
+			values = make(map[string]map[labeledTaskKey]bool)
+			l.index[label.GetKey()] = values
+		}
+		tasks, ok := values[label.GetValue()]
+		if !ok {
+			tasks = make(map[labeledTaskKey]bool)
+			values[label.GetValue()] = tasks
+		}
+		tasks[key] = true
+	}
+}
+
+// remove removes a task from the index. The lock must be held.
+func (l *IndexListener) remove(key labeledTaskKey) {
+	task, ok := l.tasks[key]
+	if !ok {
+		return
+	}
+	delete(l.tasks, key)
+	for _, label := range task.labels {
+		values := l.index[label.GetKey()]
+		delete(values[label.GetValue()], key)
+		if len(values[label.GetValue()]) == 0 {
+			delete(values, label.GetValue())
+		}
+		if len(values) == 0 {
+			delete(l.index, label.GetKey())
+		}
+	}
+}
+
+// uniqueLabeledTaskKeys removes the duplicates of sorted keys
+func uniqueLabeledTaskKeys(keys []labeledTaskKey) []labeledTaskKey {
+	var unique []labeledTaskKey
+	for i, key := range keys {
+		if i == 0 || key != keys[i-1] {
+			unique = append(unique, key)
+		}
+	}
+	return unique
+}
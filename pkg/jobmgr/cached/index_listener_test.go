@@ -0,0 +1,185 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cached
+
+import (
+	"testing"
+	"time"
+
+	pbjob "github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	pbtask "github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+type IndexListenerTestSuite struct {
+	suite.Suite
+
+	listener *IndexListener
+	now      time.Time
+	jobID    *peloton.JobID
+}
+
+func TestIndexListener(t *testing.T) {
+	suite.Run(t, new(IndexListenerTestSuite))
+}
+
+func (suite *IndexListenerTestSuite) SetupTest() {
+	suite.listener = NewIndexListener(
+		LabelIndexConfig{
+			TerminalRetention: time.Minute,
+			MaxResults:        2,
+		},
+		tally.NoopScope)
+	suite.now = time.Now()
+	suite.listener.now = func() time.Time { return suite.now }
+	suite.jobID = &peloton.JobID{Value: "3c8a3c3e-71e3-49c5-9aed-2929823f595c"}
+}
+
+// taskChanged notifies the listener of a task runtime change
+func (suite *IndexListenerTestSuite) taskChanged(
+	instanceID uint32,
+	state pbtask.TaskState,
+	labels ...*peloton.Label) {
+	suite.listener.TaskRuntimeChanged(
+		suite.jobID,
+		instanceID,
+		pbjob.JobType_SERVICE,
+		&pbtask.RuntimeInfo{State: state},
+		labels,
+		NotificationSequence{})
+}
+
+// instances returns the instances found with a label
+func (suite *IndexListenerTestSuite) instances(
+	key string,
+	value string) []uint32 {
+	tasks, total := suite.listener.FindTasksByLabel(key, value, 0, 0)
+	suite.Equal(len(tasks), total)
+	var instances []uint32
+	for _, task := range tasks {
+		suite.Equal(suite.jobID.GetValue(), task.JobID.GetValue())
+		instances = append(instances, task.InstanceID)
+	}
+	return instances
+}
+
+// TestLabelChanges tests that the tasks are indexed by their current
+// labels, and removed when deleted
+func (suite *IndexListenerTestSuite) TestLabelChanges() {
+	suite.taskChanged(0, pbtask.TaskState_RUNNING,
+		&peloton.Label{Key: "zone", Value: "a"})
+	suite.taskChanged(1, pbtask.TaskState_RUNNING,
+		&peloton.Label{Key: "zone", Value: "b"},
+		&peloton.Label{Key: "canary", Value: "true"})
+
+	suite.Equal([]uint32{0, 1}, suite.instances("zone", ""))
+	suite.Equal([]uint32{1}, suite.instances("zone", "b"))
+	suite.Equal([]uint32{1}, suite.instances("canary", "true"))
+	suite.Nil(suite.instances("zone", "c"))
+
+	// update the labels
+	suite.taskChanged(1, pbtask.TaskState_RUNNING,
+		&peloton.Label{Key: "zone", Value: "a"})
+	suite.Equal([]uint32{0, 1}, suite.instances("zone", "a"))
+	suite.Nil(suite.instances("zone", "b"))
+	suite.Nil(suite.instances("canary", ""))
+	suite.Empty(suite.listener.index["canary"])
+
+	// delete a task
+	suite.taskChanged(0, pbtask.TaskState_DELETED)
+	suite.Equal([]uint32{1}, suite.instances("zone", "a"))
+	suite.Len(suite.listener.tasks, 1)
+}
+
+// TestPagination tests that the tasks found are paginated, and capped to
+// the maximum number of results
+func (suite *IndexListenerTestSuite) TestPagination() {
+	for i := uint32(0); i < 5; i++ {
+		suite.taskChanged(i, pbtask.TaskState_RUNNING,
+			&peloton.Label{Key: "zone", Value: "a"})
+	}
+
+	tasks, total := suite.listener.FindTasksByLabel("zone", "a", 0, 10)
+	suite.Equal(5, total)
+	suite.Len(tasks, 2)
+	suite.Equal(uint32(1), tasks[1].InstanceID)
+
+	tasks, _ = suite.listener.FindTasksByLabel("zone", "a", 3, 2)
+	suite.Len(tasks, 2)
+	suite.Equal(uint32(3), tasks[0].InstanceID)
+	suite.Equal(pbtask.TaskState_RUNNING, tasks[0].State)
+
+	tasks, _ = suite.listener.FindTasksByLabel("zone", "a", 4, 1)
+	suite.Len(tasks, 1)
+	suite.Equal(uint32(4), tasks[0].InstanceID)
+
+	tasks, total = suite.listener.FindTasksByLabel("zone", "a", 5, 1)
+	suite.Empty(tasks)
+	suite.Equal(5, total)
+}
+
+// TestTerminalRetention tests that the terminal tasks are purged after the
+// retention, unless running again
+func (suite *IndexListenerTestSuite) TestTerminalRetention() {
+	label := &peloton.Label{Key: "zone", Value: "a"}
+	suite.taskChanged(0, pbtask.TaskState_RUNNING, label)
+	suite.taskChanged(1, pbtask.TaskState_SUCCEEDED, label)
+	suite.taskChanged(2, pbtask.TaskState_FAILED, label)
+
+	suite.now = suite.now.Add(30 * time.Second)
+	// the retention is from the first terminal update
+	suite.taskChanged(1, pbtask.TaskState_SUCCEEDED, label)
+	// running again
+	suite.taskChanged(2, pbtask.TaskState_RUNNING, label)
+	suite.listener.Purge(nil)
+	suite.Len(suite.listener.tasks, 3)
+
+	suite.now = suite.now.Add(30 * time.Second)
+	suite.listener.Purge(nil)
+	suite.Equal([]uint32{0, 2}, suite.instances("zone", "a"))
+	suite.Len(suite.listener.tasks, 2)
+}
+
+// TestCacheRecovered tests that the index is rebuilt from the cache when
+// it is recovered
+func (suite *IndexListenerTestSuite) TestCacheRecovered() {
+	// indexed before the recovery
+	suite.taskChanged(5, pbtask.TaskState_RUNNING,
+		&peloton.Label{Key: "zone", Value: "b"})
+
+	f := &jobFactory{
+		jobs:      map[string]*job{},
+		mtx:       NewMetrics(tally.NoopScope),
+		listeners: []JobTaskListener{suite.listener},
+	}
+	j := f.AddJob(suite.jobID).(*job)
+	for i := uint32(0); i < 2; i++ {
+		t := j.addTaskToJobMap(i)
+		t.runtime = &pbtask.RuntimeInfo{State: pbtask.TaskState_RUNNING}
+		t.config = &taskConfigCache{
+			labels: []*peloton.Label{{Key: "zone", Value: "a"}},
+		}
+	}
+	// not in cache
+	j.addTaskToJobMap(2)
+
+	f.StartRecoveryMode()
+	f.StopRecoveryMode()
+	suite.Equal([]uint32{0, 1}, suite.instances("zone", "a"))
+	suite.Nil(suite.instances("zone", "b"))
+}
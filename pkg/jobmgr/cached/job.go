@@ -316,12 +316,31 @@ type job struct {
 	resourceUsage map[string]float64
 
 	workflows map[string]*update // map of all job workflows
+
+	// revision of the runtime last handed to the listeners, 0 if none
+	publishedRevision uint64
 }
 
 func (j *job) ID() *peloton.JobID {
 	return j.id
 }
 
+// runtimeToPublish returns a copy of the runtime of the job to notify the
+// listeners of, with the sequence of the notification, or nil if its
+// revision was already handed to them. The cached runtime only changes
+// with a new revision, so the runtimes of a revision are identical. The job
+// must be locked, so that the sequences follow the order of the mutations.
+func (j *job) runtimeToPublish() (*pbjob.RuntimeInfo, NotificationSequence) {
+	version := j.runtime.GetRevision().GetVersion()
+	if version != 0 && version == j.publishedRevision {
+		j.jobFactory.mtx.JobRuntimePublishSkipped.Inc(1)
+		return nil, NotificationSequence{}
+	}
+	j.publishedRevision = version
+	return proto.Clone(j.runtime).(*pbjob.RuntimeInfo),
+		j.jobFactory.nextSequence()
+}
+
 // populateCurrentJobConfig populates the config pointed by runtime config version
 // into cache
 func (j *job) populateCurrentJobConfig(ctx context.Context) error {
@@ -536,13 +555,14 @@ func (j *job) GetTask(id uint32) Task {
 
 func (j *job) RemoveTask(id uint32) {
 	j.Lock()
-	defer j.Unlock()
+	t, ok := j.tasks[id]
+	delete(j.tasks, id)
+	j.Unlock()
 
-	if t, ok := j.tasks[id]; ok {
+	// notify listeners after dropping the lock
+	if ok {
 		t.DeleteTask()
 	}
-
-	delete(j.tasks, id)
 }
 
 func (j *job) GetAllTasks() map[uint32]Task {
@@ -557,11 +577,12 @@ func (j *job) GetAllTasks() map[uint32]Task {
 
 func (j *job) Create(ctx context.Context, config *pbjob.JobConfig, configAddOn *models.ConfigAddOn, createBy string) error {
 	var runtimeCopy *pbjob.RuntimeInfo
+	var sequence NotificationSequence
 	var jobType pbjob.JobType
 	// notify listeners after dropping the lock
 	defer func() {
 		j.jobFactory.notifyJobRuntimeChanged(j.ID(), jobType,
-			runtimeCopy)
+			runtimeCopy, sequence)
 	}()
 	j.Lock()
 	defer j.Unlock()
@@ -626,7 +647,7 @@ func (j *job) Create(ctx context.Context, config *pbjob.JobConfig, configAddOn *
 		return err
 	}
 
-	runtimeCopy = proto.Clone(j.runtime).(*pbjob.RuntimeInfo)
+	runtimeCopy, sequence = j.runtimeToPublish()
 	return nil
 }
 
@@ -639,12 +660,13 @@ func (j *job) RollingCreate(
 	createBy string,
 ) error {
 	var runtimeCopy *pbjob.RuntimeInfo
+	var sequence NotificationSequence
 	var jobType pbjob.JobType
 
 	// notify listeners after dropping the lock
 	defer func() {
 		j.jobFactory.notifyJobRuntimeChanged(j.ID(), jobType,
-			runtimeCopy)
+			runtimeCopy, sequence)
 	}()
 
 	j.Lock()
@@ -761,7 +783,7 @@ func (j *job) RollingCreate(
 	}
 	j.workflows[updateID.GetValue()] = newWorkflow
 
-	runtimeCopy = proto.Clone(j.runtime).(*pbjob.RuntimeInfo)
+	runtimeCopy, sequence = j.runtimeToPublish()
 	return nil
 }
 
@@ -824,12 +846,13 @@ func (j *job) CompareAndSetRuntime(ctx context.Context, jobRuntime *pbjob.Runtim
 		return nil, yarpcerrors.InvalidArgumentErrorf("unexpected nil jobRuntime")
 	}
 
-	var runtimeCopy *pbjob.RuntimeInfo
+	var published *pbjob.RuntimeInfo
+	var sequence NotificationSequence
 	var jobType pbjob.JobType
 	// notify listeners after dropping the lock
 	defer func() {
-		j.jobFactory.notifyJobRuntimeChanged(j.ID(), jobType,
-			runtimeCopy)
+		j.jobFactory.notifyJobRuntimeChanged(
+			j.ID(), jobType, published, sequence)
 	}()
 	j.Lock()
 	defer j.Unlock()
@@ -871,9 +894,9 @@ func (j *job) CompareAndSetRuntime(ctx context.Context, jobRuntime *pbjob.Runtim
 	}
 
 	j.runtime = &newRuntime
-	runtimeCopy = proto.Clone(j.runtime).(*pbjob.RuntimeInfo)
+	published, sequence = j.runtimeToPublish()
 	jobType = j.jobType
-	return runtimeCopy, nil
+	return proto.Clone(j.runtime).(*pbjob.RuntimeInfo), nil
 }
 
 func (j *job) CompareAndSetConfig(ctx context.Context, config *pbjob.JobConfig, configAddOn *models.ConfigAddOn) (jobmgrcommon.JobConfig, error) {
@@ -944,11 +967,12 @@ func (j *job) compareAndSetConfig(ctx context.Context, config *pbjob.JobConfig,
 // The config would be updated to the config passed in (except changeLog)
 func (j *job) Update(ctx context.Context, jobInfo *pbjob.JobInfo, configAddOn *models.ConfigAddOn, req UpdateRequest) error {
 	var runtimeCopy *pbjob.RuntimeInfo
+	var sequence NotificationSequence
 	var jobType pbjob.JobType
 	// notify listeners after dropping the lock
 	defer func() {
 		j.jobFactory.notifyJobRuntimeChanged(j.ID(), jobType,
-			runtimeCopy)
+			runtimeCopy, sequence)
 	}()
 	j.Lock()
 	defer j.Unlock()
@@ -1018,7 +1042,6 @@ func (j *job) Update(ctx context.Context, jobInfo *pbjob.JobInfo, configAddOn *m
 				j.invalidateCache()
 				return err
 			}
-			runtimeCopy = proto.Clone(j.runtime).(*pbjob.RuntimeInfo)
 		}
 
 		if updatedConfig != nil || updatedRuntime != nil {
@@ -1029,10 +1052,13 @@ func (j *job) Update(ctx context.Context, jobInfo *pbjob.JobInfo, configAddOn *m
 				updatedRuntime,
 			); err != nil {
 				j.invalidateCache()
-				runtimeCopy = nil
 				return err
 			}
 		}
+
+		if updatedRuntime != nil {
+			runtimeCopy, sequence = j.runtimeToPublish()
+		}
 	}
 	jobType = j.jobType
 	return nil
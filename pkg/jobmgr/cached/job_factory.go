@@ -45,11 +45,35 @@ type JobFactory interface {
 	// GetAllJobs returns the list of all jobs in cache.
 	GetAllJobs() map[string]Job
 
-	// Start emitting metrics.
-	Start()
+	// GetJobSnapshot returns a snapshot of the cached runtimes of a job and
+	// of its tasks, and nil if the job is not in cache. Listeners can use
+	// it to look up the state of other instances of the job.
+	GetJobSnapshot(id *peloton.JobID) *JobSnapshot
+
+	// Start emitting metrics, the notifications to the listeners being
+	// sequenced within the epoch of the leadership gained, see
+	// NotificationSequence.
+	Start(epoch uint64)
 
 	// Stop clears the current jobs and tasks in cache, stops metrics.
 	Stop()
+
+	// StartRecoveryMode starts batching the job runtime notifications to
+	// the listeners, so that only the last runtime of each job is published
+	// when the recovery is stopped.
+	StartRecoveryMode()
+
+	// StopRecoveryMode stops batching the job runtime notifications, and
+	// publishes the runtimes batched.
+	StopRecoveryMode()
+}
+
+// pendingJobRuntime is a job runtime notification batched during recovery
+type pendingJobRuntime struct {
+	jobID    *peloton.JobID
+	jobType  pbjob.JobType
+	runtime  *pbjob.RuntimeInfo
+	sequence NotificationSequence
 }
 
 type jobFactory struct {
@@ -68,8 +92,26 @@ type jobFactory struct {
 	// Tob/task listeners. This list is immutable after object is created.
 	// So it can read without a lock.
 	listeners []JobTaskListener
+	// audit of the notifications delivered to the listeners, nil if not
+	// enabled
+	audit *NotificationAudit
 	// channel to indicate that the job factory needs to stop
 	stopChan chan struct{}
+
+	// publishLock protects the batching of the job runtimes below, it is
+	// not held while the listeners are notified
+	publishLock sync.Mutex
+	// whether the job runtime notifications are batched for recovery
+	recoveryMode bool
+	// last job runtimes batched during recovery, by job id
+	pendingRuntimes map[string]*pendingJobRuntime
+
+	// sequenceLock protects the notification sequence below
+	sequenceLock sync.Mutex
+	// epoch of the notification sequences, of the leadership gained
+	epoch uint64
+	// last notification sequence, shared by all the jobs
+	sequence uint64
 }
 
 // InitJobFactory initializes the job factory object.
@@ -80,7 +122,8 @@ func InitJobFactory(
 	volumeStore storage.PersistentVolumeStore,
 	ormStore *ormobjects.Store,
 	parentScope tally.Scope,
-	listeners []JobTaskListener) JobFactory {
+	listeners []JobTaskListener,
+	audit *NotificationAudit) JobFactory {
 	return &jobFactory{
 		jobs:           map[string]*job{},
 		jobStore:       jobStore,
@@ -91,6 +134,7 @@ func InitJobFactory(
 		jobNameToIDOps: ormobjects.NewJobNameToIDOps(ormStore),
 		mtx:            NewMetrics(parentScope.SubScope("cache")),
 		listeners:      listeners,
+		audit:          audit,
 	}
 }
 
@@ -122,6 +166,12 @@ func (f *jobFactory) ClearJob(id *peloton.JobID) {
 	f.Lock()
 	defer f.Unlock()
 	delete(f.jobs, j.ID().GetValue())
+
+	f.publishLock.Lock()
+	defer f.publishLock.Unlock()
+	delete(f.pendingRuntimes, j.ID().GetValue())
+
+	f.audit.forget(j.ID().GetValue())
 }
 
 func (f *jobFactory) GetJob(id *peloton.JobID) Job {
@@ -147,7 +197,7 @@ func (f *jobFactory) GetAllJobs() map[string]Job {
 }
 
 // Start the job factory, starts emitting metrics.
-func (f *jobFactory) Start() {
+func (f *jobFactory) Start(epoch uint64) {
 	f.Lock()
 	defer f.Unlock()
 
@@ -156,6 +206,11 @@ func (f *jobFactory) Start() {
 	}
 	f.running = true
 
+	f.sequenceLock.Lock()
+	f.epoch = epoch
+	f.sequence = 0
+	f.sequenceLock.Unlock()
+
 	f.stopChan = make(chan struct{})
 	go f.runPublishMetrics(f.stopChan)
 	log.Info("job factory started")
@@ -175,9 +230,50 @@ func (f *jobFactory) Stop() {
 	f.running = false
 	f.jobs = map[string]*job{}
 	close(f.stopChan)
+
+	f.publishLock.Lock()
+	f.pendingRuntimes = nil
+	f.recoveryMode = false
+	f.publishLock.Unlock()
 	log.Info("job factory stopped")
 }
 
+// StartRecoveryMode starts batching the job runtime notifications
+func (f *jobFactory) StartRecoveryMode() {
+	f.publishLock.Lock()
+	defer f.publishLock.Unlock()
+	f.recoveryMode = true
+	log.Info("job factory recovery mode started")
+}
+
+// StopRecoveryMode stops batching the job runtime notifications, and
+// publishes the last runtime batched of each job. The listeners are then
+// notified that the cache is recovered.
+func (f *jobFactory) StopRecoveryMode() {
+	f.publishLock.Lock()
+	if !f.recoveryMode {
+		f.publishLock.Unlock()
+		return
+	}
+	f.recoveryMode = false
+
+	pending := f.pendingRuntimes
+	f.pendingRuntimes = nil
+	f.publishLock.Unlock()
+
+	for _, p := range pending {
+		f.publishJobRuntime(p.jobID, p.jobType, p.runtime, p.sequence)
+	}
+	log.WithField("jobs", len(pending)).
+		Info("job factory recovery mode stopped")
+
+	for _, l := range f.listeners {
+		if rl, ok := l.(CacheRecoveredListener); ok {
+			rl.CacheRecovered(f)
+		}
+	}
+}
+
 //TODO Refactor to remove the metrics loop into a separate component.
 // JobFactory should only implement an interface like MetricsProvides
 // to periodically publish metrics instead of having its own go routine.
@@ -240,14 +336,55 @@ func (f *jobFactory) publishMetrics() map[pbtask.TaskState]map[pbtask.TaskState]
 func (f *jobFactory) notifyJobRuntimeChanged(
 	jobID *peloton.JobID,
 	jobType pbjob.JobType,
-	runtime *pbjob.RuntimeInfo) {
+	runtime *pbjob.RuntimeInfo,
+	sequence NotificationSequence) {
 
-	if runtime != nil {
-		for _, l := range f.listeners {
-			l.JobRuntimeChanged(jobID, jobType, runtime)
+	if runtime == nil {
+		return
+	}
+
+	f.publishLock.Lock()
+	if f.recoveryMode {
+		if f.pendingRuntimes == nil {
+			f.pendingRuntimes = make(map[string]*pendingJobRuntime)
 		}
-		// TODO add metric for listener execution latency
+		// the runtimes of a job may be notified out of order by concurrent
+		// mutations, the one of the latest revision is kept
+		p, ok := f.pendingRuntimes[jobID.GetValue()]
+		if ok {
+			f.mtx.JobRuntimePublishBatched.Inc(1)
+		}
+		if !ok || p.runtime.GetRevision().GetVersion() <=
+			runtime.GetRevision().GetVersion() {
+			f.pendingRuntimes[jobID.GetValue()] = &pendingJobRuntime{
+				jobID:    jobID,
+				jobType:  jobType,
+				runtime:  runtime,
+				sequence: sequence,
+			}
+		}
+		f.publishLock.Unlock()
+		return
+	}
+	f.publishLock.Unlock()
+	f.publishJobRuntime(jobID, jobType, runtime, sequence)
+}
+
+// publishJobRuntime notifies the listeners of the runtime of a job. The
+// runtimes already notified are skipped by the job, see
+// job.runtimeToPublish.
+func (f *jobFactory) publishJobRuntime(
+	jobID *peloton.JobID,
+	jobType pbjob.JobType,
+	runtime *pbjob.RuntimeInfo,
+	sequence NotificationSequence) {
+	published := f.audit.published(
+		jobID.GetValue(), nil, runtime.GetState().String(), false)
+	for _, l := range f.listeners {
+		l.JobRuntimeChanged(jobID, jobType, runtime, sequence)
+		f.audit.delivered(published, l.Name())
 	}
+	// TODO add metric for listener execution latency
 }
 
 func (f *jobFactory) notifyTaskRuntimeChanged(
@@ -255,12 +392,35 @@ func (f *jobFactory) notifyTaskRuntimeChanged(
 	instanceID uint32,
 	jobType pbjob.JobType,
 	runtime *pbtask.RuntimeInfo,
-	labels []*peloton.Label) {
+	labels []*peloton.Label,
+	sequence NotificationSequence) {
 
 	if runtime != nil {
+		published := f.audit.published(
+			jobID.GetValue(),
+			&instanceID,
+			runtime.GetState().String(),
+			runtime.GetState() == pbtask.TaskState_DELETED)
 		for _, l := range f.listeners {
-			l.TaskRuntimeChanged(jobID, instanceID, jobType, runtime, labels)
+			l.TaskRuntimeChanged(
+				jobID, instanceID, jobType, runtime, labels, sequence)
+			f.audit.delivered(published, l.Name())
 		}
 		// TODO add metric for listener execution latency
 	}
 }
+
+// nextSequence returns the sequence of the next notification. It is
+// called with the job or task notified locked, at the time of its mutation,
+// so that the sequences of its notifications follow the order of its
+// mutations even if they are delivered out of order.
+func (f *jobFactory) nextSequence() NotificationSequence {
+	f.sequenceLock.Lock()
+	defer f.sequenceLock.Unlock()
+
+	f.sequence++
+	return NotificationSequence{
+		Epoch:    f.epoch,
+		Sequence: f.sequence,
+	}
+}
@@ -20,6 +20,7 @@ import (
 	"testing"
 	"time"
 
+	pbjob "github.com/uber/peloton/.gen/peloton/api/v0/job"
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	pbtask "github.com/uber/peloton/.gen/peloton/api/v0/task"
 
@@ -31,7 +32,7 @@ import (
 
 // TestInitJobFactory tests initialization of the job factory
 func TestInitJobFactory(t *testing.T) {
-	f := InitJobFactory(nil, nil, nil, nil, nil, tally.NoopScope, nil)
+	f := InitJobFactory(nil, nil, nil, nil, nil, tally.NoopScope, nil, nil)
 	assert.NotNil(t, f)
 }
 
@@ -69,7 +70,7 @@ func TestStartStop(t *testing.T) {
 		mtx:  NewMetrics(tally.NoopScope),
 	}
 
-	f.Start()
+	f.Start(1)
 
 	assert.True(t, f.running)
 	jobID := &peloton.JobID{Value: "3c8a3c3e-71e3-49c5-9aed-2929823f595c"}
@@ -86,6 +87,162 @@ func TestStartStop(t *testing.T) {
 	assert.Nil(t, f.GetJob(jobID))
 }
 
+// TestRuntimeToPublish tests that the runtime of a job is handed to the
+// listeners once per revision.
+func TestRuntimeToPublish(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	f := &jobFactory{
+		jobs: map[string]*job{},
+		mtx:  NewMetrics(scope),
+	}
+	j := newJob(&peloton.JobID{Value: uuid.NewRandom().String()}, f)
+	j.runtime = &pbjob.RuntimeInfo{
+		State:    pbjob.JobState_RUNNING,
+		Revision: &peloton.ChangeLog{Version: 2},
+	}
+
+	published, sequence := j.runtimeToPublish()
+	assert.Equal(t, j.runtime, published)
+	assert.False(t, published == j.runtime)
+	assert.Equal(t, uint64(1), sequence.Sequence)
+
+	// the runtimes of a revision already published are skipped, without
+	// taking a sequence
+	for i := 0; i < 3; i++ {
+		published, _ = j.runtimeToPublish()
+		assert.Nil(t, published)
+	}
+	assert.Equal(t, int64(3),
+		scope.Snapshot().Counters()["job_runtime_publish_skipped+"].Value())
+
+	// changed runtimes are published
+	j.runtime = &pbjob.RuntimeInfo{
+		State:    pbjob.JobState_SUCCEEDED,
+		Revision: &peloton.ChangeLog{Version: 3},
+	}
+	published, sequence = j.runtimeToPublish()
+	assert.Equal(t, j.runtime, published)
+	assert.Equal(t, uint64(2), sequence.Sequence)
+
+	// the runtime of a job cleared and added again is published again
+	runtime := j.runtime
+	j = newJob(j.ID(), f)
+	j.runtime = runtime
+	published, _ = j.runtimeToPublish()
+	assert.Equal(t, runtime, published)
+}
+
+// TestRecoveryModeFlush tests that the job runtimes are batched in recovery
+// mode, and that the last runtime of each job is published when it stops.
+func TestRecoveryModeFlush(t *testing.T) {
+	scope := tally.NewTestScope("", nil)
+	listener := &FakeJobListener{}
+	f := &jobFactory{
+		jobs:      map[string]*job{},
+		mtx:       NewMetrics(scope),
+		listeners: []JobTaskListener{listener},
+	}
+	jobID := &peloton.JobID{Value: uuid.NewRandom().String()}
+
+	// no-op when not in recovery
+	f.StopRecoveryMode()
+
+	f.StartRecoveryMode()
+	f.notifyJobRuntimeChanged(jobID, pbjob.JobType_SERVICE,
+		&pbjob.RuntimeInfo{State: pbjob.JobState_PENDING}, f.nextSequence())
+	f.notifyJobRuntimeChanged(jobID, pbjob.JobType_SERVICE,
+		&pbjob.RuntimeInfo{State: pbjob.JobState_INITIALIZED},
+		f.nextSequence())
+	final := &pbjob.RuntimeInfo{State: pbjob.JobState_RUNNING}
+	f.notifyJobRuntimeChanged(jobID, pbjob.JobType_SERVICE, final,
+		f.nextSequence())
+	assert.Nil(t, listener.jobRuntime)
+
+	f.StopRecoveryMode()
+	assert.Equal(t, jobID, listener.jobID)
+	assert.Equal(t, pbjob.JobType_SERVICE, listener.jobType)
+	assert.Equal(t, final, listener.jobRuntime)
+	assert.Equal(t, int64(2),
+		scope.Snapshot().Counters()["job_runtime_publish_batched+"].Value())
+
+	// the runtime of the latest revision is kept with its sequence when
+	// the runtimes of a job are notified out of order
+	listener.Reset()
+	latest := &pbjob.RuntimeInfo{
+		State:    pbjob.JobState_KILLING,
+		Revision: &peloton.ChangeLog{Version: 5},
+	}
+	older := f.nextSequence()
+	latestSequence := f.nextSequence()
+	f.StartRecoveryMode()
+	f.notifyJobRuntimeChanged(jobID, pbjob.JobType_SERVICE, latest,
+		latestSequence)
+	f.notifyJobRuntimeChanged(jobID, pbjob.JobType_SERVICE,
+		&pbjob.RuntimeInfo{
+			State:    pbjob.JobState_RUNNING,
+			Revision: &peloton.ChangeLog{Version: 4},
+		}, older)
+	f.StopRecoveryMode()
+	assert.Equal(t, latest, listener.jobRuntime)
+	assert.Equal(t, latestSequence,
+		listener.sequences[len(listener.sequences)-1])
+}
+
+// TestNotificationSequence tests that the notifications are sequenced
+// monotonically within the epoch the factory is started with, across jobs
+// and across job and task notifications, and that the sequence starts over
+// when the factory is restarted with a new epoch, as on a failover.
+func TestNotificationSequence(t *testing.T) {
+	jobListener := &FakeJobListener{}
+	taskListener := &FakeTaskListener{}
+	f := &jobFactory{
+		jobs:      map[string]*job{},
+		mtx:       NewMetrics(tally.NoopScope),
+		listeners: []JobTaskListener{jobListener, taskListener},
+	}
+	jobID := &peloton.JobID{Value: uuid.NewRandom().String()}
+	otherJobID := &peloton.JobID{Value: uuid.NewRandom().String()}
+
+	f.Start(7)
+	f.notifyJobRuntimeChanged(jobID, pbjob.JobType_SERVICE,
+		&pbjob.RuntimeInfo{State: pbjob.JobState_PENDING}, f.nextSequence())
+	for i := uint32(0); i < 2; i++ {
+		f.notifyTaskRuntimeChanged(jobID, i, pbjob.JobType_SERVICE,
+			&pbtask.RuntimeInfo{State: pbtask.TaskState_RUNNING}, nil,
+			f.nextSequence())
+	}
+	f.notifyTaskRuntimeChanged(otherJobID, 0, pbjob.JobType_SERVICE,
+		&pbtask.RuntimeInfo{State: pbtask.TaskState_RUNNING}, nil,
+		f.nextSequence())
+
+	// the sequence does not start over when the job is cleared
+	f.AddJob(jobID)
+	f.ClearJob(jobID)
+	f.notifyTaskRuntimeChanged(jobID, 0, pbjob.JobType_SERVICE,
+		&pbtask.RuntimeInfo{State: pbtask.TaskState_DELETED}, nil,
+		f.nextSequence())
+
+	assert.Equal(t, []NotificationSequence{
+		{Epoch: 7, Sequence: 1},
+	}, jobListener.sequences)
+	assert.Equal(t, []NotificationSequence{
+		{Epoch: 7, Sequence: 2},
+		{Epoch: 7, Sequence: 3},
+		{Epoch: 7, Sequence: 4},
+		{Epoch: 7, Sequence: 5},
+	}, taskListener.sequences)
+
+	// failover
+	f.Stop()
+	f.Start(8)
+	defer f.Stop()
+	f.notifyJobRuntimeChanged(jobID, pbjob.JobType_SERVICE,
+		&pbjob.RuntimeInfo{State: pbjob.JobState_RUNNING}, f.nextSequence())
+	assert.Equal(t,
+		NotificationSequence{Epoch: 8, Sequence: 1},
+		jobListener.sequences[len(jobListener.sequences)-1])
+}
+
 // TestPublishMetrics tests publishing metrics from the job factory.
 func TestPublishMetrics(t *testing.T) {
 	f := &jobFactory{
@@ -35,6 +35,9 @@ import (
 // - do processing that can take a long time, such as blocking on
 //   locks or making remote calls. Such activities must be done
 //   in separate goroutines that are managed by the listener.
+// The cached state of the other tasks of a job can be looked up with
+// JobFactory.GetJobSnapshot, which is at least as new as the change being
+// notified.
 type JobTaskListener interface {
 	// Name returns a user-friendly name for the listener
 	Name() string
@@ -44,7 +47,8 @@ type JobTaskListener interface {
 	JobRuntimeChanged(
 		jobID *peloton.JobID,
 		jobType pbjob.JobType,
-		runtime *pbjob.RuntimeInfo)
+		runtime *pbjob.RuntimeInfo,
+		sequence NotificationSequence)
 
 	// TaskRuntimeChanged is invoked when the runtime for a task is updated
 	// in cache and persistent store.
@@ -53,5 +57,28 @@ type JobTaskListener interface {
 		instanceID uint32,
 		jobType pbjob.JobType,
 		runtime *pbtask.RuntimeInfo,
-		labels []*peloton.Label)
+		labels []*peloton.Label,
+		sequence NotificationSequence)
+}
+
+// NotificationSequence orders the notifications of a job, so that the
+// consumers of the notifications can apply them idempotently, keyed on the
+// epoch and the sequence. The epoch is the version of the leader znode of
+// the job manager when the one publishing the notification gained
+// leadership, which is written by each new leader so that it increases
+// across failovers. The sequence is shared by all the jobs and increases
+// with each notification within an epoch, starting at 1, so that the
+// sequences of a job increase with its mutations but are not contiguous.
+// It starts over with a new epoch.
+type NotificationSequence struct {
+	Epoch    uint64
+	Sequence uint64
+}
+
+// CacheRecoveredListener is implemented by the JobTaskListeners which need
+// to know when the cache is recovered from the persistent store, as the
+// tasks recovered are not notified to the listeners.
+type CacheRecoveredListener interface {
+	// CacheRecovered is invoked when the recovery of the cache finishes.
+	CacheRecovered(factory JobFactory)
 }
@@ -24,6 +24,7 @@ type FakeJobListener struct {
 	jobID      *peloton.JobID
 	jobType    pbjob.JobType
 	jobRuntime *pbjob.RuntimeInfo
+	sequences  []NotificationSequence
 }
 
 func (l *FakeJobListener) Name() string {
@@ -33,10 +34,12 @@ func (l *FakeJobListener) Name() string {
 func (l *FakeJobListener) JobRuntimeChanged(
 	jobID *peloton.JobID,
 	jobType pbjob.JobType,
-	runtime *pbjob.RuntimeInfo) {
+	runtime *pbjob.RuntimeInfo,
+	sequence NotificationSequence) {
 	l.jobID = jobID
 	l.jobType = jobType
 	l.jobRuntime = runtime
+	l.sequences = append(l.sequences, sequence)
 }
 
 func (l *FakeJobListener) TaskRuntimeChanged(
@@ -44,7 +47,8 @@ func (l *FakeJobListener) TaskRuntimeChanged(
 	instanceID uint32,
 	jobType pbjob.JobType,
 	runtime *pbtask.RuntimeInfo,
-	labels []*peloton.Label) {
+	labels []*peloton.Label,
+	sequence NotificationSequence) {
 }
 
 func (l *FakeJobListener) Reset() {
@@ -58,6 +62,7 @@ type FakeTaskListener struct {
 	instanceID  uint32
 	taskRuntime *pbtask.RuntimeInfo
 	labels      []*peloton.Label
+	sequences   []NotificationSequence
 }
 
 func (l *FakeTaskListener) Name() string {
@@ -67,7 +72,8 @@ func (l *FakeTaskListener) Name() string {
 func (l *FakeTaskListener) JobRuntimeChanged(
 	jobID *peloton.JobID,
 	jobType pbjob.JobType,
-	runtime *pbjob.RuntimeInfo) {
+	runtime *pbjob.RuntimeInfo,
+	sequence NotificationSequence) {
 }
 
 func (l *FakeTaskListener) TaskRuntimeChanged(
@@ -75,10 +81,12 @@ func (l *FakeTaskListener) TaskRuntimeChanged(
 	instanceID uint32,
 	jobType pbjob.JobType,
 	runtime *pbtask.RuntimeInfo,
-	labels []*peloton.Label) {
+	labels []*peloton.Label,
+	sequence NotificationSequence) {
 	l.jobID = jobID
 	l.instanceID = instanceID
 	l.jobType = jobType
 	l.taskRuntime = runtime
 	l.labels = labels
+	l.sequences = append(l.sequences, sequence)
 }
@@ -21,6 +21,13 @@ import (
 // Metrics is the struct containing all the counters that track internal state of the cache.
 type Metrics struct {
 	scope tally.Scope
+
+	// JobRuntimePublishSkipped counts the job runtimes not published to
+	// the listeners as their revision was already published
+	JobRuntimePublishSkipped tally.Counter
+	// JobRuntimePublishBatched counts the job runtimes not published to
+	// the listeners as replaced by a later one during recovery
+	JobRuntimePublishBatched tally.Counter
 }
 
 // NewMetrics returns a new Metrics struct, with all metrics
@@ -28,5 +35,8 @@ type Metrics struct {
 func NewMetrics(scope tally.Scope) *Metrics {
 	return &Metrics{
 		scope: scope,
+
+		JobRuntimePublishSkipped: scope.Counter("job_runtime_publish_skipped"),
+		JobRuntimePublishBatched: scope.Counter("job_runtime_publish_batched"),
 	}
 }
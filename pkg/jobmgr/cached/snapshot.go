@@ -0,0 +1,94 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cached
+
+import (
+	pbjob "github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	pbtask "github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/golang/protobuf/proto"
+)
+
+// JobSnapshot is a copy of the cached runtimes of a job and of its tasks,
+// and of the labels of its tasks.
+// It is not changed by the later updates of the cache, and changing it does
+// not change the cache.
+type JobSnapshot struct {
+	// JobID is the identifier of the job
+	JobID *peloton.JobID
+	// JobType is the type of the job
+	JobType pbjob.JobType
+	// Runtime is the runtime of the job, nil if not in cache
+	Runtime *pbjob.RuntimeInfo
+	// TaskRuntimes are the runtimes of the tasks in cache, by instance id
+	TaskRuntimes map[uint32]*pbtask.RuntimeInfo
+	// TaskLabels are the labels of the tasks in cache, by instance id
+	TaskLabels map[uint32][]*peloton.Label
+}
+
+// GetJobSnapshot returns a snapshot of the cached runtimes of a job and of
+// its tasks, or nil if the job is not in cache. The runtimes are only copied
+// when a snapshot is requested. As the listeners are notified after the
+// cache is updated, the snapshot requested by a listener is at least as
+// new as the change being notified for the same job.
+func (f *jobFactory) GetJobSnapshot(id *peloton.JobID) *JobSnapshot {
+	f.RLock()
+	j, ok := f.jobs[id.GetValue()]
+	f.RUnlock()
+	if !ok {
+		return nil
+	}
+	return j.snapshot()
+}
+
+// snapshot returns a snapshot of the cached runtimes of the job and of
+// its tasks
+func (j *job) snapshot() *JobSnapshot {
+	j.RLock()
+	defer j.RUnlock()
+
+	s := &JobSnapshot{
+		JobID:        j.id,
+		JobType:      pbjob.JobType_SERVICE,
+		TaskRuntimes: make(map[uint32]*pbtask.RuntimeInfo),
+		TaskLabels:   make(map[uint32][]*peloton.Label),
+	}
+	if j.config != nil {
+		s.JobType = j.config.jobType
+	}
+	if j.runtime != nil {
+		s.Runtime = proto.Clone(j.runtime).(*pbjob.RuntimeInfo)
+	}
+	for id, t := range j.tasks {
+		if runtime, labels := t.runtimeCopy(); runtime != nil {
+			s.TaskRuntimes[id] = runtime
+			s.TaskLabels[id] = labels
+		}
+	}
+	return s
+}
+
+// runtimeCopy returns a copy of the cached runtime and labels of the task,
+// or a nil runtime if not in cache
+func (t *task) runtimeCopy() (*pbtask.RuntimeInfo, []*peloton.Label) {
+	t.RLock()
+	defer t.RUnlock()
+
+	if t.runtime == nil {
+		return nil, nil
+	}
+	return proto.Clone(t.runtime).(*pbtask.RuntimeInfo), t.copyLabelsInCache()
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cached
+
+import (
+	"context"
+
+	pbjob "github.com/uber/peloton/.gen/peloton/api/v0/job"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	pbtask "github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/golang/mock/gomock"
+	"github.com/pborman/uuid"
+)
+
+// snapshotJobListener takes a snapshot of the job notified
+type snapshotJobListener struct {
+	FakeJobListener
+
+	factory  JobFactory
+	snapshot *JobSnapshot
+}
+
+func (l *snapshotJobListener) JobRuntimeChanged(
+	jobID *peloton.JobID,
+	jobType pbjob.JobType,
+	runtime *pbjob.RuntimeInfo,
+	sequence NotificationSequence) {
+	l.FakeJobListener.JobRuntimeChanged(jobID, jobType, runtime, sequence)
+	l.snapshot = l.factory.GetJobSnapshot(jobID)
+}
+
+// TestJobSnapshotImmutable tests that the snapshot of a job is a copy of
+// its cached runtimes
+func (suite *JobTestSuite) TestJobSnapshotImmutable() {
+	suite.job.runtime.Revision = &peloton.ChangeLog{Version: 1}
+	suite.job.addTaskToJobMap(0).runtime = &pbtask.RuntimeInfo{
+		State: pbtask.TaskState_RUNNING,
+	}
+	// not in cache
+	suite.job.addTaskToJobMap(1)
+
+	snapshot := suite.job.jobFactory.GetJobSnapshot(suite.jobID)
+	suite.Equal(suite.jobID, snapshot.JobID)
+	suite.Equal(suite.job.GetJobType(), snapshot.JobType)
+	suite.Equal(suite.job.runtime, snapshot.Runtime)
+	suite.Len(snapshot.TaskRuntimes, 1)
+	suite.Equal(pbtask.TaskState_RUNNING, snapshot.TaskRuntimes[0].GetState())
+
+	// changing the snapshot does not change the cache
+	snapshot.Runtime.State = pbjob.JobState_KILLED
+	snapshot.Runtime.Revision.Version = 10
+	snapshot.TaskRuntimes[0].State = pbtask.TaskState_KILLED
+	snapshot.TaskRuntimes[1] = &pbtask.RuntimeInfo{}
+	suite.Equal(pbjob.JobState_INITIALIZED, suite.job.runtime.GetState())
+	suite.Equal(uint64(1), suite.job.runtime.GetRevision().GetVersion())
+	suite.Equal(pbtask.TaskState_RUNNING, suite.job.tasks[0].runtime.GetState())
+	suite.Nil(suite.job.tasks[1].runtime)
+
+	// changing the cache does not change the snapshot
+	suite.job.tasks[0].runtime.State = pbtask.TaskState_SUCCEEDED
+	suite.Equal(pbtask.TaskState_KILLED, snapshot.TaskRuntimes[0].GetState())
+
+	// the jobs not in cache have no snapshot
+	suite.Nil(suite.job.jobFactory.GetJobSnapshot(
+		&peloton.JobID{Value: uuid.NewRandom().String()}))
+}
+
+// TestJobSnapshotFreshness tests that the snapshot taken by a listener is
+// at least as new as the change notified
+func (suite *JobTestSuite) TestJobSnapshotFreshness() {
+	listener := &snapshotJobListener{factory: suite.job.jobFactory}
+	suite.job.jobFactory.listeners = append(
+		suite.job.jobFactory.listeners, listener)
+	suite.job.runtime.Revision = &peloton.ChangeLog{Version: 1}
+
+	suite.jobStore.EXPECT().
+		UpdateJobRuntime(gomock.Any(), suite.jobID, gomock.Any()).
+		Return(nil)
+	suite.jobIndexOps.EXPECT().
+		Update(gomock.Any(), suite.jobID, gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	jobRuntime, err := suite.job.GetRuntime(context.Background())
+	suite.NoError(err)
+	jobRuntime.State = pbjob.JobState_RUNNING
+	_, err = suite.job.CompareAndSetRuntime(context.Background(), jobRuntime)
+	suite.NoError(err)
+
+	suite.NotNil(listener.snapshot)
+	suite.Equal(pbjob.JobState_RUNNING, listener.snapshot.Runtime.GetState())
+	suite.True(listener.snapshot.Runtime.GetRevision().GetVersion() >=
+		listener.jobRuntime.GetRevision().GetVersion())
+}
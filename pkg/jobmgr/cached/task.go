@@ -316,11 +316,12 @@ func (t *task) cleanTaskCache() {
 func (t *task) CreateTask(ctx context.Context, runtime *pbtask.RuntimeInfo, owner string) error {
 	var runtimeCopy *pbtask.RuntimeInfo
 	var labelsCopy []*peloton.Label
+	var sequence NotificationSequence
 
 	// notify listeners after dropping the lock
 	defer func() {
 		t.jobFactory.notifyTaskRuntimeChanged(t.JobID(), t.ID(), t.jobType,
-			runtimeCopy, labelsCopy)
+			runtimeCopy, labelsCopy, sequence)
 	}()
 	t.Lock()
 	defer t.Unlock()
@@ -350,6 +351,7 @@ func (t *task) CreateTask(ctx context.Context, runtime *pbtask.RuntimeInfo, owne
 	t.lastRuntimeUpdateTime = time.Now()
 	runtimeCopy = proto.Clone(t.runtime).(*pbtask.RuntimeInfo)
 	labelsCopy = t.copyLabelsInCache()
+	sequence = t.jobFactory.nextSequence()
 	return nil
 }
 
@@ -368,11 +370,12 @@ func (t *task) PatchTask(ctx context.Context, diff jobmgrcommon.RuntimeDiff) err
 
 	var runtimeCopy *pbtask.RuntimeInfo
 	var labelsCopy []*peloton.Label
+	var sequence NotificationSequence
 
 	// notify listeners after dropping the lock
 	defer func() {
 		t.jobFactory.notifyTaskRuntimeChanged(t.JobID(), t.ID(), t.jobType,
-			runtimeCopy, labelsCopy)
+			runtimeCopy, labelsCopy, sequence)
 	}()
 	t.Lock()
 	defer t.Unlock()
@@ -425,6 +428,7 @@ func (t *task) PatchTask(ctx context.Context, diff jobmgrcommon.RuntimeDiff) err
 	t.lastRuntimeUpdateTime = time.Now()
 	runtimeCopy = proto.Clone(t.runtime).(*pbtask.RuntimeInfo)
 	labelsCopy = t.copyLabelsInCache()
+	sequence = t.jobFactory.nextSequence()
 	return nil
 }
 
@@ -440,11 +444,12 @@ func (t *task) CompareAndSetTask(
 
 	var runtimeCopy *pbtask.RuntimeInfo
 	var labelsCopy []*peloton.Label
+	var sequence NotificationSequence
 
 	// notify listeners after dropping the lock
 	defer func() {
 		t.jobFactory.notifyTaskRuntimeChanged(t.JobID(), t.ID(), jobType,
-			runtimeCopy, labelsCopy)
+			runtimeCopy, labelsCopy, sequence)
 	}()
 
 	t.Lock()
@@ -499,6 +504,7 @@ func (t *task) CompareAndSetTask(
 	t.lastRuntimeUpdateTime = time.Now()
 	runtimeCopy = proto.Clone(t.runtime).(*pbtask.RuntimeInfo)
 	labelsCopy = t.copyLabelsInCache()
+	sequence = t.jobFactory.nextSequence()
 	return runtimeCopy, nil
 }
 
@@ -526,6 +532,7 @@ func (t *task) DeleteTask() {
 		t.jobType,
 		runtimeCopy,
 		labelsCopy,
+		t.jobFactory.nextSequence(),
 	)
 }
 
@@ -131,6 +131,11 @@ func (suite *TaskTestSuite) checkListeners(tt *task, jobType pbjob.JobType) {
 		suite.Equal(suite.instanceID, l.instanceID, msg)
 		suite.Equal(jobType, l.jobType, msg)
 		suite.Equal(tt.runtime, l.taskRuntime, msg)
+		// the sequence taken by the mutation is notified
+		suite.Equal(
+			NotificationSequence{Sequence: tt.jobFactory.sequence},
+			l.sequences[len(l.sequences)-1],
+			msg)
 	}
 }
 
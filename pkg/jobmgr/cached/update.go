@@ -28,6 +28,7 @@ import (
 	"github.com/uber/peloton/pkg/common/util"
 	jobmgrcommon "github.com/uber/peloton/pkg/jobmgr/common"
 	taskutil "github.com/uber/peloton/pkg/jobmgr/util/task"
+	updateutil "github.com/uber/peloton/pkg/jobmgr/util/update"
 	"github.com/uber/peloton/pkg/storage"
 
 	"github.com/gogo/protobuf/proto"
@@ -610,6 +611,13 @@ func (u *update) Rollback(
 		return err
 	}
 
+	// the instances outside of the ranges the update is restricted to
+	// were not updated, they are not rolled back
+	ranges := u.updateConfig.GetInstanceRanges()
+	instancesAdded = updateutil.FilterInstancesInRanges(instancesAdded, ranges)
+	instancesUpdated = updateutil.FilterInstancesInRanges(instancesUpdated, ranges)
+	instancesRemoved = updateutil.FilterInstancesInRanges(instancesRemoved, ranges)
+
 	updateModel := &models.UpdateModel{
 		UpdateID:             u.id,
 		PrevState:            u.state,
@@ -1125,6 +1125,53 @@ func (suite *UpdateTestSuite) TestUpdateRollbackSuccess() {
 	suite.NoError(suite.update.Rollback(context.Background(), currentConfig, targetConfig))
 }
 
+// TestUpdateRollbackInstanceRanges tests that only the instances in the
+// ranges an update is restricted to are rolled back
+func (suite *UpdateTestSuite) TestUpdateRollbackInstanceRanges() {
+	suite.update.state = pbupdate.State_ROLLING_FORWARD
+	suite.update.jobVersion = uint64(1)
+	suite.update.jobID = suite.jobID
+	suite.update.updateConfig = &pbupdate.UpdateConfig{
+		InstanceRanges: []*pbtask.InstanceRange{{From: 1, To: 3}},
+	}
+
+	currentConfig := &pbjob.JobConfig{}
+	targetConfig := &pbjob.JobConfig{InstanceCount: 4}
+
+	suite.taskStore.EXPECT().
+		GetTaskRuntimesForJobByRange(gomock.Any(), suite.jobID, nil).
+		Return(nil, nil)
+
+	for _, instanceID := range []uint32{1, 2} {
+		suite.updateStore.EXPECT().
+			AddWorkflowEvent(
+				gomock.Any(),
+				gomock.Any(),
+				instanceID,
+				gomock.Any(),
+				pbupdate.State_ROLLING_BACKWARD).
+			Return(nil)
+	}
+
+	suite.updateStore.EXPECT().
+		AddJobUpdateEvent(
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any(),
+			pbupdate.State_ROLLING_BACKWARD).
+		Return(nil)
+
+	suite.updateStore.EXPECT().
+		ModifyUpdate(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, updateModel *models.UpdateModel) {
+			suite.Equal([]uint32{1, 2}, updateModel.GetInstancesAdded())
+			suite.Equal(uint32(2), updateModel.GetInstancesTotal())
+		}).
+		Return(nil)
+
+	suite.NoError(suite.update.Rollback(context.Background(), currentConfig, targetConfig))
+}
+
 // TestUpdateRollbackModifyUpdateFailure tests the failure case of
 // rolling back update due to fail to modify update in db
 func (suite *UpdateTestSuite) TestUpdateRollbackModifyUpdateFailure() {
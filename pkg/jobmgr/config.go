@@ -17,6 +17,7 @@ package jobmgr
 import (
 	"time"
 
+	"github.com/uber/peloton/pkg/jobmgr/cached"
 	"github.com/uber/peloton/pkg/jobmgr/goalstate"
 	"github.com/uber/peloton/pkg/jobmgr/jobsvc"
 	"github.com/uber/peloton/pkg/jobmgr/task/deadline"
@@ -63,4 +64,10 @@ type Config struct {
 	// check instances counts between MV and configuration,
 	// if the counts mismatch, we will re-calculate job state from cache
 	JobRuntimeCalculationViaCache bool `yaml:"job_runtime_calculation_via_cache"`
+
+	// Index of the cached tasks by label
+	LabelIndex cached.LabelIndexConfig `yaml:"label_index"`
+
+	// Audit of the notifications delivered to the cache listeners
+	NotificationAudit cached.NotificationAuditConfig `yaml:"notification_audit"`
 }
@@ -444,10 +444,14 @@ func (d *driver) Start() {
 		time.Sleep(_sleepRetryCheckRunningState)
 	}
 
+	// Batch the job runtime notifications during recovery, as every job
+	// recovered would be published, possibly several times
+	d.jobFactory.StartRecoveryMode()
 	if err := d.syncFromDB(context.Background()); err != nil {
 		log.WithError(err).
 			Fatal("failed to sync job manager with DB")
 	}
+	d.jobFactory.StopRecoveryMode()
 
 	d.Lock()
 	d.jobEngine.Start()
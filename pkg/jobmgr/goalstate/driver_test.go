@@ -636,9 +636,13 @@ func (suite *DriverTestSuite) TestEngineStartStop() {
 	suite.jobGoalStateEngine.EXPECT().Start()
 	suite.taskGoalStateEngine.EXPECT().Start()
 	suite.updateGoalStateEngine.EXPECT().Start()
-	suite.jobStore.EXPECT().
-		GetJobsByStates(gomock.Any(), gomock.Any()).
-		Return(jobIDList, nil)
+	gomock.InOrder(
+		suite.jobFactory.EXPECT().StartRecoveryMode(),
+		suite.jobStore.EXPECT().
+			GetJobsByStates(gomock.Any(), gomock.Any()).
+			Return(jobIDList, nil),
+		suite.jobFactory.EXPECT().StopRecoveryMode(),
+	)
 	suite.jobStore.EXPECT().
 		GetActiveJobs(gomock.Any()).
 		Return([]*peloton.JobID{}, nil)
@@ -180,7 +180,8 @@ func processFailedUpdate(
 ) error {
 	// rollback the update if RollbackOnFailure is set and
 	// the update itself is not a rollback
-	if cachedUpdate.GetUpdateConfig().RollbackOnFailure &&
+	updateConfig := cachedUpdate.GetUpdateConfig()
+	if updateConfig.GetRollbackOnFailure() &&
 		!isUpdateRollback(cachedUpdate) {
 		if err := cachedJob.RollbackWorkflow(ctx); err != nil {
 			log.WithFields(log.Fields{
@@ -206,6 +207,8 @@ func processFailedUpdate(
 			cachedUpdate,
 			cachedJob,
 			cachedConfig,
+			// the rollback is restricted to the ranges of the update
+			updateConfig.GetInstanceRanges(),
 		); err != nil {
 			log.WithFields(log.Fields{
 				"update_id": cachedUpdate.ID().GetValue(),
@@ -2688,6 +2688,157 @@ func (suite *UpdateRunTestSuite) TestRunningUpdateRolledBack() {
 	suite.NoError(err)
 }
 
+// TestRunningUpdateRolledBackInstanceRanges tests that the rollback of an
+// update restricted to instance ranges leaves the unchanged instances
+// outside of the ranges untouched
+func (suite *UpdateRunTestSuite) TestRunningUpdateRolledBackInstanceRanges() {
+	totalInstances := uint32(10)
+	totalInstancesToUpdate := []uint32{0, 1, 2, 3, 4, 5, 6}
+	newJobConfigVer := uint64(4)
+	failureCount := uint32(5)
+	failedInstances := uint32(3)
+
+	updateConfig := &pbupdate.UpdateConfig{
+		BatchSize:           0,
+		MaxFailureInstances: failedInstances,
+		RollbackOnFailure:   true,
+		InstanceRanges:      []*pbtask.InstanceRange{{From: 0, To: 8}},
+	}
+
+	runtimeFailed := &pbtask.RuntimeInfo{
+		State:                pbtask.TaskState_FAILED,
+		GoalState:            pbtask.TaskState_RUNNING,
+		FailureCount:         failureCount,
+		Healthy:              pbtask.HealthState_UNHEALTHY,
+		ConfigVersion:        newJobConfigVer,
+		DesiredConfigVersion: newJobConfigVer,
+	}
+
+	runtimeDone := &pbtask.RuntimeInfo{
+		State:                pbtask.TaskState_RUNNING,
+		GoalState:            pbtask.TaskState_RUNNING,
+		Healthy:              pbtask.HealthState_HEALTHY,
+		ConfigVersion:        newJobConfigVer,
+		DesiredConfigVersion: newJobConfigVer,
+	}
+
+	suite.jobFactory.EXPECT().
+		GetJob(suite.jobID).
+		Return(suite.cachedJob).
+		AnyTimes()
+
+	suite.cachedJob.EXPECT().
+		ID().
+		Return(suite.jobID).
+		AnyTimes()
+
+	suite.cachedJob.EXPECT().
+		AddWorkflow(suite.updateID).
+		Return(suite.cachedUpdate)
+
+	suite.cachedUpdate.EXPECT().
+		GetState().
+		Return(&cached.UpdateStateVector{
+			State: pbupdate.State_ROLLING_FORWARD,
+		})
+
+	suite.cachedUpdate.EXPECT().
+		GetGoalState().
+		Return(&cached.UpdateStateVector{
+			Instances:  totalInstancesToUpdate,
+			JobVersion: uint64(4),
+		}).
+		AnyTimes()
+
+	suite.cachedUpdate.EXPECT().
+		GetInstancesFailed().
+		Return([]uint32{})
+
+	suite.cachedUpdate.EXPECT().
+		GetInstancesDone().
+		Return([]uint32{})
+
+	suite.cachedUpdate.EXPECT().
+		GetInstancesCurrent().
+		Return(totalInstancesToUpdate)
+
+	suite.cachedUpdate.EXPECT().
+		GetInstancesRemoved().
+		Return([]uint32{})
+
+	suite.cachedUpdate.EXPECT().
+		GetUpdateConfig().
+		Return(updateConfig).
+		Times(4)
+
+	for i, instID := range totalInstancesToUpdate {
+		if uint32(i) < failedInstances {
+			suite.taskStore.EXPECT().
+				GetTaskRuntime(gomock.Any(), suite.jobID, instID).
+				Return(runtimeFailed, nil)
+		} else {
+			suite.taskStore.EXPECT().
+				GetTaskRuntime(gomock.Any(), suite.jobID, instID).
+				Return(runtimeDone, nil)
+		}
+	}
+
+	suite.cachedUpdate.EXPECT().
+		IsInstanceComplete(newJobConfigVer, runtimeFailed).
+		Return(false).
+		AnyTimes()
+	suite.cachedUpdate.EXPECT().
+		IsInstanceFailed(runtimeFailed, updateConfig.GetMaxInstanceAttempts()).
+		Return(true).
+		AnyTimes()
+
+	suite.cachedUpdate.EXPECT().
+		IsInstanceComplete(newJobConfigVer, runtimeDone).
+		Return(true).
+		AnyTimes()
+
+	suite.cachedUpdate.EXPECT().
+		GetWorkflowType().
+		Return(models.WorkflowType_UPDATE)
+
+	suite.cachedUpdate.EXPECT().
+		GetState().
+		Return(&cached.UpdateStateVector{
+			State: pbupdate.State_ROLLING_FORWARD,
+		})
+
+	suite.cachedJob.EXPECT().
+		RollbackWorkflow(gomock.Any()).
+		Return(nil)
+
+	suite.cachedJob.EXPECT().
+		GetConfig(gomock.Any()).
+		Return(&pbjob.JobConfig{
+			InstanceCount: totalInstances,
+		}, nil)
+
+	suite.cachedJob.EXPECT().
+		PatchTasks(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, runtimeDiffs map[uint32]jobmgrcommon.RuntimeDiff) {
+			suite.Len(runtimeDiffs, 1)
+			suite.NotEmpty(runtimeDiffs[7])
+		}).
+		Return(nil)
+
+	suite.cachedUpdate.EXPECT().
+		ID().
+		Return(suite.updateID).
+		Times(2)
+
+	suite.updateGoalStateEngine.
+		EXPECT().
+		Enqueue(gomock.Any(), gomock.Any()).
+		Return()
+
+	err := UpdateRun(context.Background(), suite.updateEnt)
+	suite.NoError(err)
+}
+
 // TestRunningUpdateRolledBack tests the case that update fails due to
 // too many instances failed and rollback is triggered but fails
 func (suite *UpdateRunTestSuite) TestRunningUpdateRolledBackFail() {
@@ -19,12 +19,14 @@ import (
 	"time"
 
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	pbtask "github.com/uber/peloton/.gen/peloton/api/v0/task"
 	"github.com/uber/peloton/.gen/peloton/api/v0/update"
 	"github.com/uber/peloton/.gen/peloton/private/models"
 
 	"github.com/uber/peloton/pkg/common/goalstate"
 	"github.com/uber/peloton/pkg/jobmgr/cached"
 	jobmgrcommon "github.com/uber/peloton/pkg/jobmgr/common"
+	updateutil "github.com/uber/peloton/pkg/jobmgr/util/update"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -64,18 +66,24 @@ func fetchWorkflowAndJobFromCache(
 // handleUnchangedInstancesInUpdate updates the runtime state of the
 // instances left unchanged with the given update; essentially, the
 // configuration and desired configuration version of all unchanged
-// tasks is updated to the newest version.
+// tasks is updated to the newest version. The instances outside of the
+// given ranges, if any, keep their current configuration version.
 func handleUnchangedInstancesInUpdate(
 	ctx context.Context,
 	cachedUpdate cached.Update,
 	cachedJob cached.Job,
-	jobConfig jobmgrcommon.JobConfig) error {
+	jobConfig jobmgrcommon.JobConfig,
+	ranges []*pbtask.InstanceRange) error {
 
 	runtimes := make(map[uint32]jobmgrcommon.RuntimeDiff)
 	instanceCount := jobConfig.GetInstanceCount()
 	instancesTotal := cachedUpdate.GetGoalState().Instances
 
 	for i := uint32(0); i < instanceCount; i++ {
+		if !updateutil.InstanceInRanges(i, ranges) {
+			continue
+		}
+
 		// first find the instances which have not been updated
 		found := false
 		for _, j := range instancesTotal {
@@ -150,6 +158,7 @@ func UpdateStart(ctx context.Context, entity goalstate.Entity) error {
 		return err
 	}
 
+	var ranges []*pbtask.InstanceRange
 	if cachedWorkflow.GetWorkflowType() == models.WorkflowType_UPDATE {
 		// Populate instancesAdded, instancesUpdated and instancesRemoved
 		// by the update. This is not done in the handler because the previous
@@ -180,6 +189,13 @@ func UpdateStart(ctx context.Context, entity goalstate.Entity) error {
 			return err
 		}
 
+		// only update the instances in the ranges the update is
+		// restricted to, if any
+		ranges = cachedWorkflow.GetUpdateConfig().GetInstanceRanges()
+		instancesAdded = updateutil.FilterInstancesInRanges(instancesAdded, ranges)
+		instancesUpdated = updateutil.FilterInstancesInRanges(instancesUpdated, ranges)
+		instancesRemoved = updateutil.FilterInstancesInRanges(instancesRemoved, ranges)
+
 		if err := cachedWorkflow.Modify(
 			ctx,
 			instancesAdded,
@@ -194,7 +210,7 @@ func UpdateStart(ctx context.Context, entity goalstate.Entity) error {
 	// update the configuration and desired configuration version of
 	// all instances which do not need to be updated
 	if err = handleUnchangedInstancesInUpdate(
-		ctx, cachedWorkflow, cachedJob, jobConfig); err != nil {
+		ctx, cachedWorkflow, cachedJob, jobConfig, ranges); err != nil {
 		goalStateDriver.mtx.updateMetrics.UpdateStartFail.Inc(1)
 		return err
 	}
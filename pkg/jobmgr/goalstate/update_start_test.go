@@ -597,6 +597,10 @@ func (suite *UpdateStartTestSuite) TestUpdateWorkflowUpdate() {
 		Return(suite.prevJobConfig.DefaultConfig, nil, nil).
 		Times(int(suite.prevJobConfig.InstanceCount))
 
+	suite.cachedUpdate.EXPECT().
+		GetUpdateConfig().
+		Return(&pbupdate.UpdateConfig{})
+
 	suite.cachedUpdate.EXPECT().
 		Modify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 		Do(func(
@@ -838,6 +842,10 @@ func (suite *UpdateStartTestSuite) TestUpdateWorkflowUpdateModifyError() {
 		Return(suite.prevJobConfig.DefaultConfig, nil, nil).
 		Times(int(suite.prevJobConfig.InstanceCount))
 
+	suite.cachedUpdate.EXPECT().
+		GetUpdateConfig().
+		Return(&pbupdate.UpdateConfig{})
+
 	suite.cachedUpdate.EXPECT().
 		Modify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
 		Return(fmt.Errorf("fake db error"))
@@ -845,3 +853,140 @@ func (suite *UpdateStartTestSuite) TestUpdateWorkflowUpdateModifyError() {
 	err := UpdateStart(context.Background(), suite.updateEnt)
 	suite.Error(err)
 }
+
+// TestUpdateWorkflowUpdateInstanceRanges tests starting a workflow update
+// restricted to instance ranges, the instances outside of the ranges are
+// neither updated nor moved to the new configuration version
+func (suite *UpdateStartTestSuite) TestUpdateWorkflowUpdateInstanceRanges() {
+	suite.prevJobConfig.InstanceCount = suite.jobConfig.InstanceCount
+	ranges := []*pbtask.InstanceRange{{From: 0, To: 2}, {From: 5, To: 6}}
+	instancesTotal := []uint32{0, 5}
+
+	taskRuntimes := make(map[uint32]*pbtask.RuntimeInfo)
+	for i := uint32(0); i < suite.prevJobConfig.InstanceCount; i++ {
+		runtime := &pbtask.RuntimeInfo{
+			State:                pbtask.TaskState_RUNNING,
+			ConfigVersion:        suite.prevJobConfig.ChangeLog.Version,
+			DesiredConfigVersion: suite.prevJobConfig.ChangeLog.Version,
+		}
+		taskRuntimes[i] = runtime
+	}
+
+	suite.jobFactory.EXPECT().
+		GetJob(suite.jobID).
+		Return(suite.cachedJob)
+
+	suite.cachedJob.EXPECT().
+		AddWorkflow(suite.updateID).
+		Return(suite.cachedUpdate)
+
+	suite.cachedUpdate.EXPECT().
+		GetState().
+		Return(&cached.UpdateStateVector{
+			State: pbupdate.State_INITIALIZED,
+		})
+
+	suite.cachedUpdate.EXPECT().
+		JobID().
+		Return(suite.jobID).
+		AnyTimes()
+
+	suite.cachedUpdate.EXPECT().
+		GetGoalState().
+		Return(&cached.UpdateStateVector{
+			JobVersion: suite.jobConfig.ChangeLog.Version,
+			Instances:  instancesTotal,
+		}).AnyTimes()
+
+	suite.jobStore.EXPECT().
+		GetJobConfigWithVersion(
+			gomock.Any(), suite.jobID.GetValue(), suite.jobConfig.ChangeLog.Version).
+		Return(suite.jobConfig, &models.ConfigAddOn{}, nil)
+
+	suite.cachedJob.EXPECT().
+		CreateTaskConfigs(gomock.Any(), suite.jobID, gomock.Any(), gomock.Any()).
+		Return(nil)
+
+	suite.cachedUpdate.EXPECT().
+		GetWorkflowType().
+		Return(models.WorkflowType_UPDATE)
+
+	suite.cachedUpdate.EXPECT().
+		GetState().
+		Return(&cached.UpdateStateVector{
+			JobVersion: suite.prevJobConfig.ChangeLog.Version,
+		})
+
+	suite.jobStore.EXPECT().
+		GetJobConfigWithVersion(
+			gomock.Any(), suite.jobID.GetValue(), suite.prevJobConfig.ChangeLog.Version).
+		Return(suite.prevJobConfig, &models.ConfigAddOn{}, nil)
+
+	suite.cachedJob.EXPECT().
+		ID().
+		Return(suite.jobID).
+		AnyTimes()
+
+	suite.taskStore.EXPECT().
+		GetTaskRuntimesForJobByRange(gomock.Any(), suite.jobID, nil).
+		Return(taskRuntimes, nil)
+
+	// instance 1 is not changed by the update
+	suite.taskStore.EXPECT().
+		GetTaskConfig(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			_ *peloton.JobID,
+			instanceID uint32,
+			_ uint64,
+		) (*pbtask.TaskConfig, *models.ConfigAddOn, error) {
+			if instanceID == 1 {
+				return suite.jobConfig.DefaultConfig, nil, nil
+			}
+			return suite.prevJobConfig.DefaultConfig, nil, nil
+		}).
+		Times(int(suite.prevJobConfig.InstanceCount))
+
+	suite.cachedUpdate.EXPECT().
+		GetUpdateConfig().
+		Return(&pbupdate.UpdateConfig{InstanceRanges: ranges})
+
+	suite.cachedUpdate.EXPECT().
+		Modify(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).
+		Do(func(
+			_ context.Context,
+			instancesAdded []uint32,
+			instancesUpdated []uint32,
+			instancesRemoved []uint32,
+		) {
+			suite.Empty(instancesAdded)
+			suite.Equal(instancesTotal, instancesUpdated)
+			suite.Empty(instancesRemoved)
+		}).
+		Return(nil)
+
+	suite.cachedJob.EXPECT().
+		PatchTasks(gomock.Any(), gomock.Any()).
+		Do(func(_ context.Context, runtimes map[uint32]jobmgrcommon.RuntimeDiff) {
+			suite.Len(runtimes, 1)
+			suite.Equal(suite.jobConfig.ChangeLog.Version,
+				runtimes[1][jobmgrcommon.ConfigVersionField])
+		}).
+		Return(nil)
+
+	suite.cachedUpdate.EXPECT().
+		WriteProgress(
+			gomock.Any(),
+			pbupdate.State_ROLLING_FORWARD,
+			[]uint32{},
+			[]uint32{},
+			gomock.Any(),
+		).Return(nil)
+
+	suite.updateGoalStateEngine.EXPECT().
+		Enqueue(gomock.Any(), gomock.Any()).
+		Return()
+
+	err := UpdateStart(context.Background(), suite.updateEnt)
+	suite.NoError(err)
+}
@@ -925,12 +925,16 @@ func (h *serviceHandler) addSecretsToDBAndConfig(
 		}
 		// store secret in DB
 		if update {
-			if err := h.secretInfoOps.UpdateSecretData(
-				ctx,
-				jobID.GetValue(),
-				string(secret.GetValue().GetData()),
-			); err != nil {
-				return err
+			// secrets without data keep their existing data, so that a
+			// request can carry the existing secrets of a job by ID or path
+			if len(secret.GetValue().GetData()) > 0 {
+				if err := h.secretInfoOps.UpdateSecretData(
+					ctx,
+					secret.GetId().GetValue(),
+					string(secret.GetValue().GetData()),
+				); err != nil {
+					return err
+				}
 			}
 		} else {
 			if err := h.secretInfoOps.CreateSecret(
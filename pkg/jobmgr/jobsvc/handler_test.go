@@ -1371,7 +1371,7 @@ func (suite *JobHandlerTestSuite) TestUpdateJobWithSecrets() {
 	// This should result in once call to update updatedSecret in DB
 	suite.mockedSecretInfoOps.EXPECT().UpdateSecretData(
 		gomock.Any(),
-		gomock.Any(),
+		secretID.GetValue(),
 		string(updatedSecret.Value.Data),
 	).Return(nil)
 	resp, err = suite.handler.Update(suite.context, req)
@@ -1473,6 +1473,45 @@ func (suite *JobHandlerTestSuite) TestUpdateJobWithSecrets() {
 	suite.Equal(jobID, resp.GetId())
 	suite.Equal("added 0 instances", resp.GetMessage())
 
+	// One secret present in oldConfig, request has the existing secret
+	// without data and a new secret. The existing secret keeps its data, so
+	// only the new secret is stored in DB
+	oldJobConfig.GetDefaultConfig().GetContainer().Volumes = []*mesos.Volume{
+		util.CreateSecretVolume(testSecretPath, secretID.GetValue())}
+	req.Config = &job.JobConfig{
+		DefaultConfig: &task.TaskConfig{
+			Command:   &mesos.CommandInfo{Value: &testCmd},
+			Container: &mesos.ContainerInfo{Type: &mesosContainerizer},
+		},
+	}
+	suite.mockedJobStore.EXPECT().
+		GetJobConfig(context.Background(), jobID.GetValue()).
+		Return(oldJobConfig, &models.ConfigAddOn{}, nil)
+	suite.mockedCachedJob.EXPECT().
+		CompareAndSetConfig(gomock.Any(), gomock.Any(), gomock.Any()).
+		Return(&job.JobConfig{
+			ChangeLog: &peloton.ChangeLog{
+				Version: 3,
+			},
+		}, nil)
+	req.Secrets = []*peloton.Secret{
+		jobmgrtask.CreateSecretProto(secretID.GetValue(), testSecretPath, nil),
+		jobmgrtask.CreateSecretProto(
+			"", testSecretPathNew, []byte(testSecretStrNew)),
+	}
+	suite.mockedSecretInfoOps.EXPECT().CreateSecret(
+		gomock.Any(),
+		jobID.Value,        // jobID
+		gomock.Any(),       // now
+		gomock.Any(),       // secretID
+		gomock.Any(),       // secretString
+		testSecretPathNew). // secretPath
+		Return(nil)
+	resp, err = suite.handler.Update(suite.context, req)
+	suite.NoError(err)
+	suite.NotNil(resp)
+	suite.Equal(2, len(util.RemoveSecretVolumesFromJobConfig(req.Config)))
+
 	// Negative tests begin
 
 	// newJobConfig contains secret volumes directly added to config.
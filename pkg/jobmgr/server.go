@@ -40,6 +40,7 @@ type Server struct {
 	role string
 
 	jobFactory         cached.JobFactory
+	epoch              func() (uint64, error)
 	taskPreemptor      preemptor.Preemptor
 	goalstateDriver    goalstate.Driver
 	deadlineTracker    deadline.Tracker
@@ -52,6 +53,7 @@ type Server struct {
 func NewServer(
 	httpPort, grpcPort int,
 	jobFactory cached.JobFactory,
+	epoch func() (uint64, error),
 	goalstateDriver goalstate.Driver,
 	taskPreemptor preemptor.Preemptor,
 	deadlineTracker deadline.Tracker,
@@ -63,6 +65,7 @@ func NewServer(
 		ID:                 leader.NewID(httpPort, grpcPort),
 		role:               common.JobManagerRole,
 		jobFactory:         jobFactory,
+		epoch:              epoch,
 		taskPreemptor:      taskPreemptor,
 		goalstateDriver:    goalstateDriver,
 		deadlineTracker:    deadlineTracker,
@@ -78,7 +81,16 @@ func (s *Server) GainedLeadershipCallback() error {
 
 	log.WithFields(log.Fields{"role": s.role}).Info("Gained leadership")
 
-	s.jobFactory.Start()
+	// the notifications of the cache are sequenced within the epoch of the
+	// leadership, the leadership is given up if it cannot be read
+	epoch, err := s.epoch()
+	if err != nil {
+		log.WithError(err).
+			WithField("role", s.role).
+			Error("failed to read the leadership epoch")
+		return err
+	}
+	s.jobFactory.Start(epoch)
 
 	// goalstateDriver will perform recovery of jobs from DB as
 	// part of startup. Other than cache initialization and start
@@ -94,6 +94,36 @@ func (h *serviceHandler) validateJobConfigUpdate(
 	return nil
 }
 
+// validateInstanceRanges validates that the instance ranges an update is
+// restricted to are within the instances of the job, and that the update
+// does not change the instance count of the job.
+func (h *serviceHandler) validateInstanceRanges(
+	prevJobConfig *job.JobConfig,
+	newJobConfig *job.JobConfig,
+	updateConfig *update.UpdateConfig) error {
+	ranges := updateConfig.GetInstanceRanges()
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	if newJobConfig.GetInstanceCount() != prevJobConfig.GetInstanceCount() {
+		return yarpcerrors.InvalidArgumentErrorf(
+			"instance count cannot be changed by an update " +
+				"restricted to instance ranges")
+	}
+
+	for _, instanceRange := range ranges {
+		if instanceRange.GetFrom() >= instanceRange.GetTo() ||
+			instanceRange.GetTo() > newJobConfig.GetInstanceCount() {
+			return yarpcerrors.InvalidArgumentErrorf(
+				"invalid instance range [%d, %d) for %d instances",
+				instanceRange.GetFrom(), instanceRange.GetTo(),
+				newJobConfig.GetInstanceCount())
+		}
+	}
+	return nil
+}
+
 // validateJobRuntime validates that the job state allows updating it
 func (h *serviceHandler) validateJobRuntime(jobRuntime *job.RuntimeInfo) error {
 	// cannot update a job which is still being created
@@ -160,6 +190,12 @@ func (h *serviceHandler) CreateUpdate(
 		return nil, err
 	}
 
+	if err = h.validateInstanceRanges(
+		prevJobConfig, jobConfig, req.GetUpdateConfig()); err != nil {
+		h.metrics.UpdateCreateFail.Inc(1)
+		return nil, err
+	}
+
 	var respoolPath string
 	for _, label := range prevConfigAddOn.GetSystemLabels() {
 		if label.GetKey() == common.SystemLabelResourcePool {
@@ -489,6 +489,107 @@ func (suite *UpdateSvcTestSuite) TestCreateReduceInstanceCount() {
 	suite.NoError(err)
 }
 
+// TestCreateInstanceRanges tests creating a job update restricted to
+// instance ranges
+func (suite *UpdateSvcTestSuite) TestCreateInstanceRanges() {
+	suite.updateConfig.InstanceRanges = []*task.InstanceRange{
+		{From: 0, To: 1},
+		{From: 5, To: 10},
+	}
+
+	suite.jobFactory.EXPECT().
+		AddJob(suite.jobID).
+		Return(suite.cachedJob)
+
+	suite.jobStore.EXPECT().
+		GetJobRuntime(gomock.Any(), suite.jobID.GetValue()).
+		Return(suite.jobRuntime, nil)
+
+	suite.jobStore.EXPECT().
+		GetJobConfig(gomock.Any(), suite.jobID.GetValue()).
+		Return(suite.jobConfig, &models.ConfigAddOn{}, nil)
+
+	suite.cachedJob.EXPECT().
+		CreateWorkflow(
+			gomock.Any(),
+			models.WorkflowType_UPDATE,
+			suite.updateConfig,
+			gomock.Any(),
+			gomock.Any(),
+			gomock.Any(),
+		).
+		Return(
+			suite.updateID,
+			jobutil.GetJobEntityVersion(
+				suite.jobRuntime.GetConfigurationVersion()+1,
+				suite.jobRuntime.GetDesiredStateVersion(),
+				suite.jobRuntime.GetWorkflowVersion()),
+			nil)
+
+	suite.goalStateDriver.EXPECT().
+		EnqueueUpdate(gomock.Any(), gomock.Any(), gomock.Any())
+
+	_, err := suite.h.CreateUpdate(
+		context.Background(),
+		&svc.CreateUpdateRequest{
+			JobId:        suite.jobID,
+			JobConfig:    suite.newJobConfig,
+			UpdateConfig: suite.updateConfig,
+		},
+	)
+	suite.NoError(err)
+}
+
+// TestCreateInvalidInstanceRanges tests creating a job update restricted
+// to instance ranges which are invalid or with a changed instance count
+func (suite *UpdateSvcTestSuite) TestCreateInvalidInstanceRanges() {
+	tests := []struct {
+		ranges        []*task.InstanceRange
+		instanceCount uint32
+		msg           string
+	}{
+		{
+			ranges:        []*task.InstanceRange{{From: 0, To: 1}},
+			instanceCount: suite.jobConfig.InstanceCount + 1,
+			msg:           "instance count cannot be changed",
+		},
+		{
+			ranges:        []*task.InstanceRange{{From: 5, To: 11}},
+			instanceCount: suite.jobConfig.InstanceCount,
+			msg:           "invalid instance range [5, 11) for 10 instances",
+		},
+		{
+			ranges:        []*task.InstanceRange{{From: 3, To: 3}},
+			instanceCount: suite.jobConfig.InstanceCount,
+			msg:           "invalid instance range [3, 3)",
+		},
+	}
+
+	for _, test := range tests {
+		suite.updateConfig.InstanceRanges = test.ranges
+		suite.newJobConfig.InstanceCount = test.instanceCount
+
+		suite.jobStore.EXPECT().
+			GetJobRuntime(gomock.Any(), suite.jobID.GetValue()).
+			Return(suite.jobRuntime, nil)
+
+		suite.jobStore.EXPECT().
+			GetJobConfig(gomock.Any(), suite.jobID.GetValue()).
+			Return(suite.jobConfig, &models.ConfigAddOn{}, nil)
+
+		_, err := suite.h.CreateUpdate(
+			context.Background(),
+			&svc.CreateUpdateRequest{
+				JobId:        suite.jobID,
+				JobConfig:    suite.newJobConfig,
+				UpdateConfig: suite.updateConfig,
+			},
+		)
+		suite.True(yarpcerrors.IsInvalidArgument(err))
+		suite.Contains(err.Error(), test.msg)
+	}
+}
+
 // TestCreateChangeRespoolID tests creating a job update with a different
 // resource pool identifier in the new job configuration
 func (suite *UpdateSvcTestSuite) TestCreateChangeRespoolID() {
@@ -0,0 +1,51 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	pbtask "github.com/uber/peloton/.gen/peloton/api/v0/task"
+)
+
+// InstanceInRanges returns whether the instance is in any of the ranges,
+// or true if there are no ranges.
+func InstanceInRanges(instanceID uint32, ranges []*pbtask.InstanceRange) bool {
+	if len(ranges) == 0 {
+		return true
+	}
+	for _, instanceRange := range ranges {
+		if instanceID >= instanceRange.GetFrom() &&
+			instanceID < instanceRange.GetTo() {
+			return true
+		}
+	}
+	return false
+}
+
+// FilterInstancesInRanges returns the instances which are in any of the
+// ranges, or all the instances if there are no ranges.
+func FilterInstancesInRanges(
+	instances []uint32,
+	ranges []*pbtask.InstanceRange) []uint32 {
+	if len(ranges) == 0 {
+		return instances
+	}
+	var result []uint32
+	for _, instanceID := range instances {
+		if InstanceInRanges(instanceID, ranges) {
+			result = append(result, instanceID)
+		}
+	}
+	return result
+}
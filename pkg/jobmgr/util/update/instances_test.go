@@ -0,0 +1,40 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package update
+
+import (
+	"testing"
+
+	pbtask "github.com/uber/peloton/.gen/peloton/api/v0/task"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestFilterInstancesInRanges tests filtering the instances in ranges
+func TestFilterInstancesInRanges(t *testing.T) {
+	ranges := []*pbtask.InstanceRange{
+		{From: 0, To: 2},
+		{From: 5, To: 6},
+	}
+	instances := []uint32{0, 1, 2, 5, 6}
+
+	assert.True(t, InstanceInRanges(1, ranges))
+	assert.False(t, InstanceInRanges(2, ranges))
+	assert.True(t, InstanceInRanges(2, nil))
+	assert.Equal(t, []uint32{0, 1, 5}, FilterInstancesInRanges(instances, ranges))
+	assert.Equal(t, instances, FilterInstancesInRanges(instances, nil))
+	assert.Empty(t, FilterInstancesInRanges(instances,
+		[]*pbtask.InstanceRange{{From: 10, To: 20}}))
+}
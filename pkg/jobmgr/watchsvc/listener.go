@@ -23,6 +23,7 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/jobmgr/cached"
 	handlerutil "github.com/uber/peloton/pkg/jobmgr/util/handler"
 )
 
@@ -52,6 +53,7 @@ func (l WatchListener) JobRuntimeChanged(
 	jobID *v0peloton.JobID,
 	jobType job.JobType,
 	runtime *job.RuntimeInfo,
+	sequence cached.NotificationSequence,
 ) {
 	// TODO(kevinxu): to be implemented
 }
@@ -64,6 +66,7 @@ func (l WatchListener) TaskRuntimeChanged(
 	jobType job.JobType,
 	runtime *task.RuntimeInfo,
 	labels []*v0peloton.Label,
+	sequence cached.NotificationSequence,
 ) {
 	// for now watch api only supports stateless
 	if jobType != job.JobType_SERVICE {
@@ -21,6 +21,7 @@ import (
 	v0peloton "github.com/uber/peloton/.gen/peloton/api/v0/peloton"
 	"github.com/uber/peloton/.gen/peloton/api/v0/task"
 
+	"github.com/uber/peloton/pkg/jobmgr/cached"
 	watchmocks "github.com/uber/peloton/pkg/jobmgr/watchsvc/mocks"
 
 	"github.com/golang/mock/gomock"
@@ -63,6 +64,7 @@ func (suite *WatchListenerTestSuite) TestTaskRuntimeChanged() {
 		job.JobType_SERVICE,
 		&task.RuntimeInfo{},
 		[]*v0peloton.Label{},
+		cached.NotificationSequence{},
 	)
 }
 
@@ -78,6 +80,7 @@ func (suite *WatchListenerTestSuite) TestTaskRuntimeChanged_NonServiceType() {
 		job.JobType_BATCH,
 		&task.RuntimeInfo{},
 		[]*v0peloton.Label{},
+		cached.NotificationSequence{},
 	)
 }
 
@@ -92,6 +95,7 @@ func (suite *WatchListenerTestSuite) TestTaskRuntimeChanged_NilFields() {
 		job.JobType_SERVICE,
 		&task.RuntimeInfo{},
 		[]*v0peloton.Label{},
+		cached.NotificationSequence{},
 	)
 
 	suite.listener.TaskRuntimeChanged(
@@ -100,6 +104,7 @@ func (suite *WatchListenerTestSuite) TestTaskRuntimeChanged_NilFields() {
 		job.JobType_SERVICE,
 		nil,
 		nil,
+		cached.NotificationSequence{},
 	)
 }
 
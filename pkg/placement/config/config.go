@@ -98,6 +98,22 @@ type PlacementConfig struct {
 	// MaxDesiredHostPlacementDuration is the max time duration to try to
 	// place a task on the desired host.
 	MaxDesiredHostPlacementDuration time.Duration `yaml:"max_desired_host_placement_duration"`
+
+	// LabelTemplates are the templates of the relation labels of the tasks
+	// placed by the mimir strategy, each a list of label names which can
+	// use the variables $job$, $instance$, $labelkey$ and $labelvalue$. A
+	// relation is made of each user label of the tasks if empty.
+	LabelTemplates [][]string `yaml:"label_templates"`
+
+	// ExplainFailedPlacements makes the mimir strategy explain why the
+	// tasks it fails to place do not fit the hosts offered, the
+	// explanations are returned by the /debug/mimir/explanations endpoint.
+	ExplainFailedPlacements bool `yaml:"explain_failed_placements"`
+
+	// SnapshotGroups makes the mimir strategy record a snapshot of the
+	// group of each host offered, the snapshots are returned by the
+	// /debug/mimir/snapshot endpoint to replay placements offline.
+	SnapshotGroups bool `yaml:"snapshot_groups"`
 }
 
 // MaxRoundsConfig is the config of the maximal number of successful rounds
@@ -0,0 +1,106 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mimir
+
+import (
+	"fmt"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+
+	"github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/labels"
+)
+
+// FromMesosAttributes will convert Mesos attributes into a bag of Mimir labels.
+// A scalar attribute with name n and value v will be turned into the label ["n", "v"].
+// A text attribute with name n and value t will be turned into the label ["n", "t"].
+// A ranges attribute with name n and ranges [r_1a:r_1b], ..., [r_na:r_nb] will be turned into one label per range,
+// i.e. the labels ["n", "[r_1a-r_1b]"], ..., ["n", "[r_na-r_nb]"].
+// A set attribute with name n and items i_1, ..., i_n will be turned into the labels ["n", "i_1"], ..., ["n", "i_n"].
+// A name containing dots, e.g. a.b, will be split into multiple names of the label, e.g. ["a", "b", "v"].
+// Attributes without a name or without a value of their type are skipped and counted by skipped.
+func FromMesosAttributes(attributes []*mesos_v1.Attribute, skipped tally.Counter) *labels.Bag {
+	result := labels.NewBag()
+	for _, attribute := range attributes {
+		values, ok := attributeValues(attribute)
+		if !ok {
+			log.WithField("attribute", attribute.String()).
+				Debug("skipping malformed attribute")
+			skipped.Inc(1)
+			continue
+		}
+		prefix := strings.Split(attribute.GetName(), ".")
+		for _, value := range values {
+			names := make([]string, 0, len(prefix)+1)
+			names = append(names, prefix...)
+			names = append(names, value)
+			result.Add(_labelInterner.NewLabel(names...))
+		}
+	}
+	return result
+}
+
+// attributeValues returns the values of the attribute which should each be turned into a label, and false if the
+// attribute is malformed.
+func attributeValues(attribute *mesos_v1.Attribute) ([]string, bool) {
+	if attribute.GetName() == "" {
+		return nil, false
+	}
+	switch attribute.GetType() {
+	case mesos_v1.Value_SCALAR:
+		if attribute.GetScalar() == nil {
+			return nil, false
+		}
+		return []string{fmt.Sprintf("%v", attribute.GetScalar().GetValue())}, true
+	case mesos_v1.Value_TEXT:
+		if attribute.GetText() == nil {
+			return nil, false
+		}
+		return []string{attribute.GetText().GetValue()}, true
+	case mesos_v1.Value_RANGES:
+		if attribute.GetRanges() == nil {
+			return nil, false
+		}
+		var values []string
+		for _, valueRange := range attribute.GetRanges().GetRange() {
+			values = append(values, fmt.Sprintf("[%v-%v]", valueRange.GetBegin(), valueRange.GetEnd()))
+		}
+		return values, true
+	case mesos_v1.Value_SET:
+		if attribute.GetSet() == nil {
+			return nil, false
+		}
+		return attribute.GetSet().GetItem(), true
+	}
+	return nil, false
+}
+
+// AddHostLabels will add the standard labels of a host to the bag, i.e. the label [HostName, hostname] and, if the
+// domain of the host is known, the labels [RegionName, region] and [ZoneName, zone].
+func AddHostLabels(bag *labels.Bag, hostname string, domain *mesos_v1.DomainInfo) {
+	bag.Add(_labelInterner.NewLabel(HostName, hostname))
+	faultDomain := domain.GetFaultDomain()
+	if faultDomain == nil {
+		return
+	}
+	if region := faultDomain.GetRegion().GetName(); region != "" {
+		bag.Add(_labelInterner.NewLabel(RegionName, region))
+	}
+	if zone := faultDomain.GetZone().GetName(); zone != "" {
+		bag.Add(_labelInterner.NewLabel(ZoneName, zone))
+	}
+}
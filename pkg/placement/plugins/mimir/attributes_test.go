@@ -0,0 +1,122 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mimir
+
+import (
+	"testing"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally"
+
+	"github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/labels"
+)
+
+func labelNames(bag *labels.Bag) []string {
+	var result []string
+	for _, label := range bag.Labels() {
+		result = append(result, label.String())
+	}
+	return result
+}
+
+func TestFromMesosAttributes(t *testing.T) {
+	valueSet := mesos_v1.Value_SET
+	valueRanges := mesos_v1.Value_RANGES
+	valueScalar := mesos_v1.Value_SCALAR
+	valueText := mesos_v1.Value_TEXT
+	attributes := []*mesos_v1.Attribute{
+		{
+			Name: proto.String("rack"),
+			Type: &valueText,
+			Text: &mesos_v1.Value_Text{Value: proto.String("a1")},
+		},
+		{
+			Name:   proto.String("cores"),
+			Type:   &valueScalar,
+			Scalar: &mesos_v1.Value_Scalar{Value: proto.Float64(24)},
+		},
+		{
+			Name: proto.String("ports"),
+			Type: &valueRanges,
+			Ranges: &mesos_v1.Value_Ranges{
+				Range: []*mesos_v1.Value_Range{
+					{Begin: proto.Uint64(31000), End: proto.Uint64(31009)},
+					{Begin: proto.Uint64(32000), End: proto.Uint64(32000)},
+				},
+			},
+		},
+		{
+			Name: proto.String("volume.types"),
+			Type: &valueSet,
+			Set:  &mesos_v1.Value_Set{Item: []string{"zfs", "ext4"}},
+		},
+	}
+
+	bag := FromMesosAttributes(attributes, tally.NoopScope.Counter(""))
+	assert.Equal(t, []string{
+		"cores.24",
+		"ports.[31000-31009]",
+		"ports.[32000-32000]",
+		"rack.a1",
+		"volume.types.ext4",
+		"volume.types.zfs",
+	}, labelNames(bag))
+	assert.Equal(t, labelNames(bag), labelNames(FromMesosAttributes(attributes, tally.NoopScope.Counter(""))))
+}
+
+func TestFromMesosAttributes_SkipsMalformedAttributes(t *testing.T) {
+	scope := tally.NewTestScope("", map[string]string{})
+
+	valueText := mesos_v1.Value_TEXT
+	valueScalar := mesos_v1.Value_SCALAR
+	attributes := []*mesos_v1.Attribute{
+		{
+			Type: &valueText,
+			Text: &mesos_v1.Value_Text{Value: proto.String("no-name")},
+		},
+		{
+			Name: proto.String("missing-scalar"),
+			Type: &valueScalar,
+		},
+		{
+			Name: proto.String("rack"),
+			Type: &valueText,
+			Text: &mesos_v1.Value_Text{Value: proto.String("a1")},
+		},
+	}
+
+	bag := FromMesosAttributes(attributes, scope.Counter("skipped_attributes"))
+	assert.Equal(t, []string{"rack.a1"}, labelNames(bag))
+	assert.Equal(t, int64(2), scope.Snapshot().Counters()["skipped_attributes+"].Value())
+}
+
+func TestAddHostLabels(t *testing.T) {
+	bag := labels.NewBag()
+	AddHostLabels(bag, "host1", nil)
+	assert.Equal(t, []string{HostName + ".host1"}, labelNames(bag))
+
+	bag = labels.NewBag()
+	AddHostLabels(bag, "host1", &mesos_v1.DomainInfo{
+		FaultDomain: &mesos_v1.DomainInfo_FaultDomain{
+			Region: &mesos_v1.DomainInfo_FaultDomain_RegionInfo{Name: proto.String("us-west")},
+			Zone:   &mesos_v1.DomainInfo_FaultDomain_ZoneInfo{Name: proto.String("phx1")},
+		},
+	})
+	assert.Equal(t, 1, bag.Count(labels.NewLabel(HostName, "host1")))
+	assert.Equal(t, 1, bag.Count(labels.NewLabel(RegionName, "us-west")))
+	assert.Equal(t, 1, bag.Count(labels.NewLabel(ZoneName, "phx1")))
+}
@@ -19,7 +19,6 @@ import (
 
 	log "github.com/sirupsen/logrus"
 
-	"github.com/uber/peloton/.gen/mesos/v1"
 	"github.com/uber/peloton/.gen/peloton/api/v0/task"
 	"github.com/uber/peloton/.gen/peloton/private/resmgr"
 	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/labels"
@@ -29,13 +28,17 @@ import (
 	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/requirements"
 )
 
-// TaskToEntity will convert a task to an entity.
-func TaskToEntity(task *resmgr.Task, isLaunched bool) *placement.Entity {
+// TaskToEntity will convert a task to an entity, with the relations made
+// by the labeler.
+func TaskToEntity(
+	task *resmgr.Task,
+	isLaunched bool,
+	labeler *TaskLabeler) *placement.Entity {
 	entity := placement.NewEntity(task.GetId().GetValue())
 	if !isLaunched {
 		addMetrics(task, entity.Metrics)
 	}
-	addRelations(task.GetLabels(), entity.Relations)
+	labeler.AddRelations(NewTaskMetadata(task), entity.Relations)
 
 	var order []placement.Ordering
 	// if the task has a desired host, add the host as the highest priority when picking group
@@ -138,13 +141,6 @@ func makeMetricRequirements(task *resmgr.Task) []placement.Requirement {
 	}
 }
 
-func addRelations(labels *mesos_v1.Labels, relations *labels.Bag) {
-	for _, label := range labels.GetLabels() {
-		log.WithField("label", label.String()).Debug("Adding relation label")
-		relations.Add(makeLabel(label.GetKey(), label.GetValue()))
-	}
-}
-
 func addMetrics(task *resmgr.Task, metricSet *metrics.Set) {
 	resource := task.GetResource()
 	metricSet.Set(CPUReserved, resource.GetCpuLimit()*100.0)
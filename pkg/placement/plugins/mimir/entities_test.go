@@ -28,7 +28,9 @@ import (
 
 func TestEntityMapper_Convert(t *testing.T) {
 	task := testutil.SetupAssignment(time.Now(), 1).GetTask().GetTask()
-	entity := TaskToEntity(task, false)
+	labeler, err := NewTaskLabeler(nil)
+	assert.NoError(t, err)
+	entity := TaskToEntity(task, false, labeler)
 	assert.Equal(t, "id", entity.Name)
 	assert.Equal(t, 1, entity.Relations.Count(labels.NewLabel("relationKey", "relationValue")))
 	assert.NotNil(t, entity.Ordering)
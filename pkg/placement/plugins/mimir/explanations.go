@@ -0,0 +1,125 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mimir
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/placement"
+)
+
+const (
+	// ExplanationsPath is the path of the debug endpoint which returns why
+	// the tasks recently failing placement did not fit the hosts offered.
+	ExplanationsPath = "/debug/mimir/explanations"
+
+	// _maxTaskExplanations is the number of tasks whose explanations are
+	// retained.
+	_maxTaskExplanations = 100
+)
+
+// TaskExplanation explains why a task could not be placed on the hosts
+// offered in a placement round.
+type TaskExplanation struct {
+	Time time.Time `json:"time"`
+	// Hosts are the explanations of the requirement of the task by hostname.
+	Hosts map[string]*placement.Explanation `json:"hosts"`
+}
+
+// ExplanationTracker remembers the explanations of the most recent tasks
+// which failed placement.
+type ExplanationTracker struct {
+	lock         sync.RWMutex
+	explanations map[string]*TaskExplanation
+	// order are the tasks explained, oldest first
+	order    []string
+	maxTasks int
+}
+
+// NewExplanationTracker creates a tracker of the explanations of the tasks
+// failing placement when the strategy is configured to explain them.
+func NewExplanationTracker() *ExplanationTracker {
+	return newExplanationTracker(_maxTaskExplanations)
+}
+
+func newExplanationTracker(maxTasks int) *ExplanationTracker {
+	return &ExplanationTracker{
+		explanations: map[string]*TaskExplanation{},
+		maxTasks:     maxTasks,
+	}
+}
+
+// explain records the explanations of why the entity of a task does not
+// fit each of the groups.
+func (tracker *ExplanationTracker) explain(
+	entity *placement.Entity,
+	groups []*placement.Group,
+	scopeSet *placement.ScopeSet) {
+	explanation := &TaskExplanation{
+		Time:  time.Now(),
+		Hosts: make(map[string]*placement.Explanation, len(groups)),
+	}
+	for _, group := range groups {
+		explanation.Hosts[group.Name] = placement.Explain(
+			entity.Requirement, group, scopeSet, entity)
+	}
+
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	if _, exists := tracker.explanations[entity.Name]; !exists {
+		tracker.order = append(tracker.order, entity.Name)
+	}
+	tracker.explanations[entity.Name] = explanation
+	for len(tracker.order) > tracker.maxTasks {
+		delete(tracker.explanations, tracker.order[0])
+		tracker.order = tracker.order[1:]
+	}
+}
+
+// get returns the explanation of the given task, or of all tasks if the
+// task id is empty.
+func (tracker *ExplanationTracker) get(taskID string) map[string]*TaskExplanation {
+	tracker.lock.RLock()
+	defer tracker.lock.RUnlock()
+
+	result := map[string]*TaskExplanation{}
+	for task, explanation := range tracker.explanations {
+		if taskID != "" && task != taskID {
+			continue
+		}
+		result[task] = explanation
+	}
+	return result
+}
+
+// ExplanationsHandler returns a handler for the explanations debug
+// endpoint of the given tracker, the optional task_id query parameter
+// restricts the result to one task.
+func ExplanationsHandler(tracker *ExplanationTracker) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := json.Marshal(tracker.get(r.URL.Query().Get("task_id")))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
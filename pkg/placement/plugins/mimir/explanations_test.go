@@ -0,0 +1,76 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mimir
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally"
+
+	"github.com/uber/peloton/pkg/placement/models"
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/labels"
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/placement"
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/requirements"
+	"github.com/uber/peloton/pkg/placement/testutil"
+)
+
+func TestExplanationTracker_RetainsLastTasks(t *testing.T) {
+	tracker := newExplanationTracker(2)
+	group := OfferToGroup(hostOfferWithRack("a1"), tally.NoopScope.Counter(""))
+	for _, name := range []string{"task1", "task2", "task1", "task3"} {
+		entity := placement.NewEntity(name)
+		entity.Requirement = requirements.NewLabelRequirement(
+			nil, labels.NewLabel("rack", "a2"), requirements.GreaterThanEqual, 1)
+		tracker.explain(entity, []*placement.Group{group}, placement.NewScopeSet(nil))
+	}
+
+	result := tracker.get("")
+	assert.Equal(t, 2, len(result))
+	assert.NotNil(t, result["task1"])
+	assert.NotNil(t, result["task3"])
+
+	explanation := result["task3"].Hosts["host1"]
+	assert.False(t, explanation.Passed)
+	assert.Equal(t, 0.0, *explanation.Observed)
+	assert.Empty(t, tracker.get("task2"))
+}
+
+func TestMimirPlaceExplainsFailedPlacements(t *testing.T) {
+	assignments := []*models.Assignment{
+		testutil.SetupAssignment(time.Now().Add(10*time.Second), 1),
+		testutil.SetupAssignment(time.Now().Add(10*time.Second), 1),
+	}
+	offers := []*models.HostOffers{
+		testutil.SetupHostOffers(),
+	}
+	strategy := setupStrategy()
+	strategy.config.ExplainFailedPlacements = true
+	strategy.PlaceOnce(assignments, offers)
+	assert.Nil(t, assignments[1].GetHost())
+
+	w := httptest.NewRecorder()
+	ExplanationsHandler(strategy.explanations)(w, httptest.NewRequest("GET", ExplanationsPath+"?task_id=id", nil))
+	result := map[string]*TaskExplanation{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 1, len(result))
+	explanation := result["id"].Hosts["hostname"]
+	assert.NotNil(t, explanation)
+	assert.Equal(t, "and", explanation.Type)
+	assert.NotEmpty(t, explanation.Explanations)
+}
@@ -15,8 +15,7 @@
 package mimir
 
 import (
-	"fmt"
-	"strings"
+	"github.com/uber-go/tally"
 
 	"github.com/uber/peloton/.gen/mesos/v1"
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
@@ -25,11 +24,18 @@ import (
 	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/placement"
 )
 
-// OfferToGroup will convert an offer to a group.
-func OfferToGroup(hostOffer *hostsvc.HostOffer) *placement.Group {
+// _maxInternedLabelNames is the maximal number of strings held by the label interner.
+const _maxInternedLabelNames = 1 << 20
+
+// _labelInterner interns the names of the labels created from host attributes so hosts with identical attributes
+// share the storage of their labels, it is reset once per placement round.
+var _labelInterner = labels.NewInterner(_maxInternedLabelNames)
+
+// OfferToGroup will convert an offer to a group, counting the malformed attributes of the offer by skippedAttributes.
+func OfferToGroup(hostOffer *hostsvc.HostOffer, skippedAttributes tally.Counter) *placement.Group {
 	group := placement.NewGroup(hostOffer.Hostname)
 	group.Metrics = makeMetrics(hostOffer.GetResources())
-	group.Labels = makeLabels(hostOffer)
+	group.Labels = makeLabels(hostOffer, skippedAttributes)
 	return group
 }
 
@@ -64,35 +70,9 @@ func makeMetrics(resources []*mesos_v1.Resource) *metrics.Set {
 	return result
 }
 
-// makeLabels will convert Mesos attributes into Mimir labels.
-// A scalar attribute with name n and value v will be turned into the label ["n", "v"].
-// A text attribute with name n and value t will be turned into the label ["n", "t"].
-// A ranges attribute with name n and ranges [r_1a:r_1b], ..., [r_na:r_nb] will be turned into
-// the label ["n", "[r_1a-r1b];...[r_na-r_nb]"].
-func makeLabels(hostOffer *hostsvc.HostOffer) *labels.Bag {
-	attributes := hostOffer.GetAttributes()
-	result := labels.NewBag()
-	for _, attribute := range attributes {
-		var value string
-		switch attribute.GetType() {
-		case mesos_v1.Value_SCALAR:
-			value = fmt.Sprintf("%v", attribute.GetScalar().GetValue())
-		case mesos_v1.Value_TEXT:
-			value = attribute.GetText().GetValue()
-		case mesos_v1.Value_RANGES:
-			ranges := attribute.GetRanges().GetRange()
-			length := len(ranges)
-			for i, valueRange := range ranges {
-				value += fmt.Sprintf("[%v-%v]", valueRange.GetBegin(), valueRange.GetEnd())
-				if i < length-1 {
-					value += ";"
-				}
-			}
-		}
-		names := strings.Split(attribute.GetName(), ".")
-		names = append(names, value)
-		result.Add(labels.NewLabel(names...))
-	}
-	result.Add(labels.NewLabel(HostName, hostOffer.GetHostname()))
+// makeLabels will convert the Mesos attributes, the hostname and the domain of the host offer into Mimir labels.
+func makeLabels(hostOffer *hostsvc.HostOffer, skippedAttributes tally.Counter) *labels.Bag {
+	result := FromMesosAttributes(hostOffer.GetAttributes(), skippedAttributes)
+	AddHostLabels(result, hostOffer.GetHostname(), hostOffer.GetDomain())
 	return result
 }
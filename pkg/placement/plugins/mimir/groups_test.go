@@ -17,8 +17,11 @@ package mimir
 import (
 	"testing"
 
+	"github.com/gogo/protobuf/proto"
 	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally"
 
+	"github.com/uber/peloton/.gen/mesos/v1"
 	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/labels"
 	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/metrics"
 	"github.com/uber/peloton/pkg/placement/testutil"
@@ -26,7 +29,7 @@ import (
 
 func TestGroupMapper_Convert(t *testing.T) {
 	offer := testutil.SetupHostOffers().GetOffer()
-	group := OfferToGroup(offer)
+	group := OfferToGroup(offer, tally.NoopScope.Counter(""))
 	assert.Equal(t, "hostname", group.Name)
 	assert.Equal(t, 4800.0, group.Metrics.Get(CPUAvailable))
 	assert.Equal(t, 128.0*metrics.GiB, group.Metrics.Get(MemoryAvailable))
@@ -37,3 +40,16 @@ func TestGroupMapper_Convert(t *testing.T) {
 	assert.Equal(t, 1, group.Labels.Count(labels.NewLabel("attribute", "1")))
 	assert.Equal(t, 1, group.Labels.Count(labels.NewLabel("attribute", "[31000-31009]")))
 }
+
+func TestGroupMapper_ConvertDomain(t *testing.T) {
+	offer := testutil.SetupHostOffers().GetOffer()
+	offer.Domain = &mesos_v1.DomainInfo{
+		FaultDomain: &mesos_v1.DomainInfo_FaultDomain{
+			Region: &mesos_v1.DomainInfo_FaultDomain_RegionInfo{Name: proto.String("us-west")},
+			Zone:   &mesos_v1.DomainInfo_FaultDomain_ZoneInfo{Name: proto.String("phx1")},
+		},
+	}
+	group := OfferToGroup(offer, tally.NoopScope.Counter(""))
+	assert.Equal(t, 1, group.Labels.Count(labels.NewLabel(RegionName, "us-west")))
+	assert.Equal(t, 1, group.Labels.Count(labels.NewLabel(ZoneName, "phx1")))
+}
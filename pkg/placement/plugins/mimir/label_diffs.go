@@ -0,0 +1,189 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mimir
+
+import (
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
+
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/labels"
+)
+
+const (
+	// LabelDiffsPath is the path of the debug endpoint which returns the
+	// recent label changes of the hosts seen by the placement engine.
+	LabelDiffsPath = "/debug/mimir/labeldiffs"
+
+	// _maxLabelDiffsPerHost is the number of label changes retained per host.
+	_maxLabelDiffsPerHost = 10
+
+	// _maxLabelDiffsHostAge is the age after which the labels and the label
+	// changes of a host which was not offered again are dropped.
+	_maxLabelDiffsHostAge = 30 * time.Minute
+)
+
+// LabelDiff is a change of the labels of a host between two offers of the host.
+type LabelDiff struct {
+	Time    time.Time      `json:"time"`
+	Added   map[string]int `json:"added,omitempty"`
+	Removed map[string]int `json:"removed,omitempty"`
+}
+
+// hostLabelHistory is the last labels of a host and their recent changes.
+type hostLabelHistory struct {
+	labels  *labels.Bag
+	updated time.Time
+	diffs   []*LabelDiff
+}
+
+// LabelDiffTracker remembers the last labels of every host and the most
+// recent changes to those labels. Hosts which are not updated within the
+// maximum age are dropped so the tracker does not grow with every host ever
+// offered.
+type LabelDiffTracker struct {
+	lock        sync.RWMutex
+	hosts       map[string]*hostLabelHistory
+	maxDiffs    int
+	maxAge      time.Duration
+	lastEvicted time.Time
+
+	labelsAdded   tally.Counter
+	labelsRemoved tally.Counter
+}
+
+// NewLabelDiffTracker creates a tracker of the label changes of the hosts
+// reporting its metrics in the given scope.
+func NewLabelDiffTracker(scope tally.Scope) *LabelDiffTracker {
+	return newLabelDiffTracker(_maxLabelDiffsPerHost, _maxLabelDiffsHostAge, scope)
+}
+
+func newLabelDiffTracker(maxDiffs int, maxAge time.Duration, scope tally.Scope) *LabelDiffTracker {
+	return &LabelDiffTracker{
+		hosts:         map[string]*hostLabelHistory{},
+		maxDiffs:      maxDiffs,
+		maxAge:        maxAge,
+		lastEvicted:   time.Now(),
+		labelsAdded:   scope.Counter("host_labels_added"),
+		labelsRemoved: scope.Counter("host_labels_removed"),
+	}
+}
+
+func labelCounts(bag *labels.Bag) map[string]int {
+	if bag.Size() == 0 {
+		return nil
+	}
+	result := make(map[string]int, bag.Size())
+	for _, label := range bag.Labels() {
+		result[label.String()] = bag.Count(label)
+	}
+	return result
+}
+
+// update records the labels of the host and returns the change since the
+// previous update of the host, or nil if the labels did not change.
+func (tracker *LabelDiffTracker) update(hostname string, current *labels.Bag) *LabelDiff {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	now := time.Now()
+	tracker.evict(now)
+
+	history, exists := tracker.hosts[hostname]
+	if !exists {
+		tracker.hosts[hostname] = &hostLabelHistory{labels: current, updated: now}
+		return nil
+	}
+	previous := history.labels
+	history.labels = current
+	history.updated = now
+	added, removed := current.Diff(previous)
+	if added.Size() == 0 && removed.Size() == 0 {
+		return nil
+	}
+
+	diff := &LabelDiff{
+		Time:    now,
+		Added:   labelCounts(added),
+		Removed: labelCounts(removed),
+	}
+	tracker.labelsAdded.Inc(int64(added.Size()))
+	tracker.labelsRemoved.Inc(int64(removed.Size()))
+	log.WithField("hostname", hostname).
+		WithField("added", diff.Added).
+		WithField("removed", diff.Removed).
+		Debug("host labels changed")
+
+	diffs := append(history.diffs, diff)
+	if len(diffs) > tracker.maxDiffs {
+		diffs = diffs[len(diffs)-tracker.maxDiffs:]
+	}
+	history.diffs = diffs
+	return diff
+}
+
+// evict drops the hosts which were not updated within the maximum age, it
+// only scans the hosts once per maximum age. The lock must be held.
+func (tracker *LabelDiffTracker) evict(now time.Time) {
+	if now.Sub(tracker.lastEvicted) < tracker.maxAge {
+		return
+	}
+	tracker.lastEvicted = now
+	for hostname, history := range tracker.hosts {
+		if now.Sub(history.updated) > tracker.maxAge {
+			delete(tracker.hosts, hostname)
+		}
+	}
+}
+
+// get returns the recent label changes of the given host, or of all hosts
+// if the hostname is empty.
+func (tracker *LabelDiffTracker) get(hostname string) map[string][]*LabelDiff {
+	tracker.lock.RLock()
+	defer tracker.lock.RUnlock()
+
+	now := time.Now()
+	result := map[string][]*LabelDiff{}
+	for host, history := range tracker.hosts {
+		if hostname != "" && host != hostname {
+			continue
+		}
+		if len(history.diffs) == 0 || now.Sub(history.updated) > tracker.maxAge {
+			continue
+		}
+		result[host] = append([]*LabelDiff{}, history.diffs...)
+	}
+	return result
+}
+
+// LabelDiffsHandler returns a handler for the label diffs debug endpoint of
+// the tracker, the optional hostname query parameter restricts the result to
+// one host.
+func LabelDiffsHandler(tracker *LabelDiffTracker) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := json.Marshal(tracker.get(r.URL.Query().Get("hostname")))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
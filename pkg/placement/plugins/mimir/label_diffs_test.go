@@ -0,0 +1,105 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mimir
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally"
+
+	"github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
+)
+
+func hostOfferWithRack(rack string) *hostsvc.HostOffer {
+	valueText := mesos_v1.Value_TEXT
+	return &hostsvc.HostOffer{
+		Hostname: "host1",
+		Attributes: []*mesos_v1.Attribute{
+			{
+				Name: proto.String("rack"),
+				Type: &valueText,
+				Text: &mesos_v1.Value_Text{Value: proto.String(rack)},
+			},
+		},
+	}
+}
+
+func TestLabelDiffTracker_RecordsAttributeChange(t *testing.T) {
+	scope := tally.NewTestScope("", map[string]string{})
+	tracker := newLabelDiffTracker(2, time.Hour, scope)
+
+	assert.Nil(t, tracker.update("host1", OfferToGroup(hostOfferWithRack("a1"), tally.NoopScope.Counter("")).Labels))
+	assert.Nil(t, tracker.update("host1", OfferToGroup(hostOfferWithRack("a1"), tally.NoopScope.Counter("")).Labels))
+
+	diff := tracker.update("host1", OfferToGroup(hostOfferWithRack("a2"), tally.NoopScope.Counter("")).Labels)
+	assert.NotNil(t, diff)
+	assert.Equal(t, map[string]int{"rack.a2": 1}, diff.Added)
+	assert.Equal(t, map[string]int{"rack.a1": 1}, diff.Removed)
+	assert.Equal(t, int64(1), scope.Snapshot().Counters()["host_labels_added+"].Value())
+	assert.Equal(t, int64(1), scope.Snapshot().Counters()["host_labels_removed+"].Value())
+	assert.Equal(t, []*LabelDiff{diff}, tracker.get("host1")["host1"])
+}
+
+func TestLabelDiffTracker_RetainsLastDiffs(t *testing.T) {
+	tracker := newLabelDiffTracker(2, time.Hour, tally.NoopScope)
+	for _, rack := range []string{"a1", "a2", "a3", "a4"} {
+		tracker.update("host1", OfferToGroup(hostOfferWithRack(rack), tally.NoopScope.Counter("")).Labels)
+	}
+
+	diffs := tracker.get("")["host1"]
+	assert.Equal(t, 2, len(diffs))
+	assert.Equal(t, map[string]int{"rack.a3": 1}, diffs[0].Added)
+	assert.Equal(t, map[string]int{"rack.a4": 1}, diffs[1].Added)
+	assert.Empty(t, tracker.get("host2"))
+}
+
+func TestLabelDiffTracker_EvictsStaleHosts(t *testing.T) {
+	tracker := newLabelDiffTracker(2, time.Minute, tally.NoopScope)
+	tracker.update("host1", OfferToGroup(hostOfferWithRack("a1"), tally.NoopScope.Counter("")).Labels)
+	tracker.update("host1", OfferToGroup(hostOfferWithRack("a2"), tally.NoopScope.Counter("")).Labels)
+	assert.Equal(t, 1, len(tracker.get("host1")))
+
+	// host1 was last offered before the maximum age, it is hidden and then
+	// dropped on the next update.
+	tracker.hosts["host1"].updated = time.Now().Add(-2 * time.Minute)
+	tracker.lastEvicted = time.Now().Add(-2 * time.Minute)
+	assert.Empty(t, tracker.get("host1"))
+
+	offer := hostOfferWithRack("a1")
+	offer.Hostname = "host2"
+	tracker.update("host2", OfferToGroup(offer, tally.NoopScope.Counter("")).Labels)
+	assert.Equal(t, 1, len(tracker.hosts))
+	assert.Contains(t, tracker.hosts, "host2")
+}
+
+func TestLabelDiffsHandler(t *testing.T) {
+	tracker := NewLabelDiffTracker(tally.NoopScope)
+	tracker.update("host1", OfferToGroup(hostOfferWithRack("a1"), tally.NoopScope.Counter("")).Labels)
+	tracker.update("host1", OfferToGroup(hostOfferWithRack("a2"), tally.NoopScope.Counter("")).Labels)
+
+	w := httptest.NewRecorder()
+	LabelDiffsHandler(tracker)(w, httptest.NewRequest("GET", LabelDiffsPath+"?hostname=host1", nil))
+
+	result := map[string][]*LabelDiff{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 1, len(result["host1"]))
+	assert.Equal(t, map[string]int{"rack.a2": 1}, result["host1"][0].Added)
+}
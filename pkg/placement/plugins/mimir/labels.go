@@ -4,4 +4,12 @@ const (
 	// HostName represents the hostname label used
 	// internally by placement engine
 	HostName = "peloton.placementengine.hostname"
+
+	// RegionName represents the label of the fault domain region
+	// of a host used internally by placement engine
+	RegionName = "peloton.placementengine.region"
+
+	// ZoneName represents the label of the fault domain zone
+	// of a host used internally by placement engine
+	ZoneName = "peloton.placementengine.zone"
 )
@@ -23,20 +23,76 @@
 package labels
 
 import (
+	"encoding/json"
 	"sort"
 	"sync"
 )
 
 // Bag represents a bag of labels and their counts, i.e. it is a multi-bag.
 type Bag struct {
-	bag  map[string]*labelCount
-	lock sync.RWMutex
+	bag map[string]*labelCount
+	// index contains the same labels as bag grouped by their number of names and their first name, so wildcard
+	// queries only have to visit the labels which can possibly match.
+	index map[indexKey]map[string]*labelCount
+	lock  sync.RWMutex
 }
 
 // NewBag will create a new label bag.
 func NewBag() *Bag {
+	return NewBagWithCapacity(0)
+}
+
+// NewBagWithCapacity will create a new label bag with room for the given number of different labels.
+func NewBagWithCapacity(size int) *Bag {
 	return &Bag{
-		bag: map[string]*labelCount{},
+		bag:   make(map[string]*labelCount, size),
+		index: map[indexKey]map[string]*labelCount{},
+	}
+}
+
+type indexKey struct {
+	length int
+	first  string
+}
+
+func indexKeyOf(label *Label) indexKey {
+	key := indexKey{
+		length: len(label.names),
+	}
+	if len(label.names) > 0 {
+		key.first = label.names[0]
+	}
+	return key
+}
+
+// put adds the pair to the bag and the index under the given key, which must be the string of the label of the pair,
+// and the label of the pair must not already be in the bag.
+func (bag *Bag) put(key string, pair *labelCount) {
+	bag.bag[key] = pair
+	indexKey := indexKeyOf(pair.label)
+	entries, exists := bag.index[indexKey]
+	if !exists {
+		entries = map[string]*labelCount{}
+		bag.index[indexKey] = entries
+	}
+	entries[key] = pair
+}
+
+// candidates calls the visitor for all pairs in the bag which can possibly match the pattern.
+func (bag *Bag) candidates(pattern *Label, visitor func(pair *labelCount)) {
+	key := indexKeyOf(pattern)
+	if key.first == "*" {
+		for _, pair := range bag.bag {
+			visitor(pair)
+		}
+		return
+	}
+	for _, pair := range bag.index[key] {
+		visitor(pair)
+	}
+	// Labels in the bag can also contain wildcards, in which case they match any first name of the pattern.
+	for _, pair := range bag.index[indexKey{length: key.length, first: "*"}] {
+		visitor(pair)
 	}
 }
 
@@ -70,10 +126,10 @@ func (bag *Bag) Add(labels ...*Label) {
 		if oldPair, found := bag.bag[key]; found {
 			oldPair.count++
 		} else {
-			bag.bag[key] = &labelCount{
+			bag.put(key, &labelCount{
 				label: label,
 				count: 1,
-			}
+			})
 		}
 	}
 }
@@ -82,8 +138,8 @@ func copyContent(src *Bag) map[string]*labelCount {
 	src.lock.RLock()
 	defer src.lock.RUnlock()
 	dst := make(map[string]*labelCount, len(src.bag))
-	for _, pair := range src.bag {
-		dst[pair.label.String()] = pair.clone()
+	for key, pair := range src.bag {
+		dst[key] = pair.clone()
 	}
 	return dst
 }
@@ -95,11 +151,11 @@ func (bag *Bag) AddAll(other *Bag) {
 	bag.lock.Lock()
 	defer bag.lock.Unlock()
 
-	for _, pair := range otherCopy {
-		if oldPair, found := bag.bag[pair.label.String()]; found {
+	for key, pair := range otherCopy {
+		if oldPair, found := bag.bag[key]; found {
 			oldPair.count += pair.count
 		} else {
-			bag.bag[pair.label.String()] = pair
+			bag.put(key, pair)
 		}
 	}
 }
@@ -109,13 +165,14 @@ func (bag *Bag) Set(label *Label, count int) {
 	bag.lock.Lock()
 	defer bag.lock.Unlock()
 
-	if oldPair, found := bag.bag[label.String()]; found {
+	key := label.String()
+	if oldPair, found := bag.bag[key]; found {
 		oldPair.count = count
 	} else {
-		bag.bag[label.String()] = &labelCount{
+		bag.put(key, &labelCount{
 			label: label,
 			count: count,
-		}
+		})
 	}
 }
 
@@ -127,11 +184,11 @@ func (bag *Bag) SetAll(other *Bag) {
 	bag.lock.Lock()
 	defer bag.lock.Unlock()
 
-	for _, pair := range otherCopy {
-		if oldPair, found := bag.bag[pair.label.String()]; found {
+	for key, pair := range otherCopy {
+		if oldPair, found := bag.bag[key]; found {
 			oldPair.count = pair.count
 		} else {
-			bag.bag[pair.label.String()] = pair
+			bag.put(key, pair)
 		}
 	}
 }
@@ -178,10 +235,18 @@ func (bag *Bag) Find(label *Label) []*Label {
 	return result
 }
 
-func (bag *Bag) findByPattern(pattern *Label) []*Label {
+// FindWith will find all labels matching the given label using the given matcher to compare names.
+func (bag *Bag) FindWith(label *Label, matcher *Matcher) []*Label {
+	if matcher.sensitive() {
+		return bag.Find(label)
+	}
+
+	bag.lock.RLock()
+	defer bag.lock.RUnlock()
+
 	var result []*Label
 	for _, pair := range bag.bag {
-		if !pattern.Match(pair.label) {
+		if !matcher.Match(label, pair.label) {
 			continue
 		}
 		result = append(result, pair.label)
@@ -189,6 +254,16 @@ func (bag *Bag) findByPattern(pattern *Label) []*Label {
 	return result
 }
 
+func (bag *Bag) findByPattern(pattern *Label) []*Label {
+	var result []*Label
+	bag.candidates(pattern, func(pair *labelCount) {
+		if pattern.Match(pair.label) {
+			result = append(result, pair.label)
+		}
+	})
+	return result
+}
+
 // Contains returns true iff the count of the label is strictly higher than zero.
 func (bag *Bag) Contains(label *Label) bool {
 	return bag.Count(label) > 0
@@ -209,13 +284,128 @@ func (bag *Bag) Count(label *Label) int {
 	return 0
 }
 
-func (bag *Bag) countByPattern(pattern *Label) int {
+// CountWith counts the number of labels that this label matches using the given matcher to compare names.
+func (bag *Bag) CountWith(label *Label, matcher *Matcher) int {
+	if matcher.sensitive() {
+		return bag.Count(label)
+	}
+
+	bag.lock.RLock()
+	defer bag.lock.RUnlock()
+
 	counts := 0
 	for _, pair := range bag.bag {
-		if !pattern.Match(pair.label) {
+		if !matcher.Match(label, pair.label) {
 			continue
 		}
 		counts += pair.count
 	}
 	return counts
 }
+
+func (bag *Bag) countByPattern(pattern *Label) int {
+	counts := 0
+	bag.candidates(pattern, func(pair *labelCount) {
+		if pattern.Match(pair.label) {
+			counts += pair.count
+		}
+	})
+	return counts
+}
+
+// counts returns a map from the escaped name of each label in the bag to its count.
+func (bag *Bag) counts() map[string]int {
+	bag.lock.RLock()
+	defer bag.lock.RUnlock()
+
+	result := make(map[string]int, len(bag.bag))
+	for _, pair := range bag.bag {
+		result[pair.label.escapedName()] = pair.count
+	}
+	return result
+}
+
+// setCounts replaces the content of the bag with the labels and counts of the given map.
+func (bag *Bag) setCounts(counts map[string]int) error {
+	content := make([]*labelCount, 0, len(counts))
+	for text, count := range counts {
+		label, err := ParseLabel(text)
+		if err != nil {
+			return err
+		}
+		content = append(content, &labelCount{
+			label: label,
+			count: count,
+		})
+	}
+
+	bag.lock.Lock()
+	defer bag.lock.Unlock()
+
+	bag.bag = make(map[string]*labelCount, len(content))
+	bag.index = map[indexKey]map[string]*labelCount{}
+	for _, pair := range content {
+		key := pair.label.String()
+		if oldPair, found := bag.bag[key]; found {
+			oldPair.count += pair.count
+			continue
+		}
+		bag.put(key, pair)
+	}
+	return nil
+}
+
+// MarshalJSON marshals the bag as a map from label strings to their counts.
+func (bag *Bag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(bag.counts())
+}
+
+// UnmarshalJSON unmarshals the bag from a map from label strings to their counts.
+func (bag *Bag) UnmarshalJSON(data []byte) error {
+	var counts map[string]int
+	if err := json.Unmarshal(data, &counts); err != nil {
+		return err
+	}
+	return bag.setCounts(counts)
+}
+
+// MarshalYAML marshals the bag as a map from label strings to their counts.
+func (bag *Bag) MarshalYAML() (interface{}, error) {
+	return bag.counts(), nil
+}
+
+// UnmarshalYAML unmarshals the bag from a map from label strings to their counts.
+func (bag *Bag) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var counts map[string]int
+	if err := unmarshal(&counts); err != nil {
+		return err
+	}
+	return bag.setCounts(counts)
+}
+
+// Diff computes the difference between this bag and the previous bag, i.e. the added bag contains the labels whose
+// count increased with the increase as count, and the removed bag contains the labels whose count decreased with the
+// decrease as count.
+func (bag *Bag) Diff(previous *Bag) (added, removed *Bag) {
+	current := copyContent(bag)
+	old := copyContent(previous)
+
+	added, removed = NewBag(), NewBag()
+	for key, pair := range current {
+		oldCount := 0
+		if oldPair, found := old[key]; found {
+			oldCount = oldPair.count
+		}
+		if pair.count > oldCount {
+			added.Set(pair.label, pair.count-oldCount)
+		} else if pair.count < oldCount {
+			removed.Set(pair.label, oldCount-pair.count)
+		}
+	}
+	for key, oldPair := range old {
+		if _, found := current[key]; !found && oldPair.count > 0 {
+			removed.Set(oldPair.label, oldPair.count)
+		}
+	}
+	return added, removed
+}
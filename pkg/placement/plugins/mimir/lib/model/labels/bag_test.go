@@ -23,10 +23,16 @@
 package labels
 
 import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"sort"
 	"strconv"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
 )
 
 func TestBag_Size(t *testing.T) {
@@ -173,3 +179,280 @@ func TestBag_CountWillCountAllMatchingLabels(t *testing.T) {
 	assert.Equal(t, 2, bag.Count(pattern1))
 	assert.Equal(t, 4, bag.Count(pattern2))
 }
+
+func TestBag_JSONRoundTrip(t *testing.T) {
+	bag := NewBag()
+	bag.Set(NewLabel("rack", "a"), 3)
+	bag.Set(NewLabel("zone", "dc1.phx"), 4)
+	bag.Add(NewLabel("volume", "*"))
+
+	data, err := json.Marshal(bag)
+	assert.NoError(t, err)
+
+	result := NewBag()
+	assert.NoError(t, json.Unmarshal(data, result))
+	assert.Equal(t, bag.Labels(), result.Labels())
+	for _, label := range bag.Labels() {
+		assert.Equal(t, bag.Count(label), result.Count(label))
+	}
+}
+
+func TestBag_YAMLRoundTrip(t *testing.T) {
+	bag := NewBag()
+	bag.Set(NewLabel("rack", "a"), 3)
+	bag.Set(NewLabel("zone", "dc1.phx"), 4)
+
+	data, err := yaml.Marshal(bag)
+	assert.NoError(t, err)
+
+	result := NewBag()
+	assert.NoError(t, yaml.Unmarshal(data, result))
+	assert.Equal(t, bag.Labels(), result.Labels())
+	assert.Equal(t, 4, result.Count(NewLabel("zone", "dc1.phx")))
+}
+
+func TestBag_JSONGolden(t *testing.T) {
+	golden, err := ioutil.ReadFile("testdata/bag.json")
+	assert.NoError(t, err)
+
+	bag := NewBag()
+	assert.NoError(t, json.Unmarshal(golden, bag))
+	assert.Equal(t, 4, bag.Size())
+	assert.Equal(t, 1, bag.Count(NewLabel("host", "agent1")))
+	assert.Equal(t, 3, bag.Count(NewLabel("rack", "a")))
+	assert.Equal(t, 4, bag.Count(NewLabel("zone", "dc1.phx")))
+	assert.Equal(t, 2, bag.Count(NewLabel("volume", "*")))
+
+	data, err := json.Marshal(bag)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(golden), string(data))
+}
+
+func bruteForceCountAndFind(bag *Bag, pattern *Label) (int, int) {
+	count, found := 0, 0
+	for _, pair := range bag.bag {
+		if pattern.Match(pair.label) {
+			count += pair.count
+			found++
+		}
+	}
+	return count, found
+}
+
+func TestBag_CountWithWildcardsMatchesBruteForce(t *testing.T) {
+	random := rand.New(rand.NewSource(42))
+	names := []string{"volume-types", "rack", "host", "*"}
+	values := []string{"zfs", "ext4", "a", "*"}
+	bag := NewBag()
+	for i := 0; i < 200; i++ {
+		label := NewLabel(names[random.Intn(len(names))], values[random.Intn(len(values))])
+		if random.Intn(4) == 0 {
+			label = NewLabel(names[random.Intn(len(names))], values[random.Intn(len(values))], "extra")
+		}
+		bag.Set(label, random.Intn(5)+1)
+	}
+
+	for _, name := range names {
+		for _, value := range values {
+			for _, pattern := range []*Label{
+				NewLabel(name, value),
+				NewLabel(name, "*"),
+				NewLabel(name, value, "*"),
+				NewLabel("*", value),
+			} {
+				if !pattern.Wildcard() {
+					continue
+				}
+				count, found := bruteForceCountAndFind(bag, pattern)
+				assert.Equal(t, count, bag.Count(pattern), pattern.String())
+				assert.Equal(t, found, len(bag.Find(pattern)), pattern.String())
+			}
+		}
+	}
+}
+
+func benchmarkBagWithLabels(size int) *Bag {
+	bag := NewBag()
+	for i := 0; i < size; i++ {
+		bag.Add(NewLabel(fmt.Sprintf("attribute-%v", i%100), fmt.Sprintf("value-%v", i)))
+	}
+	bag.Add(NewLabel("volume-types", "zfs"))
+	bag.Add(NewLabel("volume-types", "ext4"))
+	return bag
+}
+
+func BenchmarkBag_CountWithWildcard(b *testing.B) {
+	bag := benchmarkBagWithLabels(1000)
+	pattern := NewLabel("volume-types", "*")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bag.Count(pattern)
+	}
+}
+
+func BenchmarkBag_CountWithWildcardBruteForce(b *testing.B) {
+	bag := benchmarkBagWithLabels(1000)
+	pattern := NewLabel("volume-types", "*")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bruteForceCountAndFind(bag, pattern)
+	}
+}
+
+func TestBag_Diff(t *testing.T) {
+	previous := NewBag()
+	previous.Add(NewLabel("rack", "a1"))
+	previous.Set(NewLabel("volume", "zfs"), 2)
+	previous.Add(NewLabel("maintenance", "scheduled"))
+
+	current := NewBag()
+	current.Add(NewLabel("rack", "a2"))
+	current.Set(NewLabel("volume", "zfs"), 1)
+	current.Add(NewLabel("maintenance", "scheduled"))
+
+	added, removed := current.Diff(previous)
+	assert.Equal(t, []*Label{NewLabel("rack", "a2")}, added.Labels())
+	assert.Equal(t, 1, added.Count(NewLabel("rack", "a2")))
+	assert.Equal(t, []*Label{NewLabel("rack", "a1"), NewLabel("volume", "zfs")}, removed.Labels())
+	assert.Equal(t, 1, removed.Count(NewLabel("volume", "zfs")))
+
+	added, removed = current.Diff(current)
+	assert.Equal(t, 0, added.Size())
+	assert.Equal(t, 0, removed.Size())
+}
+
+// referenceBag is a naive implementation of a bag the bag is compared against.
+type referenceBag struct {
+	labels []*Label
+	counts []int
+}
+
+func (ref *referenceBag) index(label *Label) int {
+	for i, other := range ref.labels {
+		if other.String() == label.String() {
+			return i
+		}
+	}
+	return -1
+}
+
+func (ref *referenceBag) set(label *Label, count int, add bool) {
+	if i := ref.index(label); i >= 0 {
+		if add {
+			count += ref.counts[i]
+		}
+		ref.counts[i] = count
+		return
+	}
+	ref.labels = append(ref.labels, label)
+	ref.counts = append(ref.counts, count)
+}
+
+func (ref *referenceBag) countAndFind(pattern *Label) (int, []string) {
+	count := 0
+	var found []string
+	for i, label := range ref.labels {
+		if pattern.Match(label) {
+			count += ref.counts[i]
+			found = append(found, label.String())
+		}
+	}
+	return count, found
+}
+
+func labelNames(labels []*Label) []string {
+	var result []string
+	for _, label := range labels {
+		result = append(result, label.String())
+	}
+	return result
+}
+
+func TestBag_MatchesReference(t *testing.T) {
+	random := rand.New(rand.NewSource(42))
+	names := []string{"rack", "host", "volume-types", "*"}
+	values := []string{"a", "b", "zfs", "*"}
+	randomLabel := func() *Label {
+		if random.Intn(4) == 0 {
+			return NewLabel(names[random.Intn(len(names))], values[random.Intn(len(values))], "extra")
+		}
+		return NewLabel(names[random.Intn(len(names))], values[random.Intn(len(values))])
+	}
+
+	for run := 0; run < 20; run++ {
+		bag, ref := NewBag(), &referenceBag{}
+		other, otherRef := NewBagWithCapacity(10), &referenceBag{}
+		for i := 0; i < 100; i++ {
+			label, count := randomLabel(), random.Intn(5)+1
+			switch random.Intn(5) {
+			case 0:
+				bag.Add(label)
+				ref.set(label, 1, true)
+			case 1:
+				bag.Set(label, count)
+				ref.set(label, count, false)
+			case 2:
+				other.Set(label, count)
+				otherRef.set(label, count, false)
+			case 3:
+				bag.AddAll(other)
+				for j, label := range otherRef.labels {
+					ref.set(label, otherRef.counts[j], true)
+				}
+			case 4:
+				bag.SetAll(other)
+				for j, label := range otherRef.labels {
+					ref.set(label, otherRef.counts[j], false)
+				}
+			}
+		}
+
+		assert.Equal(t, len(ref.labels), bag.Size())
+		expected := labelNames(ref.labels)
+		sort.Strings(expected)
+		assert.Equal(t, expected, labelNames(bag.Labels()))
+		for _, name := range names {
+			for _, value := range values {
+				for _, pattern := range []*Label{
+					NewLabel(name, value),
+					NewLabel(name, value, "extra"),
+					NewLabel(name, value, "*"),
+				} {
+					count, found := ref.countAndFind(pattern)
+					if !pattern.Wildcard() {
+						// without wildcards only the label itself is counted, even if labels in the bag have wildcards
+						count, found = 0, nil
+						if i := ref.index(pattern); i >= 0 {
+							count, found = ref.counts[i], []string{pattern.String()}
+						}
+					}
+					assert.Equal(t, count, bag.Count(pattern), pattern.String())
+					actual := labelNames(bag.Find(pattern))
+					sort.Strings(found)
+					sort.Strings(actual)
+					assert.Equal(t, found, actual, pattern.String())
+				}
+			}
+		}
+
+		added, removed := bag.Diff(other)
+		for i, label := range ref.labels {
+			if label.Wildcard() {
+				continue
+			}
+			otherCount := 0
+			if j := otherRef.index(label); j >= 0 {
+				otherCount = otherRef.counts[j]
+			}
+			assert.Equal(t, maxInt(ref.counts[i]-otherCount, 0), added.Count(label), label.String())
+			assert.Equal(t, maxInt(otherCount-ref.counts[i], 0), removed.Count(label), label.String())
+		}
+	}
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -23,6 +23,9 @@
 package labels
 
 import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
 	"strings"
 )
 
@@ -35,18 +38,35 @@ type Label struct {
 	simpleName string
 	// wildcard is true iff the label contains a wildcard
 	wildcard bool
+	// hash is a precomputed hash of the simple name used to quickly reject labels which do not match
+	hash uint64
 }
 
 // NewLabel creates a new label from the given names.
 func NewLabel(names ...string) *Label {
-	simpleName := strings.Join(names, ".")
+	return newLabel(names, strings.Join(names, "."))
+}
+
+func newLabel(names []string, simpleName string) *Label {
 	return &Label{
 		names:      names,
 		simpleName: simpleName,
 		wildcard:   strings.Contains(simpleName, "*"),
+		hash:       hashName(simpleName),
 	}
 }
 
+func hashName(name string) uint64 {
+	hash := fnv.New64a()
+	hash.Write([]byte(name))
+	return hash.Sum64()
+}
+
+// Hash returns a hash of the concatenation of the names in the label.
+func (label *Label) Hash() uint64 {
+	return label.hash
+}
+
 // Wildcard returns true iff the label contains a wildcard.
 func (label *Label) Wildcard() bool {
 	return label.wildcard
@@ -78,7 +98,7 @@ func (label *Label) Match(other *Label) bool {
 		return true
 	}
 	if !label.wildcard && !other.wildcard {
-		return label.simpleName == other.simpleName
+		return label.hash == other.hash && label.simpleName == other.simpleName
 	}
 	if len(label.names) != len(other.names) {
 		return false
@@ -90,3 +110,78 @@ func (label *Label) Match(other *Label) bool {
 	}
 	return true
 }
+
+// escapedName returns the names of the label joined with dot as a separator, where any dots and backslashes inside
+// the names are escaped with a backslash so the label can be recreated from the result with ParseLabel.
+func (label *Label) escapedName() string {
+	escaped := make([]string, len(label.names))
+	for i, name := range label.names {
+		name = strings.Replace(name, `\`, `\\`, -1)
+		escaped[i] = strings.Replace(name, ".", `\.`, -1)
+	}
+	return strings.Join(escaped, ".")
+}
+
+// ParseLabel creates a new label from a string of names separated by dots, a dot or backslash which is part of a
+// name should be escaped with a backslash. The empty string is parsed as a label without any names.
+func ParseLabel(text string) (*Label, error) {
+	if text == "" {
+		return NewLabel(), nil
+	}
+	var names []string
+	var name []byte
+	for i := 0; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			if i+1 >= len(text) || (text[i+1] != '.' && text[i+1] != '\\') {
+				return nil, fmt.Errorf("invalid escape sequence at position %v in label %v", i, text)
+			}
+			i++
+			name = append(name, text[i])
+		case '.':
+			names = append(names, string(name))
+			name = name[:0]
+		default:
+			name = append(name, text[i])
+		}
+	}
+	names = append(names, string(name))
+	return NewLabel(names...), nil
+}
+
+// MarshalJSON marshals the label as a string of its names separated by dots.
+func (label *Label) MarshalJSON() ([]byte, error) {
+	return json.Marshal(label.escapedName())
+}
+
+// UnmarshalJSON unmarshals the label from a string of its names separated by dots.
+func (label *Label) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	return label.parse(text)
+}
+
+// MarshalYAML marshals the label as a string of its names separated by dots.
+func (label *Label) MarshalYAML() (interface{}, error) {
+	return label.escapedName(), nil
+}
+
+// UnmarshalYAML unmarshals the label from a string of its names separated by dots.
+func (label *Label) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var text string
+	if err := unmarshal(&text); err != nil {
+		return err
+	}
+	return label.parse(text)
+}
+
+func (label *Label) parse(text string) error {
+	parsed, err := ParseLabel(text)
+	if err != nil {
+		return err
+	}
+	*label = *parsed
+	return nil
+}
@@ -23,9 +23,11 @@
 package labels
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
 )
 
 func TestLabel_Match_WithoutWildcard(t *testing.T) {
@@ -100,3 +102,47 @@ func TestLabel_Match_ReturnsTrueWhenSamePointer(t *testing.T) {
 	label := NewLabel("foo", "bar", "baz")
 	assert.True(t, label.Match(label))
 }
+
+func TestLabel_JSONRoundTrip(t *testing.T) {
+	for _, label := range []*Label{
+		NewLabel("foo", "bar", "baz"),
+		NewLabel("foo", "*", "baz"),
+		NewLabel("foo.bar", "baz"),
+		NewLabel(`foo\`, `.bar\.`),
+		NewLabel(),
+	} {
+		data, err := json.Marshal(label)
+		assert.NoError(t, err)
+
+		result := &Label{}
+		assert.NoError(t, json.Unmarshal(data, result))
+		assert.Equal(t, label.Names(), result.Names())
+		assert.Equal(t, label.String(), result.String())
+		assert.Equal(t, label.Wildcard(), result.Wildcard())
+	}
+}
+
+func TestLabel_MarshalJSONEscapesDots(t *testing.T) {
+	data, err := json.Marshal(NewLabel("zone", "dc1.phx"))
+	assert.NoError(t, err)
+	assert.Equal(t, `"zone.dc1\\.phx"`, string(data))
+}
+
+func TestLabel_YAMLRoundTrip(t *testing.T) {
+	label := NewLabel("foo.bar", "*")
+	data, err := yaml.Marshal(label)
+	assert.NoError(t, err)
+
+	result := &Label{}
+	assert.NoError(t, yaml.Unmarshal(data, result))
+	assert.Equal(t, label.Names(), result.Names())
+	assert.True(t, result.Wildcard())
+}
+
+func TestParseLabel_InvalidEscape(t *testing.T) {
+	_, err := ParseLabel(`foo\bar`)
+	assert.Error(t, err)
+
+	_, err = ParseLabel(`foo\`)
+	assert.Error(t, err)
+}
@@ -23,44 +23,98 @@
 package labels
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
 	"sync"
 )
 
+const (
+	// DefaultLeftDelimiter is the default delimiter used in front of the name of a variable in a template.
+	DefaultLeftDelimiter = "$"
+
+	// DefaultRightDelimiter is the default delimiter used after the name of a variable in a template.
+	DefaultRightDelimiter = "$"
+)
+
+// ErrEmptyDelimiter is returned for a template whose left or right delimiter is empty.
+var ErrEmptyDelimiter = errors.New("template delimiters must not be empty")
+
+// MaxInstantiations is the maximal number of labels InstantiateAll will create from the cross product of the
+// multi-valued bindings of a template.
+const MaxInstantiations = 1024
+
 // Template represents a label template which can be instantiated with different values. A template can create
 // labels like schemaless.cluster.percona-cluster-$instance-name$-$zone$-db$cluster$ where the labelTemplate substrings
 // <instance-name>, <zone> and <cluster> can then be bound later, and re-bound to instantiate different labels.
+// Templates created with NewTemplate can be marshalled to and unmarshalled from JSON and YAML, to unmarshal a template
+// create an empty template with NewTemplate and unmarshal into it.
 type Template interface {
 	// Bind will bind the template with the given name to the given value.
 	Bind(name, value string) Template
 
+	// BindAll will bind the template with the given name to all of the given values, any single value bound with
+	// Bind for the same name will be replaced.
+	BindAll(name string, values []string) Template
+
 	// Mappings will return a map of all the current variables and their values.
 	Mappings() map[string]string
 
 	// Instantiate will create a new label where all templates have been replaced with their currently bound value.
 	Instantiate() *Label
+
+	// InstantiateAll will create a label for each combination in the cross product of the values of the multi-valued
+	// variables, single-valued variables are replaced as in Instantiate. An error is returned if more than
+	// MaxInstantiations labels would be created.
+	InstantiateAll() ([]*Label, error)
 }
 
 // NewTemplate will create a new label template which can be used to create labels with. Each name in the slice of
 // supplied names can use template substrings like percona-cluster-$instance-name$-$zone$-db$cluster$ and then later
 // bind the template names <instance-name>, <zone> and <cluster> using the bind method.
 func NewTemplate(names ...string) Template {
+	return newLabelTemplate(DefaultLeftDelimiter, DefaultRightDelimiter, names...)
+}
+
+// NewTemplateWithDelimiters will create a new label template like NewTemplate, but where variables are enclosed in the
+// given delimiters instead of $, e.g. with the delimiters {{ and }} the names can use template substrings like
+// percona-cluster-{{instance-name}}-{{zone}} and values containing $ are left untouched. ErrEmptyDelimiter is returned
+// if either delimiter is empty.
+func NewTemplateWithDelimiters(left, right string, names ...string) (Template, error) {
+	if left == "" || right == "" {
+		return nil, ErrEmptyDelimiter
+	}
+	return newLabelTemplate(left, right, names...), nil
+}
+
+func newLabelTemplate(left, right string, names ...string) *labelTemplate {
 	return &labelTemplate{
-		names:     names,
-		variables: map[string]string{},
+		names:          names,
+		left:           left,
+		right:          right,
+		variables:      map[string]string{},
+		multiVariables: map[string][]string{},
 	}
 }
 
 type labelTemplate struct {
-	names     []string
-	variables map[string]string
-	lock      sync.Mutex
+	names          []string
+	left           string
+	right          string
+	variables      map[string]string
+	multiVariables map[string][]string
+	lock           sync.Mutex
 }
 
 func (template *labelTemplate) replace(name string) string {
-	for variable, value := range template.variables {
-		v := fmt.Sprintf("$%v$", variable)
+	return template.replaceVariables(name, template.variables)
+}
+
+func (template *labelTemplate) replaceVariables(name string, variables map[string]string) string {
+	for variable, value := range variables {
+		v := template.left + variable + template.right
 		if strings.Contains(name, v) {
 			name = strings.Replace(name, v, value, -1)
 		}
@@ -84,22 +138,187 @@ func (template *labelTemplate) Bind(name, value string) Template {
 	template.lock.Lock()
 
 	template.variables[name] = value
+	delete(template.multiVariables, name)
+	return template
+}
+
+func (template *labelTemplate) BindAll(name string, values []string) Template {
+	defer template.lock.Unlock()
+	template.lock.Lock()
+
+	template.multiVariables[name] = append([]string{}, values...)
+	delete(template.variables, name)
 	return template
 }
 
+func (template *labelTemplate) InstantiateAll() ([]*Label, error) {
+	defer template.lock.Unlock()
+	template.lock.Lock()
+
+	// Visit the multi-valued variables in sorted order so the resulting labels have a deterministic order.
+	multiNames := make([]string, 0, len(template.multiVariables))
+	size := 1
+	for name, values := range template.multiVariables {
+		multiNames = append(multiNames, name)
+		size *= len(values)
+		if size > MaxInstantiations {
+			return nil, fmt.Errorf("template %v would instantiate more than %v labels",
+				strings.Join(template.names, "."), MaxInstantiations)
+		}
+	}
+	sort.Strings(multiNames)
+
+	bindings := make(map[string]string, len(template.variables)+len(multiNames))
+	for variable, value := range template.variables {
+		bindings[variable] = value
+	}
+	result := make([]*Label, 0, size)
+	var expand func(index int)
+	expand = func(index int) {
+		if index == len(multiNames) {
+			names := make([]string, len(template.names))
+			for i, name := range template.names {
+				names[i] = template.replaceVariables(name, bindings)
+			}
+			result = append(result, NewLabel(names...))
+			return
+		}
+		variable := multiNames[index]
+		for _, value := range template.multiVariables[variable] {
+			bindings[variable] = value
+			expand(index + 1)
+		}
+	}
+	expand(0)
+	return result, nil
+}
+
 func (template *labelTemplate) Mappings() map[string]string {
 	defer template.lock.Unlock()
 	template.lock.Lock()
 
 	mappings := make(map[string]string, len(template.variables))
 	for _, name := range template.names {
-		for i, variable := range strings.Split(name, "$") {
+		for _, variable := range template.variableNames(name) {
+			mappings[variable] = template.variables[variable]
+		}
+	}
+	return mappings
+}
+
+// variableNames returns the names of the variables used in the given name.
+func (template *labelTemplate) variableNames(name string) []string {
+	var result []string
+	if template.left == template.right {
+		for i, variable := range strings.Split(name, template.left) {
 			// A variable foo should always be used as $foo$ so every other string in the above split is a variable name
 			if i%2 != 1 {
 				continue
 			}
-			mappings[variable] = template.variables[variable]
+			result = append(result, variable)
 		}
+		return result
 	}
-	return mappings
+	for {
+		start := strings.Index(name, template.left)
+		if start < 0 {
+			return result
+		}
+		name = name[start+len(template.left):]
+		end := strings.Index(name, template.right)
+		if end < 0 {
+			return result
+		}
+		result = append(result, name[:end])
+		name = name[end+len(template.right):]
+	}
+}
+
+// templateData is the serialized form of a label template, it contains the names of the template and the current
+// bindings of its variables.
+type templateData struct {
+	Names         []string            `json:"names" yaml:"names"`
+	Left          string              `json:"left,omitempty" yaml:"left,omitempty"`
+	Right         string              `json:"right,omitempty" yaml:"right,omitempty"`
+	Bindings      map[string]string   `json:"bindings,omitempty" yaml:"bindings,omitempty"`
+	MultiBindings map[string][]string `json:"multi_bindings,omitempty" yaml:"multi_bindings,omitempty"`
+}
+
+func (template *labelTemplate) data() *templateData {
+	defer template.lock.Unlock()
+	template.lock.Lock()
+
+	data := &templateData{
+		Names: append([]string{}, template.names...),
+	}
+	if template.left != DefaultLeftDelimiter || template.right != DefaultRightDelimiter {
+		data.Left, data.Right = template.left, template.right
+	}
+	if len(template.variables) > 0 {
+		data.Bindings = make(map[string]string, len(template.variables))
+		for variable, value := range template.variables {
+			data.Bindings[variable] = value
+		}
+	}
+	if len(template.multiVariables) > 0 {
+		data.MultiBindings = make(map[string][]string, len(template.multiVariables))
+		for variable, values := range template.multiVariables {
+			data.MultiBindings[variable] = append([]string{}, values...)
+		}
+	}
+	return data
+}
+
+// setData replaces the names, the delimiters and the bindings of the template, ErrEmptyDelimiter is returned if only
+// one of the delimiters is set.
+func (template *labelTemplate) setData(data *templateData) error {
+	if (data.Left == "") != (data.Right == "") {
+		return ErrEmptyDelimiter
+	}
+
+	defer template.lock.Unlock()
+	template.lock.Lock()
+
+	template.names = data.Names
+	template.left, template.right = DefaultLeftDelimiter, DefaultRightDelimiter
+	if data.Left != "" {
+		template.left, template.right = data.Left, data.Right
+	}
+	template.variables = map[string]string{}
+	for variable, value := range data.Bindings {
+		template.variables[variable] = value
+	}
+	template.multiVariables = map[string][]string{}
+	for variable, values := range data.MultiBindings {
+		template.multiVariables[variable] = values
+	}
+	return nil
+}
+
+// MarshalJSON marshals the names of the template and the current bindings of its variables.
+func (template *labelTemplate) MarshalJSON() ([]byte, error) {
+	return json.Marshal(template.data())
+}
+
+// UnmarshalJSON unmarshals the names of the template and the bindings of its variables.
+func (template *labelTemplate) UnmarshalJSON(data []byte) error {
+	result := &templateData{}
+	if err := json.Unmarshal(data, result); err != nil {
+		return err
+	}
+	return template.setData(result)
+}
+
+// MarshalYAML marshals the names of the template and the current bindings of its variables.
+func (template *labelTemplate) MarshalYAML() (interface{}, error) {
+	return template.data(), nil
+}
+
+// UnmarshalYAML unmarshals the names of the template and the bindings of its variables.
+func (template *labelTemplate) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	result := &templateData{}
+	if err := unmarshal(result); err != nil {
+		return err
+	}
+	return template.setData(result)
 }
@@ -23,9 +23,12 @@
 package labels
 
 import (
+	"encoding/json"
+	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v2"
 )
 
 func TestTemplate(t *testing.T) {
@@ -49,3 +52,158 @@ func TestTemplate_Mappings(t *testing.T) {
 
 	assert.Equal(t, map[string]string{"bar": "bar", "baz": ""}, template.Mappings())
 }
+
+func labelStrings(labels []*Label) []string {
+	result := make([]string, 0, len(labels))
+	for _, label := range labels {
+		result = append(result, label.String())
+	}
+	return result
+}
+
+func TestTemplate_InstantiateAll_SingleValued(t *testing.T) {
+	template := NewTemplate("foo", "$bar$")
+	template.Bind("bar", "bar")
+
+	labels, err := template.InstantiateAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo.bar"}, labelStrings(labels))
+	assert.Equal(t, "foo.bar", template.Instantiate().String())
+}
+
+func TestTemplate_InstantiateAll_OneMultiValuedVariable(t *testing.T) {
+	template := NewTemplate("volume", "$type$", "$host$")
+	template.Bind("host", "host1")
+	template.BindAll("type", []string{"ssd", "hdd"})
+
+	labels, err := template.InstantiateAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"volume.ssd.host1", "volume.hdd.host1"}, labelStrings(labels))
+}
+
+func TestTemplate_InstantiateAll_CrossProduct(t *testing.T) {
+	template := NewTemplate("$rack$", "$type$")
+	template.BindAll("rack", []string{"r1", "r2"})
+	template.BindAll("type", []string{"ssd", "hdd", "nvme"})
+
+	labels, err := template.InstantiateAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"r1.ssd", "r1.hdd", "r1.nvme",
+		"r2.ssd", "r2.hdd", "r2.nvme",
+	}, labelStrings(labels))
+}
+
+func TestTemplate_InstantiateAll_Rebind(t *testing.T) {
+	template := NewTemplate("foo", "$bar$")
+	template.BindAll("bar", []string{"a", "b"})
+	template.Bind("bar", "c")
+
+	labels, err := template.InstantiateAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo.c"}, labelStrings(labels))
+}
+
+func TestTemplate_InstantiateAll_ExceedsCap(t *testing.T) {
+	values := make([]string, 0, 100)
+	for i := 0; i < 100; i++ {
+		values = append(values, fmt.Sprintf("%v", i))
+	}
+	template := NewTemplate("$a$", "$b$")
+	template.BindAll("a", values)
+	template.BindAll("b", values)
+
+	labels, err := template.InstantiateAll()
+	assert.Error(t, err)
+	assert.Nil(t, labels)
+}
+
+func TestTemplate_JSONRoundTrip(t *testing.T) {
+	template := NewTemplate("foo", "$bar$.baz", "$qux$")
+	template.Bind("bar", "bar")
+	template.BindAll("qux", []string{"a", "b"})
+
+	data, err := json.Marshal(template)
+	assert.NoError(t, err)
+
+	result := NewTemplate()
+	assert.NoError(t, json.Unmarshal(data, result))
+	assert.Equal(t, template.Mappings(), result.Mappings())
+	assert.Equal(t, template.Instantiate().Names(), result.Instantiate().Names())
+	labels, err := result.InstantiateAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo.bar.baz.a", "foo.bar.baz.b"}, labelStrings(labels))
+}
+
+func TestTemplate_YAMLRoundTrip(t *testing.T) {
+	template := NewTemplate("foo", "$bar$")
+	template.Bind("bar", "bar")
+
+	data, err := yaml.Marshal(template)
+	assert.NoError(t, err)
+
+	result := NewTemplate()
+	assert.NoError(t, yaml.Unmarshal(data, result))
+	assert.Equal(t, "foo.bar", result.Instantiate().String())
+}
+
+func TestTemplate_DefaultDelimiterInValue(t *testing.T) {
+	template := NewTemplate("dataset", "$name$")
+	template.Bind("name", "pay$ments")
+
+	assert.Equal(t, "dataset.pay$ments", template.Instantiate().String())
+	assert.Equal(t, map[string]string{"name": "pay$ments"}, template.Mappings())
+}
+
+func TestTemplate_DefaultDelimiterInNameIsAVariable(t *testing.T) {
+	template := NewTemplate("pay$ments$")
+
+	assert.Equal(t, map[string]string{"ments": ""}, template.Mappings())
+}
+
+func TestTemplateWithDelimiters(t *testing.T) {
+	template, err := NewTemplateWithDelimiters("{{", "}}", "pay$ments$", "{{zone}}-{{cluster}}")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"zone": "", "cluster": ""}, template.Mappings())
+
+	template.Bind("zone", "phx")
+	assert.Equal(t, "pay$ments$.phx-{{cluster}}", template.Instantiate().String())
+
+	template.BindAll("cluster", []string{"a", "b"})
+	labels, err := template.InstantiateAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"pay$ments$.phx-a", "pay$ments$.phx-b"}, labelStrings(labels))
+}
+
+func TestTemplateWithDelimiters_UnterminatedVariable(t *testing.T) {
+	template, err := NewTemplateWithDelimiters("{{", "}}", "{{zone}}-{{cluster")
+	assert.NoError(t, err)
+
+	assert.Equal(t, map[string]string{"zone": ""}, template.Mappings())
+}
+
+func TestTemplateWithDelimiters_JSONRoundTrip(t *testing.T) {
+	template, err := NewTemplateWithDelimiters("{{", "}}", "pay$ments", "{{zone}}")
+	assert.NoError(t, err)
+	template.Bind("zone", "phx")
+
+	data, err := json.Marshal(template)
+	assert.NoError(t, err)
+
+	result := NewTemplate()
+	assert.NoError(t, json.Unmarshal(data, result))
+	assert.Equal(t, template.Mappings(), result.Mappings())
+	result.Bind("zone", "sjc")
+	assert.Equal(t, "pay$ments.sjc", result.Instantiate().String())
+}
+
+func TestTemplateWithDelimiters_EmptyDelimiters(t *testing.T) {
+	for _, delimiters := range [][]string{{"", "}}"}, {"{{", ""}, {"", ""}} {
+		template, err := NewTemplateWithDelimiters(delimiters[0], delimiters[1], "{{zone}}")
+		assert.Equal(t, ErrEmptyDelimiter, err)
+		assert.Nil(t, template)
+	}
+
+	result := NewTemplate()
+	assert.Equal(t, ErrEmptyDelimiter, json.Unmarshal([]byte(`{"names": ["{{zone}}"], "left": "{{"}`), result))
+}
@@ -32,3 +32,11 @@ func TestNewFailed(t *testing.T) {
 	requirement := FailedRequirement()
 	assert.False(t, requirement.Passed(nil, nil, nil, nil))
 }
+
+func TestExplain_FailedRequirement(t *testing.T) {
+	explanation := Explain(FailedRequirement(), nil, nil, nil)
+	assert.False(t, explanation.Passed)
+	assert.Equal(t, "empty-type", explanation.Type)
+	assert.Nil(t, explanation.Observed)
+	assert.Equal(t, []*Explanation{explanation}, explanation.Failed())
+}
@@ -66,6 +66,23 @@ func (requirement *AndRequirement) Passed(group *placement.Group, scopeSet *plac
 	return result
 }
 
+// Explain explains the requirement by explaining all its sub-requirements.
+func (requirement *AndRequirement) Explain(group *placement.Group, scopeSet *placement.ScopeSet,
+	entity *placement.Entity) *placement.Explanation {
+	passed := true
+	explanations := make([]*placement.Explanation, 0, len(requirement.Requirements))
+	for _, subRequirement := range requirement.Requirements {
+		subExplanation := placement.Explain(subRequirement, group, scopeSet, entity)
+		if !subExplanation.Passed {
+			passed = false
+		}
+		explanations = append(explanations, subExplanation)
+	}
+	explanation := placement.NewExplanation(requirement, passed)
+	explanation.Explanations = explanations
+	return explanation
+}
+
 func (requirement *AndRequirement) String() string {
 	subRequirements := make([]string, 0, len(requirement.Requirements))
 	for _, subRequirement := range requirement.Requirements {
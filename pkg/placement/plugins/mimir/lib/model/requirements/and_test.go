@@ -23,6 +23,7 @@
 package requirements
 
 import (
+	"encoding/json"
 	"fmt"
 	"testing"
 
@@ -118,3 +119,106 @@ func TestAndRequirement_Fulfilled_returns_false_if_any_subrequirements_are_false
 
 	assert.False(t, requirement.Passed(group, scopeSet, nil, nil))
 }
+
+// setupAndOfOrsRequirement requires the group to be in rack dc1-a007 or dc1-a008, to have no issues or a zfs volume,
+// and to be in datacenter dc2 or dc3.
+func setupAndOfOrsRequirement() *AndRequirement {
+	return NewAndRequirement(
+		NewOrRequirement(
+			NewLabelRequirement(nil, labels.NewLabel("rack", "dc1-a007"), GreaterThanEqual, 1),
+			NewLabelRequirement(nil, labels.NewLabel("rack", "dc1-a008"), GreaterThanEqual, 1),
+		),
+		NewOrRequirement(
+			NewLabelRequirement(nil, labels.NewLabel("issues", "*"), LessThanEqual, 0),
+			NewLabelRequirement(nil, labels.NewLabel("volume-types", "zfs"), GreaterThanEqual, 1),
+		),
+		NewOrRequirement(
+			NewLabelRequirement(nil, labels.NewLabel("datacenter", "dc2"), GreaterThanEqual, 1),
+			NewLabelRequirement(nil, labels.NewLabel("datacenter", "dc3"), GreaterThanEqual, 1),
+		),
+	)
+}
+
+func TestAndRequirement_Explain_identifies_the_failing_leaves(t *testing.T) {
+	group := placement.NewGroup("group")
+	group.Labels, group.Relations = hostWithIssue()
+	scopeSet := placement.NewScopeSet(nil)
+	requirement := setupAndOfOrsRequirement()
+
+	explanation := requirement.Explain(group, scopeSet, nil)
+	assert.False(t, explanation.Passed)
+	assert.Equal(t, requirement.Passed(group, scopeSet, nil, nil), explanation.Passed)
+	assert.Equal(t, "and", explanation.Type)
+	assert.Equal(t, 3, len(explanation.Explanations))
+	assert.True(t, explanation.Explanations[0].Passed)
+	assert.False(t, explanation.Explanations[1].Passed)
+	assert.False(t, explanation.Explanations[2].Passed)
+
+	failed := explanation.Failed()
+	assert.Equal(t, 4, len(failed))
+	var failedRequirements []string
+	for _, leaf := range failed {
+		failedRequirements = append(failedRequirements, leaf.Requirement)
+		assert.Equal(t, "label", leaf.Type)
+	}
+	orRequirements := []*OrRequirement{
+		requirement.Requirements[1].(*OrRequirement),
+		requirement.Requirements[2].(*OrRequirement),
+	}
+	var expected []string
+	for _, or := range orRequirements {
+		for _, leaf := range or.Requirements {
+			expected = append(expected, leaf.String())
+		}
+	}
+	assert.Equal(t, expected, failedRequirements)
+
+	// the observed label counts and the expected comparisons
+	issues := failed[0]
+	assert.Equal(t, 1.0, *issues.Observed)
+	assert.Equal(t, string(LessThanEqual), issues.Comparison)
+	assert.Equal(t, 0.0, *issues.Expected)
+	zfs := failed[1]
+	assert.Equal(t, 0.0, *zfs.Observed)
+	assert.Equal(t, string(GreaterThanEqual), zfs.Comparison)
+	assert.Equal(t, 1.0, *zfs.Expected)
+
+	data, err := json.Marshal(explanation)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `"observed":1`)
+}
+
+func TestAndRequirement_Explain_passed(t *testing.T) {
+	group := placement.NewGroup("group")
+	group.Labels, group.Relations = hostWithZFSVolume()
+	scopeSet := placement.NewScopeSet(nil)
+	requirement := NewAndRequirement(setupAndOfOrsRequirement().Requirements[:2]...)
+
+	explanation := requirement.Explain(group, scopeSet, nil)
+	assert.True(t, explanation.Passed)
+	assert.Empty(t, explanation.Failed())
+}
+
+func TestAndRequirement_Passed_does_not_pay_for_explanations(t *testing.T) {
+	group := placement.NewGroup("group")
+	group.Labels, group.Relations = hostWithIssue()
+	scopeSet := placement.NewScopeSet(nil)
+	requirement := setupAndOfOrsRequirement()
+
+	// the only allocations are the ones of counting the labels of the leaves
+	var leaves []*LabelRequirement
+	for _, or := range requirement.Requirements {
+		for _, leaf := range or.(*OrRequirement).Requirements {
+			leaves = append(leaves, leaf.(*LabelRequirement))
+		}
+	}
+	counting := testing.AllocsPerRun(100, func() {
+		for _, leaf := range leaves {
+			scopeSet.LabelScope(group, leaf.Scope).CountWith(leaf.Label, leaf.Matcher)
+		}
+	})
+	passing := testing.AllocsPerRun(100, func() {
+		requirement.Passed(group, scopeSet, nil, nil)
+	})
+	assert.Equal(t, counting, passing)
+}
@@ -46,6 +46,8 @@ type LabelRequirement struct {
 	Label       *labels.Label
 	Comparison  Comparison
 	Occurrences int
+	// Matcher decides how names are compared when counting the occurrences, nil means case-sensitive.
+	Matcher *labels.Matcher
 }
 
 // NewLabelRequirement creates a new label requirement.
@@ -61,9 +63,8 @@ func NewLabelRequirement(scope, label *labels.Label, comparison Comparison, occu
 // Passed checks if the requirement is fulfilled by the given group within the scope groups.
 func (requirement *LabelRequirement) Passed(group *placement.Group, scopeSet *placement.ScopeSet,
 	entity *placement.Entity, transcript *placement.Transcript) bool {
-	occurrences := scopeSet.LabelScope(group, requirement.Scope).Count(requirement.Label)
-	fulfilled, err := requirement.Comparison.Compare(float64(occurrences), float64(requirement.Occurrences))
-	if err != nil || !fulfilled {
+	_, fulfilled := requirement.evaluate(group, scopeSet)
+	if !fulfilled {
 		transcript.IncFailed()
 		return false
 	}
@@ -71,6 +72,21 @@ func (requirement *LabelRequirement) Passed(group *placement.Group, scopeSet *pl
 	return true
 }
 
+// Explain explains the requirement with the occurrences of the label in the scope of the group.
+func (requirement *LabelRequirement) Explain(group *placement.Group, scopeSet *placement.ScopeSet,
+	entity *placement.Entity) *placement.Explanation {
+	occurrences, fulfilled := requirement.evaluate(group, scopeSet)
+	return placement.NewComparisonExplanation(requirement, fulfilled, float64(occurrences),
+		string(requirement.Comparison), float64(requirement.Occurrences))
+}
+
+// evaluate returns the occurrences of the label in the scope of the group, and whether they fulfill the requirement.
+func (requirement *LabelRequirement) evaluate(group *placement.Group, scopeSet *placement.ScopeSet) (int, bool) {
+	occurrences := scopeSet.LabelScope(group, requirement.Scope).CountWith(requirement.Label, requirement.Matcher)
+	fulfilled, err := requirement.Comparison.Compare(float64(occurrences), float64(requirement.Occurrences))
+	return occurrences, err == nil && fulfilled
+}
+
 func (requirement *LabelRequirement) String() string {
 	return fmt.Sprintf("requires that the occurrences of the label %v should be %v %v in scope %v",
 		requirement.Label, requirement.Comparison, requirement.Occurrences, requirement.Scope)
@@ -181,3 +181,21 @@ func TestLabelRequirement_Fulfilled_IsUnfulfilledForInvalidComparison(t *testing
 	)
 	assert.False(t, requirement.Passed(group, scopeSet, nil, nil))
 }
+
+func TestLabelRequirement_Fulfilled_CaseInsensitiveMatcher(t *testing.T) {
+	group := placement.NewGroup("group")
+	group.Labels, group.Relations = hostWithoutIssue()
+	group.Labels.Add(labels.NewLabel("dc", "PHX1"))
+	scopeSet := placement.NewScopeSet([]*placement.Group{group})
+
+	requirement := NewLabelRequirement(
+		nil,
+		labels.NewLabel("dc", "phx1"),
+		Equal,
+		1,
+	)
+	assert.False(t, requirement.Passed(group, scopeSet, nil, nil))
+
+	requirement.Matcher = labels.CaseInsensitive
+	assert.True(t, requirement.Passed(group, scopeSet, nil, nil))
+}
@@ -67,6 +67,15 @@ func (requirement *MetricRequirement) Passed(group *placement.Group, scopeSet *p
 	return true
 }
 
+// Explain explains the requirement with the value of the metric of the group.
+func (requirement *MetricRequirement) Explain(group *placement.Group, scopeSet *placement.ScopeSet,
+	entity *placement.Entity) *placement.Explanation {
+	value := group.Metrics.Get(requirement.MetricType)
+	fulfilled, err := requirement.Comparison.Compare(value, requirement.Value)
+	return placement.NewComparisonExplanation(requirement, err == nil && fulfilled, value,
+		string(requirement.Comparison), requirement.Value)
+}
+
 func (requirement *MetricRequirement) String() string {
 	return fmt.Sprintf("requires that %v should be %v %v %v", requirement.MetricType.Name,
 		requirement.Comparison, requirement.Value, requirement.MetricType.Unit)
@@ -66,6 +66,23 @@ func (requirement *OrRequirement) Passed(group *placement.Group, scopeSet *place
 	return result
 }
 
+// Explain explains the requirement by explaining all its sub-requirements.
+func (requirement *OrRequirement) Explain(group *placement.Group, scopeSet *placement.ScopeSet,
+	entity *placement.Entity) *placement.Explanation {
+	passed := false
+	explanations := make([]*placement.Explanation, 0, len(requirement.Requirements))
+	for _, subRequirement := range requirement.Requirements {
+		subExplanation := placement.Explain(subRequirement, group, scopeSet, entity)
+		if subExplanation.Passed {
+			passed = true
+		}
+		explanations = append(explanations, subExplanation)
+	}
+	explanation := placement.NewExplanation(requirement, passed)
+	explanation.Explanations = explanations
+	return explanation
+}
+
 func (requirement *OrRequirement) String() string {
 	subRequirements := make([]string, 0, len(requirement.Requirements))
 	for _, subRequirement := range requirement.Requirements {
@@ -46,6 +46,8 @@ type RelationRequirement struct {
 	Relation    *labels.Label
 	Comparison  Comparison
 	Occurrences int
+	// Matcher decides how names are compared when counting the occurrences, nil means case-sensitive.
+	Matcher *labels.Matcher
 }
 
 // NewRelationRequirement creates a new relation requirement.
@@ -61,9 +63,8 @@ func NewRelationRequirement(scope, relation *labels.Label, comparison Comparison
 // Passed checks if the requirement is fulfilled by the given group within the scope groups.
 func (requirement *RelationRequirement) Passed(group *placement.Group, scopeSet *placement.ScopeSet,
 	entity *placement.Entity, transcript *placement.Transcript) bool {
-	occurrences := scopeSet.RelationScope(group, requirement.Scope).Count(requirement.Relation)
-	fulfilled, err := requirement.Comparison.Compare(float64(occurrences), float64(requirement.Occurrences))
-	if err != nil || !fulfilled {
+	_, fulfilled := requirement.evaluate(group, scopeSet)
+	if !fulfilled {
 		transcript.IncFailed()
 		return false
 	}
@@ -71,6 +72,22 @@ func (requirement *RelationRequirement) Passed(group *placement.Group, scopeSet
 	return true
 }
 
+// Explain explains the requirement with the occurrences of the relation in the scope of the group.
+func (requirement *RelationRequirement) Explain(group *placement.Group, scopeSet *placement.ScopeSet,
+	entity *placement.Entity) *placement.Explanation {
+	occurrences, fulfilled := requirement.evaluate(group, scopeSet)
+	return placement.NewComparisonExplanation(requirement, fulfilled, float64(occurrences),
+		string(requirement.Comparison), float64(requirement.Occurrences))
+}
+
+// evaluate returns the occurrences of the relation in the scope of the group, and whether they fulfill the
+// requirement.
+func (requirement *RelationRequirement) evaluate(group *placement.Group, scopeSet *placement.ScopeSet) (int, bool) {
+	occurrences := scopeSet.RelationScope(group, requirement.Scope).CountWith(requirement.Relation, requirement.Matcher)
+	fulfilled, err := requirement.Comparison.Compare(float64(occurrences), float64(requirement.Occurrences))
+	return occurrences, err == nil && fulfilled
+}
+
 func (requirement *RelationRequirement) String() string {
 	return fmt.Sprintf("requires that the occurrences of the relation %v should be %v %v in scope %v",
 		requirement.Relation, requirement.Comparison, requirement.Occurrences, requirement.Scope)
@@ -0,0 +1,262 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mimir
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/labels"
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/metrics"
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/placement"
+)
+
+const (
+	// SnapshotPath is the path of the debug endpoint which returns a
+	// snapshot of the groups of the hosts seen by the placement engine.
+	SnapshotPath = "/debug/mimir/snapshot"
+
+	// _maxGroupSnapshotAge is the age after which the snapshot of a host
+	// which was not offered again is dropped.
+	_maxGroupSnapshotAge = 10 * time.Minute
+)
+
+// MetricSnapshot is the value of a metric of a group or an entity.
+type MetricSnapshot struct {
+	Name      string  `json:"name"`
+	Unit      string  `json:"unit"`
+	Inherited bool    `json:"inherited,omitempty"`
+	Value     float64 `json:"value"`
+}
+
+// EntitySnapshot is the state of an entity running on a group.
+type EntitySnapshot struct {
+	Name      string            `json:"name"`
+	Relations *labels.Bag       `json:"relations"`
+	Metrics   []*MetricSnapshot `json:"metrics,omitempty"`
+}
+
+// GroupSnapshot is the state of a group when it was last converted from
+// the offer of its host.
+type GroupSnapshot struct {
+	Time      time.Time         `json:"time"`
+	Name      string            `json:"name"`
+	Labels    *labels.Bag       `json:"labels"`
+	Relations *labels.Bag       `json:"relations"`
+	Metrics   []*MetricSnapshot `json:"metrics,omitempty"`
+	Entities  []*EntitySnapshot `json:"entities,omitempty"`
+}
+
+// Snapshot is the mimir view of the cluster, it can be loaded offline to
+// replay placement decisions.
+type Snapshot struct {
+	Time   time.Time        `json:"time"`
+	Groups []*GroupSnapshot `json:"groups"`
+}
+
+func copyBag(bag *labels.Bag) *labels.Bag {
+	result := labels.NewBagWithCapacity(bag.Size())
+	result.AddAll(bag)
+	return result
+}
+
+func exportMetrics(set *metrics.Set) []*MetricSnapshot {
+	var result []*MetricSnapshot
+	for _, metricType := range set.Types() {
+		result = append(result, &MetricSnapshot{
+			Name:      metricType.Name,
+			Unit:      metricType.Unit,
+			Inherited: metricType.Inherited,
+			Value:     set.Get(metricType),
+		})
+	}
+	return result
+}
+
+// strategyMetricType returns the metric type of the strategy with the
+// given name, the metrics of a snapshot are loaded with these types to keep
+// their derivations.
+func strategyMetricType(name string) (metrics.Type, bool) {
+	for _, known := range []metrics.Type{
+		CPUAvailable, CPUReserved, CPUFree,
+		GPUAvailable, GPUReserved, GPUFree,
+		MemoryAvailable, MemoryReserved, MemoryFree,
+		DiskAvailable, DiskReserved, DiskFree,
+		PortsAvailable, PortsReserved, PortsFree,
+	} {
+		if known.Name == name {
+			return known, true
+		}
+	}
+	return metrics.Type{}, false
+}
+
+func loadMetrics(snapshots []*MetricSnapshot) *metrics.Set {
+	set := metrics.NewSet()
+	for _, snapshot := range snapshots {
+		loadedType, known := strategyMetricType(snapshot.Name)
+		if !known {
+			loadedType = metrics.Type{
+				Name:      snapshot.Name,
+				Unit:      snapshot.Unit,
+				Inherited: snapshot.Inherited,
+			}
+		}
+		set.Set(loadedType, snapshot.Value)
+	}
+	return set
+}
+
+// ExportGroup returns a snapshot of the labels, relations, metrics and
+// entities of a group.
+func ExportGroup(group *placement.Group) *GroupSnapshot {
+	snapshot := &GroupSnapshot{
+		Time:      time.Now(),
+		Name:      group.Name,
+		Labels:    copyBag(group.Labels),
+		Relations: copyBag(group.Relations),
+		Metrics:   exportMetrics(group.Metrics),
+		Entities:  make([]*EntitySnapshot, 0, len(group.Entities)),
+	}
+	for _, entity := range group.Entities {
+		snapshot.Entities = append(snapshot.Entities, &EntitySnapshot{
+			Name:      entity.Name,
+			Relations: copyBag(entity.Relations),
+			Metrics:   exportMetrics(entity.Metrics),
+		})
+	}
+	sort.Slice(snapshot.Entities, func(i, j int) bool {
+		return snapshot.Entities[i].Name < snapshot.Entities[j].Name
+	})
+	return snapshot
+}
+
+// ExportGroups returns a snapshot of the groups.
+func ExportGroups(groups []*placement.Group) *Snapshot {
+	snapshot := &Snapshot{
+		Time:   time.Now(),
+		Groups: make([]*GroupSnapshot, 0, len(groups)),
+	}
+	for _, group := range groups {
+		snapshot.Groups = append(snapshot.Groups, ExportGroup(group))
+	}
+	return snapshot
+}
+
+// LoadGroup reconstructs a group from its snapshot. The entities only have
+// their relations and metrics, they are not meant to be placed again.
+func LoadGroup(snapshot *GroupSnapshot) *placement.Group {
+	group := placement.NewGroup(snapshot.Name)
+	if snapshot.Labels != nil {
+		group.Labels = snapshot.Labels
+	}
+	if snapshot.Relations != nil {
+		group.Relations = snapshot.Relations
+	}
+	group.Metrics = loadMetrics(snapshot.Metrics)
+	for _, entitySnapshot := range snapshot.Entities {
+		entity := placement.NewEntity(entitySnapshot.Name)
+		if entitySnapshot.Relations != nil {
+			entity.Relations = entitySnapshot.Relations
+		}
+		entity.Metrics = loadMetrics(entitySnapshot.Metrics)
+		group.Entities.Add(entity)
+	}
+	return group
+}
+
+// LoadGroups reconstructs the groups of a snapshot, e.g. to simulate the
+// placement of entities onto them.
+func LoadGroups(snapshot *Snapshot) []*placement.Group {
+	groups := make([]*placement.Group, 0, len(snapshot.Groups))
+	for _, groupSnapshot := range snapshot.Groups {
+		groups = append(groups, LoadGroup(groupSnapshot))
+	}
+	return groups
+}
+
+// GroupSnapshotTracker remembers the snapshot of the group of every host
+// when it was last converted from an offer.
+type GroupSnapshotTracker struct {
+	lock      sync.RWMutex
+	snapshots map[string]*GroupSnapshot
+	maxAge    time.Duration
+}
+
+// NewGroupSnapshotTracker creates a tracker of the snapshots of the groups
+// of the hosts converted by the strategy.
+func NewGroupSnapshotTracker() *GroupSnapshotTracker {
+	return newGroupSnapshotTracker(_maxGroupSnapshotAge)
+}
+
+func newGroupSnapshotTracker(maxAge time.Duration) *GroupSnapshotTracker {
+	return &GroupSnapshotTracker{
+		snapshots: map[string]*GroupSnapshot{},
+		maxAge:    maxAge,
+	}
+}
+
+// update records the snapshot of the group, it is taken before the group
+// is used for placement as the placer changes it concurrently.
+func (tracker *GroupSnapshotTracker) update(group *placement.Group) {
+	snapshot := ExportGroup(group)
+
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	tracker.snapshots[group.Name] = snapshot
+}
+
+// get returns a snapshot of the groups recorded within the maximum age,
+// dropping the older ones.
+func (tracker *GroupSnapshotTracker) get() *Snapshot {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	now := time.Now()
+	snapshot := &Snapshot{
+		Time:   now,
+		Groups: make([]*GroupSnapshot, 0, len(tracker.snapshots)),
+	}
+	for name, group := range tracker.snapshots {
+		if now.Sub(group.Time) > tracker.maxAge {
+			delete(tracker.snapshots, name)
+			continue
+		}
+		snapshot.Groups = append(snapshot.Groups, group)
+	}
+	sort.Slice(snapshot.Groups, func(i, j int) bool {
+		return snapshot.Groups[i].Name < snapshot.Groups[j].Name
+	})
+	return snapshot
+}
+
+// SnapshotHandler returns a handler for the snapshot debug endpoint of the
+// given tracker.
+func SnapshotHandler(tracker *GroupSnapshotTracker) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := json.Marshal(tracker.get())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(body)
+	}
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mimir
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/uber-go/tally"
+
+	"github.com/uber/peloton/pkg/placement/models"
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/labels"
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/metrics"
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/placement"
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/requirements"
+	"github.com/uber/peloton/pkg/placement/testutil"
+)
+
+func setupSnapshotGroups() []*placement.Group {
+	var groups []*placement.Group
+	for i := 0; i < 4; i++ {
+		group := placement.NewGroup(fmt.Sprintf("host%d", i))
+		group.Labels.Add(
+			labels.NewLabel("rack", fmt.Sprintf("r%d", i%2)),
+			labels.NewLabel("host", group.Name))
+		group.Metrics.Set(CPUAvailable, 800)
+		group.Metrics.Set(MemoryAvailable, 64*metrics.GiB)
+		for j := 0; j < i; j++ {
+			entity := placement.NewEntity(fmt.Sprintf("%s-task%d", group.Name, j))
+			entity.Relations.Add(labels.NewLabel("job", fmt.Sprintf("job%d", j)))
+			entity.Metrics.Set(CPUReserved, 200)
+			entity.Metrics.Set(MemoryReserved, 8*metrics.GiB)
+			group.Entities.Add(entity)
+		}
+		group.Update()
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+func setupSnapshotRequirements() []placement.Requirement {
+	rack := labels.NewLabel("rack", "*")
+	return []placement.Requirement{
+		requirements.NewLabelRequirement(
+			nil, labels.NewLabel("rack", "r1"), requirements.GreaterThanEqual, 1),
+		requirements.NewRelationRequirement(
+			nil, labels.NewLabel("job", "job1"), requirements.LessThanEqual, 0),
+		requirements.NewRelationRequirement(
+			rack, labels.NewLabel("job", "job0"), requirements.LessThanEqual, 1),
+		requirements.NewMetricRequirement(
+			CPUFree, requirements.GreaterThanEqual, 400),
+		requirements.NewNotLabelRequirement(
+			nil, labels.NewLabel("host", "host2")),
+		requirements.NewAndRequirement(
+			requirements.NewMetricRequirement(
+				MemoryReserved, requirements.LessThanEqual, 8*metrics.GiB),
+			requirements.NewOrRequirement(
+				requirements.NewLabelRequirement(
+					nil, labels.NewLabel("rack", "r0"), requirements.GreaterThanEqual, 1),
+				requirements.NewRelationRequirement(
+					nil, labels.NewLabel("job", "*"), requirements.GreaterThanEqual, 2),
+			),
+		),
+	}
+}
+
+// explainAll returns the explanation of every requirement on every group.
+func explainAll(
+	t *testing.T,
+	groups []*placement.Group,
+	reqs []placement.Requirement) []string {
+	scopeSet := placement.NewScopeSet(groups)
+	var result []string
+	for _, group := range groups {
+		for _, requirement := range reqs {
+			explanation := placement.Explain(
+				requirement, group, scopeSet, placement.NewEntity("entity"))
+			assert.Equal(t, explanation.Passed,
+				requirement.Passed(group, scopeSet, placement.NewEntity("entity"), nil))
+			body, err := json.Marshal(explanation)
+			require.NoError(t, err)
+			result = append(result, group.Name+" "+string(body))
+		}
+	}
+	return result
+}
+
+// TestSnapshotRoundTrip tests that the groups loaded from an exported
+// snapshot evaluate the requirements like the original groups
+func TestSnapshotRoundTrip(t *testing.T) {
+	groups := setupSnapshotGroups()
+	body, err := json.Marshal(ExportGroups(groups))
+	require.NoError(t, err)
+
+	snapshot := &Snapshot{}
+	require.NoError(t, json.Unmarshal(body, snapshot))
+	loaded := LoadGroups(snapshot)
+	require.Equal(t, len(groups), len(loaded))
+
+	for i, group := range groups {
+		assert.Equal(t, group.Name, loaded[i].Name)
+		assert.Equal(t, labelCounts(group.Labels), labelCounts(loaded[i].Labels))
+		assert.Equal(t, labelCounts(group.Relations), labelCounts(loaded[i].Relations))
+		assert.Equal(t, group.Metrics.Types(), loaded[i].Metrics.Types())
+		for _, metricType := range group.Metrics.Types() {
+			assert.Equal(t, group.Metrics.Get(metricType),
+				loaded[i].Metrics.Get(metricType))
+		}
+		assert.Equal(t, len(group.Entities), len(loaded[i].Entities))
+		for name, entity := range group.Entities {
+			loadedEntity := loaded[i].Entities[name]
+			require.NotNil(t, loadedEntity)
+			assert.Equal(t, labelCounts(entity.Relations),
+				labelCounts(loadedEntity.Relations))
+			assert.Equal(t, entity.Metrics.Get(CPUReserved),
+				loadedEntity.Metrics.Get(CPUReserved))
+		}
+	}
+
+	reqs := setupSnapshotRequirements()
+	assert.Equal(t, explainAll(t, groups, reqs), explainAll(t, loaded, reqs))
+
+	// the loaded groups can be updated from their entities like the originals
+	for _, group := range loaded {
+		group.Update()
+	}
+	assert.Equal(t, explainAll(t, groups, reqs), explainAll(t, loaded, reqs))
+}
+
+// TestGroupSnapshotTracker tests that the tracker keeps the last snapshot
+// of every host and drops the ones too old
+func TestGroupSnapshotTracker(t *testing.T) {
+	tracker := newGroupSnapshotTracker(time.Minute)
+	groups := setupSnapshotGroups()
+	for _, group := range groups {
+		tracker.update(group)
+	}
+	groups[0].Labels.Add(labels.NewLabel("rack", "r2"))
+	tracker.update(groups[0])
+	tracker.snapshots["host3"].Time = time.Now().Add(-2 * time.Minute)
+
+	snapshot := tracker.get()
+	require.Equal(t, 3, len(snapshot.Groups))
+	assert.Equal(t, "host0", snapshot.Groups[0].Name)
+	assert.Equal(t, 1, snapshot.Groups[0].Labels.Count(labels.NewLabel("rack", "r2")))
+	assert.Equal(t, "host2", snapshot.Groups[2].Name)
+	assert.Equal(t, 2, len(snapshot.Groups[2].Entities))
+	assert.Nil(t, tracker.snapshots["host3"])
+}
+
+func TestMimirPlaceRecordsSnapshot(t *testing.T) {
+	assignments := []*models.Assignment{
+		testutil.SetupAssignment(time.Now().Add(10*time.Second), 1),
+	}
+	offers := []*models.HostOffers{
+		testutil.SetupHostOffers(),
+	}
+	strategy := setupStrategy()
+
+	// no snapshot is recorded unless enabled
+	strategy.PlaceOnce(assignments, offers)
+	assert.Empty(t, strategy.groupSnapshots.get().Groups)
+
+	strategy.config.SnapshotGroups = true
+	offers = []*models.HostOffers{
+		testutil.SetupHostOffers(),
+	}
+	strategy.PlaceOnce(assignments, offers)
+
+	w := httptest.NewRecorder()
+	SnapshotHandler(strategy.groupSnapshots)(w, httptest.NewRequest("GET", SnapshotPath, nil))
+	snapshot := &Snapshot{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), snapshot))
+	require.Equal(t, 1, len(snapshot.Groups))
+	group := snapshot.Groups[0]
+	assert.Equal(t, "hostname", group.Name)
+	assert.NotEmpty(t, group.Metrics)
+
+	loaded := LoadGroup(group)
+	assert.Equal(t, loaded.Metrics.Get(CPUAvailable),
+		OfferToGroup(offers[0].GetOffer(), tally.NoopScope.Counter("")).Metrics.Get(CPUAvailable))
+}
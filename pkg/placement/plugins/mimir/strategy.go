@@ -18,6 +18,7 @@ import (
 	"math"
 
 	log "github.com/sirupsen/logrus"
+	"github.com/uber-go/tally"
 
 	"github.com/uber/peloton/.gen/peloton/api/v0/task"
 	"github.com/uber/peloton/.gen/peloton/private/hostmgr/hostsvc"
@@ -37,19 +38,42 @@ var _offersFactor = map[resmgr.TaskType]float64{
 	resmgr.TaskType_STATEFUL:  1.0,
 }
 
-// New will create a new strategy using Mimir-lib to do the placement logic.
-func New(placer algorithms.Placer, config *config.PlacementConfig) plugins.Strategy {
+// New will create a new strategy using Mimir-lib to do the placement logic, the labeler to make the relations of
+// the tasks, the label diff tracker to record the label changes of the hosts, the group snapshot tracker to
+// record the groups of the hosts, and the explanation tracker to record why the tasks failed placement.
+func New(
+	placer algorithms.Placer,
+	config *config.PlacementConfig,
+	labeler *TaskLabeler,
+	labelDiffs *LabelDiffTracker,
+	groupSnapshots *GroupSnapshotTracker,
+	explanations *ExplanationTracker,
+	scope tally.Scope) plugins.Strategy {
 	log.Info("Using Mimir placement strategy.")
 	return &mimir{
-		placer: placer,
-		config: config,
+		placer:            placer,
+		config:            config,
+		labeler:           labeler,
+		labelDiffs:        labelDiffs,
+		groupSnapshots:    groupSnapshots,
+		explanations:      explanations,
+		skippedAttributes: scope.Counter("skipped_attributes"),
 	}
 }
 
 // mimir is a placement strategy that uses the mimir library to decide on how to assign tasks to offers.
 type mimir struct {
-	placer algorithms.Placer
-	config *config.PlacementConfig
+	placer     algorithms.Placer
+	config     *config.PlacementConfig
+	labeler    *TaskLabeler
+	labelDiffs *LabelDiffTracker
+	// groupSnapshots records the groups when config.SnapshotGroups is set
+	groupSnapshots *GroupSnapshotTracker
+	// explanations records why the tasks failed placement when
+	// config.ExplainFailedPlacements is set
+	explanations *ExplanationTracker
+	// skippedAttributes counts the malformed Mesos attributes of the offers
+	skippedAttributes tally.Counter
 }
 
 func (mimir *mimir) convertAssignments(
@@ -63,7 +87,7 @@ func (mimir *mimir) convertAssignments(
 	for _, p := range pelotonAssignments {
 		data := p.GetTask().Data()
 		if data == nil {
-			entity := TaskToEntity(p.GetTask().GetTask(), false)
+			entity := TaskToEntity(p.GetTask().GetTask(), false, mimir.labeler)
 			p.GetTask().SetData(entity)
 			data = entity
 		}
@@ -82,14 +106,18 @@ func (mimir *mimir) convertHosts(hosts []*models.HostOffers) ([]*placement.Group
 	for _, host := range hosts {
 		data := host.Data()
 		if data == nil {
-			group := OfferToGroup(host.GetOffer())
+			group := OfferToGroup(host.GetOffer(), mimir.skippedAttributes)
+			mimir.labelDiffs.update(group.Name, group.Labels)
 			entities := placement.Entities{}
 			for _, task := range host.GetTasks() {
-				entity := TaskToEntity(task, true)
+				entity := TaskToEntity(task, true, mimir.labeler)
 				entities.Add(entity)
 			}
 			group.Entities = entities
 			group.Update()
+			if mimir.config.SnapshotGroups {
+				mimir.groupSnapshots.update(group)
+			}
 			host.SetData(group)
 			data = group
 		}
@@ -120,6 +148,8 @@ func (mimir *mimir) updateAssignments(
 func (mimir *mimir) PlaceOnce(
 	pelotonAssignments []*models.Assignment,
 	hosts []*models.HostOffers) {
+	// Labels created in earlier placement rounds should not be kept alive by the interner.
+	_labelInterner.Reset()
 	assignments, entitiesToAssignments := mimir.convertAssignments(pelotonAssignments)
 	groups, groupsToHosts := mimir.convertHosts(hosts)
 	scopeSet := placement.NewScopeSet(groups)
@@ -142,6 +172,9 @@ func (mimir *mimir) PlaceOnce(
 			log.WithField("entity", dumpEntity(assignment.Entity)).
 				WithField("transcript", assignment.Transcript.String()).
 				Debug("Did not place Mimir assignment")
+			if mimir.config.ExplainFailedPlacements {
+				mimir.explanations.explain(assignment.Entity, groups, scopeSet)
+			}
 		}
 	}
 
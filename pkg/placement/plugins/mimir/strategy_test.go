@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/uber-go/tally"
 	"github.com/uber/peloton/.gen/mesos/v1"
 	"github.com/uber/peloton/.gen/peloton/private/resmgr"
 	"github.com/uber/peloton/pkg/placement/config"
@@ -37,7 +38,15 @@ func setupStrategy() *mimir {
 		FetchOfferTasks:      false,
 	}
 	placer := algorithms.NewPlacer(1, 100)
-	return New(placer, config).(*mimir)
+	labeler, _ := NewTaskLabeler(nil)
+	return New(
+		placer,
+		config,
+		labeler,
+		NewLabelDiffTracker(tally.NoopScope),
+		NewGroupSnapshotTracker(),
+		NewExplanationTracker(),
+		tally.NoopScope).(*mimir)
 }
 
 func TestMimirPlace(t *testing.T) {
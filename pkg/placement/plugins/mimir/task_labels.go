@@ -0,0 +1,209 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mimir
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/.gen/peloton/private/resmgr"
+	"github.com/uber/peloton/pkg/common/util"
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/labels"
+)
+
+const (
+	// JobVariable is the label template variable bound to the job id
+	JobVariable = "job"
+
+	// InstanceVariable is the label template variable bound to the
+	// instance id
+	InstanceVariable = "instance"
+
+	// LabelKeyVariable is the label template variable bound to the key of
+	// each user label of the task. When it is a whole name of a template,
+	// it is replaced by the dot separated parts of the key.
+	LabelKeyVariable = "labelkey"
+
+	// LabelValueVariable is the label template variable bound to the value
+	// of each user label of the task
+	LabelValueVariable = "labelvalue"
+)
+
+// DefaultLabelTemplates are the label templates used when none are
+// configured, they make a relation of each user label of the task. The
+// task label constraints only match the relations of these templates.
+var DefaultLabelTemplates = [][]string{
+	{labelVariable(LabelKeyVariable), labelVariable(LabelValueVariable)},
+}
+
+// labelVariable returns the use of a variable in a label template
+func labelVariable(name string) string {
+	return labels.DefaultLeftDelimiter + name + labels.DefaultRightDelimiter
+}
+
+// TaskMetadata is the metadata of a task the label templates are bound to.
+// The resource pool of the task is not part of it, as it is not known by
+// the placement engine.
+type TaskMetadata struct {
+	JobID      string
+	InstanceID uint32
+	// Labels are the user labels of the task
+	Labels []*mesos_v1.Label
+}
+
+// NewTaskMetadata returns the metadata of a resource manager task.
+func NewTaskMetadata(task *resmgr.Task) *TaskMetadata {
+	metadata := &TaskMetadata{
+		JobID:  task.GetJobId().GetValue(),
+		Labels: task.GetLabels().GetLabels(),
+	}
+	if _, instanceID, err := util.ParseTaskID(task.GetId().GetValue()); err == nil {
+		metadata.InstanceID = instanceID
+	}
+	return metadata
+}
+
+// taskLabelTemplate is a label template of the task relations
+type taskLabelTemplate struct {
+	names []string
+	// variables are the variables used by the template
+	variables []string
+	// perLabel is true if the template is bound to each user label
+	perLabel bool
+}
+
+// TaskLabeler makes the relation labels of the tasks placed by the mimir
+// strategy, from label templates bound to the metadata of the tasks.
+type TaskLabeler struct {
+	templates []*taskLabelTemplate
+}
+
+// NewTaskLabeler creates a TaskLabeler from label templates, each a list
+// of label names using the variables of the task metadata. The default
+// label templates are used if there are none. An error is returned if a
+// template uses an unknown variable.
+func NewTaskLabeler(templates [][]string) (*TaskLabeler, error) {
+	if len(templates) == 0 {
+		templates = DefaultLabelTemplates
+	}
+	labeler := &TaskLabeler{}
+	for _, names := range templates {
+		if len(names) == 0 {
+			return nil, fmt.Errorf("empty label template")
+		}
+		template := &taskLabelTemplate{names: names}
+		for variable := range labels.NewTemplate(names...).Mappings() {
+			switch variable {
+			case JobVariable, InstanceVariable:
+			case LabelKeyVariable, LabelValueVariable:
+				template.perLabel = true
+			default:
+				return nil, fmt.Errorf("unknown variable %v in label template %v",
+					variable, strings.Join(names, "."))
+			}
+			template.variables = append(template.variables, variable)
+		}
+		labeler.templates = append(labeler.templates, template)
+	}
+	return labeler, nil
+}
+
+// AddRelations adds the relation labels of a task to relations.
+func (labeler *TaskLabeler) AddRelations(
+	metadata *TaskMetadata,
+	relations *labels.Bag) {
+	bindings := map[string]string{
+		JobVariable:      metadata.JobID,
+		InstanceVariable: strconv.FormatUint(uint64(metadata.InstanceID), 10),
+	}
+	for _, template := range labeler.templates {
+		if !template.perLabel {
+			if label := template.instantiate(bindings); label != nil {
+				relations.Add(label)
+			}
+			continue
+		}
+		for _, userLabel := range metadata.Labels {
+			bindings[LabelKeyVariable] = userLabel.GetKey()
+			bindings[LabelValueVariable] = userLabel.GetValue()
+			if label := template.instantiate(bindings); label != nil {
+				log.WithField("label", label.String()).
+					Debug("Adding relation label")
+				relations.Add(label)
+			}
+		}
+		delete(bindings, LabelKeyVariable)
+		delete(bindings, LabelValueVariable)
+	}
+}
+
+// instantiate returns the label of the template bound to the bindings, or
+// nil if the template uses the job and it is unknown. The variables are
+// only substituted in the names of the template, so that the values bound,
+// e.g. the user labels, are used as is even if they contain variables.
+func (template *taskLabelTemplate) instantiate(
+	bindings map[string]string) *labels.Label {
+	for _, variable := range template.variables {
+		if variable == JobVariable && bindings[variable] == "" {
+			return nil
+		}
+	}
+
+	keyVariable := labelVariable(LabelKeyVariable)
+	names := make([]string, 0, len(template.names))
+	for _, name := range template.names {
+		if template.perLabel && name == keyVariable {
+			// split the label key like the label constraints do
+			names = append(names,
+				strings.Split(bindings[LabelKeyVariable], ".")...)
+			continue
+		}
+		names = append(names, substitute(name, bindings))
+	}
+	return labels.NewLabel(names...)
+}
+
+// substitute replaces the variables used in a name of a label template by
+// their bindings, in a single pass so that the values are not substituted.
+func substitute(name string, bindings map[string]string) string {
+	var result strings.Builder
+	for {
+		start := strings.Index(name, labels.DefaultLeftDelimiter)
+		if start < 0 {
+			break
+		}
+		rest := name[start+len(labels.DefaultLeftDelimiter):]
+		end := strings.Index(rest, labels.DefaultRightDelimiter)
+		if end < 0 {
+			break
+		}
+		value, ok := bindings[rest[:end]]
+		if !ok {
+			// not a variable, its right delimiter may start one
+			result.WriteString(name[:start+len(labels.DefaultLeftDelimiter)])
+			name = rest
+			continue
+		}
+		result.WriteString(name[:start])
+		result.WriteString(value)
+		name = rest[end+len(labels.DefaultRightDelimiter):]
+	}
+	result.WriteString(name)
+	return result.String()
+}
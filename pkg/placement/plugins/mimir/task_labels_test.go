@@ -0,0 +1,171 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package mimir
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/uber/peloton/.gen/mesos/v1"
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/private/resmgr"
+	"github.com/uber/peloton/pkg/placement/plugins/mimir/lib/model/labels"
+)
+
+const _testJobID = "3c8a3c3e-71e3-49c5-9aed-2929823f595c"
+
+var _testLabelTemplates = [][]string{
+	{"$labelkey$", "$labelvalue$"},
+	{"peloton", "job", "$job$"},
+	{"peloton", "job", "$job$", "instance", "$instance$"},
+	{"peloton", "label", "$labelkey$=$labelvalue$"},
+}
+
+func makeMesosLabel(key, value string) *mesos_v1.Label {
+	return &mesos_v1.Label{Key: &key, Value: &value}
+}
+
+func makeTestTask(taskType resmgr.TaskType, labels ...*mesos_v1.Label) *resmgr.Task {
+	return &resmgr.Task{
+		Id:     &peloton.TaskID{Value: _testJobID + "-7"},
+		JobId:  &peloton.JobID{Value: _testJobID},
+		Type:   taskType,
+		Labels: &mesos_v1.Labels{Labels: labels},
+	}
+}
+
+func bagStrings(bag *labels.Bag) []string {
+	var result []string
+	for _, label := range bag.Labels() {
+		for i := 0; i < bag.Count(label); i++ {
+			result = append(result, label.String())
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// TestTaskLabelerBatchTask tests the relations of a batch task
+func TestTaskLabelerBatchTask(t *testing.T) {
+	labeler, err := NewTaskLabeler(_testLabelTemplates)
+	require.NoError(t, err)
+
+	task := makeTestTask(resmgr.TaskType_BATCH,
+		makeMesosLabel("team", "compute"))
+	relations := labels.NewBag()
+	labeler.AddRelations(NewTaskMetadata(task), relations)
+
+	assert.Equal(t, []string{
+		"peloton.job." + _testJobID,
+		"peloton.job." + _testJobID + ".instance.7",
+		"peloton.label.team=compute",
+		"team.compute",
+	}, bagStrings(relations))
+}
+
+// TestTaskLabelerStatelessTask tests the relations of a stateless task
+// with labels with dotted keys and duplicated labels
+func TestTaskLabelerStatelessTask(t *testing.T) {
+	labeler, err := NewTaskLabeler(_testLabelTemplates)
+	require.NoError(t, err)
+
+	task := makeTestTask(resmgr.TaskType_STATELESS,
+		makeMesosLabel("service.name", "web"),
+		makeMesosLabel("canary", ""),
+		makeMesosLabel("canary", ""))
+	relations := labels.NewBag()
+	labeler.AddRelations(NewTaskMetadata(task), relations)
+
+	assert.Equal(t, []string{
+		"canary.",
+		"canary.",
+		"peloton.job." + _testJobID,
+		"peloton.job." + _testJobID + ".instance.7",
+		"peloton.label.canary=",
+		"peloton.label.canary=",
+		"peloton.label.service.name=web",
+		"service.name.web",
+	}, bagStrings(relations))
+	// the dotted keys are split like the label constraints
+	assert.Equal(t, 1, relations.Count(makeLabel("service.name", "web")))
+	assert.Equal(t, 1, relations.Count(
+		labels.NewLabel("peloton", "label", "service.name=web")))
+}
+
+// TestTaskLabelerDefault tests that the default templates make a relation
+// of each user label
+func TestTaskLabelerDefault(t *testing.T) {
+	labeler, err := NewTaskLabeler(nil)
+	require.NoError(t, err)
+
+	task := makeTestTask(resmgr.TaskType_BATCH,
+		makeMesosLabel("a.b", "c"),
+		makeMesosLabel("d", "e.f"))
+	relations := labels.NewBag()
+	labeler.AddRelations(NewTaskMetadata(task), relations)
+
+	assert.Equal(t, 2, relations.Size())
+	assert.Equal(t, 1, relations.Count(makeLabel("a.b", "c")))
+	assert.Equal(t, 1, relations.Count(makeLabel("d", "e.f")))
+}
+
+// TestTaskLabelerUserLabelVariables tests that the variables used in the
+// user labels of a task are not substituted
+func TestTaskLabelerUserLabelVariables(t *testing.T) {
+	labeler, err := NewTaskLabeler(_testLabelTemplates)
+	require.NoError(t, err)
+
+	task := makeTestTask(resmgr.TaskType_BATCH,
+		makeMesosLabel("$job$", "$instance$"))
+	relations := labels.NewBag()
+	labeler.AddRelations(NewTaskMetadata(task), relations)
+
+	assert.Equal(t, 1, relations.Count(labels.NewLabel("$job$", "$instance$")))
+	assert.Equal(t, 1, relations.Count(
+		labels.NewLabel("peloton", "label", "$job$=$instance$")))
+}
+
+// TestTaskLabelerInvalidTemplates tests that the templates with unknown
+// variables are rejected, including the resource pool which is not known
+// by the placement engine
+func TestTaskLabelerInvalidTemplates(t *testing.T) {
+	_, err := NewTaskLabeler([][]string{{"peloton", "$host$"}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "host")
+
+	_, err = NewTaskLabeler([][]string{{"peloton", "respool", "$respool$"}})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "respool")
+
+	_, err = NewTaskLabeler([][]string{{}})
+	assert.Error(t, err)
+}
+
+func BenchmarkTaskLabelerAddRelations(b *testing.B) {
+	labeler, err := NewTaskLabeler(_testLabelTemplates)
+	require.NoError(b, err)
+	task := makeTestTask(resmgr.TaskType_STATELESS,
+		makeMesosLabel("service.name", "web"),
+		makeMesosLabel("team", "compute"),
+		makeMesosLabel("canary", "true"))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		labeler.AddRelations(NewTaskMetadata(task), labels.NewBag())
+	}
+}
@@ -63,6 +63,15 @@ const (
 
 const _eventStreamBufferSize = 1000
 
+// _pendingQueueTypes are the queues of a resource pool the pending gangs
+// are admitted from
+var _pendingQueueTypes = []respool.QueueType{
+	respool.PendingQueue,
+	respool.NonPreemptibleQueue,
+	respool.ControllerQueue,
+	respool.RevocableQueue,
+}
+
 // ServiceHandler implements peloton.private.resmgr.ResourceManagerService
 type ServiceHandler struct {
 	// lifecycle manager
@@ -898,24 +907,9 @@ func (h *ServiceHandler) GetPendingTasks(
 		"limit":      limit,
 	}).Info("GetPendingTasks called")
 
-	if respoolID == nil {
-		return &resmgrsvc.GetPendingTasksResponse{},
-			status.Errorf(codes.InvalidArgument,
-				"resource pool ID can't be nil")
-	}
-
-	node, err := h.resPoolTree.Get(&peloton.ResourcePoolID{
-		Value: respoolID.GetValue()})
+	node, err := h.getLeafResPool(respoolID)
 	if err != nil {
-		return &resmgrsvc.GetPendingTasksResponse{},
-			status.Errorf(codes.NotFound,
-				"resource pool ID not found:%s", respoolID)
-	}
-
-	if !node.IsLeaf() {
-		return &resmgrsvc.GetPendingTasksResponse{},
-			status.Errorf(codes.InvalidArgument,
-				"resource pool:%s is not a leaf node", respoolID)
+		return &resmgrsvc.GetPendingTasksResponse{}, err
 	}
 
 	// returns a list of pending resmgr.gangs for each queue
@@ -955,6 +949,138 @@ func (h *ServiceHandler) GetPendingTasks(
 	}, nil
 }
 
+// GetPendingGangs returns the pending gangs from a resource pool in the
+// order in which they are admitted from each queue, up to a max limit number
+// of gangs per queue, with the resources they ask for, the time since which
+// they are pending and whether they are preemptible.
+func (h *ServiceHandler) GetPendingGangs(
+	ctx context.Context,
+	req *resmgrsvc.GetPendingGangsRequest,
+) (*resmgrsvc.GetPendingGangsResponse, error) {
+
+	respoolID := req.GetRespoolID()
+	limit := req.GetLimit()
+
+	log.WithFields(log.Fields{
+		"respool_id": respoolID,
+		"limit":      limit,
+	}).Info("GetPendingGangs called")
+
+	node, err := h.getLeafResPool(respoolID)
+	if err != nil {
+		return &resmgrsvc.GetPendingGangsResponse{}, err
+	}
+
+	gangsInQueue, err := h.getPendingGangs(node, limit)
+	if err != nil {
+		return &resmgrsvc.GetPendingGangsResponse{},
+			status.Errorf(codes.Internal,
+				"failed to return pending gangs, err:%s", err.Error())
+	}
+
+	var pendingGangs []*resmgrsvc.GetPendingGangsResponse_PendingGang
+	for _, q := range _pendingQueueTypes {
+		for i, gang := range gangsInQueue[q] {
+			pendingGangs = append(pendingGangs,
+				h.fillPendingGang(q, uint32(i+1), gang))
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"respool_id":    respoolID,
+		"limit":         limit,
+		"pending_gangs": pendingGangs,
+	}).Debug("GetPendingGangs returned")
+
+	return &resmgrsvc.GetPendingGangsResponse{
+		PendingGangs: pendingGangs,
+	}, nil
+}
+
+// fillPendingGang returns the pending gang at the given position of a queue
+func (h *ServiceHandler) fillPendingGang(
+	q respool.QueueType,
+	position uint32,
+	gang *resmgrsvc.Gang) *resmgrsvc.GetPendingGangsResponse_PendingGang {
+	pendingGang := &resmgrsvc.GetPendingGangsResponse_PendingGang{
+		Queue:       q.String(),
+		Position:    position,
+		Resource:    &t.ResourceConfig{},
+		Preemptible: len(gang.GetTasks()) > 0,
+	}
+
+	var pendingSince time.Time
+	for _, task := range gang.GetTasks() {
+		pendingGang.TaskIDs = append(pendingGang.TaskIDs,
+			task.GetId().GetValue())
+
+		resource := task.GetResource()
+		pendingGang.Resource.CpuLimit += resource.GetCpuLimit()
+		pendingGang.Resource.MemLimitMb += resource.GetMemLimitMb()
+		pendingGang.Resource.DiskLimitMb += resource.GetDiskLimitMb()
+		pendingGang.Resource.GpuLimit += resource.GetGpuLimit()
+
+		pendingGang.Preemptible = pendingGang.Preemptible &&
+			task.GetPreemptible()
+
+		// the tasks of a gang are enqueued together, the oldest one is
+		// how long the gang has been waiting
+		rmTask := h.rmTracker.GetTask(task.GetId())
+		if rmTask == nil {
+			continue
+		}
+		updated := rmTask.GetCurrentState().LastUpdateTime
+		if pendingSince.IsZero() || updated.Before(pendingSince) {
+			pendingSince = updated
+		}
+	}
+	if !pendingSince.IsZero() {
+		pendingGang.PendingSince = pendingSince.Format(time.RFC3339Nano)
+	}
+	return pendingGang
+}
+
+// GetPreemptions returns the recent preemption decisions of the preemptor,
+// newest first.
+func (h *ServiceHandler) GetPreemptions(
+	ctx context.Context,
+	req *resmgrsvc.GetPreemptionsRequest,
+) (*resmgrsvc.GetPreemptionsResponse, error) {
+	log.WithField("request", req).Info("GetPreemptions called")
+
+	var since time.Time
+	if req.GetSinceSeconds() > 0 {
+		since = time.Now().Add(
+			-time.Duration(req.GetSinceSeconds()) * time.Second)
+	}
+
+	return &resmgrsvc.GetPreemptionsResponse{
+		Preemptions: h.preemptionQueue.GetPreemptions(since),
+	}, nil
+}
+
+// getLeafResPool returns the leaf resource pool with the given ID
+func (h *ServiceHandler) getLeafResPool(
+	respoolID *peloton.ResourcePoolID) (respool.ResPool, error) {
+	if respoolID == nil {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"resource pool ID can't be nil")
+	}
+
+	node, err := h.resPoolTree.Get(&peloton.ResourcePoolID{
+		Value: respoolID.GetValue()})
+	if err != nil {
+		return nil, status.Errorf(codes.NotFound,
+			"resource pool ID not found:%s", respoolID)
+	}
+
+	if !node.IsLeaf() {
+		return nil, status.Errorf(codes.InvalidArgument,
+			"resource pool:%s is not a leaf node", respoolID)
+	}
+	return node, nil
+}
+
 func (h *ServiceHandler) getPendingGangs(node respool.ResPool,
 	limit uint32) (map[respool.QueueType][]*resmgrsvc.Gang,
 	error) {
@@ -964,11 +1090,7 @@ func (h *ServiceHandler) getPendingGangs(node respool.ResPool,
 
 	gangsInQueue := make(map[respool.QueueType][]*resmgrsvc.Gang)
 
-	for _, q := range []respool.QueueType{
-		respool.PendingQueue,
-		respool.NonPreemptibleQueue,
-		respool.ControllerQueue,
-		respool.RevocableQueue} {
+	for _, q := range _pendingQueueTypes {
 		gangs, err = node.PeekGangs(q, limit)
 
 		if err != nil {
@@ -38,6 +38,7 @@ import (
 	"github.com/uber/peloton/pkg/common/statemachine"
 	rc "github.com/uber/peloton/pkg/resmgr/common"
 	"github.com/uber/peloton/pkg/resmgr/preemption/mocks"
+	r_queue "github.com/uber/peloton/pkg/resmgr/queue"
 	"github.com/uber/peloton/pkg/resmgr/respool"
 	rm "github.com/uber/peloton/pkg/resmgr/respool/mocks"
 	"github.com/uber/peloton/pkg/resmgr/scalar"
@@ -52,6 +53,8 @@ import (
 	"github.com/stretchr/testify/suite"
 	"github.com/uber-go/tally"
 	"go.uber.org/yarpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const (
@@ -1445,6 +1448,188 @@ func (s *HandlerTestSuite) TestGetPendingTasks() {
 	}
 }
 
+func (s *HandlerTestSuite) TestGetPendingGangs() {
+	defer s.rmTaskTracker.Clear()
+
+	respoolID := &peloton.ResourcePoolID{Value: "respool3"}
+	limit := uint32(2)
+
+	resp, err := respool.NewRespool(
+		tally.NoopScope,
+		"respool3",
+		nil,
+		&pb_respool.ResourcePoolConfig{
+			Policy: pb_respool.SchedulingPolicy_PriorityFIFO,
+		},
+		s.cfg,
+	)
+	s.NoError(err)
+
+	// only the first task of the first gang is tracked
+	before := time.Now()
+	tracked := &resmgr.Task{
+		Id:          &peloton.TaskID{Value: "job-1-0"},
+		Preemptible: true,
+		Resource:    &task.ResourceConfig{CpuLimit: 1, MemLimitMb: 100},
+	}
+	s.rmTaskTracker.AddTask(tracked, nil, resp,
+		tasktestutil.CreateTaskConfig())
+
+	mr := rm.NewMockResPool(s.ctrl)
+	mr.EXPECT().IsLeaf().Return(true)
+	mr.EXPECT().PeekGangs(respool.PendingQueue, limit).Return([]*resmgrsvc.Gang{
+		{
+			Tasks: []*resmgr.Task{
+				tracked,
+				{
+					Id:          &peloton.TaskID{Value: "job-1-1"},
+					Preemptible: true,
+					Resource: &task.ResourceConfig{
+						CpuLimit:    2,
+						MemLimitMb:  200,
+						DiskLimitMb: 10,
+						GpuLimit:    1,
+					},
+				},
+			},
+		},
+		{
+			Tasks: []*resmgr.Task{
+				{
+					Id:       &peloton.TaskID{Value: "job-2-0"},
+					Resource: &task.ResourceConfig{CpuLimit: 4},
+				},
+			},
+		},
+	}, nil)
+	mr.EXPECT().PeekGangs(respool.NonPreemptibleQueue, limit).
+		Return(nil, r_queue.ErrorQueueEmpty("empty"))
+	mr.EXPECT().PeekGangs(respool.ControllerQueue, limit).
+		Return(nil, r_queue.ErrorQueueEmpty("empty"))
+	mr.EXPECT().PeekGangs(respool.RevocableQueue, limit).Return([]*resmgrsvc.Gang{
+		{
+			Tasks: []*resmgr.Task{
+				{
+					Id:          &peloton.TaskID{Value: "job-3-0"},
+					Preemptible: true,
+				},
+			},
+		},
+	}, nil)
+
+	mt := rm.NewMockTree(s.ctrl)
+	mt.EXPECT().Get(respoolID).Return(mr, nil)
+
+	handler := &ServiceHandler{
+		metrics:     NewMetrics(tally.NoopScope),
+		resPoolTree: mt,
+		rmTracker:   s.rmTaskTracker,
+	}
+
+	res, err := handler.GetPendingGangs(s.context,
+		&resmgrsvc.GetPendingGangsRequest{
+			RespoolID: respoolID,
+			Limit:     limit,
+		})
+	s.NoError(err)
+
+	gangs := res.GetPendingGangs()
+	s.Len(gangs, 3)
+
+	s.Equal("pending", gangs[0].GetQueue())
+	s.Equal(uint32(1), gangs[0].GetPosition())
+	s.Equal([]string{"job-1-0", "job-1-1"}, gangs[0].GetTaskIDs())
+	s.Equal(&task.ResourceConfig{
+		CpuLimit:    3,
+		MemLimitMb:  300,
+		DiskLimitMb: 10,
+		GpuLimit:    1,
+	}, gangs[0].GetResource())
+	s.True(gangs[0].GetPreemptible())
+	pendingSince, err := time.Parse(time.RFC3339Nano, gangs[0].GetPendingSince())
+	s.NoError(err)
+	s.False(pendingSince.Before(before))
+
+	s.Equal("pending", gangs[1].GetQueue())
+	s.Equal(uint32(2), gangs[1].GetPosition())
+	s.False(gangs[1].GetPreemptible())
+	s.Empty(gangs[1].GetPendingSince())
+
+	s.Equal("revocable", gangs[2].GetQueue())
+	s.Equal(uint32(1), gangs[2].GetPosition())
+	s.Equal([]string{"job-3-0"}, gangs[2].GetTaskIDs())
+	s.True(gangs[2].GetPreemptible())
+}
+
+func (s *HandlerTestSuite) TestGetPendingGangsErrors() {
+	mt := rm.NewMockTree(s.ctrl)
+	handler := &ServiceHandler{
+		metrics:     NewMetrics(tally.NoopScope),
+		resPoolTree: mt,
+	}
+
+	_, err := handler.GetPendingGangs(s.context,
+		&resmgrsvc.GetPendingGangsRequest{})
+	s.Equal(codes.InvalidArgument, status.Code(err))
+
+	respoolID := &peloton.ResourcePoolID{Value: "respool1"}
+	mt.EXPECT().Get(respoolID).Return(nil, errors.New("not found"))
+	_, err = handler.GetPendingGangs(s.context,
+		&resmgrsvc.GetPendingGangsRequest{RespoolID: respoolID})
+	s.Equal(codes.NotFound, status.Code(err))
+
+	mr := rm.NewMockResPool(s.ctrl)
+	mr.EXPECT().IsLeaf().Return(false)
+	mt.EXPECT().Get(respoolID).Return(mr, nil)
+	_, err = handler.GetPendingGangs(s.context,
+		&resmgrsvc.GetPendingGangsRequest{RespoolID: respoolID})
+	s.Equal(codes.InvalidArgument, status.Code(err))
+
+	mr = rm.NewMockResPool(s.ctrl)
+	mr.EXPECT().IsLeaf().Return(true)
+	mr.EXPECT().PeekGangs(respool.PendingQueue, uint32(0)).
+		Return(nil, errors.New("peek failed"))
+	mt.EXPECT().Get(respoolID).Return(mr, nil)
+	_, err = handler.GetPendingGangs(s.context,
+		&resmgrsvc.GetPendingGangsRequest{RespoolID: respoolID})
+	s.Equal(codes.Internal, status.Code(err))
+}
+
+func (s *HandlerTestSuite) TestGetPreemptions() {
+	mockPreemptionQueue := mocks.NewMockQueue(s.ctrl)
+	s.handler.preemptionQueue = mockPreemptionQueue
+
+	preemptions := []*resmgrsvc.Preemption{
+		{
+			TaskID: &peloton.TaskID{Value: "job-1-0"},
+			State:  task.TaskState_RUNNING,
+			Reason: resmgr.PreemptionReason_PREEMPTION_REASON_REVOKE_RESOURCES,
+		},
+	}
+
+	// all the decisions are returned without a window
+	mockPreemptionQueue.EXPECT().
+		GetPreemptions(time.Time{}).
+		Return(preemptions)
+	res, err := s.handler.GetPreemptions(s.context,
+		&resmgrsvc.GetPreemptionsRequest{})
+	s.NoError(err)
+	s.Equal(preemptions, res.GetPreemptions())
+
+	before := time.Now()
+	mockPreemptionQueue.EXPECT().
+		GetPreemptions(gomock.Any()).
+		Do(func(since time.Time) {
+			s.False(since.Before(before.Add(-time.Hour)))
+			s.True(since.Before(time.Now().Add(-59 * time.Minute)))
+		}).
+		Return(nil)
+	res, err = s.handler.GetPreemptions(s.context,
+		&resmgrsvc.GetPreemptionsRequest{SinceSeconds: 3600})
+	s.NoError(err)
+	s.Empty(res.GetPreemptions())
+}
+
 // Test helpers
 // -----------------
 
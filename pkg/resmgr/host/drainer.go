@@ -139,9 +139,7 @@ func (d *Drainer) performDrainCycle() error {
 		return err
 	}
 
-	for _, host := range response.GetHostnames() {
-		d.drainingHosts.Add(host)
-	}
+	d.drainingHosts.AddAll(response.GetHostnames())
 	return d.drainHosts()
 }
 
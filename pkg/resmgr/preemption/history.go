@@ -0,0 +1,114 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preemption
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	peloton_task "github.com/uber/peloton/.gen/peloton/api/v0/task"
+	"github.com/uber/peloton/.gen/peloton/private/resmgr"
+	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
+
+	"github.com/uber/peloton/pkg/resmgr/task"
+)
+
+// represents the number of preemption decisions retained for debugging
+const maxPreemptionHistorySize = 1000
+
+// preemptionRecord is a preemption decision and when it was made
+type preemptionRecord struct {
+	time       time.Time
+	preemption *resmgrsvc.Preemption
+}
+
+// history retains the most recent preemption decisions of the preemptor,
+// the oldest decisions are dropped once it is full.
+type history struct {
+	sync.RWMutex
+
+	// records is a ring buffer of the decisions, next is the index the
+	// next decision is written at
+	records []preemptionRecord
+	next    int
+	full    bool
+
+	// now returns the current time, overridden in tests
+	now func() time.Time
+}
+
+// newHistory returns a history retaining up to size decisions
+func newHistory(size int) *history {
+	return &history{
+		records: make([]preemptionRecord, size),
+		now:     time.Now,
+	}
+}
+
+// add records the decision to preempt a task in the given state
+func (h *history) add(
+	t *task.RMTask,
+	state peloton_task.TaskState,
+	reason resmgr.PreemptionReason) {
+	preemption := &resmgrsvc.Preemption{
+		TaskID:   t.Task().GetId(),
+		State:    state,
+		Reason:   reason,
+		Resource: t.Task().GetResource(),
+	}
+	if pool := t.Respool(); pool != nil {
+		preemption.RespoolID = &peloton.ResourcePoolID{Value: pool.ID()}
+		preemption.RespoolPath = pool.GetPath()
+	}
+	h.record(preemption)
+}
+
+// record records a decision at the current time, dropping the oldest
+// decision if the history is full
+func (h *history) record(preemption *resmgrsvc.Preemption) {
+	now := h.now()
+	preemption.Time = now.Format(time.RFC3339Nano)
+
+	h.Lock()
+	defer h.Unlock()
+
+	h.records[h.next] = preemptionRecord{time: now, preemption: preemption}
+	h.next++
+	if h.next == len(h.records) {
+		h.next = 0
+		h.full = true
+	}
+}
+
+// since returns the decisions made since the given time, newest first
+func (h *history) since(since time.Time) []*resmgrsvc.Preemption {
+	h.RLock()
+	defer h.RUnlock()
+
+	count := h.next
+	if h.full {
+		count = len(h.records)
+	}
+	var preemptions []*resmgrsvc.Preemption
+	for i := 1; i <= count; i++ {
+		record := h.records[(h.next-i+len(h.records))%len(h.records)]
+		if record.time.Before(since) {
+			break
+		}
+		preemptions = append(preemptions, record.preemption)
+	}
+	return preemptions
+}
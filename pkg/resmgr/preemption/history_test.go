@@ -0,0 +1,63 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package preemption
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
+	"github.com/uber/peloton/.gen/peloton/private/resmgrsvc"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// taskIDs returns the IDs of the tasks of the preemptions
+func taskIDs(preemptions []*resmgrsvc.Preemption) []string {
+	var ids []string
+	for _, p := range preemptions {
+		ids = append(ids, p.GetTaskID().GetValue())
+	}
+	return ids
+}
+
+func TestHistory(t *testing.T) {
+	start := time.Date(2019, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := start
+	h := newHistory(3)
+	h.now = func() time.Time { return now }
+
+	assert.Empty(t, h.since(time.Time{}))
+
+	// a decision every minute, the oldest ones are dropped once full
+	for i := 0; i < 5; i++ {
+		h.record(&resmgrsvc.Preemption{
+			TaskID: &peloton.TaskID{Value: fmt.Sprintf("job-%d", i)},
+		})
+		if i == 1 {
+			assert.Equal(t, []string{"job-1", "job-0"}, taskIDs(h.since(time.Time{})))
+		}
+		now = now.Add(time.Minute)
+	}
+
+	preemptions := h.since(time.Time{})
+	assert.Equal(t, []string{"job-4", "job-3", "job-2"}, taskIDs(preemptions))
+	assert.Equal(t, "2019-03-01T12:04:00Z", preemptions[0].GetTime())
+
+	assert.Equal(t, []string{"job-4", "job-3"},
+		taskIDs(h.since(start.Add(3*time.Minute))))
+	assert.Empty(t, h.since(start.Add(5*time.Minute)))
+}
@@ -52,6 +52,9 @@ type Queue interface {
 	// preempt certain tasks outside of the preemptor.
 	// This can include cases where a host is being taken down for maintenance.
 	EnqueueTasks(tasks []*task.RMTask, event resmgr.PreemptionReason) error
+	// GetPreemptions returns the preemption decisions made since the given
+	// time, newest first. Only the most recent decisions are retained.
+	GetPreemptions(since time.Time) []*resmgrsvc.Preemption
 }
 
 // Preemptor preempts tasks based on either resource pool allocation or
@@ -79,6 +82,8 @@ type Preemptor struct {
 	taskSet stringset.StringSet // Set containing tasks which are currently in the PreemptionQueue
 	// The queue of tasks to be preempted
 	preemptionQueue queue.Queue
+	// The most recent preemption decisions
+	history *history
 
 	// the ranker ranks the tasks in the resource pool to be preempted
 	ranker ranker
@@ -112,6 +117,7 @@ func NewPreemptor(
 			reflect.TypeOf(resmgr.PreemptionCandidate{}),
 			maxPreemptionQueueSize,
 		),
+		history: newHistory(maxPreemptionHistorySize),
 		ranker:  newStatePriorityRuntimeRanker(tracker),
 		tracker: tracker,
 		scope:   parent.SubScope("preemption"),
@@ -204,6 +210,12 @@ func (p *Preemptor) EnqueueTasks(
 	return p.processTasks(tasks, reason)
 }
 
+// GetPreemptions returns the preemption decisions made since the given time,
+// newest first
+func (p *Preemptor) GetPreemptions(since time.Time) []*resmgrsvc.Preemption {
+	return p.history.since(since)
+}
+
 func (p *Preemptor) preemptOnce() error {
 	// collect resource allocation from all resource pools
 	p.updateResourcePoolsState()
@@ -372,6 +384,7 @@ func (p *Preemptor) processRunningTask(
 	// There could be cases where preemption is taking longer than usual
 	// so we don't want to add the same task in the next preemption cycle.
 	p.taskSet.Add(preemptionCandidate.GetId().GetValue())
+	p.history.add(t, peloton_task.TaskState_RUNNING, reason)
 
 	// ToDo: ResourcesFreed are speculated to get free if preemption
 	// runs uninterrupted. Fix it to track that running tasks reached
@@ -403,6 +416,8 @@ func (p *Preemptor) processNonRunningTask(
 	reason resmgr.PreemptionReason) error {
 	t := rmTask.Task()
 	resPool := rmTask.Respool()
+	// the state the task is evicted from, for the preemption history
+	state := rmTask.GetCurrentState().State
 
 	log.WithFields(log.Fields{
 		"respool_id": resPool.ID(),
@@ -438,6 +453,8 @@ func (p *Preemptor) processNonRunningTask(
 			"resource pool")
 	}
 
+	p.history.add(rmTask, state, reason)
+
 	resourcesFreed := scalar.ConvertToResmgrResource(rmTask.Task().Resource)
 	if rmTask.Task().GetRevocable() {
 		p.metrics(resPool).RevocableNonRunningTasksToPreempt.Inc(1)
@@ -89,6 +89,7 @@ func (suite *PreemptorTestSuite) SetupTest() {
 			reflect.TypeOf(resmgr.PreemptionCandidate{}),
 			10000,
 		),
+		history:      newHistory(maxPreemptionHistorySize),
 		taskSet:      stringset.New(),
 		respoolState: make(map[string]int),
 		ranker:       newStatePriorityRuntimeRanker(rm_task.GetTracker()),
@@ -349,6 +350,19 @@ func (suite *PreemptorTestSuite) TestProcessResourcePoolForRunningTasks() {
 	suite.Equal(numRunningTasks, suite.preemptor.preemptionQueue.Length())
 
 	suite.Equal(0, suite.preemptor.respoolState["respool-1"])
+
+	// the decisions are retained, newest first
+	preemptions := suite.preemptor.GetPreemptions(time.Time{})
+	suite.Len(preemptions, numRunningTasks)
+	for i, p := range preemptions {
+		suite.Equal(tasks[numRunningTasks-1-i].Id, p.GetTaskID())
+		suite.Equal(task.TaskState_RUNNING, p.GetState())
+		suite.Equal(resmgr.PreemptionReason_PREEMPTION_REASON_REVOKE_RESOURCES,
+			p.GetReason())
+		suite.Equal("respool-1", p.GetRespoolID().GetValue())
+		suite.Equal("/respool-1", p.GetRespoolPath())
+		suite.Equal(_taskResources, p.GetResource())
+	}
 }
 
 func (suite *PreemptorTestSuite) TestProcessResourcePoolForReadyTasks() {
@@ -412,6 +426,13 @@ func (suite *PreemptorTestSuite) TestProcessResourcePoolForReadyTasks() {
 	// Check allocation <= entitlement after
 	suite.True(allocation.GetByType(scalar.NonSlackAllocation).
 		LessThanOrEqual(mockResPool.GetNonSlackEntitlement()))
+
+	// the state the tasks were evicted from is retained
+	preemptions := suite.preemptor.GetPreemptions(time.Time{})
+	suite.Len(preemptions, numReadyTasks)
+	for _, p := range preemptions {
+		suite.Equal(task.TaskState_READY, p.GetState())
+	}
 }
 
 func (suite *PreemptorTestSuite) TestProcessResourcePoolForPlacingTasks() {
@@ -0,0 +1,266 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/uber-go/tally"
+)
+
+// frameworkInfoCacheKey is the key of a cached field of the framework info
+// of a framework name
+type frameworkInfoCacheKey struct {
+	frameworkName string
+	field         string
+}
+
+// frameworkInfoCacheValue is a cached field of the framework info, with the
+// time it was set for the mesos stream id
+type frameworkInfoCacheValue struct {
+	value   string
+	setTime time.Time
+}
+
+// frameworkInfoCacheEntry is a cached field which expires at expiry
+type frameworkInfoCacheEntry struct {
+	frameworkInfoCacheValue
+	expiry time.Time
+}
+
+// frameworkInfoCacheLoad is a read of a field from the store shared by the
+// concurrent reads of the field which missed the cache
+type frameworkInfoCacheLoad struct {
+	done  chan struct{}
+	value frameworkInfoCacheValue
+	err   error
+}
+
+// CachedFrameworkInfoStore is a FrameworkInfoStore which caches the
+// framework id and the mesos stream id read from another FrameworkInfoStore
+// for a TTL. The cache is updated when they are set through it, and the
+// concurrent reads of a field which is not cached are a single read of the
+// store. Errors and the history are not cached.
+type CachedFrameworkInfoStore struct {
+	sync.Mutex
+
+	store FrameworkInfoStore
+	ttl   time.Duration
+	// now returns the current time, overridden by tests
+	now func() time.Time
+
+	entries map[frameworkInfoCacheKey]*frameworkInfoCacheEntry
+	loads   map[frameworkInfoCacheKey]*frameworkInfoCacheLoad
+
+	hits   map[string]tally.Counter
+	misses map[string]tally.Counter
+}
+
+// NewCachedFrameworkInfoStore returns a FrameworkInfoStore caching the
+// reads of the store for the ttl, with the hit and miss counters of every
+// field in the scope
+func NewCachedFrameworkInfoStore(
+	store FrameworkInfoStore,
+	ttl time.Duration,
+	scope tally.Scope) *CachedFrameworkInfoStore {
+	s := &CachedFrameworkInfoStore{
+		store:   store,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[frameworkInfoCacheKey]*frameworkInfoCacheEntry),
+		loads:   make(map[frameworkInfoCacheKey]*frameworkInfoCacheLoad),
+		hits:    make(map[string]tally.Counter),
+		misses:  make(map[string]tally.Counter),
+	}
+	for _, field := range []string{
+		FrameworkInfoFieldFrameworkID,
+		FrameworkInfoFieldMesosStreamID,
+	} {
+		fieldScope := scope.Tagged(map[string]string{"field": field})
+		s.hits[field] = fieldScope.Counter("cache_hit")
+		s.misses[field] = fieldScope.Counter("cache_miss")
+	}
+	return s
+}
+
+// Invalidate drops the cached framework info of a framework name, so that
+// the next reads go to the store
+func (s *CachedFrameworkInfoStore) Invalidate(frameworkName string) {
+	s.Lock()
+	defer s.Unlock()
+	s.drop(frameworkInfoCacheKey{frameworkName, FrameworkInfoFieldFrameworkID})
+	s.drop(frameworkInfoCacheKey{frameworkName, FrameworkInfoFieldMesosStreamID})
+}
+
+// SetMesosStreamID stores the mesos stream id for a framework name
+func (s *CachedFrameworkInfoStore) SetMesosStreamID(
+	ctx context.Context,
+	frameworkName string,
+	mesosStreamID string) error {
+	key := frameworkInfoCacheKey{frameworkName, FrameworkInfoFieldMesosStreamID}
+	if err := s.store.SetMesosStreamID(ctx, frameworkName, mesosStreamID); err != nil {
+		s.invalidate(key)
+		return err
+	}
+	s.put(key, frameworkInfoCacheValue{mesosStreamID, s.now().UTC()})
+	return nil
+}
+
+// SetMesosStreamIDIfCurrent stores the mesos stream id for a framework name
+// only if its current mesos stream id is expectedOldID. The cached mesos
+// stream id is dropped if it is not, as it is outdated.
+func (s *CachedFrameworkInfoStore) SetMesosStreamIDIfCurrent(
+	ctx context.Context,
+	frameworkName string,
+	mesosStreamID string,
+	expectedOldID string) error {
+	key := frameworkInfoCacheKey{frameworkName, FrameworkInfoFieldMesosStreamID}
+	err := s.store.SetMesosStreamIDIfCurrent(
+		ctx, frameworkName, mesosStreamID, expectedOldID)
+	if err != nil {
+		s.invalidate(key)
+		return err
+	}
+	s.put(key, frameworkInfoCacheValue{mesosStreamID, s.now().UTC()})
+	return nil
+}
+
+// SetMesosFrameworkID stores the mesos framework id for a framework name
+func (s *CachedFrameworkInfoStore) SetMesosFrameworkID(
+	ctx context.Context,
+	frameworkName string,
+	frameworkID string) error {
+	key := frameworkInfoCacheKey{frameworkName, FrameworkInfoFieldFrameworkID}
+	if err := s.store.SetMesosFrameworkID(ctx, frameworkName, frameworkID); err != nil {
+		s.invalidate(key)
+		return err
+	}
+	s.put(key, frameworkInfoCacheValue{value: frameworkID})
+	return nil
+}
+
+// GetMesosStreamID reads the mesos stream id for a framework name
+func (s *CachedFrameworkInfoStore) GetMesosStreamID(
+	ctx context.Context,
+	frameworkName string) (string, error) {
+	streamID, _, err := s.GetMesosStreamIDWithTime(ctx, frameworkName)
+	return streamID, err
+}
+
+// GetMesosStreamIDWithTime reads the mesos stream id for a framework name
+// and when it was set
+func (s *CachedFrameworkInfoStore) GetMesosStreamIDWithTime(
+	ctx context.Context,
+	frameworkName string) (string, time.Time, error) {
+	value, err := s.get(
+		frameworkInfoCacheKey{frameworkName, FrameworkInfoFieldMesosStreamID},
+		func() (frameworkInfoCacheValue, error) {
+			streamID, setTime, err := s.store.GetMesosStreamIDWithTime(
+				ctx, frameworkName)
+			return frameworkInfoCacheValue{streamID, setTime}, err
+		})
+	return value.value, value.setTime, err
+}
+
+// GetFrameworkID reads the framework id for a framework name
+func (s *CachedFrameworkInfoStore) GetFrameworkID(
+	ctx context.Context,
+	frameworkName string) (string, error) {
+	value, err := s.get(
+		frameworkInfoCacheKey{frameworkName, FrameworkInfoFieldFrameworkID},
+		func() (frameworkInfoCacheValue, error) {
+			frameworkID, err := s.store.GetFrameworkID(ctx, frameworkName)
+			return frameworkInfoCacheValue{value: frameworkID}, err
+		})
+	return value.value, err
+}
+
+// GetFrameworkInfoHistory returns up to limit of the most recent changes of
+// the framework info of a framework name, the most recent first
+func (s *CachedFrameworkInfoStore) GetFrameworkInfoHistory(
+	ctx context.Context,
+	frameworkName string,
+	limit int) ([]*FrameworkInfoChange, error) {
+	return s.store.GetFrameworkInfoHistory(ctx, frameworkName, limit)
+}
+
+// get returns a cached field, or reads it with load if it is not cached or
+// expired. Concurrent reads of a field wait for the first one to load it.
+func (s *CachedFrameworkInfoStore) get(
+	key frameworkInfoCacheKey,
+	load func() (frameworkInfoCacheValue, error),
+) (frameworkInfoCacheValue, error) {
+	s.Lock()
+	if entry, ok := s.entries[key]; ok && s.now().Before(entry.expiry) {
+		s.Unlock()
+		s.hits[key.field].Inc(1)
+		return entry.frameworkInfoCacheValue, nil
+	}
+	s.misses[key.field].Inc(1)
+	if l, ok := s.loads[key]; ok {
+		s.Unlock()
+		<-l.done
+		return l.value, l.err
+	}
+	l := &frameworkInfoCacheLoad{done: make(chan struct{})}
+	s.loads[key] = l
+	s.Unlock()
+
+	l.value, l.err = load()
+
+	s.Lock()
+	// the field was set or invalidated during the load if the load is no
+	// longer the current one, the value loaded may then be outdated
+	if s.loads[key] == l {
+		delete(s.loads, key)
+		if l.err == nil {
+			s.entries[key] = &frameworkInfoCacheEntry{
+				frameworkInfoCacheValue: l.value,
+				expiry:                  s.now().Add(s.ttl),
+			}
+		}
+	}
+	s.Unlock()
+	close(l.done)
+	return l.value, l.err
+}
+
+// put caches a field set through the store
+func (s *CachedFrameworkInfoStore) put(
+	key frameworkInfoCacheKey,
+	value frameworkInfoCacheValue) {
+	s.Lock()
+	defer s.Unlock()
+	delete(s.loads, key)
+	s.entries[key] = &frameworkInfoCacheEntry{
+		frameworkInfoCacheValue: value,
+		expiry:                  s.now().Add(s.ttl),
+	}
+}
+
+// invalidate drops a cached field
+func (s *CachedFrameworkInfoStore) invalidate(key frameworkInfoCacheKey) {
+	s.Lock()
+	defer s.Unlock()
+	s.drop(key)
+}
+
+// drop drops a cached field and its current load, the lock must be held
+func (s *CachedFrameworkInfoStore) drop(key frameworkInfoCacheKey) {
+	delete(s.entries, key)
+	delete(s.loads, key)
+}
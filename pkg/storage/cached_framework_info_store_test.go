@@ -0,0 +1,249 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+const _testCacheTTL = time.Minute
+
+// countingStore is a FrameworkInfoStore which counts the reads of the
+// framework id, and blocks them while blocked is set
+type countingStore struct {
+	*InMemoryFrameworkInfoStore
+	reads   int64
+	blocked chan struct{}
+}
+
+func (s *countingStore) GetFrameworkID(
+	ctx context.Context,
+	frameworkName string) (string, error) {
+	atomic.AddInt64(&s.reads, 1)
+	if s.blocked != nil {
+		<-s.blocked
+	}
+	return s.InMemoryFrameworkInfoStore.GetFrameworkID(ctx, frameworkName)
+}
+
+type cachedFrameworkInfoStoreTestSuite struct {
+	suite.Suite
+	scope   tally.TestScope
+	backend *countingStore
+	store   *CachedFrameworkInfoStore
+	now     time.Time
+}
+
+func (suite *cachedFrameworkInfoStoreTestSuite) SetupTest() {
+	suite.scope = tally.NewTestScope("", nil)
+	suite.backend = &countingStore{
+		InMemoryFrameworkInfoStore: NewInMemoryFrameworkInfoStore(),
+	}
+	suite.store = NewCachedFrameworkInfoStore(
+		suite.backend, _testCacheTTL, suite.scope)
+	suite.now = time.Now()
+	suite.store.now = func() time.Time { return suite.now }
+}
+
+func TestCachedFrameworkInfoStore(t *testing.T) {
+	suite.Run(t, new(cachedFrameworkInfoStoreTestSuite))
+}
+
+// counter returns the value of a cache counter of a field
+func (suite *cachedFrameworkInfoStoreTestSuite) counter(
+	name string,
+	field string) int64 {
+	counter, ok := suite.scope.Snapshot().Counters()[name+"+field="+field]
+	if !ok {
+		return 0
+	}
+	return counter.Value()
+}
+
+// reads returns the number of reads of the framework id from the backend
+func (suite *cachedFrameworkInfoStoreTestSuite) reads() int64 {
+	return atomic.LoadInt64(&suite.backend.reads)
+}
+
+// TestTTL tests that the framework id is read from the backend once per TTL
+func (suite *cachedFrameworkInfoStoreTestSuite) TestTTL() {
+	ctx := context.Background()
+	suite.NoError(suite.backend.SetMesosFrameworkID(
+		ctx, _testFrameworkName, "framework-id"))
+
+	for i := 0; i < 3; i++ {
+		id, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+		suite.NoError(err)
+		suite.Equal("framework-id", id)
+	}
+	suite.Equal(int64(1), suite.reads())
+	suite.Equal(int64(2), suite.counter("cache_hit", FrameworkInfoFieldFrameworkID))
+	suite.Equal(int64(1), suite.counter("cache_miss", FrameworkInfoFieldFrameworkID))
+
+	// the cached framework id expires
+	suite.NoError(suite.backend.SetMesosFrameworkID(
+		ctx, _testFrameworkName, "other-framework-id"))
+	suite.now = suite.now.Add(_testCacheTTL)
+	id, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("other-framework-id", id)
+	suite.Equal(int64(2), suite.reads())
+}
+
+// TestErrorsNotCached tests that failed reads are not cached
+func (suite *cachedFrameworkInfoStoreTestSuite) TestErrorsNotCached() {
+	ctx := context.Background()
+	_, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.Error(err)
+
+	suite.NoError(suite.backend.SetMesosFrameworkID(
+		ctx, _testFrameworkName, "framework-id"))
+	id, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("framework-id", id)
+	suite.Equal(int64(2), suite.reads())
+}
+
+// TestSet tests that the fields set through the store are cached
+func (suite *cachedFrameworkInfoStoreTestSuite) TestSet() {
+	ctx := context.Background()
+	suite.NoError(suite.store.SetMesosFrameworkID(
+		ctx, _testFrameworkName, "framework-id"))
+	id, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("framework-id", id)
+	suite.Equal(int64(0), suite.reads())
+
+	suite.NoError(suite.store.SetMesosStreamID(
+		ctx, _testFrameworkName, "stream-id"))
+	streamID, setTime, err := suite.store.GetMesosStreamIDWithTime(
+		ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("stream-id", streamID)
+	suite.True(setTime.Equal(suite.now))
+	suite.Equal(int64(1), suite.counter("cache_hit", FrameworkInfoFieldMesosStreamID))
+
+	// a failed set drops the cached field
+	suite.backend.SetError(errors.New("unavailable"))
+	suite.Error(suite.store.SetMesosStreamID(
+		ctx, _testFrameworkName, "other-stream-id"))
+	_, err = suite.store.GetMesosStreamID(ctx, _testFrameworkName)
+	suite.EqualError(err, "unavailable")
+}
+
+// TestSetMesosStreamIDIfCurrentConflict tests that the cached mesos stream
+// id is dropped when it is outdated
+func (suite *cachedFrameworkInfoStoreTestSuite) TestSetMesosStreamIDIfCurrentConflict() {
+	ctx := context.Background()
+	suite.NoError(suite.store.SetMesosStreamID(
+		ctx, _testFrameworkName, "stream-id"))
+	suite.NoError(suite.backend.SetMesosStreamID(
+		ctx, _testFrameworkName, "other-stream-id"))
+
+	suite.True(IsMesosStreamIDConflict(suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "new-stream-id", "stream-id")))
+	streamID, err := suite.store.GetMesosStreamID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("other-stream-id", streamID)
+
+	suite.NoError(suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "new-stream-id", "other-stream-id"))
+	streamID, err = suite.store.GetMesosStreamID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("new-stream-id", streamID)
+}
+
+// TestInvalidate tests that an invalidated framework name is read from the
+// backend again
+func (suite *cachedFrameworkInfoStoreTestSuite) TestInvalidate() {
+	ctx := context.Background()
+	suite.NoError(suite.store.SetMesosFrameworkID(
+		ctx, _testFrameworkName, "framework-id"))
+	suite.NoError(suite.backend.SetMesosFrameworkID(
+		ctx, _testFrameworkName, "other-framework-id"))
+
+	id, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("framework-id", id)
+
+	suite.store.Invalidate(_testFrameworkName)
+	id, err = suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("other-framework-id", id)
+	suite.Equal(int64(1), suite.reads())
+}
+
+// TestSingleFlight tests that concurrent reads which miss the cache are a
+// single read of the backend
+func (suite *cachedFrameworkInfoStoreTestSuite) TestSingleFlight() {
+	ctx := context.Background()
+	suite.NoError(suite.backend.SetMesosFrameworkID(
+		ctx, _testFrameworkName, "framework-id"))
+	suite.backend.blocked = make(chan struct{})
+
+	const readers = 10
+	var wg sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			id, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+			suite.NoError(err)
+			suite.Equal("framework-id", id)
+		}()
+	}
+	// release the read once all readers missed the cache
+	for suite.counter("cache_miss", FrameworkInfoFieldFrameworkID) < readers {
+		time.Sleep(time.Millisecond)
+	}
+	close(suite.backend.blocked)
+	wg.Wait()
+
+	suite.Equal(int64(1), suite.reads())
+}
+
+// TestSetDuringLoad tests that a value loaded while the field is set is not
+// cached, as it may be outdated
+func (suite *cachedFrameworkInfoStoreTestSuite) TestSetDuringLoad() {
+	ctx := context.Background()
+	suite.NoError(suite.backend.SetMesosFrameworkID(
+		ctx, _testFrameworkName, "framework-id"))
+	suite.backend.blocked = make(chan struct{})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	}()
+	for suite.reads() < 1 {
+		time.Sleep(time.Millisecond)
+	}
+	suite.NoError(suite.store.SetMesosFrameworkID(
+		ctx, _testFrameworkName, "other-framework-id"))
+	close(suite.backend.blocked)
+	<-done
+
+	id, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("other-framework-id", id)
+}
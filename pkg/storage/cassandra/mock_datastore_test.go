@@ -18,6 +18,7 @@ package cassandra
 
 import (
 	"context"
+	"strings"
 	"testing"
 	"time"
 
@@ -31,7 +32,9 @@ import (
 	datastore "github.com/uber/peloton/pkg/storage/cassandra/api"
 	datastoremocks "github.com/uber/peloton/pkg/storage/cassandra/api/mocks"
 	datastoreimpl "github.com/uber/peloton/pkg/storage/cassandra/impl"
+	qb "github.com/uber/peloton/pkg/storage/querybuilder"
 
+	"github.com/gocql/gocql"
 	"github.com/golang/mock/gomock"
 	"github.com/pborman/uuid"
 	"github.com/pkg/errors"
@@ -376,3 +379,116 @@ func (suite *MockDatastoreTestSuite) TestDataStoreFailureGetTaskConfigs() {
 	_, _, err = suite.store.GetTaskConfigs(ctx, suite.testJobID, []uint32{0}, 0)
 	suite.Error(err)
 }
+
+// fakeResultSet is a result set of the rows of a read, or of a
+// lightweight transaction
+type fakeResultSet struct {
+	datastore.ResultSet
+	applied bool
+	rows    []map[string]interface{}
+}
+
+func (r *fakeResultSet) Applied() bool { return r.applied }
+
+func (r *fakeResultSet) All(ctx context.Context) ([]map[string]interface{}, error) {
+	return r.rows, nil
+}
+
+func (r *fakeResultSet) Close() error { return nil }
+
+// newFrameworkStore returns a store whose datastore runs the statements
+// with execute, and the statements it ran
+func (suite *MockDatastoreTestSuite) newFrameworkStore(
+	ctrl *gomock.Controller,
+	execute func(stmt string, isCAS bool) datastore.ResultSet,
+) (*Store, *[]string) {
+	mockedDataStore := datastoremocks.NewMockDataStore(ctrl)
+	mockedDataStore.EXPECT().NewQuery().
+		Return(&datastoreimpl.QueryBuilder{}).AnyTimes()
+
+	var stmts []string
+	mockedDataStore.EXPECT().Execute(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(
+			_ context.Context,
+			stmt datastore.Statement) (datastore.ResultSet, error) {
+			sql, _, err := stmt.ToSQL()
+			suite.NoError(err)
+			stmts = append(stmts, sql)
+			return execute(sql, stmt.IsCAS()), nil
+		}).AnyTimes()
+
+	return &Store{
+		DataStore: mockedDataStore,
+		metrics:   storage.NewMetrics(testScope.SubScope("storage")),
+		Conf:      &Config{},
+	}, &stmts
+}
+
+// TestSetMesosStreamIDIfCurrent tests that the mesos stream id is replaced
+// using a lightweight transaction, and that a transaction which is not
+// applied is a conflict
+func (suite *MockDatastoreTestSuite) TestSetMesosStreamIDIfCurrent() {
+	ctrl := gomock.NewController(suite.T())
+	defer ctrl.Finish()
+
+	applied := []bool{true, false, true}
+	var casStmts []string
+	store, _ := suite.newFrameworkStore(ctrl,
+		func(stmt string, isCAS bool) datastore.ResultSet {
+			if !isCAS {
+				return &fakeResultSet{}
+			}
+			casStmts = append(casStmts, stmt)
+			result := &fakeResultSet{applied: applied[0]}
+			applied = applied[1:]
+			return result
+		})
+
+	ctx := context.Background()
+	suite.NoError(store.SetMesosStreamIDIfCurrent(
+		ctx, testJobName, "stream-2", "stream-1"))
+
+	err := store.SetMesosStreamIDIfCurrent(
+		ctx, testJobName, "stream-3", "stream-1")
+	suite.True(storage.IsMesosStreamIDConflict(err))
+
+	suite.NoError(store.SetMesosStreamIDIfCurrent(
+		ctx, testJobName, "stream-1", ""))
+
+	suite.Len(casStmts, 3)
+	suite.Contains(casStmts[0], "IF mesos_stream_id = ?")
+	suite.Contains(casStmts[2], "IF mesos_stream_id = null")
+}
+
+// TestFrameworkInfoHistoryTruncation tests that the changes beyond the
+// bound of the history are deleted after a change is recorded
+func (suite *MockDatastoreTestSuite) TestFrameworkInfoHistoryTruncation() {
+	ctrl := gomock.NewController(suite.T())
+	defer ctrl.Finish()
+
+	var history []map[string]interface{}
+	store, stmts := suite.newFrameworkStore(ctrl,
+		func(stmt string, isCAS bool) datastore.ResultSet {
+			if strings.HasPrefix(stmt, "SELECT change_time FROM "+frameworkHistoryTable) {
+				return &fakeResultSet{rows: history}
+			}
+			return &fakeResultSet{}
+		})
+
+	ctx := context.Background()
+	suite.NoError(store.SetMesosFrameworkID(ctx, testJobName, "framework-id"))
+	for _, stmt := range *stmts {
+		suite.False(strings.HasPrefix(stmt, "DELETE"))
+	}
+
+	for i := 0; i <= storage.MaxFrameworkInfoHistory; i++ {
+		history = append(history, map[string]interface{}{
+			"change_time": qb.UUID{UUID: gocql.TimeUUID()},
+		})
+	}
+	*stmts = nil
+	suite.NoError(store.SetMesosStreamID(ctx, testJobName, "stream-id"))
+	last := (*stmts)[len(*stmts)-1]
+	suite.True(strings.HasPrefix(last, "DELETE FROM "+frameworkHistoryTable), last)
+	suite.Contains(last, "change_time <= ?")
+}
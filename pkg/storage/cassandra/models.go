@@ -139,6 +139,9 @@ type FrameworkInfoRecord struct {
 	MesosStreamID string    `cql:"mesos_stream_id"`
 	UpdateTime    time.Time `cql:"update_time"`
 	UpdateHost    string    `cql:"update_host"`
+	// MesosStreamIDTime is when the mesos stream id was set, zero for
+	// rows written before it was added
+	MesosStreamIDTime time.Time `cql:"mesos_stream_id_time"`
 }
 
 // Resource pool (to be added)
@@ -76,6 +76,7 @@ const (
 	jobUpdateEvents        = "job_update_events"
 	podWorkflowEventsTable = "pod_workflow_events"
 	frameworksTable        = "frameworks"
+	frameworkHistoryTable  = "framework_info_history"
 	taskJobStateView       = "mv_task_by_state"
 	jobByStateView         = "mv_job_by_state"
 	updatesByJobView       = "mv_updates_by_job"
@@ -2155,33 +2156,256 @@ func (s *Store) getTaskRuntimeRecord(ctx context.Context, jobID string, instance
 
 //SetMesosStreamID stores the mesos framework id for a framework name
 func (s *Store) SetMesosStreamID(ctx context.Context, frameworkName string, mesosStreamID string) error {
-	return s.updateFrameworkTable(ctx, map[string]interface{}{"framework_name": frameworkName, "mesos_stream_id": mesosStreamID})
+	var oldValue string
+	if record, err := s.getFrameworkInfo(ctx, frameworkName); err == nil {
+		oldValue = record.MesosStreamID
+	}
+	err := s.updateFrameworkTable(ctx, map[string]interface{}{
+		"framework_name":       frameworkName,
+		"mesos_stream_id":      mesosStreamID,
+		"mesos_stream_id_time": time.Now().UTC(),
+	})
+	if err != nil {
+		return err
+	}
+	s.addFrameworkInfoChange(ctx, frameworkName, storage.FrameworkInfoFieldMesosStreamID, oldValue, mesosStreamID)
+	return nil
 }
 
 //SetMesosFrameworkID stores the mesos framework id for a framework name
 func (s *Store) SetMesosFrameworkID(ctx context.Context, frameworkName string, frameworkID string) error {
-	return s.updateFrameworkTable(ctx, map[string]interface{}{"framework_name": frameworkName, "framework_id": frameworkID})
+	var oldValue string
+	if record, err := s.getFrameworkInfo(ctx, frameworkName); err == nil {
+		oldValue = record.FrameworkID
+	}
+	err := s.updateFrameworkTable(ctx, map[string]interface{}{"framework_name": frameworkName, "framework_id": frameworkID})
+	if err != nil {
+		return err
+	}
+	s.addFrameworkInfoChange(ctx, frameworkName, storage.FrameworkInfoFieldFrameworkID, oldValue, frameworkID)
+	return nil
 }
 
-func (s *Store) updateFrameworkTable(ctx context.Context, content map[string]interface{}) error {
+// SetMesosStreamIDIfCurrent stores the mesos stream id for a framework name
+// only if its current mesos stream id is expectedOldID, using a lightweight
+// transaction. A framework without a mesos stream id matches an empty
+// expectedOldID.
+func (s *Store) SetMesosStreamIDIfCurrent(
+	ctx context.Context,
+	frameworkName string,
+	mesosStreamID string,
+	expectedOldID string) error {
 	hostName, err := os.Hostname()
 	if err != nil {
 		return err
 	}
+
+	queryBuilder := s.DataStore.NewQuery()
+	stmt := queryBuilder.Update(frameworksTable).
+		Set("mesos_stream_id", mesosStreamID).
+		Set("mesos_stream_id_time", time.Now().UTC()).
+		Set("update_host", hostName).
+		Set("update_time", time.Now().UTC()).
+		Where(qb.Eq{"framework_name": frameworkName})
+	if expectedOldID == "" {
+		stmt = stmt.IfOnly("mesos_stream_id = null")
+	} else {
+		stmt = stmt.IfOnly(qb.Eq{"mesos_stream_id": expectedOldID})
+	}
+
+	result, err := s.executeWrite(ctx, stmt)
+	if err != nil {
+		s.metrics.FrameworkStoreMetrics.FrameworkUpdateFail.Inc(1)
+		return err
+	}
+	if result != nil {
+		defer result.Close()
+	}
+	if result != nil && !result.Applied() {
+		s.metrics.ErrorMetrics.CASNotApplied.Inc(1)
+		s.metrics.FrameworkStoreMetrics.StreamIDConflict.Inc(1)
+		return &storage.MesosStreamIDConflictError{
+			FrameworkName: frameworkName,
+			ExpectedID:    expectedOldID,
+		}
+	}
+	s.metrics.FrameworkStoreMetrics.FrameworkUpdate.Inc(1)
+	s.addFrameworkInfoChange(ctx, frameworkName,
+		storage.FrameworkInfoFieldMesosStreamID, expectedOldID, mesosStreamID)
+	return nil
+}
+
+// GetFrameworkInfoHistory returns up to limit of the most recent changes of
+// the framework id and mesos stream id of a framework name, the most recent
+// first, or all retained changes if limit is not positive
+func (s *Store) GetFrameworkInfoHistory(
+	ctx context.Context,
+	frameworkName string,
+	limit int) ([]*storage.FrameworkInfoChange, error) {
+	if limit <= 0 || limit > storage.MaxFrameworkInfoHistory {
+		limit = storage.MaxFrameworkInfoHistory
+	}
+
+	queryBuilder := s.DataStore.NewQuery()
+	stmt := queryBuilder.Select("*").From(frameworkHistoryTable).
+		Where(qb.Eq{"framework_name": frameworkName}).
+		Limit(uint64(limit))
+	result, err := s.executeRead(ctx, stmt)
+	if err != nil {
+		s.metrics.FrameworkStoreMetrics.HistoryGetFail.Inc(1)
+		return nil, err
+	}
+
+	var changes []*storage.FrameworkInfoChange
+	for _, value := range result {
+		change := &storage.FrameworkInfoChange{
+			Time: value["change_time"].(qb.UUID).Time().UTC(),
+		}
+		change.Field, _ = value["field"].(string)
+		change.OldValue, _ = value["old_value"].(string)
+		change.NewValue, _ = value["new_value"].(string)
+		change.Host, _ = value["update_host"].(string)
+		changes = append(changes, change)
+	}
+
+	s.metrics.FrameworkStoreMetrics.HistoryGet.Inc(1)
+	return changes, nil
+}
+
+// addFrameworkInfoChange records a change of the framework info of a
+// framework name in its history, and drops the changes beyond
+// storage.MaxFrameworkInfoHistory. The history is best effort, failing to
+// record a change is only logged.
+func (s *Store) addFrameworkInfoChange(
+	ctx context.Context,
+	frameworkName string,
+	field string,
+	oldValue string,
+	newValue string) {
+	hostName, _ := os.Hostname()
+	queryBuilder := s.DataStore.NewQuery()
+	stmt := queryBuilder.Insert(frameworkHistoryTable).
+		Columns(
+			"framework_name",
+			"change_time",
+			"field",
+			"old_value",
+			"new_value",
+			"update_host").
+		Values(
+			frameworkName,
+			qb.UUID{UUID: gocql.UUIDFromTime(time.Now())},
+			field,
+			oldValue,
+			newValue,
+			hostName)
+	err := s.applyStatement(ctx, stmt, frameworkName)
+	if err == nil {
+		err = s.trimFrameworkInfoHistory(ctx, frameworkName)
+	}
+	if err != nil {
+		log.WithError(err).
+			WithFields(log.Fields{
+				"framework_name": frameworkName,
+				"field":          field,
+				"new_value":      newValue,
+			}).Error("Failed to record framework info change")
+		s.metrics.FrameworkStoreMetrics.HistoryAddFail.Inc(1)
+		return
+	}
+	s.metrics.FrameworkStoreMetrics.HistoryAdd.Inc(1)
+}
+
+// trimFrameworkInfoHistory deletes the changes of a framework name older
+// than the storage.MaxFrameworkInfoHistory most recent ones
+func (s *Store) trimFrameworkInfoHistory(
+	ctx context.Context,
+	frameworkName string) error {
 	queryBuilder := s.DataStore.NewQuery()
+	stmt := queryBuilder.Select("change_time").From(frameworkHistoryTable).
+		Where(qb.Eq{"framework_name": frameworkName}).
+		Limit(storage.MaxFrameworkInfoHistory + 1)
+	result, err := s.executeRead(ctx, stmt)
+	if err != nil {
+		return err
+	}
+	if len(result) <= storage.MaxFrameworkInfoHistory {
+		return nil
+	}
+
+	// the rows are sorted by descending change time, the last one is the
+	// most recent change which is not retained
+	oldest := result[len(result)-1]["change_time"]
+	deleteStmt := queryBuilder.Delete(frameworkHistoryTable).
+		Where(qb.Eq{"framework_name": frameworkName}).
+		Where(qb.LtOrEq{"change_time": oldest})
+	return s.applyStatement(ctx, deleteStmt, frameworkName)
+}
+
+// updateFrameworkTable writes the columns of content to the row of a
+// framework name. The row is also written by the lightweight transaction of
+// SetMesosStreamIDIfCurrent, and Cassandra does not order lightweight
+// transactions with regular writes of the same row, so the row is written
+// by an update if it exists and by an insert if it does not, both
+// lightweight transactions as well.
+func (s *Store) updateFrameworkTable(ctx context.Context, content map[string]interface{}) error {
+	hostName, err := os.Hostname()
+	if err != nil {
+		return err
+	}
 	content["update_host"] = hostName
 	content["update_time"] = time.Now().UTC()
-
-	var columns []string
-	var values []interface{}
+	frameworkName := content["framework_name"]
+	columns := make(map[string]interface{}, len(content))
 	for col, val := range content {
-		columns = append(columns, col)
-		values = append(values, val)
+		if col != "framework_name" {
+			columns[col] = val
+		}
+	}
+
+	// the insert of a missing row can race with the one of another writer,
+	// in which case the row is updated again
+	for attempt := 0; attempt < 2; attempt++ {
+		queryBuilder := s.DataStore.NewQuery()
+		applied, err := s.executeFrameworkLWT(ctx, queryBuilder.Update(frameworksTable).
+			SetMap(columns).
+			Where(qb.Eq{"framework_name": frameworkName}).
+			IfOnly("EXISTS"))
+		if err != nil || applied {
+			return s.frameworkUpdateResult(err)
+		}
+
+		applied, err = s.executeFrameworkLWT(ctx, queryBuilder.Insert(frameworksTable).
+			SetMap(content).
+			IfNotExist())
+		if err != nil || applied {
+			return s.frameworkUpdateResult(err)
+		}
+		s.metrics.ErrorMetrics.CASNotApplied.Inc(1)
+	}
+	return s.frameworkUpdateResult(yarpcerrors.AbortedErrorf(
+		"framework %v is concurrently written", frameworkName))
+}
+
+// executeFrameworkLWT executes a lightweight transaction of the frameworks
+// table and returns whether it was applied
+func (s *Store) executeFrameworkLWT(
+	ctx context.Context,
+	stmt api.Statement) (bool, error) {
+	result, err := s.executeWrite(ctx, stmt)
+	if err != nil {
+		return false, err
+	}
+	if result == nil {
+		return true, nil
 	}
-	stmt := queryBuilder.Insert(frameworksTable).
-		Columns(columns...).
-		Values(values...)
-	err = s.applyStatement(ctx, stmt, frameworksTable)
+	defer result.Close()
+	return result.Applied(), nil
+}
+
+// frameworkUpdateResult counts the result of an update of the frameworks
+// table and returns its error
+func (s *Store) frameworkUpdateResult(err error) error {
 	if err != nil {
 		s.metrics.FrameworkStoreMetrics.FrameworkUpdateFail.Inc(1)
 		return err
@@ -2202,6 +2426,23 @@ func (s *Store) GetMesosStreamID(ctx context.Context, frameworkName string) (str
 	return frameworkInfoRecord.MesosStreamID, nil
 }
 
+// GetMesosStreamIDWithTime reads the mesos stream id for a framework name
+// and when it was set
+func (s *Store) GetMesosStreamIDWithTime(
+	ctx context.Context,
+	frameworkName string) (string, time.Time, error) {
+	frameworkInfoRecord, err := s.getFrameworkInfo(ctx, frameworkName)
+	if err != nil {
+		s.metrics.FrameworkStoreMetrics.StreamIDGetFail.Inc(1)
+		return "", time.Time{}, err
+	}
+
+	s.metrics.FrameworkStoreMetrics.StreamIDGet.Inc(1)
+	return frameworkInfoRecord.MesosStreamID,
+		frameworkInfoRecord.MesosStreamIDTime,
+		nil
+}
+
 //GetFrameworkID reads the framework id for a framework name
 func (s *Store) GetFrameworkID(ctx context.Context, frameworkName string) (string, error) {
 	frameworkInfoRecord, err := s.getFrameworkInfo(ctx, frameworkName)
@@ -2233,7 +2474,7 @@ func (s *Store) getFrameworkInfo(ctx context.Context, frameworkName string) (*Fr
 		}
 		return &record, nil
 	}
-	return nil, fmt.Errorf("FrameworkInfo not found for framework %v", frameworkName)
+	return nil, &storage.FrameworkInfoNotFoundError{FrameworkName: frameworkName}
 }
 
 func (s *Store) applyStatement(ctx context.Context, stmt api.Statement, itemName string) error {
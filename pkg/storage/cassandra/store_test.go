@@ -1418,6 +1418,47 @@ func (suite *CassandraStoreTestSuite) TestFrameworkInfo() {
 	frameworkID, err = frameworkStore.GetMesosStreamID(context.Background(), "framework1")
 	suite.NoError(err)
 	suite.Equal(frameworkID, "s-12345")
+
+	streamID, streamIDTime, err := frameworkStore.GetMesosStreamIDWithTime(context.Background(), "framework1")
+	suite.NoError(err)
+	suite.Equal("s-12345", streamID)
+	suite.WithinDuration(time.Now(), streamIDTime, time.Minute)
+}
+
+func (suite *CassandraStoreTestSuite) TestFrameworkInfoHistory() {
+	var frameworkStore storage.FrameworkInfoStore
+	frameworkStore = store
+	ctx := context.Background()
+	frameworkName := "framework-" + uuid.New()
+
+	suite.NoError(frameworkStore.SetMesosFrameworkID(ctx, frameworkName, "12345"))
+	suite.NoError(frameworkStore.SetMesosStreamID(ctx, frameworkName, "s-1"))
+	suite.NoError(frameworkStore.SetMesosStreamIDIfCurrent(ctx, frameworkName, "s-2", "s-1"))
+
+	changes, err := frameworkStore.GetFrameworkInfoHistory(ctx, frameworkName, 0)
+	suite.NoError(err)
+	suite.Len(changes, 3)
+	suite.Equal(storage.FrameworkInfoFieldMesosStreamID, changes[0].Field)
+	suite.Equal("s-1", changes[0].OldValue)
+	suite.Equal("s-2", changes[0].NewValue)
+	suite.Equal("", changes[1].OldValue)
+	suite.Equal("s-1", changes[1].NewValue)
+	suite.Equal(storage.FrameworkInfoFieldFrameworkID, changes[2].Field)
+	suite.Equal("12345", changes[2].NewValue)
+	suite.NotEmpty(changes[2].Host)
+
+	changes, err = frameworkStore.GetFrameworkInfoHistory(ctx, frameworkName, 1)
+	suite.NoError(err)
+	suite.Len(changes, 1)
+
+	for i := 0; i < storage.MaxFrameworkInfoHistory; i++ {
+		suite.NoError(frameworkStore.SetMesosStreamID(ctx, frameworkName, fmt.Sprint(i)))
+	}
+	changes, err = frameworkStore.GetFrameworkInfoHistory(ctx, frameworkName, 0)
+	suite.NoError(err)
+	suite.Len(changes, storage.MaxFrameworkInfoHistory)
+	suite.Equal(fmt.Sprint(storage.MaxFrameworkInfoHistory-1), changes[0].NewValue)
+	suite.Equal("0", changes[storage.MaxFrameworkInfoHistory-1].NewValue)
 }
 
 func (suite *CassandraStoreTestSuite) TestAddTasks() {
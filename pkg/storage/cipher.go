@@ -0,0 +1,152 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// _cipherKeyIDLength is the length of the id of a key prefixed to the
+// values it encrypts
+const _cipherKeyIDLength = 8
+
+// CipherProvider encrypts the values persisted by a store, and decrypts
+// them when they are read
+type CipherProvider interface {
+	// Encrypt encrypts a value with the active key
+	Encrypt(plaintext []byte) ([]byte, error)
+	// Decrypt decrypts a value encrypted with any of the keys
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// CipherKeySource returns a key of a CipherProvider, e.g. read from a file
+// or fetched from a KMS
+type CipherKeySource interface {
+	Key() ([]byte, error)
+}
+
+// CipherKeyFile is a CipherKeySource reading the base64 encoded key in a
+// file
+type CipherKeyFile string
+
+// Key reads the key in the file
+func (f CipherKeyFile) Key() ([]byte, error) {
+	buf, err := ioutil.ReadFile(string(f))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read key file %s", string(f))
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(buf)))
+	if err != nil {
+		return nil, errors.Wrapf(err, "invalid key in file %s", string(f))
+	}
+	return key, nil
+}
+
+// aesGCMKey is an AES-GCM key of an AESGCMCipherProvider
+type aesGCMKey struct {
+	id   []byte
+	aead cipher.AEAD
+}
+
+// AESGCMCipherProvider is a CipherProvider using AES-GCM. It encrypts with
+// its active key, and decrypts with any of its keys, so that the keys can
+// be rotated: the new key is made active while the old keys still decrypt
+// the values encrypted before. Every value is prefixed with the id of the
+// key which encrypted it, derived from the key.
+type AESGCMCipherProvider struct {
+	active *aesGCMKey
+	keys   []*aesGCMKey
+}
+
+// NewAESGCMCipherProvider returns a CipherProvider using the AES keys of
+// the sources, of 16, 24 or 32 bytes. The first key is the active one.
+func NewAESGCMCipherProvider(
+	sources ...CipherKeySource) (*AESGCMCipherProvider, error) {
+	if len(sources) == 0 {
+		return nil, errors.New("no cipher key")
+	}
+	p := &AESGCMCipherProvider{}
+	for _, source := range sources {
+		key, err := source.Key()
+		if err != nil {
+			return nil, err
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid cipher key")
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid cipher key")
+		}
+		sum := sha256.Sum256(key)
+		p.keys = append(p.keys, &aesGCMKey{
+			id:   sum[:_cipherKeyIDLength],
+			aead: aead,
+		})
+	}
+	p.active = p.keys[0]
+	return p, nil
+}
+
+// Encrypt encrypts a value with the active key, the result is the key id,
+// the nonce and the sealed value
+func (p *AESGCMCipherProvider) Encrypt(plaintext []byte) ([]byte, error) {
+	nonceSize := p.active.aead.NonceSize()
+	result := make([]byte, _cipherKeyIDLength+nonceSize,
+		_cipherKeyIDLength+nonceSize+len(plaintext)+p.active.aead.Overhead())
+	copy(result, p.active.id)
+	nonce := result[_cipherKeyIDLength:]
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, errors.Wrap(err, "failed to generate nonce")
+	}
+	return p.active.aead.Seal(result, nonce, plaintext, nil), nil
+}
+
+// Decrypt decrypts a value with the key whose id prefixes it
+func (p *AESGCMCipherProvider) Decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < _cipherKeyIDLength {
+		return nil, errors.New("ciphertext too short")
+	}
+	id := ciphertext[:_cipherKeyIDLength]
+	for _, key := range p.keys {
+		if !bytes.Equal(key.id, id) {
+			continue
+		}
+		nonceSize := key.aead.NonceSize()
+		if len(ciphertext) < _cipherKeyIDLength+nonceSize {
+			return nil, errors.New("ciphertext too short")
+		}
+		nonce := ciphertext[_cipherKeyIDLength : _cipherKeyIDLength+nonceSize]
+		plaintext, err := key.aead.Open(
+			nil, nonce, ciphertext[_cipherKeyIDLength+nonceSize:], nil)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to decrypt")
+		}
+		return plaintext, nil
+	}
+	return nil, errors.Errorf("unknown cipher key %s", hex.EncodeToString(id))
+}
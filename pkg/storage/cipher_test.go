@@ -0,0 +1,137 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+// staticCipherKey is a CipherKeySource of a fixed key
+type staticCipherKey []byte
+
+func (k staticCipherKey) Key() ([]byte, error) {
+	return k, nil
+}
+
+var (
+	_testCipherKey      = staticCipherKey(bytes.Repeat([]byte{1}, 32))
+	_testOtherCipherKey = staticCipherKey(bytes.Repeat([]byte{2}, 16))
+)
+
+type cipherTestSuite struct {
+	suite.Suite
+}
+
+func TestCipher(t *testing.T) {
+	suite.Run(t, new(cipherTestSuite))
+}
+
+// TestRoundTrip tests that encrypted values are decrypted, and that every
+// encryption differs
+func (suite *cipherTestSuite) TestRoundTrip() {
+	p, err := NewAESGCMCipherProvider(_testCipherKey)
+	suite.NoError(err)
+
+	first, err := p.Encrypt([]byte("stream-id"))
+	suite.NoError(err)
+	second, err := p.Encrypt([]byte("stream-id"))
+	suite.NoError(err)
+	suite.NotEqual(first, second)
+	suite.False(bytes.Contains(first, []byte("stream-id")))
+
+	for _, ciphertext := range [][]byte{first, second} {
+		plaintext, err := p.Decrypt(ciphertext)
+		suite.NoError(err)
+		suite.Equal("stream-id", string(plaintext))
+	}
+}
+
+// TestTampered tests that tampered values are not decrypted
+func (suite *cipherTestSuite) TestTampered() {
+	p, err := NewAESGCMCipherProvider(_testCipherKey)
+	suite.NoError(err)
+	ciphertext, err := p.Encrypt([]byte("stream-id"))
+	suite.NoError(err)
+
+	ciphertext[len(ciphertext)-1] ^= 1
+	_, err = p.Decrypt(ciphertext)
+	suite.Error(err)
+	_, err = p.Decrypt(ciphertext[:_cipherKeyIDLength+1])
+	suite.EqualError(err, "ciphertext too short")
+}
+
+// TestRotation tests that the values encrypted with an old key are
+// decrypted once a new key is active, and not once the old key is dropped
+func (suite *cipherTestSuite) TestRotation() {
+	old, err := NewAESGCMCipherProvider(_testCipherKey)
+	suite.NoError(err)
+	ciphertext, err := old.Encrypt([]byte("stream-id"))
+	suite.NoError(err)
+
+	rotated, err := NewAESGCMCipherProvider(_testOtherCipherKey, _testCipherKey)
+	suite.NoError(err)
+	plaintext, err := rotated.Decrypt(ciphertext)
+	suite.NoError(err)
+	suite.Equal("stream-id", string(plaintext))
+
+	newCiphertext, err := rotated.Encrypt([]byte("stream-id"))
+	suite.NoError(err)
+	_, err = old.Decrypt(newCiphertext)
+	suite.Error(err)
+
+	dropped, err := NewAESGCMCipherProvider(_testOtherCipherKey)
+	suite.NoError(err)
+	_, err = dropped.Decrypt(ciphertext)
+	suite.Error(err)
+	plaintext, err = dropped.Decrypt(newCiphertext)
+	suite.NoError(err)
+	suite.Equal("stream-id", string(plaintext))
+}
+
+// TestInvalidKeys tests that providers are not created without valid keys
+func (suite *cipherTestSuite) TestInvalidKeys() {
+	_, err := NewAESGCMCipherProvider()
+	suite.EqualError(err, "no cipher key")
+	_, err = NewAESGCMCipherProvider(staticCipherKey("short"))
+	suite.Error(err)
+}
+
+// TestKeyFile tests reading base64 encoded keys from files
+func (suite *cipherTestSuite) TestKeyFile() {
+	dir, err := ioutil.TempDir("", "cipher")
+	suite.NoError(err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "key")
+	suite.NoError(ioutil.WriteFile(path,
+		[]byte(base64.StdEncoding.EncodeToString(_testCipherKey)+"\n"), 0600))
+	key, err := CipherKeyFile(path).Key()
+	suite.NoError(err)
+	suite.Equal([]byte(_testCipherKey), key)
+
+	suite.NoError(ioutil.WriteFile(path, []byte("not base64!"), 0600))
+	_, err = CipherKeyFile(path).Key()
+	suite.Error(err)
+
+	_, err = CipherKeyFile(filepath.Join(dir, "missing")).Key()
+	suite.Error(err)
+}
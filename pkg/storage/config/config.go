@@ -15,9 +15,23 @@
 package config
 
 import (
+	"time"
+
 	"github.com/uber/peloton/pkg/storage/cassandra"
 )
 
+const (
+	// FrameworkInfoStoreMemory keeps the framework info in memory instead
+	// of Cassandra, for tests and dev clusters
+	FrameworkInfoStoreMemory = "memory"
+	// FrameworkInfoStoreFile persists the framework info in the file at
+	// FrameworkInfoPath instead of Cassandra, for single node deployments
+	FrameworkInfoStoreFile = "file"
+	// FrameworkInfoStoreZk keeps the framework info in ZK znodes under
+	// FrameworkInfoZkRoot instead of Cassandra
+	FrameworkInfoStoreZk = "zookeeper"
+)
+
 // Config contains the different DB config values for each
 // supported backend
 type Config struct {
@@ -25,4 +39,22 @@ type Config struct {
 	UseCassandra       bool             `yaml:"use_cassandra"`
 	AutoMigrate        bool             `yaml:"auto_migrate"`
 	DbWriteConcurrency int              `yaml:"db_write_concurrency"`
+
+	// FrameworkInfoStore selects the store of the framework info, the
+	// Cassandra store unless it is FrameworkInfoStoreMemory,
+	// FrameworkInfoStoreFile or FrameworkInfoStoreZk
+	FrameworkInfoStore string `yaml:"framework_info_store"`
+	// FrameworkInfoPath is the file of FrameworkInfoStoreFile
+	FrameworkInfoPath string `yaml:"framework_info_path"`
+	// FrameworkInfoZkRoot is the root path of FrameworkInfoStoreZk,
+	// <election root>/framework by default
+	FrameworkInfoZkRoot string `yaml:"framework_info_zk_root"`
+	// FrameworkInfoCacheTTL is how long the framework id and mesos stream
+	// id read from the store are cached, they are not cached if it is zero
+	FrameworkInfoCacheTTL time.Duration `yaml:"framework_info_cache_ttl"`
+	// FrameworkInfoKeyFiles are the files of the base64 encoded AES keys
+	// of the mesos stream ids persisted, which are encrypted with the key
+	// of the first file and decrypted with any of them. They are persisted
+	// in plaintext if there are none.
+	FrameworkInfoKeyFiles []string `yaml:"framework_info_key_files"`
 }
@@ -0,0 +1,209 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// _encryptedValuePrefix prefixes the values encrypted by an
+// EncryptedFrameworkInfoStore, so that they are told apart from the values
+// persisted in plaintext before, and the format can be versioned
+const _encryptedValuePrefix = "enc:v1:"
+
+// EncryptedFrameworkInfoStore is a FrameworkInfoStore which encrypts the
+// mesos stream ids persisted by another FrameworkInfoStore. The mesos
+// stream ids persisted in plaintext, e.g. before encryption was enabled,
+// are still read. The framework ids are not secret and left in plaintext.
+type EncryptedFrameworkInfoStore struct {
+	store  FrameworkInfoStore
+	cipher CipherProvider
+}
+
+// NewEncryptedFrameworkInfoStore returns a FrameworkInfoStore encrypting
+// the mesos stream ids of the store with the cipher provider
+func NewEncryptedFrameworkInfoStore(
+	store FrameworkInfoStore,
+	cipher CipherProvider) *EncryptedFrameworkInfoStore {
+	return &EncryptedFrameworkInfoStore{
+		store:  store,
+		cipher: cipher,
+	}
+}
+
+// SetMesosStreamID stores the encrypted mesos stream id for a framework
+// name
+func (s *EncryptedFrameworkInfoStore) SetMesosStreamID(
+	ctx context.Context,
+	frameworkName string,
+	mesosStreamID string) error {
+	encrypted, err := s.encrypt(mesosStreamID)
+	if err != nil {
+		return err
+	}
+	return s.store.SetMesosStreamID(ctx, frameworkName, encrypted)
+}
+
+// SetMesosStreamIDIfCurrent stores the encrypted mesos stream id for a
+// framework name only if its current mesos stream id is expectedOldID. As
+// every encryption of a mesos stream id differs, the current mesos stream
+// id persisted is compared to expectedOldID, and then set only if it is
+// still the one persisted.
+func (s *EncryptedFrameworkInfoStore) SetMesosStreamIDIfCurrent(
+	ctx context.Context,
+	frameworkName string,
+	mesosStreamID string,
+	expectedOldID string) error {
+	persisted, err := s.store.GetMesosStreamID(ctx, frameworkName)
+	if IsFrameworkInfoNotFound(err) {
+		persisted, err = "", nil
+	}
+	if err != nil {
+		return err
+	}
+	current, err := s.decrypt(persisted)
+	if err != nil {
+		return err
+	}
+	if current != expectedOldID {
+		return &MesosStreamIDConflictError{
+			FrameworkName: frameworkName,
+			ExpectedID:    expectedOldID,
+		}
+	}
+
+	encrypted, err := s.encrypt(mesosStreamID)
+	if err != nil {
+		return err
+	}
+	err = s.store.SetMesosStreamIDIfCurrent(
+		ctx, frameworkName, encrypted, persisted)
+	if conflict, ok := err.(*MesosStreamIDConflictError); ok {
+		conflict.ExpectedID = expectedOldID
+	}
+	return err
+}
+
+// SetMesosFrameworkID stores the mesos framework id for a framework name
+func (s *EncryptedFrameworkInfoStore) SetMesosFrameworkID(
+	ctx context.Context,
+	frameworkName string,
+	frameworkID string) error {
+	return s.store.SetMesosFrameworkID(ctx, frameworkName, frameworkID)
+}
+
+// GetMesosStreamID reads and decrypts the mesos stream id for a framework
+// name
+func (s *EncryptedFrameworkInfoStore) GetMesosStreamID(
+	ctx context.Context,
+	frameworkName string) (string, error) {
+	persisted, err := s.store.GetMesosStreamID(ctx, frameworkName)
+	if err != nil {
+		return "", err
+	}
+	return s.decrypt(persisted)
+}
+
+// GetMesosStreamIDWithTime reads and decrypts the mesos stream id for a
+// framework name, and reads when it was set
+func (s *EncryptedFrameworkInfoStore) GetMesosStreamIDWithTime(
+	ctx context.Context,
+	frameworkName string) (string, time.Time, error) {
+	persisted, setTime, err := s.store.GetMesosStreamIDWithTime(
+		ctx, frameworkName)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	streamID, err := s.decrypt(persisted)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return streamID, setTime, nil
+}
+
+// GetFrameworkID reads the framework id for a framework name
+func (s *EncryptedFrameworkInfoStore) GetFrameworkID(
+	ctx context.Context,
+	frameworkName string) (string, error) {
+	return s.store.GetFrameworkID(ctx, frameworkName)
+}
+
+// GetFrameworkInfoHistory returns up to limit of the most recent changes of
+// the framework info of a framework name, the most recent first, with the
+// mesos stream ids decrypted
+func (s *EncryptedFrameworkInfoStore) GetFrameworkInfoHistory(
+	ctx context.Context,
+	frameworkName string,
+	limit int) ([]*FrameworkInfoChange, error) {
+	changes, err := s.store.GetFrameworkInfoHistory(ctx, frameworkName, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*FrameworkInfoChange, 0, len(changes))
+	for _, change := range changes {
+		if change.Field != FrameworkInfoFieldMesosStreamID {
+			result = append(result, change)
+			continue
+		}
+		// the changes of the store are copied rather than modified
+		decrypted := *change
+		if decrypted.OldValue, err = s.decrypt(change.OldValue); err != nil {
+			return nil, err
+		}
+		if decrypted.NewValue, err = s.decrypt(change.NewValue); err != nil {
+			return nil, err
+		}
+		result = append(result, &decrypted)
+	}
+	return result, nil
+}
+
+// encrypt returns the encrypted value persisted for a value, the empty
+// value is persisted as is
+func (s *EncryptedFrameworkInfoStore) encrypt(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	ciphertext, err := s.cipher.Encrypt([]byte(value))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to encrypt framework info")
+	}
+	return _encryptedValuePrefix +
+		base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// decrypt returns the value of a persisted value, which is returned as is
+// if it was persisted in plaintext
+func (s *EncryptedFrameworkInfoStore) decrypt(persisted string) (string, error) {
+	if !strings.HasPrefix(persisted, _encryptedValuePrefix) {
+		return persisted, nil
+	}
+	ciphertext, err := base64.StdEncoding.DecodeString(
+		strings.TrimPrefix(persisted, _encryptedValuePrefix))
+	if err != nil {
+		return "", errors.Wrap(err, "invalid encrypted framework info")
+	}
+	plaintext, err := s.cipher.Decrypt(ciphertext)
+	if err != nil {
+		return "", errors.Wrap(err, "failed to decrypt framework info")
+	}
+	return string(plaintext), nil
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type encryptedFrameworkInfoStoreTestSuite struct {
+	suite.Suite
+	ctx     context.Context
+	backend *InMemoryFrameworkInfoStore
+	store   *EncryptedFrameworkInfoStore
+}
+
+func (suite *encryptedFrameworkInfoStoreTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.backend = NewInMemoryFrameworkInfoStore()
+	suite.store = suite.newStore(_testCipherKey)
+}
+
+func TestEncryptedFrameworkInfoStore(t *testing.T) {
+	suite.Run(t, new(encryptedFrameworkInfoStoreTestSuite))
+}
+
+// newStore returns a store encrypting the backend with the keys
+func (suite *encryptedFrameworkInfoStoreTestSuite) newStore(
+	keys ...CipherKeySource) *EncryptedFrameworkInfoStore {
+	cipher, err := NewAESGCMCipherProvider(keys...)
+	suite.NoError(err)
+	return NewEncryptedFrameworkInfoStore(suite.backend, cipher)
+}
+
+// requireStreamID checks the mesos stream id read from a store
+func (suite *encryptedFrameworkInfoStoreTestSuite) requireStreamID(
+	store FrameworkInfoStore,
+	expected string) {
+	streamID, err := store.GetMesosStreamID(suite.ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal(expected, streamID)
+	streamID, _, err = store.GetMesosStreamIDWithTime(suite.ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal(expected, streamID)
+}
+
+// TestRoundTrip tests that the mesos stream id is persisted encrypted, and
+// the framework id in plaintext
+func (suite *encryptedFrameworkInfoStoreTestSuite) TestRoundTrip() {
+	suite.NoError(suite.store.SetMesosStreamID(
+		suite.ctx, _testFrameworkName, "stream-id"))
+	suite.NoError(suite.store.SetMesosFrameworkID(
+		suite.ctx, _testFrameworkName, "framework-id"))
+
+	suite.requireStreamID(suite.store, "stream-id")
+	persisted, err := suite.backend.GetMesosStreamID(suite.ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.True(strings.HasPrefix(persisted, _encryptedValuePrefix))
+	suite.NotContains(persisted, "stream-id")
+
+	id, err := suite.store.GetFrameworkID(suite.ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("framework-id", id)
+	id, err = suite.backend.GetFrameworkID(suite.ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("framework-id", id)
+}
+
+// TestLegacyPlaintext tests that mesos stream ids persisted in plaintext
+// are read
+func (suite *encryptedFrameworkInfoStoreTestSuite) TestLegacyPlaintext() {
+	suite.NoError(suite.backend.SetMesosStreamID(
+		suite.ctx, _testFrameworkName, "stream-id"))
+	suite.requireStreamID(suite.store, "stream-id")
+
+	// it is encrypted once it is set again
+	suite.NoError(suite.store.SetMesosStreamIDIfCurrent(
+		suite.ctx, _testFrameworkName, "new-stream-id", "stream-id"))
+	suite.requireStreamID(suite.store, "new-stream-id")
+	persisted, err := suite.backend.GetMesosStreamID(suite.ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.True(strings.HasPrefix(persisted, _encryptedValuePrefix))
+}
+
+// TestRotation tests that the mesos stream ids encrypted with an old key
+// are read once a new key is active
+func (suite *encryptedFrameworkInfoStoreTestSuite) TestRotation() {
+	suite.NoError(suite.store.SetMesosStreamID(
+		suite.ctx, _testFrameworkName, "stream-id"))
+
+	rotated := suite.newStore(_testOtherCipherKey, _testCipherKey)
+	suite.requireStreamID(rotated, "stream-id")
+	suite.NoError(rotated.SetMesosStreamID(
+		suite.ctx, _testFrameworkName, "new-stream-id"))
+
+	suite.requireStreamID(suite.newStore(_testOtherCipherKey), "new-stream-id")
+	_, err := suite.store.GetMesosStreamID(suite.ctx, _testFrameworkName)
+	suite.Error(err)
+}
+
+// TestSetMesosStreamIDIfCurrent tests that the mesos stream id is compared
+// to the decrypted one
+func (suite *encryptedFrameworkInfoStoreTestSuite) TestSetMesosStreamIDIfCurrent() {
+	suite.NoError(suite.store.SetMesosStreamIDIfCurrent(
+		suite.ctx, _testFrameworkName, "stream-id", ""))
+	suite.requireStreamID(suite.store, "stream-id")
+
+	err := suite.store.SetMesosStreamIDIfCurrent(
+		suite.ctx, _testFrameworkName, "new-stream-id", "other-stream-id")
+	suite.True(IsMesosStreamIDConflict(err))
+	suite.Equal("other-stream-id", err.(*MesosStreamIDConflictError).ExpectedID)
+	suite.requireStreamID(suite.store, "stream-id")
+
+	suite.NoError(suite.store.SetMesosStreamIDIfCurrent(
+		suite.ctx, _testFrameworkName, "new-stream-id", "stream-id"))
+	suite.requireStreamID(suite.store, "new-stream-id")
+}
+
+// TestHistory tests that the mesos stream ids of the history are decrypted
+func (suite *encryptedFrameworkInfoStoreTestSuite) TestHistory() {
+	suite.NoError(suite.backend.SetMesosStreamID(
+		suite.ctx, _testFrameworkName, "stream-id"))
+	suite.NoError(suite.store.SetMesosStreamID(
+		suite.ctx, _testFrameworkName, "new-stream-id"))
+	suite.NoError(suite.store.SetMesosFrameworkID(
+		suite.ctx, _testFrameworkName, "framework-id"))
+
+	changes, err := suite.store.GetFrameworkInfoHistory(
+		suite.ctx, _testFrameworkName, 0)
+	suite.NoError(err)
+	suite.Len(changes, 3)
+	suite.Equal("framework-id", changes[0].NewValue)
+	suite.Equal("stream-id", changes[1].OldValue)
+	suite.Equal("new-stream-id", changes[1].NewValue)
+	suite.Equal("stream-id", changes[2].NewValue)
+
+	// the history of the backend is left encrypted
+	changes, err = suite.backend.GetFrameworkInfoHistory(
+		suite.ctx, _testFrameworkName, 0)
+	suite.NoError(err)
+	suite.True(strings.HasPrefix(changes[1].NewValue, _encryptedValuePrefix))
+}
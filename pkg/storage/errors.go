@@ -0,0 +1,59 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"fmt"
+)
+
+// MesosStreamIDConflictError is returned by SetMesosStreamIDIfCurrent if
+// the mesos stream id of the framework is not the expected one, e.g.
+// because another host manager subscribed to Mesos in the meantime
+type MesosStreamIDConflictError struct {
+	FrameworkName string
+	ExpectedID    string
+}
+
+// Error implements error.Error
+func (e *MesosStreamIDConflictError) Error() string {
+	return fmt.Sprintf("mesos stream id of framework %s is no longer %q",
+		e.FrameworkName, e.ExpectedID)
+}
+
+// IsMesosStreamIDConflict returns whether the error is a
+// MesosStreamIDConflictError
+func IsMesosStreamIDConflict(err error) bool {
+	_, ok := err.(*MesosStreamIDConflictError)
+	return ok
+}
+
+// FrameworkInfoNotFoundError is returned by the reads of a
+// FrameworkInfoStore for a framework name without framework info
+type FrameworkInfoNotFoundError struct {
+	FrameworkName string
+}
+
+// Error implements error.Error
+func (e *FrameworkInfoNotFoundError) Error() string {
+	return fmt.Sprintf("FrameworkInfo not found for framework %v",
+		e.FrameworkName)
+}
+
+// IsFrameworkInfoNotFound returns whether the error is a
+// FrameworkInfoNotFoundError
+func IsFrameworkInfoNotFound(err error) bool {
+	_, ok := err.(*FrameworkInfoNotFoundError)
+	return ok
+}
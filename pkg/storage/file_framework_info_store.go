@@ -0,0 +1,275 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// frameworkInfoFile is the content of the file of a FileFrameworkInfoStore.
+// Checksum is the hex encoded SHA-256 of Frameworks, which is the JSON
+// encoded framework info by framework name.
+type frameworkInfoFile struct {
+	Checksum   string          `json:"checksum"`
+	Frameworks json.RawMessage `json:"frameworks"`
+}
+
+// FileFrameworkInfoStore is a FrameworkInfoStore which persists the
+// framework info in a JSON file, for single node deployments without
+// Cassandra. The file is replaced atomically on every update, and a
+// corrupted file is read as empty.
+type FileFrameworkInfoStore struct {
+	// serializes the read-modify-write of the file within the process
+	sync.Mutex
+
+	path string
+}
+
+// NewFileFrameworkInfoStore returns a FrameworkInfoStore persisting the
+// framework info in the file at path. The file is created by the first
+// update if it does not exist.
+func NewFileFrameworkInfoStore(path string) *FileFrameworkInfoStore {
+	return &FileFrameworkInfoStore{path: path}
+}
+
+// SetMesosStreamID stores the mesos stream id for a framework name
+func (s *FileFrameworkInfoStore) SetMesosStreamID(
+	ctx context.Context,
+	frameworkName string,
+	mesosStreamID string) error {
+	return s.update(frameworkName, func(info *frameworkInfo) error {
+		info.setMesosStreamID(mesosStreamID)
+		return nil
+	})
+}
+
+// SetMesosStreamIDIfCurrent stores the mesos stream id for a framework name
+// only if its current mesos stream id is expectedOldID
+func (s *FileFrameworkInfoStore) SetMesosStreamIDIfCurrent(
+	ctx context.Context,
+	frameworkName string,
+	mesosStreamID string,
+	expectedOldID string) error {
+	return s.update(frameworkName, func(info *frameworkInfo) error {
+		return info.setMesosStreamIDIfCurrent(
+			frameworkName, mesosStreamID, expectedOldID)
+	})
+}
+
+// SetMesosFrameworkID stores the mesos framework id for a framework name
+func (s *FileFrameworkInfoStore) SetMesosFrameworkID(
+	ctx context.Context,
+	frameworkName string,
+	frameworkID string) error {
+	return s.update(frameworkName, func(info *frameworkInfo) error {
+		info.setFrameworkID(frameworkID)
+		return nil
+	})
+}
+
+// GetMesosStreamID reads the mesos stream id for a framework name
+func (s *FileFrameworkInfoStore) GetMesosStreamID(
+	ctx context.Context,
+	frameworkName string) (string, error) {
+	info, err := s.get(frameworkName)
+	if err != nil {
+		return "", err
+	}
+	return info.MesosStreamID, nil
+}
+
+// GetMesosStreamIDWithTime reads the mesos stream id for a framework name
+// and when it was set
+func (s *FileFrameworkInfoStore) GetMesosStreamIDWithTime(
+	ctx context.Context,
+	frameworkName string) (string, time.Time, error) {
+	info, err := s.get(frameworkName)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return info.MesosStreamID, info.MesosStreamIDTime, nil
+}
+
+// GetFrameworkID reads the framework id for a framework name
+func (s *FileFrameworkInfoStore) GetFrameworkID(
+	ctx context.Context,
+	frameworkName string) (string, error) {
+	info, err := s.get(frameworkName)
+	if err != nil {
+		return "", err
+	}
+	return info.FrameworkID, nil
+}
+
+// GetFrameworkInfoHistory returns up to limit of the most recent changes of
+// the framework info of a framework name, the most recent first
+func (s *FileFrameworkInfoStore) GetFrameworkInfoHistory(
+	ctx context.Context,
+	frameworkName string,
+	limit int) ([]*FrameworkInfoChange, error) {
+	info, err := s.get(frameworkName)
+	if err != nil {
+		if IsFrameworkInfoNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return recentFrameworkInfoChanges(info.History, limit), nil
+}
+
+// get returns the framework info of a framework name
+func (s *FileFrameworkInfoStore) get(frameworkName string) (*frameworkInfo, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	frameworks, err := s.read()
+	if err != nil {
+		return nil, err
+	}
+	info, ok := frameworks[frameworkName]
+	if !ok {
+		return nil, errFrameworkInfoNotFound(frameworkName)
+	}
+	return info, nil
+}
+
+// update applies a change to the framework info of a framework name,
+// creating it if it does not exist, and writes the file unless the change
+// fails
+func (s *FileFrameworkInfoStore) update(
+	frameworkName string,
+	change func(*frameworkInfo) error) error {
+	s.Lock()
+	defer s.Unlock()
+
+	frameworks, err := s.read()
+	if err != nil {
+		return err
+	}
+	info, ok := frameworks[frameworkName]
+	if !ok {
+		info = &frameworkInfo{}
+		frameworks[frameworkName] = info
+	}
+	if err := change(info); err != nil {
+		return err
+	}
+	return s.write(frameworks)
+}
+
+// read reads the framework info by framework name from the file. A missing
+// file is read as empty, as is a corrupted one after logging an error.
+func (s *FileFrameworkInfoStore) read() (map[string]*frameworkInfo, error) {
+	frameworks := make(map[string]*frameworkInfo)
+	buf, err := ioutil.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return frameworks, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read framework info file %s: %v",
+			s.path, err)
+	}
+
+	var file frameworkInfoFile
+	err = json.Unmarshal(buf, &file)
+	if err == nil && file.Checksum != frameworkInfoChecksum(file.Frameworks) {
+		err = fmt.Errorf("checksum mismatch")
+	}
+	if err == nil {
+		err = json.Unmarshal(file.Frameworks, &frameworks)
+	}
+	if err != nil {
+		log.WithError(err).
+			WithField("path", s.path).
+			Error("Framework info file is corrupted, ignoring its content")
+		return make(map[string]*frameworkInfo), nil
+	}
+	return frameworks, nil
+}
+
+// write atomically replaces the file with the framework info by framework
+// name, by writing a temporary file in the same directory and renaming it.
+// The directory is synced after the rename so that the new file survives a
+// crash.
+func (s *FileFrameworkInfoStore) write(frameworks map[string]*frameworkInfo) error {
+	content, err := json.Marshal(frameworks)
+	if err != nil {
+		return err
+	}
+	buf, err := json.Marshal(frameworkInfoFile{
+		Checksum:   frameworkInfoChecksum(content),
+		Frameworks: content,
+	})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(
+		filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write framework info file %s: %v",
+			s.path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(buf)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), s.path)
+	}
+	if err == nil {
+		err = syncDir(filepath.Dir(s.path))
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write framework info file %s: %v",
+			s.path, err)
+	}
+	return nil
+}
+
+// syncDir flushes the entries of a directory, e.g. a renamed file, to disk
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	err = d.Sync()
+	if closeErr := d.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// frameworkInfoChecksum returns the hex encoded SHA-256 of the content
+func frameworkInfoChecksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,269 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type fileFrameworkInfoStoreTestSuite struct {
+	suite.Suite
+	dir   string
+	path  string
+	store *FileFrameworkInfoStore
+}
+
+func (suite *fileFrameworkInfoStoreTestSuite) SetupTest() {
+	dir, err := ioutil.TempDir("", "framework-info")
+	suite.Require().NoError(err)
+	suite.dir = dir
+	suite.path = filepath.Join(dir, "framework.json")
+	suite.store = NewFileFrameworkInfoStore(suite.path)
+}
+
+func (suite *fileFrameworkInfoStoreTestSuite) TearDownTest() {
+	os.Chmod(suite.dir, 0700)
+	os.RemoveAll(suite.dir)
+}
+
+func TestFileFrameworkInfoStore(t *testing.T) {
+	suite.Run(t, new(fileFrameworkInfoStoreTestSuite))
+}
+
+// skipIfRoot skips tests of permission errors, which root does not get
+func (suite *fileFrameworkInfoStoreTestSuite) skipIfRoot() {
+	if os.Geteuid() == 0 {
+		suite.T().Skip("permissions are not enforced for root")
+	}
+}
+
+// TestRoundTrip tests that the framework info is read back by a new store
+// of the same file
+func (suite *fileFrameworkInfoStoreTestSuite) TestRoundTrip() {
+	ctx := context.Background()
+
+	_, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.EqualError(err, "FrameworkInfo not found for framework peloton")
+
+	suite.NoError(suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "framework-id"))
+	suite.NoError(suite.store.SetMesosStreamID(ctx, _testFrameworkName, "stream-id"))
+	suite.NoError(suite.store.SetMesosStreamID(ctx, "other", "other-stream-id"))
+
+	store := NewFileFrameworkInfoStore(suite.path)
+	id, err := store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("framework-id", id)
+	streamID, err := store.GetMesosStreamID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("stream-id", streamID)
+	streamID, err = store.GetMesosStreamID(ctx, "other")
+	suite.NoError(err)
+	suite.Equal("other-stream-id", streamID)
+
+	// no temporary files are left behind
+	files, err := ioutil.ReadDir(suite.dir)
+	suite.NoError(err)
+	suite.Len(files, 1)
+}
+
+// TestConcurrentWriters tests that concurrent updates are serialized
+// without losing any of them
+func (suite *fileFrameworkInfoStoreTestSuite) TestConcurrentWriters() {
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			suite.NoError(suite.store.SetMesosStreamID(
+				ctx, fmt.Sprintf("framework-%d", i), fmt.Sprint(i)))
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 0; i < 10; i++ {
+		streamID, err := suite.store.GetMesosStreamID(
+			ctx, fmt.Sprintf("framework-%d", i))
+		suite.NoError(err)
+		suite.Equal(fmt.Sprint(i), streamID)
+	}
+}
+
+// TestSetMesosStreamIDIfCurrent tests that a conflicting update does not
+// change the file
+func (suite *fileFrameworkInfoStoreTestSuite) TestSetMesosStreamIDIfCurrent() {
+	ctx := context.Background()
+	suite.NoError(suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "stream-1", ""))
+	suite.NoError(suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "stream-2", "stream-1"))
+
+	err := suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "stream-3", "stream-1")
+	suite.True(IsMesosStreamIDConflict(err))
+
+	store := NewFileFrameworkInfoStore(suite.path)
+	streamID, err := store.GetMesosStreamID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("stream-2", streamID)
+}
+
+// TestHistory tests that the history is persisted in the file and bounded
+func (suite *fileFrameworkInfoStoreTestSuite) TestHistory() {
+	ctx := context.Background()
+	suite.NoError(suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "framework-id"))
+	for i := 0; i < MaxFrameworkInfoHistory; i++ {
+		suite.NoError(suite.store.SetMesosStreamID(
+			ctx, _testFrameworkName, fmt.Sprint(i)))
+	}
+
+	store := NewFileFrameworkInfoStore(suite.path)
+	changes, err := store.GetFrameworkInfoHistory(ctx, _testFrameworkName, 0)
+	suite.NoError(err)
+	suite.Len(changes, MaxFrameworkInfoHistory)
+	suite.Equal(fmt.Sprint(MaxFrameworkInfoHistory-1), changes[0].NewValue)
+	suite.Equal(fmt.Sprint(MaxFrameworkInfoHistory-2), changes[0].OldValue)
+	// the change of the framework id was the oldest one
+	suite.Equal(FrameworkInfoFieldMesosStreamID,
+		changes[MaxFrameworkInfoHistory-1].Field)
+	suite.Equal("0", changes[MaxFrameworkInfoHistory-1].NewValue)
+
+	changes, err = store.GetFrameworkInfoHistory(ctx, "other", 0)
+	suite.NoError(err)
+	suite.Empty(changes)
+}
+
+// TestMesosStreamIDTime tests that the time of the stream id is persisted,
+// and is zero in a file written without it
+func (suite *fileFrameworkInfoStoreTestSuite) TestMesosStreamIDTime() {
+	ctx := context.Background()
+	suite.NoError(suite.store.SetMesosStreamID(ctx, _testFrameworkName, "stream-id"))
+	_, streamIDTime, err := suite.store.GetMesosStreamIDWithTime(ctx, _testFrameworkName)
+	suite.NoError(err)
+
+	store := NewFileFrameworkInfoStore(suite.path)
+	streamID, persistedTime, err := store.GetMesosStreamIDWithTime(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("stream-id", streamID)
+	suite.False(streamIDTime.IsZero())
+	suite.True(streamIDTime.Equal(persistedTime))
+
+	// a file written before the time was stored
+	frameworks := []byte(`{"peloton":{"framework_id":"","mesos_stream_id":"legacy-id"}}`)
+	buf, err := json.Marshal(frameworkInfoFile{
+		Checksum:   frameworkInfoChecksum(frameworks),
+		Frameworks: frameworks,
+	})
+	suite.NoError(err)
+	suite.NoError(ioutil.WriteFile(suite.path, buf, 0600))
+	streamID, persistedTime, err = store.GetMesosStreamIDWithTime(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("legacy-id", streamID)
+	suite.True(persistedTime.IsZero())
+}
+
+// TestPartialFile tests that a partially written file is read as empty,
+// and that a temporary file left by a crash does not affect the file
+func (suite *fileFrameworkInfoStoreTestSuite) TestPartialFile() {
+	ctx := context.Background()
+	suite.NoError(suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "framework-id"))
+	buf, err := ioutil.ReadFile(suite.path)
+	suite.NoError(err)
+
+	// a crash before the rename leaves a partial temporary file
+	suite.NoError(ioutil.WriteFile(
+		suite.path+".tmp123", buf[:len(buf)/2], 0600))
+	id, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("framework-id", id)
+
+	// the file itself is truncated
+	suite.NoError(ioutil.WriteFile(suite.path, buf[:len(buf)/2], 0600))
+	_, err = suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.EqualError(err, "FrameworkInfo not found for framework peloton")
+
+	// the next update replaces the corrupted file
+	suite.NoError(suite.store.SetMesosStreamID(ctx, _testFrameworkName, "stream-id"))
+	streamID, err := suite.store.GetMesosStreamID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("stream-id", streamID)
+}
+
+// TestChecksumMismatch tests that a file whose content does not match its
+// checksum is read as empty
+func (suite *fileFrameworkInfoStoreTestSuite) TestChecksumMismatch() {
+	ctx := context.Background()
+	suite.NoError(suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "framework-id"))
+	buf, err := ioutil.ReadFile(suite.path)
+	suite.NoError(err)
+
+	corrupted := strings.Replace(string(buf), "framework-id", "framework-xx", 1)
+	suite.NotEqual(string(buf), corrupted)
+	suite.NoError(ioutil.WriteFile(suite.path, []byte(corrupted), 0600))
+
+	_, err = suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.EqualError(err, "FrameworkInfo not found for framework peloton")
+}
+
+// TestReadPermissionError tests that an unreadable file fails the calls
+func (suite *fileFrameworkInfoStoreTestSuite) TestReadPermissionError() {
+	suite.skipIfRoot()
+	ctx := context.Background()
+	suite.NoError(suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "framework-id"))
+	suite.NoError(os.Chmod(suite.path, 0200))
+
+	_, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.Error(err)
+	suite.Contains(err.Error(), "failed to read framework info file")
+	suite.Error(suite.store.SetMesosStreamID(ctx, _testFrameworkName, "stream-id"))
+}
+
+// TestWritePermissionError tests that a failed write fails the update and
+// keeps the previous framework info
+func (suite *fileFrameworkInfoStoreTestSuite) TestWritePermissionError() {
+	suite.skipIfRoot()
+	ctx := context.Background()
+	suite.NoError(suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "framework-id"))
+	suite.NoError(os.Chmod(suite.dir, 0500))
+
+	err := suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "other-id")
+	suite.Error(err)
+	suite.Contains(err.Error(), "failed to write framework info file")
+
+	id, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("framework-id", id)
+}
+
+// TestMissingDirectory tests that updates fail if the directory of the file
+// does not exist
+func (suite *fileFrameworkInfoStoreTestSuite) TestMissingDirectory() {
+	store := NewFileFrameworkInfoStore(
+		filepath.Join(suite.dir, "missing", "framework.json"))
+	_, err := store.GetFrameworkID(context.Background(), _testFrameworkName)
+	suite.EqualError(err, "FrameworkInfo not found for framework peloton")
+	suite.Error(store.SetMesosFrameworkID(
+		context.Background(), _testFrameworkName, "framework-id"))
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"os"
+	"time"
+)
+
+const (
+	// Fields of the framework info recorded in its history
+	FrameworkInfoFieldFrameworkID   = "framework_id"
+	FrameworkInfoFieldMesosStreamID = "mesos_stream_id"
+
+	// MaxFrameworkInfoHistory is the number of changes retained in the
+	// history of a framework name, older changes are dropped
+	MaxFrameworkInfoHistory = 100
+)
+
+// FrameworkInfoChange is a change of the framework id or the mesos stream
+// id of a framework name, recorded for auditing
+type FrameworkInfoChange struct {
+	Time     time.Time `json:"time"`
+	Field    string    `json:"field"`
+	OldValue string    `json:"old_value"`
+	NewValue string    `json:"new_value"`
+	Host     string    `json:"host"`
+}
+
+// newFrameworkInfoChange returns a change of a field of the framework info
+// written by this host now
+func newFrameworkInfoChange(
+	field string,
+	oldValue string,
+	newValue string) *FrameworkInfoChange {
+	host, _ := os.Hostname()
+	return &FrameworkInfoChange{
+		Time:     time.Now().UTC(),
+		Field:    field,
+		OldValue: oldValue,
+		NewValue: newValue,
+		Host:     host,
+	}
+}
+
+// appendFrameworkInfoChange returns a new history, from the oldest to the
+// most recent change, with the change appended and without the oldest
+// changes beyond MaxFrameworkInfoHistory
+func appendFrameworkInfoChange(
+	history []*FrameworkInfoChange,
+	change *FrameworkInfoChange) []*FrameworkInfoChange {
+	if len(history) >= MaxFrameworkInfoHistory {
+		history = history[len(history)-MaxFrameworkInfoHistory+1:]
+	}
+	result := make([]*FrameworkInfoChange, 0, len(history)+1)
+	result = append(result, history...)
+	return append(result, change)
+}
+
+// recentFrameworkInfoChanges returns up to limit of the most recent changes
+// of a history, the most recent first. All of them are returned if limit
+// is not positive.
+func recentFrameworkInfoChanges(
+	history []*FrameworkInfoChange,
+	limit int) []*FrameworkInfoChange {
+	if limit <= 0 || limit > len(history) {
+		limit = len(history)
+	}
+	result := make([]*FrameworkInfoChange, 0, limit)
+	for i := len(history) - 1; i >= len(history)-limit; i-- {
+		result = append(result, history[i])
+	}
+	return result
+}
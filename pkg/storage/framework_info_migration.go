@@ -0,0 +1,180 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// FrameworkInfoCopy is a field of the framework info of a framework name
+// copied, or to be copied, from a FrameworkInfoStore to another
+type FrameworkInfoCopy struct {
+	Field string `json:"field"`
+	Value string `json:"value"`
+	// OldValue is the value replaced in the destination, empty if it has
+	// none
+	OldValue string `json:"old_value"`
+}
+
+// frameworkInfoField is a field of the framework info read and set through
+// a FrameworkInfoStore
+type frameworkInfoField struct {
+	name string
+	get  func(ctx context.Context, store FrameworkInfoStore, frameworkName string) (string, error)
+	set  func(ctx context.Context, store FrameworkInfoStore, frameworkName string, value string) error
+}
+
+// frameworkInfoFields are the fields of the framework info, in the order
+// they are copied
+var frameworkInfoFields = []frameworkInfoField{
+	{
+		name: FrameworkInfoFieldFrameworkID,
+		get: func(ctx context.Context, store FrameworkInfoStore, frameworkName string) (string, error) {
+			return store.GetFrameworkID(ctx, frameworkName)
+		},
+		set: func(ctx context.Context, store FrameworkInfoStore, frameworkName string, value string) error {
+			return store.SetMesosFrameworkID(ctx, frameworkName, value)
+		},
+	},
+	{
+		name: FrameworkInfoFieldMesosStreamID,
+		get: func(ctx context.Context, store FrameworkInfoStore, frameworkName string) (string, error) {
+			return store.GetMesosStreamID(ctx, frameworkName)
+		},
+		set: func(ctx context.Context, store FrameworkInfoStore, frameworkName string, value string) error {
+			return store.SetMesosStreamID(ctx, frameworkName, value)
+		},
+	},
+}
+
+// PlanFrameworkInfoMigration returns the fields of the framework info of a
+// framework name which MigrateFrameworkInfo would copy from src to dst,
+// without copying them
+func PlanFrameworkInfoMigration(
+	ctx context.Context,
+	src FrameworkInfoStore,
+	dst FrameworkInfoStore,
+	frameworkName string,
+	overwrite bool) ([]*FrameworkInfoCopy, error) {
+	var copies []*FrameworkInfoCopy
+	var conflicts []string
+	empty := true
+	for _, field := range frameworkInfoFields {
+		value, err := readFrameworkInfoField(ctx, src, frameworkName, field)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read the source")
+		}
+		if value == "" {
+			continue
+		}
+		empty = false
+
+		oldValue, err := readFrameworkInfoField(ctx, dst, frameworkName, field)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to read the destination")
+		}
+		if oldValue == value {
+			continue
+		}
+		if oldValue != "" && !overwrite {
+			conflicts = append(conflicts,
+				fmt.Sprintf("%s is %q instead of %q", field.name, oldValue, value))
+			continue
+		}
+		copies = append(copies, &FrameworkInfoCopy{
+			Field:    field.name,
+			Value:    value,
+			OldValue: oldValue,
+		})
+	}
+
+	if empty {
+		return nil, errors.Errorf(
+			"no framework info of framework %s in the source", frameworkName)
+	}
+	if len(conflicts) > 0 {
+		return nil, errors.Errorf(
+			"framework info of framework %s differs in the destination: %s",
+			frameworkName, strings.Join(conflicts, ", "))
+	}
+	return copies, nil
+}
+
+// MigrateFrameworkInfo copies the framework id and the mesos stream id of a
+// framework name from src to dst, and verifies that dst returns them. The
+// fields which already differ in dst are only replaced if overwrite is
+// set, nothing is copied otherwise. It returns the fields copied.
+func MigrateFrameworkInfo(
+	ctx context.Context,
+	src FrameworkInfoStore,
+	dst FrameworkInfoStore,
+	frameworkName string,
+	overwrite bool) ([]*FrameworkInfoCopy, error) {
+	copies, err := PlanFrameworkInfoMigration(
+		ctx, src, dst, frameworkName, overwrite)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, c := range copies {
+		field := frameworkInfoFieldByName(c.Field)
+		if err := field.set(ctx, dst, frameworkName, c.Value); err != nil {
+			return nil, errors.Wrapf(err, "failed to copy %s", c.Field)
+		}
+	}
+
+	for _, c := range copies {
+		field := frameworkInfoFieldByName(c.Field)
+		value, err := field.get(ctx, dst, frameworkName)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to verify %s", c.Field)
+		}
+		if value != c.Value {
+			return nil, errors.Errorf(
+				"%s of framework %s is %q instead of %q after the copy",
+				c.Field, frameworkName, value, c.Value)
+		}
+	}
+	return copies, nil
+}
+
+// readFrameworkInfoField reads a field of the framework info of a framework
+// name, which is empty if the framework name has no framework info
+func readFrameworkInfoField(
+	ctx context.Context,
+	store FrameworkInfoStore,
+	frameworkName string,
+	field frameworkInfoField) (string, error) {
+	value, err := field.get(ctx, store, frameworkName)
+	if IsFrameworkInfoNotFound(err) {
+		return "", nil
+	}
+	return value, err
+}
+
+// frameworkInfoFieldByName returns the field of the framework info of a
+// name
+func frameworkInfoFieldByName(name string) frameworkInfoField {
+	for _, field := range frameworkInfoFields {
+		if field.name == name {
+			return field
+		}
+	}
+	panic("unknown framework info field " + name)
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+)
+
+type frameworkInfoMigrationTestSuite struct {
+	suite.Suite
+	ctx context.Context
+	src *InMemoryFrameworkInfoStore
+	dst *InMemoryFrameworkInfoStore
+}
+
+func (suite *frameworkInfoMigrationTestSuite) SetupTest() {
+	suite.ctx = context.Background()
+	suite.src = NewInMemoryFrameworkInfoStore()
+	suite.dst = NewInMemoryFrameworkInfoStore()
+}
+
+func TestFrameworkInfoMigration(t *testing.T) {
+	suite.Run(t, new(frameworkInfoMigrationTestSuite))
+}
+
+// setFrameworkInfo sets the framework id and mesos stream id of the test
+// framework name in a store
+func (suite *frameworkInfoMigrationTestSuite) setFrameworkInfo(
+	store FrameworkInfoStore,
+	frameworkID string,
+	streamID string) {
+	suite.NoError(store.SetMesosFrameworkID(suite.ctx, _testFrameworkName, frameworkID))
+	suite.NoError(store.SetMesosStreamID(suite.ctx, _testFrameworkName, streamID))
+}
+
+// requireFrameworkInfo checks the framework id and mesos stream id of the
+// test framework name in a store
+func (suite *frameworkInfoMigrationTestSuite) requireFrameworkInfo(
+	store FrameworkInfoStore,
+	frameworkID string,
+	streamID string) {
+	id, err := store.GetFrameworkID(suite.ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal(frameworkID, id)
+	id, err = store.GetMesosStreamID(suite.ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal(streamID, id)
+}
+
+// TestEmptySource tests that nothing is copied from a source without
+// framework info
+func (suite *frameworkInfoMigrationTestSuite) TestEmptySource() {
+	_, err := MigrateFrameworkInfo(
+		suite.ctx, suite.src, suite.dst, _testFrameworkName, true)
+	suite.EqualError(err, "no framework info of framework peloton in the source")
+	_, err = suite.dst.GetFrameworkID(suite.ctx, _testFrameworkName)
+	suite.True(IsFrameworkInfoNotFound(err))
+}
+
+// TestSourceError tests that nothing is copied if the source fails
+func (suite *frameworkInfoMigrationTestSuite) TestSourceError() {
+	suite.src.SetError(errors.New("unavailable"))
+	_, err := MigrateFrameworkInfo(
+		suite.ctx, suite.src, suite.dst, _testFrameworkName, false)
+	suite.EqualError(err, "failed to read the source: unavailable")
+}
+
+// TestCopy tests that the framework info is copied, and that copying it
+// again copies nothing
+func (suite *frameworkInfoMigrationTestSuite) TestCopy() {
+	suite.setFrameworkInfo(suite.src, "framework-id", "stream-id")
+
+	copies, err := MigrateFrameworkInfo(
+		suite.ctx, suite.src, suite.dst, _testFrameworkName, false)
+	suite.NoError(err)
+	suite.Equal([]*FrameworkInfoCopy{
+		{Field: FrameworkInfoFieldFrameworkID, Value: "framework-id"},
+		{Field: FrameworkInfoFieldMesosStreamID, Value: "stream-id"},
+	}, copies)
+	suite.requireFrameworkInfo(suite.dst, "framework-id", "stream-id")
+
+	copies, err = MigrateFrameworkInfo(
+		suite.ctx, suite.src, suite.dst, _testFrameworkName, false)
+	suite.NoError(err)
+	suite.Empty(copies)
+}
+
+// TestDryRun tests that planning a migration copies nothing
+func (suite *frameworkInfoMigrationTestSuite) TestDryRun() {
+	suite.setFrameworkInfo(suite.src, "framework-id", "stream-id")
+	suite.NoError(suite.dst.SetMesosFrameworkID(
+		suite.ctx, _testFrameworkName, "framework-id"))
+
+	copies, err := PlanFrameworkInfoMigration(
+		suite.ctx, suite.src, suite.dst, _testFrameworkName, false)
+	suite.NoError(err)
+	suite.Equal([]*FrameworkInfoCopy{
+		{Field: FrameworkInfoFieldMesosStreamID, Value: "stream-id"},
+	}, copies)
+	suite.requireFrameworkInfo(suite.dst, "framework-id", "")
+}
+
+// TestConflict tests that differing framework info in the destination is
+// only replaced with overwrite
+func (suite *frameworkInfoMigrationTestSuite) TestConflict() {
+	suite.setFrameworkInfo(suite.src, "framework-id", "stream-id")
+	suite.setFrameworkInfo(suite.dst, "other-framework-id", "stream-id")
+
+	_, err := MigrateFrameworkInfo(
+		suite.ctx, suite.src, suite.dst, _testFrameworkName, false)
+	suite.EqualError(err, "framework info of framework peloton differs in "+
+		"the destination: framework_id is \"other-framework-id\" instead of "+
+		"\"framework-id\"")
+	suite.requireFrameworkInfo(suite.dst, "other-framework-id", "stream-id")
+
+	copies, err := MigrateFrameworkInfo(
+		suite.ctx, suite.src, suite.dst, _testFrameworkName, true)
+	suite.NoError(err)
+	suite.Equal([]*FrameworkInfoCopy{
+		{
+			Field:    FrameworkInfoFieldFrameworkID,
+			Value:    "framework-id",
+			OldValue: "other-framework-id",
+		},
+	}, copies)
+	suite.requireFrameworkInfo(suite.dst, "framework-id", "stream-id")
+}
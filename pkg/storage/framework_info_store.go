@@ -0,0 +1,254 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// frameworkInfo is the framework info of a framework name
+type frameworkInfo struct {
+	FrameworkID   string `json:"framework_id"`
+	MesosStreamID string `json:"mesos_stream_id"`
+	// MesosStreamIDTime is when the mesos stream id was set, zero if it
+	// was stored without its time
+	MesosStreamIDTime time.Time `json:"mesos_stream_id_time"`
+
+	// History is the history of changes, from the oldest to the most recent
+	History []*FrameworkInfoChange `json:"history,omitempty"`
+}
+
+// InMemoryFrameworkInfoStore is a FrameworkInfoStore which keeps the
+// framework info in memory, for tests and dev clusters without Cassandra.
+// The framework info is lost when the process exits.
+type InMemoryFrameworkInfoStore struct {
+	sync.RWMutex
+
+	frameworks map[string]*frameworkInfo
+
+	// latency delays every call, err is returned by every call if set
+	latency time.Duration
+	err     error
+}
+
+// NewInMemoryFrameworkInfoStore returns an empty in-memory
+// FrameworkInfoStore
+func NewInMemoryFrameworkInfoStore() *InMemoryFrameworkInfoStore {
+	return &InMemoryFrameworkInfoStore{
+		frameworks: make(map[string]*frameworkInfo),
+	}
+}
+
+// SetLatency sets the artificial latency of every call of the store
+func (s *InMemoryFrameworkInfoStore) SetLatency(latency time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.latency = latency
+}
+
+// SetError sets the error returned by every call of the store, calls
+// succeed again once it is set to nil
+func (s *InMemoryFrameworkInfoStore) SetError(err error) {
+	s.Lock()
+	defer s.Unlock()
+	s.err = err
+}
+
+// SetMesosStreamID stores the mesos stream id for a framework name
+func (s *InMemoryFrameworkInfoStore) SetMesosStreamID(
+	ctx context.Context,
+	frameworkName string,
+	mesosStreamID string) error {
+	return s.update(ctx, frameworkName, func(info *frameworkInfo) error {
+		info.setMesosStreamID(mesosStreamID)
+		return nil
+	})
+}
+
+// SetMesosStreamIDIfCurrent stores the mesos stream id for a framework name
+// only if its current mesos stream id is expectedOldID
+func (s *InMemoryFrameworkInfoStore) SetMesosStreamIDIfCurrent(
+	ctx context.Context,
+	frameworkName string,
+	mesosStreamID string,
+	expectedOldID string) error {
+	return s.update(ctx, frameworkName, func(info *frameworkInfo) error {
+		return info.setMesosStreamIDIfCurrent(
+			frameworkName, mesosStreamID, expectedOldID)
+	})
+}
+
+// SetMesosFrameworkID stores the mesos framework id for a framework name
+func (s *InMemoryFrameworkInfoStore) SetMesosFrameworkID(
+	ctx context.Context,
+	frameworkName string,
+	frameworkID string) error {
+	return s.update(ctx, frameworkName, func(info *frameworkInfo) error {
+		info.setFrameworkID(frameworkID)
+		return nil
+	})
+}
+
+// GetMesosStreamID reads the mesos stream id for a framework name
+func (s *InMemoryFrameworkInfoStore) GetMesosStreamID(
+	ctx context.Context,
+	frameworkName string) (string, error) {
+	info, err := s.get(ctx, frameworkName)
+	if err != nil {
+		return "", err
+	}
+	return info.MesosStreamID, nil
+}
+
+// GetMesosStreamIDWithTime reads the mesos stream id for a framework name
+// and when it was set
+func (s *InMemoryFrameworkInfoStore) GetMesosStreamIDWithTime(
+	ctx context.Context,
+	frameworkName string) (string, time.Time, error) {
+	info, err := s.get(ctx, frameworkName)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return info.MesosStreamID, info.MesosStreamIDTime, nil
+}
+
+// GetFrameworkID reads the framework id for a framework name
+func (s *InMemoryFrameworkInfoStore) GetFrameworkID(
+	ctx context.Context,
+	frameworkName string) (string, error) {
+	info, err := s.get(ctx, frameworkName)
+	if err != nil {
+		return "", err
+	}
+	return info.FrameworkID, nil
+}
+
+// GetFrameworkInfoHistory returns up to limit of the most recent changes of
+// the framework info of a framework name, the most recent first
+func (s *InMemoryFrameworkInfoStore) GetFrameworkInfoHistory(
+	ctx context.Context,
+	frameworkName string,
+	limit int) ([]*FrameworkInfoChange, error) {
+	info, err := s.get(ctx, frameworkName)
+	if err != nil {
+		if IsFrameworkInfoNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return recentFrameworkInfoChanges(info.History, limit), nil
+}
+
+// update applies a change to the framework info of a framework name,
+// creating it if it does not exist. Nothing is changed if the change fails.
+func (s *InMemoryFrameworkInfoStore) update(
+	ctx context.Context,
+	frameworkName string,
+	change func(*frameworkInfo) error) error {
+	if err := s.inject(ctx); err != nil {
+		return err
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	var info frameworkInfo
+	if current, ok := s.frameworks[frameworkName]; ok {
+		info = *current
+	}
+	if err := change(&info); err != nil {
+		return err
+	}
+	s.frameworks[frameworkName] = &info
+	return nil
+}
+
+// get returns a copy of the framework info of a framework name
+func (s *InMemoryFrameworkInfoStore) get(
+	ctx context.Context,
+	frameworkName string) (frameworkInfo, error) {
+	if err := s.inject(ctx); err != nil {
+		return frameworkInfo{}, err
+	}
+
+	s.RLock()
+	defer s.RUnlock()
+	info, ok := s.frameworks[frameworkName]
+	if !ok {
+		return frameworkInfo{}, errFrameworkInfoNotFound(frameworkName)
+	}
+	return *info, nil
+}
+
+// setMesosStreamIDIfCurrent sets the mesos stream id of the framework info
+// if it is expectedOldID
+func (info *frameworkInfo) setMesosStreamIDIfCurrent(
+	frameworkName string,
+	mesosStreamID string,
+	expectedOldID string) error {
+	if info.MesosStreamID != expectedOldID {
+		return &MesosStreamIDConflictError{
+			FrameworkName: frameworkName,
+			ExpectedID:    expectedOldID,
+		}
+	}
+	info.setMesosStreamID(mesosStreamID)
+	return nil
+}
+
+// setMesosStreamID sets the mesos stream id of the framework info and
+// records the change in its history
+func (info *frameworkInfo) setMesosStreamID(mesosStreamID string) {
+	change := newFrameworkInfoChange(FrameworkInfoFieldMesosStreamID,
+		info.MesosStreamID, mesosStreamID)
+	info.History = appendFrameworkInfoChange(info.History, change)
+	info.MesosStreamID = mesosStreamID
+	info.MesosStreamIDTime = change.Time
+}
+
+// setFrameworkID sets the framework id of the framework info and records
+// the change in its history
+func (info *frameworkInfo) setFrameworkID(frameworkID string) {
+	info.History = appendFrameworkInfoChange(info.History,
+		newFrameworkInfoChange(FrameworkInfoFieldFrameworkID,
+			info.FrameworkID, frameworkID))
+	info.FrameworkID = frameworkID
+}
+
+// errFrameworkInfoNotFound returns the error of a framework name without
+// framework info
+func errFrameworkInfoNotFound(frameworkName string) error {
+	return &FrameworkInfoNotFoundError{FrameworkName: frameworkName}
+}
+
+// inject waits for the artificial latency, and returns the injected error
+// or the error of the context if it is done first
+func (s *InMemoryFrameworkInfoStore) inject(ctx context.Context) error {
+	s.RLock()
+	latency, err := s.latency, s.err
+	s.RUnlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return err
+}
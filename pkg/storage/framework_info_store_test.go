@@ -0,0 +1,275 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+)
+
+const _testFrameworkName = "peloton"
+
+type inMemoryFrameworkInfoStoreTestSuite struct {
+	suite.Suite
+	store *InMemoryFrameworkInfoStore
+}
+
+func (suite *inMemoryFrameworkInfoStoreTestSuite) SetupTest() {
+	suite.store = NewInMemoryFrameworkInfoStore()
+}
+
+func TestInMemoryFrameworkInfoStore(t *testing.T) {
+	suite.Run(t, new(inMemoryFrameworkInfoStoreTestSuite))
+}
+
+// TestSetAndGet tests that the framework and stream ids of a framework are
+// stored independently
+func (suite *inMemoryFrameworkInfoStoreTestSuite) TestSetAndGet() {
+	ctx := context.Background()
+
+	_, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.EqualError(err, "FrameworkInfo not found for framework peloton")
+	_, err = suite.store.GetMesosStreamID(ctx, _testFrameworkName)
+	suite.Error(err)
+
+	suite.NoError(suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "framework-id"))
+	id, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("framework-id", id)
+	streamID, err := suite.store.GetMesosStreamID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Empty(streamID)
+
+	suite.NoError(suite.store.SetMesosStreamID(ctx, _testFrameworkName, "stream-id"))
+	streamID, err = suite.store.GetMesosStreamID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("stream-id", streamID)
+	id, err = suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("framework-id", id)
+
+	_, err = suite.store.GetFrameworkID(ctx, "other")
+	suite.Error(err)
+}
+
+// TestConcurrentAccess tests concurrent updates and reads of the store
+func (suite *inMemoryFrameworkInfoStoreTestSuite) TestConcurrentAccess() {
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("framework-%d", i%2)
+			for j := 0; j < 100; j++ {
+				suite.NoError(suite.store.SetMesosStreamID(ctx, name, fmt.Sprint(j)))
+				_, err := suite.store.GetMesosStreamID(ctx, name)
+				suite.NoError(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	for _, name := range []string{"framework-0", "framework-1"} {
+		streamID, err := suite.store.GetMesosStreamID(ctx, name)
+		suite.NoError(err)
+		suite.Equal("99", streamID)
+	}
+}
+
+// TestInjectedError tests that the injected error fails every call until
+// it is cleared
+func (suite *inMemoryFrameworkInfoStoreTestSuite) TestInjectedError() {
+	ctx := context.Background()
+	injected := errors.New("store unavailable")
+	suite.store.SetError(injected)
+
+	suite.Equal(injected, suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "id"))
+	suite.Equal(injected, suite.store.SetMesosStreamID(ctx, _testFrameworkName, "id"))
+	_, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.Equal(injected, err)
+
+	suite.store.SetError(nil)
+	suite.NoError(suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "id"))
+	id, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("id", id)
+}
+
+// TestInjectedLatency tests that calls are delayed by the latency and
+// bounded by their context
+func (suite *inMemoryFrameworkInfoStoreTestSuite) TestInjectedLatency() {
+	suite.store.SetLatency(10 * time.Millisecond)
+	start := time.Now()
+	suite.NoError(suite.store.SetMesosStreamID(
+		context.Background(), _testFrameworkName, "stream-id"))
+	suite.True(time.Since(start) >= 10*time.Millisecond)
+
+	suite.store.SetLatency(time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err := suite.store.GetMesosStreamID(ctx, _testFrameworkName)
+	suite.Equal(context.DeadlineExceeded, err)
+}
+
+// TestSetMesosStreamIDIfCurrent tests that the mesos stream id is only
+// replaced if it is the expected one
+func (suite *inMemoryFrameworkInfoStoreTestSuite) TestSetMesosStreamIDIfCurrent() {
+	ctx := context.Background()
+
+	// a framework without a stream id matches an empty expected id
+	err := suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "stream-1", "stream-0")
+	suite.True(IsMesosStreamIDConflict(err))
+	_, err = suite.store.GetMesosStreamID(ctx, _testFrameworkName)
+	suite.Error(err)
+	suite.NoError(suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "stream-1", ""))
+
+	suite.NoError(suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "stream-2", "stream-1"))
+
+	err = suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "stream-3", "stream-1")
+	suite.EqualError(err,
+		`mesos stream id of framework peloton is no longer "stream-1"`)
+	suite.True(IsMesosStreamIDConflict(err))
+	streamID, err := suite.store.GetMesosStreamID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("stream-2", streamID)
+}
+
+// TestConcurrentSetMesosStreamIDIfCurrent tests that only one of the
+// subscribers expecting the same stream id replaces it
+func (suite *inMemoryFrameworkInfoStoreTestSuite) TestConcurrentSetMesosStreamIDIfCurrent() {
+	ctx := context.Background()
+	suite.NoError(suite.store.SetMesosStreamID(ctx, _testFrameworkName, "stream-0"))
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var replaced []string
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			streamID := fmt.Sprintf("stream-%d", i)
+			err := suite.store.SetMesosStreamIDIfCurrent(
+				ctx, _testFrameworkName, streamID, "stream-0")
+			if err == nil {
+				lock.Lock()
+				replaced = append(replaced, streamID)
+				lock.Unlock()
+				return
+			}
+			suite.True(IsMesosStreamIDConflict(err))
+		}(i)
+	}
+	wg.Wait()
+
+	suite.Len(replaced, 1)
+	streamID, err := suite.store.GetMesosStreamID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal(replaced[0], streamID)
+}
+
+// TestHistory tests that every update is recorded in the history with its
+// old value, the most recent first
+func (suite *inMemoryFrameworkInfoStoreTestSuite) TestHistory() {
+	ctx := context.Background()
+	changes, err := suite.store.GetFrameworkInfoHistory(ctx, _testFrameworkName, 0)
+	suite.NoError(err)
+	suite.Empty(changes)
+
+	suite.NoError(suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "framework-id"))
+	suite.NoError(suite.store.SetMesosStreamID(ctx, _testFrameworkName, "stream-1"))
+	suite.NoError(suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "stream-2", "stream-1"))
+	// a conflicting update is not recorded
+	suite.Error(suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "stream-3", "stream-1"))
+
+	changes, err = suite.store.GetFrameworkInfoHistory(ctx, _testFrameworkName, 0)
+	suite.NoError(err)
+	suite.Len(changes, 3)
+	suite.Equal(FrameworkInfoFieldMesosStreamID, changes[0].Field)
+	suite.Equal("stream-1", changes[0].OldValue)
+	suite.Equal("stream-2", changes[0].NewValue)
+	suite.Equal("", changes[1].OldValue)
+	suite.Equal("stream-1", changes[1].NewValue)
+	suite.Equal(FrameworkInfoFieldFrameworkID, changes[2].Field)
+	suite.Equal("framework-id", changes[2].NewValue)
+	suite.NotEmpty(changes[2].Host)
+	suite.False(changes[0].Time.Before(changes[2].Time))
+
+	changes, err = suite.store.GetFrameworkInfoHistory(ctx, _testFrameworkName, 2)
+	suite.NoError(err)
+	suite.Len(changes, 2)
+	suite.Equal("stream-2", changes[0].NewValue)
+}
+
+// TestHistoryTruncation tests that only the most recent changes are
+// retained in the history
+func (suite *inMemoryFrameworkInfoStoreTestSuite) TestHistoryTruncation() {
+	ctx := context.Background()
+	for i := 0; i < MaxFrameworkInfoHistory+5; i++ {
+		suite.NoError(suite.store.SetMesosStreamID(
+			ctx, _testFrameworkName, fmt.Sprint(i)))
+	}
+
+	changes, err := suite.store.GetFrameworkInfoHistory(ctx, _testFrameworkName, 0)
+	suite.NoError(err)
+	suite.Len(changes, MaxFrameworkInfoHistory)
+	suite.Equal(fmt.Sprint(MaxFrameworkInfoHistory+4), changes[0].NewValue)
+	suite.Equal("5", changes[MaxFrameworkInfoHistory-1].NewValue)
+
+	changes, err = suite.store.GetFrameworkInfoHistory(
+		ctx, _testFrameworkName, 2*MaxFrameworkInfoHistory)
+	suite.NoError(err)
+	suite.Len(changes, MaxFrameworkInfoHistory)
+}
+
+// TestMesosStreamIDTime tests that the time the stream id was set is
+// stored with it
+func (suite *inMemoryFrameworkInfoStoreTestSuite) TestMesosStreamIDTime() {
+	ctx := context.Background()
+	_, _, err := suite.store.GetMesosStreamIDWithTime(ctx, _testFrameworkName)
+	suite.Error(err)
+
+	// a framework id alone does not set the time of the stream id
+	suite.NoError(suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "framework-id"))
+	_, streamIDTime, err := suite.store.GetMesosStreamIDWithTime(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.True(streamIDTime.IsZero())
+
+	before := time.Now()
+	suite.NoError(suite.store.SetMesosStreamID(ctx, _testFrameworkName, "stream-1"))
+	streamID, streamIDTime, err := suite.store.GetMesosStreamIDWithTime(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("stream-1", streamID)
+	suite.False(streamIDTime.Before(before))
+	suite.False(streamIDTime.After(time.Now()))
+
+	suite.NoError(suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "stream-2", "stream-1"))
+	_, updatedTime, err := suite.store.GetMesosStreamIDWithTime(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.False(updatedTime.Before(streamIDTime))
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"time"
+
+	"github.com/uber-go/tally"
+)
+
+// InstrumentedFrameworkInfoStore is a FrameworkInfoStore which records the
+// latency and result of every call of another FrameworkInfoStore
+type InstrumentedFrameworkInfoStore struct {
+	store   FrameworkInfoStore
+	metrics *OperationMetrics
+}
+
+// NewInstrumentedFrameworkInfoStore returns a FrameworkInfoStore recording
+// the metrics of the calls of the store of the backend in the scope
+func NewInstrumentedFrameworkInfoStore(
+	store FrameworkInfoStore,
+	backend string,
+	scope tally.Scope) *InstrumentedFrameworkInfoStore {
+	return &InstrumentedFrameworkInfoStore{
+		store:   store,
+		metrics: NewOperationMetrics(scope, backend),
+	}
+}
+
+// SetMesosStreamID stores the mesos stream id for a framework name
+func (s *InstrumentedFrameworkInfoStore) SetMesosStreamID(
+	ctx context.Context,
+	frameworkName string,
+	mesosStreamID string) error {
+	return s.metrics.Record("set_mesos_stream_id", func() error {
+		return s.store.SetMesosStreamID(ctx, frameworkName, mesosStreamID)
+	})
+}
+
+// SetMesosStreamIDIfCurrent stores the mesos stream id for a framework name
+// only if its current mesos stream id is expectedOldID
+func (s *InstrumentedFrameworkInfoStore) SetMesosStreamIDIfCurrent(
+	ctx context.Context,
+	frameworkName string,
+	mesosStreamID string,
+	expectedOldID string) error {
+	return s.metrics.Record("set_mesos_stream_id_if_current", func() error {
+		return s.store.SetMesosStreamIDIfCurrent(
+			ctx, frameworkName, mesosStreamID, expectedOldID)
+	})
+}
+
+// SetMesosFrameworkID stores the mesos framework id for a framework name
+func (s *InstrumentedFrameworkInfoStore) SetMesosFrameworkID(
+	ctx context.Context,
+	frameworkName string,
+	frameworkID string) error {
+	return s.metrics.Record("set_mesos_framework_id", func() error {
+		return s.store.SetMesosFrameworkID(ctx, frameworkName, frameworkID)
+	})
+}
+
+// GetMesosStreamID reads the mesos stream id for a framework name
+func (s *InstrumentedFrameworkInfoStore) GetMesosStreamID(
+	ctx context.Context,
+	frameworkName string) (string, error) {
+	var streamID string
+	err := s.metrics.Record("get_mesos_stream_id", func() error {
+		var err error
+		streamID, err = s.store.GetMesosStreamID(ctx, frameworkName)
+		return err
+	})
+	return streamID, err
+}
+
+// GetMesosStreamIDWithTime reads the mesos stream id for a framework name
+// and when it was set
+func (s *InstrumentedFrameworkInfoStore) GetMesosStreamIDWithTime(
+	ctx context.Context,
+	frameworkName string) (string, time.Time, error) {
+	var streamID string
+	var streamIDTime time.Time
+	err := s.metrics.Record("get_mesos_stream_id_with_time", func() error {
+		var err error
+		streamID, streamIDTime, err = s.store.GetMesosStreamIDWithTime(
+			ctx, frameworkName)
+		return err
+	})
+	return streamID, streamIDTime, err
+}
+
+// GetFrameworkID reads the framework id for a framework name
+func (s *InstrumentedFrameworkInfoStore) GetFrameworkID(
+	ctx context.Context,
+	frameworkName string) (string, error) {
+	var frameworkID string
+	err := s.metrics.Record("get_framework_id", func() error {
+		var err error
+		frameworkID, err = s.store.GetFrameworkID(ctx, frameworkName)
+		return err
+	})
+	return frameworkID, err
+}
+
+// GetFrameworkInfoHistory returns up to limit of the most recent changes of
+// the framework info of a framework name, the most recent first
+func (s *InstrumentedFrameworkInfoStore) GetFrameworkInfoHistory(
+	ctx context.Context,
+	frameworkName string,
+	limit int) ([]*FrameworkInfoChange, error) {
+	var changes []*FrameworkInfoChange
+	err := s.metrics.Record("get_framework_info_history", func() error {
+		var err error
+		changes, err = s.store.GetFrameworkInfoHistory(ctx, frameworkName, limit)
+		return err
+	})
+	return changes, err
+}
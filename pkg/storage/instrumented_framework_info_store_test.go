@@ -0,0 +1,122 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/suite"
+	"github.com/uber-go/tally"
+)
+
+// failingStreamIDStore is a FrameworkInfoStore whose reads of the mesos
+// stream id fail
+type failingStreamIDStore struct {
+	*InMemoryFrameworkInfoStore
+}
+
+func (s *failingStreamIDStore) GetMesosStreamID(
+	ctx context.Context,
+	frameworkName string) (string, error) {
+	return "", errors.New("stream id unavailable")
+}
+
+type instrumentedFrameworkInfoStoreTestSuite struct {
+	suite.Suite
+	scope   tally.TestScope
+	backend *InMemoryFrameworkInfoStore
+	store   *InstrumentedFrameworkInfoStore
+}
+
+func (suite *instrumentedFrameworkInfoStoreTestSuite) SetupTest() {
+	suite.scope = tally.NewTestScope("", nil)
+	suite.backend = NewInMemoryFrameworkInfoStore()
+	suite.store = NewInstrumentedFrameworkInfoStore(
+		&failingStreamIDStore{suite.backend}, "memory", suite.scope)
+}
+
+func TestInstrumentedFrameworkInfoStore(t *testing.T) {
+	suite.Run(t, new(instrumentedFrameworkInfoStoreTestSuite))
+}
+
+// counter returns the value of the calls counter of an operation with a
+// result
+func (suite *instrumentedFrameworkInfoStoreTestSuite) counter(
+	operation string,
+	result string) int64 {
+	key := "calls+backend=memory,operation=" + operation + ",result=" + result
+	counter, ok := suite.scope.Snapshot().Counters()[key]
+	if !ok {
+		return 0
+	}
+	return counter.Value()
+}
+
+// TestCalls tests that the calls are passed to the store, and counted by
+// operation and result
+func (suite *instrumentedFrameworkInfoStoreTestSuite) TestCalls() {
+	ctx := context.Background()
+
+	suite.NoError(suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "framework-id"))
+	id, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("framework-id", id)
+	_, err = suite.store.GetFrameworkID(ctx, "other")
+	suite.Error(err)
+
+	suite.NoError(suite.store.SetMesosStreamID(ctx, _testFrameworkName, "stream-id"))
+	streamID, err := suite.backend.GetMesosStreamID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("stream-id", streamID)
+	_, err = suite.store.GetMesosStreamID(ctx, _testFrameworkName)
+	suite.EqualError(err, "stream id unavailable")
+
+	suite.True(IsMesosStreamIDConflict(suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "other-stream-id", "")))
+	changes, err := suite.store.GetFrameworkInfoHistory(ctx, _testFrameworkName, 0)
+	suite.NoError(err)
+	suite.Len(changes, 2)
+
+	suite.Equal(int64(1), suite.counter("set_mesos_framework_id", "success"))
+	suite.Equal(int64(1), suite.counter("get_framework_id", "success"))
+	suite.Equal(int64(1), suite.counter("get_framework_id", "fail"))
+	suite.Equal(int64(1), suite.counter("set_mesos_stream_id", "success"))
+	suite.Equal(int64(0), suite.counter("get_mesos_stream_id", "success"))
+	suite.Equal(int64(1), suite.counter("get_mesos_stream_id", "fail"))
+	suite.Equal(int64(1), suite.counter("set_mesos_stream_id_if_current", "fail"))
+	suite.Equal(int64(1), suite.counter("get_framework_info_history", "success"))
+}
+
+// TestLatency tests that the latency of the calls is recorded
+func (suite *instrumentedFrameworkInfoStoreTestSuite) TestLatency() {
+	suite.backend.SetLatency(5 * time.Millisecond)
+	suite.NoError(suite.store.SetMesosStreamID(
+		context.Background(), _testFrameworkName, "stream-id"))
+
+	key := "latency+backend=memory,operation=set_mesos_stream_id"
+	histogram, ok := suite.scope.Snapshot().Histograms()[key]
+	suite.True(ok)
+	var count int64
+	for upper, n := range histogram.Durations() {
+		if n > 0 {
+			suite.True(upper >= 5*time.Millisecond)
+		}
+		count += n
+	}
+	suite.Equal(int64(1), count)
+}
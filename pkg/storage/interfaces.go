@@ -17,6 +17,7 @@ package storage
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/uber/peloton/.gen/peloton/api/v0/job"
 	"github.com/uber/peloton/.gen/peloton/api/v0/peloton"
@@ -241,9 +242,20 @@ type UpdateStore interface {
 // FrameworkInfoStore is the interface to store mesosStreamID for peloton frameworks
 type FrameworkInfoStore interface {
 	SetMesosStreamID(ctx context.Context, frameworkName string, mesosStreamID string) error
+	// SetMesosStreamIDIfCurrent sets the mesos stream id only if the
+	// current one is expectedOldID, and returns a
+	// MesosStreamIDConflictError otherwise
+	SetMesosStreamIDIfCurrent(ctx context.Context, frameworkName string, mesosStreamID string, expectedOldID string) error
 	SetMesosFrameworkID(ctx context.Context, frameworkName string, frameworkID string) error
 	GetMesosStreamID(ctx context.Context, frameworkName string) (string, error)
+	// GetMesosStreamIDWithTime returns the mesos stream id and when it was
+	// set, which is zero for a mesos stream id stored without its time
+	GetMesosStreamIDWithTime(ctx context.Context, frameworkName string) (string, time.Time, error)
 	GetFrameworkID(ctx context.Context, frameworkName string) (string, error)
+	// GetFrameworkInfoHistory returns up to limit of the most recent
+	// changes of the framework id and mesos stream id, the most recent
+	// first, or all retained changes if limit is not positive
+	GetFrameworkInfoHistory(ctx context.Context, frameworkName string, limit int) ([]*FrameworkInfoChange, error)
 }
 
 // ResourcePoolStore is the interface to store all the resource pool information
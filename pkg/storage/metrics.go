@@ -244,6 +244,11 @@ type FrameworkStoreMetrics struct {
 	FrameworkIDGetFail  tally.Counter
 	StreamIDGet         tally.Counter
 	StreamIDGetFail     tally.Counter
+	StreamIDConflict    tally.Counter
+	HistoryAdd          tally.Counter
+	HistoryAddFail      tally.Counter
+	HistoryGet          tally.Counter
+	HistoryGetFail      tally.Counter
 }
 
 // VolumeMetrics is a struct for tracking disk related counters in the storage layer
@@ -355,6 +360,10 @@ func NewMetrics(scope tally.Scope) *Metrics {
 	streamIDSuccessScope := streamIDScope.Tagged(map[string]string{"result": "success"})
 	streamIDFailScope := streamIDScope.Tagged(map[string]string{"result": "fail"})
 
+	frameworkHistoryScope := scope.SubScope("framework_history")
+	frameworkHistorySuccessScope := frameworkHistoryScope.Tagged(map[string]string{"result": "success"})
+	frameworkHistoryFailScope := frameworkHistoryScope.Tagged(map[string]string{"result": "fail"})
+
 	volumeScope := scope.SubScope("persistent_volume")
 	volumeSuccessScope := volumeScope.Tagged(map[string]string{"result": "success"})
 	volumeFailScope := volumeScope.Tagged(map[string]string{"result": "fail"})
@@ -518,8 +527,14 @@ func NewMetrics(scope tally.Scope) *Metrics {
 		FrameworkUpdate:     frameworkIDSuccessScope.Counter("update"),
 		FrameworkUpdateFail: frameworkIDFailScope.Counter("update"),
 
-		StreamIDGet:     streamIDSuccessScope.Counter("get"),
-		StreamIDGetFail: streamIDFailScope.Counter("get"),
+		StreamIDGet:      streamIDSuccessScope.Counter("get"),
+		StreamIDGetFail:  streamIDFailScope.Counter("get"),
+		StreamIDConflict: streamIDFailScope.Counter("conflict"),
+
+		HistoryAdd:     frameworkHistorySuccessScope.Counter("add"),
+		HistoryAddFail: frameworkHistoryFailScope.Counter("add"),
+		HistoryGet:     frameworkHistorySuccessScope.Counter("get"),
+		HistoryGetFail: frameworkHistoryFailScope.Counter("get"),
 	}
 
 	volumeMetrics := &VolumeMetrics{
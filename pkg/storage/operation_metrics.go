@@ -0,0 +1,93 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"sync"
+	"time"
+
+	"github.com/uber-go/tally"
+)
+
+// _operationLatencyBuckets are the buckets of the latency histograms of
+// store operations, from 1ms to ~33s
+var _operationLatencyBuckets = tally.MustMakeExponentialDurationBuckets(
+	time.Millisecond, 2, 16)
+
+// OperationMetrics records the latency and the success and failure counts
+// of the operations of a store, tagged by store backend and operation. It
+// is meant for instrumenting decorators of small store interfaces, such as
+// InstrumentedFrameworkInfoStore.
+type OperationMetrics struct {
+	sync.RWMutex
+
+	scope      tally.Scope
+	operations map[string]*operationMetrics
+}
+
+// operationMetrics are the metrics of one operation
+type operationMetrics struct {
+	success tally.Counter
+	fail    tally.Counter
+	latency tally.Histogram
+}
+
+// NewOperationMetrics returns the operation metrics of a store backend,
+// e.g. "cassandra", in the scope
+func NewOperationMetrics(scope tally.Scope, backend string) *OperationMetrics {
+	return &OperationMetrics{
+		scope:      scope.Tagged(map[string]string{"backend": backend}),
+		operations: make(map[string]*operationMetrics),
+	}
+}
+
+// Record runs an operation of the store, and records its latency and
+// whether it failed
+func (m *OperationMetrics) Record(operation string, f func() error) error {
+	metrics := m.get(operation)
+	start := time.Now()
+	err := f()
+	metrics.latency.RecordDuration(time.Since(start))
+	if err != nil {
+		metrics.fail.Inc(1)
+		return err
+	}
+	metrics.success.Inc(1)
+	return nil
+}
+
+// get returns the metrics of an operation, creating them on first use
+func (m *OperationMetrics) get(operation string) *operationMetrics {
+	m.RLock()
+	metrics, ok := m.operations[operation]
+	m.RUnlock()
+	if ok {
+		return metrics
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	if metrics, ok := m.operations[operation]; ok {
+		return metrics
+	}
+	scope := m.scope.Tagged(map[string]string{"operation": operation})
+	metrics = &operationMetrics{
+		success: scope.Tagged(map[string]string{"result": "success"}).Counter("calls"),
+		fail:    scope.Tagged(map[string]string{"result": "fail"}).Counter("calls"),
+		latency: scope.Histogram("latency", _operationLatencyBuckets),
+	}
+	m.operations[operation] = metrics
+	return metrics
+}
@@ -15,8 +15,12 @@
 package stores
 
 import (
+	"path"
+
+	kvstore "github.com/docker/libkv/store"
 	log "github.com/sirupsen/logrus"
 	"github.com/uber-go/tally"
+	"github.com/uber/peloton/pkg/common/leader"
 	"github.com/uber/peloton/pkg/storage"
 	"github.com/uber/peloton/pkg/storage/cassandra"
 	storage_config "github.com/uber/peloton/pkg/storage/config"
@@ -41,3 +45,107 @@ func MustCreateStore(
 	}
 	return store
 }
+
+// frameworkInfoBackendCassandra tags the metrics of the generic store used
+// as FrameworkInfoStore, other backends are tagged by their config value
+const frameworkInfoBackendCassandra = "cassandra"
+
+// MustCreateFrameworkInfoStore returns the FrameworkInfoStore selected by
+// the config, which is the generic store unless the framework info is kept
+// in memory, in a file or in the ZK servers of the election config, and
+// exits if the config is invalid. The calls of the store are instrumented
+// in the framework_info_store sub scope of the root scope, its mesos
+// stream ids are encrypted if keys are configured, and its reads are cached
+// if a cache TTL is configured.
+func MustCreateFrameworkInfoStore(
+	cfg *storage_config.Config,
+	store storage.Store,
+	election leader.ElectionConfig,
+	zkClient kvstore.Store,
+	rootScope tally.Scope) storage.FrameworkInfoStore {
+	frameworkInfoStore := mustCreateFrameworkInfoStore(
+		cfg, store, election, zkClient)
+	if len(cfg.FrameworkInfoKeyFiles) > 0 {
+		var sources []storage.CipherKeySource
+		for _, file := range cfg.FrameworkInfoKeyFiles {
+			sources = append(sources, storage.CipherKeyFile(file))
+		}
+		cipher, err := storage.NewAESGCMCipherProvider(sources...)
+		if err != nil {
+			log.Fatalf("Could not load framework info keys: %+v", err)
+		}
+		frameworkInfoStore = storage.NewEncryptedFrameworkInfoStore(
+			frameworkInfoStore, cipher)
+	}
+	backend := cfg.FrameworkInfoStore
+	if backend == "" {
+		backend = frameworkInfoBackendCassandra
+	}
+	scope := rootScope.SubScope("framework_info_store")
+	frameworkInfoStore = storage.NewInstrumentedFrameworkInfoStore(
+		frameworkInfoStore,
+		backend,
+		scope,
+	)
+	if cfg.FrameworkInfoCacheTTL > 0 {
+		frameworkInfoStore = storage.NewCachedFrameworkInfoStore(
+			frameworkInfoStore,
+			cfg.FrameworkInfoCacheTTL,
+			scope,
+		)
+	}
+	return frameworkInfoStore
+}
+
+// IsSharedFrameworkInfoStore returns whether the FrameworkInfoStore
+// selected by the config can be read by other processes than the one
+// registering the framework. The memory and file stores are local to the
+// host manager which registers the framework.
+func IsSharedFrameworkInfoStore(cfg *storage_config.Config) bool {
+	switch cfg.FrameworkInfoStore {
+	case storage_config.FrameworkInfoStoreMemory,
+		storage_config.FrameworkInfoStoreFile:
+		return false
+	}
+	return true
+}
+
+// mustCreateFrameworkInfoStore returns the FrameworkInfoStore selected by
+// the config without instrumentation
+func mustCreateFrameworkInfoStore(
+	cfg *storage_config.Config,
+	store storage.Store,
+	election leader.ElectionConfig,
+	zkClient kvstore.Store) storage.FrameworkInfoStore {
+	switch cfg.FrameworkInfoStore {
+	case "":
+		return store
+	case storage_config.FrameworkInfoStoreMemory:
+		log.Warn("Framework info is kept in memory and lost on restart")
+		return storage.NewInMemoryFrameworkInfoStore()
+	case storage_config.FrameworkInfoStoreFile:
+		if cfg.FrameworkInfoPath == "" {
+			log.Fatal("framework_info_path is required to keep framework info in a file")
+		}
+		log.WithField("path", cfg.FrameworkInfoPath).
+			Info("Framework info is kept in a file")
+		return storage.NewFileFrameworkInfoStore(cfg.FrameworkInfoPath)
+	case storage_config.FrameworkInfoStoreZk:
+		root := cfg.FrameworkInfoZkRoot
+		if root == "" {
+			root = path.Join(election.Root, "framework")
+		}
+		if zkClient == nil {
+			var err error
+			zkClient, err = leader.NewZkClient(election)
+			if err != nil {
+				log.Fatalf("Could not create zookeeper client of framework info: %+v", err)
+			}
+		}
+		log.WithField("root", root).Info("Framework info is kept in zookeeper")
+		return storage.NewZkFrameworkInfoStore(zkClient, root)
+	default:
+		log.Fatalf("Invalid framework info store %q", cfg.FrameworkInfoStore)
+	}
+	return nil
+}
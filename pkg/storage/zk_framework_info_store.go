@@ -0,0 +1,363 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/uber/peloton/pkg/common/backoff"
+
+	"github.com/docker/libkv/store"
+	"github.com/pkg/errors"
+	"github.com/samuel/go-zookeeper/zk"
+	log "github.com/sirupsen/logrus"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	// znodes of the framework id and mesos stream id of a framework name
+	_zkFrameworkIDNode   = "id"
+	_zkMesosStreamIDNode = "stream_id"
+	// znode of when the mesos stream id was set, in RFC 3339 format
+	_zkMesosStreamIDTimeNode = "stream_id_time"
+	// znode of the JSON encoded history of changes of a framework name
+	_zkHistoryNode = "history"
+
+	// _zkFrameworkInfoAttempts is the maximum number of attempts of a ZK
+	// call which fails because ZK is unavailable, e.g. while the session
+	// is re-established after it expired
+	_zkFrameworkInfoAttempts = 3
+	// _zkFrameworkInfoRetryInterval is the delay between retries
+	_zkFrameworkInfoRetryInterval = 500 * time.Millisecond
+)
+
+// ZkFrameworkInfoStore is a FrameworkInfoStore which keeps the framework
+// info in ZK znodes, so that it does not depend on Cassandra. The framework
+// id and mesos stream id of a framework name are the znodes
+// <root>/<name>/id and <root>/<name>/stream_id, when the mesos stream id was
+// set is the znode <root>/<name>/stream_id_time, and the history of changes
+// is the znode <root>/<name>/history.
+type ZkFrameworkInfoStore struct {
+	client      store.Store
+	root        string
+	retryPolicy backoff.RetryPolicy
+}
+
+// NewZkFrameworkInfoStore returns a FrameworkInfoStore keeping the
+// framework info under the root path, e.g. /peloton/<cluster>/framework,
+// using the ZK client, e.g. one returned by leader.NewZkClient
+func NewZkFrameworkInfoStore(
+	client store.Store,
+	root string) *ZkFrameworkInfoStore {
+	return &ZkFrameworkInfoStore{
+		client: client,
+		// NOTE: remember, there cannot be a leading / for libkv
+		root: strings.TrimPrefix(root, "/"),
+		retryPolicy: backoff.NewRetryPolicy(
+			_zkFrameworkInfoAttempts, _zkFrameworkInfoRetryInterval),
+	}
+}
+
+// SetMesosStreamID stores the mesos stream id for a framework name
+func (s *ZkFrameworkInfoStore) SetMesosStreamID(
+	ctx context.Context,
+	frameworkName string,
+	mesosStreamID string) error {
+	err := s.put(frameworkName, _zkMesosStreamIDNode,
+		FrameworkInfoFieldMesosStreamID, mesosStreamID)
+	if err != nil {
+		return err
+	}
+	return s.setMesosStreamIDTime(frameworkName)
+}
+
+// SetMesosStreamIDIfCurrent stores the mesos stream id for a framework name
+// only if its current mesos stream id is expectedOldID, using the version
+// of the znode read
+func (s *ZkFrameworkInfoStore) SetMesosStreamIDIfCurrent(
+	ctx context.Context,
+	frameworkName string,
+	mesosStreamID string,
+	expectedOldID string) error {
+	key := s.key(frameworkName, _zkMesosStreamIDNode)
+	conflict := &MesosStreamIDConflictError{
+		FrameworkName: frameworkName,
+		ExpectedID:    expectedOldID,
+	}
+	err := backoff.Retry(
+		func() error {
+			// a missing znode is created if no mesos stream id is expected
+			previous, err := s.client.Get(key)
+			if err == store.ErrKeyNotFound {
+				previous, err = nil, nil
+			}
+			if err != nil {
+				return err
+			}
+			if previous == nil && expectedOldID != "" ||
+				previous != nil && string(previous.Value) != expectedOldID {
+				return conflict
+			}
+
+			_, _, err = s.client.AtomicPut(key, []byte(mesosStreamID), previous, nil)
+			if err == store.ErrKeyModified || err == store.ErrKeyExists {
+				return conflict
+			}
+			return err
+		},
+		s.retryPolicy,
+		isZkUnavailable,
+	)
+	if err == conflict {
+		return err
+	}
+	if err != nil {
+		return zkFrameworkInfoError(err, key)
+	}
+	s.record(frameworkName, newFrameworkInfoChange(
+		FrameworkInfoFieldMesosStreamID, expectedOldID, mesosStreamID))
+	return s.setMesosStreamIDTime(frameworkName)
+}
+
+// SetMesosFrameworkID stores the mesos framework id for a framework name
+func (s *ZkFrameworkInfoStore) SetMesosFrameworkID(
+	ctx context.Context,
+	frameworkName string,
+	frameworkID string) error {
+	return s.put(frameworkName, _zkFrameworkIDNode,
+		FrameworkInfoFieldFrameworkID, frameworkID)
+}
+
+// GetMesosStreamID reads the mesos stream id for a framework name
+func (s *ZkFrameworkInfoStore) GetMesosStreamID(
+	ctx context.Context,
+	frameworkName string) (string, error) {
+	return s.get(frameworkName, _zkMesosStreamIDNode)
+}
+
+// GetMesosStreamIDWithTime reads the mesos stream id for a framework name
+// and when it was set
+func (s *ZkFrameworkInfoStore) GetMesosStreamIDWithTime(
+	ctx context.Context,
+	frameworkName string) (string, time.Time, error) {
+	streamID, err := s.get(frameworkName, _zkMesosStreamIDNode)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	// the time is unknown for a mesos stream id set before it was stored
+	key := s.key(frameworkName, _zkMesosStreamIDTimeNode)
+	pair, err := s.read(key)
+	if err == store.ErrKeyNotFound {
+		return streamID, time.Time{}, nil
+	}
+	if err != nil {
+		return "", time.Time{}, zkFrameworkInfoError(err, key)
+	}
+	streamIDTime, err := time.Parse(time.RFC3339Nano, string(pair.Value))
+	if err != nil {
+		log.WithError(err).
+			WithField("key", key).
+			Warn("Invalid time of the mesos stream id, ignoring it")
+		return streamID, time.Time{}, nil
+	}
+	return streamID, streamIDTime, nil
+}
+
+// GetFrameworkID reads the framework id for a framework name
+func (s *ZkFrameworkInfoStore) GetFrameworkID(
+	ctx context.Context,
+	frameworkName string) (string, error) {
+	return s.get(frameworkName, _zkFrameworkIDNode)
+}
+
+// GetFrameworkInfoHistory returns up to limit of the most recent changes of
+// the framework info of a framework name, the most recent first
+func (s *ZkFrameworkInfoStore) GetFrameworkInfoHistory(
+	ctx context.Context,
+	frameworkName string,
+	limit int) ([]*FrameworkInfoChange, error) {
+	key := s.key(frameworkName, _zkHistoryNode)
+	pair, err := s.read(key)
+	if err == store.ErrKeyNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, zkFrameworkInfoError(err, key)
+	}
+	var history []*FrameworkInfoChange
+	if err := json.Unmarshal(pair.Value, &history); err != nil {
+		return nil, errors.Wrapf(err, "invalid framework info history %s", key)
+	}
+	return recentFrameworkInfoChanges(history, limit), nil
+}
+
+// key returns the key of a znode of a framework name
+func (s *ZkFrameworkInfoStore) key(frameworkName string, node string) string {
+	return path.Join(s.root, frameworkName, node)
+}
+
+// put creates or sets a znode of a framework name, and records the change
+// of the field in the history
+func (s *ZkFrameworkInfoStore) put(
+	frameworkName string,
+	node string,
+	field string,
+	value string) error {
+	key := s.key(frameworkName, node)
+	var oldValue string
+	pair, err := s.read(key)
+	if err == nil {
+		oldValue = string(pair.Value)
+	} else if err != store.ErrKeyNotFound {
+		return zkFrameworkInfoError(err, key)
+	}
+
+	if err := s.write(key, value); err != nil {
+		return err
+	}
+	s.record(frameworkName, newFrameworkInfoChange(field, oldValue, value))
+	return nil
+}
+
+// setMesosStreamIDTime sets the time of the mesos stream id of a framework
+// name to now
+func (s *ZkFrameworkInfoStore) setMesosStreamIDTime(frameworkName string) error {
+	return s.write(
+		s.key(frameworkName, _zkMesosStreamIDTimeNode),
+		time.Now().UTC().Format(time.RFC3339Nano))
+}
+
+// write creates or sets a znode, retrying while ZK is unavailable
+func (s *ZkFrameworkInfoStore) write(key string, value string) error {
+	err := backoff.Retry(
+		func() error {
+			return s.client.Put(key, []byte(value), nil)
+		},
+		s.retryPolicy,
+		isZkUnavailable,
+	)
+	return zkFrameworkInfoError(err, key)
+}
+
+// get reads a znode of a framework name
+func (s *ZkFrameworkInfoStore) get(
+	frameworkName string,
+	node string) (string, error) {
+	key := s.key(frameworkName, node)
+	pair, err := s.read(key)
+	if err == store.ErrKeyNotFound {
+		return "", errFrameworkInfoNotFound(frameworkName)
+	}
+	if err != nil {
+		return "", zkFrameworkInfoError(err, key)
+	}
+	return string(pair.Value), nil
+}
+
+// read reads a znode, retrying while ZK is unavailable
+func (s *ZkFrameworkInfoStore) read(key string) (*store.KVPair, error) {
+	var pair *store.KVPair
+	err := backoff.Retry(
+		func() error {
+			var err error
+			pair, err = s.client.Get(key)
+			return err
+		},
+		s.retryPolicy,
+		isZkUnavailable,
+	)
+	return pair, err
+}
+
+// record appends a change to the history znode of a framework name, using
+// the version of the znode read so that concurrent changes are not lost.
+// The history is best effort, failing to record a change is only logged.
+func (s *ZkFrameworkInfoStore) record(
+	frameworkName string,
+	change *FrameworkInfoChange) {
+	key := s.key(frameworkName, _zkHistoryNode)
+	err := backoff.Retry(
+		func() error {
+			previous, err := s.client.Get(key)
+			if err == store.ErrKeyNotFound {
+				previous, err = nil, nil
+			}
+			if err != nil {
+				return err
+			}
+
+			var history []*FrameworkInfoChange
+			if previous != nil {
+				if err := json.Unmarshal(previous.Value, &history); err != nil {
+					log.WithError(err).
+						WithField("key", key).
+						Warn("Framework info history is corrupted, replacing it")
+					history = nil
+				}
+			}
+			buf, err := json.Marshal(appendFrameworkInfoChange(history, change))
+			if err != nil {
+				return err
+			}
+			_, _, err = s.client.AtomicPut(key, buf, previous, nil)
+			return err
+		},
+		s.retryPolicy,
+		func(err error) bool {
+			return isZkUnavailable(err) ||
+				err == store.ErrKeyModified ||
+				err == store.ErrKeyExists
+		},
+	)
+	if err != nil {
+		log.WithError(err).
+			WithFields(log.Fields{
+				"framework_name": frameworkName,
+				"field":          change.Field,
+				"new_value":      change.NewValue,
+			}).Error("Failed to record framework info change")
+	}
+}
+
+// isZkUnavailable returns whether a ZK call failed because ZK is
+// unavailable
+func isZkUnavailable(err error) bool {
+	switch err {
+	case zk.ErrSessionExpired,
+		zk.ErrConnectionClosed,
+		zk.ErrNoServer,
+		zk.ErrClosing:
+		return true
+	}
+	return false
+}
+
+// zkFrameworkInfoError returns the error of a ZK call on the key, an
+// unavailable error if ZK is unavailable
+func zkFrameworkInfoError(err error, key string) error {
+	if err == nil {
+		return nil
+	}
+	if isZkUnavailable(err) {
+		return yarpcerrors.UnavailableErrorf(
+			"zookeeper unavailable for framework info %s: %v", key, err)
+	}
+	return errors.Wrapf(err, "failed to access framework info %s", key)
+}
@@ -0,0 +1,353 @@
+// Copyright (c) 2019 Uber Technologies, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/uber/peloton/pkg/common/backoff"
+
+	"github.com/docker/libkv/store"
+	libkvmock "github.com/docker/libkv/store/mock"
+	"github.com/samuel/go-zookeeper/zk"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/suite"
+	"go.uber.org/yarpc/yarpcerrors"
+)
+
+const (
+	_testZkRoot        = "/peloton/test/framework"
+	_testZkIDKey       = "peloton/test/framework/peloton/id"
+	_testZkStreamIDKey = "peloton/test/framework/peloton/stream_id"
+	_testZkHistoryKey  = "peloton/test/framework/peloton/history"
+
+	_testZkStreamIDTimeKey = "peloton/test/framework/peloton/stream_id_time"
+)
+
+type zkFrameworkInfoStoreTestSuite struct {
+	suite.Suite
+	client *libkvmock.Mock
+	store  *ZkFrameworkInfoStore
+}
+
+func (suite *zkFrameworkInfoStoreTestSuite) SetupTest() {
+	kv, err := libkvmock.New([]string{}, nil)
+	suite.Require().NoError(err)
+	suite.client = kv.(*libkvmock.Mock)
+	suite.store = NewZkFrameworkInfoStore(suite.client, _testZkRoot)
+	suite.store.retryPolicy = backoff.NewRetryPolicy(3, time.Millisecond)
+}
+
+func (suite *zkFrameworkInfoStoreTestSuite) TearDownTest() {
+	suite.client.AssertExpectations(suite.T())
+}
+
+func TestZkFrameworkInfoStore(t *testing.T) {
+	suite.Run(t, new(zkFrameworkInfoStoreTestSuite))
+}
+
+// expectNotFound expects reads of a missing znode
+func (suite *zkFrameworkInfoStoreTestSuite) expectNotFound(key string, times int) {
+	suite.client.On("Get", key).
+		Return((*store.KVPair)(nil), store.ErrKeyNotFound).
+		Times(times)
+}
+
+// expectRecord expects changes to be recorded in an empty history
+func (suite *zkFrameworkInfoStoreTestSuite) expectRecord(times int) {
+	suite.expectNotFound(_testZkHistoryKey, times)
+	suite.client.On("AtomicPut", _testZkHistoryKey, mock.Anything,
+		(*store.KVPair)(nil), (*store.WriteOptions)(nil)).
+		Return(true, &store.KVPair{}, nil).
+		Times(times)
+}
+
+// expectStreamIDTime expects the time of the stream id to be set
+func (suite *zkFrameworkInfoStoreTestSuite) expectStreamIDTime(times int) {
+	suite.client.On("Put", _testZkStreamIDTimeKey, mock.Anything,
+		(*store.WriteOptions)(nil)).Return(nil).Times(times)
+}
+
+// TestSet tests that the framework and stream ids are written to their
+// znodes
+func (suite *zkFrameworkInfoStoreTestSuite) TestSet() {
+	ctx := context.Background()
+	suite.expectNotFound(_testZkIDKey, 1)
+	suite.client.On("Put", _testZkIDKey, []byte("framework-id"),
+		(*store.WriteOptions)(nil)).Return(nil).Once()
+	suite.expectNotFound(_testZkStreamIDKey, 1)
+	suite.client.On("Put", _testZkStreamIDKey, []byte("stream-id"),
+		(*store.WriteOptions)(nil)).Return(nil).Once()
+	suite.expectRecord(2)
+	suite.expectStreamIDTime(1)
+
+	suite.NoError(suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "framework-id"))
+	suite.NoError(suite.store.SetMesosStreamID(ctx, _testFrameworkName, "stream-id"))
+}
+
+// TestGet tests reading the framework and stream ids from their znodes
+func (suite *zkFrameworkInfoStoreTestSuite) TestGet() {
+	ctx := context.Background()
+	suite.client.On("Get", _testZkIDKey).
+		Return(&store.KVPair{Key: _testZkIDKey, Value: []byte("framework-id")}, nil).
+		Once()
+	suite.client.On("Get", _testZkStreamIDKey).
+		Return(&store.KVPair{Key: _testZkStreamIDKey, Value: []byte("stream-id")}, nil).
+		Once()
+
+	id, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("framework-id", id)
+	streamID, err := suite.store.GetMesosStreamID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("stream-id", streamID)
+}
+
+// TestGetNotFound tests that a missing znode is not found
+func (suite *zkFrameworkInfoStoreTestSuite) TestGetNotFound() {
+	suite.client.On("Get", _testZkIDKey).
+		Return((*store.KVPair)(nil), store.ErrKeyNotFound).
+		Once()
+
+	_, err := suite.store.GetFrameworkID(context.Background(), _testFrameworkName)
+	suite.EqualError(err, "FrameworkInfo not found for framework peloton")
+}
+
+// TestSetMesosStreamIDIfCurrent tests that the stream id znode is only
+// replaced if it has the expected value and was not modified since it was
+// read
+func (suite *zkFrameworkInfoStoreTestSuite) TestSetMesosStreamIDIfCurrent() {
+	ctx := context.Background()
+	previous := &store.KVPair{
+		Key:       _testZkStreamIDKey,
+		Value:     []byte("stream-1"),
+		LastIndex: 3,
+	}
+	suite.client.On("Get", _testZkStreamIDKey).Return(previous, nil).Times(3)
+
+	suite.client.On("AtomicPut", _testZkStreamIDKey, []byte("stream-2"),
+		previous, (*store.WriteOptions)(nil)).
+		Return(true, &store.KVPair{}, nil).Once()
+	suite.expectRecord(1)
+	suite.expectStreamIDTime(1)
+	suite.NoError(suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "stream-2", "stream-1"))
+
+	// the value is not the expected one
+	err := suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "stream-2", "stream-0")
+	suite.True(IsMesosStreamIDConflict(err))
+
+	// the znode was modified after it was read
+	suite.client.On("AtomicPut", _testZkStreamIDKey, []byte("stream-3"),
+		previous, (*store.WriteOptions)(nil)).
+		Return(false, (*store.KVPair)(nil), store.ErrKeyModified).Once()
+	err = suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "stream-3", "stream-1")
+	suite.EqualError(err,
+		`mesos stream id of framework peloton is no longer "stream-1"`)
+}
+
+// TestSetMesosStreamIDIfCurrentMissing tests that a missing stream id znode
+// is only created if no stream id is expected
+func (suite *zkFrameworkInfoStoreTestSuite) TestSetMesosStreamIDIfCurrentMissing() {
+	ctx := context.Background()
+	suite.client.On("Get", _testZkStreamIDKey).
+		Return((*store.KVPair)(nil), store.ErrKeyNotFound).
+		Times(3)
+
+	err := suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "stream-1", "stream-0")
+	suite.True(IsMesosStreamIDConflict(err))
+
+	suite.client.On("AtomicPut", _testZkStreamIDKey, []byte("stream-1"),
+		(*store.KVPair)(nil), (*store.WriteOptions)(nil)).
+		Return(true, &store.KVPair{}, nil).Once()
+	suite.expectRecord(1)
+	suite.expectStreamIDTime(1)
+	suite.NoError(suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "stream-1", ""))
+
+	// another subscriber created the znode after it was read
+	suite.client.On("AtomicPut", _testZkStreamIDKey, []byte("stream-2"),
+		(*store.KVPair)(nil), (*store.WriteOptions)(nil)).
+		Return(false, (*store.KVPair)(nil), store.ErrKeyExists).Once()
+	err = suite.store.SetMesosStreamIDIfCurrent(
+		ctx, _testFrameworkName, "stream-2", "")
+	suite.True(IsMesosStreamIDConflict(err))
+}
+
+// TestRetrySessionExpired tests that calls are retried after the session
+// expired
+func (suite *zkFrameworkInfoStoreTestSuite) TestRetrySessionExpired() {
+	ctx := context.Background()
+	suite.expectNotFound(_testZkIDKey, 1)
+	suite.client.On("Put", _testZkIDKey, []byte("framework-id"),
+		(*store.WriteOptions)(nil)).Return(zk.ErrSessionExpired).Once()
+	suite.client.On("Put", _testZkIDKey, []byte("framework-id"),
+		(*store.WriteOptions)(nil)).Return(nil).Once()
+	suite.expectRecord(1)
+	suite.NoError(suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "framework-id"))
+
+	suite.client.On("Get", _testZkIDKey).
+		Return((*store.KVPair)(nil), zk.ErrConnectionClosed).
+		Once()
+	suite.client.On("Get", _testZkIDKey).
+		Return(&store.KVPair{Key: _testZkIDKey, Value: []byte("framework-id")}, nil).
+		Once()
+	id, err := suite.store.GetFrameworkID(ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("framework-id", id)
+}
+
+// TestUnavailable tests that calls fail with an unavailable error once the
+// retries are exhausted
+func (suite *zkFrameworkInfoStoreTestSuite) TestUnavailable() {
+	ctx := context.Background()
+	suite.client.On("Get", _testZkStreamIDKey).
+		Return((*store.KVPair)(nil), zk.ErrNoServer).
+		Times(3)
+	streamID, err := suite.store.GetMesosStreamID(ctx, _testFrameworkName)
+	suite.Empty(streamID)
+	suite.True(yarpcerrors.IsUnavailable(err))
+
+	suite.expectNotFound(_testZkIDKey, 1)
+	suite.client.On("Put", _testZkIDKey, []byte("framework-id"),
+		(*store.WriteOptions)(nil)).Return(zk.ErrSessionExpired).Times(3)
+	err = suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "framework-id")
+	suite.True(yarpcerrors.IsUnavailable(err))
+}
+
+// TestOtherErrors tests that other errors are not retried
+func (suite *zkFrameworkInfoStoreTestSuite) TestOtherErrors() {
+	suite.client.On("Get", _testZkIDKey).
+		Return((*store.KVPair)(nil), errors.New("zk: not authenticated")).
+		Once()
+
+	_, err := suite.store.GetFrameworkID(context.Background(), _testFrameworkName)
+	suite.EqualError(err,
+		"failed to access framework info "+_testZkIDKey+": zk: not authenticated")
+	suite.False(yarpcerrors.IsUnavailable(err))
+}
+
+// TestHistory tests that changes are appended to the history znode with
+// their old values, and read back the most recent first
+func (suite *zkFrameworkInfoStoreTestSuite) TestHistory() {
+	ctx := context.Background()
+	history, err := json.Marshal([]*FrameworkInfoChange{
+		{Field: FrameworkInfoFieldMesosStreamID, NewValue: "stream-1"},
+	})
+	suite.NoError(err)
+	previous := &store.KVPair{Key: _testZkHistoryKey, Value: history, LastIndex: 7}
+
+	suite.client.On("Get", _testZkStreamIDKey).
+		Return(&store.KVPair{Key: _testZkStreamIDKey, Value: []byte("stream-1")}, nil).
+		Once()
+	suite.client.On("Put", _testZkStreamIDKey, []byte("stream-2"),
+		(*store.WriteOptions)(nil)).Return(nil).Once()
+	suite.client.On("Get", _testZkHistoryKey).Return(previous, nil).Once()
+
+	var written []byte
+	suite.client.On("AtomicPut", _testZkHistoryKey, mock.Anything,
+		previous, (*store.WriteOptions)(nil)).
+		Run(func(args mock.Arguments) { written = args.Get(1).([]byte) }).
+		Return(true, &store.KVPair{}, nil).Once()
+	suite.expectStreamIDTime(1)
+	suite.NoError(suite.store.SetMesosStreamID(ctx, _testFrameworkName, "stream-2"))
+
+	suite.client.On("Get", _testZkHistoryKey).
+		Return(&store.KVPair{Key: _testZkHistoryKey, Value: written}, nil).
+		Once()
+	changes, err := suite.store.GetFrameworkInfoHistory(ctx, _testFrameworkName, 0)
+	suite.NoError(err)
+	suite.Len(changes, 2)
+	suite.Equal("stream-1", changes[0].OldValue)
+	suite.Equal("stream-2", changes[0].NewValue)
+	suite.NotEmpty(changes[0].Host)
+	suite.Equal("stream-1", changes[1].NewValue)
+}
+
+// TestHistoryRecordConflict tests that a change is appended again to the
+// history modified by another writer, and that failing to record it does
+// not fail the update
+func (suite *zkFrameworkInfoStoreTestSuite) TestHistoryRecordConflict() {
+	ctx := context.Background()
+	suite.expectNotFound(_testZkIDKey, 2)
+	suite.client.On("Put", _testZkIDKey, []byte("framework-id"),
+		(*store.WriteOptions)(nil)).Return(nil).Twice()
+
+	suite.expectNotFound(_testZkHistoryKey, 2)
+	suite.client.On("AtomicPut", _testZkHistoryKey, mock.Anything,
+		(*store.KVPair)(nil), (*store.WriteOptions)(nil)).
+		Return(false, (*store.KVPair)(nil), store.ErrKeyExists).Once()
+	suite.client.On("AtomicPut", _testZkHistoryKey, mock.Anything,
+		(*store.KVPair)(nil), (*store.WriteOptions)(nil)).
+		Return(true, &store.KVPair{}, nil).Once()
+	suite.NoError(suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "framework-id"))
+
+	suite.client.On("Get", _testZkHistoryKey).
+		Return((*store.KVPair)(nil), errors.New("zk: not authenticated")).
+		Once()
+	suite.NoError(suite.store.SetMesosFrameworkID(ctx, _testFrameworkName, "framework-id"))
+}
+
+// TestHistoryNotFound tests that a framework without history has no changes
+func (suite *zkFrameworkInfoStoreTestSuite) TestHistoryNotFound() {
+	suite.expectNotFound(_testZkHistoryKey, 1)
+	changes, err := suite.store.GetFrameworkInfoHistory(
+		context.Background(), _testFrameworkName, 10)
+	suite.NoError(err)
+	suite.Empty(changes)
+}
+
+// TestGetMesosStreamIDWithTime tests reading the stream id with the time it
+// was set, which is zero if it is missing or invalid
+func (suite *zkFrameworkInfoStoreTestSuite) TestGetMesosStreamIDWithTime() {
+	ctx := context.Background()
+	setTime := time.Date(2019, 3, 4, 5, 6, 7, 8, time.UTC)
+	suite.client.On("Get", _testZkStreamIDKey).
+		Return(&store.KVPair{Key: _testZkStreamIDKey, Value: []byte("stream-id")}, nil).
+		Times(3)
+	suite.client.On("Get", _testZkStreamIDTimeKey).
+		Return(&store.KVPair{
+			Key:   _testZkStreamIDTimeKey,
+			Value: []byte(setTime.Format(time.RFC3339Nano)),
+		}, nil).
+		Once()
+	suite.client.On("Get", _testZkStreamIDTimeKey).
+		Return((*store.KVPair)(nil), store.ErrKeyNotFound).
+		Once()
+	suite.client.On("Get", _testZkStreamIDTimeKey).
+		Return(&store.KVPair{Key: _testZkStreamIDTimeKey, Value: []byte("yesterday")}, nil).
+		Once()
+
+	streamID, streamIDTime, err := suite.store.GetMesosStreamIDWithTime(
+		ctx, _testFrameworkName)
+	suite.NoError(err)
+	suite.Equal("stream-id", streamID)
+	suite.True(setTime.Equal(streamIDTime))
+
+	for i := 0; i < 2; i++ {
+		streamID, streamIDTime, err = suite.store.GetMesosStreamIDWithTime(
+			ctx, _testFrameworkName)
+		suite.NoError(err)
+		suite.Equal("stream-id", streamID)
+		suite.True(streamIDTime.IsZero())
+	}
+}